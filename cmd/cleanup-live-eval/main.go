@@ -6,13 +6,13 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/Napageneral/mnemonic/internal/config"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-const defaultDBPath = "/Users/tyler/Library/Application Support/Cortex/cortex.db"
-
 func main() {
 	runID := flag.String("run-id", "", "Run ID to delete (required)")
+	defaultDBPath, _ := config.DBPath()
 	dbPath := flag.String("db", defaultDBPath, "SQLite DB to clean")
 	dryRun := flag.Bool("dry-run", false, "Show counts without deleting")
 	flag.Parse()
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -12,28 +14,53 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	stdsync "sync"
 	"syscall"
 	"time"
 
 	"github.com/Napageneral/mnemonic/internal/adapters"
+	"github.com/Napageneral/mnemonic/internal/audit"
+	"github.com/Napageneral/mnemonic/internal/avatars"
 	"github.com/Napageneral/mnemonic/internal/bus"
+	"github.com/Napageneral/mnemonic/internal/changelog"
 	"github.com/Napageneral/mnemonic/internal/chunk"
 	"github.com/Napageneral/mnemonic/internal/compute"
 	"github.com/Napageneral/mnemonic/internal/config"
+	"github.com/Napageneral/mnemonic/internal/coverage"
 	"github.com/Napageneral/mnemonic/internal/db"
+	"github.com/Napageneral/mnemonic/internal/debugbundle"
+	"github.com/Napageneral/mnemonic/internal/digest"
 	"github.com/Napageneral/mnemonic/internal/documents"
+	"github.com/Napageneral/mnemonic/internal/drafts"
+	"github.com/Napageneral/mnemonic/internal/errs"
 	"github.com/Napageneral/mnemonic/internal/gemini"
 	"github.com/Napageneral/mnemonic/internal/identify"
 	"github.com/Napageneral/mnemonic/internal/importer"
+	"github.com/Napageneral/mnemonic/internal/instancelock"
 	"github.com/Napageneral/mnemonic/internal/live"
+	"github.com/Napageneral/mnemonic/internal/maintenance"
 	"github.com/Napageneral/mnemonic/internal/me"
+	"github.com/Napageneral/mnemonic/internal/memory"
+	"github.com/Napageneral/mnemonic/internal/namespace"
+	"github.com/Napageneral/mnemonic/internal/notes"
+	"github.com/Napageneral/mnemonic/internal/plugin"
+	"github.com/Napageneral/mnemonic/internal/power"
 	"github.com/Napageneral/mnemonic/internal/query"
+	"github.com/Napageneral/mnemonic/internal/repl"
 	"github.com/Napageneral/mnemonic/internal/search"
+	"github.com/Napageneral/mnemonic/internal/seed"
+	"github.com/Napageneral/mnemonic/internal/server"
 	"github.com/Napageneral/mnemonic/internal/sync"
 	"github.com/Napageneral/mnemonic/internal/tag"
+	"github.com/Napageneral/mnemonic/internal/threads"
 	"github.com/Napageneral/mnemonic/internal/timeline"
+	"github.com/Napageneral/mnemonic/internal/todos"
+	"github.com/Napageneral/mnemonic/internal/usage"
+	"github.com/Napageneral/mnemonic/internal/worker"
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/cobra"
 )
@@ -52,6 +79,30 @@ func main() {
 		Long: `Mnemonic aggregates your communications and AI sessions across all channels 
 (iMessage, Gmail, Cursor, Codex, etc.) into a unified searchable memory
 with identity resolution, semantic search, and analysis.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if cmd.Name() == "version" || cmd.Name() == "help" {
+				return
+			}
+			// Sensitive extraction settings apply to every command that syncs facts.
+			// An unreadable config would silently drop the medical opt-in and
+			// write private facts to the shared namespace, so it stops the
+			// command instead.
+			cfg, err := config.Load()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to load config: %w", err))
+			}
+			identify.SetMedicalFactsEnabled(cfg.Privacy.MedicalFacts)
+			namespace.Set(cfg.Me.Namespace)
+			// So does the household member whose private data is in scope
+			if ns := os.Getenv("MNEMONIC_NAMESPACE"); ns != "" {
+				namespace.Set(ns)
+			}
+			if ns := namespace.Current(); ns != "" {
+				if err := namespace.Validate(ns); err != nil {
+					exitWithError(err)
+				}
+			}
+		},
 	}
 
 	rootCmd.PersistentFlags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
@@ -173,6 +224,7 @@ with identity resolution, semantic search, and analysis.`,
 				fmt.Printf("✓ Data directory: %s\n", result.DataDir)
 				fmt.Printf("✓ Database: %s\n", result.DBPath)
 				fmt.Println("\nMnemonic initialized successfully!")
+				fmt.Println("After your first sync, run 'mnemonic report coverage' to see what was imported.")
 			}
 		},
 	})
@@ -213,16 +265,7 @@ with identity resolution, semantic search, and analysis.`,
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{
-					OK:      false,
-					Message: fmt.Sprintf("Failed to open database: %v", err),
-				}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -314,21 +357,13 @@ with identity resolution, semantic search, and analysis.`,
 				OK         bool           `json:"ok"`
 				Message    string         `json:"message,omitempty"`
 				Name       string         `json:"name,omitempty"`
+				Namespace  string         `json:"namespace,omitempty"`
 				Identities []IdentityInfo `json:"identities,omitempty"`
 			}
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{
-					OK:      false,
-					Message: fmt.Sprintf("Failed to open database: %v", err),
-				}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -377,6 +412,9 @@ with identity resolution, semantic search, and analysis.`,
 				OK:   true,
 				Name: person.CanonicalName,
 			}
+			if person.Namespace != nil {
+				result.Namespace = *person.Namespace
+			}
 
 			for _, id := range identities {
 				result.Identities = append(result.Identities, IdentityInfo{
@@ -389,6 +427,9 @@ with identity resolution, semantic search, and analysis.`,
 				printJSON(result)
 			} else {
 				fmt.Printf("Name: %s\n", person.CanonicalName)
+				if result.Namespace != "" {
+					fmt.Printf("Namespace: %s\n", result.Namespace)
+				}
 				if len(identities) > 0 {
 					fmt.Println("\nIdentities:")
 					for _, id := range identities {
@@ -401,88 +442,301 @@ with identity resolution, semantic search, and analysis.`,
 		},
 	}
 
+	meClaimCmd := &cobra.Command{
+		Use:   "claim",
+		Short: "Move your identity and sensitive facts into your namespace",
+		Long: `Prepare a database for sharing with another household member. Set
+me.namespace in your config (or MNEMONIC_NAMESPACE), then run this once:
+your "me" person and the sensitive facts stored so far become private to
+your namespace. The other member sets their own namespace and runs
+'mnemonic me set' to create their identity. Contacts, messages and the
+memory graph stay shared.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool            `json:"ok"`
+				Result  *me.ClaimResult `json:"result,omitempty"`
+				Message string          `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			result, err := me.ClaimNamespace(database)
+			if err != nil {
+				res := Result{OK: false, Message: fmt.Sprintf("Failed to claim namespace: %v", err)}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Result: result})
+				return
+			}
+			fmt.Printf("Claimed namespace %s: your identity and %d sensitive facts are now private to it\n", result.Namespace, result.SensitiveFacts)
+		},
+	}
+
 	meCmd.AddCommand(meSetCmd)
 	meCmd.AddCommand(meShowCmd)
+	meCmd.AddCommand(meClaimCmd)
 	rootCmd.AddCommand(meCmd)
 
 	// adapters command
+	listAdapters := func(cmd *cobra.Command, args []string) {
+		type Result struct {
+			OK       bool          `json:"ok"`
+			Message  string        `json:"message,omitempty"`
+			Adapters []adapterInfo `json:"adapters,omitempty"`
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			result := Result{
+				OK:      false,
+				Message: fmt.Sprintf("Failed to load config: %v", err),
+			}
+			if jsonOutput {
+				printJSON(result)
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+			}
+			os.Exit(1)
+		}
+
+		result := Result{OK: true}
+
+		if len(cfg.Adapters) == 0 {
+			result.Message = "No adapters configured. Run 'mnemonic connect <adapter>' to configure one."
+			if jsonOutput {
+				printJSON(result)
+			} else {
+				fmt.Println(result.Message)
+			}
+			return
+		}
+
+		result.Adapters, err = collectAdapterInfo(cfg, nil)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		if jsonOutput {
+			printJSON(result)
+		} else {
+			fmt.Println("Configured adapters:")
+			for _, a := range result.Adapters {
+				statusSymbol := "✗"
+				if a.Status == "ready" {
+					statusSymbol = "✓"
+				}
+				enabledStr := "disabled"
+				if a.Enabled {
+					enabledStr = "enabled"
+				}
+				lastSync := "never synced"
+				if a.LastSyncAt != "" {
+					lastSync = "last sync " + a.LastSyncAt
+				}
+				fmt.Printf("  %s %s (%s) - %s - %s - %s\n", statusSymbol, a.Name, a.Type, enabledStr, a.Status, lastSync)
+			}
+			for _, problem := range cfg.PluginErrors {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", problem)
+			}
+		}
+	}
+
 	adaptersCmd := &cobra.Command{
 		Use:   "adapters",
 		Short: "List configured adapters",
-		Run: func(cmd *cobra.Command, args []string) {
-			type AdapterInfo struct {
-				Name    string `json:"name"`
-				Type    string `json:"type"`
-				Enabled bool   `json:"enabled"`
-				Status  string `json:"status"`
+		Long: `List configured adapters with their readiness and last sync time.
+
+The subcommands enable or disable adapters in config.yaml and show an
+adapter's sync status in detail.`,
+		Args: cobra.NoArgs,
+		Run:  listAdapters,
+	}
+
+	adaptersListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List configured adapters",
+		Args:  cobra.NoArgs,
+		Run:   listAdapters,
+	}
+
+	setAdaptersEnabled := func(enabled bool) func(cmd *cobra.Command, args []string) {
+		return func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK       bool     `json:"ok"`
+				Adapters []string `json:"adapters"`
+				Enabled  bool     `json:"enabled"`
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to load config: %w", err))
+			}
+			for _, name := range args {
+				if err := cfg.SetAdapterEnabled(name, enabled); err != nil {
+					exitWithError(err)
+				}
+			}
+			if err := cfg.Save(); err != nil {
+				exitWithError(fmt.Errorf("Failed to save config: %w", err))
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Adapters: args, Enabled: enabled})
+				return
+			}
+			state := "Disabled"
+			if enabled {
+				state = "Enabled"
 			}
+			fmt.Printf("%s %s\n", state, strings.Join(args, ", "))
+		}
+	}
+
+	adaptersEnableCmd := &cobra.Command{
+		Use:   "enable <adapter...>",
+		Short: "Enable adapters so sync and live watch pick them up",
+		Args:  cobra.MinimumNArgs(1),
+		Run:   setAdaptersEnabled(true),
+	}
 
+	adaptersDisableCmd := &cobra.Command{
+		Use:   "disable <adapter...>",
+		Short: "Disable adapters without removing their configuration",
+		Long: `Disable adapters. Their configuration and synced data are kept;
+sync skips them until they are enabled again.`,
+		Args: cobra.MinimumNArgs(1),
+		Run:  setAdaptersEnabled(false),
+	}
+
+	adaptersStatusCmd := &cobra.Command{
+		Use:   "status [adapter...]",
+		Short: "Show adapter readiness and last sync",
+		Args:  cobra.ArbitraryArgs,
+		Run: func(cmd *cobra.Command, args []string) {
 			type Result struct {
 				OK       bool          `json:"ok"`
-				Message  string        `json:"message,omitempty"`
-				Adapters []AdapterInfo `json:"adapters,omitempty"`
+				Adapters []adapterInfo `json:"adapters"`
 			}
 
 			cfg, err := config.Load()
 			if err != nil {
-				result := Result{
-					OK:      false,
-					Message: fmt.Sprintf("Failed to load config: %v", err),
+				exitWithError(fmt.Errorf("Failed to load config: %w", err))
+			}
+			adapters, err := collectAdapterInfo(cfg, args)
+			if err != nil {
+				exitWithError(err)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Adapters: adapters})
+				return
+			}
+			if len(adapters) == 0 {
+				fmt.Println("No adapters configured. Run 'mnemonic connect <adapter>' to configure one.")
+				return
+			}
+			for i, a := range adapters {
+				if i > 0 {
+					fmt.Println()
 				}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				fmt.Printf("%s (%s)\n", a.Name, a.Type)
+				fmt.Printf("  Enabled: %v\n", a.Enabled)
+				fmt.Printf("  Status: %s\n", a.Status)
+				fmt.Printf("  Live: %v\n", a.Live)
+				if a.LastSyncAt == "" {
+					fmt.Println("  Last sync: never")
+					continue
+				}
+				fmt.Printf("  Last sync: %s (%s)\n", a.LastSyncAt, a.LastSyncStatus)
+				if a.LastError != "" {
+					fmt.Printf("  Error: %s\n", a.LastError)
 				}
-				os.Exit(1)
 			}
+		},
+	}
 
-			result := Result{OK: true}
+	adaptersCmd.AddCommand(adaptersListCmd)
+	adaptersCmd.AddCommand(adaptersEnableCmd)
+	adaptersCmd.AddCommand(adaptersDisableCmd)
+	adaptersCmd.AddCommand(adaptersStatusCmd)
+	rootCmd.AddCommand(adaptersCmd)
 
-			if len(cfg.Adapters) == 0 {
-				result.Message = "No adapters configured. Run 'mnemonic connect <adapter>' to configure one."
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Println(result.Message)
-				}
-				return
+	// plugins command
+	pluginsCmd := &cobra.Command{
+		Use:   "plugins",
+		Short: "List plugins from config.yaml and the plugin directory",
+		Long: `List external plugins: those under plugins in config.yaml, and those
+found in the plugin directory (plugins/ in the config directory, or
+plugin_dir). The directory holds <name>.yaml manifests and executables
+named cortex-<kind>-<name>. Adapter plugins also show up as adapters and
+sync like any other.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type PluginInfo struct {
+				Name    string `json:"name"`
+				Kind    string `json:"kind"`
+				Command string `json:"command"`
+				Source  string `json:"source"`
+			}
+			type Result struct {
+				OK       bool         `json:"ok"`
+				Dir      string       `json:"dir"`
+				Plugins  []PluginInfo `json:"plugins"`
+				Problems []string     `json:"problems,omitempty"`
+				Message  string       `json:"message,omitempty"`
 			}
 
-			// Determine adapter status based on enabled flag and prerequisites
-			for name, adapter := range cfg.Adapters {
-				status := "disabled"
-				if adapter.Enabled {
-					status = checkAdapterStatus(name, adapter)
-				}
+			cfg, err := config.Load()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to load config: %w", err))
+			}
+			dir, err := cfg.PluginDirPath()
+			if err != nil {
+				exitWithError(err)
+			}
 
-				result.Adapters = append(result.Adapters, AdapterInfo{
-					Name:    name,
-					Type:    adapter.Type,
-					Enabled: adapter.Enabled,
-					Status:  status,
-				})
+			result := Result{OK: true, Dir: dir, Plugins: []PluginInfo{}, Problems: cfg.PluginErrors}
+			for name, pc := range cfg.Plugins {
+				source := pc.Source
+				if source == "" {
+					source = "config.yaml"
+				}
+				result.Plugins = append(result.Plugins, PluginInfo{Name: name, Kind: pc.Kind, Command: pc.Command, Source: source})
 			}
+			sort.Slice(result.Plugins, func(i, j int) bool { return result.Plugins[i].Name < result.Plugins[j].Name })
 
 			if jsonOutput {
 				printJSON(result)
-			} else {
-				fmt.Println("Configured adapters:")
-				for _, a := range result.Adapters {
-					statusSymbol := "✗"
-					if a.Status == "ready" {
-						statusSymbol = "✓"
-					}
-					enabledStr := "disabled"
-					if a.Enabled {
-						enabledStr = "enabled"
-					}
-					fmt.Printf("  %s %s (%s) - %s - %s\n", statusSymbol, a.Name, a.Type, enabledStr, a.Status)
+				return
+			}
+			fmt.Printf("Plugin directory: %s\n", dir)
+			if len(result.Plugins) == 0 {
+				fmt.Println("No plugins")
+			}
+			for _, p := range result.Plugins {
+				fmt.Printf("  %s (%s) - %s\n", p.Name, p.Kind, p.Command)
+				if p.Source != p.Command {
+					fmt.Printf("      from %s\n", p.Source)
 				}
 			}
+			for _, problem := range result.Problems {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", problem)
+			}
 		},
 	}
-	rootCmd.AddCommand(adaptersCmd)
+	rootCmd.AddCommand(pluginsCmd)
 
 	// connect command
 	connectCmd := &cobra.Command{
@@ -494,44 +748,85 @@ with identity resolution, semantic search, and analysis.`,
 	// connect imessage
 	connectImessageCmd := &cobra.Command{
 		Use:   "imessage",
-		Short: "Configure iMessage adapter (via Eve)",
+		Short: "Configure iMessage adapter (via Eve, or chat.db with --chat-db)",
 		Run: func(cmd *cobra.Command, args []string) {
+			useChatDB, _ := cmd.Flags().GetBool("chat-db")
+			chatDBPath, _ := cmd.Flags().GetString("path")
 			type Result struct {
 				OK      bool   `json:"ok"`
 				Message string `json:"message,omitempty"`
 			}
 
-			// Check if Eve database exists
-			home, err := os.UserHomeDir()
-			if err != nil {
-				result := Result{
-					OK:      false,
-					Message: fmt.Sprintf("Failed to get home directory: %v", err),
+			adapterCfg := config.AdapterConfig{Type: "eve", Enabled: true}
+			sourceLabel, sourcePath := "Eve database", ""
+			if useChatDB {
+				// Read the Messages database directly, without Eve
+				sourceLabel, sourcePath = "Messages database", chatDBPath
+				if sourcePath == "" {
+					var err error
+					if sourcePath, err = adapters.DefaultChatDBPath(); err != nil {
+						result := Result{
+							OK:      false,
+							Message: err.Error(),
+						}
+						if jsonOutput {
+							printJSON(result)
+						} else {
+							fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+						}
+						os.Exit(1)
+					}
 				}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				if _, err := os.Stat(sourcePath); err != nil {
+					result := Result{
+						OK:      false,
+						Message: fmt.Sprintf("Messages database not readable at %s: %v", sourcePath, err),
+					}
+					if jsonOutput {
+						printJSON(result)
+					} else {
+						fmt.Fprintf(os.Stderr, "Error: %s\n\n", result.Message)
+						fmt.Fprintf(os.Stderr, "Grant Full Disk Access to your terminal in System Settings > Privacy & Security.\n")
+					}
+					os.Exit(1)
 				}
-				os.Exit(1)
-			}
-
-			eveDBPath := filepath.Join(home, "Library", "Application Support", "Eve", "eve.db")
-			if _, err := os.Stat(eveDBPath); os.IsNotExist(err) {
-				result := Result{
-					OK:      false,
-					Message: fmt.Sprintf("Eve database not found at %s. Install and run Eve first: brew install Napageneral/tap/eve && eve init && eve sync", eveDBPath),
+				adapterCfg = config.AdapterConfig{Type: "chatdb", Enabled: true}
+				if chatDBPath != "" {
+					adapterCfg.Options = map[string]interface{}{"path": chatDBPath}
 				}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n\n", result.Message)
-					fmt.Fprintf(os.Stderr, "To fix this:\n")
-					fmt.Fprintf(os.Stderr, "  1. Install Eve: brew install Napageneral/tap/eve\n")
-					fmt.Fprintf(os.Stderr, "  2. Initialize Eve: eve init\n")
-					fmt.Fprintf(os.Stderr, "  3. Sync Eve: eve sync\n")
+			} else {
+				// Check if Eve database exists
+				var err error
+				sourcePath, err = config.EveDBPath()
+				if err != nil {
+					result := Result{
+						OK:      false,
+						Message: err.Error(),
+					}
+					if jsonOutput {
+						printJSON(result)
+					} else {
+						fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+					}
+					os.Exit(1)
+				}
+
+				if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
+					result := Result{
+						OK:      false,
+						Message: fmt.Sprintf("Eve database not found at %s. Install and run Eve first: brew install Napageneral/tap/eve && eve init && eve sync", sourcePath),
+					}
+					if jsonOutput {
+						printJSON(result)
+					} else {
+						fmt.Fprintf(os.Stderr, "Error: %s\n\n", result.Message)
+						fmt.Fprintf(os.Stderr, "To fix this:\n")
+						fmt.Fprintf(os.Stderr, "  1. Install Eve: brew install Napageneral/tap/eve\n")
+						fmt.Fprintf(os.Stderr, "  2. Initialize Eve: eve init\n")
+						fmt.Fprintf(os.Stderr, "  3. Sync Eve: eve sync\n")
+					}
+					os.Exit(1)
 				}
-				os.Exit(1)
 			}
 
 			cfg, err := config.Load()
@@ -548,10 +843,7 @@ with identity resolution, semantic search, and analysis.`,
 				os.Exit(1)
 			}
 
-			cfg.Adapters["imessage"] = config.AdapterConfig{
-				Type:    "eve",
-				Enabled: true,
-			}
+			cfg.Adapters["imessage"] = adapterCfg
 
 			if err := cfg.Save(); err != nil {
 				result := Result{
@@ -575,12 +867,15 @@ with identity resolution, semantic search, and analysis.`,
 				printJSON(result)
 			} else {
 				fmt.Println("✓ iMessage adapter configured")
-				fmt.Printf("  Eve database: %s\n", eveDBPath)
+				fmt.Printf("  %s: %s\n", sourceLabel, sourcePath)
 				fmt.Println("\nRun 'mnemonic sync' to sync iMessage events")
 			}
 		},
 	}
 
+	connectImessageCmd.Flags().Bool("chat-db", false, "Read ~/Library/Messages/chat.db directly instead of Eve (needs Full Disk Access)")
+	connectImessageCmd.Flags().String("path", "", "Path to chat.db (with --chat-db)")
+
 	// connect gmail
 	connectGmailCmd := &cobra.Command{
 		Use:   "gmail",
@@ -681,55 +976,291 @@ with identity resolution, semantic search, and analysis.`,
 	connectCmd.AddCommand(connectImessageCmd)
 	connectCmd.AddCommand(connectGmailCmd)
 
-	// connect calendar
-	connectCalendarCmd := &cobra.Command{
-		Use:   "calendar",
-		Short: "Configure Google Calendar adapter (via gogcli)",
+	// connect calls
+	var callsPath, callsVoicemail string
+	connectCallsCmd := &cobra.Command{
+		Use:   "calls",
+		Short: "Configure call history adapter (macOS call history and voicemail)",
+		Long: `Sync phone and FaceTime calls from the macOS call history, which also
+holds the calls of an iPhone signed in to the same Apple account. Each
+call is an event with its direction, duration and whether it was answered,
+so calls show up on a person's timeline next to their messages.
+
+With --voicemail, voicemails are imported too, with their transcripts,
+from a voicemail.db or the directory holding it (such as Library/Voicemail
+in an unencrypted iPhone backup).
+
+Examples:
+  mnemonic connect calls
+  mnemonic connect calls --voicemail ~/Backups/iPhone/Library/Voicemail`,
+		Args: cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			type Result struct {
 				OK      bool   `json:"ok"`
 				Message string `json:"message,omitempty"`
 			}
-
-			account, _ := cmd.Flags().GetString("account")
-			if account == "" {
-				result := Result{OK: false, Message: "The --account flag is required (e.g., --account user@gmail.com)"}
+			fail := func(msg string) {
 				if jsonOutput {
-					printJSON(result)
+					printJSON(Result{OK: false, Message: msg})
 				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
 				}
 				os.Exit(1)
 			}
-			account = strings.TrimSpace(strings.ToLower(account))
-			adapterName := fmt.Sprintf("calendar-%s", account)
 
-			cfg, err := config.Load()
-			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to load config: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+			options := map[string]interface{}{}
+			for key, path := range map[string]*string{"path": &callsPath, "voicemail": &callsVoicemail} {
+				if *path == "" {
+					continue
 				}
-				os.Exit(1)
-			}
-
-			cfg.Adapters[adapterName] = config.AdapterConfig{
-				Type:    "gogcli_calendar",
-				Enabled: true,
-				Options: map[string]interface{}{
-					"account": account,
-				},
+				abs, err := filepath.Abs(*path)
+				if err != nil {
+					fail(fmt.Sprintf("Invalid path: %v", err))
+				}
+				*path = abs
+				options[key] = abs
 			}
-			if err := cfg.Save(); err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to save config: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+			if _, err := adapters.NewCallHistoryAdapter(adapters.CallHistoryAdapterOptions{Path: callsPath, Voicemail: callsVoicemail}); err != nil {
+				if !jsonOutput {
+					fmt.Fprintf(os.Stderr, "Grant Full Disk Access to your terminal in System Settings > Privacy & Security.\n")
 				}
-				os.Exit(1)
+				fail(err.Error())
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				fail(fmt.Sprintf("Failed to load config: %v", err))
+			}
+			adapterCfg := config.AdapterConfig{Type: "callhistory", Enabled: true}
+			if len(options) > 0 {
+				adapterCfg.Options = options
+			}
+			cfg.Adapters["calls"] = adapterCfg
+			if err := cfg.Save(); err != nil {
+				fail(fmt.Sprintf("Failed to save config: %v", err))
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Message: "Call history adapter configured successfully"})
+				return
+			}
+			fmt.Println("✓ Call history adapter configured")
+			if callsPath != "" {
+				fmt.Printf("  Calls: %s\n", callsPath)
+			} else {
+				fmt.Println("  Calls: macOS call history")
+			}
+			if callsVoicemail != "" {
+				fmt.Printf("  Voicemail: %s\n", callsVoicemail)
+			}
+			fmt.Println("\nRun 'mnemonic sync calls' to import calls")
+		},
+	}
+	connectCallsCmd.Flags().StringVar(&callsPath, "path", "", "Call history database (default: ~/Library/Application Support/CallHistoryDB/CallHistory.storedata)")
+	connectCallsCmd.Flags().StringVar(&callsVoicemail, "voicemail", "", "voicemail.db, or the directory holding it, to import voicemails")
+	connectCmd.AddCommand(connectCallsCmd)
+
+	// connect discord
+	var discordTokenEnv string
+	var discordBot, discordDMs bool
+	var discordChannels []string
+	connectDiscordCmd := &cobra.Command{
+		Use:   "discord",
+		Short: "Configure Discord adapter (DMs and guild channels)",
+		Long: `Sync Discord direct messages and selected guild channels through the
+Discord API. Each channel becomes a thread; replies keep pointing at the
+message they answer, and people are matched across channels by their
+Discord user ID.
+
+The token is read from an environment variable (DISCORD_TOKEN unless
+--token-env names another) and never stored in the config. A bot token
+(--bot) sees the guild channels the bot was added to; listing your own
+DMs needs your account's token.
+
+Channel IDs are shown by "Copy Channel ID" in Discord with Developer Mode
+enabled.
+
+Examples:
+  mnemonic connect discord --dms
+  mnemonic connect discord --bot --channel 1234567890 --channel 2345678901`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool   `json:"ok"`
+				Message string `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+				}
+				os.Exit(1)
+			}
+
+			opts := adapters.DiscordAdapterOptions{TokenEnv: discordTokenEnv, Bot: discordBot, DMs: discordDMs, Channels: discordChannels}
+			if _, err := adapters.NewDiscordAdapter(opts); err != nil {
+				fail(err.Error())
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				fail(fmt.Sprintf("Failed to load config: %v", err))
+			}
+			options := map[string]interface{}{"dms": discordDMs}
+			if discordTokenEnv != "" {
+				options["token_env"] = discordTokenEnv
+			}
+			if discordBot {
+				options["bot"] = true
+			}
+			if len(discordChannels) > 0 {
+				options["channels"] = discordChannels
+			}
+			cfg.Adapters["discord"] = config.AdapterConfig{Type: "discord", Enabled: true, Options: options}
+			if err := cfg.Save(); err != nil {
+				fail(fmt.Sprintf("Failed to save config: %v", err))
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Message: "Discord adapter configured successfully"})
+				return
+			}
+			fmt.Println("✓ Discord adapter configured")
+			if discordDMs {
+				fmt.Println("  DMs: all direct messages and group DMs")
+			}
+			if len(discordChannels) > 0 {
+				fmt.Printf("  Channels: %s\n", strings.Join(discordChannels, ", "))
+			}
+			fmt.Println("\nRun 'mnemonic sync discord' to import messages")
+		},
+	}
+	connectDiscordCmd.Flags().StringVar(&discordTokenEnv, "token-env", "", "Environment variable holding the token (default: DISCORD_TOKEN)")
+	connectDiscordCmd.Flags().BoolVar(&discordBot, "bot", false, "The token is a bot token")
+	connectDiscordCmd.Flags().BoolVar(&discordDMs, "dms", false, "Sync direct messages and group DMs")
+	connectDiscordCmd.Flags().StringArrayVar(&discordChannels, "channel", nil, "Guild channel ID to sync (repeatable)")
+	connectCmd.AddCommand(connectDiscordCmd)
+
+	// connect calendar
+	var calendarName, calendarTimezone string
+	connectCalendarCmd := &cobra.Command{
+		Use:   "calendar [ics-file-or-url]",
+		Short: "Configure a calendar adapter (Google Calendar via gogcli, or ICS)",
+		Long: `Sync calendar events, with their organizer and attendees as participants.
+
+With --account, events come from Google Calendar through gogcli. With an
+argument, they come from an .ics file, a directory of .ics files, or an
+iCal feed URL such as Google Calendar's "Secret address in iCal format";
+unchanged files and feeds are skipped on sync.
+
+Examples:
+  mnemonic connect calendar --account tyler@example.com
+  mnemonic connect calendar ~/Calendars --name calendar-ics
+  mnemonic connect calendar "https://calendar.google.com/calendar/ical/.../basic.ics"`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool   `json:"ok"`
+				Message string `json:"message,omitempty"`
+			}
+
+			if len(args) == 1 {
+				fail := func(msg string) {
+					if jsonOutput {
+						printJSON(Result{OK: false, Message: msg})
+					} else {
+						fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+					}
+					os.Exit(1)
+				}
+
+				path := args[0]
+				if !strings.Contains(path, "://") {
+					abs, err := filepath.Abs(path)
+					if err != nil {
+						fail(fmt.Sprintf("Invalid path: %v", err))
+					}
+					path = abs
+				}
+				if _, err := adapters.NewICSAdapter(calendarName, adapters.ICSAdapterOptions{Path: path, Timezone: calendarTimezone}); err != nil {
+					fail(err.Error())
+				}
+
+				cfg, err := config.Load()
+				if err != nil {
+					fail(fmt.Sprintf("Failed to load config: %v", err))
+				}
+				if existing, ok := cfg.Adapters[calendarName]; ok && existing.Type != "ics" {
+					fail(fmt.Sprintf("Adapter '%s' is already configured as %s; pick another --name", calendarName, existing.Type))
+				}
+				options := map[string]interface{}{"path": path}
+				if calendarTimezone != "" {
+					options["timezone"] = calendarTimezone
+				}
+				cfg.Adapters[calendarName] = config.AdapterConfig{
+					Type:    "ics",
+					Enabled: true,
+					Options: options,
+				}
+				if err := cfg.Save(); err != nil {
+					fail(fmt.Sprintf("Failed to save config: %v", err))
+				}
+
+				if jsonOutput {
+					printJSON(Result{OK: true, Message: "Calendar adapter configured successfully"})
+					return
+				}
+				fmt.Println("✓ Calendar adapter configured")
+				fmt.Printf("  Adapter: %s\n", calendarName)
+				if strings.Contains(path, "://") {
+					fmt.Println("  Source: iCal feed")
+				} else {
+					fmt.Printf("  Source: %s\n", path)
+				}
+				fmt.Printf("\nRun 'mnemonic sync %s' to sync calendar events\n", calendarName)
+				return
+			}
+
+			account, _ := cmd.Flags().GetString("account")
+			if account == "" {
+				result := Result{OK: false, Message: "Pass --account for Google Calendar (e.g., --account user@gmail.com), or an .ics file or URL"}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+			account = strings.TrimSpace(strings.ToLower(account))
+			adapterName := fmt.Sprintf("calendar-%s", account)
+
+			cfg, err := config.Load()
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to load config: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			cfg.Adapters[adapterName] = config.AdapterConfig{
+				Type:    "gogcli_calendar",
+				Enabled: true,
+				Options: map[string]interface{}{
+					"account": account,
+				},
+			}
+			if err := cfg.Save(); err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to save config: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
 			}
 
 			result := Result{OK: true, Message: "Calendar adapter configured successfully"}
@@ -747,21 +1278,96 @@ with identity resolution, semantic search, and analysis.`,
 		},
 	}
 	connectCalendarCmd.Flags().String("account", "", "Google account email address")
+	connectCalendarCmd.Flags().StringVar(&calendarName, "name", "calendar", "Adapter name for an ICS calendar")
+	connectCalendarCmd.Flags().StringVar(&calendarTimezone, "timezone", "", "Timezone for ICS times that don't name one (default: local)")
 	connectCmd.AddCommand(connectCalendarCmd)
 
 	// connect contacts
+	var contactsName string
+	var contactsMacOS bool
 	connectContactsCmd := &cobra.Command{
-		Use:   "contacts",
-		Short: "Configure Google Contacts adapter (via gogcli)",
+		Use:   "contacts [vcf-file-or-dir]",
+		Short: "Configure a contacts adapter (Google Contacts via gogcli, macOS Contacts, or vCard)",
+		Long: `Seed contacts and persons from an address book, so people are known by
+name before their messages arrive. Each card's phone numbers and emails are
+linked to one person named after the card.
+
+With --account, cards come from Google Contacts through gogcli. With
+--macos, they come from the macOS Contacts databases. With an argument,
+they come from a vCard (.vcf) file or a directory of them, such as an
+export from Contacts, Outlook or a phone.
+
+Examples:
+  mnemonic connect contacts --account tyler@example.com
+  mnemonic connect contacts --macos
+  mnemonic connect contacts ~/Downloads/contacts.vcf --name phone-contacts`,
+		Args: cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			type Result struct {
 				OK      bool   `json:"ok"`
 				Message string `json:"message,omitempty"`
 			}
 
+			if len(args) == 1 || contactsMacOS {
+				fail := func(msg string) {
+					if jsonOutput {
+						printJSON(Result{OK: false, Message: msg})
+					} else {
+						fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+					}
+					os.Exit(1)
+				}
+				if len(args) == 1 && contactsMacOS {
+					fail("Pass a vCard path or --macos, not both")
+				}
+
+				path := ""
+				if len(args) == 1 {
+					abs, err := filepath.Abs(args[0])
+					if err != nil {
+						fail(fmt.Sprintf("Invalid path: %v", err))
+					}
+					path = abs
+				}
+				if _, err := adapters.NewAddressBookAdapter(contactsName, adapters.AddressBookAdapterOptions{Path: path}); err != nil {
+					fail(err.Error())
+				}
+
+				cfg, err := config.Load()
+				if err != nil {
+					fail(fmt.Sprintf("Failed to load config: %v", err))
+				}
+				if existing, ok := cfg.Adapters[contactsName]; ok && existing.Type != "addressbook" {
+					fail(fmt.Sprintf("Adapter '%s' is already configured as %s; pick another --name", contactsName, existing.Type))
+				}
+				adapterCfg := config.AdapterConfig{Type: "addressbook", Enabled: true}
+				if path != "" {
+					adapterCfg.Options = map[string]interface{}{"path": path}
+				}
+				cfg.Adapters[contactsName] = adapterCfg
+				if err := cfg.Save(); err != nil {
+					fail(fmt.Sprintf("Failed to save config: %v", err))
+				}
+
+				if jsonOutput {
+					printJSON(Result{OK: true, Message: "Contacts adapter configured successfully"})
+					return
+				}
+				fmt.Println("✓ Contacts adapter configured")
+				fmt.Printf("  Adapter: %s\n", contactsName)
+				if path == "" {
+					fmt.Println("  Source: macOS Contacts")
+					fmt.Println("\nNote: the terminal needs Full Disk Access to read macOS Contacts")
+				} else {
+					fmt.Printf("  Source: %s\n", path)
+				}
+				fmt.Printf("\nRun 'mnemonic sync %s' to seed contacts and persons\n", contactsName)
+				return
+			}
+
 			account, _ := cmd.Flags().GetString("account")
 			if account == "" {
-				result := Result{OK: false, Message: "The --account flag is required (e.g., --account user@gmail.com)"}
+				result := Result{OK: false, Message: "Pass --account for Google Contacts (e.g., --account user@gmail.com), --macos, or a vCard file"}
 				if jsonOutput {
 					printJSON(result)
 				} else {
@@ -814,6 +1420,8 @@ with identity resolution, semantic search, and analysis.`,
 		},
 	}
 	connectContactsCmd.Flags().String("account", "", "Google account email address")
+	connectContactsCmd.Flags().BoolVar(&contactsMacOS, "macos", false, "Read the macOS Contacts databases")
+	connectContactsCmd.Flags().StringVar(&contactsName, "name", "address-book", "Adapter name for macOS Contacts or vCard files")
 	connectCmd.AddCommand(connectContactsCmd)
 
 	// connect google (gmail + calendar + contacts)
@@ -906,9 +1514,9 @@ with identity resolution, semantic search, and analysis.`,
 				Message string `json:"message,omitempty"`
 			}
 
-			home, err := os.UserHomeDir()
+			aixDBPath, err := config.AixDBPath()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to get home directory: %v", err)}
+				result := Result{OK: false, Message: err.Error()}
 				if jsonOutput {
 					printJSON(result)
 				} else {
@@ -917,7 +1525,6 @@ with identity resolution, semantic search, and analysis.`,
 				os.Exit(1)
 			}
 
-			aixDBPath := filepath.Join(home, "Library", "Application Support", "aix", "aix.db")
 			if _, err := os.Stat(aixDBPath); os.IsNotExist(err) {
 				result := Result{
 					OK:      false,
@@ -1037,17 +1644,194 @@ with identity resolution, semantic search, and analysis.`,
 		},
 	}
 	connectCmd.AddCommand(connectXCmd)
+
+	var whatsappMe, whatsappTimezone, whatsappDateOrder string
+	connectWhatsAppCmd := &cobra.Command{
+		Use:   "whatsapp <export-dir>",
+		Short: "Configure WhatsApp adapter (chat exports)",
+		Long: `Sync WhatsApp chats exported with "Export chat" (.txt or .zip, with or
+without media) from a directory. Save new exports there and run sync;
+unchanged exports are skipped, and a later export of the same chat only
+adds the messages that are new.
+
+Example:
+  mnemonic connect whatsapp ~/Documents/WhatsApp --me "Tyler Brandt"`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool   `json:"ok"`
+				Message string `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+				}
+				os.Exit(1)
+			}
+
+			path, err := filepath.Abs(args[0])
+			if err != nil {
+				fail(fmt.Sprintf("Invalid path: %v", err))
+			}
+			if _, err := adapters.NewWhatsAppAdapter(adapters.WhatsAppAdapterOptions{
+				Path: path, Me: whatsappMe, Timezone: whatsappTimezone, DateOrder: whatsappDateOrder,
+			}); err != nil {
+				fail(err.Error())
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				fail(fmt.Sprintf("Failed to load config: %v", err))
+			}
+			options := map[string]interface{}{"path": path}
+			if whatsappMe != "" {
+				options["me"] = whatsappMe
+			}
+			if whatsappTimezone != "" {
+				options["timezone"] = whatsappTimezone
+			}
+			if whatsappDateOrder != "" {
+				options["date_order"] = whatsappDateOrder
+			}
+			cfg.Adapters["whatsapp"] = config.AdapterConfig{
+				Type:    "whatsapp_export",
+				Enabled: true,
+				Options: options,
+			}
+			if err := cfg.Save(); err != nil {
+				fail(fmt.Sprintf("Failed to save config: %v", err))
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Message: "WhatsApp adapter configured successfully"})
+				return
+			}
+			fmt.Println("✓ WhatsApp adapter configured")
+			fmt.Printf("  Exports: %s\n", path)
+			fmt.Println("\nRun 'mnemonic sync whatsapp' to import the exports")
+		},
+	}
+	connectWhatsAppCmd.Flags().StringVar(&whatsappMe, "me", "", "Your name as it appears in the exports (default: your identity name)")
+	connectWhatsAppCmd.Flags().StringVar(&whatsappTimezone, "timezone", "", "Timezone of the phone that exported the chats (default: local)")
+	connectWhatsAppCmd.Flags().StringVar(&whatsappDateOrder, "date-order", "", "dmy or mdy, for exports whose dates are ambiguous (default: guessed)")
+	connectCmd.AddCommand(connectWhatsAppCmd)
+
+	var genericFormat, genericChannel, genericTimestampFormat, genericTimezone string
+	var genericMap, genericMe []string
+	connectGenericCmd := &cobra.Command{
+		Use:   "generic <name> <file-or-dir>",
+		Short: "Configure a generic JSONL/CSV adapter",
+		Long: `Sync communication data from JSONL or CSV files under an adapter name of
+your choice. Map event fields (id, timestamp, sender, sender_name,
+recipients, content, thread, thread_name, direction, reply_to) to your
+fields or columns with --map; unmapped fields are read from a field of
+the same name. Only timestamp and content are required. Changed files are
+read again on sync; unchanged ones are skipped.
+
+Example:
+  mnemonic connect generic slack ~/exports/slack.jsonl \
+    --map sender=user.email --map sender_name=user.name --map content=text \
+    --map thread=channel_id --map timestamp=ts --me tyler@example.com`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool   `json:"ok"`
+				Message string `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+				}
+				os.Exit(1)
+			}
+
+			name := args[0]
+			path, err := filepath.Abs(args[1])
+			if err != nil {
+				fail(fmt.Sprintf("Invalid path: %v", err))
+			}
+			options := map[string]interface{}{"path": path}
+			mapping := map[string]interface{}{}
+			for _, m := range genericMap {
+				field, source, ok := strings.Cut(m, "=")
+				if !ok {
+					fail(fmt.Sprintf("Invalid --map %q (use field=source)", m))
+				}
+				mapping[field] = source
+			}
+			for key, value := range map[string]string{
+				"format": genericFormat, "channel": genericChannel,
+				"timestamp_format": genericTimestampFormat, "timezone": genericTimezone,
+			} {
+				if value != "" {
+					options[key] = value
+				}
+			}
+			if len(mapping) > 0 {
+				options["mapping"] = mapping
+			}
+			if len(genericMe) > 0 {
+				options["me"] = strings.Join(genericMe, ";")
+			}
+			opts, err := adapters.GenericOptionsFromConfig(options)
+			if err != nil {
+				fail(err.Error())
+			}
+			if _, err := adapters.NewGenericAdapter(name, opts); err != nil {
+				fail(err.Error())
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				fail(fmt.Sprintf("Failed to load config: %v", err))
+			}
+			if existing, ok := cfg.Adapters[name]; ok && existing.Type != "generic" {
+				fail(fmt.Sprintf("Adapter %s already exists (type %s)", name, existing.Type))
+			}
+			cfg.Adapters[name] = config.AdapterConfig{
+				Type:    "generic",
+				Enabled: true,
+				Options: options,
+			}
+			if err := cfg.Save(); err != nil {
+				fail(fmt.Sprintf("Failed to save config: %v", err))
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Message: fmt.Sprintf("Generic adapter %s configured successfully", name)})
+				return
+			}
+			fmt.Printf("✓ Generic adapter %s configured\n", name)
+			fmt.Printf("  Data: %s\n", path)
+			fmt.Printf("\nRun 'mnemonic sync %s' to import it\n", name)
+		},
+	}
+	connectGenericCmd.Flags().StringVar(&genericFormat, "format", "", "jsonl or csv (default: from the file extension)")
+	connectGenericCmd.Flags().StringVar(&genericChannel, "channel", "", "Channel of the events (default: the adapter name)")
+	connectGenericCmd.Flags().StringArrayVar(&genericMap, "map", nil, "Map an event field to your field or column, as field=source (repeatable)")
+	connectGenericCmd.Flags().StringVar(&genericTimestampFormat, "timestamp-format", "", "unix, unix_ms, or a Go time layout (default: guessed)")
+	connectGenericCmd.Flags().StringVar(&genericTimezone, "timezone", "", "Timezone of timestamps without one (default: local)")
+	connectGenericCmd.Flags().StringArrayVar(&genericMe, "me", nil, "Your sender identifier; its events are sent (repeatable)")
+	connectCmd.AddCommand(connectGenericCmd)
 	rootCmd.AddCommand(connectCmd)
 
 	// sync command
 	syncCmd := &cobra.Command{
-		Use:   "sync [adapter]",
+		Use:   "sync [adapter...]",
 		Short: "Sync communications from adapters",
-		Long:  "Synchronize communications from all enabled adapters or a specific adapter",
-		Args:  cobra.MaximumNArgs(1),
+		Long: `Synchronize communications from all enabled adapters or the named ones.
+
+Adapters sync concurrently, --concurrency at a time; one failing doesn't stop
+the others. Use --concurrency 1 to sync one after another.`,
+		Args: cobra.ArbitraryArgs,
 		Example: strings.TrimSpace(`
 mnemonic sync
 mnemonic sync imessage
+mnemonic sync imessage gmail --concurrency 2
 mnemonic sync --adapter gmail --full
 mnemonic sync --background
 `),
@@ -1059,14 +1843,15 @@ mnemonic sync --background
 				Mode     string               `json:"mode,omitempty"`
 			}
 
-			adapterFlag, _ := cmd.Flags().GetString("adapter")
+			adapterFlag, _ := cmd.Flags().GetStringSlice("adapter")
 			full, _ := cmd.Flags().GetBool("full")
 			background, _ := cmd.Flags().GetBool("background")
+			concurrency, _ := cmd.Flags().GetInt("concurrency")
 
-			if adapterFlag != "" && len(args) > 0 {
+			if len(adapterFlag) > 0 && len(args) > 0 {
 				result := Result{
 					OK:      false,
-					Message: "Provide adapter as a positional arg or via --adapter, not both",
+					Message: "Provide adapters as positional args or via --adapter, not both",
 				}
 				if jsonOutput {
 					printJSON(result)
@@ -1075,10 +1860,13 @@ mnemonic sync --background
 				}
 				os.Exit(1)
 			}
+			if concurrency < 1 {
+				exitWithError(fmt.Errorf("--concurrency must be at least 1"))
+			}
 
-			adapterName := adapterFlag
-			if adapterName == "" && len(args) > 0 {
-				adapterName = args[0]
+			adapterNames := adapterFlag
+			if len(adapterNames) == 0 {
+				adapterNames = args
 			}
 
 			// Background mode: re-exec without --background and return immediately.
@@ -1165,29 +1953,30 @@ mnemonic sync --background
 			// Open database
 			database, err := db.Open()
 			if err != nil {
-				result := Result{
-					OK:      false,
-					Message: fmt.Sprintf("Failed to open database: %v", err),
-				}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
+			var lockNames []string
+			for _, name := range adapterNames {
+				lockNames = append(lockNames, "sync:"+name)
+			}
+			if len(adapterNames) == 0 {
+				for name, a := range cfg.Adapters {
+					if a.Enabled {
+						lockNames = append(lockNames, "sync:"+name)
+					}
+				}
+			}
+			lock := acquireInstanceLocks(cmd, database, lockNames)
+			defer lock.Release()
+
 			// Create context
 			ctx := cmd.Context()
 
 			// Sync adapters
-			var syncResult sync.SyncResult
-			if adapterName != "" {
-				syncResult = sync.SyncOne(ctx, database, cfg, adapterName, full)
-			} else {
-				syncResult = sync.SyncAll(ctx, database, cfg, full)
-			}
+			started := time.Now()
+			syncResult := sync.SyncAdapters(ctx, database, cfg, adapterNames, sync.SyncOptions{Full: full, Concurrency: concurrency})
 
 			result := Result{
 				OK:       syncResult.OK,
@@ -1196,8 +1985,20 @@ mnemonic sync --background
 				Mode:     "foreground",
 			}
 
+			// Failed syncs exit with the first failed adapter's error class
+			exitCode := errs.ExitError
+			for _, adapterResult := range syncResult.Adapters {
+				if !adapterResult.Success {
+					exitCode = errs.ExitCodeFor(adapterResult.ErrorCode)
+					break
+				}
+			}
+
 			if jsonOutput {
 				printJSON(result)
+				if !syncResult.OK {
+					os.Exit(exitCode)
+				}
 			} else {
 				if !syncResult.OK && syncResult.Message != "" {
 					fmt.Fprintf(os.Stderr, "Error: %s\n", syncResult.Message)
@@ -1238,17 +2039,28 @@ mnemonic sync --background
 						fmt.Printf("  Error: %s\n", adapterResult.Error)
 					}
 				}
+				if len(syncResult.Adapters) > 1 {
+					failed := 0
+					for _, adapterResult := range syncResult.Adapters {
+						if !adapterResult.Success {
+							failed++
+						}
+					}
+					fmt.Printf("\n%d adapters synced, %d failed (%s)\n", len(syncResult.Adapters)-failed, failed, time.Since(started).Round(time.Millisecond))
+				}
 
 				// If any adapter failed, exit with error code
 				if !syncResult.OK {
-					os.Exit(1)
+					os.Exit(exitCode)
 				}
 			}
 		},
 	}
-	syncCmd.Flags().String("adapter", "", "Sync specific adapter (e.g., imessage, gmail)")
+	syncCmd.Flags().StringSlice("adapter", nil, "Sync specific adapters (e.g., imessage, gmail; repeatable)")
+	syncCmd.Flags().Int("concurrency", 4, "Adapters to sync at once")
 	syncCmd.Flags().Bool("full", false, "Force full re-sync instead of incremental")
 	syncCmd.Flags().Bool("background", false, "Run sync in background (writes logs to mnemonic-sync.log)")
+	syncCmd.Flags().Bool("force", false, "Take over adapter locks held by another process")
 
 	// sync status subcommand
 	syncStatusCmd := &cobra.Command{
@@ -1263,13 +2075,7 @@ mnemonic sync --background
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -1404,13 +2210,7 @@ mnemonic sync --background
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -1469,6 +2269,169 @@ mnemonic sync --background
 	importMBoxCmd.Flags().Bool("dry-run", false, "Parse and count but do not write to database")
 
 	importCmd.AddCommand(importMBoxCmd)
+
+	var importWhatsAppMe, importWhatsAppTimezone, importWhatsAppDateOrder string
+	importWhatsAppCmd := &cobra.Command{
+		Use:   "whatsapp <export>",
+		Short: "Import WhatsApp chat exports (.txt, .zip or a directory of them)",
+		Long: `Import chats exported with WhatsApp's "Export chat". Each chat becomes a
+thread, each message an event from its sender, and media (or the
+placeholder for media left out of the export) an attachment. Importing a
+later export of the same chat only adds the messages that are new.
+
+Dates like 03/04/24 are read day-first or month-first from the rest of
+the export; pass --date-order when a short export can't tell.
+
+Examples:
+  mnemonic import whatsapp "WhatsApp Chat - Trip.zip"
+  mnemonic import whatsapp ~/Downloads/exports --me "Tyler Brandt" --timezone Europe/Lisbon`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                 `json:"ok"`
+				Result  *adapters.SyncResult `json:"result,omitempty"`
+				Message string               `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+				}
+				os.Exit(1)
+			}
+
+			adapter, err := adapters.NewWhatsAppAdapter(adapters.WhatsAppAdapterOptions{
+				Path:      args[0],
+				Me:        importWhatsAppMe,
+				Timezone:  importWhatsAppTimezone,
+				DateOrder: importWhatsAppDateOrder,
+			})
+			if err != nil {
+				fail(err.Error())
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			// Explicit imports always re-read the files
+			res, err := adapter.Sync(context.Background(), database, true)
+			if err != nil {
+				fail(fmt.Sprintf("Import failed: %v", err))
+			}
+			if jsonOutput {
+				printJSON(Result{OK: true, Result: &res})
+				return
+			}
+			fmt.Println("✓ WhatsApp import completed")
+			fmt.Printf("  Exports: %s\n", res.Perf["exports"])
+			fmt.Printf("  Events created: %d\n", res.EventsCreated)
+			fmt.Printf("  Events updated: %d\n", res.EventsUpdated)
+			fmt.Printf("  Threads created: %d\n", res.ThreadsCreated)
+			fmt.Printf("  Attachments created: %d\n", res.AttachmentsCreated)
+			fmt.Printf("  Persons created: %d\n", res.PersonsCreated)
+			fmt.Printf("  Duration: %s\n", res.Duration)
+		},
+	}
+	importWhatsAppCmd.Flags().StringVar(&importWhatsAppMe, "me", "", "Your name as it appears in the export (default: your identity name)")
+	importWhatsAppCmd.Flags().StringVar(&importWhatsAppTimezone, "timezone", "", "Timezone of the phone that exported the chat (default: local)")
+	importWhatsAppCmd.Flags().StringVar(&importWhatsAppDateOrder, "date-order", "", "dmy or mdy, for exports whose dates are ambiguous (default: guessed)")
+	importCmd.AddCommand(importWhatsAppCmd)
+
+	var importGenericFormat, importGenericChannel, importGenericTimestampFormat, importGenericTimezone string
+	var importGenericMap, importGenericMe []string
+	importGenericCmd := &cobra.Command{
+		Use:   "generic <file-or-dir|->",
+		Short: "Import events from JSONL or CSV files (or stdin)",
+		Long: `Import communication data from JSONL or CSV, with "-" reading stdin.
+Events are stored on --channel, which also names their source. Map event
+fields (id, timestamp, sender, sender_name, recipients, content, thread,
+thread_name, direction, reply_to) to your fields or columns with --map;
+unmapped fields are read from a field of the same name. Only timestamp
+and content are required. Rows without an id get one derived from their
+fields, so importing the same data again adds nothing.
+
+Examples:
+  mnemonic import generic signal.csv --channel signal --map sender=from --map content=body --map timestamp=sent_at
+  my-exporter | mnemonic import generic - --format jsonl --channel matrix --me @tyler:matrix.org`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                 `json:"ok"`
+				Result  *adapters.SyncResult `json:"result,omitempty"`
+				Message string               `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+				}
+				os.Exit(1)
+			}
+
+			if importGenericChannel == "" {
+				fail("--channel is required")
+			}
+			mapping := map[string]string{}
+			for _, m := range importGenericMap {
+				field, source, ok := strings.Cut(m, "=")
+				if !ok {
+					fail(fmt.Sprintf("Invalid --map %q (use field=source)", m))
+				}
+				mapping[field] = source
+			}
+			parsed, err := adapters.ParseGenericMapping(mapping)
+			if err != nil {
+				fail(err.Error())
+			}
+			adapter, err := adapters.NewGenericAdapter(importGenericChannel, adapters.GenericAdapterOptions{
+				Path:            args[0],
+				Input:           os.Stdin,
+				Format:          importGenericFormat,
+				Mapping:         parsed,
+				TimestampFormat: importGenericTimestampFormat,
+				Timezone:        importGenericTimezone,
+				Me:              importGenericMe,
+			})
+			if err != nil {
+				fail(err.Error())
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			// Explicit imports always re-read the files
+			res, err := adapter.Sync(context.Background(), database, true)
+			if err != nil {
+				fail(fmt.Sprintf("Import failed: %v", err))
+			}
+			if jsonOutput {
+				printJSON(Result{OK: true, Result: &res})
+				return
+			}
+			fmt.Println("✓ Import completed")
+			fmt.Printf("  Rows: %s (%s without content skipped)\n", res.Perf["rows"], res.Perf["rows.skipped"])
+			fmt.Printf("  Events created: %d\n", res.EventsCreated)
+			fmt.Printf("  Events updated: %d\n", res.EventsUpdated)
+			fmt.Printf("  Threads created: %d\n", res.ThreadsCreated)
+			fmt.Printf("  Persons created: %d\n", res.PersonsCreated)
+			fmt.Printf("  Duration: %s\n", res.Duration)
+		},
+	}
+	importGenericCmd.Flags().StringVar(&importGenericFormat, "format", "", "jsonl or csv (default: from the file extension; required for stdin)")
+	importGenericCmd.Flags().StringVar(&importGenericChannel, "channel", "", "Channel of the events (required)")
+	importGenericCmd.Flags().StringArrayVar(&importGenericMap, "map", nil, "Map an event field to your field or column, as field=source (repeatable)")
+	importGenericCmd.Flags().StringVar(&importGenericTimestampFormat, "timestamp-format", "", "unix, unix_ms, or a Go time layout (default: guessed)")
+	importGenericCmd.Flags().StringVar(&importGenericTimezone, "timezone", "", "Timezone of timestamps without one (default: local)")
+	importGenericCmd.Flags().StringArrayVar(&importGenericMe, "me", nil, "Your sender identifier; its events are sent (repeatable)")
+	importCmd.AddCommand(importGenericCmd)
 	rootCmd.AddCommand(importCmd)
 
 	// watch command
@@ -1481,6 +2444,11 @@ mnemonic sync --background
 	watchRunCmd := &cobra.Command{
 		Use:   "run",
 		Short: "Run all live-enabled adapter watchers",
+		Long: `Run all live-enabled adapter watchers.
+
+When maintenance.enabled is set in config, also runs the maintenance
+scheduler (embedding backfill, merge candidates, summaries, metrics,
+backups). See 'mnemonic maintenance status'.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			heartbeatSec, _ := cmd.Flags().GetInt("heartbeat-seconds")
 			restartSec, _ := cmd.Flags().GetInt("restart-seconds")
@@ -1493,8 +2461,7 @@ mnemonic sync --background
 
 			database, err := db.Open()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: failed to open database: %v\n", err)
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -1517,6 +2484,49 @@ mnemonic sync --background
 				manager.RestartBackoff = time.Duration(restartSec) * time.Second
 			}
 
+			// One daemon at a time, and no manual sync of an adapter it watches
+			lockNames := []string{"daemon"}
+			if specs, err := manager.BuildSpecs(); err == nil {
+				for _, spec := range specs {
+					for _, adapter := range spec.Adapters {
+						lockNames = append(lockNames, "sync:"+adapter)
+					}
+				}
+			}
+			lock := acquireInstanceLocks(cmd, database, lockNames)
+			defer lock.Release()
+
+			// Maintenance runs alongside the watchers when enabled
+			maintenanceRunning := false
+			if cfg.Maintenance.Enabled {
+				dataDir, err := config.GetDataDir()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: failed to get data dir: %v\n", err)
+					os.Exit(1)
+				}
+				tasks, err := maintenance.BuildTasks(database, cfg.Maintenance, os.Getenv("GEMINI_API_KEY"), dataDir)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: invalid maintenance config: %v\n", err)
+					os.Exit(1)
+				}
+				gate, err := power.NewGate(cfg.Power)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: invalid power config: %v\n", err)
+					os.Exit(1)
+				}
+				scheduler := maintenance.NewScheduler(database, tasks)
+				scheduler.Power = gate
+				go scheduler.Run(ctx)
+				maintenanceRunning = true
+				fmt.Printf("Maintenance scheduler started (%d tasks)\n", len(tasks))
+			}
+
+			if specs, err := manager.BuildSpecs(); err == nil && len(specs) == 0 && maintenanceRunning {
+				fmt.Println("No live watchers enabled; running maintenance only (Ctrl+C to stop)...")
+				<-ctx.Done()
+				return
+			}
+
 			fmt.Println("Starting live watchers (Ctrl+C to stop)...")
 			if err := manager.Run(ctx); err != nil {
 				fmt.Fprintf(os.Stderr, "watch run error: %v\n", err)
@@ -1526,6 +2536,7 @@ mnemonic sync --background
 	}
 	watchRunCmd.Flags().Int("heartbeat-seconds", 10, "Heartbeat interval for live status")
 	watchRunCmd.Flags().Int("restart-seconds", 3, "Base restart backoff seconds")
+	watchRunCmd.Flags().Bool("force", false, "Take over locks held by another daemon or sync")
 
 	// watch status: show live watcher status
 	watchStatusCmd := &cobra.Command{
@@ -1540,8 +2551,7 @@ mnemonic sync --background
 
 			database, err := db.Open()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: failed to open database: %v\n", err)
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -1594,8 +2604,7 @@ mnemonic sync --background
 
 			database, err := db.Open()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: failed to open database: %v\n", err)
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -1622,6 +2631,13 @@ mnemonic sync --background
 				return out
 			}
 
+			var lockNames []string
+			for _, name := range selectAdapters() {
+				lockNames = append(lockNames, "sync:"+name)
+			}
+			lock := acquireInstanceLocks(cmd, database, lockNames)
+			defer lock.Release()
+
 			runAdapter := func(adapterName string) {
 				mu.Lock()
 				st, ok := stateByAdapter[adapterName]
@@ -1718,6 +2734,7 @@ mnemonic sync --background
 	watchGmailCmd.Flags().String("token", "", "Shared token (Authorization Bearer or ?token=)")
 	watchGmailCmd.Flags().String("adapter", "", "Only trigger sync for this adapter (default: all gogcli adapters)")
 	watchGmailCmd.Flags().Int("debounce-seconds", 10, "Minimum seconds between sync triggers per adapter")
+	watchGmailCmd.Flags().Bool("force", false, "Take over adapter locks held by another process")
 
 	// watch aix: watch aix.db for changes and trigger incremental sync
 	watchAIXCmd := &cobra.Command{
@@ -1730,8 +2747,7 @@ mnemonic sync --background
 
 			database, err := db.Open()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: failed to open database: %v\n", err)
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -1741,6 +2757,9 @@ mnemonic sync --background
 				os.Exit(1)
 			}
 
+			lock := acquireInstanceLocks(cmd, database, []string{"sync:" + adapter.Name()})
+			defer lock.Release()
+
 			aixDBPath, err := adapters.DefaultAixDBPath()
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: failed to determine aix.db path: %v\n", err)
@@ -1842,6 +2861,7 @@ mnemonic sync --background
 	watchAIXCmd.Flags().String("source", "cursor", "AIX source (cursor, codex, ...)")
 	watchAIXCmd.Flags().Int("debounce-seconds", 2, "Minimum seconds between sync triggers")
 	watchAIXCmd.Flags().Bool("extract-metadata", true, "Extract facets from AIX metadata after sync")
+	watchAIXCmd.Flags().Bool("force", false, "Take over the adapter lock held by another process")
 
 	watchCmd.AddCommand(watchRunCmd)
 	watchCmd.AddCommand(watchStatusCmd)
@@ -1849,6 +2869,231 @@ mnemonic sync --background
 	watchCmd.AddCommand(watchAIXCmd)
 	rootCmd.AddCommand(watchCmd)
 
+	// maintenance command
+	maintenanceCmd := &cobra.Command{
+		Use:   "maintenance",
+		Short: "Inspect and run background maintenance tasks",
+		Long: `Background maintenance keeps derived data fresh: embedding backfill,
+merge candidate generation and processing, entity summaries, metrics
+snapshots, and database backups. 'watch run' schedules these when
+maintenance.enabled is set in config; schedules are per task
+(maintenance.tasks.<name>.interval / .jitter / .enabled).`,
+	}
+
+	maintenanceStatusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show maintenance task schedules and last results",
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                     `json:"ok"`
+				Enabled bool                     `json:"enabled"`
+				Tasks   []maintenance.TaskStatus `json:"tasks"`
+				Message string                   `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			scheduler, enabled, err := newMaintenanceScheduler(database)
+			var statuses []maintenance.TaskStatus
+			if err == nil {
+				statuses, err = scheduler.Status(context.Background())
+			}
+			if err != nil {
+				result := Result{OK: false, Message: err.Error()}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Enabled: enabled, Tasks: statuses})
+				return
+			}
+			if !enabled {
+				fmt.Println("Scheduler disabled (set maintenance.enabled in config to run from 'watch run')")
+			}
+			for _, st := range statuses {
+				next, last := "not scheduled", "never run"
+				if st.NextRunAt != nil {
+					next = "next " + st.NextRunAt.Format("2006-01-02 15:04")
+				}
+				if st.RunningSince != nil {
+					next = "running since " + st.RunningSince.Format("2006-01-02 15:04")
+				}
+				if st.LastFinishedAt != nil {
+					last = fmt.Sprintf("%s %s: %s", st.LastFinishedAt.Format("2006-01-02 15:04"), st.LastStatus, st.LastMessage)
+				}
+				fmt.Printf("  %-17s every %-8s %-26s %s\n", st.Task, st.Interval, next, last)
+			}
+		},
+	}
+
+	maintenanceRunCmd := &cobra.Command{
+		Use:   "run [task...]",
+		Short: "Run maintenance tasks now",
+		Long: `Run the named maintenance tasks now (all tasks if none are named),
+regardless of schedule. A task already running elsewhere is skipped.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                  `json:"ok"`
+				Runs    []maintenance.TaskRun `json:"runs"`
+				Message string                `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			scheduler, _, err := newMaintenanceScheduler(database)
+			if err != nil {
+				result := Result{OK: false, Message: err.Error()}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+			names := args
+			if len(names) == 0 {
+				for _, task := range scheduler.Tasks {
+					names = append(names, task.Name)
+				}
+			}
+
+			ctx := context.Background()
+			result := Result{OK: true, Runs: []maintenance.TaskRun{}}
+			for _, name := range names {
+				run, err := scheduler.RunNow(ctx, name)
+				if err != nil {
+					run = &maintenance.TaskRun{Task: name, Error: err.Error()}
+				}
+				if run.Error != "" {
+					result.OK = false
+				}
+				result.Runs = append(result.Runs, *run)
+				if !jsonOutput {
+					switch {
+					case run.Skipped:
+						fmt.Printf("  %-17s skipped (already running)\n", name)
+					case run.Error != "":
+						fmt.Printf("  %-17s failed: %s\n", name, run.Error)
+					default:
+						fmt.Printf("  %-17s %s (%s)\n", name, run.Message, run.Duration.Round(time.Millisecond))
+					}
+				}
+			}
+			if jsonOutput {
+				printJSON(result)
+			}
+			if !result.OK {
+				os.Exit(1)
+			}
+		},
+	}
+
+	maintenanceCmd.AddCommand(maintenanceStatusCmd)
+	maintenanceCmd.AddCommand(maintenanceRunCmd)
+	rootCmd.AddCommand(maintenanceCmd)
+
+	// power command
+	powerCmd := &cobra.Command{
+		Use:   "power",
+		Short: "Show how background jobs respond to battery and idle state",
+	}
+
+	powerStatusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show power state and what each background job type would do",
+		Long: `Show whether the machine is on battery and how long it has been idle,
+and what each background job type (compute analysis/embedding jobs and
+maintenance tasks) would do now: run, throttle, or pause.
+
+Configure under power: in config (enabled, idle_after, throttle_delay,
+default, and per-type jobs.<type>.on_battery / when_active).`,
+		Run: func(cmd *cobra.Command, args []string) {
+			type JobDecision struct {
+				JobType string       `json:"job_type"`
+				Policy  power.Policy `json:"policy"`
+				Action  power.Action `json:"action"`
+				Reason  string       `json:"reason,omitempty"`
+			}
+			type Result struct {
+				OK      bool          `json:"ok"`
+				Enabled bool          `json:"enabled"`
+				State   power.State   `json:"state"`
+				Jobs    []JobDecision `json:"jobs,omitempty"`
+				Message string        `json:"message,omitempty"`
+			}
+
+			cfg, err := config.Load()
+			var gate *power.Gate
+			if err == nil {
+				gate, err = power.NewGate(cfg.Power)
+			}
+			if err != nil {
+				result := Result{OK: false, Message: err.Error()}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			result := Result{OK: true, Enabled: gate != nil, State: power.Probe()}
+			if gate != nil {
+				jobTypes := []string{compute.JobTypeAnalysis, compute.JobTypeEmbedding,
+					maintenance.TaskEmbeddings, maintenance.TaskAliasMining, maintenance.TaskMergeCandidates, maintenance.TaskSummaries,
+					maintenance.TaskEntityTypes, maintenance.TaskCoMentions, maintenance.TaskStaleFacts, maintenance.TaskCurrentFacts, maintenance.TaskMetrics, maintenance.TaskBackup}
+				for _, jobType := range jobTypes {
+					action, reason := gate.Decide(jobType)
+					result.Jobs = append(result.Jobs, JobDecision{JobType: jobType, Policy: gate.Policy(jobType), Action: action, Reason: reason})
+				}
+			}
+
+			if jsonOutput {
+				printJSON(result)
+				return
+			}
+			source := "AC power"
+			if result.State.OnBattery {
+				source = "battery"
+			}
+			if result.State.BatteryPercent >= 0 {
+				source += fmt.Sprintf(" (%d%%)", result.State.BatteryPercent)
+			}
+			idle := "unknown"
+			if result.State.IdleKnown {
+				idle = result.State.IdleFor.Round(time.Second).String()
+			}
+			fmt.Printf("Power: %s, idle: %s\n", source, idle)
+			if gate == nil {
+				fmt.Println("Power policy disabled (set power.enabled in config); background jobs always run")
+				return
+			}
+			for _, job := range result.Jobs {
+				line := fmt.Sprintf("  %-17s %-8s (battery: %s, active: %s)", job.JobType, job.Action, job.Policy.OnBattery, job.Policy.WhenActive)
+				if job.Reason != "" {
+					line += " - " + job.Reason
+				}
+				fmt.Println(line)
+			}
+		},
+	}
+
+	powerCmd.AddCommand(powerStatusCmd)
+	rootCmd.AddCommand(powerCmd)
+
 	// bus command
 	busCmd := &cobra.Command{
 		Use:   "bus",
@@ -1864,8 +3109,7 @@ mnemonic sync --background
 
 			database, err := db.Open()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: failed to open database: %v\n", err)
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -1912,8 +3156,7 @@ mnemonic sync --background
 
 			database, err := db.Open()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: failed to open database: %v\n", err)
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -1995,16 +3238,7 @@ mnemonic sync --background
 			// Open database
 			database, err := db.Open()
 			if err != nil {
-				result := Result{
-					OK:      false,
-					Message: fmt.Sprintf("Failed to open database: %v", err),
-				}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -2127,16 +3361,7 @@ mnemonic sync --background
 			// Open database
 			database, err := db.Open()
 			if err != nil {
-				result := Result{
-					OK:      false,
-					Message: fmt.Sprintf("Failed to open database: %v", err),
-				}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -2256,16 +3481,7 @@ mnemonic sync --background
 			// Open database
 			database, err := db.Open()
 			if err != nil {
-				result := Result{
-					OK:      false,
-					Message: fmt.Sprintf("Failed to open database: %v", err),
-				}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -2407,13 +3623,7 @@ mnemonic sync --background
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -2477,13 +3687,7 @@ mnemonic sync --background
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -2547,13 +3751,7 @@ mnemonic sync --background
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -2602,13 +3800,7 @@ mnemonic sync --background
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -2669,18 +3861,13 @@ The algorithm runs in three phases:
 				SoftAccumulations       int    `json:"soft_accumulations"`
 				MergeSuggestionsCreated int    `json:"merge_suggestions_created"`
 				AutoMergesExecuted      int    `json:"auto_merges_executed,omitempty"`
+				FactsAttributed         int    `json:"facts_attributed,omitempty"`
 				Message                 string `json:"message,omitempty"`
 			}
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -2742,6 +3929,7 @@ The algorithm runs in three phases:
 				SoftAccumulations:       res.SoftAccumulations,
 				MergeSuggestionsCreated: res.MergeSuggestionsCreated,
 				AutoMergesExecuted:      res.AutoMergesExecuted,
+				FactsAttributed:         res.FactsAttributed,
 			}
 
 			if jsonOutput {
@@ -2752,6 +3940,7 @@ The algorithm runs in three phases:
 				fmt.Printf("  Compound matches: %d\n", res.CompoundMatches)
 				fmt.Printf("  Soft accumulations: %d\n", res.SoftAccumulations)
 				fmt.Printf("  Merge suggestions created: %d\n", res.MergeSuggestionsCreated)
+				fmt.Printf("  Unattributed facts attributed: %d\n", res.FactsAttributed)
 				if resolveAutoMerge {
 					fmt.Printf("  Auto-merges executed: %d\n", res.AutoMergesExecuted)
 				} else {
@@ -2793,13 +3982,7 @@ The algorithm runs in three phases:
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -2889,13 +4072,7 @@ The algorithm runs in three phases:
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -2927,8 +4104,7 @@ The algorithm runs in three phases:
 		Run: func(cmd *cobra.Command, args []string) {
 			database, err := db.Open()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -2971,79 +4147,244 @@ The algorithm runs in three phases:
 	identifyCmd.AddCommand(identifyStatusCmd)
 	rootCmd.AddCommand(identifyCmd)
 
-	// events command
-	eventsCmd := &cobra.Command{
-		Use:   "events",
-		Short: "Query communication events",
-		Long:  "Query and filter communication events across all channels",
+	// repair command - backfill data imported before newer linking logic
+	repairCmd := &cobra.Command{
+		Use:   "repair",
+		Short: "Repair historical data",
+	}
+
+	var repairBatchSize int
+	var repairLimit int
+	var repairChannel string
+	var repairDryRun bool
+	repairParticipantsCmd := &cobra.Command{
+		Use:   "participants",
+		Short: "Backfill event participants and person links for historical events",
+		Long: `Re-resolve senders and recipients for events imported before person linking.
+
+Participant contacts with no person are linked via legacy identities (or get a
+person from their display name), and sent/received events with no participants
+are backfilled in batches from message metadata and the rest of their thread.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			type ParticipantInfo struct {
-				Name string `json:"name"`
-				Role string `json:"role"`
+			type Result struct {
+				OK      bool                             `json:"ok"`
+				Stats   *identify.ParticipantRepairStats `json:"stats,omitempty"`
+				DryRun  bool                             `json:"dry_run,omitempty"`
+				Message string                           `json:"message,omitempty"`
 			}
 
-			type EventInfo struct {
-				ID           string            `json:"id"`
-				Timestamp    int64             `json:"timestamp"`
-				TimestampStr string            `json:"timestamp_str"`
-				Channel      string            `json:"channel"`
-				ContentTypes string            `json:"content_types"`
-				Content      string            `json:"content"`
-				Direction    string            `json:"direction"`
-				ThreadID     *string           `json:"thread_id,omitempty"`
-				ReplyTo      *string           `json:"reply_to,omitempty"`
-				Participants []ParticipantInfo `json:"participants"`
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			stats, err := identify.RepairParticipants(database, identify.ParticipantRepairOptions{
+				BatchSize: repairBatchSize,
+				Limit:     repairLimit,
+				Channel:   repairChannel,
+				DryRun:    repairDryRun,
+			})
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to repair participants: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
 			}
 
+			result := Result{OK: true, Stats: stats, DryRun: repairDryRun}
+			if jsonOutput {
+				printJSON(result)
+			} else {
+				if repairDryRun {
+					fmt.Println("Dry run - no changes made:")
+				} else {
+					fmt.Println("✓ Participant repair complete:")
+				}
+				fmt.Printf("  Orphan contacts: %d\n", stats.OrphanContacts)
+				fmt.Printf("  Contacts linked to existing persons: %d\n", stats.ContactsLinked)
+				fmt.Printf("  Persons created: %d\n", stats.PersonsCreated)
+				fmt.Printf("  Events missing participants: %d\n", stats.EventsScanned)
+				fmt.Printf("  Events repaired: %d\n", stats.EventsRepaired)
+				fmt.Printf("  Participants added: %d\n", stats.ParticipantsAdded)
+				fmt.Printf("  Events unresolved: %d\n", stats.EventsUnresolved)
+			}
+		},
+	}
+	repairParticipantsCmd.Flags().IntVar(&repairBatchSize, "batch-size", identify.DefaultRepairBatchSize, "Events per transaction")
+	repairParticipantsCmd.Flags().IntVar(&repairLimit, "limit", 0, "Max events to repair (0 = all)")
+	repairParticipantsCmd.Flags().StringVar(&repairChannel, "channel", "", "Only repair events on this channel")
+	repairParticipantsCmd.Flags().BoolVar(&repairDryRun, "dry-run", false, "Show what would be repaired without making changes")
+
+	var directionsBatchSize int
+	var directionsLimit int
+	var directionsChannel string
+	var directionsObserved bool
+	var directionsDryRun bool
+	repairDirectionsCmd := &cobra.Command{
+		Use:   "directions",
+		Short: "Correct sent/received direction of messages from sender identity",
+		Long: `Infer message direction by comparing each text message's sender against
+your own contacts: messages from you become "sent", messages from anyone else
+become "received". Events with both you and someone else as sender are left
+alone, as are calendar, membership and tool events.
+
+Events marked "observed" (timeline posts, agent transcripts) are skipped. Pass
+--observed with --channel to repair a channel whose adapter marked its
+messages "observed" by mistake.
+
+Re-run 'mnemonic chunk run' afterwards so turn-pair episodes pick up the fix.`,
+		Run: func(cmd *cobra.Command, args []string) {
 			type Result struct {
-				OK      bool        `json:"ok"`
-				Message string      `json:"message,omitempty"`
-				Count   int         `json:"count"`
-				Events  []EventInfo `json:"events,omitempty"`
+				OK      bool                           `json:"ok"`
+				Stats   *identify.DirectionRepairStats `json:"stats,omitempty"`
+				DryRun  bool                           `json:"dry_run,omitempty"`
+				Message string                         `json:"message,omitempty"`
 			}
 
-			// Parse flags
-			personName, _ := cmd.Flags().GetString("person")
-			channel, _ := cmd.Flags().GetString("channel")
-			sinceStr, _ := cmd.Flags().GetString("since")
-			untilStr, _ := cmd.Flags().GetString("until")
-			direction, _ := cmd.Flags().GetString("direction")
-			limit, _ := cmd.Flags().GetInt("limit")
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
 
-			// Build filters
-			filters := query.EventFilters{
-				PersonName: personName,
-				Channel:    channel,
-				Direction:  direction,
-				Limit:      limit,
+			stats, err := identify.RepairDirections(database, identify.DirectionRepairOptions{
+				BatchSize: directionsBatchSize,
+				Limit:     directionsLimit,
+				Channel:   directionsChannel,
+				Observed:  directionsObserved,
+				DryRun:    directionsDryRun,
+			})
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to repair directions: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
 			}
 
-			// Parse since date
-			if sinceStr != "" {
-				since, err := parseDate(sinceStr)
-				if err != nil {
-					result := Result{
-						OK:      false,
-						Message: fmt.Sprintf("Invalid since date: %v. Use format YYYY-MM-DD", err),
-					}
-					if jsonOutput {
-						printJSON(result)
-					} else {
-						fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-					}
-					os.Exit(1)
+			result := Result{OK: true, Stats: stats, DryRun: directionsDryRun}
+			if jsonOutput {
+				printJSON(result)
+			} else {
+				if directionsDryRun {
+					fmt.Println("Dry run - no changes made:")
+				} else {
+					fmt.Println("✓ Direction repair complete:")
+				}
+				fmt.Printf("  Messages scanned: %d\n", stats.EventsScanned)
+				fmt.Printf("  Corrected to sent: %d\n", stats.CorrectedSent)
+				fmt.Printf("  Corrected to received: %d\n", stats.CorrectedRecv)
+				fmt.Printf("  Unchanged: %d\n", stats.Unchanged)
+				fmt.Printf("  Ambiguous: %d\n", stats.Ambiguous)
+				for channel, n := range stats.ByChannel {
+					fmt.Printf("    %s: %d\n", channel, n)
+				}
+				if !directionsDryRun && stats.CorrectedSent+stats.CorrectedRecv > 0 {
+					fmt.Println("\nRe-run 'mnemonic chunk run' to rebuild affected episodes")
 				}
-				filters.Since = since
 			}
+		},
+	}
+	repairDirectionsCmd.Flags().IntVar(&directionsBatchSize, "batch-size", identify.DefaultRepairBatchSize, "Events per transaction")
+	repairDirectionsCmd.Flags().IntVar(&directionsLimit, "limit", 0, "Max events to examine (0 = all)")
+	repairDirectionsCmd.Flags().StringVar(&directionsChannel, "channel", "", "Only repair events on this channel")
+	repairDirectionsCmd.Flags().BoolVar(&directionsObserved, "observed", false, "Also repair events marked observed (requires --channel)")
+	repairDirectionsCmd.Flags().BoolVar(&directionsDryRun, "dry-run", false, "Show what would be corrected without making changes")
+
+	repairCmd.AddCommand(repairParticipantsCmd)
+	repairCmd.AddCommand(repairDirectionsCmd)
+	rootCmd.AddCommand(repairCmd)
+
+	// threads command - merge and split fragmented conversations
+	threadsCmd := &cobra.Command{
+		Use:   "threads",
+		Short: "Merge and split conversation threads and show group rosters",
+	}
 
-			// Parse until date
-			if untilStr != "" {
-				until, err := parseDate(untilStr)
+	var threadsMergeReason string
+	threadsMergeCmd := &cobra.Command{
+		Use:   "merge <from-thread> <into-thread>",
+		Short: "Move every event of one thread into another",
+		Long: `Merge a fragmented conversation (e.g. after a phone number change or a
+channel migration) by moving all events of <from-thread> into <into-thread>.
+Episodes follow their events, and the move is recorded in thread_changes.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                  `json:"ok"`
+				Change  *threads.ChangeResult `json:"change,omitempty"`
+				Message string                `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			change, err := threads.Merge(database, args[0], args[1], threadsMergeReason)
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to merge threads: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			result := Result{OK: true, Change: change}
+			if jsonOutput {
+				printJSON(result)
+			} else {
+				fmt.Printf("✓ Merged %s into %s:\n", change.SourceThreadID, change.TargetThreadID)
+				fmt.Printf("  Events moved: %d\n", change.EventsMoved)
+				fmt.Printf("  Episodes updated: %d\n", change.EpisodesUpdated)
+				fmt.Printf("  Change ID: %s\n", change.ChangeID)
+			}
+		},
+	}
+	threadsMergeCmd.Flags().StringVar(&threadsMergeReason, "reason", "", "Why the threads are being merged (recorded in the audit trail)")
+
+	var threadsSplitSince string
+	var threadsSplitEvents []string
+	var threadsSplitNewID string
+	var threadsSplitName string
+	var threadsSplitReason string
+	threadsSplitCmd := &cobra.Command{
+		Use:   "split <thread>",
+		Short: "Move part of a thread into a new thread",
+		Long: `Split a thread by moving either the listed events (--event) or every event
+since a point in time (--since) into a new thread. Episodes entirely on one
+side follow their events; episodes straddling the split are marked as
+spanning threads. The move is recorded in thread_changes.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                  `json:"ok"`
+				Change  *threads.ChangeResult `json:"change,omitempty"`
+				Message string                `json:"message,omitempty"`
+			}
+
+			opts := threads.SplitOptions{
+				EventIDs:    threadsSplitEvents,
+				NewThreadID: threadsSplitNewID,
+				Name:        threadsSplitName,
+				Reason:      threadsSplitReason,
+			}
+			if threadsSplitSince != "" {
+				since, err := time.Parse(time.RFC3339, threadsSplitSince)
 				if err != nil {
-					result := Result{
-						OK:      false,
-						Message: fmt.Sprintf("Invalid until date: %v. Use format YYYY-MM-DD", err),
-					}
+					since, err = parseDate(threadsSplitSince)
+				}
+				if err != nil {
+					result := Result{OK: false, Message: fmt.Sprintf("Invalid since: %v. Use YYYY-MM-DD or RFC3339", err)}
 					if jsonOutput {
 						printJSON(result)
 					} else {
@@ -3051,15 +4392,18 @@ The algorithm runs in three phases:
 					}
 					os.Exit(1)
 				}
-				filters.Until = until
+				opts.Since = since.Unix()
 			}
 
-			// Validate direction
-			if direction != "" && direction != "sent" && direction != "received" && direction != "observed" {
-				result := Result{
-					OK:      false,
-					Message: "Invalid direction. Must be one of: sent, received, observed",
-				}
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			change, err := threads.Split(database, args[0], opts)
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to split thread: %v", err)}
 				if jsonOutput {
 					printJSON(result)
 				} else {
@@ -3068,13 +4412,48 @@ The algorithm runs in three phases:
 				os.Exit(1)
 			}
 
-			// Open database
+			result := Result{OK: true, Change: change}
+			if jsonOutput {
+				printJSON(result)
+			} else {
+				fmt.Printf("✓ Split %s into %s:\n", change.SourceThreadID, change.TargetThreadID)
+				fmt.Printf("  Events moved: %d\n", change.EventsMoved)
+				fmt.Printf("  Episodes updated: %d\n", change.EpisodesUpdated)
+				fmt.Printf("  Change ID: %s\n", change.ChangeID)
+			}
+		},
+	}
+	threadsSplitCmd.Flags().StringVar(&threadsSplitSince, "since", "", "Move events at or after this time (YYYY-MM-DD or RFC3339)")
+	threadsSplitCmd.Flags().StringSliceVar(&threadsSplitEvents, "event", nil, "Event ID to move (repeatable; overrides --since)")
+	threadsSplitCmd.Flags().StringVar(&threadsSplitNewID, "new-id", "", "ID for the new thread (generated if empty)")
+	threadsSplitCmd.Flags().StringVar(&threadsSplitName, "name", "", "Name for the new thread (defaults to the original's)")
+	threadsSplitCmd.Flags().StringVar(&threadsSplitReason, "reason", "", "Why the thread is being split (recorded in the audit trail)")
+
+	var threadsHistoryLimit int
+	threadsHistoryCmd := &cobra.Command{
+		Use:   "history [thread]",
+		Short: "Show the audit trail of thread merges and splits",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool             `json:"ok"`
+				Changes []threads.Change `json:"changes"`
+				Message string           `json:"message,omitempty"`
+			}
+
 			database, err := db.Open()
 			if err != nil {
-				result := Result{
-					OK:      false,
-					Message: fmt.Sprintf("Failed to open database: %v", err),
-				}
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			threadID := ""
+			if len(args) > 0 {
+				threadID = args[0]
+			}
+			changes, err := threads.ListChanges(database, threadID, threadsHistoryLimit)
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to list thread changes: %v", err)}
 				if jsonOutput {
 					printJSON(result)
 				} else {
@@ -3082,164 +4461,280 @@ The algorithm runs in three phases:
 				}
 				os.Exit(1)
 			}
-			defer database.Close()
 
-			// Query events
-			events, err := query.QueryEvents(database, filters)
-			if err != nil {
-				result := Result{
-					OK:      false,
-					Message: fmt.Sprintf("Failed to query events: %v", err),
+			if jsonOutput {
+				printJSON(Result{OK: true, Changes: changes})
+				return
+			}
+			if len(changes) == 0 {
+				fmt.Println("No thread changes recorded")
+				return
+			}
+			for _, c := range changes {
+				fmt.Printf("%s  %-5s  %s -> %s  (%d events, %d episodes)\n",
+					c.CreatedAt.Format("2006-01-02 15:04"), c.Operation, c.SourceThreadID, c.TargetThreadID,
+					len(c.EventIDs), c.EpisodesUpdated)
+				if c.Reason != "" {
+					fmt.Printf("    reason: %s\n", c.Reason)
 				}
+			}
+		},
+	}
+	threadsHistoryCmd.Flags().IntVar(&threadsHistoryLimit, "limit", 50, "Maximum changes to show")
+
+	var threadsMembersAt string
+	var threadsMembersHistory bool
+	threadsMembersCmd := &cobra.Command{
+		Use:   "members <thread>",
+		Short: "Show who is (or was) in a group thread",
+		Long: `Show a group thread's roster from thread_membership: current members by
+default, members at a point in time with --at, or every join and leave with
+--history. Rosters are rebuilt on each iMessage sync; run
+'threads rebuild-members' after importing older history.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                 `json:"ok"`
+				At      string               `json:"at,omitempty"`
+				Members []threads.Membership `json:"members"`
+				Message string               `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
 				if jsonOutput {
-					printJSON(result)
+					printJSON(Result{OK: false, Message: msg})
 				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
 				}
 				os.Exit(1)
 			}
 
-			result := Result{
-				OK:    true,
-				Count: len(events),
-			}
-
-			// Convert events to result format
-			for _, e := range events {
-				eventInfo := EventInfo{
-					ID:           e.ID,
-					Timestamp:    e.Timestamp,
-					TimestampStr: query.FormatTimestamp(e.Timestamp),
-					Channel:      e.Channel,
-					ContentTypes: e.ContentTypes,
-					Content:      e.Content,
-					Direction:    e.Direction,
-					ThreadID:     e.ThreadID,
-					ReplyTo:      e.ReplyTo,
+			at := time.Now()
+			if threadsMembersAt != "" {
+				t, err := time.Parse(time.RFC3339, threadsMembersAt)
+				if err != nil {
+					t, err = parseDate(threadsMembersAt)
 				}
-
-				for _, p := range e.Participants {
-					eventInfo.Participants = append(eventInfo.Participants, ParticipantInfo{
-						Name: p.Name,
-						Role: p.Role,
-					})
+				if err != nil {
+					fail(fmt.Sprintf("Invalid at: %v. Use YYYY-MM-DD or RFC3339", err))
 				}
+				at = t
+			}
 
-				result.Events = append(result.Events, eventInfo)
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
+			defer database.Close()
 
-			if jsonOutput {
-				printJSON(result)
+			var members []threads.Membership
+			result := Result{OK: true}
+			if threadsMembersHistory {
+				members, err = threads.Roster(database, args[0])
 			} else {
-				if len(events) == 0 {
-					fmt.Println("No events found matching the specified filters.")
-					return
-				}
+				members, err = threads.MembersAt(database, args[0], at)
+				result.At = at.Format(time.RFC3339)
+			}
+			if err != nil {
+				fail(fmt.Sprintf("Failed to load members: %v", err))
+			}
+			if members == nil {
+				members = []threads.Membership{}
+			}
+			result.Members = members
 
-				// Build filter description
-				filterParts := []string{}
-				if personName != "" {
-					filterParts = append(filterParts, fmt.Sprintf("person: %s", personName))
-				}
-				if channel != "" {
-					filterParts = append(filterParts, fmt.Sprintf("channel: %s", channel))
-				}
-				if direction != "" {
-					filterParts = append(filterParts, fmt.Sprintf("direction: %s", direction))
+			if jsonOutput {
+				printJSON(result)
+				return
+			}
+			if len(members) == 0 {
+				fmt.Println("No members recorded (is this a group thread? try 'threads rebuild-members')")
+				return
+			}
+			spanDate := func(t *time.Time, open string) string {
+				if t == nil {
+					return open
 				}
-				if sinceStr != "" {
-					filterParts = append(filterParts, fmt.Sprintf("since: %s", sinceStr))
+				return t.Format("2006-01-02")
+			}
+			for _, m := range members {
+				name := m.Name
+				if name == "" {
+					name = m.ContactID
 				}
-				if untilStr != "" {
-					filterParts = append(filterParts, fmt.Sprintf("until: %s", untilStr))
+				if threadsMembersHistory {
+					fmt.Printf("%-30s  %s -> %s  (%s)\n", name,
+						spanDate(m.JoinedAt, "start"), spanDate(m.LeftAt, "now"), m.Source)
+				} else {
+					fmt.Printf("%-30s  since %s\n", name, spanDate(m.JoinedAt, "start"))
 				}
-
-				if len(filterParts) > 0 {
-					fmt.Printf("Events (%s):\n", strings.Join(filterParts, ", "))
+			}
+		},
+	}
+	threadsMembersCmd.Flags().StringVar(&threadsMembersAt, "at", "", "Show members at this time (YYYY-MM-DD or RFC3339)")
+	threadsMembersCmd.Flags().BoolVar(&threadsMembersHistory, "history", false, "Show every membership span")
+
+	var threadsRebuildSince string
+	threadsRebuildMembersCmd := &cobra.Command{
+		Use:   "rebuild-members",
+		Short: "Rebuild group thread rosters from membership events",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool   `json:"ok"`
+				Threads int    `json:"threads"`
+				Message string `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
 				} else {
-					fmt.Printf("Recent events (showing up to %d):\n", limit)
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
 				}
-				fmt.Printf("Found %d event(s)\n\n", len(events))
-
-				for i, e := range events {
-					if i > 0 {
-						fmt.Println("---")
-					}
-
-					fmt.Printf("ID: %s\n", e.ID)
-					fmt.Printf("Time: %s\n", query.FormatTimestamp(e.Timestamp))
-					fmt.Printf("Channel: %s\n", e.Channel)
-					fmt.Printf("Direction: %s\n", e.Direction)
+				os.Exit(1)
+			}
 
-					if len(e.Participants) > 0 {
-						fmt.Println("Participants:")
-						for _, p := range e.Participants {
-							fmt.Printf("  - %s (%s)\n", p.Name, p.Role)
-						}
-					}
+			var since int64
+			if threadsRebuildSince != "" {
+				t, err := parseDate(threadsRebuildSince)
+				if err != nil {
+					fail(fmt.Sprintf("Invalid since: %v. Use YYYY-MM-DD", err))
+				}
+				since = t.Unix()
+			}
 
-					if e.Content != "" {
-						// Truncate long content for display
-						content := e.Content
-						if len(content) > 200 {
-							content = content[:200] + "..."
-						}
-						fmt.Printf("Content: %s\n", content)
-					}
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
 
-					if e.ThreadID != nil {
-						fmt.Printf("Thread: %s\n", *e.ThreadID)
-					}
-				}
+			n, err := threads.RebuildMembership(database, since)
+			if err != nil {
+				fail(fmt.Sprintf("Failed to rebuild members: %v", err))
+			}
+			if jsonOutput {
+				printJSON(Result{OK: true, Threads: n})
+				return
 			}
+			fmt.Printf("Rebuilt rosters for %d threads\n", n)
 		},
 	}
+	threadsRebuildMembersCmd.Flags().StringVar(&threadsRebuildSince, "since", "", "Only threads with events on or after this date (YYYY-MM-DD)")
+
+	threadsCmd.AddCommand(threadsMergeCmd)
+	threadsCmd.AddCommand(threadsSplitCmd)
+	threadsCmd.AddCommand(threadsHistoryCmd)
+	threadsCmd.AddCommand(threadsMembersCmd)
+	threadsCmd.AddCommand(threadsRebuildMembersCmd)
+	rootCmd.AddCommand(threadsCmd)
+
+	// stats command - memory extraction cost per channel/thread/model
+	var statsBy string
+	var statsLimit int
+	var statsTop int
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show memory extraction cost and latency by channel, thread or model",
+		Long: `Show tokens, estimated cost and wall time spent extracting memory, grouped by
+channel, thread or model, plus the most expensive episodes (e.g. huge group
+chats that eat the budget).`,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK          bool                         `json:"ok"`
+				GroupBy     string                       `json:"group_by"`
+				Groups      []memory.ProcessingCostGroup `json:"groups"`
+				TopEpisodes []memory.EpisodeProcessing   `json:"top_episodes,omitempty"`
+				Message     string                       `json:"message,omitempty"`
+			}
 
-	eventsCmd.Flags().String("person", "", "Filter by person name")
-	eventsCmd.Flags().String("channel", "", "Filter by channel (e.g., imessage, gmail)")
-	eventsCmd.Flags().String("since", "", "Filter by start date (YYYY-MM-DD)")
-	eventsCmd.Flags().String("until", "", "Filter by end date (YYYY-MM-DD)")
-	eventsCmd.Flags().String("direction", "", "Filter by direction (sent, received, observed)")
-	eventsCmd.Flags().Int("limit", 100, "Maximum number of events to return")
-	rootCmd.AddCommand(eventsCmd)
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
 
-	// people command
-	peopleCmd := &cobra.Command{
-		Use:   "people [name]",
-		Short: "List and search contacts",
-		Long:  "List all persons, search by name, or show details for a specific person",
-		Args:  cobra.MaximumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			type IdentityInfo struct {
-				Channel    string `json:"channel"`
-				Identifier string `json:"identifier"`
+			ctx := context.Background()
+			processingLog := memory.NewProcessingLog(database)
+			groups, err := processingLog.CostBreakdown(ctx, statsBy, statsLimit)
+			var top []memory.EpisodeProcessing
+			if err == nil && statsTop > 0 {
+				top, err = processingLog.TopEpisodes(ctx, statsTop)
+			}
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to load processing stats: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
 			}
 
-			type PersonInfo struct {
-				ID           string         `json:"id"`
-				Name         string         `json:"name"`
-				DisplayName  string         `json:"display_name,omitempty"`
-				IsMe         bool           `json:"is_me"`
-				Relationship string         `json:"relationship,omitempty"`
-				Identities   []IdentityInfo `json:"identities"`
-				EventCount   int            `json:"event_count"`
-				LastEventAt  string         `json:"last_event_at,omitempty"`
+			if jsonOutput {
+				printJSON(Result{OK: true, GroupBy: statsBy, Groups: groups, TopEpisodes: top})
+				return
+			}
+			if len(groups) == 0 {
+				fmt.Println("No episodes have been processed yet")
+				return
+			}
+
+			fmt.Printf("Cost by %s:\n", statsBy)
+			for _, g := range groups {
+				key := g.Key
+				if key == "" {
+					key = "(none)"
+				}
+				fmt.Printf("  %-40s  %5d episodes  $%.4f  (avg $%.4f, %dms)", key, g.Episodes, g.CostUSD, g.AvgCostUSD, g.AvgDurationMs)
+				if g.Errors > 0 {
+					fmt.Printf("  %d errors", g.Errors)
+				}
+				fmt.Println()
+			}
+			if len(top) > 0 {
+				fmt.Println("\nMost expensive episodes:")
+				for _, ep := range top {
+					fmt.Printf("  %s  $%.4f  %d tokens  %dms  %d chars  [%s %s]\n",
+						ep.EpisodeID, ep.CostUSD, ep.PromptTokens+ep.OutputTokens, ep.DurationMs,
+						ep.ContentChars, ep.Channel, ep.ThreadID)
+				}
 			}
+		},
+	}
+	statsCmd.Flags().StringVar(&statsBy, "by", "channel", "Group by: channel, thread, model, route")
+	statsCmd.Flags().IntVar(&statsLimit, "limit", 20, "Maximum groups to show")
+	statsCmd.Flags().IntVar(&statsTop, "top", 10, "Show the N most expensive episodes (0 = none)")
+	rootCmd.AddCommand(statsCmd)
+
+	// report command - summaries of the ingested data
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Reports on the ingested data",
+	}
 
+	reportCoverageCmd := &cobra.Command{
+		Use:   "coverage",
+		Short: "Summarize what has been ingested and where the gaps are",
+		Long: `Show events per channel per year, threads, contacts with and without names,
+the share of events in episodes and of episodes processed into memory, and
+gaps worth backfilling (e.g. "no gmail before 2019 imported"). Run it after
+the first sync to check the import is complete.`,
+		Run: func(cmd *cobra.Command, args []string) {
 			type Result struct {
-				OK      bool         `json:"ok"`
-				Message string       `json:"message,omitempty"`
-				Count   int          `json:"count,omitempty"`
-				Persons []PersonInfo `json:"persons,omitempty"`
+				OK      bool             `json:"ok"`
+				Report  *coverage.Report `json:"report,omitempty"`
+				Message string           `json:"message,omitempty"`
 			}
 
-			// Open database
 			database, err := db.Open()
 			if err != nil {
-				result := Result{
-					OK:      false,
-					Message: fmt.Sprintf("Failed to open database: %v", err),
-				}
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			report, err := coverage.Build(context.Background(), database)
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to build coverage report: %v", err)}
 				if jsonOutput {
 					printJSON(result)
 				} else {
@@ -3247,109 +4742,291 @@ The algorithm runs in three phases:
 				}
 				os.Exit(1)
 			}
-			defer database.Close()
-
-			// Handle specific person detail view
-			if len(args) == 1 {
-				personName := args[0]
-				person, err := identify.GetPersonByName(database, personName)
-				if err != nil {
-					result := Result{
-						OK:      false,
-						Message: fmt.Sprintf("Failed to get person: %v", err),
-					}
-					if jsonOutput {
-						printJSON(result)
-					} else {
-						fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-					}
-					os.Exit(1)
-				}
 
-				if person == nil {
-					result := Result{
-						OK:      false,
-						Message: fmt.Sprintf("Person '%s' not found", personName),
-					}
-					if jsonOutput {
-						printJSON(result)
-					} else {
-						fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-					}
-					os.Exit(1)
-				}
+			if jsonOutput {
+				printJSON(Result{OK: true, Report: report})
+				return
+			}
 
-				// Convert to result format
-				personInfo := PersonInfo{
-					ID:         person.ID,
-					Name:       person.CanonicalName,
-					IsMe:       person.IsMe,
-					EventCount: person.EventCount,
-				}
-				if person.DisplayName != nil {
-					personInfo.DisplayName = *person.DisplayName
-				}
-				if person.RelationType != nil {
-					personInfo.Relationship = *person.RelationType
+			fmt.Printf("Events: %d  Threads: %d  Contacts: %d (%d named)\n",
+				report.Events, report.Threads, report.Contacts, report.NamedContacts)
+			fmt.Printf("In episodes: %.0f%% of events  Processed: %d/%d episodes (%.0f%%)\n",
+				100*report.EpisodeShare(), report.EpisodesDone, report.Episodes, 100*report.ProcessedShare())
+			for _, c := range report.Channels {
+				if c.Events == 0 {
+					continue
 				}
-				if person.LastEventAt != nil {
-					personInfo.LastEventAt = query.FormatTimestamp(person.LastEventAt.Unix())
+				fmt.Printf("\n%s: %d events, %d threads, %s to %s\n", c.Channel, c.Events, c.Threads,
+					time.Unix(c.FirstEvent, 0).UTC().Format("2006-01-02"), time.Unix(c.LastEvent, 0).UTC().Format("2006-01-02"))
+				for _, y := range c.Years {
+					fmt.Printf("  %d  %8d\n", y.Year, y.Events)
 				}
-				for _, id := range person.Identities {
-					personInfo.Identities = append(personInfo.Identities, IdentityInfo{
-						Channel:    id.Channel,
-						Identifier: id.Identifier,
-					})
+			}
+			if len(report.Gaps) > 0 {
+				fmt.Println("\nGaps:")
+				for _, g := range report.Gaps {
+					fmt.Printf("  - %s\n", g)
 				}
+			}
+		},
+	}
+	reportCmd.AddCommand(reportCoverageCmd)
+	rootCmd.AddCommand(reportCmd)
+
+	var usageDays int
+	usageCmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Show API token and cost usage against quotas",
+		Long: `Show API usage per provider and API key for today and this month, as
+recorded by every command that calls a model. Keys are shown as a short
+hash. Quotas come from config:
+
+  usage:
+    warn_at: 0.8
+    quotas:
+      gemini:
+        daily_tokens: 2000000
+        monthly_usd: 20
+
+Commands log a warning when usage reaches warn_at of a quota.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool          `json:"ok"`
+				Report  *usage.Report `json:"report,omitempty"`
+				Message string        `json:"message,omitempty"`
+			}
 
-				result := Result{
-					OK:      true,
-					Count:   1,
-					Persons: []PersonInfo{personInfo},
-				}
+			cfg, err := config.Load()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to load config: %w", err))
+			}
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
 
+			report, err := usage.GetReport(context.Background(), database, cfg.Usage, time.Now(), usageDays)
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to get usage: %v", err)}
 				if jsonOutput {
 					printJSON(result)
 				} else {
-					nameStr := person.CanonicalName
-					if person.IsMe {
-						nameStr += " (me)"
-					}
-					fmt.Printf("%s\n", nameStr)
-					fmt.Printf("ID: %s\n", person.ID)
-					if person.DisplayName != nil {
-						fmt.Printf("Display name: %s\n", *person.DisplayName)
-					}
-					if person.RelationType != nil {
-						fmt.Printf("Relationship: %s\n", *person.RelationType)
-					}
-					fmt.Printf("\nStatistics:\n")
-					fmt.Printf("  Events: %d\n", person.EventCount)
-					if person.LastEventAt != nil {
-						fmt.Printf("  Last event: %s\n", query.FormatTimestamp(person.LastEventAt.Unix()))
-					}
-					if len(person.Identities) > 0 {
-						fmt.Printf("\nIdentities:\n")
-						for _, id := range person.Identities {
-							fmt.Printf("  - %s: %s\n", id.Channel, id.Identifier)
-						}
-					}
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
 				}
-				return
+				os.Exit(1)
 			}
 
-			// Handle list/search mode
-			searchTerm, _ := cmd.Flags().GetString("search")
-			topN, _ := cmd.Flags().GetInt("top")
-
-			var persons []identify.PersonWithIdentities
-			if searchTerm != "" {
-				persons, err = identify.Search(database, searchTerm)
-				if err != nil {
-					result := Result{
-						OK:      false,
-						Message: fmt.Sprintf("Failed to search persons: %v", err),
+			if jsonOutput {
+				printJSON(Result{OK: true, Report: report})
+				return
+			}
+			if len(report.Keys) == 0 {
+				fmt.Println("No API usage this month")
+			}
+			for _, k := range report.Keys {
+				fmt.Printf("%s  key %s\n", k.Provider, k.KeyID)
+				for _, p := range []struct {
+					label string
+					t     usage.Totals
+				}{{"today", k.Today}, {"month", k.Month}} {
+					fmt.Printf("  %-6s %d tokens (%d in, %d out), %d embed chars, %d calls, $%.4f\n",
+						p.label+":", p.t.Tokens(), p.t.PromptTokens, p.t.OutputTokens, p.t.EmbedChars, p.t.Calls, p.t.CostUSD)
+				}
+			}
+			if len(report.Daily) > 0 {
+				fmt.Println("\nDaily:")
+				for _, d := range report.Daily {
+					fmt.Printf("  %s  %-8s %10d tokens  %6d calls  $%.4f\n", d.Day, d.Provider, d.Tokens(), d.Calls, d.CostUSD)
+				}
+			}
+			if len(report.Quotas) > 0 {
+				fmt.Println("\nQuotas:")
+				for _, q := range report.Quotas {
+					used, limit := fmt.Sprintf("%.0f", q.Used), fmt.Sprintf("%.0f", q.Limit)
+					if q.Metric == "usd" {
+						used, limit = fmt.Sprintf("$%.2f", q.Used), fmt.Sprintf("$%.2f", q.Limit)
+					}
+					fmt.Printf("  %-8s %-5s %-6s %s / %s (%.0f%%)\n", q.Provider, q.Period, q.Metric, used, limit, q.Fraction*100)
+				}
+				for _, q := range report.Quotas {
+					if q.Warning {
+						fmt.Printf("Warning: %s\n", q.Message())
 					}
+				}
+			}
+		},
+	}
+	usageCmd.Flags().IntVar(&usageDays, "days", 0, "Also show usage per day for the last N days")
+	rootCmd.AddCommand(usageCmd)
+
+	// memory command - knowledge graph quality tools
+	memoryCmd := &cobra.Command{
+		Use:   "memory",
+		Short: "Inspect and evaluate the memory graph",
+	}
+
+	calibrationCmd := &cobra.Command{
+		Use:   "calibration",
+		Short: "Compare extracted confidence against human spot-checks",
+	}
+
+	var calibrationReviewLimit int
+	var calibrationBuckets int
+	calibrationReviewCmd := &cobra.Command{
+		Use:   "review",
+		Short: "Label a sample of extracted relationships as correct or incorrect",
+		Long: `Sample unlabeled relationships spread across confidence buckets and label
+each one interactively: y = correct, n = incorrect, s = skip, q = quit.
+With --json the sample is printed instead of prompting.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                       `json:"ok"`
+				Samples []memory.CalibrationSample `json:"samples"`
+				Message string                     `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			ctx := context.Background()
+			calibration := memory.NewCalibration(database)
+			samples, err := calibration.Sample(ctx, calibrationReviewLimit, calibrationBuckets)
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to sample relationships: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Samples: samples})
+				return
+			}
+			if len(samples) == 0 {
+				fmt.Println("No unlabeled relationships to review")
+				return
+			}
+
+			reader := bufio.NewReader(os.Stdin)
+			labeled := 0
+			for i, s := range samples {
+				fmt.Printf("\n[%d/%d] confidence %.2f\n", i+1, len(samples), s.Confidence)
+				fmt.Printf("  %s %s %s\n", s.SourceName, s.RelationType, s.Target)
+				fmt.Printf("  %q\n", s.Fact)
+				fmt.Print("Correct? [y/n/s/q] ")
+
+				line, err := reader.ReadString('\n')
+				answer := strings.ToLower(strings.TrimSpace(line))
+				if err != nil && answer == "" {
+					break
+				}
+				if answer == "q" {
+					break
+				}
+				if answer != "y" && answer != "n" {
+					continue
+				}
+				if err := calibration.Label(ctx, s.RelationshipID, answer == "y", ""); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					continue
+				}
+				labeled++
+			}
+			fmt.Printf("\n✓ Labeled %d relationships\n", labeled)
+		},
+	}
+	calibrationReviewCmd.Flags().IntVar(&calibrationReviewLimit, "limit", 20, "Number of relationships to sample")
+	calibrationReviewCmd.Flags().IntVar(&calibrationBuckets, "buckets", memory.DefaultCalibrationBuckets, "Confidence buckets to spread the sample across")
+
+	var calibrationReportBuckets int
+	calibrationReportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Show the confidence calibration curve from labeled samples",
+		Long: `Bucket relationships by stated confidence and compare each bucket's mean
+confidence with the precision of its human labels. Use this to set
+thresholds such as auto-merge or digest inclusion from data.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                      `json:"ok"`
+				Report  *memory.CalibrationReport `json:"report,omitempty"`
+				Message string                    `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			report, err := memory.NewCalibration(database).Report(context.Background(), calibrationReportBuckets)
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to build calibration report: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Report: report})
+				return
+			}
+
+			fmt.Printf("Calibration (%d labeled, %d correct):\n", report.Labeled, report.Correct)
+			fmt.Printf("  %-11s  %13s  %7s  %9s  %9s\n", "confidence", "relationships", "labeled", "mean conf", "precision")
+			for _, b := range report.Buckets {
+				if b.Relationships == 0 && b.Labeled == 0 {
+					continue
+				}
+				precision, meanConf := "-", "-"
+				if b.Labeled > 0 {
+					precision = fmt.Sprintf("%.2f", b.Precision)
+					meanConf = fmt.Sprintf("%.2f", b.MeanConfidence)
+				}
+				fmt.Printf("  %.2f-%.2f    %13d  %7d  %9s  %9s\n", b.Lower, b.Upper, b.Relationships, b.Labeled, meanConf, precision)
+			}
+			if report.Labeled > 0 {
+				fmt.Printf("Expected calibration error: %.3f\n", report.ECE)
+			} else {
+				fmt.Println("No labels yet - run 'mnemonic memory calibration review'")
+			}
+		},
+	}
+	calibrationReportCmd.Flags().IntVar(&calibrationReportBuckets, "buckets", memory.DefaultCalibrationBuckets, "Number of confidence buckets")
+
+	var dupesThreshold float64
+	var dupesLimit int
+	var dupesType string
+	memoryDupesCmd := &cobra.Command{
+		Use:   "dupes",
+		Short: "List possibly duplicate entities that are not yet merge candidates",
+		Long: `List entity pairs of the same type with similar names or embeddings that
+have not been proposed as merge candidates, with how many episodes mention
+both. Nothing is merged - this is a worklist for manual review.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                   `json:"ok"`
+				Pairs   []memory.DuplicatePair `json:"pairs"`
+				Message string                 `json:"message,omitempty"`
+			}
+
+			var opts memory.DuplicateReportOptions
+			opts.Threshold = dupesThreshold
+			opts.Limit = dupesLimit
+			if dupesType != "" {
+				et := memory.GetEntityTypeByName(dupesType)
+				if et == nil {
+					result := Result{OK: false, Message: fmt.Sprintf("Unknown entity type %q (valid: %s)", dupesType, strings.Join(memory.EntityTypeNames(), ", "))}
 					if jsonOutput {
 						printJSON(result)
 					} else {
@@ -3357,13 +5034,211 @@ The algorithm runs in three phases:
 					}
 					os.Exit(1)
 				}
-			} else {
-				persons, err = identify.ListAll(database)
-				if err != nil {
-					result := Result{
-						OK:      false,
-						Message: fmt.Sprintf("Failed to list persons: %v", err),
-					}
+				opts.EntityTypeID = &et.ID
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			pairs, err := memory.NewDuplicateFinder(database, "").Find(context.Background(), opts)
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to find duplicates: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Pairs: pairs})
+				return
+			}
+			if len(pairs) == 0 {
+				fmt.Println("No possible duplicates found")
+				return
+			}
+
+			fmt.Printf("Possible duplicates (%d):\n", len(pairs))
+			for _, p := range pairs {
+				embedding := "-"
+				if p.EmbeddingSimilarity != nil {
+					embedding = fmt.Sprintf("%.2f", *p.EmbeddingSimilarity)
+				}
+				fmt.Printf("  %.2f  %s (%d episodes)  ~  %s (%d episodes)\n", p.Score, p.A.Name, p.A.Episodes, p.B.Name, p.B.Episodes)
+				fmt.Printf("        name %.2f  embedding %s  shared episodes %d  [%s %s]\n", p.NameSimilarity, embedding, p.SharedEpisodes, p.A.ID, p.B.ID)
+			}
+		},
+	}
+	memoryDupesCmd.Flags().Float64Var(&dupesThreshold, "threshold", memory.DefaultDuplicateThreshold, "Minimum name or embedding similarity (0-1)")
+	memoryDupesCmd.Flags().IntVar(&dupesLimit, "limit", 100, "Maximum pairs to show (0 = all)")
+	memoryDupesCmd.Flags().StringVar(&dupesType, "type", "", "Only compare entities of this type (e.g. Person)")
+
+	var sharedDryRun bool
+	memorySharedAliasesCmd := &cobra.Command{
+		Use:   "shared-aliases",
+		Short: "Mark emails and phones shared by different people",
+		Long: `Find emails and phones held by several entities that are evidently
+different people - related (spouse, parent, sibling), mentioned together,
+with different birthdates, or with the same surname - and mark them shared.
+Shared aliases are weak evidence for merging rather than hard identifiers.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                 `json:"ok"`
+				Checked int                  `json:"checked"`
+				Shared  []memory.SharedAlias `json:"shared"`
+				DryRun  bool                 `json:"dry_run,omitempty"`
+				Message string               `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			detected, err := memory.NewSharedAliasDetector(database).Detect(context.Background(), !sharedDryRun)
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to detect shared aliases: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Checked: detected.Checked, Shared: detected.Shared, DryRun: sharedDryRun})
+				return
+			}
+			verb := "Marked"
+			if sharedDryRun {
+				verb = "Would mark"
+			}
+			fmt.Printf("%s %d of %d multi-holder aliases as shared\n", verb, len(detected.Shared), detected.Checked)
+			for _, s := range detected.Shared {
+				fmt.Printf("  %-5s  %s  (%s)  %s\n", s.AliasType, s.Normalized, s.Reason, strings.Join(s.EntityIDs, ", "))
+			}
+		},
+	}
+	memorySharedAliasesCmd.Flags().BoolVar(&sharedDryRun, "dry-run", false, "Show what would be marked without changing anything")
+
+	var mergeBatchSize int
+	var mergeMaxConflictRate float64
+	var mergeMinSample int
+	memoryMergeCmd := &cobra.Command{
+		Use:   "merge",
+		Short: "Process pending merge candidates in a bounded batch",
+		Long: `Evaluate up to --batch-size pending merge candidates, least recently
+evaluated first, auto-merging the safe ones and leaving the rest for review.
+Run it again to continue through the queue.
+
+Conflicts are checked for the whole batch before anything merges. If more
+than --max-conflict-rate of the batch conflicts, nothing is merged: that
+usually means the candidate generator is misconfigured.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                                 `json:"ok"`
+				Result  *memory.ProcessMergeCandidatesResult `json:"result,omitempty"`
+				Message string                               `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			opts := memory.MergeBatchOptions{
+				BatchSize:       mergeBatchSize,
+				MaxConflictRate: mergeMaxConflictRate,
+				MinSample:       mergeMinSample,
+			}
+			if !jsonOutput {
+				opts.Progress = func(done, total int) {
+					fmt.Fprintf(os.Stderr, "\rProcessed %d/%d", done, total)
+				}
+			}
+			processed, err := memory.NewAutoMerger(database).ProcessMergeCandidatesBatch(context.Background(), opts)
+			if !jsonOutput {
+				fmt.Fprintln(os.Stderr)
+			}
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to process merge candidates: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: !processed.Aborted, Result: processed, Message: processed.AbortReason})
+				if processed.Aborted {
+					os.Exit(1)
+				}
+				return
+			}
+			if processed.Aborted {
+				fmt.Fprintf(os.Stderr, "Aborted: %s\n", processed.AbortReason)
+			}
+			fmt.Printf("Processed %d: %d merged, %d conflicts, %d need review, %d failed (%d remaining)\n",
+				processed.Processed, processed.AutoMerged, processed.Conflicts, processed.NeedsReview, processed.Failed, processed.Remaining)
+			for _, e := range processed.Errors {
+				fmt.Printf("  %s  %s: %s\n", e.CandidateID, e.Stage, e.Error)
+			}
+			if processed.Aborted {
+				os.Exit(1)
+			}
+		},
+	}
+	memoryMergeCmd.Flags().IntVar(&mergeBatchSize, "batch-size", memory.DefaultMergeBatchSize, "Maximum candidates to evaluate (0 = all)")
+	memoryMergeCmd.Flags().Float64Var(&mergeMaxConflictRate, "max-conflict-rate", memory.DefaultMaxConflictRate, "Abort without merging if more than this fraction conflicts (0 = never)")
+	memoryMergeCmd.Flags().IntVar(&mergeMinSample, "min-sample", memory.DefaultConflictRateMinSample, "Candidates evaluated before --max-conflict-rate applies")
+
+	var graphDepth int
+	var graphMaxNodes int
+	var graphMaxNeighbors int
+	var graphRelations []string
+	var graphIncludeInvalidated bool
+	var graphOrigins []string
+	var graphIncludeInferred bool
+	memoryGraphCmd := &cobra.Command{
+		Use:   "graph <entity-id>",
+		Short: "Show a size-bounded neighborhood of an entity as nodes and links",
+		Long: `Show the relationship neighborhood of an entity up to --depth hops.
+High-degree entities keep only their strongest --max-neighbors neighbors,
+and literal relationships (emails, dates, ...) are folded into node
+attributes. With --json the output is the nodes/links shape used by the
+web explorer.
+
+Entities nobody mentioned (origin "inferred", like regions added by geo
+normalization) are hidden unless --include-inferred is set. --origin keeps
+only neighbors with the given origins: extracted, imported, contact_seed,
+manual, inferred.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool             `json:"ok"`
+				Graph   *memory.Subgraph `json:"graph,omitempty"`
+				Message string           `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			for _, o := range graphOrigins {
+				if !memory.IsValidOrigin(o) {
+					result := Result{OK: false, Message: fmt.Sprintf("Unknown origin %q (one of %s)", o, strings.Join(memory.EntityOrigins, ", "))}
 					if jsonOutput {
 						printJSON(result)
 					} else {
@@ -3373,371 +5248,4276 @@ The algorithm runs in three phases:
 				}
 			}
 
-			// Apply top N limit if specified
-			if topN > 0 && topN < len(persons) {
-				persons = persons[:topN]
-			}
-
-			result := Result{
-				OK:    true,
-				Count: len(persons),
+			query := memory.DefaultQueryOptions()
+			query.RelationTypes = graphRelations
+			query.IncludeInvalidated = graphIncludeInvalidated
+			query.Origins = graphOrigins
+			if !graphIncludeInferred && len(graphOrigins) == 0 {
+				query.ExcludeOrigins = []string{memory.OriginInferred}
+			}
+
+			engine := memory.NewQueryEngine(database)
+			defer engine.Close()
+			graph, err := engine.GetSubgraph(context.Background(), args[0], memory.SubgraphOptions{
+				Depth:        graphDepth,
+				MaxNodes:     graphMaxNodes,
+				MaxNeighbors: graphMaxNeighbors,
+				Query:        query,
+			})
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to build graph: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Graph: graph})
+				return
+			}
+
+			names := make(map[string]string, len(graph.Nodes))
+			fmt.Printf("Nodes (%d):\n", len(graph.Nodes))
+			for _, n := range graph.Nodes {
+				names[n.ID] = n.Name
+				line := fmt.Sprintf("  %s%s (%s)", strings.Repeat("  ", n.Depth), n.Name, n.Type)
+				if n.Hidden > 0 {
+					line += fmt.Sprintf("  +%d more", n.Hidden)
+				}
+				fmt.Println(line)
+				for relType, values := range n.Attributes {
+					fmt.Printf("  %s  %s: %s\n", strings.Repeat("  ", n.Depth), relType, strings.Join(values, ", "))
+				}
+			}
+			fmt.Printf("Links (%d):\n", len(graph.Links))
+			for _, l := range graph.Links {
+				fmt.Printf("  %s -[%s x%d]-> %s\n", names[l.Source], l.Type, l.Count, names[l.Target])
+			}
+			if graph.Truncated {
+				fmt.Println("(some neighbors omitted - raise --max-neighbors or --max-nodes to see more)")
+			}
+		},
+	}
+	memoryGraphCmd.Flags().IntVar(&graphDepth, "depth", memory.DefaultSubgraphDepth, "Hops from the entity")
+	memoryGraphCmd.Flags().IntVar(&graphMaxNodes, "max-nodes", memory.DefaultSubgraphMaxNodes, "Maximum nodes returned")
+	memoryGraphCmd.Flags().IntVar(&graphMaxNeighbors, "max-neighbors", memory.DefaultSubgraphMaxNeighbors, "Maximum new neighbors kept per node")
+	memoryGraphCmd.Flags().StringSliceVar(&graphRelations, "relation", nil, "Only follow these relation types (repeatable)")
+	memoryGraphCmd.Flags().BoolVar(&graphIncludeInvalidated, "include-invalidated", false, "Include relationships that are no longer valid")
+	memoryGraphCmd.Flags().StringSliceVar(&graphOrigins, "origin", nil, "Only include neighbors with these origins (repeatable)")
+	memoryGraphCmd.Flags().BoolVar(&graphIncludeInferred, "include-inferred", false, "Include entities nobody mentioned (origin inferred)")
+
+	memoryReweightCmd := &cobra.Command{
+		Use:   "reweight",
+		Short: "Recompute relationship weights from mention frequency, recency, and source type",
+		Long: `Recompute every relationship's ranking weight. The pipeline updates weights
+for relationships it sees, but recency decay only advances when weights are
+recomputed, so run this periodically.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool   `json:"ok"`
+				Updated int    `json:"updated"`
+				Message string `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			updated, err := memory.NewRelationshipWeigher(database).RefreshAll(context.Background())
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to recompute weights: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Updated: updated})
+				return
+			}
+			fmt.Printf("✓ Recomputed weights for %d relationships\n", updated)
+		},
+	}
+
+	memoryBridgeCmd := &cobra.Command{
+		Use:   "bridge",
+		Short: "Sync person facts and the memory graph",
+		Long: `Link persons to Person entities (by shared identifiers, else an unambiguous
+name) and mirror facts between the two stores: person facts such as employer,
+location, and identifiers become relationships and aliases on the entity, and
+the entity's relationships and aliases become person facts. Each mirrored
+pair is recorded, so running this repeatedly never mirrors a fact back.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                          `json:"ok"`
+				Stats   *memory.PersonFactBridgeStats `json:"stats,omitempty"`
+				Message string                        `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			stats, err := memory.NewPersonFactBridge(database).Sync(context.Background())
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Bridge sync failed: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Stats: stats})
+				return
+			}
+			fmt.Printf("✓ Bridge sync complete\n")
+			fmt.Printf("  Persons linked:            %d\n", stats.PersonsLinked)
+			fmt.Printf("  Relationships from facts:  %d\n", stats.RelationshipsFromFacts)
+			fmt.Printf("  Aliases from facts:        %d\n", stats.AliasesFromFacts)
+			fmt.Printf("  Facts from graph:          %d\n", stats.FactsFromGraph)
+			fmt.Printf("  Relationships ended:       %d\n", stats.RelationshipsEnded)
+		},
+	}
+
+	var sharePreset string
+	var shareRelations []string
+	var shareEntityTypes []string
+	var shareRoots []string
+	var shareDepth int
+	var shareIncludeInvalidated bool
+	var shareIncludeIdentifiers bool
+	var shareIncludeSummaries bool
+	var shareFormat string
+	var shareOut string
+	var sharePlugin string
+	memoryShareCmd := &cobra.Command{
+		Use:   "share",
+		Short: "Export a scoped, redacted subgraph to share with another instance",
+		Long: `Export part of the graph as a standalone SQLite database or JSON bundle,
+for example to give a work assistant your work graph and nothing else.
+
+Only entities, name aliases and relationships are exported: never raw
+messages, episodes or mention provenance. Relationships are limited to
+--relation types (or a --preset such as "work") between entities of
+--entity-type types; with --root, only what is within --depth hops of the
+roots is kept. Emails, phones, handles and usernames are left out unless
+--include-identifiers is set, entity summaries (written from every fact,
+so they can leak out-of-scope facts) unless --include-summaries is set,
+and account numbers, passwords and IP addresses are never exported.
+
+The format follows the --out extension (.db, .sqlite: SQLite; anything
+else: JSON) unless --format is given. A SQLite export has the full schema,
+so the other instance can use it as its database directly. With --plugin,
+the JSON bundle is sent to an exporter plugin from the config instead.
+
+Examples:
+  mnemonic memory share --preset work --out work.db
+  mnemonic memory share --preset work --plugin notion
+  mnemonic memory share --relation WORKS_AT --relation WORKING_ON --out work.json
+  mnemonic memory share --preset work --root <entity-id> --depth 2 --out team.db`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK            bool   `json:"ok"`
+				Out           string `json:"out,omitempty"`
+				Plugin        string `json:"plugin,omitempty"`
+				Format        string `json:"format,omitempty"`
+				Entities      int    `json:"entities"`
+				Relationships int    `json:"relationships"`
+				Message       string `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+				}
+				os.Exit(1)
+			}
+
+			var scope memory.ShareScope
+			if sharePreset != "" {
+				preset, ok := memory.ShareScopePresets[sharePreset]
+				if !ok {
+					fail(fmt.Sprintf("Unknown preset %q (one of: work)", sharePreset))
+				}
+				scope = preset
+			}
+			if len(shareRelations) > 0 {
+				scope.RelationTypes = shareRelations
+			}
+			if len(shareEntityTypes) > 0 {
+				scope.EntityTypes = nil
+				for _, name := range shareEntityTypes {
+					et := memory.GetEntityTypeByName(name)
+					if et == nil {
+						fail(fmt.Sprintf("Unknown entity type: %s", name))
+					}
+					scope.EntityTypes = append(scope.EntityTypes, et.ID)
+				}
+			}
+			scope.Roots = shareRoots
+			scope.Depth = shareDepth
+			scope.IncludeInvalidated = shareIncludeInvalidated
+			scope.IncludeIdentifiers = shareIncludeIdentifiers
+			scope.IncludeSummaries = shareIncludeSummaries
+
+			var exporter *plugin.Plugin
+			switch {
+			case sharePlugin != "" && shareOut != "":
+				fail("Use --out or --plugin, not both")
+			case sharePlugin != "":
+				cfg, err := config.Load()
+				if err != nil {
+					fail(fmt.Sprintf("Failed to load config: %v", err))
+				}
+				if exporter, err = plugin.Get(cfg, sharePlugin, plugin.KindExporter); err != nil {
+					fail(err.Error())
+				}
+			case shareOut == "":
+				fail("--out or --plugin is required")
+			}
+
+			format := shareFormat
+			if exporter != nil {
+				format = "plugin"
+			} else if format == "" {
+				switch strings.ToLower(filepath.Ext(shareOut)) {
+				case ".db", ".sqlite", ".sqlite3":
+					format = "sqlite"
+				default:
+					format = "json"
+				}
+			}
+			if exporter == nil && format != "json" && format != "sqlite" {
+				fail(fmt.Sprintf("Unknown format %q (json or sqlite)", format))
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			ctx := context.Background()
+			bundle, err := memory.BuildShareBundle(ctx, database, scope)
+			if err != nil {
+				fail(fmt.Sprintf("Failed to build export: %v", err))
+			}
+
+			pluginMessage := ""
+			if exporter != nil {
+				raw, err := exporter.Call(ctx, map[string]interface{}{"bundle": bundle}, nil)
+				if err != nil {
+					fail(fmt.Sprintf("Failed to export: %v", err))
+				}
+				var res struct {
+					Message string `json:"message"`
+				}
+				_ = json.Unmarshal(raw, &res)
+				pluginMessage = res.Message
+			} else if format == "sqlite" {
+				dst, err := db.Create(shareOut)
+				if err != nil {
+					fail(fmt.Sprintf("Failed to create %s: %v", shareOut, err))
+				}
+				err = memory.WriteShareBundle(ctx, dst, bundle)
+				dst.Close()
+				if err != nil {
+					os.Remove(shareOut)
+					fail(fmt.Sprintf("Failed to write %s: %v", shareOut, err))
+				}
+			} else {
+				data, err := json.MarshalIndent(bundle, "", "  ")
+				if err != nil {
+					fail(fmt.Sprintf("Failed to encode export: %v", err))
+				}
+				if err := os.WriteFile(shareOut, append(data, '\n'), 0600); err != nil {
+					fail(fmt.Sprintf("Failed to write %s: %v", shareOut, err))
+				}
+			}
+
+			result := Result{
+				OK:            true,
+				Out:           shareOut,
+				Plugin:        sharePlugin,
+				Format:        format,
+				Entities:      len(bundle.Entities),
+				Relationships: len(bundle.Relationships),
+				Message:       pluginMessage,
+			}
+			if jsonOutput {
+				printJSON(result)
+				return
+			}
+			if exporter != nil {
+				fmt.Printf("Exported %d entities and %d relationships to plugin %s\n",
+					result.Entities, result.Relationships, sharePlugin)
+				if pluginMessage != "" {
+					fmt.Println(pluginMessage)
+				}
+				return
+			}
+			fmt.Printf("Exported %d entities and %d relationships to %s (%s)\n",
+				result.Entities, result.Relationships, shareOut, format)
+		},
+	}
+	memoryShareCmd.Flags().StringVar(&sharePreset, "preset", "", "Start from a named scope (work)")
+	memoryShareCmd.Flags().StringArrayVar(&shareRelations, "relation", nil, "Only export these relation types (repeatable; replaces the preset's)")
+	memoryShareCmd.Flags().StringArrayVar(&shareEntityTypes, "entity-type", nil, "Only export entities of these types (repeatable; replaces the preset's)")
+	memoryShareCmd.Flags().StringArrayVar(&shareRoots, "root", nil, "Only export what is within --depth hops of this entity ID (repeatable)")
+	memoryShareCmd.Flags().IntVar(&shareDepth, "depth", memory.DefaultSubgraphDepth, "Hops from --root to include")
+	memoryShareCmd.Flags().BoolVar(&shareIncludeInvalidated, "include-invalidated", false, "Also export relationships that have ended")
+	memoryShareCmd.Flags().BoolVar(&shareIncludeIdentifiers, "include-identifiers", false, "Also export emails, phones, handles and usernames")
+	memoryShareCmd.Flags().BoolVar(&shareIncludeSummaries, "include-summaries", false, "Also export entity summaries")
+	memoryShareCmd.Flags().StringVar(&shareFormat, "format", "", "json or sqlite (default: from the --out extension)")
+	memoryShareCmd.Flags().StringVarP(&shareOut, "out", "o", "", "File to write")
+	memoryShareCmd.Flags().StringVar(&sharePlugin, "plugin", "", "Send the export to this exporter plugin instead of a file")
+
+	calibrationCmd.AddCommand(calibrationReviewCmd)
+	calibrationCmd.AddCommand(calibrationReportCmd)
+	var skippedReason string
+	var skippedLimit int
+	memorySkippedCmd := &cobra.Command{
+		Use:   "skipped",
+		Short: "List episodes the quality gate skipped before extraction",
+		Long: `List episodes skipped by the extraction quality gate (link shares,
+emoji-only replies, a lone "ok") with their information score and why, lowest
+score first. Skipped episodes are scored again on every run, so they are
+extracted once the gate is turned off or its thresholds lowered.
+
+Reasons: too_little_text, mostly_links_or_emoji, short_messages.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK       bool                       `json:"ok"`
+				Episodes []memory.EpisodeProcessing `json:"episodes"`
+				Message  string                     `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			episodes, err := memory.NewProcessingLog(database).SkippedEpisodes(context.Background(), skippedReason, skippedLimit)
+			if err != nil {
+				res := Result{OK: false, Message: fmt.Sprintf("Failed to list skipped episodes: %v", err)}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				if episodes == nil {
+					episodes = []memory.EpisodeProcessing{}
+				}
+				printJSON(Result{OK: true, Episodes: episodes})
+				return
+			}
+			if len(episodes) == 0 {
+				fmt.Println("No skipped episodes")
+				return
+			}
+			for _, ep := range episodes {
+				score := 0.0
+				if ep.QualityScore != nil {
+					score = *ep.QualityScore
+				}
+				fmt.Printf("  %s  score %.2f  %-22s  %d chars  [%s %s]\n",
+					ep.EpisodeID, score, ep.SkipReason, ep.ContentChars, ep.Channel, ep.ThreadID)
+			}
+		},
+	}
+	memorySkippedCmd.Flags().StringVar(&skippedReason, "reason", "", "Only episodes skipped for this reason")
+	memorySkippedCmd.Flags().IntVar(&skippedLimit, "limit", 50, "Maximum episodes to list")
+
+	var replayRelation, replayFilter, replayQuery, replayChannel, replaySince, replayModel string
+	var replayMinScore float64
+	var replayLimit int
+	var replayDryRun bool
+	memoryReplayCmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Re-extract only the episodes likely to contain a new relation type",
+		Long: `Re-run extraction for one relation type on the episodes likely to mention
+it, after adding the type to the ontology, instead of reprocessing the whole
+corpus. Episodes are picked by a keyword filter (a case-insensitive regular
+expression over the episode text), by embedding similarity to --query, or
+both; an episode matching either is replayed.
+
+Only episodes extracted before are considered, and only relationships of the
+given type are kept, so existing facts are not duplicated. Use --dry-run to
+review the candidates before spending on extraction.
+
+Set memory.model_routing in the config file to send long and group-chat
+episodes to a stronger model than short 1:1 ones.
+
+Examples:
+  mnemonic memory replay --relation HAS_DIETARY_RESTRICTION --filter "vegan|allerg" --dry-run
+  mnemonic memory replay --relation HAS_DIETARY_RESTRICTION --filter "vegan|allerg" --query "food allergies and diets"`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                 `json:"ok"`
+				DryRun  bool                 `json:"dry_run,omitempty"`
+				Result  *memory.ReplayResult `json:"result,omitempty"`
+				Message string               `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				res := Result{OK: false, Message: msg}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			if replayRelation == "" {
+				fail("--relation is required")
+			}
+			if replayFilter == "" && replayQuery == "" {
+				fail("--filter or --query is required")
+			}
+			opts := memory.ReplayOptions{
+				Filter:         replayFilter,
+				EmbeddingModel: replayModel,
+				MinScore:       replayMinScore,
+				Channel:        replayChannel,
+				Limit:          replayLimit,
+			}
+			if replaySince != "" {
+				if opts.Since = parseSince(replaySince); opts.Since.IsZero() {
+					fail(fmt.Sprintf("invalid --since %q (use 30d, 12h or YYYY-MM-DD)", replaySince))
+				}
+			}
+			cfg, err := config.Load()
+			if err != nil {
+				fail(fmt.Sprintf("Failed to load config: %v", err))
+			}
+			pipelineConfig, err := memory.NewPipelineConfig(cfg.Memory)
+			if err != nil {
+				fail(fmt.Sprintf("Invalid memory config: %v", err))
+			}
+
+			apiKey := os.Getenv("GEMINI_API_KEY")
+			if apiKey == "" && (replayQuery != "" || !replayDryRun) {
+				exitWithError(errs.New(errs.ErrNoAPIKey, "GEMINI_API_KEY environment variable required for replay"))
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			ctx := context.Background()
+			var geminiClient *gemini.Client
+			if apiKey != "" {
+				geminiClient = gemini.NewClient(apiKey)
+				defer usage.Attach(database, geminiClient)()
+			}
+			if replayQuery != "" {
+				model := replayModel
+				if model == "" {
+					model = memory.DefaultEmbeddingModel
+				}
+				embedder := &search.GeminiEmbedder{Client: geminiClient}
+				if opts.QueryEmbedding, err = embedder.Embed(replayQuery, model); err != nil {
+					fail(fmt.Sprintf("Failed to embed query: %v", err))
+				}
+			}
+
+			text := func(ctx context.Context, episodeID string) (string, error) {
+				return compute.EpisodeText(ctx, database, episodeID)
+			}
+			candidates, scanned, err := memory.FindReplayCandidates(ctx, database, opts, text)
+			if err != nil {
+				fail(fmt.Sprintf("Failed to find episodes: %v", err))
+			}
+
+			pipeline := memory.NewMemoryPipeline(database, geminiClient, pipelineConfig)
+			var result *memory.ReplayResult
+			if replayDryRun {
+				// Validate the relation type without extracting anything
+				if result, err = pipeline.Replay(ctx, replayRelation, nil, text); err == nil {
+					result.Candidates = candidates
+				}
+			} else {
+				result, err = pipeline.Replay(ctx, replayRelation, candidates, text)
+			}
+			if result != nil {
+				result.Scanned = scanned
+				if result.Candidates == nil {
+					result.Candidates = []memory.ReplayCandidate{}
+				}
+			}
+			if err != nil {
+				if result == nil {
+					fail(err.Error())
+				}
+				fail(fmt.Sprintf("Replay stopped after %d episodes: %v", result.Processed+result.Failed, err))
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, DryRun: replayDryRun, Result: result})
+				return
+			}
+			if !memory.KnownRelationType(result.RelationType) {
+				fmt.Fprintf(os.Stderr, "Warning: %s is not in the ontology; extraction may not produce it\n", result.RelationType)
+			}
+			fmt.Printf("%d of %d processed episodes match\n", len(candidates), scanned)
+			for _, c := range candidates {
+				why := c.Match
+				if why == "" {
+					why = fmt.Sprintf("similarity %.2f", c.Score)
+				}
+				fmt.Printf("  %s  %s  [%s]  %s\n", c.EpisodeID, time.Unix(c.StartTime, 0).Format("2006-01-02"), c.Channel, why)
+			}
+			if replayDryRun || len(candidates) == 0 {
+				return
+			}
+			fmt.Printf("\nReplayed %d episodes for %s (%d failed): %d new relationships, $%.4f\n",
+				result.Processed, result.RelationType, result.Failed, result.NewRelationships, result.CostUSD)
+			for _, fact := range result.Facts {
+				fmt.Printf("  %s\n", fact)
+			}
+		},
+	}
+	memoryReplayCmd.Flags().StringVar(&replayRelation, "relation", "", "Relation type to extract (e.g. HAS_DIETARY_RESTRICTION)")
+	memoryReplayCmd.Flags().StringVar(&replayFilter, "filter", "", "Replay episodes whose text matches this regular expression (case-insensitive)")
+	memoryReplayCmd.Flags().StringVar(&replayQuery, "query", "", "Replay episodes whose embedding is similar to this text")
+	memoryReplayCmd.Flags().Float64Var(&replayMinScore, "min-score", memory.DefaultReplayMinScore, "Minimum similarity (0-1) for --query")
+	memoryReplayCmd.Flags().StringVar(&replayModel, "model", "", "Embedding model for --query (default: gemini-embedding-001)")
+	memoryReplayCmd.Flags().StringVar(&replayChannel, "channel", "", "Only episodes from this channel")
+	memoryReplayCmd.Flags().StringVar(&replaySince, "since", "", "Only episodes since (30d, 12h, or YYYY-MM-DD)")
+	memoryReplayCmd.Flags().IntVar(&replayLimit, "limit", 0, "Maximum episodes to replay (0 = all)")
+	memoryReplayCmd.Flags().BoolVar(&replayDryRun, "dry-run", false, "List the matching episodes without extracting")
+
+	var coMentionMin, coMentionLimit int
+	var coMentionDryRun bool
+	memoryCoMentionsCmd := &cobra.Command{
+		Use:   "co-mentions",
+		Short: "Suggest KNOWS edges for people often mentioned together",
+		Long: `Find pairs of people mentioned together in many episodes who have no
+relationship in the graph ("Casey and Dana co-occur in 40 episodes") and
+record a KNOWS suggestion for each. Episodes mentioning more than a dozen
+people (big group chats) are not counted, and the user is never paired.
+
+Suggestions wait in 'memory edge-suggestions' for accept or reject;
+accepting adds a low-confidence inferred edge, and rejected pairs are not
+suggested again. The co_mentions maintenance task runs this daily.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                    `json:"ok"`
+				Result  *memory.CoMentionResult `json:"result,omitempty"`
+				Message string                  `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			result, err := memory.AnalyzeCoMentions(context.Background(), database, memory.CoMentionOptions{
+				MinEpisodes: coMentionMin,
+				Limit:       coMentionLimit,
+				Record:      !coMentionDryRun,
+			})
+			if err != nil {
+				res := Result{OK: false, Message: fmt.Sprintf("Co-mention analysis failed: %v", err)}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Result: result})
+				return
+			}
+			fmt.Printf("%d co-mentioned pairs without an edge", len(result.Suggestions))
+			if !coMentionDryRun {
+				fmt.Printf(", %d new suggestions", result.Created)
+			}
+			fmt.Println()
+			for _, s := range result.Suggestions {
+				fmt.Printf("  %s and %s co-occur in %d episodes\n", s.EntityAName, s.EntityBName, s.CoMentions)
+			}
+			if result.Created > 0 {
+				fmt.Println("\nUse 'mnemonic memory edge-suggestions' to review them")
+			}
+		},
+	}
+	memoryCoMentionsCmd.Flags().IntVar(&coMentionMin, "min", memory.CoMentionMinEpisodes, "Minimum shared episodes")
+	memoryCoMentionsCmd.Flags().IntVar(&coMentionLimit, "limit", 0, "Maximum pairs (0 = all)")
+	memoryCoMentionsCmd.Flags().BoolVar(&coMentionDryRun, "dry-run", false, "Report pairs without recording suggestions")
+
+	var staleFactsAfter time.Duration
+	var staleFactsRelations []string
+	var staleFactsLimit int
+	var staleFactsDryRun bool
+	memoryStaleFactsCmd := &cobra.Command{
+		Use:   "stale-facts",
+		Short: "Queue aging facts for re-verification",
+		Long: `Find current facts of changeable relation types (LIVES_IN, WORKS_AT,
+DATING by default) that nothing has mentioned or confirmed for a long time
+(a year by default). A fact with a newer fact of the same type pointing
+elsewhere is ended where the newer one begins. The rest are queued in
+'memory verifications' to confirm or end, at most --limit pending at a
+time so the queue fills gradually.
+
+Defaults come from maintenance.stale_facts in the config; the stale_facts
+maintenance task runs this daily.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                    `json:"ok"`
+				Result  *memory.StaleFactResult `json:"result,omitempty"`
+				Message string                  `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				res := Result{OK: false, Message: msg}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			var opts memory.StaleFactOptions
+			if cfg, err := config.Load(); err == nil {
+				if opts, err = maintenance.StaleFactOptions(cfg.Maintenance.StaleFacts); err != nil {
+					fail(err.Error())
+				}
+			}
+			if cmd.Flags().Changed("after") {
+				opts.After = staleFactsAfter
+			}
+			if cmd.Flags().Changed("relation") {
+				opts.RelationTypes = staleFactsRelations
+			}
+			if cmd.Flags().Changed("limit") {
+				opts.Limit = staleFactsLimit
+			}
+			opts.DryRun = staleFactsDryRun
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			result, err := memory.FindStaleFacts(context.Background(), database, opts)
+			if err != nil {
+				fail(fmt.Sprintf("Stale fact check failed: %v", err))
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Result: result})
+				return
+			}
+			verb := ""
+			if result.DryRun {
+				verb = "would be "
+			}
+			fmt.Printf("%d stale facts: %d %squeued for verification, %d %ssuperseded by newer facts", len(result.Stale)+result.Deferred,
+				result.Queued, verb, result.Superseded, verb)
+			if result.Deferred > 0 {
+				fmt.Printf(", %d deferred (queue full)", result.Deferred)
+			}
+			fmt.Println()
+			for _, v := range result.Stale {
+				fmt.Printf("  [%s] %s (last seen %s)\n", v.Status, v.Fact, v.LastSeenAt)
+			}
+			if result.Queued > 0 && !result.DryRun {
+				fmt.Println("\nUse 'mnemonic memory verifications' to review them")
+			}
+		},
+	}
+	memoryStaleFactsCmd.Flags().DurationVar(&staleFactsAfter, "after", memory.DefaultStaleAfter, "Unmentioned this long counts as stale")
+	memoryStaleFactsCmd.Flags().StringSliceVar(&staleFactsRelations, "relation", memory.DefaultStaleRelationTypes, "Relation types to re-verify")
+	memoryStaleFactsCmd.Flags().IntVar(&staleFactsLimit, "limit", memory.DefaultStaleLimit, "Maximum pending verifications")
+	memoryStaleFactsCmd.Flags().BoolVar(&staleFactsDryRun, "dry-run", false, "Report stale facts without queueing or ending them")
+
+	var verificationsStatus string
+	var verificationsLimit int
+	memoryVerificationsCmd := &cobra.Command{
+		Use:   "verifications",
+		Short: "List stale facts queued for re-verification",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK            bool                      `json:"ok"`
+				Verifications []memory.FactVerification `json:"verifications"`
+				Message       string                    `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			verifications, err := memory.ListFactVerifications(context.Background(), database, verificationsStatus, verificationsLimit)
+			if err != nil {
+				res := Result{OK: false, Message: fmt.Sprintf("Failed to list fact verifications: %v", err)}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Verifications: verifications})
+				return
+			}
+			if len(verifications) == 0 {
+				fmt.Println("No fact verifications")
+				return
+			}
+			for _, v := range verifications {
+				fmt.Printf("%s  %s (last seen %s): still true?", v.ID, v.Fact, v.LastSeenAt)
+				if verificationsStatus != memory.FactVerificationPending {
+					fmt.Printf(" [%s]", v.Status)
+				}
+				fmt.Println()
+			}
+			if verificationsStatus == memory.FactVerificationPending {
+				fmt.Println("\nUse 'mnemonic memory verify-confirm <id>' or 'mnemonic memory verify-end <id>'")
+			}
+		},
+	}
+	memoryVerificationsCmd.Flags().StringVar(&verificationsStatus, "status", memory.FactVerificationPending, "Status to list (pending, confirmed, ended, superseded; empty for all)")
+	memoryVerificationsCmd.Flags().IntVar(&verificationsLimit, "limit", 50, "Maximum verifications to list")
+
+	memoryVerifyConfirmCmd := &cobra.Command{
+		Use:   "verify-confirm <id>",
+		Short: "Confirm a stale fact still holds",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool   `json:"ok"`
+				Message string `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			if err := memory.ConfirmFactVerification(context.Background(), database, args[0]); err != nil {
+				res := Result{OK: false, Message: fmt.Sprintf("Failed to confirm fact: %v", err)}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true})
+				return
+			}
+			fmt.Printf("Confirmed %s\n", args[0])
+		},
+	}
+
+	var verifyEndAt string
+	memoryVerifyEndCmd := &cobra.Command{
+		Use:   "verify-end <id>",
+		Short: "End a stale fact that no longer holds",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK           bool                       `json:"ok"`
+				Relationship *memory.EntityRelationship `json:"relationship,omitempty"`
+				Message      string                     `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			rel, err := memory.EndFactVerification(context.Background(), database, args[0], verifyEndAt)
+			if err != nil {
+				res := Result{OK: false, Message: fmt.Sprintf("Failed to end fact: %v", err)}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Relationship: rel})
+				return
+			}
+			fmt.Printf("Ended %s as of %s: %s\n", rel.ID, *rel.InvalidAt, rel.Fact)
+		},
+	}
+	memoryVerifyEndCmd.Flags().StringVar(&verifyEndAt, "at", "", "Date the fact stopped being true (YYYY-MM-DD; default today)")
+
+	var violationsStatus string
+	var violationsLimit int
+	memoryViolationsCmd := &cobra.Command{
+		Use:   "violations",
+		Short: "List facts that break a one-per-entity rule and need review",
+		Long: `List cardinality violations: a new fact of a relation type an entity may
+only have one of (a birthdate, a birthplace), recorded for review instead of
+replacing the fact it conflicts with. Rules come from memory.cardinality in
+the config on top of the defaults.
+
+Resolve one with 'mnemonic memory violation-resolve <id>'.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK         bool                          `json:"ok"`
+				Violations []memory.CardinalityViolation `json:"violations"`
+				Message    string                        `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			violations, err := memory.NewContradictionDetector(database).ListCardinalityViolations(context.Background(), violationsStatus, violationsLimit)
+			if err != nil {
+				res := Result{OK: false, Message: fmt.Sprintf("Failed to list violations: %v", err)}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				if violations == nil {
+					violations = []memory.CardinalityViolation{}
+				}
+				printJSON(Result{OK: true, Violations: violations})
+				return
+			}
+			if len(violations) == 0 {
+				fmt.Println("No cardinality violations")
+				return
+			}
+			for _, v := range violations {
+				fmt.Printf("%s  %s %s: %s conflicts with %s", v.ID, v.EntityID, v.RelationType, v.RelationshipID, v.ConflictingRelationshipID)
+				if violationsStatus != memory.ViolationStatusPending {
+					fmt.Printf(" [%s]", v.Status)
+				}
+				fmt.Println()
+			}
+			if violationsStatus == memory.ViolationStatusPending {
+				fmt.Println("\nUse 'mnemonic memory violation-resolve <id> --keep <relationship-id>' to end the other fact, or without --keep to keep both")
+			}
+		},
+	}
+	memoryViolationsCmd.Flags().StringVar(&violationsStatus, "status", memory.ViolationStatusPending, "Status to list (pending, resolved, dismissed; empty for all)")
+	memoryViolationsCmd.Flags().IntVar(&violationsLimit, "limit", 50, "Maximum violations to list")
+
+	var violationKeep string
+	memoryViolationResolveCmd := &cobra.Command{
+		Use:   "violation-resolve <id>",
+		Short: "Resolve a cardinality violation",
+		Long: `Resolve a cardinality violation. With --keep, the other of the two
+conflicting facts is ended; without it, the violation is dismissed and both
+facts are kept.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool   `json:"ok"`
+				Kept    string `json:"kept,omitempty"`
+				Message string `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			if err := memory.NewContradictionDetector(database).ResolveCardinalityViolation(context.Background(), args[0], violationKeep); err != nil {
+				res := Result{OK: false, Message: fmt.Sprintf("Failed to resolve violation: %v", err)}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Kept: violationKeep})
+				return
+			}
+			if violationKeep == "" {
+				fmt.Printf("Dismissed %s; both facts kept\n", args[0])
+				return
+			}
+			fmt.Printf("Resolved %s; kept %s\n", args[0], violationKeep)
+		},
+	}
+	memoryViolationResolveCmd.Flags().StringVar(&violationKeep, "keep", "", "Relationship to keep; the other fact is ended")
+
+	var mineAliasesDryRun bool
+	memoryMineAliasesCmd := &cobra.Command{
+		Use:   "mine-aliases",
+		Short: "Add name aliases from contact names and email signatures",
+		Long: `Add name aliases to entities linked to contacts, without any LLM call:
+each contact's display name (address book, chat, or the display part of an
+email address), and the name a sender signs their emails with ("Best,
+Bob") when at least two emails are signed that way. Aliases are stored
+with origin deterministic; names an entity already has are skipped.
+The alias_mining maintenance task runs this daily.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                      `json:"ok"`
+				Result  *memory.AliasMiningResult `json:"result,omitempty"`
+				Message string                    `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			result, err := memory.MineAliases(context.Background(), database, memory.AliasMiningOptions{DryRun: mineAliasesDryRun})
+			if err != nil {
+				res := Result{OK: false, Message: fmt.Sprintf("Alias mining failed: %v", err)}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Result: result})
+				return
+			}
+			verb := "Added"
+			if result.DryRun {
+				verb = "Would add"
+			}
+			fmt.Printf("%s %d aliases (%d contact names, %d email display names, %d signatures)\n", verb, len(result.Aliases),
+				result.BySource[memory.AliasSourceContactName], result.BySource[memory.AliasSourceEmailDisplay], result.BySource[memory.AliasSourceSignature])
+			for _, a := range result.Aliases {
+				fmt.Printf("  %s: %q (%s)\n", a.EntityName, a.Alias, a.Source)
+			}
+		},
+	}
+	memoryMineAliasesCmd.Flags().BoolVar(&mineAliasesDryRun, "dry-run", false, "Report aliases without adding them")
+
+	var edgeSuggestionsStatus string
+	var edgeSuggestionsLimit int
+	memoryEdgeSuggestionsCmd := &cobra.Command{
+		Use:   "edge-suggestions",
+		Short: "List suggested edges from co-mention analysis",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK          bool                    `json:"ok"`
+				Suggestions []memory.EdgeSuggestion `json:"suggestions"`
+				Message     string                  `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			suggestions, err := memory.ListEdgeSuggestions(context.Background(), database, edgeSuggestionsStatus, edgeSuggestionsLimit)
+			if err != nil {
+				res := Result{OK: false, Message: fmt.Sprintf("Failed to list edge suggestions: %v", err)}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Suggestions: suggestions})
+				return
+			}
+			if len(suggestions) == 0 {
+				fmt.Println("No edge suggestions")
+				return
+			}
+			for _, s := range suggestions {
+				fmt.Printf("%s  %s and %s co-occur in %d episodes: add %s?", s.ID, s.EntityAName, s.EntityBName, s.CoMentions, s.RelationType)
+				if edgeSuggestionsStatus != memory.EdgeSuggestionPending {
+					fmt.Printf(" [%s]", s.Status)
+				}
+				fmt.Println()
+			}
+			if edgeSuggestionsStatus == memory.EdgeSuggestionPending {
+				fmt.Println("\nUse 'mnemonic memory edge-accept <id>' or 'mnemonic memory edge-reject <id>'")
+			}
+		},
+	}
+	memoryEdgeSuggestionsCmd.Flags().StringVar(&edgeSuggestionsStatus, "status", memory.EdgeSuggestionPending, "Status to list (pending, accepted, rejected; empty for all)")
+	memoryEdgeSuggestionsCmd.Flags().IntVar(&edgeSuggestionsLimit, "limit", 50, "Maximum suggestions to list")
+
+	memoryEdgeAcceptCmd := &cobra.Command{
+		Use:   "edge-accept <id>",
+		Short: "Add a suggested edge as a low-confidence inferred fact",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK           bool                       `json:"ok"`
+				Relationship *memory.EntityRelationship `json:"relationship,omitempty"`
+				Message      string                     `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			rel, err := memory.AcceptEdgeSuggestion(context.Background(), database, args[0])
+			if err != nil {
+				res := Result{OK: false, Message: fmt.Sprintf("Failed to accept edge suggestion: %v", err)}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Relationship: rel})
+				return
+			}
+			fmt.Printf("Added %s: %s\n", rel.ID, rel.Fact)
+		},
+	}
+
+	memoryEdgeRejectCmd := &cobra.Command{
+		Use:   "edge-reject <id>",
+		Short: "Reject a suggested edge; the pair is not suggested again",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool   `json:"ok"`
+				Message string `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			if err := memory.RejectEdgeSuggestion(context.Background(), database, args[0]); err != nil {
+				res := Result{OK: false, Message: fmt.Sprintf("Failed to reject edge suggestion: %v", err)}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true})
+				return
+			}
+			fmt.Printf("Rejected edge suggestion %s\n", args[0])
+		},
+	}
+
+	memoryCmd.AddCommand(calibrationCmd)
+	memoryCmd.AddCommand(memoryDupesCmd)
+	memoryCmd.AddCommand(memorySharedAliasesCmd)
+	memoryCmd.AddCommand(memoryMergeCmd)
+	memoryCmd.AddCommand(memoryGraphCmd)
+	memoryCmd.AddCommand(memoryShareCmd)
+	memoryCmd.AddCommand(memoryReweightCmd)
+	memoryCmd.AddCommand(memoryBridgeCmd)
+	memoryCmd.AddCommand(memorySkippedCmd)
+	memoryCmd.AddCommand(memoryReplayCmd)
+	memoryCmd.AddCommand(memoryCoMentionsCmd)
+	memoryCmd.AddCommand(memoryStaleFactsCmd)
+	memoryCmd.AddCommand(memoryVerificationsCmd)
+	memoryCmd.AddCommand(memoryVerifyConfirmCmd)
+	memoryCmd.AddCommand(memoryVerifyEndCmd)
+	memoryCmd.AddCommand(memoryViolationsCmd)
+	memoryCmd.AddCommand(memoryViolationResolveCmd)
+	memoryCmd.AddCommand(memoryMineAliasesCmd)
+	memoryCmd.AddCommand(memoryEdgeSuggestionsCmd)
+	memoryCmd.AddCommand(memoryEdgeAcceptCmd)
+	memoryCmd.AddCommand(memoryEdgeRejectCmd)
+	rootCmd.AddCommand(memoryCmd)
+
+	// entity command - manual edits to memory graph entities
+	entityCmd := &cobra.Command{
+		Use:   "entity",
+		Short: "Show and edit memory graph entities",
+	}
+
+	var showType string
+	var showPickFirst bool
+	entityShowCmd := &cobra.Command{
+		Use:   "show <name-or-id>",
+		Short: "Show an entity with its aliases and facts",
+		Long: `Show an entity by ID or name. When a name matches several entities
+(two people called Chris), they are listed with their type, strongest facts
+and when they were last mentioned, and you pick one. Exact name matches win
+over partial ones.
+
+Without a terminal, or with --json, an ambiguous name lists the candidates
+and exits non-zero; --pick-first takes the most mentioned one instead.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK            bool                        `json:"ok"`
+				Entity        *memory.Entity              `json:"entity,omitempty"`
+				TypeName      string                      `json:"type_name,omitempty"`
+				Aliases       []memory.EntityAlias        `json:"aliases,omitempty"`
+				Relationships []memory.EntityRelationship `json:"relationships,omitempty"`
+				CurrentFacts  []memory.CurrentFact        `json:"current_facts,omitempty"`
+				Candidates    []memory.EntityCandidate    `json:"candidates,omitempty"`
+				Message       string                      `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+				}
+				os.Exit(1)
+			}
+
+			var typeID *int
+			if showType != "" {
+				et := memory.GetEntityTypeByName(showType)
+				if et == nil {
+					fail(fmt.Sprintf("Unknown entity type %q (one of %s)", showType, strings.Join(memory.EntityTypeNames(), ", ")))
+				}
+				typeID = &et.ID
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			ctx := context.Background()
+			engine := memory.NewQueryEngine(database)
+			defer engine.Close()
+
+			picked, candidates, err := pickEntity(ctx, engine, args[0], typeID, showPickFirst)
+			if err != nil {
+				fail(err.Error())
+			}
+			if picked == nil {
+				msg := fmt.Sprintf("%q matches %d entities; pass an ID or --pick-first", args[0], len(candidates))
+				if jsonOutput {
+					printJSON(Result{OK: false, Candidates: candidates, Message: msg})
+				} else {
+					printEntityCandidates(candidates)
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+				}
+				os.Exit(1)
+			}
+
+			aliases, err := engine.GetEntityAliases(ctx, picked.ID)
+			if err != nil {
+				fail(fmt.Sprintf("Failed to load aliases: %v", err))
+			}
+			relationships, err := engine.GetEntityRelationships(ctx, picked.ID, memory.DefaultQueryOptions())
+			if err != nil {
+				fail(fmt.Sprintf("Failed to load relationships: %v", err))
+			}
+			current, err := engine.GetCurrentFacts(ctx, picked.ID)
+			if err != nil {
+				fail(fmt.Sprintf("Failed to load current facts: %v", err))
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Entity: &picked.Entity, TypeName: picked.TypeName, Aliases: aliases, Relationships: relationships, CurrentFacts: current})
+				return
+			}
+			fmt.Printf("%s (%s)  [%s]\n", picked.CanonicalName, picked.TypeName, picked.ID)
+			if picked.Summary != nil && *picked.Summary != "" {
+				fmt.Printf("\n%s\n", *picked.Summary)
+			}
+			if len(aliases) > 0 {
+				fmt.Println("\nAliases:")
+				for _, a := range aliases {
+					fmt.Printf("  %-8s %s\n", a.AliasType, a.Alias)
+				}
+			}
+			if len(current) > 0 {
+				fmt.Println("\nCurrently:")
+				for _, f := range current {
+					fmt.Printf("  %-16s %s\n", f.RelationType, f.Fact)
+				}
+			}
+			if len(relationships) > 0 {
+				fmt.Println("\nFacts:")
+				for _, r := range relationships {
+					fmt.Printf("  %s\n", r.Fact)
+				}
+			}
+		},
+	}
+	entityShowCmd.Flags().StringVar(&showType, "type", "", "Only match entities of this type (Person, Organization, ...)")
+	entityShowCmd.Flags().BoolVar(&showPickFirst, "pick-first", false, "When the name is ambiguous, take the most mentioned match")
+
+	var renameLock bool
+	entityRenameCmd := &cobra.Command{
+		Use:   "rename <entity-id> <name>",
+		Short: "Set an entity's canonical name",
+		Long: `Set an entity's canonical name, keeping the old name as an alias.
+With --lock, merges and normalization never rename the entity again
+(see 'entity lock').`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool               `json:"ok"`
+				Change  *memory.NameChange `json:"change,omitempty"`
+				Locked  bool               `json:"locked,omitempty"`
+				Message string             `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			change, err := memory.RenameEntity(context.Background(), database, args[0], args[1], renameLock)
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to rename entity: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Change: change, Locked: renameLock})
+				return
+			}
+			if change == nil {
+				fmt.Printf("Entity %s is already named %q\n", args[0], args[1])
+			} else {
+				fmt.Printf("Renamed %q -> %q\n", change.OldName, change.NewName)
+			}
+			if renameLock {
+				fmt.Println("Name locked against automated renames")
+			}
+		},
+	}
+	entityRenameCmd.Flags().BoolVar(&renameLock, "lock", false, "Prevent merges and normalization from renaming the entity")
+
+	var unlockName bool
+	entityLockCmd := &cobra.Command{
+		Use:   "lock <entity-id>",
+		Short: "Lock an entity's name against automated renames",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool   `json:"ok"`
+				Locked  bool   `json:"locked"`
+				Message string `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			if err := memory.SetEntityNameLocked(context.Background(), database, args[0], !unlockName); err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to update entity: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Locked: !unlockName})
+				return
+			}
+			if unlockName {
+				fmt.Printf("Unlocked name of %s\n", args[0])
+			} else {
+				fmt.Printf("Locked name of %s\n", args[0])
+			}
+		},
+	}
+	entityLockCmd.Flags().BoolVar(&unlockName, "unlock", false, "Allow automated renames again")
+
+	entityNamesCmd := &cobra.Command{
+		Use:   "names <entity-id>",
+		Short: "Show an entity's name history",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                `json:"ok"`
+				History []memory.NameChange `json:"history"`
+				Message string              `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			history, err := memory.GetNameHistory(context.Background(), database, args[0])
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to get name history: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, History: history})
+				return
+			}
+			if len(history) == 0 {
+				fmt.Println("No name changes")
+				return
+			}
+			for _, c := range history {
+				by := c.Reason
+				if c.SourceEntityID != nil {
+					by += " from " + *c.SourceEntityID
+				}
+				fmt.Printf("  %s  %q -> %q  (%s, %s)\n", c.CreatedAt, c.OldName, c.NewName, by, c.ChangedBy)
+			}
+		},
+	}
+
+	var changesSince int64
+	entityChangesCmd := &cobra.Command{
+		Use:   "changes <entity-id>",
+		Short: "Show an entity's change feed",
+		Long: `Show changes to an entity in the order they happened: relationships
+added or invalidated, merges, summary updates and renames.
+
+Each change has a sequence number. Pass the last one seen with --since to get
+only what is new.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                  `json:"ok"`
+				Changes []memory.EntityChange `json:"changes"`
+				Cursor  int64                 `json:"cursor"`
+				Message string                `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			engine := memory.NewQueryEngine(database)
+			defer engine.Close()
+
+			changes, cursor, err := engine.GetEntityChanges(context.Background(), args[0], changesSince)
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to get changes: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if changes == nil {
+				changes = []memory.EntityChange{}
+			}
+			if jsonOutput {
+				printJSON(Result{OK: true, Changes: changes, Cursor: cursor})
+				return
+			}
+			if len(changes) == 0 {
+				fmt.Println("No changes")
+				return
+			}
+			for _, c := range changes {
+				detail := c.Fact
+				if detail == "" && c.OtherName != "" {
+					detail = c.OtherName
+				}
+				if detail == "" {
+					detail = c.RefID
+				}
+				fmt.Printf("  %6d  %s  %-24s %s\n", c.Seq, c.CreatedAt, c.Type, detail)
+			}
+			if len(changes) == memory.EntityChangesPageSize {
+				fmt.Printf("\nMore changes: run again with --since %d\n", cursor)
+			}
+		},
+	}
+	entityChangesCmd.Flags().Int64Var(&changesSince, "since", 0, "Only changes after this sequence number")
+
+	entityRetypeCmd := &cobra.Command{
+		Use:   "retype <entity-id> <entity-type>",
+		Short: "Set an entity's type",
+		Long: `Set an entity's type (Person, Organization, Location, ...), e.g. when
+extraction typed a colleague as a company. The type is locked: the type
+checker never flags the entity again. Pending type flags on the entity
+are closed.
+
+Examples:
+  mnemonic entity retype 3f1c... Person`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool               `json:"ok"`
+				Change  *memory.TypeChange `json:"change,omitempty"`
+				Message string             `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+				}
+				os.Exit(1)
+			}
+
+			et := memory.GetEntityTypeByName(args[1])
+			if et == nil {
+				fail(fmt.Sprintf("Unknown entity type %q (one of %s)", args[1], strings.Join(memory.EntityTypeNames(), ", ")))
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			change, err := memory.RetypeEntity(context.Background(), database, args[0], et.ID)
+			if err != nil {
+				fail(fmt.Sprintf("Failed to retype entity: %v", err))
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Change: change})
+				return
+			}
+			fmt.Printf("Retyped %s: %s -> %s\n", args[0], entityTypeName(change.OldTypeID), et.Name)
+			if change.FlagsResolved > 0 {
+				fmt.Printf("Closed %d pending type flag(s)\n", change.FlagsResolved)
+			}
+		},
+	}
+
+	var typeCheckDryRun bool
+	entityTypeCheckCmd := &cobra.Command{
+		Use:   "type-check",
+		Short: "Flag entities whose relationships don't fit their type",
+		Long: `Score every entity's relationships against the types each relation
+expects (SPOUSE_OF links people, WORKS_AT points at an organization, ...)
+and flag entities where most edges expect another type. Flags wait in
+'entity type-flags' for accept or reject; rejected suggestions are not
+made again. The entity_types maintenance task runs this daily.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                    `json:"ok"`
+				Result  *memory.TypeCheckResult `json:"result,omitempty"`
+				Message string                  `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			result, err := memory.NewTypeChecker(database).Check(context.Background(), !typeCheckDryRun)
+			if err != nil {
+				res := Result{OK: false, Message: fmt.Sprintf("Type check failed: %v", err)}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Result: result})
+				return
+			}
+			fmt.Printf("Checked %d entities: %d inconsistent", result.Checked, len(result.Flagged))
+			if !typeCheckDryRun {
+				fmt.Printf(", %d new flags", result.Created)
+			}
+			fmt.Println()
+			for _, f := range result.Flagged {
+				fmt.Printf("  %s [%s -> %s] %d/%d edges (%s)  %s\n", f.CanonicalName, entityTypeName(f.CurrentTypeID),
+					entityTypeName(f.SuggestedTypeID), f.Evidence, f.Total, strings.Join(f.RelationTypes, ", "), f.EntityID)
+			}
+			if result.Created > 0 {
+				fmt.Println("\nUse 'mnemonic entity type-flags' to review them")
+			}
+		},
+	}
+	entityTypeCheckCmd.Flags().BoolVar(&typeCheckDryRun, "dry-run", false, "Report inconsistent entities without recording flags")
+
+	var typeFlagsStatus string
+	var typeFlagsLimit int
+	entityTypeFlagsCmd := &cobra.Command{
+		Use:   "type-flags",
+		Short: "List entity type flags",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool              `json:"ok"`
+				Flags   []memory.TypeFlag `json:"flags"`
+				Message string            `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			flags, err := memory.ListTypeFlags(context.Background(), database, typeFlagsStatus, typeFlagsLimit)
+			if err != nil {
+				res := Result{OK: false, Message: fmt.Sprintf("Failed to list type flags: %v", err)}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Flags: flags})
+				return
+			}
+			if len(flags) == 0 {
+				fmt.Println("No type flags")
+				return
+			}
+			for _, f := range flags {
+				fmt.Printf("%s  %s\n", f.ID, f.CanonicalName)
+				fmt.Printf("  %s -> %s: %d of %d edges (%s)", entityTypeName(f.CurrentTypeID), entityTypeName(f.SuggestedTypeID),
+					f.Evidence, f.Total, strings.Join(f.RelationTypes, ", "))
+				if typeFlagsStatus != memory.TypeFlagStatusPending {
+					fmt.Printf(" [%s]", f.Status)
+				}
+				fmt.Println()
+			}
+			if typeFlagsStatus == memory.TypeFlagStatusPending {
+				fmt.Println("\nUse 'mnemonic entity type-accept <flag-id>' or 'mnemonic entity type-reject <flag-id>'")
+			}
+		},
+	}
+	entityTypeFlagsCmd.Flags().StringVar(&typeFlagsStatus, "status", memory.TypeFlagStatusPending, "Status to list (pending, accepted, rejected, resolved; empty for all)")
+	entityTypeFlagsCmd.Flags().IntVar(&typeFlagsLimit, "limit", 50, "Maximum flags to list")
+
+	entityTypeAcceptCmd := &cobra.Command{
+		Use:   "type-accept <flag-id>",
+		Short: "Retype a flagged entity to the suggested type",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool               `json:"ok"`
+				Change  *memory.TypeChange `json:"change,omitempty"`
+				Message string             `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			change, err := memory.AcceptTypeFlag(context.Background(), database, args[0])
+			if err != nil {
+				res := Result{OK: false, Message: fmt.Sprintf("Failed to accept type flag: %v", err)}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Change: change})
+				return
+			}
+			fmt.Printf("Retyped %s: %s -> %s\n", change.EntityID, entityTypeName(change.OldTypeID), entityTypeName(change.NewTypeID))
+		},
+	}
+
+	entityTypeRejectCmd := &cobra.Command{
+		Use:   "type-reject <flag-id>",
+		Short: "Keep a flagged entity's type",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool   `json:"ok"`
+				Message string `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			if err := memory.RejectTypeFlag(context.Background(), database, args[0]); err != nil {
+				res := Result{OK: false, Message: fmt.Sprintf("Failed to reject type flag: %v", err)}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true})
+				return
+			}
+			fmt.Printf("Rejected type flag %s\n", args[0])
+		},
+	}
+
+	entityCmd.AddCommand(entityShowCmd)
+	entityCmd.AddCommand(entityRenameCmd)
+	entityCmd.AddCommand(entityLockCmd)
+	entityCmd.AddCommand(entityNamesCmd)
+	entityCmd.AddCommand(entityChangesCmd)
+	entityCmd.AddCommand(entityRetypeCmd)
+	entityCmd.AddCommand(entityTypeCheckCmd)
+	entityCmd.AddCommand(entityTypeFlagsCmd)
+	entityCmd.AddCommand(entityTypeAcceptCmd)
+	entityCmd.AddCommand(entityTypeRejectCmd)
+	rootCmd.AddCommand(entityCmd)
+
+	// fact command - hand-entered ground truth in the memory graph
+	factCmd := &cobra.Command{
+		Use:   "fact",
+		Short: "Add and correct memory graph facts by hand",
+	}
+
+	printFact := func(rel *memory.EntityRelationship) {
+		target := ""
+		if rel.TargetName != nil {
+			target = *rel.TargetName
+		} else if rel.TargetLiteral != nil {
+			target = *rel.TargetLiteral
+		}
+		fmt.Printf("%s  %s %s %s\n", rel.ID, rel.SourceName, rel.RelationType, target)
+		fmt.Printf("  %s\n", rel.Fact)
+		if rel.ValidAt != nil || rel.InvalidAt != nil {
+			from, to := "?", "now"
+			if rel.ValidAt != nil {
+				from = *rel.ValidAt
+			}
+			if rel.InvalidAt != nil {
+				to = *rel.InvalidAt
+			}
+			fmt.Printf("  valid %s .. %s\n", from, to)
+		}
+	}
+
+	var factAddValidAt, factAddInvalidAt, factAddText, factAddSourceType, factAddTargetType string
+	var factAddNewType bool
+	factAddCmd := &cobra.Command{
+		Use:   "add <source> <relation-type> <target>",
+		Short: "Add a fact as ground truth",
+		Long: `Add a fact to the memory graph by hand, with origin manual and full
+confidence. Source and target are entity IDs or exact names; for literal
+relation types (BORN_ON, HAS_SIZE, ...) the target is the value.
+
+The fact is validated against the ontology: the relation type must be
+known (or --new-type given), endpoints must have the types the relation
+expects (WORKS_AT points at an Organization), and dates must be ISO
+(YYYY, YYYY-MM, YYYY-MM-DD). Endpoints that don't exist yet are created;
+their type comes from the relation type or --source-type/--target-type.
+Like extracted facts, a new employer or home supersedes the current one.
+
+Examples:
+  mnemonic fact add Tyler WORKS_AT Anthropic --valid-at 2026-01
+  mnemonic fact add Tyler BORN_ON 1990-04-12`,
+		Args: cobra.ExactArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                     `json:"ok"`
+				Result  *memory.ManualFactResult `json:"result,omitempty"`
+				Message string                   `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+				}
+				os.Exit(1)
+			}
+
+			f := memory.ManualFact{
+				Source:       args[0],
+				RelationType: args[1],
+				Target:       args[2],
+				Fact:         factAddText,
+				ValidAt:      factAddValidAt,
+				InvalidAt:    factAddInvalidAt,
+				AllowNewType: factAddNewType,
+			}
+			for _, t := range []struct {
+				name string
+				id   *int
+			}{{factAddSourceType, &f.SourceTypeID}, {factAddTargetType, &f.TargetTypeID}} {
+				if t.name == "" {
+					continue
+				}
+				et := memory.GetEntityTypeByName(t.name)
+				if et == nil {
+					fail(fmt.Sprintf("Unknown entity type %q (one of %s)", t.name, strings.Join(memory.EntityTypeNames(), ", ")))
+				}
+				*t.id = et.ID
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			res, err := memory.AddManualFact(context.Background(), database, f)
+			if err != nil {
+				fail(fmt.Sprintf("Failed to add fact: %v", err))
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Result: res})
+				return
+			}
+			if res.Existing {
+				fmt.Println("Fact already known; marked manual")
+			} else {
+				fmt.Println("Added fact")
+			}
+			printFact(res.Relationship)
+			if len(res.CreatedEntities) > 0 {
+				fmt.Printf("Created %d entities: %s\n", len(res.CreatedEntities), strings.Join(res.CreatedEntities, ", "))
+			}
+			if len(res.Invalidated) > 0 {
+				fmt.Printf("Superseded %d fact(s): %s\n", len(res.Invalidated), strings.Join(res.Invalidated, ", "))
+			}
+			if res.ViolationsFlagged > 0 {
+				fmt.Printf("Flagged %d conflicting fact(s) for review\n", res.ViolationsFlagged)
+			}
+		},
+	}
+	factAddCmd.Flags().StringVar(&factAddValidAt, "valid-at", "", "When the fact became true (YYYY, YYYY-MM or YYYY-MM-DD)")
+	factAddCmd.Flags().StringVar(&factAddInvalidAt, "invalid-at", "", "When the fact stopped being true")
+	factAddCmd.Flags().StringVar(&factAddText, "fact", "", "Fact text (default: generated, e.g. \"Tyler works at Anthropic\")")
+	factAddCmd.Flags().StringVar(&factAddSourceType, "source-type", "", "Type of the source if it must be created")
+	factAddCmd.Flags().StringVar(&factAddTargetType, "target-type", "", "Type of the target if it must be created")
+	factAddCmd.Flags().BoolVar(&factAddNewType, "new-type", false, "Allow a relation type the ontology does not know")
+
+	var factEditText, factEditTarget, factEditValidAt, factEditInvalidAt string
+	factEditCmd := &cobra.Command{
+		Use:   "edit <fact-id>",
+		Short: "Correct a fact",
+		Long: `Correct a fact's text, target or dates. The fact is marked manual.
+An empty --valid-at or --invalid-at clears the date. A new --target must
+already exist and fit the relation type.
+
+Examples:
+  mnemonic fact edit 3f1c... --valid-at 2025-11
+  mnemonic fact edit 3f1c... --target "Anthropic" --fact "Tyler works at Anthropic"`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                       `json:"ok"`
+				Fact    *memory.EntityRelationship `json:"fact,omitempty"`
+				Message string                     `json:"message,omitempty"`
+			}
+
+			var edit memory.FactEdit
+			for _, f := range []struct {
+				flag  string
+				value *string
+				dest  **string
+			}{
+				{"fact", &factEditText, &edit.Fact},
+				{"target", &factEditTarget, &edit.Target},
+				{"valid-at", &factEditValidAt, &edit.ValidAt},
+				{"invalid-at", &factEditInvalidAt, &edit.InvalidAt},
+			} {
+				if cmd.Flags().Changed(f.flag) {
+					*f.dest = f.value
+				}
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			rel, err := memory.EditFact(context.Background(), database, args[0], edit)
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to edit fact: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Fact: rel})
+				return
+			}
+			fmt.Println("Edited fact")
+			printFact(rel)
+		},
+	}
+	factEditCmd.Flags().StringVar(&factEditText, "fact", "", "New fact text")
+	factEditCmd.Flags().StringVar(&factEditTarget, "target", "", "New target entity (ID or exact name) or literal value")
+	factEditCmd.Flags().StringVar(&factEditValidAt, "valid-at", "", "When the fact became true (empty clears)")
+	factEditCmd.Flags().StringVar(&factEditInvalidAt, "invalid-at", "", "When the fact stopped being true (empty clears)")
+
+	var factInvalidateAt string
+	factInvalidateCmd := &cobra.Command{
+		Use:   "invalidate <fact-id>",
+		Short: "Mark a fact as no longer true",
+		Long: `Set a current fact's invalid_at, e.g. when someone left a job. The fact
+stays in history; queries as of earlier dates still see it.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                       `json:"ok"`
+				Fact    *memory.EntityRelationship `json:"fact,omitempty"`
+				Message string                     `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			rel, err := memory.InvalidateFact(context.Background(), database, args[0], factInvalidateAt)
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to invalidate fact: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Fact: rel})
+				return
+			}
+			fmt.Println("Invalidated fact")
+			printFact(rel)
+		},
+	}
+	factInvalidateCmd.Flags().StringVar(&factInvalidateAt, "at", "", "When the fact stopped being true (default: today)")
+
+	var factExportMaxConfidence float64
+	var factExportLimit int
+	var factExportIncludeReviewed bool
+	var factExportOut string
+	factExportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export low-confidence facts to CSV for review",
+		Long: `Write facts below --max-confidence to CSV, least confident first, for
+review in a spreadsheet. Facts already reviewed and manual facts are left
+out. Fill in the action column (keep, fix or delete), edit the target,
+fact, valid_at or invalid_at cells of facts to fix, then apply the file
+with 'fact import'.
+
+Examples:
+  mnemonic fact export --out review.csv
+  mnemonic fact export --max-confidence 0.5 --limit 200 > review.csv`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			out := os.Stdout
+			if factExportOut != "" {
+				f, err := os.Create(factExportOut)
+				if err != nil {
+					exitWithError(fmt.Errorf("Failed to create %s: %w", factExportOut, err))
+				}
+				defer f.Close()
+				out = f
+			}
+
+			n, err := memory.ExportFactsForReview(context.Background(), database, out, memory.FactReviewExportOptions{
+				MaxConfidence:   factExportMaxConfidence,
+				Limit:           factExportLimit,
+				IncludeReviewed: factExportIncludeReviewed,
+			})
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to export facts: %w", err))
+			}
+			if factExportOut != "" {
+				fmt.Fprintf(os.Stderr, "Exported %d facts to %s\n", n, factExportOut)
+			}
+		},
+	}
+	factExportCmd.Flags().Float64Var(&factExportMaxConfidence, "max-confidence", memory.DefaultReviewMaxConfidence, "Export facts below this confidence")
+	factExportCmd.Flags().IntVar(&factExportLimit, "limit", 500, "Maximum facts to export (0 = all)")
+	factExportCmd.Flags().BoolVar(&factExportIncludeReviewed, "include-reviewed", false, "Also export facts labeled in an earlier review")
+	factExportCmd.Flags().StringVarP(&factExportOut, "out", "o", "", "Write to a file instead of stdout")
+
+	var factImportDryRun bool
+	factImportCmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Apply a reviewed fact CSV",
+		Long: `Apply a spreadsheet from 'fact export' in bulk. Rows marked keep are
+labeled correct; rows marked fix are corrected from their edited cells and
+become manual facts; rows marked delete are removed. Rows with a blank
+action are skipped. Labels feed 'memory calibration report'.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                           `json:"ok"`
+				Result  *memory.FactReviewImportResult `json:"result,omitempty"`
+				Message string                         `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+				}
+				os.Exit(1)
+			}
+
+			f, err := os.Open(args[0])
+			if err != nil {
+				fail(fmt.Sprintf("Failed to open %s: %v", args[0], err))
+			}
+			defer f.Close()
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			res, err := memory.ImportFactReview(context.Background(), database, f, factImportDryRun)
+			if err != nil {
+				fail(fmt.Sprintf("Failed to import review: %v", err))
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: len(res.Errors) == 0, Result: res})
+				if len(res.Errors) > 0 {
+					os.Exit(1)
+				}
+				return
+			}
+			verb := "Applied"
+			if res.DryRun {
+				verb = "Would apply"
+			}
+			fmt.Printf("%s %d rows: %d kept, %d fixed, %d deleted, %d skipped\n",
+				verb, res.Rows, res.Kept, res.Fixed, res.Deleted, res.Skipped)
+			for _, e := range res.Errors {
+				fmt.Fprintf(os.Stderr, "  line %d (%s): %s\n", e.Line, e.ID, e.Message)
+			}
+			if len(res.Errors) > 0 {
+				fmt.Fprintf(os.Stderr, "Error: %d rows failed\n", len(res.Errors))
+				os.Exit(1)
+			}
+		},
+	}
+	factImportCmd.Flags().BoolVar(&factImportDryRun, "dry-run", false, "Check the file without applying it")
+
+	var factNormalizeDryRun bool
+	var factNormalizeLimit int
+	factNormalizeCmd := &cobra.Command{
+		Use:   "normalize",
+		Short: "Rewrite extracted facts into canonical form",
+		Long: `Rewrite extracted facts into their relation type's template, such as
+"Tyler works at Anthropic since 2024-01". New facts are stored this way;
+this pass converts older free-text facts. The original wording stays on
+each fact's episode mentions. Manual facts are never rewritten.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                        `json:"ok"`
+				Result  *memory.FactNormalizeResult `json:"result,omitempty"`
+				Message string                      `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			res, err := memory.NormalizeFacts(context.Background(), database, memory.FactNormalizeOptions{
+				DryRun: factNormalizeDryRun,
+				Limit:  factNormalizeLimit,
+			})
+			if err != nil {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: err.Error()})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Result: res})
+				return
+			}
+			for _, rw := range res.Rewrites {
+				fmt.Printf("%s  %s\n  - %s\n  + %s\n", rw.RelationshipID, rw.RelationType, rw.Before, rw.After)
+			}
+			verb := "Rewrote"
+			if res.DryRun {
+				verb = "Would rewrite"
+			}
+			fmt.Printf("%s %d of %d facts\n", verb, len(res.Rewrites), res.Checked)
+		},
+	}
+	factNormalizeCmd.Flags().BoolVar(&factNormalizeDryRun, "dry-run", false, "Show the rewrites without applying them")
+	factNormalizeCmd.Flags().IntVar(&factNormalizeLimit, "limit", 0, "Rewrite at most this many facts (0 = all)")
+
+	var factInferDryRun bool
+	factInferCmd := &cobra.Command{
+		Use:   "infer",
+		Short: "Derive facts from inference rules",
+		Long: `Apply inference rules to the current facts and store what they imply,
+such as a GRANDPARENT_OF fact from two PARENT_OF facts. Inferred facts are
+marked with origin 'inferred' and keep the premises they were derived
+from ('fact premises'). Inferred facts whose premises no longer hold are
+ended; invalidating or deleting a premise retracts them right away.
+
+Rules come from maintenance.inference in the config (grandparent,
+shared_parent and sibling_of_sibling by default); the inference
+maintenance task runs this daily.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                `json:"ok"`
+				Result  *memory.InferResult `json:"result,omitempty"`
+				Message string              `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				res := Result{OK: false, Message: msg}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			opts := memory.InferOptions{DryRun: factInferDryRun}
+			if cfg, err := config.Load(); err == nil {
+				if opts.Rules, err = maintenance.InferenceRules(cfg.Maintenance.Inference); err != nil {
+					fail(err.Error())
+				}
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			result, err := memory.RunInference(context.Background(), database, opts)
+			if err != nil {
+				fail(fmt.Sprintf("Inference failed: %v", err))
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Result: result})
+				return
+			}
+			for _, f := range result.Inferred {
+				label := f.Rule
+				if f.Revived {
+					label += ", revived"
+				}
+				fmt.Printf("  %s (%s)\n", f.Fact, label)
+				for _, p := range f.Premises {
+					fmt.Printf("    <- %s\n", p)
+				}
+			}
+			verb := ""
+			if result.DryRun {
+				verb = "would be "
+			}
+			fmt.Printf("%d facts %sinferred, %d unchanged, %d %sretracted\n",
+				len(result.Inferred), verb, result.Unchanged, result.Retracted, verb)
+		},
+	}
+	factInferCmd.Flags().BoolVar(&factInferDryRun, "dry-run", false, "Report what would be inferred or retracted without storing it")
+
+	factPremisesCmd := &cobra.Command{
+		Use:   "premises <fact-id>",
+		Short: "Show the facts an inferred fact was derived from",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK       bool                      `json:"ok"`
+				Premises []memory.InferencePremise `json:"premises"`
+				Message  string                    `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			premises, err := memory.GetInferencePremises(context.Background(), database, args[0])
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to get premises: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Premises: premises})
+				return
+			}
+			if len(premises) == 0 {
+				fmt.Println("No premises (not an inferred fact)")
+				return
+			}
+			derivation := ""
+			for _, p := range premises {
+				if p.Derivation != derivation {
+					derivation = p.Derivation
+					fmt.Printf("By %s:\n", p.Rule)
+				}
+				printFact(&p.EntityRelationship)
+			}
+		},
+	}
+
+	factDeleteCmd := &cobra.Command{
+		Use:   "delete <fact-id>",
+		Short: "Remove a wrong or duplicate fact",
+		Long: `Delete a fact that was never true, such as an extraction error or a
+duplicate, along with its mentions. To record that a fact stopped being
+true, use 'fact invalidate' instead.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                       `json:"ok"`
+				Fact    *memory.EntityRelationship `json:"fact,omitempty"`
+				Message string                     `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				res := Result{OK: false, Message: msg}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			ctx := context.Background()
+			rel, err := memory.GetFact(ctx, database, args[0])
+			if err != nil {
+				fail(fmt.Sprintf("Failed to get fact: %v", err))
+			}
+			if err := memory.DeleteFact(ctx, database, rel.ID); err != nil {
+				fail(fmt.Sprintf("Failed to delete fact: %v", err))
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Fact: rel})
+				return
+			}
+			fmt.Println("Deleted fact")
+			printFact(rel)
+		},
+	}
+
+	var factCheckConstraints []string
+	factCheckCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check the graph against consistency constraints",
+		Long: `Report facts that break the graph's constraints, with the offending facts
+and suggested fixes. Nothing is changed; apply a fix with the command shown.
+
+Constraint kinds:
+  at_most_one  an entity has at most one current fact of the type (BORN_ON)
+  exclusive    an entity's facts of the type to different entities may not
+               overlap in time (MARRIED_TO); symmetric types count both ends
+  symmetric    a symmetric fact is stored once, not both ways round, and
+               never from an entity to itself
+
+Constraints come from maintenance.constraints in the config (one birthdate,
+birthplace and death date; exclusive, symmetric spouses and symmetric
+siblings by default); the constraints maintenance task runs this daily.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                     `json:"ok"`
+				Report  *memory.ConstraintReport `json:"report,omitempty"`
+				Message string                   `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				res := Result{OK: false, Message: msg}
+				if jsonOutput {
+					printJSON(res)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", res.Message)
+				}
+				os.Exit(1)
+			}
+
+			opts := memory.CheckOptions{Names: factCheckConstraints}
+			if cfg, err := config.Load(); err == nil {
+				if opts.Constraints, err = maintenance.Constraints(cfg.Maintenance.Constraints); err != nil {
+					fail(err.Error())
+				}
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			report, err := memory.CheckConstraints(context.Background(), database, opts)
+			if err != nil {
+				fail(fmt.Sprintf("Constraint check failed: %v", err))
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Report: report})
+				return
+			}
+			for _, v := range report.Violations {
+				fmt.Printf("[%s] %s\n", v.Constraint, v.Message)
+				for i := range v.Edges {
+					printFact(&v.Edges[i])
+				}
+				for _, fix := range v.Fixes {
+					var command string
+					switch fix.Action {
+					case memory.FixInvalidate:
+						command = "mnemonic fact invalidate " + fix.RelationshipID
+					case memory.FixEnd:
+						command = fmt.Sprintf("mnemonic fact edit %s --invalid-at %s", fix.RelationshipID, fix.InvalidAt)
+					case memory.FixStart:
+						command = fmt.Sprintf("mnemonic fact edit %s --valid-at %s", fix.RelationshipID, fix.ValidAt)
+					case memory.FixDelete:
+						command = "mnemonic fact delete " + fix.RelationshipID
+					}
+					fmt.Printf("  fix: %s  (%s)\n", command, fix.Reason)
+				}
+				fmt.Println()
+			}
+			fmt.Printf("%d violations of %d constraints\n", len(report.Violations), len(report.Constraints))
+		},
+	}
+	factCheckCmd.Flags().StringSliceVar(&factCheckConstraints, "constraint", nil, "Check only these constraints, by name")
+
+	factCmd.AddCommand(factAddCmd)
+	factCmd.AddCommand(factEditCmd)
+	factCmd.AddCommand(factInvalidateCmd)
+	factCmd.AddCommand(factDeleteCmd)
+	factCmd.AddCommand(factExportCmd)
+	factCmd.AddCommand(factImportCmd)
+	factCmd.AddCommand(factNormalizeCmd)
+	factCmd.AddCommand(factInferCmd)
+	factCmd.AddCommand(factPremisesCmd)
+	factCmd.AddCommand(factCheckCmd)
+	rootCmd.AddCommand(factCmd)
+
+	// query command - graph query language over the memory graph
+	var queryExpr string
+	graphQueryCmd := &cobra.Command{
+		Use:   "query [expr]",
+		Short: "Run a graph query expression against the memory graph",
+		Long: `Compose graph traversals without writing SQL. A query is a chain of steps
+starting from entity(name...) or id(entity_id...):
+
+  out(rel...)  in(rel...)  both(rel...)   follow relationships (no args = any)
+  type(name...)  name(substring)  limit(n)  filter the current results
+  valid_at(date)  only follow relationships valid at a date (YYYY[-MM[-DD]])
+  history()       also follow invalidated relationships
+  source(type...) only follow relationships with this strongest source type
+                  (self_disclosed, mentioned, inferred)
+
+Examples:
+  mnemonic query -e 'entity("Tyler").out("WORKS_AT").valid_at("2024-06")'
+  mnemonic query -e 'entity("Acme").in("WORKS_AT").type("Person")'
+  mnemonic query -e 'entity("Tyler").out().source("self_disclosed")'
+  mnemonic query 'id("ent-123").both().limit(20)'`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                     `json:"ok"`
+				Result  *memory.GraphQueryResult `json:"result,omitempty"`
+				Message string                   `json:"message,omitempty"`
+			}
+
+			expr := queryExpr
+			if expr == "" && len(args) == 1 {
+				expr = args[0]
+			}
+			if strings.TrimSpace(expr) == "" {
+				result := Result{OK: false, Message: "Query expression is required (-e '<expr>')"}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			engine := memory.NewQueryEngine(database)
+			defer engine.Close()
+			res, err := engine.RunGraphQuery(context.Background(), expr)
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Query failed: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Result: res})
+				return
+			}
+			if res.Count == 0 {
+				fmt.Println("No results")
+				return
+			}
+			for _, row := range res.Rows {
+				if row.Literal != nil {
+					fmt.Printf("  %s", *row.Literal)
+				} else {
+					fmt.Printf("  %s (%s) [%s]", row.Name, row.Type, row.ID)
+				}
+				if row.Via != nil {
+					fmt.Printf("  <- %s %s: %s", row.Via.FromName, row.Via.RelationType, row.Via.Fact)
+				}
+				fmt.Println()
+			}
+			fmt.Printf("%d result(s)\n", res.Count)
+		},
+	}
+	graphQueryCmd.Flags().StringVarP(&queryExpr, "expr", "e", "", "Query expression")
+	rootCmd.AddCommand(graphQueryCmd)
+
+	// repl command
+	replCmd := &cobra.Command{
+		Use:   "repl",
+		Short: "Interactive session for exploring the memory graph",
+		Long: `Start an interactive session that keeps the database open between commands.
+
+Commands: find <name>, show <entity>, related <entity>, ask <question>,
+query <expr>, history, help, quit. An <entity> can be #n from the last list.
+History persists in the data directory; !! and !<n> rerun earlier commands.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			session := repl.New(database, os.Stdout)
+			defer session.Close()
+			if dataDir, err := config.GetDataDir(); err == nil {
+				session.HistoryPath = filepath.Join(dataDir, "repl_history")
+			}
+			// ask is the only command that needs the API; the rest work offline
+			if apiKey := os.Getenv("GEMINI_API_KEY"); apiKey != "" {
+				geminiClient := gemini.NewClient(apiKey)
+				defer usage.Attach(database, geminiClient)()
+				searcher := search.NewSearcher(database, &search.GeminiEmbedder{Client: geminiClient})
+				if dataDir, err := config.GetDataDir(); err == nil {
+					searcher.SetIndexDir(filepath.Join(dataDir, "indexes"))
+				}
+				session.Searcher = searcher
+				session.Model = "gemini-embedding-001"
+				session.Preview = func(ctx context.Context, episodeID string) string {
+					preview, _ := getEpisodePreview(ctx, database, episodeID, 150)
+					return preview
+				}
+			}
+
+			fmt.Println("mnemonic repl - type help for commands, quit to leave")
+			if err := session.Run(cmd.Context(), os.Stdin); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+	rootCmd.AddCommand(replCmd)
+
+	// events command
+	eventsCmd := &cobra.Command{
+		Use:   "events",
+		Short: "Query communication events",
+		Long:  "Query and filter communication events across all channels",
+		Run: func(cmd *cobra.Command, args []string) {
+			type ParticipantInfo struct {
+				Name string `json:"name"`
+				Role string `json:"role"`
+			}
+
+			type EventInfo struct {
+				ID           string            `json:"id"`
+				Timestamp    int64             `json:"timestamp"`
+				TimestampStr string            `json:"timestamp_str"`
+				Channel      string            `json:"channel"`
+				ContentTypes string            `json:"content_types"`
+				Content      string            `json:"content"`
+				Direction    string            `json:"direction"`
+				ThreadID     *string           `json:"thread_id,omitempty"`
+				ReplyTo      *string           `json:"reply_to,omitempty"`
+				Participants []ParticipantInfo `json:"participants"`
+			}
+
+			type Result struct {
+				OK      bool        `json:"ok"`
+				Message string      `json:"message,omitempty"`
+				Count   int         `json:"count"`
+				Events  []EventInfo `json:"events,omitempty"`
+			}
+
+			// Parse flags
+			personName, _ := cmd.Flags().GetString("person")
+			channel, _ := cmd.Flags().GetString("channel")
+			sinceStr, _ := cmd.Flags().GetString("since")
+			untilStr, _ := cmd.Flags().GetString("until")
+			direction, _ := cmd.Flags().GetString("direction")
+			limit, _ := cmd.Flags().GetInt("limit")
+
+			// Build filters
+			filters := query.EventFilters{
+				PersonName: personName,
+				Channel:    channel,
+				Direction:  direction,
+				Limit:      limit,
+			}
+
+			// Parse since date
+			if sinceStr != "" {
+				since, err := parseDate(sinceStr)
+				if err != nil {
+					result := Result{
+						OK:      false,
+						Message: fmt.Sprintf("Invalid since date: %v. Use format YYYY-MM-DD", err),
+					}
+					if jsonOutput {
+						printJSON(result)
+					} else {
+						fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+					}
+					os.Exit(1)
+				}
+				filters.Since = since
+			}
+
+			// Parse until date
+			if untilStr != "" {
+				until, err := parseDate(untilStr)
+				if err != nil {
+					result := Result{
+						OK:      false,
+						Message: fmt.Sprintf("Invalid until date: %v. Use format YYYY-MM-DD", err),
+					}
+					if jsonOutput {
+						printJSON(result)
+					} else {
+						fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+					}
+					os.Exit(1)
+				}
+				filters.Until = until
+			}
+
+			// Validate direction
+			if direction != "" && direction != "sent" && direction != "received" && direction != "observed" && direction != drafts.Direction {
+				result := Result{
+					OK:      false,
+					Message: "Invalid direction. Must be one of: sent, received, observed, draft",
+				}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			// Open database
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			// Query events
+			events, err := query.QueryEvents(database, filters)
+			if err != nil {
+				result := Result{
+					OK:      false,
+					Message: fmt.Sprintf("Failed to query events: %v", err),
+				}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			result := Result{
+				OK:    true,
+				Count: len(events),
+			}
+
+			// Convert events to result format
+			for _, e := range events {
+				eventInfo := EventInfo{
+					ID:           e.ID,
+					Timestamp:    e.Timestamp,
+					TimestampStr: query.FormatTimestamp(e.Timestamp),
+					Channel:      e.Channel,
+					ContentTypes: e.ContentTypes,
+					Content:      e.Content,
+					Direction:    e.Direction,
+					ThreadID:     e.ThreadID,
+					ReplyTo:      e.ReplyTo,
+				}
+
+				for _, p := range e.Participants {
+					eventInfo.Participants = append(eventInfo.Participants, ParticipantInfo{
+						Name: p.Name,
+						Role: p.Role,
+					})
+				}
+
+				result.Events = append(result.Events, eventInfo)
+			}
+
+			if jsonOutput {
+				printJSON(result)
+			} else {
+				if len(events) == 0 {
+					fmt.Println("No events found matching the specified filters.")
+					return
+				}
+
+				// Build filter description
+				filterParts := []string{}
+				if personName != "" {
+					filterParts = append(filterParts, fmt.Sprintf("person: %s", personName))
+				}
+				if channel != "" {
+					filterParts = append(filterParts, fmt.Sprintf("channel: %s", channel))
+				}
+				if direction != "" {
+					filterParts = append(filterParts, fmt.Sprintf("direction: %s", direction))
+				}
+				if sinceStr != "" {
+					filterParts = append(filterParts, fmt.Sprintf("since: %s", sinceStr))
+				}
+				if untilStr != "" {
+					filterParts = append(filterParts, fmt.Sprintf("until: %s", untilStr))
+				}
+
+				if len(filterParts) > 0 {
+					fmt.Printf("Events (%s):\n", strings.Join(filterParts, ", "))
+				} else {
+					fmt.Printf("Recent events (showing up to %d):\n", limit)
+				}
+				fmt.Printf("Found %d event(s)\n\n", len(events))
+
+				for i, e := range events {
+					if i > 0 {
+						fmt.Println("---")
+					}
+
+					fmt.Printf("ID: %s\n", e.ID)
+					fmt.Printf("Time: %s\n", query.FormatTimestamp(e.Timestamp))
+					fmt.Printf("Channel: %s\n", e.Channel)
+					if drafts.IsDraft(e.Direction) {
+						fmt.Printf("Direction: %s (not sent)\n", e.Direction)
+					} else {
+						fmt.Printf("Direction: %s\n", e.Direction)
+					}
+
+					if len(e.Participants) > 0 {
+						fmt.Println("Participants:")
+						for _, p := range e.Participants {
+							fmt.Printf("  - %s (%s)\n", p.Name, p.Role)
+						}
+					}
+
+					if e.Content != "" {
+						// Truncate long content for display
+						content := e.Content
+						if len(content) > 200 {
+							content = content[:200] + "..."
+						}
+						fmt.Printf("Content: %s\n", content)
+					}
+
+					if e.ThreadID != nil {
+						fmt.Printf("Thread: %s\n", *e.ThreadID)
+					}
+				}
+			}
+		},
+	}
+
+	eventsCmd.Flags().String("person", "", "Filter by person name")
+	eventsCmd.Flags().String("channel", "", "Filter by channel (e.g., imessage, gmail)")
+	eventsCmd.Flags().String("since", "", "Filter by start date (YYYY-MM-DD)")
+	eventsCmd.Flags().String("until", "", "Filter by end date (YYYY-MM-DD)")
+	eventsCmd.Flags().String("direction", "", "Filter by direction (sent, received, observed, draft)")
+	eventsCmd.Flags().Int("limit", 100, "Maximum number of events to return")
+	rootCmd.AddCommand(eventsCmd)
+
+	// draft command - things I plan to say or send
+	draftCmd := &cobra.Command{
+		Use:   "draft",
+		Short: "Record drafts and talking points (never treated as sent)",
+		Long: `Record things you plan to say or send - drafts and talking points - on
+the draft channel. Drafts show up in events and episode context labeled
+"draft, not sent", so memory extraction never treats them as something the
+recipient was told.`,
+	}
+
+	var draftTo, draftThread string
+	var draftTalkingPoint bool
+	draftAddCmd := &cobra.Command{
+		Use:   "add <text>",
+		Short: "Record a draft or talking point",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool          `json:"ok"`
+				Draft   *drafts.Draft `json:"draft,omitempty"`
+				Message string        `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+				}
+				os.Exit(1)
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			d := drafts.Draft{Content: strings.Join(args, " "), ThreadID: draftThread}
+			if draftTalkingPoint {
+				d.Kind = drafts.KindTalkingPoint
+			}
+			if draftTo != "" {
+				personID, err := findPersonID(database, draftTo)
+				if err != nil {
+					fail(fmt.Sprintf("Person not found: %s", draftTo))
+				}
+				d.PersonID = personID
+			}
+			added, err := drafts.Add(database, d)
+			if err != nil {
+				fail(fmt.Sprintf("Failed to record draft: %v", err))
+			}
+			if jsonOutput {
+				printJSON(Result{OK: true, Draft: added})
+				return
+			}
+			fmt.Printf("Recorded %s %s\n", strings.ReplaceAll(added.Kind, "_", " "), added.ID)
+		},
+	}
+	draftAddCmd.Flags().StringVar(&draftTo, "to", "", "Person the draft is meant for (name or ID)")
+	draftAddCmd.Flags().StringVar(&draftThread, "thread", "", "Thread the draft is meant for")
+	draftAddCmd.Flags().BoolVar(&draftTalkingPoint, "talking-point", false, "Record a talking point rather than a message draft")
+
+	var draftListTo, draftListStatus string
+	var draftListLimit int
+	draftListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List drafts (pending by default)",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool           `json:"ok"`
+				Drafts  []drafts.Draft `json:"drafts"`
+				Message string         `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+				}
+				os.Exit(1)
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			opts := drafts.ListOptions{Status: draftListStatus, Limit: draftListLimit}
+			if draftListTo != "" {
+				personID, err := findPersonID(database, draftListTo)
+				if err != nil {
+					fail(fmt.Sprintf("Person not found: %s", draftListTo))
+				}
+				opts.PersonID = personID
+			}
+			list, err := drafts.List(database, opts)
+			if err != nil {
+				fail(fmt.Sprintf("Failed to list drafts: %v", err))
+			}
+			if list == nil {
+				list = []drafts.Draft{}
+			}
+			if jsonOutput {
+				printJSON(Result{OK: true, Drafts: list})
+				return
+			}
+			if len(list) == 0 {
+				fmt.Println("No drafts")
+				return
+			}
+			for _, d := range list {
+				to := ""
+				if d.Recipient != "" {
+					to = " to " + d.Recipient
+				}
+				fmt.Printf("%s  %s  %s%s [%s]\n", d.ID[:8], d.CreatedAt.Format("2006-01-02"),
+					strings.ReplaceAll(d.Kind, "_", " "), to, d.Status)
+				fmt.Printf("    %s\n", d.Content)
+			}
+		},
+	}
+	draftListCmd.Flags().StringVar(&draftListTo, "to", "", "Only drafts meant for this person")
+	draftListCmd.Flags().StringVar(&draftListStatus, "status", "", "pending (default), sent, discarded, or all")
+	draftListCmd.Flags().IntVar(&draftListLimit, "limit", 50, "Maximum drafts to show")
+
+	draftStatusCmd := func(use, short, status string) *cobra.Command {
+		return &cobra.Command{
+			Use:   use + " <draft-id>",
+			Short: short,
+			Args:  cobra.ExactArgs(1),
+			Run: func(cmd *cobra.Command, args []string) {
+				type Result struct {
+					OK      bool   `json:"ok"`
+					ID      string `json:"id"`
+					Status  string `json:"status,omitempty"`
+					Message string `json:"message,omitempty"`
+				}
+
+				database, err := db.Open()
+				if err != nil {
+					exitWithError(fmt.Errorf("Failed to open database: %w", err))
+				}
+				defer database.Close()
+
+				id := args[0]
+				var full string
+				if err := database.QueryRow(`
+					SELECT id FROM events WHERE channel = ? AND source_adapter = ? AND id LIKE ? || '%'
+				`, drafts.Channel, drafts.SourceAdapter, id).Scan(&full); err == nil {
+					id = full
+				}
+				if err := drafts.SetStatus(database, id, status); err != nil {
+					result := Result{OK: false, ID: args[0], Message: err.Error()}
+					if jsonOutput {
+						printJSON(result)
+					} else {
+						fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+					}
+					os.Exit(1)
+				}
+				if jsonOutput {
+					printJSON(Result{OK: true, ID: id, Status: status})
+					return
+				}
+				fmt.Printf("Marked %s %s\n", id, status)
+			},
+		}
+	}
+
+	draftCmd.AddCommand(draftAddCmd)
+	draftCmd.AddCommand(draftListCmd)
+	draftCmd.AddCommand(draftStatusCmd("sent", "Mark a draft as sent", drafts.StatusSent))
+	draftCmd.AddCommand(draftStatusCmd("discard", "Mark a draft as discarded", drafts.StatusDiscarded))
+	rootCmd.AddCommand(draftCmd)
+
+	// note command - human notes on episodes and events
+	noteCmd := &cobra.Command{
+		Use:   "note",
+		Short: "Attach notes and corrections to episodes and events",
+		Long: `Attach freeform notes to an episode or a single event ("this was
+sarcasm", "Sam is my cousin, not my coworker"). Notes are added as context
+whenever the episode is analyzed or extracted again, so what you know
+steers extraction. Memory extraction re-extracts an episode once its notes
+change.`,
+	}
+
+	// failNote prints a note command error and exits.
+	failNote := func(msg string) {
+		if jsonOutput {
+			printJSON(map[string]any{"ok": false, "message": msg})
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+		}
+		os.Exit(1)
+	}
+	printNote := func(n notes.Note) {
+		target := "episode " + n.EpisodeID
+		if n.EventID != "" {
+			target = "event " + n.EventID
+		}
+		fmt.Printf("%s  %s  %s\n", n.ID[:8], n.CreatedAt.Format("2006-01-02"), target)
+		if n.EventSnippet != "" {
+			fmt.Printf("    on %q\n", n.EventSnippet)
+		}
+		fmt.Printf("    %s\n", n.Content)
+	}
+
+	noteAddCmd := &cobra.Command{
+		Use:   "add <episode-or-event-id> <text>",
+		Short: "Attach a note to an episode or event",
+		Long: `Attach a note to an episode or event. The ID can be shortened to any
+unambiguous prefix.
+
+Example:
+  mnemonic note add 3f2a9c1e "this was sarcasm, he loves his job"`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool        `json:"ok"`
+				Note    *notes.Note `json:"note,omitempty"`
+				Message string      `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			n, err := notes.Add(context.Background(), database, args[0], strings.Join(args[1:], " "))
+			if err != nil {
+				failNote(fmt.Sprintf("Failed to add note: %v", err))
+			}
+			if jsonOutput {
+				printJSON(Result{OK: true, Note: n})
+				return
+			}
+			if n.EventID != "" {
+				fmt.Printf("Added note %s to event %s\n", n.ID[:8], n.EventID)
+			} else {
+				fmt.Printf("Added note %s to episode %s\n", n.ID[:8], n.EpisodeID)
+			}
+		},
+	}
+
+	noteListCmd := &cobra.Command{
+		Use:   "list [episode-or-event-id]",
+		Short: "List notes (all, or on one episode and its events)",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool         `json:"ok"`
+				Notes   []notes.Note `json:"notes"`
+				Message string       `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			target := ""
+			if len(args) == 1 {
+				target = args[0]
+			}
+			list, err := notes.List(context.Background(), database, target)
+			if err != nil {
+				failNote(fmt.Sprintf("Failed to list notes: %v", err))
+			}
+			if list == nil {
+				list = []notes.Note{}
+			}
+			if jsonOutput {
+				printJSON(Result{OK: true, Notes: list})
+				return
+			}
+			if len(list) == 0 {
+				fmt.Println("No notes")
+				return
+			}
+			for _, n := range list {
+				printNote(n)
+			}
+		},
+	}
+
+	noteRemoveCmd := &cobra.Command{
+		Use:   "rm <note-id>",
+		Short: "Remove a note",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			if err := notes.Delete(context.Background(), database, args[0]); err != nil {
+				failNote(fmt.Sprintf("Failed to remove note %s: %v", args[0], err))
+			}
+			if jsonOutput {
+				printJSON(map[string]any{"ok": true, "id": args[0]})
+				return
+			}
+			fmt.Printf("Removed note %s\n", args[0])
+		},
+	}
+
+	noteCmd.AddCommand(noteAddCmd)
+	noteCmd.AddCommand(noteListCmd)
+	noteCmd.AddCommand(noteRemoveCmd)
+	rootCmd.AddCommand(noteCmd)
+
+	// people command
+	peopleCmd := &cobra.Command{
+		Use:   "people [name]",
+		Short: "List and search contacts",
+		Long:  "List all persons, search by name, or show details for a specific person",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type IdentityInfo struct {
+				Channel    string `json:"channel"`
+				Identifier string `json:"identifier"`
+			}
+
+			type PersonInfo struct {
+				ID           string         `json:"id"`
+				Name         string         `json:"name"`
+				DisplayName  string         `json:"display_name,omitempty"`
+				IsMe         bool           `json:"is_me"`
+				Relationship string         `json:"relationship,omitempty"`
+				Identities   []IdentityInfo `json:"identities"`
+				EventCount   int            `json:"event_count"`
+				LastEventAt  string         `json:"last_event_at,omitempty"`
+			}
+
+			type Result struct {
+				OK      bool         `json:"ok"`
+				Message string       `json:"message,omitempty"`
+				Count   int          `json:"count,omitempty"`
+				Persons []PersonInfo `json:"persons,omitempty"`
+			}
+
+			// Open database
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			// Handle specific person detail view
+			if len(args) == 1 {
+				personName := args[0]
+				person, err := identify.GetPersonByName(database, personName)
+				if err != nil {
+					result := Result{
+						OK:      false,
+						Message: fmt.Sprintf("Failed to get person: %v", err),
+					}
+					if jsonOutput {
+						printJSON(result)
+					} else {
+						fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+					}
+					os.Exit(1)
+				}
+
+				if person == nil {
+					result := Result{
+						OK:      false,
+						Message: fmt.Sprintf("Person '%s' not found", personName),
+					}
+					if jsonOutput {
+						printJSON(result)
+					} else {
+						fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+					}
+					os.Exit(1)
+				}
+
+				// Convert to result format
+				personInfo := PersonInfo{
+					ID:         person.ID,
+					Name:       person.CanonicalName,
+					IsMe:       person.IsMe,
+					EventCount: person.EventCount,
+				}
+				if person.DisplayName != nil {
+					personInfo.DisplayName = *person.DisplayName
+				}
+				if person.RelationType != nil {
+					personInfo.Relationship = *person.RelationType
+				}
+				if person.LastEventAt != nil {
+					personInfo.LastEventAt = query.FormatTimestamp(person.LastEventAt.Unix())
+				}
+				for _, id := range person.Identities {
+					personInfo.Identities = append(personInfo.Identities, IdentityInfo{
+						Channel:    id.Channel,
+						Identifier: id.Identifier,
+					})
+				}
+
+				result := Result{
+					OK:      true,
+					Count:   1,
+					Persons: []PersonInfo{personInfo},
+				}
+
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					nameStr := person.CanonicalName
+					if person.IsMe {
+						nameStr += " (me)"
+					}
+					fmt.Printf("%s\n", nameStr)
+					fmt.Printf("ID: %s\n", person.ID)
+					if person.DisplayName != nil {
+						fmt.Printf("Display name: %s\n", *person.DisplayName)
+					}
+					if person.RelationType != nil {
+						fmt.Printf("Relationship: %s\n", *person.RelationType)
+					}
+					fmt.Printf("\nStatistics:\n")
+					fmt.Printf("  Events: %d\n", person.EventCount)
+					if person.LastEventAt != nil {
+						fmt.Printf("  Last event: %s\n", query.FormatTimestamp(person.LastEventAt.Unix()))
+					}
+					if len(person.Identities) > 0 {
+						fmt.Printf("\nIdentities:\n")
+						for _, id := range person.Identities {
+							fmt.Printf("  - %s: %s\n", id.Channel, id.Identifier)
+						}
+					}
+				}
+				return
+			}
+
+			// Handle list/search mode
+			searchTerm, _ := cmd.Flags().GetString("search")
+			topN, _ := cmd.Flags().GetInt("top")
+
+			var persons []identify.PersonWithIdentities
+			if searchTerm != "" {
+				persons, err = identify.Search(database, searchTerm)
+				if err != nil {
+					result := Result{
+						OK:      false,
+						Message: fmt.Sprintf("Failed to search persons: %v", err),
+					}
+					if jsonOutput {
+						printJSON(result)
+					} else {
+						fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+					}
+					os.Exit(1)
+				}
+			} else {
+				persons, err = identify.ListAll(database)
+				if err != nil {
+					result := Result{
+						OK:      false,
+						Message: fmt.Sprintf("Failed to list persons: %v", err),
+					}
+					if jsonOutput {
+						printJSON(result)
+					} else {
+						fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+					}
+					os.Exit(1)
+				}
+			}
+
+			// Apply top N limit if specified
+			if topN > 0 && topN < len(persons) {
+				persons = persons[:topN]
+			}
+
+			result := Result{
+				OK:    true,
+				Count: len(persons),
+			}
+
+			if len(persons) == 0 {
+				result.Message = "No persons found"
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Println(result.Message)
+				}
+				return
+			}
+
+			// Convert to result format
+			for _, p := range persons {
+				personInfo := PersonInfo{
+					ID:         p.ID,
+					Name:       p.CanonicalName,
+					IsMe:       p.IsMe,
+					EventCount: p.EventCount,
+				}
+				if p.DisplayName != nil {
+					personInfo.DisplayName = *p.DisplayName
+				}
+				if p.RelationType != nil {
+					personInfo.Relationship = *p.RelationType
+				}
+				if p.LastEventAt != nil {
+					personInfo.LastEventAt = query.FormatTimestamp(p.LastEventAt.Unix())
+				}
+				for _, id := range p.Identities {
+					personInfo.Identities = append(personInfo.Identities, IdentityInfo{
+						Channel:    id.Channel,
+						Identifier: id.Identifier,
+					})
+				}
+				result.Persons = append(result.Persons, personInfo)
+			}
+
+			if jsonOutput {
+				printJSON(result)
+			} else {
+				// Print header
+				if searchTerm != "" {
+					fmt.Printf("Search results for '%s':\n", searchTerm)
+				} else if topN > 0 {
+					fmt.Printf("Top %d contacts by event count:\n", len(persons))
+				} else {
+					fmt.Printf("All contacts (%d total):\n", len(persons))
+				}
+				fmt.Println()
+
+				// Print person list
+				for _, p := range persons {
+					nameStr := p.CanonicalName
+					if p.IsMe {
+						nameStr += " (me)"
+					}
+					if p.DisplayName != nil && *p.DisplayName != p.CanonicalName {
+						nameStr += fmt.Sprintf(" [%s]", *p.DisplayName)
+					}
+
+					fmt.Printf("• %s\n", nameStr)
+					if p.EventCount > 0 {
+						fmt.Printf("  Events: %d", p.EventCount)
+						if p.LastEventAt != nil {
+							fmt.Printf(" (last: %s)", query.FormatTimestamp(p.LastEventAt.Unix()))
+						}
+						fmt.Println()
+					}
+					if len(p.Identities) > 0 {
+						fmt.Printf("  Identities: ")
+						identStrs := []string{}
+						for _, id := range p.Identities {
+							identStrs = append(identStrs, fmt.Sprintf("%s:%s", id.Channel, id.Identifier))
+						}
+						fmt.Println(strings.Join(identStrs, ", "))
+					}
+					fmt.Println()
+				}
+			}
+		},
+	}
+
+	peopleCmd.Flags().String("search", "", "Search for persons by name")
+	peopleCmd.Flags().Int("top", 0, "Show only top N persons by event count")
+	rootCmd.AddCommand(peopleCmd)
+
+	// person command - view person details and facts
+	personCmd := &cobra.Command{
+		Use:   "person",
+		Short: "View and manage person details",
+	}
+
+	// person facts - show all facts for a person
+	var factsIncludeEvidence bool
+	var factsCategory string
+	var factsHistory bool
+	var factsIncludeSensitive bool
+	personFactsCmd := &cobra.Command{
+		Use:   "facts <person_name_or_id>",
+		Short: "Show all extracted facts for a person",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type FactInfo struct {
+				Category   string  `json:"category"`
+				FactType   string  `json:"fact_type"`
+				FactValue  string  `json:"fact_value"`
+				Confidence float64 `json:"confidence"`
+				Source     string  `json:"source,omitempty"`
+				Channel    string  `json:"channel,omitempty"`
+				Evidence   string  `json:"evidence,omitempty"`
+				SeenCount  int     `json:"seen_count,omitempty"`
+			}
+
+			type Result struct {
+				OK              bool                  `json:"ok"`
+				PersonID        string                `json:"person_id"`
+				PersonName      string                `json:"person_name"`
+				Facts           []FactInfo            `json:"facts"`
+				SensitiveHidden int                   `json:"sensitive_hidden,omitempty"`
+				History         []identify.FactChange `json:"history,omitempty"`
+				Message         string                `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			// Find person by name or ID
+			personRef := args[0]
+			var personID, personName string
+
+			// Try by ID first
+			err = database.QueryRow(`SELECT id, canonical_name FROM persons WHERE id = ?`, personRef).Scan(&personID, &personName)
+			if err != nil {
+				// Try by name
+				err = database.QueryRow(`
+					SELECT id, canonical_name FROM persons 
+					WHERE canonical_name LIKE ? OR display_name LIKE ?
+					LIMIT 1
+				`, "%"+personRef+"%", "%"+personRef+"%").Scan(&personID, &personName)
+			}
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Person not found: %s", personRef)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			// Get facts
+			var facts []identify.PersonFact
+			if factsCategory != "" {
+				facts, err = identify.GetFactsByCategory(database, personID, factsCategory)
+			} else {
+				facts, err = identify.GetFactsForPerson(database, personID)
+			}
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to get facts: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			var infos []FactInfo
+			var sensitiveReads []audit.Read
+			hidden := 0
+			for _, f := range facts {
+				if f.IsSensitive && !factsIncludeSensitive {
+					hidden++
+					continue
+				}
+				if f.IsSensitive {
+					sensitiveReads = append(sensitiveReads, audit.Read{Table: audit.TablePersonFacts, RowID: f.ID, PersonID: personID, FactType: f.FactType})
+				}
+				info := FactInfo{
+					Category:   f.Category,
+					FactType:   f.FactType,
+					FactValue:  f.FactValue,
+					Confidence: f.Confidence,
+					Source:     f.SourceType,
+					SeenCount:  f.SeenCount,
+				}
+				if f.SourceChannel != nil {
+					info.Channel = *f.SourceChannel
+				}
+				if factsIncludeEvidence && f.Evidence != nil {
+					info.Evidence = *f.Evidence
+				}
+				infos = append(infos, info)
+			}
+
+			result := Result{OK: true, PersonID: personID, PersonName: personName, Facts: infos, SensitiveHidden: hidden}
+			if factsHistory {
+				var history []identify.FactChange
+				history, err = identify.GetFactHistory(database, personID)
+				for _, c := range history {
+					sensitive := identify.IsSensitiveFact("", c.FactType)
+					if factsIncludeSensitive || !sensitive {
+						result.History = append(result.History, c)
+					}
+					if factsIncludeSensitive && sensitive {
+						sensitiveReads = append(sensitiveReads, audit.Read{Table: audit.TablePersonFactHistory, RowID: c.ID, PersonID: personID, FactType: c.FactType})
+					}
+				}
+				if err != nil {
+					result := Result{OK: false, Message: fmt.Sprintf("Failed to get fact history: %v", err)}
+					if jsonOutput {
+						printJSON(result)
+					} else {
+						fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+					}
+					os.Exit(1)
+				}
+			}
+
+			// Sensitive values are only shown once their read is on record
+			if err := audit.Log(database, auditCaller(cmd), sensitiveReads); err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to write audit log: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(result)
+			} else {
+				fmt.Printf("Facts for %s (%s):\n\n", personName, personID[:8])
+				if len(infos) == 0 {
+					fmt.Println("  No facts extracted yet.")
+					fmt.Println("\n  Run 'cortex extract pii' to extract facts from segments")
+				} else {
+					currentCat := ""
+					for _, f := range infos {
+						if f.Category != currentCat {
+							currentCat = f.Category
+							fmt.Printf("\n  [%s]\n", currentCat)
+						}
+						confidenceStr := ""
+						if f.Confidence >= 0.8 {
+							confidenceStr = "●●●"
+						} else if f.Confidence >= 0.5 {
+							confidenceStr = "●●○"
+						} else {
+							confidenceStr = "●○○"
+						}
+						seenStr := ""
+						if f.SeenCount > 1 {
+							seenStr = fmt.Sprintf("  (seen %dx)", f.SeenCount)
+						}
+						fmt.Printf("    %s: %s  %s%s\n", f.FactType, f.FactValue, confidenceStr, seenStr)
+						if f.Evidence != "" {
+							// Truncate evidence
+							ev := f.Evidence
+							if len(ev) > 80 {
+								ev = ev[:77] + "..."
+							}
+							fmt.Printf("      └─ \"%s\"\n", ev)
+						}
+					}
+				}
+				if hidden > 0 {
+					fmt.Printf("\n  (%d sensitive facts hidden - use --include-sensitive to show)\n", hidden)
+				}
+				if factsHistory {
+					fmt.Println("\n  History:")
+					for _, c := range result.History {
+						switch c.ChangeType {
+						case identify.FactChangeSuperseded:
+							fmt.Printf("    %s  %s: %s → %s\n", c.CreatedAt.Format("2006-01-02"), c.FactType, *c.OldValue, *c.NewValue)
+						default:
+							fmt.Printf("    %s  %s %s: %s\n", c.CreatedAt.Format("2006-01-02"), c.ChangeType, c.FactType, *c.NewValue)
+						}
+					}
+				}
+			}
+		},
+	}
+	personFactsCmd.Flags().BoolVar(&factsIncludeEvidence, "include-evidence", false, "Include source evidence quotes")
+	personFactsCmd.Flags().StringVar(&factsCategory, "category", "", "Filter by category (core_identity, contact_information, etc.)")
+	personFactsCmd.Flags().BoolVar(&factsHistory, "history", false, "Include the history of fact changes")
+	personFactsCmd.Flags().BoolVar(&factsIncludeSensitive, "include-sensitive", false, "Include sensitive facts (government IDs, medical)")
+
+	// person profile - formatted profile view
+	personProfileCmd := &cobra.Command{
+		Use:   "profile <person_name_or_id>",
+		Short: "Show formatted profile view for a person",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			// Find person
+			personRef := args[0]
+			var personID, personName string
+			var displayName sql.NullString
+
+			err = database.QueryRow(`SELECT id, canonical_name, display_name FROM persons WHERE id = ?`, personRef).Scan(&personID, &personName, &displayName)
+			if err != nil {
+				err = database.QueryRow(`
+					SELECT id, canonical_name, display_name FROM persons 
+					WHERE canonical_name LIKE ? OR display_name LIKE ?
+					LIMIT 1
+				`, "%"+personRef+"%", "%"+personRef+"%").Scan(&personID, &personName, &displayName)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Person not found: %s\n", personRef)
+				os.Exit(1)
+			}
+
+			// Get all facts
+			facts, _ := identify.GetFactsForPerson(database, personID)
+
+			// Get contact identifiers
+			rows, _ := database.Query(`
+				SELECT ci.type, ci.value
+				FROM person_contact_links pcl
+				JOIN contact_identifiers ci ON pcl.contact_id = ci.contact_id
+				WHERE pcl.person_id = ?
+			`, personID)
+			var identities []string
+			if rows != nil {
+				for rows.Next() {
+					var ch, id string
+					rows.Scan(&ch, &id)
+					identities = append(identities, fmt.Sprintf("%s:%s", ch, id))
+				}
+				rows.Close()
+			}
+
+			// Get event count
+			var eventCount int
+			database.QueryRow(`
+				SELECT COUNT(DISTINCT ep.event_id)
+				FROM event_participants ep
+				JOIN person_contact_links pcl ON ep.contact_id = pcl.contact_id
+				WHERE pcl.person_id = ?
+			`, personID).Scan(&eventCount)
+
+			// Format output
+			fmt.Println("╭─────────────────────────────────────────╮")
+			fmt.Printf("│  %s\n", personName)
+			if displayName.Valid && displayName.String != "" && displayName.String != personName {
+				fmt.Printf("│  aka %s\n", displayName.String)
+			}
+			fmt.Println("╰─────────────────────────────────────────╯")
+			fmt.Printf("  ID: %s\n", personID[:8])
+			fmt.Printf("  Events: %d\n", eventCount)
+			fmt.Println()
+
+			if len(identities) > 0 {
+				fmt.Println("  📇 Identities:")
+				for _, id := range identities {
+					fmt.Printf("     • %s\n", id)
+				}
+				fmt.Println()
+			}
+
+			// Group facts by category
+			factsByCategory := make(map[string][]identify.PersonFact)
+			for _, f := range facts {
+				factsByCategory[f.Category] = append(factsByCategory[f.Category], f)
+			}
+
+			categoryOrder := []string{
+				identify.CategoryCoreIdentity,
+				identify.CategoryContactInfo,
+				identify.CategoryProfessional,
+				identify.CategoryRelationships,
+				identify.CategoryLocation,
+				identify.CategoryEducation,
+			}
+
+			categoryEmoji := map[string]string{
+				identify.CategoryCoreIdentity:    "👤",
+				identify.CategoryContactInfo:     "📞",
+				identify.CategoryProfessional:    "💼",
+				identify.CategoryRelationships:   "👨‍👩‍👧‍👦",
+				identify.CategoryLocation:        "📍",
+				identify.CategoryEducation:       "🎓",
+				identify.CategoryDigitalIdentity: "🌐",
+				identify.CategoryGovernmentID:    "🪪",
+			}
+
+			for _, cat := range categoryOrder {
+				catFacts, ok := factsByCategory[cat]
+				if !ok || len(catFacts) == 0 {
+					continue
+				}
+				emoji := categoryEmoji[cat]
+				if emoji == "" {
+					emoji = "📋"
+				}
+				fmt.Printf("  %s %s:\n", emoji, cat)
+				for _, f := range catFacts {
+					fmt.Printf("     %s: %s\n", f.FactType, f.FactValue)
+				}
+				fmt.Println()
+			}
+		},
+	}
+
+	var personAvatarOut string
+	personAvatarCmd := &cobra.Command{
+		Use:   "avatar <person_name_or_id>",
+		Short: "Show or save a person's contact photo",
+		Long: `Show which contact photo a person has, or write it to --out. Photos come
+from Google Contacts (contacts adapter) and macOS Contacts (eve adapter)
+and are served to UIs at /api/persons/<id>/avatar by 'serve'.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK          bool   `json:"ok"`
+				PersonID    string `json:"person_id,omitempty"`
+				Source      string `json:"source,omitempty"`
+				ContentType string `json:"content_type,omitempty"`
+				Bytes       int    `json:"bytes,omitempty"`
+				Out         string `json:"out,omitempty"`
+				Message     string `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+				}
+				os.Exit(1)
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			personID, err := findPersonID(database, args[0])
+			if err != nil {
+				fail(fmt.Sprintf("Person not found: %s", args[0]))
+			}
+			avatar, err := avatars.ForPerson(database, personID)
+			if err != nil {
+				fail(fmt.Sprintf("Failed to load avatar: %v", err))
+			}
+			if avatar == nil {
+				fail(fmt.Sprintf("No photo for %s", args[0]))
+			}
+			if personAvatarOut != "" {
+				if err := os.WriteFile(personAvatarOut, avatar.Data, 0644); err != nil {
+					fail(fmt.Sprintf("Failed to write %s: %v", personAvatarOut, err))
+				}
+			}
+
+			result := Result{
+				OK:          true,
+				PersonID:    personID,
+				Source:      avatar.Source,
+				ContentType: avatar.ContentType,
+				Bytes:       len(avatar.Data),
+				Out:         personAvatarOut,
+			}
+			if jsonOutput {
+				printJSON(result)
+				return
+			}
+			fmt.Printf("%s photo from %s (%d bytes, updated %s)\n", avatar.ContentType, avatar.Source,
+				len(avatar.Data), avatar.UpdatedAt.Format("2006-01-02"))
+			if personAvatarOut != "" {
+				fmt.Printf("Wrote %s\n", personAvatarOut)
+			}
+		},
+	}
+	personAvatarCmd.Flags().StringVarP(&personAvatarOut, "out", "o", "", "Write the photo to this file")
+
+	personCmd.AddCommand(personFactsCmd)
+	personCmd.AddCommand(personAvatarCmd)
+	personCmd.AddCommand(personProfileCmd)
+	rootCmd.AddCommand(personCmd)
+
+	// audit command - who read sensitive data
+	var auditPerson, auditCommand, auditToken, auditSince string
+	var auditLimit int
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Show reads of sensitive data (government IDs, medical facts)",
+		Long: `Show every read of a sensitive row: sensitive person facts and their
+history (government IDs, medical facts) and sensitive unattributed facts.
+Each entry records when the row was read and by which CLI command or API
+token. Values themselves are never logged.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool          `json:"ok"`
+				Entries []audit.Entry `json:"entries"`
+				Message string        `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				result := Result{OK: false, Message: msg}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			filter := audit.Filter{Command: auditCommand, TokenID: auditToken, Limit: auditLimit}
+			if auditSince != "" {
+				filter.Since = parseSince(auditSince)
+				if filter.Since.IsZero() {
+					fail(fmt.Sprintf("Invalid --since %q (use e.g. 7d, 12h or YYYY-MM-DD)", auditSince))
+				}
+			}
+			if auditPerson != "" {
+				personID, err := findPersonID(database, auditPerson)
+				if err != nil {
+					fail(fmt.Sprintf("Person not found: %s", auditPerson))
+				}
+				filter.PersonID = personID
+			}
+
+			entries, err := audit.List(database, filter)
+			if err != nil {
+				fail(fmt.Sprintf("Failed to read audit log: %v", err))
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Entries: entries})
+				return
+			}
+			if len(entries) == 0 {
+				fmt.Println("No sensitive reads logged.")
+				return
+			}
+			for _, e := range entries {
+				by := e.Command
+				if e.TokenID != "" {
+					by = "token " + e.TokenID
+				}
+				subject := e.PersonName
+				if subject == "" {
+					subject = "(unattributed)"
+				}
+				fmt.Printf("%s  %-28s %-20s %-16s %s/%s\n", e.AccessedAt.Format("2006-01-02 15:04:05"), by, subject, e.FactType, e.Table, e.RowID)
+			}
+		},
+	}
+	auditCmd.Flags().StringVar(&auditPerson, "person", "", "Only reads of this person's facts (name or ID)")
+	auditCmd.Flags().StringVar(&auditCommand, "command", "", "Only reads by commands containing this (e.g. 'person facts')")
+	auditCmd.Flags().StringVar(&auditToken, "token", "", "Only reads through this API token")
+	auditCmd.Flags().StringVar(&auditSince, "since", "", "Only reads since (e.g. 7d, 12h, YYYY-MM-DD)")
+	auditCmd.Flags().IntVar(&auditLimit, "limit", 100, "Maximum entries to show (0 = all)")
+	rootCmd.AddCommand(auditCmd)
+
+	// changes command - global change log for downstream consumers
+	var changesFrom int64
+	var changesLimit int
+	var changesTypes []string
+	var changesHead bool
+	changesCmd := &cobra.Command{
+		Use:   "changes",
+		Short: "Show the global change log",
+		Long: `Show the append-only change log: entities created, relationships added,
+merges executed and episodes processed, each with a sequence number.
+
+Syncers keep the last sequence number they processed and pass it with
+--since to get what is new. --head prints the newest sequence number, to
+start following from now without replaying history.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool               `json:"ok"`
+				Changes []changelog.Change `json:"changes,omitempty"`
+				Cursor  int64              `json:"cursor"`
+				Message string             `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+				}
+				os.Exit(1)
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
+			defer database.Close()
 
-			if len(persons) == 0 {
-				result.Message = "No persons found"
+			if changesHead {
+				head, err := changelog.Head(database)
+				if err != nil {
+					fail(err.Error())
+				}
 				if jsonOutput {
-					printJSON(result)
+					printJSON(Result{OK: true, Cursor: head})
 				} else {
-					fmt.Println(result.Message)
+					fmt.Println(head)
 				}
 				return
 			}
 
-			// Convert to result format
-			for _, p := range persons {
-				personInfo := PersonInfo{
-					ID:         p.ID,
-					Name:       p.CanonicalName,
-					IsMe:       p.IsMe,
-					EventCount: p.EventCount,
-				}
-				if p.DisplayName != nil {
-					personInfo.DisplayName = *p.DisplayName
-				}
-				if p.RelationType != nil {
-					personInfo.Relationship = *p.RelationType
-				}
-				if p.LastEventAt != nil {
-					personInfo.LastEventAt = query.FormatTimestamp(p.LastEventAt.Unix())
-				}
-				for _, id := range p.Identities {
-					personInfo.Identities = append(personInfo.Identities, IdentityInfo{
-						Channel:    id.Channel,
-						Identifier: id.Identifier,
-					})
-				}
-				result.Persons = append(result.Persons, personInfo)
+			changes, cursor, err := changelog.Read(database, changelog.ReadOptions{Since: changesFrom, Limit: changesLimit, Types: changesTypes})
+			if err != nil {
+				fail(err.Error())
 			}
-
 			if jsonOutput {
-				printJSON(result)
-			} else {
-				// Print header
-				if searchTerm != "" {
-					fmt.Printf("Search results for '%s':\n", searchTerm)
-				} else if topN > 0 {
-					fmt.Printf("Top %d contacts by event count:\n", len(persons))
+				printJSON(Result{OK: true, Changes: changes, Cursor: cursor})
+				return
+			}
+			if len(changes) == 0 {
+				fmt.Println("No changes")
+				return
+			}
+			for _, c := range changes {
+				subject := c.SubjectID
+				if c.RefID != "" {
+					subject += " <- " + c.RefID
+				}
+				fmt.Printf("%8d  %s  %-18s %s\n", c.Seq, c.CreatedAt, c.Type, subject)
+			}
+			fmt.Printf("\nNext: --since %d\n", cursor)
+		},
+	}
+	changesCmd.Flags().Int64Var(&changesFrom, "since", 0, "Only changes after this sequence number")
+	changesCmd.Flags().IntVar(&changesLimit, "limit", changelog.DefaultLimit, "Maximum changes to show")
+	changesCmd.Flags().StringSliceVar(&changesTypes, "type", nil, "Only these change types ("+strings.Join(changelog.Types, ", ")+")")
+	changesCmd.Flags().BoolVar(&changesHead, "head", false, "Print the newest sequence number and exit")
+	rootCmd.AddCommand(changesCmd)
+
+	// digest command - daily reports of what the graph learned
+	var digestPreview bool
+	var digestListLimit int
+	digestCmd := &cobra.Command{
+		Use:   "digest [digest-id]",
+		Short: "Show what the graph learned since the previous digest",
+		Long: `Show a digest: the entities created, facts learned, facts no longer
+true, merges and episodes analyzed since the previous digest. Without an
+ID the latest is shown.
+
+The digest maintenance task stores one a day and, when configured under
+maintenance.digest, POSTs it to a webhook and emails it. Run it now with
+'mnemonic maintenance run digest'. --preview shows what the next digest
+would hold without storing it.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool           `json:"ok"`
+				Digest  *digest.Digest `json:"digest,omitempty"`
+				Message string         `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
 				} else {
-					fmt.Printf("All contacts (%d total):\n", len(persons))
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
 				}
-				fmt.Println()
+				os.Exit(1)
+			}
 
-				// Print person list
-				for _, p := range persons {
-					nameStr := p.CanonicalName
-					if p.IsMe {
-						nameStr += " (me)"
-					}
-					if p.DisplayName != nil && *p.DisplayName != p.CanonicalName {
-						nameStr += fmt.Sprintf(" [%s]", *p.DisplayName)
-					}
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
 
-					fmt.Printf("• %s\n", nameStr)
-					if p.EventCount > 0 {
-						fmt.Printf("  Events: %d", p.EventCount)
-						if p.LastEventAt != nil {
-							fmt.Printf(" (last: %s)", query.FormatTimestamp(p.LastEventAt.Unix()))
-						}
-						fmt.Println()
-					}
-					if len(p.Identities) > 0 {
-						fmt.Printf("  Identities: ")
-						identStrs := []string{}
-						for _, id := range p.Identities {
-							identStrs = append(identStrs, fmt.Sprintf("%s:%s", id.Channel, id.Identifier))
-						}
-						fmt.Println(strings.Join(identStrs, ", "))
-					}
-					fmt.Println()
+			var d *digest.Digest
+			switch {
+			case digestPreview && len(args) > 0:
+				fail("Pass a digest ID or --preview, not both")
+			case digestPreview:
+				d, err = digest.Preview(cmd.Context(), database, time.Now())
+			case len(args) > 0:
+				d, err = digest.Get(database, args[0])
+			default:
+				d, err = digest.Latest(database)
+				if errors.Is(err, digest.ErrNoDigest) {
+					fail("No digest yet; run 'mnemonic maintenance run digest' or see 'mnemonic digest --preview'")
 				}
 			}
+			if err != nil {
+				fail(err.Error())
+			}
+			if jsonOutput {
+				printJSON(Result{OK: true, Digest: d})
+				return
+			}
+			fmt.Print(digest.Render(d))
 		},
 	}
+	digestCmd.Flags().BoolVar(&digestPreview, "preview", false, "Show what the next digest would hold, without storing it")
 
-	peopleCmd.Flags().String("search", "", "Search for persons by name")
-	peopleCmd.Flags().Int("top", 0, "Show only top N persons by event count")
-	rootCmd.AddCommand(peopleCmd)
-
-	// person command - view person details and facts
-	personCmd := &cobra.Command{
-		Use:   "person",
-		Short: "View and manage person details",
-	}
-
-	// person facts - show all facts for a person
-	var factsIncludeEvidence bool
-	var factsCategory string
-	personFactsCmd := &cobra.Command{
-		Use:   "facts <person_name_or_id>",
-		Short: "Show all extracted facts for a person",
-		Args:  cobra.ExactArgs(1),
+	digestListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List stored digests, newest first",
+		Args:  cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
-			type FactInfo struct {
-				Category   string  `json:"category"`
-				FactType   string  `json:"fact_type"`
-				FactValue  string  `json:"fact_value"`
-				Confidence float64 `json:"confidence"`
-				Source     string  `json:"source,omitempty"`
-				Channel    string  `json:"channel,omitempty"`
-				Evidence   string  `json:"evidence,omitempty"`
-			}
-
 			type Result struct {
-				OK         bool       `json:"ok"`
-				PersonID   string     `json:"person_id"`
-				PersonName string     `json:"person_name"`
-				Facts      []FactInfo `json:"facts"`
-				Message    string     `json:"message,omitempty"`
+				OK      bool            `json:"ok"`
+				Digests []digest.Digest `json:"digests"`
+				Message string          `json:"message,omitempty"`
 			}
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			digests, err := digest.List(database, digestListLimit)
+			if err != nil {
 				if jsonOutput {
-					printJSON(result)
+					printJSON(Result{OK: false, Message: err.Error()})
 				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+					fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 				}
 				os.Exit(1)
 			}
+			if jsonOutput {
+				printJSON(Result{OK: true, Digests: digests})
+				return
+			}
+			if len(digests) == 0 {
+				fmt.Println("No digests")
+				return
+			}
+			for _, d := range digests {
+				fmt.Printf("%s  %s  %s\n", d.ID[:8], time.Unix(d.CreatedAt, 0).Format("2006-01-02 15:04"), d.Summary())
+			}
+		},
+	}
+	digestListCmd.Flags().IntVar(&digestListLimit, "limit", 30, "Maximum digests to list")
+	digestCmd.AddCommand(digestListCmd)
+	rootCmd.AddCommand(digestCmd)
+
+	// serve command - HTTP API with role-scoped tokens
+	var serveBind string
+	var servePort int
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the HTTP API (requires an API token)",
+		Long: `Serve the HTTP API. Every request except /healthz needs a token
+(Authorization: Bearer <token>) created with 'mnemonic token create', and
+each endpoint needs a scope:
+
+  read-graph    GET  /api/entities?name=..., /api/entities/{id}, /api/entities/{id}/changes,
+                     /api/merge-candidates, /api/changes
+  read-events   GET  /api/events (raw message content), /api/drafts
+  write-merges  POST /api/merge-candidates/{id}/accept, /api/merge-candidates/{id}/reject
+  write-drafts  POST /api/drafts, /api/drafts/{id}/status
+  write-events  POST /api/events (push events into the store)
+  admin         everything, plus GET /api/tokens
+
+A dashboard widget given only read-graph can read the graph but not
+messages, and cannot trigger merges.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
 			defer database.Close()
 
-			// Find person by name or ID
-			personRef := args[0]
-			var personID, personName string
-
-			// Try by ID first
-			err = database.QueryRow(`SELECT id, canonical_name FROM persons WHERE id = ?`, personRef).Scan(&personID, &personName)
+			active, err := server.ActiveTokenCount(database)
 			if err != nil {
-				// Try by name
-				err = database.QueryRow(`
-					SELECT id, canonical_name FROM persons 
-					WHERE canonical_name LIKE ? OR display_name LIKE ?
-					LIMIT 1
-				`, "%"+personRef+"%", "%"+personRef+"%").Scan(&personID, &personName)
+				exitWithError(fmt.Errorf("Failed to read API tokens: %w", err))
 			}
-			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Person not found: %s", personRef)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
+			if active == 0 {
+				fmt.Fprintln(os.Stderr, "Error: no API tokens; create one first:")
+				fmt.Fprintln(os.Stderr, "  mnemonic token create dashboard --scope read-graph")
 				os.Exit(1)
 			}
 
-			// Get facts
-			var facts []identify.PersonFact
-			if factsCategory != "" {
-				facts, err = identify.GetFactsByCategory(database, personID, factsCategory)
-			} else {
-				facts, err = identify.GetFactsForPerson(database, personID)
+			addr := fmt.Sprintf("%s:%d", serveBind, servePort)
+			srv := &http.Server{Addr: addr, Handler: server.New(database)}
+			sigChan := make(chan os.Signal, 1)
+			signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+			go func() {
+				<-sigChan
+				fmt.Fprintf(os.Stderr, "\nStopping server...\n")
+				_ = srv.Shutdown(context.Background())
+			}()
+
+			fmt.Printf("Listening on http://%s (%d active tokens)\n", addr, active)
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fmt.Fprintf(os.Stderr, "server stopped: %v\n", err)
+				os.Exit(1)
 			}
+		},
+	}
+	serveCmd.Flags().StringVar(&serveBind, "bind", "127.0.0.1", "Bind address")
+	serveCmd.Flags().IntVar(&servePort, "port", 8787, "Listen port")
+	rootCmd.AddCommand(serveCmd)
+
+	// worker command - headless processing for containers
+	workerCmd := &cobra.Command{
+		Use:   "worker",
+		Short: "Run headless: ingest over the API and an inbox, process, serve",
+		Long: `Run the headless worker for a container or NAS. It runs no local source
+adapters: events arrive over the API (POST /api/events, write-events scope)
+or as JSONL files in the inbox (<inbox>/<source>/<file>.jsonl, one event
+per line in the adapter plugin format; imported files move to done/ or
+failed/). Every cycle it imports the inbox, chunks new events into
+episodes with every episode definition (see 'mnemonic chunk seed') and,
+with GEMINI_API_KEY set, queues analysis and embeddings and runs them. It also serves the API, /healthz (the process is up) and
+/readyz (the database is reachable, plus the last cycle's outcome).
+
+The database is created or upgraded on start. Configuration comes from the
+environment:
+
+  MNEMONIC_DATA_DIR, MNEMONIC_CONFIG_DIR   where the database and config live
+  MNEMONIC_WORKER_BIND                     listen address (default 0.0.0.0)
+  MNEMONIC_WORKER_PORT                     listen port (default 8787)
+  MNEMONIC_WORKER_INTERVAL                 time between cycles (default 5m)
+  MNEMONIC_WORKER_INBOX                    inbox dir (default <data dir>/inbox; off disables)
+  MNEMONIC_WORKER_ANALYSIS                 analysis types to queue (default convo-all-v1; off disables)
+  MNEMONIC_WORKER_COMPUTE_WORKERS          concurrent compute jobs (default 10)
+  GEMINI_API_KEY                           without it, episodes are built but not analyzed
+
+Maintenance tasks run too when maintenance.enabled is set in config.yaml.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			dataDir, err := config.GetDataDir()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to get facts: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to get data directory: %w", err))
+			}
+			workerCfg, err := worker.ConfigFromEnv(os.Getenv, dataDir)
+			if err != nil {
+				exitWithError(err)
+			}
+			appCfg, err := config.Load()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to load config: %w", err))
 			}
 
-			var infos []FactInfo
-			for _, f := range facts {
-				info := FactInfo{
-					Category:   f.Category,
-					FactType:   f.FactType,
-					FactValue:  f.FactValue,
-					Confidence: f.Confidence,
-					Source:     f.SourceType,
-				}
-				if f.SourceChannel != nil {
-					info.Channel = *f.SourceChannel
-				}
-				if factsIncludeEvidence && f.Evidence != nil {
-					info.Evidence = *f.Evidence
-				}
-				infos = append(infos, info)
+			if err := os.MkdirAll(dataDir, 0755); err != nil {
+				exitWithError(fmt.Errorf("Failed to create data directory: %w", err))
+			}
+			if err := db.Init(); err != nil {
+				exitWithError(fmt.Errorf("Failed to initialize database: %w", err))
+			}
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
+			defer database.Close()
 
-			result := Result{OK: true, PersonID: personID, PersonName: personName, Facts: infos}
+			lock := acquireInstanceLocks(cmd, database, []string{"daemon", "compute"})
+			defer lock.Release()
 
-			if jsonOutput {
-				printJSON(result)
-			} else {
-				fmt.Printf("Facts for %s (%s):\n\n", personName, personID[:8])
-				if len(infos) == 0 {
-					fmt.Println("  No facts extracted yet.")
-					fmt.Println("\n  Run 'cortex extract pii' to extract facts from segments")
-				} else {
-					currentCat := ""
-					for _, f := range infos {
-						if f.Category != currentCat {
-							currentCat = f.Category
-							fmt.Printf("\n  [%s]\n", currentCat)
-						}
-						confidenceStr := ""
-						if f.Confidence >= 0.8 {
-							confidenceStr = "●●●"
-						} else if f.Confidence >= 0.5 {
-							confidenceStr = "●●○"
-						} else {
-							confidenceStr = "●○○"
-						}
-						fmt.Printf("    %s: %s  %s\n", f.FactType, f.FactValue, confidenceStr)
-						if f.Evidence != "" {
-							// Truncate evidence
-							ev := f.Evidence
-							if len(ev) > 80 {
-								ev = ev[:77] + "..."
-							}
-							fmt.Printf("      └─ \"%s\"\n", ev)
-						}
-					}
+			w := worker.New(database, workerCfg)
+			w.Logf = func(format string, args ...any) {
+				fmt.Fprintf(os.Stderr, "[worker] "+format+"\n", args...)
+			}
+			if w.Compute.PostProcessors, err = plugin.Load(appCfg, plugin.KindPostprocessor); err != nil {
+				exitWithError(fmt.Errorf("invalid plugin config: %w", err))
+			}
+			if w.Gemini != nil {
+				defer usage.Attach(database, w.Gemini)()
+			}
+			if appCfg.Maintenance.Enabled {
+				scheduler, _, err := newMaintenanceScheduler(database)
+				if err != nil {
+					exitWithError(err)
 				}
+				w.Maintenance = scheduler
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			sigChan := make(chan os.Signal, 1)
+			signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+			go func() {
+				<-sigChan
+				fmt.Fprintf(os.Stderr, "\nStopping worker...\n")
+				cancel()
+			}()
+
+			if active, err := server.ActiveTokenCount(database); err == nil && active == 0 {
+				fmt.Fprintln(os.Stderr, "Warning: no API tokens; only /healthz and /readyz answer until one is created:")
+				fmt.Fprintln(os.Stderr, "  mnemonic token create pusher --scope write-events")
+			}
+			fmt.Printf("Worker listening on http://%s\n", workerCfg.Addr())
+			if workerCfg.InboxDir != "" {
+				fmt.Printf("Inbox: %s\n", workerCfg.InboxDir)
+			}
+			if w.Gemini == nil || len(workerCfg.AnalysisTypes) == 0 {
+				fmt.Println("Analysis: off (set GEMINI_API_KEY to analyze episodes)")
+			} else {
+				fmt.Printf("Analysis: %s, every %s\n", strings.Join(workerCfg.AnalysisTypes, ", "), workerCfg.Interval)
+			}
+			if err := w.Run(ctx); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
 			}
 		},
 	}
-	personFactsCmd.Flags().BoolVar(&factsIncludeEvidence, "include-evidence", false, "Include source evidence quotes")
-	personFactsCmd.Flags().StringVar(&factsCategory, "category", "", "Filter by category (core_identity, contact_information, etc.)")
+	workerCmd.Flags().Bool("force", false, "Take over locks held by another daemon or compute run")
+	rootCmd.AddCommand(workerCmd)
 
-	// person profile - formatted profile view
-	personProfileCmd := &cobra.Command{
-		Use:   "profile <person_name_or_id>",
-		Short: "Show formatted profile view for a person",
-		Args:  cobra.ExactArgs(1),
+	// token command - manage API tokens for serve
+	tokenCmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage API tokens for 'serve'",
+	}
+
+	var tokenScopes []string
+	tokenCreateCmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create an API token (the secret is shown once)",
+		Long: `Create a named API token with one or more scopes: read-graph,
+read-events, write-merges, write-drafts, write-events, admin. The secret is
+printed once and only its hash is stored.`,
+		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool          `json:"ok"`
+				Token   *server.Token `json:"token,omitempty"`
+				Secret  string        `json:"secret,omitempty"`
+				Message string        `json:"message,omitempty"`
+			}
+
 			database, err := db.Open()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
-			// Find person
-			personRef := args[0]
-			var personID, personName string
-			var displayName sql.NullString
-
-			err = database.QueryRow(`SELECT id, canonical_name, display_name FROM persons WHERE id = ?`, personRef).Scan(&personID, &personName, &displayName)
+			token, secret, err := server.CreateToken(database, args[0], tokenScopes)
 			if err != nil {
-				err = database.QueryRow(`
-					SELECT id, canonical_name, display_name FROM persons 
-					WHERE canonical_name LIKE ? OR display_name LIKE ?
-					LIMIT 1
-				`, "%"+personRef+"%", "%"+personRef+"%").Scan(&personID, &personName, &displayName)
-			}
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: Person not found: %s\n", personRef)
+				result := Result{OK: false, Message: err.Error()}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
 				os.Exit(1)
 			}
 
-			// Get all facts
-			facts, _ := identify.GetFactsForPerson(database, personID)
-
-			// Get contact identifiers
-			rows, _ := database.Query(`
-				SELECT ci.type, ci.value
-				FROM person_contact_links pcl
-				JOIN contact_identifiers ci ON pcl.contact_id = ci.contact_id
-				WHERE pcl.person_id = ?
-			`, personID)
-			var identities []string
-			if rows != nil {
-				for rows.Next() {
-					var ch, id string
-					rows.Scan(&ch, &id)
-					identities = append(identities, fmt.Sprintf("%s:%s", ch, id))
-				}
-				rows.Close()
+			if jsonOutput {
+				printJSON(Result{OK: true, Token: token, Secret: secret})
+				return
 			}
+			fmt.Printf("✓ Created token %s (%s)\n", token.Name, strings.Join(token.Scopes, ", "))
+			fmt.Printf("  %s\n", secret)
+			fmt.Println("  Store it now: it cannot be shown again.")
+		},
+	}
+	tokenCreateCmd.Flags().StringSliceVar(&tokenScopes, "scope", nil, "Scope to grant (repeatable or comma-separated): read-graph, read-events, write-merges, write-drafts, write-events, admin")
 
-			// Get event count
-			var eventCount int
-			database.QueryRow(`
-				SELECT COUNT(DISTINCT ep.event_id)
-				FROM event_participants ep
-				JOIN person_contact_links pcl ON ep.contact_id = pcl.contact_id
-				WHERE pcl.person_id = ?
-			`, personID).Scan(&eventCount)
+	tokenListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List API tokens",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool           `json:"ok"`
+				Tokens  []server.Token `json:"tokens"`
+				Message string         `json:"message,omitempty"`
+			}
 
-			// Format output
-			fmt.Println("╭─────────────────────────────────────────╮")
-			fmt.Printf("│  %s\n", personName)
-			if displayName.Valid && displayName.String != "" && displayName.String != personName {
-				fmt.Printf("│  aka %s\n", displayName.String)
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
-			fmt.Println("╰─────────────────────────────────────────╯")
-			fmt.Printf("  ID: %s\n", personID[:8])
-			fmt.Printf("  Events: %d\n", eventCount)
-			fmt.Println()
+			defer database.Close()
 
-			if len(identities) > 0 {
-				fmt.Println("  📇 Identities:")
-				for _, id := range identities {
-					fmt.Printf("     • %s\n", id)
+			tokens, err := server.ListTokens(database)
+			if err != nil {
+				result := Result{OK: false, Message: err.Error()}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
 				}
-				fmt.Println()
+				os.Exit(1)
 			}
 
-			// Group facts by category
-			factsByCategory := make(map[string][]identify.PersonFact)
-			for _, f := range facts {
-				factsByCategory[f.Category] = append(factsByCategory[f.Category], f)
+			if jsonOutput {
+				printJSON(Result{OK: true, Tokens: tokens})
+				return
+			}
+			if len(tokens) == 0 {
+				fmt.Println("No API tokens.")
+				return
 			}
+			for _, t := range tokens {
+				status := "never used"
+				if t.LastUsedAt != nil {
+					status = "last used " + t.LastUsedAt.Format("2006-01-02 15:04")
+				}
+				if t.RevokedAt != nil {
+					status = "revoked " + t.RevokedAt.Format("2006-01-02")
+				}
+				fmt.Printf("  %-20s %-40s %s\n", t.Name, strings.Join(t.Scopes, ","), status)
+			}
+		},
+	}
 
-			categoryOrder := []string{
-				identify.CategoryCoreIdentity,
-				identify.CategoryContactInfo,
-				identify.CategoryProfessional,
-				identify.CategoryRelationships,
-				identify.CategoryLocation,
-				identify.CategoryEducation,
+	tokenRevokeCmd := &cobra.Command{
+		Use:   "revoke <name-or-id>",
+		Short: "Revoke an API token",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool          `json:"ok"`
+				Token   *server.Token `json:"token,omitempty"`
+				Message string        `json:"message,omitempty"`
 			}
 
-			categoryEmoji := map[string]string{
-				identify.CategoryCoreIdentity:    "👤",
-				identify.CategoryContactInfo:     "📞",
-				identify.CategoryProfessional:    "💼",
-				identify.CategoryRelationships:   "👨‍👩‍👧‍👦",
-				identify.CategoryLocation:        "📍",
-				identify.CategoryEducation:       "🎓",
-				identify.CategoryDigitalIdentity: "🌐",
-				identify.CategoryGovernmentID:    "🪪",
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
+			defer database.Close()
 
-			for _, cat := range categoryOrder {
-				catFacts, ok := factsByCategory[cat]
-				if !ok || len(catFacts) == 0 {
-					continue
-				}
-				emoji := categoryEmoji[cat]
-				if emoji == "" {
-					emoji = "📋"
-				}
-				fmt.Printf("  %s %s:\n", emoji, cat)
-				for _, f := range catFacts {
-					fmt.Printf("     %s: %s\n", f.FactType, f.FactValue)
+			token, err := server.RevokeToken(database, args[0])
+			if err != nil {
+				result := Result{OK: false, Message: err.Error()}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
 				}
-				fmt.Println()
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Token: token})
+			} else {
+				fmt.Printf("✓ Revoked token %s\n", token.Name)
 			}
 		},
 	}
 
-	personCmd.AddCommand(personFactsCmd)
-	personCmd.AddCommand(personProfileCmd)
-	rootCmd.AddCommand(personCmd)
+	tokenCmd.AddCommand(tokenCreateCmd)
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenRevokeCmd)
+	rootCmd.AddCommand(tokenCmd)
 
 	// unattributed command - manage unattributed facts
 	unattributedCmd := &cobra.Command{
@@ -3758,6 +9538,7 @@ The algorithm runs in three phases:
 				SharedBy  string `json:"shared_by,omitempty"`
 				Context   string `json:"context,omitempty"`
 				Resolved  bool   `json:"resolved"`
+				Discarded bool   `json:"discarded,omitempty"`
 			}
 
 			type Result struct {
@@ -3768,23 +9549,17 @@ The algorithm runs in three phases:
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
 			query := `
-				SELECT uf.id, uf.fact_type, uf.fact_value, p.canonical_name, uf.context, uf.resolved_to_person_id
+				SELECT uf.id, uf.fact_type, uf.fact_value, p.canonical_name, uf.context, uf.resolved_to_person_id, uf.discarded_at
 				FROM unattributed_facts uf
 				LEFT JOIN persons p ON uf.shared_by_person_id = p.id
 			`
 			if unattributedUnresolved {
-				query += ` WHERE uf.resolved_to_person_id IS NULL`
+				query += ` WHERE uf.resolved_to_person_id IS NULL AND uf.discarded_at IS NULL`
 			}
 			query += ` ORDER BY uf.created_at DESC LIMIT 100`
 
@@ -3801,10 +9576,12 @@ The algorithm runs in three phases:
 			defer rows.Close()
 
 			var infos []FactInfo
+			var sensitiveReads []audit.Read
 			for rows.Next() {
 				var info FactInfo
 				var sharedBy, context, resolvedTo sql.NullString
-				rows.Scan(&info.ID, &info.FactType, &info.FactValue, &sharedBy, &context, &resolvedTo)
+				var discardedAt sql.NullInt64
+				rows.Scan(&info.ID, &info.FactType, &info.FactValue, &sharedBy, &context, &resolvedTo, &discardedAt)
 				if sharedBy.Valid {
 					info.SharedBy = sharedBy.String
 				}
@@ -3812,7 +9589,22 @@ The algorithm runs in three phases:
 					info.Context = context.String
 				}
 				info.Resolved = resolvedTo.Valid
+				info.Discarded = discardedAt.Valid
 				infos = append(infos, info)
+				if identify.IsSensitiveUnattributedFact(info.FactType) {
+					sensitiveReads = append(sensitiveReads, audit.Read{Table: audit.TableUnattributedFacts, RowID: info.ID, PersonID: resolvedTo.String, FactType: info.FactType})
+				}
+			}
+			rows.Close()
+
+			if err := audit.Log(database, auditCaller(cmd), sensitiveReads); err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to write audit log: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
 			}
 
 			result := Result{OK: true, Facts: infos}
@@ -3828,6 +9620,8 @@ The algorithm runs in three phases:
 						resolvedStr := ""
 						if f.Resolved {
 							resolvedStr = " [RESOLVED]"
+						} else if f.Discarded {
+							resolvedStr = " [DISCARDED]"
 						}
 						fmt.Printf("  [%s]%s %s: %s\n", f.ID[:8], resolvedStr, f.FactType, f.FactValue)
 						if f.SharedBy != "" {
@@ -3859,9 +9653,89 @@ The algorithm runs in three phases:
 				Message string `json:"message,omitempty"`
 			}
 
-			database, err := db.Open()
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			factRef := args[0]
+			personRef := args[1]
+
+			// Find the fact (try full ID first, then prefix match)
+			var factID string
+			err = database.QueryRow(`SELECT id FROM unattributed_facts WHERE id = ?`, factRef).Scan(&factID)
+			if err != nil {
+				err = database.QueryRow(`SELECT id FROM unattributed_facts WHERE id LIKE ?`, factRef+"%").Scan(&factID)
+			}
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Fact not found: %s", factRef)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			// Find person
+			personID, err := findPersonID(database, personRef)
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Person not found: %s", personRef)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			// Resolve the fact and record it as a person fact
+			err = identify.AttributeFact(database, factID, personID, "manual attribution")
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to update: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			result := Result{OK: true, Message: "Fact attributed successfully"}
+
+			if jsonOutput {
+				printJSON(result)
+			} else {
+				fmt.Println("✓ Fact attributed successfully")
+			}
+		},
+	}
+
+	// unattributed discard - drop a fact from the review queue
+	var unattributedDiscardReason string
+	unattributedDiscardCmd := &cobra.Command{
+		Use:   "discard <fact_id>",
+		Short: "Discard an unattributed fact so it is never attributed",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool   `json:"ok"`
+				Message string `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			fact, err := identify.GetUnattributedFact(database, args[0])
+			if err == nil {
+				err = identify.DiscardUnattributedFact(database, fact.ID, unattributedDiscardReason)
+			}
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
+				result := Result{OK: false, Message: err.Error()}
 				if jsonOutput {
 					printJSON(result)
 				} else {
@@ -3869,19 +9743,48 @@ The algorithm runs in three phases:
 				}
 				os.Exit(1)
 			}
-			defer database.Close()
 
-			factRef := args[0]
-			personRef := args[1]
+			if jsonOutput {
+				printJSON(Result{OK: true, Message: "Fact discarded"})
+			} else {
+				fmt.Println("✓ Fact discarded")
+			}
+		},
+	}
+	unattributedDiscardCmd.Flags().StringVar(&unattributedDiscardReason, "reason", "", "Why the fact was discarded")
 
-			// Find the fact (try full ID first, then prefix match)
-			var factID string
-			err = database.QueryRow(`SELECT id FROM unattributed_facts WHERE id = ?`, factRef).Scan(&factID)
+	// unattributed resolve - re-attempt attribution against the current person graph
+	var unattributedResolveDryRun bool
+	unattributedResolveCmd := &cobra.Command{
+		Use:   "resolve",
+		Short: "Re-attempt attribution of pending facts",
+		Long: `Re-attempt attribution of every pending unattributed fact against the
+current person graph. A fact is attributed when exactly one person matches,
+first by identifier (a contact identifier or person fact with the same
+value), then by the extractor's possible attributions (name, display name,
+or name facts such as nicknames). Ambiguous facts stay in the queue.
+
+Also runs as part of 'mnemonic identify resolve'.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK          bool                              `json:"ok"`
+				Checked     int                               `json:"checked"`
+				Resolved    int                               `json:"resolved"`
+				Ambiguous   int                               `json:"ambiguous"`
+				Resolutions []identify.UnattributedResolution `json:"resolutions,omitempty"`
+				DryRun      bool                              `json:"dry_run,omitempty"`
+				Message     string                            `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
 			if err != nil {
-				err = database.QueryRow(`SELECT id FROM unattributed_facts WHERE id LIKE ?`, factRef+"%").Scan(&factID)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
+			defer database.Close()
+
+			stats, err := identify.ResolveUnattributedFacts(database, unattributedResolveDryRun)
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Fact not found: %s", factRef)}
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to resolve facts: %v", err)}
 				if jsonOutput {
 					printJSON(result)
 				} else {
@@ -3890,35 +9793,55 @@ The algorithm runs in three phases:
 				os.Exit(1)
 			}
 
-			// Find person
-			var personID string
-			err = database.QueryRow(`SELECT id FROM persons WHERE id = ?`, personRef).Scan(&personID)
-			if err != nil {
-				err = database.QueryRow(`
-					SELECT id FROM persons 
-					WHERE canonical_name LIKE ? OR display_name LIKE ?
-					LIMIT 1
-				`, "%"+personRef+"%", "%"+personRef+"%").Scan(&personID)
+			result := Result{
+				OK:          true,
+				Checked:     stats.Checked,
+				Resolved:    stats.Resolved,
+				Ambiguous:   stats.Ambiguous,
+				Resolutions: stats.Resolutions,
+				DryRun:      unattributedResolveDryRun,
+			}
+			if jsonOutput {
+				printJSON(result)
+				return
+			}
+
+			verb := "Attributed"
+			if unattributedResolveDryRun {
+				verb = "Would attribute"
+			}
+			fmt.Printf("✓ Checked %d pending facts: %d attributed, %d ambiguous\n", stats.Checked, stats.Resolved, stats.Ambiguous)
+			for _, r := range stats.Resolutions {
+				fmt.Printf("  %s %s %s to %s (%s)\n", verb, r.FactType, r.FactValue, r.PersonName, r.Evidence)
+			}
+		},
+	}
+	unattributedResolveCmd.Flags().BoolVar(&unattributedResolveDryRun, "dry-run", false, "Show attributions without making changes")
+
+	// unattributed review - interactively assign or discard pending facts
+	var unattributedReviewLimit int
+	unattributedReviewCmd := &cobra.Command{
+		Use:   "review",
+		Short: "Review pending unattributed facts one at a time",
+		Long: `Walk the queue of pending unattributed facts. For each fact, type a
+person name or ID to attribute it, d to discard it, s to skip, or q to quit.
+With --json the queue is printed instead of prompting.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool                        `json:"ok"`
+				Facts   []identify.UnattributedFact `json:"facts"`
+				Message string                      `json:"message,omitempty"`
 			}
+
+			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Person not found: %s", personRef)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
+			defer database.Close()
 
-			// Update the fact
-			now := time.Now().Unix()
-			_, err = database.Exec(`
-				UPDATE unattributed_facts 
-				SET resolved_to_person_id = ?, resolution_evidence = 'manual attribution', resolved_at = ?
-				WHERE id = ?
-			`, personID, now, factID)
+			facts, err := identify.ListUnattributedFacts(database, identify.UnattributedPending, unattributedReviewLimit)
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to update: %v", err)}
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to load queue: %v", err)}
 				if jsonOutput {
 					printJSON(result)
 				} else {
@@ -3927,18 +9850,71 @@ The algorithm runs in three phases:
 				os.Exit(1)
 			}
 
-			result := Result{OK: true, Message: "Fact attributed successfully"}
-
 			if jsonOutput {
-				printJSON(result)
-			} else {
-				fmt.Println("✓ Fact attributed successfully")
+				printJSON(Result{OK: true, Facts: facts})
+				return
+			}
+			if len(facts) == 0 {
+				fmt.Println("No pending unattributed facts")
+				return
+			}
+
+			reader := bufio.NewReader(os.Stdin)
+			attributed, discarded := 0, 0
+			for i, f := range facts {
+				fmt.Printf("\n[%d/%d] %s: %s\n", i+1, len(facts), f.FactType, f.FactValue)
+				if f.SharedByName != nil {
+					fmt.Printf("  Shared by: %s\n", *f.SharedByName)
+				}
+				if f.Context != nil && *f.Context != "" {
+					fmt.Printf("  Context: %s\n", *f.Context)
+				}
+				if len(f.PossibleAttributions) > 0 {
+					fmt.Printf("  Possibly: %s\n", strings.Join(f.PossibleAttributions, ", "))
+				}
+				fmt.Print("Person, or [d]iscard/[s]kip/[q]uit: ")
+
+				line, err := reader.ReadString('\n')
+				answer := strings.TrimSpace(line)
+				if err != nil && answer == "" {
+					break
+				}
+				switch strings.ToLower(answer) {
+				case "q":
+					fmt.Printf("\n✓ Attributed %d, discarded %d\n", attributed, discarded)
+					return
+				case "", "s":
+					continue
+				case "d":
+					if err := identify.DiscardUnattributedFact(database, f.ID, "discarded in review"); err != nil {
+						fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+						continue
+					}
+					discarded++
+					continue
+				}
+
+				personID, err := findPersonID(database, answer)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: person not found: %s\n", answer)
+					continue
+				}
+				if err := identify.AttributeFact(database, f.ID, personID, "manual attribution"); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					continue
+				}
+				attributed++
 			}
+			fmt.Printf("\n✓ Attributed %d, discarded %d\n", attributed, discarded)
 		},
 	}
+	unattributedReviewCmd.Flags().IntVar(&unattributedReviewLimit, "limit", 20, "Number of facts to review")
 
 	unattributedCmd.AddCommand(unattributedListCmd)
 	unattributedCmd.AddCommand(unattributedAttributeCmd)
+	unattributedCmd.AddCommand(unattributedDiscardCmd)
+	unattributedCmd.AddCommand(unattributedResolveCmd)
+	unattributedCmd.AddCommand(unattributedReviewCmd)
 	rootCmd.AddCommand(unattributedCmd)
 
 	// timeline command
@@ -4021,14 +9997,7 @@ Examples:
 			// Open database
 			database, err := db.Open()
 			if err != nil {
-				result.OK = false
-				result.Message = fmt.Sprintf("Failed to open database: %v", err)
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -4110,6 +10079,96 @@ Examples:
 	timelineCmd.Flags().Bool("week", false, "Show this week's events")
 	rootCmd.AddCommand(timelineCmd)
 
+	// pair command - history between two entities
+	var pairEdgesOnly, pairPickFirst bool
+	pairCmd := &cobra.Command{
+		Use:   "pair <a> <b>",
+		Short: "Show the history between two entities",
+		Long: `Show the history between two entities, oldest first: when each
+relationship between them started and ended (met, started dating, moved in,
+broke up), and the episodes that mention both, with the facts about the pair
+taken from each. Relationships without a date are placed at their first
+mention.
+
+Entities are given by ID or name; ambiguous names are resolved as in
+'entity show'.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK       bool                       `json:"ok"`
+				A        *memory.Entity             `json:"a,omitempty"`
+				B        *memory.Entity             `json:"b,omitempty"`
+				Timeline []memory.PairTimelineEntry `json:"timeline,omitempty"`
+				Message  string                     `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+				}
+				os.Exit(1)
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			ctx := context.Background()
+			engine := memory.NewQueryEngine(database)
+			defer engine.Close()
+
+			var pair [2]*memory.EntityCandidate
+			for i, ref := range args {
+				picked, candidates, err := pickEntity(ctx, engine, ref, nil, pairPickFirst)
+				if err != nil {
+					fail(err.Error())
+				}
+				if picked == nil {
+					if !jsonOutput {
+						printEntityCandidates(candidates)
+					}
+					fail(fmt.Sprintf("%q matches %d entities; pass an ID or --pick-first", ref, len(candidates)))
+				}
+				pair[i] = picked
+			}
+
+			timeline, err := engine.GetPairTimeline(ctx, pair[0].ID, pair[1].ID, !pairEdgesOnly)
+			if err != nil {
+				fail(fmt.Sprintf("Failed to build timeline: %v", err))
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, A: &pair[0].Entity, B: &pair[1].Entity, Timeline: timeline})
+				return
+			}
+			fmt.Printf("%s and %s\n\n", pair[0].CanonicalName, pair[1].CanonicalName)
+			if len(timeline) == 0 {
+				fmt.Println("No shared history")
+				return
+			}
+			for _, e := range timeline {
+				at := e.At
+				if len(at) > 10 {
+					at = at[:10]
+				}
+				if e.Kind == memory.PairSharedEpisode {
+					fmt.Printf("  %-10s  episode  %s %s\n", at, e.Channel, e.EpisodeID)
+					for _, fact := range e.Facts {
+						fmt.Printf("  %-10s           %s\n", "", fact)
+					}
+					continue
+				}
+				fmt.Printf("  %-10s  %-7s  %s\n", at, e.Kind, e.Fact)
+			}
+		},
+	}
+	pairCmd.Flags().BoolVar(&pairEdgesOnly, "edges-only", false, "Only relationship changes, no shared episodes")
+	pairCmd.Flags().BoolVar(&pairPickFirst, "pick-first", false, "When a name is ambiguous, take the most mentioned match")
+	rootCmd.AddCommand(pairCmd)
+
 	// tag command
 	tagCmd := &cobra.Command{
 		Use:   "tag",
@@ -4144,16 +10203,7 @@ Examples:
 			// Open database
 			database, err := db.Open()
 			if err != nil {
-				result := Result{
-					OK:      false,
-					Message: fmt.Sprintf("Failed to open database: %v", err),
-				}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -4316,16 +10366,7 @@ Examples:
 			// Open database
 			database, err := db.Open()
 			if err != nil {
-				result := Result{
-					OK:      false,
-					Message: fmt.Sprintf("Failed to open database: %v", err),
-				}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -4524,16 +10565,7 @@ Examples:
 			// Open database
 			database, err := db.Open()
 			if err != nil {
-				result := Result{
-					OK:      false,
-					Message: fmt.Sprintf("Failed to open database: %v", err),
-				}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -4661,6 +10693,235 @@ Examples:
 	dbCmd.AddCommand(dbQueryCmd)
 	rootCmd.AddCommand(dbCmd)
 
+	// dev command
+	devCmd := &cobra.Command{
+		Use:   "dev",
+		Short: "Development and demo helpers",
+	}
+
+	var seedOpts seed.Options
+	var seedTruth string
+	var seedReset, seedAllowExisting bool
+	devSeedCmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Generate a fake communications corpus",
+		Long: `Generate fake people, one-on-one and group threads, and messages, with
+facts planted at known messages, so the pipeline and UI can be demoed and
+regression-tested without real personal data.
+
+The planted facts are the ground truth: --truth writes them as JSON for
+comparing against what extraction finds. Seeded data is tagged with the
+dev-seed adapter; 'dev clear' removes it.
+
+Examples:
+  mnemonic dev seed --people 200 --events 100000
+  mnemonic dev seed --people 20 --events 2000 --seed 7 --truth truth.json
+  mnemonic dev seed --reset`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool         `json:"ok"`
+				Removed int64        `json:"removed_events,omitempty"`
+				Seed    *seed.Result `json:"seed,omitempty"`
+				Truth   string       `json:"truth_path,omitempty"`
+				Message string       `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+				}
+				os.Exit(1)
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+			ctx := context.Background()
+
+			// Fake data mixed into a real corpus is hard to tell apart later
+			if !seedAllowExisting {
+				var real int
+				if err := database.QueryRow(`SELECT COUNT(*) FROM events WHERE source_adapter != ?`, seed.SourceAdapter).Scan(&real); err != nil {
+					fail(fmt.Sprintf("Failed to count events: %v", err))
+				}
+				if real > 0 {
+					fail(fmt.Sprintf("Database has %d real events; seed a separate data dir (MNEMONIC_DATA_DIR) or pass --allow-existing", real))
+				}
+			}
+
+			result := Result{OK: true}
+			if seedReset {
+				if result.Removed, err = seed.Remove(ctx, database); err != nil {
+					fail(fmt.Sprintf("Failed to remove seeded data: %v", err))
+				}
+			}
+			if result.Seed, err = seed.Generate(ctx, database, seedOpts); err != nil {
+				fail(fmt.Sprintf("Failed to seed: %v", err))
+			}
+			if seedTruth != "" {
+				if err := seed.WriteTruth(seedTruth, result.Seed.Facts); err != nil {
+					fail(fmt.Sprintf("Failed to write ground truth: %v", err))
+				}
+				result.Truth = seedTruth
+			}
+
+			if jsonOutput {
+				printJSON(result)
+				return
+			}
+			if result.Removed > 0 {
+				fmt.Printf("Removed %d previously seeded events\n", result.Removed)
+			}
+			fmt.Printf("Seeded %d people, %d threads (%d groups), %d events, %d planted facts\n",
+				result.Seed.People, result.Seed.Threads, result.Seed.Groups, result.Seed.Events, len(result.Seed.Facts))
+			if result.Truth != "" {
+				fmt.Printf("Ground truth written to %s\n", result.Truth)
+			}
+		},
+	}
+	devSeedCmd.Flags().IntVar(&seedOpts.People, "people", 200, "People to create (besides you)")
+	devSeedCmd.Flags().IntVar(&seedOpts.Events, "events", 100000, "Messages to create")
+	devSeedCmd.Flags().IntVar(&seedOpts.Groups, "groups", 0, "Group chats (default people/10)")
+	devSeedCmd.Flags().IntVar(&seedOpts.Facts, "facts", 0, "Facts to plant (default one per person)")
+	devSeedCmd.Flags().IntVar(&seedOpts.Days, "days", 365, "Days of history, ending now")
+	devSeedCmd.Flags().Int64Var(&seedOpts.Seed, "seed", 1, "Random seed; the same seed gives the same corpus")
+	devSeedCmd.Flags().StringVar(&seedTruth, "truth", "", "Write planted facts (ground truth) to this JSON file")
+	devSeedCmd.Flags().BoolVar(&seedReset, "reset", false, "Remove previously seeded data first")
+	devSeedCmd.Flags().BoolVar(&seedAllowExisting, "allow-existing", false, "Seed even though the database has real events")
+	devCmd.AddCommand(devSeedCmd)
+
+	devClearCmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Remove data created by 'dev seed'",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			removed, err := seed.Remove(context.Background(), database)
+			if err != nil {
+				if jsonOutput {
+					printJSON(map[string]any{"ok": false, "message": err.Error()})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				}
+				os.Exit(1)
+			}
+			if jsonOutput {
+				printJSON(map[string]any{"ok": true, "removed_events": removed})
+			} else {
+				fmt.Printf("Removed %d seeded events\n", removed)
+			}
+		},
+	}
+	devCmd.AddCommand(devClearCmd)
+	rootCmd.AddCommand(devCmd)
+
+	// debug command
+	debugCmd := &cobra.Command{
+		Use:   "debug",
+		Short: "Diagnostics for bug reports",
+	}
+
+	var bundleOutput string
+	var bundleSamples int
+	debugBundleCmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Write an anonymized diagnostics archive for a bug report",
+		Long: `Write a zip archive with schema versions, row counts, metrics snapshots,
+recent errors, the tail of the sync log, and a few sample rows per table.
+
+Everything personal is removed: IDs become salted hashes, message content,
+names, and other free text are replaced by their length, and error messages
+are scrubbed of emails, phone numbers, URLs, and home directories. The
+archive is plain JSON inside - look before you share.
+
+Works on databases with an outdated schema too, so it can be used to report
+migration problems.
+
+Examples:
+  mnemonic debug bundle
+  mnemonic debug bundle -o /tmp/report.zip --samples 10`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK       bool                  `json:"ok"`
+				Path     string                `json:"path,omitempty"`
+				Manifest *debugbundle.Manifest `json:"manifest,omitempty"`
+				Message  string                `json:"message,omitempty"`
+			}
+			fail := func(msg string) {
+				if jsonOutput {
+					printJSON(Result{OK: false, Message: msg})
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+				}
+				os.Exit(1)
+			}
+
+			database, err := db.Open()
+			if errors.Is(err, errs.ErrSchemaOutdated) {
+				// A schema from a newer build is worth reporting; read it as-is
+				if dbPath, pathErr := db.GetPath(); pathErr == nil {
+					database, err = sql.Open("sqlite", "file:"+dbPath+"?mode=ro")
+				}
+			}
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			opts := debugbundle.Options{
+				Version:    version,
+				SchemaWant: db.SchemaVersion,
+				Samples:    bundleSamples,
+			}
+			if cfg, err := config.Load(); err == nil {
+				opts.Config = cfg
+			}
+			if dataDir, err := config.GetDataDir(); err == nil {
+				opts.LogPath = filepath.Join(dataDir, "mnemonic-sync.log")
+			}
+
+			path := bundleOutput
+			if path == "" {
+				path = fmt.Sprintf("mnemonic-debug-%s.zip", time.Now().Format("20060102-150405"))
+			}
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+			if err != nil {
+				fail(fmt.Sprintf("Failed to create %s: %v", path, err))
+			}
+			manifest, err := debugbundle.Write(context.Background(), database, f, opts)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				fail(fmt.Sprintf("Failed to write bundle: %v", err))
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Path: path, Manifest: manifest})
+				return
+			}
+			fmt.Printf("Wrote %s (schema %d, build wants %d)\n", path, manifest.SchemaVersion, manifest.SchemaWant)
+			for _, name := range manifest.Files {
+				fmt.Printf("  %s\n", name)
+			}
+		},
+	}
+	debugBundleCmd.Flags().StringVarP(&bundleOutput, "output", "o", "", "Archive path (default mnemonic-debug-<timestamp>.zip)")
+	debugBundleCmd.Flags().IntVar(&bundleSamples, "samples", 5, "Sample rows per table")
+	debugCmd.AddCommand(debugBundleCmd)
+	rootCmd.AddCommand(debugCmd)
+
 	// chunk command
 	chunkCmd := &cobra.Command{
 		Use:   "chunk",
@@ -4686,13 +10947,7 @@ Examples:
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -4782,13 +11037,7 @@ Examples:
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -4846,13 +11095,7 @@ Examples:
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -4875,87 +11118,517 @@ Examples:
 				}
 				os.Exit(1)
 			}
-			created++
+			created++
+
+			// Create gmail_thread definition
+			threadConfig := chunk.ThreadConfig{}
+			_, err = chunk.CreateDefinition(ctx, database, "gmail_thread", "gmail", "thread", threadConfig,
+				"Gmail segments using native thread boundaries")
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to create gmail_thread: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+			created++
+
+			// Create single_event definition (channel-agnostic)
+			singleEventConfig := chunk.SingleEventConfig{}
+			_, err = chunk.CreateDefinition(ctx, database, "single_event", "", "single_event", singleEventConfig,
+				"Single-event segments (one event per event)")
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to create single_event: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+			created++
+
+			// Create ai_session definition
+			aiThreadConfig := chunk.ThreadConfig{}
+			_, err = chunk.CreateDefinition(ctx, database, "ai_session", "cursor", "thread", aiThreadConfig,
+				"AI sessions (one segment per session thread)")
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to create ai_session: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+			created++
+
+			// Create ai_turn_pair definition
+			turnPairConfig := chunk.TurnPairConfig{IncludeTools: true}
+			_, err = chunk.CreateDefinition(ctx, database, "ai_turn_pair", "cursor", "turn_pair", turnPairConfig,
+				"AI turn pairs (user + assistant responses, includes tool events)")
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to create ai_turn_pair: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+			created++
+
+			result := Result{OK: true, Created: created}
+
+			if jsonOutput {
+				printJSON(result)
+			} else {
+				fmt.Printf("Created %d segment definition(s):\n", created)
+				fmt.Println("  - imessage_3hr (3-hour gap, thread-scoped)")
+				fmt.Println("  - gmail_thread (native Gmail threads)")
+				fmt.Println("  - single_event (one event per segment)")
+				fmt.Println("  - ai_session (AI sessions by thread)")
+				fmt.Println("  - ai_turn_pair (AI turn pairs, includes tools)")
+			}
+		},
+	}
+
+	chunkCmd.AddCommand(chunkListCmd)
+	chunkCmd.AddCommand(chunkSeedCmd)
+	chunkCmd.AddCommand(chunkRunCmd)
+	rootCmd.AddCommand(chunkCmd)
+
+	// episodes command
+	episodesCmd := &cobra.Command{
+		Use:   "episodes",
+		Short: "Manage episodes and how they are chunked",
+	}
+
+	defsCmd := &cobra.Command{
+		Use:   "defs",
+		Short: "Manage episode definitions",
+		Long: `Episode definitions say how events are chunked into episodes: which
+channel (or all), which strategy, and the strategy's config.
+
+Strategies and their config:
+  time_gap      {"gap_seconds": 10800, "scope": "thread"|"channel"}
+  thread        {}  one episode per thread
+  single_event  {"source_adapter": "..."}  one episode per event
+  turn_pair     {"include_tools": true}  a user turn and the replies to it`,
+	}
+
+	// failDefs prints a defs command error and exits.
+	failDefs := func(msg string) {
+		if jsonOutput {
+			printJSON(map[string]any{"ok": false, "message": msg})
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+		}
+		os.Exit(1)
+	}
+	printDefinition := func(def *chunk.Definition) {
+		channel := def.Channel
+		if channel == "" {
+			channel = "all"
+		}
+		fmt.Printf("  %s\n", def.Name)
+		fmt.Printf("    Strategy: %s\n", def.Strategy)
+		fmt.Printf("    Channel:  %s\n", channel)
+		fmt.Printf("    Config:   %s\n", def.ConfigJSON)
+		if def.Description != "" {
+			fmt.Printf("    Description: %s\n", def.Description)
+		}
+	}
+
+	defsListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List episode definitions",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			type DefinitionInfo struct {
+				chunk.Definition
+				Episodes   int      `json:"episodes"`
+				PrimaryFor []string `json:"primary_for,omitempty"`
+			}
+			type Result struct {
+				OK          bool             `json:"ok"`
+				Definitions []DefinitionInfo `json:"definitions"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			definitions, err := chunk.ListDefinitions(context.Background(), database)
+			if err != nil {
+				failDefs(fmt.Sprintf("Failed to list definitions: %v", err))
+			}
+			primaries, err := chunk.ListPrimaryDefinitions(context.Background(), database)
+			if err != nil {
+				failDefs(err.Error())
+			}
+			result := Result{OK: true, Definitions: make([]DefinitionInfo, len(definitions))}
+			for i, def := range definitions {
+				result.Definitions[i].Definition = def
+				for _, p := range primaries {
+					if p.DefinitionID == def.ID {
+						result.Definitions[i].PrimaryFor = append(result.Definitions[i].PrimaryFor, p.Channel)
+					}
+				}
+				// Non-fatal - the count is informational
+				err := database.QueryRow(`SELECT COUNT(*) FROM episodes WHERE definition_id = ?`, def.ID).Scan(&result.Definitions[i].Episodes)
+				_ = err
+			}
+
+			if jsonOutput {
+				printJSON(result)
+				return
+			}
+			if len(definitions) == 0 {
+				fmt.Println("No episode definitions")
+				fmt.Println("\nRun 'mnemonic chunk seed' for the defaults or 'mnemonic episodes defs create'")
+				return
+			}
+			for _, def := range result.Definitions {
+				printDefinition(&def.Definition)
+				if len(def.PrimaryFor) > 0 {
+					fmt.Printf("    Primary:  %s\n", strings.Join(def.PrimaryFor, ", "))
+				}
+				fmt.Printf("    Episodes: %d\n\n", def.Episodes)
+			}
+		},
+	}
+
+	var defChannel, defStrategy, defConfig, defDescription string
+	defsCreateCmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create an episode definition",
+		Long: `Create an episode definition. The strategy and config are validated
+before anything is written.
+
+Examples:
+  mnemonic episodes defs create imessage_90min --channel imessage --strategy time_gap \
+    --config '{"gap_seconds": 5400, "scope": "thread"}'
+  mnemonic episodes defs create slack_thread --channel slack --strategy thread`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK         bool              `json:"ok"`
+				Definition *chunk.Definition `json:"definition,omitempty"`
+			}
+
+			name := strings.TrimSpace(args[0])
+			if name == "" {
+				failDefs("Name is required")
+			}
+			if err := chunk.ValidateDefinition(defStrategy, defConfig); err != nil {
+				failDefs(err.Error())
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+			ctx := context.Background()
+
+			if existing, err := chunk.GetDefinition(ctx, database, name); err != nil {
+				failDefs(err.Error())
+			} else if existing != nil {
+				failDefs(fmt.Sprintf("A definition named %q already exists (use 'episodes defs update')", name))
+			}
+			if _, err := chunk.CreateDefinition(ctx, database, name, defChannel, defStrategy, json.RawMessage(defConfig), defDescription); err != nil {
+				failDefs(fmt.Sprintf("Failed to create definition: %v", err))
+			}
+			def, err := chunk.GetDefinition(ctx, database, name)
+			if err != nil {
+				failDefs(err.Error())
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Definition: def})
+				return
+			}
+			fmt.Println("Created episode definition:")
+			printDefinition(def)
+			fmt.Printf("\nRun 'mnemonic episodes defs run %s' to chunk events\n", def.Name)
+		},
+	}
+	defsCreateCmd.Flags().StringVar(&defChannel, "channel", "", "Channel to chunk (default all channels)")
+	defsCreateCmd.Flags().StringVar(&defStrategy, "strategy", "", "Chunking strategy: "+strings.Join(chunk.Strategies, ", "))
+	defsCreateCmd.Flags().StringVar(&defConfig, "config", "{}", "Strategy config as a JSON object")
+	defsCreateCmd.Flags().StringVar(&defDescription, "description", "", "What the definition is for")
+	_ = defsCreateCmd.MarkFlagRequired("strategy")
+
+	defsUpdateCmd := &cobra.Command{
+		Use:   "update <definition>",
+		Short: "Change an episode definition",
+		Long: `Change an episode definition's name, channel, strategy, config, or
+description. Only the flags given change; the resulting strategy and config
+are validated together. Existing episodes are kept - the change applies to
+events chunked from then on.
+
+Examples:
+  mnemonic episodes defs update imessage_3hr --config '{"gap_seconds": 7200, "scope": "thread"}'
+  mnemonic episodes defs update single_event --channel ""`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK         bool              `json:"ok"`
+				Definition *chunk.Definition `json:"definition,omitempty"`
+			}
+
+			var update chunk.DefinitionUpdate
+			for flag, field := range map[string]**string{
+				"name": &update.Name, "channel": &update.Channel, "strategy": &update.Strategy,
+				"config": &update.ConfigJSON, "description": &update.Description,
+			} {
+				if cmd.Flags().Changed(flag) {
+					value, _ := cmd.Flags().GetString(flag)
+					*field = &value
+				}
+			}
+			if update == (chunk.DefinitionUpdate{}) {
+				failDefs("Nothing to change (see --help for flags)")
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			def, err := chunk.UpdateDefinition(context.Background(), database, args[0], update)
+			if err != nil {
+				failDefs(fmt.Sprintf("Failed to update definition: %v", err))
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Definition: def})
+				return
+			}
+			fmt.Println("Updated episode definition:")
+			printDefinition(def)
+		},
+	}
+	defsUpdateCmd.Flags().String("name", "", "New name")
+	defsUpdateCmd.Flags().String("channel", "", "Channel to chunk (empty for all channels)")
+	defsUpdateCmd.Flags().String("strategy", "", "Chunking strategy: "+strings.Join(chunk.Strategies, ", "))
+	defsUpdateCmd.Flags().String("config", "", "Strategy config as a JSON object")
+	defsUpdateCmd.Flags().String("description", "", "What the definition is for")
+
+	defsDeleteCmd := &cobra.Command{
+		Use:   "delete <definition>",
+		Short: "Delete an episode definition that has no episodes",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			if err := chunk.DeleteDefinition(context.Background(), database, args[0]); err != nil {
+				failDefs(fmt.Sprintf("Failed to delete definition: %v", err))
+			}
+			if jsonOutput {
+				printJSON(map[string]any{"ok": true})
+				return
+			}
+			fmt.Printf("Deleted episode definition %s\n", args[0])
+		},
+	}
+
+	defsRunCmd := &cobra.Command{
+		Use:   "run <definition>",
+		Short: "Chunk events into episodes with a definition",
+		Long: `Apply a definition's chunking strategy. Events already in one of the
+definition's episodes are skipped, so runs are incremental.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK              bool   `json:"ok"`
+				DefinitionName  string `json:"definition_name"`
+				EpisodesCreated int    `json:"episodes_created"`
+				EventsProcessed int    `json:"events_processed"`
+				Duration        string `json:"duration"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+			ctx := context.Background()
+
+			def, err := chunk.GetDefinition(ctx, database, args[0])
+			if err != nil {
+				failDefs(err.Error())
+			}
+			if def == nil {
+				failDefs(fmt.Sprintf("Definition %q not found", args[0]))
+			}
+			chunker, err := chunk.GetChunkerForDefinition(ctx, database, def.ID)
+			if err != nil {
+				failDefs(fmt.Sprintf("Failed to create chunker: %v", err))
+			}
+			chunkResult, err := chunker.Chunk(ctx, database, def.ID)
+			if err != nil {
+				failDefs(fmt.Sprintf("Chunking failed: %v", err))
+			}
+
+			result := Result{
+				OK:              true,
+				DefinitionName:  def.Name,
+				EpisodesCreated: chunkResult.EpisodesCreated,
+				EventsProcessed: chunkResult.EventsProcessed,
+				Duration:        chunkResult.Duration.String(),
+			}
+			if jsonOutput {
+				printJSON(result)
+				return
+			}
+			fmt.Printf("Chunked %d events into %d episodes using '%s' in %s\n",
+				result.EventsProcessed, result.EpisodesCreated, result.DefinitionName, result.Duration)
+		},
+	}
+
+	var defsPrimaryClear bool
+	defsPrimaryCmd := &cobra.Command{
+		Use:   "primary [channel] [definition]",
+		Short: "Show or set the primary definition per channel",
+		Long: `An event can be in episodes of several definitions (a thread episode and
+a time_gap one, say). Analysis and memory extraction only process episodes
+of a channel's primary definition, so the same messages are not processed
+twice. Channels without a primary process every definition.
+
+With no arguments, lists primary definitions. With a channel, shows (or
+with --clear, removes) its primary. With a channel and a definition, sets it.
+
+Examples:
+  mnemonic episodes defs primary
+  mnemonic episodes defs primary imessage imessage_3hr
+  mnemonic episodes defs primary imessage --clear`,
+		Args: cobra.MaximumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK        bool                      `json:"ok"`
+				Primaries []chunk.PrimaryDefinition `json:"primaries"`
+				Cleared   string                    `json:"cleared,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+			ctx := context.Background()
 
-			// Create gmail_thread definition
-			threadConfig := chunk.ThreadConfig{}
-			_, err = chunk.CreateDefinition(ctx, database, "gmail_thread", "gmail", "thread", threadConfig,
-				"Gmail segments using native thread boundaries")
-			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to create gmail_thread: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+			result := Result{OK: true}
+			switch {
+			case defsPrimaryClear:
+				if len(args) != 1 {
+					failDefs("--clear takes exactly one channel")
+				}
+				if err := chunk.ClearPrimaryDefinition(ctx, database, args[0]); err != nil {
+					failDefs(err.Error())
+				}
+				result.Cleared = args[0]
+			case len(args) == 2:
+				if _, err := chunk.SetPrimaryDefinition(ctx, database, args[0], args[1]); err != nil {
+					failDefs(fmt.Sprintf("Failed to set primary definition: %v", err))
 				}
-				os.Exit(1)
 			}
-			created++
-
-			// Create single_event definition (channel-agnostic)
-			singleEventConfig := chunk.SingleEventConfig{}
-			_, err = chunk.CreateDefinition(ctx, database, "single_event", "", "single_event", singleEventConfig,
-				"Single-event segments (one event per event)")
+			primaries, err := chunk.ListPrimaryDefinitions(ctx, database)
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to create single_event: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				failDefs(err.Error())
+			}
+			result.Primaries = []chunk.PrimaryDefinition{}
+			for _, p := range primaries {
+				if len(args) == 0 || p.Channel == args[0] {
+					result.Primaries = append(result.Primaries, p)
 				}
-				os.Exit(1)
 			}
-			created++
 
-			// Create ai_session definition
-			aiThreadConfig := chunk.ThreadConfig{}
-			_, err = chunk.CreateDefinition(ctx, database, "ai_session", "cursor", "thread", aiThreadConfig,
-				"AI sessions (one segment per session thread)")
-			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to create ai_session: %v", err)}
-				if jsonOutput {
-					printJSON(result)
+			if jsonOutput {
+				printJSON(result)
+				return
+			}
+			if result.Cleared != "" {
+				fmt.Printf("Cleared the primary definition for %s; all its definitions are processed\n", result.Cleared)
+				return
+			}
+			if len(result.Primaries) == 0 {
+				if len(args) == 1 {
+					fmt.Printf("%s has no primary definition; all its definitions are processed\n", args[0])
 				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+					fmt.Println("No primary definitions; every definition is processed")
 				}
-				os.Exit(1)
+				return
 			}
-			created++
+			for _, p := range result.Primaries {
+				fmt.Printf("  %-12s  %s\n", p.Channel, p.DefinitionName)
+			}
+		},
+	}
+	defsPrimaryCmd.Flags().BoolVar(&defsPrimaryClear, "clear", false, "Remove the channel's primary definition")
 
-			// Create ai_turn_pair definition
-			turnPairConfig := chunk.TurnPairConfig{IncludeTools: true}
-			_, err = chunk.CreateDefinition(ctx, database, "ai_turn_pair", "cursor", "turn_pair", turnPairConfig,
-				"AI turn pairs (user + assistant responses, includes tool events)")
-			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to create ai_turn_pair: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+	episodesOfCmd := &cobra.Command{
+		Use:   "of <event-id>",
+		Short: "List the episodes an event belongs to, across definitions",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK       bool                 `json:"ok"`
+				EventID  string               `json:"event_id"`
+				Episodes []chunk.EventEpisode `json:"episodes"`
 			}
-			created++
 
-			result := Result{OK: true, Created: created}
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
 
+			episodes, err := chunk.EventEpisodes(context.Background(), database, args[0])
+			if err != nil {
+				failDefs(err.Error())
+			}
+			result := Result{OK: true, EventID: args[0], Episodes: episodes}
 			if jsonOutput {
 				printJSON(result)
-			} else {
-				fmt.Printf("Created %d segment definition(s):\n", created)
-				fmt.Println("  - imessage_3hr (3-hour gap, thread-scoped)")
-				fmt.Println("  - gmail_thread (native Gmail threads)")
-				fmt.Println("  - single_event (one event per segment)")
-				fmt.Println("  - ai_session (AI sessions by thread)")
-				fmt.Println("  - ai_turn_pair (AI turn pairs, includes tools)")
+				return
+			}
+			if len(episodes) == 0 {
+				fmt.Printf("Event %s is in no episodes\n", args[0])
+				return
+			}
+			for _, ep := range episodes {
+				marker := " "
+				if ep.Primary {
+					marker = "*"
+				}
+				fmt.Printf("%s %-16s  %s  %s -> %s  (event %d of %d)\n", marker, ep.DefinitionName, ep.EpisodeID,
+					time.Unix(ep.StartTime, 0).Format("2006-01-02 15:04"), time.Unix(ep.EndTime, 0).Format("2006-01-02 15:04"),
+					ep.Position, ep.EventCount)
 			}
+			fmt.Println("\n* processed by analysis and memory extraction")
 		},
 	}
 
-	chunkCmd.AddCommand(chunkListCmd)
-	chunkCmd.AddCommand(chunkSeedCmd)
-	chunkCmd.AddCommand(chunkRunCmd)
-	rootCmd.AddCommand(chunkCmd)
+	defsCmd.AddCommand(defsListCmd)
+	defsCmd.AddCommand(defsCreateCmd)
+	defsCmd.AddCommand(defsUpdateCmd)
+	defsCmd.AddCommand(defsDeleteCmd)
+	defsCmd.AddCommand(defsRunCmd)
+	defsCmd.AddCommand(defsPrimaryCmd)
+	episodesCmd.AddCommand(defsCmd)
+	episodesCmd.AddCommand(episodesOfCmd)
+	rootCmd.AddCommand(episodesCmd)
 
 	// ==================== COMPUTE COMMAND ====================
 	computeCmd := &cobra.Command{
@@ -4977,13 +11650,13 @@ Examples:
 		Run: func(cmd *cobra.Command, args []string) {
 			database, err := db.Open()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
 			apiKey := os.Getenv("GEMINI_API_KEY")
 			geminiClient := gemini.NewClient(apiKey)
+			defer usage.Attach(database, geminiClient)()
 
 			cfg := compute.DefaultConfig()
 			if computeWorkers > 0 {
@@ -5000,6 +11673,23 @@ Examples:
 			}
 			cfg.DisableAdaptive = computeDisableAdaptive
 
+			appCfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: failed to load config: %v\n", err)
+				os.Exit(1)
+			}
+			if cfg.Power, err = power.NewGate(appCfg.Power); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid power config: %v\n", err)
+				os.Exit(1)
+			}
+			if cfg.PostProcessors, err = plugin.Load(appCfg, plugin.KindPostprocessor); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid plugin config: %v\n", err)
+				os.Exit(1)
+			}
+
+			lock := acquireInstanceLocks(cmd, database, []string{"compute"})
+			defer lock.Release()
+
 			engine, err := compute.NewEngine(database, geminiClient, cfg)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error creating engine: %v\n", err)
@@ -5017,6 +11707,9 @@ Examples:
 			} else {
 				fmt.Println("Adaptive controllers: enabled (auto-RPM, adaptive concurrency)")
 			}
+			if cfg.Power != nil {
+				fmt.Println("Power policy: enabled (jobs yield on battery and while you're active)")
+			}
 
 			// Pre-encode segments if requested (for maximum throughput)
 			if computePreload {
@@ -5113,6 +11806,7 @@ Examples:
 	computeRunCmd.Flags().BoolVar(&computePreload, "preload", false, "Pre-load all segments into cache for max throughput")
 	computeRunCmd.Flags().BoolVar(&computeDisableAdaptive, "no-adaptive", false, "Disable adaptive concurrency controller")
 	computeRunCmd.Flags().IntVar(&computeEmbedBatchSize, "embed-batch-size", 100, "Embedding batch size (max 100)")
+	computeRunCmd.Flags().Bool("force", false, "Take over the compute lock held by another process")
 
 	// compute enqueue - queue jobs
 	computeEnqueueCmd := &cobra.Command{
@@ -5123,8 +11817,7 @@ Examples:
 		Run: func(cmd *cobra.Command, args []string) {
 			database, err := db.Open()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -5154,21 +11847,26 @@ Examples:
 				count, err = engine.EnqueuePersonEmbeddings(ctx)
 			case "document-embeddings":
 				count, err = engine.EnqueueDocumentEmbeddings(ctx)
+			case "entity-embeddings":
+				count, err = engine.EnqueueEntityEmbeddings(ctx)
 			case "all-embeddings":
 				// Enqueue all embedding types
 				c1, e1 := engine.EnqueueEmbeddings(ctx)
 				c2, e2 := engine.EnqueueFacetEmbeddings(ctx)
 				c3, e3 := engine.EnqueuePersonEmbeddings(ctx)
 				c4, e4 := engine.EnqueueDocumentEmbeddings(ctx)
-				count = c1 + c2 + c3 + c4
+				c5, e5 := engine.EnqueueEntityEmbeddings(ctx)
+				count = c1 + c2 + c3 + c4 + c5
 				if e1 != nil {
 					err = e1
 				} else if e2 != nil {
 					err = e2
 				} else if e3 != nil {
 					err = e3
-				} else {
+				} else if e4 != nil {
 					err = e4
+				} else {
+					err = e5
 				}
 				if jsonOutput {
 					printJSON(map[string]any{
@@ -5177,13 +11875,14 @@ Examples:
 						"facets":    c2,
 						"persons":   c3,
 						"documents": c4,
+						"entities":  c5,
 						"total":     count,
 					})
 					return
 				}
 			default:
 				fmt.Fprintf(os.Stderr, "Unknown job type: %s\n", jobType)
-				fmt.Fprintf(os.Stderr, "Available types: analysis, embeddings, facet-embeddings, person-embeddings, document-embeddings, all-embeddings\n")
+				fmt.Fprintf(os.Stderr, "Available types: analysis, embeddings, facet-embeddings, person-embeddings, document-embeddings, entity-embeddings, all-embeddings\n")
 				os.Exit(1)
 			}
 
@@ -5208,8 +11907,7 @@ Examples:
 		Run: func(cmd *cobra.Command, args []string) {
 			database, err := db.Open()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -5247,8 +11945,7 @@ Examples:
 		Run: func(cmd *cobra.Command, args []string) {
 			database, err := db.Open()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -5684,6 +12381,19 @@ Conversation:
 				os.Exit(1)
 			}
 
+			// Registered analysis types that carry their own prompts
+			var registered []compute.AnalysisSpec
+			for _, spec := range compute.RegisteredAnalyses() {
+				if spec.Prompt == "" {
+					continue
+				}
+				if _, err := compute.EnsureAnalysisType(ctx, database, spec); err != nil {
+					fmt.Fprintf(os.Stderr, "Error seeding %s: %v\n", spec.Name, err)
+					os.Exit(1)
+				}
+				registered = append(registered, spec)
+			}
+
 			if jsonOutput {
 				printJSON(map[string]any{"ok": true, "message": "Seeded analysis types"})
 			} else {
@@ -5698,6 +12408,9 @@ Conversation:
 				fmt.Println("  - relationship_context_extraction_v1 (relationship context)")
 				fmt.Println("  - workspace_pattern_extraction_v1 (workspace conventions)")
 				fmt.Println("  - turn_quality_v1 (turn-level quality signals)")
+				for _, spec := range registered {
+					fmt.Printf("  - %s (%s)\n", spec.Name, strings.ToLower(spec.Description))
+				}
 			}
 		},
 	}
@@ -5760,36 +12473,29 @@ Examples:
 			// Get API key
 			apiKey := os.Getenv("GEMINI_API_KEY")
 			if apiKey == "" {
-				result := Result{OK: false, Query: queryText, Message: "GEMINI_API_KEY environment variable required for semantic search"}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(errs.New(errs.ErrNoAPIKey, "GEMINI_API_KEY environment variable required for semantic search"))
 			}
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Query: queryText, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
 			ctx := context.Background()
 
-			embedder := &search.GeminiEmbedder{Client: gemini.NewClient(apiKey)}
+			geminiClient := gemini.NewClient(apiKey)
+			defer usage.Attach(database, geminiClient)()
+			embedder := &search.GeminiEmbedder{Client: geminiClient}
 			model := searchModel
 			if model == "" {
 				model = "gemini-embedding-001"
 			}
 
 			searcher := search.NewSearcher(database, embedder)
+			if dataDir, err := config.GetDataDir(); err == nil {
+				searcher.SetIndexDir(filepath.Join(dataDir, "indexes"))
+			}
 			resp, err := searcher.SearchSegments(ctx, search.SegmentSearchRequest{
 				Query:         queryText,
 				Channel:       searchChannel,
@@ -5920,24 +12626,12 @@ Examples:
 
 			apiKey := os.Getenv("GEMINI_API_KEY")
 			if apiKey == "" {
-				result := Result{OK: false, Query: queryText, Message: "GEMINI_API_KEY environment variable required for routing search"}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(errs.New(errs.ErrNoAPIKey, "GEMINI_API_KEY environment variable required for routing search"))
 			}
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Query: queryText, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -5957,7 +12651,12 @@ Examples:
 				UseEmbeddings:  true,
 			}
 
-			searcher := search.NewSearcher(database, &search.GeminiEmbedder{Client: gemini.NewClient(apiKey)})
+			geminiClient := gemini.NewClient(apiKey)
+			defer usage.Attach(database, geminiClient)()
+			searcher := search.NewSearcher(database, &search.GeminiEmbedder{Client: geminiClient})
+			if dataDir, err := config.GetDataDir(); err == nil {
+				searcher.SetIndexDir(filepath.Join(dataDir, "indexes"))
+			}
 			resp, err := searcher.SearchSegments(cmd.Context(), segmentReq)
 			if err != nil {
 				result := Result{OK: false, Query: queryText, Message: fmt.Sprintf("Failed to search segments: %v", err)}
@@ -6069,12 +12768,7 @@ Examples:
 
 			database, err := db.Open()
 			if err != nil {
-				if jsonOutput {
-					printJSON(map[string]any{"ok": false, "message": fmt.Sprintf("Failed to open database: %v", err)})
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -6094,7 +12788,9 @@ Examples:
 						fmt.Fprintln(os.Stderr, "Warning: GEMINI_API_KEY not set, falling back to lexical search")
 					}
 				} else {
-					embedder = &search.GeminiEmbedder{Client: gemini.NewClient(apiKey)}
+					geminiClient := gemini.NewClient(apiKey)
+					defer usage.Attach(database, geminiClient)()
+					embedder = &search.GeminiEmbedder{Client: geminiClient}
 				}
 			}
 
@@ -6188,21 +12884,15 @@ Examples:
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
 			// Determine skills path
 			skillsPath := indexSkillsPath
 			if skillsPath == "" {
-				home, _ := os.UserHomeDir()
-				skillsPath = filepath.Join(home, "nexus", "skills")
+				nexusDir, _ := config.NexusDir()
+				skillsPath = filepath.Join(nexusDir, "skills")
 			}
 
 			var indexed, skipped, errors int
@@ -6310,6 +13000,8 @@ Examples:
 	var extractDryRun bool
 	var extractLimit int
 	var extractDefinition string
+	var extractRun bool
+	var extractConcurrency int
 
 	extractPIICmd := &cobra.Command{
 		Use:   "pii",
@@ -6317,130 +13009,45 @@ Examples:
 		Long: `Extract all personally identifiable information from segments
 using AI analysis. Creates person_facts for identity resolution.
 
+By default jobs are queued for 'compute run'. With --run, segments are
+analyzed immediately and completed runs are synced into person_facts.
+
 Examples:
   cortex extract pii --channel imessage --since 30d
   cortex extract pii --segment <segment_id>
-  cortex extract pii --person "Dad" --limit 50`,
+  cortex extract pii --person "Dad" --limit 50
+  cortex extract pii --since 7d --run`,
 		Run: func(cmd *cobra.Command, args []string) {
 			type Result struct {
-				OK                     bool   `json:"ok"`
-				JobsEnqueued           int    `json:"jobs_enqueued"`
-				ConversationsToProcess int    `json:"segments_to_process,omitempty"`
-				Message                string `json:"message,omitempty"`
+				OK                     bool                        `json:"ok"`
+				JobsEnqueued           int                         `json:"jobs_enqueued"`
+				ConversationsToProcess int                         `json:"segments_to_process,omitempty"`
+				Batch                  *compute.AnalysisBatchStats `json:"batch,omitempty"`
+				Message                string                      `json:"message,omitempty"`
 			}
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
-			// Build query to find segments to process
-			querySQL := `
-				SELECT c.id FROM segments c
-				WHERE NOT EXISTS (
-					SELECT 1 FROM analysis_runs ar
-					JOIN analysis_types at ON ar.analysis_type_id = at.id
-					WHERE ar.segment_id = c.id
-					AND at.name = 'pii_extraction'
-				)
-			`
-			var queryArgs []interface{}
-			var defID string
-			var hasDefinition bool
-
-			if extractDefinition != "" {
-				err := database.QueryRow(`SELECT id FROM segment_definitions WHERE name = ?`, extractDefinition).Scan(&defID)
-				if err != nil {
-					result := Result{OK: false, Message: fmt.Sprintf("Definition '%s' not found", extractDefinition)}
-					if jsonOutput {
-						printJSON(result)
-					} else {
-						fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-					}
-					os.Exit(1)
-				}
-				hasDefinition = true
-				querySQL += ` AND c.definition_id = ?`
-				queryArgs = append(queryArgs, defID)
-			}
-
-			if extractChannel != "" {
-				querySQL += ` AND c.channel = ?`
-				queryArgs = append(queryArgs, extractChannel)
-			}
-
-			if extractSince != "" {
-				// Parse since duration (e.g., "30d", "7d", "1h")
-				var sinceTime time.Time
-				if strings.HasSuffix(extractSince, "d") {
-					days, _ := fmt.Sscanf(extractSince, "%dd", new(int))
-					if days > 0 {
-						var d int
-						fmt.Sscanf(extractSince, "%dd", &d)
-						sinceTime = time.Now().AddDate(0, 0, -d)
-					}
-				} else if strings.HasSuffix(extractSince, "h") {
-					var h int
-					fmt.Sscanf(extractSince, "%dh", &h)
-					sinceTime = time.Now().Add(-time.Duration(h) * time.Hour)
-				} else {
-					// Try parsing as date
-					sinceTime, _ = time.Parse("2006-01-02", extractSince)
-				}
-				if !sinceTime.IsZero() {
-					querySQL += ` AND c.start_time >= ?`
-					queryArgs = append(queryArgs, sinceTime.Unix())
-				}
+			opts := compute.AnalysisBatchOptions{
+				Channel:    extractChannel,
+				Definition: extractDefinition,
+				Person:     extractPerson,
+				Limit:      extractLimit,
 			}
-
 			if extractSegment != "" {
-				querySQL = `SELECT c.id FROM segments c WHERE c.id = ?`
-				queryArgs = []interface{}{extractSegment}
-				if hasDefinition {
-					querySQL += ` AND c.definition_id = ?`
-					queryArgs = append(queryArgs, defID)
-				}
-			}
-
-			if extractPerson != "" {
-				querySQL = `
-					SELECT DISTINCT c.id FROM segments c
-					JOIN segment_events ce ON c.id = ce.segment_id
-					JOIN event_participants ep ON ce.event_id = ep.event_id
-					JOIN person_contact_links pcl ON ep.contact_id = pcl.contact_id
-					JOIN persons p ON pcl.person_id = p.id
-					WHERE (p.canonical_name LIKE ? OR p.display_name LIKE ?)
-					AND NOT EXISTS (
-						SELECT 1 FROM analysis_runs ar
-						JOIN analysis_types at ON ar.analysis_type_id = at.id
-						WHERE ar.segment_id = c.id
-						AND at.name = 'pii_extraction'
-					)
-				`
-				queryArgs = []interface{}{"%" + extractPerson + "%", "%" + extractPerson + "%"}
-				if hasDefinition {
-					querySQL += ` AND c.definition_id = ?`
-					queryArgs = append(queryArgs, defID)
-				}
+				opts.EpisodeIDs = []string{extractSegment}
 			}
-
-			querySQL += ` ORDER BY c.start_time DESC`
-
-			if extractLimit > 0 {
-				querySQL += fmt.Sprintf(` LIMIT %d`, extractLimit)
+			if extractSince != "" {
+				opts.Since = parseSince(extractSince)
 			}
 
-			// Execute query
-			rows, err := database.Query(querySQL, queryArgs...)
+			convIDs, err := compute.PendingEpisodes(context.Background(), database, compute.PIIAnalysisType, opts)
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to query segments: %v", err)}
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to find segments: %v", err)}
 				if jsonOutput {
 					printJSON(result)
 				} else {
@@ -6449,16 +13056,6 @@ Examples:
 				os.Exit(1)
 			}
 
-			var convIDs []string
-			for rows.Next() {
-				var id string
-				if err := rows.Scan(&id); err != nil {
-					continue
-				}
-				convIDs = append(convIDs, id)
-			}
-			rows.Close()
-
 			if extractDryRun {
 				result := Result{
 					OK:                     true,
@@ -6482,6 +13079,7 @@ Examples:
 			// Enqueue analysis jobs
 			apiKey := os.Getenv("GEMINI_API_KEY")
 			geminiClient := gemini.NewClient(apiKey)
+			defer usage.Attach(database, geminiClient)()
 			engine, err := compute.NewEngine(database, geminiClient, compute.DefaultConfig())
 			if err != nil {
 				result := Result{OK: false, Message: fmt.Sprintf("Failed to create compute engine: %v", err)}
@@ -6493,8 +13091,38 @@ Examples:
 				os.Exit(1)
 			}
 
-			ctx := context.Background()
-			count, err := engine.EnqueueAnalysis(ctx, "pii_extraction", convIDs...)
+			ctx := context.Background()
+			if extractRun {
+				opts.Concurrency = extractConcurrency
+				stats, err := engine.RunAnalysisBatch(ctx, compute.PIIAnalysisType, opts)
+				if err != nil {
+					result := Result{OK: false, Batch: stats, Message: fmt.Sprintf("PII extraction failed: %v", err)}
+					if jsonOutput {
+						printJSON(result)
+					} else {
+						fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+					}
+					os.Exit(1)
+				}
+				if jsonOutput {
+					printJSON(Result{OK: true, Batch: stats})
+					return
+				}
+				fmt.Printf("✓ Extracted PII from %d segments\n", stats.Completed)
+				if stats.Blocked > 0 {
+					fmt.Printf("  Blocked: %d\n", stats.Blocked)
+				}
+				if stats.Failed > 0 {
+					fmt.Printf("  Failed: %d (retried on the next run)\n", stats.Failed)
+				}
+				fmt.Printf("  Runs synced into person_facts: %d\n", stats.Synced)
+				if stats.SyncErrors > 0 {
+					fmt.Printf("  Sync errors: %d\n", stats.SyncErrors)
+				}
+				return
+			}
+
+			count, err := engine.EnqueueAnalysis(ctx, compute.PIIAnalysisType, convIDs...)
 			if err != nil {
 				result := Result{OK: false, Message: fmt.Sprintf("Failed to enqueue analysis: %v", err)}
 				if jsonOutput {
@@ -6526,6 +13154,8 @@ Examples:
 	extractPIICmd.Flags().BoolVar(&extractDryRun, "dry-run", false, "Show what would be processed without enqueueing")
 	extractPIICmd.Flags().IntVar(&extractLimit, "limit", 0, "Limit number of segments to process")
 	extractPIICmd.Flags().StringVar(&extractDefinition, "definition", "", "Filter by segment definition name")
+	extractPIICmd.Flags().BoolVar(&extractRun, "run", false, "Analyze now and sync into person_facts instead of enqueueing")
+	extractPIICmd.Flags().IntVar(&extractConcurrency, "concurrency", compute.DefaultAnalysisConcurrency, "Segments analyzed at once with --run")
 
 	// extract nexus-cli - extract nexus CLI invocations from segments
 	var extractNexusChannel string
@@ -6553,13 +13183,7 @@ Examples:
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -6676,6 +13300,7 @@ Examples:
 			}
 
 			geminiClient := gemini.NewClient("")
+			defer usage.Attach(database, geminiClient)()
 			engine, err := compute.NewEngine(database, geminiClient, compute.DefaultConfig())
 			if err != nil {
 				result := Result{OK: false, Message: fmt.Sprintf("Failed to create compute engine: %v", err)}
@@ -6746,13 +13371,7 @@ Examples:
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -6869,6 +13488,7 @@ Examples:
 			}
 
 			geminiClient := gemini.NewClient("")
+			defer usage.Attach(database, geminiClient)()
 			engine, err := compute.NewEngine(database, geminiClient, compute.DefaultConfig())
 			if err != nil {
 				result := Result{OK: false, Message: fmt.Sprintf("Failed to create compute engine: %v", err)}
@@ -6939,13 +13559,7 @@ Examples:
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -7062,6 +13676,7 @@ Examples:
 			}
 
 			geminiClient := gemini.NewClient("")
+			defer usage.Attach(database, geminiClient)()
 			engine, err := compute.NewEngine(database, geminiClient, compute.DefaultConfig())
 			if err != nil {
 				result := Result{OK: false, Message: fmt.Sprintf("Failed to create compute engine: %v", err)}
@@ -7125,13 +13740,7 @@ Examples:
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -7189,13 +13798,7 @@ Examples:
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
@@ -7271,63 +13874,452 @@ This extracts:
 - Capabilities used
 
 Examples:
-  cortex extract aix-metadata --channel cursor
-  cortex extract aix-metadata --since 7d`,
+  cortex extract aix-metadata --channel cursor
+  cortex extract aix-metadata --since 7d`,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK              bool   `json:"ok"`
+				EventsProcessed int    `json:"events_processed"`
+				EpisodesCreated int    `json:"episodes_created"`
+				FacetsCreated   int    `json:"facets_created"`
+				Duration        string `json:"duration"`
+				Message         string `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			// Parse since flag
+			var sinceTS int64
+			if extractAIXSince != "" {
+				var sinceTime time.Time
+				if strings.HasSuffix(extractAIXSince, "d") {
+					var d int
+					fmt.Sscanf(extractAIXSince, "%dd", &d)
+					if d > 0 {
+						sinceTime = time.Now().AddDate(0, 0, -d)
+					}
+				} else if strings.HasSuffix(extractAIXSince, "h") {
+					var h int
+					fmt.Sscanf(extractAIXSince, "%dh", &h)
+					if h > 0 {
+						sinceTime = time.Now().Add(-time.Duration(h) * time.Hour)
+					}
+				} else {
+					sinceTime, _ = time.Parse("2006-01-02", extractAIXSince)
+				}
+				if !sinceTime.IsZero() {
+					sinceTS = sinceTime.Unix()
+				}
+			}
+
+			channel := extractAIXChannel
+			if channel == "" {
+				channel = "cursor"
+			}
+
+			extractor := adapters.NewAIXFacetExtractor(database)
+			extractResult, err := extractor.ExtractFacetsFromMetadata(cmd.Context(), channel, sinceTS)
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Extraction failed: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			result := Result{
+				OK:              true,
+				EventsProcessed: extractResult.EventsProcessed,
+				EpisodesCreated: extractResult.EpisodesCreated,
+				FacetsCreated:   extractResult.FacetsCreated,
+				Duration:        extractResult.Duration.String(),
+			}
+
+			if jsonOutput {
+				printJSON(result)
+			} else {
+				fmt.Println("AIX Metadata Extraction Results:")
+				fmt.Printf("  Events processed: %d\n", result.EventsProcessed)
+				fmt.Printf("  Episodes created: %d\n", result.EpisodesCreated)
+				fmt.Printf("  Facets created: %d\n", result.FacetsCreated)
+				fmt.Printf("  Duration: %s\n", result.Duration)
+			}
+		},
+	}
+	extractAIXMetadataCmd.Flags().StringVar(&extractAIXChannel, "channel", "cursor", "Channel to extract from")
+	extractAIXMetadataCmd.Flags().StringVar(&extractAIXSince, "since", "", "Only process events since (e.g., 30d, 7d)")
+
+	extractCmd.AddCommand(extractPIICmd)
+	extractCmd.AddCommand(extractNexusCmd)
+	extractCmd.AddCommand(extractTerminalCmd)
+	extractCmd.AddCommand(extractTurnQualityCmd)
+	extractCmd.AddCommand(extractSyncCmd)
+	extractCmd.AddCommand(extractStatusCmd)
+	extractCmd.AddCommand(extractAIXMetadataCmd)
+	rootCmd.AddCommand(extractCmd)
+
+	// ==================== ANALYZE COMMAND ====================
+	var analyzeChannel string
+	var analyzeSince string
+	var analyzeSegment string
+	var analyzePerson string
+	var analyzeDefinition string
+	var analyzeLimit int
+	var analyzeConcurrency int
+	var analyzeDryRun bool
+
+	analyzeCmd := &cobra.Command{
+		Use:   "analyze [type]",
+		Short: "Run a registered analysis over segments",
+		Long: `Run an analysis type over segments that haven't had it yet: each segment is
+sent to the model with the type's prompt, the output is stored as an analysis
+run with its facets, and types with a sync handler (e.g., pii_extraction into
+person_facts) process completed runs. Failed segments are retried next time.
+
+With no type, lists the registered analysis types.
+
+Examples:
+  cortex analyze
+  cortex analyze sentiment --channel imessage --since 30d
+  cortex analyze action_items --since 7d --limit 100
+  cortex analyze topic_tagging --dry-run`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			type TypeInfo struct {
+				Name        string `json:"name"`
+				Version     string `json:"version"`
+				Description string `json:"description"`
+				Syncs       bool   `json:"syncs"`
+			}
+			type Result struct {
+				OK      bool                        `json:"ok"`
+				Types   []TypeInfo                  `json:"types,omitempty"`
+				Pending int                         `json:"pending,omitempty"`
+				Batch   *compute.AnalysisBatchStats `json:"batch,omitempty"`
+				Message string                      `json:"message,omitempty"`
+			}
+
+			if len(args) == 0 {
+				var types []TypeInfo
+				for _, spec := range compute.RegisteredAnalyses() {
+					types = append(types, TypeInfo{Name: spec.Name, Version: spec.Version, Description: spec.Description, Syncs: spec.Sync != nil})
+				}
+				if jsonOutput {
+					printJSON(Result{OK: true, Types: types})
+					return
+				}
+				fmt.Println("Analysis types:")
+				for _, t := range types {
+					fmt.Printf("  %-16s %s\n", t.Name, t.Description)
+				}
+				return
+			}
+			analysisType := args[0]
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			opts := compute.AnalysisBatchOptions{
+				Channel:     analyzeChannel,
+				Definition:  analyzeDefinition,
+				Person:      analyzePerson,
+				Limit:       analyzeLimit,
+				Concurrency: analyzeConcurrency,
+			}
+			if analyzeSegment != "" {
+				opts.EpisodeIDs = []string{analyzeSegment}
+			}
+			if analyzeSince != "" {
+				opts.Since = parseSince(analyzeSince)
+			}
+
+			ctx := context.Background()
+			if analyzeDryRun {
+				pending, err := compute.PendingEpisodes(ctx, database, analysisType, opts)
+				if err != nil {
+					result := Result{OK: false, Message: fmt.Sprintf("Failed to find segments: %v", err)}
+					if jsonOutput {
+						printJSON(result)
+					} else {
+						fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+					}
+					os.Exit(1)
+				}
+				if jsonOutput {
+					printJSON(Result{OK: true, Pending: len(pending)})
+					return
+				}
+				fmt.Printf("Dry run: would run %s on %d segments\n", analysisType, len(pending))
+				return
+			}
+
+			geminiClient := gemini.NewClient(os.Getenv("GEMINI_API_KEY"))
+			defer usage.Attach(database, geminiClient)()
+			engine, err := compute.NewEngine(database, geminiClient, compute.DefaultConfig())
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to create compute engine: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+			defer engine.Close()
+
+			stats, err := engine.RunAnalysisBatch(ctx, analysisType, opts)
+			if err != nil {
+				result := Result{OK: false, Batch: stats, Message: fmt.Sprintf("Analysis failed: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Batch: stats})
+				return
+			}
+			fmt.Printf("✓ Ran %s on %d segments\n", analysisType, stats.Episodes)
+			fmt.Printf("  Completed: %d\n", stats.Completed)
+			if stats.Blocked > 0 {
+				fmt.Printf("  Blocked: %d\n", stats.Blocked)
+			}
+			if stats.Failed > 0 {
+				fmt.Printf("  Failed: %d (retried on the next run)\n", stats.Failed)
+			}
+			if stats.Synced > 0 || stats.SyncErrors > 0 {
+				fmt.Printf("  Synced: %d\n", stats.Synced)
+			}
+			if stats.SyncErrors > 0 {
+				fmt.Printf("  Sync errors: %d\n", stats.SyncErrors)
+			}
+		},
+	}
+	analyzeCmd.Flags().StringVar(&analyzeChannel, "channel", "", "Only segments on this channel")
+	analyzeCmd.Flags().StringVar(&analyzeSince, "since", "", "Only segments since (e.g., 30d, 12h, 2024-01-01)")
+	analyzeCmd.Flags().StringVar(&analyzeSegment, "segment", "", "Only this segment ID")
+	analyzeCmd.Flags().StringVar(&analyzePerson, "person", "", "Only segments involving this person")
+	analyzeCmd.Flags().StringVar(&analyzeDefinition, "definition", "", "Only segments from this definition")
+	analyzeCmd.Flags().IntVar(&analyzeLimit, "limit", 0, "Limit number of segments (most recent first)")
+	analyzeCmd.Flags().IntVar(&analyzeConcurrency, "concurrency", compute.DefaultAnalysisConcurrency, "Segments analyzed at once")
+	analyzeCmd.Flags().BoolVar(&analyzeDryRun, "dry-run", false, "Show how many segments would be analyzed")
+	rootCmd.AddCommand(analyzeCmd)
+
+	// ==================== TODOS COMMAND ====================
+	var todosStatus string
+	var todosMine bool
+	var todosTheirs bool
+	var todosPerson string
+	var todosDue string
+	var todosLimit int
+
+	todosCmd := &cobra.Command{
+		Use:   "todos",
+		Short: "List action items extracted from conversations",
+		Long: `List commitments and requests found by the action_items analysis
+(run 'analyze action_items' to extract them). Items you owe are marked "→",
+items owed to you "←". Soonest due first.
+
+Examples:
+  cortex todos
+  cortex todos --mine --due 7d
+  cortex todos --person "Casey"
+  cortex todos done <id>`,
+		Run: func(cmd *cobra.Command, args []string) {
+			type Result struct {
+				OK      bool         `json:"ok"`
+				Items   []todos.Item `json:"items"`
+				Message string       `json:"message,omitempty"`
+			}
+
+			database, err := db.Open()
+			if err != nil {
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
+			}
+			defer database.Close()
+
+			opts := todos.ListOptions{Status: todosStatus, Limit: todosLimit}
+			if todosMine && !todosTheirs {
+				opts.Owner = todos.OwnerMe
+			} else if todosTheirs && !todosMine {
+				opts.Owner = todos.OwnerThem
+			}
+			if todosDue != "" {
+				// --due 7d: due within the next 7 days (or overdue)
+				var n int
+				if _, err := fmt.Sscanf(todosDue, "%dd", &n); err == nil {
+					opts.DueBefore = time.Now().AddDate(0, 0, n)
+				} else if t, err := time.ParseInLocation("2006-01-02", todosDue, time.Local); err == nil {
+					opts.DueBefore = t
+				}
+			}
+			if todosPerson != "" {
+				personID, err := findPersonID(database, todosPerson)
+				if err != nil {
+					result := Result{OK: false, Message: err.Error()}
+					if jsonOutput {
+						printJSON(result)
+					} else {
+						fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+					}
+					os.Exit(1)
+				}
+				opts.PersonID = personID
+			}
+
+			items, err := todos.List(database, opts)
+			if err != nil {
+				result := Result{OK: false, Message: fmt.Sprintf("Failed to list action items: %v", err)}
+				if jsonOutput {
+					printJSON(result)
+				} else {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+				}
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				printJSON(Result{OK: true, Items: items})
+				return
+			}
+			if len(items) == 0 {
+				fmt.Println("No action items")
+				return
+			}
+			now := time.Now()
+			for _, item := range items {
+				arrow := "→"
+				if item.Owner == todos.OwnerThem {
+					arrow = "←"
+				}
+				line := fmt.Sprintf("%s %s", arrow, item.Description)
+				if item.PersonName != nil {
+					line += fmt.Sprintf(" (%s)", *item.PersonName)
+				}
+				if item.DueAt != nil {
+					due := item.DueAt.Format("Mon Jan 2")
+					if item.DueAt.Before(now) && item.Status == todos.StatusOpen {
+						due += ", overdue"
+					}
+					line += fmt.Sprintf(" — due %s", due)
+				}
+				fmt.Printf("  %s  %s\n", item.ID[:8], line)
+			}
+		},
+	}
+	todosCmd.Flags().StringVar(&todosStatus, "status", todos.StatusOpen, "Status to list (open, done, dismissed)")
+	todosCmd.Flags().BoolVar(&todosMine, "mine", false, "Only items I owe")
+	todosCmd.Flags().BoolVar(&todosTheirs, "theirs", false, "Only items owed to me")
+	todosCmd.Flags().StringVar(&todosPerson, "person", "", "Only items with this person")
+	todosCmd.Flags().StringVar(&todosDue, "due", "", "Only items due within a window (e.g., 7d) or before a date")
+	todosCmd.Flags().IntVar(&todosLimit, "limit", 50, "Maximum items to list")
+
+	for _, action := range []struct{ use, short, status, verb string }{
+		{"done <id>", "Mark an action item done", todos.StatusDone, "Completed"},
+		{"dismiss <id>", "Dismiss an action item that isn't real or relevant", todos.StatusDismissed, "Dismissed"},
+		{"reopen <id>", "Reopen a done or dismissed action item", todos.StatusOpen, "Reopened"},
+	} {
+		action := action
+		todosCmd.AddCommand(&cobra.Command{
+			Use:   action.use,
+			Short: action.short,
+			Args:  cobra.ExactArgs(1),
+			Run: func(cmd *cobra.Command, args []string) {
+				type Result struct {
+					OK      bool        `json:"ok"`
+					Item    *todos.Item `json:"item,omitempty"`
+					Message string      `json:"message,omitempty"`
+				}
+
+				database, err := db.Open()
+				if err != nil {
+					exitWithError(fmt.Errorf("Failed to open database: %w", err))
+				}
+				defer database.Close()
+
+				item, err := todos.SetStatus(database, args[0], action.status)
+				if err != nil {
+					result := Result{OK: false, Message: err.Error()}
+					if jsonOutput {
+						printJSON(result)
+					} else {
+						fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
+					}
+					os.Exit(1)
+				}
+				if jsonOutput {
+					printJSON(Result{OK: true, Item: item})
+					return
+				}
+				fmt.Printf("✓ %s: %s\n", action.verb, item.Description)
+			},
+		})
+	}
+	rootCmd.AddCommand(todosCmd)
+
+	// ==================== GIFTS COMMAND ====================
+	var giftsWithin string
+
+	giftsCmd := &cobra.Command{
+		Use:   "gifts [person]",
+		Short: "Show gift ideas from stated preferences",
+		Long: `Show what a person has said they want, like, and dislike, and their sizes,
+with their next birthday. Preferences are extracted by the memory pipeline
+as WANTS, PREFERS, DISLIKES, and HAS_SIZE relationships.
+
+Without a person, lists everyone whose birthday is within --within.
+
+Examples:
+  cortex gifts "Casey"
+  cortex gifts --within 60d`,
+		Args: cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			type Result struct {
-				OK              bool   `json:"ok"`
-				EventsProcessed int    `json:"events_processed"`
-				EpisodesCreated int    `json:"episodes_created"`
-				FacetsCreated   int    `json:"facets_created"`
-				Duration        string `json:"duration"`
-				Message         string `json:"message,omitempty"`
+				OK       bool                 `json:"ok"`
+				Profiles []memory.GiftProfile `json:"profiles"`
+				Message  string               `json:"message,omitempty"`
 			}
 
 			database, err := db.Open()
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Failed to open database: %v", err)}
-				if jsonOutput {
-					printJSON(result)
-				} else {
-					fmt.Fprintf(os.Stderr, "Error: %s\n", result.Message)
-				}
-				os.Exit(1)
+				exitWithError(fmt.Errorf("Failed to open database: %w", err))
 			}
 			defer database.Close()
 
-			// Parse since flag
-			var sinceTS int64
-			if extractAIXSince != "" {
-				var sinceTime time.Time
-				if strings.HasSuffix(extractAIXSince, "d") {
-					var d int
-					fmt.Sscanf(extractAIXSince, "%dd", &d)
-					if d > 0 {
-						sinceTime = time.Now().AddDate(0, 0, -d)
-					}
-				} else if strings.HasSuffix(extractAIXSince, "h") {
-					var h int
-					fmt.Sscanf(extractAIXSince, "%dh", &h)
-					if h > 0 {
-						sinceTime = time.Now().Add(-time.Duration(h) * time.Hour)
+			ctx := context.Background()
+			now := time.Now()
+			var profiles []memory.GiftProfile
+			if len(args) == 1 {
+				var entityID string
+				entityID, err = findPersonEntityID(ctx, database, args[0])
+				if err == nil {
+					var profile *memory.GiftProfile
+					profile, err = memory.GetGiftProfile(ctx, database, entityID, now)
+					if profile != nil {
+						profiles = append(profiles, *profile)
 					}
-				} else {
-					sinceTime, _ = time.Parse("2006-01-02", extractAIXSince)
 				}
-				if !sinceTime.IsZero() {
-					sinceTS = sinceTime.Unix()
+			} else {
+				days := 30
+				if _, err := fmt.Sscanf(giftsWithin, "%dd", &days); err != nil {
+					days = 30
 				}
+				profiles, err = memory.UpcomingBirthdays(ctx, database, now, time.Duration(days)*24*time.Hour)
 			}
-
-			channel := extractAIXChannel
-			if channel == "" {
-				channel = "cursor"
-			}
-
-			extractor := adapters.NewAIXFacetExtractor(database)
-			extractResult, err := extractor.ExtractFacetsFromMetadata(cmd.Context(), channel, sinceTS)
 			if err != nil {
-				result := Result{OK: false, Message: fmt.Sprintf("Extraction failed: %v", err)}
+				result := Result{OK: false, Message: err.Error()}
 				if jsonOutput {
 					printJSON(result)
 				} else {
@@ -7336,40 +14328,367 @@ Examples:
 				os.Exit(1)
 			}
 
-			result := Result{
-				OK:              true,
-				EventsProcessed: extractResult.EventsProcessed,
-				EpisodesCreated: extractResult.EpisodesCreated,
-				FacetsCreated:   extractResult.FacetsCreated,
-				Duration:        extractResult.Duration.String(),
-			}
-
 			if jsonOutput {
-				printJSON(result)
-			} else {
-				fmt.Println("AIX Metadata Extraction Results:")
-				fmt.Printf("  Events processed: %d\n", result.EventsProcessed)
-				fmt.Printf("  Episodes created: %d\n", result.EpisodesCreated)
-				fmt.Printf("  Facets created: %d\n", result.FacetsCreated)
-				fmt.Printf("  Duration: %s\n", result.Duration)
+				printJSON(Result{OK: true, Profiles: profiles})
+				return
+			}
+			if len(profiles) == 0 {
+				fmt.Println("No upcoming birthdays")
+				return
+			}
+			for i, profile := range profiles {
+				if i > 0 {
+					fmt.Println()
+				}
+				header := profile.Name
+				if profile.NextBirthday != nil {
+					header += fmt.Sprintf(" — birthday %s (in %d days)", *profile.NextBirthday, *profile.DaysUntil)
+				}
+				fmt.Println(header)
+				for _, section := range []struct {
+					title string
+					items []memory.PreferenceItem
+				}{
+					{"Wants", profile.Wants},
+					{"Likes", profile.Likes},
+					{"Dislikes", profile.Dislikes},
+					{"Sizes", profile.Sizes},
+				} {
+					if len(section.items) == 0 {
+						continue
+					}
+					fmt.Printf("  %s:\n", section.title)
+					for _, item := range section.items {
+						line := fmt.Sprintf("    - %s", item.Value)
+						if item.Mentions > 1 {
+							line += fmt.Sprintf(" (x%d)", item.Mentions)
+						}
+						if item.LastMentioned != nil {
+							line += fmt.Sprintf("  last mentioned %s", (*item.LastMentioned)[:10])
+						}
+						fmt.Println(line)
+					}
+				}
+				if len(profile.Wants)+len(profile.Likes)+len(profile.Dislikes)+len(profile.Sizes) == 0 {
+					fmt.Println("  No stated preferences yet")
+				}
 			}
 		},
 	}
-	extractAIXMetadataCmd.Flags().StringVar(&extractAIXChannel, "channel", "cursor", "Channel to extract from")
-	extractAIXMetadataCmd.Flags().StringVar(&extractAIXSince, "since", "", "Only process events since (e.g., 30d, 7d)")
+	giftsCmd.Flags().StringVar(&giftsWithin, "within", "30d", "Without a person, birthdays within this window (e.g., 30d)")
+	rootCmd.AddCommand(giftsCmd)
 
-	extractCmd.AddCommand(extractPIICmd)
-	extractCmd.AddCommand(extractNexusCmd)
-	extractCmd.AddCommand(extractTerminalCmd)
-	extractCmd.AddCommand(extractTurnQualityCmd)
-	extractCmd.AddCommand(extractSyncCmd)
-	extractCmd.AddCommand(extractStatusCmd)
-	extractCmd.AddCommand(extractAIXMetadataCmd)
-	rootCmd.AddCommand(extractCmd)
+	registerCompletions(rootCmd)
 
 	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+		// Commands exit themselves; errors here are bad arguments or flags
+		os.Exit(errs.ExitUsage)
+	}
+}
+
+// registerCompletions wires dynamic shell completion (see 'mnemonic
+// completion') into every command: flags and positional arguments named for
+// adapters, people, entities, channels, and episode definitions complete
+// from the config and database.
+func registerCompletions(cmd *cobra.Command) {
+	for name, complete := range map[string]cobra.CompletionFunc{
+		"adapter":    completeAdapters,
+		"person":     completePersons,
+		"entity":     completeEntities,
+		"channel":    completeChannels,
+		"definition": completeDefinitions,
+	} {
+		if cmd.Flags().Lookup(name) != nil {
+			// Non-fatal - only fails if already registered
+			err := cmd.RegisterFlagCompletionFunc(name, complete)
+			_ = err
+		}
+	}
+
+	if cmd.ValidArgsFunction == nil && len(cmd.ValidArgs) == 0 {
+		placeholders := strings.Fields(cmd.Use)[1:]
+		cmd.ValidArgsFunction = func(c *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+			i := len(args)
+			if i >= len(placeholders) {
+				// A trailing "[task...]" repeats
+				if len(placeholders) == 0 || !strings.HasSuffix(placeholders[len(placeholders)-1], "...]") {
+					return nil, cobra.ShellCompDirectiveNoFileComp
+				}
+				i = len(placeholders) - 1
+			}
+			switch strings.Trim(placeholders[i], "<>[].") {
+			case "adapter":
+				return completeAdapters(c, args, toComplete)
+			case "person", "person1", "person2", "person_name_or_id", "name":
+				return completePersons(c, args, toComplete)
+			case "entity-id":
+				return completeEntities(c, args, toComplete)
+			case "entity-type":
+				return completeEntityTypes(c, args, toComplete)
+			case "task":
+				return completeMaintenanceTasks(c, args, toComplete)
+			case "definition":
+				return completeDefinitions(c, args, toComplete)
+			case "channel":
+				return completeChannels(c, args, toComplete)
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+
+	for _, sub := range cmd.Commands() {
+		registerCompletions(sub)
+	}
+}
+
+func completeAdapters(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []cobra.Completion
+	for name, adapter := range cfg.Adapters {
+		if strings.HasPrefix(name, toComplete) {
+			names = append(names, cobra.CompletionWithDesc(name, adapter.Type))
+		}
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completePersons(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	return completeFromDB(`
+		SELECT COALESCE(display_name, canonical_name), '' FROM persons
+		WHERE COALESCE(display_name, canonical_name) LIKE ? || '%'
+		ORDER BY is_me DESC, 1 LIMIT 200
+	`, toComplete)
+}
+
+// completeEntities completes entity IDs, described by name. The shell
+// matches on the ID, so typing a name prefix narrows by the description.
+func completeEntities(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	return completeFromDB(`
+		SELECT id, canonical_name FROM entities
+		WHERE merged_into IS NULL AND (id LIKE ? || '%' OR canonical_name LIKE ? || '%')
+		ORDER BY canonical_name LIMIT 200
+	`, toComplete, toComplete)
+}
+
+func completeEntityTypes(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	var names []cobra.Completion
+	for _, et := range memory.DefaultEntityTypes {
+		if strings.HasPrefix(strings.ToLower(et.Name), strings.ToLower(toComplete)) {
+			names = append(names, cobra.CompletionWithDesc(et.Name, et.Description))
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeChannels(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	return completeFromDB(`
+		SELECT DISTINCT channel, '' FROM events WHERE channel LIKE ? || '%' ORDER BY channel
+	`, toComplete)
+}
+
+func completeDefinitions(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	return completeFromDB(`
+		SELECT name, COALESCE(description, strategy) FROM episode_definitions
+		WHERE name LIKE ? || '%' ORDER BY name
+	`, toComplete)
+}
+
+func completeMaintenanceTasks(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	var names []cobra.Completion
+	for _, name := range maintenance.TaskNames() {
+		if strings.HasPrefix(name, toComplete) && !slices.Contains(args, name) {
+			names = append(names, name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFromDB runs a (value, description) query for completion. Any
+// failure - no database yet, say - just completes nothing.
+func completeFromDB(query string, args ...any) ([]cobra.Completion, cobra.ShellCompDirective) {
+	database, err := db.Open()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer database.Close()
+
+	rows, err := database.Query(query, args...)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer rows.Close()
+
+	var values []cobra.Completion
+	for rows.Next() {
+		var value, desc string
+		if err := rows.Scan(&value, &desc); err != nil {
+			break
+		}
+		values = append(values, cobra.CompletionWithDesc(value, desc))
+	}
+	return values, cobra.ShellCompDirectiveNoFileComp
+}
+
+// findPersonID resolves a person ID or (partial) canonical/display name.
+func findPersonID(database *sql.DB, ref string) (string, error) {
+	var personID string
+	err := database.QueryRow(`SELECT id FROM persons WHERE id = ?`, ref).Scan(&personID)
+	if err != nil {
+		err = database.QueryRow(`
+			SELECT id FROM persons
+			WHERE canonical_name LIKE ? OR display_name LIKE ?
+			LIMIT 1
+		`, "%"+ref+"%", "%"+ref+"%").Scan(&personID)
+	}
+	return personID, err
+}
+
+// findPersonEntityID resolves a Person entity ID or name (exact, then partial).
+func findPersonEntityID(ctx context.Context, database *sql.DB, ref string) (string, error) {
+	var entityID string
+	err := database.QueryRowContext(ctx, `SELECT id FROM entities WHERE id = ? AND merged_into IS NULL`, ref).Scan(&entityID)
+	if err == nil {
+		return entityID, nil
+	}
+
+	personType := memory.EntityTypePerson
+	engine := memory.NewQueryEngine(database)
+	defer engine.Close()
+	matches, err := engine.FindEntitiesByName(ctx, ref, &personType)
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	for _, match := range matches {
+		if strings.EqualFold(match.CanonicalName, ref) {
+			return match.ID, nil
+		}
+		names = append(names, match.CanonicalName)
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no person entity matches %q", ref)
+	case 1:
+		return matches[0].ID, nil
+	default:
+		return "", fmt.Errorf("%q matches several people: %s", ref, strings.Join(names, ", "))
+	}
+}
+
+// pickEntity resolves an entity ID or name. A name matching several
+// entities is settled by pickFirst (most mentioned), or by asking when stdin
+// is a terminal and output is not JSON. Otherwise it returns a nil pick with
+// the candidates, for the caller to list.
+func pickEntity(ctx context.Context, engine *memory.QueryEngine, ref string, typeID *int, pickFirst bool) (*memory.EntityCandidate, []memory.EntityCandidate, error) {
+	candidates, err := engine.LookupEntities(ctx, ref, typeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case len(candidates) == 0:
+		return nil, nil, fmt.Errorf("no entity matches %q", ref)
+	case len(candidates) == 1 || pickFirst:
+		return &candidates[0], candidates, nil
+	}
+
+	if jsonOutput {
+		return nil, candidates, nil
+	}
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return nil, candidates, nil
+	}
+
+	fmt.Printf("%q matches %d entities:\n", ref, len(candidates))
+	printEntityCandidates(candidates)
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Printf("Choose [1-%d], or q to quit: ", len(candidates))
+		line, err := reader.ReadString('\n')
+		answer := strings.TrimSpace(line)
+		if answer == "q" || (err != nil && answer == "") {
+			return nil, nil, fmt.Errorf("no entity chosen")
+		}
+		if n, convErr := strconv.Atoi(answer); convErr == nil && n >= 1 && n <= len(candidates) {
+			fmt.Println()
+			return &candidates[n-1], candidates, nil
+		}
+	}
+}
+
+// printEntityCandidates lists lookup candidates, numbered for pickEntity.
+func printEntityCandidates(candidates []memory.EntityCandidate) {
+	for i, c := range candidates {
+		last := "never mentioned"
+		if c.LastMentionAt != nil {
+			last = fmt.Sprintf("last mentioned %s, %d mention(s)", c.LastMentionAt.Format("2006-01-02"), c.Mentions)
+		}
+		fmt.Printf("  %d. %s (%s)  %s  [%s]\n", i+1, c.CanonicalName, c.TypeName, last, c.ID)
+		for _, fact := range c.TopFacts {
+			fmt.Printf("       %s\n", fact)
+		}
+	}
+}
+
+// auditCaller identifies cmd in the sensitive-data audit log.
+func auditCaller(cmd *cobra.Command) audit.Caller {
+	return audit.Caller{Command: cmd.CommandPath()}
+}
+
+// acquireInstanceLocks takes the named instance locks for cmd (honoring its
+// --force flag), exiting if another live process holds one. A crashed holder's
+// locks go stale and are taken over, so callers only need to defer Release.
+func acquireInstanceLocks(cmd *cobra.Command, database *sql.DB, names []string) *instancelock.Lock {
+	force, _ := cmd.Flags().GetBool("force")
+	dataDir, err := config.GetDataDir()
+	var lock *instancelock.Lock
+	if err == nil {
+		lock, err = instancelock.Acquire(database, filepath.Join(dataDir, "locks"), names, instancelock.Options{
+			Command: cmd.CommandPath(),
+			Force:   force,
+		})
+	}
+	if err != nil {
+		exitWithError(err)
+	}
+	return lock
+}
+
+// newMaintenanceScheduler builds a scheduler for the configured maintenance
+// tasks and reports whether background maintenance is enabled.
+func newMaintenanceScheduler(database *sql.DB) (*maintenance.Scheduler, bool, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load config: %w", err)
+	}
+	dataDir, err := config.GetDataDir()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get data dir: %w", err)
+	}
+	tasks, err := maintenance.BuildTasks(database, cfg.Maintenance, os.Getenv("GEMINI_API_KEY"), dataDir)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid maintenance config: %w", err)
+	}
+	return maintenance.NewScheduler(database, tasks), cfg.Maintenance.Enabled, nil
+}
+
+// exitWithError reports err and exits with the exit code of its errs class.
+// In --json mode it prints {"ok": false, "message": ..., "error": {...}} so
+// scripts can branch on error.code.
+func exitWithError(err error) {
+	info := errs.Classify(err)
+	if jsonOutput {
+		printJSON(map[string]any{"ok": false, "message": info.Message, "error": info})
+	} else {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", info.Message)
+		if info.Hint != "" {
+			fmt.Fprintf(os.Stderr, "Hint: %s\n", info.Hint)
+		}
 	}
+	os.Exit(info.ExitCode)
 }
 
 func printJSON(v interface{}) {
@@ -7379,25 +14698,146 @@ func printJSON(v interface{}) {
 }
 
 // parseDate parses a date string in YYYY-MM-DD format
+// parseSince parses a lookback like "30d" or "12h", or a YYYY-MM-DD date.
+// Returns the zero time if it can't be parsed.
+func parseSince(since string) time.Time {
+	var n int
+	switch {
+	case strings.HasSuffix(since, "d"):
+		if _, err := fmt.Sscanf(since, "%dd", &n); err == nil {
+			return time.Now().AddDate(0, 0, -n)
+		}
+	case strings.HasSuffix(since, "h"):
+		if _, err := fmt.Sscanf(since, "%dh", &n); err == nil {
+			return time.Now().Add(-time.Duration(n) * time.Hour)
+		}
+	default:
+		if t, err := time.Parse("2006-01-02", since); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
 func parseDate(dateStr string) (time.Time, error) {
 	return time.Parse("2006-01-02", dateStr)
 }
 
 // checkAdapterStatus checks if an adapter's prerequisites are met
+// adapterInfo describes a configured adapter for the adapters commands.
+// Status is its readiness; the last sync comes from the sync job table.
+type adapterInfo struct {
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	Enabled        bool   `json:"enabled"`
+	Status         string `json:"status"`
+	Live           bool   `json:"live,omitempty"`
+	LastSyncAt     string `json:"last_sync_at,omitempty"`
+	LastSyncStatus string `json:"last_sync_status,omitempty"`
+	LastError      string `json:"last_error,omitempty"`
+}
+
+// collectAdapterInfo describes the named adapters, or every configured one
+// sorted by name when names is empty. The database is only read if it
+// already exists, so listing adapters never creates one.
+func collectAdapterInfo(cfg *config.Config, names []string) ([]adapterInfo, error) {
+	if len(names) == 0 {
+		for name := range cfg.Adapters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	jobs := map[string]sync.JobStatus{}
+	if dbPath, err := config.DBPath(); err == nil {
+		if _, err := os.Stat(dbPath); err == nil {
+			database, err := db.Open()
+			if err != nil {
+				return nil, fmt.Errorf("Failed to open database: %w", err)
+			}
+			defer database.Close()
+			list, err := sync.ListJobs(database)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to list sync jobs: %w", err)
+			}
+			for _, j := range list {
+				jobs[j.Adapter] = j
+			}
+		}
+	}
+
+	var out []adapterInfo
+	for _, name := range names {
+		adapter, ok := cfg.Adapters[name]
+		if !ok {
+			return nil, fmt.Errorf("adapter '%s' not configured", name)
+		}
+		info := adapterInfo{
+			Name:    name,
+			Type:    adapter.Type,
+			Enabled: adapter.Enabled,
+			Status:  "disabled",
+			Live:    adapter.Live != nil && adapter.Live.Enabled,
+		}
+		if adapter.Enabled {
+			info.Status = checkAdapterStatus(name, adapter)
+		}
+		if j, ok := jobs[name]; ok {
+			info.LastSyncAt = time.Unix(j.UpdatedAt, 0).Local().Format(time.RFC3339)
+			info.LastSyncStatus = j.Status
+			if j.LastError != nil {
+				info.LastError = *j.LastError
+			}
+		}
+		out = append(out, info)
+	}
+	return out, nil
+}
+
 func checkAdapterStatus(name string, adapter config.AdapterConfig) string {
 	switch adapter.Type {
 	case "eve":
 		// Check if Eve database exists
-		home, err := os.UserHomeDir()
+		eveDBPath, err := config.EveDBPath()
 		if err != nil {
 			return "error"
 		}
-		eveDBPath := filepath.Join(home, "Library", "Application Support", "Eve", "eve.db")
 		if _, err := os.Stat(eveDBPath); os.IsNotExist(err) {
 			return "missing Eve database"
 		}
 		return "ready"
 
+	case "chatdb":
+		path, _ := adapter.Options["path"].(string)
+		if path == "" {
+			var err error
+			if path, err = adapters.DefaultChatDBPath(); err != nil {
+				return "error"
+			}
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return "missing Messages database"
+		}
+		return "ready (needs Full Disk Access)"
+
+	case "callhistory":
+		path, _ := adapter.Options["path"].(string)
+		if path == "" {
+			var err error
+			if path, err = config.CallHistoryPath(); err != nil {
+				return "error"
+			}
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return "missing call history"
+		}
+		if voicemail, _ := adapter.Options["voicemail"].(string); voicemail != "" {
+			if _, err := os.Stat(voicemail); err != nil {
+				return "missing voicemail database"
+			}
+		}
+		return "ready (needs Full Disk Access)"
+
 	case "gogcli":
 		// Check if account is configured
 		if account, ok := adapter.Options["account"].(string); ok && account != "" {
@@ -7408,11 +14848,10 @@ func checkAdapterStatus(name string, adapter config.AdapterConfig) string {
 		return "missing account"
 
 	case "aix", "aix-events", "aix-agents":
-		home, err := os.UserHomeDir()
+		aixDBPath, err := config.AixDBPath()
 		if err != nil {
 			return "error"
 		}
-		aixDBPath := filepath.Join(home, "Library", "Application Support", "aix", "aix.db")
 		if _, err := os.Stat(aixDBPath); os.IsNotExist(err) {
 			return "missing aix database (run: aix sync --all)"
 		}
@@ -7428,11 +14867,11 @@ func checkAdapterStatus(name string, adapter config.AdapterConfig) string {
 		} else if v, ok := adapter.Options["state_dir"].(string); ok && v != "" {
 			eventsDir = filepath.Join(v, "events")
 		} else {
-			home, err := os.UserHomeDir()
+			stateDir, err := config.NexusStateDir()
 			if err != nil {
 				return "error"
 			}
-			eventsDir = filepath.Join(home, "nexus", "state", "events")
+			eventsDir = filepath.Join(stateDir, "events")
 		}
 		if _, err := os.Stat(eventsDir); os.IsNotExist(err) {
 			return "missing nexus events dir"
@@ -7446,6 +14885,64 @@ func checkAdapterStatus(name string, adapter config.AdapterConfig) string {
 		}
 		return "ready"
 
+	case "generic":
+		path, _ := adapter.Options["path"].(string)
+		if _, err := os.Stat(path); err != nil {
+			return "missing data file"
+		}
+		return "ready"
+
+	case "discord":
+		tokenEnv, _ := adapter.Options["token_env"].(string)
+		if tokenEnv == "" {
+			tokenEnv = adapters.DefaultDiscordTokenEnv
+		}
+		if os.Getenv(tokenEnv) == "" {
+			return "missing token (export " + tokenEnv + ")"
+		}
+		return "ready"
+
+	case "whatsapp_export":
+		path, _ := adapter.Options["path"].(string)
+		if _, err := os.Stat(path); err != nil {
+			return "missing WhatsApp export directory"
+		}
+		return "ready"
+
+	case "addressbook":
+		path, _ := adapter.Options["path"].(string)
+		if path == "" {
+			dir, err := config.AddressBookDir()
+			if err != nil {
+				return err.Error()
+			}
+			path = dir
+		}
+		if _, err := os.Stat(path); err != nil {
+			return "missing address book"
+		}
+		return "ready"
+
+	case "ics":
+		path, _ := adapter.Options["path"].(string)
+		if strings.Contains(path, "://") {
+			return "ready"
+		}
+		if _, err := os.Stat(path); err != nil {
+			return "missing calendar file"
+		}
+		return "ready"
+
+	case "plugin":
+		p, err := plugin.FromOptions(name, adapter.Options)
+		if err != nil {
+			return err.Error()
+		}
+		if _, err := exec.LookPath(p.Command); err != nil {
+			return "plugin command not found: " + p.Command
+		}
+		return "ready"
+
 	default:
 		return "unknown adapter type"
 	}
@@ -7531,3 +15028,11 @@ func getEpisodePreview(ctx context.Context, database *sql.DB, episodeID string,
 	}
 	return preview, nil
 }
+
+// entityTypeName returns the display name of an entity type ID.
+func entityTypeName(id int) string {
+	if et := memory.GetEntityTypeByID(id); et != nil {
+		return et.Name
+	}
+	return fmt.Sprintf("type %d", id)
+}
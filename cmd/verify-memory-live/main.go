@@ -15,6 +15,13 @@
 //	      Number of events per episode (default: 50)
 //	-verbose
 //	      Show detailed output
+//	-self-critique
+//	      Review extracted relationships with a second LLM pass
+//	-gleaning-rounds int
+//	      Max entity gleaning re-prompts for low-recall episodes (0 disables)
+//	-fixtures string
+//	      Run an eval set of fixture episodes instead of live threads
+//	      (e.g. internal/memory/testdata/eval/non_ego)
 package main
 
 import (
@@ -29,14 +36,21 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Napageneral/mnemonic/internal/config"
 	"github.com/Napageneral/mnemonic/internal/gemini"
 	"github.com/Napageneral/mnemonic/internal/memory"
+	"github.com/Napageneral/mnemonic/internal/threads"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-const cortexDBPath = "/Users/tyler/Library/Application Support/Cortex/cortex.db"
-
 func main() {
+	// The source database (MNEMONIC_DATA_DIR, else the platform data dir)
+	cortexDBPath, err := config.DBPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
 	// Parse flags
 	threadIDs := flag.String("threads", "", "Comma-separated thread IDs (empty = auto-select)")
 	episodesPerThread := flag.Int("episodes-per-thread", 5, "Episodes per thread")
@@ -49,6 +63,11 @@ func main() {
 	outputDB := flag.String("output-db", cortexDBPath, "Path to output SQLite DB (defaults to cortex.db)")
 	resetSchema := flag.Bool("reset-schema", false, "Drop and recreate memory tables in output DB (dangerous)")
 	debugDir := flag.String("debug-dir", "", "Directory to dump prompts and responses per episode")
+	gleaningRounds := flag.Int("gleaning-rounds", 0, "Max entity gleaning re-prompts for low-recall episodes (0 disables)")
+	selfCritique := flag.Bool("self-critique", false, "Review extracted relationships with a second LLM pass")
+	modelRouting := flag.Bool("model-routing", false, "Send long and group-chat episodes to a stronger model (-model stays the cheap one)")
+	qualityGate := flag.Bool("quality-gate", false, "Skip low-information episodes (links, emoji, \"ok\") before extraction")
+	fixturesDir := flag.String("fixtures", "", "Run the fixture eval set in this directory instead of live threads")
 	flag.Parse()
 
 	// Check for GEMINI_API_KEY
@@ -107,11 +126,53 @@ func main() {
 	pipelineConfig := &memory.PipelineConfig{
 		ExtractionModel: *model,
 		SkipEmbeddings:  true, // Skip for faster testing
+		SelfCritique:    *selfCritique,
+
+		EntityGeneration:       memory.DefaultEntityGenerationParams(),
+		RelationshipGeneration: memory.DefaultRelationshipGenerationParams(),
+		CritiqueGeneration:     memory.DefaultCritiqueGenerationParams(),
+	}
+	if *modelRouting {
+		routing := memory.DefaultModelRouterConfig()
+		routing.CheapModel = *model
+		pipelineConfig.ModelRouting = &routing
+	}
+	if *qualityGate {
+		quality := memory.DefaultEpisodeQualityConfig()
+		pipelineConfig.QualityGate = &quality
+	}
+	if *gleaningRounds > 0 {
+		gleaning := memory.DefaultGleaningConfig()
+		gleaning.MaxRounds = *gleaningRounds
+		pipelineConfig.Gleaning = &gleaning
 	}
 	pipeline := memory.NewMemoryPipeline(memDB, geminiClient, pipelineConfig)
 
 	ctx := context.Background()
 
+	// Fixture eval sets run through the same pipeline, scored against expectations
+	if *fixturesDir != "" {
+		harness := memory.NewVerificationHarness(memDB, *fixturesDir, pipeline)
+		results, err := harness.RunAll(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error running fixtures: %v\n", err)
+			os.Exit(2)
+		}
+		failed := false
+		for _, result := range results {
+			fmt.Print(memory.FormatResult(result))
+			if *verbose && result.ActualOutput != nil {
+				fmt.Print(memory.FormatDetailedOutput(result.ActualOutput))
+			}
+			failed = failed || !result.Passed
+		}
+		fmt.Print(memory.FormatSummary(results))
+		if failed {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Get threads to test
 	var threads []ThreadInfo
 	if *threadIDs == "" {
@@ -139,6 +200,8 @@ func main() {
 
 	// Process each thread
 	var totalEntities, totalRelationships int
+	var totalCritiqueDropped, totalCritiqueAdjusted int
+	var totalGleaned int
 	var totalDuration time.Duration
 
 	for _, thread := range threads {
@@ -169,13 +232,7 @@ func main() {
 		}
 
 		// Convert participants to known entities for the pipeline
-		var knownEntities []memory.KnownEntity
-		for _, p := range participants {
-			knownEntities = append(knownEntities, memory.KnownEntity{
-				Name:       p,
-				EntityType: "Person",
-			})
-		}
+		knownEntities := toKnownEntities(participants)
 
 		applySenderFallback(episodes, thread, selfName, otherName)
 		for i, ep := range episodes {
@@ -204,6 +261,12 @@ func main() {
 				ReferenceTime: ep.EndTime.Format(time.RFC3339),
 				KnownEntities: knownEntities,
 			}
+			// Group rosters change; prime with who was in the chat at the time
+			if thread.IsGroup {
+				if members := getThreadMembersAt(cortexDB, thread.ID, ep.StartTime); len(members) > 0 {
+					input.KnownEntities = toKnownEntities(members)
+				}
+			}
 
 			// Process through pipeline
 			runCtx := ctx
@@ -225,12 +288,23 @@ func main() {
 
 			totalEntities += result.NewEntities
 			totalRelationships += result.NewRelationships
+			totalCritiqueDropped += len(result.CritiqueDropped)
+			totalGleaned += result.GleanedEntities
+			totalCritiqueAdjusted += result.CritiqueAdjusted
 
-			if *verbose {
+			if result.SkipReason != "" {
+				fmt.Printf("    Episode %d: skipped (%s, score %.2f)\n", i+1, result.SkipReason, result.Quality.Score)
+			} else if *verbose {
 				fmt.Printf("    Episode %d (%d events, %s):\n", i+1, len(ep.Events), duration.Round(time.Millisecond))
 				fmt.Printf("      Entities: %d new, %d existing\n", result.NewEntities, result.ExistingEntities)
+				if result.GleaningRounds > 0 {
+					fmt.Printf("      Gleaning: %d rounds, %d entities added\n", result.GleaningRounds, result.GleanedEntities)
+				}
 				fmt.Printf("      Relationships: %d new, %d existing\n", result.NewRelationships, result.ExistingRelationships)
 				fmt.Printf("      Aliases: %d, Mentions: %d\n", result.AliasesCreated, result.EntityMentionsCreated)
+				for _, d := range result.CritiqueDropped {
+					fmt.Printf("      Critique dropped: %s (%s)\n", d.Relationship.Fact, d.Reason)
+				}
 			}
 
 			// Reset memory DB for each episode to test in isolation (unless persisting)
@@ -247,6 +321,12 @@ func main() {
 	fmt.Println("=== Summary ===")
 	fmt.Printf("Total entities extracted: %d\n", totalEntities)
 	fmt.Printf("Total relationships extracted: %d\n", totalRelationships)
+	if *gleaningRounds > 0 {
+		fmt.Printf("Entities found by gleaning: %d\n", totalGleaned)
+	}
+	if *selfCritique {
+		fmt.Printf("Self-critique: %d dropped, %d confidence-adjusted\n", totalCritiqueDropped, totalCritiqueAdjusted)
+	}
 	fmt.Printf("Total processing time: %s\n", totalDuration.Round(time.Millisecond))
 
 	// Print API usage
@@ -426,6 +506,38 @@ func getThreadParticipants(db *sql.DB, threadID string) ([]string, error) {
 	return participants, rows.Err()
 }
 
+// getThreadMembersAt gets the names of a group thread's members at a point
+// in time from its roster history (empty if no roster was recorded)
+func getThreadMembersAt(db *sql.DB, threadID string, at time.Time) []string {
+	members, err := threads.MembersAt(db, threadID, at)
+	if err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for _, m := range members {
+		name := strings.TrimSpace(m.Name)
+		if name == "" || seen[name] || looksLikePhone(name) || strings.Contains(name, "@") {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func toKnownEntities(names []string) []memory.KnownEntity {
+	var known []memory.KnownEntity
+	for _, name := range names {
+		known = append(known, memory.KnownEntity{
+			Name:       name,
+			EntityType: "Person",
+		})
+	}
+	return known
+}
+
 func getSelfNameForThread(db *sql.DB, threadID string) string {
 	query := `
 		SELECT COALESCE(p.canonical_name, p.display_name, c.display_name, 'Me') as name,
@@ -1153,12 +1265,24 @@ func initMemorySchema(db *sql.DB, reset bool) error {
 			origin TEXT,
 			confidence REAL DEFAULT 1.0,
 			merged_into TEXT REFERENCES entities(id),
+			name_locked INTEGER NOT NULL DEFAULT 0,
 			created_at TEXT DEFAULT (datetime('now')),
 			updated_at TEXT DEFAULT (datetime('now'))
 		);
 		CREATE INDEX IF NOT EXISTS idx_entities_type ON entities(entity_type_id);
 		CREATE INDEX IF NOT EXISTS idx_entities_name ON entities(canonical_name);
 
+		CREATE TABLE IF NOT EXISTS entity_name_history (
+			id TEXT PRIMARY KEY,
+			entity_id TEXT NOT NULL REFERENCES entities(id),
+			old_name TEXT NOT NULL,
+			new_name TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			source_entity_id TEXT,
+			changed_by TEXT NOT NULL,
+			created_at TEXT NOT NULL
+		);
+
 		CREATE TABLE IF NOT EXISTS entity_aliases (
 			id TEXT PRIMARY KEY,
 			entity_id TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
@@ -1183,6 +1307,8 @@ func initMemorySchema(db *sql.DB, reset bool) error {
 			invalid_at TEXT,
 			created_at TEXT DEFAULT (datetime('now')),
 			confidence REAL DEFAULT 1.0,
+			weight REAL DEFAULT 0,
+			source_type TEXT,
 			CHECK ((target_entity_id IS NULL) != (target_literal IS NULL))
 		);
 		CREATE INDEX IF NOT EXISTS idx_relationships_source ON relationships(source_entity_id);
@@ -1193,6 +1319,7 @@ func initMemorySchema(db *sql.DB, reset bool) error {
 			episode_id TEXT NOT NULL REFERENCES episodes(id) ON DELETE CASCADE,
 			entity_id TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
 			mention_count INTEGER DEFAULT 1,
+			mention_offsets TEXT,
 			created_at TEXT DEFAULT (datetime('now')),
 			PRIMARY KEY (episode_id, entity_id)
 		);
@@ -1225,6 +1352,7 @@ func initMemorySchema(db *sql.DB, reset bool) error {
 			candidates_considered TEXT,
 			conflicts TEXT,
 			status TEXT DEFAULT 'pending',
+			last_evaluated_at TEXT,
 			created_at TEXT NOT NULL,
 			resolved_at TEXT,
 			resolved_by TEXT,
@@ -0,0 +1,402 @@
+package adapters
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime/quotedprintable"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/avatars"
+	"github.com/Napageneral/mnemonic/internal/config"
+	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/errs"
+)
+
+// AddressBookAdapterOptions configures the address book adapter.
+type AddressBookAdapterOptions struct {
+	// Path is a vCard (.vcf) file, a directory of them, or a macOS
+	// Contacts directory (default: the local macOS Contacts)
+	Path string
+}
+
+// AddressBookAdapter seeds contacts and persons from an address book: the
+// macOS Contacts databases or exported vCard files. Each card's phone
+// numbers and emails become contacts named after the card, linked to one
+// person with the card's name, so identity resolution starts from real
+// names instead of bare phone numbers. It creates no events; syncing again
+// is idempotent.
+type AddressBookAdapter struct {
+	name string
+	path string
+}
+
+// NewAddressBookAdapter creates an adapter for vCard files or macOS Contacts.
+func NewAddressBookAdapter(name string, opts AddressBookAdapterOptions) (*AddressBookAdapter, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("adapter instance name is required for addressbook adapter")
+	}
+	path := strings.TrimSpace(opts.Path)
+	if path == "" {
+		dir, err := config.AddressBookDir()
+		if err != nil {
+			return nil, err
+		}
+		path = dir
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, errs.New(errs.ErrAdapterSourceMissing, "address book not found at %s: %w", path, err)
+	}
+	return &AddressBookAdapter{name: name, path: path}, nil
+}
+
+func (a *AddressBookAdapter) Name() string { return a.name }
+
+// addressCard is one address book card.
+type addressCard struct {
+	Name   string
+	Emails []string
+	Phones []string
+	Photo  []byte // vCard only; macOS photos come from avatars.ImportAddressBook
+}
+
+func (a *AddressBookAdapter) Sync(ctx context.Context, cortexDB *sql.DB, full bool) (SyncResult, error) {
+	start := time.Now()
+	res := SyncResult{Perf: map[string]string{}}
+	_ = full // every sync reads the whole address book (idempotent)
+
+	databases, vcards, err := addressBookSources(a.path)
+	if err != nil {
+		return res, err
+	}
+
+	var stats addressBookStats
+	for _, path := range databases {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		cards, err := readMacOSAddressBook(ctx, path)
+		if err != nil {
+			return res, fmt.Errorf("%s: %w", path, err)
+		}
+		if err := a.storeCards(cortexDB, cards, &res, &stats); err != nil {
+			return res, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	for _, path := range vcards {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return res, fmt.Errorf("read %s: %w", path, err)
+		}
+		cards, err := parseVCards(f)
+		f.Close()
+		if err != nil {
+			return res, fmt.Errorf("%s: %w", path, err)
+		}
+		if err := a.storeCards(cortexDB, cards, &res, &stats); err != nil {
+			return res, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if len(databases) > 0 {
+		dir := a.path
+		if len(databases) == 1 && databases[0] == a.path {
+			dir = filepath.Dir(a.path)
+		}
+		// Non-fatal - photos are cosmetic
+		if n, err := avatars.ImportAddressBook(ctx, cortexDB, dir); err == nil {
+			stats.photos += n
+		} else {
+			stats.photoErrors++
+		}
+	}
+
+	res.Perf["sources"] = strconv.Itoa(len(databases) + len(vcards))
+	res.Perf["cards"] = strconv.Itoa(stats.cards)
+	res.Perf["contacts_created"] = strconv.Itoa(stats.contactsCreated)
+	res.Perf["contacts_linked"] = strconv.Itoa(stats.linked)
+	res.Perf["photos_stored"] = strconv.Itoa(stats.photos)
+	res.Perf["photo_errors"] = strconv.Itoa(stats.photoErrors)
+	res.Duration = time.Since(start)
+	res.Perf["total"] = res.Duration.String()
+	return res, nil
+}
+
+type addressBookStats struct {
+	cards, contactsCreated, linked, photos, photoErrors int
+}
+
+// storeCards writes one source's cards in a transaction.
+func (a *AddressBookAdapter) storeCards(db *sql.DB, cards []addressCard, res *SyncResult, stats *addressBookStats) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, card := range cards {
+		contactIDs := make([]string, 0, len(card.Emails)+len(card.Phones))
+		for _, id := range []struct {
+			idType string
+			values []string
+		}{{"email", card.Emails}, {"phone", card.Phones}} {
+			for _, v := range id.values {
+				if contacts.NormalizeIdentifier(v, id.idType) == "" {
+					continue
+				}
+				contactID, created, err := contacts.GetOrCreateContact(tx, id.idType, v, card.Name, a.Name())
+				if err != nil {
+					return err
+				}
+				if created {
+					stats.contactsCreated++
+				}
+				contactIDs = append(contactIDs, contactID)
+			}
+		}
+		if len(contactIDs) == 0 {
+			continue
+		}
+		stats.cards++
+
+		person, created, err := contacts.LinkCard(tx, contactIDs, card.Name)
+		if err != nil {
+			return err
+		}
+		if created {
+			res.PersonsCreated++
+		}
+		if person != "" {
+			stats.linked += len(contactIDs)
+		}
+		if len(card.Photo) > 0 {
+			for _, cid := range contactIDs {
+				if err := avatars.Store(tx, cid, a.Name(), card.Photo, ""); err != nil {
+					stats.photoErrors++
+					break
+				}
+				stats.photos++
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// addressBookSources returns the macOS Contacts databases or the vCard
+// files under path.
+func addressBookSources(path string) (databases, vcards []string, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		if strings.EqualFold(filepath.Ext(path), ".abcddb") {
+			return []string{path}, nil, nil
+		}
+		return nil, []string{path}, nil
+	}
+
+	if _, err := os.Stat(filepath.Join(path, "AddressBook-v22.abcddb")); err == nil {
+		databases = append(databases, filepath.Join(path, "AddressBook-v22.abcddb"))
+	}
+	sources, _ := filepath.Glob(filepath.Join(path, "Sources", "*", "AddressBook-v22.abcddb"))
+	if databases = append(databases, sources...); len(databases) > 0 {
+		return databases, nil, nil
+	}
+
+	err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if ext := strings.ToLower(filepath.Ext(p)); ext == ".vcf" || ext == ".vcard" {
+			vcards = append(vcards, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("list vCards: %w", err)
+	}
+	sort.Strings(vcards)
+	return nil, vcards, nil
+}
+
+// readMacOSAddressBook reads the cards of one macOS Contacts database.
+func readMacOSAddressBook(ctx context.Context, path string) ([]addressCard, error) {
+	abDB, err := sql.Open("sqlite", "file:"+path+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	defer abDB.Close()
+
+	rows, err := abDB.QueryContext(ctx, `
+		SELECT r.Z_PK, TRIM(COALESCE(r.ZFIRSTNAME, '') || ' ' || COALESCE(r.ZLASTNAME, '')), COALESCE(r.ZORGANIZATION, ''),
+		       'phone', p.ZFULLNUMBER
+		FROM ZABCDRECORD r JOIN ZABCDPHONENUMBER p ON p.ZOWNER = r.Z_PK
+		WHERE p.ZFULLNUMBER IS NOT NULL
+		UNION ALL
+		SELECT r.Z_PK, TRIM(COALESCE(r.ZFIRSTNAME, '') || ' ' || COALESCE(r.ZLASTNAME, '')), COALESCE(r.ZORGANIZATION, ''),
+		       'email', e.ZADDRESS
+		FROM ZABCDRECORD r JOIN ZABCDEMAILADDRESS e ON e.ZOWNER = r.Z_PK
+		WHERE e.ZADDRESS IS NOT NULL
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byCard := map[int64]*addressCard{}
+	var order []int64
+	for rows.Next() {
+		var pk int64
+		var name, org, idType, value string
+		if err := rows.Scan(&pk, &name, &org, &idType, &value); err != nil {
+			return nil, err
+		}
+		card, ok := byCard[pk]
+		if !ok {
+			if name == "" {
+				name = org
+			}
+			card = &addressCard{Name: name}
+			byCard[pk] = card
+			order = append(order, pk)
+		}
+		if idType == "phone" {
+			card.Phones = append(card.Phones, value)
+		} else {
+			card.Emails = append(card.Emails, value)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+	cards := make([]addressCard, len(order))
+	for i, pk := range order {
+		cards[i] = *byCard[pk]
+	}
+	return cards, nil
+}
+
+// parseVCards reads the cards of a vCard stream (versions 2.1, 3.0 and
+// 4.0). A card is named by FN, else by N, else by its organization.
+func parseVCards(r io.Reader) ([]addressCard, error) {
+	lines, err := unfoldICSLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var cards []addressCard
+	var card *addressCard
+	var structured, org string
+	for _, line := range lines {
+		p, ok := parseICSProperty(line)
+		if !ok {
+			continue
+		}
+		// Grouped properties (item1.EMAIL) belong to their group's card
+		if i := strings.LastIndex(p.name, "."); i >= 0 {
+			p.name = p.name[i+1:]
+		}
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VCARD"):
+			card, structured, org = &addressCard{}, "", ""
+			continue
+		case p.name == "END" && strings.EqualFold(p.value, "VCARD") && card != nil:
+			if card.Name == "" {
+				card.Name = structured
+			}
+			if card.Name == "" {
+				card.Name = org
+			}
+			cards = append(cards, *card)
+			card = nil
+			continue
+		}
+		if card == nil {
+			continue
+		}
+
+		value := p.value
+		if strings.EqualFold(p.params["ENCODING"], "QUOTED-PRINTABLE") {
+			if b, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(value))); err == nil {
+				value = string(b)
+			}
+		}
+		switch p.name {
+		case "FN":
+			card.Name = icsText(value)
+		case "N":
+			// Family;Given;Additional;Prefix;Suffix
+			parts := splitVCardValue(value)
+			var given, family string
+			if len(parts) > 1 {
+				given = parts[1]
+			}
+			if len(parts) > 0 {
+				family = parts[0]
+			}
+			structured = strings.TrimSpace(icsText(given) + " " + icsText(family))
+		case "ORG":
+			org = icsText(splitVCardValue(value)[0])
+		case "EMAIL":
+			if v := strings.TrimSpace(value); v != "" {
+				card.Emails = append(card.Emails, strings.TrimPrefix(v, "mailto:"))
+			}
+		case "TEL":
+			if v := strings.TrimSpace(value); v != "" {
+				card.Phones = append(card.Phones, strings.TrimPrefix(v, "tel:"))
+			}
+		case "PHOTO":
+			card.Photo = vCardPhoto(p.params, value)
+		}
+	}
+	return cards, nil
+}
+
+// splitVCardValue splits a structured value on its unescaped semicolons.
+func splitVCardValue(v string) []string {
+	var parts []string
+	last := 0
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case '\\':
+			i++
+		case ';':
+			parts = append(parts, v[last:i])
+			last = i + 1
+		}
+	}
+	return append(parts, v[last:])
+}
+
+// vCardPhoto decodes an inline photo: base64 with ENCODING=b (3.0) or
+// BASE64 (2.1), or a data: URI (4.0). Photos given by URL are not fetched.
+func vCardPhoto(params map[string]string, value string) []byte {
+	value = strings.TrimSpace(value)
+	enc := strings.ToUpper(params["ENCODING"])
+	if strings.HasPrefix(value, "data:") {
+		meta, data, ok := strings.Cut(value[len("data:"):], ",")
+		if !ok || !strings.HasSuffix(meta, ";base64") {
+			return nil
+		}
+		value, enc = data, "B"
+	}
+	if enc != "B" && enc != "BASE64" {
+		return nil
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+	if err != nil || len(data) == 0 || len(data) > avatars.MaxBytes {
+		return nil
+	}
+	return data
+}
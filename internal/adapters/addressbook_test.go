@@ -0,0 +1,123 @@
+package adapters
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+const testVCF = "BEGIN:VCARD\r\n" +
+	"VERSION:3.0\r\n" +
+	"FN:Casey Lee\r\n" +
+	"N:Lee;Casey;;;\r\n" +
+	"item1.EMAIL;type=INTERNET;type=pref:casey@example.com\r\n" +
+	"TEL;type=CELL;type=VOICE:(555) 123-4567\r\n" +
+	"PHOTO;ENCODING=b;TYPE=PNG:iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAIAAACQd1PeAAAADElEQVR4\r\n" +
+	" nGP4z8AAAAMBAQDJ/pLvAAAAAElFTkSuQmCC\r\n" +
+	"END:VCARD\r\n" +
+	"BEGIN:VCARD\r\n" +
+	"VERSION:2.1\r\n" +
+	"N;ENCODING=QUOTED-PRINTABLE;CHARSET=UTF-8:M=C3=BCller;J=C3=BCrgen;;;\r\n" +
+	"TEL;HOME:+49 30 1234567\r\n" +
+	"END:VCARD\r\n" +
+	"BEGIN:VCARD\r\n" +
+	"VERSION:4.0\r\n" +
+	"ORG:Acme\\, Inc.;Support\r\n" +
+	"TEL;VALUE=uri:tel:+1-555-987-6543\r\n" +
+	"END:VCARD\r\n" +
+	"BEGIN:VCARD\r\n" +
+	"VERSION:3.0\r\n" +
+	"FN:No Identifiers\r\n" +
+	"END:VCARD\r\n"
+
+func TestParseVCards(t *testing.T) {
+	cards, err := parseVCards(strings.NewReader(testVCF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 4 {
+		t.Fatalf("cards = %+v", cards)
+	}
+	if c := cards[0]; c.Name != "Casey Lee" || len(c.Emails) != 1 || c.Emails[0] != "casey@example.com" ||
+		len(c.Phones) != 1 || c.Phones[0] != "(555) 123-4567" || len(c.Photo) == 0 {
+		t.Errorf("3.0 card = %+v", c)
+	}
+	if c := cards[1]; c.Name != "Jürgen Müller" || len(c.Phones) != 1 {
+		t.Errorf("2.1 card = %+v", c)
+	}
+	if c := cards[2]; c.Name != "Acme, Inc." || len(c.Phones) != 1 || c.Phones[0] != "+1-555-987-6543" {
+		t.Errorf("4.0 card = %+v", c)
+	}
+}
+
+func TestAddressBookAdapterSync(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	// Seen before as a bare number by a messaging adapter
+	contactID, _, err := contacts.GetOrCreateContact(db, "phone", "+15551234567", "", "imessage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	if _, err := db.Exec(`INSERT INTO persons (id, canonical_name, is_me, created_at, updated_at) VALUES ('p-number', '+15551234567', 0, ?, ?)`, now, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := contacts.EnsurePersonContactLink(db, "p-number", contactID, "deterministic", 1.0); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "contacts.vcf"), []byte(testVCF), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewAddressBookAdapter("address-book", AddressBookAdapterOptions{Path: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := a.Sync(ctx, db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.PersonsCreated != 2 || res.Perf["cards"] != "3" || res.Perf["contacts_created"] != "3" || res.Perf["photos_stored"] != "2" {
+		t.Errorf("first sync = %+v", res)
+	}
+
+	// Casey's email joins the person the number was already linked to,
+	// which takes the card's name
+	var name string
+	var linked int
+	if err := db.QueryRow(`
+		SELECT p.canonical_name, COUNT(*) FROM persons p
+		JOIN person_contact_links l ON l.person_id = p.id
+		WHERE p.id = 'p-number'
+	`).Scan(&name, &linked); err != nil {
+		t.Fatal(err)
+	}
+	if name != "Casey Lee" || linked != 2 {
+		t.Errorf("person = %q with %d contacts", name, linked)
+	}
+	var display string
+	if err := db.QueryRow(`SELECT display_name FROM contacts WHERE id = ?`, contactID).Scan(&display); err != nil || display != "Casey Lee" {
+		t.Errorf("contact name = %q (%v)", display, err)
+	}
+
+	res, err = a.Sync(ctx, db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var persons int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM persons`).Scan(&persons); err != nil {
+		t.Fatal(err)
+	}
+	if res.PersonsCreated != 0 || res.Perf["contacts_created"] != "0" || persons != 3 {
+		t.Errorf("second sync = %+v, %d persons", res, persons)
+	}
+}
@@ -6,12 +6,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
-	"runtime"
 	"strings"
 	"time"
 
+	"github.com/Napageneral/mnemonic/internal/config"
 	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/errs"
+	"github.com/Napageneral/mnemonic/internal/me"
 	_ "modernc.org/sqlite"
 )
 
@@ -34,7 +35,7 @@ func NewAixAdapter(source string) (*AixAdapter, error) {
 		return nil, err
 	}
 	if _, err := os.Stat(dbPath); err != nil {
-		return nil, fmt.Errorf("aix database not found at %s (run aix sync --all first): %w", dbPath, err)
+		return nil, errs.New(errs.ErrAdapterSourceMissing, "aix database not found at %s (run aix sync --all first): %w", dbPath, err)
 	}
 
 	return &AixAdapter{
@@ -95,8 +96,7 @@ func (a *AixAdapter) Sync(ctx context.Context, cortexDB *sql.DB, full bool) (Syn
 	}
 
 	// Look up me person if present (optional).
-	var mePersonID string
-	_ = cortexDB.QueryRow("SELECT id FROM persons WHERE is_me = 1 LIMIT 1").Scan(&mePersonID)
+	mePersonID, _ := me.LookupMePersonID(cortexDB)
 
 	// Ensure "me" has a contact endpoint for this source.
 	meContactID, err := a.ensureMeContact(cortexDB, mePersonID)
@@ -774,18 +774,5 @@ func DefaultAixDBPath() (string, error) {
 }
 
 func defaultAixDBPath() (string, error) {
-	if override := os.Getenv("AIX_DB_PATH"); strings.TrimSpace(override) != "" {
-		return filepath.Clean(os.ExpandEnv(override)), nil
-	}
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
-	}
-	if runtime.GOOS == "darwin" {
-		return filepath.Join(home, "Library", "Application Support", "aix", "aix.db"), nil
-	}
-	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
-		return filepath.Join(xdg, "aix", "aix.db"), nil
-	}
-	return filepath.Join(home, ".local", "share", "aix", "aix.db"), nil
+	return config.AixDBPath()
 }
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	_ "modernc.org/sqlite"
+
+	"github.com/Napageneral/mnemonic/internal/errs"
 )
 
 // AixAgentsAdapter syncs full fidelity AI session data from AIX to the Agents Ledger.
@@ -30,7 +32,7 @@ func NewAixAgentsAdapter(source string) (*AixAgentsAdapter, error) {
 		return nil, err
 	}
 	if _, err := os.Stat(dbPath); err != nil {
-		return nil, fmt.Errorf("aix database not found at %s (run aix sync --all first): %w", dbPath, err)
+		return nil, errs.New(errs.ErrAdapterSourceMissing, "aix database not found at %s (run aix sync --all first): %w", dbPath, err)
 	}
 
 	return &AixAgentsAdapter{
@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/errs"
+	"github.com/Napageneral/mnemonic/internal/me"
 	_ "modernc.org/sqlite"
 )
 
@@ -34,7 +36,7 @@ func NewAixEventsAdapter(source string) (*AixEventsAdapter, error) {
 		return nil, err
 	}
 	if _, err := os.Stat(dbPath); err != nil {
-		return nil, fmt.Errorf("aix database not found at %s (run aix sync --all first): %w", dbPath, err)
+		return nil, errs.New(errs.ErrAdapterSourceMissing, "aix database not found at %s (run aix sync --all first): %w", dbPath, err)
 	}
 
 	return &AixEventsAdapter{
@@ -85,8 +87,7 @@ func (a *AixEventsAdapter) Sync(ctx context.Context, cortexDB *sql.DB, full bool
 	result.Perf = map[string]string{}
 
 	// Look up me person if present (optional)
-	var mePersonID string
-	_ = cortexDB.QueryRow("SELECT id FROM persons WHERE is_me = 1 LIMIT 1").Scan(&mePersonID)
+	mePersonID, _ := me.LookupMePersonID(cortexDB)
 
 	// Ensure "me" has a contact endpoint for this source
 	meContactID, err := a.ensureMeContact(cortexDB, mePersonID)
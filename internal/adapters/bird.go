@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/errs"
 	"github.com/google/uuid"
 	_ "modernc.org/sqlite"
 )
@@ -22,7 +23,7 @@ type BirdAdapter struct {
 func NewBirdAdapter(username string) (*BirdAdapter, error) {
 	// Verify bird is available
 	if _, err := exec.LookPath("bird"); err != nil {
-		return nil, fmt.Errorf("bird not found in PATH. Install with: brew install steipete/tap/bird")
+		return nil, errs.New(errs.ErrAdapterSourceMissing, "bird not found in PATH. Install with: brew install steipete/tap/bird")
 	}
 
 	// Get username from bird whoami if not provided
@@ -11,6 +11,7 @@ import (
 
 	"github.com/Napageneral/mnemonic/internal/bus"
 	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/errs"
 	"github.com/Napageneral/mnemonic/internal/state"
 )
 
@@ -28,7 +29,7 @@ func NewCalendarAdapter(name, account string) (*CalendarAdapter, error) {
 		return nil, fmt.Errorf("account email is required for calendar adapter")
 	}
 	if _, err := exec.LookPath("gog"); err != nil {
-		return nil, fmt.Errorf("gogcli (gog) not found in PATH. Install with: brew install steipete/tap/gogcli")
+		return nil, errs.New(errs.ErrAdapterSourceMissing, "gogcli (gog) not found in PATH. Install with: brew install steipete/tap/gogcli")
 	}
 	return &CalendarAdapter{name: name, account: account}, nil
 }
@@ -82,6 +83,11 @@ type gogEventPerson struct {
 }
 
 func (c *CalendarAdapter) ensureCalendarTables(db *sql.DB) error {
+	return ensureCalendarTables(db)
+}
+
+// ensureCalendarTables creates the state and tag tables calendar events use.
+func ensureCalendarTables(db *sql.DB) error {
 	// Defensive for existing DBs.
 	stmts := []string{
 		`CREATE TABLE IF NOT EXISTS event_state (
@@ -186,7 +192,7 @@ func parseEventStartUTC(e gogCalendarEvent) (int64, error) {
 	return 0, fmt.Errorf("missing start time")
 }
 
-func (c *CalendarAdapter) getOrCreateContactByEmail(db *sql.DB, email, displayName string, cache map[string]string) (string, bool, error) {
+func getOrCreateCalendarContact(db *sql.DB, adapter, email, displayName string, cache map[string]string) (string, bool, error) {
 	normalized := contacts.NormalizeIdentifier(email, "email")
 	if normalized == "" {
 		return "", false, fmt.Errorf("empty email")
@@ -195,7 +201,7 @@ func (c *CalendarAdapter) getOrCreateContactByEmail(db *sql.DB, email, displayNa
 		return id, false, nil
 	}
 
-	contactID, created, err := contacts.GetOrCreateContact(db, "email", email, displayName, c.Name())
+	contactID, created, err := contacts.GetOrCreateContact(db, "email", email, displayName, adapter)
 	if err != nil {
 		return "", false, err
 	}
@@ -203,7 +209,7 @@ func (c *CalendarAdapter) getOrCreateContactByEmail(db *sql.DB, email, displayNa
 	return contactID, created, nil
 }
 
-func (c *CalendarAdapter) upsertEvent(db *sql.DB, eventID string, ts int64, content string, threadID string, sourceID string) (created bool, updated bool, err error) {
+func upsertCalendarEvent(db *sql.DB, adapter string, eventID string, ts int64, content string, threadID string, sourceID string) (created bool, updated bool, err error) {
 	contentTypes := `["calendar_event"]`
 	direction := "observed"
 
@@ -212,18 +218,18 @@ func (c *CalendarAdapter) upsertEvent(db *sql.DB, eventID string, ts int64, cont
 			id, timestamp, channel, content_types, content,
 			direction, thread_id, reply_to, source_adapter, source_id
 		) VALUES (?, ?, 'calendar', ?, ?, ?, ?, '', ?, ?)
-	`, eventID, ts, contentTypes, content, direction, threadID, c.Name(), sourceID)
+	`, eventID, ts, contentTypes, content, direction, threadID, adapter, sourceID)
 	if err != nil {
 		return false, false, err
 	}
 	if n, _ := res.RowsAffected(); n > 0 {
-		_ = bus.Emit(db, "cortex.event.created", c.Name(), eventID, map[string]any{
+		_ = bus.Emit(db, "cortex.event.created", adapter, eventID, map[string]any{
 			"channel":        "calendar",
 			"direction":      direction,
 			"timestamp":      ts,
 			"thread_id":      threadID,
 			"source_id":      sourceID,
-			"source_adapter": c.Name(),
+			"source_adapter": adapter,
 		})
 		return true, false, nil
 	}
@@ -235,25 +241,25 @@ func (c *CalendarAdapter) upsertEvent(db *sql.DB, eventID string, ts int64, cont
 			content = ?,
 			thread_id = ?
 		WHERE source_adapter = ? AND source_id = ?
-	`, ts, content, threadID, c.Name(), sourceID)
+	`, ts, content, threadID, adapter, sourceID)
 	if err != nil {
 		return false, false, err
 	}
 	if n, _ := res.RowsAffected(); n > 0 {
-		_ = bus.Emit(db, "cortex.event.updated", c.Name(), eventID, map[string]any{
+		_ = bus.Emit(db, "cortex.event.updated", adapter, eventID, map[string]any{
 			"channel":        "calendar",
 			"direction":      direction,
 			"timestamp":      ts,
 			"thread_id":      threadID,
 			"source_id":      sourceID,
-			"source_adapter": c.Name(),
+			"source_adapter": adapter,
 		})
 		return false, true, nil
 	}
 	return false, false, nil
 }
 
-func (c *CalendarAdapter) upsertStateAndTags(db *sql.DB, eventID string, calendarID string, ev gogCalendarEvent) error {
+func upsertCalendarStateAndTags(db *sql.DB, eventID string, calendarID string, ev gogCalendarEvent) error {
 	now := time.Now().Unix()
 	_, _ = db.Exec(`DELETE FROM event_tags WHERE event_id = ? AND source = 'calendar'`, eventID)
 	_, _ = db.Exec(`
@@ -279,6 +285,62 @@ func (c *CalendarAdapter) upsertStateAndTags(db *sql.DB, eventID string, calenda
 	return err
 }
 
+// writeCalendarEvent stores a calendar event with its organizer and
+// attendees as participants, and its status and calendar as state and tags.
+func writeCalendarEvent(db *sql.DB, adapter string, cal gogCalendar, ev gogCalendarEvent, cache map[string]string, res *SyncResult) error {
+	if strings.TrimSpace(ev.ID) == "" {
+		return nil
+	}
+	ts, err := parseEventStartUTC(ev)
+	if err != nil {
+		return nil
+	}
+
+	sourceID := fmt.Sprintf("%s:%s", cal.ID, ev.ID)
+	eventID := fmt.Sprintf("%s:%s", adapter, sourceID)
+	threadID := "calendar:" + cal.ID
+
+	content := fmt.Sprintf("Summary: %s\nCalendar: %s\nStatus: %s\nLink: %s\nLocation: %s\n\n%s",
+		ev.Summary, cal.Summary, ev.Status, ev.HTMLLink, ev.Location, ev.Description)
+
+	created, updated, err := upsertCalendarEvent(db, adapter, eventID, ts, content, threadID, sourceID)
+	if err != nil {
+		return err
+	}
+	if created {
+		res.EventsCreated++
+	} else if updated {
+		res.EventsUpdated++
+	}
+
+	// Participants: organizer + attendees
+	people := ev.Attendees
+	roles := make([]string, len(people))
+	for i := range roles {
+		roles[i] = "attendee"
+	}
+	if ev.Organizer != nil {
+		people = append([]gogEventPerson{*ev.Organizer}, people...)
+		roles = append([]string{"organizer"}, roles...)
+	}
+	for i, p := range people {
+		if p.Email == "" {
+			continue
+		}
+		contactID, _, err := getOrCreateCalendarContact(db, adapter, p.Email, p.DisplayName, cache)
+		if err != nil {
+			continue
+		}
+		if _, created, err := contacts.EnsurePersonForContact(db, contactID, p.DisplayName, "deterministic", 0.9); err == nil && created {
+			res.PersonsCreated++
+		}
+		_, _ = db.Exec(`INSERT OR IGNORE INTO event_participants (event_id, contact_id, role) VALUES (?, ?, ?)`, eventID, contactID, roles[i])
+	}
+
+	_ = upsertCalendarStateAndTags(db, eventID, cal.ID, ev)
+	return nil
+}
+
 func (c *CalendarAdapter) getCursor(db *sql.DB) (string, bool) {
 	v, ok, err := state.Get(db, c.Name(), "calendar_backfill_cursor")
 	if err != nil || !ok || strings.TrimSpace(v) == "" {
@@ -341,55 +403,9 @@ func (c *CalendarAdapter) Sync(ctx context.Context, cortexDB *sql.DB, full bool)
 					return res, err
 				}
 				for _, ev := range events {
-					if strings.TrimSpace(ev.ID) == "" {
-						continue
-					}
-					ts, err := parseEventStartUTC(ev)
-					if err != nil {
-						continue
-					}
-
-					sourceID := fmt.Sprintf("%s:%s", cal.ID, ev.ID)
-					eventID := fmt.Sprintf("%s:%s", c.Name(), sourceID)
-					threadID := "calendar:" + cal.ID
-
-					content := fmt.Sprintf("Summary: %s\nCalendar: %s\nStatus: %s\nLink: %s\nLocation: %s\n\n%s",
-						ev.Summary, cal.Summary, ev.Status, ev.HTMLLink, ev.Location, ev.Description)
-
-					created, updated, err := c.upsertEvent(cortexDB, eventID, ts, content, threadID, sourceID)
-					if err != nil {
+					if err := writeCalendarEvent(cortexDB, c.Name(), cal, ev, cache, &res); err != nil {
 						return res, err
 					}
-					if created {
-						res.EventsCreated++
-					} else if updated {
-						res.EventsUpdated++
-					}
-
-					// Participants: organizer + attendees
-					if ev.Organizer != nil && ev.Organizer.Email != "" {
-						contactID, _, err := c.getOrCreateContactByEmail(cortexDB, ev.Organizer.Email, ev.Organizer.DisplayName, cache)
-						if err == nil {
-							if _, created, err := contacts.EnsurePersonForContact(cortexDB, contactID, ev.Organizer.DisplayName, "deterministic", 0.9); err == nil && created {
-								res.PersonsCreated++
-							}
-							_, _ = cortexDB.Exec(`INSERT OR IGNORE INTO event_participants (event_id, contact_id, role) VALUES (?, ?, 'organizer')`, eventID, contactID)
-						}
-					}
-					for _, a := range ev.Attendees {
-						if a.Email == "" {
-							continue
-						}
-						contactID, _, err := c.getOrCreateContactByEmail(cortexDB, a.Email, a.DisplayName, cache)
-						if err == nil {
-							if _, created, err := contacts.EnsurePersonForContact(cortexDB, contactID, a.DisplayName, "deterministic", 0.9); err == nil && created {
-								res.PersonsCreated++
-							}
-							_, _ = cortexDB.Exec(`INSERT OR IGNORE INTO event_participants (event_id, contact_id, role) VALUES (?, ?, 'attendee')`, eventID, contactID)
-						}
-					}
-
-					_ = c.upsertStateAndTags(cortexDB, eventID, cal.ID, ev)
 				}
 			}
 
@@ -413,47 +429,9 @@ func (c *CalendarAdapter) Sync(ctx context.Context, cortexDB *sql.DB, full bool)
 				return res, err
 			}
 			for _, ev := range events {
-				ts, err := parseEventStartUTC(ev)
-				if err != nil {
-					continue
-				}
-				sourceID := fmt.Sprintf("%s:%s", cal.ID, ev.ID)
-				eventID := fmt.Sprintf("%s:%s", c.Name(), sourceID)
-				threadID := "calendar:" + cal.ID
-				content := fmt.Sprintf("Summary: %s\nCalendar: %s\nStatus: %s\nLink: %s\nLocation: %s\n\n%s",
-					ev.Summary, cal.Summary, ev.Status, ev.HTMLLink, ev.Location, ev.Description)
-
-				created, updated, err := c.upsertEvent(cortexDB, eventID, ts, content, threadID, sourceID)
-				if err != nil {
+				if err := writeCalendarEvent(cortexDB, c.Name(), cal, ev, cache, &res); err != nil {
 					return res, err
 				}
-				if created {
-					res.EventsCreated++
-				} else if updated {
-					res.EventsUpdated++
-				}
-				if ev.Organizer != nil && ev.Organizer.Email != "" {
-					contactID, _, err := c.getOrCreateContactByEmail(cortexDB, ev.Organizer.Email, ev.Organizer.DisplayName, cache)
-					if err == nil {
-						if _, created, err := contacts.EnsurePersonForContact(cortexDB, contactID, ev.Organizer.DisplayName, "deterministic", 0.9); err == nil && created {
-							res.PersonsCreated++
-						}
-						_, _ = cortexDB.Exec(`INSERT OR IGNORE INTO event_participants (event_id, contact_id, role) VALUES (?, ?, 'organizer')`, eventID, contactID)
-					}
-				}
-				for _, a := range ev.Attendees {
-					if a.Email == "" {
-						continue
-					}
-					contactID, _, err := c.getOrCreateContactByEmail(cortexDB, a.Email, a.DisplayName, cache)
-					if err == nil {
-						if _, created, err := contacts.EnsurePersonForContact(cortexDB, contactID, a.DisplayName, "deterministic", 0.9); err == nil && created {
-							res.PersonsCreated++
-						}
-						_, _ = cortexDB.Exec(`INSERT OR IGNORE INTO event_participants (event_id, contact_id, role) VALUES (?, ?, 'attendee')`, eventID, contactID)
-					}
-				}
-				_ = c.upsertStateAndTags(cortexDB, eventID, cal.ID, ev)
 			}
 		}
 	}
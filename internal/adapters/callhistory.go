@@ -0,0 +1,528 @@
+package adapters
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/avatars"
+	"github.com/Napageneral/mnemonic/internal/config"
+	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/errs"
+	_ "modernc.org/sqlite"
+)
+
+// CallHistoryAdapterOptions configures the call history adapter.
+type CallHistoryAdapterOptions struct {
+	// Path is the macOS call history database (default:
+	// config.CallHistoryPath)
+	Path string
+	// Voicemail is a voicemail.db, or the directory holding it, such as
+	// Library/Voicemail from an unencrypted iPhone backup (optional)
+	Voicemail string
+}
+
+// CallHistoryAdapter imports phone and FaceTime calls from the macOS call
+// history (CallHistory.storedata, which also holds calls made on an iPhone
+// signed in to the same account) and, optionally, voicemails. Each call is
+// an event on the call channel with its direction, duration and whether it
+// was answered in the metadata, in a thread per number, so calls show up
+// on a person's timeline next to their messages. Voicemails are events in
+// the same threads, with the transcript as content when there is one.
+// Every sync reads both databases whole; calls already imported are left
+// alone and voicemails pick up transcripts that arrived later.
+type CallHistoryAdapter struct {
+	path           string
+	voicemailPath  string
+	addressBookDir string
+}
+
+// NewCallHistoryAdapter creates an adapter for the call history database.
+// Reading it needs Full Disk Access for the terminal or binary running the
+// sync.
+func NewCallHistoryAdapter(opts CallHistoryAdapterOptions) (*CallHistoryAdapter, error) {
+	path := strings.TrimSpace(opts.Path)
+	if path == "" {
+		var err error
+		if path, err = config.CallHistoryPath(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, errs.New(errs.ErrAdapterSourceMissing, "call history not found at %s: %w", path, err)
+	}
+	voicemail := strings.TrimSpace(opts.Voicemail)
+	if voicemail != "" {
+		info, err := os.Stat(voicemail)
+		if err != nil {
+			return nil, errs.New(errs.ErrAdapterSourceMissing, "voicemail not found at %s: %w", voicemail, err)
+		}
+		if info.IsDir() {
+			voicemail = filepath.Join(voicemail, "voicemail.db")
+			if _, err := os.Stat(voicemail); err != nil {
+				return nil, errs.New(errs.ErrAdapterSourceMissing, "voicemail not found at %s: %w", voicemail, err)
+			}
+		}
+	}
+	abDir, _ := avatars.DefaultAddressBookDir()
+	return &CallHistoryAdapter{path: path, voicemailPath: voicemail, addressBookDir: abDir}, nil
+}
+
+func (a *CallHistoryAdapter) Name() string {
+	return "calls"
+}
+
+// ZCALLRECORD.ZCALLTYPE and ZSERVICE_PROVIDER values (phone calls are
+// type 1)
+const (
+	callTypeFaceTimeVideo = 8
+	callTypeFaceTimeAudio = 16
+	callProviderTelephony = "com.apple.Telephony"
+	callProviderFaceTime  = "com.apple.FaceTime"
+)
+
+// callRecord is one call or voicemail, before it is written.
+type callRecord struct {
+	sourceID  string
+	timestamp int64
+	address   string // phone number or email of the other party ("" when withheld)
+	name      string // name the call history or voicemail gives them
+	outgoing  bool
+	answered  bool
+	duration  int64 // seconds
+	service   string
+	voicemail bool
+	text      string // voicemail transcript
+}
+
+// callHistoryStats tallies what a sync wrote.
+type callHistoryStats struct {
+	calls, missed, voicemails, transcripts int
+}
+
+func (a *CallHistoryAdapter) Sync(ctx context.Context, cortexDB *sql.DB, full bool) (SyncResult, error) {
+	start := time.Now()
+	res := SyncResult{Perf: map[string]string{}}
+	_ = full // every sync reads the whole history (idempotent)
+
+	records, err := readCallHistory(ctx, a.path)
+	if err != nil {
+		return res, fmt.Errorf("failed to read call history (Full Disk Access may be required): %w", err)
+	}
+	if a.voicemailPath != "" {
+		voicemails, err := readVoicemails(ctx, a.voicemailPath)
+		if err != nil {
+			return res, fmt.Errorf("failed to read voicemail: %w", err)
+		}
+		records = append(records, voicemails...)
+	}
+
+	// Names from macOS Contacts; the call history often has none
+	names, err := addressBookNames(ctx, a.addressBookDir)
+	if err != nil {
+		// Non-fatal - contacts are named by identifier instead
+		res.Perf["address_book.error"] = err.Error()
+	}
+
+	_, _ = cortexDB.Exec("PRAGMA foreign_keys = ON")
+	tx, err := cortexDB.BeginTx(ctx, nil)
+	if err != nil {
+		return res, fmt.Errorf("begin cortex tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	meContactID, meCreated, err := meContact(tx, a.Name())
+	if err != nil {
+		return res, err
+	}
+	res.PersonsCreated += meCreated
+
+	var stats callHistoryStats
+	parties := map[string]*callParty{}
+	for _, r := range records {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		party, err := a.party(tx, r, names, parties)
+		if err != nil {
+			return res, err
+		}
+		if err := a.writeRecord(tx, r, party, meContactID, &res, &stats); err != nil {
+			return res, err
+		}
+	}
+
+	// A thread per party, and identifiers on one Contacts card belong to
+	// one person
+	keys := make([]string, 0, len(parties))
+	for key := range parties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	cards := map[string][]string{}
+	var cardOrder []string
+	cardNames := map[string]string{}
+	for _, key := range keys {
+		p := parties[key]
+		if err := a.upsertThread(tx, p, &res); err != nil {
+			return res, err
+		}
+		if p.card == "" || p.contactID == "" {
+			continue
+		}
+		if _, ok := cards[p.card]; !ok {
+			cardOrder = append(cardOrder, p.card)
+			cardNames[p.card] = p.name
+		}
+		cards[p.card] = append(cards[p.card], p.contactID)
+	}
+	for _, card := range cardOrder {
+		if _, created, err := contacts.LinkCard(tx, cards[card], cardNames[card]); err != nil {
+			return res, fmt.Errorf("link contact person: %w", err)
+		} else if created {
+			res.PersonsCreated++
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return res, fmt.Errorf("commit cortex tx: %w", err)
+	}
+
+	res.Perf["calls"] = strconv.Itoa(stats.calls)
+	res.Perf["calls.missed"] = strconv.Itoa(stats.missed)
+	res.Perf["voicemails"] = strconv.Itoa(stats.voicemails)
+	res.Perf["voicemails.transcribed"] = strconv.Itoa(stats.transcripts)
+	res.Duration = time.Since(start)
+	res.Perf["total"] = res.Duration.String()
+	return res, nil
+}
+
+// callParty is the other side of calls: a contact and the thread of their
+// calls.
+type callParty struct {
+	contactID    string // "" when the number was withheld
+	address      string
+	threadSource string
+	threadID     string
+	name         string
+	card         string
+	first, last  int64 // timestamps of their first and last call
+}
+
+// party returns the other party of a record, creating their contact on
+// first use.
+func (a *CallHistoryAdapter) party(tx *sql.Tx, r callRecord, names map[string]addressBookName, parties map[string]*callParty) (*callParty, error) {
+	idType := "phone"
+	if strings.Contains(r.address, "@") {
+		idType = "email"
+	}
+	normalized := contacts.NormalizeIdentifier(r.address, idType)
+	key := idType + ":" + normalized
+	if normalized == "" {
+		key = "unknown"
+	}
+	if p, ok := parties[key]; ok {
+		p.first, p.last = min(p.first, r.timestamp), max(p.last, r.timestamp)
+		return p, nil
+	}
+
+	p := &callParty{name: r.name, address: r.address, first: r.timestamp, last: r.timestamp}
+	if known, ok := names[key]; ok {
+		p.name, p.card = known.name, known.card
+	}
+	if normalized != "" {
+		contactID, _, err := contacts.GetOrCreateContact(tx, idType, r.address, p.name, a.Name())
+		if err != nil {
+			return nil, fmt.Errorf("contact %s: %w", r.address, err)
+		}
+		p.contactID = contactID
+	}
+	p.threadSource = "calls:" + key
+	p.threadID = a.Name() + ":" + p.threadSource
+	parties[key] = p
+	return p, nil
+}
+
+// upsertThread stores the thread of calls with a party.
+func (a *CallHistoryAdapter) upsertThread(tx *sql.Tx, p *callParty, res *SyncResult) error {
+	name := p.name
+	if name == "" {
+		name = p.address
+	}
+	if name == "" {
+		name = "Unknown caller"
+	}
+	var exists int
+	if err := tx.QueryRow(`SELECT 1 FROM threads WHERE source_adapter = ? AND source_id = ?`, a.Name(), p.threadSource).Scan(&exists); err == sql.ErrNoRows {
+		res.ThreadsCreated++
+	} else if err != nil {
+		return fmt.Errorf("lookup thread: %w", err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO threads (id, channel, name, is_group, source_adapter, source_id, created_at, updated_at)
+		VALUES (?, 'call', ?, 0, ?, ?, ?, ?)
+		ON CONFLICT(source_adapter, source_id) DO UPDATE SET
+			name = excluded.name,
+			created_at = MIN(threads.created_at, excluded.created_at),
+			updated_at = MAX(threads.updated_at, excluded.updated_at)
+	`, p.threadID, name, a.Name(), p.threadSource, p.first, p.last); err != nil {
+		return fmt.Errorf("upsert thread: %w", err)
+	}
+	return nil
+}
+
+// writeRecord stores a call or voicemail with its caller as sender and the
+// callee as recipient.
+func (a *CallHistoryAdapter) writeRecord(tx *sql.Tx, r callRecord, p *callParty, meContactID string, res *SyncResult, stats *callHistoryStats) error {
+	contentTypes := `["call"]`
+	content := callSummary(r)
+	metadata := map[string]any{
+		"duration": r.duration,
+		"service":  r.service,
+	}
+	if r.voicemail {
+		stats.voicemails++
+		contentTypes = `["voicemail"]`
+		if r.text != "" {
+			stats.transcripts++
+			contentTypes = `["text","voicemail"]`
+			content = r.text
+		}
+		metadata["voicemail"] = true
+	} else {
+		stats.calls++
+		metadata["call_direction"] = "incoming"
+		if r.outgoing {
+			metadata["call_direction"] = "outgoing"
+		}
+		metadata["answered"] = r.answered
+		if !r.outgoing && !r.answered {
+			stats.missed++
+			metadata["missed"] = true
+		}
+	}
+	if r.address != "" {
+		metadata["address"] = r.address
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+
+	direction := "received"
+	if r.outgoing {
+		direction = "sent"
+	}
+	eventID := a.Name() + ":" + r.sourceID
+	result, err := tx.Exec(`
+		INSERT OR IGNORE INTO events (
+			id, timestamp, channel, content_types, content,
+			direction, thread_id, reply_to, source_adapter, source_id, metadata_json
+		) VALUES (?, ?, 'call', ?, ?, ?, ?, '', ?, ?, ?)
+	`, eventID, r.timestamp, contentTypes, content, direction, p.threadID, a.Name(), r.sourceID, string(metadataJSON))
+	if err != nil {
+		return fmt.Errorf("insert call event: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 1 {
+		res.EventsCreated++
+	} else {
+		// A voicemail's transcript can arrive after the recording
+		result, err := tx.Exec(`
+			UPDATE events SET content = ?, content_types = ?, metadata_json = ?
+			WHERE id = ? AND (content IS NOT ? OR content_types IS NOT ?)
+		`, content, contentTypes, string(metadataJSON), eventID, content, contentTypes)
+		if err != nil {
+			return fmt.Errorf("update call event: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			res.EventsUpdated++
+		}
+	}
+
+	caller, callee := p.contactID, meContactID
+	if r.outgoing {
+		caller, callee = meContactID, p.contactID
+	}
+	for _, participant := range []struct{ contactID, role string }{{caller, "sender"}, {callee, "recipient"}} {
+		if participant.contactID == "" {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO event_participants (event_id, contact_id, role) VALUES (?, ?, ?)`, eventID, participant.contactID, participant.role); err != nil {
+			return fmt.Errorf("insert call participant: %w", err)
+		}
+	}
+	return nil
+}
+
+// callSummary describes a call in words, e.g. "Outgoing FaceTime video
+// call, 3m12s" or "Missed call".
+func callSummary(r callRecord) string {
+	kind := "call"
+	switch r.service {
+	case "facetime_video":
+		kind = "FaceTime video call"
+	case "facetime_audio":
+		kind = "FaceTime audio call"
+	case "phone", "":
+	default:
+		kind = r.service + " call"
+	}
+	if r.voicemail {
+		if r.duration > 0 {
+			return fmt.Sprintf("Voicemail, %s", time.Duration(r.duration)*time.Second)
+		}
+		return "Voicemail"
+	}
+	switch {
+	case !r.outgoing && !r.answered:
+		return "Missed " + kind
+	case r.outgoing && !r.answered:
+		return "Unanswered outgoing " + kind
+	case r.outgoing:
+		return fmt.Sprintf("Outgoing %s, %s", kind, time.Duration(r.duration)*time.Second)
+	default:
+		return fmt.Sprintf("Incoming %s, %s", kind, time.Duration(r.duration)*time.Second)
+	}
+}
+
+// callService names the service a call was made with: phone,
+// facetime_audio, facetime_video, or the provider of a third-party app.
+func callService(callType int64, provider string) string {
+	switch callType {
+	case callTypeFaceTimeVideo:
+		return "facetime_video"
+	case callTypeFaceTimeAudio:
+		return "facetime_audio"
+	}
+	switch provider {
+	case "", callProviderTelephony:
+		return "phone"
+	case callProviderFaceTime:
+		return "facetime_audio"
+	default:
+		return provider
+	}
+}
+
+// readCallHistory reads the calls in a CallHistory.storedata database.
+// Columns that older macOS versions lack are optional.
+func readCallHistory(ctx context.Context, path string) ([]callRecord, error) {
+	historyDB, err := sql.Open("sqlite", "file:"+path+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	defer historyDB.Close()
+	columns, err := tableColumns(ctx, historyDB, "ZCALLRECORD")
+	if err != nil {
+		return nil, err
+	}
+	optional := func(column, fallback string) string {
+		if columns[column] {
+			return column
+		}
+		return fallback
+	}
+
+	rows, err := historyDB.QueryContext(ctx, `
+		SELECT Z_PK, `+optional("ZUNIQUE_ID", "NULL")+`, CAST(ZADDRESS AS TEXT), ZDATE, ZDURATION,
+		       ZORIGINATED, ZANSWERED, `+optional("ZCALLTYPE", "1")+`,
+		       `+optional("ZSERVICE_PROVIDER", "NULL")+`, `+optional("ZNAME", "NULL")+`
+		FROM ZCALLRECORD
+		ORDER BY ZDATE, Z_PK
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query calls: %w", err)
+	}
+	defer rows.Close()
+
+	var records []callRecord
+	for rows.Next() {
+		var pk int64
+		var uniqueID, address, provider, name sql.NullString
+		var date, duration sql.NullFloat64
+		var originated, answered, callType sql.NullInt64
+		if err := rows.Scan(&pk, &uniqueID, &address, &date, &duration, &originated, &answered, &callType, &provider, &name); err != nil {
+			return nil, fmt.Errorf("scan call: %w", err)
+		}
+		sourceID := "call:" + uniqueID.String
+		if uniqueID.String == "" {
+			sourceID = fmt.Sprintf("call:%d", pk)
+		}
+		records = append(records, callRecord{
+			sourceID:  sourceID,
+			timestamp: int64(date.Float64) + appleEpoch,
+			address:   strings.TrimSpace(address.String),
+			name:      strings.TrimSpace(name.String),
+			outgoing:  originated.Int64 == 1,
+			answered:  answered.Int64 == 1,
+			duration:  int64(duration.Float64 + 0.5),
+			service:   callService(callType.Int64, provider.String),
+		})
+	}
+	return records, rows.Err()
+}
+
+// readVoicemails reads the voicemails in a voicemail.db, skipping those
+// moved to the trash. The transcript comes from the transcript column,
+// where the database has one.
+func readVoicemails(ctx context.Context, path string) ([]callRecord, error) {
+	voicemailDB, err := sql.Open("sqlite", "file:"+path+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	defer voicemailDB.Close()
+	columns, err := tableColumns(ctx, voicemailDB, "voicemail")
+	if err != nil {
+		return nil, err
+	}
+	transcript := "NULL"
+	if columns["transcript"] {
+		transcript = "transcript"
+	}
+	trashed := "0"
+	if columns["trashed_date"] {
+		trashed = "COALESCE(trashed_date, 0)"
+	}
+
+	rows, err := voicemailDB.QueryContext(ctx, `
+		SELECT ROWID, remote_uid, date, sender, callback_num, duration, `+transcript+`
+		FROM voicemail
+		WHERE `+trashed+` = 0
+		ORDER BY date, ROWID
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query voicemails: %w", err)
+	}
+	defer rows.Close()
+
+	var records []callRecord
+	for rows.Next() {
+		var rowID int64
+		var remoteUID, date, duration sql.NullInt64
+		var sender, callback, text sql.NullString
+		if err := rows.Scan(&rowID, &remoteUID, &date, &sender, &callback, &duration, &text); err != nil {
+			return nil, fmt.Errorf("scan voicemail: %w", err)
+		}
+		address := strings.TrimSpace(sender.String)
+		if address == "" {
+			address = strings.TrimSpace(callback.String)
+		}
+		sourceID := fmt.Sprintf("voicemail:%d", remoteUID.Int64)
+		if remoteUID.Int64 == 0 {
+			sourceID = fmt.Sprintf("voicemail:row:%d", rowID)
+		}
+		records = append(records, callRecord{
+			sourceID:  sourceID,
+			timestamp: date.Int64,
+			address:   address,
+			duration:  duration.Int64,
+			service:   "phone",
+			voicemail: true,
+			text:      strings.TrimSpace(text.String),
+		})
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,154 @@
+package adapters
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func createTestCallHistory(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	historyPath := filepath.Join(dir, "CallHistory.storedata")
+	historyDB, err := sql.Open("sqlite", historyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer historyDB.Close()
+	// Dates are seconds since 2001; 700000000 is 2023-03-08
+	if _, err := historyDB.Exec(`
+		CREATE TABLE ZCALLRECORD (
+			Z_PK INTEGER PRIMARY KEY, ZANSWERED INTEGER, ZCALLTYPE INTEGER, ZORIGINATED INTEGER,
+			ZDATE TIMESTAMP, ZDURATION FLOAT, ZADDRESS BLOB, ZNAME VARCHAR,
+			ZSERVICE_PROVIDER VARCHAR, ZUNIQUE_ID VARCHAR
+		);
+		INSERT INTO ZCALLRECORD VALUES
+			(1, 1, 1, 1, 700000000.5, 192.4, '+15551234567', NULL, 'com.apple.Telephony', 'A1'),
+			(2, 0, 1, 0, 700003600, 0, '(555) 123-4567', NULL, 'com.apple.Telephony', 'A2'),
+			(3, 1, 8, 0, 700007200, 45, 'dana@example.com', 'Dana', 'com.apple.FaceTime', 'A3'),
+			(4, 0, 1, 0, 700010800, 0, '', NULL, 'com.apple.Telephony', 'A4');
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	voicemailPath := filepath.Join(dir, "voicemail.db")
+	voicemailDB, err := sql.Open("sqlite", voicemailPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer voicemailDB.Close()
+	if _, err := voicemailDB.Exec(`
+		CREATE TABLE voicemail (
+			ROWID INTEGER PRIMARY KEY, remote_uid INTEGER, date INTEGER, token TEXT, sender TEXT,
+			callback_num TEXT, duration INTEGER, expiration INTEGER, trashed_date INTEGER, flags INTEGER,
+			transcript TEXT
+		);
+		INSERT INTO voicemail VALUES
+			(1, 101, 1678300000, '', '+15551234567', '+15551234567', 21, 0, 0, 0, NULL),
+			(2, 102, 1678310000, '', '+15559990000', '+15559990000', 5, 0, 1678320000, 0, 'Deleted');
+	`); err != nil {
+		t.Fatal(err)
+	}
+	return historyPath, voicemailPath
+}
+
+func TestCallHistoryAdapterSync(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	historyPath, voicemailPath := createTestCallHistory(t, t.TempDir())
+	a, err := NewCallHistoryAdapter(CallHistoryAdapterOptions{Path: historyPath, Voicemail: filepath.Dir(voicemailPath)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.addressBookDir = ""
+
+	res, err := a.Sync(ctx, db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.EventsCreated != 5 || res.ThreadsCreated != 3 || res.Perf["calls.missed"] != "2" || res.Perf["voicemails"] != "1" {
+		t.Errorf("first sync = %+v", res)
+	}
+
+	type call struct {
+		content, direction, thread, metadata string
+		participants                         int
+	}
+	calls := map[string]call{}
+	rows, err := db.Query(`
+		SELECT e.source_id, e.content, e.direction, e.thread_id, e.metadata_json,
+		       (SELECT COUNT(*) FROM event_participants ep WHERE ep.event_id = e.id)
+		FROM events e WHERE e.channel = 'call'
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var id string
+		var c call
+		if err := rows.Scan(&id, &c.content, &c.direction, &c.thread, &c.metadata, &c.participants); err != nil {
+			t.Fatal(err)
+		}
+		calls[id] = c
+	}
+	rows.Close()
+
+	// Both numbers are the same contact, so their calls share a thread
+	if c := calls["call:A1"]; c.content != "Outgoing call, 3m12s" || c.direction != "sent" || c.participants != 2 ||
+		c.thread != calls["call:A2"].thread || c.thread != calls["voicemail:101"].thread {
+		t.Errorf("outgoing call = %+v", c)
+	}
+	var meta map[string]any
+	if err := json.Unmarshal([]byte(calls["call:A1"].metadata), &meta); err != nil || meta["duration"] != float64(192) ||
+		meta["call_direction"] != "outgoing" || meta["answered"] != true {
+		t.Errorf("outgoing metadata = %v (%v)", meta, err)
+	}
+	if c := calls["call:A2"]; c.content != "Missed call" || c.direction != "received" {
+		t.Errorf("missed call = %+v", c)
+	}
+	if c := calls["call:A3"]; c.content != "Incoming FaceTime video call, 45s" {
+		t.Errorf("FaceTime call = %+v", c)
+	}
+	// A withheld number has only the user as participant
+	if c := calls["call:A4"]; c.content != "Missed call" || c.participants != 1 {
+		t.Errorf("withheld call = %+v", c)
+	}
+	if c := calls["voicemail:101"]; c.content != "Voicemail, 21s" {
+		t.Errorf("voicemail = %+v", c)
+	}
+	if _, ok := calls["voicemail:102"]; ok {
+		t.Error("trashed voicemail imported")
+	}
+	var name string
+	if err := db.QueryRow(`SELECT name FROM threads WHERE id = ?`, calls["call:A3"].thread).Scan(&name); err != nil || name != "Dana" {
+		t.Errorf("thread name = %q (%v)", name, err)
+	}
+
+	// A transcript that arrives later replaces the placeholder
+	voicemailDB, err := sql.Open("sqlite", voicemailPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := voicemailDB.Exec(`UPDATE voicemail SET transcript = 'Call me back about Saturday' WHERE ROWID = 1`); err != nil {
+		t.Fatal(err)
+	}
+	voicemailDB.Close()
+
+	res, err = a.Sync(ctx, db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.EventsCreated != 0 || res.EventsUpdated != 1 || res.ThreadsCreated != 0 {
+		t.Errorf("second sync = %+v", res)
+	}
+	var content, contentTypes string
+	if err := db.QueryRow(`SELECT content, content_types FROM events WHERE source_id = 'voicemail:101'`).Scan(&content, &contentTypes); err != nil ||
+		content != "Call me back about Saturday" || contentTypes != `["text","voicemail"]` {
+		t.Errorf("transcribed voicemail = %q %s (%v)", content, contentTypes, err)
+	}
+}
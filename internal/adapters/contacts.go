@@ -5,12 +5,15 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os/exec"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/Napageneral/mnemonic/internal/avatars"
 	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/errs"
 	"github.com/google/uuid"
 )
 
@@ -47,7 +50,7 @@ func NewContactsAdapter(name, account string, opts ...ContactsAdapterOptions) (*
 		return nil, fmt.Errorf("account email is required for contacts adapter")
 	}
 	if _, err := exec.LookPath("gog"); err != nil {
-		return nil, fmt.Errorf("gogcli (gog) not found in PATH. Install with: brew install steipete/tap/gogcli")
+		return nil, errs.New(errs.ErrAdapterSourceMissing, "gogcli (gog) not found in PATH. Install with: brew install steipete/tap/gogcli")
 	}
 	var o ContactsAdapterOptions
 	if len(opts) > 0 {
@@ -143,24 +146,28 @@ type gogContactFull struct {
 		CanonicalForm string `json:"canonicalForm"`
 		Value         string `json:"value"`
 	} `json:"phoneNumbers"`
+	Photos []struct {
+		URL     string `json:"url"`
+		Default bool   `json:"default"` // generated letter placeholder
+	} `json:"photos"`
 }
 
-func (c *ContactsAdapter) fetchContactDetails(ctx context.Context, resource string) (name string, emails []string, phones []string, ok bool, err error) {
+func (c *ContactsAdapter) fetchContactDetails(ctx context.Context, resource string) (name string, emails []string, phones []string, photoURL string, ok bool, err error) {
 	if !strings.HasPrefix(resource, "people/") {
-		return "", nil, nil, false, nil
+		return "", nil, nil, "", false, nil
 	}
 	cmd := exec.CommandContext(ctx, "gog", "contacts", "get", resource, "--json", "--account", c.account)
 	b, err := cmd.CombinedOutput()
 	if err != nil {
-		return "", nil, nil, false, fmt.Errorf("gog contacts get failed (%s): %w (output: %s)", resource, err, string(b))
+		return "", nil, nil, "", false, fmt.Errorf("gog contacts get failed (%s): %w (output: %s)", resource, err, string(b))
 	}
 	var resp gogContactGetResponse
 	if err := json.Unmarshal(b, &resp); err != nil {
-		return "", nil, nil, false, fmt.Errorf("failed to parse contacts get json: %w", err)
+		return "", nil, nil, "", false, fmt.Errorf("failed to parse contacts get json: %w", err)
 	}
 	// Some implementations return {"found":false}.
 	if resp.Found == false && resp.Contact.ResourceName == "" {
-		return "", nil, nil, false, nil
+		return "", nil, nil, "", false, nil
 	}
 	if len(resp.Contact.Names) > 0 {
 		name = strings.TrimSpace(resp.Contact.Names[0].DisplayName)
@@ -193,7 +200,13 @@ func (c *ContactsAdapter) fetchContactDetails(ctx context.Context, resource stri
 		seenP[v] = struct{}{}
 		phones = append(phones, v)
 	}
-	return name, emails, phones, true, nil
+	for _, p := range resp.Contact.Photos {
+		if p.URL != "" && !p.Default {
+			photoURL = p.URL
+			break
+		}
+	}
+	return name, emails, phones, photoURL, true, nil
 }
 
 func (c *ContactsAdapter) getPersonByIdentity(db *sql.DB, channel string, ident string) (personID string, isMe bool, ok bool, err error) {
@@ -244,6 +257,7 @@ type contactWithDetails struct {
 	Name     string
 	Emails   []string
 	Phones   []string
+	PhotoURL string
 }
 
 func (c *ContactsAdapter) Sync(ctx context.Context, cortexDB *sql.DB, full bool) (SyncResult, error) {
@@ -272,6 +286,7 @@ func (c *ContactsAdapter) Sync(ctx context.Context, cortexDB *sql.DB, full bool)
 	tProcess := time.Now()
 	processed := 0
 	linked := 0
+	photos, photoErrors := 0, 0
 
 	for _, ctc := range detailed {
 		select {
@@ -310,34 +325,24 @@ func (c *ContactsAdapter) Sync(ctx context.Context, cortexDB *sql.DB, full bool)
 			continue
 		}
 
-		var basePerson string
-		for _, cid := range contactIDs {
-			pid, err := contacts.GetLinkedPersonID(cortexDB, cid)
-			if err != nil {
-				return res, err
-			}
-			if pid != "" {
-				basePerson = pid
-				break
-			}
+		// The card says these identifiers are one person; link them now
+		// rather than leaving persons per identifier for merge resolution.
+		basePerson, created, err := contacts.LinkCard(cortexDB, contactIDs, name)
+		if err != nil {
+			return res, err
 		}
-		if basePerson == "" && contacts.IsMeaningfulPersonName(name) {
-			pid, created, err := contacts.EnsurePersonForContact(cortexDB, contactIDs[0], name, "deterministic", 1.0)
-			if err != nil {
-				return res, err
-			}
-			if created {
-				res.PersonsCreated++
-			}
-			basePerson = pid
+		if created {
+			res.PersonsCreated++
 		}
-
 		if basePerson != "" {
-			for _, cid := range contactIDs {
-				if err := contacts.EnsurePersonContactLink(cortexDB, basePerson, cid, "deterministic", 1.0); err != nil {
-					return res, err
-				}
-				linked++
+			linked += len(contactIDs)
+		}
+		if ctc.PhotoURL != "" {
+			// Non-fatal - photos are cosmetic
+			n, err := c.storePhoto(ctx, cortexDB, contactIDs, ctc.PhotoURL)
+			photos += n
+			if err != nil {
+				photoErrors++
 			}
 		}
 
@@ -350,11 +355,39 @@ func (c *ContactsAdapter) Sync(ctx context.Context, cortexDB *sql.DB, full bool)
 	res.Perf["process_duration"] = time.Since(tProcess).String()
 	res.Perf["contacts_processed"] = fmt.Sprintf("%d", processed)
 	res.Perf["contacts_linked"] = fmt.Sprintf("%d", linked)
+	res.Perf["photos_stored"] = fmt.Sprintf("%d", photos)
+	res.Perf["photo_errors"] = fmt.Sprintf("%d", photoErrors)
 	res.Duration = time.Since(start)
 	res.Perf["total"] = res.Duration.String()
 	return res, nil
 }
 
+// storePhoto downloads a card's photo onto its contacts, unless they
+// already have the photo from this URL. Returns the contacts updated.
+func (c *ContactsAdapter) storePhoto(ctx context.Context, db *sql.DB, contactIDs []string, url string) (int, error) {
+	var data []byte
+	stored := 0
+	for _, cid := range contactIDs {
+		prev, err := avatars.SourceURL(db, cid, c.Name())
+		if err != nil {
+			return stored, err
+		}
+		if prev == url {
+			continue
+		}
+		if data == nil {
+			if data, err = avatars.Download(ctx, http.DefaultClient, url); err != nil {
+				return stored, err
+			}
+		}
+		if err := avatars.Store(db, cid, c.Name(), data, url); err != nil {
+			return stored, err
+		}
+		stored++
+	}
+	return stored, nil
+}
+
 // fetchAllDetailsParallel fetches contact details in parallel using worker pool
 func (c *ContactsAdapter) fetchAllDetailsParallel(ctx context.Context, contacts []gogContact) []contactWithDetails {
 	// Build list of contacts that need detail fetch
@@ -396,11 +429,12 @@ func (c *ContactsAdapter) fetchAllDetailsParallel(ctx context.Context, contacts
 
 				// Fetch full details if people/... resource
 				if strings.HasPrefix(j.ctc.Resource, "people/") {
-					n2, e2, p2, ok, err := c.fetchContactDetails(ctx, j.ctc.Resource)
+					n2, e2, p2, photo, ok, err := c.fetchContactDetails(ctx, j.ctc.Resource)
 					if err == nil && ok {
 						if n2 != "" {
 							det.Name = n2
 						}
+						det.PhotoURL = photo
 						det.Emails = append(det.Emails, e2...)
 						det.Phones = append(det.Phones, p2...)
 					}
@@ -0,0 +1,607 @@
+package adapters
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/state"
+)
+
+// DefaultDiscordTokenEnv holds the Discord token when the adapter config
+// doesn't name another variable.
+const DefaultDiscordTokenEnv = "DISCORD_TOKEN"
+
+const (
+	discordAPI      = "https://discord.com/api/v10"
+	discordPageSize = 100
+	// discordMaxRetries bounds the waits on one rate-limited request
+	discordMaxRetries = 5
+)
+
+// Discord channel types (https://discord.com/developers/docs/resources/channel)
+const (
+	discordChannelDM      = 1
+	discordChannelGroupDM = 3
+)
+
+// Discord message types imported; joins, pins, boosts and other system
+// messages carry no conversation
+const (
+	discordMessageDefault = 0
+	discordMessageReply   = 19
+)
+
+// DiscordAdapterOptions configures the Discord adapter.
+type DiscordAdapterOptions struct {
+	// TokenEnv names the environment variable holding the token
+	// (default: DISCORD_TOKEN). The token itself never goes in the config.
+	TokenEnv string
+	// Bot marks the token as a bot token. A bot sees the guild channels it
+	// was added to and only its own DMs.
+	Bot bool
+	// DMs syncs every direct message and group DM the account is in.
+	DMs bool
+	// Channels are the IDs of guild channels (or threads) to sync.
+	Channels []string
+}
+
+// DiscordAdapter syncs Discord DMs and selected guild channels through the
+// Discord API. Each channel is a thread on the discord channel; each
+// message is an event whose ID is the message ID, so replies point at the
+// message they answer through reply_to. Authors become contacts with a
+// "discord" identifier holding their user ID, which stays the same when
+// they rename themselves; the account's own ID goes on the user's contact.
+// Syncs are incremental per channel: only messages newer than the last one
+// seen are fetched. A full sync fetches everything again and records
+// messages edited since as revisions.
+type DiscordAdapter struct {
+	token    string
+	bot      bool
+	dms      bool
+	channels []string
+	baseURL  string
+	client   *http.Client
+}
+
+// NewDiscordAdapter creates a Discord adapter, reading its token from the
+// environment.
+func NewDiscordAdapter(opts DiscordAdapterOptions) (*DiscordAdapter, error) {
+	tokenEnv := strings.TrimSpace(opts.TokenEnv)
+	if tokenEnv == "" {
+		tokenEnv = DefaultDiscordTokenEnv
+	}
+	token := strings.TrimSpace(os.Getenv(tokenEnv))
+	if token == "" {
+		return nil, fmt.Errorf("Discord token not set: export %s=<token>", tokenEnv)
+	}
+	var channels []string
+	seen := map[string]bool{}
+	for _, id := range opts.Channels {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid Discord channel ID %q", id)
+		}
+		seen[id] = true
+		channels = append(channels, id)
+	}
+	if !opts.DMs && len(channels) == 0 {
+		return nil, fmt.Errorf("nothing to sync: enable DMs or list guild channels")
+	}
+	return &DiscordAdapter{
+		token:    token,
+		bot:      opts.Bot,
+		dms:      opts.DMs,
+		channels: channels,
+		baseURL:  discordAPI,
+		client:   &http.Client{Timeout: time.Minute},
+	}, nil
+}
+
+func (a *DiscordAdapter) Name() string {
+	return "discord"
+}
+
+type discordUser struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+	Bot        bool   `json:"bot"`
+}
+
+// displayName is the name Discord shows for a user.
+func (u discordUser) displayName() string {
+	if u.GlobalName != "" {
+		return u.GlobalName
+	}
+	return u.Username
+}
+
+type discordChannel struct {
+	ID         string        `json:"id"`
+	Type       int           `json:"type"`
+	Name       string        `json:"name"`
+	GuildID    string        `json:"guild_id"`
+	Recipients []discordUser `json:"recipients"`
+
+	guildName string
+}
+
+type discordAttachment struct {
+	ID          string `json:"id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	URL         string `json:"url"`
+}
+
+type discordMessage struct {
+	ID               string              `json:"id"`
+	ChannelID        string              `json:"channel_id"`
+	Type             int                 `json:"type"`
+	Author           discordUser         `json:"author"`
+	Content          string              `json:"content"`
+	Timestamp        time.Time           `json:"timestamp"`
+	EditedTimestamp  *time.Time          `json:"edited_timestamp"`
+	Attachments      []discordAttachment `json:"attachments"`
+	Mentions         []discordUser       `json:"mentions"`
+	MessageReference *struct {
+		MessageID string `json:"message_id"`
+	} `json:"message_reference"`
+	ReferencedMessage *struct {
+		Author discordUser `json:"author"`
+	} `json:"referenced_message"`
+}
+
+// discordStats tallies what a sync wrote.
+type discordStats struct {
+	messages, replies, edits, skipped int
+}
+
+// discordCursorKey is the state key of the newest message ID seen in a
+// channel.
+func discordCursorKey(channelID string) string {
+	return "channel:" + channelID + ":last_message_id"
+}
+
+func (a *DiscordAdapter) Sync(ctx context.Context, cortexDB *sql.DB, full bool) (SyncResult, error) {
+	start := time.Now()
+	res := SyncResult{Perf: map[string]string{}}
+
+	var me discordUser
+	if err := a.get(ctx, "/users/@me", nil, &me); err != nil {
+		return res, fmt.Errorf("identify Discord account: %w", err)
+	}
+	channels, err := a.listChannels(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	// Fetch before opening the transaction; the cursors live in the same
+	// database
+	messages := map[string][]discordMessage{}
+	cursors := map[string]string{}
+	for _, ch := range channels {
+		after := "0"
+		if !full {
+			if v, ok, err := state.Get(cortexDB, a.Name(), discordCursorKey(ch.ID)); err != nil {
+				return res, err
+			} else if ok {
+				after = v
+			}
+		}
+		msgs, err := a.fetchMessages(ctx, ch.ID, after)
+		if err != nil {
+			return res, fmt.Errorf("fetch messages of channel %s: %w", ch.ID, err)
+		}
+		messages[ch.ID] = msgs
+		if len(msgs) > 0 {
+			cursors[ch.ID] = msgs[len(msgs)-1].ID
+		}
+	}
+
+	_, _ = cortexDB.Exec("PRAGMA foreign_keys = ON")
+	tx, err := cortexDB.BeginTx(ctx, nil)
+	if err != nil {
+		return res, fmt.Errorf("begin cortex tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	meContactID, meCreated, err := meContact(tx, a.Name())
+	if err != nil {
+		return res, err
+	}
+	res.PersonsCreated += meCreated
+	if err := contacts.EnsureContactIdentifier(tx, meContactID, "discord", me.ID); err != nil {
+		return res, err
+	}
+
+	users := map[string]string{me.ID: meContactID}
+	var stats discordStats
+	for _, ch := range channels {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		if err := a.writeChannel(tx, ch, messages[ch.ID], me, users, &res, &stats); err != nil {
+			return res, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return res, fmt.Errorf("commit cortex tx: %w", err)
+	}
+	for channelID, last := range cursors {
+		if err := state.Set(cortexDB, a.Name(), discordCursorKey(channelID), last); err != nil {
+			return res, err
+		}
+	}
+
+	res.Perf["channels"] = strconv.Itoa(len(channels))
+	res.Perf["messages"] = strconv.Itoa(stats.messages)
+	res.Perf["replies"] = strconv.Itoa(stats.replies)
+	res.Perf["edits"] = strconv.Itoa(stats.edits)
+	res.Perf["skipped"] = strconv.Itoa(stats.skipped)
+	res.Duration = time.Since(start)
+	res.Perf["total"] = res.Duration.String()
+	return res, nil
+}
+
+// listChannels returns the DMs (when enabled) and configured guild
+// channels, with guild names filled in.
+func (a *DiscordAdapter) listChannels(ctx context.Context) ([]discordChannel, error) {
+	var channels []discordChannel
+	seen := map[string]bool{}
+	if a.dms {
+		var dms []discordChannel
+		if err := a.get(ctx, "/users/@me/channels", nil, &dms); err != nil {
+			return nil, fmt.Errorf("list Discord DMs: %w", err)
+		}
+		for _, ch := range dms {
+			if ch.Type == discordChannelDM || ch.Type == discordChannelGroupDM {
+				channels = append(channels, ch)
+				seen[ch.ID] = true
+			}
+		}
+	}
+	guilds := map[string]string{}
+	for _, id := range a.channels {
+		if seen[id] {
+			continue
+		}
+		var ch discordChannel
+		if err := a.get(ctx, "/channels/"+id, nil, &ch); err != nil {
+			return nil, fmt.Errorf("Discord channel %s: %w", id, err)
+		}
+		if ch.GuildID != "" {
+			name, ok := guilds[ch.GuildID]
+			if !ok {
+				var guild struct {
+					Name string `json:"name"`
+				}
+				// Non-fatal - the thread is named after the channel alone
+				_ = a.get(ctx, "/guilds/"+ch.GuildID, nil, &guild)
+				name = guild.Name
+				guilds[ch.GuildID] = name
+			}
+			ch.guildName = name
+		}
+		channels = append(channels, ch)
+		seen[id] = true
+	}
+	return channels, nil
+}
+
+// fetchMessages returns a channel's messages newer than the after
+// snowflake, oldest first.
+func (a *DiscordAdapter) fetchMessages(ctx context.Context, channelID, after string) ([]discordMessage, error) {
+	var all []discordMessage
+	for {
+		var page []discordMessage
+		query := neturl.Values{"limit": {strconv.Itoa(discordPageSize)}, "after": {after}}
+		if err := a.get(ctx, "/channels/"+channelID+"/messages", query, &page); err != nil {
+			return nil, err
+		}
+		sort.Slice(page, func(i, j int) bool { return snowflakeLess(page[i].ID, page[j].ID) })
+		all = append(all, page...)
+		if len(page) < discordPageSize {
+			return all, nil
+		}
+		after = page[len(page)-1].ID
+	}
+}
+
+// snowflakeLess orders Discord IDs, which grow with time.
+func snowflakeLess(a, b string) bool {
+	x, _ := strconv.ParseUint(a, 10, 64)
+	y, _ := strconv.ParseUint(b, 10, 64)
+	return x < y
+}
+
+// get fetches an API path into v, waiting out rate limits.
+func (a *DiscordAdapter) get(ctx context.Context, path string, query neturl.Values, v any) error {
+	url := a.baseURL + path
+	if len(query) > 0 {
+		url += "?" + query.Encode()
+	}
+	auth := a.token
+	if a.bot {
+		auth = "Bot " + a.token
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", auth)
+		req.Header.Set("User-Agent", "mnemonic (https://github.com/Napageneral/mnemonic)")
+		resp, err := a.client.Do(req)
+		if err != nil {
+			var uerr *neturl.Error
+			if errors.As(err, &uerr) {
+				err = uerr.Err
+			}
+			return err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests && attempt < discordMaxRetries:
+			var limit struct {
+				RetryAfter float64 `json:"retry_after"`
+			}
+			_ = json.Unmarshal(body, &limit)
+			wait := time.Duration(limit.RetryAfter * float64(time.Second))
+			if wait <= 0 {
+				wait = time.Second
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		case resp.StatusCode == http.StatusUnauthorized:
+			return fmt.Errorf("HTTP 401: the token was rejected")
+		case resp.StatusCode/100 != 2:
+			var apiErr struct {
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+				return fmt.Errorf("HTTP %d: %s", resp.StatusCode, apiErr.Message)
+			}
+			return fmt.Errorf("HTTP %d", resp.StatusCode)
+		}
+		return json.Unmarshal(body, v)
+	}
+}
+
+// contact returns the contact of a Discord user, creating it on first use.
+func (a *DiscordAdapter) contact(tx *sql.Tx, u discordUser, users map[string]string, res *SyncResult) (string, error) {
+	if id, ok := users[u.ID]; ok {
+		return id, nil
+	}
+	name := u.displayName()
+	contactID, _, err := contacts.GetOrCreateContact(tx, "discord", u.ID, name, a.Name())
+	if err != nil {
+		return "", fmt.Errorf("contact %s: %w", u.ID, err)
+	}
+	if !u.Bot && contacts.IsMeaningfulPersonName(name) {
+		if _, created, err := contacts.EnsurePersonForContact(tx, contactID, name, "deterministic", 0.8); err != nil {
+			return "", err
+		} else if created {
+			res.PersonsCreated++
+		}
+	}
+	users[u.ID] = contactID
+	return contactID, nil
+}
+
+// threadName names a channel: the other people in a DM, or the guild and
+// channel.
+func (ch discordChannel) threadName() string {
+	if ch.Type == discordChannelDM || ch.Type == discordChannelGroupDM {
+		if ch.Name != "" {
+			return ch.Name
+		}
+		names := make([]string, 0, len(ch.Recipients))
+		for _, u := range ch.Recipients {
+			names = append(names, u.displayName())
+		}
+		return strings.Join(names, ", ")
+	}
+	if ch.guildName != "" {
+		return ch.guildName + " #" + ch.Name
+	}
+	return "#" + ch.Name
+}
+
+// writeChannel stores a channel's thread and messages.
+func (a *DiscordAdapter) writeChannel(tx *sql.Tx, ch discordChannel, msgs []discordMessage, me discordUser, users map[string]string, res *SyncResult, stats *discordStats) error {
+	threadSource := "channel:" + ch.ID
+	threadID := a.Name() + ":" + threadSource
+	isGroup := 1
+	if ch.Type == discordChannelDM {
+		isGroup = 0
+	}
+	// A channel with nothing new keeps its last activity time
+	now := time.Now().Unix()
+	first, last := now, now
+	if len(msgs) > 0 {
+		first, last = msgs[0].Timestamp.Unix(), msgs[len(msgs)-1].Timestamp.Unix()
+	}
+	var exists int
+	if err := tx.QueryRow(`SELECT 1 FROM threads WHERE source_adapter = ? AND source_id = ?`, a.Name(), threadSource).Scan(&exists); err == sql.ErrNoRows {
+		res.ThreadsCreated++
+	} else if err != nil {
+		return fmt.Errorf("lookup thread: %w", err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO threads (id, channel, name, is_group, source_adapter, source_id, created_at, updated_at)
+		VALUES (?, 'discord', ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(source_adapter, source_id) DO UPDATE SET
+			name = excluded.name,
+			updated_at = CASE WHEN ? THEN MAX(threads.updated_at, excluded.updated_at) ELSE threads.updated_at END
+	`, threadID, ch.threadName(), isGroup, a.Name(), threadSource, first, last, len(msgs) > 0); err != nil {
+		return fmt.Errorf("upsert thread: %w", err)
+	}
+
+	// Everyone in a DM receives its messages
+	var dmMembers []string
+	if ch.Type == discordChannelDM || ch.Type == discordChannelGroupDM {
+		dmMembers = append(dmMembers, users[me.ID])
+		for _, u := range ch.Recipients {
+			contactID, err := a.contact(tx, u, users, res)
+			if err != nil {
+				return err
+			}
+			dmMembers = append(dmMembers, contactID)
+		}
+	}
+
+	for _, m := range msgs {
+		if m.Type != discordMessageDefault && m.Type != discordMessageReply {
+			stats.skipped++
+			continue
+		}
+		if err := a.writeMessage(tx, m, threadID, dmMembers, me, users, res, stats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeMessage stores a message with its author as sender, and as
+// recipients the other DM members or, in a guild channel, the people it
+// mentions or replies to.
+func (a *DiscordAdapter) writeMessage(tx *sql.Tx, m discordMessage, threadID string, dmMembers []string, me discordUser, users map[string]string, res *SyncResult, stats *discordStats) error {
+	var types []string
+	if m.Content != "" {
+		types = append(types, "text")
+	}
+	for _, att := range m.Attachments {
+		kind := deriveMediaType(att.ContentType, false)
+		if !slices.Contains(types, kind) {
+			types = append(types, kind)
+		}
+	}
+	if len(types) == 0 {
+		// Embeds, stickers and polls only
+		stats.skipped++
+		return nil
+	}
+	contentTypes, _ := json.Marshal(types)
+
+	senderID, err := a.contact(tx, m.Author, users, res)
+	if err != nil {
+		return err
+	}
+	direction := "received"
+	if m.Author.ID == me.ID {
+		direction = "sent"
+	}
+	replyTo := ""
+	if m.MessageReference != nil && m.MessageReference.MessageID != "" {
+		replyTo = a.Name() + ":" + m.MessageReference.MessageID
+	}
+	metadata := map[string]any{
+		"channel_id": m.ChannelID,
+		"author":     m.Author.Username,
+	}
+	if m.EditedTimestamp != nil {
+		metadata["edited_at"] = m.EditedTimestamp.Unix()
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+
+	eventID := a.Name() + ":" + m.ID
+	result, err := tx.Exec(`
+		INSERT OR IGNORE INTO events (
+			id, timestamp, channel, content_types, content,
+			direction, thread_id, reply_to, source_adapter, source_id, metadata_json
+		) VALUES (?, ?, 'discord', ?, ?, ?, ?, ?, ?, ?, ?)
+	`, eventID, m.Timestamp.Unix(), string(contentTypes), m.Content, direction, threadID, replyTo, a.Name(), m.ID, string(metadataJSON))
+	if err != nil {
+		return fmt.Errorf("insert discord event: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		// Seen before; a full sync picks up edits
+		if m.EditedTimestamp == nil {
+			return nil
+		}
+		kind, err := recordContentChange(tx, eventID, m.Content, string(contentTypes), false, m.EditedTimestamp.Unix())
+		if err != nil {
+			return err
+		}
+		if kind != "" {
+			stats.edits++
+			res.EventsUpdated++
+		}
+		return nil
+	}
+	res.EventsCreated++
+	stats.messages++
+	if replyTo != "" {
+		stats.replies++
+	}
+
+	recipients := dmMembers
+	if dmMembers == nil {
+		for _, u := range m.Mentions {
+			contactID, err := a.contact(tx, u, users, res)
+			if err != nil {
+				return err
+			}
+			recipients = append(recipients, contactID)
+		}
+		if m.ReferencedMessage != nil && m.ReferencedMessage.Author.ID != "" {
+			contactID, err := a.contact(tx, m.ReferencedMessage.Author, users, res)
+			if err != nil {
+				return err
+			}
+			recipients = append(recipients, contactID)
+		}
+	}
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO event_participants (event_id, contact_id, role) VALUES (?, ?, 'sender')`, eventID, senderID); err != nil {
+		return fmt.Errorf("insert discord participant: %w", err)
+	}
+	for _, contactID := range recipients {
+		if contactID == senderID {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO event_participants (event_id, contact_id, role) VALUES (?, ?, 'recipient')`, eventID, contactID); err != nil {
+			return fmt.Errorf("insert discord participant: %w", err)
+		}
+	}
+
+	for _, att := range m.Attachments {
+		attMeta, _ := json.Marshal(map[string]any{"discord_attachment_id": att.ID})
+		if _, err := tx.Exec(`
+			INSERT INTO attachments (
+				id, event_id, filename, mime_type, size_bytes, media_type,
+				storage_uri, storage_type, source_id, metadata_json, created_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, 'url', ?, ?, ?)
+			ON CONFLICT(id) DO NOTHING
+		`, eventID+":"+att.ID, eventID, att.Filename, nullIfEmpty(att.ContentType), att.Size,
+			deriveMediaType(att.ContentType, false), att.URL, att.ID, string(attMeta), m.Timestamp.Unix()); err != nil {
+			return fmt.Errorf("insert discord attachment: %w", err)
+		}
+		res.AttachmentsCreated++
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+// fakeDiscord serves a DM with Dana and the #general channel of a guild.
+type fakeDiscord struct {
+	mu       sync.Mutex
+	messages map[string][]map[string]any // channel ID -> messages, oldest first
+	limited  bool                        // a rate limit was served
+}
+
+func (f *fakeDiscord) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bot test-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	dana := map[string]any{"id": "2", "username": "dana", "global_name": "Dana Smith"}
+	var body any
+	switch r.URL.Path {
+	case "/users/@me":
+		body = map[string]any{"id": "1", "username": "me"}
+	case "/users/@me/channels":
+		body = []any{
+			map[string]any{"id": "10", "type": 1, "recipients": []any{dana}},
+			map[string]any{"id": "11", "type": 0, "name": "not-a-dm"},
+		}
+	case "/channels/20":
+		body = map[string]any{"id": "20", "type": 0, "name": "general", "guild_id": "30"}
+	case "/guilds/30":
+		body = map[string]any{"id": "30", "name": "Climbers"}
+	case "/channels/10/messages", "/channels/20/messages":
+		if !f.limited {
+			f.limited = true
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"message": "You are being rate limited.", "retry_after": 0.01}`))
+			return
+		}
+		after, _ := strconv.ParseUint(r.URL.Query().Get("after"), 10, 64)
+		var page []map[string]any
+		for _, m := range f.messages[strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/channels/"), "/messages")] {
+			if id, _ := strconv.ParseUint(m["id"].(string), 10, 64); id > after {
+				page = append(page, m)
+			}
+		}
+		// Newest first, as Discord returns them
+		sort.Slice(page, func(i, j int) bool { return snowflakeLess(page[j]["id"].(string), page[i]["id"].(string)) })
+		body = page
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "Unknown Channel", "code": 10003}`))
+		return
+	}
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func TestDiscordAdapterSync(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	me := map[string]any{"id": "1", "username": "me"}
+	dana := map[string]any{"id": "2", "username": "dana", "global_name": "Dana Smith"}
+	sam := map[string]any{"id": "3", "username": "sam_climbs"}
+	fake := &fakeDiscord{messages: map[string][]map[string]any{
+		"10": {
+			{"id": "100", "channel_id": "10", "type": 0, "author": dana, "content": "Climbing Saturday?", "timestamp": "2024-05-01T10:00:00+00:00"},
+			{"id": "101", "channel_id": "10", "type": 19, "author": me, "content": "Yes!", "timestamp": "2024-05-01T10:05:00+00:00",
+				"message_reference": map[string]any{"message_id": "100"}, "referenced_message": map[string]any{"author": dana}},
+		},
+		"20": {
+			{"id": "200", "channel_id": "20", "type": 7, "author": sam, "content": "", "timestamp": "2024-05-01T09:00:00+00:00"},
+			{"id": "201", "channel_id": "20", "type": 0, "author": sam, "content": "New route is up", "timestamp": "2024-05-01T11:00:00+00:00",
+				"mentions": []any{dana}, "attachments": []any{map[string]any{"id": "900", "filename": "route.jpg", "content_type": "image/jpeg", "size": 1024, "url": "https://cdn.example/route.jpg"}}},
+		},
+	}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	t.Setenv("DISCORD_TEST_TOKEN", "test-token")
+	if _, err := NewDiscordAdapter(DiscordAdapterOptions{TokenEnv: "DISCORD_TEST_TOKEN"}); err == nil {
+		t.Error("adapter with nothing to sync accepted")
+	}
+	a, err := NewDiscordAdapter(DiscordAdapterOptions{TokenEnv: "DISCORD_TEST_TOKEN", Bot: true, DMs: true, Channels: []string{"20", "20"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.baseURL = srv.URL
+
+	res, err := a.Sync(ctx, db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.EventsCreated != 3 || res.ThreadsCreated != 2 || res.AttachmentsCreated != 1 || res.Perf["skipped"] != "1" || res.Perf["replies"] != "1" {
+		t.Errorf("first sync = %+v", res)
+	}
+
+	var replyTo, direction, threadName string
+	var recipients int
+	if err := db.QueryRow(`
+		SELECT e.reply_to, e.direction, t.name,
+		       (SELECT COUNT(*) FROM event_participants ep WHERE ep.event_id = e.id AND ep.role = 'recipient')
+		FROM events e JOIN threads t ON t.id = e.thread_id
+		WHERE e.id = 'discord:101'
+	`).Scan(&replyTo, &direction, &threadName, &recipients); err != nil {
+		t.Fatal(err)
+	}
+	if replyTo != "discord:100" || direction != "sent" || threadName != "Dana Smith" || recipients != 1 {
+		t.Errorf("reply = %q %q %q %d", replyTo, direction, threadName, recipients)
+	}
+
+	// Dana is one contact across the DM and the guild, keyed by user ID
+	var danaContact, mentioned, contentTypes string
+	if err := db.QueryRow(`SELECT contact_id FROM contact_identifiers WHERE type = 'discord' AND normalized = '2'`).Scan(&danaContact); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(`
+		SELECT ep.contact_id, e.content_types, t.name
+		FROM events e JOIN threads t ON t.id = e.thread_id
+		JOIN event_participants ep ON ep.event_id = e.id AND ep.role = 'recipient'
+		WHERE e.id = 'discord:201'
+	`).Scan(&mentioned, &contentTypes, &threadName); err != nil {
+		t.Fatal(err)
+	}
+	if mentioned != danaContact || contentTypes != `["text","image"]` || threadName != "Climbers #general" {
+		t.Errorf("guild message = %q %s %q (dana %q)", mentioned, contentTypes, threadName, danaContact)
+	}
+	var meOwnsID int
+	if err := db.QueryRow(`
+		SELECT COUNT(*) FROM contact_identifiers ci
+		JOIN person_contact_links pcl ON pcl.contact_id = ci.contact_id
+		JOIN persons p ON p.id = pcl.person_id
+		WHERE ci.type = 'discord' AND ci.normalized = '1' AND p.is_me = 1
+	`).Scan(&meOwnsID); err != nil || meOwnsID != 1 {
+		t.Errorf("own Discord ID not on the me contact (%v)", err)
+	}
+
+	// Incremental syncs fetch only newer messages
+	fake.mu.Lock()
+	fake.messages["10"] = append(fake.messages["10"], map[string]any{"id": "102", "channel_id": "10", "type": 0, "author": dana, "content": "See you there", "timestamp": "2024-05-01T10:10:00+00:00"})
+	fake.messages["10"][0]["content"] = "Climbing Sunday?"
+	fake.messages["10"][0]["edited_timestamp"] = "2024-05-01T10:20:00+00:00"
+	fake.mu.Unlock()
+	res, err = a.Sync(ctx, db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.EventsCreated != 1 || res.EventsUpdated != 0 || res.ThreadsCreated != 0 {
+		t.Errorf("incremental sync = %+v", res)
+	}
+
+	// A full sync records the edit as a revision
+	res, err = a.Sync(ctx, db, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.EventsCreated != 0 || res.EventsUpdated != 1 || res.Perf["edits"] != "1" {
+		t.Errorf("full sync = %+v", res)
+	}
+	var revised string
+	if err := db.QueryRow(`SELECT content FROM events WHERE supersedes = 'discord:100'`).Scan(&revised); err != nil || revised != "Climbing Sunday?" {
+		t.Errorf("revision = %q (%v)", revised, err)
+	}
+}
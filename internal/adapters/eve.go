@@ -15,7 +15,13 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Napageneral/mnemonic/internal/avatars"
+	"github.com/Napageneral/mnemonic/internal/config"
 	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/errs"
+	"github.com/Napageneral/mnemonic/internal/me"
+	"github.com/Napageneral/mnemonic/internal/namespace"
+	"github.com/Napageneral/mnemonic/internal/threads"
 	"github.com/google/uuid"
 	_ "modernc.org/sqlite"
 )
@@ -27,14 +33,12 @@ type EveAdapter struct {
 
 // NewEveAdapter creates a new Eve adapter.
 func NewEveAdapter() (*EveAdapter, error) {
-	home, err := os.UserHomeDir()
+	eveDBPath, err := config.EveDBPath()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, err
 	}
-
-	eveDBPath := filepath.Join(home, "Library", "Application Support", "Eve", "eve.db")
 	if _, err := os.Stat(eveDBPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("Eve database not found at %s", eveDBPath)
+		return nil, errs.New(errs.ErrAdapterSourceMissing, "Eve database not found at %s", eveDBPath)
 	}
 
 	return &EveAdapter{
@@ -128,6 +132,16 @@ func (e *EveAdapter) Sync(ctx context.Context, cortexDB *sql.DB, full bool) (Syn
 	}
 	result.Perf["contacts.total"] = time.Since(contactsStart).String()
 
+	// Contact photos from macOS Contacts, so UIs can show faces
+	if dir, err := avatars.DefaultAddressBookDir(); err == nil {
+		n, err := avatars.ImportAddressBook(ctx, cortexDB, dir)
+		// Non-fatal - photos are cosmetic, and Contacts may not be readable
+		if err != nil {
+			result.Perf["avatars.error"] = err.Error()
+		}
+		result.Perf["avatars.stored"] = fmt.Sprintf("%d", n)
+	}
+
 	// Sync chats/threads (to establish thread metadata)
 	chatsStart := time.Now()
 	threadsCreated, threadsUpdated, perfChats, err := e.syncChats(ctx, eveDB, cortexDB)
@@ -193,6 +207,16 @@ func (e *EveAdapter) Sync(ctx context.Context, cortexDB *sql.DB, full bool) (Syn
 	}
 	result.Perf["membership.total"] = time.Since(membershipStart).String()
 
+	// Rebuild rosters of group threads touched since the last sync
+	rosterStart := time.Now()
+	if n, err := threads.RebuildMembership(cortexDB, lastSyncTimestamp); err != nil {
+		// Non-fatal - rosters only refine participant priming
+		result.Perf["roster.error"] = err.Error()
+	} else {
+		result.Perf["roster.threads"] = fmt.Sprintf("%d", n)
+	}
+	result.Perf["roster.total"] = time.Since(rosterStart).String()
+
 	// Update sync watermark
 	// IMPORTANT: use the max imported event timestamp, NOT wall-clock time.
 	// This avoids skipping late-arriving/backfilled messages whose timestamp is older than "now".
@@ -262,6 +286,31 @@ func (e *EveAdapter) syncContacts(ctx context.Context, eveDB, cortexDB *sql.DB)
 	}
 	defer tx.Rollback()
 
+	// Identifiers of one Eve contact are one address book card. An identifier
+	// another adapter saw first stays on that adapter's contact, so each card
+	// collects every contact its identifiers live on, to link them to one person.
+	type card struct {
+		name       string
+		contactIDs []string
+	}
+	cards := make(map[int64]*card)
+	var cardOrder []int64
+	addToCard := func(eveContactID int64, name, contactID string) {
+		c := cards[eveContactID]
+		if c == nil {
+			c = &card{}
+			cards[eveContactID] = c
+			cardOrder = append(cardOrder, eveContactID)
+		}
+		c.name = name
+		for _, id := range c.contactIDs {
+			if id == contactID {
+				return
+			}
+		}
+		c.contactIDs = append(c.contactIDs, contactID)
+	}
+
 	for rows.Next() {
 		var eveContactID int64
 		var name, nickname sql.NullString
@@ -300,17 +349,17 @@ func (e *EveAdapter) syncContacts(ctx context.Context, eveDB, cortexDB *sql.DB)
 			contactMap[eveContactID] = contactID
 		}
 
+		addToCard(eveContactID, displayName, contactID)
 		if identifier.Valid && identifierType.Valid {
 			if err := contacts.EnsureContactIdentifier(tx, contactID, identifierType.String, identifier.String); err != nil {
 				return personsCreated, contactMap, meContactID, perf, fmt.Errorf("attach contact identifier: %w", err)
 			}
-		}
-
-		if contacts.IsMeaningfulPersonName(displayName) {
-			if _, created, err := contacts.EnsurePersonForContact(tx, contactID, displayName, "deterministic", 1.0); err != nil {
-				return personsCreated, contactMap, meContactID, perf, fmt.Errorf("link contact person: %w", err)
-			} else if created {
-				personsCreated++
+			owner, err := contacts.LookupContact(tx, identifierType.String, identifier.String)
+			if err != nil {
+				return personsCreated, contactMap, meContactID, perf, fmt.Errorf("lookup contact identifier: %w", err)
+			}
+			if owner != "" {
+				addToCard(eveContactID, displayName, owner)
 			}
 		}
 	}
@@ -318,6 +367,15 @@ func (e *EveAdapter) syncContacts(ctx context.Context, eveDB, cortexDB *sql.DB)
 	if err := rows.Err(); err != nil {
 		return personsCreated, contactMap, meContactID, perf, err
 	}
+
+	for _, eveContactID := range cardOrder {
+		c := cards[eveContactID]
+		if _, created, err := contacts.LinkCard(tx, c.contactIDs, c.name); err != nil {
+			return personsCreated, contactMap, meContactID, perf, fmt.Errorf("link contact person: %w", err)
+		} else if created {
+			personsCreated++
+		}
+	}
 	if err := tx.Commit(); err != nil {
 		return personsCreated, contactMap, meContactID, perf, fmt.Errorf("commit cortex tx: %w", err)
 	}
@@ -370,15 +428,14 @@ func (e *EveAdapter) syncWhoami(ctx context.Context, eveDB, cortexDB *sql.DB) (p
 		if bestName == "" {
 			bestName = "Me"
 		}
-		var mePersonID string
-		_ = cortexDB.QueryRow("SELECT id FROM persons WHERE is_me = 1 LIMIT 1").Scan(&mePersonID)
+		mePersonID, _ := me.LookupMePersonID(cortexDB)
 		now := time.Now().Unix()
 		if mePersonID == "" {
 			mePersonID = uuid.New().String()
 			if _, err := cortexDB.Exec(`
-				INSERT INTO persons (id, canonical_name, is_me, created_at, updated_at)
-				VALUES (?, ?, 1, ?, ?)
-			`, mePersonID, bestName, now, now); err != nil {
+				INSERT INTO persons (id, canonical_name, is_me, namespace, created_at, updated_at)
+				VALUES (?, ?, 1, ?, ?, ?)
+			`, mePersonID, bestName, namespace.Value(), now, now); err != nil {
 				return 0, "", fmt.Errorf("failed to create me person: %w", err)
 			}
 			personsCreated++
@@ -687,6 +744,8 @@ func (e *EveAdapter) syncMessages(ctx context.Context, eveDB, cortexDB *sql.DB,
 	}
 	defer stmtInsertParticipant.Close()
 
+	syncStart := time.Now().Unix()
+	edited, unsends := 0, 0
 	for rows.Next() {
 		var messageID int64
 		var guid, threadID string
@@ -736,10 +795,29 @@ func (e *EveAdapter) syncMessages(ctx context.Context, eveDB, cortexDB *sql.DB,
 		if n, _ := res.RowsAffected(); n == 1 {
 			created++
 		} else {
+			// Edits and unsends of text we already have become revisions and
+			// tombstones; the original content is kept.
+			storedContent := content.String
+			var stored sql.NullString
+			_ = tx.QueryRow(`SELECT content FROM events WHERE id = ?`, eventID).Scan(&stored)
+			if stored.String != "" {
+				unsent := !hasText && !hasAttachment
+				kind, err := recordContentChange(tx, eventID, content.String, contentTypesJSON, unsent, syncStart)
+				if err != nil {
+					return created, updated, maxImportedTimestamp, perf, err
+				}
+				switch kind {
+				case revisionEdited:
+					edited++
+				case revisionUnsent:
+					unsends++
+				}
+				storedContent = stored.String
+			}
 			res2, err := stmtUpdateEvent.Exec(
-				content.String, contentTypesJSON, threadID, replyToGuid.String,
+				storedContent, contentTypesJSON, threadID, replyToGuid.String,
 				e.Name(), guid,
-				content.String, contentTypesJSON, threadID, replyToGuid.String,
+				storedContent, contentTypesJSON, threadID, replyToGuid.String,
 			)
 			if err != nil {
 				return created, updated, maxImportedTimestamp, perf, fmt.Errorf("update event: %w", err)
@@ -771,6 +849,8 @@ func (e *EveAdapter) syncMessages(ctx context.Context, eveDB, cortexDB *sql.DB,
 		return created, updated, maxImportedTimestamp, perf, fmt.Errorf("commit cortex tx: %w", err)
 	}
 	perf["tx_commit"] = time.Since(txStart).String()
+	perf["edited"] = fmt.Sprintf("%d", edited)
+	perf["unsent"] = fmt.Sprintf("%d", unsends)
 	return created, updated, maxImportedTimestamp, perf, nil
 }
 
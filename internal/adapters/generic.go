@@ -0,0 +1,583 @@
+package adapters
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/errs"
+)
+
+// Generic file formats.
+const (
+	GenericFormatJSONL = "jsonl"
+	GenericFormatCSV   = "csv"
+)
+
+// GenericMapping names the field holding each event field: a key in JSONL
+// records (dotted for nested objects, e.g. "author.name") or a CSV column
+// header. Empty entries default to the event field's own name (timestamp,
+// sender, content, ...).
+type GenericMapping struct {
+	ID         string `json:"id,omitempty"`
+	Timestamp  string `json:"timestamp,omitempty"`
+	Sender     string `json:"sender,omitempty"`
+	SenderName string `json:"sender_name,omitempty"`
+	Recipients string `json:"recipients,omitempty"` // a list, or a string separated by ";" or ","
+	Content    string `json:"content,omitempty"`
+	Thread     string `json:"thread,omitempty"`
+	ThreadName string `json:"thread_name,omitempty"`
+	Direction  string `json:"direction,omitempty"` // sent, received or observed
+	ReplyTo    string `json:"reply_to,omitempty"`
+}
+
+// genericFields are the mappable event fields, by their default names.
+var genericFields = []string{"id", "timestamp", "sender", "sender_name", "recipients", "content", "thread", "thread_name", "direction", "reply_to"}
+
+// ParseGenericMapping builds a mapping from event field names to source
+// fields, rejecting names that are not event fields.
+func ParseGenericMapping(m map[string]string) (GenericMapping, error) {
+	var mapping GenericMapping
+	for field, source := range m {
+		source = strings.TrimSpace(source)
+		switch strings.TrimSpace(field) {
+		case "id":
+			mapping.ID = source
+		case "timestamp":
+			mapping.Timestamp = source
+		case "sender":
+			mapping.Sender = source
+		case "sender_name":
+			mapping.SenderName = source
+		case "recipients":
+			mapping.Recipients = source
+		case "content":
+			mapping.Content = source
+		case "thread":
+			mapping.Thread = source
+		case "thread_name":
+			mapping.ThreadName = source
+		case "direction":
+			mapping.Direction = source
+		case "reply_to":
+			mapping.ReplyTo = source
+		default:
+			return mapping, fmt.Errorf("unknown mapping field %q (%s)", field, strings.Join(genericFields, ", "))
+		}
+	}
+	return mapping, nil
+}
+
+// source returns the source field for an event field.
+func (m GenericMapping) source(field string) string {
+	var s string
+	switch field {
+	case "id":
+		s = m.ID
+	case "timestamp":
+		s = m.Timestamp
+	case "sender":
+		s = m.Sender
+	case "sender_name":
+		s = m.SenderName
+	case "recipients":
+		s = m.Recipients
+	case "content":
+		s = m.Content
+	case "thread":
+		s = m.Thread
+	case "thread_name":
+		s = m.ThreadName
+	case "direction":
+		s = m.Direction
+	case "reply_to":
+		s = m.ReplyTo
+	}
+	if s == "" {
+		return field
+	}
+	return s
+}
+
+// GenericAdapterOptions configures a generic file adapter.
+type GenericAdapterOptions struct {
+	// Path is a .jsonl/.csv file or a directory of them; "-" reads Input
+	Path  string
+	Input io.Reader
+	// Format is jsonl or csv (default: from the file extension; required
+	// when reading Input)
+	Format string
+	// Channel of the events (default: the adapter name)
+	Channel string
+	Mapping GenericMapping
+	// TimestampFormat is unix, unix_ms, or a Go time layout (default: unix
+	// seconds or milliseconds, RFC 3339, or "2006-01-02 15:04:05")
+	TimestampFormat string
+	// Timezone for timestamps without one (default: local time)
+	Timezone string
+	// Me lists the sender identifiers that are yours; events from them are
+	// sent unless a direction is mapped
+	Me []string
+}
+
+// GenericOptionsFromConfig reads generic adapter options from an adapter's
+// config options (path, format, channel, mapping, timestamp_format,
+// timezone, me).
+func GenericOptionsFromConfig(options map[string]interface{}) (GenericAdapterOptions, error) {
+	var opts GenericAdapterOptions
+	opts.Path, _ = options["path"].(string)
+	opts.Format, _ = options["format"].(string)
+	opts.Channel, _ = options["channel"].(string)
+	opts.TimestampFormat, _ = options["timestamp_format"].(string)
+	opts.Timezone, _ = options["timezone"].(string)
+	opts.Me = genericList(options["me"])
+	if raw, ok := options["mapping"].(map[string]interface{}); ok {
+		m := make(map[string]string, len(raw))
+		for k, v := range raw {
+			m[k] = fmt.Sprint(v)
+		}
+		mapping, err := ParseGenericMapping(m)
+		if err != nil {
+			return opts, err
+		}
+		opts.Mapping = mapping
+	}
+	return opts, nil
+}
+
+// GenericAdapter ingests communication data from JSONL or CSV files, so
+// channels cortex doesn't support can be piped in without code. Each row
+// becomes an event; a field mapping says where its timestamp, sender,
+// content and thread are. Rows without an ID get one derived from their
+// fields, so importing the same file again, or a longer version of it,
+// only adds what is new. Unchanged files are skipped on incremental syncs.
+type GenericAdapter struct {
+	name string
+	opts GenericAdapterOptions
+	loc  *time.Location
+	me   map[string]bool
+}
+
+// NewGenericAdapter creates a generic file adapter named name.
+func NewGenericAdapter(name string, opts GenericAdapterOptions) (*GenericAdapter, error) {
+	opts.Path = strings.TrimSpace(opts.Path)
+	if opts.Path == "" {
+		return nil, fmt.Errorf("generic adapter %s: path is required", name)
+	}
+	opts.Format = strings.ToLower(strings.TrimSpace(opts.Format))
+	if opts.Path == "-" {
+		if opts.Input == nil {
+			return nil, fmt.Errorf("generic adapter %s: no input to read", name)
+		}
+		if opts.Format == "" {
+			return nil, fmt.Errorf("generic adapter %s: format is required when reading stdin", name)
+		}
+	} else if _, err := os.Stat(opts.Path); err != nil {
+		return nil, errs.New(errs.ErrAdapterSourceMissing, "generic adapter %s: %s not found: %w", name, opts.Path, err)
+	}
+	switch opts.Format {
+	case "", GenericFormatJSONL, GenericFormatCSV:
+	default:
+		return nil, fmt.Errorf("generic adapter %s: unknown format %q (jsonl or csv)", name, opts.Format)
+	}
+	loc := time.Local
+	if opts.Timezone != "" {
+		l, err := time.LoadLocation(opts.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", opts.Timezone, err)
+		}
+		loc = l
+	}
+	meIDs := map[string]bool{}
+	for _, id := range opts.Me {
+		if id = strings.ToLower(strings.TrimSpace(id)); id != "" {
+			meIDs[id] = true
+		}
+	}
+	return &GenericAdapter{name: name, opts: opts, loc: loc, me: meIDs}, nil
+}
+
+func (a *GenericAdapter) Name() string {
+	return a.name
+}
+
+func (a *GenericAdapter) Sync(ctx context.Context, cortexDB *sql.DB, full bool) (SyncResult, error) {
+	start := time.Now()
+	result := SyncResult{Perf: map[string]string{}}
+
+	var files []string
+	if a.opts.Path != "-" {
+		var err error
+		if files, err = genericFiles(a.opts.Path); err != nil {
+			return result, err
+		}
+	}
+
+	tx, err := cortexDB.BeginTx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("begin cortex tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	threads := map[string]bool{}
+	rows, skippedRows := 0, 0
+	write := func(r io.Reader, format, label string) error {
+		n, skipped, err := a.readRows(r, format, func(row genericRow) error {
+			ev, err := a.event(row)
+			if err != nil {
+				return err
+			}
+			return writeExternalEvent(tx, a.name, ev, threads, &result)
+		})
+		rows += n
+		skippedRows += skipped
+		if err != nil {
+			return fmt.Errorf("%s: %w", label, err)
+		}
+		return nil
+	}
+
+	if a.opts.Path == "-" {
+		if err := write(a.opts.Input, a.opts.Format, "stdin"); err != nil {
+			return result, err
+		}
+	}
+	unchanged := 0
+	for _, file := range files {
+		stamp, err := fileStamp(file)
+		if err != nil {
+			return result, err
+		}
+		stateKey := "file:" + file
+		if !full {
+			var seen string
+			_ = tx.QueryRow(`SELECT value FROM adapter_state WHERE adapter = ? AND key = ?`, a.name, stateKey).Scan(&seen)
+			if seen == stamp {
+				unchanged++
+				continue
+			}
+		}
+		format := a.opts.Format
+		if format == "" {
+			format = genericFormatOf(file)
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return result, fmt.Errorf("open %s: %w", file, err)
+		}
+		err = write(f, format, file)
+		f.Close()
+		if err != nil {
+			return result, err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO adapter_state (adapter, key, value, updated_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT(adapter, key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+		`, a.name, stateKey, stamp, time.Now().Unix()); err != nil {
+			return result, fmt.Errorf("save file state: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("commit cortex tx: %w", err)
+	}
+	result.Perf["files"] = strconv.Itoa(len(files))
+	result.Perf["files.unchanged"] = strconv.Itoa(unchanged)
+	result.Perf["rows"] = strconv.Itoa(rows)
+	result.Perf["rows.skipped"] = strconv.Itoa(skippedRows)
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// genericFiles lists the .jsonl, .ndjson and .csv files under path.
+func genericFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		abs, _ := filepath.Abs(path)
+		return []string{abs}, nil
+	}
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if genericFormatOf(p) != "" {
+			abs, _ := filepath.Abs(p)
+			files = append(files, abs)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list files: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func genericFormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return GenericFormatJSONL
+	case ".csv":
+		return GenericFormatCSV
+	}
+	return ""
+}
+
+// genericRow is one record: field lookup plus its position for errors and
+// derived IDs.
+type genericRow struct {
+	line  int
+	get   func(field string) string
+	list  func(field string) []string
+	count int // earlier rows in the file with the same derived key
+}
+
+// readRows parses records and passes each one to fn. It returns the
+// number of rows read and of rows skipped for having no content.
+func (a *GenericAdapter) readRows(r io.Reader, format string, fn func(genericRow) error) (int, int, error) {
+	seen := map[string]int{}
+	n, skipped := 0, 0
+	emit := func(row genericRow) error {
+		n++
+		if strings.TrimSpace(row.get(a.opts.Mapping.source("content"))) == "" {
+			skipped++
+			return nil
+		}
+		if row.get(a.opts.Mapping.source("id")) == "" {
+			key := a.derivedKey(row)
+			row.count = seen[key]
+			seen[key]++
+		}
+		if err := fn(row); err != nil {
+			return fmt.Errorf("line %d: %w", row.line, err)
+		}
+		return nil
+	}
+
+	switch format {
+	case GenericFormatJSONL:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		line := 0
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+			var record map[string]interface{}
+			if err := json.Unmarshal([]byte(text), &record); err != nil {
+				return n, skipped, fmt.Errorf("line %d: invalid JSON: %w", line, err)
+			}
+			if err := emit(genericRow{
+				line: line,
+				get:  func(field string) string { return genericString(genericLookup(record, field)) },
+				list: func(field string) []string { return genericList(genericLookup(record, field)) },
+			}); err != nil {
+				return n, skipped, err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return n, skipped, err
+		}
+	case GenericFormatCSV:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		header, err := cr.Read()
+		if err == io.EOF {
+			return 0, 0, nil
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("read header: %w", err)
+		}
+		columns := make(map[string]int, len(header))
+		for i, h := range header {
+			columns[strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))] = i
+		}
+		for _, field := range []string{"timestamp", "content"} {
+			if _, ok := columns[a.opts.Mapping.source(field)]; !ok {
+				return 0, 0, fmt.Errorf("no %q column for %s (columns: %s)", a.opts.Mapping.source(field), field, strings.Join(header, ", "))
+			}
+		}
+		for {
+			record, err := cr.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return n, skipped, err
+			}
+			line, _ := cr.FieldPos(0)
+			get := func(field string) string {
+				if i, ok := columns[field]; ok && i < len(record) {
+					return strings.TrimSpace(record[i])
+				}
+				return ""
+			}
+			if err := emit(genericRow{
+				line: line,
+				get:  get,
+				list: func(field string) []string { return genericList(get(field)) },
+			}); err != nil {
+				return n, skipped, err
+			}
+		}
+	default:
+		return 0, 0, fmt.Errorf("unknown format %q (jsonl or csv)", format)
+	}
+	return n, skipped, nil
+}
+
+// derivedKey identifies a row without an ID by what it says, who said it
+// and when.
+func (a *GenericAdapter) derivedKey(row genericRow) string {
+	m := a.opts.Mapping
+	return strings.Join([]string{
+		row.get(m.source("timestamp")), row.get(m.source("sender")),
+		row.get(m.source("thread")), row.get(m.source("content")),
+	}, "\x00")
+}
+
+// event maps a row to an event.
+func (a *GenericAdapter) event(row genericRow) (PluginEvent, error) {
+	m := a.opts.Mapping
+	ev := PluginEvent{
+		ID:         row.get(m.source("id")),
+		Channel:    a.opts.Channel,
+		Content:    row.get(m.source("content")),
+		ThreadID:   row.get(m.source("thread")),
+		ThreadName: row.get(m.source("thread_name")),
+		ReplyTo:    row.get(m.source("reply_to")),
+		Direction:  strings.ToLower(row.get(m.source("direction"))),
+	}
+	raw := row.get(m.source("timestamp"))
+	if raw == "" {
+		return ev, fmt.Errorf("no timestamp in %q", m.source("timestamp"))
+	}
+	ts, err := parseGenericTimestamp(raw, a.opts.TimestampFormat, a.loc)
+	if err != nil {
+		return ev, err
+	}
+	ev.Timestamp = ts
+	if ev.ID == "" {
+		sum := sha256.Sum256([]byte(a.derivedKey(row)))
+		ev.ID = hex.EncodeToString(sum[:12])
+		if row.count > 0 {
+			ev.ID += "-" + strconv.Itoa(row.count)
+		}
+	}
+
+	if sender := row.get(m.source("sender")); sender != "" {
+		ev.Sender = &PluginContact{Identifier: sender, Name: row.get(m.source("sender_name"))}
+		if ev.Direction == "" && a.me[strings.ToLower(sender)] {
+			ev.Direction = "sent"
+		}
+	}
+	for _, r := range row.list(m.source("recipients")) {
+		ev.Recipients = append(ev.Recipients, PluginContact{Identifier: r})
+	}
+	ev.IsGroup = len(ev.Recipients) > 1
+	return ev, nil
+}
+
+// parseGenericTimestamp reads unix seconds or milliseconds, RFC 3339, a
+// plain date-time, or the given layout.
+func parseGenericTimestamp(raw, format string, loc *time.Location) (int64, error) {
+	switch format {
+	case "unix", "unix_ms", "":
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			if format == "unix_ms" || (format == "" && n > 1e11) {
+				return int64(n / 1000), nil
+			}
+			return int64(n), nil
+		}
+		if format != "" {
+			return 0, fmt.Errorf("invalid %s timestamp %q", format, raw)
+		}
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+			if t, err := time.ParseInLocation(layout, raw, loc); err == nil {
+				return t.Unix(), nil
+			}
+		}
+		return 0, fmt.Errorf("unrecognized timestamp %q (set a timestamp format)", raw)
+	default:
+		t, err := time.ParseInLocation(format, raw, loc)
+		if err != nil {
+			return 0, fmt.Errorf("timestamp %q doesn't match %q", raw, format)
+		}
+		return t.Unix(), nil
+	}
+}
+
+// genericLookup follows a dotted path through nested JSON objects.
+func genericLookup(record map[string]interface{}, field string) interface{} {
+	if v, ok := record[field]; ok {
+		return v
+	}
+	var cur interface{} = record
+	for _, part := range strings.Split(field, ".") {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = obj[part]
+	}
+	return cur
+}
+
+func genericString(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(x)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	default:
+		b, _ := json.Marshal(x)
+		return string(b)
+	}
+}
+
+// genericList reads a JSON array, or a string separated by ";" or ",".
+func genericList(v interface{}) []string {
+	var items []string
+	switch x := v.(type) {
+	case []interface{}:
+		for _, item := range x {
+			items = append(items, genericString(item))
+		}
+	default:
+		s := genericString(x)
+		sep := ";"
+		if !strings.Contains(s, sep) {
+			sep = ","
+		}
+		items = strings.Split(s, sep)
+	}
+	var out []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
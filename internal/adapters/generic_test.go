@@ -0,0 +1,162 @@
+package adapters
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestGenericAdapterJSONL(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "matrix.jsonl")
+	data := `{"ts": 1700000000, "user": {"id": "@casey:matrix.org", "name": "Casey Lee"}, "text": "lunch?", "room": "!abc", "room_name": "Friends", "to": ["@tyler:matrix.org", "@dana:matrix.org"]}
+{"ts": 1700000060000, "user": {"id": "@tyler:matrix.org"}, "text": "sure", "room": "!abc"}
+{"ts": 1700000120, "user": {"id": "@casey:matrix.org"}, "text": "", "room": "!abc"}
+
+{"ts": 1700000060000, "user": {"id": "@tyler:matrix.org"}, "text": "sure", "room": "!abc"}
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mapping, err := ParseGenericMapping(map[string]string{
+		"timestamp": "ts", "sender": "user.id", "sender_name": "user.name",
+		"content": "text", "thread": "room", "thread_name": "room_name", "recipients": "to",
+	})
+	if err != nil {
+		t.Fatalf("ParseGenericMapping: %v", err)
+	}
+	if _, err := ParseGenericMapping(map[string]string{"body": "text"}); err == nil {
+		t.Error("unknown mapping field accepted")
+	}
+
+	adapter, err := NewGenericAdapter("matrix", GenericAdapterOptions{Path: dir, Mapping: mapping, Me: []string{"@Tyler:matrix.org"}})
+	if err != nil {
+		t.Fatalf("NewGenericAdapter: %v", err)
+	}
+	res, err := adapter.Sync(ctx, db, false)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	// The repeated "sure" is a second message with the same fields
+	if res.EventsCreated != 3 || res.ThreadsCreated != 1 || res.Perf["rows.skipped"] != "1" {
+		t.Fatalf("result = %+v", res)
+	}
+
+	var direction, content string
+	var ts int64
+	if err := db.QueryRow(`
+		SELECT direction, content, timestamp FROM events WHERE source_adapter = 'matrix' AND timestamp = 1700000060 ORDER BY id LIMIT 1
+	`).Scan(&direction, &content, &ts); err != nil {
+		t.Fatalf("query sent event: %v", err)
+	}
+	if direction != "sent" || content != "sure" {
+		t.Errorf("millisecond event = %s %q", direction, content)
+	}
+	var name string
+	var isGroup int
+	db.QueryRow(`SELECT name, is_group FROM threads WHERE id = 'matrix:!abc'`).Scan(&name, &isGroup)
+	if name != "Friends" || isGroup != 1 {
+		t.Errorf("thread = %q %d", name, isGroup)
+	}
+	var sender string
+	db.QueryRow(`
+		SELECT c.display_name FROM events e
+		JOIN event_participants ep ON ep.event_id = e.id AND ep.role = 'sender'
+		JOIN contacts c ON c.id = ep.contact_id
+		WHERE e.source_adapter = 'matrix' AND e.content = 'lunch?'
+	`).Scan(&sender)
+	if sender != "Casey Lee" {
+		t.Errorf("sender = %q", sender)
+	}
+
+	// Unchanged files are skipped; a full sync re-reads without duplicating
+	res, err = adapter.Sync(ctx, db, false)
+	if err != nil || res.Perf["files.unchanged"] != "1" || res.EventsCreated != 0 {
+		t.Fatalf("incremental Sync = %+v, %v", res, err)
+	}
+	res, err = adapter.Sync(ctx, db, true)
+	if err != nil || res.EventsCreated != 0 {
+		t.Fatalf("full Sync = %+v, %v", res, err)
+	}
+}
+
+func TestGenericAdapterCSV(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	csvData := "\ufeffmsg_id,sent_at,from,body,chat\n" +
+		"1,2024-03-12 09:15:00,+15550100,\"Hi, it's Sam\",c1\n" +
+		"2,2024-03-12 09:16:00,me@example.com,hey,c1\n"
+	adapter, err := NewGenericAdapter("signal", GenericAdapterOptions{
+		Path:     "-",
+		Input:    strings.NewReader(csvData),
+		Format:   GenericFormatCSV,
+		Mapping:  GenericMapping{ID: "msg_id", Timestamp: "sent_at", Sender: "from", Content: "body", Thread: "chat"},
+		Timezone: "UTC",
+		Me:       []string{"me@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("NewGenericAdapter: %v", err)
+	}
+	res, err := adapter.Sync(ctx, db, false)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if res.EventsCreated != 2 {
+		t.Fatalf("result = %+v", res)
+	}
+	var content, direction string
+	var ts int64
+	db.QueryRow(`SELECT content, direction, timestamp FROM events WHERE id = 'signal:1'`).Scan(&content, &direction, &ts)
+	want := time.Date(2024, 3, 12, 9, 15, 0, 0, time.UTC).Unix()
+	if content != "Hi, it's Sam" || direction != "received" || ts != want {
+		t.Errorf("event 1 = %q %s %d", content, direction, ts)
+	}
+	db.QueryRow(`SELECT direction FROM events WHERE id = 'signal:2'`).Scan(&direction)
+	if direction != "sent" {
+		t.Errorf("event 2 direction = %s", direction)
+	}
+
+	// A missing column is reported
+	bad, _ := NewGenericAdapter("bad", GenericAdapterOptions{Path: "-", Input: strings.NewReader("a,b\n1,2\n"), Format: GenericFormatCSV})
+	if _, err := bad.Sync(ctx, db, false); err == nil || !strings.Contains(err.Error(), `no "timestamp" column`) {
+		t.Errorf("missing column: err = %v", err)
+	}
+	if _, err := NewGenericAdapter("x", GenericAdapterOptions{Path: "-", Input: strings.NewReader("")}); err == nil {
+		t.Error("stdin without a format accepted")
+	}
+}
+
+func TestParseGenericTimestamp(t *testing.T) {
+	tests := []struct {
+		raw, format string
+		want        int64
+		wantErr     bool
+	}{
+		{"1700000000", "", 1700000000, false},
+		{"1700000000123", "", 1700000000, false},
+		{"1700000000.5", "unix", 1700000000, false},
+		{"1700000000123", "unix_ms", 1700000000, false},
+		{"2023-11-14T22:13:20Z", "", 1700000000, false},
+		{"2023-11-14 22:13:20", "", 1700000000, false},
+		{"14/11/2023 22:13", "02/01/2006 15:04", 1699999980, false},
+		{"yesterday", "", 0, true},
+		{"abc", "unix", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseGenericTimestamp(tt.raw, tt.format, time.UTC)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseGenericTimestamp(%q, %q) = %d, %v; want %d", tt.raw, tt.format, got, err, tt.want)
+		}
+	}
+}
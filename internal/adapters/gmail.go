@@ -16,6 +16,7 @@ import (
 	"encoding/base64"
 	"github.com/Napageneral/mnemonic/internal/bus"
 	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/errs"
 	"github.com/Napageneral/mnemonic/internal/state"
 	_ "modernc.org/sqlite"
 )
@@ -70,7 +71,7 @@ func NewGmailAdapter(name, account string, opts GmailAdapterOptions) (*GmailAdap
 
 	// Verify gogcli is available
 	if _, err := exec.LookPath("gog"); err != nil {
-		return nil, fmt.Errorf("gogcli (gog) not found in PATH. Install with: brew install steipete/tap/gogcli")
+		return nil, errs.New(errs.ErrAdapterSourceMissing, "gogcli (gog) not found in PATH. Install with: brew install steipete/tap/gogcli")
 	}
 
 	return &GmailAdapter{
@@ -158,7 +159,14 @@ func (g *GmailAdapter) ensureGmailStateTables(db *sql.DB) error {
 	return nil
 }
 
+// getHistoryID returns the Gmail historyId incremental syncs resume from.
+// It lives in sync_watermarks; databases synced before that column existed
+// still have it in adapter_state.
 func (g *GmailAdapter) getHistoryID(db *sql.DB) (int64, bool) {
+	var hist sql.NullInt64
+	if err := db.QueryRow(`SELECT history_id FROM sync_watermarks WHERE adapter = ?`, g.Name()).Scan(&hist); err == nil && hist.Valid && hist.Int64 > 0 {
+		return hist.Int64, true
+	}
 	v, ok, err := state.Get(db, g.Name(), "gmail_history_id")
 	if err != nil || !ok {
 		return 0, false
@@ -178,7 +186,13 @@ func (g *GmailAdapter) setHistoryID(db *sql.DB, historyID int64) {
 	if historyID <= 0 {
 		return
 	}
-	_ = state.Set(db, g.Name(), "gmail_history_id", fmt.Sprintf("%d", historyID))
+	// A new row keeps last_sync_at at 0 so an interrupted first sync still
+	// counts as a first run.
+	_, _ = db.Exec(`
+		INSERT INTO sync_watermarks (adapter, last_sync_at, history_id)
+		VALUES (?, 0, ?)
+		ON CONFLICT(adapter) DO UPDATE SET history_id = excluded.history_id
+	`, g.Name(), historyID)
 }
 
 func (g *GmailAdapter) syncGmailStateAndTags(cortexDB contacts.DBTX, eventID string, labelIDs []string, direction string) error {
@@ -1073,6 +1087,10 @@ func (g *GmailAdapter) syncMessageWithDB(cortexDB contacts.DBTX, message GmailMe
 		threadID = message.ID
 	}
 
+	if err := g.upsertThread(cortexDB, threadID, subject, timestamp); err != nil {
+		return false, false, participantsCreated, err
+	}
+
 	eventID := fmt.Sprintf("%s:%s", g.Name(), message.ID)
 	created, updated, err := g.upsertEvent(cortexDB, eventID, timestamp, contentTypesJSON, content, direction, threadID, message.ID)
 	if err != nil {
@@ -1091,6 +1109,28 @@ func (g *GmailAdapter) syncMessageWithDB(cortexDB contacts.DBTX, message GmailMe
 	return created, updated, participantsCreated, nil
 }
 
+// upsertThread records the Gmail thread a message belongs to. The thread is
+// named after the subject of its earliest synced message.
+func (g *GmailAdapter) upsertThread(cortexDB contacts.DBTX, threadID, subject string, timestamp int64) error {
+	var name sql.NullString
+	if s := strings.TrimSpace(subject); s != "" {
+		name = sql.NullString{String: s, Valid: true}
+	}
+	_, err := cortexDB.Exec(`
+		INSERT INTO threads (id, channel, name, is_group, source_adapter, source_id, created_at, updated_at)
+		VALUES (?, 'gmail', ?, 0, ?, ?, ?, ?)
+		ON CONFLICT(source_adapter, source_id) DO UPDATE SET
+			name = CASE WHEN excluded.created_at < threads.created_at OR threads.name IS NULL
+				THEN COALESCE(excluded.name, threads.name) ELSE threads.name END,
+			created_at = MIN(threads.created_at, excluded.created_at),
+			updated_at = MAX(threads.updated_at, excluded.updated_at)
+	`, threadID, name, g.Name(), threadID, timestamp, timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to upsert thread: %w", err)
+	}
+	return nil
+}
+
 func (g *GmailAdapter) upsertEvent(cortexDB contacts.DBTX, eventID string, timestamp int64, contentTypesJSON string, content string, direction string, threadID string, sourceID string) (created bool, updated bool, err error) {
 	// Try insert first.
 	res, err := cortexDB.Exec(`
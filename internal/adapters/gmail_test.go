@@ -0,0 +1,110 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/state"
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func gmailTestMessage(id, threadID, date, from, to, cc, subject string, labels ...string) GmailMessage {
+	return GmailMessage{
+		ID:           id,
+		ThreadID:     threadID,
+		LabelIDs:     labels,
+		InternalDate: date,
+		Payload: GmailPayload{
+			MimeType: "text/plain",
+			Headers: []GmailHeader{
+				{Name: "From", Value: from},
+				{Name: "To", Value: to},
+				{Name: "Cc", Value: cc},
+				{Name: "Subject", Value: subject},
+			},
+		},
+	}
+}
+
+func TestGmailSyncMessageThreadsAndParticipants(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	g := &GmailAdapter{name: "gmail-me", account: "me@example.com", opts: GmailAdapterOptions{}.withDefaults()}
+	if err := g.ensureGmailStateTables(db); err != nil {
+		t.Fatalf("ensure tables: %v", err)
+	}
+	cache := newEmailContactCache()
+
+	// The reply is synced before the message that started the thread
+	reply := gmailTestMessage("m2", "t1", "1704070800000", "Me <me@example.com>", "Casey Lee <casey@example.com>", "", "Re: Dinner", "SENT")
+	first := gmailTestMessage("m1", "t1", "1704067200000", "Casey Lee <casey@example.com>", "me@example.com", "Bob <bob@example.com>", "Dinner", "INBOX")
+	for _, m := range []GmailMessage{reply, first} {
+		if _, _, _, err := g.syncMessageWithDB(db, m, cache); err != nil {
+			t.Fatalf("sync %s: %v", m.ID, err)
+		}
+	}
+
+	var name, channel string
+	var createdAt, updatedAt int64
+	if err := db.QueryRow(`SELECT name, channel, created_at, updated_at FROM threads WHERE id = 't1' AND source_adapter = 'gmail-me'`).Scan(&name, &channel, &createdAt, &updatedAt); err != nil {
+		t.Fatalf("query thread: %v", err)
+	}
+	if name != "Dinner" || channel != "gmail" || createdAt != 1704067200 || updatedAt != 1704070800 {
+		t.Errorf("thread = %q %s %d %d", name, channel, createdAt, updatedAt)
+	}
+	var threadID string
+	db.QueryRow(`SELECT thread_id FROM events WHERE id = 'gmail-me:m1'`).Scan(&threadID)
+	if threadID != "t1" {
+		t.Errorf("event thread_id = %q", threadID)
+	}
+
+	rows, err := db.Query(`
+		SELECT ep.role, ci.normalized FROM event_participants ep
+		JOIN contact_identifiers ci ON ci.contact_id = ep.contact_id AND ci.type = 'email'
+		WHERE ep.event_id = 'gmail-me:m1' ORDER BY ep.role, ci.normalized
+	`)
+	if err != nil {
+		t.Fatalf("query participants: %v", err)
+	}
+	var got []string
+	for rows.Next() {
+		var role, email string
+		if err := rows.Scan(&role, &email); err != nil {
+			t.Fatalf("scan participant: %v", err)
+		}
+		got = append(got, role+":"+email)
+	}
+	rows.Close()
+	if len(got) < 3 || got[0] != "cc:bob@example.com" {
+		t.Errorf("participants = %v", got)
+	}
+}
+
+func TestGmailHistoryIDWatermark(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	g := &GmailAdapter{name: "gmail-me", account: "me@example.com"}
+
+	if _, ok := g.getHistoryID(db); ok {
+		t.Fatal("history ID before any sync")
+	}
+	// Databases synced before sync_watermarks.history_id kept it in adapter_state
+	if err := state.Set(db, g.Name(), "gmail_history_id", "41"); err != nil {
+		t.Fatalf("state.Set: %v", err)
+	}
+	if h, ok := g.getHistoryID(db); !ok || h != 41 {
+		t.Errorf("legacy history ID = %d, %v", h, ok)
+	}
+
+	g.setHistoryID(db, 42)
+	var lastSync int64
+	db.QueryRow(`SELECT last_sync_at FROM sync_watermarks WHERE adapter = ?`, g.Name()).Scan(&lastSync)
+	if lastSync != 0 {
+		t.Errorf("recording a history ID set last_sync_at = %d", lastSync)
+	}
+	if err := g.upsertWatermark(db, 1704067200, "backfill:2020-01-01"); err != nil {
+		t.Fatalf("upsertWatermark: %v", err)
+	}
+	if h, ok := g.getHistoryID(db); !ok || h != 42 {
+		t.Errorf("history ID = %d, %v", h, ok)
+	}
+}
@@ -0,0 +1,435 @@
+package adapters
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/errs"
+	"github.com/Napageneral/mnemonic/internal/state"
+)
+
+// ICSAdapterOptions configures the ICS calendar adapter.
+type ICSAdapterOptions struct {
+	// Path is an .ics file, a directory of them, or an http(s)/webcal URL
+	// such as Google Calendar's secret address in iCal format
+	Path string
+	// Timezone for times without one (default: local time)
+	Timezone string
+}
+
+// ICSAdapter imports iCalendar (.ics) files and feeds. Events are stored
+// like the Google Calendar adapter's: on the calendar channel, with the
+// organizer and attendees as participants mapped to contacts by email.
+// A recurring event is stored once, at its first occurrence, with its rule
+// in the content; instances that were moved or changed are stored on their
+// own. Unchanged files and feeds are skipped on sync.
+type ICSAdapter struct {
+	name   string
+	path   string
+	loc    *time.Location
+	client *http.Client
+}
+
+// NewICSAdapter creates an adapter for iCalendar files or a feed URL.
+func NewICSAdapter(name string, opts ICSAdapterOptions) (*ICSAdapter, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("adapter instance name is required for ics adapter")
+	}
+	path := strings.TrimSpace(opts.Path)
+	if path == "" {
+		return nil, fmt.Errorf("ICS path or URL is required")
+	}
+	if !isICSURL(path) {
+		if _, err := os.Stat(path); err != nil {
+			return nil, errs.New(errs.ErrAdapterSourceMissing, "calendar not found at %s: %w", path, err)
+		}
+	}
+	loc := time.Local
+	if opts.Timezone != "" {
+		l, err := time.LoadLocation(opts.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", opts.Timezone, err)
+		}
+		loc = l
+	}
+	return &ICSAdapter{name: name, path: path, loc: loc, client: &http.Client{Timeout: 2 * time.Minute}}, nil
+}
+
+func (a *ICSAdapter) Name() string { return a.name }
+
+func isICSURL(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "webcal://")
+}
+
+// icsCalendar is one parsed VCALENDAR.
+type icsCalendar struct {
+	name   string // X-WR-CALNAME
+	events []gogCalendarEvent
+}
+
+func (a *ICSAdapter) Sync(ctx context.Context, cortexDB *sql.DB, full bool) (SyncResult, error) {
+	start := time.Now()
+	res := SyncResult{Perf: map[string]string{}}
+
+	if _, err := cortexDB.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		return res, err
+	}
+	if err := ensureCalendarTables(cortexDB); err != nil {
+		return res, err
+	}
+
+	sources := []string{a.path}
+	if !isICSURL(a.path) {
+		files, err := icsFiles(a.path)
+		if err != nil {
+			return res, err
+		}
+		sources = files
+	}
+
+	cache := map[string]string{}
+	skipped := 0
+	for _, source := range sources {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		data, err := a.read(ctx, source)
+		if err != nil {
+			return res, err
+		}
+		sum := sha256.Sum256(data)
+		stamp := hex.EncodeToString(sum[:])
+		stateKey := "ics:" + source
+		if !full {
+			if seen, ok, _ := state.Get(cortexDB, a.Name(), stateKey); ok && seen == stamp {
+				skipped++
+				continue
+			}
+		}
+
+		parsed, err := parseICS(bytes.NewReader(data), a.loc)
+		if err != nil {
+			return res, fmt.Errorf("%s: %w", source, err)
+		}
+		cal := gogCalendar{ID: a.calendarID(source), Summary: parsed.name}
+		if cal.Summary == "" {
+			cal.Summary = cal.ID
+		}
+		for _, ev := range parsed.events {
+			if err := writeCalendarEvent(cortexDB, a.Name(), cal, ev, cache, &res); err != nil {
+				return res, err
+			}
+		}
+		if err := state.Set(cortexDB, a.Name(), stateKey, stamp); err != nil {
+			return res, fmt.Errorf("save calendar state: %w", err)
+		}
+	}
+
+	res.Perf["calendars"] = strconv.Itoa(len(sources))
+	res.Perf["calendars.unchanged"] = strconv.Itoa(skipped)
+	res.Duration = time.Since(start)
+	res.Perf["total"] = res.Duration.String()
+	return res, nil
+}
+
+// calendarID names a calendar after the adapter and, for files, the file:
+// the URL of a feed is kept out of it since it is often secret.
+func (a *ICSAdapter) calendarID(source string) string {
+	if isICSURL(source) {
+		return a.name
+	}
+	return a.name + "/" + strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+}
+
+func (a *ICSAdapter) read(ctx context.Context, source string) ([]byte, error) {
+	if !isICSURL(source) {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", source, err)
+		}
+		return data, nil
+	}
+
+	url := source
+	if strings.HasPrefix(strings.ToLower(url), "webcal://") {
+		url = "https://" + url[len("webcal://"):]
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("calendar feed request: %w", err)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		// url.Error names the URL; keep the secret out of sync results
+		var uerr *neturl.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return nil, fmt.Errorf("fetch calendar feed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("fetch calendar feed: HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read calendar feed: %w", err)
+	}
+	return data, nil
+}
+
+// icsFiles lists the .ics files under path.
+func icsFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		abs, _ := filepath.Abs(path)
+		return []string{abs}, nil
+	}
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if strings.EqualFold(filepath.Ext(p), ".ics") {
+			abs, _ := filepath.Abs(p)
+			files = append(files, abs)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list calendars: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// icsProperty is a content line: NAME;PARAM=value:VALUE.
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseICS reads the events of an iCalendar stream into the Google
+// Calendar event shape the calendar adapters share. Times without a zone,
+// or in a zone Go doesn't know, are read in loc.
+func parseICS(r io.Reader, loc *time.Location) (*icsCalendar, error) {
+	lines, err := unfoldICSLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cal := &icsCalendar{}
+	var ev *gogCalendarEvent
+	var recurrence, rrule string
+	nested := 0 // components inside the VEVENT (VALARM)
+	for _, line := range lines {
+		p, ok := parseICSProperty(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT"):
+			ev, recurrence, rrule, nested = &gogCalendarEvent{}, "", "", 0
+			continue
+		case p.name == "BEGIN" && ev != nil:
+			nested++
+			continue
+		case p.name == "END" && ev != nil && nested > 0:
+			nested--
+			continue
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT") && ev != nil:
+			if ev.ID == "" {
+				sum := sha256.Sum256([]byte(ev.Start.DateTime + ev.Start.Date + "\x00" + ev.Summary))
+				ev.ID = hex.EncodeToString(sum[:12])
+			}
+			if recurrence != "" {
+				ev.ID += "/" + recurrence
+			}
+			if rrule != "" {
+				ev.Description = strings.TrimSpace(ev.Description + "\n\nRepeats: " + rrule)
+			}
+			cal.events = append(cal.events, *ev)
+			ev = nil
+			continue
+		}
+
+		if ev == nil {
+			if p.name == "X-WR-CALNAME" {
+				cal.name = icsText(p.value)
+			}
+			continue
+		}
+		if nested > 0 {
+			continue
+		}
+		switch p.name {
+		case "UID":
+			ev.ID = strings.TrimSpace(p.value)
+		case "SUMMARY":
+			ev.Summary = icsText(p.value)
+		case "DESCRIPTION":
+			ev.Description = icsText(p.value)
+		case "LOCATION":
+			ev.Location = icsText(p.value)
+		case "URL":
+			ev.HTMLLink = strings.TrimSpace(p.value)
+		case "STATUS":
+			// CONFIRMED, TENTATIVE or CANCELLED, lowercased like Google's
+			ev.Status = strings.ToLower(strings.TrimSpace(p.value))
+		case "DTSTART":
+			ev.Start = icsTime(p, loc)
+		case "DTEND":
+			ev.End = icsTime(p, loc)
+		case "CREATED":
+			ev.Created = icsTime(p, loc).DateTime
+		case "LAST-MODIFIED":
+			ev.Updated = icsTime(p, loc).DateTime
+		case "RECURRENCE-ID":
+			recurrence = strings.TrimSpace(p.value)
+		case "RRULE":
+			rrule = strings.TrimSpace(p.value)
+		case "ORGANIZER":
+			person := icsPerson(p)
+			ev.Organizer = &person
+		case "ATTENDEE":
+			ev.Attendees = append(ev.Attendees, icsPerson(p))
+		}
+	}
+	return cal, nil
+}
+
+// unfoldICSLines joins folded lines: a line starting with a space or tab
+// continues the previous one.
+func unfoldICSLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read calendar: %w", err)
+	}
+	return lines, nil
+}
+
+// parseICSProperty splits a content line into its name, parameters and
+// value. Quoted parameter values may hold ':' and ';'.
+func parseICSProperty(line string) (icsProperty, bool) {
+	quoted := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon <= 0 {
+		return icsProperty{}, false
+	}
+
+	var parts []string
+	quoted = false
+	last := 0
+	head := line[:colon]
+	for i, r := range head {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ';' && !quoted {
+			parts = append(parts, head[last:i])
+			last = i + 1
+		}
+	}
+	parts = append(parts, head[last:])
+
+	p := icsProperty{name: strings.ToUpper(strings.TrimSpace(parts[0])), params: map[string]string{}, value: line[colon+1:]}
+	for _, param := range parts[1:] {
+		if k, v, ok := strings.Cut(param, "="); ok {
+			p.params[strings.ToUpper(strings.TrimSpace(k))] = strings.Trim(v, `"`)
+		}
+	}
+	return p, true
+}
+
+var icsTextEscapes = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+// icsText unescapes a TEXT value.
+func icsText(v string) string {
+	return strings.TrimSpace(icsTextEscapes.Replace(v))
+}
+
+// icsTime reads a DATE or DATE-TIME value: UTC ("Z"), in its TZID zone, or
+// floating (in loc).
+func icsTime(p icsProperty, loc *time.Location) gogEventTime {
+	v := strings.TrimSpace(p.value)
+	if strings.EqualFold(p.params["VALUE"], "DATE") || len(v) == 8 {
+		t, err := time.Parse("20060102", v)
+		if err != nil {
+			return gogEventTime{}
+		}
+		return gogEventTime{Date: t.Format("2006-01-02")}
+	}
+
+	zone := loc
+	tzid := p.params["TZID"]
+	if tzid != "" {
+		if l, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			zone = l
+		}
+	}
+	var t time.Time
+	var err error
+	if strings.HasSuffix(v, "Z") {
+		t, err = time.Parse("20060102T150405Z", v)
+	} else {
+		t, err = time.ParseInLocation("20060102T150405", v, zone)
+	}
+	if err != nil {
+		return gogEventTime{}
+	}
+	return gogEventTime{DateTime: t.Format(time.RFC3339), TimeZone: tzid}
+}
+
+// icsPerson reads an ORGANIZER or ATTENDEE: a mailto: address with its
+// CN (name) and PARTSTAT (response).
+func icsPerson(p icsProperty) gogEventPerson {
+	email := strings.TrimSpace(p.value)
+	if len(email) >= 7 && strings.EqualFold(email[:7], "mailto:") {
+		email = email[7:]
+	}
+	if !strings.Contains(email, "@") {
+		email = ""
+	}
+	return gogEventPerson{
+		Email:       email,
+		DisplayName: strings.TrimSpace(p.params["CN"]),
+		Response:    strings.ToLower(p.params["PARTSTAT"]),
+	}
+}
@@ -0,0 +1,166 @@
+package adapters
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+const testICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"X-WR-CALNAME:Home\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:dinner-1@example.com\r\n" +
+	"DTSTART;TZID=America/New_York:20260313T190000\r\n" +
+	"DTEND;TZID=America/New_York:20260313T210000\r\n" +
+	"SUMMARY:Dinner with Casey\r\n" +
+	"DESCRIPTION:At the new place\\, bring\\nthe book\r\n" +
+	"LOCATION:Lupa\\; NYC\r\n" +
+	"STATUS:CONFIRMED\r\n" +
+	"ORGANIZER;CN=Tyler Brandt:mailto:tyler@example.com\r\n" +
+	"ATTENDEE;CN=\"Lee, Casey\";PARTSTAT=ACCEPTED:mailto:casey@exa\r\n" +
+	" mple.com\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"DESCRIPTION:Reminder\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup@example.com\r\n" +
+	"DTSTART:20260302T150000Z\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO\r\n" +
+	"SUMMARY:Standup\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup@example.com\r\n" +
+	"RECURRENCE-ID:20260309T150000Z\r\n" +
+	"DTSTART:20260309T160000Z\r\n" +
+	"SUMMARY:Standup (moved)\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:trip@example.com\r\n" +
+	"DTSTART;VALUE=DATE:20260401\r\n" +
+	"SUMMARY:Trip\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	cal, err := parseICS(strings.NewReader(testICS), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cal.name != "Home" || len(cal.events) != 4 {
+		t.Fatalf("calendar = %+v", cal)
+	}
+
+	dinner := cal.events[0]
+	if dinner.Summary != "Dinner with Casey" || dinner.Description != "At the new place, bring\nthe book" ||
+		dinner.Location != "Lupa; NYC" || dinner.Status != "confirmed" {
+		t.Errorf("dinner = %+v", dinner)
+	}
+	if dinner.Start.DateTime != "2026-03-13T19:00:00-04:00" {
+		t.Errorf("dinner start = %+v", dinner.Start)
+	}
+	if dinner.Organizer == nil || dinner.Organizer.Email != "tyler@example.com" ||
+		len(dinner.Attendees) != 1 || dinner.Attendees[0].Email != "casey@example.com" || dinner.Attendees[0].DisplayName != "Lee, Casey" {
+		t.Errorf("dinner people = %+v %+v", dinner.Organizer, dinner.Attendees)
+	}
+
+	if standup := cal.events[1]; standup.ID != "standup@example.com" || !strings.Contains(standup.Description, "Repeats: FREQ=WEEKLY;BYDAY=MO") {
+		t.Errorf("recurring = %+v", standup)
+	}
+	if moved := cal.events[2]; moved.ID != "standup@example.com/20260309T150000Z" || moved.Status != "cancelled" {
+		t.Errorf("moved instance = %+v", moved)
+	}
+	if trip := cal.events[3]; trip.Start.Date != "2026-04-01" || trip.Start.DateTime != "" {
+		t.Errorf("all-day = %+v", trip.Start)
+	}
+}
+
+func TestICSAdapterSync(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "home.ics"), []byte(testICS), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewICSAdapter("calendar", ICSAdapterOptions{Path: dir, Timezone: "UTC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := a.Sync(ctx, db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.EventsCreated != 4 || res.PersonsCreated != 2 {
+		t.Errorf("first sync = %+v", res)
+	}
+
+	var content, thread string
+	var ts int64
+	if err := db.QueryRow(`
+		SELECT content, thread_id, timestamp FROM events
+		WHERE channel = 'calendar' AND source_id = 'calendar/home:dinner-1@example.com'
+	`).Scan(&content, &thread, &ts); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(content, "Summary: Dinner with Casey\nCalendar: Home\n") || thread != "calendar:calendar/home" ||
+		ts != time.Date(2026, 3, 13, 23, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("dinner event = %q %s %d", content, thread, ts)
+	}
+	var roles string
+	if err := db.QueryRow(`
+		SELECT GROUP_CONCAT(ep.role || ':' || ci.normalized, ',') FROM (
+			SELECT * FROM event_participants WHERE event_id = 'calendar:calendar/home:dinner-1@example.com' ORDER BY role
+		) ep JOIN contact_identifiers ci ON ci.contact_id = ep.contact_id
+	`).Scan(&roles); err != nil {
+		t.Fatal(err)
+	}
+	if roles != "attendee:casey@example.com,organizer:tyler@example.com" {
+		t.Errorf("participants = %s", roles)
+	}
+	var status string
+	if err := db.QueryRow(`SELECT status FROM event_state WHERE event_id = 'calendar:calendar/home:standup@example.com/20260309T150000Z'`).Scan(&status); err != nil || status != "cancelled" {
+		t.Errorf("moved instance status = %q (%v)", status, err)
+	}
+
+	// Unchanged calendars are skipped
+	res, err = a.Sync(ctx, db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.EventsCreated != 0 || res.Perf["calendars.unchanged"] != "1" {
+		t.Errorf("second sync = %+v", res)
+	}
+
+	// A feed URL
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Replace(testICS, "Dinner with Casey", "Dinner with Casey and Sam", 1)))
+	}))
+	defer srv.Close()
+	feed, err := NewICSAdapter("work", ICSAdapterOptions{Path: srv.URL + "/basic.ics"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = feed.Sync(ctx, db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.EventsCreated != 4 {
+		t.Errorf("feed sync = %+v", res)
+	}
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM events WHERE thread_id = 'calendar:work' AND content LIKE '%Casey and Sam%'`).Scan(&n); err != nil || n != 1 {
+		t.Errorf("feed events = %d (%v)", n, err)
+	}
+}
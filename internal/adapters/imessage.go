@@ -0,0 +1,825 @@
+package adapters
+
+// IMessageAdapter syncs iMessage straight from the macOS Messages database
+// (~/Library/Messages/chat.db), for machines without Eve. Event, thread and
+// attachment IDs match the Eve adapter's (both are keyed by the Messages
+// GUIDs), so switching between the two never duplicates history.
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/avatars"
+	"github.com/Napageneral/mnemonic/internal/config"
+	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/errs"
+	"github.com/Napageneral/mnemonic/internal/me"
+	"github.com/Napageneral/mnemonic/internal/namespace"
+	"github.com/Napageneral/mnemonic/internal/state"
+	"github.com/Napageneral/mnemonic/internal/threads"
+	"github.com/google/uuid"
+	_ "modernc.org/sqlite"
+)
+
+// appleEpoch is 2001-01-01 UTC in unix seconds, the zero of Messages dates.
+const appleEpoch = 978307200
+
+// Incremental sync state (adapter_state keys). Messages are read by ROWID
+// since dates in chat.db are not in insertion order; edits and unsends of
+// older messages are picked up by their edit date.
+const (
+	chatDBRowIDKey    = "chatdb_message_rowid"
+	chatDBSyncedAtKey = "chatdb_synced_at" // Apple-epoch nanoseconds of the last sync
+)
+
+// chat.db chat.style of group chats (one-to-one chats are 45).
+const chatStyleGroup = 43
+
+// IMessageAdapter syncs iMessage events from chat.db.
+type IMessageAdapter struct {
+	chatDBPath     string
+	addressBookDir string
+}
+
+// DefaultChatDBPath returns where macOS keeps the Messages database.
+func DefaultChatDBPath() (string, error) {
+	return config.ChatDBPath()
+}
+
+// NewIMessageAdapter creates an adapter for the chat.db at path (default
+// DefaultChatDBPath). Reading chat.db needs Full Disk Access for the
+// terminal or binary running the sync.
+func NewIMessageAdapter(path string) (*IMessageAdapter, error) {
+	if path == "" {
+		var err error
+		if path, err = DefaultChatDBPath(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, errs.New(errs.ErrAdapterSourceMissing, "Messages database not found at %s", path)
+	}
+	abDir, _ := avatars.DefaultAddressBookDir()
+	return &IMessageAdapter{chatDBPath: path, addressBookDir: abDir}, nil
+}
+
+func (a *IMessageAdapter) Name() string {
+	return "imessage"
+}
+
+// chatInfo is a chat.db chat mapped to a cortex thread.
+type chatInfo struct {
+	threadID string
+	members  []string // cortex contact IDs of the chat's handles
+}
+
+// messageCounts tallies what syncMessages wrote.
+type messageCounts struct {
+	created, updated                   int
+	reactionsCreated, reactionsRemoved int
+	membership, edited, unsent         int
+	maxRowID, maxTimestamp             int64
+}
+
+func (a *IMessageAdapter) Sync(ctx context.Context, cortexDB *sql.DB, full bool) (SyncResult, error) {
+	startTime := time.Now()
+	result := SyncResult{Perf: map[string]string{}}
+
+	chatDB, err := sql.Open("sqlite", "file:"+a.chatDBPath+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
+		return result, fmt.Errorf("failed to open Messages database: %w", err)
+	}
+	defer chatDB.Close()
+	columns, err := tableColumns(ctx, chatDB, "message")
+	if err != nil {
+		return result, fmt.Errorf("failed to read Messages database (Full Disk Access may be required): %w", err)
+	}
+
+	_, _ = cortexDB.Exec("PRAGMA foreign_keys = ON")
+	_, _ = cortexDB.Exec("PRAGMA busy_timeout = 5000")
+
+	var lastRowID, lastSyncedAt, lastSyncTimestamp int64
+	if !full {
+		if v, ok, err := state.Get(cortexDB, a.Name(), chatDBRowIDKey); err != nil {
+			return result, err
+		} else if ok {
+			lastRowID, _ = strconv.ParseInt(v, 10, 64)
+		}
+		if v, ok, err := state.Get(cortexDB, a.Name(), chatDBSyncedAtKey); err != nil {
+			return result, err
+		} else if ok {
+			lastSyncedAt, _ = strconv.ParseInt(v, 10, 64)
+		}
+		row := cortexDB.QueryRow("SELECT last_sync_at FROM sync_watermarks WHERE adapter = ?", a.Name())
+		if err := row.Scan(&lastSyncTimestamp); err != nil && err != sql.ErrNoRows {
+			return result, fmt.Errorf("failed to get sync watermark: %w", err)
+		}
+	}
+	syncStartApple := (startTime.Unix() - appleEpoch) * int64(time.Second)
+
+	// Names from macOS Contacts; chat.db itself only has phone numbers and emails
+	names, err := addressBookNames(ctx, a.addressBookDir)
+	if err != nil {
+		// Non-fatal - contacts are named by identifier instead
+		result.Perf["address_book.error"] = err.Error()
+	}
+
+	tx, err := cortexDB.BeginTx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("begin cortex tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	handles, personsCreated, err := a.syncHandles(ctx, chatDB, tx, names)
+	if err != nil {
+		return result, fmt.Errorf("failed to sync handles: %w", err)
+	}
+	meContactID, meCreated, err := meContact(tx, a.Name())
+	if err != nil {
+		return result, err
+	}
+	result.PersonsCreated = personsCreated + meCreated
+
+	chats, err := a.syncChats(ctx, chatDB, tx, handles, &result)
+	if err != nil {
+		return result, fmt.Errorf("failed to sync chats: %w", err)
+	}
+
+	counts, err := a.syncMessages(ctx, chatDB, tx, columns, lastRowID, lastSyncedAt, handles, chats, meContactID)
+	if err != nil {
+		return result, fmt.Errorf("failed to sync messages: %w", err)
+	}
+	result.EventsCreated = counts.created + counts.membership
+	result.EventsUpdated = counts.updated
+	result.ReactionsCreated = counts.reactionsCreated
+	result.Perf["messages.edited"] = strconv.Itoa(counts.edited)
+	result.Perf["messages.unsent"] = strconv.Itoa(counts.unsent)
+	result.Perf["reactions.removed"] = strconv.Itoa(counts.reactionsRemoved)
+	result.Perf["membership"] = strconv.Itoa(counts.membership)
+
+	if result.AttachmentsCreated, result.AttachmentsUpdated, err = a.syncAttachments(ctx, chatDB, tx, lastRowID); err != nil {
+		return result, fmt.Errorf("failed to sync attachments: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("commit cortex tx: %w", err)
+	}
+
+	// Rebuild rosters of group threads touched since the last sync
+	if n, err := threads.RebuildMembership(cortexDB, lastSyncTimestamp); err != nil {
+		// Non-fatal - rosters only refine participant priming
+		result.Perf["roster.error"] = err.Error()
+	} else {
+		result.Perf["roster.threads"] = strconv.Itoa(n)
+	}
+
+	if counts.maxRowID > lastRowID {
+		if err := state.Set(cortexDB, a.Name(), chatDBRowIDKey, strconv.FormatInt(counts.maxRowID, 10)); err != nil {
+			return result, err
+		}
+	}
+	if err := state.Set(cortexDB, a.Name(), chatDBSyncedAtKey, strconv.FormatInt(syncStartApple, 10)); err != nil {
+		return result, err
+	}
+	// Max imported event timestamp, not wall-clock time (as the Eve adapter does)
+	watermark := lastSyncTimestamp
+	if counts.maxTimestamp > watermark {
+		watermark = counts.maxTimestamp
+	}
+	if _, err := cortexDB.Exec(`
+		INSERT INTO sync_watermarks (adapter, last_sync_at)
+		VALUES (?, ?)
+		ON CONFLICT(adapter) DO UPDATE SET last_sync_at = excluded.last_sync_at
+	`, a.Name(), watermark); err != nil {
+		return result, fmt.Errorf("failed to update sync watermark: %w", err)
+	}
+
+	result.Duration = time.Since(startTime)
+	result.Perf["total"] = result.Duration.String()
+	return result, nil
+}
+
+// syncHandles maps chat.db handles (phone numbers and emails) to contacts,
+// named from macOS Contacts when a card has the identifier. Identifiers on
+// one card are linked to one person. Returns handle ROWID -> contact ID.
+func (a *IMessageAdapter) syncHandles(ctx context.Context, chatDB *sql.DB, tx *sql.Tx, names map[string]addressBookName) (map[int64]string, int, error) {
+	rows, err := chatDB.QueryContext(ctx, `SELECT ROWID, id FROM handle ORDER BY ROWID`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query handles: %w", err)
+	}
+	defer rows.Close()
+
+	type card struct {
+		name       string
+		contactIDs []string
+	}
+	cards := map[string]*card{}
+	var cardOrder []string
+	handles := map[int64]string{}
+	for rows.Next() {
+		var rowID int64
+		var identifier string
+		if err := rows.Scan(&rowID, &identifier); err != nil {
+			return nil, 0, fmt.Errorf("scan handle: %w", err)
+		}
+		idType := "phone"
+		if strings.Contains(identifier, "@") {
+			idType = "email"
+		}
+		name := names[idType+":"+contacts.NormalizeIdentifier(identifier, idType)]
+		contactID, _, err := contacts.GetOrCreateContact(tx, idType, identifier, name.name, a.Name())
+		if err != nil {
+			// Short codes and malformed handles have no usable identifier
+			continue
+		}
+		handles[rowID] = contactID
+		if name.card == "" {
+			continue
+		}
+		c := cards[name.card]
+		if c == nil {
+			c = &card{name: name.name}
+			cards[name.card] = c
+			cardOrder = append(cardOrder, name.card)
+		}
+		c.contactIDs = append(c.contactIDs, contactID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	personsCreated := 0
+	for _, key := range cardOrder {
+		c := cards[key]
+		if _, created, err := contacts.LinkCard(tx, c.contactIDs, c.name); err != nil {
+			return nil, 0, fmt.Errorf("link contact person: %w", err)
+		} else if created {
+			personsCreated++
+		}
+	}
+	return handles, personsCreated, nil
+}
+
+// meContact returns the user's contact, creating the "me" person
+// and a contact for it when missing.
+func meContact(tx *sql.Tx, source string) (string, int, error) {
+	var contactID string
+	personID, _ := me.LookupMePersonID(tx)
+	if personID != "" {
+		_ = tx.QueryRow(`SELECT contact_id FROM person_contact_links WHERE person_id = ? ORDER BY first_seen_at LIMIT 1`, personID).Scan(&contactID)
+		if contactID != "" {
+			return contactID, 0, nil
+		}
+	}
+
+	created := 0
+	now := time.Now().Unix()
+	if personID == "" {
+		personID = uuid.New().String()
+		if _, err := tx.Exec(`
+			INSERT INTO persons (id, canonical_name, is_me, namespace, created_at, updated_at)
+			VALUES (?, 'Me', 1, ?, ?, ?)
+		`, personID, namespace.Value(), now, now); err != nil {
+			return "", 0, fmt.Errorf("failed to create me person: %w", err)
+		}
+		created++
+	}
+	contactID = uuid.New().String()
+	if _, err := tx.Exec(`
+		INSERT INTO contacts (id, display_name, source, created_at, updated_at)
+		VALUES (?, 'Me', ?, ?, ?)
+	`, contactID, source, now, now); err != nil {
+		return "", 0, fmt.Errorf("failed to create me contact: %w", err)
+	}
+	if err := contacts.EnsurePersonContactLink(tx, personID, contactID, "deterministic", 1.0); err != nil {
+		return "", 0, err
+	}
+	return contactID, created, nil
+}
+
+// syncChats upserts a thread per chat and returns chat ROWID -> chatInfo.
+func (a *IMessageAdapter) syncChats(ctx context.Context, chatDB *sql.DB, tx *sql.Tx, handles map[int64]string, result *SyncResult) (map[int64]*chatInfo, error) {
+	chats := map[int64]*chatInfo{}
+	rows, err := chatDB.QueryContext(ctx, `SELECT ROWID, chat_identifier, display_name, style FROM chat ORDER BY ROWID`)
+	if err != nil {
+		return nil, fmt.Errorf("query chats: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now().Unix()
+	for rows.Next() {
+		var rowID int64
+		var identifier string
+		var displayName sql.NullString
+		var style sql.NullInt64
+		if err := rows.Scan(&rowID, &identifier, &displayName, &style); err != nil {
+			return nil, fmt.Errorf("scan chat: %w", err)
+		}
+		threadID := a.Name() + ":" + identifier
+		name := identifier
+		if displayName.String != "" {
+			name = displayName.String
+		}
+		isGroup := 0
+		if style.Int64 == chatStyleGroup {
+			isGroup = 1
+		}
+
+		var exists int
+		_ = tx.QueryRow(`SELECT 1 FROM threads WHERE source_adapter = ? AND source_id = ?`, a.Name(), identifier).Scan(&exists)
+		res, err := tx.Exec(`
+			INSERT INTO threads (id, channel, name, is_group, source_adapter, source_id, created_at, updated_at)
+			VALUES (?, 'imessage', ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(source_adapter, source_id) DO UPDATE SET
+				name = excluded.name,
+				is_group = excluded.is_group,
+				updated_at = excluded.updated_at
+			WHERE threads.name IS NOT excluded.name OR threads.is_group != excluded.is_group
+		`, threadID, name, isGroup, a.Name(), identifier, now, now)
+		if err != nil {
+			return nil, fmt.Errorf("upsert thread: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			if exists == 1 {
+				result.ThreadsUpdated++
+			} else {
+				result.ThreadsCreated++
+			}
+		}
+		chats[rowID] = &chatInfo{threadID: threadID}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	members, err := chatDB.QueryContext(ctx, `SELECT chat_id, handle_id FROM chat_handle_join`)
+	if err != nil {
+		return nil, fmt.Errorf("query chat members: %w", err)
+	}
+	defer members.Close()
+	for members.Next() {
+		var chatID, handleID int64
+		if err := members.Scan(&chatID, &handleID); err != nil {
+			return nil, fmt.Errorf("scan chat member: %w", err)
+		}
+		if c, ok := chats[chatID]; ok && handles[handleID] != "" {
+			c.members = append(c.members, handles[handleID])
+		}
+	}
+	return chats, members.Err()
+}
+
+// syncMessages maps messages to events: text (decoding attributedBody when
+// text is empty), tapbacks as reaction events, and group joins and leaves
+// as membership events. Messages already synced are checked for edits and
+// unsends, which are recorded as revisions and tombstones.
+func (a *IMessageAdapter) syncMessages(ctx context.Context, chatDB *sql.DB, tx *sql.Tx, columns map[string]bool, lastRowID, lastSyncedAt int64, handles map[int64]string, chats map[int64]*chatInfo, meContactID string) (messageCounts, error) {
+	var counts messageCounts
+	optional := func(column, fallback string) string {
+		if columns[column] {
+			return "m." + column
+		}
+		return fallback + " AS " + column
+	}
+	where := "m.ROWID > ?"
+	args := []interface{}{lastRowID}
+	if lastRowID > 0 {
+		for _, column := range []string{"date_edited", "date_retracted"} {
+			if columns[column] {
+				where += " OR m." + column + " > ?"
+				args = append(args, lastSyncedAt)
+			}
+		}
+	}
+
+	rows, err := chatDB.QueryContext(ctx, `
+		SELECT m.ROWID, m.guid, m.text, m.attributedBody, m.handle_id, m.date, m.is_from_me,
+		       m.item_type, m.group_action_type, m.other_handle,
+		       m.associated_message_guid, m.associated_message_type, m.cache_has_attachments,
+		       `+optional("thread_originator_guid", "NULL")+`,
+		       `+optional("date_retracted", "0")+`,
+		       `+optional("associated_message_emoji", "NULL")+`,
+		       (SELECT cmj.chat_id FROM chat_message_join cmj WHERE cmj.message_id = m.ROWID LIMIT 1)
+		FROM message m
+		WHERE `+where+`
+		ORDER BY m.ROWID
+	`, args...)
+	if err != nil {
+		return counts, fmt.Errorf("query messages: %w", err)
+	}
+	defer rows.Close()
+
+	stmtParticipant, err := tx.Prepare(`INSERT OR IGNORE INTO event_participants (event_id, contact_id, role) VALUES (?, ?, ?)`)
+	if err != nil {
+		return counts, fmt.Errorf("prepare insert participant: %w", err)
+	}
+	defer stmtParticipant.Close()
+
+	prefix := a.Name() + ":"
+	observedAt := time.Now().Unix()
+	for rows.Next() {
+		var m struct {
+			rowID, handleID, date              int64
+			guid                               string
+			text, assocGUID, replyGUID, emoji  sql.NullString
+			body                               []byte
+			isFromMe, hasAttachments           bool
+			itemType, groupAction, otherHandle sql.NullInt64
+			assocType, retracted, chatID       sql.NullInt64
+		}
+		if err := rows.Scan(&m.rowID, &m.guid, &m.text, &m.body, &m.handleID, &m.date, &m.isFromMe,
+			&m.itemType, &m.groupAction, &m.otherHandle, &m.assocGUID, &m.assocType, &m.hasAttachments,
+			&m.replyGUID, &m.retracted, &m.emoji, &m.chatID); err != nil {
+			return counts, fmt.Errorf("scan message: %w", err)
+		}
+		if m.rowID > counts.maxRowID {
+			counts.maxRowID = m.rowID
+		}
+		timestamp := appleTimeToUnix(m.date)
+		if timestamp > counts.maxTimestamp {
+			counts.maxTimestamp = timestamp
+		}
+
+		threadID := prefix + fmt.Sprintf("chat_id:%d", m.chatID.Int64)
+		var members []string
+		if c, ok := chats[m.chatID.Int64]; ok {
+			threadID, members = c.threadID, c.members
+		}
+		direction := "received"
+		if m.isFromMe {
+			direction = "sent"
+		}
+		senderContactID := handles[m.handleID]
+		if m.isFromMe {
+			senderContactID = meContactID
+		}
+		eventID := prefix + m.guid
+
+		switch {
+		case m.assocType.Int64 >= 2000 && m.assocType.Int64 < 3000:
+			// Tapback on another message
+			content := mapReactionType(m.assocType.Int64)
+			if m.emoji.String != "" {
+				content = m.emoji.String
+			}
+			res, err := tx.Exec(`
+				INSERT OR IGNORE INTO events (
+					id, timestamp, channel, content_types, content,
+					direction, thread_id, reply_to, source_adapter, source_id
+				) VALUES (?, ?, 'imessage', '["reaction"]', ?, ?, ?, ?, ?, ?)
+			`, eventID, timestamp, content, direction, threadID, prefix+tapbackTarget(m.assocGUID.String), a.Name(), m.guid)
+			if err != nil {
+				return counts, fmt.Errorf("insert reaction event: %w", err)
+			}
+			if n, _ := res.RowsAffected(); n == 1 {
+				counts.reactionsCreated++
+			}
+			if senderContactID != "" {
+				_, _ = stmtParticipant.Exec(eventID, senderContactID, "sender")
+			}
+
+		case m.assocType.Int64 >= 3000 && m.assocType.Int64 < 4000:
+			// Tapback removed: drop the sender's matching reaction
+			content := mapReactionType(m.assocType.Int64 - 1000)
+			if m.emoji.String != "" {
+				content = m.emoji.String
+			}
+			res, err := tx.Exec(`
+				DELETE FROM events
+				WHERE channel = 'imessage' AND content_types = '["reaction"]'
+				  AND reply_to = ? AND content = ?
+				  AND id IN (SELECT event_id FROM event_participants WHERE contact_id = ? AND role = 'sender')
+			`, prefix+tapbackTarget(m.assocGUID.String), content, senderContactID)
+			if err != nil {
+				return counts, fmt.Errorf("remove reaction: %w", err)
+			}
+			n, _ := res.RowsAffected()
+			counts.reactionsRemoved += int(n)
+
+		case (m.itemType.Int64 == 1 && m.groupAction.Int64 <= 1) || (m.itemType.Int64 == 3 && m.groupAction.Int64 == 0):
+			// Group membership: item type 1 adds (action 0) or removes (1)
+			// other_handle; item type 3 action 0 is the sender leaving
+			action, member := "added", handles[m.otherHandle.Int64]
+			if m.itemType.Int64 == 3 {
+				action, member = "removed", senderContactID
+			} else if m.groupAction.Int64 == 1 {
+				action = "removed"
+			}
+			metadata, _ := json.Marshal(map[string]any{"action": action, "group_action_type": m.groupAction.Int64, "item_type": m.itemType.Int64})
+			res, err := tx.Exec(`
+				INSERT OR IGNORE INTO events (
+					id, timestamp, channel, content_types, content,
+					direction, thread_id, reply_to, source_adapter, source_id, metadata_json
+				) VALUES (?, ?, 'imessage', '["membership"]', ?, ?, ?, '', ?, ?, ?)
+			`, eventID, timestamp, action, direction, threadID, a.Name(), m.guid, string(metadata))
+			if err != nil {
+				return counts, fmt.Errorf("insert membership event: %w", err)
+			}
+			if n, _ := res.RowsAffected(); n == 1 {
+				counts.membership++
+			}
+			if member != "" {
+				_, _ = stmtParticipant.Exec(eventID, member, "member")
+			}
+			if senderContactID != "" {
+				_, _ = stmtParticipant.Exec(eventID, senderContactID, "sender")
+			}
+
+		case m.itemType.Int64 != 0:
+			// Renames, group photo changes and other system items carry no message
+
+		default:
+			text := messageText(m.text.String, m.body)
+			contentTypes := `["text"]`
+			switch {
+			case text != "" && m.hasAttachments:
+				contentTypes = `["text","attachment"]`
+			case m.hasAttachments:
+				contentTypes = `["attachment"]`
+			}
+			res, err := tx.Exec(`
+				INSERT OR IGNORE INTO events (
+					id, timestamp, channel, content_types, content,
+					direction, thread_id, reply_to, source_adapter, source_id
+				) VALUES (?, ?, 'imessage', ?, ?, ?, ?, ?, ?, ?)
+			`, eventID, timestamp, contentTypes, text, direction, threadID, prefixIfSet(prefix, m.replyGUID.String), a.Name(), m.guid)
+			if err != nil {
+				return counts, fmt.Errorf("insert event: %w", err)
+			}
+			if n, _ := res.RowsAffected(); n == 1 {
+				counts.created++
+			} else {
+				unsent := m.retracted.Int64 > 0 || (text == "" && !m.hasAttachments)
+				kind, err := recordContentChange(tx, eventID, text, contentTypes, unsent, observedAt)
+				if err != nil {
+					return counts, err
+				}
+				switch kind {
+				case revisionEdited:
+					counts.edited++
+					counts.updated++
+				case revisionUnsent:
+					counts.unsent++
+					counts.updated++
+				}
+			}
+			if senderContactID != "" {
+				_, _ = stmtParticipant.Exec(eventID, senderContactID, "sender")
+			}
+			if m.isFromMe {
+				for _, member := range members {
+					_, _ = stmtParticipant.Exec(eventID, member, "recipient")
+				}
+			} else if meContactID != "" {
+				_, _ = stmtParticipant.Exec(eventID, meContactID, "recipient")
+			}
+		}
+	}
+	return counts, rows.Err()
+}
+
+// syncAttachments records the attachments of messages after lastRowID
+// (all messages when 0), pointing at the files under ~/Library/Messages.
+func (a *IMessageAdapter) syncAttachments(ctx context.Context, chatDB *sql.DB, tx *sql.Tx, lastRowID int64) (created, updated int, err error) {
+	rows, err := chatDB.QueryContext(ctx, `
+		SELECT at.guid, at.filename, at.transfer_name, at.mime_type, at.total_bytes, at.is_sticker, at.uti,
+		       at.created_date, m.guid
+		FROM attachment at
+		JOIN message_attachment_join maj ON maj.attachment_id = at.ROWID
+		JOIN message m ON m.ROWID = maj.message_id
+		WHERE m.ROWID > ?
+		ORDER BY at.ROWID
+	`, lastRowID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	home, _ := os.UserHomeDir()
+	prefix := a.Name() + ":"
+	for rows.Next() {
+		var guid, messageGUID string
+		var filename, transferName, mimeType, uti sql.NullString
+		var size, createdDate sql.NullInt64
+		var isSticker bool
+		if err := rows.Scan(&guid, &filename, &transferName, &mimeType, &size, &isSticker, &uti, &createdDate, &messageGUID); err != nil {
+			return created, updated, fmt.Errorf("scan attachment: %w", err)
+		}
+		eventID := prefix + messageGUID
+		var exists int
+		_ = tx.QueryRow(`SELECT 1 FROM events WHERE id = ?`, eventID).Scan(&exists)
+		if exists == 0 {
+			continue // tapback or system item
+		}
+
+		name := transferName.String
+		storageURI := ""
+		if path := filename.String; path != "" {
+			if strings.HasPrefix(path, "~/") && home != "" {
+				path = filepath.Join(home, path[2:])
+			}
+			storageURI = "file://" + path
+			if name == "" {
+				name = filepath.Base(path)
+			}
+		}
+		metadata, _ := json.Marshal(map[string]any{"uti": uti.String, "is_sticker": isSticker})
+
+		attachmentID := prefix + guid
+		var existing int
+		_ = tx.QueryRow(`SELECT 1 FROM attachments WHERE id = ?`, attachmentID).Scan(&existing)
+		if _, err := tx.Exec(`
+			INSERT INTO attachments (
+				id, event_id, filename, mime_type, size_bytes,
+				media_type, storage_uri, storage_type, content_hash,
+				source_id, metadata_json, created_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, 'local', '', ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				filename = excluded.filename,
+				mime_type = excluded.mime_type,
+				size_bytes = excluded.size_bytes,
+				media_type = excluded.media_type,
+				storage_uri = excluded.storage_uri
+		`, attachmentID, eventID, name, mimeType.String, size.Int64, deriveMediaType(mimeType.String, isSticker),
+			storageURI, guid, string(metadata), appleTimeToUnix(createdDate.Int64)); err != nil {
+			return created, updated, fmt.Errorf("upsert attachment: %w", err)
+		}
+		if existing == 1 {
+			updated++
+		} else {
+			created++
+		}
+	}
+	return created, updated, rows.Err()
+}
+
+// appleTimeToUnix converts a Messages date to unix seconds. Dates are
+// nanoseconds since 2001 on macOS 10.13 and later, seconds before that.
+func appleTimeToUnix(t int64) int64 {
+	if t == 0 {
+		return 0
+	}
+	if t > 1e11 {
+		return t/int64(time.Second) + appleEpoch
+	}
+	return t + appleEpoch
+}
+
+// tapbackTarget strips the part prefix from a tapback's associated message
+// GUID: "p:0/<guid>" or "bp:<guid>" -> "<guid>".
+func tapbackTarget(guid string) string {
+	if i := strings.Index(guid, "/"); i >= 0 {
+		return guid[i+1:]
+	}
+	return strings.TrimPrefix(guid, "bp:")
+}
+
+func prefixIfSet(prefix, s string) string {
+	if s == "" {
+		return ""
+	}
+	return prefix + s
+}
+
+// messageText returns a message's text: the text column, or the string
+// decoded from attributedBody, which is all newer macOS versions keep.
+// Attachment placeholders (U+FFFC) are removed.
+func messageText(text string, attributedBody []byte) string {
+	if text == "" && len(attributedBody) > 0 {
+		text = decodeAttributedBody(attributedBody)
+	}
+	return strings.TrimSpace(strings.ReplaceAll(text, "￼", ""))
+}
+
+// decodeAttributedBody extracts the string from an attributedBody blob, a
+// typedstream-archived NSAttributedString. The string follows the first
+// NSString class name: a '+' type marker, then its length (one byte, or
+// 0x81 and a uint16, or 0x82 and a uint32, little-endian) and UTF-8 bytes.
+func decodeAttributedBody(b []byte) string {
+	i := bytes.Index(b, []byte("NSString"))
+	if i < 0 {
+		return ""
+	}
+	b = b[i+len("NSString"):]
+	j := bytes.IndexByte(b, '+')
+	if j < 0 || j > 8 || j+1 >= len(b) {
+		return ""
+	}
+	b = b[j+1:]
+	var n int
+	switch b[0] {
+	case 0x81:
+		if len(b) < 3 {
+			return ""
+		}
+		n, b = int(binary.LittleEndian.Uint16(b[1:3])), b[3:]
+	case 0x82:
+		if len(b) < 5 {
+			return ""
+		}
+		n, b = int(binary.LittleEndian.Uint32(b[1:5])), b[5:]
+	default:
+		n, b = int(b[0]), b[1:]
+	}
+	if n > len(b) {
+		return ""
+	}
+	return string(b[:n])
+}
+
+// tableColumns returns the column names of a table.
+func tableColumns(ctx context.Context, db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	if len(columns) == 0 && rows.Err() == nil {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+	return columns, rows.Err()
+}
+
+// addressBookName is a macOS Contacts card's display name.
+type addressBookName struct {
+	card string // database path and record key, to group a card's identifiers
+	name string
+}
+
+// addressBookNames reads card names from the macOS Contacts databases under
+// dir, keyed by "phone:<normalized>" or "email:<normalized>".
+func addressBookNames(ctx context.Context, dir string) (map[string]addressBookName, error) {
+	names := map[string]addressBookName{}
+	if dir == "" {
+		return names, nil
+	}
+	paths := []string{filepath.Join(dir, "AddressBook-v22.abcddb")}
+	sources, _ := filepath.Glob(filepath.Join(dir, "Sources", "*", "AddressBook-v22.abcddb"))
+	for _, path := range append(paths, sources...) {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := readAddressBookNames(ctx, path, names); err != nil {
+			return names, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return names, nil
+}
+
+func readAddressBookNames(ctx context.Context, path string, names map[string]addressBookName) error {
+	abDB, err := sql.Open("sqlite", "file:"+path+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
+		return err
+	}
+	defer abDB.Close()
+
+	rows, err := abDB.QueryContext(ctx, `
+		SELECT r.Z_PK, TRIM(COALESCE(r.ZFIRSTNAME, '') || ' ' || COALESCE(r.ZLASTNAME, '')), COALESCE(r.ZORGANIZATION, ''),
+		       'phone', p.ZFULLNUMBER
+		FROM ZABCDRECORD r JOIN ZABCDPHONENUMBER p ON p.ZOWNER = r.Z_PK
+		WHERE p.ZFULLNUMBER IS NOT NULL
+		UNION ALL
+		SELECT r.Z_PK, TRIM(COALESCE(r.ZFIRSTNAME, '') || ' ' || COALESCE(r.ZLASTNAME, '')), COALESCE(r.ZORGANIZATION, ''),
+		       'email', e.ZADDRESS
+		FROM ZABCDRECORD r JOIN ZABCDEMAILADDRESS e ON e.ZOWNER = r.Z_PK
+		WHERE e.ZADDRESS IS NOT NULL
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pk int64
+		var name, org, idType, value string
+		if err := rows.Scan(&pk, &name, &org, &idType, &value); err != nil {
+			return err
+		}
+		if name == "" {
+			name = org
+		}
+		if name == "" {
+			continue
+		}
+		key := idType + ":" + contacts.NormalizeIdentifier(value, idType)
+		if _, ok := names[key]; !ok {
+			names[key] = addressBookName{card: fmt.Sprintf("%s#%d", path, pk), name: name}
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,195 @@
+package adapters
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+// attributedBody builds a minimal typedstream blob holding s.
+func attributedBody(s string) []byte {
+	b := []byte("\x04\x0bstreamtyped\x81\xe8\x03\x84\x01@\x84\x84\x84\x12NSAttributedString\x00\x84\x84\x08NSObject\x00\x85\x92\x84\x84\x84\x08NSString\x01\x94\x84\x01+")
+	if len(s) < 0x80 {
+		b = append(b, byte(len(s)))
+	} else {
+		b = append(b, 0x81, byte(len(s)), byte(len(s)>>8))
+	}
+	return append(append(b, s...), 0x86)
+}
+
+func TestDecodeAttributedBody(t *testing.T) {
+	long := make([]byte, 300)
+	for i := range long {
+		long[i] = 'a'
+	}
+	for _, want := range []string{"hello", "see you at 5 🎉", string(long)} {
+		if got := decodeAttributedBody(attributedBody(want)); got != want {
+			t.Errorf("decoded %q, want %q", got, want)
+		}
+	}
+	if got := decodeAttributedBody([]byte("not a typedstream")); got != "" {
+		t.Errorf("garbage decoded to %q", got)
+	}
+	if got := messageText("", attributedBody("photo ￼")); got != "photo" {
+		t.Errorf("messageText = %q", got)
+	}
+}
+
+// newTestChatDB creates a chat.db with the tables and columns the adapter reads.
+func newTestChatDB(t *testing.T) (string, *sql.DB) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chat.db")
+	chatDB, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatalf("open chat.db: %v", err)
+	}
+	t.Cleanup(func() { _ = chatDB.Close() })
+	if _, err := chatDB.Exec(`
+		CREATE TABLE handle (ROWID INTEGER PRIMARY KEY, id TEXT);
+		CREATE TABLE chat (ROWID INTEGER PRIMARY KEY, chat_identifier TEXT, display_name TEXT, style INTEGER);
+		CREATE TABLE chat_handle_join (chat_id INTEGER, handle_id INTEGER);
+		CREATE TABLE message (
+			ROWID INTEGER PRIMARY KEY, guid TEXT, text TEXT, attributedBody BLOB, handle_id INTEGER,
+			date INTEGER, is_from_me INTEGER, item_type INTEGER DEFAULT 0, group_action_type INTEGER DEFAULT 0,
+			other_handle INTEGER DEFAULT 0, associated_message_guid TEXT, associated_message_type INTEGER DEFAULT 0,
+			cache_has_attachments INTEGER DEFAULT 0, thread_originator_guid TEXT,
+			date_edited INTEGER DEFAULT 0, date_retracted INTEGER DEFAULT 0
+		);
+		CREATE TABLE chat_message_join (chat_id INTEGER, message_id INTEGER);
+		CREATE TABLE attachment (
+			ROWID INTEGER PRIMARY KEY, guid TEXT, filename TEXT, transfer_name TEXT, mime_type TEXT,
+			total_bytes INTEGER, is_sticker INTEGER DEFAULT 0, uti TEXT, created_date INTEGER
+		);
+		CREATE TABLE message_attachment_join (message_id INTEGER, attachment_id INTEGER);
+
+		INSERT INTO handle VALUES (1, '+15550100'), (2, 'casey@example.com');
+		INSERT INTO chat VALUES (1, '+15550100', NULL, 45), (2, 'chat42', 'Trip', 43);
+		INSERT INTO chat_handle_join VALUES (1, 1), (2, 1), (2, 2);
+	`); err != nil {
+		t.Fatalf("create chat.db: %v", err)
+	}
+	return path, chatDB
+}
+
+func addChatMessage(t *testing.T, chatDB *sql.DB, chatID int64, columns string, values ...interface{}) {
+	t.Helper()
+	placeholders := "?"
+	for i := 1; i < len(values); i++ {
+		placeholders += ", ?"
+	}
+	res, err := chatDB.Exec(`INSERT INTO message (`+columns+`) VALUES (`+placeholders+`)`, values...)
+	if err != nil {
+		t.Fatalf("insert message: %v", err)
+	}
+	id, _ := res.LastInsertId()
+	if _, err := chatDB.Exec(`INSERT INTO chat_message_join VALUES (?, ?)`, chatID, id); err != nil {
+		t.Fatalf("join message: %v", err)
+	}
+}
+
+func TestIMessageAdapterSync(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	path, chatDB := newTestChatDB(t)
+
+	// 2024-01-01 in Apple-epoch nanoseconds
+	const day = int64(725760000) * 1e9
+	addChatMessage(t, chatDB, 1, "guid, text, handle_id, date, is_from_me", "g1", "dinner at 7?", 1, day, 0)
+	addChatMessage(t, chatDB, 1, "guid, attributedBody, handle_id, date, is_from_me, thread_originator_guid", "g2", attributedBody("works for me"), 1, day+1e9, 1, "g1")
+	addChatMessage(t, chatDB, 1, "guid, handle_id, date, is_from_me, associated_message_guid, associated_message_type", "g3", 1, day+2e9, 0, "p:0/g2", 2001)
+	addChatMessage(t, chatDB, 2, "guid, handle_id, date, is_from_me, item_type, group_action_type, other_handle", "g4", 0, day+3e9, 1, 1, 0, 2)
+	addChatMessage(t, chatDB, 2, "guid, text, handle_id, date, is_from_me, cache_has_attachments", "g5", "￼", 2, day+4e9, 0, 1)
+	addChatMessage(t, chatDB, 2, "guid, handle_id, date, is_from_me, item_type, group_action_type", "g6", 2, day+5e9, 0, 2, 0)
+	if _, err := chatDB.Exec(`
+		INSERT INTO attachment VALUES (1, 'att1', '~/Library/Messages/Attachments/ab/IMG_1.heic', 'IMG_1.heic', 'image/heic', 1024, 0, 'public.heic', 725760004);
+		INSERT INTO message_attachment_join VALUES (5, 1);
+	`); err != nil {
+		t.Fatalf("attachment: %v", err)
+	}
+
+	adapter, err := NewIMessageAdapter(path)
+	if err != nil {
+		t.Fatalf("NewIMessageAdapter: %v", err)
+	}
+	adapter.addressBookDir = ""
+	res, err := adapter.Sync(ctx, db, false)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if res.EventsCreated != 4 || res.ReactionsCreated != 1 || res.ThreadsCreated != 2 || res.AttachmentsCreated != 1 {
+		t.Fatalf("result = %+v", res)
+	}
+
+	var content, direction, threadID, replyTo string
+	var timestamp int64
+	if err := db.QueryRow(`SELECT content, direction, thread_id, reply_to, timestamp FROM events WHERE id = 'imessage:g2'`).Scan(&content, &direction, &threadID, &replyTo, &timestamp); err != nil {
+		t.Fatalf("query g2: %v", err)
+	}
+	if content != "works for me" || direction != "sent" || threadID != "imessage:+15550100" || replyTo != "imessage:g1" || timestamp != 1704067201 {
+		t.Errorf("g2 = %q %s %s %s %d", content, direction, threadID, replyTo, timestamp)
+	}
+	var reaction string
+	db.QueryRow(`SELECT content FROM events WHERE reply_to = 'imessage:g2' AND content_types = '["reaction"]'`).Scan(&reaction)
+	if reaction != mapReactionType(2001) {
+		t.Errorf("reaction = %q", reaction)
+	}
+	var action, member string
+	db.QueryRow(`
+		SELECT e.content, ci.normalized FROM events e
+		JOIN event_participants ep ON ep.event_id = e.id AND ep.role = 'member'
+		JOIN contact_identifiers ci ON ci.contact_id = ep.contact_id
+		WHERE e.id = 'imessage:g4'
+	`).Scan(&action, &member)
+	if action != "added" || member != "casey@example.com" {
+		t.Errorf("membership = %q %q", action, member)
+	}
+	var types, storage, media string
+	db.QueryRow(`SELECT e.content_types, a.storage_uri, a.media_type FROM events e JOIN attachments a ON a.event_id = e.id WHERE e.id = 'imessage:g5'`).Scan(&types, &storage, &media)
+	if types != `["attachment"]` || filepath.Base(storage) != "IMG_1.heic" || media != "image" {
+		t.Errorf("attachment = %s %s %s", types, storage, media)
+	}
+	var isGroup int
+	db.QueryRow(`SELECT is_group FROM threads WHERE id = 'imessage:chat42'`).Scan(&isGroup)
+	if isGroup != 1 {
+		t.Errorf("group chat is_group = %d", isGroup)
+	}
+	var recipients int
+	db.QueryRow(`SELECT COUNT(*) FROM event_participants WHERE event_id = 'imessage:g2' AND role = 'recipient'`).Scan(&recipients)
+	if recipients != 1 {
+		t.Errorf("sent message has %d recipients", recipients)
+	}
+
+	// Incremental sync: only new messages are read, plus edits since the last sync
+	var syncedAt int64
+	db.QueryRow(`SELECT CAST(value AS INTEGER) FROM adapter_state WHERE adapter = 'imessage' AND key = ?`, chatDBSyncedAtKey).Scan(&syncedAt)
+	if _, err := chatDB.Exec(`UPDATE message SET text = 'dinner at 8?', date_edited = ? WHERE guid = 'g1'`, syncedAt+1); err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	addChatMessage(t, chatDB, 1, "guid, text, handle_id, date, is_from_me", "g7", "see you then", 1, day+6e9, 0)
+	addChatMessage(t, chatDB, 1, "guid, handle_id, date, is_from_me, associated_message_guid, associated_message_type", "g8", 1, day+7e9, 0, "p:0/g2", 3001)
+	res, err = adapter.Sync(ctx, db, false)
+	if err != nil {
+		t.Fatalf("incremental Sync: %v", err)
+	}
+	if res.EventsCreated != 1 || res.EventsUpdated != 1 || res.Perf["reactions.removed"] != "1" {
+		t.Fatalf("incremental result = %+v", res)
+	}
+	var revision string
+	db.QueryRow(`SELECT content FROM events WHERE supersedes = 'imessage:g1'`).Scan(&revision)
+	if revision != "dinner at 8?" {
+		t.Errorf("edit revision = %q", revision)
+	}
+	var n int
+	db.QueryRow(`SELECT COUNT(*) FROM events WHERE content_types = '["reaction"]'`).Scan(&n)
+	if n != 0 {
+		t.Errorf("%d reactions left after the tapback was removed", n)
+	}
+
+	if res, err = adapter.Sync(ctx, db, false); err != nil || res.EventsCreated+res.EventsUpdated != 0 {
+		t.Errorf("repeat sync = %+v, %v", res, err)
+	}
+}
@@ -11,6 +11,9 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/Napageneral/mnemonic/internal/config"
+	"github.com/Napageneral/mnemonic/internal/errs"
 )
 
 type NexusAdapterOptions struct {
@@ -31,17 +34,16 @@ func NewNexusAdapter(opts NexusAdapterOptions) (*NexusAdapter, error) {
 	if eventsDir == "" {
 		stateDir := strings.TrimSpace(opts.StateDir)
 		if stateDir == "" {
-			home, err := os.UserHomeDir()
-			if err != nil {
-				return nil, fmt.Errorf("failed to get home directory: %w", err)
+			var err error
+			if stateDir, err = config.NexusStateDir(); err != nil {
+				return nil, err
 			}
-			stateDir = filepath.Join(home, "nexus", "state")
 		}
 		eventsDir = filepath.Join(stateDir, "events")
 	}
 
 	if _, err := os.Stat(eventsDir); err != nil {
-		return nil, fmt.Errorf("nexus events directory not found at %s: %w", eventsDir, err)
+		return nil, errs.New(errs.ErrAdapterSourceMissing, "nexus events directory not found at %s: %w", eventsDir, err)
 	}
 
 	return &NexusAdapter{
@@ -0,0 +1,294 @@
+package adapters
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/plugin"
+)
+
+// PluginAdapter syncs events from an external program speaking the plugin
+// protocol. The program gets the state it returned last time and streams
+// PluginEvent messages; everything it sends is written in one transaction,
+// together with its new state, so a failed run leaves nothing behind.
+type PluginAdapter struct {
+	name   string
+	plugin *plugin.Plugin
+}
+
+// PluginSyncParams are the params of an adapter plugin call.
+type PluginSyncParams struct {
+	Full  bool              `json:"full"`
+	State map[string]string `json:"state"`
+}
+
+// PluginSyncResult is an adapter plugin's result: state to send next time
+// (replaces the old state when set).
+type PluginSyncResult struct {
+	State map[string]string `json:"state,omitempty"`
+}
+
+// PluginEvent is an event sent by an adapter plugin. IDs are the plugin's
+// own; cortex prefixes them with the adapter name.
+type PluginEvent struct {
+	ID         string          `json:"id"`
+	Timestamp  int64           `json:"timestamp"`         // unix seconds
+	Channel    string          `json:"channel,omitempty"` // default: the adapter name
+	Direction  string          `json:"direction,omitempty"`
+	Content    string          `json:"content"`
+	ThreadID   string          `json:"thread_id,omitempty"`
+	ThreadName string          `json:"thread_name,omitempty"`
+	IsGroup    bool            `json:"is_group,omitempty"`
+	ReplyTo    string          `json:"reply_to,omitempty"` // ID of the event replied to
+	Sender     *PluginContact  `json:"sender,omitempty"`
+	Recipients []PluginContact `json:"recipients,omitempty"`
+}
+
+// PluginContact is an event participant.
+type PluginContact struct {
+	Identifier string `json:"identifier"`
+	Type       string `json:"type,omitempty"` // email, phone, ...; guessed when empty
+	Name       string `json:"name,omitempty"`
+}
+
+var pluginDirections = map[string]bool{"sent": true, "received": true, "observed": true}
+
+// NewPluginAdapter creates an adapter backed by an adapter plugin.
+func NewPluginAdapter(name string, p *plugin.Plugin) (*PluginAdapter, error) {
+	if p.Kind != plugin.KindAdapter {
+		return nil, fmt.Errorf("plugin %s is of kind %s, not %s", p.Name, p.Kind, plugin.KindAdapter)
+	}
+	return &PluginAdapter{name: name, plugin: p}, nil
+}
+
+func (a *PluginAdapter) Name() string {
+	return a.name
+}
+
+func (a *PluginAdapter) Sync(ctx context.Context, cortexDB *sql.DB, full bool) (SyncResult, error) {
+	start := time.Now()
+	var result SyncResult
+
+	state, err := a.loadState(cortexDB)
+	if err != nil {
+		return result, err
+	}
+
+	tx, err := cortexDB.BeginTx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("begin cortex tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	threads := map[string]bool{}
+	raw, err := a.plugin.Call(ctx, PluginSyncParams{Full: full, State: state}, func(msg json.RawMessage) error {
+		var ev PluginEvent
+		if err := json.Unmarshal(msg, &ev); err != nil {
+			return fmt.Errorf("plugin %s: invalid event: %w", a.plugin.Name, err)
+		}
+		if err := writeExternalEvent(tx, a.name, ev, threads, &result); err != nil {
+			return fmt.Errorf("plugin %s: %w", a.plugin.Name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	var res PluginSyncResult
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return result, fmt.Errorf("plugin %s: invalid result: %w", a.plugin.Name, err)
+	}
+	if res.State != nil {
+		if err := a.saveState(tx, res.State); err != nil {
+			return result, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("commit cortex tx: %w", err)
+	}
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// IngestEvents stores events pushed to cortex (over the API, or from an
+// import file) under the adapter name source, in one transaction.
+func IngestEvents(ctx context.Context, cortexDB *sql.DB, source string, events []PluginEvent) (SyncResult, error) {
+	start := time.Now()
+	var result SyncResult
+	source = strings.TrimSpace(source)
+	if source == "" || strings.ContainsAny(source, ": /") {
+		return result, fmt.Errorf("invalid source %q (a name without spaces, colons or slashes)", source)
+	}
+
+	tx, err := cortexDB.BeginTx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("begin cortex tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	threads := map[string]bool{}
+	for i, ev := range events {
+		if err := writeExternalEvent(tx, source, ev, threads, &result); err != nil {
+			return SyncResult{}, fmt.Errorf("event %d: %w", i+1, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return SyncResult{}, fmt.Errorf("commit cortex tx: %w", err)
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// writeExternalEvent stores one event from a source outside cortex (an
+// adapter plugin, a generic file) with its thread and participants. IDs
+// are the source's own, prefixed with the adapter name; threads records
+// the threads already counted in result.
+func writeExternalEvent(tx *sql.Tx, adapter string, ev PluginEvent, threads map[string]bool, result *SyncResult) error {
+	ev.ID = strings.TrimSpace(ev.ID)
+	if ev.ID == "" || ev.Timestamp <= 0 {
+		return fmt.Errorf("event needs an id and a timestamp")
+	}
+	channel := strings.TrimSpace(ev.Channel)
+	if channel == "" {
+		channel = adapter
+	}
+	direction := ev.Direction
+	if direction == "" {
+		direction = "received"
+	}
+	if !pluginDirections[direction] {
+		return fmt.Errorf("event %s has invalid direction %q (sent, received or observed)", ev.ID, ev.Direction)
+	}
+
+	prefix := adapter + ":"
+	var threadID, replyTo interface{}
+	if ev.ThreadID != "" {
+		threadID = prefix + ev.ThreadID
+		if !threads[ev.ThreadID] {
+			var exists int
+			err := tx.QueryRow(`SELECT 1 FROM threads WHERE source_adapter = ? AND source_id = ?`, adapter, ev.ThreadID).Scan(&exists)
+			if err != nil && err != sql.ErrNoRows {
+				return fmt.Errorf("lookup thread: %w", err)
+			}
+			if err == sql.ErrNoRows {
+				result.ThreadsCreated++
+			} else {
+				result.ThreadsUpdated++
+			}
+			threads[ev.ThreadID] = true
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO threads (id, channel, name, is_group, source_adapter, source_id, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(source_adapter, source_id) DO UPDATE SET
+				name = COALESCE(excluded.name, threads.name),
+				is_group = MAX(threads.is_group, excluded.is_group),
+				created_at = MIN(threads.created_at, excluded.created_at),
+				updated_at = MAX(threads.updated_at, excluded.updated_at)
+		`, threadID, channel, nullIfEmpty(strings.TrimSpace(ev.ThreadName)), ev.IsGroup, adapter, ev.ThreadID, ev.Timestamp, ev.Timestamp); err != nil {
+			return fmt.Errorf("upsert thread: %w", err)
+		}
+	}
+	if ev.ReplyTo != "" {
+		replyTo = prefix + ev.ReplyTo
+	}
+
+	eventID := prefix + ev.ID
+	res, err := tx.Exec(`
+		INSERT OR IGNORE INTO events (
+			id, timestamp, channel, content_types, content,
+			direction, thread_id, reply_to, source_adapter, source_id
+		) VALUES (?, ?, ?, '["text"]', ?, ?, ?, ?, ?, ?)
+	`, eventID, ev.Timestamp, channel, ev.Content, direction, threadID, replyTo, adapter, ev.ID)
+	if err != nil {
+		return fmt.Errorf("insert event: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		result.EventsCreated++
+	} else {
+		res, err := tx.Exec(`
+			UPDATE events SET timestamp = ?, content = ?, direction = ?, thread_id = ?, reply_to = ?
+			WHERE source_adapter = ? AND source_id = ?
+			  AND (timestamp IS NOT ? OR content IS NOT ? OR direction IS NOT ? OR thread_id IS NOT ? OR reply_to IS NOT ?)
+		`, ev.Timestamp, ev.Content, direction, threadID, replyTo, adapter, ev.ID,
+			ev.Timestamp, ev.Content, direction, threadID, replyTo)
+		if err != nil {
+			return fmt.Errorf("update event: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			result.EventsUpdated++
+		}
+	}
+
+	participants := make([]PluginContact, 0, len(ev.Recipients)+1)
+	roles := make([]string, 0, len(ev.Recipients)+1)
+	if ev.Sender != nil {
+		participants = append(participants, *ev.Sender)
+		roles = append(roles, "sender")
+	}
+	for _, r := range ev.Recipients {
+		participants = append(participants, r)
+		roles = append(roles, "recipient")
+	}
+	for i, c := range participants {
+		identifier := strings.TrimSpace(c.Identifier)
+		if identifier == "" {
+			continue
+		}
+		idType := c.Type
+		if idType == "" {
+			idType = contacts.GuessIdentifierType(identifier)
+		}
+		if idType == "" {
+			idType = "handle"
+		}
+		contactID, created, err := contacts.GetOrCreateContact(tx, idType, identifier, c.Name, adapter)
+		if err != nil {
+			return fmt.Errorf("contact %s: %w", identifier, err)
+		}
+		if created {
+			result.PersonsCreated++
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO event_participants (event_id, contact_id, role) VALUES (?, ?, ?)`, eventID, contactID, roles[i]); err != nil {
+			return fmt.Errorf("insert participant: %w", err)
+		}
+	}
+	return nil
+}
+
+func (a *PluginAdapter) loadState(cortexDB *sql.DB) (map[string]string, error) {
+	rows, err := cortexDB.Query(`SELECT key, value FROM adapter_state WHERE adapter = ?`, a.name)
+	if err != nil {
+		return nil, fmt.Errorf("load adapter state: %w", err)
+	}
+	defer rows.Close()
+	state := map[string]string{}
+	for rows.Next() {
+		var k, v string
+		if err := rows.Scan(&k, &v); err != nil {
+			return nil, fmt.Errorf("scan adapter state: %w", err)
+		}
+		state[k] = v
+	}
+	return state, rows.Err()
+}
+
+func (a *PluginAdapter) saveState(tx *sql.Tx, state map[string]string) error {
+	if _, err := tx.Exec(`DELETE FROM adapter_state WHERE adapter = ?`, a.name); err != nil {
+		return fmt.Errorf("clear adapter state: %w", err)
+	}
+	now := time.Now().Unix()
+	for k, v := range state {
+		if _, err := tx.Exec(`INSERT INTO adapter_state (adapter, key, value, updated_at) VALUES (?, ?, ?, ?)`, a.name, k, v, now); err != nil {
+			return fmt.Errorf("save adapter state: %w", err)
+		}
+	}
+	return nil
+}
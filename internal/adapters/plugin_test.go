@@ -0,0 +1,84 @@
+package adapters
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/plugin"
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestPluginAdapterSync(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	// Sends two messages on the first run, then only what came after the cursor
+	p := &plugin.Plugin{Name: "forum", Kind: plugin.KindAdapter, Command: "sh", Args: []string{"-c", `
+		read req
+		case "$req" in
+		*'"cursor":"2"'*)
+			echo '{"event":{"id":"m3","timestamp":1700000200,"content":"see you","thread_id":"t1","direction":"sent","sender":{"identifier":"me@example.com"}}}'
+			echo '{"result":{"state":{"cursor":"3"}}}' ;;
+		*)
+			echo '{"event":{"id":"m1","timestamp":1700000000,"content":"hi all","thread_id":"t1","thread_name":"Board","is_group":true,"sender":{"identifier":"casey@example.com","name":"Casey Lee"},"recipients":[{"identifier":"+15550100"},{"identifier":"me@example.com"}]}}'
+			echo '{"event":{"id":"m2","timestamp":1700000100,"content":"hello","thread_id":"t1","reply_to":"m1","sender":{"identifier":"dana","type":"handle"}}}'
+			echo '{"result":{"state":{"cursor":"2"}}}' ;;
+		esac
+	`}}
+	adapter, err := NewPluginAdapter("forum", p)
+	if err != nil {
+		t.Fatalf("NewPluginAdapter: %v", err)
+	}
+	res, err := adapter.Sync(ctx, db, false)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if res.EventsCreated != 2 || res.ThreadsCreated != 1 || res.PersonsCreated != 4 {
+		t.Fatalf("result = %+v", res)
+	}
+
+	var channel, threadID, replyTo, direction string
+	if err := db.QueryRow(`SELECT channel, thread_id, reply_to, direction FROM events WHERE id = 'forum:m2'`).Scan(&channel, &threadID, &replyTo, &direction); err != nil {
+		t.Fatalf("query m2: %v", err)
+	}
+	if channel != "forum" || threadID != "forum:t1" || replyTo != "forum:m1" || direction != "received" {
+		t.Errorf("m2 = %s %s %s %s", channel, threadID, replyTo, direction)
+	}
+	var name string
+	var isGroup int
+	db.QueryRow(`SELECT name, is_group FROM threads WHERE id = 'forum:t1'`).Scan(&name, &isGroup)
+	if name != "Board" || isGroup != 1 {
+		t.Errorf("thread = %q %d", name, isGroup)
+	}
+	var sender string
+	db.QueryRow(`
+		SELECT c.display_name FROM event_participants ep JOIN contacts c ON c.id = ep.contact_id
+		WHERE ep.event_id = 'forum:m1' AND ep.role = 'sender'
+	`).Scan(&sender)
+	if sender != "Casey Lee" {
+		t.Errorf("sender = %q", sender)
+	}
+
+	res, err = adapter.Sync(ctx, db, false)
+	if err != nil || res.EventsCreated != 1 || res.ThreadsUpdated != 1 {
+		t.Fatalf("incremental Sync = %+v, %v", res, err)
+	}
+	var cursor string
+	db.QueryRow(`SELECT value FROM adapter_state WHERE adapter = 'forum' AND key = 'cursor'`).Scan(&cursor)
+	if cursor != "3" {
+		t.Errorf("cursor = %q", cursor)
+	}
+
+	// A failing plugin leaves no partial writes
+	p.Args = []string{"-c", `echo '{"event":{"id":"m9","timestamp":1700000900,"content":"x"}}'; echo '{"error":"rate limited"}'`}
+	if _, err := adapter.Sync(ctx, db, false); err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Fatalf("failing plugin: %v", err)
+	}
+	var n int
+	db.QueryRow(`SELECT COUNT(*) FROM events WHERE source_adapter = 'forum'`).Scan(&n)
+	if n != 3 {
+		t.Errorf("%d events after a failed sync, want 3", n)
+	}
+}
@@ -0,0 +1,99 @@
+package adapters
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Edits and unsends are recorded as new events that supersede the original
+// rather than by rewriting it, so what was first said stays in the event
+// store and episodes already built from it stay reproducible:
+//
+//   - a revision copies the original's thread, direction and participants
+//     with the edited content;
+//   - a tombstone has direction "deleted", content type "tombstone" and no
+//     content.
+//
+// Both point at the original through events.supersedes and are skipped by
+// the chunkers; episode encoding shows the newest revision in place of the
+// original, marked "(edited)" or "(unsent)".
+
+// Revision kinds returned by recordContentChange.
+const (
+	revisionEdited   = "edited"
+	revisionUnsent   = "unsent"
+	contentTypesTomb = `["tombstone"]`
+)
+
+// recordContentChange compares the content an adapter now reports for an
+// existing event with the event's current content (its newest revision, if
+// any) and records an edit or, when unsent is true, a tombstone. observedAt
+// timestamps the new event, since sources rarely say when an edit happened.
+// Returns the kind recorded, or "" when nothing changed.
+func recordContentChange(tx *sql.Tx, eventID, content, contentTypes string, unsent bool, observedAt int64) (string, error) {
+	var current sql.NullString
+	var currentDirection string
+	var revisions int
+	err := tx.QueryRow(`
+		SELECT
+			COALESCE((SELECT r.content FROM events r WHERE r.supersedes = e.id ORDER BY r.timestamp DESC, r.id DESC LIMIT 1), e.content),
+			COALESCE((SELECT r.direction FROM events r WHERE r.supersedes = e.id ORDER BY r.timestamp DESC, r.id DESC LIMIT 1), e.direction),
+			(SELECT COUNT(*) FROM events r WHERE r.supersedes = e.id)
+		FROM events e WHERE e.id = ?
+	`, eventID).Scan(&current, &currentDirection, &revisions)
+	if err != nil {
+		return "", fmt.Errorf("load event %s: %w", eventID, err)
+	}
+	if currentDirection == "deleted" {
+		// Nothing follows an unsend
+		return "", nil
+	}
+
+	if unsent {
+		if _, err := tx.Exec(`
+			INSERT OR IGNORE INTO events (
+				id, timestamp, channel, content_types, content,
+				direction, thread_id, source_adapter, source_id, supersedes
+			)
+			SELECT id || ':tombstone', ?, channel, ?, '', 'deleted', thread_id,
+			       source_adapter, source_id || ':tombstone', id
+			FROM events WHERE id = ?
+		`, observedAt, contentTypesTomb, eventID); err != nil {
+			return "", fmt.Errorf("insert tombstone: %w", err)
+		}
+		if err := copyParticipants(tx, eventID, eventID+":tombstone"); err != nil {
+			return "", err
+		}
+		return revisionUnsent, nil
+	}
+
+	if content == current.String {
+		return "", nil
+	}
+	suffix := fmt.Sprintf(":rev:%d", revisions+1)
+	if _, err := tx.Exec(`
+		INSERT OR IGNORE INTO events (
+			id, timestamp, channel, content_types, content,
+			direction, thread_id, reply_to, source_adapter, source_id, supersedes
+		)
+		SELECT id || ?, ?, channel, ?, ?, direction, thread_id, reply_to,
+		       source_adapter, source_id || ?, id
+		FROM events WHERE id = ?
+	`, suffix, observedAt, contentTypes, content, suffix, eventID); err != nil {
+		return "", fmt.Errorf("insert revision: %w", err)
+	}
+	if err := copyParticipants(tx, eventID, eventID+suffix); err != nil {
+		return "", err
+	}
+	return revisionEdited, nil
+}
+
+func copyParticipants(tx *sql.Tx, fromEventID, toEventID string) error {
+	if _, err := tx.Exec(`
+		INSERT OR IGNORE INTO event_participants (event_id, contact_id, role)
+		SELECT ?, contact_id, role FROM event_participants WHERE event_id = ?
+	`, toEventID, fromEventID); err != nil {
+		return fmt.Errorf("copy participants: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestRecordContentChange(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec(`
+		INSERT INTO events (id, timestamp, channel, content_types, content, direction, thread_id, source_adapter, source_id)
+		VALUES ('imessage:g1', 100, 'imessage', '["text"]', 'see you at 5', 'received', 'imessage:chat1', 'imessage', 'g1');
+		INSERT INTO contacts (id, display_name, created_at, updated_at) VALUES ('c1', 'Casey', 0, 0);
+		INSERT INTO event_participants (event_id, contact_id, role) VALUES ('imessage:g1', 'c1', 'sender');
+	`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	record := func(content string, unsent bool) string {
+		t.Helper()
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("begin: %v", err)
+		}
+		defer tx.Rollback()
+		kind, err := recordContentChange(tx, "imessage:g1", content, `["text"]`, unsent, 200)
+		if err != nil {
+			t.Fatalf("recordContentChange: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("commit: %v", err)
+		}
+		return kind
+	}
+
+	if kind := record("see you at 5", false); kind != "" {
+		t.Fatalf("unchanged content recorded %q", kind)
+	}
+	if kind := record("see you at 6", false); kind != revisionEdited {
+		t.Fatalf("edit recorded %q, want %q", kind, revisionEdited)
+	}
+	if kind := record("see you at 6", false); kind != "" {
+		t.Fatalf("repeated edit recorded %q", kind)
+	}
+	if kind := record("see you at 7", false); kind != revisionEdited {
+		t.Fatalf("second edit recorded %q", kind)
+	}
+
+	var original, rev2, sender string
+	if err := db.QueryRow(`SELECT content FROM events WHERE id = 'imessage:g1'`).Scan(&original); err != nil {
+		t.Fatalf("query original: %v", err)
+	}
+	if original != "see you at 5" {
+		t.Errorf("original content = %q, want it kept", original)
+	}
+	if err := db.QueryRow(`SELECT content FROM events WHERE id = 'imessage:g1:rev:2' AND supersedes = 'imessage:g1'`).Scan(&rev2); err != nil {
+		t.Fatalf("query revision: %v", err)
+	}
+	if rev2 != "see you at 7" {
+		t.Errorf("revision content = %q", rev2)
+	}
+	if err := db.QueryRow(`SELECT contact_id FROM event_participants WHERE event_id = 'imessage:g1:rev:2' AND role = 'sender'`).Scan(&sender); err != nil {
+		t.Fatalf("revision sender: %v", err)
+	}
+
+	if kind := record("", true); kind != revisionUnsent {
+		t.Fatalf("unsend recorded %q, want %q", kind, revisionUnsent)
+	}
+	var direction, types string
+	if err := db.QueryRow(`SELECT direction, content_types FROM events WHERE id = 'imessage:g1:tombstone'`).Scan(&direction, &types); err != nil {
+		t.Fatalf("query tombstone: %v", err)
+	}
+	if direction != "deleted" || types != contentTypesTomb {
+		t.Errorf("tombstone = %s %s", direction, types)
+	}
+	if kind := record("back again", false); kind != "" {
+		t.Fatalf("change after unsend recorded %q", kind)
+	}
+}
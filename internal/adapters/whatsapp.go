@@ -0,0 +1,682 @@
+package adapters
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/errs"
+	"github.com/Napageneral/mnemonic/internal/me"
+)
+
+// WhatsAppAdapterOptions configures the WhatsApp export adapter.
+type WhatsAppAdapterOptions struct {
+	// Path is an exported chat (.txt or .zip) or a directory of exports
+	Path string
+	// Me is your name as it appears in the exports (default: the "me"
+	// person's name, or the other sender of a one-to-one chat)
+	Me string
+	// Timezone the exporting phone was in (default: local time)
+	Timezone string
+	// DateOrder is "dmy" or "mdy" for exports whose dates don't say
+	// (default: mdy for 12-hour exports, dmy otherwise)
+	DateOrder string
+}
+
+// WhatsAppAdapter imports chats saved with WhatsApp's "Export chat", with
+// or without media, from iOS and Android. Each chat becomes a thread and
+// each message an event from its sender; media shows up as an attachment,
+// with its file when the export includes it. System notices (encryption,
+// group changes) are skipped. Event IDs are derived from the message
+// itself, so re-importing a later export of the same chat only adds what
+// is new.
+type WhatsAppAdapter struct {
+	path      string
+	me        string
+	loc       *time.Location
+	dateOrder string
+}
+
+// NewWhatsAppAdapter creates an adapter for WhatsApp chat exports.
+func NewWhatsAppAdapter(opts WhatsAppAdapterOptions) (*WhatsAppAdapter, error) {
+	path := strings.TrimSpace(opts.Path)
+	if path == "" {
+		return nil, fmt.Errorf("WhatsApp export path is required")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, errs.New(errs.ErrAdapterSourceMissing, "WhatsApp export not found at %s: %w", path, err)
+	}
+	loc := time.Local
+	if opts.Timezone != "" {
+		l, err := time.LoadLocation(opts.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", opts.Timezone, err)
+		}
+		loc = l
+	}
+	switch opts.DateOrder {
+	case "", "dmy", "mdy":
+	default:
+		return nil, fmt.Errorf("invalid date order %q (dmy or mdy)", opts.DateOrder)
+	}
+	return &WhatsAppAdapter{path: path, me: strings.TrimSpace(opts.Me), loc: loc, dateOrder: opts.DateOrder}, nil
+}
+
+func (a *WhatsAppAdapter) Name() string {
+	return "whatsapp"
+}
+
+// whatsappChat is one parsed export.
+type whatsappChat struct {
+	name     string
+	path     string
+	mediaDir string // directory holding exported media ("" for zips)
+	messages []whatsappMessage
+}
+
+type whatsappMessage struct {
+	timestamp int64
+	sender    string
+	text      string
+	media     *whatsappMedia
+}
+
+type whatsappMedia struct {
+	kind     string // image, video, audio, sticker, document, contact, or media when unknown
+	filename string // "" when the media was omitted from the export
+}
+
+func (a *WhatsAppAdapter) Sync(ctx context.Context, cortexDB *sql.DB, full bool) (SyncResult, error) {
+	start := time.Now()
+	result := SyncResult{Perf: map[string]string{}}
+
+	files, err := whatsappExportFiles(a.path)
+	if err != nil {
+		return result, err
+	}
+
+	meName := a.me
+	if meName == "" {
+		if person, err := me.GetMePerson(cortexDB); err == nil && person != nil {
+			meName = person.CanonicalName
+		}
+	}
+
+	tx, err := cortexDB.BeginTx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("begin cortex tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	meContactID, meCreated, err := meContact(tx, a.Name())
+	if err != nil {
+		return result, err
+	}
+	result.PersonsCreated += meCreated
+
+	skipped := 0
+	for _, file := range files {
+		stamp, err := fileStamp(file)
+		if err != nil {
+			return result, err
+		}
+		stateKey := "export:" + file
+		if !full {
+			var seen string
+			_ = tx.QueryRow(`SELECT value FROM adapter_state WHERE adapter = ? AND key = ?`, a.Name(), stateKey).Scan(&seen)
+			if seen == stamp {
+				skipped++
+				continue
+			}
+		}
+
+		chat, err := a.readExport(file)
+		if err != nil {
+			return result, err
+		}
+		if err := a.writeChat(tx, chat, meName, meContactID, &result); err != nil {
+			return result, fmt.Errorf("%s: %w", file, err)
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO adapter_state (adapter, key, value, updated_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT(adapter, key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+		`, a.Name(), stateKey, stamp, time.Now().Unix()); err != nil {
+			return result, fmt.Errorf("save export state: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("commit cortex tx: %w", err)
+	}
+	result.Perf["exports"] = strconv.Itoa(len(files))
+	result.Perf["exports.unchanged"] = strconv.Itoa(skipped)
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// whatsappExportFiles lists the exports under path: .zip files and chat
+// .txt files.
+func whatsappExportFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		abs, _ := filepath.Abs(path)
+		return []string{abs}, nil
+	}
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".zip", ".txt":
+			abs, _ := filepath.Abs(p)
+			files = append(files, abs)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list WhatsApp exports: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// fileStamp identifies a file version by size and modification time.
+func fileStamp(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("stat %s: %w", path, err)
+	}
+	return fmt.Sprintf("%d:%d", info.Size(), info.ModTime().Unix()), nil
+}
+
+// readExport parses a .txt export or the chat inside a .zip export.
+func (a *WhatsAppAdapter) readExport(path string) (*whatsappChat, error) {
+	chat := &whatsappChat{path: path}
+	var lines []string
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", path, err)
+		}
+		defer zr.Close()
+		var chatFile *zip.File
+		for _, f := range zr.File {
+			if strings.EqualFold(filepath.Ext(f.Name), ".txt") && (chatFile == nil || filepath.Base(f.Name) == "_chat.txt") {
+				chatFile = f
+			}
+		}
+		if chatFile == nil {
+			return nil, fmt.Errorf("%s has no chat text file", path)
+		}
+		rc, err := chatFile.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open %s in %s: %w", chatFile.Name, path, err)
+		}
+		lines, err = readLines(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		chat.name = whatsappChatName(path)
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", path, err)
+		}
+		lines, err = readLines(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		chat.mediaDir = filepath.Dir(path)
+		chat.name = whatsappChatName(path)
+		if filepath.Base(path) == "_chat.txt" {
+			chat.name = whatsappChatName(filepath.Dir(path))
+		}
+	}
+	chat.messages = parseWhatsAppLines(lines, a.dateOrder, a.loc)
+	return chat, nil
+}
+
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// whatsappChatName takes the chat name from an export's file name
+// ("WhatsApp Chat with Casey.txt", "WhatsApp Chat - Trip.zip").
+func whatsappChatName(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for _, prefix := range []string{"WhatsApp Chat with ", "WhatsApp Chat - ", "WhatsApp Chat "} {
+		if strings.HasPrefix(name, prefix) {
+			return strings.TrimSpace(strings.TrimPrefix(name, prefix))
+		}
+	}
+	return strings.TrimSpace(name)
+}
+
+// whatsappLine matches the start of a message in iOS ("[31/12/2023,
+// 21:05:12] Name: text") and Android ("12/31/23, 9:05 PM - Name: text")
+// exports.
+var whatsappLine = regexp.MustCompile(`^\[?(\d{1,4})[./-](\d{1,2})[./-](\d{1,4}),?\s+(\d{1,2})[:.](\d{2})(?:[:.](\d{2}))?\s*([AaPp]\.?\s?[Mm]\.?)?\]?\s+(?:-\s+)?(.*)$`)
+
+var whatsappEdited = regexp.MustCompile(`\s*<This message was edited>$`)
+
+// whatsappMarks are invisible characters WhatsApp puts around system text
+// and attachments, and the spaces iOS uses around times.
+var whatsappMarks = strings.NewReplacer("\u200e", "", "\u200f", "", "\ufeff", "", "\u202f", " ", "\u00a0", " ")
+
+// parseWhatsAppLines turns export lines into messages. Lines that don't
+// start a message continue the previous one.
+func parseWhatsAppLines(lines []string, dateOrder string, loc *time.Location) []whatsappMessage {
+	if dateOrder == "" {
+		dateOrder = detectWhatsAppDateOrder(lines)
+	}
+
+	var messages []whatsappMessage
+	var current *whatsappMessage
+	var body []string
+	system := false
+	flush := func() {
+		if current != nil && !system {
+			finishWhatsAppMessage(current, body)
+			messages = append(messages, *current)
+		}
+		current, body, system = nil, nil, false
+	}
+	for _, raw := range lines {
+		line := strings.TrimLeft(whatsappMarks.Replace(raw), " ")
+		m := whatsappLine.FindStringSubmatch(line)
+		if m == nil {
+			if current != nil {
+				body = append(body, whatsappMarks.Replace(raw))
+			}
+			continue
+		}
+		ts, ok := whatsappTimestamp(m, dateOrder, loc)
+		if !ok {
+			if current != nil {
+				body = append(body, whatsappMarks.Replace(raw))
+			}
+			continue
+		}
+		flush()
+
+		rest := m[8]
+		sender, text := "", rest
+		if i := strings.Index(rest, ": "); i > 0 {
+			sender, text = strings.TrimSpace(rest[:i]), rest[i+2:]
+		}
+		current = &whatsappMessage{timestamp: ts, sender: sender}
+		body = []string{text}
+		// System notices have no sender, or (iOS) text that starts with a
+		// left-to-right mark and isn't an attachment
+		system = sender == "" || (whatsappSystemMarked(raw, sender) && parseWhatsAppMedia(text) == nil)
+	}
+	flush()
+	return messages
+}
+
+// whatsappSystemMarked reports whether the text after "sender: " in the raw
+// line starts with a left-to-right mark.
+func whatsappSystemMarked(raw, sender string) bool {
+	i := strings.Index(raw, sender+": ")
+	return i >= 0 && strings.HasPrefix(raw[i+len(sender)+2:], "\u200e")
+}
+
+func finishWhatsAppMessage(m *whatsappMessage, body []string) {
+	if len(body) > 0 {
+		if media := parseWhatsAppMedia(body[0]); media != nil {
+			m.media = media
+			body = body[1:]
+		}
+	}
+	text := strings.TrimSpace(strings.Join(body, "\n"))
+	m.text = whatsappEdited.ReplaceAllString(text, "")
+}
+
+// detectWhatsAppDateOrder tells day-first from month-first dates by a
+// day above 12, falling back to month-first for 12-hour exports.
+func detectWhatsAppDateOrder(lines []string) string {
+	twelveHour := false
+	for _, raw := range lines {
+		m := whatsappLine.FindStringSubmatch(strings.TrimLeft(whatsappMarks.Replace(raw), " "))
+		if m == nil || len(m[1]) == 4 {
+			continue
+		}
+		first, _ := strconv.Atoi(m[1])
+		second, _ := strconv.Atoi(m[2])
+		if first > 12 {
+			return "dmy"
+		}
+		if second > 12 {
+			return "mdy"
+		}
+		if m[7] != "" {
+			twelveHour = true
+		}
+	}
+	if twelveHour {
+		return "mdy"
+	}
+	return "dmy"
+}
+
+func whatsappTimestamp(m []string, dateOrder string, loc *time.Location) (int64, bool) {
+	n := func(s string) int { v, _ := strconv.Atoi(s); return v }
+	var year, month, day int
+	switch {
+	case len(m[1]) == 4:
+		year, month, day = n(m[1]), n(m[2]), n(m[3])
+	case dateOrder == "mdy":
+		month, day, year = n(m[1]), n(m[2]), n(m[3])
+	default:
+		day, month, year = n(m[1]), n(m[2]), n(m[3])
+	}
+	if year < 100 {
+		year += 2000
+	}
+	hour, min, sec := n(m[4]), n(m[5]), n(m[6])
+	if ampm := strings.ToLower(strings.NewReplacer(".", "", " ", "").Replace(m[7])); ampm != "" {
+		if hour < 1 || hour > 12 {
+			return 0, false
+		}
+		hour %= 12
+		if ampm == "pm" {
+			hour += 12
+		}
+	}
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || min > 59 || sec > 59 {
+		return 0, false
+	}
+	return time.Date(year, time.Month(month), day, hour, min, sec, 0, loc).Unix(), true
+}
+
+var (
+	whatsappAttached = regexp.MustCompile(`^<attached: (.+)>$`)
+	whatsappFile     = regexp.MustCompile(`^(.+\.[A-Za-z0-9]+) \(file attached\)$`)
+	whatsappOmitted  = map[string]string{
+		"image omitted":        "image",
+		"video omitted":        "video",
+		"audio omitted":        "audio",
+		"sticker omitted":      "sticker",
+		"gif omitted":          "image",
+		"contact card omitted": "contact",
+		"<media omitted>":      "media",
+	}
+)
+
+// parseWhatsAppMedia recognizes an attachment line: a file included in the
+// export or a placeholder for media left out of it.
+func parseWhatsAppMedia(text string) *whatsappMedia {
+	text = strings.TrimSpace(whatsappMarks.Replace(text))
+	if m := whatsappAttached.FindStringSubmatch(text); m != nil {
+		return &whatsappMedia{kind: whatsappMediaKind(m[1]), filename: m[1]}
+	}
+	if m := whatsappFile.FindStringSubmatch(text); m != nil {
+		return &whatsappMedia{kind: whatsappMediaKind(m[1]), filename: m[1]}
+	}
+	lower := strings.ToLower(text)
+	if kind, ok := whatsappOmitted[lower]; ok {
+		return &whatsappMedia{kind: kind}
+	}
+	if strings.HasSuffix(lower, "document omitted") {
+		return &whatsappMedia{kind: "document"}
+	}
+	return nil
+}
+
+// whatsappMediaKind guesses the media type of an exported file from the
+// kind iOS puts in its name (00000012-PHOTO-...) or its extension.
+func whatsappMediaKind(filename string) string {
+	upper := strings.ToUpper(filename)
+	for marker, kind := range map[string]string{"-PHOTO-": "image", "-VIDEO-": "video", "-AUDIO-": "audio", "-STICKER-": "sticker", "-GIF-": "image"} {
+		if strings.Contains(upper, marker) {
+			return kind
+		}
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".webp":
+		return "sticker"
+	case ".jpg", ".jpeg", ".png", ".heic", ".gif":
+		return "image"
+	case ".mp4", ".mov", ".3gp":
+		return "video"
+	case ".opus", ".ogg", ".m4a", ".mp3", ".aac":
+		return "audio"
+	case ".vcf":
+		return "contact"
+	default:
+		return "document"
+	}
+}
+
+// writeChat stores a parsed chat: its thread, messages, senders and media.
+func (a *WhatsAppAdapter) writeChat(tx *sql.Tx, chat *whatsappChat, meName, meContactID string, result *SyncResult) error {
+	if len(chat.messages) == 0 {
+		return nil
+	}
+
+	// Work out which sender is the user and whether this is a group
+	senders := map[string]bool{}
+	for _, m := range chat.messages {
+		senders[m.sender] = true
+	}
+	isMe := func(sender string) bool {
+		return strings.EqualFold(sender, meName) || sender == "You"
+	}
+	found := false
+	for s := range senders {
+		if isMe(s) {
+			found = true
+		}
+	}
+	if !found && len(senders) == 2 && senders[chat.name] {
+		// One-to-one chat named after the other person: the user is the other sender
+		for s := range senders {
+			if s != chat.name {
+				meName = s
+			}
+		}
+	}
+	others := 0
+	for s := range senders {
+		if !isMe(s) {
+			others++
+		}
+	}
+	isGroup := others > 1
+
+	threadSource := "chat:" + strings.ToLower(chat.name)
+	threadID := a.Name() + ":" + threadSource
+	first, last := chat.messages[0].timestamp, chat.messages[len(chat.messages)-1].timestamp
+	var exists int
+	if err := tx.QueryRow(`SELECT 1 FROM threads WHERE source_adapter = ? AND source_id = ?`, a.Name(), threadSource).Scan(&exists); err == sql.ErrNoRows {
+		result.ThreadsCreated++
+	} else if err != nil {
+		return fmt.Errorf("lookup thread: %w", err)
+	} else {
+		result.ThreadsUpdated++
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO threads (id, channel, name, is_group, source_adapter, source_id, created_at, updated_at)
+		VALUES (?, 'whatsapp', ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(source_adapter, source_id) DO UPDATE SET
+			name = excluded.name,
+			is_group = MAX(threads.is_group, excluded.is_group),
+			created_at = MIN(threads.created_at, excluded.created_at),
+			updated_at = MAX(threads.updated_at, excluded.updated_at)
+	`, threadID, chat.name, isGroup, a.Name(), threadSource, first, last); err != nil {
+		return fmt.Errorf("upsert thread: %w", err)
+	}
+
+	// One contact per sender; members receive each other's messages
+	contactIDs := map[string]string{}
+	members := []string{meContactID}
+	for s := range senders {
+		if isMe(s) {
+			contactIDs[s] = meContactID
+			continue
+		}
+		idType := "whatsapp_name"
+		if contacts.GuessIdentifierType(s) == "phone" {
+			idType = "phone"
+		}
+		contactID, _, err := contacts.GetOrCreateContact(tx, idType, s, s, a.Name())
+		if err != nil {
+			return fmt.Errorf("contact %s: %w", s, err)
+		}
+		if idType != "phone" {
+			if _, created, err := contacts.EnsurePersonForContact(tx, contactID, s, "deterministic", 0.8); err != nil {
+				return err
+			} else if created {
+				result.PersonsCreated++
+			}
+		}
+		contactIDs[s] = contactID
+		members = append(members, contactID)
+	}
+	sort.Strings(members[1:])
+
+	seen := map[string]int{}
+	for _, m := range chat.messages {
+		key := whatsappMessageKey(m)
+		seen[key]++
+		sourceID := threadSource + "/" + key
+		if n := seen[key]; n > 1 {
+			sourceID += fmt.Sprintf("-%d", n)
+		}
+		eventID := a.Name() + ":" + sourceID
+
+		direction := "received"
+		if isMe(m.sender) {
+			direction = "sent"
+		}
+		contentTypes := `["text"]`
+		if m.media != nil {
+			contentTypes = `["attachment"]`
+			if m.text != "" {
+				contentTypes = `["text","attachment"]`
+			}
+		}
+
+		res, err := tx.Exec(`
+			INSERT OR IGNORE INTO events (
+				id, timestamp, channel, content_types, content,
+				direction, thread_id, source_adapter, source_id
+			) VALUES (?, ?, 'whatsapp', ?, ?, ?, ?, ?, ?)
+		`, eventID, m.timestamp, contentTypes, m.text, direction, threadID, a.Name(), sourceID)
+		if err != nil {
+			return fmt.Errorf("insert event: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			res, err := tx.Exec(`
+				UPDATE events SET direction = ?, thread_id = ?
+				WHERE id = ? AND (direction IS NOT ? OR thread_id IS NOT ?)
+			`, direction, threadID, eventID, direction, threadID)
+			if err != nil {
+				return fmt.Errorf("update event: %w", err)
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				result.EventsUpdated++
+			}
+		} else {
+			result.EventsCreated++
+		}
+
+		senderID := contactIDs[m.sender]
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO event_participants (event_id, contact_id, role) VALUES (?, ?, 'sender')`, eventID, senderID); err != nil {
+			return fmt.Errorf("insert sender: %w", err)
+		}
+		for _, member := range members {
+			if member == senderID {
+				continue
+			}
+			if _, err := tx.Exec(`INSERT OR IGNORE INTO event_participants (event_id, contact_id, role) VALUES (?, ?, 'recipient')`, eventID, member); err != nil {
+				return fmt.Errorf("insert recipient: %w", err)
+			}
+		}
+
+		if m.media != nil {
+			created, err := a.writeMedia(tx, chat, eventID, m)
+			if err != nil {
+				return err
+			}
+			if created {
+				result.AttachmentsCreated++
+			}
+		}
+	}
+	return nil
+}
+
+// whatsappMessageKey identifies a message by its time, sender and content.
+func whatsappMessageKey(m whatsappMessage) string {
+	media := ""
+	if m.media != nil {
+		media = m.media.kind + "/" + m.media.filename
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d\x00%s\x00%s\x00%s", m.timestamp, m.sender, media, m.text)))
+	return hex.EncodeToString(sum[:12])
+}
+
+// writeMedia records a message's attachment. Files of unzipped exports are
+// linked by path; for zips the archive member is kept in the metadata.
+func (a *WhatsAppAdapter) writeMedia(tx *sql.Tx, chat *whatsappChat, eventID string, m whatsappMessage) (bool, error) {
+	var filename, mimeType, storageURI, storageType interface{}
+	metadata := map[string]interface{}{"export": chat.path}
+	if m.media.filename == "" {
+		metadata["omitted"] = true
+	} else {
+		filename = m.media.filename
+		if t := mime.TypeByExtension(filepath.Ext(m.media.filename)); t != "" {
+			mimeType = t
+		}
+		if chat.mediaDir != "" {
+			p := filepath.Join(chat.mediaDir, m.media.filename)
+			if _, err := os.Stat(p); err == nil {
+				storageURI, storageType = p, "local"
+			}
+		} else {
+			metadata["archive_member"] = m.media.filename
+		}
+	}
+	meta, _ := json.Marshal(metadata)
+	res, err := tx.Exec(`
+		INSERT INTO attachments (
+			id, event_id, filename, mime_type, media_type, storage_uri, storage_type,
+			source_id, metadata_json, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO NOTHING
+	`, eventID+":media", eventID, filename, mimeType, m.media.kind, storageURI, storageType,
+		nullIfEmpty(m.media.filename), string(meta), m.timestamp)
+	if err != nil {
+		return false, fmt.Errorf("insert attachment: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
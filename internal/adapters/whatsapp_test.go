@@ -0,0 +1,136 @@
+package adapters
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestParseWhatsAppLines(t *testing.T) {
+	utc := time.UTC
+	// iOS: bracketed 24-hour times, LRM-marked system notices and attachments
+	ios := parseWhatsAppLines([]string{
+		"\u200e[31/12/2023, 21:05:12] Trip: \u200eMessages and calls are end-to-end encrypted.",
+		"[31/12/2023, 21:06:00] Casey Lee: Happy new year!",
+		"See you all soon",
+		"[31/12/2023, 21:07:30] Tyler: \u200e<attached: 00000012-PHOTO-2023-12-31-21-07-30.jpg>",
+		"[31/12/2023, 21:08:00] Dana: \u200evideo omitted",
+		"[31/12/2023, 21:09:00] Dana: fixed the typo \u200e<This message was edited>",
+	}, "", utc)
+	if len(ios) != 4 {
+		t.Fatalf("iOS messages = %+v", ios)
+	}
+	if ios[0].sender != "Casey Lee" || ios[0].text != "Happy new year!\nSee you all soon" ||
+		ios[0].timestamp != time.Date(2023, 12, 31, 21, 6, 0, 0, utc).Unix() {
+		t.Errorf("multi-line message = %+v", ios[0])
+	}
+	if m := ios[1].media; m == nil || m.kind != "image" || m.filename != "00000012-PHOTO-2023-12-31-21-07-30.jpg" {
+		t.Errorf("attached photo = %+v", ios[1].media)
+	}
+	if m := ios[2].media; m == nil || m.kind != "video" || m.filename != "" {
+		t.Errorf("omitted video = %+v", ios[2].media)
+	}
+	if ios[3].text != "fixed the typo" {
+		t.Errorf("edited text = %q", ios[3].text)
+	}
+
+	// Android: dash-separated 12-hour times, month first
+	android := parseWhatsAppLines([]string{
+		"1/2/24, 9:05\u202fPM - Casey Lee created group \"Trip\"",
+		"1/2/24, 9:06\u202fPM - +1 555 010 0100: <Media omitted>",
+		"1/2/24, 12:15\u202fAM - Tyler: IMG-20240102-WA0001.jpg (file attached)",
+		"the view",
+	}, "", utc)
+	if len(android) != 2 {
+		t.Fatalf("Android messages = %+v", android)
+	}
+	if android[0].timestamp != time.Date(2024, 1, 2, 21, 6, 0, 0, utc).Unix() || android[0].media == nil || android[0].media.kind != "media" {
+		t.Errorf("media omitted = %+v", android[0])
+	}
+	if android[1].timestamp != time.Date(2024, 1, 2, 0, 15, 0, 0, utc).Unix() || android[1].text != "the view" || android[1].media.filename != "IMG-20240102-WA0001.jpg" {
+		t.Errorf("captioned file = %+v", android[1])
+	}
+
+	if order := detectWhatsAppDateOrder([]string{"03/04/2024, 10:00 - A: x", "13/04/2024, 10:00 - A: y"}); order != "dmy" {
+		t.Errorf("date order = %s", order)
+	}
+}
+
+func TestWhatsAppAdapterSync(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// A one-to-one chat exported without media
+	oneToOne := "[01/06/2024, 10:00:00] Casey Lee: lunch?\n[01/06/2024, 10:01:00] Tyler Brandt: sure\n"
+	if err := os.WriteFile(filepath.Join(dir, "WhatsApp Chat with Casey Lee.txt"), []byte(oneToOne), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A group chat exported with media, as a zip
+	zf, err := os.Create(filepath.Join(dir, "WhatsApp Chat - Trip.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(zf)
+	w, _ := zw.Create("_chat.txt")
+	w.Write([]byte("[02/06/2024, 18:00:00] Casey Lee: who's driving?\n[02/06/2024, 18:01:00] Dana: \u200e<attached: 00000003-PHOTO-2024-06-02-18-01-00.jpg>\n[02/06/2024, 18:02:00] Tyler Brandt: me\n"))
+	zw.Create("00000003-PHOTO-2024-06-02-18-01-00.jpg")
+	zw.Close()
+	zf.Close()
+
+	adapter, err := NewWhatsAppAdapter(WhatsAppAdapterOptions{Path: dir, Timezone: "UTC"})
+	if err != nil {
+		t.Fatalf("NewWhatsAppAdapter: %v", err)
+	}
+	res, err := adapter.Sync(ctx, db, false)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if res.EventsCreated != 5 || res.ThreadsCreated != 2 || res.AttachmentsCreated != 1 {
+		t.Fatalf("result = %+v", res)
+	}
+
+	// The user is the other sender of the one-to-one chat
+	var direction string
+	db.QueryRow(`SELECT direction FROM events WHERE channel = 'whatsapp' AND content = 'sure'`).Scan(&direction)
+	if direction != "sent" {
+		t.Errorf("own message direction = %q", direction)
+	}
+	var isGroup int
+	db.QueryRow(`SELECT is_group FROM threads WHERE name = 'Trip'`).Scan(&isGroup)
+	if isGroup != 1 {
+		t.Error("Trip is not a group")
+	}
+	var casey int
+	db.QueryRow(`
+		SELECT COUNT(DISTINCT ep.contact_id) FROM event_participants ep
+		JOIN contacts c ON c.id = ep.contact_id
+		WHERE ep.role = 'sender' AND c.display_name = 'Casey Lee'
+	`).Scan(&casey)
+	if casey != 1 {
+		t.Errorf("Casey is %d contacts across chats", casey)
+	}
+	var mediaType, member string
+	db.QueryRow(`SELECT media_type, json_extract(metadata_json, '$.archive_member') FROM attachments`).Scan(&mediaType, &member)
+	if mediaType != "image" || member != "00000003-PHOTO-2024-06-02-18-01-00.jpg" {
+		t.Errorf("attachment = %s %s", mediaType, member)
+	}
+
+	// Unchanged exports are skipped; a longer re-export only adds new messages
+	if res, err := adapter.Sync(ctx, db, false); err != nil || res.EventsCreated != 0 || res.Perf["exports.unchanged"] != "2" {
+		t.Fatalf("repeat Sync = %+v, %v", res, err)
+	}
+	later := oneToOne + "[01/06/2024, 10:02:00] Casey Lee: 12:30 at the usual\n"
+	if err := os.WriteFile(filepath.Join(dir, "WhatsApp Chat with Casey Lee.txt"), []byte(later), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := adapter.Sync(ctx, db, false); err != nil || res.EventsCreated != 1 {
+		t.Fatalf("re-export Sync = %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,151 @@
+// Package audit records reads of sensitive rows (sensitive person facts
+// such as government IDs and medical facts, and identifiers returned by the
+// API) in the audit_log table, with the CLI command or API token that read
+// them, so exposure of sensitive data is traceable.
+//
+// Reads are logged where sensitive values are handed to a caller, not where
+// they are loaded: identity resolution compares government IDs internally
+// without exposing them, and that is not logged.
+package audit
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Tables whose sensitive rows are audited.
+const (
+	TablePersonFacts       = "person_facts"
+	TablePersonFactHistory = "person_fact_history"
+	TableUnattributedFacts = "unattributed_facts"
+	TableEntityAliases     = "entity_aliases"
+)
+
+// IdentifierAliasTypes are the entity alias types that identify a person
+// (as opposed to names), whose reads through the API are audited.
+var IdentifierAliasTypes = map[string]bool{"email": true, "phone": true, "handle": true, "username": true}
+
+// Caller identifies who read sensitive data.
+type Caller struct {
+	Command string `json:"command,omitempty"`  // CLI command path, e.g. "mnemonic person facts"
+	TokenID string `json:"token_id,omitempty"` // API token, when read through the API
+}
+
+// Read is one sensitive row handed to a caller.
+type Read struct {
+	Table    string
+	RowID    string
+	PersonID string // empty when the row is not attributed to a person
+	FactType string
+}
+
+// Log records reads by caller, all with the same timestamp. Logging
+// nothing is a no-op.
+func Log(db *sql.DB, caller Caller, reads []Read) error {
+	if len(reads) == 0 {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin audit log: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Unix()
+	for _, r := range reads {
+		if _, err := tx.Exec(`
+			INSERT INTO audit_log (accessed_at, command, token_id, table_name, row_id, person_id, fact_type)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, now, nullIfEmpty(caller.Command), nullIfEmpty(caller.TokenID), r.Table, r.RowID,
+			nullIfEmpty(r.PersonID), nullIfEmpty(r.FactType)); err != nil {
+			return fmt.Errorf("write audit log: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Entry is one logged read.
+type Entry struct {
+	ID         int64     `json:"id"`
+	AccessedAt time.Time `json:"accessed_at"`
+	Caller
+	Table      string `json:"table"`
+	RowID      string `json:"row_id"`
+	PersonID   string `json:"person_id,omitempty"`
+	PersonName string `json:"person_name,omitempty"`
+	FactType   string `json:"fact_type,omitempty"`
+}
+
+// Filter selects audit log entries. Zero fields match everything.
+type Filter struct {
+	PersonID string
+	Command  string // matches command paths containing this
+	TokenID  string
+	Since    time.Time
+	Limit    int
+}
+
+// List returns logged reads matching filter, newest first.
+func List(db *sql.DB, filter Filter) ([]Entry, error) {
+	query := `
+		SELECT a.id, a.accessed_at, COALESCE(a.command, ''), COALESCE(a.token_id, ''),
+		       a.table_name, a.row_id, COALESCE(a.person_id, ''), COALESCE(p.canonical_name, ''),
+		       COALESCE(a.fact_type, '')
+		FROM audit_log a
+		LEFT JOIN persons p ON p.id = a.person_id
+	`
+	var where []string
+	var args []interface{}
+	if filter.PersonID != "" {
+		where = append(where, "a.person_id = ?")
+		args = append(args, filter.PersonID)
+	}
+	if filter.Command != "" {
+		where = append(where, "a.command LIKE ?")
+		args = append(args, "%"+filter.Command+"%")
+	}
+	if filter.TokenID != "" {
+		where = append(where, "a.token_id = ?")
+		args = append(args, filter.TokenID)
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "a.accessed_at >= ?")
+		args = append(args, filter.Since.Unix())
+	}
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY a.accessed_at DESC, a.id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		var accessedAt int64
+		if err := rows.Scan(&e.ID, &accessedAt, &e.Command, &e.TokenID, &e.Table, &e.RowID,
+			&e.PersonID, &e.PersonName, &e.FactType); err != nil {
+			return nil, fmt.Errorf("scan audit log: %w", err)
+		}
+		e.AccessedAt = time.Unix(accessedAt, 0)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestLogAndList(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec(`INSERT INTO persons (id, canonical_name, created_at, updated_at) VALUES ('p1', 'Tyler', 0, 0)`); err != nil {
+		t.Fatal(err)
+	}
+	if err := Log(db, Caller{Command: "mnemonic person facts"}, nil); err != nil {
+		t.Fatalf("Log(nothing): %v", err)
+	}
+	if err := Log(db, Caller{Command: "mnemonic person facts"}, []Read{
+		{Table: TablePersonFacts, RowID: "f1", PersonID: "p1", FactType: "ssn"},
+		{Table: TablePersonFactHistory, RowID: "h1", PersonID: "p1", FactType: "ssn"},
+	}); err != nil {
+		t.Fatalf("Log: %v", err)
+	}
+	if err := Log(db, Caller{TokenID: "tok-1"}, []Read{
+		{Table: TableUnattributedFacts, RowID: "u1", FactType: "passport_number"},
+	}); err != nil {
+		t.Fatalf("Log: %v", err)
+	}
+
+	all, err := List(db, Filter{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(all) != 3 || all[0].RowID != "u1" || all[0].TokenID != "tok-1" || all[0].PersonName != "" {
+		t.Fatalf("List = %+v", all)
+	}
+	for _, tc := range []struct {
+		filter Filter
+		want   int
+	}{
+		{Filter{PersonID: "p1"}, 2},
+		{Filter{Command: "person facts"}, 2},
+		{Filter{TokenID: "tok-1"}, 1},
+		{Filter{Limit: 1}, 1},
+		{Filter{Since: time.Now().Add(time.Hour)}, 0},
+	} {
+		entries, err := List(db, tc.filter)
+		if err != nil || len(entries) != tc.want {
+			t.Errorf("List(%+v) = %d entries, %v; want %d", tc.filter, len(entries), err, tc.want)
+		}
+	}
+	if byPerson, _ := List(db, Filter{PersonID: "p1"}); byPerson[0].PersonName != "Tyler" {
+		t.Errorf("entry person name = %q", byPerson[0].PersonName)
+	}
+}
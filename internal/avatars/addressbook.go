@@ -0,0 +1,138 @@
+package avatars
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Napageneral/mnemonic/internal/config"
+	"github.com/Napageneral/mnemonic/internal/contacts"
+)
+
+// SourceMacOSContacts is the source of photos imported from macOS Contacts.
+const SourceMacOSContacts = "macos-contacts"
+
+// DefaultAddressBookDir is where macOS Contacts keeps its databases.
+func DefaultAddressBookDir() (string, error) {
+	return config.AddressBookDir()
+}
+
+// addressBookDatabases returns the Contacts databases under dir: the local
+// one and one per account source (iCloud, Google, Exchange, ...).
+func addressBookDatabases(dir string) []string {
+	var paths []string
+	if _, err := os.Stat(filepath.Join(dir, "AddressBook-v22.abcddb")); err == nil {
+		paths = append(paths, filepath.Join(dir, "AddressBook-v22.abcddb"))
+	}
+	sources, _ := filepath.Glob(filepath.Join(dir, "Sources", "*", "AddressBook-v22.abcddb"))
+	return append(paths, sources...)
+}
+
+// ImportAddressBook copies photos from the macOS Contacts databases under
+// dir onto the contacts for each card's phone numbers and emails. Cards
+// whose identifiers no adapter has seen are skipped: a photo is only useful
+// next to someone in the event store. Returns the number of contacts given
+// a photo.
+func ImportAddressBook(ctx context.Context, db *sql.DB, dir string) (int, error) {
+	stored := 0
+	for _, path := range addressBookDatabases(dir) {
+		n, err := importAddressBookDatabase(ctx, db, path)
+		stored += n
+		if err != nil {
+			return stored, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return stored, nil
+}
+
+func importAddressBookDatabase(ctx context.Context, db *sql.DB, path string) (int, error) {
+	abDB, err := sql.Open("sqlite", "file:"+path+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
+		return 0, fmt.Errorf("open: %w", err)
+	}
+	defer abDB.Close()
+
+	// Identifiers per card (ZOWNER is the card's Z_PK)
+	type identifier struct{ idType, value string }
+	cardIdentifiers := make(map[int64][]identifier)
+	for _, q := range []struct{ idType, query string }{
+		{"phone", `SELECT ZOWNER, ZFULLNUMBER FROM ZABCDPHONENUMBER WHERE ZOWNER IS NOT NULL AND ZFULLNUMBER IS NOT NULL`},
+		{"email", `SELECT ZOWNER, ZADDRESS FROM ZABCDEMAILADDRESS WHERE ZOWNER IS NOT NULL AND ZADDRESS IS NOT NULL`},
+	} {
+		rows, err := abDB.QueryContext(ctx, q.query)
+		if err != nil {
+			return 0, fmt.Errorf("query %s identifiers: %w", q.idType, err)
+		}
+		for rows.Next() {
+			var owner int64
+			var value string
+			if err := rows.Scan(&owner, &value); err != nil {
+				rows.Close()
+				return 0, fmt.Errorf("scan %s: %w", q.idType, err)
+			}
+			cardIdentifiers[owner] = append(cardIdentifiers[owner], identifier{q.idType, value})
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	rows, err := abDB.QueryContext(ctx, `SELECT Z_PK, ZTHUMBNAILIMAGEDATA FROM ZABCDRECORD WHERE ZTHUMBNAILIMAGEDATA IS NOT NULL`)
+	if err != nil {
+		return 0, fmt.Errorf("query cards: %w", err)
+	}
+	defer rows.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stored := 0
+	for rows.Next() {
+		var card int64
+		var data []byte
+		if err := rows.Scan(&card, &data); err != nil {
+			return 0, fmt.Errorf("scan card: %w", err)
+		}
+		data = thumbnailImage(data)
+		if contentType(data) == "" || len(data) > MaxBytes {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, id := range cardIdentifiers[card] {
+			contactID, err := contacts.LookupContact(tx, id.idType, id.value)
+			if err != nil {
+				return 0, err
+			}
+			if contactID == "" || seen[contactID] {
+				continue
+			}
+			seen[contactID] = true
+			if err := Store(tx, contactID, SourceMacOSContacts, data, ""); err != nil {
+				return 0, err
+			}
+			stored++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return stored, nil
+}
+
+// thumbnailImage strips the one-byte header Contacts writes before the
+// image data of some thumbnails.
+func thumbnailImage(data []byte) []byte {
+	if len(data) > 1 && data[0] == 0x01 && contentType(data) == "" && contentType(data[1:]) != "" {
+		return data[1:]
+	}
+	return data
+}
@@ -0,0 +1,158 @@
+// Package avatars stores contact photos and finds the one to show for a
+// person or graph entity. Photos are kept per contact and source in the
+// avatars table, so the database stays self-contained; a person (and the
+// entity it is linked to) shows the newest photo among its contacts.
+package avatars
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/contacts"
+)
+
+// MaxBytes bounds a stored photo. Address book photos are thumbnails, so
+// anything larger is not worth keeping in the database.
+const MaxBytes = 2 << 20
+
+// ErrNotImage is returned for data that is not a supported image.
+var ErrNotImage = errors.New("not a JPEG, PNG, GIF or WebP image")
+
+// Avatar is a stored photo.
+type Avatar struct {
+	ContactID   string
+	Source      string
+	ContentType string
+	Data        []byte
+	SHA256      string
+	UpdatedAt   time.Time
+}
+
+// contentType returns the image type of data, or "" if it is not one we serve.
+func contentType(data []byte) string {
+	switch ct := http.DetectContentType(data); ct {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return ct
+	default:
+		return ""
+	}
+}
+
+// Store saves data as the contact's photo from source, replacing the
+// previous one. sourceURL, when the photo was downloaded, lets the next
+// sync skip unchanged photos (see SourceURL).
+func Store(db contacts.DBTX, contactID, source string, data []byte, sourceURL string) error {
+	if len(data) > MaxBytes {
+		return fmt.Errorf("avatar is %d bytes, more than %d", len(data), MaxBytes)
+	}
+	ct := contentType(data)
+	if ct == "" {
+		return ErrNotImage
+	}
+	sum := sha256.Sum256(data)
+	var url interface{}
+	if sourceURL != "" {
+		url = sourceURL
+	}
+	_, err := db.Exec(`
+		INSERT INTO avatars (contact_id, source, content_type, data, sha256, source_url, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(contact_id, source) DO UPDATE SET
+			content_type = excluded.content_type,
+			data = excluded.data,
+			sha256 = excluded.sha256,
+			source_url = excluded.source_url,
+			updated_at = CASE WHEN avatars.sha256 = excluded.sha256 THEN avatars.updated_at ELSE excluded.updated_at END
+	`, contactID, source, ct, data, hex.EncodeToString(sum[:]), url, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("store avatar: %w", err)
+	}
+	return nil
+}
+
+// SourceURL returns the URL the contact's photo from source was downloaded
+// from, or "" if there is none.
+func SourceURL(db contacts.DBTX, contactID, source string) (string, error) {
+	var url sql.NullString
+	err := db.QueryRow(`SELECT source_url FROM avatars WHERE contact_id = ? AND source = ?`, contactID, source).Scan(&url)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("query avatar: %w", err)
+	}
+	return url.String, nil
+}
+
+const avatarColumns = `a.contact_id, a.source, a.content_type, a.data, a.sha256, a.updated_at`
+
+func scanAvatar(row *sql.Row) (*Avatar, error) {
+	var a Avatar
+	var updatedAt int64
+	err := row.Scan(&a.ContactID, &a.Source, &a.ContentType, &a.Data, &a.SHA256, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query avatar: %w", err)
+	}
+	a.UpdatedAt = time.Unix(updatedAt, 0)
+	return &a, nil
+}
+
+// ForPerson returns the newest photo of the person's contacts, or nil.
+func ForPerson(db *sql.DB, personID string) (*Avatar, error) {
+	return scanAvatar(db.QueryRow(`
+		SELECT `+avatarColumns+`
+		FROM avatars a
+		JOIN person_contact_links l ON l.contact_id = a.contact_id
+		WHERE l.person_id = ?
+		ORDER BY l.confidence DESC, a.updated_at DESC
+		LIMIT 1
+	`, personID))
+}
+
+// ForEntity returns the photo of the person linked to a graph entity, or nil.
+func ForEntity(db *sql.DB, entityID string) (*Avatar, error) {
+	return scanAvatar(db.QueryRow(`
+		SELECT `+avatarColumns+`
+		FROM avatars a
+		JOIN person_contact_links l ON l.contact_id = a.contact_id
+		JOIN person_entity_links pel ON pel.person_id = l.person_id
+		WHERE pel.entity_id = ?
+		ORDER BY l.confidence DESC, a.updated_at DESC
+		LIMIT 1
+	`, entityID))
+}
+
+// Download fetches a photo, refusing responses over MaxBytes.
+func Download(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download avatar: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download avatar: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("download avatar: %w", err)
+	}
+	if len(data) > MaxBytes {
+		return nil, fmt.Errorf("avatar at %s is larger than %d bytes", strings.SplitN(url, "?", 2)[0], MaxBytes)
+	}
+	return data, nil
+}
@@ -0,0 +1,102 @@
+package avatars
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestStoreAndLookup(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	phone, _, err := contacts.GetOrCreateContact(db, "phone", "+1 555 123 4567", "Jane Doe", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	person, _, err := contacts.EnsurePersonForContact(db, phone, "Jane Doe", "test", 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`INSERT INTO entities (id, canonical_name, entity_type_id, origin, created_at, updated_at)
+			VALUES ('jane', 'Jane Doe', 1, 'imported', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')`,
+		`INSERT INTO person_entity_links (person_id, entity_id, method, created_at) VALUES ('` + person + `', 'jane', 'identifier', 0)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Store(db, phone, "test", []byte("hello"), ""); err != ErrNotImage {
+		t.Errorf("Store(text) = %v, want ErrNotImage", err)
+	}
+	if a, err := ForPerson(db, person); err != nil || a != nil {
+		t.Errorf("ForPerson before Store = %v, %v", a, err)
+	}
+	if err := Store(db, phone, "test", testPNG, "https://example.com/jane.png"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	a, err := ForPerson(db, person)
+	if err != nil || a == nil || a.ContentType != "image/png" || string(a.Data) != string(testPNG) {
+		t.Fatalf("ForPerson = %+v, %v", a, err)
+	}
+	if a, err := ForEntity(db, "jane"); err != nil || a == nil || a.ContactID != phone {
+		t.Errorf("ForEntity = %+v, %v", a, err)
+	}
+	if url, _ := SourceURL(db, phone, "test"); url != "https://example.com/jane.png" {
+		t.Errorf("SourceURL = %q", url)
+	}
+}
+
+func TestImportAddressBook(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	email, _, err := contacts.GetOrCreateContact(db, "email", "sam@example.com", "Sam Lee", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	ab, err := sql.Open("sqlite", filepath.Join(dir, "AddressBook-v22.abcddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE ZABCDRECORD (Z_PK INTEGER PRIMARY KEY, ZTHUMBNAILIMAGEDATA BLOB)`,
+		`CREATE TABLE ZABCDPHONENUMBER (ZOWNER INTEGER, ZFULLNUMBER TEXT)`,
+		`CREATE TABLE ZABCDEMAILADDRESS (ZOWNER INTEGER, ZADDRESS TEXT)`,
+		`INSERT INTO ZABCDEMAILADDRESS VALUES (1, 'Sam@Example.com'), (2, 'nobody@example.com')`,
+		`INSERT INTO ZABCDPHONENUMBER VALUES (1, '(555) 999-0000')`,
+	} {
+		if _, err := ab.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Contacts prefixes some thumbnails with a 0x01 byte
+	if _, err := ab.Exec(`INSERT INTO ZABCDRECORD VALUES (1, ?), (2, ?)`, append([]byte{0x01}, testPNG...), testPNG); err != nil {
+		t.Fatal(err)
+	}
+	ab.Close()
+
+	n, err := ImportAddressBook(context.Background(), db, dir)
+	if err != nil || n != 1 {
+		t.Fatalf("ImportAddressBook = %d, %v; want 1 (only Sam is a known contact)", n, err)
+	}
+	var data []byte
+	if err := db.QueryRow(`SELECT data FROM avatars WHERE contact_id = ? AND source = ?`, email, SourceMacOSContacts).Scan(&data); err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(testPNG) {
+		t.Errorf("stored %q, want the thumbnail without its header byte", data)
+	}
+}
@@ -0,0 +1,112 @@
+// Package changelog reads the global change log: an append-only record of
+// entities created, relationships added, merges executed and episodes
+// processed. Entries are written by database triggers (see schema.sql), so
+// every writer is covered; consumers remember the last seq they saw and ask
+// for what came after it.
+package changelog
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Change types.
+const (
+	EntityCreated     = "entity_created"
+	RelationshipAdded = "relationship_added"
+	MergeExecuted     = "merge_executed"
+	EpisodeProcessed  = "episode_processed"
+)
+
+// Types lists every change type.
+var Types = []string{EntityCreated, RelationshipAdded, MergeExecuted, EpisodeProcessed}
+
+// DefaultLimit and MaxLimit bound one Read.
+const (
+	DefaultLimit = 500
+	MaxLimit     = 5000
+)
+
+// Change is one changelog entry.
+type Change struct {
+	Seq       int64  `json:"seq"`
+	Type      string `json:"type"`
+	SubjectID string `json:"subject_id"`       // entity, relationship, merge target, or episode
+	RefID     string `json:"ref_id,omitempty"` // for merges: the entity merged away
+	CreatedAt string `json:"created_at"`
+}
+
+// ReadOptions filters Read. Zero values mean no filter.
+type ReadOptions struct {
+	Since int64    // return entries after this seq
+	Limit int      // default DefaultLimit, capped at MaxLimit
+	Types []string // only these change types
+}
+
+// Read returns entries after opts.Since in seq order, and the cursor to pass
+// as Since next time (unchanged when there is nothing new). A full page
+// means more entries may be waiting.
+func Read(db *sql.DB, opts ReadOptions) ([]Change, int64, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	query := `SELECT seq, change_type, subject_id, COALESCE(ref_id, ''), created_at FROM changelog WHERE seq > ?`
+	args := []interface{}{opts.Since}
+	if len(opts.Types) > 0 {
+		for _, t := range opts.Types {
+			if !validType(t) {
+				return nil, opts.Since, fmt.Errorf("unknown change type %q (use %s)", t, strings.Join(Types, ", "))
+			}
+			args = append(args, t)
+		}
+		query += ` AND change_type IN (?` + strings.Repeat(", ?", len(opts.Types)-1) + `)`
+	}
+	query += ` ORDER BY seq LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, opts.Since, fmt.Errorf("failed to query changelog: %w", err)
+	}
+	defer rows.Close()
+
+	cursor := opts.Since
+	changes := []Change{}
+	for rows.Next() {
+		var c Change
+		if err := rows.Scan(&c.Seq, &c.Type, &c.SubjectID, &c.RefID, &c.CreatedAt); err != nil {
+			return nil, opts.Since, fmt.Errorf("failed to scan changelog entry: %w", err)
+		}
+		changes = append(changes, c)
+		cursor = c.Seq
+	}
+	if err := rows.Err(); err != nil {
+		return nil, opts.Since, err
+	}
+	return changes, cursor, nil
+}
+
+// Head returns the newest seq, or 0 for an empty log. A consumer starting
+// from the current state (rather than replaying history) begins here.
+func Head(db *sql.DB) (int64, error) {
+	var seq int64
+	if err := db.QueryRow(`SELECT COALESCE(MAX(seq), 0) FROM changelog`).Scan(&seq); err != nil {
+		return 0, fmt.Errorf("failed to read changelog head: %w", err)
+	}
+	return seq, nil
+}
+
+func validType(t string) bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package changelog
+
+import (
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestChangelogTriggersAndRead(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec(`
+		INSERT INTO entities (id, canonical_name, entity_type_id, origin, created_at, updated_at) VALUES
+			('tyler', 'Tyler', 1, 'extracted', '2024-01-01T00:00:00Z', '2024-01-01T00:00:00Z'),
+			('acme', 'Acme', 2, 'extracted', '2024-01-01T00:00:00Z', '2024-01-01T00:00:00Z'),
+			('acme2', 'ACME', 2, 'extracted', '2024-01-01T00:00:00Z', '2024-01-01T00:00:00Z');
+		INSERT INTO relationships (id, source_entity_id, target_entity_id, relation_type, fact, created_at)
+			VALUES ('r1', 'tyler', 'acme', 'WORKS_AT', 'Tyler works at Acme', '2024-01-01T00:00:00Z');
+		INSERT INTO entity_merge_events (id, source_entity_id, target_entity_id, merge_type, created_at)
+			VALUES ('m1', 'acme2', 'acme', 'manual', '2024-01-01T00:00:00Z');
+	`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	changes, cursor, err := Read(db, ReadOptions{})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Type+":"+c.SubjectID)
+	}
+	want := []string{"entity_created:tyler", "entity_created:acme", "entity_created:acme2", "relationship_added:r1", "merge_executed:acme"}
+	if len(got) != len(want) {
+		t.Fatalf("changes = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("changes = %v, want %v", got, want)
+		}
+	}
+	if changes[4].RefID != "acme2" {
+		t.Errorf("merge ref = %q, want acme2", changes[4].RefID)
+	}
+	if head, _ := Head(db); head != cursor {
+		t.Errorf("head %d != cursor %d", head, cursor)
+	}
+
+	// Episode processing is upserted; only successful runs are logged
+	if _, err := db.Exec(`
+		INSERT INTO threads (id, channel, source_adapter, source_id, created_at, updated_at) VALUES ('t1', 'imessage', 'imessage', 't1', 0, 0);
+		INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0);
+		INSERT INTO episodes (id, definition_id, channel, thread_id, start_time, end_time, event_count, created_at) VALUES ('ep1', 'def', 'imessage', 't1', 0, 0, 0, 0);
+		INSERT INTO episode_processing (episode_id, status, processed_at) VALUES ('ep1', 'error', '2024-01-01T00:00:00Z');
+		INSERT INTO episode_processing (episode_id, status, processed_at) VALUES ('ep1', 'ok', '2024-01-02T00:00:00Z')
+			ON CONFLICT(episode_id) DO UPDATE SET status = excluded.status, processed_at = excluded.processed_at;
+	`); err != nil {
+		t.Fatalf("episode processing: %v", err)
+	}
+
+	changes, next, err := Read(db, ReadOptions{Since: cursor, Types: []string{EpisodeProcessed}})
+	if err != nil {
+		t.Fatalf("Read since cursor: %v", err)
+	}
+	if len(changes) != 1 || changes[0].SubjectID != "ep1" || next <= cursor {
+		t.Fatalf("episode changes = %+v, cursor %d -> %d", changes, cursor, next)
+	}
+
+	page, pageCursor, err := Read(db, ReadOptions{Limit: 2})
+	if err != nil || len(page) != 2 || pageCursor != page[1].Seq {
+		t.Errorf("limited read = %+v, cursor %d, err %v", page, pageCursor, err)
+	}
+	if _, _, err := Read(db, ReadOptions{Types: []string{"bogus"}}); err == nil {
+		t.Error("unknown type accepted")
+	}
+}
@@ -0,0 +1,70 @@
+package chunk
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+// Benchmarks run with 'make bench'; MNEMONIC_BENCH_SCALE=0.01 shrinks the
+// workloads for a quick pass.
+
+const benchEvents = 1_000_000
+
+func BenchmarkSplitByTimeGap(b *testing.B) {
+	n := testutil.Scaled(benchEvents)
+	events := make([]Event, n)
+	ts := int64(1_700_000_000)
+	for i := range events {
+		ts += 60
+		if i%50 == 0 {
+			ts += 4 * 3600
+		}
+		events[i] = Event{ID: fmt.Sprintf("ev-%d", i), Timestamp: ts, ThreadID: "th-0", Channel: "imessage"}
+	}
+	c := NewTimeGapChunker(TimeGapConfig{GapSeconds: 90 * 60, Scope: "thread"})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if eps := c.splitByTimeGap(events); len(eps) == 0 {
+			b.Fatal("no episodes")
+		}
+	}
+}
+
+func BenchmarkTimeGapChunk(b *testing.B) {
+	db := testutil.OpenTestDB(b)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	n := testutil.Scaled(benchEvents)
+	testutil.GenerateEvents(b, db, testutil.EventSpec{Events: n})
+	config := TimeGapConfig{GapSeconds: 90 * 60, Scope: "thread"}
+	defID, err := CreateDefinition(ctx, db, "bench_90min", "imessage", "time_gap", config, "")
+	if err != nil {
+		b.Fatal(err)
+	}
+	c := NewTimeGapChunker(config)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		if _, err := db.Exec(`DELETE FROM episodes WHERE definition_id = ?`, defID); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+
+		res, err := c.Chunk(ctx, db, defID)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if res.EventsProcessed != n {
+			b.Fatalf("processed %d events, want %d", res.EventsProcessed, n)
+		}
+	}
+	b.ReportMetric(float64(n)*float64(b.N)/b.Elapsed().Seconds(), "events/s")
+}
@@ -56,12 +56,18 @@ func (c *TimeGapChunker) Chunk(ctx context.Context, db *sql.DB, definitionID str
 	// Get definition details to determine scope
 	var defName, channel string
 	err := db.QueryRowContext(ctx, `
-		SELECT name, channel FROM episode_definitions WHERE id = ?
+		SELECT name, COALESCE(channel, '') FROM episode_definitions WHERE id = ?
 	`, definitionID).Scan(&defName, &channel)
 	if err != nil {
 		return result, fmt.Errorf("failed to fetch definition: %w", err)
 	}
 
+	// Events already in an episode of this definition stay where they are
+	existing, err := loadExistingEventIDs(ctx, db, definitionID)
+	if err != nil {
+		return result, fmt.Errorf("failed to load existing episodes: %w", err)
+	}
+
 	// Query events based on scope
 	var query string
 	var args []interface{}
@@ -72,7 +78,7 @@ func (c *TimeGapChunker) Chunk(ctx context.Context, db *sql.DB, definitionID str
 			query = `
 				SELECT id, timestamp, thread_id, channel
 				FROM events
-				WHERE channel = ? AND thread_id IS NOT NULL
+				WHERE channel = ? AND thread_id IS NOT NULL AND supersedes IS NULL
 				ORDER BY thread_id, timestamp ASC
 			`
 			args = []interface{}{channel}
@@ -80,7 +86,7 @@ func (c *TimeGapChunker) Chunk(ctx context.Context, db *sql.DB, definitionID str
 			query = `
 				SELECT id, timestamp, thread_id, channel
 				FROM events
-				WHERE thread_id IS NOT NULL
+				WHERE thread_id IS NOT NULL AND supersedes IS NULL
 				ORDER BY thread_id, timestamp ASC
 			`
 		}
@@ -90,7 +96,7 @@ func (c *TimeGapChunker) Chunk(ctx context.Context, db *sql.DB, definitionID str
 			query = `
 				SELECT id, timestamp, thread_id, channel
 				FROM events
-				WHERE channel = ?
+				WHERE channel = ? AND supersedes IS NULL
 				ORDER BY timestamp ASC
 			`
 			args = []interface{}{channel}
@@ -98,6 +104,7 @@ func (c *TimeGapChunker) Chunk(ctx context.Context, db *sql.DB, definitionID str
 			query = `
 				SELECT id, timestamp, thread_id, channel
 				FROM events
+				WHERE supersedes IS NULL
 				ORDER BY timestamp ASC
 			`
 		}
@@ -121,6 +128,9 @@ func (c *TimeGapChunker) Chunk(ctx context.Context, db *sql.DB, definitionID str
 			return result, fmt.Errorf("failed to scan event: %w", err)
 		}
 
+		if _, ok := existing[e.ID]; ok {
+			continue
+		}
 		if threadID.Valid {
 			e.ThreadID = threadID.String
 		}
@@ -263,11 +273,15 @@ func (c *TimeGapChunker) insertEpisode(ctx context.Context, db *sql.DB, definiti
 			return fmt.Errorf("failed to insert episode_event mapping: %w", err)
 		}
 	}
+	if _, err := UpdateContentHash(ctx, tx, episodeID); err != nil {
+		return err
+	}
 
 	return tx.Commit()
 }
 
-// CreateDefinition creates an episode definition in the database
+// CreateDefinition creates an episode definition in the database. If one with
+// the same name exists, its ID is returned and nothing changes.
 func CreateDefinition(ctx context.Context, db *sql.DB, name, channel, strategy string, config interface{}, description string) (string, error) {
 	// Check if definition already exists
 	var existingID string
@@ -284,6 +298,9 @@ func CreateDefinition(ctx context.Context, db *sql.DB, name, channel, strategy s
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal config: %w", err)
 	}
+	if err := ValidateDefinition(strategy, string(configJSON)); err != nil {
+		return "", err
+	}
 
 	definitionID := uuid.New().String()
 	now := time.Now().Unix()
@@ -330,12 +347,17 @@ func (c *ThreadChunker) Chunk(ctx context.Context, db *sql.DB, definitionID stri
 	// Get definition details
 	var defName, channel string
 	err := db.QueryRowContext(ctx, `
-		SELECT name, channel FROM episode_definitions WHERE id = ?
+		SELECT name, COALESCE(channel, '') FROM episode_definitions WHERE id = ?
 	`, definitionID).Scan(&defName, &channel)
 	if err != nil {
 		return result, fmt.Errorf("failed to fetch definition: %w", err)
 	}
 
+	existing, err := loadExistingEventIDs(ctx, db, definitionID)
+	if err != nil {
+		return result, fmt.Errorf("failed to load existing episodes: %w", err)
+	}
+
 	// Query events based on channel scope
 	var query string
 	var args []interface{}
@@ -344,7 +366,7 @@ func (c *ThreadChunker) Chunk(ctx context.Context, db *sql.DB, definitionID stri
 		query = `
 			SELECT id, timestamp, thread_id, channel
 			FROM events
-			WHERE channel = ? AND thread_id IS NOT NULL
+			WHERE channel = ? AND thread_id IS NOT NULL AND supersedes IS NULL
 			ORDER BY thread_id, timestamp ASC
 		`
 		args = []interface{}{channel}
@@ -352,7 +374,7 @@ func (c *ThreadChunker) Chunk(ctx context.Context, db *sql.DB, definitionID stri
 		query = `
 			SELECT id, timestamp, thread_id, channel
 			FROM events
-			WHERE thread_id IS NOT NULL
+			WHERE thread_id IS NOT NULL AND supersedes IS NULL
 			ORDER BY thread_id, timestamp ASC
 		`
 	}
@@ -379,6 +401,9 @@ func (c *ThreadChunker) Chunk(ctx context.Context, db *sql.DB, definitionID stri
 			e.ThreadID = threadID.String
 		}
 
+		if _, ok := existing[e.ID]; ok {
+			continue
+		}
 		if e.ThreadID != "" {
 			eventsByThread[e.ThreadID] = append(eventsByThread[e.ThreadID], e)
 			result.EventsProcessed++
@@ -389,39 +414,12 @@ func (c *ThreadChunker) Chunk(ctx context.Context, db *sql.DB, definitionID stri
 		return result, fmt.Errorf("error iterating events: %w", err)
 	}
 
-	// Create one episode per thread
-	existingThreads := make(map[string]struct{})
-	existingRows, err := db.QueryContext(ctx, `
-		SELECT thread_id FROM episodes
-		WHERE definition_id = ? AND thread_id IS NOT NULL
-	`, definitionID)
-	if err != nil {
-		return result, fmt.Errorf("failed to query existing episodes: %w", err)
-	}
-	for existingRows.Next() {
-		var tid sql.NullString
-		if err := existingRows.Scan(&tid); err != nil {
-			existingRows.Close()
-			return result, fmt.Errorf("failed to scan existing episode: %w", err)
-		}
-		if tid.Valid && tid.String != "" {
-			existingThreads[tid.String] = struct{}{}
-		}
-	}
-	if err := existingRows.Err(); err != nil {
-		existingRows.Close()
-		return result, fmt.Errorf("error iterating existing episodes: %w", err)
-	}
-	existingRows.Close()
-
+	// One episode per thread. Events added to a thread after it was chunked
+	// become a follow-up episode for that thread.
 	for threadID, events := range eventsByThread {
 		if len(events) == 0 {
 			continue
 		}
-		if _, ok := existingThreads[threadID]; ok {
-			continue
-		}
-
 		ep := episode{
 			events:    events,
 			startTime: events[0].Timestamp,
@@ -486,6 +484,9 @@ func (c *ThreadChunker) insertEpisode(ctx context.Context, db *sql.DB, definitio
 			return fmt.Errorf("failed to insert episode_event mapping: %w", err)
 		}
 	}
+	if _, err := UpdateContentHash(ctx, tx, episodeID); err != nil {
+		return err
+	}
 
 	return tx.Commit()
 }
@@ -514,7 +515,7 @@ func (c *SingleEventChunker) Chunk(ctx context.Context, db *sql.DB, definitionID
 	// Get definition details
 	var channel string
 	err := db.QueryRowContext(ctx, `
-		SELECT channel FROM episode_definitions WHERE id = ?
+		SELECT COALESCE(channel, '') FROM episode_definitions WHERE id = ?
 	`, definitionID).Scan(&channel)
 	if err != nil {
 		return result, fmt.Errorf("failed to fetch definition: %w", err)
@@ -530,7 +531,7 @@ func (c *SingleEventChunker) Chunk(ctx context.Context, db *sql.DB, definitionID
 		FROM events
 	`
 	args := []interface{}{}
-	clauses := []string{}
+	clauses := []string{"supersedes IS NULL"}
 	if channel != "" {
 		clauses = append(clauses, "channel = ?")
 		args = append(args, channel)
@@ -550,6 +551,28 @@ func (c *SingleEventChunker) Chunk(ctx context.Context, db *sql.DB, definitionID
 	}
 	defer rows.Close()
 
+	// Read everything before writing: the database allows one connection,
+	// so the transaction can't start while rows is still open.
+	var events []Event
+	for rows.Next() {
+		var e Event
+		var threadID sql.NullString
+		if err := rows.Scan(&e.ID, &e.Timestamp, &threadID, &e.Channel); err != nil {
+			return result, fmt.Errorf("failed to scan event: %w", err)
+		}
+		if _, ok := existing[e.ID]; ok {
+			continue
+		}
+		if threadID.Valid {
+			e.ThreadID = threadID.String
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return result, fmt.Errorf("error iterating events: %w", err)
+	}
+	rows.Close()
+
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return result, fmt.Errorf("failed to begin transaction: %w", err)
@@ -578,19 +601,7 @@ func (c *SingleEventChunker) Chunk(ctx context.Context, db *sql.DB, definitionID
 	defer stmtInsertEvent.Close()
 
 	now := time.Now().Unix()
-	for rows.Next() {
-		var e Event
-		var threadID sql.NullString
-		if err := rows.Scan(&e.ID, &e.Timestamp, &threadID, &e.Channel); err != nil {
-			return result, fmt.Errorf("failed to scan event: %w", err)
-		}
-		if _, ok := existing[e.ID]; ok {
-			continue
-		}
-		if threadID.Valid {
-			e.ThreadID = threadID.String
-		}
-
+	for _, e := range events {
 		episodeID := uuid.New().String()
 		var threadIDValue interface{} = nil
 		if e.ThreadID != "" {
@@ -621,14 +632,13 @@ func (c *SingleEventChunker) Chunk(ctx context.Context, db *sql.DB, definitionID
 		if _, err := stmtInsertEvent.Exec(episodeID, e.ID, 1); err != nil {
 			return result, fmt.Errorf("insert episode_event: %w", err)
 		}
+		if _, err := UpdateContentHash(ctx, tx, episodeID); err != nil {
+			return result, err
+		}
 
-		existing[e.ID] = struct{}{}
 		result.EpisodesCreated++
 		result.EventsProcessed++
 	}
-	if err := rows.Err(); err != nil {
-		return result, fmt.Errorf("error iterating events: %w", err)
-	}
 
 	if err := tx.Commit(); err != nil {
 		return result, fmt.Errorf("commit episodes: %w", err)
@@ -669,7 +679,7 @@ func (c *TurnPairChunker) Chunk(ctx context.Context, db *sql.DB, definitionID st
 
 	var channel string
 	err := db.QueryRowContext(ctx, `
-		SELECT channel FROM episode_definitions WHERE id = ?
+		SELECT COALESCE(channel, '') FROM episode_definitions WHERE id = ?
 	`, definitionID).Scan(&channel)
 	if err != nil {
 		return result, fmt.Errorf("failed to fetch definition: %w", err)
@@ -683,7 +693,7 @@ func (c *TurnPairChunker) Chunk(ctx context.Context, db *sql.DB, definitionID st
 	query := `
 		SELECT id, timestamp, thread_id, channel, direction, source_adapter
 		FROM events
-		WHERE thread_id IS NOT NULL
+		WHERE thread_id IS NOT NULL AND supersedes IS NULL
 	`
 	args := []interface{}{}
 	if channel != "" {
@@ -751,14 +761,19 @@ func (c *TurnPairChunker) Chunk(ctx context.Context, db *sql.DB, definitionID st
 
 		var current []turnEvent
 		flush := func() error {
+			// A turn chunked on an earlier run keeps its episode; replies
+			// that arrived since become a follow-up episode.
+			fresh := current[:0]
+			for _, ev := range current {
+				if _, ok := existing[ev.ID]; !ok {
+					fresh = append(fresh, ev)
+				}
+			}
+			current = fresh
 			if len(current) == 0 {
 				return nil
 			}
 			first := current[0]
-			if _, ok := existing[first.ID]; ok {
-				current = nil
-				return nil
-			}
 
 			episodeID := uuid.New().String()
 			channelValue := interface{}(first.Channel)
@@ -787,6 +802,9 @@ func (c *TurnPairChunker) Chunk(ctx context.Context, db *sql.DB, definitionID st
 				}
 				existing[ev.ID] = struct{}{}
 			}
+			if _, err := UpdateContentHash(ctx, tx, episodeID); err != nil {
+				return err
+			}
 
 			result.EpisodesCreated++
 			current = nil
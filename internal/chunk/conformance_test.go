@@ -0,0 +1,332 @@
+package chunk
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+// conformanceEvent is one row of the conformance fixture.
+type conformanceEvent struct {
+	id        string
+	ts        int64
+	channel   string
+	direction string
+	thread    string // empty for no thread
+}
+
+// conformanceBatches is the fixture every strategy is run against: bursts
+// and gaps, a tied timestamp, an event without a thread, and a coding
+// session with tool output. The second batch arrives after the first has
+// been chunked - more messages in existing threads (including a late reply
+// to an already-chunked turn) and a new thread.
+var conformanceBatches = [][]conformanceEvent{
+	{
+		{"im-1a", 1000, "imessage", "sent", "im-1"},
+		{"im-1b", 1060, "imessage", "received", "im-1"},
+		{"im-1c", 1060, "imessage", "sent", "im-1"},
+		{"im-1d", 9000, "imessage", "received", "im-1"},
+		{"im-2a", 1030, "imessage", "received", "im-2"},
+		{"im-2b", 20000, "imessage", "sent", "im-2"},
+		{"im-x", 1500, "imessage", "received", ""},
+		{"gm-1a", 2000, "gmail", "received", "gm-1"},
+		{"gm-1b", 90000, "gmail", "sent", "gm-1"},
+		{"gm-2a", 2500, "gmail", "received", "gm-2"},
+		{"cu-1a", 3000, "cursor", "received", "cu-1"},
+		{"cu-1b", 3010, "cursor", "sent", "cu-1"},
+		{"cu-1c", 3020, "cursor", "received", "cu-1"},
+		{"cu-1d", 3030, "cursor", "observed", "cu-1"},
+		{"cu-1e", 3040, "cursor", "received", "cu-1"},
+		{"cu-1f", 9000, "cursor", "sent", "cu-1"},
+		{"cu-1g", 9010, "cursor", "received", "cu-1"},
+	},
+	{
+		{"im-1e", 9100, "imessage", "sent", "im-1"},
+		{"im-1f", 30000, "imessage", "received", "im-1"},
+		{"im-3a", 30010, "imessage", "sent", "im-3"},
+		{"gm-1c", 95000, "gmail", "received", "gm-1"},
+		{"cu-1h", 9020, "cursor", "received", "cu-1"},
+		{"cu-1i", 12000, "cursor", "sent", "cu-1"},
+		{"cu-1j", 12010, "cursor", "received", "cu-1"},
+	},
+}
+
+// conformanceCase is one strategy configuration under test. New strategies
+// add a row here.
+type conformanceCase struct {
+	name     string
+	channel  string // definition channel; empty for all channels
+	strategy string
+	config   interface{}
+	// complete means every in-scope event ends up in an episode (turn_pair
+	// drops replies with no preceding turn, for instance).
+	complete bool
+	// needsThread means events without a thread are out of scope.
+	needsThread bool
+}
+
+var conformanceCases = []conformanceCase{
+	{"time_gap/thread", "imessage", "time_gap", TimeGapConfig{GapSeconds: 3600, Scope: "thread"}, true, true},
+	{"time_gap/channel", "", "time_gap", TimeGapConfig{GapSeconds: 3600, Scope: "channel"}, true, false},
+	{"thread", "gmail", "thread", ThreadConfig{}, true, true},
+	{"thread/all", "", "thread", ThreadConfig{}, true, true},
+	{"single_event", "", "single_event", SingleEventConfig{}, true, false},
+	{"turn_pair", "cursor", "turn_pair", TurnPairConfig{IncludeTools: true}, false, true},
+}
+
+// TestChunkerConformance checks the guarantees every Chunker makes, whatever
+// its strategy: episodes are well-formed and chronological, no event lands
+// in two episodes of one definition, re-running is a no-op, and chunking
+// incrementally (after new events arrive) leaves earlier episodes alone and
+// covers the same events as chunking everything at once.
+func TestChunkerConformance(t *testing.T) {
+	for _, tc := range conformanceCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := testutil.OpenTestDB(t)
+			defer db.Close()
+			db.SetMaxOpenConns(1)
+			ctx := context.Background()
+
+			insertConformanceBatch(t, db, conformanceBatches[0])
+			defID, err := CreateDefinition(ctx, db, "incremental", tc.channel, tc.strategy, tc.config, "")
+			if err != nil {
+				t.Fatalf("CreateDefinition: %v", err)
+			}
+			run := func() ChunkResult {
+				t.Helper()
+				chunker, err := GetChunkerForDefinition(ctx, db, defID)
+				if err != nil {
+					t.Fatalf("GetChunkerForDefinition: %v", err)
+				}
+				result, err := chunker.Chunk(ctx, db, defID)
+				if err != nil {
+					t.Fatalf("Chunk: %v", err)
+				}
+				return result
+			}
+
+			if result := run(); result.EpisodesCreated == 0 {
+				t.Fatal("first run created no episodes")
+			}
+			first := checkConformance(t, db, tc, defID)
+
+			// Idempotency
+			if result := run(); result.EpisodesCreated != 0 {
+				t.Errorf("second run created %d episodes", result.EpisodesCreated)
+			}
+			if again := loadConformanceEpisodes(t, db, defID); fmt.Sprint(again) != fmt.Sprint(first) {
+				t.Errorf("second run changed episodes:\n%v\nwant\n%v", again, first)
+			}
+
+			// Watermark: only events that arrived since are chunked
+			chunked := eventSet(first)
+			insertConformanceBatch(t, db, conformanceBatches[1])
+			run()
+			after := checkConformance(t, db, tc, defID)
+			for id, ep := range first {
+				if fmt.Sprint(after[id]) != fmt.Sprint(ep) {
+					t.Errorf("episode %s changed after new events: %v, was %v", id, after[id], ep)
+				}
+			}
+			for id, ep := range after {
+				if _, ok := first[id]; ok {
+					continue
+				}
+				for _, e := range ep.events {
+					if chunked[e] {
+						t.Errorf("new episode %s re-chunked event %s", id, e)
+					}
+				}
+			}
+
+			// Chunking in two steps covers what chunking once does
+			freshID, err := CreateDefinition(ctx, db, "fresh", tc.channel, tc.strategy, tc.config, "")
+			if err != nil {
+				t.Fatalf("CreateDefinition: %v", err)
+			}
+			chunker, err := GetChunkerForDefinition(ctx, db, freshID)
+			if err != nil {
+				t.Fatalf("GetChunkerForDefinition: %v", err)
+			}
+			if _, err := chunker.Chunk(ctx, db, freshID); err != nil {
+				t.Fatalf("Chunk: %v", err)
+			}
+			fresh := checkConformance(t, db, tc, freshID)
+			if got, want := sortedKeys(eventSet(after)), sortedKeys(eventSet(fresh)); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("incremental runs covered %v, a single run %v", got, want)
+			}
+		})
+	}
+}
+
+type conformanceEpisode struct {
+	channel        sql.NullString
+	start, end     int64
+	count          int
+	first, last    string
+	events         []string
+	positions      []int
+	eventTimes     []int64
+	eventChannels  []string
+	eventHasThread []bool
+}
+
+func (e conformanceEpisode) String() string {
+	return fmt.Sprintf("{%s %d-%d %v}", e.channel.String, e.start, e.end, e.events)
+}
+
+func loadConformanceEpisodes(t *testing.T, db *sql.DB, defID string) map[string]conformanceEpisode {
+	t.Helper()
+	rows, err := db.Query(`
+		SELECT ep.id, ep.channel, ep.start_time, ep.end_time, ep.event_count,
+			COALESCE(ep.first_event_id, ''), COALESCE(ep.last_event_id, ''),
+			ee.event_id, ee.position, e.timestamp, e.channel, e.thread_id IS NOT NULL
+		FROM episodes ep
+		JOIN episode_events ee ON ee.episode_id = ep.id
+		JOIN events e ON e.id = ee.event_id
+		WHERE ep.definition_id = ?
+		ORDER BY ep.id, ee.position
+	`, defID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	episodes := make(map[string]conformanceEpisode)
+	for rows.Next() {
+		var id, eventID, eventChannel string
+		var ep conformanceEpisode
+		var position int
+		var ts int64
+		var hasThread bool
+		if err := rows.Scan(&id, &ep.channel, &ep.start, &ep.end, &ep.count, &ep.first, &ep.last,
+			&eventID, &position, &ts, &eventChannel, &hasThread); err != nil {
+			t.Fatal(err)
+		}
+		if prev, ok := episodes[id]; ok {
+			prev.events, prev.positions = append(prev.events, eventID), append(prev.positions, position)
+			prev.eventTimes, prev.eventChannels = append(prev.eventTimes, ts), append(prev.eventChannels, eventChannel)
+			prev.eventHasThread = append(prev.eventHasThread, hasThread)
+			ep = prev
+		} else {
+			ep.events, ep.positions = []string{eventID}, []int{position}
+			ep.eventTimes, ep.eventChannels = []int64{ts}, []string{eventChannel}
+			ep.eventHasThread = []bool{hasThread}
+		}
+		episodes[id] = ep
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// An episode with no events would be missed by the join
+	var total int
+	db.QueryRow(`SELECT COUNT(*) FROM episodes WHERE definition_id = ?`, defID).Scan(&total)
+	if total != len(episodes) {
+		t.Errorf("%d episodes, %d with events", total, len(episodes))
+	}
+	return episodes
+}
+
+// checkConformance loads a definition's episodes and checks the invariants
+// that hold after any run.
+func checkConformance(t *testing.T, db *sql.DB, tc conformanceCase, defID string) map[string]conformanceEpisode {
+	t.Helper()
+	episodes := loadConformanceEpisodes(t, db, defID)
+	seen := make(map[string]string)
+	for id, ep := range episodes {
+		n := len(ep.events)
+		if ep.count != n || ep.first != ep.events[0] || ep.last != ep.events[n-1] {
+			t.Errorf("episode %v: count %d, first %s, last %s", ep, ep.count, ep.first, ep.last)
+		}
+		if ep.start != ep.eventTimes[0] || ep.end != ep.eventTimes[n-1] {
+			t.Errorf("episode %v: bounds %d-%d, events %v", ep, ep.start, ep.end, ep.eventTimes)
+		}
+		if tc.channel != "" && ep.channel.String != tc.channel {
+			t.Errorf("episode %v: channel %q, definition is %q", ep, ep.channel.String, tc.channel)
+		}
+		for i, e := range ep.events {
+			if ep.positions[i] != i+1 {
+				t.Errorf("episode %v: positions %v", ep, ep.positions)
+				break
+			}
+			if i > 0 && ep.eventTimes[i] < ep.eventTimes[i-1] {
+				t.Errorf("episode %v: out of order %v", ep, ep.eventTimes)
+			}
+			if tc.channel != "" && ep.eventChannels[i] != tc.channel {
+				t.Errorf("episode %v: event %s from %s", ep, e, ep.eventChannels[i])
+			}
+			if tc.needsThread && !ep.eventHasThread[i] {
+				t.Errorf("episode %v: event %s has no thread", ep, e)
+			}
+			if other, ok := seen[e]; ok {
+				t.Errorf("event %s in episodes %s and %s", e, other, id)
+			}
+			seen[e] = id
+		}
+	}
+
+	if tc.complete {
+		query := `SELECT id FROM events WHERE (? = '' OR channel = ?)`
+		if tc.needsThread {
+			query += ` AND thread_id IS NOT NULL`
+		}
+		rows, err := db.Query(query, tc.channel, tc.channel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id string
+			rows.Scan(&id)
+			if _, ok := seen[id]; !ok {
+				t.Errorf("in-scope event %s not chunked", id)
+			}
+		}
+	}
+	return episodes
+}
+
+func insertConformanceBatch(t *testing.T, db *sql.DB, events []conformanceEvent) {
+	t.Helper()
+	for _, e := range events {
+		var thread interface{}
+		if e.thread != "" {
+			thread = e.thread
+			if _, err := db.Exec(`
+				INSERT OR IGNORE INTO threads (id, channel, source_adapter, source_id, created_at, updated_at)
+				VALUES (?, ?, ?, ?, 0, 0)
+			`, e.thread, e.channel, e.channel, e.thread); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := db.Exec(`
+			INSERT INTO events (id, timestamp, channel, content_types, content, direction, thread_id, source_adapter, source_id)
+			VALUES (?, ?, ?, '["text"]', ?, ?, ?, ?, ?)
+		`, e.id, e.ts, e.channel, "message "+e.id, e.direction, thread, e.channel, e.id); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func eventSet(episodes map[string]conformanceEpisode) map[string]bool {
+	set := make(map[string]bool)
+	for _, ep := range episodes {
+		for _, e := range ep.events {
+			set[e] = true
+		}
+	}
+	return set
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
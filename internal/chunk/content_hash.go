@@ -0,0 +1,73 @@
+package chunk
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+)
+
+// querier is satisfied by *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// ContentHash returns the content hash of an episode: a SHA-256 over its
+// event IDs in order, each with the ID of its newest edit or unsend. Two
+// episodes with the same messages in the same state hash the same, whatever
+// their episode IDs, so rechunking into identical episodes is detectable;
+// an edit to any message changes the hash. Empty if the episode has no events.
+func ContentHash(ctx context.Context, q querier, episodeID string) (string, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT ee.event_id,
+		       COALESCE((SELECT r.id FROM events r WHERE r.supersedes = ee.event_id
+		                 ORDER BY r.timestamp DESC, r.id DESC LIMIT 1), '')
+		FROM episode_events ee
+		WHERE ee.episode_id = ?
+		ORDER BY ee.position
+	`, episodeID)
+	if err != nil {
+		return "", fmt.Errorf("query episode events: %w", err)
+	}
+	defer rows.Close()
+
+	h := sha256.New()
+	n := 0
+	for rows.Next() {
+		var eventID, revisionID string
+		if err := rows.Scan(&eventID, &revisionID); err != nil {
+			return "", fmt.Errorf("scan episode event: %w", err)
+		}
+		fmt.Fprintf(h, "%s\x00%s\n", eventID, revisionID)
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "", nil
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// UpdateContentHash recomputes an episode's content hash and stores it on
+// the episode.
+func UpdateContentHash(ctx context.Context, q querier, episodeID string) (string, error) {
+	hash, err := ContentHash(ctx, q, episodeID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := q.ExecContext(ctx, `UPDATE episodes SET content_hash = ? WHERE id = ?`, nullIfEmpty(hash), episodeID); err != nil {
+		return "", fmt.Errorf("store content hash: %w", err)
+	}
+	return hash, nil
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,77 @@
+package chunk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestContentHash(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	insertConformanceBatch(t, db, conformanceBatches[0])
+	// The same thread chunking under two definitions yields identical episodes
+	hashes := map[string][]string{} // definition -> hashes by start time
+	for _, name := range []string{"threads_a", "threads_b"} {
+		id, err := CreateDefinition(ctx, db, name, "", "thread", ThreadConfig{}, "")
+		if err != nil {
+			t.Fatalf("CreateDefinition: %v", err)
+		}
+		chunker, _ := GetChunkerForDefinition(ctx, db, id)
+		if _, err := chunker.Chunk(ctx, db, id); err != nil {
+			t.Fatalf("Chunk: %v", err)
+		}
+		rows, err := db.Query(`SELECT COALESCE(content_hash, '') FROM episodes WHERE definition_id = ? ORDER BY start_time, first_event_id`, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rows.Next() {
+			var h string
+			rows.Scan(&h)
+			hashes[name] = append(hashes[name], h)
+		}
+		rows.Close()
+	}
+	a, b := hashes["threads_a"], hashes["threads_b"]
+	if len(a) == 0 || len(a) != len(b) {
+		t.Fatalf("episodes: %d vs %d", len(a), len(b))
+	}
+	for i := range a {
+		if a[i] == "" || a[i] != b[i] {
+			t.Fatalf("rechunked episode %d hash %q != %q", i, a[i], b[i])
+		}
+	}
+
+	// Editing a message changes the hash of the episodes containing it
+	var episodeID, eventID, before string
+	if err := db.QueryRow(`
+		SELECT ep.id, ee.event_id, ep.content_hash FROM episodes ep
+		JOIN episode_events ee ON ee.episode_id = ep.id
+		LIMIT 1
+	`).Scan(&episodeID, &eventID, &before); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO events (id, timestamp, channel, content_types, content, direction, source_adapter, source_id, supersedes)
+		SELECT 'edit-1', timestamp + 60, channel, content_types, 'edited', direction, source_adapter, 'edit-1', id
+		FROM events WHERE id = ?
+	`, eventID); err != nil {
+		t.Fatal(err)
+	}
+	after, err := UpdateContentHash(ctx, db, episodeID)
+	if err != nil || after == before || after == "" {
+		t.Errorf("hash after edit = %q (%v), before %q", after, err, before)
+	}
+	var stored string
+	db.QueryRow(`SELECT content_hash FROM episodes WHERE id = ?`, episodeID).Scan(&stored)
+	if stored != after {
+		t.Errorf("stored hash = %q, want %q", stored, after)
+	}
+	if h, err := ContentHash(ctx, db, "missing"); err != nil || h != "" {
+		t.Errorf("hash of an episode without events = %q, %v", h, err)
+	}
+}
@@ -0,0 +1,170 @@
+package chunk
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Strategies are the chunking strategies GetChunkerForDefinition supports.
+var Strategies = []string{"time_gap", "thread", "single_event", "turn_pair"}
+
+// ValidateDefinition checks that strategy is supported and configJSON is a
+// JSON object of that strategy's config, with no unknown fields. Catching a
+// typo here beats a definition that fails (or silently misbehaves) on run.
+func ValidateDefinition(strategy, configJSON string) error {
+	var config interface{}
+	switch strategy {
+	case "time_gap":
+		config = &TimeGapConfig{}
+	case "thread":
+		config = &ThreadConfig{}
+	case "single_event":
+		config = &SingleEventConfig{}
+	case "turn_pair":
+		config = &TurnPairConfig{}
+	default:
+		return fmt.Errorf("unknown strategy %q (one of %s)", strategy, strings.Join(Strategies, ", "))
+	}
+
+	trimmed := strings.TrimSpace(configJSON)
+	if !strings.HasPrefix(trimmed, "{") {
+		return fmt.Errorf("config must be a JSON object, got %q", configJSON)
+	}
+	dec := json.NewDecoder(bytes.NewReader([]byte(trimmed)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(config); err != nil {
+		return fmt.Errorf("invalid %s config: %w", strategy, err)
+	}
+	if dec.More() {
+		return fmt.Errorf("invalid %s config: trailing data after JSON object", strategy)
+	}
+
+	if c, ok := config.(*TimeGapConfig); ok {
+		if c.GapSeconds <= 0 {
+			return fmt.Errorf("invalid time_gap config: gap_seconds must be positive")
+		}
+		if c.Scope != "thread" && c.Scope != "channel" {
+			return fmt.Errorf("invalid time_gap config: scope must be \"thread\" or \"channel\", got %q", c.Scope)
+		}
+	}
+	return nil
+}
+
+// GetDefinition returns the definition with the given name or ID, or nil if
+// there is none.
+func GetDefinition(ctx context.Context, db *sql.DB, nameOrID string) (*Definition, error) {
+	var d Definition
+	var channel, description sql.NullString
+	err := db.QueryRowContext(ctx, `
+		SELECT id, name, channel, strategy, config_json, description, created_at, updated_at
+		FROM episode_definitions
+		WHERE name = ? OR id = ?
+		ORDER BY name = ? DESC
+		LIMIT 1
+	`, nameOrID, nameOrID, nameOrID).Scan(&d.ID, &d.Name, &channel, &d.Strategy, &d.ConfigJSON, &description, &d.CreatedAt, &d.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch definition: %w", err)
+	}
+	d.Channel = channel.String
+	d.Description = description.String
+	return &d, nil
+}
+
+// DefinitionUpdate lists the fields UpdateDefinition changes; nil fields are
+// left as they are. An empty Channel means all channels.
+type DefinitionUpdate struct {
+	Name        *string
+	Channel     *string
+	Strategy    *string
+	ConfigJSON  *string
+	Description *string
+}
+
+// UpdateDefinition changes a definition. The resulting strategy and config
+// are validated together, so changing only the strategy fails if the old
+// config does not fit it. Existing episodes are kept; the change applies to
+// events chunked from then on.
+func UpdateDefinition(ctx context.Context, db *sql.DB, nameOrID string, update DefinitionUpdate) (*Definition, error) {
+	d, err := GetDefinition(ctx, db, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, fmt.Errorf("definition %q not found", nameOrID)
+	}
+
+	if update.Name != nil {
+		name := strings.TrimSpace(*update.Name)
+		if name == "" {
+			return nil, fmt.Errorf("name is required")
+		}
+		d.Name = name
+	}
+	if update.Channel != nil {
+		d.Channel = *update.Channel
+	}
+	if update.Strategy != nil {
+		d.Strategy = *update.Strategy
+	}
+	if update.ConfigJSON != nil {
+		d.ConfigJSON = *update.ConfigJSON
+	}
+	if update.Description != nil {
+		d.Description = *update.Description
+	}
+	if err := ValidateDefinition(d.Strategy, d.ConfigJSON); err != nil {
+		return nil, err
+	}
+
+	var channel interface{}
+	if d.Channel != "" {
+		channel = d.Channel
+	}
+	d.UpdatedAt = time.Now().Unix()
+	_, err = db.ExecContext(ctx, `
+		UPDATE episode_definitions
+		SET name = ?, channel = ?, strategy = ?, config_json = ?, description = ?, updated_at = ?
+		WHERE id = ?
+	`, d.Name, channel, d.Strategy, d.ConfigJSON, d.Description, d.UpdatedAt, d.ID)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return nil, fmt.Errorf("a definition named %q already exists", d.Name)
+		}
+		return nil, fmt.Errorf("failed to update definition: %w", err)
+	}
+	return d, nil
+}
+
+// DeleteDefinition removes a definition that has no episodes. Episodes carry
+// extraction results and analyses, so a definition in use is never deleted
+// out from under them.
+func DeleteDefinition(ctx context.Context, db *sql.DB, nameOrID string) error {
+	d, err := GetDefinition(ctx, db, nameOrID)
+	if err != nil {
+		return err
+	}
+	if d == nil {
+		return fmt.Errorf("definition %q not found", nameOrID)
+	}
+
+	var episodes int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM episodes WHERE definition_id = ?`, d.ID).Scan(&episodes); err != nil {
+		return fmt.Errorf("failed to count episodes: %w", err)
+	}
+	if episodes > 0 {
+		return fmt.Errorf("definition %q has %d episodes; it can't be deleted", d.Name, episodes)
+	}
+
+	if _, err := db.ExecContext(ctx, `DELETE FROM episode_definitions WHERE id = ?`, d.ID); err != nil {
+		return fmt.Errorf("failed to delete definition: %w", err)
+	}
+	return nil
+}
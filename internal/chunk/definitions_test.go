@@ -0,0 +1,95 @@
+package chunk
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestValidateDefinition(t *testing.T) {
+	for _, tc := range []struct {
+		strategy, config, wantErr string
+	}{
+		{"time_gap", `{"gap_seconds": 5400, "scope": "thread"}`, ""},
+		{"thread", `{}`, ""},
+		{"single_event", ` {"source_adapter": "aix"} `, ""},
+		{"turn_pair", `{"include_tools": true}`, ""},
+		{"daily", `{}`, "unknown strategy"},
+		{"time_gap", `{"gap_seconds": 5400, "scope": "thread"`, "invalid time_gap config"},
+		{"time_gap", `{"gap": 5400, "scope": "thread"}`, "unknown field"},
+		{"time_gap", `{"gap_seconds": 0, "scope": "thread"}`, "gap_seconds"},
+		{"time_gap", `{"gap_seconds": 60, "scope": "day"}`, "scope"},
+		{"thread", `[]`, "JSON object"},
+		{"thread", `{} {}`, "trailing data"},
+		{"turn_pair", `{"include_tools": "yes"}`, "invalid turn_pair config"},
+	} {
+		err := ValidateDefinition(tc.strategy, tc.config)
+		if tc.wantErr == "" && err != nil {
+			t.Errorf("%s %s: %v", tc.strategy, tc.config, err)
+		}
+		if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+			t.Errorf("%s %s: err = %v, want %q", tc.strategy, tc.config, err, tc.wantErr)
+		}
+	}
+}
+
+func TestDefinitionCRUD(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := CreateDefinition(ctx, db, "bad", "", "daily", struct{}{}, ""); err == nil {
+		t.Error("CreateDefinition accepted an unknown strategy")
+	}
+
+	id, err := CreateDefinition(ctx, db, "im_90", "imessage", "time_gap", TimeGapConfig{GapSeconds: 5400, Scope: "thread"}, "")
+	if err != nil {
+		t.Fatalf("CreateDefinition: %v", err)
+	}
+	def, err := GetDefinition(ctx, db, id)
+	if err != nil || def == nil || def.Name != "im_90" {
+		t.Fatalf("GetDefinition by ID = %+v, %v", def, err)
+	}
+	if missing, err := GetDefinition(ctx, db, "nope"); missing != nil || err != nil {
+		t.Errorf("GetDefinition(nope) = %+v, %v", missing, err)
+	}
+
+	// Changing only the strategy must fit the existing config
+	thread := "thread"
+	if _, err := UpdateDefinition(ctx, db, "im_90", DefinitionUpdate{Strategy: &thread}); err == nil {
+		t.Error("UpdateDefinition kept a time_gap config under the thread strategy")
+	}
+	config, all, name := "{}", "", "all_threads"
+	def, err = UpdateDefinition(ctx, db, "im_90", DefinitionUpdate{Name: &name, Strategy: &thread, ConfigJSON: &config, Channel: &all})
+	if err != nil {
+		t.Fatalf("UpdateDefinition: %v", err)
+	}
+	if def.Name != "all_threads" || def.Strategy != "thread" || def.Channel != "" {
+		t.Errorf("updated = %+v", def)
+	}
+	var channelIsNull bool
+	db.QueryRow(`SELECT channel IS NULL FROM episode_definitions WHERE id = ?`, id).Scan(&channelIsNull)
+	if !channelIsNull {
+		t.Error("empty channel should be stored as NULL (all channels)")
+	}
+
+	// A definition with episodes is kept
+	if _, err := db.Exec(`
+		INSERT INTO episodes (id, definition_id, start_time, end_time, event_count, created_at)
+		VALUES ('ep-1', ?, 0, 0, 0, 0)
+	`, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteDefinition(ctx, db, "all_threads"); err == nil || !strings.Contains(err.Error(), "1 episodes") {
+		t.Errorf("DeleteDefinition with episodes: %v", err)
+	}
+	db.Exec(`DELETE FROM episodes`)
+	if err := DeleteDefinition(ctx, db, "all_threads"); err != nil {
+		t.Fatalf("DeleteDefinition: %v", err)
+	}
+	if err := DeleteDefinition(ctx, db, "all_threads"); err == nil {
+		t.Error("deleting a missing definition should fail")
+	}
+}
@@ -0,0 +1,142 @@
+package chunk
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PrimaryDefinition is the definition whose episodes downstream consumers
+// use for a channel.
+type PrimaryDefinition struct {
+	Channel        string `json:"channel"`
+	DefinitionID   string `json:"definition_id"`
+	DefinitionName string `json:"definition_name"`
+	UpdatedAt      int64  `json:"updated_at"`
+}
+
+// PrimaryEpisodeCondition returns a SQL condition that is true for episodes
+// (table alias alias) of their channel's primary definition. Episodes of a
+// channel with no primary, or with no channel, always match, so setting a
+// primary only ever narrows what is processed.
+func PrimaryEpisodeCondition(alias string) string {
+	return fmt.Sprintf(`COALESCE((
+		SELECT pd.definition_id FROM episode_primary_definitions pd WHERE pd.channel = %[1]s.channel
+	), %[1]s.definition_id) = %[1]s.definition_id`, alias)
+}
+
+// SetPrimaryDefinition makes a definition the primary one for channel. The
+// definition must cover the channel: either it is scoped to it or it applies
+// to all channels.
+func SetPrimaryDefinition(ctx context.Context, db *sql.DB, channel, nameOrID string) (*PrimaryDefinition, error) {
+	channel = strings.TrimSpace(channel)
+	if channel == "" {
+		return nil, fmt.Errorf("channel is required")
+	}
+	d, err := GetDefinition(ctx, db, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, fmt.Errorf("definition %q not found", nameOrID)
+	}
+	if d.Channel != "" && d.Channel != channel {
+		return nil, fmt.Errorf("definition %q only chunks %s events, not %s", d.Name, d.Channel, channel)
+	}
+
+	p := &PrimaryDefinition{Channel: channel, DefinitionID: d.ID, DefinitionName: d.Name, UpdatedAt: time.Now().Unix()}
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO episode_primary_definitions (channel, definition_id, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(channel) DO UPDATE SET definition_id = excluded.definition_id, updated_at = excluded.updated_at
+	`, p.Channel, p.DefinitionID, p.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set primary definition: %w", err)
+	}
+	return p, nil
+}
+
+// ClearPrimaryDefinition removes channel's primary definition, so episodes
+// of every definition are processed again.
+func ClearPrimaryDefinition(ctx context.Context, db *sql.DB, channel string) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM episode_primary_definitions WHERE channel = ?`, channel)
+	if err != nil {
+		return fmt.Errorf("failed to clear primary definition: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("channel %q has no primary definition", channel)
+	}
+	return nil
+}
+
+// ListPrimaryDefinitions lists primary definitions by channel.
+func ListPrimaryDefinitions(ctx context.Context, db *sql.DB) ([]PrimaryDefinition, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT pd.channel, pd.definition_id, d.name, pd.updated_at
+		FROM episode_primary_definitions pd
+		JOIN episode_definitions d ON d.id = pd.definition_id
+		ORDER BY pd.channel
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query primary definitions: %w", err)
+	}
+	defer rows.Close()
+
+	primaries := []PrimaryDefinition{}
+	for rows.Next() {
+		var p PrimaryDefinition
+		if err := rows.Scan(&p.Channel, &p.DefinitionID, &p.DefinitionName, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan primary definition: %w", err)
+		}
+		primaries = append(primaries, p)
+	}
+	return primaries, rows.Err()
+}
+
+// EventEpisode is one episode an event belongs to.
+type EventEpisode struct {
+	EpisodeID      string `json:"episode_id"`
+	DefinitionID   string `json:"definition_id"`
+	DefinitionName string `json:"definition_name"`
+	Strategy       string `json:"strategy"`
+	Channel        string `json:"channel,omitempty"`
+	ThreadID       string `json:"thread_id,omitempty"`
+	StartTime      int64  `json:"start_time"`
+	EndTime        int64  `json:"end_time"`
+	EventCount     int    `json:"event_count"`
+	Position       int    `json:"position"`
+	// Primary is true when downstream consumers process this episode (see
+	// PrimaryEpisodeCondition).
+	Primary bool `json:"primary"`
+}
+
+// EventEpisodes lists the episodes containing an event across all
+// definitions, primary ones first.
+func EventEpisodes(ctx context.Context, db *sql.DB, eventID string) ([]EventEpisode, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT ep.id, d.id, d.name, d.strategy, COALESCE(ep.channel, ''), COALESCE(ep.thread_id, ''),
+			ep.start_time, ep.end_time, ep.event_count, ee.position, `+PrimaryEpisodeCondition("ep")+`
+		FROM episode_events ee
+		JOIN episodes ep ON ep.id = ee.episode_id
+		JOIN episode_definitions d ON d.id = ep.definition_id
+		WHERE ee.event_id = ?
+		ORDER BY 11 DESC, d.name, ep.start_time
+	`, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query event episodes: %w", err)
+	}
+	defer rows.Close()
+
+	episodes := []EventEpisode{}
+	for rows.Next() {
+		var e EventEpisode
+		if err := rows.Scan(&e.EpisodeID, &e.DefinitionID, &e.DefinitionName, &e.Strategy, &e.Channel, &e.ThreadID,
+			&e.StartTime, &e.EndTime, &e.EventCount, &e.Position, &e.Primary); err != nil {
+			return nil, fmt.Errorf("failed to scan event episode: %w", err)
+		}
+		episodes = append(episodes, e)
+	}
+	return episodes, rows.Err()
+}
@@ -0,0 +1,94 @@
+package chunk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestPrimaryDefinitions(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	insertConformanceBatch(t, db, conformanceBatches[0])
+	for _, def := range []struct {
+		name, channel, strategy string
+		config                  interface{}
+	}{
+		{"im_gap", "imessage", "time_gap", TimeGapConfig{GapSeconds: 3600, Scope: "thread"}},
+		{"threads", "", "thread", ThreadConfig{}},
+		{"gm_thread", "gmail", "thread", ThreadConfig{}},
+	} {
+		id, err := CreateDefinition(ctx, db, def.name, def.channel, def.strategy, def.config, "")
+		if err != nil {
+			t.Fatalf("CreateDefinition: %v", err)
+		}
+		chunker, _ := GetChunkerForDefinition(ctx, db, id)
+		if _, err := chunker.Chunk(ctx, db, id); err != nil {
+			t.Fatalf("Chunk %s: %v", def.name, err)
+		}
+	}
+
+	countPrimary := func() int {
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM episodes ep WHERE ` + PrimaryEpisodeCondition("ep")).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	var all int
+	db.QueryRow(`SELECT COUNT(*) FROM episodes`).Scan(&all)
+	if got := countPrimary(); got != all {
+		t.Errorf("no primaries: %d of %d episodes match", got, all)
+	}
+
+	if _, err := SetPrimaryDefinition(ctx, db, "imessage", "gm_thread"); err == nil {
+		t.Error("a gmail definition was made primary for imessage")
+	}
+	if _, err := SetPrimaryDefinition(ctx, db, "imessage", "threads"); err != nil {
+		t.Fatalf("SetPrimaryDefinition: %v", err)
+	}
+	// Setting again replaces
+	if _, err := SetPrimaryDefinition(ctx, db, "imessage", "im_gap"); err != nil {
+		t.Fatalf("SetPrimaryDefinition: %v", err)
+	}
+	primaries, err := ListPrimaryDefinitions(ctx, db)
+	if err != nil || len(primaries) != 1 || primaries[0].DefinitionName != "im_gap" {
+		t.Fatalf("ListPrimaryDefinitions = %+v, %v", primaries, err)
+	}
+
+	// imessage episodes of "threads" drop out; gmail and cursor keep both
+	var threadsIM int
+	db.QueryRow(`
+		SELECT COUNT(*) FROM episodes ep JOIN episode_definitions d ON d.id = ep.definition_id
+		WHERE d.name = 'threads' AND ep.channel = 'imessage'
+	`).Scan(&threadsIM)
+	if got := countPrimary(); threadsIM == 0 || got != all-threadsIM {
+		t.Errorf("with an imessage primary: %d match, want %d", got, all-threadsIM)
+	}
+
+	episodes, err := EventEpisodes(ctx, db, "im-1a")
+	if err != nil {
+		t.Fatalf("EventEpisodes: %v", err)
+	}
+	if len(episodes) != 2 || !episodes[0].Primary || episodes[0].DefinitionName != "im_gap" ||
+		episodes[1].Primary || episodes[1].DefinitionName != "threads" {
+		t.Errorf("EventEpisodes(im-1a) = %+v", episodes)
+	}
+	if episodes, _ := EventEpisodes(ctx, db, "gm-1a"); len(episodes) != 2 || !episodes[0].Primary || !episodes[1].Primary {
+		t.Errorf("EventEpisodes(gm-1a) = %+v", episodes)
+	}
+
+	if err := ClearPrimaryDefinition(ctx, db, "imessage"); err != nil {
+		t.Fatalf("ClearPrimaryDefinition: %v", err)
+	}
+	if err := ClearPrimaryDefinition(ctx, db, "imessage"); err == nil {
+		t.Error("clearing a channel with no primary should fail")
+	}
+	if got := countPrimary(); got != all {
+		t.Errorf("after clear: %d of %d episodes match", got, all)
+	}
+}
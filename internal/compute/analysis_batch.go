@@ -0,0 +1,294 @@
+package compute
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/chunk"
+)
+
+// DefaultAnalysisConcurrency is how many episodes RunAnalysisBatch analyzes at once.
+const DefaultAnalysisConcurrency = 8
+
+// AnalysisBatchOptions selects the episodes a batch covers. Episodes that
+// already have a completed (or blocked) run of the analysis type are always
+// skipped; failed runs are retried. Unless EpisodeIDs or Definition pick
+// episodes explicitly, only episodes of each channel's primary definition
+// are covered, so an event is not analyzed once per definition.
+type AnalysisBatchOptions struct {
+	EpisodeIDs  []string  // Only these episodes
+	Channel     string    // Only episodes on this channel
+	Definition  string    // Only episodes from this definition (by name)
+	Person      string    // Only episodes involving a person whose name matches
+	Since       time.Time // Only episodes starting at or after this time
+	Limit       int       // Most recent first; 0 = no limit
+	Concurrency int       // 0 = DefaultAnalysisConcurrency
+}
+
+// AnalysisBatchStats summarizes a batch run.
+type AnalysisBatchStats struct {
+	AnalysisType string `json:"analysis_type"`
+	Episodes     int    `json:"episodes"`
+	Completed    int    `json:"completed"`
+	Blocked      int    `json:"blocked"`
+	Failed       int    `json:"failed"`
+	Synced       int    `json:"synced"`
+	SyncErrors   int    `json:"sync_errors"`
+}
+
+// PendingEpisodes returns episodes matching opts that lack a completed run
+// of the analysis type, most recent first.
+func PendingEpisodes(ctx context.Context, db *sql.DB, analysisType string, opts AnalysisBatchOptions) ([]string, error) {
+	query := `
+		SELECT ep.id FROM episodes ep
+		WHERE NOT EXISTS (
+			SELECT 1 FROM analysis_runs ar
+			JOIN analysis_types at ON ar.analysis_type_id = at.id
+			WHERE ar.episode_id = ep.id
+			AND at.name = ?
+			AND ar.status IN ('completed', 'blocked')
+		)
+	`
+	args := []interface{}{analysisType}
+
+	if len(opts.EpisodeIDs) > 0 {
+		query += ` AND ep.id IN (?` + strings.Repeat(", ?", len(opts.EpisodeIDs)-1) + `)`
+		for _, id := range opts.EpisodeIDs {
+			args = append(args, id)
+		}
+	}
+	if opts.Definition != "" {
+		var defID string
+		err := db.QueryRowContext(ctx, `SELECT id FROM episode_definitions WHERE name = ?`, opts.Definition).Scan(&defID)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("definition '%s' not found", opts.Definition)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get definition: %w", err)
+		}
+		query += ` AND ep.definition_id = ?`
+		args = append(args, defID)
+	} else if len(opts.EpisodeIDs) == 0 {
+		query += ` AND ` + chunk.PrimaryEpisodeCondition("ep")
+	}
+	if opts.Channel != "" {
+		query += ` AND ep.channel = ?`
+		args = append(args, opts.Channel)
+	}
+	if !opts.Since.IsZero() {
+		query += ` AND ep.start_time >= ?`
+		args = append(args, opts.Since.Unix())
+	}
+	if opts.Person != "" {
+		query += ` AND EXISTS (
+			SELECT 1 FROM episode_events ee
+			JOIN event_participants evp ON evp.event_id = ee.event_id
+			JOIN person_contact_links pcl ON pcl.contact_id = evp.contact_id
+			JOIN persons p ON p.id = pcl.person_id
+			WHERE ee.episode_id = ep.id
+			AND (p.canonical_name LIKE ? OR p.display_name LIKE ?)
+		)`
+		args = append(args, "%"+opts.Person+"%", "%"+opts.Person+"%")
+	}
+	query += ` ORDER BY ep.start_time DESC`
+	if opts.Limit > 0 {
+		query += fmt.Sprintf(` LIMIT %d`, opts.Limit)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query episodes: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// RunAnalysisBatch runs an analysis end to end without the job queue: it
+// analyzes each pending episode (creating or retrying its analysis run and
+// storing facets), then hands completed runs to the type's sync handler. A
+// failed episode doesn't stop the batch; it is counted and retried next time.
+func (e *Engine) RunAnalysisBatch(ctx context.Context, analysisType string, opts AnalysisBatchOptions) (*AnalysisBatchStats, error) {
+	analysisTypeID, err := e.analysisTypeID(ctx, analysisType)
+	if err != nil {
+		return nil, err
+	}
+
+	episodeIDs, err := PendingEpisodes(ctx, e.db, analysisType, opts)
+	if err != nil {
+		return nil, err
+	}
+	stats := &AnalysisBatchStats{AnalysisType: analysisType, Episodes: len(episodeIDs)}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultAnalysisConcurrency
+	}
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for episodeID := range work {
+				// Failures are recorded on the run itself
+				_ = e.runAnalysis(ctx, AnalysisJobPayload{EpisodeID: episodeID, AnalysisTypeID: analysisTypeID})
+			}
+		}()
+	}
+	for _, episodeID := range episodeIDs {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- episodeID
+	}
+	close(work)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return stats, err
+	}
+
+	if err := e.countRuns(ctx, analysisTypeID, episodeIDs, stats); err != nil {
+		return stats, err
+	}
+
+	stats.Synced, stats.SyncErrors, err = SyncAnalysisRuns(ctx, e.db, analysisType)
+	if err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// analysisTypeID resolves an analysis type's row, registering it from the
+// registry first if needed.
+func (e *Engine) analysisTypeID(ctx context.Context, analysisType string) (string, error) {
+	if spec, ok := LookupAnalysis(analysisType); ok {
+		return EnsureAnalysisType(ctx, e.db, spec)
+	}
+	var id string
+	err := e.db.QueryRowContext(ctx, `SELECT id FROM analysis_types WHERE name = ?`, analysisType).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("unknown analysis type %s", analysisType)
+	}
+	if err != nil {
+		return "", fmt.Errorf("get analysis type: %w", err)
+	}
+	return id, nil
+}
+
+// SyncAnalysisRuns hands each completed, unsynced run of the analysis type
+// to its registered sync handler and marks it synced. Types without a
+// handler are left alone. Returns the number synced and the number that
+// failed (and will be retried).
+func SyncAnalysisRuns(ctx context.Context, db *sql.DB, analysisType string) (int, int, error) {
+	spec, ok := LookupAnalysis(analysisType)
+	if !ok || spec.Sync == nil {
+		return 0, 0, nil
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT ar.id, ar.episode_id, ar.output_text
+		FROM analysis_runs ar
+		JOIN analysis_types at ON ar.analysis_type_id = at.id
+		WHERE at.name = ?
+		AND ar.status = 'completed'
+		AND ar.output_text IS NOT NULL AND ar.output_text != ''
+		AND ar.facts_synced_at IS NULL
+		ORDER BY ar.completed_at
+	`, analysisType)
+	if err != nil {
+		return 0, 0, fmt.Errorf("query unsynced runs: %w", err)
+	}
+	var runs []AnalysisRun
+	for rows.Next() {
+		var run AnalysisRun
+		if err := rows.Scan(&run.ID, &run.EpisodeID, &run.Output); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		runs = append(runs, run)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	synced, failed := 0, 0
+	for _, run := range runs {
+		if err := spec.Sync(ctx, db, run); err != nil {
+			failed++
+			continue
+		}
+		if _, err := db.ExecContext(ctx, `
+			UPDATE analysis_runs SET facts_synced_at = ? WHERE id = ?
+		`, time.Now().Unix(), run.ID); err != nil {
+			return synced, failed, fmt.Errorf("mark run synced: %w", err)
+		}
+		synced++
+	}
+	return synced, failed, nil
+}
+
+// countRuns tallies the outcome of the batch's analysis runs.
+func (e *Engine) countRuns(ctx context.Context, analysisTypeID string, episodeIDs []string, stats *AnalysisBatchStats) error {
+	for _, chunk := range chunkStrings(episodeIDs, 500) {
+		args := []interface{}{analysisTypeID}
+		for _, id := range chunk {
+			args = append(args, id)
+		}
+		rows, err := e.db.QueryContext(ctx, `
+			SELECT status, COUNT(*) FROM analysis_runs
+			WHERE analysis_type_id = ? AND episode_id IN (?`+strings.Repeat(", ?", len(chunk)-1)+`)
+			GROUP BY status
+		`, args...)
+		if err != nil {
+			return fmt.Errorf("count analysis runs: %w", err)
+		}
+		for rows.Next() {
+			var status string
+			var count int
+			if err := rows.Scan(&status, &count); err != nil {
+				rows.Close()
+				return err
+			}
+			switch status {
+			case "completed":
+				stats.Completed += count
+			case "blocked":
+				stats.Blocked += count
+			default:
+				stats.Failed += count
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunkStrings splits ids into slices of at most size.
+func chunkStrings(ids []string, size int) [][]string {
+	var chunks [][]string
+	for len(ids) > size {
+		chunks = append(chunks, ids[:size])
+		ids = ids[size:]
+	}
+	if len(ids) > 0 {
+		chunks = append(chunks, ids)
+	}
+	return chunks
+}
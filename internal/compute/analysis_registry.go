@@ -0,0 +1,279 @@
+package compute
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/identify"
+	"github.com/Napageneral/mnemonic/internal/todos"
+)
+
+// Built-in analysis types.
+const (
+	PIIAnalysisType          = "pii_extraction"
+	SentimentAnalysisType    = "sentiment"
+	TopicTaggingAnalysisType = "topic_tagging"
+	ActionItemsAnalysisType  = "action_items"
+)
+
+// AnalysisRun is a completed analysis run handed to a sync handler.
+type AnalysisRun struct {
+	ID        string
+	EpisodeID string
+	Output    string
+}
+
+// AnalysisSyncFunc turns a completed run's output into durable records
+// beyond facets (e.g., person facts). It must be safe to retry.
+type AnalysisSyncFunc func(ctx context.Context, db *sql.DB, run AnalysisRun) error
+
+// AnalysisSpec describes a pluggable analysis type: how to prompt for it,
+// what its output looks like, and what to do with completed runs.
+type AnalysisSpec struct {
+	Name        string
+	Version     string
+	Description string
+	OutputType  string // "structured" or "freeform"
+
+	// Prompt is the template; {{{segment_text}}} is replaced with the episode
+	// text. Empty means the type's row is seeded elsewhere (compute seed).
+	Prompt       string
+	FacetsConfig string         // facet mappings for structured output (JSON)
+	Schema       map[string]any // response JSON schema; nil = none
+
+	MaskSpeakers bool             // anonymize speaker labels in the episode text
+	Sync         AnalysisSyncFunc // nil = facets only
+}
+
+var (
+	analysisRegistryMu sync.RWMutex
+	analysisRegistry   = map[string]AnalysisSpec{}
+)
+
+// RegisterAnalysis adds or replaces an analysis type in the registry.
+func RegisterAnalysis(spec AnalysisSpec) {
+	if spec.Name == "" {
+		panic("compute: analysis spec without a name")
+	}
+	analysisRegistryMu.Lock()
+	defer analysisRegistryMu.Unlock()
+	analysisRegistry[spec.Name] = spec
+}
+
+// LookupAnalysis returns the registered analysis type with the given name.
+func LookupAnalysis(name string) (AnalysisSpec, bool) {
+	analysisRegistryMu.RLock()
+	defer analysisRegistryMu.RUnlock()
+	spec, ok := analysisRegistry[name]
+	return spec, ok
+}
+
+// RegisteredAnalyses returns all registered analysis types, by name.
+func RegisteredAnalyses() []AnalysisSpec {
+	analysisRegistryMu.RLock()
+	defer analysisRegistryMu.RUnlock()
+	specs := make([]AnalysisSpec, 0, len(analysisRegistry))
+	for _, spec := range analysisRegistry {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+// EnsureAnalysisType makes sure the spec has an analysis_types row, updating
+// the prompt and facet config when the spec's version changes. Returns the
+// row's ID.
+func EnsureAnalysisType(ctx context.Context, db *sql.DB, spec AnalysisSpec) (string, error) {
+	if spec.Prompt != "" {
+		now := time.Now().Unix()
+		if _, err := db.ExecContext(ctx, `
+			INSERT INTO analysis_types (id, name, version, description, output_type, facets_config_json, prompt_template, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(name) DO UPDATE SET
+				version = excluded.version,
+				description = excluded.description,
+				output_type = excluded.output_type,
+				facets_config_json = excluded.facets_config_json,
+				prompt_template = excluded.prompt_template,
+				updated_at = excluded.updated_at
+			WHERE analysis_types.version != excluded.version
+		`, spec.Name, spec.Name, spec.Version, spec.Description, spec.OutputType,
+			nullIfEmpty(spec.FacetsConfig), spec.Prompt, now, now); err != nil {
+			return "", fmt.Errorf("register analysis type %s: %w", spec.Name, err)
+		}
+	}
+
+	var id string
+	err := db.QueryRowContext(ctx, `SELECT id FROM analysis_types WHERE name = ?`, spec.Name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("analysis type %s not found (run 'mnemonic compute seed')", spec.Name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("get analysis type: %w", err)
+	}
+	return id, nil
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func init() {
+	RegisterAnalysis(AnalysisSpec{
+		Name:         PIIAnalysisType,
+		Version:      "1.0.0",
+		Description:  "Extract all PII from segments for identity resolution",
+		OutputType:   "structured",
+		MaskSpeakers: true,
+		Sync: func(ctx context.Context, db *sql.DB, run AnalysisRun) error {
+			_, err := identify.SyncSingleRun(db, run.ID)
+			return err
+		},
+	})
+
+	RegisterAnalysis(AnalysisSpec{
+		Name:        SentimentAnalysisType,
+		Version:     "1.0.0",
+		Description: "Overall and per-participant sentiment",
+		OutputType:  "structured",
+		Prompt: `# Sentiment Analysis
+
+Read the conversation and judge its emotional tone.
+
+Return JSON with:
+- "overall": one of "positive", "neutral", "negative", "mixed"
+- "score": a number from -1 (very negative) to 1 (very positive)
+- "participants": for each speaker, {"label": speaker label as shown, "sentiment": one of the overall values}
+
+Conversation:
+{{{segment_text}}}`,
+		FacetsConfig: `{
+			"mappings": [
+				{"json_path": "overall", "facet_type": "sentiment"},
+				{"json_path": "participants[].sentiment", "facet_type": "participant_sentiment"}
+			]
+		}`,
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"overall": map[string]any{"type": "string", "enum": []string{"positive", "neutral", "negative", "mixed"}},
+				"score":   map[string]any{"type": "number"},
+				"participants": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"label":     map[string]any{"type": "string"},
+							"sentiment": map[string]any{"type": "string", "enum": []string{"positive", "neutral", "negative", "mixed"}},
+						},
+						"required": []string{"label", "sentiment"},
+					},
+				},
+			},
+			"required": []string{"overall", "score"},
+		},
+	})
+
+	RegisterAnalysis(AnalysisSpec{
+		Name:        TopicTaggingAnalysisType,
+		Version:     "1.0.0",
+		Description: "Short topic tags for browsing and search",
+		OutputType:  "structured",
+		Prompt: `# Topic Tagging
+
+Tag the conversation with 1-5 short, lowercase topics (1-3 words each, e.g.
+"travel plans", "job search", "birthday"). Prefer specific topics over
+generic ones like "chat" or "catching up". Skip topics only mentioned in passing.
+
+Return JSON: {"topics": [{"name": "...", "confidence": 0.0-1.0}]}
+
+Conversation:
+{{{segment_text}}}`,
+		FacetsConfig: `{
+			"mappings": [
+				{"json_path": "topics[].name", "facet_type": "topic_tag"}
+			]
+		}`,
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"topics": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"name":       map[string]any{"type": "string"},
+							"confidence": map[string]any{"type": "number"},
+						},
+						"required": []string{"name"},
+					},
+				},
+			},
+			"required": []string{"topics"},
+		},
+	})
+
+	RegisterAnalysis(AnalysisSpec{
+		Name:        ActionItemsAnalysisType,
+		Version:     "1.0.0",
+		Description: "Commitments and requests made in the conversation",
+		OutputType:  "structured",
+		Prompt: `# Action Item Extraction
+
+Find concrete commitments and requests in the conversation: things someone said
+they will do ("I'll send the contract Monday") or asked someone else to do.
+Ignore vague intentions ("we should hang out sometime") and things already done.
+
+For each action item return:
+- "description": what needs doing, as a short imperative ("Send the contract")
+- "owner": "user" if the user committed to it, "other" if someone else did
+- "owner_ref": the speaker label or name of the owner
+- "requested_by_ref": who asked for it, if anyone
+- "due": the due date as YYYY-MM-DD if stated or clearly implied, else ""
+- "evidence": the exact quote
+- "confidence": 0.0-1.0
+
+Return JSON: {"action_items": [...]}
+
+Conversation:
+{{{segment_text}}}`,
+		FacetsConfig: `{
+			"mappings": [
+				{"json_path": "action_items[].description", "facet_type": "action_item"}
+			]
+		}`,
+		Sync: func(ctx context.Context, db *sql.DB, run AnalysisRun) error {
+			_, err := todos.SyncRun(db, run.ID, run.EpisodeID, extractJSON(run.Output))
+			return err
+		},
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"action_items": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"description":      map[string]any{"type": "string"},
+							"owner":            map[string]any{"type": "string", "enum": []string{"user", "other"}},
+							"owner_ref":        map[string]any{"type": "string"},
+							"requested_by_ref": map[string]any{"type": "string"},
+							"due":              map[string]any{"type": "string"},
+							"evidence":         map[string]any{"type": "string"},
+							"confidence":       map[string]any{"type": "number"},
+						},
+						"required": []string{"description", "owner"},
+					},
+				},
+			},
+			"required": []string{"action_items"},
+		},
+	})
+}
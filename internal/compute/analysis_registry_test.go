@@ -0,0 +1,80 @@
+package compute
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestAnalysisRegistry_EnsureAndSync(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	var synced []string
+	spec := AnalysisSpec{
+		Name:       "test_analysis",
+		Version:    "1.0.0",
+		OutputType: "structured",
+		Prompt:     "v1 {{{segment_text}}}",
+		Sync: func(ctx context.Context, db *sql.DB, run AnalysisRun) error {
+			synced = append(synced, run.EpisodeID)
+			return nil
+		},
+	}
+	RegisterAnalysis(spec)
+	if got, ok := LookupAnalysis("test_analysis"); !ok || got.Version != "1.0.0" {
+		t.Fatalf("lookup = %+v, %v", got, ok)
+	}
+
+	typeID, err := EnsureAnalysisType(ctx, db, spec)
+	if err != nil {
+		t.Fatalf("EnsureAnalysisType: %v", err)
+	}
+	// Same version keeps the stored prompt; a new version replaces it
+	spec.Prompt = "v1 edited {{{segment_text}}}"
+	EnsureAnalysisType(ctx, db, spec)
+	spec.Version, spec.Prompt = "1.1.0", "v2 {{{segment_text}}}"
+	RegisterAnalysis(spec)
+	if _, err := EnsureAnalysisType(ctx, db, spec); err != nil {
+		t.Fatalf("EnsureAnalysisType v2: %v", err)
+	}
+	var prompt string
+	db.QueryRow(`SELECT prompt_template FROM analysis_types WHERE id = ?`, typeID).Scan(&prompt)
+	if prompt != "v2 {{{segment_text}}}" {
+		t.Errorf("prompt = %q, want v2", prompt)
+	}
+
+	if _, err := EnsureAnalysisType(ctx, db, AnalysisSpec{Name: "unseeded"}); err == nil {
+		t.Error("a spec without a prompt or row should fail")
+	}
+
+	db.Exec(`INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0)`)
+	for i, ep := range []string{"ep-done", "ep-failed", "ep-new", "ep-other"} {
+		channel := "imessage"
+		if ep == "ep-other" {
+			channel = "gmail"
+		}
+		db.Exec(`INSERT INTO episodes (id, definition_id, channel, start_time, end_time, event_count, created_at) VALUES (?, 'def', ?, ?, ?, 1, 0)`, ep, channel, i, i)
+	}
+	db.Exec(`INSERT INTO analysis_runs (id, analysis_type_id, episode_id, status, output_text, completed_at, created_at) VALUES ('r1', ?, 'ep-done', 'completed', '{}', 1, 0)`, typeID)
+	db.Exec(`INSERT INTO analysis_runs (id, analysis_type_id, episode_id, status, created_at) VALUES ('r2', ?, 'ep-failed', 'failed', 0)`, typeID)
+
+	pending, err := PendingEpisodes(ctx, db, "test_analysis", AnalysisBatchOptions{Channel: "imessage"})
+	if err != nil {
+		t.Fatalf("PendingEpisodes: %v", err)
+	}
+	if len(pending) != 2 || pending[0] != "ep-new" || pending[1] != "ep-failed" {
+		t.Errorf("pending = %v, want [ep-new ep-failed]", pending)
+	}
+
+	n, failed, err := SyncAnalysisRuns(ctx, db, "test_analysis")
+	if err != nil || n != 1 || failed != 0 || len(synced) != 1 || synced[0] != "ep-done" {
+		t.Fatalf("first sync = %d, %d, %v (synced %v)", n, failed, err, synced)
+	}
+	if n, _, _ := SyncAnalysisRuns(ctx, db, "test_analysis"); n != 0 {
+		t.Errorf("second sync = %d, want 0", n)
+	}
+}
@@ -15,7 +15,14 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Napageneral/mnemonic/internal/chunk"
+	"github.com/Napageneral/mnemonic/internal/drafts"
 	"github.com/Napageneral/mnemonic/internal/gemini"
+	"github.com/Napageneral/mnemonic/internal/me"
+	"github.com/Napageneral/mnemonic/internal/memory"
+	"github.com/Napageneral/mnemonic/internal/notes"
+	"github.com/Napageneral/mnemonic/internal/plugin"
+	"github.com/Napageneral/mnemonic/internal/power"
 	"github.com/Napageneral/taskengine/engine"
 	"github.com/Napageneral/taskengine/queue"
 	"github.com/google/uuid"
@@ -48,6 +55,10 @@ type Engine struct {
 	// Embedding batcher for high-throughput batch API calls
 	embeddingBatcher *EmbeddingsBatcher
 
+	power *power.Gate
+
+	postProcessors []*plugin.Plugin
+
 	// Pre-encoded episode cache for high-throughput bulk processing
 	// Maps episode_id -> encoded text
 	episodeTextCache   map[string]string
@@ -69,6 +80,12 @@ type Config struct {
 
 	// Disable adaptive concurrency controller (no in-flight throttling)
 	DisableAdaptive bool
+
+	// Power pauses or throttles jobs on battery or while the user is active (nil = always run)
+	Power *power.Gate
+
+	// PostProcessors rewrite analysis output before it is stored, in order
+	PostProcessors []*plugin.Plugin
 }
 
 // DefaultConfig returns sensible defaults optimized for high-throughput processing
@@ -119,6 +136,8 @@ func NewEngine(db *sql.DB, geminiClient *gemini.Client, cfg Config) (*Engine, er
 		metrics:        NewJobMetrics(),
 		analysisModel:  cfg.AnalysisModel,
 		embeddingModel: cfg.EmbeddingModel,
+		power:          cfg.Power,
+		postProcessors: cfg.PostProcessors,
 	}
 
 	// Initialize TxBatchWriter if enabled
@@ -171,6 +190,11 @@ func NewEngine(db *sql.DB, geminiClient *gemini.Client, cfg Config) (*Engine, er
 // wrapHandler wraps a job handler with adaptive control (semaphore + observation)
 func (e *Engine) wrapHandler(base func(context.Context, *queue.Job) error, jobType string) func(context.Context, *queue.Job) error {
 	return func(ctx context.Context, job *queue.Job) error {
+		// Hold off while on battery or the user is active, per power policy
+		if err := e.power.Wait(ctx, jobType); err != nil {
+			return err
+		}
+
 		// Acquire semaphore if adaptive control is enabled
 		if e.sem != nil {
 			if err := e.sem.Acquire(ctx); err != nil {
@@ -382,7 +406,7 @@ func (e *Engine) EnqueueAnalysis(ctx context.Context, analysisTypeName string, e
 	if len(episodeIDs) > 0 {
 		epIDs = episodeIDs
 	} else {
-		// Find episodes without analysis runs for this type
+		// Find episodes of each channel's primary definition without analysis runs for this type
 		// Collect all IDs first, then close rows before enqueueing (SQLite deadlock avoidance)
 		rows, err := e.db.QueryContext(ctx, `
 			SELECT ep.id FROM episodes ep
@@ -391,7 +415,7 @@ func (e *Engine) EnqueueAnalysis(ctx context.Context, analysisTypeName string, e
 				WHERE ar.episode_id = ep.id
 				AND ar.analysis_type_id = ?
 			)
-		`, analysisTypeID)
+			AND `+chunk.PrimaryEpisodeCondition("ep"), analysisTypeID)
 		if err != nil {
 			return 0, fmt.Errorf("query episodes: %w", err)
 		}
@@ -643,6 +667,64 @@ func (e *Engine) EnqueueDocumentEmbeddings(ctx context.Context) (int, error) {
 	return count, nil
 }
 
+// EnqueueEntityEmbeddings queues embedding jobs for memory graph entities
+// that have no embedding or whose canonical name changed since embedding.
+func (e *Engine) EnqueueEntityEmbeddings(ctx context.Context) (int, error) {
+	rows, err := e.db.QueryContext(ctx, `
+		SELECT ent.id, ent.canonical_name, em.source_text_hash
+		FROM entities ent
+		LEFT JOIN embeddings em
+		  ON em.target_type = ?
+		 AND em.target_id = ent.id
+		 AND em.model = ?
+		WHERE ent.merged_into IS NULL
+	`, memory.TargetTypeEntity, e.embeddingModel)
+	if err != nil {
+		return 0, fmt.Errorf("query entities: %w", err)
+	}
+
+	type staleEntity struct{ id, name string }
+	var stale []staleEntity
+	for rows.Next() {
+		var id, name string
+		var sourceHash sql.NullString
+		if err := rows.Scan(&id, &name, &sourceHash); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		name = strings.TrimSpace(name)
+		if name == "" || (sourceHash.Valid && sourceHash.String == hashText(name)) {
+			continue
+		}
+		stale = append(stale, staleEntity{id: id, name: name})
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, err
+	}
+	rows.Close()
+
+	count := 0
+	for _, ent := range stale {
+		payload := EmbeddingJobPayload{
+			EntityType: memory.TargetTypeEntity,
+			EntityID:   ent.id,
+		}
+
+		if err := e.queue.Enqueue(queue.EnqueueOptions{
+			Type:    JobTypeEmbedding,
+			Key:     memory.EntityEmbeddingJobKey(ent.id, ent.name),
+			Payload: payload,
+		}); err != nil {
+			log.Printf("failed to enqueue entity embedding for %s: %v", ent.id, err)
+			continue
+		}
+		count++
+	}
+
+	return count, nil
+}
+
 // AnalysisJobPayload for analysis jobs
 type AnalysisJobPayload struct {
 	EpisodeID      string `json:"segment_id"` // JSON key kept as segment_id for backward compat with queued jobs
@@ -657,6 +739,16 @@ type EmbeddingJobPayload struct {
 
 // handleAnalysisJob processes an analysis job
 func (e *Engine) handleAnalysisJob(ctx context.Context, job *queue.Job) error {
+	var payload AnalysisJobPayload
+	if err := json.Unmarshal([]byte(job.PayloadJSON), &payload); err != nil {
+		return fmt.Errorf("parse payload: %w", err)
+	}
+	return e.runAnalysis(ctx, payload)
+}
+
+// runAnalysis analyzes one episode, recording the outcome in analysis_runs
+// and any facets the analysis type extracts.
+func (e *Engine) runAnalysis(ctx context.Context, payload AnalysisJobPayload) error {
 	overallStart := time.Now()
 	var (
 		dbReadDur     time.Duration
@@ -680,11 +772,6 @@ func (e *Engine) handleAnalysisJob(ctx context.Context, job *queue.Job) error {
 		})
 	}()
 
-	var payload AnalysisJobPayload
-	if err := json.Unmarshal([]byte(job.PayloadJSON), &payload); err != nil {
-		return fmt.Errorf("parse payload: %w", err)
-	}
-
 	// Get analysis type config
 	t0 := time.Now()
 	var promptTemplate, outputType, analysisTypeName string
@@ -702,7 +789,9 @@ func (e *Engine) handleAnalysisJob(ctx context.Context, job *queue.Job) error {
 	episodeID := payload.EpisodeID
 	t1 := time.Now()
 	var epText string
-	if analysisTypeName == "pii_extraction" {
+	spec, hasSpec := LookupAnalysis(analysisTypeName)
+	masked := hasSpec && spec.MaskSpeakers
+	if masked {
 		var err error
 		epText, err = e.buildEpisodeTextMasked(ctx, episodeID)
 		if err != nil {
@@ -725,6 +814,17 @@ func (e *Engine) handleAnalysisJob(ctx context.Context, job *queue.Job) error {
 			}
 		}
 	}
+	// Notes the user attached to the episode steer the analysis. Masked
+	// text gets none: a note naming people would undo the masking.
+	if !masked {
+		episodeNotes, err := notes.ForEpisode(ctx, e.db, episodeID)
+		if err != nil {
+			return fmt.Errorf("load episode notes: %w", err)
+		}
+		if section := notes.PromptSection(episodeNotes); section != "" {
+			epText += "\n\n" + section
+		}
+	}
 	textBuildDur = time.Since(t1)
 
 	// Build prompt (template uses {{{segment_text}}} for backward compatibility)
@@ -789,6 +889,13 @@ func (e *Engine) handleAnalysisJob(ctx context.Context, job *queue.Job) error {
 			`, err.Error(), time.Now().Unix(), runID)
 			return fmt.Errorf("build local output: %w", err)
 		}
+		if outputText, err = e.postProcess(ctx, analysisTypeName, outputType, episodeID, outputText); err != nil {
+			e.db.ExecContext(ctx, `
+				UPDATE analysis_runs SET status = 'failed', error_message = ?, completed_at = ?
+				WHERE id = ?
+			`, err.Error(), time.Now().Unix(), runID)
+			return err
+		}
 
 		tWrite := time.Now()
 		if facetsConfigJSON.Valid {
@@ -873,6 +980,13 @@ func (e *Engine) handleAnalysisJob(ctx context.Context, job *queue.Job) error {
 		`, time.Now().Unix(), runID)
 		return fmt.Errorf("empty model output")
 	}
+	if outputText, err = e.postProcess(ctx, analysisTypeName, outputType, episodeID, outputText); err != nil {
+		e.db.ExecContext(ctx, `
+			UPDATE analysis_runs SET status = 'failed', error_message = ?, completed_at = ?
+			WHERE id = ?
+		`, err.Error(), time.Now().Unix(), runID)
+		return err
+	}
 
 	// Persist results
 	t4 := time.Now()
@@ -936,6 +1050,8 @@ func (e *Engine) handleEmbeddingJob(ctx context.Context, job *queue.Job) error {
 		text, err = e.buildPersonText(ctx, payload.EntityID)
 	case "document":
 		text, err = e.buildDocumentText(ctx, payload.EntityID)
+	case memory.TargetTypeEntity:
+		text, err = e.buildEntityText(ctx, payload.EntityID)
 	default:
 		return fmt.Errorf("unsupported entity type: %s", payload.EntityType)
 	}
@@ -1012,15 +1128,29 @@ func (e *Engine) handleEmbeddingJob(ctx context.Context, job *queue.Job) error {
 	return writeErr
 }
 
+// latestRevisionSQL selects a column of the newest edit or unsend
+// (tombstone) superseding event e; NULL if the message was never changed.
+func latestRevisionSQL(column string) string {
+	return `(SELECT r.` + column + ` FROM events r WHERE r.supersedes = e.id ORDER BY r.timestamp DESC, r.id DESC LIMIT 1)`
+}
+
 // buildEpisodeText builds text representation of an episode
 // Format matches Eve's encoding: "Name: message text [Image] [Attachment: file.pdf]"
+// Edited messages show their newest revision marked "(edited)"; unsent ones show "(unsent)".
+// Drafts are labeled "(draft, not sent)" after the name.
 // Attachments are encoded as [Image], [Video], [Audio], [Sticker], or [Attachment: filename]
+// EpisodeText returns an episode as analysis sees it: a line per message
+// with its sender, edits and unsends in place of the original text.
+func EpisodeText(ctx context.Context, db *sql.DB, episodeID string) (string, error) {
+	return (&Engine{db: db}).buildEpisodeText(ctx, episodeID)
+}
+
 func (e *Engine) buildEpisodeText(ctx context.Context, episodeID string) (string, error) {
 	// Query events with aggregated attachment info
 	rows, err := e.db.QueryContext(ctx, `
 		SELECT
 			e.id,
-			e.content,
+			COALESCE(`+latestRevisionSQL("content")+`, e.content),
 			e.timestamp,
 			e.thread_id,
 			COALESCE(p.canonical_name, c.display_name,
@@ -1060,7 +1190,8 @@ func (e *Engine) buildEpisodeText(ctx context.Context, episodeID string) (string
 					END, '|'
 				)
 				FROM attachments a WHERE a.event_id = e.id
-			) as attachments
+			) as attachments,
+			`+latestRevisionSQL("direction")+` as revision
 		FROM episode_events ee
 		JOIN events e ON ee.event_id = e.id
 		LEFT JOIN event_participants ep ON e.id = ep.event_id AND ep.role = 'sender'
@@ -1094,8 +1225,9 @@ func (e *Engine) buildEpisodeText(ctx context.Context, episodeID string) (string
 		var replyTo sql.NullString
 		var members sql.NullString
 		var attachments sql.NullString
+		var revision sql.NullString
 
-		if err := rows.Scan(&eventID, &content, &timestamp, &threadID, &senderName, &direction, &contentTypes, &metadataJSON, &replyTo, &members, &attachments); err != nil {
+		if err := rows.Scan(&eventID, &content, &timestamp, &threadID, &senderName, &direction, &contentTypes, &metadataJSON, &replyTo, &members, &attachments, &revision); err != nil {
 			return "", err
 		}
 
@@ -1148,6 +1280,14 @@ func (e *Engine) buildEpisodeText(ctx context.Context, episodeID string) (string
 			continue
 		}
 
+		if drafts.IsDraft(direction) {
+			name += " (" + drafts.Label + ")"
+		}
+		if revision.String == "deleted" {
+			sb.WriteString(fmt.Sprintf("%s: (unsent)\n", name))
+			continue
+		}
+
 		// Build message parts
 		var parts []string
 
@@ -1187,6 +1327,9 @@ func (e *Engine) buildEpisodeText(ctx context.Context, episodeID string) (string
 		}
 
 		// Only write line if there's content
+		if revision.Valid && len(parts) > 0 {
+			parts = append(parts, "(edited)")
+		}
 		if len(parts) > 0 {
 			sb.WriteString(fmt.Sprintf("%s: %s\n", name, strings.Join(parts, " ")))
 		}
@@ -1203,7 +1346,7 @@ func (e *Engine) buildEpisodeText(ctx context.Context, episodeID string) (string
 // buildTurnQualityText builds a compact turn-quality input using user messages only.
 func (e *Engine) buildTurnQualityText(ctx context.Context, episodeID string) (string, error) {
 	rows, err := e.db.QueryContext(ctx, `
-		SELECT e.content, e.direction
+		SELECT COALESCE(`+latestRevisionSQL("content")+`, e.content), e.direction
 		FROM episode_events ee
 		JOIN events e ON ee.event_id = e.id
 		WHERE ee.episode_id = ?
@@ -1245,7 +1388,7 @@ func (e *Engine) buildEpisodeTextMasked(ctx context.Context, episodeID string) (
 	rows, err := e.db.QueryContext(ctx, `
 		SELECT
 			e.id,
-			e.content,
+			COALESCE(`+latestRevisionSQL("content")+`, e.content),
 			e.timestamp,
 			e.thread_id,
 			ep.contact_id,
@@ -1278,7 +1421,8 @@ func (e *Engine) buildEpisodeTextMasked(ctx context.Context, episodeID string) (
 					END, '|'
 				)
 				FROM attachments a WHERE a.event_id = e.id
-			) as attachments
+			) as attachments,
+			`+latestRevisionSQL("direction")+` as revision
 		FROM episode_events ee
 		JOIN events e ON ee.event_id = e.id
 		LEFT JOIN event_participants ep ON e.id = ep.event_id AND ep.role = 'sender'
@@ -1314,8 +1458,9 @@ func (e *Engine) buildEpisodeTextMasked(ctx context.Context, episodeID string) (
 		var replyTo sql.NullString
 		var members sql.NullString
 		var attachments sql.NullString
+		var revision sql.NullString
 
-		if err := rows.Scan(&eventID, &content, &timestamp, &threadID, &senderID, &isMe, &direction, &contentTypes, &metadataJSON, &replyTo, &members, &attachments); err != nil {
+		if err := rows.Scan(&eventID, &content, &timestamp, &threadID, &senderID, &isMe, &direction, &contentTypes, &metadataJSON, &replyTo, &members, &attachments, &revision); err != nil {
 			return "", err
 		}
 		timestampStr := time.Unix(timestamp, 0).UTC().Format(time.RFC3339)
@@ -1383,6 +1528,14 @@ func (e *Engine) buildEpisodeTextMasked(ctx context.Context, episodeID string) (
 			continue
 		}
 
+		if drafts.IsDraft(direction) {
+			name += " (" + drafts.Label + ")"
+		}
+		if revision.String == "deleted" {
+			sb.WriteString(fmt.Sprintf("%s: (unsent)\n", name))
+			continue
+		}
+
 		var parts []string
 		if content.Valid && content.String != "" {
 			parts = append(parts, content.String)
@@ -1416,6 +1569,9 @@ func (e *Engine) buildEpisodeTextMasked(ctx context.Context, episodeID string) (
 			}
 		}
 
+		if revision.Valid && len(parts) > 0 {
+			parts = append(parts, "(edited)")
+		}
 		if len(parts) > 0 {
 			sb.WriteString(fmt.Sprintf("%s: %s\n", name, strings.Join(parts, " ")))
 		}
@@ -1585,15 +1741,15 @@ func overrideMembershipAction(metadataJSON sql.NullString, action string) sql.Nu
 }
 
 func getSelfName(ctx context.Context, db *sql.DB) string {
-	var name sql.NullString
-	_ = db.QueryRowContext(ctx, `
-		SELECT COALESCE(canonical_name, display_name, '')
-		FROM persons
-		WHERE is_me = 1
-		LIMIT 1
-	`).Scan(&name)
-	if name.Valid && strings.TrimSpace(name.String) != "" {
-		return strings.TrimSpace(name.String)
+	person, err := me.GetMePerson(db)
+	if err != nil || person == nil {
+		return ""
+	}
+	if name := strings.TrimSpace(person.CanonicalName); name != "" {
+		return name
+	}
+	if person.DisplayName != nil {
+		return strings.TrimSpace(*person.DisplayName)
 	}
 	return ""
 }
@@ -1692,6 +1848,22 @@ func (e *Engine) buildFacetText(ctx context.Context, facetID string) (string, er
 	return fmt.Sprintf("%s: %s", facetType, value), nil
 }
 
+// buildEntityText returns the text embedded for a memory graph entity: its
+// canonical name, matching what the memory pipeline embeds inline.
+func (e *Engine) buildEntityText(ctx context.Context, entityID string) (string, error) {
+	var canonicalName string
+	err := e.db.QueryRowContext(ctx, `
+		SELECT canonical_name FROM entities WHERE id = ? AND merged_into IS NULL
+	`, entityID).Scan(&canonicalName)
+	if err == sql.ErrNoRows {
+		return "", nil // Merged away or deleted - nothing to embed
+	}
+	if err != nil {
+		return "", fmt.Errorf("get entity: %w", err)
+	}
+	return canonicalName, nil
+}
+
 // buildPersonText builds text representation of a person for embedding.
 // Includes name and linked contact identifiers/facts.
 func (e *Engine) buildPersonText(ctx context.Context, personID string) (string, error) {
@@ -1784,6 +1956,47 @@ func (e *Engine) buildDocumentText(ctx context.Context, docKey string) (string,
 	return sb.String(), nil
 }
 
+// postProcessParams are sent to post-processor plugins.
+type postProcessParams struct {
+	AnalysisType string `json:"analysis_type"`
+	OutputType   string `json:"output_type"`
+	EpisodeID    string `json:"episode_id"`
+	Output       string `json:"output"`
+}
+
+// postProcessResult is a post-processor's answer; a missing output keeps
+// the output unchanged.
+type postProcessResult struct {
+	Output *string `json:"output"`
+}
+
+// postProcess passes analysis output through the post-processor plugins
+// that handle this analysis type, each getting the previous one's output.
+func (e *Engine) postProcess(ctx context.Context, analysisType, outputType, episodeID, outputText string) (string, error) {
+	for _, p := range e.postProcessors {
+		if !p.Handles(analysisType) {
+			continue
+		}
+		raw, err := p.Call(ctx, postProcessParams{
+			AnalysisType: analysisType,
+			OutputType:   outputType,
+			EpisodeID:    episodeID,
+			Output:       outputText,
+		}, nil)
+		if err != nil {
+			return "", fmt.Errorf("post-process output: %w", err)
+		}
+		var res postProcessResult
+		if err := json.Unmarshal(raw, &res); err != nil {
+			return "", fmt.Errorf("post-process output: plugin %s: invalid result: %w", p.Name, err)
+		}
+		if res.Output != nil {
+			outputText = *res.Output
+		}
+	}
+	return outputText, nil
+}
+
 // extractAndPersistFacets parses structured output and saves facets
 func (e *Engine) extractAndPersistFacets(ctx context.Context, runID, episodeID, outputText, facetsConfig string) error {
 	// Parse the JSON output (object or array)
@@ -2034,6 +2247,9 @@ func normalizeAnalysisModel(model string) (string, error) {
 // getResponseSchema returns the Gemini response schema for known analysis types
 // This enforces JSON structure at the API level for more reliable output parsing
 func getResponseSchema(analysisTypeName string) any {
+	if spec, ok := LookupAnalysis(analysisTypeName); ok && spec.Schema != nil {
+		return spec.Schema
+	}
 	switch analysisTypeName {
 	case "convo-all-v1":
 		// Schema for segment analysis: summary, entities, topics, emotions, humor
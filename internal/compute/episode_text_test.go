@@ -0,0 +1,50 @@
+package compute
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestBuildEpisodeTextLabels(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec(`
+		INSERT INTO contacts (id, display_name, created_at, updated_at) VALUES ('c1', 'Casey', 0, 0);
+		INSERT INTO threads (id, channel, source_adapter, source_id, created_at, updated_at) VALUES ('t1', 'imessage', 'imessage', 't1', 0, 0);
+		INSERT INTO events (id, timestamp, channel, content_types, content, direction, thread_id, source_adapter, source_id, supersedes) VALUES
+			('m1', 100, 'imessage', '["text"]', 'see you at 5', 'received', 't1', 'imessage', 'm1', NULL),
+			('m2', 110, 'imessage', '["text"]', 'wrong chat', 'received', 't1', 'imessage', 'm2', NULL),
+			('m3', 120, 'imessage', '["text"]', 'ok', 'received', 't1', 'imessage', 'm3', NULL),
+			('d1', 130, 'draft', '["text"]', 'I got the job', 'draft', 't1', 'drafts', 'd1', NULL),
+			('m1:rev:1', 200, 'imessage', '["text"]', 'see you at 6', 'received', 't1', 'imessage', 'm1:rev:1', 'm1'),
+			('m2:tombstone', 210, 'imessage', '["tombstone"]', '', 'deleted', 't1', 'imessage', 'm2:tombstone', 'm2');
+		INSERT INTO event_participants (event_id, contact_id, role) VALUES ('m1', 'c1', 'sender'), ('m2', 'c1', 'sender'), ('m3', 'c1', 'sender'), ('d1', 'c1', 'sender');
+		INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0);
+		INSERT INTO episodes (id, definition_id, channel, thread_id, start_time, end_time, event_count, created_at) VALUES ('ep1', 'def', 'imessage', 't1', 100, 130, 4, 0);
+		INSERT INTO episode_events (episode_id, event_id, position) VALUES ('ep1', 'm1', 1), ('ep1', 'm2', 2), ('ep1', 'm3', 3), ('ep1', 'd1', 4);
+	`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	e := &Engine{db: db}
+	text, err := e.buildEpisodeText(context.Background(), "ep1")
+	if err != nil {
+		t.Fatalf("buildEpisodeText: %v", err)
+	}
+	want := "Casey: see you at 6 (edited)\nCasey: (unsent)\nCasey: ok\nCasey (draft, not sent): I got the job\n"
+	if text != want {
+		t.Errorf("text =\n%s\nwant\n%s", text, want)
+	}
+
+	masked, err := e.buildEpisodeTextMasked(context.Background(), "ep1")
+	if err != nil {
+		t.Fatalf("buildEpisodeTextMasked: %v", err)
+	}
+	if !strings.Contains(masked, "see you at 6 (edited)") || strings.Contains(masked, "wrong chat") {
+		t.Errorf("masked text = %q", masked)
+	}
+}
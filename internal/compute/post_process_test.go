@@ -0,0 +1,37 @@
+package compute
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/plugin"
+)
+
+func TestPostProcess(t *testing.T) {
+	postprocessor := func(name, body string, types ...string) *plugin.Plugin {
+		return &plugin.Plugin{Name: name, Kind: plugin.KindPostprocessor, Command: "sh", Args: []string{"-c", body}, Timeout: 10 * time.Second, AnalysisTypes: types}
+	}
+	e := &Engine{postProcessors: []*plugin.Plugin{
+		// Replaces the output with a redacted one
+		postprocessor("redact", `read req; echo '{"result":{"output":"{\"ssn\":\"[redacted]\"}"}}'`, "pii_extraction_v1"),
+		// Leaves the output alone
+		postprocessor("audit", `read req; echo '{"result":{}}'`),
+	}}
+	ctx := context.Background()
+
+	out, err := e.postProcess(ctx, "pii_extraction_v1", "structured", "ep1", `{"ssn":"123-45-6789"}`)
+	if err != nil || out != `{"ssn":"[redacted]"}` {
+		t.Errorf("pii output = %q, %v", out, err)
+	}
+	out, err = e.postProcess(ctx, "convo_all_v1", "structured", "ep1", `{"summary":"x"}`)
+	if err != nil || out != `{"summary":"x"}` {
+		t.Errorf("other output = %q, %v", out, err)
+	}
+
+	e.postProcessors = append(e.postProcessors, postprocessor("broken", `exit 1`))
+	if _, err := e.postProcess(ctx, "convo_all_v1", "structured", "ep1", "{}"); err == nil || !strings.Contains(err.Error(), "plugin broken") {
+		t.Errorf("failing post-processor: %v", err)
+	}
+}
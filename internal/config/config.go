@@ -4,21 +4,44 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the mnemonic configuration
 type Config struct {
-	Me       MeConfig                `yaml:"me"`
+	Me       MeConfig                 `yaml:"me"`
 	Adapters map[string]AdapterConfig `yaml:"adapters"`
+	Privacy  PrivacyConfig            `yaml:"privacy,omitempty"`
+	Power    PowerConfig              `yaml:"power,omitempty"`
+
+	Maintenance MaintenanceConfig `yaml:"maintenance,omitempty"`
+	Usage       UsageConfig       `yaml:"usage,omitempty"`
+	Memory      MemoryConfig      `yaml:"memory,omitempty"`
+
+	// Plugins are external programs speaking the plugin protocol (see
+	// internal/plugin), keyed by name
+	Plugins map[string]PluginConfig `yaml:"plugins,omitempty"`
+	// PluginDir holds plugin manifests and executables picked up by Load
+	// (default: plugins/ in the config directory)
+	PluginDir string `yaml:"plugin_dir,omitempty"`
+
+	// PluginErrors lists plugin directory entries Load could not use
+	PluginErrors []string `yaml:"-"`
+
+	// Entries Load added from the plugin directory; Save leaves them out
+	// unless they were changed
+	discoveredAdapters map[string]AdapterConfig
+	discoveredPlugins  map[string]PluginConfig
 }
 
 // MeConfig represents the user's identity
 type MeConfig struct {
 	CanonicalName string     `yaml:"canonical_name"`
 	Identities    []Identity `yaml:"identities"`
+	// Namespace names this user when several people share one database
+	// (a household instance). Each keeps their own "me" and sensitive facts.
+	Namespace string `yaml:"namespace,omitempty"`
 }
 
 // Identity represents a user identifier in a specific channel
@@ -27,6 +50,156 @@ type Identity struct {
 	Identifier string `yaml:"identifier"`
 }
 
+// PrivacyConfig gates extraction of sensitive personal data
+type PrivacyConfig struct {
+	// MedicalFacts enables storing self-disclosed allergies, medications,
+	// and conditions. Off unless explicitly enabled.
+	MedicalFacts bool `yaml:"medical_facts,omitempty"`
+}
+
+// MaintenanceConfig controls the background maintenance scheduler that
+// daemon mode (watch run) starts.
+type MaintenanceConfig struct {
+	Enabled     bool                             `yaml:"enabled,omitempty"`
+	Tasks       map[string]MaintenanceTaskConfig `yaml:"tasks,omitempty"`       // by task name
+	BackupDir   string                           `yaml:"backup_dir,omitempty"`  // default: <data dir>/backups
+	BackupKeep  int                              `yaml:"backup_keep,omitempty"` // default: 7
+	StaleFacts  StaleFactsConfig                 `yaml:"stale_facts,omitempty"`
+	Inference   InferenceConfig                  `yaml:"inference,omitempty"`
+	Constraints ConstraintsConfig                `yaml:"constraints,omitempty"`
+	Digest      DigestConfig                     `yaml:"digest,omitempty"`
+}
+
+// StaleFactsConfig controls which facts the stale_facts task re-verifies.
+type StaleFactsConfig struct {
+	After         string   `yaml:"after,omitempty"`          // unmentioned this long counts as stale; default 8760h (a year)
+	RelationTypes []string `yaml:"relation_types,omitempty"` // default LIVES_IN, WORKS_AT, DATING
+	Limit         int      `yaml:"limit,omitempty"`          // max pending verifications; default 20
+}
+
+// InferenceConfig sets the rules the inference task derives facts with.
+type InferenceConfig struct {
+	Rules []InferenceRuleConfig `yaml:"rules,omitempty"` // default: grandparent, shared_parent, sibling_of_sibling
+}
+
+// InferenceRuleConfig is one inference rule: a chain of premise relation
+// types and the relation type it implies between the chain's ends.
+type InferenceRuleConfig struct {
+	Name string   `yaml:"name"`
+	If   []string `yaml:"if"`
+	Then string   `yaml:"then"`
+}
+
+// ConstraintsConfig sets the graph constraints the constraints task checks.
+type ConstraintsConfig struct {
+	Rules []ConstraintRuleConfig `yaml:"rules,omitempty"` // default: one birthdate, birthplace and death date; exclusive, symmetric spouses
+}
+
+// ConstraintRuleConfig declares one constraint: at_most_one, exclusive or
+// symmetric, on a relation type.
+type ConstraintRuleConfig struct {
+	Name     string `yaml:"name"`
+	Kind     string `yaml:"kind"`
+	Relation string `yaml:"relation"`
+}
+
+// DigestConfig says where the digest task sends its daily report of what
+// the graph learned. Reports are always kept for 'mnemonic digest';
+// sending them is optional.
+type DigestConfig struct {
+	Webhook string            `yaml:"webhook,omitempty"` // URL the report is POSTed to as JSON
+	Email   DigestEmailConfig `yaml:"email,omitempty"`
+}
+
+// DigestEmailConfig sends the report by email through an SMTP server. The
+// password is read from the environment, never from the config file.
+type DigestEmailConfig struct {
+	To          []string `yaml:"to,omitempty"`
+	From        string   `yaml:"from,omitempty"`         // default: the first To address
+	SMTPServer  string   `yaml:"smtp_server,omitempty"`  // host:port, e.g. smtp.gmail.com:587
+	Username    string   `yaml:"username,omitempty"`     // default: From
+	PasswordEnv string   `yaml:"password_env,omitempty"` // default: MNEMONIC_SMTP_PASSWORD
+}
+
+// MaintenanceTaskConfig overrides one task's schedule. Durations use Go
+// syntax ("24h", "90m").
+type MaintenanceTaskConfig struct {
+	Enabled  *bool  `yaml:"enabled,omitempty"`
+	Interval string `yaml:"interval,omitempty"`
+	Jitter   string `yaml:"jitter,omitempty"`
+}
+
+// PowerConfig makes background jobs yield while a laptop is on battery or
+// in active use. Off unless explicitly enabled.
+type PowerConfig struct {
+	Enabled       bool                         `yaml:"enabled,omitempty"`
+	IdleAfter     string                       `yaml:"idle_after,omitempty"`     // no input for this long counts as idle; default 5m
+	ThrottleDelay string                       `yaml:"throttle_delay,omitempty"` // minimum gap between throttled jobs; default 2s
+	Default       PowerPolicyConfig            `yaml:"default,omitempty"`
+	Jobs          map[string]PowerPolicyConfig `yaml:"jobs,omitempty"` // by job type: analysis, embedding, or a maintenance task
+}
+
+// PowerPolicyConfig says what a job type does on battery and while the user
+// is active: "run", "throttle", or "pause".
+type PowerPolicyConfig struct {
+	OnBattery  string `yaml:"on_battery,omitempty"`  // default pause
+	WhenActive string `yaml:"when_active,omitempty"` // default throttle
+}
+
+// MemoryConfig tunes the memory extraction pipeline used by 'mnemonic
+// memory replay'.
+type MemoryConfig struct {
+	ModelRouting *ModelRoutingConfig `yaml:"model_routing,omitempty"` // unset: every episode uses the extraction model
+	// RelationTypes limits the relation types extracted per channel. A listed
+	// channel replaces its default (AI-session channels block HAS_EMAIL and
+	// HAS_PHONE); an empty entry lifts the default.
+	RelationTypes map[string]RelationTypesConfig `yaml:"relation_types,omitempty"`
+	// Cardinality overrides the default cardinality rules by relation type
+	// (one employer, one birthdate, ...). An empty entry removes a default rule.
+	Cardinality map[string]CardinalityConfig `yaml:"cardinality,omitempty"`
+}
+
+// CardinalityConfig declares how many current facts of a relation type an
+// entity may have.
+type CardinalityConfig struct {
+	Cardinality string `yaml:"cardinality,omitempty"` // one or many
+	// For one: invalidate ends the older fact (default); flag keeps both and
+	// records a violation for 'mnemonic memory violations'
+	Enforcement string `yaml:"enforcement,omitempty"`
+}
+
+// RelationTypesConfig is one channel's relation type policy. Blocked wins
+// over Allowed.
+type RelationTypesConfig struct {
+	Allowed []string `yaml:"allowed,omitempty"` // default: every type not blocked
+	Blocked []string `yaml:"blocked,omitempty"`
+}
+
+// ModelRoutingConfig sends long and group-chat episodes to a stronger model
+// than short 1:1 ones. Zero fields take the router's defaults.
+type ModelRoutingConfig struct {
+	CheapModel           string `yaml:"cheap_model,omitempty"`            // default: the extraction model
+	StrongModel          string `yaml:"strong_model,omitempty"`           // default: gemini-2.5-pro
+	MaxCheapTokens       int    `yaml:"max_cheap_tokens,omitempty"`       // longer episodes go strong; default 3000
+	MaxCheapParticipants int    `yaml:"max_cheap_participants,omitempty"` // more participants go strong; default 2
+}
+
+// UsageConfig sets API usage quotas. Usage is always tracked; quotas only
+// add warnings as usage approaches them.
+type UsageConfig struct {
+	Quotas map[string]QuotaConfig `yaml:"quotas,omitempty"`  // by provider, e.g. gemini
+	WarnAt float64                `yaml:"warn_at,omitempty"` // fraction of a quota that triggers a warning; default 0.8
+}
+
+// QuotaConfig is one provider's quota. Zero fields are unlimited. Tokens
+// count prompt and output tokens.
+type QuotaConfig struct {
+	DailyTokens   int64   `yaml:"daily_tokens,omitempty"`
+	MonthlyTokens int64   `yaml:"monthly_tokens,omitempty"`
+	DailyUSD      float64 `yaml:"daily_usd,omitempty"`
+	MonthlyUSD    float64 `yaml:"monthly_usd,omitempty"`
+}
+
 // AdapterConfig represents adapter configuration
 type AdapterConfig struct {
 	Type    string                 `yaml:"type"`
@@ -35,67 +208,35 @@ type AdapterConfig struct {
 	Options map[string]interface{} `yaml:"options,omitempty"`
 }
 
+// PluginConfig configures an external plugin program.
+type PluginConfig struct {
+	Kind    string            `yaml:"kind"` // adapter, postprocessor or exporter
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args,omitempty"`
+	Env     map[string]string `yaml:"env,omitempty"`
+	Timeout string            `yaml:"timeout,omitempty"` // per call, e.g. "30s" (default 10m)
+	// AnalysisTypes limits a post-processor to these analysis types (default: all)
+	AnalysisTypes []string `yaml:"analysis_types,omitempty"`
+	// Source is the manifest or executable a discovered plugin came from
+	Source string `yaml:"-"`
+}
+
 // LiveConfig controls live watching for an adapter.
 type LiveConfig struct {
 	Enabled bool                   `yaml:"enabled"`
 	Options map[string]interface{} `yaml:"options,omitempty"`
 }
 
-// GetConfigDir returns the XDG-compliant config directory
-func GetConfigDir() (string, error) {
-	// Explicit override (useful for tests and portable installs)
-	if override := os.Getenv("MNEMONIC_CONFIG_DIR"); override != "" {
-		return override, nil
-	}
-	// Legacy fallbacks for migration
-	if override := os.Getenv("CORTEX_CONFIG_DIR"); override != "" {
-		return override, nil
-	}
-	if override := os.Getenv("COMMS_CONFIG_DIR"); override != "" {
-		return override, nil
-	}
-
-	var base string
-	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
-		base = xdg
-	} else {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return "", fmt.Errorf("failed to get home directory: %w", err)
-		}
-		base = filepath.Join(home, ".config")
+// SetAdapterEnabled enables or disables a configured adapter. Call Save to
+// keep the change.
+func (c *Config) SetAdapterEnabled(name string, enabled bool) error {
+	adapter, ok := c.Adapters[name]
+	if !ok {
+		return fmt.Errorf("adapter '%s' not configured", name)
 	}
-	return filepath.Join(base, "mnemonic"), nil
-}
-
-// GetDataDir returns the platform-specific data directory
-func GetDataDir() (string, error) {
-	// Explicit override (useful for tests and portable installs)
-	if override := os.Getenv("MNEMONIC_DATA_DIR"); override != "" {
-		return override, nil
-	}
-	// Legacy fallbacks for migration
-	if override := os.Getenv("CORTEX_DATA_DIR"); override != "" {
-		return override, nil
-	}
-	if override := os.Getenv("COMMS_DATA_DIR"); override != "" {
-		return override, nil
-	}
-
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
-	}
-
-	if runtime.GOOS == "darwin" {
-		return filepath.Join(home, "Library", "Application Support", "Mnemonic"), nil
-	}
-
-	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
-		return filepath.Join(xdg, "mnemonic"), nil
-	}
-
-	return filepath.Join(home, ".local", "share", "mnemonic"), nil
+	adapter.Enabled = enabled
+	c.Adapters[name] = adapter
+	return nil
 }
 
 // Load loads config from the config file
@@ -111,9 +252,13 @@ func Load() (*Config, error) {
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Return default empty config
-			return &Config{
+			cfg := &Config{
 				Adapters: make(map[string]AdapterConfig),
-			}, nil
+			}
+			if err := cfg.loadPlugins(configDir); err != nil {
+				return nil, err
+			}
+			return cfg, nil
 		}
 		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
@@ -126,6 +271,9 @@ func Load() (*Config, error) {
 	if cfg.Adapters == nil {
 		cfg.Adapters = make(map[string]AdapterConfig)
 	}
+	if err := cfg.loadPlugins(configDir); err != nil {
+		return nil, err
+	}
 
 	return &cfg, nil
 }
@@ -143,7 +291,7 @@ func (c *Config) Save() error {
 
 	configPath := filepath.Join(configDir, "config.yaml")
 
-	data, err := yaml.Marshal(c)
+	data, err := yaml.Marshal(c.withoutDiscovered())
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
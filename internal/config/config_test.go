@@ -0,0 +1,30 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestSetAdapterEnabled(t *testing.T) {
+	t.Setenv("MNEMONIC_CONFIG_DIR", t.TempDir())
+	cfg := &Config{Adapters: map[string]AdapterConfig{
+		"gmail": {Type: "gogcli", Enabled: true, Options: map[string]interface{}{"account": "me@example.com"}},
+	}}
+	if err := cfg.SetAdapterEnabled("gmail", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.SetAdapterEnabled("slack", true); err == nil {
+		t.Error("enabled an adapter that isn't configured")
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gmail := cfg.Adapters["gmail"]
+	if gmail.Enabled || gmail.Options["account"] != "me@example.com" {
+		t.Errorf("gmail = %+v", gmail)
+	}
+}
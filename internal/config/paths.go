@@ -0,0 +1,207 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Path resolution for mnemonic and the tools it reads from. Every default
+// has an environment override, so headless Linux servers and Windows
+// machines can point at copies of the source databases.
+//
+//	           config dir                data dir
+//	macOS      ~/.config/mnemonic        ~/Library/Application Support/Mnemonic
+//	Linux      $XDG_CONFIG_HOME/mnemonic $XDG_DATA_HOME/mnemonic
+//	Windows    %APPDATA%\mnemonic        %LOCALAPPDATA%\Mnemonic
+
+// DBFileName is the name of the database file in the data directory.
+const DBFileName = "cortex.db"
+
+// GetConfigDir returns the config directory: MNEMONIC_CONFIG_DIR, else the
+// platform default.
+func GetConfigDir() (string, error) {
+	// Explicit override (useful for tests and portable installs)
+	if override := envPath("MNEMONIC_CONFIG_DIR", "CORTEX_CONFIG_DIR", "COMMS_CONFIG_DIR"); override != "" {
+		return override, nil
+	}
+
+	if runtime.GOOS == "windows" {
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			return filepath.Join(appData, "mnemonic"), nil
+		}
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "mnemonic"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "mnemonic"), nil
+}
+
+// GetDataDir returns the data directory: MNEMONIC_DATA_DIR, else the
+// platform default.
+func GetDataDir() (string, error) {
+	// Explicit override (useful for tests and portable installs)
+	if override := envPath("MNEMONIC_DATA_DIR", "CORTEX_DATA_DIR", "COMMS_DATA_DIR"); override != "" {
+		return override, nil
+	}
+	return appDataDir("Mnemonic", "mnemonic")
+}
+
+// DBPath returns the path to the mnemonic database in the data directory.
+func DBPath() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, DBFileName), nil
+}
+
+// EveDBPath returns where Eve keeps its iMessage database: EVE_DB_PATH, else
+// Eve's data directory.
+func EveDBPath() (string, error) {
+	if override := envPath("EVE_DB_PATH"); override != "" {
+		return override, nil
+	}
+	dir, err := appDataDir("Eve", "eve")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "eve.db"), nil
+}
+
+// AixDBPath returns where aix keeps its AI session database: AIX_DB_PATH,
+// else aix's data directory.
+func AixDBPath() (string, error) {
+	if override := envPath("AIX_DB_PATH"); override != "" {
+		return override, nil
+	}
+	dir, err := appDataDir("aix", "aix")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "aix.db"), nil
+}
+
+// ChatDBPath returns where macOS keeps the Messages database: CHAT_DB_PATH,
+// else ~/Library/Messages/chat.db. Elsewhere the default only exists on a
+// copied home directory, so set the override.
+func ChatDBPath() (string, error) {
+	if override := envPath("CHAT_DB_PATH"); override != "" {
+		return override, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, "Library", "Messages", "chat.db"), nil
+}
+
+// AddressBookDir returns where macOS Contacts keeps its databases:
+// ADDRESSBOOK_DIR, else ~/Library/Application Support/AddressBook.
+func AddressBookDir() (string, error) {
+	if override := envPath("ADDRESSBOOK_DIR"); override != "" {
+		return override, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, "Library", "Application Support", "AddressBook"), nil
+}
+
+// CallHistoryPath returns where macOS keeps the call history database:
+// CALL_HISTORY_DB_PATH, else
+// ~/Library/Application Support/CallHistoryDB/CallHistory.storedata.
+func CallHistoryPath() (string, error) {
+	if override := envPath("CALL_HISTORY_DB_PATH"); override != "" {
+		return override, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, "Library", "Application Support", "CallHistoryDB", "CallHistory.storedata"), nil
+}
+
+// NexusDir returns the Nexus home directory: NEXUS_HOME, else ~/nexus.
+func NexusDir() (string, error) {
+	if override := envPath("NEXUS_HOME"); override != "" {
+		return override, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, "nexus"), nil
+}
+
+// NexusStateDir returns the Nexus state directory, which holds its event
+// logs: NEXUS_STATE_DIR, else state/ in NexusDir.
+func NexusStateDir() (string, error) {
+	if override := envPath("NEXUS_STATE_DIR"); override != "" {
+		return override, nil
+	}
+	dir, err := NexusDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "state"), nil
+}
+
+// appDataDir returns an application's data directory: macName under
+// ~/Library/Application Support on macOS, macName under %LOCALAPPDATA% on
+// Windows, and name under $XDG_DATA_HOME (default ~/.local/share)
+// elsewhere.
+func appDataDir(macName, name string) (string, error) {
+	if runtime.GOOS == "windows" {
+		if local := os.Getenv("LOCALAPPDATA"); local != "" {
+			return filepath.Join(local, macName), nil
+		}
+	}
+	if runtime.GOOS != "darwin" && runtime.GOOS != "windows" {
+		if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+			return filepath.Join(xdg, name), nil
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support", macName), nil
+	case "windows":
+		return filepath.Join(home, "AppData", "Local", macName), nil
+	}
+	return filepath.Join(home, ".local", "share", name), nil
+}
+
+// envPath returns the first of the environment variables that is set, with
+// ~ and $VARS expanded.
+func envPath(names ...string) string {
+	for _, name := range names {
+		if v := strings.TrimSpace(os.Getenv(name)); v != "" {
+			return filepath.Clean(expandHome(os.ExpandEnv(v)))
+		}
+	}
+	return ""
+}
+
+// expandHome expands a leading ~ to the home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestPathsOverrides(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	dir := t.TempDir()
+	t.Setenv("MNEMONIC_DATA_DIR", dir+"/data/")
+	t.Setenv("EVE_DB_PATH", "~/eve/eve.db")
+	t.Setenv("AIX_DB_PATH", "$AIX_TEST_ROOT/aix.db")
+	t.Setenv("AIX_TEST_ROOT", dir)
+	t.Setenv("NEXUS_HOME", dir)
+	t.Setenv("NEXUS_STATE_DIR", "")
+	t.Setenv("CALL_HISTORY_DB_PATH", "~/calls/CallHistory.storedata")
+
+	tests := []struct {
+		name string
+		fn   func() (string, error)
+		want string
+	}{
+		{"DBPath", DBPath, filepath.Join(dir, "data", "cortex.db")},
+		{"EveDBPath", EveDBPath, filepath.Join(home, "eve", "eve.db")},
+		{"AixDBPath", AixDBPath, filepath.Join(dir, "aix.db")},
+		{"NexusStateDir", NexusStateDir, filepath.Join(dir, "state")},
+		{"CallHistoryPath", CallHistoryPath, filepath.Join(home, "calls", "CallHistory.storedata")},
+	}
+	for _, tt := range tests {
+		got, err := tt.fn()
+		if err != nil || got != tt.want {
+			t.Errorf("%s() = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestPathsLinuxDefaults(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("XDG defaults apply on Linux")
+	}
+	for _, name := range []string{"MNEMONIC_DATA_DIR", "CORTEX_DATA_DIR", "COMMS_DATA_DIR", "MNEMONIC_CONFIG_DIR", "CORTEX_CONFIG_DIR", "COMMS_CONFIG_DIR", "EVE_DB_PATH", "AIX_DB_PATH"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("XDG_DATA_HOME", "/srv/data")
+	t.Setenv("XDG_CONFIG_HOME", "/srv/config")
+
+	tests := []struct {
+		name string
+		fn   func() (string, error)
+		want string
+	}{
+		{"GetDataDir", GetDataDir, "/srv/data/mnemonic"},
+		{"GetConfigDir", GetConfigDir, "/srv/config/mnemonic"},
+		{"EveDBPath", EveDBPath, "/srv/data/eve/eve.db"},
+		{"AixDBPath", AixDBPath, "/srv/data/aix/aix.db"},
+	}
+	for _, tt := range tests {
+		got, err := tt.fn()
+		if err != nil || got != tt.want {
+			t.Errorf("%s() = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
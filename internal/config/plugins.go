@@ -0,0 +1,238 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Plugin kinds, as written in plugin configs.
+const (
+	PluginKindAdapter       = "adapter"
+	PluginKindPostprocessor = "postprocessor"
+	PluginKindExporter      = "exporter"
+)
+
+// pluginExecutablePrefix starts the names of plugin executables that need
+// no manifest: cortex-<kind>-<name>.
+const pluginExecutablePrefix = "cortex-"
+
+// pluginManifest is a plugin directory manifest: a plugin config plus an
+// optional name (default: the file name).
+type pluginManifest struct {
+	Name         string `yaml:"name"`
+	PluginConfig `yaml:",inline"`
+}
+
+// PluginDirPath returns the directory plugins are discovered in.
+func (c *Config) PluginDirPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return c.pluginDir(configDir), nil
+}
+
+func (c *Config) pluginDir(configDir string) string {
+	if c.PluginDir != "" {
+		return expandHome(c.PluginDir)
+	}
+	return filepath.Join(configDir, "plugins")
+}
+
+// DiscoverPlugins reads a plugin directory: <name>.yaml manifests (a plugin
+// config, with a command relative to the directory) and executables named
+// cortex-<kind>-<name>. A manifest wins over an executable of the same
+// name. Entries that can't be used are returned as problems, not errors,
+// so one broken plugin doesn't stop the rest.
+func DiscoverPlugins(dir string) (map[string]PluginConfig, []string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("read plugin directory: %w", err)
+	}
+
+	found := map[string]PluginConfig{}
+	var problems []string
+	fromManifest := map[string]bool{}
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		var m pluginManifest
+		if err := yaml.Unmarshal(data, &m); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		name := strings.TrimSpace(m.Name)
+		if name == "" {
+			name = strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		}
+		pc := m.PluginConfig
+		pc.Kind = strings.ToLower(strings.TrimSpace(pc.Kind))
+		switch pc.Kind {
+		case PluginKindAdapter, PluginKindPostprocessor, PluginKindExporter:
+		default:
+			problems = append(problems, fmt.Sprintf("%s: unknown kind %q (adapter, postprocessor or exporter)", path, m.Kind))
+			continue
+		}
+		if pc.Command == "" {
+			problems = append(problems, fmt.Sprintf("%s: command is required", path))
+			continue
+		}
+		// Relative commands, and bare names of files next to the
+		// manifest, run from the plugin directory; other bare names
+		// are looked up on PATH
+		pc.Command = expandHome(pc.Command)
+		if !filepath.IsAbs(pc.Command) {
+			local := filepath.Join(dir, pc.Command)
+			if _, err := os.Stat(local); err == nil || strings.ContainsRune(pc.Command, filepath.Separator) {
+				pc.Command = local
+			}
+		}
+		pc.Source = path
+		found[name] = pc
+		fromManifest[name] = true
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), pluginExecutablePrefix) {
+			continue
+		}
+		kind, name, ok := strings.Cut(strings.TrimPrefix(e.Name(), pluginExecutablePrefix), "-")
+		if !ok || name == "" || fromManifest[name] {
+			continue
+		}
+		switch kind {
+		case PluginKindAdapter, PluginKindPostprocessor, PluginKindExporter:
+		default:
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+			problems = append(problems, fmt.Sprintf("%s: not executable", path))
+			continue
+		}
+		if prev, ok := found[name]; ok {
+			problems = append(problems, fmt.Sprintf("%s: plugin %s is already provided by %s", path, name, prev.Source))
+			continue
+		}
+		found[name] = PluginConfig{Kind: kind, Command: path, Source: path}
+	}
+	return found, problems, nil
+}
+
+// loadPlugins adds plugins from the plugin directory to Plugins, and turns
+// adapter plugins (from the directory or the plugins section) into
+// adapters of type plugin. Plugins and adapters in config.yaml win over
+// discovered ones with the same name.
+func (c *Config) loadPlugins(configDir string) error {
+	found, problems, err := DiscoverPlugins(c.pluginDir(configDir))
+	if err != nil {
+		return err
+	}
+	c.PluginErrors = problems
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := c.Plugins[name]; ok {
+			c.PluginErrors = append(c.PluginErrors, fmt.Sprintf("%s: plugin %s is also in config.yaml, which wins", found[name].Source, name))
+			continue
+		}
+		if c.Plugins == nil {
+			c.Plugins = map[string]PluginConfig{}
+		}
+		if c.discoveredPlugins == nil {
+			c.discoveredPlugins = map[string]PluginConfig{}
+		}
+		c.Plugins[name] = found[name]
+		c.discoveredPlugins[name] = found[name]
+	}
+
+	for name, pc := range c.Plugins {
+		if strings.ToLower(strings.TrimSpace(pc.Kind)) != PluginKindAdapter {
+			continue
+		}
+		if _, ok := c.Adapters[name]; ok {
+			continue
+		}
+		if c.discoveredAdapters == nil {
+			c.discoveredAdapters = map[string]AdapterConfig{}
+		}
+		// Separate copies, so Save can tell whether the adapter was changed
+		c.Adapters[name] = pluginAdapterConfig(pc)
+		c.discoveredAdapters[name] = pluginAdapterConfig(pc)
+	}
+	return nil
+}
+
+// pluginAdapterConfig is the adapter config running an adapter plugin.
+func pluginAdapterConfig(pc PluginConfig) AdapterConfig {
+	options := map[string]interface{}{"command": pc.Command}
+	if len(pc.Args) > 0 {
+		args := make([]interface{}, len(pc.Args))
+		for i, a := range pc.Args {
+			args[i] = a
+		}
+		options["args"] = args
+	}
+	if len(pc.Env) > 0 {
+		env := make(map[string]interface{}, len(pc.Env))
+		for k, v := range pc.Env {
+			env[k] = v
+		}
+		options["env"] = env
+	}
+	if pc.Timeout != "" {
+		options["timeout"] = pc.Timeout
+	}
+	return AdapterConfig{Type: "plugin", Enabled: true, Options: options}
+}
+
+// withoutDiscovered returns the config to save: discovered plugins and
+// adapters are left out unless they were changed (e.g. disabled).
+func (c *Config) withoutDiscovered() *Config {
+	if len(c.discoveredAdapters) == 0 && len(c.discoveredPlugins) == 0 {
+		return c
+	}
+	out := *c
+	out.Adapters = make(map[string]AdapterConfig, len(c.Adapters))
+	for name, ac := range c.Adapters {
+		if d, ok := c.discoveredAdapters[name]; ok && reflect.DeepEqual(d, ac) {
+			continue
+		}
+		out.Adapters[name] = ac
+	}
+	if c.Plugins != nil {
+		out.Plugins = make(map[string]PluginConfig, len(c.Plugins))
+		for name, pc := range c.Plugins {
+			if d, ok := c.discoveredPlugins[name]; ok && reflect.DeepEqual(d, pc) {
+				continue
+			}
+			out.Plugins[name] = pc
+		}
+		if len(out.Plugins) == 0 {
+			out.Plugins = nil
+		}
+	}
+	return &out
+}
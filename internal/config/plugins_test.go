@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadDiscoversPlugins(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("MNEMONIC_CONFIG_DIR", configDir)
+	pluginDir := filepath.Join(configDir, "plugins")
+	if err := os.MkdirAll(pluginDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, data string, mode os.FileMode) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(pluginDir, name), []byte(data), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("forum.yaml", "kind: adapter\ncommand: forum-sync\nargs: [--site, example.org]\ntimeout: 30m\n", 0o644)
+	write("forum-sync", "#!/bin/sh\n", 0o755)
+	write("cortex-postprocessor-redact", "#!/bin/sh\n", 0o755)
+	write("cortex-exporter-notion", "#!/bin/sh\n", 0o644) // not executable
+	write("cortex-adapter-forum", "#!/bin/sh\n", 0o755)   // the manifest wins
+	write("broken.yaml", "kind: widget\ncommand: x\n", 0o644)
+	write("README.md", "not a plugin", 0o644)
+	write("config-shadowed.yaml", "name: mine\nkind: exporter\ncommand: /bin/true\n", 0o644)
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte(`
+adapters:
+  imessage:
+    type: eve
+    enabled: true
+plugins:
+  mine:
+    kind: exporter
+    command: /usr/bin/mine
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	forum, ok := cfg.Plugins["forum"]
+	if !ok || forum.Kind != PluginKindAdapter || forum.Command != filepath.Join(pluginDir, "forum-sync") {
+		t.Fatalf("forum plugin = %+v", forum)
+	}
+	if redact := cfg.Plugins["redact"]; redact.Kind != PluginKindPostprocessor {
+		t.Errorf("redact plugin = %+v", redact)
+	}
+	if _, ok := cfg.Plugins["notion"]; ok {
+		t.Error("non-executable plugin discovered")
+	}
+	if cfg.Plugins["mine"].Command != "/usr/bin/mine" {
+		t.Errorf("config.yaml plugin replaced by a discovered one: %+v", cfg.Plugins["mine"])
+	}
+	problems := strings.Join(cfg.PluginErrors, "\n")
+	for _, want := range []string{"broken.yaml: unknown kind", "cortex-exporter-notion: not executable", "plugin mine is also in config.yaml"} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("problems %q missing %q", problems, want)
+		}
+	}
+
+	adapter, ok := cfg.Adapters["forum"]
+	if !ok || adapter.Type != "plugin" || !adapter.Enabled || adapter.Options["timeout"] != "30m" {
+		t.Fatalf("forum adapter = %+v", adapter)
+	}
+
+	// Saving leaves discovered entries out, unless they were changed
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(configDir, "config.yaml"))
+	if strings.Contains(string(data), "forum") || strings.Contains(string(data), "redact") {
+		t.Errorf("discovered plugins saved:\n%s", data)
+	}
+	adapter.Enabled = false
+	cfg.Adapters["forum"] = adapter
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if cfg.Adapters["forum"].Enabled {
+		t.Error("disabling a discovered adapter did not persist")
+	}
+}
@@ -64,6 +64,19 @@ func normalizePhone(value string) string {
 	return digits
 }
 
+// GuessIdentifierType returns "email" or "phone" for values that look like
+// one, or "" if the type can't be told from the value alone.
+func GuessIdentifierType(value string) string {
+	switch {
+	case looksLikeEmail(value):
+		return "email"
+	case looksLikePhone(value):
+		return "phone"
+	default:
+		return ""
+	}
+}
+
 func looksLikeEmail(value string) bool {
 	value = strings.TrimSpace(value)
 	return strings.Contains(value, "@")
@@ -181,6 +194,15 @@ func getContactIDByIdentifier(db DBTX, identifierType, normalized string) (strin
 	return contactID, nil
 }
 
+// LookupContact returns the contact that owns an identifier, or "" if none does.
+func LookupContact(db DBTX, identifierType, rawValue string) (string, error) {
+	normalized := NormalizeIdentifier(rawValue, identifierType)
+	if normalized == "" {
+		return "", nil
+	}
+	return getContactIDByIdentifier(db, identifierType, normalized)
+}
+
 // GetOrCreateContact returns the contact ID for an identifier, creating a new contact if needed.
 func GetOrCreateContact(db DBTX, identifierType, rawValue, displayName, source string) (string, bool, error) {
 	normalized := NormalizeIdentifier(rawValue, identifierType)
@@ -257,6 +279,8 @@ func updateContactName(db DBTX, contactID, candidate, normalized string, now int
 }
 
 // EnsurePersonForContact creates or returns a person linked to a contact if the name is meaningful.
+// An unlinked contact is linked to an existing person with the same full name
+// when that match is unambiguous (see matchPersonByName).
 func EnsurePersonForContact(db DBTX, contactID, name, sourceType string, confidence float64) (string, bool, error) {
 	if !IsMeaningfulPersonName(name) {
 		return "", false, nil
@@ -285,6 +309,16 @@ func EnsurePersonForContact(db DBTX, contactID, name, sourceType string, confide
 		return "", false, fmt.Errorf("lookup person link: %w", err)
 	}
 
+	// Link to an existing person of the same full name before creating one
+	if matched, err := matchPersonByName(db, contactID, name); err != nil {
+		return "", false, err
+	} else if matched != "" {
+		if err := insertPersonContactLink(db, matched, contactID, SourceNameMatch, nameMatchConfidence, now); err != nil {
+			return "", false, err
+		}
+		return matched, false, nil
+	}
+
 	personID = uuid.New().String()
 	if _, err := db.Exec(`
 		INSERT INTO persons (id, canonical_name, is_me, created_at, updated_at)
@@ -0,0 +1,180 @@
+package contacts
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Adapters create one contact per identifier they see, so the same person
+// reached by email (Gmail) and by phone (iMessage) starts out as two
+// contacts. Where import-time evidence already says they are one person,
+// the contacts are linked to that person right away instead of leaving a
+// second person for merge resolution to find later:
+//
+//   - contact cards: identifiers listed on one address book card belong to
+//     one person (LinkCard);
+//   - names: a new contact whose name is a full name that exactly one
+//     person has, and that person is only known on other kinds of
+//     identifiers (matchPersonByName, used by EnsurePersonForContact).
+
+// Link source types for import-time linking.
+const (
+	SourceContactCard = "contact_card"
+	SourceNameMatch   = "name_match"
+)
+
+// nameMatchConfidence is lower than identifier links: a shared name is
+// strong evidence but not proof.
+const nameMatchConfidence = 0.7
+
+// normalizePersonName lowercases a name and collapses its whitespace.
+func normalizePersonName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// matchPersonByName returns the person a new contact named name should be
+// linked to, or "" when the name is not a full name, is shared by several
+// persons, or belongs to a person already known on the same kind of
+// identifier as the contact (two emails under one name are as likely
+// namesakes as one person).
+func matchPersonByName(db DBTX, contactID, name string) (string, error) {
+	normalized := normalizePersonName(name)
+	if len(strings.Fields(normalized)) < 2 {
+		return "", nil
+	}
+
+	var count int
+	var personID string
+	if err := db.QueryRow(`
+		SELECT COUNT(*), COALESCE(MIN(id), '') FROM persons
+		WHERE is_me = 0
+		  AND (LOWER(TRIM(canonical_name)) = ? OR LOWER(TRIM(COALESCE(display_name, ''))) = ?)
+	`, normalized, normalized).Scan(&count, &personID); err != nil {
+		return "", fmt.Errorf("match person by name: %w", err)
+	}
+	if count != 1 {
+		return "", nil
+	}
+
+	var sameKind int
+	if err := db.QueryRow(`
+		SELECT COUNT(*) FROM person_contact_links l
+		JOIN contact_identifiers ci ON ci.contact_id = l.contact_id
+		WHERE l.person_id = ?
+		  AND ci.type IN (SELECT type FROM contact_identifiers WHERE contact_id = ?)
+	`, personID, contactID).Scan(&sameKind); err != nil {
+		return "", fmt.Errorf("check name match identifiers: %w", err)
+	}
+	if sameKind > 0 {
+		return "", nil
+	}
+	return personID, nil
+}
+
+// LinkCard links the contacts for the identifiers on one contact card to a
+// single person: the person one of them is already linked to, else a
+// person matched or created from the card's name. A person still named by
+// a phone number or email takes the card's name. A contact linked to a
+// placeholder person - one that exists only for that contact - is moved and
+// the placeholder removed, since the card is the better evidence. A contact
+// linked to any other person keeps its link: an identifier on two cards (a
+// household landline) would otherwise flip between them on every sync.
+// Returns "" when no contact is linked and the name is not meaningful.
+func LinkCard(db DBTX, contactIDs []string, name string) (string, bool, error) {
+	if len(contactIDs) == 0 {
+		return "", false, nil
+	}
+
+	linked := make([]string, len(contactIDs))
+	basePerson := ""
+	for i, cid := range contactIDs {
+		pid, err := GetLinkedPersonID(db, cid)
+		if err != nil {
+			return "", false, err
+		}
+		linked[i] = pid
+		if basePerson == "" && pid != "" && !isMePerson(db, pid) {
+			basePerson = pid
+		}
+	}
+
+	created := false
+	if basePerson == "" {
+		if !IsMeaningfulPersonName(name) {
+			return "", false, nil
+		}
+		pid, isNew, err := EnsurePersonForContact(db, contactIDs[0], name, SourceContactCard, 1.0)
+		if err != nil {
+			return "", false, err
+		}
+		if pid == "" || isMePerson(db, pid) {
+			return pid, false, nil
+		}
+		basePerson, created = pid, isNew
+		linked[0] = pid
+	}
+
+	now := time.Now().Unix()
+	if !created && !isMePerson(db, basePerson) {
+		if err := updatePersonNameIfGeneric(db, basePerson, name, now); err != nil {
+			return "", false, err
+		}
+	}
+	for i, cid := range contactIDs {
+		prev := linked[i]
+		if prev != "" && prev != basePerson {
+			if isMePerson(db, prev) {
+				continue
+			}
+			refs, err := personRefs(db, prev)
+			if err != nil {
+				return "", false, err
+			}
+			if refs > 1 {
+				continue
+			}
+			if _, err := db.Exec(`DELETE FROM person_contact_links WHERE person_id = ? AND contact_id = ?`, prev, cid); err != nil {
+				return "", false, fmt.Errorf("unlink contact: %w", err)
+			}
+			removeEmptyPerson(db, prev)
+		}
+		if err := insertPersonContactLink(db, basePerson, cid, SourceContactCard, 1.0, now); err != nil {
+			return "", false, err
+		}
+	}
+	return basePerson, created, nil
+}
+
+func isMePerson(db DBTX, personID string) bool {
+	var isMe bool
+	_ = db.QueryRow(`SELECT is_me FROM persons WHERE id = ?`, personID).Scan(&isMe)
+	return isMe
+}
+
+// personRefs counts the rows deleting a person would cascade to: its
+// contact links, facts, identities, merge suggestions and memory graph
+// link. Tables without ON DELETE CASCADE block the delete instead.
+func personRefs(db DBTX, personID string) (int, error) {
+	var refs int
+	err := db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM person_contact_links WHERE person_id = ?1)
+		     + (SELECT COUNT(*) FROM person_facts WHERE person_id = ?1)
+		     + (SELECT COUNT(*) FROM identities WHERE person_id = ?1)
+		     + (SELECT COUNT(*) FROM merge_suggestions WHERE person1_id = ?1 OR person2_id = ?1)
+		     + (SELECT COUNT(*) FROM person_entity_links WHERE person_id = ?1)
+	`, personID).Scan(&refs)
+	if err != nil {
+		return 0, fmt.Errorf("count person references: %w", err)
+	}
+	return refs, nil
+}
+
+// removeEmptyPerson deletes a person nothing refers to (see personRefs).
+func removeEmptyPerson(db DBTX, personID string) {
+	if refs, err := personRefs(db, personID); err != nil || refs > 0 {
+		return
+	}
+	// Non-fatal - a person still referenced elsewhere is left for merge resolution
+	_, _ = db.Exec(`DELETE FROM persons WHERE id = ? AND is_me = 0`, personID)
+}
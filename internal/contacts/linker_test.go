@@ -0,0 +1,148 @@
+package contacts
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func openLinkerTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	for _, stmt := range []string{
+		`PRAGMA foreign_keys = ON`,
+		`CREATE TABLE persons (id TEXT PRIMARY KEY, canonical_name TEXT NOT NULL, display_name TEXT,
+			is_me INTEGER DEFAULT 0, created_at INTEGER NOT NULL, updated_at INTEGER NOT NULL)`,
+		`CREATE TABLE contacts (id TEXT PRIMARY KEY, display_name TEXT, source TEXT,
+			created_at INTEGER NOT NULL, updated_at INTEGER NOT NULL)`,
+		`CREATE TABLE contact_identifiers (id TEXT PRIMARY KEY, contact_id TEXT NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+			type TEXT NOT NULL, value TEXT NOT NULL, normalized TEXT NOT NULL, created_at INTEGER NOT NULL,
+			last_seen_at INTEGER, UNIQUE(type, normalized))`,
+		`CREATE TABLE person_contact_links (id TEXT PRIMARY KEY, person_id TEXT NOT NULL REFERENCES persons(id) ON DELETE CASCADE,
+			contact_id TEXT NOT NULL REFERENCES contacts(id) ON DELETE CASCADE, confidence REAL DEFAULT 1.0,
+			source_type TEXT, first_seen_at INTEGER, last_seen_at INTEGER, UNIQUE(person_id, contact_id))`,
+		`CREATE TABLE person_facts (id TEXT PRIMARY KEY, person_id TEXT NOT NULL REFERENCES persons(id) ON DELETE CASCADE)`,
+		`CREATE TABLE identities (id TEXT PRIMARY KEY, person_id TEXT NOT NULL REFERENCES persons(id) ON DELETE CASCADE)`,
+		`CREATE TABLE merge_suggestions (id TEXT PRIMARY KEY, person1_id TEXT NOT NULL REFERENCES persons(id) ON DELETE CASCADE,
+			person2_id TEXT NOT NULL REFERENCES persons(id) ON DELETE CASCADE)`,
+		`CREATE TABLE person_entity_links (person_id TEXT PRIMARY KEY REFERENCES persons(id) ON DELETE CASCADE,
+			entity_id TEXT NOT NULL)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("schema: %v", err)
+		}
+	}
+	return db
+}
+
+func TestImportTimeLinking(t *testing.T) {
+	db := openLinkerTestDB(t)
+
+	contact := func(idType, value, name string) string {
+		t.Helper()
+		id, _, err := GetOrCreateContact(db, idType, value, name, "test")
+		if err != nil {
+			t.Fatalf("create contact %s: %v", value, err)
+		}
+		return id
+	}
+	person := func(contactID, name string) string {
+		t.Helper()
+		id, _, err := EnsurePersonForContact(db, contactID, name, "deterministic", 1.0)
+		if err != nil {
+			t.Fatalf("ensure person %s: %v", name, err)
+		}
+		return id
+	}
+	countPersons := func() int {
+		var n int
+		db.QueryRow(`SELECT COUNT(*) FROM persons`).Scan(&n)
+		return n
+	}
+
+	// iMessage sees Jane's phone, then Gmail sees her email under the same name
+	janePhone := contact("phone", "+1 555 123 4567", "Jane Doe")
+	jane := person(janePhone, "Jane Doe")
+	janeEmail := contact("email", "jane@example.com", "Jane Doe")
+	if got := person(janeEmail, "jane  doe"); got != jane {
+		t.Errorf("email contact linked to %s, want Jane %s", got, jane)
+	}
+
+	// A second email under the same name is as likely a namesake
+	otherEmail := contact("email", "jane.doe@other.com", "Jane Doe")
+	if got := person(otherEmail, "Jane Doe"); got == jane {
+		t.Error("second email should not be name-matched to a person known by email")
+	}
+
+	// First names alone never match
+	bobPhone := contact("phone", "+1 555 000 1111", "Bob")
+	bob := person(bobPhone, "Bob")
+	if got := person(contact("email", "bob@example.com", "Bob"), "Bob"); got == bob {
+		t.Error("single-word name should not be matched")
+	}
+
+	// A card ties Sam's phone and email, which two adapters gave two persons
+	samPhone := contact("phone", "+1 555 222 3333", "Sam")
+	sam := person(samPhone, "Sam")
+	samEmail := contact("email", "samuel@example.com", "Samuel Lee")
+	samuel := person(samEmail, "Samuel Lee")
+	before := countPersons()
+	linked, created, err := LinkCard(db, []string{samPhone, samEmail}, "Samuel Lee")
+	if err != nil {
+		t.Fatalf("LinkCard: %v", err)
+	}
+	if linked != sam || created {
+		t.Errorf("LinkCard = %s (created %v), want existing person %s", linked, created, sam)
+	}
+	if got, _ := GetLinkedPersonID(db, samEmail); got != sam {
+		t.Errorf("email contact linked to %s, want %s", got, sam)
+	}
+	var exists int
+	db.QueryRow(`SELECT COUNT(*) FROM persons WHERE id = ?`, samuel).Scan(&exists)
+	if exists != 0 || countPersons() != before-1 {
+		t.Errorf("emptied person should be removed: exists=%d persons %d -> %d", exists, before, countPersons())
+	}
+
+	// A landline on two household cards stays with the first person
+	landline := contact("phone", "+1 555 777 0000", "Home")
+	alex, _, err := LinkCard(db, []string{contact("email", "alex@example.com", "Alex Kim"), landline}, "Alex Kim")
+	if err != nil {
+		t.Fatalf("LinkCard: %v", err)
+	}
+	robinEmail := contact("email", "robin@example.com", "Robin Kim")
+	robin := person(robinEmail, "Robin Kim")
+	for i := 0; i < 2; i++ {
+		if got, _, err := LinkCard(db, []string{robinEmail, landline}, "Robin Kim"); err != nil || got != robin {
+			t.Fatalf("LinkCard = %s, %v; want %s", got, err, robin)
+		}
+		if got, _ := GetLinkedPersonID(db, landline); got != alex {
+			t.Fatalf("landline moved to %s, want it left with %s", got, alex)
+		}
+		if _, _, err := LinkCard(db, []string{contact("email", "alex@example.com", "Alex Kim"), landline}, "Alex Kim"); err != nil {
+			t.Fatalf("LinkCard: %v", err)
+		}
+	}
+
+	// A person linked to the memory graph is not a placeholder: it keeps its contact
+	drewPhone := contact("phone", "+1 555 888 9999", "Drew")
+	drew := person(drewPhone, "Drew")
+	if _, err := db.Exec(`INSERT INTO person_entity_links (person_id, entity_id) VALUES (?, 'entity-drew')`, drew); err != nil {
+		t.Fatal(err)
+	}
+	drewEmail := contact("email", "drew.park@example.com", "Drew Park")
+	person(drewEmail, "Drew Park")
+	if _, _, err := LinkCard(db, []string{drewEmail, drewPhone}, "Drew Park"); err != nil {
+		t.Fatalf("LinkCard: %v", err)
+	}
+	var links int
+	db.QueryRow(`SELECT COUNT(*) FROM person_entity_links WHERE person_id = ?`, drew).Scan(&links)
+	if got, _ := GetLinkedPersonID(db, drewPhone); got != drew || links != 1 {
+		t.Errorf("graph-linked person lost its contact (%s) or entity link (%d)", got, links)
+	}
+}
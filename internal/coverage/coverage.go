@@ -0,0 +1,247 @@
+// Package coverage summarizes what has been ingested - events per channel
+// and year, threads, contacts, episodes and how much of it the memory
+// pipeline has processed - and points out gaps worth backfilling, such as a
+// channel whose history starts years after the others.
+package coverage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/Napageneral/mnemonic/internal/chunk"
+	"github.com/Napageneral/mnemonic/internal/contacts"
+)
+
+// UnnamedContactsGap is the share of unnamed contacts reported as a gap.
+const UnnamedContactsGap = 0.25
+
+// YearCount is a channel's events in one calendar year (UTC).
+type YearCount struct {
+	Year   int `json:"year"`
+	Events int `json:"events"`
+}
+
+// ChannelCoverage is what has been ingested for one channel.
+type ChannelCoverage struct {
+	Channel          string      `json:"channel"`
+	Events           int         `json:"events"`
+	Threads          int         `json:"threads"`
+	FirstEvent       int64       `json:"first_event"`
+	LastEvent        int64       `json:"last_event"`
+	Years            []YearCount `json:"years"`
+	EventsInEpisodes int         `json:"events_in_episodes"`
+	Episodes         int         `json:"episodes"`
+	EpisodesDone     int         `json:"episodes_processed"`
+}
+
+// Report summarizes the ingested data. Revisions and tombstones of edited
+// or unsent messages are not counted as events.
+type Report struct {
+	Events           int               `json:"events"`
+	Threads          int               `json:"threads"`
+	Contacts         int               `json:"contacts"`
+	NamedContacts    int               `json:"named_contacts"`
+	EventsInEpisodes int               `json:"events_in_episodes"`
+	Episodes         int               `json:"episodes"`
+	EpisodesDone     int               `json:"episodes_processed"`
+	Channels         []ChannelCoverage `json:"channels"`
+	Gaps             []string          `json:"gaps"`
+}
+
+// EpisodeShare is the fraction of events that are in an episode.
+func (r *Report) EpisodeShare() float64 {
+	return ratio(r.EventsInEpisodes, r.Events)
+}
+
+// ProcessedShare is the fraction of episodes the memory pipeline has
+// processed (extracted or deliberately skipped).
+func (r *Report) ProcessedShare() float64 {
+	return ratio(r.EpisodesDone, r.Episodes)
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+// Build computes the coverage report.
+func Build(ctx context.Context, db *sql.DB) (*Report, error) {
+	r := &Report{Channels: []ChannelCoverage{}, Gaps: []string{}}
+	byChannel := map[string]*ChannelCoverage{}
+	channel := func(name string) *ChannelCoverage {
+		c := byChannel[name]
+		if c == nil {
+			c = &ChannelCoverage{Channel: name, Years: []YearCount{}}
+			byChannel[name] = c
+		}
+		return c
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT channel, CAST(strftime('%Y', timestamp, 'unixepoch') AS INTEGER), COUNT(*), MIN(timestamp), MAX(timestamp)
+		FROM events
+		WHERE supersedes IS NULL
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("count events: %w", err)
+	}
+	for rows.Next() {
+		var name string
+		var year, n int
+		var first, last int64
+		if err := rows.Scan(&name, &year, &n, &first, &last); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan event counts: %w", err)
+		}
+		c := channel(name)
+		if c.Events == 0 || first < c.FirstEvent {
+			c.FirstEvent = first
+		}
+		if last > c.LastEvent {
+			c.LastEvent = last
+		}
+		c.Events += n
+		c.Years = append(c.Years, YearCount{Year: year, Events: n})
+		r.Events += n
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+
+	counts := []struct {
+		query string
+		set   func(c *ChannelCoverage, n int)
+	}{
+		{`SELECT channel, COUNT(*) FROM threads GROUP BY channel`,
+			func(c *ChannelCoverage, n int) { c.Threads = n }},
+		{`SELECT e.channel, COUNT(*) FROM events e
+		  WHERE e.supersedes IS NULL AND EXISTS (SELECT 1 FROM episode_events ee WHERE ee.event_id = e.id)
+		  GROUP BY e.channel`,
+			func(c *ChannelCoverage, n int) { c.EventsInEpisodes = n }},
+		{`SELECT COALESCE(ep.channel, ''), COUNT(*) FROM episodes ep
+		  WHERE ` + chunk.PrimaryEpisodeCondition("ep") + `
+		  GROUP BY 1`,
+			func(c *ChannelCoverage, n int) { c.Episodes = n }},
+		{`SELECT COALESCE(ep.channel, ''), COUNT(*) FROM episodes ep
+		  JOIN episode_processing p ON p.episode_id = ep.id AND p.status IN ('ok', 'skipped')
+		  WHERE ` + chunk.PrimaryEpisodeCondition("ep") + `
+		  GROUP BY 1`,
+			func(c *ChannelCoverage, n int) { c.EpisodesDone = n }},
+	}
+	for _, q := range counts {
+		rows, err := db.QueryContext(ctx, q.query)
+		if err != nil {
+			return nil, fmt.Errorf("count coverage: %w", err)
+		}
+		for rows.Next() {
+			var name string
+			var n int
+			if err := rows.Scan(&name, &n); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scan coverage: %w", err)
+			}
+			q.set(channel(name), n)
+		}
+		if err := closeRows(rows); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := countContacts(ctx, db, r); err != nil {
+		return nil, err
+	}
+
+	for _, c := range byChannel {
+		r.Threads += c.Threads
+		r.EventsInEpisodes += c.EventsInEpisodes
+		r.Episodes += c.Episodes
+		r.EpisodesDone += c.EpisodesDone
+		r.Channels = append(r.Channels, *c)
+	}
+	sort.Slice(r.Channels, func(i, j int) bool {
+		if r.Channels[i].Events != r.Channels[j].Events {
+			return r.Channels[i].Events > r.Channels[j].Events
+		}
+		return r.Channels[i].Channel < r.Channels[j].Channel
+	})
+	r.Gaps = findGaps(r)
+	return r, nil
+}
+
+// countContacts counts contacts and those with a real name (not a phone
+// number, email or placeholder).
+func countContacts(ctx context.Context, db *sql.DB, r *Report) error {
+	rows, err := db.QueryContext(ctx, `SELECT COALESCE(display_name, '') FROM contacts`)
+	if err != nil {
+		return fmt.Errorf("count contacts: %w", err)
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan contact: %w", err)
+		}
+		r.Contacts++
+		if contacts.IsMeaningfulPersonName(name) {
+			r.NamedContacts++
+		}
+	}
+	return closeRows(rows)
+}
+
+func closeRows(rows *sql.Rows) error {
+	err := rows.Err()
+	rows.Close()
+	return err
+}
+
+// findGaps lists what looks missing: channels whose history starts after
+// the earliest channel's, years without events inside a channel's range,
+// events never chunked into episodes, episodes not yet processed, and
+// contacts without names.
+func findGaps(r *Report) []string {
+	gaps := []string{}
+	if r.Events == 0 {
+		return append(gaps, "no events imported - connect an adapter and run sync")
+	}
+
+	earliest := 0
+	for _, c := range r.Channels {
+		if len(c.Years) > 0 && (earliest == 0 || c.Years[0].Year < earliest) {
+			earliest = c.Years[0].Year
+		}
+	}
+	for _, c := range r.Channels {
+		if len(c.Years) == 0 {
+			continue
+		}
+		if first := c.Years[0].Year; first > earliest {
+			gaps = append(gaps, fmt.Sprintf("no %s before %d imported (other channels go back to %d)", c.Channel, first, earliest))
+		}
+		for i := 1; i < len(c.Years); i++ {
+			for y := c.Years[i-1].Year + 1; y < c.Years[i].Year; y++ {
+				gaps = append(gaps, fmt.Sprintf("no %s events in %d", c.Channel, y))
+			}
+		}
+	}
+
+	for _, c := range r.Channels {
+		switch {
+		case c.Events > 0 && c.EventsInEpisodes == 0:
+			gaps = append(gaps, fmt.Sprintf("%s has %d events but none in episodes - run chunk run", c.Channel, c.Events))
+		case c.Episodes > 0 && c.EpisodesDone < c.Episodes:
+			gaps = append(gaps, fmt.Sprintf("%s has %d of %d episodes not yet processed into memory", c.Channel, c.Episodes-c.EpisodesDone, c.Episodes))
+		}
+	}
+
+	if unnamed := r.Contacts - r.NamedContacts; r.Contacts > 0 && ratio(unnamed, r.Contacts) >= UnnamedContactsGap {
+		gaps = append(gaps, fmt.Sprintf("%d of %d contacts have no name - sync an address book (connect contacts) to name them", unnamed, r.Contacts))
+	}
+	return gaps
+}
@@ -0,0 +1,93 @@
+package coverage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestBuild(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+
+	year := func(y int) int64 { return time.Date(y, 6, 1, 0, 0, 0, 0, time.UTC).Unix() }
+	if _, err := db.Exec(`
+		INSERT INTO threads (id, channel, source_adapter, source_id, created_at, updated_at) VALUES
+			('t1', 'imessage', 'imessage', 't1', 0, 0), ('t2', 'gmail', 'gmail', 't2', 0, 0);
+		INSERT INTO contacts (id, display_name, created_at, updated_at) VALUES
+			('c1', 'Casey Lee', 0, 0), ('c2', '+15550100', 0, 0), ('c3', 'bob@example.com', 0, 0);
+		INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0);
+	`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	// iMessage in 2015, 2016 and 2018; email only from 2019
+	events := []struct {
+		id, channel, thread string
+		year                int
+	}{
+		{"m1", "imessage", "t1", 2015}, {"m2", "imessage", "t1", 2016}, {"m3", "imessage", "t1", 2018},
+		{"g1", "gmail", "t2", 2019}, {"g2", "gmail", "t2", 2020},
+	}
+	for _, e := range events {
+		if _, err := db.Exec(`INSERT INTO events (id, timestamp, channel, content_types, content, direction, thread_id, source_adapter, source_id) VALUES (?, ?, ?, '["text"]', 'hi', 'received', ?, ?, ?)`,
+			e.id, year(e.year), e.channel, e.thread, e.channel, e.id); err != nil {
+			t.Fatalf("event: %v", err)
+		}
+	}
+	// An edit revision is not a separate event
+	if _, err := db.Exec(`INSERT INTO events (id, timestamp, channel, content_types, content, direction, thread_id, source_adapter, source_id, supersedes) VALUES ('m3:rev:1', ?, 'imessage', '["text"]', 'hi!', 'received', 't1', 'imessage', 'm3:rev:1', 'm3')`, year(2018)); err != nil {
+		t.Fatalf("revision: %v", err)
+	}
+	// Two iMessage episodes, one processed; email never chunked
+	for i, ids := range [][]string{{"m1", "m2"}, {"m3"}} {
+		ep := fmt.Sprintf("ep%d", i)
+		if _, err := db.Exec(`INSERT INTO episodes (id, definition_id, channel, thread_id, start_time, end_time, event_count, created_at) VALUES (?, 'def', 'imessage', 't1', 0, 0, ?, 0)`, ep, len(ids)); err != nil {
+			t.Fatalf("episode: %v", err)
+		}
+		for pos, id := range ids {
+			if _, err := db.Exec(`INSERT INTO episode_events (episode_id, event_id, position) VALUES (?, ?, ?)`, ep, id, pos+1); err != nil {
+				t.Fatalf("episode event: %v", err)
+			}
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO episode_processing (episode_id, channel, status, processed_at) VALUES ('ep0', 'imessage', 'ok', 'x')`); err != nil {
+		t.Fatalf("processing: %v", err)
+	}
+
+	r, err := Build(context.Background(), db)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if r.Events != 5 || r.Threads != 2 || r.Contacts != 3 || r.NamedContacts != 1 ||
+		r.EventsInEpisodes != 3 || r.Episodes != 2 || r.EpisodesDone != 1 {
+		t.Fatalf("report = %+v", r)
+	}
+	if len(r.Channels) != 2 || r.Channels[0].Channel != "imessage" || len(r.Channels[0].Years) != 3 ||
+		r.Channels[0].FirstEvent != year(2015) || r.Channels[1].Years[0] != (YearCount{Year: 2019, Events: 1}) {
+		t.Fatalf("channels = %+v", r.Channels)
+	}
+	if share := r.EpisodeShare(); share != 0.6 {
+		t.Errorf("episode share = %v", share)
+	}
+
+	want := []string{
+		"no imessage events in 2017",
+		"no gmail before 2019 imported (other channels go back to 2015)",
+		"imessage has 1 of 2 episodes not yet processed into memory",
+		"gmail has 2 events but none in episodes - run chunk run",
+		"2 of 3 contacts have no name",
+	}
+	gaps := strings.Join(r.Gaps, "\n")
+	for _, w := range want {
+		if !strings.Contains(gaps, w) {
+			t.Errorf("gaps missing %q:\n%s", w, gaps)
+		}
+	}
+	if len(r.Gaps) != len(want) {
+		t.Errorf("got %d gaps, want %d:\n%s", len(r.Gaps), len(want), gaps)
+	}
+}
@@ -3,8 +3,9 @@ package db
 import (
 	"database/sql"
 	_ "embed"
+	"errors"
 	"fmt"
-	"path/filepath"
+	"os"
 	"strings"
 	"time"
 
@@ -12,21 +13,26 @@ import (
 
 	"github.com/Napageneral/mnemonic/internal/config"
 	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/errs"
 	"github.com/google/uuid"
 )
 
 //go:embed schema.sql
 var schemaSQL string
 
+// SchemaVersion is stored in PRAGMA user_version once the schema is
+// current. Bump it with every schema change: Open upgrades older databases
+// in place, and refuses databases written by a newer build rather than
+// guess at columns it does not know.
+const SchemaVersion = 23
+
 // Init initializes the database and creates tables if needed
 func Init() error {
-	dataDir, err := config.GetDataDir()
+	dbPath, err := config.DBPath()
 	if err != nil {
 		return err
 	}
 
-	dbPath := filepath.Join(dataDir, "cortex.db")
-
 	db, err := sql.Open("sqlite", dbPath)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
@@ -46,6 +52,13 @@ func Init() error {
 	_, _ = db.Exec("PRAGMA busy_timeout = 30000")
 	_, _ = db.Exec("PRAGMA foreign_keys = ON")
 
+	return migrate(db)
+}
+
+// migrate applies the schema and data migrations, then records
+// SchemaVersion. Every step is idempotent, so it is safe on a database that
+// is already current.
+func migrate(db *sql.DB) error {
 	// Ensure legacy columns exist before applying schema (prevents index errors).
 	if err := ensureLegacyColumns(db); err != nil {
 		return err
@@ -62,18 +75,30 @@ func Init() error {
 	if err := ensureEventParticipantIndexes(db); err != nil {
 		return err
 	}
+	if err := backfillRelationshipSourceTypes(db); err != nil {
+		return err
+	}
+	if err := standardizeEntityOrigins(db); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", SchemaVersion)); err != nil {
+		return fmt.Errorf("failed to set schema version: %w", err)
+	}
 
 	return nil
 }
 
-// Open opens a connection to the database
+// Open opens a connection to the database, upgrading its schema first if
+// an older build wrote it.
 func Open() (*sql.DB, error) {
-	dataDir, err := config.GetDataDir()
+	dbPath, err := config.DBPath()
 	if err != nil {
 		return nil, err
 	}
-
-	dbPath := filepath.Join(dataDir, "cortex.db")
+	if _, err := os.Stat(dbPath); errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w (no database at %s)", errs.ErrNotInitialized, dbPath)
+	}
 
 	db, err := sql.Open("sqlite", dbPath)
 	if err != nil {
@@ -105,16 +130,59 @@ func Open() (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
 	}
 
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	switch {
+	case version > SchemaVersion:
+		db.Close()
+		return nil, fmt.Errorf("%w (version %d, this build knows %d)", errs.ErrSchemaOutdated, version, SchemaVersion)
+	case version < SchemaVersion:
+		if err := migrate(db); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to upgrade schema from version %d: %w", version, err)
+		}
+	}
+
 	return db, nil
 }
 
 // GetPath returns the path to the database file
 func GetPath() (string, error) {
-	dataDir, err := config.GetDataDir()
+	return config.DBPath()
+}
+
+// Create creates a standalone database with the full schema at path, which
+// must not exist yet. It is used for files meant to leave this machine
+// (share exports), so it keeps the rollback journal: the file is complete
+// on its own once closed, with no -wal sidecar to copy along.
+func Create(path string) (*sql.DB, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%s already exists", path)
+	}
+
+	db, err := sql.Open("sqlite", path)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
-	return filepath.Join(dataDir, "cortex.db"), nil
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+	}
+	if _, err := db.Exec(schemaSQL); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", SchemaVersion)); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set schema version: %w", err)
+	}
+	return db, nil
 }
 
 func ensureLegacyColumns(db *sql.DB) error {
@@ -128,10 +196,97 @@ func ensureLegacyColumns(db *sql.DB) error {
 	if err := ensureColumn(db, "candidate_mentions", "source_episode_id", "TEXT REFERENCES episodes(id)"); err != nil {
 		return err
 	}
+	// Person fact upsert bookkeeping
+	for _, col := range []struct{ name, def string }{
+		{"normalized_value", "TEXT"},
+		{"seen_count", "INTEGER DEFAULT 1"},
+		{"last_seen_at", "INTEGER"},
+		{"superseded_by", "TEXT"},
+		{"superseded_at", "INTEGER"},
+	} {
+		if err := ensureColumn(db, "person_facts", col.name, col.def); err != nil {
+			return err
+		}
+	}
+	// Unattributed facts dismissed in the review queue
+	if err := ensureColumn(db, "unattributed_facts", "discarded_at", "INTEGER"); err != nil {
+		return err
+	}
+	// PII extraction runs already synced into person_facts
+	if err := ensureColumn(db, "analysis_runs", "facts_synced_at", "INTEGER"); err != nil {
+		return err
+	}
 	// Add is_group column to threads table (for group vs 1:1 chat detection)
 	if err := ensureColumn(db, "threads", "is_group", "INTEGER NOT NULL DEFAULT 0"); err != nil {
 		return err
 	}
+	// Mention offsets for provenance highlighting
+	if err := ensureColumn(db, "episode_entity_mentions", "mention_offsets", "TEXT"); err != nil {
+		return err
+	}
+	// Relationship ranking weight
+	if err := ensureColumn(db, "relationships", "weight", "REAL DEFAULT 0"); err != nil {
+		return err
+	}
+	// Strongest mention source type, for "stated by the person" queries
+	if err := ensureColumn(db, "relationships", "source_type", "TEXT"); err != nil {
+		return err
+	}
+	// Hand-entered facts
+	if err := ensureColumn(db, "relationships", "origin", "TEXT"); err != nil {
+		return err
+	}
+	// User-locked entity names
+	if err := ensureColumn(db, "entities", "name_locked", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	// User-set entity types, skipped by the type checker
+	if err := ensureColumn(db, "entities", "type_locked", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	// Resumable batch merge processing
+	if err := ensureColumn(db, "merge_candidates", "last_evaluated_at", "TEXT"); err != nil {
+		return err
+	}
+	// Message edits and unsends
+	if err := ensureColumn(db, "events", "supersedes", "TEXT"); err != nil {
+		return err
+	}
+	// Episode content hashes
+	if err := ensureColumn(db, "episodes", "content_hash", "TEXT"); err != nil {
+		return err
+	}
+	// Mined aliases
+	if err := ensureColumn(db, "entity_aliases", "origin", "TEXT"); err != nil {
+		return err
+	}
+	// Gmail history watermark
+	if err := ensureColumn(db, "sync_watermarks", "history_id", "INTEGER"); err != nil {
+		return err
+	}
+	// Household namespaces
+	if err := ensureColumn(db, "persons", "namespace", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "person_facts", "namespace", "TEXT"); err != nil {
+		return err
+	}
+	// Model routing decisions on episode_processing
+	for _, col := range []struct{ name, def string }{
+		{"route_tier", "TEXT"},
+		{"route_reason", "TEXT"},
+		{"estimated_tokens", "INTEGER"},
+		{"participants", "INTEGER"},
+		// Episode quality gate
+		{"quality_score", "REAL"},
+		{"skip_reason", "TEXT"},
+		// Content hashing
+		{"content_hash", "TEXT"},
+	} {
+		if err := ensureColumn(db, "episode_processing", col.name, col.def); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -153,6 +308,48 @@ func ensureColumn(db *sql.DB, table, column, definition string) error {
 	return nil
 }
 
+// backfillRelationshipSourceTypes sets relationships.source_type from the
+// strongest source_type among each relationship's mentions, for rows written
+// before the column existed.
+func backfillRelationshipSourceTypes(db *sql.DB) error {
+	_, err := db.Exec(`
+		UPDATE relationships SET source_type = (
+			SELECT erm.source_type FROM episode_relationship_mentions erm
+			WHERE erm.relationship_id = relationships.id
+			  AND erm.source_type IN ('self_disclosed', 'mentioned', 'inferred')
+			ORDER BY CASE erm.source_type WHEN 'self_disclosed' THEN 3 WHEN 'mentioned' THEN 2 ELSE 1 END DESC
+			LIMIT 1
+		)
+		WHERE source_type IS NULL
+		  AND EXISTS (
+			SELECT 1 FROM episode_relationship_mentions erm
+			WHERE erm.relationship_id = relationships.id
+			  AND erm.source_type IN ('self_disclosed', 'mentioned', 'inferred')
+		  )
+	`)
+	if err != nil {
+		return fmt.Errorf("backfill relationship source types: %w", err)
+	}
+	return nil
+}
+
+// standardizeEntityOrigins renames origins written by older builds to the
+// standard set (see memory.EntityOrigins).
+func standardizeEntityOrigins(db *sql.DB) error {
+	_, err := db.Exec(`
+		UPDATE entities SET origin = CASE origin
+			WHEN 'person_fact' THEN 'imported'
+			WHEN 'gazetteer' THEN 'inferred'
+			WHEN 'contact_import' THEN 'contact_seed'
+		END
+		WHERE origin IN ('person_fact', 'gazetteer', 'contact_import')
+	`)
+	if err != nil {
+		return fmt.Errorf("standardize entity origins: %w", err)
+	}
+	return nil
+}
+
 func ensureEventParticipantIndexes(db *sql.DB) error {
 	if !tableExists(db, "event_participants") {
 		return nil
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/errs"
+)
+
+func TestOpenSchemaVersions(t *testing.T) {
+	t.Setenv("MNEMONIC_DATA_DIR", t.TempDir())
+
+	if _, err := Open(); !errors.Is(err, errs.ErrNotInitialized) {
+		t.Fatalf("Open before Init = %v, want ErrNotInitialized", err)
+	}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	d, err := Open()
+	if err != nil {
+		t.Fatalf("Open after Init: %v", err)
+	}
+
+	// A database from an older build is upgraded in place
+	if _, err := d.Exec(fmt.Sprintf("PRAGMA user_version = %d", SchemaVersion-1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Exec("DROP TABLE entity_blocking_keys"); err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+	if d, err = Open(); err != nil {
+		t.Fatalf("Open older schema: %v", err)
+	}
+	var version, tables int
+	d.QueryRow("PRAGMA user_version").Scan(&version)
+	d.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'entity_blocking_keys'").Scan(&tables)
+	if version != SchemaVersion || tables != 1 {
+		t.Errorf("after upgrade: version %d, blocking keys table %d; want %d, 1", version, tables, SchemaVersion)
+	}
+
+	// One from a newer build is refused
+	if _, err := d.Exec(fmt.Sprintf("PRAGMA user_version = %d", SchemaVersion+1)); err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+	if _, err := Open(); !errors.Is(err, errs.ErrSchemaOutdated) {
+		t.Errorf("Open newer schema = %v, want ErrSchemaOutdated", err)
+	}
+}
@@ -29,12 +29,14 @@ CREATE TABLE IF NOT EXISTS events (
     source_adapter TEXT NOT NULL,
     source_id TEXT NOT NULL,
     metadata_json TEXT,           -- Optional structured metadata (AIX tool calls, files, etc.)
+    supersedes TEXT,              -- Original event this edit (revision) or unsend (tombstone) replaces
     UNIQUE(source_adapter, source_id)
 );
 
 CREATE INDEX IF NOT EXISTS idx_events_timestamp ON events(timestamp);
 CREATE INDEX IF NOT EXISTS idx_events_channel ON events(channel);
 CREATE INDEX IF NOT EXISTS idx_events_thread ON events(thread_id);
+CREATE INDEX IF NOT EXISTS idx_events_supersedes ON events(supersedes) WHERE supersedes IS NOT NULL;
 
 -- Document heads: Stable pointers for document-style events (skills, docs, memory, tools)
 CREATE TABLE IF NOT EXISTS document_heads (
@@ -73,6 +75,7 @@ CREATE TABLE IF NOT EXISTS persons (
     display_name TEXT,
     is_me INTEGER DEFAULT 0,
     relationship_type TEXT,
+    namespace TEXT,                 -- Household member owning this "me" person; NULL = shared
     created_at INTEGER NOT NULL,
     updated_at INTEGER NOT NULL
 );
@@ -119,6 +122,20 @@ CREATE TABLE IF NOT EXISTS person_contact_links (
 CREATE INDEX IF NOT EXISTS idx_person_contact_links_person ON person_contact_links(person_id);
 CREATE INDEX IF NOT EXISTS idx_person_contact_links_contact ON person_contact_links(contact_id);
 
+-- Avatars: contact photos from address books (Google Contacts, macOS
+-- Contacts), one per contact and source. Persons and entities show the
+-- newest avatar of their contacts.
+CREATE TABLE IF NOT EXISTS avatars (
+    contact_id TEXT NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    source TEXT NOT NULL,        -- adapter or importer that supplied it
+    content_type TEXT NOT NULL,  -- image/jpeg, image/png, ...
+    data BLOB NOT NULL,
+    sha256 TEXT NOT NULL,
+    source_url TEXT,             -- download URL, to skip refetching unchanged photos
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (contact_id, source)
+);
+
 -- Identities: legacy identifiers linked to persons (deprecated)
 CREATE TABLE IF NOT EXISTS identities (
     id TEXT PRIMARY KEY,
@@ -200,6 +217,39 @@ CREATE INDEX IF NOT EXISTS idx_threads_channel ON threads(channel);
 CREATE INDEX IF NOT EXISTS idx_threads_parent ON threads(parent_thread_id);
 CREATE INDEX IF NOT EXISTS idx_threads_name ON threads(name);
 
+-- Thread membership: group chat roster history, derived from membership
+-- events (joins/leaves) and participation. joined_at NULL means a member
+-- since before the first record; left_at NULL means still a member.
+CREATE TABLE IF NOT EXISTS thread_membership (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    thread_id TEXT NOT NULL,
+    contact_id TEXT NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    joined_at INTEGER,
+    left_at INTEGER,
+    join_event_id TEXT,
+    leave_event_id TEXT,
+    source TEXT NOT NULL              -- 'membership_event', 'observed'
+);
+
+CREATE INDEX IF NOT EXISTS idx_thread_membership_thread ON thread_membership(thread_id, contact_id);
+CREATE INDEX IF NOT EXISTS idx_thread_membership_contact ON thread_membership(contact_id);
+
+-- Thread changes: Audit trail for manual thread merges and splits
+-- event_ids records exactly which events were moved so a change can be reviewed or undone
+CREATE TABLE IF NOT EXISTS thread_changes (
+    id TEXT PRIMARY KEY,
+    operation TEXT NOT NULL,          -- 'merge', 'split'
+    source_thread_id TEXT NOT NULL,   -- thread events were moved out of
+    target_thread_id TEXT NOT NULL,   -- thread events were moved into
+    event_ids TEXT NOT NULL,          -- JSON array of moved event IDs
+    episodes_updated INTEGER NOT NULL DEFAULT 0,
+    reason TEXT,
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_thread_changes_source ON thread_changes(source_thread_id);
+CREATE INDEX IF NOT EXISTS idx_thread_changes_target ON thread_changes(target_thread_id);
+
 -- Attachments: Media/file metadata for events
 CREATE TABLE IF NOT EXISTS attachments (
     id TEXT PRIMARY KEY,
@@ -238,7 +288,8 @@ CREATE INDEX IF NOT EXISTS idx_attachments_hash ON attachments(content_hash);
 CREATE TABLE IF NOT EXISTS sync_watermarks (
     adapter TEXT PRIMARY KEY,
     last_sync_at INTEGER NOT NULL,
-    last_event_id TEXT
+    last_event_id TEXT,
+    history_id INTEGER              -- Gmail historyId incremental syncs resume from
 );
 
 -- Adapter state: generic key/value store for adapter-specific durable state
@@ -250,6 +301,98 @@ CREATE TABLE IF NOT EXISTS adapter_state (
     PRIMARY KEY (adapter, key)
 );
 
+-- Maintenance runs: schedule and lease per background maintenance task.
+-- running_since doubles as a lease so two processes never run a task at once.
+CREATE TABLE IF NOT EXISTS maintenance_runs (
+    task TEXT PRIMARY KEY,
+    next_run_at INTEGER NOT NULL,
+    running_since INTEGER,          -- Non-null while a run holds the lease
+    last_started_at INTEGER,
+    last_finished_at INTEGER,
+    last_status TEXT,               -- 'ok', 'error'
+    last_message TEXT,              -- Task summary or error
+    runs INTEGER NOT NULL DEFAULT 0
+);
+
+-- API usage: tokens and estimated cost per provider, API key, and local day,
+-- accumulated across processes so quotas can be tracked (see 'usage').
+CREATE TABLE IF NOT EXISTS api_usage (
+    provider TEXT NOT NULL,         -- 'gemini'
+    key_id TEXT NOT NULL,           -- Short hash of the API key, or 'adc'
+    day TEXT NOT NULL,              -- YYYY-MM-DD, local time
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    embed_chars INTEGER NOT NULL DEFAULT 0,
+    calls INTEGER NOT NULL DEFAULT 0,
+    cost_usd REAL NOT NULL DEFAULT 0,
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (provider, key_id, day)
+);
+
+-- API tokens for 'serve': each token carries scopes (read-graph,
+-- read-events, write-merges, admin). Only a hash of the secret is stored.
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    token_hash TEXT NOT NULL UNIQUE, -- SHA-256 of the secret, hex
+    scopes TEXT NOT NULL,            -- Comma-separated
+    created_at INTEGER NOT NULL,
+    last_used_at INTEGER,
+    revoked_at INTEGER
+);
+
+-- Audit log: every read of a sensitive row (sensitive person_facts such as
+-- government IDs and medical facts, identifiers returned by the API) with who read it, so exposure of
+-- sensitive data is traceable (see 'audit').
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    accessed_at INTEGER NOT NULL,
+    command TEXT,                   -- CLI command path ('mnemonic person facts')
+    token_id TEXT,                  -- API token, when read through the API
+    table_name TEXT NOT NULL,       -- 'person_facts', 'person_fact_history', 'unattributed_facts', 'entity_aliases'
+    row_id TEXT NOT NULL,
+    person_id TEXT,
+    fact_type TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_accessed ON audit_log(accessed_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_person ON audit_log(person_id, accessed_at);
+
+-- Instance locks: which process is doing exclusive work ('sync:<adapter>',
+-- 'daemon', 'compute'). Mirrored by lock files in <data dir>/locks.
+CREATE TABLE IF NOT EXISTS instance_locks (
+    name TEXT PRIMARY KEY,
+    pid INTEGER NOT NULL,
+    hostname TEXT NOT NULL,
+    command TEXT NOT NULL,
+    acquired_at INTEGER NOT NULL,
+    heartbeat_at INTEGER NOT NULL,  -- Refreshed while held; stale once it stops
+    token TEXT NOT NULL             -- Unique per acquisition
+);
+
+-- Metrics snapshots: periodic row counts for trend tracking
+CREATE TABLE IF NOT EXISTS metrics_snapshots (
+    id TEXT PRIMARY KEY,
+    taken_at INTEGER NOT NULL,
+    metrics_json TEXT NOT NULL      -- {"entities": 123, "relationships": 456, ...}
+);
+
+CREATE INDEX IF NOT EXISTS idx_metrics_snapshots_taken ON metrics_snapshots(taken_at);
+
+-- Digests: daily reports of what the graph learned, covering the changelog
+-- and entity_changes entries after the previous digest
+CREATE TABLE IF NOT EXISTS digests (
+    id TEXT PRIMARY KEY,
+    created_at INTEGER NOT NULL,
+    changelog_from INTEGER NOT NULL,       -- changelog seq the digest starts after
+    changelog_to INTEGER NOT NULL,         -- last changelog seq covered
+    entity_changes_from INTEGER NOT NULL,  -- same, for entity_changes (invalidations)
+    entity_changes_to INTEGER NOT NULL,
+    report_json TEXT NOT NULL              -- digest.Digest
+);
+
+CREATE INDEX IF NOT EXISTS idx_digests_created ON digests(created_at);
+
 -- Bus events: append-only event stream for downstream automation
 CREATE TABLE IF NOT EXISTS bus_events (
     seq INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -323,6 +466,14 @@ CREATE TABLE IF NOT EXISTS person_facts (
     is_sensitive INTEGER DEFAULT 0,       -- SSN, medical, financial
     is_identifier INTEGER DEFAULT 0,      -- used for identity matching
     is_hard_identifier INTEGER DEFAULT 0, -- triggers instant merge consideration
+    namespace TEXT,                       -- household member a sensitive fact is private to; NULL = shared
+
+    -- Upsert bookkeeping (see identify.InsertFact)
+    normalized_value TEXT,          -- dedup key with person_id + fact_type
+    seen_count INTEGER DEFAULT 1,   -- times this value was extracted
+    last_seen_at INTEGER,
+    superseded_by TEXT,             -- newer value of a single-valued fact type
+    superseded_at INTEGER,
 
     -- Timestamps
     created_at INTEGER NOT NULL,
@@ -334,9 +485,53 @@ CREATE TABLE IF NOT EXISTS person_facts (
 CREATE INDEX IF NOT EXISTS idx_person_facts_person ON person_facts(person_id);
 CREATE INDEX IF NOT EXISTS idx_person_facts_type ON person_facts(category, fact_type);
 CREATE INDEX IF NOT EXISTS idx_person_facts_value ON person_facts(fact_value);
+CREATE INDEX IF NOT EXISTS idx_person_facts_normalized ON person_facts(person_id, fact_type, normalized_value);
 CREATE INDEX IF NOT EXISTS idx_person_facts_hard_id ON person_facts(fact_type, fact_value)
     WHERE is_hard_identifier = 1;
 
+-- Person fact history: how a person's facts changed over time
+CREATE TABLE IF NOT EXISTS person_fact_history (
+    id TEXT PRIMARY KEY,
+    fact_id TEXT NOT NULL,
+    person_id TEXT NOT NULL,
+    fact_type TEXT NOT NULL,
+    change_type TEXT NOT NULL,      -- 'created', 'reinforced', 'superseded'
+    old_value TEXT,
+    new_value TEXT,
+    old_confidence REAL,
+    new_confidence REAL,
+    source_episode_id TEXT,
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_person_fact_history_person ON person_fact_history(person_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_person_fact_history_fact ON person_fact_history(fact_id);
+
+-- Person ↔ entity links: which memory-graph entity a contacts-graph person is
+CREATE TABLE IF NOT EXISTS person_entity_links (
+    person_id TEXT PRIMARY KEY REFERENCES persons(id) ON DELETE CASCADE,
+    entity_id TEXT NOT NULL REFERENCES entities(id),
+    method TEXT NOT NULL,           -- 'identifier', 'name'
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_person_entity_links_entity ON person_entity_links(entity_id);
+
+-- Person fact ↔ graph mirror: the relationship or alias a person fact is mirrored as.
+-- origin records which side the fact came from so it is never mirrored back.
+CREATE TABLE IF NOT EXISTS person_fact_graph_links (
+    id TEXT PRIMARY KEY,
+    person_fact_id TEXT NOT NULL,
+    relationship_id TEXT,           -- exactly one of relationship_id or alias_id
+    alias_id TEXT,
+    origin TEXT NOT NULL,           -- 'person_fact' or 'graph'
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_person_fact_graph_links_fact ON person_fact_graph_links(person_fact_id);
+CREATE INDEX IF NOT EXISTS idx_person_fact_graph_links_rel ON person_fact_graph_links(relationship_id);
+CREATE INDEX IF NOT EXISTS idx_person_fact_graph_links_alias ON person_fact_graph_links(alias_id);
+
 -- Unattributed facts: Facts extracted from episodes that couldn't be attributed to a specific person
 -- For example: phone numbers shared without context about whose number it is
 CREATE TABLE IF NOT EXISTS unattributed_facts (
@@ -354,7 +549,8 @@ CREATE TABLE IF NOT EXISTS unattributed_facts (
     resolution_evidence TEXT,
 
     created_at INTEGER NOT NULL,
-    resolved_at INTEGER
+    resolved_at INTEGER,
+    discarded_at INTEGER            -- dismissed during review; never auto-resolved
 );
 
 CREATE INDEX IF NOT EXISTS idx_unattributed_value ON unattributed_facts(fact_type, fact_value);
@@ -416,6 +612,16 @@ CREATE TABLE IF NOT EXISTS episode_definitions (
     updated_at INTEGER NOT NULL
 );
 
+-- Primary definitions: the definition whose episodes downstream consumers
+-- (analysis, memory extraction) use for a channel. An event can be in
+-- episodes of several definitions; only the primary one is processed.
+-- Channels without a row process every definition.
+CREATE TABLE IF NOT EXISTS episode_primary_definitions (
+    channel TEXT PRIMARY KEY,
+    definition_id TEXT NOT NULL REFERENCES episode_definitions(id) ON DELETE CASCADE,
+    updated_at INTEGER NOT NULL
+);
+
 -- Episodes: instances produced by applying a definition
 CREATE TABLE IF NOT EXISTS episodes (
     id TEXT PRIMARY KEY,
@@ -437,6 +643,9 @@ CREATE TABLE IF NOT EXISTS episodes (
     first_event_id TEXT,
     last_event_id TEXT,
 
+    -- SHA-256 of the ordered event IDs and their newest revisions (chunk.ContentHash)
+    content_hash TEXT,
+
     created_at INTEGER NOT NULL
 );
 
@@ -444,6 +653,7 @@ CREATE INDEX IF NOT EXISTS idx_episodes_definition ON episodes(definition_id);
 CREATE INDEX IF NOT EXISTS idx_episodes_channel ON episodes(channel);
 CREATE INDEX IF NOT EXISTS idx_episodes_thread ON episodes(thread_id);
 CREATE INDEX IF NOT EXISTS idx_episodes_time ON episodes(start_time, end_time);
+CREATE INDEX IF NOT EXISTS idx_episodes_content_hash ON episodes(content_hash);
 
 -- Episode events: which events belong to which episode
 CREATE TABLE IF NOT EXISTS episode_events (
@@ -455,6 +665,20 @@ CREATE TABLE IF NOT EXISTS episode_events (
 
 CREATE INDEX IF NOT EXISTS idx_episode_events_event ON episode_events(event_id);
 
+-- Episode notes: freeform notes and corrections a person attached to an
+-- episode or to one of its events ("this was sarcasm"), added as context
+-- whenever the episode is analyzed or extracted again
+CREATE TABLE IF NOT EXISTS episode_notes (
+    id TEXT PRIMARY KEY,
+    episode_id TEXT REFERENCES episodes(id) ON DELETE CASCADE, -- exactly one of episode_id, event_id
+    event_id TEXT REFERENCES events(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_episode_notes_episode ON episode_notes(episode_id);
+CREATE INDEX IF NOT EXISTS idx_episode_notes_event ON episode_notes(event_id);
+
 -- Analysis types: defines what kind of analysis this is
 CREATE TABLE IF NOT EXISTS analysis_types (
     id TEXT PRIMARY KEY,
@@ -497,6 +721,9 @@ CREATE TABLE IF NOT EXISTS analysis_runs (
     blocked_reason TEXT,
     retry_count INTEGER DEFAULT 0,
 
+    -- When the output was synced into person_facts (PII extraction)
+    facts_synced_at INTEGER,
+
     created_at INTEGER NOT NULL,
 
     UNIQUE(analysis_type_id, episode_id)
@@ -534,6 +761,27 @@ CREATE INDEX IF NOT EXISTS idx_facets_analysis_run ON facets(analysis_run_id);
 CREATE INDEX IF NOT EXISTS idx_facets_person ON facets(person_id);
 CREATE INDEX IF NOT EXISTS idx_facets_value ON facets(value);
 
+-- Action items: commitments and requests extracted by the action_items analysis
+CREATE TABLE IF NOT EXISTS action_items (
+    id TEXT PRIMARY KEY,
+    description TEXT NOT NULL,
+    owner TEXT NOT NULL,                 -- 'me' (I owe it) or 'them' (owed to me)
+    person_id TEXT REFERENCES persons(id), -- the other party
+    due_at INTEGER,                      -- NULL if no due date
+    status TEXT NOT NULL DEFAULT 'open', -- 'open', 'done', 'dismissed'
+    evidence TEXT,
+    confidence REAL,
+    source_episode_id TEXT REFERENCES episodes(id),
+    analysis_run_id TEXT REFERENCES analysis_runs(id) ON DELETE SET NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    completed_at INTEGER,
+    UNIQUE(analysis_run_id, description)
+);
+
+CREATE INDEX IF NOT EXISTS idx_action_items_status_due ON action_items(status, due_at);
+CREATE INDEX IF NOT EXISTS idx_action_items_person ON action_items(person_id);
+
 -- ============================================
 -- EMBEDDINGS (unified for all embeddable types)
 -- ============================================
@@ -602,9 +850,11 @@ CREATE TABLE IF NOT EXISTS entities (
     summary_updated_at TEXT,    -- When summary was last regenerated
 
     -- How this entity was created
-    origin TEXT NOT NULL,       -- 'contact_import', 'extracted', 'manual'
+    origin TEXT NOT NULL,       -- 'extracted', 'imported', 'contact_seed', 'manual', 'inferred'
     confidence REAL DEFAULT 1.0,
     merged_into TEXT REFERENCES entities(id),  -- Non-null if this entity was merged
+    name_locked INTEGER NOT NULL DEFAULT 0,    -- 1 = canonical_name set by the user; automation never renames
+    type_locked INTEGER NOT NULL DEFAULT 0,    -- 1 = entity_type_id set by the user; the type checker skips it
 
     created_at TEXT NOT NULL,
     updated_at TEXT NOT NULL
@@ -613,6 +863,20 @@ CREATE TABLE IF NOT EXISTS entities (
 CREATE INDEX IF NOT EXISTS idx_entities_type ON entities(entity_type_id);
 CREATE INDEX IF NOT EXISTS idx_entities_name ON entities(canonical_name);
 
+-- Every canonical_name change: merges, normalization, and manual renames
+CREATE TABLE IF NOT EXISTS entity_name_history (
+    id TEXT PRIMARY KEY,
+    entity_id TEXT NOT NULL REFERENCES entities(id),
+    old_name TEXT NOT NULL,
+    new_name TEXT NOT NULL,
+    reason TEXT NOT NULL,           -- 'merge', 'geo_normalization', 'manual'
+    source_entity_id TEXT,          -- For merges: the entity whose name was taken
+    changed_by TEXT NOT NULL,       -- 'auto' or 'user'
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_entity_name_history_entity ON entity_name_history(entity_id, created_at);
+
 -- ============================================
 -- ENTITY ALIASES (identity resolution)
 -- ============================================
@@ -627,10 +891,11 @@ CREATE TABLE IF NOT EXISTS entity_aliases (
     id TEXT PRIMARY KEY,
     entity_id TEXT NOT NULL REFERENCES entities(id),
     alias TEXT NOT NULL,
-    alias_type TEXT NOT NULL,  -- 'name', 'email', 'phone', 'handle', 'username', 'nickname'
+    alias_type TEXT NOT NULL,  -- 'name', 'email', 'phone', 'handle', 'username', 'nickname', 'domain'
     normalized TEXT,           -- Lowercase/cleaned for matching
     is_shared BOOLEAN DEFAULT FALSE,  -- TRUE if multiple entities share this alias
-    created_at TEXT NOT NULL
+    created_at TEXT NOT NULL,
+    origin TEXT                -- NULL = extracted or entered; 'deterministic' = mined from contact names and email signatures
     -- NOTE: No UNIQUE constraint - same alias can map to multiple entities
 );
 
@@ -638,6 +903,22 @@ CREATE INDEX IF NOT EXISTS idx_entity_aliases_lookup ON entity_aliases(alias, al
 CREATE INDEX IF NOT EXISTS idx_entity_aliases_normalized ON entity_aliases(normalized, alias_type);
 CREATE INDEX IF NOT EXISTS idx_entity_aliases_entity ON entity_aliases(entity_id);
 
+-- ============================================
+-- ENTITY BLOCKING KEYS (resolution candidate index)
+-- ============================================
+-- Cheap, lossy keys derived from aliases so resolution only compares entities
+-- that share a key instead of scanning every alias/embedding.
+-- Maintained on write (new entity, promoted alias, merge).
+CREATE TABLE IF NOT EXISTS entity_blocking_keys (
+    key_type TEXT NOT NULL,    -- 'token', 'phonetic', 'ident'
+    key TEXT NOT NULL,         -- Normalized token, Soundex code, or identifier hash
+    entity_id TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
+    created_at TEXT NOT NULL,
+    PRIMARY KEY (key_type, key, entity_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_entity_blocking_keys_entity ON entity_blocking_keys(entity_id);
+
 -- ============================================
 -- RELATIONSHIPS (deduplicated triples with temporal bounds)
 -- ============================================
@@ -659,6 +940,9 @@ CREATE TABLE IF NOT EXISTS relationships (
 
     -- Metadata
     confidence REAL DEFAULT 1.0,
+    weight REAL DEFAULT 0,  -- Ranking strength from mention frequency, recency, and source type
+    source_type TEXT,       -- Strongest source_type across mentions: 'self_disclosed' > 'mentioned' > 'inferred'
+    origin TEXT,            -- NULL = extracted; 'manual' = entered or corrected by hand ('fact add'/'fact edit'); 'inferred' = derived by inference rules
 
     -- Exactly one of target_entity_id or target_literal must be set
     CHECK (
@@ -672,6 +956,11 @@ CREATE INDEX IF NOT EXISTS idx_relationships_target ON relationships(target_enti
 CREATE INDEX IF NOT EXISTS idx_relationships_type ON relationships(relation_type);
 CREATE INDEX IF NOT EXISTS idx_relationships_temporal ON relationships(valid_at, invalid_at);
 
+-- Composite indexes for the hot traversal paths: "current X of entity" filters
+-- on (entity, relation_type, invalid_at) in both directions.
+CREATE INDEX IF NOT EXISTS idx_relationships_source_type_invalid ON relationships(source_entity_id, relation_type, invalid_at);
+CREATE INDEX IF NOT EXISTS idx_relationships_target_type_invalid ON relationships(target_entity_id, relation_type, invalid_at);
+
 -- Uniqueness for entity-target relationships
 CREATE UNIQUE INDEX IF NOT EXISTS idx_relationships_unique_entity
 ON relationships(source_entity_id, target_entity_id, relation_type, valid_at)
@@ -682,6 +971,129 @@ CREATE UNIQUE INDEX IF NOT EXISTS idx_relationships_unique_literal
 ON relationships(source_entity_id, target_literal, relation_type, valid_at)
 WHERE target_literal IS NOT NULL;
 
+-- ============================================
+-- RELATIONSHIP PREMISES (provenance of inferred facts)
+-- ============================================
+-- The edges an inferred relationship (origin 'inferred') was derived from
+-- by an inference rule. Each derivation is one chain of premises, named by
+-- their IDs in order; the inferred fact holds while any derivation's
+-- premises all do.
+CREATE TABLE IF NOT EXISTS relationship_premises (
+    relationship_id TEXT NOT NULL REFERENCES relationships(id) ON DELETE CASCADE,
+    derivation TEXT NOT NULL,   -- Premise IDs in chain order, comma-separated
+    position INTEGER NOT NULL,  -- Premise's place in the chain, from 0
+    premise_id TEXT NOT NULL REFERENCES relationships(id) ON DELETE CASCADE,
+    rule TEXT NOT NULL,         -- Inference rule name
+    created_at TEXT NOT NULL,
+    PRIMARY KEY (relationship_id, derivation, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_relationship_premises_premise ON relationship_premises(premise_id);
+
+-- ============================================
+-- ENTITY CURRENT FACTS (materialized)
+-- ============================================
+-- Latest non-invalidated outgoing edge per (entity, relation_type).
+-- Refreshed incrementally by the pipeline and merges; fully rebuilt by maintenance.
+CREATE TABLE IF NOT EXISTS entity_current_facts (
+    entity_id TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
+    relation_type TEXT NOT NULL,
+    relationship_id TEXT NOT NULL REFERENCES relationships(id) ON DELETE CASCADE,
+    target_entity_id TEXT,
+    target_literal TEXT,
+    fact TEXT NOT NULL,
+    valid_at TEXT,
+    confidence REAL DEFAULT 1.0,
+    updated_at TEXT NOT NULL,
+    PRIMARY KEY (entity_id, relation_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_entity_current_facts_target ON entity_current_facts(target_entity_id);
+
+-- ============================================
+-- CARDINALITY VIOLATIONS (flagged for review)
+-- ============================================
+-- A new edge conflicting with an existing current edge of a relation type
+-- declared cardinality "one" with enforcement "flag" (e.g., a second BORN_ON).
+-- Both edges stay valid until the violation is resolved.
+CREATE TABLE IF NOT EXISTS cardinality_violations (
+    id TEXT PRIMARY KEY,
+    entity_id TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
+    relation_type TEXT NOT NULL,
+    relationship_id TEXT NOT NULL REFERENCES relationships(id) ON DELETE CASCADE,              -- New edge
+    conflicting_relationship_id TEXT NOT NULL REFERENCES relationships(id) ON DELETE CASCADE,  -- Existing edge
+    status TEXT NOT NULL DEFAULT 'pending',  -- 'pending', 'resolved', 'dismissed'
+    created_at TEXT NOT NULL,
+    resolved_at TEXT,
+    UNIQUE(relationship_id, conflicting_relationship_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cardinality_violations_status ON cardinality_violations(status);
+CREATE INDEX IF NOT EXISTS idx_cardinality_violations_entity ON cardinality_violations(entity_id);
+
+-- ============================================
+-- ENTITY TYPE FLAGS (flagged for review)
+-- ============================================
+-- An entity whose relationships fit another type better than its own, e.g. an
+-- Organization with SPOUSE_OF and FRIEND_OF edges. Accepting retypes the
+-- entity; rejected flags are kept so the suggestion is not made again.
+CREATE TABLE IF NOT EXISTS entity_type_flags (
+    id TEXT PRIMARY KEY,
+    entity_id TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
+    current_type_id INTEGER NOT NULL,
+    suggested_type_id INTEGER NOT NULL,
+    evidence INTEGER NOT NULL,               -- Edges expecting suggested_type_id
+    total INTEGER NOT NULL,                  -- Edges whose relation type constrains the entity's type
+    relation_types TEXT NOT NULL,            -- JSON array of relation types that don't fit current_type_id
+    status TEXT NOT NULL DEFAULT 'pending',  -- 'pending', 'accepted', 'rejected', 'resolved'
+    created_at TEXT NOT NULL,
+    resolved_at TEXT,
+    UNIQUE(entity_id, suggested_type_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_entity_type_flags_status ON entity_type_flags(status);
+
+-- ============================================
+-- FACT VERIFICATIONS (stale facts review queue)
+-- ============================================
+-- A current fact of a changeable relation type (LIVES_IN, WORKS_AT) that
+-- nothing has mentioned for a long time. A newer contradicting fact ends it
+-- automatically ('superseded'); otherwise the user confirms it still holds,
+-- which restarts its clock, or ends it.
+CREATE TABLE IF NOT EXISTS fact_verifications (
+    id TEXT PRIMARY KEY,
+    relationship_id TEXT NOT NULL REFERENCES relationships(id) ON DELETE CASCADE,
+    last_seen_at TEXT NOT NULL,              -- Latest creation, mention or confirmation when queued
+    status TEXT NOT NULL DEFAULT 'pending',  -- 'pending', 'confirmed', 'ended', 'superseded'
+    superseded_by TEXT REFERENCES relationships(id) ON DELETE SET NULL,
+    created_at TEXT NOT NULL,
+    resolved_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_fact_verifications_status ON fact_verifications(status);
+CREATE INDEX IF NOT EXISTS idx_fact_verifications_relationship ON fact_verifications(relationship_id);
+
+-- ============================================
+-- EDGE SUGGESTIONS (co-mention review queue)
+-- ============================================
+-- Two people mentioned together in many episodes with no relationship
+-- between them. Accepting adds a low-confidence inferred KNOWS edge;
+-- rejected suggestions are kept so the pair is not suggested again.
+CREATE TABLE IF NOT EXISTS edge_suggestions (
+    id TEXT PRIMARY KEY,
+    entity_a_id TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,  -- entity_a_id < entity_b_id
+    entity_b_id TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
+    relation_type TEXT NOT NULL,             -- Suggested edge, e.g. 'KNOWS'
+    co_mentions INTEGER NOT NULL,            -- Episodes mentioning both when last analyzed
+    status TEXT NOT NULL DEFAULT 'pending',  -- 'pending', 'accepted', 'rejected'
+    relationship_id TEXT,                    -- Edge created on accept
+    created_at TEXT NOT NULL,
+    resolved_at TEXT,
+    UNIQUE(entity_a_id, entity_b_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_edge_suggestions_status ON edge_suggestions(status);
+
 -- ============================================
 -- EPISODE-ENTITY MENTIONS (which episodes mention which entities)
 -- ============================================
@@ -692,6 +1104,7 @@ CREATE TABLE IF NOT EXISTS episode_entity_mentions (
     episode_id TEXT NOT NULL REFERENCES episodes(id) ON DELETE CASCADE,
     entity_id TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
     mention_count INTEGER DEFAULT 1,
+    mention_offsets TEXT,  -- JSON: [{start, end, message, text}, ...] character offsets for highlighting
     created_at TEXT NOT NULL,
     PRIMARY KEY (episode_id, entity_id)
 );
@@ -726,6 +1139,57 @@ CREATE TABLE IF NOT EXISTS episode_relationship_mentions (
 CREATE INDEX IF NOT EXISTS idx_episode_rel_mentions_episode ON episode_relationship_mentions(episode_id);
 CREATE INDEX IF NOT EXISTS idx_episode_rel_mentions_relationship ON episode_relationship_mentions(relationship_id);
 
+-- ============================================
+-- EPISODE PROCESSING (cost and latency per processed episode)
+-- ============================================
+-- One row per episode run through the memory pipeline (latest run wins).
+-- Used for: cost per thread/channel, finding pathological episodes
+-- (huge group chats) that eat the extraction budget.
+CREATE TABLE IF NOT EXISTS episode_processing (
+    episode_id TEXT PRIMARY KEY REFERENCES episodes(id) ON DELETE CASCADE,
+    channel TEXT,
+    thread_id TEXT,
+    model TEXT,
+    llm_calls INTEGER NOT NULL DEFAULT 0,
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    embed_chars INTEGER NOT NULL DEFAULT 0,
+    cost_usd REAL NOT NULL DEFAULT 0,
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    content_chars INTEGER NOT NULL DEFAULT 0,
+    route_tier TEXT,                 -- 'cheap', 'strong' (NULL when model routing is off)
+    route_reason TEXT,               -- 'short', 'long', 'group'
+    estimated_tokens INTEGER,        -- router's content token estimate
+    participants INTEGER,            -- router's participant count
+    quality_score REAL,              -- 0-1 information score (NULL when the quality gate is off)
+    skip_reason TEXT,                -- why the quality gate skipped it, e.g. 'mostly_links_or_emoji'
+    content_hash TEXT,               -- episode content hash when processed; unchanged content is not re-extracted
+    status TEXT NOT NULL,            -- 'ok', 'error', 'skipped'
+    error TEXT,
+    processed_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_episode_processing_channel ON episode_processing(channel);
+CREATE INDEX IF NOT EXISTS idx_episode_processing_thread ON episode_processing(thread_id);
+CREATE INDEX IF NOT EXISTS idx_episode_processing_cost ON episode_processing(cost_usd DESC);
+CREATE INDEX IF NOT EXISTS idx_episode_processing_route ON episode_processing(route_tier);
+CREATE INDEX IF NOT EXISTS idx_episode_processing_status ON episode_processing(status);
+CREATE INDEX IF NOT EXISTS idx_episode_processing_hash ON episode_processing(content_hash);
+
+-- ============================================
+-- RELATIONSHIP LABELS (human spot-check labels for calibration)
+-- ============================================
+-- Sampled relationships a human marked correct or incorrect during CLI review.
+-- Used to compare stated confidence against observed precision so thresholds
+-- (auto-merge, digest inclusion) can be set from data.
+CREATE TABLE IF NOT EXISTS relationship_labels (
+    relationship_id TEXT PRIMARY KEY REFERENCES relationships(id) ON DELETE CASCADE,
+    correct INTEGER NOT NULL,        -- 1 = correct, 0 = incorrect
+    confidence REAL,                 -- Stated confidence at labeling time
+    note TEXT,
+    labeled_at TEXT NOT NULL
+);
+
 -- ============================================
 -- MERGE CANDIDATES (suspected duplicates for review)
 -- ============================================
@@ -741,7 +1205,7 @@ CREATE TABLE IF NOT EXISTS merge_candidates (
     auto_eligible BOOLEAN DEFAULT FALSE,
 
     -- Evidence (why we think they match)
-    reason TEXT NOT NULL,             -- 'hard_identifier', 'name_similarity', 'compound', 'soft_accumulation', 'ambiguous_resolution'
+    reason TEXT NOT NULL,             -- 'hard_identifier', 'name_similarity', 'compound', 'soft_accumulation', 'ambiguous_resolution', 'geo_normalization', 'org_normalization'
     matching_facts TEXT,              -- JSON: [{fact_type, fact_value}, ...]
     context TEXT,                     -- JSON: additional evidence
     candidates_considered TEXT,       -- JSON: top N candidates we scored (for debugging)
@@ -751,6 +1215,7 @@ CREATE TABLE IF NOT EXISTS merge_candidates (
 
     -- Status
     status TEXT DEFAULT 'pending',    -- 'pending', 'merged', 'rejected', 'deferred'
+    last_evaluated_at TEXT,           -- Last batch run that checked it (pending ones resume oldest-first)
 
     -- Resolution
     created_at TEXT NOT NULL,
@@ -782,6 +1247,103 @@ CREATE TABLE IF NOT EXISTS entity_merge_events (
 
 CREATE INDEX IF NOT EXISTS idx_entity_merge_events_target ON entity_merge_events(target_entity_id);
 
+-- ============================================
+-- ENTITY CHANGES (per-entity change feed)
+-- ============================================
+-- Append-only feed of changes touching an entity, read incrementally by seq
+-- cursor (UI refresh, webhooks). Written by the triggers below so every
+-- writer - extraction, merges, summaries, manual edits - is covered.
+CREATE TABLE IF NOT EXISTS entity_changes (
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,
+    entity_id TEXT NOT NULL,
+    change_type TEXT NOT NULL,     -- 'relationship_added', 'relationship_invalidated', 'merged', 'merged_into', 'summary_updated', 'renamed'
+    ref_id TEXT,                   -- Relationship ID, or the other entity for merges
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_entity_changes_entity ON entity_changes(entity_id, seq);
+
+CREATE TRIGGER IF NOT EXISTS entity_changes_relationship_insert AFTER INSERT ON relationships BEGIN
+    INSERT INTO entity_changes (entity_id, change_type, ref_id, created_at)
+    VALUES (new.source_entity_id, 'relationship_added', new.id, strftime('%Y-%m-%dT%H:%M:%SZ', 'now'));
+    INSERT INTO entity_changes (entity_id, change_type, ref_id, created_at)
+    SELECT new.target_entity_id, 'relationship_added', new.id, strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+    WHERE new.target_entity_id IS NOT NULL AND new.target_entity_id != new.source_entity_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS entity_changes_relationship_invalidate AFTER UPDATE OF invalid_at ON relationships
+WHEN old.invalid_at IS NULL AND new.invalid_at IS NOT NULL BEGIN
+    INSERT INTO entity_changes (entity_id, change_type, ref_id, created_at)
+    VALUES (new.source_entity_id, 'relationship_invalidated', new.id, strftime('%Y-%m-%dT%H:%M:%SZ', 'now'));
+    INSERT INTO entity_changes (entity_id, change_type, ref_id, created_at)
+    SELECT new.target_entity_id, 'relationship_invalidated', new.id, strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+    WHERE new.target_entity_id IS NOT NULL AND new.target_entity_id != new.source_entity_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS entity_changes_merge AFTER UPDATE OF merged_into ON entities
+WHEN old.merged_into IS NULL AND new.merged_into IS NOT NULL BEGIN
+    INSERT INTO entity_changes (entity_id, change_type, ref_id, created_at)
+    VALUES (new.merged_into, 'merged', new.id, strftime('%Y-%m-%dT%H:%M:%SZ', 'now'));
+    INSERT INTO entity_changes (entity_id, change_type, ref_id, created_at)
+    VALUES (new.id, 'merged_into', new.merged_into, strftime('%Y-%m-%dT%H:%M:%SZ', 'now'));
+END;
+
+CREATE TRIGGER IF NOT EXISTS entity_changes_summary AFTER UPDATE OF summary ON entities
+WHEN new.summary IS NOT old.summary BEGIN
+    INSERT INTO entity_changes (entity_id, change_type, ref_id, created_at)
+    VALUES (new.id, 'summary_updated', NULL, strftime('%Y-%m-%dT%H:%M:%SZ', 'now'));
+END;
+
+CREATE TRIGGER IF NOT EXISTS entity_changes_rename AFTER UPDATE OF canonical_name ON entities
+WHEN new.canonical_name IS NOT old.canonical_name BEGIN
+    INSERT INTO entity_changes (entity_id, change_type, ref_id, created_at)
+    VALUES (new.id, 'renamed', NULL, strftime('%Y-%m-%dT%H:%M:%SZ', 'now'));
+END;
+
+-- ============================================
+-- CHANGELOG (global change log for downstream consumers)
+-- ============================================
+-- Append-only log of graph changes with a monotonically increasing seq, so
+-- external syncers and the web UI can resume from the last seq they saw.
+-- Written by the triggers below; never updated or pruned.
+CREATE TABLE IF NOT EXISTS changelog (
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,
+    change_type TEXT NOT NULL,     -- 'entity_created', 'relationship_added', 'merge_executed', 'episode_processed'
+    subject_id TEXT NOT NULL,      -- Entity, relationship, merge target entity, or episode
+    ref_id TEXT,                   -- For merges: the entity merged away
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_changelog_type ON changelog(change_type, seq);
+
+CREATE TRIGGER IF NOT EXISTS changelog_entity_insert AFTER INSERT ON entities BEGIN
+    INSERT INTO changelog (change_type, subject_id, created_at)
+    VALUES ('entity_created', new.id, strftime('%Y-%m-%dT%H:%M:%SZ', 'now'));
+END;
+
+CREATE TRIGGER IF NOT EXISTS changelog_relationship_insert AFTER INSERT ON relationships BEGIN
+    INSERT INTO changelog (change_type, subject_id, created_at)
+    VALUES ('relationship_added', new.id, strftime('%Y-%m-%dT%H:%M:%SZ', 'now'));
+END;
+
+CREATE TRIGGER IF NOT EXISTS changelog_merge_insert AFTER INSERT ON entity_merge_events BEGIN
+    INSERT INTO changelog (change_type, subject_id, ref_id, created_at)
+    VALUES ('merge_executed', new.target_entity_id, new.source_entity_id, strftime('%Y-%m-%dT%H:%M:%SZ', 'now'));
+END;
+
+-- episode_processing is upserted, so a reprocessed episode fires the update trigger
+CREATE TRIGGER IF NOT EXISTS changelog_episode_processed_insert AFTER INSERT ON episode_processing
+WHEN new.status = 'ok' BEGIN
+    INSERT INTO changelog (change_type, subject_id, created_at)
+    VALUES ('episode_processed', new.episode_id, strftime('%Y-%m-%dT%H:%M:%SZ', 'now'));
+END;
+
+CREATE TRIGGER IF NOT EXISTS changelog_episode_processed_update AFTER UPDATE OF processed_at ON episode_processing
+WHEN new.status = 'ok' BEGIN
+    INSERT INTO changelog (change_type, subject_id, created_at)
+    VALUES ('episode_processed', new.episode_id, strftime('%Y-%m-%dT%H:%M:%SZ', 'now'));
+END;
+
 -- ============================================
 -- AGENTS LEDGER (full fidelity AI session data)
 -- ============================================
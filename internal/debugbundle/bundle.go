@@ -0,0 +1,357 @@
+// Package debugbundle writes a zip archive for bug reports: schema versions,
+// row counts, metrics snapshots, recent errors, the tail of the sync log, and
+// sample rows - all anonymized so the bundle can be shared.
+//
+// Anonymization is deny-by-default. In sample rows, numbers pass through,
+// IDs are replaced by salted hashes (consistent within one bundle, so joins
+// still line up, but not reversible by guessing), a short list of structural
+// columns (channel, status, relation_type, ...) is kept, and every other
+// string is replaced by its length. Free text that has to be kept, like
+// error messages, is scrubbed of emails, phone numbers, URLs, and home
+// directories.
+package debugbundle
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/config"
+)
+
+// Options configures Write.
+type Options struct {
+	Version    string // build version, for the manifest
+	SchemaWant int    // schema version this build expects
+	Samples    int    // sample rows per table (default 5)
+	LogPath    string // log file to tail; empty skips it
+	LogLines   int    // lines of log to keep (default 200)
+	Config     *config.Config
+}
+
+// Manifest describes a bundle; it is also written into it as manifest.json.
+type Manifest struct {
+	CreatedAt     string   `json:"created_at"`
+	Version       string   `json:"version"`
+	GoVersion     string   `json:"go_version"`
+	OS            string   `json:"os"`
+	Arch          string   `json:"arch"`
+	SchemaVersion int      `json:"schema_version"` // PRAGMA user_version of the database
+	SchemaWant    int      `json:"schema_want"`
+	Files         []string `json:"files"`
+}
+
+// SampleTables are the tables sampled into samples/<table>.json.
+var SampleTables = []string{
+	"events", "event_participants", "threads", "persons", "contacts", "contact_identifiers",
+	"episodes", "entities", "entity_aliases", "relationships", "person_facts", "episode_processing",
+}
+
+// keptColumns are low-cardinality structural columns safe to include as-is.
+var keptColumns = map[string]bool{
+	"channel": true, "direction": true, "content_types": true, "source_adapter": true,
+	"status": true, "phase": true, "role": true, "type": true, "alias_type": true,
+	"relation_type": true, "origin": true, "category": true, "fact_type": true,
+	"source_type": true, "strategy": true, "model": true, "route_tier": true, "route_reason": true,
+	"read_state": true, "last_status": true, "relationship_type": true, "source": true,
+}
+
+// Write builds a bundle from db into w.
+func Write(ctx context.Context, db *sql.DB, w io.Writer, opts Options) (*Manifest, error) {
+	if opts.Samples <= 0 {
+		opts.Samples = 5
+	}
+	if opts.LogLines <= 0 {
+		opts.LogLines = 200
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	a := &anonymizer{salt: salt}
+
+	m := &Manifest{
+		CreatedAt:  time.Now().UTC().Format(time.RFC3339),
+		Version:    opts.Version,
+		GoVersion:  runtime.Version(),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		SchemaWant: opts.SchemaWant,
+	}
+	if err := db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&m.SchemaVersion); err != nil {
+		return nil, fmt.Errorf("read schema version: %w", err)
+	}
+
+	zw := zip.NewWriter(w)
+	add := func(name string, v any) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return fmt.Errorf("write %s: %w", name, err)
+		}
+		m.Files = append(m.Files, name)
+		return nil
+	}
+
+	tables, err := listTables(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(tables))
+	for _, t := range tables {
+		var n int64
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM "`+t+`"`).Scan(&n); err != nil {
+			return nil, fmt.Errorf("count %s: %w", t, err)
+		}
+		counts[t] = n
+	}
+	if err := add("row_counts.json", counts); err != nil {
+		return nil, err
+	}
+
+	if err := add("schema.json", map[string]any{"user_version": m.SchemaVersion, "want": opts.SchemaWant, "tables": tables}); err != nil {
+		return nil, err
+	}
+
+	if slices.Contains(tables, "metrics_snapshots") {
+		metrics, err := queryRows(ctx, db, `SELECT taken_at, metrics_json FROM metrics_snapshots ORDER BY taken_at DESC LIMIT 48`)
+		if err != nil {
+			return nil, err
+		}
+		if err := add("metrics_snapshots.json", metrics); err != nil {
+			return nil, err
+		}
+	}
+
+	errs, err := recentErrors(ctx, db, tables, a)
+	if err != nil {
+		return nil, err
+	}
+	if err := add("errors.json", errs); err != nil {
+		return nil, err
+	}
+
+	if opts.Config != nil {
+		if err := add("config.json", a.config(opts.Config)); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.LogPath != "" {
+		if lines, err := tail(opts.LogPath, opts.LogLines); err == nil {
+			for i := range lines {
+				lines[i] = a.scrub(lines[i])
+			}
+			if err := add("sync_log_tail.json", lines); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, t := range SampleTables {
+		if !slices.Contains(tables, t) {
+			continue
+		}
+		rows, err := queryRows(ctx, db, fmt.Sprintf(`SELECT * FROM "%s" ORDER BY rowid DESC LIMIT %d`, t, opts.Samples))
+		if err != nil {
+			return nil, fmt.Errorf("sample %s: %w", t, err)
+		}
+		for _, row := range rows {
+			for col, v := range row {
+				row[col] = a.value(col, v)
+			}
+		}
+		if err := add("samples/"+t+".json", rows); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := add("manifest.json", m); err != nil {
+		return nil, err
+	}
+	return m, zw.Close()
+}
+
+// recentErrors collects the latest failures recorded by sync, maintenance,
+// extraction, and analysis, with messages scrubbed.
+func recentErrors(ctx context.Context, db *sql.DB, tables []string, a *anonymizer) (map[string][]map[string]any, error) {
+	queries := []struct{ table, query string }{
+		{"sync_jobs", `SELECT adapter, status, phase, updated_at, last_error AS error FROM sync_jobs WHERE last_error IS NOT NULL AND last_error != '' ORDER BY updated_at DESC LIMIT 50`},
+		{"maintenance_runs", `SELECT task, last_finished_at, last_message AS error FROM maintenance_runs WHERE last_status = 'error' ORDER BY last_finished_at DESC LIMIT 50`},
+		{"episode_processing", `SELECT channel, model, processed_at, error FROM episode_processing WHERE status = 'error' ORDER BY processed_at DESC LIMIT 50`},
+		{"analysis_runs", `SELECT status, completed_at, retry_count, error_message AS error FROM analysis_runs WHERE error_message IS NOT NULL AND error_message != '' ORDER BY completed_at DESC LIMIT 50`},
+	}
+	out := make(map[string][]map[string]any)
+	for _, q := range queries {
+		if !slices.Contains(tables, q.table) {
+			continue
+		}
+		rows, err := queryRows(ctx, db, q.query)
+		if err != nil {
+			return nil, fmt.Errorf("read %s errors: %w", q.table, err)
+		}
+		for _, row := range rows {
+			if msg, ok := row["error"].(string); ok {
+				row["error"] = a.scrub(msg)
+			}
+			// Adapter names can embed account emails
+			if name, ok := row["adapter"].(string); ok {
+				row["adapter"] = a.scrub(name)
+			}
+		}
+		out[q.table] = rows
+	}
+	return out, nil
+}
+
+type anonymizer struct {
+	salt []byte
+}
+
+// hash returns a short salted hash of s, stable within one bundle.
+func (a *anonymizer) hash(s string) string {
+	h := sha256.New()
+	h.Write(a.salt)
+	h.Write([]byte(s))
+	return "h:" + hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// value anonymizes one sampled column value.
+func (a *anonymizer) value(col string, v any) any {
+	switch x := v.(type) {
+	case nil, int64, float64, bool:
+		return x
+	case []byte:
+		return fmt.Sprintf("<blob %d bytes>", len(x))
+	case string:
+		switch {
+		case keptColumns[col]:
+			// Adapter names can embed account emails
+			return a.scrub(x)
+		case col == "id" || strings.HasSuffix(col, "_id") || col == "reply_to":
+			return a.hash(x)
+		case x == "":
+			return ""
+		}
+		return fmt.Sprintf("<redacted %d chars>", len(x))
+	}
+	return fmt.Sprintf("<%T>", v)
+}
+
+var (
+	emailRe = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	phoneRe = regexp.MustCompile(`\+?\d[\d\s().-]{7,}\d`)
+	urlRe   = regexp.MustCompile(`https?://\S+`)
+	homeRe  = regexp.MustCompile(`(/Users|/home)/[^/\s]+`)
+)
+
+// scrub removes identifying substrings from free text.
+func (a *anonymizer) scrub(s string) string {
+	s = urlRe.ReplaceAllString(s, "<url>")
+	s = emailRe.ReplaceAllStringFunc(s, func(m string) string { return "<email " + a.hash(m) + ">" })
+	s = phoneRe.ReplaceAllString(s, "<phone>")
+	s = homeRe.ReplaceAllString(s, "$1/<user>")
+	return s
+}
+
+// config keeps the shape of the config - adapter types, what is enabled -
+// without names, paths, or options.
+func (a *anonymizer) config(cfg *config.Config) map[string]any {
+	adapters := make([]map[string]any, 0, len(cfg.Adapters))
+	for name, ad := range cfg.Adapters {
+		adapters = append(adapters, map[string]any{
+			"name":    a.hash(name),
+			"type":    ad.Type,
+			"enabled": ad.Enabled,
+			"live":    ad.Live != nil,
+			"options": len(ad.Options),
+		})
+	}
+	sort.Slice(adapters, func(i, j int) bool { return adapters[i]["name"].(string) < adapters[j]["name"].(string) })
+	return map[string]any{
+		"adapters":    adapters,
+		"maintenance": cfg.Maintenance.Enabled,
+		"power":       cfg.Power.Enabled,
+	}
+}
+
+func listTables(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+func queryRows(ctx context.Context, db *sql.DB, query string) ([]map[string]any, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	out := []map[string]any{}
+	for rows.Next() {
+		values := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]any, len(cols))
+		for i, c := range cols {
+			row[c] = values[i]
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// tail returns the last n lines of the file at path.
+func tail(path string, n int) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
@@ -0,0 +1,123 @@
+package debugbundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestWriteAnonymizes(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	exec := func(query string, args ...any) {
+		t.Helper()
+		if _, err := db.ExecContext(ctx, query, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const secret = "meet Alice at 221B Baker St"
+	exec(`
+		INSERT INTO threads (id, channel, name, source_adapter, source_id, created_at, updated_at)
+		VALUES ('thread-alice', 'imessage', 'Alice Liddell', 'imessage', 'chat-alice', 0, 0)
+	`)
+	exec(`
+		INSERT INTO events (id, timestamp, channel, content_types, content, direction, thread_id, source_adapter, source_id)
+		VALUES ('event-secret', 1700000000, 'imessage', '["text"]', ?, 'received', 'thread-alice', 'imessage', 'msg-1')
+	`, secret)
+	exec(`
+		INSERT INTO sync_jobs (adapter, status, phase, updated_at, last_error)
+		VALUES ('gmail-alice@example.com', 'error', 'sync', 1, 'token for alice@example.com expired; see /home/alice/.config or call +1 (415) 555-0100')
+	`)
+
+	logPath := filepath.Join(t.TempDir(), "sync.log")
+	if err := os.WriteFile(logPath, []byte("first\nsyncing https://mail.google.com/u/alice\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	m, err := Write(ctx, db, &buf, Options{Version: "test", SchemaWant: 1, LogPath: logPath, LogLines: 1})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	files := readZip(t, buf.Bytes())
+	if len(files) != len(m.Files) {
+		t.Errorf("archive has %d files, manifest lists %d", len(files), len(m.Files))
+	}
+
+	var all strings.Builder
+	for _, data := range files {
+		all.Write(data)
+	}
+	for _, leak := range []string{secret, "Alice Liddell", "event-secret", "thread-alice", "alice@example.com", "555-0100", "/home/alice", "mail.google.com"} {
+		if strings.Contains(all.String(), leak) {
+			t.Errorf("bundle leaks %q", leak)
+		}
+	}
+
+	var counts map[string]int64
+	if err := json.Unmarshal(files["row_counts.json"], &counts); err != nil {
+		t.Fatal(err)
+	}
+	if counts["events"] != 1 || counts["threads"] != 1 {
+		t.Errorf("row counts = events %d, threads %d", counts["events"], counts["threads"])
+	}
+
+	// IDs hash consistently, so an event still points at its thread
+	var events, threads []map[string]any
+	json.Unmarshal(files["samples/events.json"], &events)
+	json.Unmarshal(files["samples/threads.json"], &threads)
+	if len(events) != 1 || len(threads) != 1 {
+		t.Fatalf("samples: %d events, %d threads", len(events), len(threads))
+	}
+	if events[0]["thread_id"] != threads[0]["id"] {
+		t.Errorf("thread_id %v != thread id %v", events[0]["thread_id"], threads[0]["id"])
+	}
+	if events[0]["channel"] != "imessage" || events[0]["content"] != "<redacted 27 chars>" {
+		t.Errorf("event sample = %v", events[0])
+	}
+
+	var errors map[string][]map[string]any
+	json.Unmarshal(files["errors.json"], &errors)
+	if jobs := errors["sync_jobs"]; len(jobs) != 1 || !strings.Contains(jobs[0]["error"].(string), "expired") {
+		t.Errorf("sync_jobs errors = %v", jobs)
+	}
+
+	var lines []string
+	json.Unmarshal(files["sync_log_tail.json"], &lines)
+	if len(lines) != 1 || lines[0] != "syncing <url>" {
+		t.Errorf("log tail = %q", lines)
+	}
+}
+
+func readZip(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("read zip: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = b
+	}
+	return files
+}
@@ -0,0 +1,377 @@
+// Package digest builds reports of what the graph learned: the entities
+// created, facts added and invalidated, merges and episodes analyzed since
+// the previous report. It reads the changelog and entity_changes feeds, so
+// every writer is covered. Reports are kept in the digests table, and the
+// digest maintenance task sends each day's to a webhook or by email.
+package digest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/changelog"
+	"github.com/Napageneral/mnemonic/internal/memory"
+	"github.com/google/uuid"
+)
+
+// ItemLimit caps the items listed per section; counts cover everything.
+const ItemLimit = 50
+
+// FirstWindow is how far back the first digest looks.
+const FirstWindow = 24 * time.Hour
+
+// ErrNoDigest is returned when there is no digest to show.
+var ErrNoDigest = errors.New("no digest yet")
+
+// Entity is an entity created in the digest's window.
+type Entity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Fact is a fact added or invalidated in the digest's window.
+type Fact struct {
+	ID           string `json:"id"`
+	Fact         string `json:"fact"`
+	RelationType string `json:"relation_type"`
+	Origin       string `json:"origin,omitempty"`
+	InvalidAt    string `json:"invalid_at,omitempty"`
+}
+
+// Merge is one entity merged into another.
+type Merge struct {
+	TargetID   string `json:"target_id"`
+	TargetName string `json:"target_name"`
+	MergedID   string `json:"merged_id"`
+	MergedName string `json:"merged_name"`
+}
+
+// Digest is one report. Item lists hold at most ItemLimit entries.
+type Digest struct {
+	ID        string `json:"id,omitempty"` // empty until stored
+	CreatedAt int64  `json:"created_at"`
+	Since     int64  `json:"since"` // the previous digest, or FirstWindow before the first
+
+	Entities         []Entity `json:"entities"`
+	EntityCount      int      `json:"entity_count"`
+	Facts            []Fact   `json:"facts"`
+	FactCount        int      `json:"fact_count"`
+	Invalidated      []Fact   `json:"invalidated"`
+	InvalidatedCount int      `json:"invalidated_count"`
+	Merges           []Merge  `json:"merges"`
+	MergeCount       int      `json:"merge_count"`
+	Episodes         int      `json:"episodes"`
+
+	// Feed cursors the digest covers: after From, up to and including To
+	ChangelogFrom     int64 `json:"changelog_from"`
+	ChangelogTo       int64 `json:"changelog_to"`
+	EntityChangesFrom int64 `json:"entity_changes_from"`
+	EntityChangesTo   int64 `json:"entity_changes_to"`
+}
+
+// Empty reports whether nothing changed in the digest's window.
+func (d *Digest) Empty() bool {
+	return d.EntityCount == 0 && d.FactCount == 0 && d.InvalidatedCount == 0 && d.MergeCount == 0 && d.Episodes == 0
+}
+
+// Preview builds the next digest, covering what changed since the previous
+// one, without storing it.
+func Preview(ctx context.Context, db *sql.DB, now time.Time) (*Digest, error) {
+	d := &Digest{CreatedAt: now.Unix()}
+	prev, err := Latest(db)
+	switch {
+	case err == nil:
+		d.Since = prev.CreatedAt
+		d.ChangelogFrom, d.EntityChangesFrom = prev.ChangelogTo, prev.EntityChangesTo
+	case errors.Is(err, ErrNoDigest):
+		since := now.Add(-FirstWindow)
+		d.Since = since.Unix()
+		cutoff := since.UTC().Format("2006-01-02T15:04:05Z")
+		if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(seq), 0) FROM changelog WHERE created_at < ?`, cutoff).Scan(&d.ChangelogFrom); err != nil {
+			return nil, fmt.Errorf("find changelog start: %w", err)
+		}
+		if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(seq), 0) FROM entity_changes WHERE created_at < ?`, cutoff).Scan(&d.EntityChangesFrom); err != nil {
+			return nil, fmt.Errorf("find entity changes start: %w", err)
+		}
+	default:
+		return nil, err
+	}
+
+	if d.ChangelogTo, err = changelog.Head(db); err != nil {
+		return nil, err
+	}
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(seq), 0) FROM entity_changes`).Scan(&d.EntityChangesTo); err != nil {
+		return nil, fmt.Errorf("read entity changes head: %w", err)
+	}
+	if err := d.load(ctx, db); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Create builds the next digest and stores it. Nothing is stored when
+// nothing changed; the digest is still returned, with an empty ID.
+func Create(ctx context.Context, db *sql.DB, now time.Time) (*Digest, error) {
+	d, err := Preview(ctx, db, now)
+	if err != nil {
+		return nil, err
+	}
+	if d.Empty() {
+		return d, nil
+	}
+	d.ID = uuid.New().String()
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO digests (id, created_at, changelog_from, changelog_to, entity_changes_from, entity_changes_to, report_json)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, d.ID, d.CreatedAt, d.ChangelogFrom, d.ChangelogTo, d.EntityChangesFrom, d.EntityChangesTo, string(data)); err != nil {
+		return nil, fmt.Errorf("store digest: %w", err)
+	}
+	return d, nil
+}
+
+// Latest returns the newest stored digest, or ErrNoDigest.
+func Latest(db *sql.DB) (*Digest, error) {
+	return scanDigest(db.QueryRow(`SELECT report_json FROM digests ORDER BY created_at DESC, rowid DESC LIMIT 1`))
+}
+
+// Get returns a stored digest by ID or ID prefix.
+func Get(db *sql.DB, id string) (*Digest, error) {
+	d, err := scanDigest(db.QueryRow(`SELECT report_json FROM digests WHERE id = ? OR id LIKE ? ORDER BY created_at DESC LIMIT 1`, id, id+"%"))
+	if errors.Is(err, ErrNoDigest) {
+		return nil, fmt.Errorf("digest %s not found", id)
+	}
+	return d, err
+}
+
+// List returns the newest stored digests first.
+func List(db *sql.DB, limit int) ([]Digest, error) {
+	if limit <= 0 {
+		limit = 30
+	}
+	rows, err := db.Query(`SELECT report_json FROM digests ORDER BY created_at DESC, rowid DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list digests: %w", err)
+	}
+	defer rows.Close()
+	digests := []Digest{}
+	for rows.Next() {
+		d, err := scanDigest(rows)
+		if err != nil {
+			return nil, err
+		}
+		digests = append(digests, *d)
+	}
+	return digests, rows.Err()
+}
+
+func scanDigest(row interface{ Scan(...any) error }) (*Digest, error) {
+	var data string
+	if err := row.Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoDigest
+		}
+		return nil, fmt.Errorf("read digest: %w", err)
+	}
+	var d Digest
+	if err := json.Unmarshal([]byte(data), &d); err != nil {
+		return nil, fmt.Errorf("decode digest: %w", err)
+	}
+	return &d, nil
+}
+
+// load fills the digest's sections from the feeds between its cursors.
+func (d *Digest) load(ctx context.Context, db *sql.DB) error {
+	d.Entities, d.Facts, d.Invalidated, d.Merges = []Entity{}, []Fact{}, []Fact{}, []Merge{}
+	clFrom, clTo := d.ChangelogFrom, d.ChangelogTo
+
+	// Entities merged away since are left out; their merge is listed instead
+	rows, err := db.QueryContext(ctx, `
+		SELECT e.id, e.canonical_name, e.entity_type_id
+		FROM changelog c
+		JOIN entities e ON e.id = c.subject_id
+		WHERE c.change_type = ? AND c.seq > ? AND c.seq <= ? AND e.merged_into IS NULL
+		ORDER BY c.seq
+	`, changelog.EntityCreated, clFrom, clTo)
+	if err != nil {
+		return fmt.Errorf("query new entities: %w", err)
+	}
+	for rows.Next() {
+		var e Entity
+		var typeID int
+		if err := rows.Scan(&e.ID, &e.Name, &typeID); err != nil {
+			rows.Close()
+			return err
+		}
+		if et := memory.GetEntityTypeByID(typeID); et != nil {
+			e.Type = et.Name
+		}
+		d.EntityCount++
+		if len(d.Entities) < ItemLimit {
+			d.Entities = append(d.Entities, e)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	facts := func(query string, args ...any) ([]Fact, int, error) {
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer rows.Close()
+		list, count := []Fact{}, 0
+		for rows.Next() {
+			var f Fact
+			if err := rows.Scan(&f.ID, &f.Fact, &f.RelationType, &f.Origin, &f.InvalidAt); err != nil {
+				return nil, 0, err
+			}
+			count++
+			if len(list) < ItemLimit {
+				list = append(list, f)
+			}
+		}
+		return list, count, rows.Err()
+	}
+	if d.Facts, d.FactCount, err = facts(`
+		SELECT r.id, r.fact, r.relation_type, COALESCE(r.origin, ''), COALESCE(r.invalid_at, '')
+		FROM changelog c
+		JOIN relationships r ON r.id = c.subject_id
+		WHERE c.change_type = ? AND c.seq > ? AND c.seq <= ?
+		ORDER BY c.seq
+	`, changelog.RelationshipAdded, clFrom, clTo); err != nil {
+		return fmt.Errorf("query new facts: %w", err)
+	}
+	// Both ends of an edge log its invalidation
+	if d.Invalidated, d.InvalidatedCount, err = facts(`
+		SELECT r.id, r.fact, r.relation_type, COALESCE(r.origin, ''), COALESCE(r.invalid_at, '')
+		FROM entity_changes c
+		JOIN relationships r ON r.id = c.ref_id
+		WHERE c.change_type = ? AND c.seq > ? AND c.seq <= ? AND r.invalid_at IS NOT NULL
+		GROUP BY r.id
+		ORDER BY MIN(c.seq)
+	`, memory.ChangeRelationshipInvalidated, d.EntityChangesFrom, d.EntityChangesTo); err != nil {
+		return fmt.Errorf("query invalidated facts: %w", err)
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT c.subject_id, COALESCE(t.canonical_name, ''), COALESCE(c.ref_id, ''), COALESCE(m.canonical_name, '')
+		FROM changelog c
+		LEFT JOIN entities t ON t.id = c.subject_id
+		LEFT JOIN entities m ON m.id = c.ref_id
+		WHERE c.change_type = ? AND c.seq > ? AND c.seq <= ?
+		ORDER BY c.seq
+	`, changelog.MergeExecuted, clFrom, clTo)
+	if err != nil {
+		return fmt.Errorf("query merges: %w", err)
+	}
+	for rows.Next() {
+		var m Merge
+		if err := rows.Scan(&m.TargetID, &m.TargetName, &m.MergedID, &m.MergedName); err != nil {
+			rows.Close()
+			return err
+		}
+		d.MergeCount++
+		if len(d.Merges) < ItemLimit {
+			d.Merges = append(d.Merges, m)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if err := db.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT subject_id) FROM changelog
+		WHERE change_type = ? AND seq > ? AND seq <= ?
+	`, changelog.EpisodeProcessed, clFrom, clTo).Scan(&d.Episodes); err != nil {
+		return fmt.Errorf("count episodes: %w", err)
+	}
+	return nil
+}
+
+// Summary is a one-line account of the digest, e.g. "3 new entities,
+// 5 new facts, 1 invalidated, 0 merges, 12 episodes".
+func (d *Digest) Summary() string {
+	return fmt.Sprintf("%d new entities, %d new facts, %d invalidated, %d merges, %d episodes",
+		d.EntityCount, d.FactCount, d.InvalidatedCount, d.MergeCount, d.Episodes)
+}
+
+// renderLimit caps the items per section in Render.
+const renderLimit = 10
+
+// Render formats the digest as a short plain-text report.
+func Render(d *Digest) string {
+	var b strings.Builder
+	since := time.Unix(d.Since, 0).Format("Mon Jan 2 15:04")
+	if d.Empty() {
+		fmt.Fprintf(&b, "Nothing new since %s.\n", since)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Learned since %s: %s\n", since, d.Summary())
+
+	more := func(shown, total int) {
+		if total > shown {
+			fmt.Fprintf(&b, "  … and %d more\n", total-shown)
+		}
+	}
+	if d.EntityCount > 0 {
+		fmt.Fprintf(&b, "\nNew (%d):\n", d.EntityCount)
+		n := min(len(d.Entities), renderLimit)
+		for _, e := range d.Entities[:n] {
+			if e.Type != "" {
+				fmt.Fprintf(&b, "  • %s (%s)\n", e.Name, e.Type)
+			} else {
+				fmt.Fprintf(&b, "  • %s\n", e.Name)
+			}
+		}
+		more(n, d.EntityCount)
+	}
+	if d.FactCount > 0 {
+		fmt.Fprintf(&b, "\nFacts learned (%d):\n", d.FactCount)
+		n := min(len(d.Facts), renderLimit)
+		for _, f := range d.Facts[:n] {
+			if f.Origin == "inferred" {
+				fmt.Fprintf(&b, "  • %s (inferred)\n", f.Fact)
+			} else {
+				fmt.Fprintf(&b, "  • %s\n", f.Fact)
+			}
+		}
+		more(n, d.FactCount)
+	}
+	if d.InvalidatedCount > 0 {
+		fmt.Fprintf(&b, "\nNo longer true (%d):\n", d.InvalidatedCount)
+		n := min(len(d.Invalidated), renderLimit)
+		for _, f := range d.Invalidated[:n] {
+			fmt.Fprintf(&b, "  • %s (until %s)\n", f.Fact, f.InvalidAt)
+		}
+		more(n, d.InvalidatedCount)
+	}
+	if d.MergeCount > 0 {
+		fmt.Fprintf(&b, "\nMerged (%d):\n", d.MergeCount)
+		n := min(len(d.Merges), renderLimit)
+		for _, m := range d.Merges[:n] {
+			merged := m.MergedName
+			if merged == "" {
+				merged = m.MergedID
+			}
+			fmt.Fprintf(&b, "  • %s into %s\n", merged, m.TargetName)
+		}
+		more(n, d.MergeCount)
+	}
+	if d.Episodes > 0 {
+		fmt.Fprintf(&b, "\nEpisodes analyzed: %d\n", d.Episodes)
+	}
+	return b.String()
+}
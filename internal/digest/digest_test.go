@@ -0,0 +1,116 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/config"
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestCreateDigest(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := Latest(db); !errors.Is(err, ErrNoDigest) {
+		t.Fatalf("Latest on empty = %v", err)
+	}
+	d, err := Create(ctx, db, time.Now())
+	if err != nil || !d.Empty() || d.ID != "" {
+		t.Fatalf("empty digest = %+v, %v", d, err)
+	}
+
+	if _, err := db.Exec(`
+		INSERT INTO entities (id, canonical_name, entity_type_id, origin, created_at, updated_at) VALUES
+			('tyler', 'Tyler', 1, 'extracted', '2024-01-01T00:00:00Z', '2024-01-01T00:00:00Z'),
+			('acme', 'Acme', 2, 'extracted', '2024-01-01T00:00:00Z', '2024-01-01T00:00:00Z'),
+			('acme2', 'ACME Inc', 2, 'extracted', '2024-01-01T00:00:00Z', '2024-01-01T00:00:00Z');
+		INSERT INTO relationships (id, source_entity_id, target_entity_id, relation_type, fact, created_at)
+			VALUES ('r1', 'tyler', 'acme', 'WORKS_AT', 'Tyler works at Acme', '2024-01-01T00:00:00Z');
+		INSERT INTO relationships (id, source_entity_id, target_literal, relation_type, fact, created_at)
+			VALUES ('r2', 'tyler', 'Austin', 'LIVES_IN', 'Tyler lives in Austin', '2024-01-01T00:00:00Z');
+		INSERT INTO entity_merge_events (id, source_entity_id, target_entity_id, merge_type, created_at)
+			VALUES ('m1', 'acme2', 'acme', 'manual', '2024-01-01T00:00:00Z');
+		UPDATE entities SET merged_into = 'acme' WHERE id = 'acme2';
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := Create(ctx, db, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID == "" || first.EntityCount != 2 || first.FactCount != 2 || first.MergeCount != 1 || first.InvalidatedCount != 0 {
+		t.Fatalf("first digest = %+v", first)
+	}
+	if first.Entities[1].Type != "Organization" || first.Merges[0].MergedName != "ACME Inc" || first.Merges[0].TargetName != "Acme" {
+		t.Errorf("first digest items = %+v %+v", first.Entities, first.Merges)
+	}
+	text := Render(first)
+	for _, want := range []string{"2 new entities, 2 new facts", "Tyler (Person)", "Tyler works at Acme", "ACME Inc into Acme"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report lacks %q:\n%s", want, text)
+		}
+	}
+
+	// The next digest only covers what changed after the first
+	if _, err := db.Exec(`UPDATE relationships SET invalid_at = '2026-10' WHERE id = 'r2'`); err != nil {
+		t.Fatal(err)
+	}
+	second, err := Create(ctx, db, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.EntityCount != 0 || second.FactCount != 0 || second.InvalidatedCount != 1 || second.Since != first.CreatedAt {
+		t.Fatalf("second digest = %+v", second)
+	}
+	if !strings.Contains(Render(second), "Tyler lives in Austin (until 2026-10)") {
+		t.Errorf("second report:\n%s", Render(second))
+	}
+
+	latest, err := Latest(db)
+	if err != nil || latest.ID != second.ID {
+		t.Errorf("latest = %+v, %v", latest, err)
+	}
+	if got, err := Get(db, first.ID[:8]); err != nil || got.FactCount != 2 {
+		t.Errorf("get by prefix = %+v, %v", got, err)
+	}
+	if list, err := List(db, 0); err != nil || len(list) != 2 || list[0].ID != second.ID {
+		t.Errorf("list = %+v, %v", list, err)
+	}
+}
+
+func TestSendWebhook(t *testing.T) {
+	var got WebhookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	d := &Digest{CreatedAt: time.Now().Unix(), FactCount: 1, Facts: []Fact{{ID: "r1", Fact: "Tyler works at Acme"}}}
+	if err := Send(context.Background(), d, config.DigestConfig{Webhook: srv.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != "digest" || got.Digest == nil || got.Digest.FactCount != 1 || !strings.Contains(got.Text, "Tyler works at Acme") {
+		t.Errorf("payload = %+v", got)
+	}
+
+	for _, bad := range []config.DigestConfig{
+		{Webhook: "ftp://example.com"},
+		{Email: config.DigestEmailConfig{SMTPServer: "smtp.example.com:587"}},
+		{Email: config.DigestEmailConfig{To: []string{"me@example.com"}, SMTPServer: "smtp.example.com"}},
+	} {
+		if err := Validate(bad); err == nil {
+			t.Errorf("%+v: no error", bad)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/config"
+)
+
+// DefaultPasswordEnv holds the SMTP password when DigestEmailConfig
+// doesn't name another variable.
+const DefaultPasswordEnv = "MNEMONIC_SMTP_PASSWORD"
+
+// webhookClient posts digests; a slow receiver must not stall maintenance.
+var webhookClient = &http.Client{Timeout: 30 * time.Second}
+
+// Validate checks a digest delivery config.
+func Validate(cfg config.DigestConfig) error {
+	if cfg.Webhook != "" && !strings.HasPrefix(cfg.Webhook, "http://") && !strings.HasPrefix(cfg.Webhook, "https://") {
+		return fmt.Errorf("digest webhook must be an http(s) URL")
+	}
+	email := cfg.Email
+	if len(email.To) == 0 && email.SMTPServer == "" {
+		return nil
+	}
+	if len(email.To) == 0 {
+		return fmt.Errorf("digest email needs at least one 'to' address")
+	}
+	if _, _, err := net.SplitHostPort(email.SMTPServer); err != nil {
+		return fmt.Errorf("digest email 'smtp_server' must be host:port: %q", email.SMTPServer)
+	}
+	return nil
+}
+
+// Send delivers a digest to the configured webhook and email recipients.
+// Empty digests are not sent. Both deliveries are attempted; the errors
+// are joined.
+func Send(ctx context.Context, d *Digest, cfg config.DigestConfig) error {
+	if d.Empty() {
+		return nil
+	}
+	var errs []error
+	if cfg.Webhook != "" {
+		if err := postWebhook(ctx, d, cfg.Webhook); err != nil {
+			errs = append(errs, fmt.Errorf("digest webhook: %w", err))
+		}
+	}
+	if len(cfg.Email.To) > 0 {
+		if err := sendEmail(d, cfg.Email); err != nil {
+			errs = append(errs, fmt.Errorf("digest email: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WebhookPayload is the JSON body POSTed to the webhook.
+type WebhookPayload struct {
+	Type   string  `json:"type"` // always "digest"
+	Text   string  `json:"text"` // Render's report, for chat webhooks
+	Digest *Digest `json:"digest"`
+}
+
+func postWebhook(ctx context.Context, d *Digest, url string) error {
+	body, err := json.Marshal(WebhookPayload{Type: "digest", Text: Render(d), Digest: d})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func sendEmail(d *Digest, cfg config.DigestEmailConfig) error {
+	from := cfg.From
+	if from == "" {
+		from = cfg.To[0]
+	}
+	host, _, err := net.SplitHostPort(cfg.SMTPServer)
+	if err != nil {
+		return fmt.Errorf("smtp_server must be host:port: %w", err)
+	}
+	var auth smtp.Auth
+	passwordEnv := cfg.PasswordEnv
+	if passwordEnv == "" {
+		passwordEnv = DefaultPasswordEnv
+	}
+	if password := os.Getenv(passwordEnv); password != "" {
+		username := cfg.Username
+		if username == "" {
+			username = from
+		}
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return smtp.SendMail(cfg.SMTPServer, auth, from, cfg.To, emailMessage(d, from, cfg.To))
+}
+
+// emailMessage formats a digest as a plain-text email.
+func emailMessage(d *Digest, from string, to []string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: Digest for %s: %s\r\n", time.Unix(d.CreatedAt, 0).Format("Mon Jan 2"), d.Summary())
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Unix(d.CreatedAt, 0).Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(Render(d), "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,219 @@
+// Package drafts records things the user plans to say or send — drafts and
+// talking points — as events on their own channel. Drafts show up next to
+// real messages in context views, but always labeled as not sent, so they
+// are never read as something the recipient was told.
+package drafts
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/me"
+	"github.com/google/uuid"
+)
+
+// Drafts are events with this channel, direction and source adapter.
+const (
+	Channel       = "draft"
+	Direction     = "draft"
+	SourceAdapter = "drafts"
+)
+
+// ErrNotFound is returned for an unknown draft ID.
+var ErrNotFound = errors.New("draft not found")
+
+// Label marks drafts wherever they are shown alongside real messages.
+const Label = "draft, not sent"
+
+// Kinds of draft.
+const (
+	KindDraft        = "draft"         // a message to send
+	KindTalkingPoint = "talking_point" // something to bring up
+)
+
+// Statuses of a draft.
+const (
+	StatusPending   = "pending"
+	StatusSent      = "sent"
+	StatusDiscarded = "discarded"
+)
+
+// Draft is an unsent message or talking point.
+type Draft struct {
+	ID        string    `json:"id"`
+	Content   string    `json:"content"`
+	Kind      string    `json:"kind"`
+	Status    string    `json:"status"`
+	PersonID  string    `json:"person_id,omitempty"` // intended recipient
+	Recipient string    `json:"recipient,omitempty"`
+	ThreadID  string    `json:"thread_id,omitempty"` // conversation it is meant for
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type metadata struct {
+	Kind     string `json:"kind"`
+	Status   string `json:"status"`
+	PersonID string `json:"person_id,omitempty"`
+}
+
+// Add records a draft. The recipient's primary contact is added as the
+// event's recipient and the user's as its sender, so drafts appear in
+// per-person views.
+func Add(db *sql.DB, d Draft) (*Draft, error) {
+	d.Content = strings.TrimSpace(d.Content)
+	if d.Content == "" {
+		return nil, fmt.Errorf("draft content is empty")
+	}
+	if d.Kind == "" {
+		d.Kind = KindDraft
+	}
+	if d.Kind != KindDraft && d.Kind != KindTalkingPoint {
+		return nil, fmt.Errorf("unknown draft kind %q (use %s or %s)", d.Kind, KindDraft, KindTalkingPoint)
+	}
+	d.Status = StatusPending
+	d.ID = uuid.New().String()
+	d.CreatedAt = time.Now()
+
+	meta, err := json.Marshal(metadata{Kind: d.Kind, Status: d.Status, PersonID: d.PersonID})
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO events (id, timestamp, channel, content_types, content, direction, thread_id, source_adapter, source_id, metadata_json)
+		VALUES (?, ?, ?, '["text"]', ?, ?, ?, ?, ?, ?)
+	`, d.ID, d.CreatedAt.Unix(), Channel, d.Content, Direction, nullIfEmpty(d.ThreadID), SourceAdapter, d.ID, string(meta)); err != nil {
+		return nil, fmt.Errorf("failed to insert draft: %w", err)
+	}
+
+	meID, _ := me.LookupMePersonID(tx)
+	if err := addParticipant(tx, d.ID, meID, "sender"); err != nil {
+		return nil, err
+	}
+	if err := addParticipant(tx, d.ID, d.PersonID, "recipient"); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return &d, nil
+}
+
+// addParticipant adds a person's primary contact to an event.
+func addParticipant(tx *sql.Tx, eventID, personID, role string) error {
+	if personID == "" {
+		return nil
+	}
+	if _, err := tx.Exec(`
+		INSERT OR IGNORE INTO event_participants (event_id, contact_id, role)
+		SELECT ?, contact_id, ? FROM person_contact_links
+		WHERE person_id = ?
+		ORDER BY confidence DESC, last_seen_at DESC
+		LIMIT 1
+	`, eventID, role, personID); err != nil {
+		return fmt.Errorf("failed to add draft %s: %w", role, err)
+	}
+	return nil
+}
+
+// ListOptions filters List. Zero values mean no filter.
+type ListOptions struct {
+	PersonID string
+	Status   string // default pending; "all" for every status
+	Limit    int
+}
+
+// List returns drafts, newest first.
+func List(db *sql.DB, opts ListOptions) ([]Draft, error) {
+	query := `
+		SELECT e.id, COALESCE(e.content, ''), COALESCE(e.thread_id, ''), e.timestamp, COALESCE(e.metadata_json, '{}'),
+		       COALESCE((SELECT canonical_name FROM persons WHERE id = json_extract(e.metadata_json, '$.person_id')), '')
+		FROM events e
+		WHERE e.channel = ? AND e.source_adapter = ?
+	`
+	args := []interface{}{Channel, SourceAdapter}
+	status := opts.Status
+	if status == "" {
+		status = StatusPending
+	}
+	if status != "all" {
+		query += ` AND json_extract(e.metadata_json, '$.status') = ?`
+		args = append(args, status)
+	}
+	if opts.PersonID != "" {
+		query += ` AND json_extract(e.metadata_json, '$.person_id') = ?`
+		args = append(args, opts.PersonID)
+	}
+	query += ` ORDER BY e.timestamp DESC, e.id`
+	if opts.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, opts.Limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query drafts: %w", err)
+	}
+	defer rows.Close()
+
+	var drafts []Draft
+	for rows.Next() {
+		var d Draft
+		var ts int64
+		var metaJSON string
+		if err := rows.Scan(&d.ID, &d.Content, &d.ThreadID, &ts, &metaJSON, &d.Recipient); err != nil {
+			return nil, fmt.Errorf("failed to scan draft: %w", err)
+		}
+		var meta metadata
+		_ = json.Unmarshal([]byte(metaJSON), &meta)
+		d.Kind, d.Status, d.PersonID = meta.Kind, meta.Status, meta.PersonID
+		d.CreatedAt = time.Unix(ts, 0)
+		drafts = append(drafts, d)
+	}
+	return drafts, rows.Err()
+}
+
+// SetStatus marks a draft sent or discarded (or pending again). Marking a
+// draft sent does not make it a message: the real message arrives through
+// its channel's adapter.
+func SetStatus(db *sql.DB, id, status string) error {
+	switch status {
+	case StatusPending, StatusSent, StatusDiscarded:
+	default:
+		return fmt.Errorf("unknown draft status %q (use %s, %s or %s)", status, StatusPending, StatusSent, StatusDiscarded)
+	}
+	res, err := db.Exec(`
+		UPDATE events SET metadata_json = json_set(COALESCE(metadata_json, '{}'), '$.status', ?)
+		WHERE id = ? AND channel = ? AND source_adapter = ?
+	`, status, id, Channel, SourceAdapter)
+	if err != nil {
+		return fmt.Errorf("failed to update draft: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return nil
+}
+
+// IsDraft reports whether an event with this direction is a draft.
+func IsDraft(direction string) bool {
+	return direction == Direction
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,76 @@
+package drafts
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestDrafts(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec(`
+		INSERT INTO persons (id, canonical_name, is_me, created_at, updated_at) VALUES ('me', 'Tyler', 1, 0, 0), ('p1', 'Casey Lee', 0, 0, 0);
+		INSERT INTO contacts (id, display_name, created_at, updated_at) VALUES ('c-me', 'Tyler', 0, 0), ('c1', 'Casey', 0, 0);
+		INSERT INTO person_contact_links (person_id, contact_id, confidence, source_type, first_seen_at, last_seen_at)
+		VALUES ('me', 'c-me', 1.0, 'test', 0, 0), ('p1', 'c1', 1.0, 'test', 0, 0);
+	`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	if _, err := Add(db, Draft{Content: "  "}); err == nil {
+		t.Fatal("empty draft accepted")
+	}
+	d, err := Add(db, Draft{Content: "ask about the Denver offer", Kind: KindTalkingPoint, PersonID: "p1"})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	var channel, direction string
+	if err := db.QueryRow(`SELECT channel, direction FROM events WHERE id = ?`, d.ID).Scan(&channel, &direction); err != nil {
+		t.Fatalf("query event: %v", err)
+	}
+	if channel != Channel || !IsDraft(direction) {
+		t.Errorf("event channel/direction = %s/%s", channel, direction)
+	}
+	roles := map[string]string{}
+	rows, err := db.Query(`SELECT contact_id, role FROM event_participants WHERE event_id = ?`, d.ID)
+	if err != nil {
+		t.Fatalf("query participants: %v", err)
+	}
+	for rows.Next() {
+		var contactID, role string
+		rows.Scan(&contactID, &role)
+		roles[role] = contactID
+	}
+	rows.Close()
+	if roles["sender"] != "c-me" || roles["recipient"] != "c1" {
+		t.Errorf("participants = %v", roles)
+	}
+
+	list, err := List(db, ListOptions{PersonID: "p1"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 1 || list[0].Recipient != "Casey Lee" || list[0].Kind != KindTalkingPoint || list[0].Status != StatusPending {
+		t.Fatalf("List = %+v", list)
+	}
+
+	if err := SetStatus(db, d.ID, StatusSent); err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+	if list, _ := List(db, ListOptions{}); len(list) != 0 {
+		t.Errorf("pending drafts after send = %d", len(list))
+	}
+	if list, _ := List(db, ListOptions{Status: "all"}); len(list) != 1 || list[0].Status != StatusSent {
+		t.Errorf("all drafts = %+v", list)
+	}
+	if err := SetStatus(db, "missing", StatusSent); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetStatus(missing) = %v, want ErrNotFound", err)
+	}
+	if err := SetStatus(db, d.ID, "maybe"); err == nil {
+		t.Error("unknown status accepted")
+	}
+}
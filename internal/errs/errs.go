@@ -0,0 +1,94 @@
+// Package errs defines the failure classes the CLI reports distinctly, so
+// wrappers and scripts can branch on the kind of failure rather than parse
+// messages. Wrap a sentinel with fmt.Errorf("...: %w", ErrX) where the
+// failure is detected; Classify recovers the class anywhere up the stack.
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinel errors for failure classes with their own exit codes.
+var (
+	ErrNoAPIKey             = errors.New("GEMINI_API_KEY environment variable not set")
+	ErrDBLocked             = errors.New("database is locked by another process")
+	ErrAdapterSourceMissing = errors.New("adapter source not found")
+	ErrSchemaOutdated       = errors.New("this build is older than the database schema")
+	ErrNotInitialized       = errors.New("mnemonic is not initialized")
+)
+
+// New returns an error with the formatted message, classified as kind. The
+// message reads as if kind were not there; a %w in format still wraps.
+func New(kind error, format string, args ...any) error {
+	return &classified{kind: kind, err: fmt.Errorf(format, args...)}
+}
+
+type classified struct {
+	kind error
+	err  error
+}
+
+func (e *classified) Error() string   { return e.err.Error() }
+func (e *classified) Unwrap() []error { return []error{e.kind, e.err} }
+
+// Exit codes. 1 covers every failure without a class of its own.
+const (
+	ExitError                = 1
+	ExitUsage                = 2
+	ExitNoAPIKey             = 3
+	ExitDBLocked             = 4
+	ExitAdapterSourceMissing = 5
+	ExitSchemaOutdated       = 6
+	ExitNotInitialized       = 7
+)
+
+// Info is the machine-readable form of an error, as printed in --json mode.
+type Info struct {
+	Code     string `json:"code"`
+	ExitCode int    `json:"exit_code"`
+	Message  string `json:"message"`
+	Hint     string `json:"hint,omitempty"`
+}
+
+var classes = []struct {
+	err  error
+	code string
+	exit int
+	hint string
+}{
+	{ErrNoAPIKey, "no_api_key", ExitNoAPIKey, "export GEMINI_API_KEY=<key>"},
+	{ErrDBLocked, "db_locked", ExitDBLocked, "wait for the other mnemonic process to finish; if it is gone, rerun with --force"},
+	{ErrAdapterSourceMissing, "adapter_source_missing", ExitAdapterSourceMissing, "install or sync the adapter's source, then retry"},
+	{ErrSchemaOutdated, "schema_outdated", ExitSchemaOutdated, "upgrade mnemonic to the version that wrote the database"},
+	{ErrNotInitialized, "not_initialized", ExitNotInitialized, "run: mnemonic init"},
+}
+
+// ExitCodeFor returns the exit code for an Info.Code, ExitError if unknown.
+func ExitCodeFor(code string) int {
+	for _, c := range classes {
+		if c.code == code {
+			return c.exit
+		}
+	}
+	return ExitError
+}
+
+// Classify returns the class of err. SQLite busy/locked errors count as
+// ErrDBLocked even when not wrapped.
+func Classify(err error) Info {
+	if err == nil {
+		return Info{}
+	}
+	for _, c := range classes {
+		if errors.Is(err, c.err) {
+			return Info{Code: c.code, ExitCode: c.exit, Message: err.Error(), Hint: c.hint}
+		}
+	}
+	if msg := err.Error(); strings.Contains(msg, "database is locked") || strings.Contains(msg, "SQLITE_BUSY") {
+		c := classes[1]
+		return Info{Code: c.code, ExitCode: c.exit, Message: msg, Hint: c.hint}
+	}
+	return Info{Code: "error", ExitCode: ExitError, Message: err.Error()}
+}
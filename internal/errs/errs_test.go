@@ -0,0 +1,32 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	cause := errors.New("stat /x/eve.db: no such file")
+	err := fmt.Errorf("create adapter: %w", New(ErrAdapterSourceMissing, "Eve database not found: %w", cause))
+	info := Classify(err)
+	if info.Code != "adapter_source_missing" || info.ExitCode != ExitAdapterSourceMissing || info.Hint == "" {
+		t.Errorf("Classify = %+v", info)
+	}
+	if err.Error() != "create adapter: Eve database not found: stat /x/eve.db: no such file" || !errors.Is(err, cause) {
+		t.Errorf("message = %q; wrapped cause lost", err.Error())
+	}
+
+	if info := Classify(errors.New("exec: database is locked (5) (SQLITE_BUSY)")); info.Code != "db_locked" || info.ExitCode != ExitDBLocked {
+		t.Errorf("sqlite busy = %+v", info)
+	}
+	if info := Classify(errors.New("boom")); info.Code != "error" || info.ExitCode != ExitError {
+		t.Errorf("plain error = %+v", info)
+	}
+	if info := Classify(fmt.Errorf("open: %w", ErrNotInitialized)); info.Code != "not_initialized" || info.ExitCode != ExitNotInitialized {
+		t.Errorf("not initialized = %+v", info)
+	}
+	if ExitCodeFor("schema_outdated") != ExitSchemaOutdated || ExitCodeFor("") != ExitError {
+		t.Error("ExitCodeFor mismatch")
+	}
+}
@@ -3,17 +3,22 @@ package gemini
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"math"
 	"math/rand"
+	"net"
 	"net/http"
 	"os/exec"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Napageneral/mnemonic/internal/errs"
 	"github.com/Napageneral/mnemonic/internal/ratelimit"
 )
 
@@ -22,53 +27,75 @@ const (
 	maxRetries          = 5
 	initialBackoff      = 500 * time.Millisecond
 	maxBackoff          = 30 * time.Second
-	defaultTimeout      = 120 * time.Second
-	maxIdleConns        = 1000
-	maxConnsPerHost     = 1000
+	defaultTimeout      = 120 * time.Second // per attempt; see SetRequestTimeout
+	maxIdleConns        = 256
+	maxIdleConnsPerHost = 128 // every request goes to one host
 	idleConnTimeout     = 90 * time.Second
 	tlsHandshakeTimeout = 10 * time.Second
+	dialTimeout         = 10 * time.Second
+	dialKeepAlive       = 30 * time.Second
 )
 
-// Client is a Gemini API client with HTTP/2 support and retries
+// sharedTransport pools connections for every Client in the process, so
+// commands that create several clients (per API key, pipeline and compute
+// engine) reuse the same HTTP/2 connections. Connections per host are not
+// capped; concurrency is bounded by the rate limiters and worker counts.
+var sharedTransport = &http.Transport{
+	Proxy:                 http.ProxyFromEnvironment,
+	DialContext:           (&net.Dialer{Timeout: dialTimeout, KeepAlive: dialKeepAlive}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          maxIdleConns,
+	MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+	IdleConnTimeout:       idleConnTimeout,
+	TLSHandshakeTimeout:   tlsHandshakeTimeout,
+	ExpectContinueTimeout: time.Second,
+}
+
+// Client is a Gemini API client with HTTP/2 support and retries. It is safe
+// for concurrent use; pipeline workers should share one client.
 type Client struct {
-	httpClient      *http.Client
-	apiKey          string
+	httpClient     *http.Client
+	baseURL        string
+	apiKey         string
+	requestTimeout time.Duration
+	useADC         bool
+	accessToken    string
+	tokenExpiry    time.Time
+	tokenMu        sync.Mutex
+
+	limiterMu       sync.RWMutex
 	analysisLimiter *ratelimit.LeakyBucket
 	embedLimiter    *ratelimit.LeakyBucket
-	useADC          bool
-	accessToken     string
-	tokenExpiry     time.Time
-	tokenMu         sync.Mutex
 
-	// Usage tracking
-	usageMu            sync.Mutex
-	totalPromptTokens  int64
-	totalOutputTokens  int64
-	totalEmbedChars    int64
-	generateCalls      int64
-	embedCalls         int64
+	// Usage tracking; atomic so concurrent workers never contend on it
+	promptTokens  atomic.Int64
+	outputTokens  atomic.Int64
+	embedChars    atomic.Int64
+	generateCalls atomic.Int64
+	embedCalls    atomic.Int64
+	usageSink     atomic.Pointer[func(UsageRecord)]
 }
 
-// NewClient creates a new Gemini client with HTTP/2 pooling and retries
+// NewClient creates a new Gemini client with pooled HTTP/2 connections and retries
 // If apiKey is empty, uses Application Default Credentials (gcloud auth)
 func NewClient(apiKey string) *Client {
-	transport := &http.Transport{
-		MaxIdleConns:        maxIdleConns,
-		MaxIdleConnsPerHost: maxConnsPerHost,
-		MaxConnsPerHost:     maxConnsPerHost,
-		IdleConnTimeout:     idleConnTimeout,
-		TLSHandshakeTimeout: tlsHandshakeTimeout,
-		ForceAttemptHTTP2:   true, // Enable HTTP/2
+	return &Client{
+		httpClient:     &http.Client{Transport: sharedTransport},
+		baseURL:        baseURL,
+		apiKey:         apiKey,
+		requestTimeout: defaultTimeout,
+		useADC:         apiKey == "",
 	}
+}
 
-	return &Client{
-		httpClient: &http.Client{
-			Transport: transport,
-			Timeout:   defaultTimeout,
-		},
-		apiKey: apiKey,
-		useADC: apiKey == "",
+// SetRequestTimeout bounds each attempt of a request (default 2 minutes);
+// retries get a fresh timeout. The caller's context still bounds the whole
+// call. d<=0 restores the default.
+func (c *Client) SetRequestTimeout(d time.Duration) {
+	if d <= 0 {
+		d = defaultTimeout
 	}
+	c.requestTimeout = d
 }
 
 // SetAnalysisRPM sets a smooth rate limit for GenerateContent requests.
@@ -77,6 +104,8 @@ func (c *Client) SetAnalysisRPM(rpm int) {
 	if c == nil {
 		return
 	}
+	c.limiterMu.Lock()
+	defer c.limiterMu.Unlock()
 	if rpm <= 0 {
 		if c.analysisLimiter != nil {
 			c.analysisLimiter.Close()
@@ -97,6 +126,8 @@ func (c *Client) SetEmbedRPM(rpm int) {
 	if c == nil {
 		return
 	}
+	c.limiterMu.Lock()
+	defer c.limiterMu.Unlock()
 	if rpm <= 0 {
 		if c.embedLimiter != nil {
 			c.embedLimiter.Close()
@@ -111,6 +142,16 @@ func (c *Client) SetEmbedRPM(rpm int) {
 	c.embedLimiter.SetRPM(rpm)
 }
 
+// limiter returns the current analysis or embedding rate limiter (nil if unlimited).
+func (c *Client) limiter(embed bool) *ratelimit.LeakyBucket {
+	c.limiterMu.RLock()
+	defer c.limiterMu.RUnlock()
+	if embed {
+		return c.embedLimiter
+	}
+	return c.analysisLimiter
+}
+
 func (c *Client) getAccessToken() (string, error) {
 	c.tokenMu.Lock()
 	defer c.tokenMu.Unlock()
@@ -122,7 +163,7 @@ func (c *Client) getAccessToken() (string, error) {
 	cmd := exec.Command("gcloud", "auth", "application-default", "print-access-token")
 	output, err := cmd.Output()
 	if err != nil {
-		return "", fmt.Errorf("gcloud auth failed: %w (run 'gcloud auth application-default login')", err)
+		return "", errs.New(errs.ErrNoAPIKey, "GEMINI_API_KEY not set and gcloud auth failed: %w (run 'gcloud auth application-default login')", err)
 	}
 
 	c.accessToken = strings.TrimSpace(string(output))
@@ -133,9 +174,9 @@ func (c *Client) getAccessToken() (string, error) {
 func (c *Client) buildRequest(ctx context.Context, method, endpoint string, body []byte) (*http.Request, error) {
 	var url string
 	if c.useADC {
-		url = fmt.Sprintf("%s/%s", baseURL, endpoint)
+		url = fmt.Sprintf("%s/%s", c.baseURL, endpoint)
 	} else {
-		url = fmt.Sprintf("%s/%s?key=%s", baseURL, endpoint, c.apiKey)
+		url = fmt.Sprintf("%s/%s?key=%s", c.baseURL, endpoint, c.apiKey)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
@@ -164,6 +205,9 @@ type GenerateContentRequest struct {
 }
 
 type GenerationConfig struct {
+	Temperature        *float64        `json:"temperature,omitempty"`
+	TopP               *float64        `json:"topP,omitempty"`
+	MaxOutputTokens    int             `json:"maxOutputTokens,omitempty"`
 	ThinkingConfig     *ThinkingConfig `json:"thinkingConfig,omitempty"`
 	ResponseMimeType   string          `json:"responseMimeType,omitempty"`
 	ResponseSchema     any             `json:"responseSchema,omitempty"`
@@ -264,62 +308,25 @@ func (c *Client) GenerateContent(ctx context.Context, model string, req *Generat
 
 	endpoint := fmt.Sprintf("models/%s:generateContent", model)
 
-	var lastErr error
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		// Wait for rate limiter if configured
-		if c.analysisLimiter != nil {
-			if err := c.analysisLimiter.Wait(ctx); err != nil {
-				return nil, err
-			}
-		}
-		if attempt > 0 {
-			backoff := calculateBackoff(attempt)
-			time.Sleep(backoff)
-		}
-
-		httpReq, err := c.buildRequest(ctx, "POST", endpoint, body)
-		if err != nil {
-			return nil, err
-		}
-
-		resp, err := c.httpClient.Do(httpReq)
-		if err != nil {
-			lastErr = err
-			continue
-		}
-
-		respBody, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			lastErr = err
-			continue
-		}
-
-		if isRetryableStatus(resp.StatusCode) {
-			lastErr = fmt.Errorf("status %d", resp.StatusCode)
-			continue
-		}
-
-		var result GenerateContentResponse
+	var result GenerateContentResponse
+	err = c.post(ctx, false, endpoint, body, func(respBody []byte) (bool, error) {
+		result = GenerateContentResponse{}
 		if err := json.Unmarshal(respBody, &result); err != nil {
-			return nil, fmt.Errorf("unmarshal response: %w", err)
+			return false, fmt.Errorf("unmarshal response: %w", err)
 		}
-
 		if result.Error != nil {
-			if isRetryableStatus(result.Error.Code) {
-				lastErr = result.Error
-				continue
-			}
-			return nil, result.Error
+			return isRetryableStatus(result.Error.Code), result.Error
 		}
-
-		// Record usage for cost tracking
-		c.recordGenerateUsage(result.UsageMetadata)
-
-		return &result, nil
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("max retries exceeded: %w", lastErr)
+	// Record usage for cost tracking
+	c.recordGenerateUsage(result.UsageMetadata)
+
+	return &result, nil
 }
 
 // EmbedContent calls the Gemini embedContent API
@@ -337,62 +344,25 @@ func (c *Client) EmbedContent(ctx context.Context, req *EmbedContentRequest) (*E
 
 	endpoint := fmt.Sprintf("models/%s:embedContent", req.Model)
 
-	var lastErr error
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		// Wait for rate limiter if configured
-		if c.embedLimiter != nil {
-			if err := c.embedLimiter.Wait(ctx); err != nil {
-				return nil, err
-			}
-		}
-		if attempt > 0 {
-			backoff := calculateBackoff(attempt)
-			time.Sleep(backoff)
-		}
-
-		httpReq, err := c.buildRequest(ctx, "POST", endpoint, body)
-		if err != nil {
-			return nil, err
-		}
-
-		resp, err := c.httpClient.Do(httpReq)
-		if err != nil {
-			lastErr = err
-			continue
-		}
-
-		respBody, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			lastErr = err
-			continue
-		}
-
-		if isRetryableStatus(resp.StatusCode) {
-			lastErr = fmt.Errorf("status %d", resp.StatusCode)
-			continue
-		}
-
-		var result EmbedContentResponse
+	var result EmbedContentResponse
+	err = c.post(ctx, true, endpoint, body, func(respBody []byte) (bool, error) {
+		result = EmbedContentResponse{}
 		if err := json.Unmarshal(respBody, &result); err != nil {
-			return nil, fmt.Errorf("unmarshal response: %w", err)
+			return false, fmt.Errorf("unmarshal response: %w", err)
 		}
-
 		if result.Error != nil {
-			if isRetryableStatus(result.Error.Code) {
-				lastErr = result.Error
-				continue
-			}
-			return nil, result.Error
+			return isRetryableStatus(result.Error.Code), result.Error
 		}
-
-		// Record usage for cost tracking
-		c.recordEmbedUsage(charCount)
-
-		return &result, nil
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("max retries exceeded: %w", lastErr)
+	// Record usage for cost tracking
+	c.recordEmbedUsage(charCount)
+
+	return &result, nil
 }
 
 // BatchEmbedContents calls the Gemini batchEmbedContents API for batch embeddings
@@ -422,62 +392,94 @@ func (c *Client) BatchEmbedContents(ctx context.Context, model string, requests
 
 	endpoint := fmt.Sprintf("models/%s:batchEmbedContents", model)
 
+	var result BatchEmbedContentsResponse
+	err = c.post(ctx, true, endpoint, body, func(respBody []byte) (bool, error) {
+		result = BatchEmbedContentsResponse{}
+		if err := json.Unmarshal(respBody, &result); err != nil {
+			return false, fmt.Errorf("unmarshal response: %w", err)
+		}
+		if result.Error != nil {
+			return isRetryableStatus(result.Error.Code), result.Error
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Record usage for cost tracking
+	c.recordEmbedUsage(totalCharCount)
+
+	return &result, nil
+}
+
+// post sends a request with rate limiting and retries. Each attempt has its
+// own timeout (SetRequestTimeout); transport errors, 429s and 5xx are
+// retried with backoff. decode parses a response body and reports whether
+// an error in it is retryable.
+func (c *Client) post(ctx context.Context, embed bool, endpoint string, body []byte, decode func([]byte) (retry bool, err error)) error {
 	var lastErr error
 	for attempt := 0; attempt <= maxRetries; attempt++ {
 		// Wait for rate limiter if configured
-		if c.embedLimiter != nil {
-			if err := c.embedLimiter.Wait(ctx); err != nil {
-				return nil, err
+		if limiter := c.limiter(embed); limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				return err
 			}
 		}
 		if attempt > 0 {
-			backoff := calculateBackoff(attempt)
-			time.Sleep(backoff)
-		}
-
-		httpReq, err := c.buildRequest(ctx, "POST", endpoint, body)
-		if err != nil {
-			return nil, err
+			select {
+			case <-time.After(calculateBackoff(attempt)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 		}
 
-		resp, err := c.httpClient.Do(httpReq)
+		respBody, status, err := c.attempt(ctx, endpoint, body)
 		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err() // Cancelled by the caller - don't retry
+			}
 			lastErr = err
 			continue
 		}
-
-		respBody, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			lastErr = err
+		if isRetryableStatus(status) {
+			lastErr = fmt.Errorf("status %d", status)
 			continue
 		}
 
-		if isRetryableStatus(resp.StatusCode) {
-			lastErr = fmt.Errorf("status %d", resp.StatusCode)
-			continue
+		retry, err := decode(respBody)
+		if err == nil {
+			return nil
 		}
-
-		var result BatchEmbedContentsResponse
-		if err := json.Unmarshal(respBody, &result); err != nil {
-			return nil, fmt.Errorf("unmarshal response: %w", err)
+		if !retry {
+			return err
 		}
+		lastErr = err
+	}
 
-		if result.Error != nil {
-			if isRetryableStatus(result.Error.Code) {
-				lastErr = result.Error
-				continue
-			}
-			return nil, result.Error
-		}
+	return fmt.Errorf("max retries exceeded: %w", lastErr)
+}
 
-		// Record usage for cost tracking
-		c.recordEmbedUsage(totalCharCount)
+// attempt makes one HTTP request bounded by the per-request timeout.
+func (c *Client) attempt(ctx context.Context, endpoint string, body []byte) ([]byte, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.requestTimeout)
+	defer cancel()
 
-		return &result, nil
+	httpReq, err := c.buildRequest(ctx, "POST", endpoint, body)
+	if err != nil {
+		return nil, 0, err
 	}
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
 
-	return nil, fmt.Errorf("max retries exceeded: %w", lastErr)
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return respBody, resp.StatusCode, nil
 }
 
 func isRetryableStatus(code int) bool {
@@ -495,11 +497,11 @@ func calculateBackoff(attempt int) time.Duration {
 
 // UsageStats contains accumulated usage statistics
 type UsageStats struct {
-	PromptTokens    int64   `json:"prompt_tokens"`
-	OutputTokens    int64   `json:"output_tokens"`
-	EmbedChars      int64   `json:"embed_chars"`
-	GenerateCalls   int64   `json:"generate_calls"`
-	EmbedCalls      int64   `json:"embed_calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	OutputTokens     int64   `json:"output_tokens"`
+	EmbedChars       int64   `json:"embed_chars"`
+	GenerateCalls    int64   `json:"generate_calls"`
+	EmbedCalls       int64   `json:"embed_calls"`
 	EstimatedCostUSD float64 `json:"estimated_cost_usd"`
 }
 
@@ -508,52 +510,88 @@ type UsageStats struct {
 //   - Input: $0.075 per 1M tokens
 //   - Output: $0.30 per 1M tokens
 //   - Embeddings: $0.00001 per 1K characters
+//
+// It is safe to call while other goroutines are making requests; counters
+// are read individually, so a call in flight may be partly reflected.
 func (c *Client) GetUsageStats() UsageStats {
-	c.usageMu.Lock()
-	defer c.usageMu.Unlock()
-
 	stats := UsageStats{
-		PromptTokens:  c.totalPromptTokens,
-		OutputTokens:  c.totalOutputTokens,
-		EmbedChars:    c.totalEmbedChars,
-		GenerateCalls: c.generateCalls,
-		EmbedCalls:    c.embedCalls,
+		PromptTokens:  c.promptTokens.Load(),
+		OutputTokens:  c.outputTokens.Load(),
+		EmbedChars:    c.embedChars.Load(),
+		GenerateCalls: c.generateCalls.Load(),
+		EmbedCalls:    c.embedCalls.Load(),
 	}
 
 	// Calculate cost
-	inputCost := float64(c.totalPromptTokens) * 0.075 / 1_000_000
-	outputCost := float64(c.totalOutputTokens) * 0.30 / 1_000_000
-	embedCost := float64(c.totalEmbedChars) * 0.00001 / 1_000
-	stats.EstimatedCostUSD = inputCost + outputCost + embedCost
+	stats.EstimatedCostUSD = EstimateCost(stats.PromptTokens, stats.OutputTokens, stats.EmbedChars)
 
 	return stats
 }
 
+// EstimateCost returns the estimated USD cost of the given usage, using the
+// same pricing as GetUsageStats.
+func EstimateCost(promptTokens, outputTokens, embedChars int64) float64 {
+	inputCost := float64(promptTokens) * 0.075 / 1_000_000
+	outputCost := float64(outputTokens) * 0.30 / 1_000_000
+	embedCost := float64(embedChars) * 0.00001 / 1_000
+	return inputCost + outputCost + embedCost
+}
+
 // ResetUsageStats clears accumulated usage statistics
 func (c *Client) ResetUsageStats() {
-	c.usageMu.Lock()
-	defer c.usageMu.Unlock()
-	c.totalPromptTokens = 0
-	c.totalOutputTokens = 0
-	c.totalEmbedChars = 0
-	c.generateCalls = 0
-	c.embedCalls = 0
+	c.promptTokens.Store(0)
+	c.outputTokens.Store(0)
+	c.embedChars.Store(0)
+	c.generateCalls.Store(0)
+	c.embedCalls.Store(0)
+}
+
+// UsageRecord is the usage of one successful API call.
+type UsageRecord struct {
+	PromptTokens int64
+	OutputTokens int64
+	EmbedChars   int64
+}
+
+// SetUsageSink registers fn to receive the usage of every successful call,
+// so it can outlive the process (see the usage package). fn is called on
+// the calling goroutine and must not block.
+func (c *Client) SetUsageSink(fn func(UsageRecord)) {
+	if fn == nil {
+		c.usageSink.Store(nil)
+		return
+	}
+	c.usageSink.Store(&fn)
+}
+
+// KeyID identifies the client's credentials without revealing them: a short
+// hash of the API key, or "adc" for Application Default Credentials.
+func (c *Client) KeyID() string {
+	if c.useADC {
+		return "adc"
+	}
+	sum := sha256.Sum256([]byte(c.apiKey))
+	return hex.EncodeToString(sum[:4])
 }
 
 func (c *Client) recordGenerateUsage(usage *UsageMetadata) {
 	if usage == nil {
 		return
 	}
-	c.usageMu.Lock()
-	defer c.usageMu.Unlock()
-	c.totalPromptTokens += int64(usage.PromptTokenCount)
-	c.totalOutputTokens += int64(usage.CandidatesTokenCount)
-	c.generateCalls++
+	c.promptTokens.Add(int64(usage.PromptTokenCount))
+	c.outputTokens.Add(int64(usage.CandidatesTokenCount))
+	c.generateCalls.Add(1)
+
+	if sink := c.usageSink.Load(); sink != nil {
+		(*sink)(UsageRecord{PromptTokens: int64(usage.PromptTokenCount), OutputTokens: int64(usage.CandidatesTokenCount)})
+	}
 }
 
 func (c *Client) recordEmbedUsage(charCount int) {
-	c.usageMu.Lock()
-	defer c.usageMu.Unlock()
-	c.totalEmbedChars += int64(charCount)
-	c.embedCalls++
+	c.embedChars.Add(int64(charCount))
+	c.embedCalls.Add(1)
+
+	if sink := c.usageSink.Load(); sink != nil {
+		(*sink)(UsageRecord{EmbedChars: int64(charCount)})
+	}
 }
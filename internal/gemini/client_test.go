@@ -0,0 +1,104 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c := NewClient("test-key")
+	c.baseURL = srv.URL
+	return c
+}
+
+func TestConcurrentUsageStats(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"candidates":[],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":2}}`)
+	})
+	var sunk atomic.Int64
+	c.SetUsageSink(func(u UsageRecord) { sunk.Add(u.PromptTokens) })
+
+	const workers, calls = 8, 25
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < calls; i++ {
+				if _, err := c.GenerateContent(context.Background(), "m", &GenerateContentRequest{}); err != nil {
+					t.Error(err)
+					return
+				}
+				_ = c.GetUsageStats()
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := c.GetUsageStats()
+	if stats.GenerateCalls != workers*calls || stats.PromptTokens != 10*workers*calls || stats.OutputTokens != 2*workers*calls {
+		t.Errorf("stats = %+v", stats)
+	}
+	if sunk.Load() != stats.PromptTokens {
+		t.Errorf("sink saw %d prompt tokens, stats %d", sunk.Load(), stats.PromptTokens)
+	}
+	c.ResetUsageStats()
+	if stats := c.GetUsageStats(); stats.GenerateCalls != 0 || stats.EstimatedCostUSD != 0 {
+		t.Errorf("after reset = %+v", stats)
+	}
+}
+
+func TestRequestTimeoutRetries(t *testing.T) {
+	var hits atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			time.Sleep(300 * time.Millisecond) // first attempt outlasts the request timeout
+			return
+		}
+		fmt.Fprint(w, `{"embedding":{"values":[0.5]}}`)
+	})
+	c.SetRequestTimeout(50 * time.Millisecond)
+
+	resp, err := c.EmbedContent(context.Background(), &EmbedContentRequest{Model: "models/e", Content: Content{Parts: []Part{{Text: "hi"}}}})
+	if err != nil {
+		t.Fatalf("EmbedContent: %v", err)
+	}
+	if resp.Embedding == nil || len(resp.Embedding.Values) != 1 || hits.Load() != 2 {
+		t.Errorf("resp = %+v after %d attempts", resp, hits.Load())
+	}
+	if stats := c.GetUsageStats(); stats.EmbedCalls != 1 || stats.EmbedChars != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestCancelledContextStopsRetries(t *testing.T) {
+	var hits atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.GenerateContent(ctx, "m", &GenerateContentRequest{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("kept retrying for %s after the context ended", elapsed)
+	}
+	if hits.Load() == 0 || hits.Load() > 2 {
+		t.Errorf("%d attempts", hits.Load())
+	}
+}
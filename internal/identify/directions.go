@@ -0,0 +1,220 @@
+package identify
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// DirectionRepairOptions configures RepairDirections.
+type DirectionRepairOptions struct {
+	BatchSize int    // Events per transaction (default DefaultRepairBatchSize)
+	Limit     int    // Max events to examine (0 = all)
+	Channel   string // Only repair events on this channel ("" = all)
+	Observed  bool   // Also infer direction for "observed" events (requires Channel)
+	DryRun    bool   // Count what would change without writing
+}
+
+// DirectionRepairStats holds statistics about a direction repair run.
+type DirectionRepairStats struct {
+	EventsScanned  int            `json:"events_scanned"`
+	CorrectedSent  int            `json:"corrected_sent"`     // Now sent (sender is me)
+	CorrectedRecv  int            `json:"corrected_received"` // Now received (sender is someone else)
+	Unchanged      int            `json:"unchanged"`
+	Ambiguous      int            `json:"ambiguous"` // Both me and others as sender
+	ByChannel      map[string]int `json:"by_channel,omitempty"`
+	Batches        int            `json:"batches"`
+	MeContactCount int            `json:"me_contacts"`
+}
+
+// directionEvent is a message event with its sender participants.
+type directionEvent struct {
+	id        string
+	channel   string
+	direction string
+	senders   []string
+}
+
+// RepairDirections infers the direction of text messages from their sender
+// participants: messages sent by one of my contacts are "sent", messages sent
+// by anyone else are "received". Adapters that swap sent/received break
+// turn-pair chunking and speaker attribution, so chunking should be re-run
+// after corrections.
+//
+// Only events with content type "text" and at least one sender participant
+// are considered, which leaves calendar, membership and tool events alone.
+// Events marked "observed" (timeline posts, agent transcripts) have no
+// conversational direction and are skipped unless opts.Observed is set for
+// a single channel whose adapter is known to mark its messages "observed".
+func RepairDirections(db *sql.DB, opts DirectionRepairOptions) (*DirectionRepairStats, error) {
+	if opts.Observed && opts.Channel == "" {
+		return nil, fmt.Errorf("repairing observed events requires a channel")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultRepairBatchSize
+	}
+	stats := &DirectionRepairStats{ByChannel: make(map[string]int)}
+
+	meContacts, err := loadMeContacts(db)
+	if err != nil {
+		return stats, err
+	}
+	stats.MeContactCount = len(meContacts)
+	if len(meContacts) == 0 {
+		// Without me identities every message would look received
+		return stats, fmt.Errorf("no contacts linked to me; run 'mnemonic me set' first")
+	}
+
+	lastID := ""
+	for {
+		if opts.Limit > 0 && stats.EventsScanned >= opts.Limit {
+			return stats, nil
+		}
+		batchSize := opts.BatchSize
+		if opts.Limit > 0 && opts.Limit-stats.EventsScanned < batchSize {
+			batchSize = opts.Limit - stats.EventsScanned
+		}
+
+		events, err := loadDirectionBatch(db, opts.Channel, opts.Observed, lastID, batchSize)
+		if err != nil {
+			return stats, err
+		}
+		if len(events) == 0 {
+			return stats, nil
+		}
+		lastID = events[len(events)-1].id
+		stats.Batches++
+
+		if err := correctDirectionBatch(db, events, meContacts, opts.DryRun, stats); err != nil {
+			return stats, err
+		}
+	}
+}
+
+// loadDirectionBatch loads the next batch of text events that have senders.
+func loadDirectionBatch(db *sql.DB, channel string, observed bool, lastID string, batchSize int) ([]directionEvent, error) {
+	directions := `'sent', 'received'`
+	if observed {
+		directions += `, 'observed'`
+	}
+	query := `
+		SELECT e.id, e.channel, e.direction
+		FROM events e
+		WHERE e.id > ?
+		  AND e.direction IN (` + directions + `)
+		  AND e.content_types LIKE '%"text"%'
+		  AND EXISTS (SELECT 1 FROM event_participants ep WHERE ep.event_id = e.id AND ep.role = 'sender')
+	`
+	args := []interface{}{lastID}
+	if channel != "" {
+		query += ` AND e.channel = ?`
+		args = append(args, channel)
+	}
+	query += ` ORDER BY e.id LIMIT ?`
+	args = append(args, batchSize)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query events: %w", err)
+	}
+	var events []directionEvent
+	index := make(map[string]int)
+	for rows.Next() {
+		var ev directionEvent
+		if err := rows.Scan(&ev.id, &ev.channel, &ev.direction); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan event: %w", err)
+		}
+		index[ev.id] = len(events)
+		events = append(events, ev)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate events: %w", err)
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	senderRows, err := db.Query(`
+		SELECT ep.event_id, ep.contact_id
+		FROM event_participants ep
+		WHERE ep.role = 'sender' AND ep.event_id >= ? AND ep.event_id <= ?
+	`, events[0].id, events[len(events)-1].id)
+	if err != nil {
+		return nil, fmt.Errorf("query senders: %w", err)
+	}
+	defer senderRows.Close()
+	for senderRows.Next() {
+		var eventID, contactID string
+		if err := senderRows.Scan(&eventID, &contactID); err != nil {
+			return nil, fmt.Errorf("scan sender: %w", err)
+		}
+		if i, ok := index[eventID]; ok {
+			events[i].senders = append(events[i].senders, contactID)
+		}
+	}
+	return events, senderRows.Err()
+}
+
+// inferDirection returns the direction implied by an event's senders, or ""
+// if me and someone else are both recorded as sender.
+func inferDirection(senders []string, meContacts map[string]bool) string {
+	fromMe, fromOther := false, false
+	for _, id := range senders {
+		if meContacts[id] {
+			fromMe = true
+		} else {
+			fromOther = true
+		}
+	}
+	switch {
+	case fromMe && fromOther:
+		return ""
+	case fromMe:
+		return "sent"
+	default:
+		return "received"
+	}
+}
+
+// correctDirectionBatch updates events whose direction disagrees with their senders.
+func correctDirectionBatch(db *sql.DB, events []directionEvent, meContacts map[string]bool, dryRun bool, stats *DirectionRepairStats) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, ev := range events {
+		stats.EventsScanned++
+		want := inferDirection(ev.senders, meContacts)
+		switch {
+		case want == "":
+			stats.Ambiguous++
+			continue
+		case want == ev.direction:
+			stats.Unchanged++
+			continue
+		case want == "sent":
+			stats.CorrectedSent++
+		default:
+			stats.CorrectedRecv++
+		}
+		stats.ByChannel[ev.channel]++
+
+		if dryRun {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE events SET direction = ? WHERE id = ?`, want, ev.id); err != nil {
+			return fmt.Errorf("update direction: %w", err)
+		}
+	}
+
+	if dryRun {
+		return nil
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package identify
+
+import (
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestRepairDirections(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+
+	if _, err := RepairDirections(db, DirectionRepairOptions{}); err == nil {
+		t.Error("expected an error when no me contacts exist")
+	}
+
+	if _, err := db.Exec(`INSERT INTO persons (id, canonical_name, is_me, created_at, updated_at) VALUES ('me', 'Me', 1, 0, 0)`); err != nil {
+		t.Fatalf("insert me: %v", err)
+	}
+	meContact, _, _ := contacts.GetOrCreateContact(db, "email", "me@example.com", "", "test")
+	if err := contacts.EnsurePersonContactLink(db, "me", meContact, "test", 1.0); err != nil {
+		t.Fatalf("link me: %v", err)
+	}
+	other, _, _ := contacts.GetOrCreateContact(db, "email", "casey@example.com", "Casey", "test")
+
+	insertRepairEvent(t, db, "e1", "observed", "t1", "")
+	insertRepairEvent(t, db, "e2", "received", "t1", "")
+	insertRepairEvent(t, db, "e3", "sent", "t1", "")
+	insertRepairEvent(t, db, "e4", "received", "t1", "")
+	insertRepairEvent(t, db, "e5", "observed", "t1", "")
+	db.Exec(`INSERT INTO event_participants (event_id, contact_id, role) VALUES
+		('e1', ?, 'sender'), ('e2', ?, 'sender'), ('e3', ?, 'sender'), ('e4', ?, 'sender'),
+		('e5', ?, 'sender'), ('e5', ?, 'sender')`,
+		meContact, meContact, other, other, meContact, other)
+	// Calendar events are left alone
+	db.Exec(`INSERT INTO events (id, timestamp, channel, content_types, direction, source_adapter, source_id)
+		VALUES ('cal', 0, 'calendar', '["calendar_event"]', 'observed', 'calendar', 'cal')`)
+	db.Exec(`INSERT INTO event_participants (event_id, contact_id, role) VALUES ('cal', ?, 'sender')`, other)
+
+	dry, err := RepairDirections(db, DirectionRepairOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if dry.CorrectedSent != 1 || dry.CorrectedRecv != 1 {
+		t.Errorf("dry run = %+v, want 1 sent and 1 received correction", dry)
+	}
+	var direction string
+	db.QueryRow(`SELECT direction FROM events WHERE id = 'e2'`).Scan(&direction)
+	if direction != "received" {
+		t.Fatalf("dry run changed direction to %q", direction)
+	}
+
+	// Observed events are skipped by default
+	stats, err := RepairDirections(db, DirectionRepairOptions{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("RepairDirections: %v", err)
+	}
+	if stats.EventsScanned != 3 || stats.Unchanged != 1 || stats.Ambiguous != 0 || stats.Batches != 2 {
+		t.Errorf("stats = %+v, want 3 scanned, 1 unchanged, 2 batches", stats)
+	}
+
+	want := map[string]string{"e1": "observed", "e2": "sent", "e3": "received", "e4": "received", "e5": "observed", "cal": "observed"}
+	for id, w := range want {
+		db.QueryRow(`SELECT direction FROM events WHERE id = ?`, id).Scan(&direction)
+		if direction != w {
+			t.Errorf("%s direction = %q, want %q", id, direction, w)
+		}
+	}
+
+	// Opting a channel in repairs its observed messages
+	if _, err := RepairDirections(db, DirectionRepairOptions{Observed: true}); err == nil {
+		t.Error("expected an error when repairing observed events without a channel")
+	}
+	stats, err = RepairDirections(db, DirectionRepairOptions{Channel: "imessage", Observed: true})
+	if err != nil {
+		t.Fatalf("RepairDirections observed: %v", err)
+	}
+	if stats.EventsScanned != 5 || stats.CorrectedSent != 1 || stats.Ambiguous != 1 {
+		t.Errorf("observed stats = %+v, want 5 scanned, 1 sent, 1 ambiguous", stats)
+	}
+	want["e1"] = "sent"
+	for id, w := range want {
+		db.QueryRow(`SELECT direction FROM events WHERE id = ?`, id).Scan(&direction)
+		if direction != w {
+			t.Errorf("%s direction = %q, want %q", id, direction, w)
+		}
+	}
+}
@@ -2,10 +2,14 @@ package identify
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/namespace"
 	"github.com/google/uuid"
 )
 
@@ -25,6 +29,9 @@ type PersonFact struct {
 	IsSensitive        bool
 	IsIdentifier       bool
 	IsHardIdentifier   bool
+	SeenCount          int
+	LastSeenAt         *time.Time
+	SupersededBy       *string
 	CreatedAt          time.Time
 	UpdatedAt          time.Time
 }
@@ -65,6 +72,13 @@ const (
 	FactTypeSchoolAttended     = "school_attended"
 )
 
+// Fact type constants - Medical (only stored when enabled; see MedicalFactsEnabled)
+const (
+	FactTypeAllergy          = "allergy"
+	FactTypeMedication       = "medication"
+	FactTypeMedicalCondition = "medical_condition"
+)
+
 // Fact categories
 const (
 	CategoryCoreIdentity     = "core_identity"
@@ -137,9 +151,128 @@ var SoftIdentifierWeights = map[string]float64{
 	FactTypeBirthdate:       0.25,
 }
 
-// InsertFact inserts or updates a person fact
-// Uses UNIQUE constraint on (person_id, category, fact_type, fact_value) to prevent duplicates
+// SingleValuedFactTypes hold one current value per person: a new value
+// supersedes the previous one instead of accumulating beside it.
+var SingleValuedFactTypes = map[string]bool{
+	FactTypeFullLegalName:   true,
+	FactTypeFamilyName:      true,
+	FactTypeBirthdate:       true,
+	FactTypeEmployerCurrent: true,
+	FactTypeLocationCurrent: true,
+	FactTypeSpouseFirstName: true,
+	FactTypeSSN:             true,
+}
+
+// Fact history change types
+const (
+	FactChangeCreated    = "created"
+	FactChangeReinforced = "reinforced"
+	FactChangeSuperseded = "superseded"
+)
+
+// FactChange is one entry in a person's fact history.
+type FactChange struct {
+	ID              string    `json:"id"`
+	FactID          string    `json:"fact_id"`
+	PersonID        string    `json:"person_id"`
+	FactType        string    `json:"fact_type"`
+	ChangeType      string    `json:"change_type"`
+	OldValue        *string   `json:"old_value,omitempty"`
+	NewValue        *string   `json:"new_value,omitempty"`
+	OldConfidence   *float64  `json:"old_confidence,omitempty"`
+	NewConfidence   *float64  `json:"new_confidence,omitempty"`
+	SourceEpisodeID *string   `json:"source_episode_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// NormalizeFactValue returns the dedup key for a fact value: phone numbers
+// reduced to digits, handles without "@", everything else lowercased with
+// whitespace collapsed.
+func NormalizeFactValue(factType, value string) string {
+	switch {
+	case strings.HasPrefix(factType, "phone"):
+		if normalized := contacts.NormalizeIdentifier(value, "phone"); normalized != "" {
+			return normalized
+		}
+	case strings.HasPrefix(factType, "social_") || factType == FactTypeUsernameGeneric:
+		return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "@")
+	}
+	return strings.ToLower(strings.Join(strings.Fields(value), " "))
+}
+
+// InsertFact upserts a person fact keyed by (person, fact_type, normalized
+// value). Seeing a value again bumps its confidence (noisy-or) and
+// last_seen_at; a new value of a single-valued type supersedes the old one.
+// Every change is recorded in person_fact_history.
 func InsertFact(db *sql.DB, fact PersonFact) error {
+	_, err := UpsertFact(db, fact)
+	return err
+}
+
+// ErrFactNotAdmitted is returned by InsertFact and UpsertFact for facts that
+// must not be stored, such as medical facts while they are disabled.
+var ErrFactNotAdmitted = errors.New("fact not admitted")
+
+var medicalFactsEnabled atomic.Bool
+
+// SetMedicalFactsEnabled turns storing medical facts (allergies, medications,
+// conditions) on or off. Off by default; set from the privacy.medical_facts
+// config flag.
+func SetMedicalFactsEnabled(enabled bool) {
+	medicalFactsEnabled.Store(enabled)
+}
+
+// MedicalFactsEnabled reports whether medical facts may be stored.
+func MedicalFactsEnabled() bool {
+	return medicalFactsEnabled.Load()
+}
+
+// IsMedicalFact reports whether a fact of this category and type is medical.
+func IsMedicalFact(category, factType string) bool {
+	switch factType {
+	case FactTypeAllergy, FactTypeMedication, FactTypeMedicalCondition:
+		return true
+	}
+	return category == CategoryMedical
+}
+
+// IsSensitiveFact reports whether a fact of this category and type is stored
+// as sensitive and kept out of exports by default.
+func IsSensitiveFact(category, factType string) bool {
+	return IsMedicalFact(category, factType) || isSensitiveFactType(factType)
+}
+
+// admitFact rejects facts that must not be stored. Medical facts are only
+// stored when enabled and self-disclosed, and always as sensitive.
+func admitFact(fact *PersonFact) error {
+	if !IsMedicalFact(fact.Category, fact.FactType) {
+		return nil
+	}
+	if !MedicalFactsEnabled() {
+		return fmt.Errorf("%w: medical facts are disabled", ErrFactNotAdmitted)
+	}
+	if fact.SourceType != "self_disclosed" {
+		return fmt.Errorf("%w: medical facts must be self-disclosed", ErrFactNotAdmitted)
+	}
+	fact.Category = CategoryMedical
+	fact.IsSensitive = true
+	return nil
+}
+
+// factNamespace is the namespace a new fact is stored in: sensitive facts
+// are private to the household member whose data revealed them.
+func factNamespace(fact PersonFact) interface{} {
+	if !fact.IsSensitive {
+		return nil
+	}
+	return namespace.Value()
+}
+
+// UpsertFact is InsertFact returning the ID of the inserted or matched fact.
+func UpsertFact(db *sql.DB, fact PersonFact) (string, error) {
+	if err := admitFact(&fact); err != nil {
+		return "", err
+	}
 	if fact.ID == "" {
 		fact.ID = uuid.New().String()
 	}
@@ -153,49 +286,205 @@ func InsertFact(db *sql.DB, fact PersonFact) error {
 	// Determine identifier flags based on fact type
 	fact.IsIdentifier = isIdentifierType(fact.FactType)
 	fact.IsHardIdentifier = isHardIdentifierType(fact.FactType)
+	normalized := NormalizeFactValue(fact.FactType, fact.FactValue)
 
-	_, err := db.Exec(`
-		INSERT INTO person_facts (
-			id, person_id, category, fact_type, fact_value,
-			confidence, source_type, source_channel, source_segment_id,
-			source_facet_id, evidence, is_sensitive, is_identifier, is_hard_identifier,
-			created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT(person_id, category, fact_type, fact_value) DO UPDATE SET
-			confidence = MAX(confidence, excluded.confidence),
-			source_type = excluded.source_type,
-			source_channel = COALESCE(excluded.source_channel, source_channel),
-			source_segment_id = COALESCE(excluded.source_segment_id, source_segment_id),
-			source_facet_id = COALESCE(excluded.source_facet_id, source_facet_id),
-			evidence = COALESCE(excluded.evidence, evidence),
-			updated_at = excluded.updated_at
-	`,
-		fact.ID, fact.PersonID, fact.Category, fact.FactType, fact.FactValue,
-		fact.Confidence, fact.SourceType, fact.SourceChannel, fact.SourceSegment,
-		fact.SourceFacetID, fact.Evidence, boolToInt(fact.IsSensitive),
-		boolToInt(fact.IsIdentifier), boolToInt(fact.IsHardIdentifier),
-		fact.CreatedAt.Unix(), fact.UpdatedAt.Unix(),
-	)
+	tx, err := db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Rows written before normalized_value existed match on the raw value
+	var existingID string
+	var existingConfidence float64
+	err = tx.QueryRow(`
+		SELECT id, confidence FROM person_facts
+		WHERE person_id = ? AND fact_type = ?
+		  AND (normalized_value = ? OR (normalized_value IS NULL AND LOWER(TRIM(fact_value)) = ?))
+		ORDER BY superseded_at IS NOT NULL, confidence DESC
+		LIMIT 1
+	`, fact.PersonID, fact.FactType, normalized, strings.ToLower(strings.TrimSpace(fact.FactValue))).Scan(&existingID, &existingConfidence)
+	switch {
+	case err == sql.ErrNoRows:
+		_, err = tx.Exec(`
+			INSERT INTO person_facts (
+				id, person_id, category, fact_type, fact_value,
+				confidence, source_type, source_channel, source_episode_id,
+				source_facet_id, evidence, is_sensitive, is_identifier, is_hard_identifier,
+				normalized_value, seen_count, last_seen_at, namespace, created_at, updated_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?, ?, ?)
+		`,
+			fact.ID, fact.PersonID, fact.Category, fact.FactType, fact.FactValue,
+			fact.Confidence, fact.SourceType, fact.SourceChannel, fact.SourceSegment,
+			fact.SourceFacetID, fact.Evidence, boolToInt(fact.IsSensitive),
+			boolToInt(fact.IsIdentifier), boolToInt(fact.IsHardIdentifier),
+			normalized, now, factNamespace(fact), fact.CreatedAt.Unix(), fact.UpdatedAt.Unix(),
+		)
+		if err != nil {
+			return "", fmt.Errorf("failed to insert fact: %w", err)
+		}
+		if err := recordFactChange(tx, fact.ID, fact, FactChangeCreated, nil, &fact.FactValue, nil, &fact.Confidence, now); err != nil {
+			return "", err
+		}
+	case err != nil:
+		return "", fmt.Errorf("find existing fact: %w", err)
+	default:
+		fact.ID = existingID
+		confidence := clampConfidence(1 - (1-clampConfidence(existingConfidence))*(1-clampConfidence(fact.Confidence)))
+		_, err = tx.Exec(`
+			UPDATE person_facts SET
+				confidence = ?,
+				source_type = ?,
+				source_channel = COALESCE(?, source_channel),
+				source_episode_id = COALESCE(?, source_episode_id),
+				source_facet_id = COALESCE(?, source_facet_id),
+				evidence = COALESCE(?, evidence),
+				normalized_value = ?,
+				seen_count = COALESCE(seen_count, 1) + 1,
+				last_seen_at = ?,
+				superseded_by = NULL,
+				superseded_at = NULL,
+				updated_at = ?
+			WHERE id = ?
+		`, confidence, fact.SourceType, fact.SourceChannel, fact.SourceSegment,
+			fact.SourceFacetID, fact.Evidence, normalized, now, now, existingID)
+		if err != nil {
+			return "", fmt.Errorf("failed to update fact: %w", err)
+		}
+		if err := recordFactChange(tx, existingID, fact, FactChangeReinforced, nil, &fact.FactValue, &existingConfidence, &confidence, now); err != nil {
+			return "", err
+		}
+	}
+
+	if SingleValuedFactTypes[fact.FactType] {
+		if err := supersedeOtherValues(tx, fact, now); err != nil {
+			return "", err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("commit transaction: %w", err)
+	}
+	return fact.ID, nil
+}
 
+// supersedeOtherValues marks a person's other current values of fact's type
+// as superseded by it.
+func supersedeOtherValues(tx *sql.Tx, fact PersonFact, now int64) error {
+	rows, err := tx.Query(`
+		SELECT id, fact_value, confidence FROM person_facts
+		WHERE person_id = ? AND fact_type = ? AND id != ? AND superseded_at IS NULL
+	`, fact.PersonID, fact.FactType, fact.ID)
 	if err != nil {
-		return fmt.Errorf("failed to insert fact: %w", err)
+		return fmt.Errorf("find superseded facts: %w", err)
+	}
+	type oldFact struct {
+		id, value  string
+		confidence float64
 	}
+	var old []oldFact
+	for rows.Next() {
+		var f oldFact
+		if err := rows.Scan(&f.id, &f.value, &f.confidence); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan superseded fact: %w", err)
+		}
+		old = append(old, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, f := range old {
+		if _, err := tx.Exec(`
+			UPDATE person_facts SET superseded_by = ?, superseded_at = ?, updated_at = ? WHERE id = ?
+		`, fact.ID, now, now, f.id); err != nil {
+			return fmt.Errorf("supersede fact: %w", err)
+		}
+		if err := recordFactChange(tx, f.id, fact, FactChangeSuperseded, &f.value, &fact.FactValue, &f.confidence, &fact.Confidence, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+func recordFactChange(tx *sql.Tx, factID string, fact PersonFact, changeType string, oldValue, newValue *string, oldConfidence, newConfidence *float64, now int64) error {
+	_, err := tx.Exec(`
+		INSERT INTO person_fact_history (
+			id, fact_id, person_id, fact_type, change_type,
+			old_value, new_value, old_confidence, new_confidence, source_episode_id, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, uuid.New().String(), factID, fact.PersonID, fact.FactType, changeType,
+		oldValue, newValue, oldConfidence, newConfidence, fact.SourceSegment, now)
+	if err != nil {
+		return fmt.Errorf("record fact history: %w", err)
+	}
 	return nil
 }
 
-// GetFactsForPerson returns all facts for a person
+// GetFactHistory returns a person's fact changes, oldest first.
+// Changes to another household member's private facts are left out.
+func GetFactHistory(db *sql.DB, personID string) ([]FactChange, error) {
+	visible, ns := namespace.Visible("pf.namespace")
+	rows, err := db.Query(`
+		SELECT h.id, h.fact_id, h.person_id, h.fact_type, h.change_type,
+			h.old_value, h.new_value, h.old_confidence, h.new_confidence, h.source_episode_id, h.created_at
+		FROM person_fact_history h
+		WHERE h.person_id = ?
+		  AND NOT EXISTS (SELECT 1 FROM person_facts pf WHERE pf.id = h.fact_id AND NOT `+visible+`)
+		ORDER BY h.created_at, h.rowid
+	`, personID, ns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fact history: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []FactChange
+	for rows.Next() {
+		var c FactChange
+		var oldValue, newValue, episodeID sql.NullString
+		var oldConfidence, newConfidence sql.NullFloat64
+		var createdAt int64
+		if err := rows.Scan(&c.ID, &c.FactID, &c.PersonID, &c.FactType, &c.ChangeType,
+			&oldValue, &newValue, &oldConfidence, &newConfidence, &episodeID, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan fact change: %w", err)
+		}
+		if oldValue.Valid {
+			c.OldValue = &oldValue.String
+		}
+		if newValue.Valid {
+			c.NewValue = &newValue.String
+		}
+		if oldConfidence.Valid {
+			c.OldConfidence = &oldConfidence.Float64
+		}
+		if newConfidence.Valid {
+			c.NewConfidence = &newConfidence.Float64
+		}
+		if episodeID.Valid {
+			c.SourceEpisodeID = &episodeID.String
+		}
+		c.CreatedAt = time.Unix(createdAt, 0)
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// GetFactsForPerson returns all facts for a person visible to the active
+// household member
 func GetFactsForPerson(db *sql.DB, personID string) ([]PersonFact, error) {
+	visible, ns := namespace.Visible("namespace")
 	rows, err := db.Query(`
 		SELECT
 			id, person_id, category, fact_type, fact_value,
-			confidence, source_type, source_channel, source_segment_id,
+			confidence, source_type, source_channel, source_episode_id,
 			source_facet_id, evidence, is_sensitive, is_identifier, is_hard_identifier,
-			created_at, updated_at
+			seen_count, last_seen_at, superseded_by, created_at, updated_at
 		FROM person_facts
-		WHERE person_id = ?
+		WHERE person_id = ? AND superseded_at IS NULL AND `+visible+`
 		ORDER BY category, fact_type, confidence DESC
-	`, personID)
+	`, personID, ns)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query facts: %w", err)
 	}
@@ -218,11 +507,11 @@ func GetHardIdentifiers(db *sql.DB) ([]PersonFact, error) {
 	rows, err := db.Query(`
 		SELECT
 			id, person_id, category, fact_type, fact_value,
-			confidence, source_type, source_channel, source_segment_id,
+			confidence, source_type, source_channel, source_episode_id,
 			source_facet_id, evidence, is_sensitive, is_identifier, is_hard_identifier,
-			created_at, updated_at
+			seen_count, last_seen_at, superseded_by, created_at, updated_at
 		FROM person_facts
-		WHERE is_hard_identifier = 1
+		WHERE is_hard_identifier = 1 AND superseded_at IS NULL
 		ORDER BY fact_type, fact_value
 	`)
 	if err != nil {
@@ -247,11 +536,11 @@ func GetFactsByType(db *sql.DB, factType string) ([]PersonFact, error) {
 	rows, err := db.Query(`
 		SELECT
 			id, person_id, category, fact_type, fact_value,
-			confidence, source_type, source_channel, source_segment_id,
+			confidence, source_type, source_channel, source_episode_id,
 			source_facet_id, evidence, is_sensitive, is_identifier, is_hard_identifier,
-			created_at, updated_at
+			seen_count, last_seen_at, superseded_by, created_at, updated_at
 		FROM person_facts
-		WHERE fact_type = ?
+		WHERE fact_type = ? AND superseded_at IS NULL
 		ORDER BY person_id, confidence DESC
 	`, factType)
 	if err != nil {
@@ -272,17 +561,19 @@ func GetFactsByType(db *sql.DB, factType string) ([]PersonFact, error) {
 }
 
 // GetFactsByCategory returns all facts in a specific category for a person
+// visible to the active household member
 func GetFactsByCategory(db *sql.DB, personID string, category string) ([]PersonFact, error) {
+	visible, ns := namespace.Visible("namespace")
 	rows, err := db.Query(`
 		SELECT
 			id, person_id, category, fact_type, fact_value,
-			confidence, source_type, source_channel, source_segment_id,
+			confidence, source_type, source_channel, source_episode_id,
 			source_facet_id, evidence, is_sensitive, is_identifier, is_hard_identifier,
-			created_at, updated_at
+			seen_count, last_seen_at, superseded_by, created_at, updated_at
 		FROM person_facts
-		WHERE person_id = ? AND category = ?
+		WHERE person_id = ? AND category = ? AND superseded_at IS NULL AND `+visible+`
 		ORDER BY fact_type, confidence DESC
-	`, personID, category)
+	`, personID, category, ns)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query facts by category: %w", err)
 	}
@@ -470,13 +761,15 @@ func scanPersonFact(rows *sql.Rows) (PersonFact, error) {
 	var fact PersonFact
 	var sourceChannel, sourceSegment, sourceFacetID, evidence sql.NullString
 	var isSensitive, isIdentifier, isHardIdentifier int
+	var seenCount, lastSeenAt sql.NullInt64
+	var supersededBy sql.NullString
 	var createdAt, updatedAt int64
 
 	err := rows.Scan(
 		&fact.ID, &fact.PersonID, &fact.Category, &fact.FactType, &fact.FactValue,
 		&fact.Confidence, &fact.SourceType, &sourceChannel, &sourceSegment,
 		&sourceFacetID, &evidence, &isSensitive, &isIdentifier, &isHardIdentifier,
-		&createdAt, &updatedAt,
+		&seenCount, &lastSeenAt, &supersededBy, &createdAt, &updatedAt,
 	)
 	if err != nil {
 		return fact, fmt.Errorf("failed to scan fact: %w", err)
@@ -498,6 +791,17 @@ func scanPersonFact(rows *sql.Rows) (PersonFact, error) {
 	fact.IsSensitive = isSensitive == 1
 	fact.IsIdentifier = isIdentifier == 1
 	fact.IsHardIdentifier = isHardIdentifier == 1
+	fact.SeenCount = 1
+	if seenCount.Valid {
+		fact.SeenCount = int(seenCount.Int64)
+	}
+	if lastSeenAt.Valid {
+		t := time.Unix(lastSeenAt.Int64, 0)
+		fact.LastSeenAt = &t
+	}
+	if supersededBy.Valid {
+		fact.SupersededBy = &supersededBy.String
+	}
 	fact.CreatedAt = time.Unix(createdAt, 0)
 	fact.UpdatedAt = time.Unix(updatedAt, 0)
 
@@ -524,6 +828,18 @@ func isHardIdentifierType(factType string) bool {
 	return false
 }
 
+// clampConfidence keeps a confidence within [0, 0.99] so repeated sightings
+// approach but never reach certainty.
+func clampConfidence(c float64) float64 {
+	switch {
+	case c < 0:
+		return 0
+	case c > 0.99:
+		return 0.99
+	}
+	return c
+}
+
 func boolToInt(b bool) int {
 	if b {
 		return 1
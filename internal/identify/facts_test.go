@@ -1,7 +1,11 @@
 package identify
 
 import (
+	"errors"
 	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/namespace"
+	"github.com/Napageneral/mnemonic/internal/testutil"
 )
 
 func TestFactTypeConstants(t *testing.T) {
@@ -138,3 +142,161 @@ func TestBoolToInt(t *testing.T) {
 		t.Error("boolToInt(false) should return 0")
 	}
 }
+
+func TestNormalizeFactValue(t *testing.T) {
+	tests := []struct {
+		factType, value, want string
+	}{
+		{FactTypePhoneMobile, "+1 (555) 123-4567", "5551234567"},
+		{FactTypeSocialTwitter, " @Casey ", "casey"},
+		{FactTypeEmployerCurrent, "  Acme   Corp ", "acme corp"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeFactValue(tt.factType, tt.value); got != tt.want {
+			t.Errorf("NormalizeFactValue(%s, %q) = %q, want %q", tt.factType, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestInsertFact_Upsert(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec(`INSERT INTO persons (id, canonical_name, created_at, updated_at) VALUES ('casey', 'Casey', 0, 0)`); err != nil {
+		t.Fatalf("insert person: %v", err)
+	}
+	insert := func(factType, value string, confidence float64) {
+		t.Helper()
+		if err := InsertFact(db, PersonFact{PersonID: "casey", Category: CategoryContactInfo, FactType: factType, FactValue: value, Confidence: confidence, SourceType: "mentioned"}); err != nil {
+			t.Fatalf("InsertFact(%s, %s): %v", factType, value, err)
+		}
+	}
+
+	// The same phone in two formats is one fact, seen twice, more confident
+	insert(FactTypePhoneMobile, "555-123-4567", 0.5)
+	insert(FactTypePhoneMobile, "(555) 123 4567", 0.5)
+	// Multi-valued types accumulate
+	insert(FactTypeEmailPersonal, "casey@example.com", 0.7)
+	insert(FactTypeEmailPersonal, "cj@example.com", 0.7)
+	// Single-valued types supersede
+	insert(FactTypeEmployerCurrent, "Initech", 0.7)
+	insert(FactTypeEmployerCurrent, "Acme", 0.7)
+
+	facts, err := GetFactsForPerson(db, "casey")
+	if err != nil {
+		t.Fatalf("GetFactsForPerson: %v", err)
+	}
+	byType := map[string][]PersonFact{}
+	for _, f := range facts {
+		byType[f.FactType] = append(byType[f.FactType], f)
+	}
+	if phones := byType[FactTypePhoneMobile]; len(phones) != 1 || phones[0].SeenCount != 2 || phones[0].Confidence != 0.75 || phones[0].LastSeenAt == nil {
+		t.Errorf("phones = %+v, want one fact seen twice at 0.75", phones)
+	}
+	if emails := byType[FactTypeEmailPersonal]; len(emails) != 2 {
+		t.Errorf("emails = %+v, want 2", emails)
+	}
+	if employers := byType[FactTypeEmployerCurrent]; len(employers) != 1 || employers[0].FactValue != "Acme" {
+		t.Errorf("employers = %+v, want only Acme", employers)
+	}
+
+	// Re-asserting the old employer makes it current again
+	insert(FactTypeEmployerCurrent, "initech", 0.5)
+	var current string
+	db.QueryRow(`SELECT fact_value FROM person_facts WHERE fact_type = ? AND superseded_at IS NULL`, FactTypeEmployerCurrent).Scan(&current)
+	if current != "Initech" {
+		t.Errorf("current employer = %q, want Initech", current)
+	}
+
+	history, err := GetFactHistory(db, "casey")
+	if err != nil {
+		t.Fatalf("GetFactHistory: %v", err)
+	}
+	counts := map[string]int{}
+	for _, c := range history {
+		counts[c.ChangeType]++
+	}
+	if counts[FactChangeCreated] != 5 || counts[FactChangeReinforced] != 2 || counts[FactChangeSuperseded] != 2 {
+		t.Errorf("history counts = %v, want 5 created, 2 reinforced, 2 superseded", counts)
+	}
+}
+
+func TestInsertFact_MedicalGate(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	defer SetMedicalFactsEnabled(false)
+
+	if _, err := db.Exec(`INSERT INTO persons (id, canonical_name, created_at, updated_at) VALUES ('casey', 'Casey', 0, 0)`); err != nil {
+		t.Fatalf("insert person: %v", err)
+	}
+	allergy := func(sourceType string) PersonFact {
+		return PersonFact{PersonID: "casey", Category: CategoryPreferences, FactType: FactTypeAllergy, FactValue: "peanuts", Confidence: 0.9, SourceType: sourceType}
+	}
+
+	if err := InsertFact(db, allergy("self_disclosed")); !errors.Is(err, ErrFactNotAdmitted) {
+		t.Errorf("disabled: err = %v, want ErrFactNotAdmitted", err)
+	}
+
+	SetMedicalFactsEnabled(true)
+	if err := InsertFact(db, allergy("mentioned")); !errors.Is(err, ErrFactNotAdmitted) {
+		t.Errorf("mentioned: err = %v, want ErrFactNotAdmitted", err)
+	}
+	if err := InsertFact(db, allergy("self_disclosed")); err != nil {
+		t.Fatalf("self-disclosed: %v", err)
+	}
+
+	facts, err := GetFactsForPerson(db, "casey")
+	if err != nil || len(facts) != 1 {
+		t.Fatalf("facts = %+v, %v", facts, err)
+	}
+	if !facts[0].IsSensitive || facts[0].Category != CategoryMedical {
+		t.Errorf("allergy = %+v, want sensitive medical fact", facts[0])
+	}
+}
+
+func TestInsertFact_NamespacedSensitiveFacts(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	defer namespace.Set("")
+
+	if _, err := db.Exec(`INSERT INTO persons (id, canonical_name, created_at, updated_at) VALUES ('casey', 'Casey', 0, 0)`); err != nil {
+		t.Fatalf("insert person: %v", err)
+	}
+	// Tyler's data reveals Casey's passport number and employer
+	namespace.Set("tyler")
+	for _, f := range []PersonFact{
+		{PersonID: "casey", Category: CategoryGovernmentID, FactType: FactTypePassportNumber, FactValue: "X1234567", Confidence: 0.9, SourceType: "mentioned", IsSensitive: true},
+		{PersonID: "casey", Category: CategoryProfessional, FactType: FactTypeEmployerCurrent, FactValue: "Acme", Confidence: 0.9, SourceType: "mentioned"},
+	} {
+		if err := InsertFact(db, f); err != nil {
+			t.Fatalf("InsertFact(%s): %v", f.FactType, err)
+		}
+	}
+
+	visible := func() []string {
+		t.Helper()
+		facts, err := GetFactsForPerson(db, "casey")
+		if err != nil {
+			t.Fatalf("GetFactsForPerson: %v", err)
+		}
+		var types []string
+		for _, f := range facts {
+			types = append(types, f.FactType)
+		}
+		return types
+	}
+	if got := visible(); len(got) != 2 {
+		t.Errorf("tyler sees %v, want both facts", got)
+	}
+	// Another household member, or no member, only sees the shared fact
+	for _, ns := range []string{"jordan", ""} {
+		namespace.Set(ns)
+		if got := visible(); len(got) != 1 || got[0] != FactTypeEmployerCurrent {
+			t.Errorf("%q sees %v, want only the employer", ns, got)
+		}
+		history, err := GetFactHistory(db, "casey")
+		if err != nil || len(history) != 1 {
+			t.Errorf("%q sees history %+v, %v", ns, history, err)
+		}
+	}
+}
@@ -0,0 +1,542 @@
+package identify
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/Napageneral/mnemonic/internal/contacts"
+)
+
+// DefaultRepairBatchSize is the number of events backfilled per transaction.
+const DefaultRepairBatchSize = 500
+
+// ParticipantRepairOptions configures RepairParticipants.
+type ParticipantRepairOptions struct {
+	BatchSize int    // Events per transaction (default DefaultRepairBatchSize)
+	Limit     int    // Max events to backfill (0 = all)
+	Channel   string // Only repair events on this channel ("" = all)
+	DryRun    bool   // Count what would change without writing
+}
+
+// ParticipantRepairStats holds statistics about a participant repair run.
+type ParticipantRepairStats struct {
+	OrphanContacts    int `json:"orphan_contacts"`    // Participant contacts with no linked person
+	ContactsLinked    int `json:"contacts_linked"`    // Linked to existing persons via legacy identities
+	PersonsCreated    int `json:"persons_created"`    // Created from meaningful contact names
+	EventsScanned     int `json:"events_scanned"`     // Messages with no participant rows
+	EventsRepaired    int `json:"events_repaired"`    // Events that gained participants
+	ParticipantsAdded int `json:"participants_added"` // event_participants rows added
+	EventsUnresolved  int `json:"events_unresolved"`  // Events we couldn't attribute
+	Batches           int `json:"batches"`
+}
+
+// participantCandidate is a participant recovered for an event.
+type participantCandidate struct {
+	contactID string
+	role      string
+}
+
+// RepairParticipants re-resolves participants for historical events imported
+// before person linking:
+//
+//  1. Participant contacts with no person are linked via the legacy identities
+//     table (contact_identifiers → identities → persons), or get a person from
+//     their display name when it is meaningful.
+//  2. Sent/received events with no event_participants rows are backfilled in
+//     batches from sender/recipient fields in metadata_json, falling back to
+//     the participants of other events in the same thread.
+func RepairParticipants(db *sql.DB, opts ParticipantRepairOptions) (*ParticipantRepairStats, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultRepairBatchSize
+	}
+	stats := &ParticipantRepairStats{}
+
+	if err := linkOrphanContacts(db, opts, stats); err != nil {
+		return stats, fmt.Errorf("link orphan contacts: %w", err)
+	}
+	if err := backfillEventParticipants(db, opts, stats); err != nil {
+		return stats, fmt.Errorf("backfill event participants: %w", err)
+	}
+	return stats, nil
+}
+
+// linkOrphanContacts links participant contacts that have no person.
+func linkOrphanContacts(db *sql.DB, opts ParticipantRepairOptions, stats *ParticipantRepairStats) error {
+	legacy, err := loadLegacyIdentities(db)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.Query(`
+		SELECT c.id, COALESCE(c.display_name, ''), ci.type, ci.normalized
+		FROM contacts c
+		LEFT JOIN contact_identifiers ci ON ci.contact_id = c.id
+		WHERE EXISTS (SELECT 1 FROM event_participants ep WHERE ep.contact_id = c.id)
+		  AND NOT EXISTS (SELECT 1 FROM person_contact_links pcl WHERE pcl.contact_id = c.id)
+		ORDER BY c.id
+	`)
+	if err != nil {
+		return fmt.Errorf("query orphan contacts: %w", err)
+	}
+
+	type orphan struct {
+		id       string
+		name     string
+		personID string
+	}
+	var orphans []*orphan
+	byID := make(map[string]*orphan)
+	for rows.Next() {
+		var id, name string
+		var idType, normalized sql.NullString
+		if err := rows.Scan(&id, &name, &idType, &normalized); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan orphan contact: %w", err)
+		}
+		o, ok := byID[id]
+		if !ok {
+			o = &orphan{id: id, name: name}
+			byID[id] = o
+			orphans = append(orphans, o)
+		}
+		if o.personID == "" && idType.Valid && normalized.Valid {
+			o.personID = legacy[idType.String+"|"+normalized.String]
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate orphan contacts: %w", err)
+	}
+	stats.OrphanContacts = len(orphans)
+
+	if opts.DryRun {
+		for _, o := range orphans {
+			if o.personID != "" {
+				stats.ContactsLinked++
+			} else if contacts.IsMeaningfulPersonName(o.name) {
+				stats.PersonsCreated++
+			}
+		}
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, o := range orphans {
+		if o.personID != "" {
+			if err := contacts.EnsurePersonContactLink(tx, o.personID, o.id, "repair", 0.9); err != nil {
+				return err
+			}
+			stats.ContactsLinked++
+			continue
+		}
+		if _, created, err := contacts.EnsurePersonForContact(tx, o.id, o.name, "repair", 0.7); err != nil {
+			return err
+		} else if created {
+			stats.PersonsCreated++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// loadLegacyIdentities maps "type|normalized" to person ID from the legacy
+// identities table, using the same channel → contact type mapping as the
+// contacts migration.
+func loadLegacyIdentities(db *sql.DB) (map[string]string, error) {
+	rows, err := db.Query(`
+		SELECT i.person_id, i.channel, i.identifier
+		FROM identities i
+		JOIN persons p ON p.id = i.person_id
+		ORDER BY i.created_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query identities: %w", err)
+	}
+	defer rows.Close()
+
+	legacy := make(map[string]string)
+	for rows.Next() {
+		var personID, channel, identifier string
+		if err := rows.Scan(&personID, &channel, &identifier); err != nil {
+			return nil, fmt.Errorf("scan identity: %w", err)
+		}
+		contactType := channel
+		switch channel {
+		case "aix":
+			contactType = "human"
+		case "ai":
+			contactType = "ai"
+		}
+		normalized := contacts.NormalizeIdentifier(identifier, contactType)
+		if normalized == "" {
+			continue
+		}
+		key := contactType + "|" + normalized
+		if _, ok := legacy[key]; !ok {
+			legacy[key] = personID
+		}
+	}
+	return legacy, rows.Err()
+}
+
+// backfillEventParticipants adds participants to messages that have none.
+func backfillEventParticipants(db *sql.DB, opts ParticipantRepairOptions, stats *ParticipantRepairStats) error {
+	meContacts, err := loadMeContacts(db)
+	if err != nil {
+		return err
+	}
+
+	lastID := ""
+	for {
+		if opts.Limit > 0 && stats.EventsScanned >= opts.Limit {
+			return nil
+		}
+		batchSize := opts.BatchSize
+		if opts.Limit > 0 && opts.Limit-stats.EventsScanned < batchSize {
+			batchSize = opts.Limit - stats.EventsScanned
+		}
+
+		done, next, err := backfillBatch(db, opts, lastID, batchSize, meContacts, stats)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		lastID = next
+	}
+}
+
+// repairEvent is an event missing participants.
+type repairEvent struct {
+	id        string
+	direction string
+	threadID  sql.NullString
+	metadata  sql.NullString
+}
+
+// backfillBatch repairs one batch of events after lastID (keyset pagination,
+// so unresolved events are not rescanned). Returns done when no events remain.
+func backfillBatch(db *sql.DB, opts ParticipantRepairOptions, lastID string, batchSize int, meContacts map[string]bool, stats *ParticipantRepairStats) (bool, string, error) {
+	query := `
+		SELECT e.id, e.direction, e.thread_id, e.metadata_json
+		FROM events e
+		WHERE e.id > ?
+		  AND e.direction IN ('sent', 'received')
+		  AND NOT EXISTS (SELECT 1 FROM event_participants ep WHERE ep.event_id = e.id)
+	`
+	args := []interface{}{lastID}
+	if opts.Channel != "" {
+		query += ` AND e.channel = ?`
+		args = append(args, opts.Channel)
+	}
+	query += ` ORDER BY e.id LIMIT ?`
+	args = append(args, batchSize)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return false, "", fmt.Errorf("query events: %w", err)
+	}
+	var events []repairEvent
+	for rows.Next() {
+		var ev repairEvent
+		if err := rows.Scan(&ev.id, &ev.direction, &ev.threadID, &ev.metadata); err != nil {
+			rows.Close()
+			return false, "", fmt.Errorf("scan event: %w", err)
+		}
+		events = append(events, ev)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, "", fmt.Errorf("iterate events: %w", err)
+	}
+	if len(events) == 0 {
+		return true, "", nil
+	}
+	stats.Batches++
+
+	tx, err := db.Begin()
+	if err != nil {
+		return false, "", fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	threadCache := make(map[string][]string)
+	for _, ev := range events {
+		stats.EventsScanned++
+
+		participants, err := participantsFromMetadata(tx, ev, opts.DryRun)
+		if err != nil {
+			return false, "", err
+		}
+		if len(participants) == 0 && ev.threadID.Valid {
+			threadContacts, ok := threadCache[ev.threadID.String]
+			if !ok {
+				threadContacts, err = loadThreadContacts(tx, ev.threadID.String)
+				if err != nil {
+					return false, "", err
+				}
+				threadCache[ev.threadID.String] = threadContacts
+			}
+			participants = participantsFromThread(ev.direction, threadContacts, meContacts)
+		}
+		if len(participants) == 0 {
+			stats.EventsUnresolved++
+			continue
+		}
+
+		added := 0
+		for _, p := range participants {
+			if opts.DryRun {
+				added++
+				continue
+			}
+			res, err := tx.Exec(`
+				INSERT INTO event_participants (event_id, contact_id, role)
+				VALUES (?, ?, ?)
+				ON CONFLICT(event_id, contact_id, role) DO NOTHING
+			`, ev.id, p.contactID, p.role)
+			if err != nil {
+				return false, "", fmt.Errorf("insert participant: %w", err)
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				added++
+			}
+		}
+		if added > 0 {
+			stats.EventsRepaired++
+			stats.ParticipantsAdded += added
+		}
+	}
+
+	if opts.DryRun {
+		return false, events[len(events)-1].id, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return false, "", fmt.Errorf("commit transaction: %w", err)
+	}
+	return false, events[len(events)-1].id, nil
+}
+
+// metadataParticipantKeys maps metadata_json keys to participant roles.
+var metadataParticipantKeys = map[string]string{
+	"from":         "sender",
+	"sender":       "sender",
+	"to":           "recipient",
+	"recipient":    "recipient",
+	"recipients":   "recipient",
+	"cc":           "cc",
+	"participants": "observer",
+}
+
+// participantsFromMetadata recovers participants from sender/recipient fields
+// in metadata_json. Addresses are resolved via contact_identifiers; unknown
+// addresses get a new contact (except in dry runs, where they are only counted).
+func participantsFromMetadata(tx *sql.Tx, ev repairEvent, dryRun bool) ([]participantCandidate, error) {
+	if !ev.metadata.Valid || strings.TrimSpace(ev.metadata.String) == "" {
+		return nil, nil
+	}
+	var meta map[string]interface{}
+	if err := json.Unmarshal([]byte(ev.metadata.String), &meta); err != nil {
+		return nil, nil // Not JSON - nothing to recover
+	}
+
+	var out []participantCandidate
+	addAddresses := func(value interface{}, role string) error {
+		for _, addr := range metadataAddresses(value) {
+			contactID, err := resolveParticipantContact(tx, addr.value, addr.name, dryRun)
+			if err != nil {
+				return err
+			}
+			if contactID != "" {
+				out = append(out, participantCandidate{contactID: contactID, role: role})
+			}
+		}
+		return nil
+	}
+	for key, role := range metadataParticipantKeys {
+		value, ok := meta[key]
+		if !ok {
+			continue
+		}
+		if err := addAddresses(value, role); err != nil {
+			return nil, err
+		}
+	}
+	// A bare handle is the other party: the sender of received messages, the
+	// recipient of sent ones.
+	if value, ok := meta["handle"]; ok {
+		role := "sender"
+		if ev.direction == "sent" {
+			role = "recipient"
+		}
+		if err := addAddresses(value, role); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+type metadataAddress struct {
+	value string
+	name  string
+}
+
+// metadataAddresses flattens a metadata value (string, address list, or array)
+// into identifiers.
+func metadataAddresses(value interface{}) []metadataAddress {
+	switch v := value.(type) {
+	case string:
+		if list, err := mail.ParseAddressList(v); err == nil {
+			out := make([]metadataAddress, 0, len(list))
+			for _, a := range list {
+				out = append(out, metadataAddress{value: a.Address, name: a.Name})
+			}
+			return out
+		}
+		var out []metadataAddress
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, metadataAddress{value: part})
+			}
+		}
+		return out
+	case []interface{}:
+		var out []metadataAddress
+		for _, item := range v {
+			out = append(out, metadataAddresses(item)...)
+		}
+		return out
+	}
+	return nil
+}
+
+// resolveParticipantContact finds (or creates) the contact for an identifier.
+func resolveParticipantContact(tx *sql.Tx, value, name string, dryRun bool) (string, error) {
+	idType := contacts.GuessIdentifierType(value)
+	if idType == "" {
+		return "", nil
+	}
+	normalized := contacts.NormalizeIdentifier(value, idType)
+
+	var contactID string
+	err := tx.QueryRow(`
+		SELECT contact_id FROM contact_identifiers WHERE type = ? AND normalized = ?
+	`, idType, normalized).Scan(&contactID)
+	if err == nil {
+		return contactID, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", fmt.Errorf("lookup contact identifier: %w", err)
+	}
+	if dryRun {
+		return "new:" + idType + ":" + normalized, nil
+	}
+
+	contactID, _, err = contacts.GetOrCreateContact(tx, idType, value, name, "repair")
+	if err != nil {
+		return "", err
+	}
+	if _, _, err := contacts.EnsurePersonForContact(tx, contactID, name, "repair", 0.7); err != nil {
+		return "", err
+	}
+	return contactID, nil
+}
+
+// loadThreadContacts returns the distinct contacts participating in a thread.
+func loadThreadContacts(tx *sql.Tx, threadID string) ([]string, error) {
+	rows, err := tx.Query(`
+		SELECT DISTINCT ep.contact_id
+		FROM events e
+		JOIN event_participants ep ON ep.event_id = e.id
+		WHERE e.thread_id = ?
+		ORDER BY ep.contact_id
+	`, threadID)
+	if err != nil {
+		return nil, fmt.Errorf("query thread contacts: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan thread contact: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// participantsFromThread infers participants from the rest of the thread:
+// sent events are from me to everyone else; received events in a 1:1 thread
+// are from the other person to me. The sender of a received group message
+// can't be inferred, so nothing is added and the event is counted as
+// unresolved rather than getting me as recipient without a sender.
+func participantsFromThread(direction string, threadContacts []string, meContacts map[string]bool) []participantCandidate {
+	var me string
+	var others []string
+	for _, id := range threadContacts {
+		if meContacts[id] {
+			if me == "" {
+				me = id
+			}
+			continue
+		}
+		others = append(others, id)
+	}
+
+	var out []participantCandidate
+	switch direction {
+	case "sent":
+		if me != "" {
+			out = append(out, participantCandidate{contactID: me, role: "sender"})
+		}
+		for _, id := range others {
+			out = append(out, participantCandidate{contactID: id, role: "recipient"})
+		}
+	case "received":
+		if len(others) == 1 {
+			out = append(out, participantCandidate{contactID: others[0], role: "sender"})
+		}
+		if me != "" && len(out) > 0 {
+			out = append(out, participantCandidate{contactID: me, role: "recipient"})
+		}
+	}
+	return out
+}
+
+// loadMeContacts returns contacts linked to the "me" person.
+func loadMeContacts(db *sql.DB) (map[string]bool, error) {
+	rows, err := db.Query(`
+		SELECT pcl.contact_id
+		FROM person_contact_links pcl
+		JOIN persons p ON p.id = pcl.person_id
+		WHERE p.is_me = 1
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query me contacts: %w", err)
+	}
+	defer rows.Close()
+
+	me := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan me contact: %w", err)
+		}
+		me[id] = true
+	}
+	return me, rows.Err()
+}
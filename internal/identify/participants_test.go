@@ -0,0 +1,125 @@
+package identify
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func insertRepairEvent(t *testing.T, db *sql.DB, id, direction, threadID, metadata string) {
+	t.Helper()
+	var meta interface{}
+	if metadata != "" {
+		meta = metadata
+	}
+	if _, err := db.Exec(`
+		INSERT INTO events (id, timestamp, channel, content_types, content, direction, thread_id, source_adapter, source_id, metadata_json)
+		VALUES (?, 1700000000, 'imessage', '["text"]', 'hi', ?, ?, 'imessage', ?, ?)
+	`, id, direction, threadID, id, meta); err != nil {
+		t.Fatalf("insert event %s: %v", id, err)
+	}
+}
+
+func TestRepairParticipants(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+
+	// Me and a 1:1 thread with Casey that has some participants already
+	if _, err := db.Exec(`INSERT INTO persons (id, canonical_name, is_me, created_at, updated_at) VALUES ('me', 'Me', 1, 0, 0)`); err != nil {
+		t.Fatalf("insert me: %v", err)
+	}
+	meContact, _, err := contacts.GetOrCreateContact(db, "phone", "+1 (555) 000-0000", "", "test")
+	if err != nil {
+		t.Fatalf("create me contact: %v", err)
+	}
+	if err := contacts.EnsurePersonContactLink(db, "me", meContact, "test", 1.0); err != nil {
+		t.Fatalf("link me: %v", err)
+	}
+	casey, _, err := contacts.GetOrCreateContact(db, "phone", "+1 555 123 4567", "", "test")
+	if err != nil {
+		t.Fatalf("create casey contact: %v", err)
+	}
+
+	// Casey has a legacy identity but no person link
+	if _, err := db.Exec(`INSERT INTO persons (id, canonical_name, created_at, updated_at) VALUES ('casey', 'Casey Jones', 0, 0)`); err != nil {
+		t.Fatalf("insert casey: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO identities (id, person_id, channel, identifier, created_at) VALUES ('i1', 'casey', 'phone', '5551234567', 0)`); err != nil {
+		t.Fatalf("insert identity: %v", err)
+	}
+
+	insertRepairEvent(t, db, "e1", "received", "t1", "")
+	db.Exec(`INSERT INTO event_participants (event_id, contact_id, role) VALUES ('e1', ?, 'sender'), ('e1', ?, 'recipient')`, casey, meContact)
+
+	// Missing participants: inferable from the thread, from metadata, or not at all
+	insertRepairEvent(t, db, "e2", "sent", "t1", "")
+	insertRepairEvent(t, db, "e3", "received", "t1", "")
+	insertRepairEvent(t, db, "e4", "received", "t2", `{"from": "Riley Smith <riley@example.com>"}`)
+	insertRepairEvent(t, db, "e5", "received", "t3", "")
+
+	dry, err := RepairParticipants(db, ParticipantRepairOptions{BatchSize: 2, DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if dry.EventsRepaired != 3 || dry.ContactsLinked != 1 {
+		t.Errorf("dry run = %+v, want 3 events repaired and 1 contact linked", dry)
+	}
+	var count int
+	db.QueryRow(`SELECT COUNT(*) FROM event_participants`).Scan(&count)
+	if count != 2 {
+		t.Fatalf("dry run wrote participants: %d rows", count)
+	}
+
+	stats, err := RepairParticipants(db, ParticipantRepairOptions{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("RepairParticipants: %v", err)
+	}
+	if stats.ContactsLinked != 1 {
+		t.Errorf("ContactsLinked = %d, want 1", stats.ContactsLinked)
+	}
+	if stats.EventsScanned != 4 || stats.EventsRepaired != 3 || stats.EventsUnresolved != 1 {
+		t.Errorf("stats = %+v, want 4 scanned, 3 repaired, 1 unresolved", stats)
+	}
+	if stats.Batches != 2 {
+		t.Errorf("Batches = %d, want 2", stats.Batches)
+	}
+
+	if personID, _ := contacts.GetLinkedPersonID(db, casey); personID != "casey" {
+		t.Errorf("casey contact linked to %q, want casey", personID)
+	}
+
+	var sender string
+	db.QueryRow(`SELECT contact_id FROM event_participants WHERE event_id = 'e2' AND role = 'sender'`).Scan(&sender)
+	if sender != meContact {
+		t.Errorf("e2 sender = %q, want me", sender)
+	}
+	db.QueryRow(`SELECT contact_id FROM event_participants WHERE event_id = 'e3' AND role = 'sender'`).Scan(&sender)
+	if sender != casey {
+		t.Errorf("e3 sender = %q, want casey", sender)
+	}
+
+	var rileyName string
+	if err := db.QueryRow(`
+		SELECT p.canonical_name
+		FROM event_participants ep
+		JOIN person_contact_links pcl ON pcl.contact_id = ep.contact_id
+		JOIN persons p ON p.id = pcl.person_id
+		WHERE ep.event_id = 'e4' AND ep.role = 'sender'
+	`).Scan(&rileyName); err != nil {
+		t.Fatalf("e4 sender: %v", err)
+	}
+	if rileyName != "Riley Smith" {
+		t.Errorf("e4 sender person = %q, want Riley Smith", rileyName)
+	}
+
+	// Re-running only revisits the unresolved event
+	again, err := RepairParticipants(db, ParticipantRepairOptions{})
+	if err != nil {
+		t.Fatalf("RepairParticipants again: %v", err)
+	}
+	if again.EventsScanned != 1 || again.ParticipantsAdded != 0 || again.ContactsLinked != 0 {
+		t.Errorf("second run = %+v, want only the unresolved event", again)
+	}
+}
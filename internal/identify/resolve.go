@@ -38,6 +38,7 @@ type ResolutionResult struct {
 	SoftAccumulations   int
 	MergeSuggestionsCreated int
 	AutoMergesExecuted  int
+	FactsAttributed     int
 	Errors              int
 }
 
@@ -515,11 +516,11 @@ func hasConflictingFacts(db *sql.DB, person1ID, person2ID string) (bool, error)
 		var val1, val2 sql.NullString
 		db.QueryRow(`
 			SELECT fact_value FROM person_facts 
-			WHERE person_id = ? AND fact_type = ?
+			WHERE person_id = ? AND fact_type = ? AND superseded_at IS NULL
 		`, person1ID, factType).Scan(&val1)
 		db.QueryRow(`
 			SELECT fact_value FROM person_facts 
-			WHERE person_id = ? AND fact_type = ?
+			WHERE person_id = ? AND fact_type = ? AND superseded_at IS NULL
 		`, person2ID, factType).Scan(&val2)
 
 		if val1.Valid && val2.Valid && val1.String != val2.String {
@@ -547,6 +548,13 @@ func RunFullResolution(db *sql.DB, autoMerge bool, includeSoft bool, tier1Only b
 		result.AutoMergesExecuted = executed
 	}
 
+	// Merges and new facts may make unattributed facts attributable
+	attributed, err := ResolveUnattributedFacts(db, false)
+	if err != nil {
+		return nil, fmt.Errorf("resolve unattributed facts: %w", err)
+	}
+	result.FactsAttributed = attributed.Resolved
+
 	return result, nil
 }
 
@@ -635,7 +643,7 @@ func GetResolutionStats(db *sql.DB) (*ResolutionStats, error) {
 	db.QueryRow(`SELECT COUNT(*) FROM person_facts WHERE is_hard_identifier = 1`).Scan(&stats.HardIdentifiers)
 	db.QueryRow(`SELECT COUNT(*) FROM merge_events WHERE status = 'pending'`).Scan(&stats.PendingMerges)
 	db.QueryRow(`SELECT COUNT(*) FROM merge_events WHERE status = 'pending' AND auto_eligible = 1`).Scan(&stats.AutoEligibleMerges)
-	db.QueryRow(`SELECT COUNT(*) FROM unattributed_facts WHERE resolved_to_person_id IS NULL`).Scan(&stats.UnresolvedFacts)
+	db.QueryRow(`SELECT COUNT(*) FROM unattributed_facts WHERE resolved_to_person_id IS NULL AND discarded_at IS NULL`).Scan(&stats.UnresolvedFacts)
 
 	// Cross-channel linkage: persons with facts from multiple channels
 	db.QueryRow(`
@@ -3,11 +3,13 @@ package identify
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/me"
 	"github.com/google/uuid"
 )
 
@@ -41,6 +43,9 @@ var FacetToFactMapping = map[string]struct {
 	"pii_ssn":               {CategoryGovernmentID, FactTypeSSN},
 	"pii_passport_number":   {CategoryGovernmentID, FactTypePassportNumber},
 	"pii_drivers_license":   {CategoryGovernmentID, FactTypeDriversLicense},
+	"pii_allergy":           {CategoryMedical, FactTypeAllergy},
+	"pii_medication":        {CategoryMedical, FactTypeMedication},
+	"pii_medical_condition": {CategoryMedical, FactTypeMedicalCondition},
 }
 
 // SyncStats holds statistics about a sync operation
@@ -55,19 +60,21 @@ type SyncStats struct {
 }
 
 // SyncFacetsToPersonFacts processes facets from pii_extraction analysis runs
-// and creates/updates person_facts entries
+// and creates/updates person_facts entries. Each run is synced once: it is
+// marked with facts_synced_at so later syncs don't count its facts again.
 func SyncFacetsToPersonFacts(db *sql.DB) (*SyncStats, error) {
 	stats := &SyncStats{}
 
-	// Get completed pii_extraction analysis runs with JSON output
+	// Get completed, unsynced pii_extraction analysis runs with JSON output
 	rows, err := db.Query(`
-		SELECT DISTINCT ar.id, ar.segment_id
+		SELECT DISTINCT ar.id, ar.episode_id
 		FROM analysis_runs ar
 		JOIN analysis_types at ON ar.analysis_type_id = at.id
 		WHERE at.name = 'pii_extraction'
 		AND ar.status = 'completed'
 		AND ar.output_text IS NOT NULL
 		AND ar.output_text != ''
+		AND ar.facts_synced_at IS NULL
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("query analysis runs: %w", err)
@@ -99,6 +106,9 @@ func SyncFacetsToPersonFacts(db *sql.DB) (*SyncStats, error) {
 			stats.Errors++
 			continue
 		}
+		if err := markRunSynced(db, run.ID); err != nil {
+			stats.Errors++
+		}
 		stats.AnalysisRunsProcessed++
 		stats.FacetsProcessed += runStats.FacetsProcessed
 		stats.FactsCreated += runStats.FactsCreated
@@ -117,10 +127,10 @@ func syncAnalysisRun(db *sql.DB, runID, segmentID string) (*SyncStats, error) {
 	// Get the channel for this segment (for source_channel)
 	var channel sql.NullString
 	err := db.QueryRow(`
-		SELECT channel FROM segments WHERE id = ?
+		SELECT channel FROM episodes WHERE id = ?
 	`, segmentID).Scan(&channel)
 	if err != nil {
-		return nil, fmt.Errorf("get segment channel: %w", err)
+		return nil, fmt.Errorf("get episode channel: %w", err)
 	}
 
 	// Prefer parsing full JSON output for attribution-aware facts.
@@ -206,12 +216,16 @@ func syncAnalysisRun(db *sql.DB, runID, segmentID string) (*SyncStats, error) {
 			}
 		}
 
-		// If no person_id, this is an unattributed fact
+		// If no person_id, this is an unattributed fact. Medical facts are
+		// never kept unattributed: they can't be shown to be self-disclosed
 		if !personID.Valid || personID.String == "" {
+			if IsMedicalFact(mapping.Category, mapping.FactType) {
+				continue
+			}
 			// Insert into unattributed_facts
 			_, err := db.Exec(`
 				INSERT INTO unattributed_facts (
-					id, fact_type, fact_value, source_segment_id, context, created_at
+					id, fact_type, fact_value, source_episode_id, context, created_at
 				) VALUES (?, ?, ?, ?, ?, ?)
 				ON CONFLICT DO NOTHING
 			`, uuid.New().String(), mapping.FactType, value, segmentID, metadataJSON.String, now)
@@ -245,6 +259,9 @@ func syncAnalysisRun(db *sql.DB, runID, segmentID string) (*SyncStats, error) {
 		fact.IsSensitive = isSensitiveFactType(mapping.FactType)
 
 		err := InsertFact(db, fact)
+		if errors.Is(err, ErrFactNotAdmitted) {
+			continue
+		}
 		if err != nil {
 			stats.Errors++
 		} else {
@@ -258,19 +275,35 @@ func syncAnalysisRun(db *sql.DB, runID, segmentID string) (*SyncStats, error) {
 // SyncSingleRun processes a single analysis run by ID
 func SyncSingleRun(db *sql.DB, runID string) (*SyncStats, error) {
 	var segmentID string
-	err := db.QueryRow(`SELECT segment_id FROM analysis_runs WHERE id = ?`, runID).Scan(&segmentID)
+	err := db.QueryRow(`SELECT episode_id FROM analysis_runs WHERE id = ?`, runID).Scan(&segmentID)
 	if err != nil {
 		return nil, fmt.Errorf("get analysis run: %w", err)
 	}
-	return syncAnalysisRun(db, runID, segmentID)
+	stats, err := syncAnalysisRun(db, runID, segmentID)
+	if err != nil {
+		return nil, err
+	}
+	return stats, markRunSynced(db, runID)
+}
+
+// markRunSynced records that a run's output is in person_facts.
+func markRunSynced(db *sql.DB, runID string) error {
+	_, err := db.Exec(`UPDATE analysis_runs SET facts_synced_at = ? WHERE id = ?`, time.Now().Unix(), runID)
+	if err != nil {
+		return fmt.Errorf("mark run synced: %w", err)
+	}
+	return nil
 }
 
 // isSensitiveFactType determines if a fact type should be marked as sensitive
 func isSensitiveFactType(factType string) bool {
 	sensitiveTypes := map[string]bool{
-		FactTypeSSN:            true,
-		FactTypePassportNumber: true,
-		FactTypeDriversLicense: true,
+		FactTypeSSN:              true,
+		FactTypePassportNumber:   true,
+		FactTypeDriversLicense:   true,
+		FactTypeAllergy:          true,
+		FactTypeMedication:       true,
+		FactTypeMedicalCondition: true,
 	}
 	return sensitiveTypes[factType]
 }
@@ -461,7 +494,7 @@ func ProcessPIIExtractionOutput(db *sql.DB, runID, segmentID, outputJSON string)
 
 		// Process unattributed facts
 		for _, uf := range output.UnattributedFacts {
-			if uf.FactValue == "" {
+			if uf.FactValue == "" || IsMedicalFact("", mapFactKey(uf.FactType)) {
 				continue
 			}
 
@@ -479,7 +512,7 @@ func ProcessPIIExtractionOutput(db *sql.DB, runID, segmentID, outputJSON string)
 			_, err := db.Exec(`
 				INSERT INTO unattributed_facts (
 					id, fact_type, fact_value, shared_by_person_id,
-					source_segment_id, context, possible_attributions, created_at
+					source_episode_id, context, possible_attributions, created_at
 				) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 				ON CONFLICT DO NOTHING
 			`, uuid.New().String(), uf.FactType, uf.FactValue, sharedByPersonID,
@@ -506,8 +539,8 @@ func loadSegmentParticipants(db *sql.DB, segmentID string) ([]segmentParticipant
 		FROM persons p
 		JOIN person_contact_links pcl ON p.id = pcl.person_id
 		JOIN event_participants ep ON pcl.contact_id = ep.contact_id
-		JOIN segment_events se ON se.event_id = ep.event_id
-		WHERE se.segment_id = ?
+		JOIN episode_events ee ON ee.event_id = ep.event_id
+		WHERE ee.episode_id = ?
 	`, segmentID)
 	if err != nil {
 		return nil, err
@@ -529,7 +562,7 @@ func loadSegmentParticipants(db *sql.DB, segmentID string) ([]segmentParticipant
 
 func getSegmentChannel(db *sql.DB, segmentID string) string {
 	var channel sql.NullString
-	_ = db.QueryRow(`SELECT channel FROM segments WHERE id = ?`, segmentID).Scan(&channel)
+	_ = db.QueryRow(`SELECT channel FROM episodes WHERE id = ?`, segmentID).Scan(&channel)
 	if channel.Valid {
 		return channel.String
 	}
@@ -537,12 +570,7 @@ func getSegmentChannel(db *sql.DB, segmentID string) string {
 }
 
 func getMePersonID(db *sql.DB) (string, error) {
-	var id string
-	err := db.QueryRow(`SELECT id FROM persons WHERE is_me = 1 LIMIT 1`).Scan(&id)
-	if err != nil {
-		return "", err
-	}
-	return id, nil
+	return me.LookupMePersonID(db)
 }
 
 func matchParticipantByName(participants []segmentParticipant, reference string) (string, bool) {
@@ -682,6 +710,9 @@ func mapFactKey(key string) string {
 		"ssn":              FactTypeSSN,
 		"passport_number":  FactTypePassportNumber,
 		"drivers_license":  FactTypeDriversLicense,
+		"allergies":        FactTypeAllergy,
+		"medications":      FactTypeMedication,
+		"conditions":       FactTypeMedicalCondition,
 	}
 	if mapped, ok := keyMap[key]; ok {
 		return mapped
@@ -755,7 +786,7 @@ func insertCandidateMention(db *sql.DB, reference string, knownFacts map[string]
 	factsJSON, _ := json.Marshal(knownFacts)
 	_, err := db.Exec(`
 		INSERT INTO candidate_mentions (
-			id, reference, known_facts_json, source_segment_id, created_at
+			id, reference, known_facts_json, source_episode_id, created_at
 		) VALUES (?, ?, ?, ?, ?)
 	`, uuid.New().String(), reference, string(factsJSON), segmentID, now)
 	return err
@@ -767,7 +798,7 @@ func ensureCandidateMentionsTable(db *sql.DB) error {
 			id TEXT PRIMARY KEY,
 			reference TEXT NOT NULL,
 			known_facts_json TEXT,
-			source_segment_id TEXT,
+			source_episode_id TEXT,
 			created_at INTEGER NOT NULL
 		)
 	`)
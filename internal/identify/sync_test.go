@@ -2,6 +2,8 @@ package identify
 
 import (
 	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
 )
 
 func TestFacetToFactMapping(t *testing.T) {
@@ -101,3 +103,46 @@ func TestMapCategory(t *testing.T) {
 		}
 	}
 }
+
+func TestSyncFacetsToPersonFacts_SyncsEachRunOnce(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+
+	stmts := []string{
+		`INSERT INTO persons (id, canonical_name, is_me, created_at, updated_at) VALUES ('me', 'Me', 1, 0, 0)`,
+		`INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0)`,
+		`INSERT INTO episodes (id, definition_id, channel, start_time, end_time, event_count, created_at) VALUES ('ep1', 'def', 'imessage', 0, 0, 1, 0)`,
+		`INSERT INTO analysis_types (id, name, version, output_type, prompt_template, created_at, updated_at) VALUES ('pii', 'pii_extraction', '1.0.0', 'structured', '', 0, 0)`,
+		`INSERT INTO analysis_runs (id, analysis_type_id, episode_id, status, output_text, created_at) VALUES ('run1', 'pii', 'ep1', 'completed',
+			'{"facts": [{"subject_kind": "user", "category": "professional", "fact_type": "employer_current", "value": "Acme", "confidence": "high", "source": "self_disclosed"}]}', 0)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("setup: %v", err)
+		}
+	}
+
+	stats, err := SyncFacetsToPersonFacts(db)
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if stats.AnalysisRunsProcessed != 1 || stats.FactsCreated != 1 {
+		t.Fatalf("first sync = %+v, want 1 run and 1 fact", stats)
+	}
+
+	stats, err = SyncFacetsToPersonFacts(db)
+	if err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	if stats.AnalysisRunsProcessed != 0 {
+		t.Errorf("second sync processed %d runs, want 0", stats.AnalysisRunsProcessed)
+	}
+
+	facts, err := GetFactsForPerson(db, "me")
+	if err != nil || len(facts) != 1 {
+		t.Fatalf("facts = %+v, %v; want 1", facts, err)
+	}
+	if facts[0].SeenCount != 1 || facts[0].SourceChannel == nil || *facts[0].SourceChannel != "imessage" {
+		t.Errorf("fact = %+v, want seen once on imessage", facts[0])
+	}
+}
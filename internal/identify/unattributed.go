@@ -0,0 +1,456 @@
+package identify
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/contacts"
+)
+
+// Unattributed fact statuses
+const (
+	UnattributedPending   = "pending"
+	UnattributedResolved  = "resolved"
+	UnattributedDiscarded = "discarded"
+)
+
+// UnattributedFact is a fact extracted from an episode without a clear owner,
+// e.g. a phone number shared with no context about whose it is.
+type UnattributedFact struct {
+	ID                   string     `json:"id"`
+	FactType             string     `json:"fact_type"`
+	FactValue            string     `json:"fact_value"`
+	SharedByPersonID     *string    `json:"shared_by_person_id,omitempty"`
+	SharedByName         *string    `json:"shared_by_name,omitempty"`
+	SourceEpisodeID      *string    `json:"source_episode_id,omitempty"`
+	Context              *string    `json:"context,omitempty"`
+	PossibleAttributions []string   `json:"possible_attributions,omitempty"`
+	ResolvedToPersonID   *string    `json:"resolved_to_person_id,omitempty"`
+	ResolvedToName       *string    `json:"resolved_to_name,omitempty"`
+	ResolutionEvidence   *string    `json:"resolution_evidence,omitempty"`
+	Status               string     `json:"status"`
+	CreatedAt            time.Time  `json:"created_at"`
+	ResolvedAt           *time.Time `json:"resolved_at,omitempty"`
+}
+
+// UnattributedResolution records one fact the resolver attributed (or would
+// attribute, in a dry run).
+type UnattributedResolution struct {
+	FactID     string `json:"fact_id"`
+	FactType   string `json:"fact_type"`
+	FactValue  string `json:"fact_value"`
+	PersonID   string `json:"person_id"`
+	PersonName string `json:"person_name"`
+	Evidence   string `json:"evidence"`
+}
+
+// UnattributedResolveStats summarizes a resolver pass.
+type UnattributedResolveStats struct {
+	Checked     int
+	Resolved    int
+	Ambiguous   int
+	Resolutions []UnattributedResolution
+}
+
+// nameFactTypes are person facts treated as names when matching possible
+// attributions, so aliases learned after extraction still count.
+var nameFactTypes = []string{FactTypeFullLegalName, FactTypeGivenName, "nickname"}
+
+// ListUnattributedFacts returns unattributed facts with the given status
+// (pending, resolved, discarded, or "" for all), newest first.
+func ListUnattributedFacts(db *sql.DB, status string, limit int) ([]UnattributedFact, error) {
+	query := `
+		SELECT uf.id, uf.fact_type, uf.fact_value, uf.shared_by_person_id, sp.canonical_name,
+			uf.source_episode_id, uf.context, uf.possible_attributions,
+			uf.resolved_to_person_id, rp.canonical_name, uf.resolution_evidence,
+			uf.created_at, uf.resolved_at, uf.discarded_at
+		FROM unattributed_facts uf
+		LEFT JOIN persons sp ON uf.shared_by_person_id = sp.id
+		LEFT JOIN persons rp ON uf.resolved_to_person_id = rp.id
+	`
+	switch status {
+	case "":
+	case UnattributedPending:
+		query += ` WHERE uf.resolved_to_person_id IS NULL AND uf.discarded_at IS NULL`
+	case UnattributedResolved:
+		query += ` WHERE uf.resolved_to_person_id IS NOT NULL`
+	case UnattributedDiscarded:
+		query += ` WHERE uf.discarded_at IS NOT NULL`
+	default:
+		return nil, fmt.Errorf("unknown status %q", status)
+	}
+	query += ` ORDER BY uf.created_at DESC, uf.id`
+	var args []interface{}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query unattributed facts: %w", err)
+	}
+	defer rows.Close()
+
+	var facts []UnattributedFact
+	for rows.Next() {
+		f, err := scanUnattributedFact(rows)
+		if err != nil {
+			return nil, err
+		}
+		facts = append(facts, f)
+	}
+	return facts, rows.Err()
+}
+
+// IsSensitiveUnattributedFact reports whether an unattributed fact of this
+// type becomes a sensitive person fact once attributed.
+func IsSensitiveUnattributedFact(factType string) bool {
+	return IsSensitiveFact("", mapFactKey(factType))
+}
+
+// GetUnattributedFact looks up a fact by ID or unique ID prefix.
+func GetUnattributedFact(db *sql.DB, ref string) (*UnattributedFact, error) {
+	rows, err := db.Query(`
+		SELECT uf.id, uf.fact_type, uf.fact_value, uf.shared_by_person_id, sp.canonical_name,
+			uf.source_episode_id, uf.context, uf.possible_attributions,
+			uf.resolved_to_person_id, rp.canonical_name, uf.resolution_evidence,
+			uf.created_at, uf.resolved_at, uf.discarded_at
+		FROM unattributed_facts uf
+		LEFT JOIN persons sp ON uf.shared_by_person_id = sp.id
+		LEFT JOIN persons rp ON uf.resolved_to_person_id = rp.id
+		WHERE uf.id = ? OR uf.id LIKE ?
+		ORDER BY uf.id = ? DESC
+		LIMIT 2
+	`, ref, ref+"%", ref)
+	if err != nil {
+		return nil, fmt.Errorf("query unattributed fact: %w", err)
+	}
+	defer rows.Close()
+
+	var facts []UnattributedFact
+	for rows.Next() {
+		f, err := scanUnattributedFact(rows)
+		if err != nil {
+			return nil, err
+		}
+		facts = append(facts, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	switch {
+	case len(facts) == 0:
+		return nil, fmt.Errorf("unattributed fact not found: %s", ref)
+	case len(facts) > 1 && facts[0].ID != ref:
+		return nil, fmt.Errorf("ambiguous unattributed fact prefix: %s", ref)
+	}
+	return &facts[0], nil
+}
+
+// AttributeFact resolves an unattributed fact to a person and records it as
+// a person fact so identity resolution can use it.
+func AttributeFact(db *sql.DB, factID, personID, evidence string) error {
+	fact, err := GetUnattributedFact(db, factID)
+	if err != nil {
+		return err
+	}
+	return attributeFact(db, fact, personID, evidence)
+}
+
+// DiscardUnattributedFact removes a fact from the review queue. Discarded
+// facts are never re-attempted by the resolver.
+func DiscardUnattributedFact(db *sql.DB, factID, reason string) error {
+	var evidence *string
+	if reason != "" {
+		evidence = &reason
+	}
+	res, err := db.Exec(`
+		UPDATE unattributed_facts
+		SET discarded_at = ?, resolution_evidence = COALESCE(?, resolution_evidence)
+		WHERE id = ? AND resolved_to_person_id IS NULL
+	`, time.Now().Unix(), evidence, factID)
+	if err != nil {
+		return fmt.Errorf("discard unattributed fact: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("unattributed fact %s not found or already resolved", factID)
+	}
+	return nil
+}
+
+// ResolveUnattributedFacts re-attempts attribution of every pending fact
+// against the current person graph. A fact is attributed only when exactly
+// one person matches: first by identifier (a contact identifier or person
+// fact with the same value), then by its possible attributions (a person
+// whose name, display name, or name fact matches). Run it whenever the graph
+// grows, e.g. after merges or new third parties.
+func ResolveUnattributedFacts(db *sql.DB, dryRun bool) (*UnattributedResolveStats, error) {
+	facts, err := ListUnattributedFacts(db, UnattributedPending, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &UnattributedResolveStats{}
+	for i := range facts {
+		fact := &facts[i]
+		stats.Checked++
+
+		personID, evidence, ambiguous, err := matchUnattributedFact(db, fact)
+		if err != nil {
+			return nil, err
+		}
+		if ambiguous {
+			stats.Ambiguous++
+			continue
+		}
+		if personID == "" {
+			continue
+		}
+
+		if !dryRun {
+			if err := attributeFact(db, fact, personID, evidence); err != nil {
+				return nil, err
+			}
+		}
+		var name string
+		_ = db.QueryRow(`SELECT canonical_name FROM persons WHERE id = ?`, personID).Scan(&name)
+		stats.Resolved++
+		stats.Resolutions = append(stats.Resolutions, UnattributedResolution{
+			FactID:     fact.ID,
+			FactType:   fact.FactType,
+			FactValue:  fact.FactValue,
+			PersonID:   personID,
+			PersonName: name,
+			Evidence:   evidence,
+		})
+	}
+	return stats, nil
+}
+
+// matchUnattributedFact finds the single person a fact belongs to. It
+// returns ambiguous when the strongest available evidence points at more
+// than one person.
+func matchUnattributedFact(db *sql.DB, fact *UnattributedFact) (string, string, bool, error) {
+	// Identifier evidence: someone already has this value on file
+	matches := map[string]string{}
+	if kind := identifierKind(fact.FactType, fact.FactValue); kind != "" {
+		normalized := contacts.NormalizeIdentifier(fact.FactValue, kind)
+		ids, err := queryPersonIDs(db, `
+			SELECT DISTINCT pcl.person_id
+			FROM contact_identifiers ci
+			JOIN person_contact_links pcl ON pcl.contact_id = ci.contact_id
+			JOIN persons p ON p.id = pcl.person_id
+			WHERE ci.normalized = ? AND p.canonical_name NOT LIKE '%[MERGED%'
+		`, normalized)
+		if err != nil {
+			return "", "", false, err
+		}
+		for _, id := range ids {
+			matches[id] = fmt.Sprintf("matches contact identifier %s", normalized)
+		}
+	}
+	rows, err := db.Query(`
+		SELECT DISTINCT pf.person_id, pf.fact_type
+		FROM person_facts pf
+		JOIN persons p ON p.id = pf.person_id
+		WHERE LOWER(pf.fact_value) = LOWER(?) AND pf.is_identifier = 1
+		  AND p.canonical_name NOT LIKE '%[MERGED%'
+	`, strings.TrimSpace(fact.FactValue))
+	if err != nil {
+		return "", "", false, fmt.Errorf("query matching person facts: %w", err)
+	}
+	for rows.Next() {
+		var personID, factType string
+		if err := rows.Scan(&personID, &factType); err != nil {
+			rows.Close()
+			return "", "", false, err
+		}
+		if _, ok := matches[personID]; !ok {
+			matches[personID] = fmt.Sprintf("matches %s on file", factType)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", "", false, err
+	}
+	if personID, evidence, ambiguous := singleMatch(matches); personID != "" || ambiguous {
+		return personID, evidence, ambiguous, nil
+	}
+
+	// Name evidence: one of the extractor's guesses now names a known person
+	for _, name := range fact.PossibleAttributions {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		ids, err := queryPersonIDs(db, `
+			SELECT id FROM persons
+			WHERE (LOWER(canonical_name) = LOWER(?) OR LOWER(display_name) = LOWER(?))
+			  AND canonical_name NOT LIKE '%[MERGED%'
+			UNION
+			SELECT pf.person_id FROM person_facts pf
+			JOIN persons p ON p.id = pf.person_id
+			WHERE pf.fact_type IN (?, ?, ?) AND LOWER(pf.fact_value) = LOWER(?)
+			  AND p.canonical_name NOT LIKE '%[MERGED%'
+		`, name, name, nameFactTypes[0], nameFactTypes[1], nameFactTypes[2], name)
+		if err != nil {
+			return "", "", false, err
+		}
+		for _, id := range ids {
+			if _, ok := matches[id]; !ok {
+				matches[id] = fmt.Sprintf("possible attribution %q matches a known person", name)
+			}
+		}
+	}
+	personID, evidence, ambiguous := singleMatch(matches)
+	return personID, evidence, ambiguous, nil
+}
+
+// singleMatch returns the only person in matches, or ambiguous if several.
+func singleMatch(matches map[string]string) (string, string, bool) {
+	if len(matches) > 1 {
+		return "", "", true
+	}
+	for id, evidence := range matches {
+		return id, evidence, false
+	}
+	return "", "", false
+}
+
+func queryPersonIDs(db *sql.DB, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query persons: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, rows.Err()
+}
+
+// identifierKind returns the contacts identifier type for a fact value, or
+// "" if it can't be a contact identifier.
+func identifierKind(factType, value string) string {
+	factType = mapFactKey(factType)
+	switch {
+	case strings.HasPrefix(factType, "email") || (strings.Contains(value, "@") && !strings.HasPrefix(value, "@")):
+		return "email"
+	case strings.HasPrefix(factType, "phone"):
+		return "phone"
+	case strings.HasPrefix(factType, "social_") || factType == FactTypeUsernameGeneric:
+		return "handle"
+	}
+	return ""
+}
+
+// categoryForFactType picks the person_facts category for a fact type.
+func categoryForFactType(factType string) string {
+	for _, mapping := range FacetToFactMapping {
+		if mapping.FactType == factType {
+			return mapping.Category
+		}
+	}
+	switch {
+	case strings.HasPrefix(factType, "email"), strings.HasPrefix(factType, "phone"):
+		return CategoryContactInfo
+	case strings.HasPrefix(factType, "social_"):
+		return CategoryDigitalIdentity
+	}
+	return CategoryCoreIdentity
+}
+
+func attributeFact(db *sql.DB, fact *UnattributedFact, personID, evidence string) error {
+	var exists int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM persons WHERE id = ?`, personID).Scan(&exists); err != nil {
+		return fmt.Errorf("check person: %w", err)
+	}
+	if exists == 0 {
+		return fmt.Errorf("person not found: %s", personID)
+	}
+
+	factType := mapFactKey(fact.FactType)
+	personFact := PersonFact{
+		PersonID:      personID,
+		Category:      categoryForFactType(factType),
+		FactType:      factType,
+		FactValue:     fact.FactValue,
+		Confidence:    0.5,
+		SourceType:    "mentioned",
+		SourceSegment: fact.SourceEpisodeID,
+		Evidence:      fact.Context,
+		IsSensitive:   isSensitiveFactType(factType),
+	}
+	if err := InsertFact(db, personFact); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`
+		UPDATE unattributed_facts
+		SET resolved_to_person_id = ?, resolution_evidence = ?, resolved_at = ?, discarded_at = NULL
+		WHERE id = ?
+	`, personID, evidence, time.Now().Unix(), fact.ID)
+	if err != nil {
+		return fmt.Errorf("resolve unattributed fact: %w", err)
+	}
+	return nil
+}
+
+func scanUnattributedFact(rows *sql.Rows) (UnattributedFact, error) {
+	var (
+		f                                                UnattributedFact
+		sharedByID, sharedByName, episodeID, context     sql.NullString
+		attributions, resolvedID, resolvedName, evidence sql.NullString
+		createdAt                                        int64
+		resolvedAt, discardedAt                          sql.NullInt64
+	)
+	if err := rows.Scan(&f.ID, &f.FactType, &f.FactValue, &sharedByID, &sharedByName,
+		&episodeID, &context, &attributions, &resolvedID, &resolvedName, &evidence,
+		&createdAt, &resolvedAt, &discardedAt); err != nil {
+		return f, fmt.Errorf("scan unattributed fact: %w", err)
+	}
+	f.SharedByPersonID = nullStringPtr(sharedByID)
+	f.SharedByName = nullStringPtr(sharedByName)
+	f.SourceEpisodeID = nullStringPtr(episodeID)
+	f.Context = nullStringPtr(context)
+	f.ResolvedToPersonID = nullStringPtr(resolvedID)
+	f.ResolvedToName = nullStringPtr(resolvedName)
+	f.ResolutionEvidence = nullStringPtr(evidence)
+	if attributions.Valid {
+		_ = json.Unmarshal([]byte(attributions.String), &f.PossibleAttributions)
+	}
+	f.CreatedAt = time.Unix(createdAt, 0)
+	if resolvedAt.Valid {
+		t := time.Unix(resolvedAt.Int64, 0)
+		f.ResolvedAt = &t
+	}
+
+	switch {
+	case resolvedID.Valid:
+		f.Status = UnattributedResolved
+	case discardedAt.Valid:
+		f.Status = UnattributedDiscarded
+	default:
+		f.Status = UnattributedPending
+	}
+	return f, nil
+}
+
+func nullStringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
@@ -0,0 +1,109 @@
+package identify
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func insertUnattributedFact(t *testing.T, db *sql.DB, id, factType, value, attributions string) {
+	t.Helper()
+	if _, err := db.Exec(`
+		INSERT INTO unattributed_facts (id, fact_type, fact_value, context, possible_attributions, created_at)
+		VALUES (?, ?, ?, 'shared in chat', ?, 1700000000)
+	`, id, factType, value, attributions); err != nil {
+		t.Fatalf("insert unattributed fact %s: %v", id, err)
+	}
+}
+
+func TestResolveUnattributedFacts(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+
+	for _, p := range [][2]string{{"casey", "Casey Jones"}, {"sam", "Sam Lee"}, {"sam2", "Sam Park"}} {
+		if _, err := db.Exec(`INSERT INTO persons (id, canonical_name, created_at, updated_at) VALUES (?, ?, 0, 0)`, p[0], p[1]); err != nil {
+			t.Fatalf("insert person: %v", err)
+		}
+	}
+	contactID, _, err := contacts.GetOrCreateContact(db, "phone", "+1 555 123 4567", "", "test")
+	if err != nil {
+		t.Fatalf("create contact: %v", err)
+	}
+	if err := contacts.EnsurePersonContactLink(db, "casey", contactID, "test", 1.0); err != nil {
+		t.Fatalf("link contact: %v", err)
+	}
+	// Sam Park is known as "Sammy" only through a nickname fact
+	if err := InsertFact(db, PersonFact{PersonID: "sam2", Category: CategoryCoreIdentity, FactType: "nickname", FactValue: "Sammy", SourceType: "mentioned"}); err != nil {
+		t.Fatalf("insert nickname: %v", err)
+	}
+
+	insertUnattributedFact(t, db, "uf-phone", "phone_mobile", "(555) 123-4567", `["Bob"]`)
+	insertUnattributedFact(t, db, "uf-alias", "email_personal", "sammy@example.com", `["Sammy"]`)
+	insertUnattributedFact(t, db, "uf-ambiguous", "email_personal", "sam@example.com", `["Sam Lee", "Sam Park"]`)
+	insertUnattributedFact(t, db, "uf-unknown", "email_personal", "who@example.com", `["Nobody"]`)
+	insertUnattributedFact(t, db, "uf-discarded", "phone_mobile", "555-123-4567", `[]`)
+	if err := DiscardUnattributedFact(db, "uf-discarded", "spam"); err != nil {
+		t.Fatalf("discard: %v", err)
+	}
+
+	stats, err := ResolveUnattributedFacts(db, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	var resolved int
+	db.QueryRow(`SELECT COUNT(*) FROM unattributed_facts WHERE resolved_to_person_id IS NOT NULL`).Scan(&resolved)
+	if stats.Resolved != 2 || resolved != 0 {
+		t.Fatalf("dry run resolved %d (wrote %d), want 2 and no writes", stats.Resolved, resolved)
+	}
+
+	stats, err = ResolveUnattributedFacts(db, false)
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if stats.Checked != 4 || stats.Resolved != 2 || stats.Ambiguous != 1 {
+		t.Errorf("stats = %+v, want 4 checked, 2 resolved, 1 ambiguous", stats)
+	}
+
+	want := map[string]string{"uf-phone": "casey", "uf-alias": "sam2", "uf-ambiguous": "", "uf-unknown": "", "uf-discarded": ""}
+	for id, personID := range want {
+		fact, err := GetUnattributedFact(db, id)
+		if err != nil {
+			t.Fatalf("get %s: %v", id, err)
+		}
+		got := ""
+		if fact.ResolvedToPersonID != nil {
+			got = *fact.ResolvedToPersonID
+		}
+		if got != personID {
+			t.Errorf("%s resolved to %q, want %q", id, got, personID)
+		}
+	}
+
+	// Attributed facts become person facts
+	facts, err := GetFactsForPerson(db, "sam2")
+	if err != nil {
+		t.Fatalf("get facts: %v", err)
+	}
+	found := false
+	for _, f := range facts {
+		if f.FactType == FactTypeEmailPersonal && f.FactValue == "sammy@example.com" {
+			found = f.Category == CategoryContactInfo
+		}
+	}
+	if !found {
+		t.Errorf("sam2 facts = %+v, want email_personal contact fact", facts)
+	}
+
+	pending, err := ListUnattributedFacts(db, UnattributedPending, 0)
+	if err != nil || len(pending) != 2 {
+		t.Fatalf("pending = %+v, %v; want 2", pending, err)
+	}
+	if err := AttributeFact(db, "uf-amb", "sam", "manual attribution"); err != nil {
+		t.Fatalf("manual attribution by prefix: %v", err)
+	}
+	if err := DiscardUnattributedFact(db, "uf-ambiguous", ""); err == nil {
+		t.Error("discarding a resolved fact should fail")
+	}
+}
@@ -0,0 +1,302 @@
+// Package instancelock keeps two mnemonic processes from doing the same
+// mutating work at once - two syncs of one adapter, or two daemons - which
+// would interleave watermark updates.
+//
+// Each named lock is held twice: as a lock file in the data directory and as
+// a row in the instance_locks table, so it holds whether a second process
+// finds the data dir or only the database. Holders heartbeat both. A lock
+// whose holder has exited (same host) or stopped heartbeating (any host) is
+// stale and is taken over, so a crash never wedges the next run.
+package instancelock
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/errs"
+	"github.com/google/uuid"
+)
+
+// Defaults for Options fields left zero.
+const (
+	DefaultHeartbeat  = 30 * time.Second
+	DefaultStaleAfter = 3 * time.Minute
+)
+
+// Holder identifies the process holding a lock.
+type Holder struct {
+	Name        string    `json:"name"`
+	PID         int       `json:"pid"`
+	Hostname    string    `json:"hostname"`
+	Command     string    `json:"command"`
+	AcquiredAt  time.Time `json:"acquired_at"`
+	HeartbeatAt time.Time `json:"heartbeat_at"`
+	Token       string    `json:"token"` // unique per Acquire, so a process can tell its locks from a takeover
+}
+
+// HeldError is returned when a live process holds a requested lock.
+type HeldError struct {
+	Holder Holder
+}
+
+func (e *HeldError) Error() string {
+	return fmt.Sprintf("%s is locked by pid %d on %s (%q, since %s)",
+		e.Holder.Name, e.Holder.PID, e.Holder.Hostname, e.Holder.Command, e.Holder.AcquiredAt.Local().Format("2006-01-02 15:04:05"))
+}
+
+// Unwrap classifies a held lock as errs.ErrDBLocked.
+func (e *HeldError) Unwrap() error {
+	return errs.ErrDBLocked
+}
+
+// Options configures Acquire.
+type Options struct {
+	Command    string        // shown to processes that find the lock held
+	Force      bool          // take over locks even from live holders
+	Heartbeat  time.Duration // how often the holder refreshes its locks
+	StaleAfter time.Duration // a lock not refreshed for this long is stale
+}
+
+// Lock is a set of held named locks.
+type Lock struct {
+	db     *sql.DB
+	dir    string
+	holder Holder
+	names  []string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Acquire takes every named lock or none. Locks live in dir (lock files) and
+// db (instance_locks rows, created by the schema db.Open applies). The
+// returned Lock heartbeats until Release.
+func Acquire(db *sql.DB, dir string, names []string, opts Options) (*Lock, error) {
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = DefaultHeartbeat
+	}
+	if opts.StaleAfter <= 0 {
+		opts.StaleAfter = DefaultStaleAfter
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create lock dir: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+	now := time.Now().UTC().Truncate(time.Second)
+	l := &Lock{
+		db:     db,
+		dir:    dir,
+		holder: Holder{PID: os.Getpid(), Hostname: hostname, Command: opts.Command, AcquiredAt: now, HeartbeatAt: now, Token: uuid.New().String()},
+	}
+
+	// A fixed order keeps two processes wanting overlapping sets from deadlocking
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		if err := l.acquireFile(name, opts); err != nil {
+			l.Release()
+			return nil, err
+		}
+		l.names = append(l.names, name)
+		if err := l.acquireRow(name, opts); err != nil {
+			l.Release()
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	l.cancel, l.done = cancel, make(chan struct{})
+	go l.heartbeat(ctx, opts.Heartbeat)
+	return l, nil
+}
+
+// Release gives up every lock held. It is safe to call more than once.
+func (l *Lock) Release() {
+	if l.cancel != nil {
+		l.cancel()
+		<-l.done
+		l.cancel = nil
+	}
+	for _, name := range l.names {
+		// Only remove what is still ours; a --force run may have taken over
+		if h, err := readFile(l.path(name)); err == nil && l.owns(h) {
+			_ = os.Remove(l.path(name))
+		}
+		_, _ = l.db.Exec(`DELETE FROM instance_locks WHERE name = ? AND token = ?`, name, l.holder.Token)
+	}
+	l.names = nil
+}
+
+// List returns the current holders of all locks recorded in db, with stale
+// ones included.
+func List(db *sql.DB) ([]Holder, error) {
+	rows, err := db.Query(`
+		SELECT name, pid, hostname, command, acquired_at, heartbeat_at, token
+		FROM instance_locks ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query locks: %w", err)
+	}
+	defer rows.Close()
+
+	var holders []Holder
+	for rows.Next() {
+		var h Holder
+		var acquired, heartbeat int64
+		if err := rows.Scan(&h.Name, &h.PID, &h.Hostname, &h.Command, &acquired, &heartbeat, &h.Token); err != nil {
+			return nil, err
+		}
+		h.AcquiredAt, h.HeartbeatAt = time.Unix(acquired, 0).UTC(), time.Unix(heartbeat, 0).UTC()
+		holders = append(holders, h)
+	}
+	return holders, rows.Err()
+}
+
+// IsStale reports whether a holder is gone: its process has exited (when on
+// this host) or it has not heartbeat within staleAfter.
+func IsStale(h Holder, staleAfter time.Duration, now time.Time) bool {
+	if now.Sub(h.HeartbeatAt) > staleAfter {
+		return true
+	}
+	hostname, _ := os.Hostname()
+	return h.Hostname == hostname && !processAlive(h.PID)
+}
+
+func (l *Lock) acquireFile(name string, opts Options) error {
+	path := l.path(name)
+	holder := l.holder
+	holder.Name = name
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, werr := f.Write(data)
+			cerr := f.Close()
+			if werr != nil || cerr != nil {
+				_ = os.Remove(path)
+				return fmt.Errorf("write lock file: %v", errors.Join(werr, cerr))
+			}
+			return nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("create lock file: %w", err)
+		}
+
+		existing, rerr := readFile(path)
+		if rerr == nil && !opts.Force && !IsStale(existing, opts.StaleAfter, time.Now()) {
+			return &HeldError{Holder: existing}
+		}
+		// Stale, unreadable (crashed mid-write), or forced: take it over
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove stale lock file: %w", err)
+		}
+	}
+	// Another process re-created the lock between our remove and create
+	existing, err := readFile(path)
+	if err != nil {
+		return fmt.Errorf("lock %s is contended", name)
+	}
+	return &HeldError{Holder: existing}
+}
+
+func (l *Lock) acquireRow(name string, opts Options) error {
+	h := l.holder
+	res, err := l.db.Exec(`
+		INSERT INTO instance_locks (name, pid, hostname, command, acquired_at, heartbeat_at, token)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO NOTHING
+	`, name, h.PID, h.Hostname, h.Command, h.AcquiredAt.Unix(), h.HeartbeatAt.Unix(), h.Token)
+	if err != nil {
+		return fmt.Errorf("insert lock row: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		return nil
+	}
+
+	var existing Holder
+	var acquired, heartbeat int64
+	err = l.db.QueryRow(`
+		SELECT pid, hostname, command, acquired_at, heartbeat_at, token FROM instance_locks WHERE name = ?
+	`, name).Scan(&existing.PID, &existing.Hostname, &existing.Command, &acquired, &heartbeat, &existing.Token)
+	if err != nil {
+		return fmt.Errorf("read lock row: %w", err)
+	}
+	existing.Name = name
+	existing.AcquiredAt, existing.HeartbeatAt = time.Unix(acquired, 0).UTC(), time.Unix(heartbeat, 0).UTC()
+	if !opts.Force && !IsStale(existing, opts.StaleAfter, time.Now()) {
+		return &HeldError{Holder: existing}
+	}
+
+	// Compare-and-swap so only one of several takers wins
+	res, err = l.db.Exec(`
+		UPDATE instance_locks
+		SET pid = ?, hostname = ?, command = ?, acquired_at = ?, heartbeat_at = ?, token = ?
+		WHERE name = ? AND token = ?
+	`, h.PID, h.Hostname, h.Command, h.AcquiredAt.Unix(), h.HeartbeatAt.Unix(), h.Token,
+		name, existing.Token)
+	if err != nil {
+		return fmt.Errorf("take over lock row: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return &HeldError{Holder: existing}
+	}
+	return nil
+}
+
+func (l *Lock) heartbeat(ctx context.Context, interval time.Duration) {
+	defer close(l.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		now := time.Now()
+		for _, name := range l.names {
+			// Non-fatal - a missed beat only matters after StaleAfter
+			_ = os.Chtimes(l.path(name), now, now)
+			_, err := l.db.Exec(`
+				UPDATE instance_locks SET heartbeat_at = ? WHERE name = ? AND token = ?
+			`, now.Unix(), name, l.holder.Token)
+			_ = err
+		}
+	}
+}
+
+func (l *Lock) path(name string) string {
+	return filepath.Join(l.dir, strings.ReplaceAll(name, ":", "-")+".lock")
+}
+
+func (l *Lock) owns(h Holder) bool {
+	return h.Token == l.holder.Token
+}
+
+// readFile reads a lock file. Its heartbeat is the file's modification time.
+func readFile(path string) (Holder, error) {
+	var h Holder
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return h, err
+	}
+	if err := json.Unmarshal(data, &h); err != nil {
+		return h, err
+	}
+	if info, err := os.Stat(path); err == nil {
+		h.HeartbeatAt = info.ModTime().UTC()
+	}
+	return h, nil
+}
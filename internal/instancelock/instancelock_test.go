@@ -0,0 +1,105 @@
+package instancelock
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestAcquire(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	dir := t.TempDir()
+
+	lock, err := Acquire(db, dir, []string{"sync:gmail", "daemon"}, Options{Command: "watch run"})
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	// Overlapping sets fail as a whole and leave the first holder intact
+	_, err = Acquire(db, dir, []string{"sync:imessage", "sync:gmail"}, Options{Command: "sync"})
+	var held *HeldError
+	if !errors.As(err, &held) || held.Holder.Name != "sync:gmail" || held.Holder.Command != "watch run" {
+		t.Fatalf("second Acquire = %v, want held sync:gmail", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sync-imessage.lock")); !os.IsNotExist(err) {
+		t.Errorf("partial acquire left sync:imessage locked: %v", err)
+	}
+	holders, err := List(db)
+	if err != nil || len(holders) != 2 {
+		t.Errorf("List = %+v, %v", holders, err)
+	}
+
+	// --force takes over; the old holder's release then leaves it alone
+	forced, err := Acquire(db, dir, []string{"sync:gmail"}, Options{Command: "sync", Force: true})
+	if err != nil {
+		t.Fatalf("forced Acquire: %v", err)
+	}
+	lock.Release()
+	if holders, _ := List(db); len(holders) != 1 || holders[0].Command != "sync" {
+		t.Errorf("after old release = %+v, want only the forced sync:gmail", holders)
+	}
+	forced.Release()
+	forced.Release()
+	if holders, _ := List(db); len(holders) != 0 {
+		t.Errorf("after release = %+v", holders)
+	}
+}
+
+func TestAcquireStale(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	dir := t.TempDir()
+	hostname, _ := os.Hostname()
+
+	// A holder that crashed: its process has exited
+	proc := exec.Command("true")
+	if err := proc.Run(); err != nil {
+		t.Skipf("cannot start a process: %v", err)
+	}
+	dead := Holder{Name: "sync:gmail", PID: proc.Process.Pid, Hostname: hostname, Command: "sync", AcquiredAt: time.Now()}
+	data, _ := json.Marshal(dead)
+	if err := os.WriteFile(filepath.Join(dir, "sync-gmail.lock"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	if _, err := db.Exec(`INSERT INTO instance_locks (name, pid, hostname, command, acquired_at, heartbeat_at, token) VALUES (?, ?, ?, ?, ?, ?, 't1')`,
+		"sync:gmail", dead.PID, hostname, "sync", now, now); err != nil {
+		t.Fatal(err)
+	}
+	// A holder on another machine that stopped heartbeating
+	old := time.Now().Add(-time.Hour).Unix()
+	if _, err := db.Exec(`INSERT INTO instance_locks (name, pid, hostname, command, acquired_at, heartbeat_at, token) VALUES (?, ?, ?, ?, ?, ?, 't2')`,
+		"daemon", 1, "elsewhere", "watch run", old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := Acquire(db, dir, []string{"sync:gmail", "daemon"}, Options{Command: "watch run"})
+	if err != nil {
+		t.Fatalf("Acquire over stale locks: %v", err)
+	}
+	defer lock.Release()
+	if holders, _ := List(db); len(holders) != 2 || holders[0].PID != os.Getpid() || holders[1].PID != os.Getpid() {
+		t.Errorf("holders = %+v, want both ours", holders)
+	}
+
+	// Live and fresh is never stale
+	if IsStale(Holder{PID: os.Getpid(), Hostname: hostname, HeartbeatAt: time.Now()}, DefaultStaleAfter, time.Now()) {
+		t.Error("own process should not be stale")
+	}
+}
+
+func TestProcessAlive(t *testing.T) {
+	if !processAlive(os.Getpid()) {
+		t.Error("current process reported dead")
+	}
+	if processAlive(0) || processAlive(-1) {
+		t.Error("invalid pid reported alive")
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -12,6 +11,7 @@ import (
 	"github.com/fsnotify/fsnotify"
 
 	"github.com/Napageneral/mnemonic/internal/adapters"
+	"github.com/Napageneral/mnemonic/internal/config"
 )
 
 func NewEveWatcher(db *sql.DB, adapterName string, opts map[string]any, heartbeatInterval time.Duration, logf func(format string, args ...any)) WatcherSpec {
@@ -58,11 +58,10 @@ func NewEveWatcher(db *sql.DB, adapterName string, opts map[string]any, heartbea
 			}
 
 			if eveDBPath == "" {
-				home, err := os.UserHomeDir()
-				if err != nil {
-					return fmt.Errorf("failed to get home dir: %w", err)
+				var err error
+				if eveDBPath, err = config.EveDBPath(); err != nil {
+					return err
 				}
-				eveDBPath = filepath.Join(home, "Library", "Application Support", "Eve", "eve.db")
 			}
 			eveDBDir := filepath.Dir(eveDBPath)
 
@@ -0,0 +1,240 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/power"
+)
+
+// Task is a recurring maintenance job.
+type Task struct {
+	Name     string
+	Interval time.Duration // time between the end of one run and the next
+	Jitter   time.Duration // up to this much random delay is added to each run
+
+	// Run does the work and returns a one-line summary.
+	Run func(ctx context.Context) (string, error)
+}
+
+// TaskStatus is a task's schedule and last outcome.
+type TaskStatus struct {
+	Task           string     `json:"task"`
+	Interval       string     `json:"interval"`
+	NextRunAt      *time.Time `json:"next_run_at,omitempty"`
+	RunningSince   *time.Time `json:"running_since,omitempty"`
+	LastStartedAt  *time.Time `json:"last_started_at,omitempty"`
+	LastFinishedAt *time.Time `json:"last_finished_at,omitempty"`
+	LastStatus     string     `json:"last_status,omitempty"`
+	LastMessage    string     `json:"last_message,omitempty"`
+	Runs           int        `json:"runs"`
+}
+
+// TaskRun is the outcome of one run.
+type TaskRun struct {
+	Task     string        `json:"task"`
+	Skipped  bool          `json:"skipped,omitempty"` // another run holds the lease
+	Paused   string        `json:"paused,omitempty"`  // why power policy deferred the run
+	Message  string        `json:"message,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Scheduler runs maintenance tasks on their schedules. Schedules and leases
+// live in maintenance_runs, so a task never runs twice at once - not even
+// from two processes - and restarts keep their place.
+type Scheduler struct {
+	DB    *sql.DB
+	Tasks []Task
+
+	Tick       time.Duration // how often to look for due tasks
+	StaleAfter time.Duration // a lease older than this is from a crashed run and is taken over
+	Logf       func(format string, args ...any)
+	Power      *power.Gate // defers due tasks on battery or while the user is active (nil = never)
+
+	now    func() time.Time
+	jitter func(max time.Duration) time.Duration
+	mu     sync.Mutex // serializes runs within this process
+}
+
+// NewScheduler creates a Scheduler for the given tasks.
+func NewScheduler(db *sql.DB, tasks []Task) *Scheduler {
+	return &Scheduler{
+		DB:         db,
+		Tasks:      tasks,
+		Tick:       time.Minute,
+		StaleAfter: 6 * time.Hour,
+		Logf:       log.Printf,
+		now:        time.Now,
+		jitter: func(max time.Duration) time.Duration {
+			if max <= 0 {
+				return 0
+			}
+			return time.Duration(rand.Int63n(int64(max)))
+		},
+	}
+}
+
+// Run checks for due tasks every Tick until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.Tick)
+	defer ticker.Stop()
+	for {
+		for _, run := range s.RunDue(ctx) {
+			if run.Error != "" {
+				s.Logf("maintenance %s failed after %s: %s", run.Task, run.Duration.Round(time.Second), run.Error)
+			} else if !run.Skipped && run.Paused == "" {
+				s.Logf("maintenance %s done in %s: %s", run.Task, run.Duration.Round(time.Second), run.Message)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue runs every task whose next run time has passed, one at a time.
+// A task seen for the first time is scheduled one jitter from now rather than
+// run immediately, so restarts don't stampede.
+func (s *Scheduler) RunDue(ctx context.Context) []TaskRun {
+	var runs []TaskRun
+	for _, task := range s.Tasks {
+		if ctx.Err() != nil {
+			break
+		}
+		due, err := s.isDue(ctx, task)
+		if err != nil {
+			runs = append(runs, TaskRun{Task: task.Name, Error: err.Error()})
+			continue
+		}
+		if !due {
+			continue
+		}
+		// A paused task stays due and runs on the first tick it is allowed
+		if ok, reason := s.Power.Allow(task.Name); !ok {
+			runs = append(runs, TaskRun{Task: task.Name, Paused: reason})
+			continue
+		}
+		runs = append(runs, s.runTask(ctx, task))
+	}
+	return runs
+}
+
+// RunNow runs the named task immediately, unless another run holds its lease.
+// Power policy does not apply: the run was asked for.
+func (s *Scheduler) RunNow(ctx context.Context, name string) (*TaskRun, error) {
+	for _, task := range s.Tasks {
+		if task.Name == name {
+			if _, err := s.isDue(ctx, task); err != nil {
+				return nil, err
+			}
+			run := s.runTask(ctx, task)
+			return &run, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown maintenance task: %s", name)
+}
+
+// Status returns every task's schedule and last outcome.
+func (s *Scheduler) Status(ctx context.Context) ([]TaskStatus, error) {
+	statuses := make([]TaskStatus, 0, len(s.Tasks))
+	for _, task := range s.Tasks {
+		status := TaskStatus{Task: task.Name, Interval: task.Interval.String()}
+		var next, running, started, finished sql.NullInt64
+		var lastStatus, lastMessage sql.NullString
+		err := s.DB.QueryRowContext(ctx, `
+			SELECT next_run_at, running_since, last_started_at, last_finished_at, last_status, last_message, runs
+			FROM maintenance_runs WHERE task = ?
+		`, task.Name).Scan(&next, &running, &started, &finished, &lastStatus, &lastMessage, &status.Runs)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("get %s status: %w", task.Name, err)
+		}
+		status.NextRunAt = unixPtr(next)
+		status.RunningSince = unixPtr(running)
+		status.LastStartedAt = unixPtr(started)
+		status.LastFinishedAt = unixPtr(finished)
+		status.LastStatus = lastStatus.String
+		status.LastMessage = lastMessage.String
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// isDue reports whether the task's next run time has passed, scheduling it
+// if it has never been seen.
+func (s *Scheduler) isDue(ctx context.Context, task Task) (bool, error) {
+	now := s.now()
+	first := now.Add(s.jitter(task.Jitter)).Unix()
+	if _, err := s.DB.ExecContext(ctx, `
+		INSERT INTO maintenance_runs (task, next_run_at) VALUES (?, ?)
+		ON CONFLICT(task) DO NOTHING
+	`, task.Name, first); err != nil {
+		return false, fmt.Errorf("schedule %s: %w", task.Name, err)
+	}
+
+	var next int64
+	if err := s.DB.QueryRowContext(ctx, `
+		SELECT next_run_at FROM maintenance_runs WHERE task = ?
+	`, task.Name).Scan(&next); err != nil {
+		return false, fmt.Errorf("get %s schedule: %w", task.Name, err)
+	}
+	return next <= now.Unix(), nil
+}
+
+// runTask takes the task's lease, runs it, and schedules the next run.
+func (s *Scheduler) runTask(ctx context.Context, task Task) TaskRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run := TaskRun{Task: task.Name}
+	start := s.now()
+	res, err := s.DB.ExecContext(ctx, `
+		UPDATE maintenance_runs
+		SET running_since = ?, last_started_at = ?
+		WHERE task = ? AND (running_since IS NULL OR running_since < ?)
+	`, start.Unix(), start.Unix(), task.Name, start.Add(-s.StaleAfter).Unix())
+	if err != nil {
+		run.Error = fmt.Sprintf("take lease: %v", err)
+		return run
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		run.Skipped = true
+		return run
+	}
+
+	message, err := task.Run(ctx)
+	finished := s.now()
+	run.Duration = finished.Sub(start)
+	run.Message = message
+	status := "ok"
+	if err != nil {
+		status, run.Error, message = "error", err.Error(), err.Error()
+	}
+
+	next := finished.Add(task.Interval).Add(s.jitter(task.Jitter))
+	// Release the lease even if ctx was cancelled mid-run
+	if _, err := s.DB.Exec(`
+		UPDATE maintenance_runs
+		SET running_since = NULL, last_finished_at = ?, last_status = ?, last_message = ?,
+		    next_run_at = ?, runs = runs + 1
+		WHERE task = ?
+	`, finished.Unix(), status, message, next.Unix(), task.Name); err != nil && run.Error == "" {
+		run.Error = fmt.Sprintf("release lease: %v", err)
+	}
+	return run
+}
+
+func unixPtr(v sql.NullInt64) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := time.Unix(v.Int64, 0)
+	return &t
+}
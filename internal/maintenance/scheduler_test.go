@@ -0,0 +1,201 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/config"
+	"github.com/Napageneral/mnemonic/internal/memory"
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestSchedulerRunDue(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	clock := time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC)
+	calls := map[string]int{}
+	tasks := []Task{
+		{Name: "a", Interval: time.Hour, Jitter: 10 * time.Minute, Run: func(ctx context.Context) (string, error) {
+			calls["a"]++
+			return "did a", nil
+		}},
+		{Name: "b", Interval: 2 * time.Hour, Run: func(ctx context.Context) (string, error) {
+			calls["b"]++
+			return "", errors.New("boom")
+		}},
+	}
+	s := NewScheduler(db, tasks)
+	s.now = func() time.Time { return clock }
+	s.jitter = func(max time.Duration) time.Duration { return max }
+
+	// First sight schedules a jitter out: b (no jitter) is due now, a is not
+	runs := s.RunDue(ctx)
+	if len(runs) != 1 || runs[0].Task != "b" || runs[0].Error != "boom" || calls["a"] != 0 {
+		t.Fatalf("first RunDue = %+v, calls %v", runs, calls)
+	}
+
+	clock = clock.Add(10 * time.Minute)
+	runs = s.RunDue(ctx)
+	if len(runs) != 1 || runs[0].Task != "a" || runs[0].Message != "did a" {
+		t.Fatalf("second RunDue = %+v", runs)
+	}
+
+	// Next run is interval + jitter after the finish
+	statuses, err := s.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	a, b := statuses[0], statuses[1]
+	if a.Runs != 1 || a.LastStatus != "ok" || !a.NextRunAt.Equal(clock.Add(70*time.Minute)) {
+		t.Errorf("a status = %+v", a)
+	}
+	if b.Runs != 1 || b.LastStatus != "error" || b.LastMessage != "boom" || b.RunningSince != nil {
+		t.Errorf("b status = %+v", b)
+	}
+
+	if runs := s.RunDue(ctx); len(runs) != 0 {
+		t.Errorf("nothing should be due, got %+v", runs)
+	}
+}
+
+func TestSchedulerLeasePreventsOverlap(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	clock := time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC)
+	ran := 0
+	s := NewScheduler(db, []Task{{Name: "a", Interval: time.Hour, Run: func(ctx context.Context) (string, error) {
+		ran++
+		return "", nil
+	}}})
+	s.now = func() time.Time { return clock }
+
+	// Another process holds the lease
+	if _, err := db.Exec(`INSERT INTO maintenance_runs (task, next_run_at, running_since) VALUES ('a', ?, ?)`,
+		clock.Unix(), clock.Add(-time.Hour).Unix()); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	run, err := s.RunNow(ctx, "a")
+	if err != nil || !run.Skipped || ran != 0 {
+		t.Fatalf("RunNow with live lease = %+v, %v (ran %d)", run, err, ran)
+	}
+
+	// A lease past StaleAfter is from a crashed run and is taken over
+	clock = clock.Add(s.StaleAfter)
+	run, err = s.RunNow(ctx, "a")
+	if err != nil || run.Skipped || ran != 1 {
+		t.Fatalf("RunNow with stale lease = %+v, %v (ran %d)", run, err, ran)
+	}
+
+	if _, err := s.RunNow(ctx, "nope"); err == nil {
+		t.Error("unknown task should fail")
+	}
+}
+
+func TestBuildTasks(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+
+	off := false
+	tasks, err := BuildTasks(db, config.MaintenanceConfig{Tasks: map[string]config.MaintenanceTaskConfig{
+		TaskBackup:  {Enabled: &off},
+		TaskMetrics: {Interval: "15m", Jitter: "0s"},
+	}}, "", t.TempDir())
+	if err != nil {
+		t.Fatalf("BuildTasks: %v", err)
+	}
+	// No API key: no embeddings; backup disabled
+	names := map[string]Task{}
+	for _, task := range tasks {
+		names[task.Name] = task
+	}
+	if len(tasks) != 11 || names[TaskMetrics].Interval != 15*time.Minute || names[TaskMetrics].Jitter != 0 {
+		t.Errorf("tasks = %+v", names)
+	}
+
+	for _, cfg := range []config.MaintenanceConfig{
+		{Tasks: map[string]config.MaintenanceTaskConfig{TaskMetrics: {Interval: "often"}}},
+		{Tasks: map[string]config.MaintenanceTaskConfig{"vacuum": {}}},
+		{StaleFacts: config.StaleFactsConfig{After: "a year"}},
+		{Inference: config.InferenceConfig{Rules: []config.InferenceRuleConfig{{Name: "aunt", If: []string{"SIBLING_OF", "PARENT_OF"}}}}},
+		{Constraints: config.ConstraintsConfig{Rules: []config.ConstraintRuleConfig{{Name: "one_employer", Kind: "unique", Relation: "WORKS_AT"}}}},
+		{Digest: config.DigestConfig{Webhook: "example.com/hook"}},
+	} {
+		if _, err := BuildTasks(db, cfg, "", t.TempDir()); err == nil {
+			t.Errorf("BuildTasks(%+v) should fail", cfg)
+		}
+	}
+}
+
+func TestMetricsAndBackup(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := RecordMetrics(ctx, db); err != nil {
+		t.Fatalf("RecordMetrics: %v", err)
+	}
+	var snapshots int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM metrics_snapshots`).Scan(&snapshots); err != nil || snapshots != 1 {
+		t.Errorf("snapshots = %d, %v", snapshots, err)
+	}
+
+	dir := t.TempDir()
+	for _, old := range []string{"cortex-20200101T000000Z.db", "cortex-20200102T000000Z.db"} {
+		if err := os.WriteFile(filepath.Join(dir, old), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Backup(ctx, db, dir, 2); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "cortex-*.db"))
+	if len(matches) != 2 || filepath.Base(matches[0]) != "cortex-20200102T000000Z.db" {
+		t.Errorf("backups = %v, want the newest old one and the new one", matches)
+	}
+}
+
+func TestCurrentFactsTask(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, stmt := range []string{
+		`INSERT INTO entities (id, canonical_name, entity_type_id, origin, created_at, updated_at) VALUES
+			('tyler', 'Tyler', 1, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z'),
+			('acme', 'Acme', 2, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')`,
+		`INSERT INTO relationships (id, source_entity_id, target_entity_id, relation_type, fact, valid_at, created_at, confidence)
+			VALUES ('r1', 'tyler', 'acme', 'WORKS_AT', 'Tyler works at Acme', '2024-01-01', '2026-01-01T00:00:00Z', 1.0)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tasks, err := BuildTasks(db, config.MaintenanceConfig{}, "", t.TempDir())
+	if err != nil {
+		t.Fatalf("BuildTasks: %v", err)
+	}
+	for _, task := range tasks {
+		if task.Name != TaskCurrentFacts {
+			continue
+		}
+		if summary, err := task.Run(ctx); err != nil || summary != "1 current facts" {
+			t.Fatalf("current_facts = %q, %v", summary, err)
+		}
+	}
+
+	engine := memory.NewQueryEngine(db)
+	defer engine.Close()
+	facts, err := engine.GetCurrentFacts(ctx, "tyler")
+	if err != nil || len(facts) != 1 || facts[0].RelationshipID != "r1" {
+		t.Errorf("current facts = %+v, %v", facts, err)
+	}
+}
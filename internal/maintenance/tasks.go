@@ -0,0 +1,377 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/config"
+	"github.com/Napageneral/mnemonic/internal/digest"
+	"github.com/Napageneral/mnemonic/internal/gemini"
+	"github.com/Napageneral/mnemonic/internal/memory"
+	"github.com/Napageneral/mnemonic/internal/usage"
+	"github.com/google/uuid"
+)
+
+// Task names.
+const (
+	TaskEmbeddings      = "embeddings"
+	TaskAliasMining     = "alias_mining"
+	TaskMergeCandidates = "merge_candidates"
+	TaskSummaries       = "summaries"
+	TaskEntityTypes     = "entity_types"
+	TaskCoMentions      = "co_mentions"
+	TaskStaleFacts      = "stale_facts"
+	TaskCurrentFacts    = "current_facts"
+	TaskInference       = "inference"
+	TaskConstraints     = "constraints"
+	TaskDigest          = "digest"
+	TaskMetrics         = "metrics"
+	TaskBackup          = "backup"
+)
+
+// Per-run caps, so one run never monopolizes the database.
+const (
+	EmbeddingBatchLimit = 500
+	SummaryBatchLimit   = 1000
+	DefaultBackupKeep   = 7
+)
+
+// defaultSchedules are the built-in intervals and jitters, in run order.
+var defaultSchedules = []struct {
+	name             string
+	interval, jitter time.Duration
+}{
+	{TaskEmbeddings, 6 * time.Hour, 30 * time.Minute},
+	{TaskAliasMining, 24 * time.Hour, time.Hour},
+	{TaskMergeCandidates, 24 * time.Hour, time.Hour},
+	{TaskSummaries, 24 * time.Hour, time.Hour},
+	{TaskEntityTypes, 24 * time.Hour, time.Hour},
+	{TaskCoMentions, 24 * time.Hour, time.Hour},
+	{TaskStaleFacts, 24 * time.Hour, time.Hour},
+	{TaskCurrentFacts, 24 * time.Hour, time.Hour},
+	{TaskInference, 24 * time.Hour, time.Hour},
+	{TaskConstraints, 24 * time.Hour, time.Hour},
+	{TaskDigest, 24 * time.Hour, time.Hour},
+	{TaskMetrics, time.Hour, 5 * time.Minute},
+	{TaskBackup, 24 * time.Hour, time.Hour},
+}
+
+// TaskNames returns the built-in task names in run order.
+func TaskNames() []string {
+	names := make([]string, len(defaultSchedules))
+	for i, s := range defaultSchedules {
+		names[i] = s.name
+	}
+	return names
+}
+
+// MetricsTables are counted by the metrics task.
+var MetricsTables = []string{
+	"events", "persons", "contacts", "episodes", "entities", "relationships",
+	"entity_aliases", "merge_candidates", "embeddings", "analysis_runs",
+}
+
+// BuildTasks returns the enabled maintenance tasks with cfg's schedule
+// overrides applied. The embeddings task is left out when apiKey is empty.
+func BuildTasks(db *sql.DB, cfg config.MaintenanceConfig, apiKey, dataDir string) ([]Task, error) {
+	backupDir := cfg.BackupDir
+	if backupDir == "" {
+		backupDir = filepath.Join(dataDir, "backups")
+	}
+	keep := cfg.BackupKeep
+	if keep <= 0 {
+		keep = DefaultBackupKeep
+	}
+
+	staleOpts, err := StaleFactOptions(cfg.StaleFacts)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := InferenceRules(cfg.Inference)
+	if err != nil {
+		return nil, err
+	}
+	constraints, err := Constraints(cfg.Constraints)
+	if err != nil {
+		return nil, err
+	}
+	if err := digest.Validate(cfg.Digest); err != nil {
+		return nil, err
+	}
+
+	runs := map[string]func(ctx context.Context) (string, error){
+		TaskAliasMining:     func(ctx context.Context) (string, error) { return runAliasMining(ctx, db) },
+		TaskMergeCandidates: func(ctx context.Context) (string, error) { return runMergeCandidates(ctx, db) },
+		TaskSummaries:       func(ctx context.Context) (string, error) { return runSummaries(ctx, db) },
+		TaskEntityTypes:     func(ctx context.Context) (string, error) { return runEntityTypes(ctx, db) },
+		TaskCoMentions:      func(ctx context.Context) (string, error) { return runCoMentions(ctx, db) },
+		TaskStaleFacts:      func(ctx context.Context) (string, error) { return runStaleFacts(ctx, db, staleOpts) },
+		TaskCurrentFacts:    func(ctx context.Context) (string, error) { return runCurrentFacts(ctx, db) },
+		TaskInference:       func(ctx context.Context) (string, error) { return runInference(ctx, db, rules) },
+		TaskConstraints:     func(ctx context.Context) (string, error) { return runConstraints(ctx, db, constraints) },
+		TaskDigest:          func(ctx context.Context) (string, error) { return RunDigest(ctx, db, cfg.Digest) },
+		TaskMetrics:         func(ctx context.Context) (string, error) { return RecordMetrics(ctx, db) },
+		TaskBackup:          func(ctx context.Context) (string, error) { return Backup(ctx, db, backupDir, keep) },
+	}
+	if apiKey != "" {
+		runs[TaskEmbeddings] = func(ctx context.Context) (string, error) { return runEmbeddings(ctx, db, apiKey) }
+	}
+
+	for name := range cfg.Tasks {
+		if !isTaskName(name) {
+			return nil, fmt.Errorf("unknown maintenance task in config: %s", name)
+		}
+	}
+
+	var tasks []Task
+	for _, def := range defaultSchedules {
+		override := cfg.Tasks[def.name]
+		if override.Enabled != nil && !*override.Enabled {
+			continue
+		}
+		run, ok := runs[def.name]
+		if !ok {
+			continue
+		}
+		task := Task{Name: def.name, Interval: def.interval, Jitter: def.jitter, Run: run}
+		if override.Interval != "" {
+			d, err := time.ParseDuration(override.Interval)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid interval for %s: %q", def.name, override.Interval)
+			}
+			task.Interval = d
+		}
+		if override.Jitter != "" {
+			d, err := time.ParseDuration(override.Jitter)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("invalid jitter for %s: %q", def.name, override.Jitter)
+			}
+			task.Jitter = d
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func isTaskName(name string) bool {
+	for _, def := range defaultSchedules {
+		if def.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func runEmbeddings(ctx context.Context, db *sql.DB, apiKey string) (string, error) {
+	client := gemini.NewClient(apiKey)
+	defer usage.Attach(db, client)()
+	embedder := memory.NewEntityEmbedder(db, client, memory.DefaultEmbeddingModel)
+	entities, err := embedder.GetEntitiesNeedingEmbeddings(ctx)
+	if err != nil {
+		return "", err
+	}
+	pending := len(entities)
+	if len(entities) > EmbeddingBatchLimit {
+		entities = entities[:EmbeddingBatchLimit]
+	}
+	embedded, err := embedder.EmbedEntities(ctx, entities)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("embedded %d of %d entities", embedded, pending), nil
+}
+
+func runAliasMining(ctx context.Context, db *sql.DB) (string, error) {
+	result, err := memory.MineAliases(ctx, db, memory.AliasMiningOptions{})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d new aliases from contact names and signatures", len(result.Aliases)), nil
+}
+
+func runMergeCandidates(ctx context.Context, db *sql.DB) (string, error) {
+	detected, err := memory.NewCollisionDetector(db).DetectCollisions(ctx, true)
+	if err != nil {
+		return "", fmt.Errorf("detect collisions: %w", err)
+	}
+	processed, err := memory.NewAutoMerger(db).ProcessMergeCandidatesBatch(ctx, memory.MergeBatchOptions{})
+	if err != nil {
+		return "", fmt.Errorf("process candidates: %w", err)
+	}
+	msg := fmt.Sprintf("%d candidates created, %d merged, %d conflicts, %d remaining",
+		detected.CandidatesCreated, processed.AutoMerged, processed.Conflicts, processed.Remaining)
+	if processed.Aborted {
+		return msg, fmt.Errorf("merge aborted: %s", processed.AbortReason)
+	}
+	return msg, nil
+}
+
+func runSummaries(ctx context.Context, db *sql.DB) (string, error) {
+	n, err := memory.RefreshEntitySummaries(ctx, db, SummaryBatchLimit)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("refreshed %d summaries", n), nil
+}
+
+func runEntityTypes(ctx context.Context, db *sql.DB) (string, error) {
+	result, err := memory.NewTypeChecker(db).Check(ctx, true)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("checked %d entities, %d new type flags", result.Checked, result.Created), nil
+}
+
+func runCoMentions(ctx context.Context, db *sql.DB) (string, error) {
+	result, err := memory.AnalyzeCoMentions(ctx, db, memory.CoMentionOptions{Record: true})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d co-mentioned pairs without an edge, %d new suggestions", len(result.Suggestions), result.Created), nil
+}
+
+// StaleFactOptions converts the stale_facts config into memory options.
+func StaleFactOptions(cfg config.StaleFactsConfig) (memory.StaleFactOptions, error) {
+	opts := memory.StaleFactOptions{RelationTypes: cfg.RelationTypes, Limit: cfg.Limit}
+	if cfg.After != "" {
+		d, err := time.ParseDuration(cfg.After)
+		if err != nil || d <= 0 {
+			return opts, fmt.Errorf("invalid stale_facts after: %q", cfg.After)
+		}
+		opts.After = d
+	}
+	return opts, nil
+}
+
+func runStaleFacts(ctx context.Context, db *sql.DB, opts memory.StaleFactOptions) (string, error) {
+	result, err := memory.FindStaleFacts(ctx, db, opts)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d queued for verification, %d superseded, %d deferred", result.Queued, result.Superseded, result.Deferred), nil
+}
+
+// runCurrentFacts rebuilds entity_current_facts, dropping edges whose
+// invalid_at has since passed and repairing failed incremental refreshes.
+func runCurrentFacts(ctx context.Context, db *sql.DB) (string, error) {
+	n, err := memory.NewCurrentFactsStore(db).RefreshAll(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d current facts", n), nil
+}
+
+// InferenceRules converts the inference config into memory rules, or
+// returns nil for the defaults.
+func InferenceRules(cfg config.InferenceConfig) ([]memory.InferenceRule, error) {
+	if len(cfg.Rules) == 0 {
+		return nil, nil
+	}
+	rules := make([]memory.InferenceRule, len(cfg.Rules))
+	for i, r := range cfg.Rules {
+		rules[i] = memory.InferenceRule{Name: r.Name, If: r.If, Then: r.Then}
+	}
+	return memory.ValidateInferenceRules(rules)
+}
+
+func runInference(ctx context.Context, db *sql.DB, rules []memory.InferenceRule) (string, error) {
+	result, err := memory.RunInference(ctx, db, memory.InferOptions{Rules: rules})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d facts inferred, %d unchanged, %d retracted", len(result.Inferred), result.Unchanged, result.Retracted), nil
+}
+
+// Constraints converts the constraints config into memory constraints, or
+// returns nil for the defaults.
+func Constraints(cfg config.ConstraintsConfig) ([]memory.Constraint, error) {
+	if len(cfg.Rules) == 0 {
+		return nil, nil
+	}
+	constraints := make([]memory.Constraint, len(cfg.Rules))
+	for i, r := range cfg.Rules {
+		constraints[i] = memory.Constraint{Name: r.Name, Kind: memory.ConstraintKind(r.Kind), RelationType: r.Relation}
+	}
+	return memory.ValidateConstraints(constraints)
+}
+
+func runConstraints(ctx context.Context, db *sql.DB, constraints []memory.Constraint) (string, error) {
+	report, err := memory.CheckConstraints(ctx, db, memory.CheckOptions{Constraints: constraints})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d constraint violations", len(report.Violations)), nil
+}
+
+// RunDigest stores a digest of what changed since the previous one and
+// sends it as cfg says. A failed delivery is an error, but the digest is
+// kept and the next one starts after it.
+func RunDigest(ctx context.Context, db *sql.DB, cfg config.DigestConfig) (string, error) {
+	d, err := digest.Create(ctx, db, time.Now())
+	if err != nil {
+		return "", err
+	}
+	if d.Empty() {
+		return "nothing new", nil
+	}
+	if err := digest.Send(ctx, d, cfg); err != nil {
+		return "", err
+	}
+	return d.Summary(), nil
+}
+
+// RecordMetrics stores a snapshot of row counts for MetricsTables.
+func RecordMetrics(ctx context.Context, db *sql.DB) (string, error) {
+	counts := make(map[string]int, len(MetricsTables))
+	for _, table := range MetricsTables {
+		var n int
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table).Scan(&n); err != nil {
+			return "", fmt.Errorf("count %s: %w", table, err)
+		}
+		counts[table] = n
+	}
+	data, err := json.Marshal(counts)
+	if err != nil {
+		return "", err
+	}
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO metrics_snapshots (id, taken_at, metrics_json) VALUES (?, ?, ?)
+	`, uuid.New().String(), time.Now().Unix(), string(data)); err != nil {
+		return "", fmt.Errorf("insert snapshot: %w", err)
+	}
+	return fmt.Sprintf("%d entities, %d relationships, %d events", counts["entities"], counts["relationships"], counts["events"]), nil
+}
+
+// Backup writes a consistent copy of the database to dir and deletes all but
+// the newest keep backups.
+func Backup(ctx context.Context, db *sql.DB, dir string, keep int) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("create backup dir: %w", err)
+	}
+	path := filepath.Join(dir, "cortex-"+time.Now().UTC().Format("20060102T150405Z")+".db")
+	if _, err := db.ExecContext(ctx, `VACUUM INTO ?`, path); err != nil {
+		return "", fmt.Errorf("vacuum into %s: %w", path, err)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "cortex-*.db"))
+	if err != nil {
+		return "", err
+	}
+	// Timestamped names sort oldest first
+	sort.Strings(matches)
+	removed := 0
+	for len(matches) > keep {
+		if err := os.Remove(matches[0]); err != nil {
+			return "", fmt.Errorf("remove old backup: %w", err)
+		}
+		matches = matches[1:]
+		removed++
+	}
+	return fmt.Sprintf("wrote %s, removed %d old backups", path, removed), nil
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/namespace"
 	"github.com/google/uuid"
 )
 
@@ -16,6 +17,7 @@ type Person struct {
 	DisplayName    *string
 	IsMe           bool
 	RelationType   *string
+	Namespace      *string
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 }
@@ -29,20 +31,40 @@ type Identity struct {
 	CreatedAt  time.Time
 }
 
-// GetMePerson returns the person marked as "me", or nil if not set
+// mePersonQuery selects the active household member's "me" person: the one
+// in their namespace, else one no member has claimed yet.
+func mePersonQuery(columns string) (string, interface{}) {
+	visible, arg := namespace.Visible("namespace")
+	return `SELECT ` + columns + ` FROM persons WHERE is_me = 1 AND ` + visible + `
+		ORDER BY namespace IS NULL LIMIT 1`, arg
+}
+
+// LookupMePersonID returns the ID of the active household member's "me"
+// person, or "" if there is none.
+func LookupMePersonID(q contacts.DBTX) (string, error) {
+	query, arg := mePersonQuery("id")
+	var id string
+	err := q.QueryRow(query, arg).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get me person: %w", err)
+	}
+	return id, nil
+}
+
+// GetMePerson returns the person marked as "me" for the active household
+// member (see LookupMePersonID), or nil if not set
 func GetMePerson(db *sql.DB) (*Person, error) {
-	row := db.QueryRow(`
-		SELECT id, canonical_name, display_name, is_me, relationship_type, created_at, updated_at
-		FROM persons
-		WHERE is_me = 1
-		LIMIT 1
-	`)
+	query, arg := mePersonQuery("id, canonical_name, display_name, is_me, relationship_type, namespace, created_at, updated_at")
+	row := db.QueryRow(query, arg)
 
 	var p Person
-	var displayName, relationType sql.NullString
+	var displayName, relationType, ns sql.NullString
 	var createdAt, updatedAt int64
 
-	err := row.Scan(&p.ID, &p.CanonicalName, &displayName, &p.IsMe, &relationType, &createdAt, &updatedAt)
+	err := row.Scan(&p.ID, &p.CanonicalName, &displayName, &p.IsMe, &relationType, &ns, &createdAt, &updatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -56,6 +78,9 @@ func GetMePerson(db *sql.DB) (*Person, error) {
 	if relationType.Valid {
 		p.RelationType = &relationType.String
 	}
+	if ns.Valid {
+		p.Namespace = &ns.String
+	}
 	p.CreatedAt = time.Unix(createdAt, 0)
 	p.UpdatedAt = time.Unix(updatedAt, 0)
 
@@ -102,20 +127,20 @@ func SetMeName(db *sql.DB, name string) error {
 	now := time.Now().Unix()
 
 	// Check if me person exists
-	var existingID string
-	err = tx.QueryRow("SELECT id FROM persons WHERE is_me = 1 LIMIT 1").Scan(&existingID)
-	if err == sql.ErrNoRows {
+	existingID, err := LookupMePersonID(tx)
+	if err != nil {
+		return err
+	}
+	if existingID == "" {
 		// Create new me person
 		newID := uuid.New().String()
 		_, err = tx.Exec(`
-			INSERT INTO persons (id, canonical_name, is_me, created_at, updated_at)
-			VALUES (?, ?, 1, ?, ?)
-		`, newID, name, now, now)
+			INSERT INTO persons (id, canonical_name, is_me, namespace, created_at, updated_at)
+			VALUES (?, ?, 1, ?, ?, ?)
+		`, newID, name, namespace.Value(), now, now)
 		if err != nil {
 			return fmt.Errorf("failed to create me person: %w", err)
 		}
-	} else if err != nil {
-		return fmt.Errorf("failed to check existing me person: %w", err)
 	} else {
 		// Update existing me person
 		_, err = tx.Exec(`
@@ -144,21 +169,21 @@ func AddIdentity(db *sql.DB, channel, identifier string) error {
 	defer tx.Rollback()
 
 	// Get or create me person
-	var meID string
-	err = tx.QueryRow("SELECT id FROM persons WHERE is_me = 1 LIMIT 1").Scan(&meID)
-	if err == sql.ErrNoRows {
+	meID, err := LookupMePersonID(tx)
+	if err != nil {
+		return err
+	}
+	if meID == "" {
 		// Create me person with empty name (will be set later)
 		meID = uuid.New().String()
 		now := time.Now().Unix()
 		_, err = tx.Exec(`
-			INSERT INTO persons (id, canonical_name, is_me, created_at, updated_at)
-			VALUES (?, '', 1, ?, ?)
-		`, meID, now, now)
+			INSERT INTO persons (id, canonical_name, is_me, namespace, created_at, updated_at)
+			VALUES (?, '', 1, ?, ?, ?)
+		`, meID, namespace.Value(), now, now)
 		if err != nil {
 			return fmt.Errorf("failed to create me person: %w", err)
 		}
-	} else if err != nil {
-		return fmt.Errorf("failed to get me person: %w", err)
 	}
 
 	contactID, _, err := contacts.GetOrCreateContact(tx, channel, identifier, "", "manual")
@@ -175,3 +200,57 @@ func AddIdentity(db *sql.DB, channel, identifier string) error {
 
 	return nil
 }
+
+// ClaimResult reports what ClaimNamespace moved into the active namespace.
+type ClaimResult struct {
+	Namespace      string `json:"namespace"`
+	PersonID       string `json:"person_id"`
+	SensitiveFacts int    `json:"sensitive_facts"`
+}
+
+// ClaimNamespace moves the unclaimed "me" person, and the sensitive facts
+// stored before namespaces were in use, into the active household member's
+// namespace. Run it once as the database's original user when a second
+// member starts sharing the database.
+func ClaimNamespace(db *sql.DB) (*ClaimResult, error) {
+	ns := namespace.Current()
+	if ns == "" {
+		return nil, fmt.Errorf("no namespace set: add me.namespace to your config")
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &ClaimResult{Namespace: ns}
+	var owned string
+	if err := tx.QueryRow(`SELECT id FROM persons WHERE is_me = 1 AND namespace = ? LIMIT 1`, ns).Scan(&owned); err == nil {
+		return nil, fmt.Errorf("namespace %s already has a me person: %s", ns, owned)
+	} else if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to check namespace: %w", err)
+	}
+	err = tx.QueryRow(`SELECT id FROM persons WHERE is_me = 1 AND namespace IS NULL LIMIT 1`).Scan(&result.PersonID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no unclaimed me person")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get me person: %w", err)
+	}
+
+	now := time.Now().Unix()
+	if _, err := tx.Exec(`UPDATE persons SET namespace = ?, updated_at = ? WHERE id = ?`, ns, now, result.PersonID); err != nil {
+		return nil, fmt.Errorf("failed to claim me person: %w", err)
+	}
+	res, err := tx.Exec(`UPDATE person_facts SET namespace = ?, updated_at = ? WHERE is_sensitive = 1 AND namespace IS NULL`, ns, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim sensitive facts: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	result.SensitiveFacts = int(n)
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package me
+
+import (
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/namespace"
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestHouseholdNamespaces(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	defer namespace.Set("")
+
+	// A single-user database: the me person is unclaimed
+	if err := SetMeName(db, "Tyler"); err != nil {
+		t.Fatalf("SetMeName: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO persons (id, canonical_name, created_at, updated_at) VALUES ('casey', 'Casey', 0, 0);
+		INSERT INTO person_facts (id, person_id, category, fact_type, fact_value, source_type, is_sensitive, created_at, updated_at)
+		VALUES ('f1', 'casey', 'government_legal_ids', 'ssn', '123-45-6789', 'mentioned', 1, 0, 0),
+		       ('f2', 'casey', 'professional', 'employer_current', 'Acme', 'mentioned', 0, 0, 0);
+	`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	if _, err := ClaimNamespace(db); err == nil {
+		t.Fatal("claiming without a namespace should fail")
+	}
+	namespace.Set("tyler")
+	claim, err := ClaimNamespace(db)
+	if err != nil {
+		t.Fatalf("ClaimNamespace: %v", err)
+	}
+	if claim.SensitiveFacts != 1 {
+		t.Errorf("claimed %d sensitive facts, want 1", claim.SensitiveFacts)
+	}
+	tyler, err := GetMePerson(db)
+	if err != nil || tyler == nil || tyler.CanonicalName != "Tyler" || tyler.Namespace == nil || *tyler.Namespace != "tyler" {
+		t.Fatalf("tyler's me = %+v, %v", tyler, err)
+	}
+
+	// A second member gets their own me person
+	namespace.Set("casey")
+	if id, err := LookupMePersonID(db); err != nil || id != "" {
+		t.Fatalf("casey's me before setup = %q, %v", id, err)
+	}
+	if err := SetMeName(db, "Casey"); err != nil {
+		t.Fatalf("SetMeName: %v", err)
+	}
+	if err := AddIdentity(db, "email", "casey@example.com"); err != nil {
+		t.Fatalf("AddIdentity: %v", err)
+	}
+	casey, err := GetMePerson(db)
+	if err != nil || casey == nil || casey.ID == tyler.ID || casey.CanonicalName != "Casey" {
+		t.Fatalf("casey's me = %+v, %v", casey, err)
+	}
+	identities, err := GetIdentities(db, casey.ID)
+	if err != nil || len(identities) != 1 {
+		t.Errorf("casey's identities = %+v, %v", identities, err)
+	}
+
+	namespace.Set("tyler")
+	if id, _ := LookupMePersonID(db); id != tyler.ID {
+		t.Errorf("tyler's me = %q, want %q", id, tyler.ID)
+	}
+	if _, err := ClaimNamespace(db); err == nil {
+		t.Error("claiming twice should fail")
+	}
+}
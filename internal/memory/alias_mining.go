@@ -0,0 +1,406 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/google/uuid"
+)
+
+// Alias mining adds name aliases from data already on hand - contact
+// display names, the display part of email addresses, and the name line of
+// email signatures - so entity resolution can match "Bob" or "Robert Smith"
+// before any LLM call. Mined aliases are stored with origin deterministic.
+const AliasOriginDeterministic = "deterministic"
+
+// Where a mined alias came from.
+const (
+	AliasSourceContactName  = "contact_name"  // address book or chat display name
+	AliasSourceEmailDisplay = "email_display" // "Robert Smith <bob@example.com>"
+	AliasSourceSignature    = "signature"     // the name under a sign-off in the sender's emails
+)
+
+// SignatureMinEmails is how many of a sender's emails must be signed with
+// the same name before it is mined, so a one-off signature (a forwarded
+// message, someone signing for a colleague) is never taken.
+const SignatureMinEmails = 2
+
+// MinedAlias is a name alias found for an entity.
+type MinedAlias struct {
+	EntityID   string `json:"entity_id"`
+	EntityName string `json:"entity_name"`
+	Alias      string `json:"alias"`
+	Source     string `json:"source"`
+}
+
+// AliasMiningOptions configures MineAliases.
+type AliasMiningOptions struct {
+	DryRun bool // report the aliases without adding them
+}
+
+// AliasMiningResult is the output of MineAliases.
+type AliasMiningResult struct {
+	Aliases  []MinedAlias   `json:"aliases"` // new aliases, added unless DryRun
+	BySource map[string]int `json:"by_source"`
+	DryRun   bool           `json:"dry_run,omitempty"`
+}
+
+// MineAliases finds name aliases for entities linked to contacts: each
+// contact's display name, and the name its emails are signed with. Names
+// the entity already has are skipped, so repeated runs add nothing new.
+func MineAliases(ctx context.Context, db *sql.DB, opts AliasMiningOptions) (*AliasMiningResult, error) {
+	contactEntities, err := contactEntityMap(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AliasMiningResult{Aliases: []MinedAlias{}, BySource: map[string]int{}, DryRun: opts.DryRun}
+	if len(contactEntities) == 0 {
+		return result, nil
+	}
+	known, err := entityNameKeys(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	add := func(entityID, alias, source string) {
+		key := entityID + "\x00" + normalizeAlias(alias)
+		if known[key] != "" {
+			return
+		}
+		known[key] = alias
+		result.Aliases = append(result.Aliases, MinedAlias{EntityID: entityID, Alias: alias, Source: source})
+		result.BySource[source]++
+	}
+
+	names, err := contactDisplayNames(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range names {
+		entityID := contactEntities[n.contactID]
+		if entityID == "" {
+			continue
+		}
+		if name, ok := cleanAliasName(n.name, false); ok {
+			add(entityID, name, n.source)
+		}
+	}
+
+	signatures, err := signatureNames(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range signatures {
+		if entityID := contactEntities[s.contactID]; entityID != "" {
+			add(entityID, s.name, AliasSourceSignature)
+		}
+	}
+
+	for i := range result.Aliases {
+		result.Aliases[i].EntityName = known[result.Aliases[i].EntityID+"\x00"]
+	}
+	if opts.DryRun || len(result.Aliases) == 0 {
+		return result, nil
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	for _, a := range result.Aliases {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO entity_aliases (id, entity_id, alias, alias_type, normalized, is_shared, created_at, origin)
+			VALUES (?, ?, ?, 'name', ?, FALSE, ?, ?)
+		`, uuid.New().String(), a.EntityID, a.Alias, normalizeAlias(a.Alias), now, AliasOriginDeterministic); err != nil {
+			return nil, fmt.Errorf("insert alias: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	index := NewBlockingIndex(db)
+	for _, a := range result.Aliases {
+		if err := index.IndexAlias(ctx, a.EntityID, a.Alias, "name"); err != nil {
+			// Non-fatal - IndexMissing catches up on the next run
+			_ = err
+		}
+	}
+	return result, nil
+}
+
+// contactEntityMap maps contacts to the entity they belong to, through the
+// contact's person when that person is linked to an entity and through
+// email and phone aliases. Contacts matching more than one entity, or only
+// an identifier several entities share, are left out.
+func contactEntityMap(ctx context.Context, db *sql.DB) (map[string]string, error) {
+	matches := map[string]map[string]bool{}
+	link := func(contactID, entityID string) {
+		if matches[contactID] == nil {
+			matches[contactID] = map[string]bool{}
+		}
+		matches[contactID][entityID] = true
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT pcl.contact_id, pel.entity_id
+		FROM person_contact_links pcl
+		JOIN person_entity_links pel ON pel.person_id = pcl.person_id
+		JOIN entities e ON e.id = pel.entity_id AND e.merged_into IS NULL
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query person links: %w", err)
+	}
+	for rows.Next() {
+		var contactID, entityID string
+		if err := rows.Scan(&contactID, &entityID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		link(contactID, entityID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Identifier aliases, keyed the way contact identifiers are normalized
+	byIdentifier := map[string]string{}
+	rows, err = db.QueryContext(ctx, `
+		SELECT ea.alias_type, ea.alias, ea.entity_id
+		FROM entity_aliases ea
+		JOIN entities e ON e.id = ea.entity_id AND e.merged_into IS NULL
+		WHERE ea.alias_type IN ('email', 'phone')
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query identifier aliases: %w", err)
+	}
+	for rows.Next() {
+		var aliasType, alias, entityID string
+		if err := rows.Scan(&aliasType, &alias, &entityID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		key := aliasType + ":" + contacts.NormalizeIdentifier(alias, aliasType)
+		if prev, ok := byIdentifier[key]; ok && prev != entityID {
+			byIdentifier[key] = "" // shared identifier: ambiguous
+		} else if !ok {
+			byIdentifier[key] = entityID
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(byIdentifier) > 0 {
+		rows, err = db.QueryContext(ctx, `
+			SELECT contact_id, type, normalized FROM contact_identifiers WHERE type IN ('email', 'phone')
+		`)
+		if err != nil {
+			return nil, fmt.Errorf("query contact identifiers: %w", err)
+		}
+		for rows.Next() {
+			var contactID, idType, normalized string
+			if err := rows.Scan(&contactID, &idType, &normalized); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if entityID := byIdentifier[idType+":"+normalized]; entityID != "" {
+				link(contactID, entityID)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	out := make(map[string]string, len(matches))
+	for contactID, entities := range matches {
+		if len(entities) != 1 {
+			continue
+		}
+		for entityID := range entities {
+			out[contactID] = entityID
+		}
+	}
+	return out, nil
+}
+
+// entityNameKeys returns every entity's canonical name and name aliases,
+// keyed by entity ID and normalized name. The canonical name is also stored
+// under the entity ID alone.
+func entityNameKeys(ctx context.Context, db *sql.DB) (map[string]string, error) {
+	known := map[string]string{}
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, canonical_name, canonical_name FROM entities WHERE merged_into IS NULL
+		UNION ALL
+		SELECT entity_id, alias, NULL FROM entity_aliases WHERE alias_type IN ('name', 'nickname')
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query entity names: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entityID, name string
+		var canonical sql.NullString
+		if err := rows.Scan(&entityID, &name, &canonical); err != nil {
+			return nil, err
+		}
+		known[entityID+"\x00"+normalizeAlias(name)] = name
+		if canonical.Valid {
+			known[entityID+"\x00"] = canonical.String
+		}
+	}
+	return known, rows.Err()
+}
+
+type contactName struct {
+	contactID, name, source string
+}
+
+// contactDisplayNames returns each contact's display name. Names of contacts
+// seen on email are the display part of their address.
+func contactDisplayNames(ctx context.Context, db *sql.DB) ([]contactName, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.id, c.display_name,
+		       EXISTS (SELECT 1 FROM event_participants ep JOIN events ev ON ev.id = ep.event_id
+		               WHERE ep.contact_id = c.id AND ev.channel IN ('gmail', 'email'))
+		FROM contacts c
+		WHERE c.display_name IS NOT NULL AND TRIM(c.display_name) != ''
+		ORDER BY c.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query contact names: %w", err)
+	}
+	defer rows.Close()
+	var names []contactName
+	for rows.Next() {
+		var n contactName
+		var onEmail bool
+		if err := rows.Scan(&n.contactID, &n.name, &onEmail); err != nil {
+			return nil, err
+		}
+		n.source = AliasSourceContactName
+		if onEmail {
+			n.source = AliasSourceEmailDisplay
+		}
+		names = append(names, n)
+	}
+	return names, rows.Err()
+}
+
+// signatureNames returns the names senders sign their emails with, when
+// they sign at least SignatureMinEmails emails the same way.
+func signatureNames(ctx context.Context, db *sql.DB) ([]contactName, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT ep.contact_id, ev.content
+		FROM events ev
+		JOIN event_participants ep ON ep.event_id = ev.id AND ep.role = 'sender'
+		WHERE ev.channel IN ('gmail', 'email') AND ev.content IS NOT NULL
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query emails: %w", err)
+	}
+	defer rows.Close()
+
+	type key struct{ contactID, name string }
+	counts := map[key]int{}
+	for rows.Next() {
+		var contactID, content string
+		if err := rows.Scan(&contactID, &content); err != nil {
+			return nil, err
+		}
+		if name := SignatureName(content); name != "" {
+			counts[key{contactID, name}]++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var names []contactName
+	for k, n := range counts {
+		if n >= SignatureMinEmails {
+			names = append(names, contactName{contactID: k.contactID, name: k.name, source: AliasSourceSignature})
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].contactID != names[j].contactID {
+			return names[i].contactID < names[j].contactID
+		}
+		return names[i].name < names[j].name
+	})
+	return names, nil
+}
+
+var (
+	// A sign-off line: "Best,", "Thanks!", "Kind regards"
+	signOffPattern = regexp.MustCompile(`(?i)^(best|best regards|best wishes|all the best|thanks|thanks again|thank you|many thanks|cheers|regards|kind regards|warm regards|warmly|sincerely|yours|yours truly|talk soon|take care|respectfully|love)[\s,.!]*$`)
+	// Where quoted or forwarded text starts; signatures below belong to someone else
+	quoteStartPattern = regexp.MustCompile(`(?i)^(on .+ wrote:|-+ ?(original|forwarded) message ?-+|from: .+)$`)
+)
+
+// SignatureName returns the name an email is signed with: the line after a
+// sign-off ("Best,") or a "--" signature delimiter, above any quoted reply.
+// Empty if the email has no recognizable signature.
+func SignatureName(content string) string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, ">") || quoteStartPattern.MatchString(line) {
+			break
+		}
+		lines = append(lines, line)
+	}
+	for i := len(lines) - 2; i >= 0; i-- {
+		if lines[i] != "--" && !signOffPattern.MatchString(lines[i]) {
+			continue
+		}
+		for _, next := range lines[i+1:] {
+			if next == "" {
+				continue
+			}
+			if name, ok := cleanAliasName(next, true); ok {
+				return name
+			}
+			break
+		}
+		return ""
+	}
+	return ""
+}
+
+// cleanAliasName tidies a display or signature name and reports whether it
+// looks like a name: letters only, at most four words, so no email address
+// or phone number. Signature names must also be capitalized ("Bob", "Mary-Kate
+// O'Neil"), which rules out body text like "Sent from my phone".
+func cleanAliasName(s string, signature bool) (string, bool) {
+	s = strings.Trim(strings.TrimSpace(s), `"'`)
+	words := strings.Fields(s)
+	if len(words) == 0 || len(words) > 4 || len(s) > 60 {
+		return "", false
+	}
+	for _, w := range words {
+		first := []rune(w)[0]
+		if !unicode.IsLetter(first) || (signature && !unicode.IsUpper(first)) {
+			return "", false
+		}
+		for _, r := range w {
+			if !unicode.IsLetter(r) && r != '-' && r != '\'' && r != '.' {
+				return "", false
+			}
+		}
+	}
+	return strings.Join(words, " "), true
+}
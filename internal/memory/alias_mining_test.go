@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestSignatureName(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"sign-off", "Subject: Lease\n\nThe landlord agreed.\n\nBest,\nBob Smith\nAcme Realty", "Bob Smith"},
+		{"delimiter", "See attached.\n\n--\nMary-Kate O'Neil\n555-0100", "Mary-Kate O'Neil"},
+		{"single word", "Sounds good.\n\nThanks!\nBob", "Bob"},
+		{"quoted reply", "Sure.\n\nOn Mon, Jan 1, 2024 Sam wrote:\n> Thanks,\n> Sam Lee", ""},
+		{"phone footer", "Ok\n\nSent from my iPhone", ""},
+		{"not a name", "Cheers,\n555-0100", ""},
+	}
+	for _, tt := range tests {
+		if got := SignatureName(tt.content); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMineAliases(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertQueryEngineTestEntity(t, db, "robert", "Robert Smith", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "casey", "Casey", EntityTypePerson)
+	if _, err := db.Exec(`
+		INSERT INTO entity_aliases (id, entity_id, alias, alias_type, normalized, created_at) VALUES
+			('a1', 'robert', 'bob@example.com', 'email', 'bob@example.com', 'x');
+		INSERT INTO persons (id, canonical_name, created_at, updated_at) VALUES ('p-casey', 'Casey', 0, 0);
+		INSERT INTO person_entity_links (person_id, entity_id, method, created_at) VALUES ('p-casey', 'casey', 'name', 0);
+		INSERT INTO contacts (id, display_name, created_at, updated_at) VALUES
+			('c-bob', 'Bob Smith', 0, 0), ('c-casey', 'Case', 0, 0), ('c-stranger', 'Pat', 0, 0);
+		INSERT INTO contact_identifiers (id, contact_id, type, value, normalized, created_at) VALUES
+			('i1', 'c-bob', 'email', 'Bob@Example.com', 'bob@example.com', 0);
+		INSERT INTO person_contact_links (id, person_id, contact_id) VALUES ('l1', 'p-casey', 'c-casey');
+	`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	// Bob signs two emails "Bobby", and one forwarded message carries another signature
+	for _, e := range []struct{ id, content string }{
+		{"e1", "Subject: Lease\n\nSigned.\n\nThanks,\nBobby"},
+		{"e2", "Subject: Keys\n\nDropping them off.\n\nBest,\nBobby"},
+		{"e3", "Subject: Fwd\n\nFYI\n\nRegards,\nJane Roe"},
+	} {
+		if _, err := db.Exec(`INSERT INTO events (id, timestamp, channel, content_types, content, direction, source_adapter, source_id) VALUES (?, 0, 'gmail', '["text"]', ?, 'received', 'gmail', ?)`, e.id, e.content, e.id); err != nil {
+			t.Fatalf("event: %v", err)
+		}
+		if _, err := db.Exec(`INSERT INTO event_participants (event_id, contact_id, role) VALUES (?, 'c-bob', 'sender')`, e.id); err != nil {
+			t.Fatalf("participant: %v", err)
+		}
+	}
+
+	dry, err := MineAliases(ctx, db, AliasMiningOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("MineAliases dry run: %v", err)
+	}
+	got := map[string]string{}
+	for _, a := range dry.Aliases {
+		got[a.EntityID+":"+a.Alias] = a.Source
+	}
+	want := map[string]string{
+		"robert:Bob Smith": AliasSourceEmailDisplay,
+		"robert:Bobby":     AliasSourceSignature,
+		"casey:Case":       AliasSourceContactName,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("mined %v, want %v", got, want)
+	}
+	for k, source := range want {
+		if got[k] != source {
+			t.Errorf("%s: source %q, want %q", k, got[k], source)
+		}
+	}
+	var n int
+	db.QueryRow(`SELECT COUNT(*) FROM entity_aliases WHERE origin = ?`, AliasOriginDeterministic).Scan(&n)
+	if n != 0 {
+		t.Fatalf("dry run added %d aliases", n)
+	}
+
+	if res, err := MineAliases(ctx, db, AliasMiningOptions{}); err != nil || len(res.Aliases) != 3 {
+		t.Fatalf("MineAliases = %+v, %v", res, err)
+	}
+	db.QueryRow(`SELECT COUNT(*) FROM entity_aliases WHERE origin = ? AND alias_type = 'name'`, AliasOriginDeterministic).Scan(&n)
+	if n != 3 {
+		t.Errorf("%d deterministic aliases stored, want 3", n)
+	}
+	if again, _ := MineAliases(ctx, db, AliasMiningOptions{}); len(again.Aliases) != 0 {
+		t.Errorf("second run mined %+v", again.Aliases)
+	}
+}
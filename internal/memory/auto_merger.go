@@ -48,18 +48,54 @@ type MergeResult struct {
 
 // ProcessMergeCandidatesResult contains the result of processing merge candidates.
 type ProcessMergeCandidatesResult struct {
-	Processed     int            `json:"processed"`
-	AutoMerged    int            `json:"auto_merged"`
-	Conflicts     int            `json:"conflicts"`
-	NeedsReview   int            `json:"needs_review"`
-	MergeResults  []*MergeResult `json:"merge_results,omitempty"`
+	Processed    int                   `json:"processed"`
+	AutoMerged   int                   `json:"auto_merged"`
+	Conflicts    int                   `json:"conflicts"`
+	NeedsReview  int                   `json:"needs_review"`
+	Failed       int                   `json:"failed"`
+	Remaining    int                   `json:"remaining"` // pending candidates not evaluated in this run
+	Aborted      bool                  `json:"aborted,omitempty"`
+	AbortReason  string                `json:"abort_reason,omitempty"`
+	Errors       []MergeCandidateError `json:"errors,omitempty"`
+	MergeResults []*MergeResult        `json:"merge_results,omitempty"`
+}
+
+// MergeCandidateError is a candidate that could not be processed.
+type MergeCandidateError struct {
+	CandidateID string `json:"candidate_id"`
+	Stage       string `json:"stage"` // "detect_conflicts", "update", "check", "merge"
+	Error       string `json:"error"`
+}
+
+// Batch merge defaults.
+const (
+	DefaultMergeBatchSize        = 500
+	DefaultMaxConflictRate       = 0.5
+	DefaultConflictRateMinSample = 20
+)
+
+// MergeBatchOptions bounds a ProcessMergeCandidatesBatch run. The zero value
+// evaluates every pending candidate with no kill switch.
+type MergeBatchOptions struct {
+	BatchSize int // max candidates evaluated per run; 0 = all
+
+	// Kill switch: if more than MaxConflictRate of the evaluated candidates
+	// conflict, nothing is merged - the candidate generator is likely
+	// misconfigured. Applies once at least MinSample candidates are evaluated.
+	// Candidates that conflicted on an earlier run are not counted.
+	// 0 = disabled.
+	MaxConflictRate float64
+	MinSample       int
+
+	Progress func(done, total int) // called after each candidate is handled
 }
 
 // AutoMerger evaluates merge candidates and executes auto-merges when appropriate.
 // It is conservative - false positives (wrongly merging different people) are much
 // worse than duplicates (keeping them separate).
 type AutoMerger struct {
-	db *sql.DB
+	db    *sql.DB
+	cache *EntityCache // Optional: invalidated on merge
 }
 
 // NewAutoMerger creates a new AutoMerger.
@@ -67,6 +103,12 @@ func NewAutoMerger(db *sql.DB) *AutoMerger {
 	return &AutoMerger{db: db}
 }
 
+// SetCache sets the hot-entity cache to invalidate when entities are merged.
+// Pass the pipeline's cache when merging while a batch is running.
+func (m *AutoMerger) SetCache(cache *EntityCache) {
+	m.cache = cache
+}
+
 // DetectConflicts checks for conflicts between two entities that would prevent merging.
 // Conflicts include:
 // - Different hard identifiers of the same type (both have phones, but different phones)
@@ -143,12 +185,15 @@ func (m *AutoMerger) checkDifferentAliases(ctx context.Context, entityAID, entit
 	return nil, nil
 }
 
-// getEntityAliases returns all aliases of a given type for an entity.
+// getEntityAliases returns the personal (non-shared) aliases of a given type
+// for an entity. Shared aliases say nothing about whether two entities are the
+// same person, so they neither cause nor mask a conflict.
 func (m *AutoMerger) getEntityAliases(ctx context.Context, entityID, aliasType string) ([]string, error) {
 	rows, err := m.db.QueryContext(ctx, `
 		SELECT normalized
 		FROM entity_aliases
 		WHERE entity_id = ? AND alias_type = ?
+		  AND NOT COALESCE(is_shared, FALSE)
 	`, entityID, aliasType)
 	if err != nil {
 		return nil, err
@@ -233,15 +278,16 @@ func (m *AutoMerger) ShouldAutoMerge(candidate *MergeCandidate) bool {
 		return false
 	}
 
-	// Rule 2: Hard identifier with high confidence (≥0.95)
+	// Rule 2: Hard identifier with high confidence (≥0.95). A shared alias
+	// (family email, landline) is weak evidence, not a hard identifier.
+	hardMatches := m.countHardIdentifierMatches(candidate.MatchingFacts)
 	if candidate.Reason == string(ReasonHardIdentifier) || candidate.Reason == string(ReasonMultipleHardIDs) {
-		if candidate.Confidence >= 0.95 {
+		if candidate.Confidence >= 0.95 && (hardMatches > 0 || !hasSharedFact(candidate.MatchingFacts)) {
 			return true
 		}
 	}
 
 	// Rule 3: Multiple hard identifiers match (any confidence, since confidence is already 0.99)
-	hardMatches := m.countHardIdentifierMatches(candidate.MatchingFacts)
 	if hardMatches >= 2 {
 		return true
 	}
@@ -255,14 +301,33 @@ func (m *AutoMerger) ShouldAutoMerge(candidate *MergeCandidate) bool {
 		}
 	}
 
+	// Rule 5: Location variants of the same gazetteer place ("Austin TX" / "ATX")
+	if candidate.Reason == ReasonGeoNormalization && candidate.AutoEligible {
+		if candidate.Confidence >= GeoNormalizationConfidence {
+			return true
+		}
+	}
+
+	// Rule 6: Same company by normalized name and email domain. A well-known
+	// alias (Facebook/Meta) alone needs review.
+	if candidate.Reason == ReasonOrgNormalization && candidate.AutoEligible {
+		if candidate.Confidence >= OrgNameAndDomainConfidence {
+			return true
+		}
+	}
+
 	// Default: Don't auto-merge (require human review)
 	return false
 }
 
 // countHardIdentifierMatches counts how many hard identifier matches are in the matching facts.
+// Facts marked shared (see markSharedFacts) are not counted.
 func (m *AutoMerger) countHardIdentifierMatches(facts []map[string]interface{}) int {
 	count := 0
 	for _, fact := range facts {
+		if shared, _ := fact["shared"].(bool); shared {
+			continue
+		}
 		if factType, ok := fact["type"].(string); ok {
 			for _, hardType := range HardIdentifierTypes {
 				if factType == hardType {
@@ -275,6 +340,41 @@ func (m *AutoMerger) countHardIdentifierMatches(facts []map[string]interface{})
 	return count
 }
 
+// hasSharedFact reports whether any matching fact is marked shared.
+func hasSharedFact(facts []map[string]interface{}) bool {
+	for _, fact := range facts {
+		if shared, _ := fact["shared"].(bool); shared {
+			return true
+		}
+	}
+	return false
+}
+
+// markSharedFacts flags the candidate's hard identifier facts whose value is
+// now a shared alias of either entity with "shared": true.
+func (m *AutoMerger) markSharedFacts(ctx context.Context, candidate *MergeCandidate) error {
+	for _, fact := range candidate.MatchingFacts {
+		factType, _ := fact["type"].(string)
+		value, _ := fact["value"].(string)
+		if factType == "" || value == "" {
+			continue
+		}
+		var shared int
+		err := m.db.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM entity_aliases
+			WHERE entity_id IN (?, ?) AND alias_type = ? AND normalized = ?
+			  AND COALESCE(is_shared, FALSE)
+		`, candidate.EntityAID, candidate.EntityBID, factType, value).Scan(&shared)
+		if err != nil {
+			return fmt.Errorf("check shared alias: %w", err)
+		}
+		if shared > 0 {
+			fact["shared"] = true
+		}
+	}
+	return nil
+}
+
 // ExecuteMerge merges entity A into entity B (A is source, B is target).
 // Source entity is marked as merged_into target; target entity remains active.
 func (m *AutoMerger) ExecuteMerge(ctx context.Context, candidate *MergeCandidate, resolvedBy string) (*MergeResult, error) {
@@ -372,6 +472,22 @@ func (m *AutoMerger) ExecuteMerge(ctx context.Context, candidate *MergeCandidate
 		return nil, fmt.Errorf("commit transaction: %w", err)
 	}
 
+	// Cached lookups for either side are stale after the merge
+	m.cache.InvalidateEntity(sourceID)
+	m.cache.InvalidateEntity(targetID)
+
+	// Current facts of both sides (and entities pointing at them) changed
+	if err := NewCurrentFactsStore(m.db).RefreshAfterMerge(ctx, sourceID, targetID); err != nil {
+		// Non-fatal - the current_facts maintenance task repairs the table
+		_ = err
+	}
+
+	// Point the source's blocking keys at the target
+	if err := NewBlockingIndex(m.db).MoveEntity(ctx, sourceID, targetID); err != nil {
+		// Non-fatal - merged entities are excluded from blocking lookups anyway
+		_ = err
+	}
+
 	return result, nil
 }
 
@@ -422,16 +538,28 @@ func (m *AutoMerger) moveRelationships(ctx context.Context, tx *sql.Tx, sourceID
 }
 
 // moveMentions moves episode_entity_mentions from source to target.
-// Uses INSERT OR REPLACE to handle cases where both entities are mentioned in same episode.
+// Uses INSERT OR REPLACE to handle cases where both entities are mentioned in same episode,
+// combining their mention offsets.
 func (m *AutoMerger) moveMentions(ctx context.Context, tx *sql.Tx, sourceID, targetID string) (int, error) {
 	// First, merge mentions where both entities appear in the same episode
 	// (add mention counts together)
 	_, err := tx.ExecContext(ctx, `
-		INSERT OR REPLACE INTO episode_entity_mentions (episode_id, entity_id, mention_count, created_at)
+		INSERT OR REPLACE INTO episode_entity_mentions (episode_id, entity_id, mention_count, mention_offsets, created_at)
 		SELECT
 			eem1.episode_id,
 			? as entity_id,
 			COALESCE(eem1.mention_count, 0) + COALESCE(eem2.mention_count, 0) as mention_count,
+			CASE
+				WHEN eem2.mention_offsets IS NULL THEN eem1.mention_offsets
+				WHEN eem1.mention_offsets IS NULL THEN eem2.mention_offsets
+				ELSE (
+					SELECT json_group_array(json(value)) FROM (
+						SELECT value FROM json_each(eem2.mention_offsets)
+						UNION ALL
+						SELECT value FROM json_each(eem1.mention_offsets)
+					)
+				)
+			END as mention_offsets,
 			MIN(eem1.created_at, COALESCE(eem2.created_at, eem1.created_at)) as created_at
 		FROM episode_entity_mentions eem1
 		LEFT JOIN episode_entity_mentions eem2
@@ -455,9 +583,20 @@ func (m *AutoMerger) moveMentions(ctx context.Context, tx *sql.Tx, sourceID, tar
 	return int(rows), nil
 }
 
-// maybeUpdateCanonicalName updates the target's canonical name if source has a better name.
-// "Better" means: more complete (longer but not excessively), or title-cased vs all-caps.
+// maybeUpdateCanonicalName picks the target's canonical name after a merge and
+// records any change in the name history. In order of preference:
+// - a locked target name is never changed; a locked source name wins (and stays locked)
+// - a contact-card name beats an extracted one, the target's first
+// - otherwise the source name wins only if it is "better" (see isBetterName)
 func (m *AutoMerger) maybeUpdateCanonicalName(ctx context.Context, tx *sql.Tx, sourceID, targetID string) error {
+	targetLocked, err := isNameLocked(ctx, tx, targetID)
+	if err != nil {
+		return err
+	}
+	if targetLocked {
+		return nil
+	}
+
 	sourceName, err := m.getEntityCanonicalNameTx(ctx, tx, sourceID)
 	if err != nil {
 		return err
@@ -468,16 +607,34 @@ func (m *AutoMerger) maybeUpdateCanonicalName(ctx context.Context, tx *sql.Tx, s
 		return err
 	}
 
-	if m.isBetterName(sourceName, targetName) {
-		_, err = tx.ExecContext(ctx, `
-			UPDATE entities
-			SET canonical_name = ?, updated_at = ?
-			WHERE id = ?
-		`, sourceName, time.Now().Format(time.RFC3339), targetID)
+	sourceLocked, err := isNameLocked(ctx, tx, sourceID)
+	if err != nil {
+		return err
+	}
+
+	newName, fromID := "", ""
+	if sourceLocked {
+		newName, fromID = sourceName, sourceID
+		if _, err := tx.ExecContext(ctx, `UPDATE entities SET name_locked = 1 WHERE id = ?`, targetID); err != nil {
+			return err
+		}
+	} else if contactName, err := contactCardName(ctx, tx, targetID); err != nil {
 		return err
+	} else if contactName != "" {
+		newName = contactName
+	} else if contactName, err := contactCardName(ctx, tx, sourceID); err != nil {
+		return err
+	} else if contactName != "" {
+		newName, fromID = contactName, sourceID
+	} else if m.isBetterName(sourceName, targetName) {
+		newName, fromID = sourceName, sourceID
 	}
 
-	return nil
+	if newName == "" || newName == targetName {
+		return nil
+	}
+	_, err = renameEntity(ctx, tx, targetID, targetName, newName, NameChangeMerge, fromID, "auto")
+	return err
 }
 
 // getEntityCanonicalName returns the canonical name for an entity.
@@ -540,55 +697,172 @@ func (m *AutoMerger) isBetterName(source, target string) bool {
 // 2. Update candidate with conflict information
 // 3. Auto-merge if eligible, otherwise leave for human review
 func (m *AutoMerger) ProcessMergeCandidates(ctx context.Context) (*ProcessMergeCandidatesResult, error) {
+	return m.ProcessMergeCandidatesBatch(ctx, MergeBatchOptions{})
+}
+
+// ProcessMergeCandidatesBatch processes up to opts.BatchSize pending merge
+// candidates, least recently evaluated first, so repeated or interrupted runs
+// work through the whole queue. Conflicts are detected for the whole batch
+// before anything is merged; if the conflict rate trips the kill switch the
+// run is aborted with no merges. Per-candidate failures are reported in the
+// result instead of stopping the run.
+func (m *AutoMerger) ProcessMergeCandidatesBatch(ctx context.Context, opts MergeBatchOptions) (*ProcessMergeCandidatesResult, error) {
 	result := &ProcessMergeCandidatesResult{
 		MergeResults: make([]*MergeResult, 0),
 	}
 
-	// Get all pending candidates
-	candidates, err := m.GetPendingCandidates(ctx)
+	// Mark newly evident shared aliases first, so candidates proposed on a
+	// family email or landline are not merged on it
+	if _, err := NewSharedAliasDetector(m.db).Detect(ctx, true); err != nil {
+		// Non-fatal - fall back to the existing is_shared flags
+		_ = err
+	}
+
+	var pending int
+	if err := m.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM merge_candidates WHERE status = 'pending'
+	`).Scan(&pending); err != nil {
+		return nil, fmt.Errorf("count pending candidates: %w", err)
+	}
+
+	candidates, err := m.nextBatch(ctx, opts.BatchSize)
 	if err != nil {
 		return nil, fmt.Errorf("get pending candidates: %w", err)
 	}
+	result.Remaining = pending - len(candidates)
+
+	fail := func(candidateID, stage string, err error) {
+		result.Failed++
+		result.Errors = append(result.Errors, MergeCandidateError{CandidateID: candidateID, Stage: stage, Error: err.Error()})
+	}
 
+	// Phase 1: detect conflicts for the whole batch. Candidates that already
+	// conflicted on an earlier run are waiting for review and say nothing
+	// about the candidate generator, so the kill switch leaves them out.
+	now := time.Now().Format(time.RFC3339)
+	var eligible []MergeCandidate
+	var knownConflicts int
 	for _, candidate := range candidates {
 		result.Processed++
+		known := len(candidate.Conflicts) > 0
+		if _, err := m.db.ExecContext(ctx, `
+			UPDATE merge_candidates SET last_evaluated_at = ? WHERE id = ?
+		`, now, candidate.ID); err != nil {
+			fail(candidate.ID, "update", err)
+			continue
+		}
 
 		// Detect conflicts for this pair
 		conflicts, err := m.DetectConflicts(ctx, candidate.EntityAID, candidate.EntityBID)
 		if err != nil {
-			// Log but continue
+			fail(candidate.ID, "detect_conflicts", err)
 			continue
 		}
 		candidate.Conflicts = conflicts
 
-		// Update candidate with conflicts
+		// Update candidate with conflicts and where each value came from
 		if len(conflicts) > 0 {
-			err = m.updateCandidateConflicts(ctx, &candidate)
-			if err != nil {
-				// Log but continue
+			if reviews, err := m.GatherConflictEvidence(ctx, &candidate); err != nil {
+				// Non-fatal - the conflicts are recorded without evidence
+				_ = err
+			} else {
+				if candidate.Context == nil {
+					candidate.Context = make(map[string]interface{})
+				}
+				candidate.Context["conflict_evidence"] = reviews
+			}
+			if err := m.updateCandidateConflicts(ctx, &candidate); err != nil {
+				fail(candidate.ID, "update", err)
 				continue
 			}
 			result.Conflicts++
+			if known {
+				knownConflicts++
+			}
 			continue
 		}
 
-		// Check if should auto-merge
+		if err := m.markSharedFacts(ctx, &candidate); err != nil {
+			fail(candidate.ID, "detect_conflicts", err)
+			continue
+		}
+		eligible = append(eligible, candidate)
+	}
+
+	// Kill switch: a batch that is mostly new conflicts means bad candidates
+	evaluated := result.Processed - result.Failed - knownConflicts
+	if opts.MaxConflictRate > 0 && evaluated > 0 && evaluated >= opts.MinSample {
+		rate := float64(result.Conflicts-knownConflicts) / float64(evaluated)
+		if rate > opts.MaxConflictRate {
+			result.Aborted = true
+			result.AbortReason = fmt.Sprintf("%.0f%% of %d candidates conflict (limit %.0f%%); check the candidate generator before merging",
+				rate*100, evaluated, opts.MaxConflictRate*100)
+			result.NeedsReview = len(eligible)
+			return result, nil
+		}
+	}
+
+	// Phase 2: merge
+	done := result.Processed - len(eligible)
+	if opts.Progress != nil {
+		opts.Progress(done, len(candidates))
+	}
+	for _, candidate := range eligible {
 		if m.ShouldAutoMerge(&candidate) {
-			mergeResult, err := m.ExecuteMerge(ctx, &candidate, "auto")
-			if err != nil {
-				// Log but continue
-				continue
+			// An earlier merge in this batch may have absorbed one side
+			if merged, err := m.eitherMerged(ctx, candidate.EntityAID, candidate.EntityBID); err != nil {
+				fail(candidate.ID, "check", err)
+			} else if merged {
+				fail(candidate.ID, "check", fmt.Errorf("entity already merged in this batch"))
+			} else if mergeResult, err := m.ExecuteMerge(ctx, &candidate, "auto"); err != nil {
+				fail(candidate.ID, "merge", err)
+			} else {
+				result.AutoMerged++
+				result.MergeResults = append(result.MergeResults, mergeResult)
 			}
-			result.AutoMerged++
-			result.MergeResults = append(result.MergeResults, mergeResult)
 		} else {
 			result.NeedsReview++
 		}
+		done++
+		if opts.Progress != nil {
+			opts.Progress(done, len(candidates))
+		}
 	}
 
 	return result, nil
 }
 
+// eitherMerged reports whether either entity has been merged into another.
+func (m *AutoMerger) eitherMerged(ctx context.Context, entityAID, entityBID string) (bool, error) {
+	var merged int
+	err := m.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM entities WHERE id IN (?, ?) AND merged_into IS NOT NULL
+	`, entityAID, entityBID).Scan(&merged)
+	return merged > 0, err
+}
+
+// nextBatch returns up to limit pending candidates (0 = all), never-evaluated
+// first, then least recently evaluated, then by confidence.
+func (m *AutoMerger) nextBatch(ctx context.Context, limit int) ([]MergeCandidate, error) {
+	query := `
+		SELECT id, entity_a_id, entity_b_id, confidence, auto_eligible,
+		       reason, matching_facts, context, conflicts, status, created_at,
+		       resolved_at, resolved_by, resolution_reason
+		FROM merge_candidates
+		WHERE status = 'pending'
+		ORDER BY last_evaluated_at IS NOT NULL, last_evaluated_at, confidence DESC, created_at ASC
+	`
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	rows, err := m.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanMergeCandidates(rows)
+}
+
 // GetPendingCandidates returns all pending merge candidates.
 func (m *AutoMerger) GetPendingCandidates(ctx context.Context) ([]MergeCandidate, error) {
 	rows, err := m.db.QueryContext(ctx, `
@@ -603,7 +877,12 @@ func (m *AutoMerger) GetPendingCandidates(ctx context.Context) ([]MergeCandidate
 		return nil, err
 	}
 	defer rows.Close()
+	return scanMergeCandidates(rows)
+}
 
+// scanMergeCandidates scans merge_candidates rows selected in the column
+// order of GetPendingCandidates.
+func scanMergeCandidates(rows *sql.Rows) ([]MergeCandidate, error) {
 	var candidates []MergeCandidate
 	for rows.Next() {
 		var c MergeCandidate
@@ -644,16 +923,23 @@ func (m *AutoMerger) GetPendingCandidates(ctx context.Context) ([]MergeCandidate
 	return candidates, rows.Err()
 }
 
-// updateCandidateConflicts updates a merge candidate with detected conflicts.
+// updateCandidateConflicts updates a merge candidate with detected conflicts
+// and its context (which carries the conflict evidence).
 func (m *AutoMerger) updateCandidateConflicts(ctx context.Context, candidate *MergeCandidate) error {
 	conflictsJSON, _ := json.Marshal(candidate.Conflicts)
+	var contextJSON interface{}
+	if candidate.Context != nil {
+		data, _ := json.Marshal(candidate.Context)
+		contextJSON = string(data)
+	}
 
 	_, err := m.db.ExecContext(ctx, `
 		UPDATE merge_candidates
 		SET conflicts = ?,
+		    context = COALESCE(?, context),
 		    auto_eligible = FALSE
 		WHERE id = ?
-	`, string(conflictsJSON), candidate.ID)
+	`, string(conflictsJSON), contextJSON, candidate.ID)
 
 	return err
 }
@@ -28,10 +28,34 @@ func setupAutoMergerTestDB(t *testing.T) *sql.DB {
 			origin TEXT,
 			confidence REAL,
 			merged_into TEXT REFERENCES entities(id),
+			name_locked INTEGER NOT NULL DEFAULT 0,
 			created_at TEXT NOT NULL,
 			updated_at TEXT NOT NULL
 		);
 
+		CREATE TABLE entity_name_history (
+			id TEXT PRIMARY KEY,
+			entity_id TEXT NOT NULL,
+			old_name TEXT NOT NULL,
+			new_name TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			source_entity_id TEXT,
+			changed_by TEXT NOT NULL,
+			created_at TEXT NOT NULL
+		);
+
+		CREATE TABLE persons (
+			id TEXT PRIMARY KEY,
+			canonical_name TEXT NOT NULL
+		);
+
+		CREATE TABLE person_entity_links (
+			person_id TEXT PRIMARY KEY,
+			entity_id TEXT NOT NULL,
+			method TEXT NOT NULL,
+			created_at INTEGER NOT NULL
+		);
+
 		CREATE TABLE entity_aliases (
 			id TEXT PRIMARY KEY,
 			entity_id TEXT NOT NULL REFERENCES entities(id),
@@ -52,7 +76,9 @@ func setupAutoMergerTestDB(t *testing.T) *sql.DB {
 			valid_at TEXT,
 			invalid_at TEXT,
 			created_at TEXT NOT NULL,
-			confidence REAL
+			confidence REAL,
+			weight REAL DEFAULT 0,
+			source_type TEXT
 		);
 
 		CREATE TABLE episodes (
@@ -70,10 +96,34 @@ func setupAutoMergerTestDB(t *testing.T) *sql.DB {
 			episode_id TEXT NOT NULL,
 			entity_id TEXT NOT NULL,
 			mention_count INTEGER DEFAULT 1,
+			mention_offsets TEXT,
 			created_at TEXT NOT NULL,
 			PRIMARY KEY (episode_id, entity_id)
 		);
 
+		CREATE TABLE episode_relationship_mentions (
+			id TEXT PRIMARY KEY,
+			episode_id TEXT NOT NULL,
+			relationship_id TEXT,
+			extracted_fact TEXT NOT NULL,
+			source_type TEXT,
+			target_literal TEXT,
+			alias_id TEXT,
+			created_at TEXT NOT NULL
+		);
+
+		CREATE TABLE events (
+			id TEXT PRIMARY KEY,
+			timestamp INTEGER NOT NULL,
+			content TEXT
+		);
+
+		CREATE TABLE episode_events (
+			episode_id TEXT NOT NULL,
+			event_id TEXT NOT NULL,
+			position INTEGER NOT NULL
+		);
+
 		CREATE TABLE merge_candidates (
 			id TEXT PRIMARY KEY,
 			entity_a_id TEXT NOT NULL REFERENCES entities(id),
@@ -85,6 +135,7 @@ func setupAutoMergerTestDB(t *testing.T) *sql.DB {
 			context TEXT,
 			conflicts TEXT,
 			status TEXT DEFAULT 'pending',
+			last_evaluated_at TEXT,
 			created_at TEXT NOT NULL,
 			resolved_at TEXT,
 			resolved_by TEXT,
@@ -753,6 +804,180 @@ func TestProcessMergeCandidates_DetectsConflicts(t *testing.T) {
 	}
 }
 
+func TestShouldAutoMerge_SharedAliasIsWeak(t *testing.T) {
+	merger := &AutoMerger{}
+
+	candidate := &MergeCandidate{
+		Confidence: 0.95,
+		Reason:     "hard_identifier",
+		MatchingFacts: []map[string]interface{}{
+			{"type": "email", "value": "smiths@example.com", "shared": true},
+		},
+	}
+	if merger.ShouldAutoMerge(candidate) {
+		t.Error("expected should NOT auto-merge on a shared alias alone")
+	}
+
+	candidate.MatchingFacts = append(candidate.MatchingFacts, map[string]interface{}{"type": "phone", "value": "+15551234567"})
+	if !merger.ShouldAutoMerge(candidate) {
+		t.Error("expected should auto-merge when a personal identifier also matches")
+	}
+}
+
+func TestProcessMergeCandidates_SharedAlias(t *testing.T) {
+	db := setupAutoMergerTestDB(t)
+	defer db.Close()
+
+	// Proposed on an email that turns out to be a family address
+	createTestEntity(t, db, "entity-a", "Ann Smith", 1)
+	createTestEntity(t, db, "entity-b", "Bob Smith", 1)
+	createTestAlias(t, db, "entity-a", "test@example.com", "email", "test@example.com", false)
+	createTestAlias(t, db, "entity-b", "test@example.com", "email", "test@example.com", false)
+	createTestMergeCandidate(t, db, "entity-a", "entity-b", 0.95, true, "hard_identifier")
+
+	merger := NewAutoMerger(db)
+	result, err := merger.ProcessMergeCandidates(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.AutoMerged != 0 || result.NeedsReview != 1 {
+		t.Errorf("expected review instead of auto-merge, got %+v", result)
+	}
+
+	// A shared alias does not mask different personal phones
+	createTestAlias(t, db, "entity-a", "+1-555-111-1111", "phone", "+15551111111", false)
+	createTestAlias(t, db, "entity-b", "+1-555-222-2222", "phone", "+15552222222", false)
+	createTestAlias(t, db, "entity-a", "555-0100", "phone", "5550100", true)
+	createTestAlias(t, db, "entity-b", "555-0100", "phone", "5550100", true)
+	conflicts, err := merger.DetectConflicts(context.Background(), "entity-a", "entity-b")
+	if err != nil {
+		t.Fatalf("DetectConflicts: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Type != "different_phones" {
+		t.Errorf("expected different_phones conflict, got %+v", conflicts)
+	}
+}
+
+func TestProcessMergeCandidatesBatch_Resumes(t *testing.T) {
+	db := setupAutoMergerTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		createTestEntity(t, db, id, "Tyler "+id, 1)
+	}
+	// Low confidence: evaluated but left for review
+	createTestMergeCandidate(t, db, "a", "b", 0.80, false, "hard_identifier")
+	createTestMergeCandidate(t, db, "c", "d", 0.70, false, "hard_identifier")
+
+	merger := NewAutoMerger(db)
+	var progress []int
+	first, err := merger.ProcessMergeCandidatesBatch(ctx, MergeBatchOptions{
+		BatchSize: 1,
+		Progress:  func(done, total int) { progress = append(progress, done) },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.Processed != 1 || first.NeedsReview != 1 || first.Remaining != 1 || len(progress) == 0 {
+		t.Errorf("first batch = %+v, progress %v", first, progress)
+	}
+
+	// The next run picks up the candidate not yet evaluated
+	second, err := merger.ProcessMergeCandidatesBatch(ctx, MergeBatchOptions{BatchSize: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var unevaluated int
+	db.QueryRow(`SELECT COUNT(*) FROM merge_candidates WHERE last_evaluated_at IS NULL`).Scan(&unevaluated)
+	if second.Processed != 1 || unevaluated != 0 {
+		t.Errorf("second batch = %+v, %d never evaluated", second, unevaluated)
+	}
+}
+
+func TestProcessMergeCandidatesBatch_KillSwitch(t *testing.T) {
+	db := setupAutoMergerTestDB(t)
+	defer db.Close()
+
+	for _, id := range []string{"a", "b", "c", "d", "e", "f"} {
+		createTestEntity(t, db, id, "Tyler "+id, 1)
+	}
+	createTestAlias(t, db, "a", "+1-555-111-1111", "phone", "+15551111111", false)
+	createTestAlias(t, db, "b", "+1-555-222-2222", "phone", "+15552222222", false)
+	createTestAlias(t, db, "c", "+1-555-333-3333", "phone", "+15553333333", false)
+	createTestAlias(t, db, "d", "+1-555-444-4444", "phone", "+15554444444", false)
+	createTestMergeCandidate(t, db, "a", "b", 0.95, true, "hard_identifier")
+	createTestMergeCandidate(t, db, "c", "d", 0.95, true, "hard_identifier")
+	createTestMergeCandidate(t, db, "e", "f", 0.95, true, "hard_identifier")
+
+	merger := NewAutoMerger(db)
+	result, err := merger.ProcessMergeCandidatesBatch(context.Background(), MergeBatchOptions{MaxConflictRate: 0.5, MinSample: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Aborted || result.AutoMerged != 0 || result.Conflicts != 2 {
+		t.Errorf("expected abort with no merges, got %+v", result)
+	}
+
+	// Below the minimum sample the kill switch does not apply
+	db.Exec(`UPDATE merge_candidates SET last_evaluated_at = NULL`)
+	result, err = merger.ProcessMergeCandidatesBatch(context.Background(), MergeBatchOptions{MaxConflictRate: 0.5, MinSample: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Aborted || result.AutoMerged != 1 {
+		t.Errorf("expected the clean candidate merged, got %+v", result)
+	}
+}
+
+func TestProcessMergeCandidatesBatch_KillSwitchIgnoresKnownConflicts(t *testing.T) {
+	db := setupAutoMergerTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		createTestEntity(t, db, id, "Tyler "+id, 1)
+	}
+	createTestAlias(t, db, "a", "+1-555-111-1111", "phone", "+15551111111", false)
+	createTestAlias(t, db, "b", "+1-555-222-2222", "phone", "+15552222222", false)
+	createTestMergeCandidate(t, db, "a", "b", 0.95, true, "hard_identifier")
+
+	merger := NewAutoMerger(db)
+	if _, err := merger.ProcessMergeCandidatesBatch(ctx, MergeBatchOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The conflict now waits for review; a new clean candidate is merged
+	createTestMergeCandidate(t, db, "c", "d", 0.95, true, "hard_identifier")
+	result, err := merger.ProcessMergeCandidatesBatch(ctx, MergeBatchOptions{MaxConflictRate: 0.4, MinSample: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Aborted || result.Conflicts != 1 || result.AutoMerged != 1 {
+		t.Errorf("expected known conflict ignored by the kill switch, got %+v", result)
+	}
+}
+
+func TestProcessMergeCandidatesBatch_ReportsErrors(t *testing.T) {
+	db := setupAutoMergerTestDB(t)
+	defer db.Close()
+
+	createTestEntity(t, db, "a", "Tyler A", 1)
+	createTestEntity(t, db, "b", "Tyler B", 1)
+	createTestEntity(t, db, "c", "Tyler C", 1)
+	createTestMergeCandidate(t, db, "a", "b", 0.95, true, "hard_identifier")
+	// Same source entity: by its turn "a" is already merged into "b"
+	createTestMergeCandidate(t, db, "a", "c", 0.95, true, "hard_identifier")
+
+	result, err := NewAutoMerger(db).ProcessMergeCandidatesBatch(context.Background(), MergeBatchOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.AutoMerged != 1 || result.Failed != 1 || len(result.Errors) != 1 || result.Errors[0].Stage != "check" {
+		t.Errorf("expected one merge and one reported failure, got %+v", result)
+	}
+}
+
 func TestIsBetterName(t *testing.T) {
 	merger := &AutoMerger{}
 
@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+// Benchmarks run with 'make bench'; MNEMONIC_BENCH_SCALE=0.01 shrinks the
+// workloads for a quick pass.
+
+const (
+	benchGraphEntities = 50_000
+	benchGraphEdges    = 500_000
+	benchAliasEntities = 100_000
+	benchEmbeddingDims = 3072 // gemini-embedding-001
+)
+
+func BenchmarkQueryEngine(b *testing.B) {
+	db := testutil.OpenTestDB(b)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ids := testutil.GenerateGraph(b, db, testutil.GraphSpec{
+		Entities: testutil.Scaled(benchGraphEntities),
+		Edges:    testutil.Scaled(benchGraphEdges),
+	})
+	ctx := context.Background()
+	q := NewQueryEngine(db)
+	defer q.Close()
+	rng := rand.New(rand.NewSource(1))
+
+	// Entity 0 is the biggest hub; random entities have a handful of edges
+	b.Run("related/hub", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := q.GetRelatedEntities(ctx, ids[0], DefaultQueryOptions()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("related/random", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := q.GetRelatedEntities(ctx, ids[rng.Intn(len(ids))], DefaultQueryOptions()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("related/batch100", func(b *testing.B) {
+		batch := make([]string, 100)
+		for i := 0; i < b.N; i++ {
+			for j := range batch {
+				batch[j] = ids[rng.Intn(len(ids))]
+			}
+			if _, err := q.GetRelatedEntitiesBatch(ctx, batch, DefaultQueryOptions()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("graph_query/two_hops", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			expr := fmt.Sprintf(`id("%s").out().out("KNOWS").limit(100)`, ids[rng.Intn(len(ids))])
+			if _, err := q.RunGraphQuery(ctx, expr); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkAliasResolution(b *testing.B) {
+	db := testutil.OpenTestDB(b)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	n := testutil.Scaled(benchAliasEntities)
+	testutil.GenerateGraph(b, db, testutil.GraphSpec{Entities: n, AliasesPerEntity: 2})
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(1))
+
+	run := func(b *testing.B, r *EntityResolver, names func() string) {
+		for i := 0; i < b.N; i++ {
+			if _, err := r.findAliasCandidates(ctx, names(), EntityTypePerson); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	random := func() string { return fmt.Sprintf("entity %d alt1", rng.Intn(n)) }
+
+	b.Run("uncached", func(b *testing.B) {
+		run(b, NewEntityResolver(db, nil, ""), random)
+	})
+	b.Run("miss", func(b *testing.B) {
+		run(b, NewEntityResolver(db, nil, ""), func() string { return fmt.Sprintf("nobody %d", rng.Int()) })
+	})
+	b.Run("cached_hot", func(b *testing.B) {
+		// Real workloads mention the same few hundred people over and over
+		r := NewEntityResolver(db, nil, "")
+		r.SetCache(NewEntityCache(1000))
+		run(b, r, func() string { return fmt.Sprintf("entity %d alt1", rng.Intn(min(n, 500))) })
+	})
+}
+
+func BenchmarkEmbeddingBlob(b *testing.B) {
+	values := testutil.Embedding(1, benchEmbeddingDims)
+	blob := float64SliceToBlob(values)
+
+	b.Run("encode", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(blob)))
+		for i := 0; i < b.N; i++ {
+			_ = float64SliceToBlob(values)
+		}
+	})
+	b.Run("decode", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(blob)))
+		for i := 0; i < b.N; i++ {
+			if len(blobToFloat64Slice(blob)) != benchEmbeddingDims {
+				b.Fatal("bad decode")
+			}
+		}
+	})
+	b.Run("decode_cosine", func(b *testing.B) {
+		other := float64SliceToBlob(testutil.Embedding(2, benchEmbeddingDims))
+		for i := 0; i < b.N; i++ {
+			_ = cosineSimilarity(blobToFloat64Slice(blob), blobToFloat64Slice(other))
+		}
+	})
+}
@@ -0,0 +1,317 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Blocking key types stored in entity_blocking_keys.
+const (
+	BlockingKeyToken    = "token"    // Normalized name token
+	BlockingKeyPhonetic = "phonetic" // Soundex code of a name token
+	BlockingKeyIdent    = "ident"    // Hash of a normalized hard identifier (email, phone, ...)
+
+	// MaxBlockingCandidates bounds how many entities a single lookup returns.
+	MaxBlockingCandidates = 500
+)
+
+// blockingStopwords are tokens too common to be useful as blocking keys.
+var blockingStopwords = map[string]bool{
+	"the": true, "and": true, "of": true, "inc": true, "llc": true,
+	"ltd": true, "co": true, "corp": true, "mr": true, "mrs": true,
+	"ms": true, "dr": true, "jr": true, "sr": true,
+}
+
+// blockingIdentTypes are alias types whose whole value is keyed by hash
+// rather than tokenized.
+var blockingIdentTypes = map[string]bool{
+	"email":    true,
+	"phone":    true,
+	"handle":   true,
+	"username": true,
+}
+
+// BlockingKey is a cheap, lossy key that groups entities likely to match.
+type BlockingKey struct {
+	Type string
+	Key  string
+}
+
+// BlockingIndex maintains entity_blocking_keys so entity resolution can
+// narrow candidates to entities sharing a key instead of scanning every
+// alias or embedding. Keys are written alongside aliases.
+type BlockingIndex struct {
+	db *sql.DB
+}
+
+// NewBlockingIndex creates a new BlockingIndex.
+func NewBlockingIndex(db *sql.DB) *BlockingIndex {
+	return &BlockingIndex{db: db}
+}
+
+// BlockingKeysFor derives blocking keys for an alias value.
+// Identifier aliases produce a single ident key; names produce token and phonetic keys.
+func BlockingKeysFor(alias, aliasType string) []BlockingKey {
+	alias = strings.TrimSpace(alias)
+	if alias == "" {
+		return nil
+	}
+
+	if blockingIdentTypes[aliasType] {
+		normalized := normalizeIdentityValue(alias, aliasType)
+		return []BlockingKey{{Type: BlockingKeyIdent, Key: aliasType + ":" + hashText(normalized)[:16]}}
+	}
+
+	var keys []BlockingKey
+	seen := make(map[BlockingKey]bool)
+	add := func(k BlockingKey) {
+		if k.Key == "" || seen[k] {
+			return
+		}
+		seen[k] = true
+		keys = append(keys, k)
+	}
+
+	// Names that are really identifiers (e.g., an email extracted as a name)
+	if strings.Contains(alias, "@") && strings.Contains(alias, ".") && !strings.Contains(alias, " ") {
+		add(BlockingKey{Type: BlockingKeyIdent, Key: "email:" + hashText(normalizeIdentityValue(alias, "email"))[:16]})
+	}
+
+	for _, tok := range blockingTokens(alias) {
+		add(BlockingKey{Type: BlockingKeyToken, Key: tok})
+		add(BlockingKey{Type: BlockingKeyPhonetic, Key: soundex(tok)})
+	}
+	return keys
+}
+
+// blockingTokens splits a name into lowercase tokens, dropping stopwords and single characters.
+func blockingTokens(name string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if len([]rune(f)) < 2 || blockingStopwords[f] {
+			continue
+		}
+		tokens = append(tokens, f)
+	}
+	return tokens
+}
+
+// soundex returns the American Soundex code for an ASCII token, or "" if it has no letters.
+func soundex(token string) string {
+	codes := map[rune]byte{
+		'b': '1', 'f': '1', 'p': '1', 'v': '1',
+		'c': '2', 'g': '2', 'j': '2', 'k': '2', 'q': '2', 's': '2', 'x': '2', 'z': '2',
+		'd': '3', 't': '3',
+		'l': '4',
+		'm': '5', 'n': '5',
+		'r': '6',
+	}
+
+	var out []byte
+	var last byte
+	for _, r := range strings.ToLower(token) {
+		if r < 'a' || r > 'z' {
+			continue
+		}
+		code := codes[r]
+		if len(out) == 0 {
+			out = append(out, byte(unicode.ToUpper(r)))
+			last = code
+			continue
+		}
+		if r == 'h' || r == 'w' {
+			continue // h/w do not separate duplicate codes
+		}
+		if code == 0 {
+			last = 0 // vowels separate duplicate codes
+			continue
+		}
+		if code != last {
+			out = append(out, code)
+			if len(out) == 4 {
+				break
+			}
+		}
+		last = code
+	}
+	if len(out) == 0 {
+		return ""
+	}
+	for len(out) < 4 {
+		out = append(out, '0')
+	}
+	return string(out)
+}
+
+// IndexAlias writes the blocking keys for one alias of an entity.
+func (b *BlockingIndex) IndexAlias(ctx context.Context, entityID, alias, aliasType string) error {
+	keys := BlockingKeysFor(alias, aliasType)
+	if len(keys) == 0 {
+		return nil
+	}
+	return b.insertKeys(ctx, entityID, keys)
+}
+
+// insertKeys writes blocking keys for an entity, skipping ones it already has.
+func (b *BlockingIndex) insertKeys(ctx context.Context, entityID string, keys []BlockingKey) error {
+	now := time.Now().Format(time.RFC3339)
+	for _, k := range keys {
+		_, err := b.db.ExecContext(ctx, `
+			INSERT INTO entity_blocking_keys (key_type, key, entity_id, created_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(key_type, key, entity_id) DO NOTHING
+		`, k.Type, k.Key, entityID, now)
+		if err != nil {
+			return fmt.Errorf("insert blocking key: %w", err)
+		}
+	}
+	return nil
+}
+
+// IndexMissing indexes every name and alias of an active entity whose blocking
+// keys are not all present. This catches up aliases written before the index
+// existed or by other writers (contact import, manual edits), including new
+// aliases of entities that already have keys. Returns the number of entities
+// that gained keys.
+func (b *BlockingIndex) IndexMissing(ctx context.Context) (int, error) {
+	type entityKey struct {
+		entityID string
+		key      BlockingKey
+	}
+	existing := make(map[entityKey]bool)
+	keyRows, err := b.db.QueryContext(ctx, `SELECT entity_id, key_type, key FROM entity_blocking_keys`)
+	if err != nil {
+		return 0, fmt.Errorf("query blocking keys: %w", err)
+	}
+	for keyRows.Next() {
+		var ek entityKey
+		if err := keyRows.Scan(&ek.entityID, &ek.key.Type, &ek.key.Key); err != nil {
+			keyRows.Close()
+			return 0, fmt.Errorf("scan blocking key: %w", err)
+		}
+		existing[ek] = true
+	}
+	keyRows.Close()
+	if err := keyRows.Err(); err != nil {
+		return 0, fmt.Errorf("iterate blocking keys: %w", err)
+	}
+
+	rows, err := b.db.QueryContext(ctx, `
+		SELECT e.id, e.canonical_name, 'name' AS alias_type
+		FROM entities e
+		WHERE e.merged_into IS NULL
+		UNION ALL
+		SELECT ea.entity_id, ea.alias, ea.alias_type
+		FROM entity_aliases ea
+		JOIN entities e ON e.id = ea.entity_id
+		WHERE e.merged_into IS NULL
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("query entity aliases: %w", err)
+	}
+
+	// Collect all missing keys first (SQLite single connection)
+	missing := make(map[string][]BlockingKey)
+	var order []string
+	for rows.Next() {
+		var entityID, alias, aliasType string
+		if err := rows.Scan(&entityID, &alias, &aliasType); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan entity alias: %w", err)
+		}
+		for _, k := range BlockingKeysFor(alias, aliasType) {
+			ek := entityKey{entityID, k}
+			if existing[ek] {
+				continue
+			}
+			existing[ek] = true
+			if missing[entityID] == nil {
+				order = append(order, entityID)
+			}
+			missing[entityID] = append(missing[entityID], k)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("iterate entity aliases: %w", err)
+	}
+
+	for i, entityID := range order {
+		if err := b.insertKeys(ctx, entityID, missing[entityID]); err != nil {
+			return i, err
+		}
+	}
+	return len(order), nil
+}
+
+// MoveEntity reassigns blocking keys from a merged entity to its merge target.
+func (b *BlockingIndex) MoveEntity(ctx context.Context, sourceID, targetID string) error {
+	_, err := b.db.ExecContext(ctx, `
+		INSERT INTO entity_blocking_keys (key_type, key, entity_id, created_at)
+		SELECT key_type, key, ?, created_at
+		FROM entity_blocking_keys
+		WHERE entity_id = ?
+		ON CONFLICT(key_type, key, entity_id) DO NOTHING
+	`, targetID, sourceID)
+	if err != nil {
+		return fmt.Errorf("copy blocking keys: %w", err)
+	}
+	_, err = b.db.ExecContext(ctx, `DELETE FROM entity_blocking_keys WHERE entity_id = ?`, sourceID)
+	if err != nil {
+		return fmt.Errorf("delete blocking keys: %w", err)
+	}
+	return nil
+}
+
+// Candidates returns active entity IDs sharing at least one blocking key with
+// the name, ordered by the number of shared keys (most first).
+func (b *BlockingIndex) Candidates(ctx context.Context, name string, limit int) ([]string, error) {
+	keys := BlockingKeysFor(name, "name")
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = MaxBlockingCandidates
+	}
+
+	clauses := make([]string, 0, len(keys))
+	args := make([]interface{}, 0, len(keys)*2+1)
+	for _, k := range keys {
+		clauses = append(clauses, "(bk.key_type = ? AND bk.key = ?)")
+		args = append(args, k.Type, k.Key)
+	}
+	args = append(args, limit)
+
+	rows, err := b.db.QueryContext(ctx, `
+		SELECT bk.entity_id, COUNT(*) AS hits
+		FROM entity_blocking_keys bk
+		JOIN entities e ON e.id = bk.entity_id
+		WHERE e.merged_into IS NULL
+		  AND (`+strings.Join(clauses, " OR ")+`)
+		GROUP BY bk.entity_id
+		ORDER BY hits DESC, bk.entity_id
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query blocking candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		var hits int
+		if err := rows.Scan(&id, &hits); err != nil {
+			return nil, fmt.Errorf("scan blocking candidate: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
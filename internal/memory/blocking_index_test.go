@@ -0,0 +1,194 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// setupBlockingTestDB creates the resolver schema plus entity_blocking_keys.
+func setupBlockingTestDB(t *testing.T) *sql.DB {
+	db := setupResolverTestDB(t)
+	_, err := db.Exec(`
+		CREATE TABLE entity_blocking_keys (
+			key_type TEXT NOT NULL,
+			key TEXT NOT NULL,
+			entity_id TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
+			created_at TEXT NOT NULL,
+			PRIMARY KEY (key_type, key, entity_id)
+		);
+		CREATE INDEX idx_entity_blocking_keys_entity ON entity_blocking_keys(entity_id);
+	`)
+	if err != nil {
+		t.Fatalf("failed to create blocking schema: %v", err)
+	}
+	return db
+}
+
+func TestSoundex(t *testing.T) {
+	tests := map[string]string{
+		"robert":   "R163",
+		"rupert":   "R163",
+		"tymczak":  "T522",
+		"pfister":  "P236",
+		"ashcraft": "A261",
+		"lee":      "L000",
+		"1234":     "",
+	}
+	for in, want := range tests {
+		if got := soundex(in); got != want {
+			t.Errorf("soundex(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBlockingKeysFor(t *testing.T) {
+	keys := BlockingKeysFor("Dr. Casey O'Neil", "name")
+	has := func(typ, key string) bool {
+		for _, k := range keys {
+			if k.Type == typ && k.Key == key {
+				return true
+			}
+		}
+		return false
+	}
+	if !has(BlockingKeyToken, "casey") || !has(BlockingKeyToken, "neil") {
+		t.Errorf("expected name tokens, got %v", keys)
+	}
+	if has(BlockingKeyToken, "dr") || has(BlockingKeyToken, "o") {
+		t.Errorf("stopwords/single chars should be dropped, got %v", keys)
+	}
+	if !has(BlockingKeyPhonetic, "C200") {
+		t.Errorf("expected phonetic key for casey, got %v", keys)
+	}
+
+	a := BlockingKeysFor("Casey@Example.com", "email")
+	b := BlockingKeysFor("casey@example.com ", "email")
+	if len(a) != 1 || a[0].Type != BlockingKeyIdent || a[0] != b[0] {
+		t.Errorf("email keys should be a single normalized ident key: %v vs %v", a, b)
+	}
+	// Email extracted as a name shares the ident key with the email alias
+	c := BlockingKeysFor("casey@example.com", "name")
+	found := false
+	for _, k := range c {
+		if k == a[0] {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("email-like name should include ident key %v, got %v", a[0], c)
+	}
+
+	if BlockingKeysFor("  ", "name") != nil {
+		t.Error("empty alias should produce no keys")
+	}
+}
+
+func TestBlockingIndex_Candidates(t *testing.T) {
+	db := setupBlockingTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	idx := NewBlockingIndex(db)
+
+	insertTestEntity(t, db, "e1", "Casey Adams", EntityTypePerson)
+	insertTestEntity(t, db, "e2", "Casey Brown", EntityTypePerson)
+	insertTestEntity(t, db, "e3", "Jordan Lee", EntityTypePerson)
+	for id, name := range map[string]string{"e1": "Casey Adams", "e2": "Casey Brown", "e3": "Jordan Lee"} {
+		if err := idx.IndexAlias(ctx, id, name, "name"); err != nil {
+			t.Fatalf("IndexAlias: %v", err)
+		}
+	}
+	// Indexing twice is a no-op
+	if err := idx.IndexAlias(ctx, "e1", "Casey Adams", "name"); err != nil {
+		t.Fatalf("IndexAlias (repeat): %v", err)
+	}
+
+	ids, err := idx.Candidates(ctx, "Casey Adamz", 0)
+	if err != nil {
+		t.Fatalf("Candidates: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != "e1" {
+		t.Fatalf("Candidates = %v, want [e1 e2] (e1 shares most keys)", ids)
+	}
+
+	ids, err = idx.Candidates(ctx, "Acme Corp", 0)
+	if err != nil {
+		t.Fatalf("Candidates: %v", err)
+	}
+	if len(ids) != 0 {
+		t.Errorf("Candidates = %v, want none", ids)
+	}
+
+	// Merged entities are excluded; keys follow the merge target
+	if _, err := db.Exec(`UPDATE entities SET merged_into = 'e2' WHERE id = 'e1'`); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if err := idx.MoveEntity(ctx, "e1", "e2"); err != nil {
+		t.Fatalf("MoveEntity: %v", err)
+	}
+	ids, _ = idx.Candidates(ctx, "Adams", 0)
+	if len(ids) != 1 || ids[0] != "e2" {
+		t.Errorf("after merge Candidates = %v, want [e2]", ids)
+	}
+}
+
+func TestBlockingIndex_IndexMissing(t *testing.T) {
+	db := setupBlockingTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertTestEntity(t, db, "e1", "Casey Adams", EntityTypePerson)
+	insertTestAlias(t, db, "a1", "e1", "casey@example.com", "email", false)
+
+	idx := NewBlockingIndex(db)
+	n, err := idx.IndexMissing(ctx)
+	if err != nil {
+		t.Fatalf("IndexMissing: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("IndexMissing indexed %d entities, want 1", n)
+	}
+
+	ids, _ := idx.Candidates(ctx, "casey@example.com", 0)
+	if len(ids) != 1 || ids[0] != "e1" {
+		t.Errorf("Candidates by email = %v, want [e1]", ids)
+	}
+
+	n, err = idx.IndexMissing(ctx)
+	if err != nil || n != 0 {
+		t.Errorf("second IndexMissing = %d, %v; want 0, nil", n, err)
+	}
+
+	// An alias added without the index is caught up even though the entity has keys
+	insertTestAlias(t, db, "a2", "e1", "Casey Brightwater", "name", false)
+	n, err = idx.IndexMissing(ctx)
+	if err != nil || n != 1 {
+		t.Errorf("IndexMissing after new alias = %d, %v; want 1, nil", n, err)
+	}
+	ids, _ = idx.Candidates(ctx, "Brightwater", 0)
+	if len(ids) != 1 || ids[0] != "e1" {
+		t.Errorf("Candidates by new alias = %v, want [e1]", ids)
+	}
+}
+
+func TestEntityResolver_NewEntityIsBlockingIndexed(t *testing.T) {
+	db := setupBlockingTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	resolver := NewEntityResolver(db, nil, "")
+	_, err := resolver.Resolve(ctx, []ExtractedEntity{{ID: 0, Name: "Riley Park", EntityTypeID: EntityTypePerson}}, ResolutionContext{})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+
+	ids, err := NewBlockingIndex(db).Candidates(ctx, "Riley", 0)
+	if err != nil {
+		t.Fatalf("Candidates: %v", err)
+	}
+	if len(ids) != 1 {
+		t.Errorf("new entity should be blocking-indexed, got %v", ids)
+	}
+}
@@ -0,0 +1,230 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+)
+
+// DefaultCalibrationBuckets is the number of equal-width confidence buckets.
+const DefaultCalibrationBuckets = 10
+
+// CalibrationSample is a relationship offered for human spot-checking.
+type CalibrationSample struct {
+	RelationshipID string  `json:"relationship_id"`
+	SourceName     string  `json:"source_name"`
+	RelationType   string  `json:"relation_type"`
+	Target         string  `json:"target"` // Target entity name or literal
+	Fact           string  `json:"fact"`
+	Confidence     float64 `json:"confidence"`
+}
+
+// CalibrationBucket is one point on the calibration curve.
+type CalibrationBucket struct {
+	Lower          float64 `json:"lower"`
+	Upper          float64 `json:"upper"`
+	Relationships  int     `json:"relationships"` // All relationships in this confidence range
+	Labeled        int     `json:"labeled"`
+	Correct        int     `json:"correct"`
+	MeanConfidence float64 `json:"mean_confidence"` // Mean stated confidence of labeled samples
+	Precision      float64 `json:"precision"`       // Correct / Labeled (0 when unlabeled)
+}
+
+// CalibrationReport compares stated confidence with human labels.
+type CalibrationReport struct {
+	Buckets []CalibrationBucket `json:"buckets"`
+	Labeled int                 `json:"labeled"`
+	Correct int                 `json:"correct"`
+	// Expected calibration error: label-weighted mean |precision - confidence|
+	ECE float64 `json:"ece"`
+}
+
+// Calibration samples relationships for review, stores human labels, and
+// reports how well stated confidence tracks observed precision.
+type Calibration struct {
+	db *sql.DB
+}
+
+// NewCalibration creates a new Calibration.
+func NewCalibration(db *sql.DB) *Calibration {
+	return &Calibration{db: db}
+}
+
+// Sample returns up to limit unlabeled relationships for review, spread
+// evenly across confidence buckets so sparse low-confidence ranges still get
+// labels.
+func (c *Calibration) Sample(ctx context.Context, limit, buckets int) ([]CalibrationSample, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	if buckets <= 0 {
+		buckets = DefaultCalibrationBuckets
+	}
+
+	perBucket := (limit + buckets - 1) / buckets
+	var samples []CalibrationSample
+	for i := buckets - 1; i >= 0 && len(samples) < limit; i-- {
+		lower, upper := bucketBounds(i, buckets)
+		bucketSamples, err := c.sampleRange(ctx, lower, upper, i == buckets-1, perBucket)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, bucketSamples...)
+	}
+	if len(samples) > limit {
+		samples = samples[:limit]
+	}
+	return samples, nil
+}
+
+func (c *Calibration) sampleRange(ctx context.Context, lower, upper float64, inclusive bool, limit int) ([]CalibrationSample, error) {
+	upperOp := "<"
+	if inclusive {
+		upperOp = "<="
+	}
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT r.id, s.canonical_name, r.relation_type,
+			COALESCE(t.canonical_name, r.target_literal, ''), r.fact, COALESCE(r.confidence, 1.0)
+		FROM relationships r
+		JOIN entities s ON s.id = r.source_entity_id
+		LEFT JOIN entities t ON t.id = r.target_entity_id
+		LEFT JOIN relationship_labels l ON l.relationship_id = r.id
+		WHERE l.relationship_id IS NULL
+			AND COALESCE(r.confidence, 1.0) >= ? AND COALESCE(r.confidence, 1.0) `+upperOp+` ?
+		ORDER BY RANDOM()
+		LIMIT ?
+	`, lower, upper, limit)
+	if err != nil {
+		return nil, fmt.Errorf("sample relationships: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []CalibrationSample
+	for rows.Next() {
+		var s CalibrationSample
+		if err := rows.Scan(&s.RelationshipID, &s.SourceName, &s.RelationType, &s.Target, &s.Fact, &s.Confidence); err != nil {
+			return nil, fmt.Errorf("scan sample: %w", err)
+		}
+		samples = append(samples, s)
+	}
+	return samples, rows.Err()
+}
+
+// Label records whether a relationship was judged correct, snapshotting its
+// current confidence. Re-labeling replaces the previous label.
+func (c *Calibration) Label(ctx context.Context, relationshipID string, correct bool, note string) error {
+	var confidence float64
+	err := c.db.QueryRowContext(ctx, `SELECT COALESCE(confidence, 1.0) FROM relationships WHERE id = ?`, relationshipID).Scan(&confidence)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("relationship not found: %s", relationshipID)
+	}
+	if err != nil {
+		return fmt.Errorf("load relationship: %w", err)
+	}
+
+	correctInt := 0
+	if correct {
+		correctInt = 1
+	}
+	_, err = c.db.ExecContext(ctx, `
+		INSERT INTO relationship_labels (relationship_id, correct, confidence, note, labeled_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(relationship_id) DO UPDATE SET
+			correct = excluded.correct,
+			confidence = excluded.confidence,
+			note = excluded.note,
+			labeled_at = excluded.labeled_at
+	`, relationshipID, correctInt, confidence, nullIfEmpty(note), time.Now().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("save label: %w", err)
+	}
+	return nil
+}
+
+// Report buckets relationships by stated confidence and compares each
+// bucket's mean confidence against the precision of its human labels.
+func (c *Calibration) Report(ctx context.Context, buckets int) (*CalibrationReport, error) {
+	if buckets <= 0 {
+		buckets = DefaultCalibrationBuckets
+	}
+	report := &CalibrationReport{Buckets: make([]CalibrationBucket, buckets)}
+	confSums := make([]float64, buckets)
+	for i := range report.Buckets {
+		report.Buckets[i].Lower, report.Buckets[i].Upper = bucketBounds(i, buckets)
+	}
+
+	rows, err := c.db.QueryContext(ctx, `SELECT COALESCE(confidence, 1.0) FROM relationships`)
+	if err != nil {
+		return nil, fmt.Errorf("load relationships: %w", err)
+	}
+	for rows.Next() {
+		var conf float64
+		if err := rows.Scan(&conf); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan relationship: %w", err)
+		}
+		report.Buckets[bucketIndex(conf, buckets)].Relationships++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = c.db.QueryContext(ctx, `SELECT COALESCE(confidence, 1.0), correct FROM relationship_labels`)
+	if err != nil {
+		return nil, fmt.Errorf("load labels: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var conf float64
+		var correct int
+		if err := rows.Scan(&conf, &correct); err != nil {
+			return nil, fmt.Errorf("scan label: %w", err)
+		}
+		idx := bucketIndex(conf, buckets)
+		b := &report.Buckets[idx]
+		b.Labeled++
+		confSums[idx] += conf
+		report.Labeled++
+		if correct == 1 {
+			b.Correct++
+			report.Correct++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range report.Buckets {
+		b := &report.Buckets[i]
+		if b.Labeled == 0 {
+			continue
+		}
+		b.MeanConfidence = confSums[i] / float64(b.Labeled)
+		b.Precision = float64(b.Correct) / float64(b.Labeled)
+		report.ECE += float64(b.Labeled) * math.Abs(b.Precision-b.MeanConfidence)
+	}
+	if report.Labeled > 0 {
+		report.ECE /= float64(report.Labeled)
+	}
+	return report, nil
+}
+
+// bucketBounds returns the [lower, upper) range of bucket i.
+func bucketBounds(i, buckets int) (float64, float64) {
+	return float64(i) / float64(buckets), float64(i+1) / float64(buckets)
+}
+
+// bucketIndex maps a confidence to its bucket; 1.0 falls in the last bucket.
+func bucketIndex(conf float64, buckets int) int {
+	idx := int(conf * float64(buckets))
+	if idx < 0 {
+		return 0
+	}
+	if idx >= buckets {
+		return buckets - 1
+	}
+	return idx
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestCalibration(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	db.Exec(`INSERT INTO entities (id, canonical_name, entity_type_id, origin, created_at, updated_at) VALUES ('tyler', 'Tyler', 1, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')`)
+	confidences := []float64{0.95, 0.95, 0.95, 0.95, 0.55, 0.55, 0.15}
+	for i, conf := range confidences {
+		id := fmt.Sprintf("rel-%d", i)
+		if _, err := db.Exec(`INSERT INTO relationships (id, source_entity_id, target_literal, relation_type, fact, created_at, confidence) VALUES (?, 'tyler', 'x', 'LIKES', 'Tyler likes x', '2026-01-01T00:00:00Z', ?)`, id, conf); err != nil {
+			t.Fatalf("insert relationship: %v", err)
+		}
+	}
+
+	calibration := NewCalibration(db)
+
+	samples, err := calibration.Sample(ctx, 3, 10)
+	if err != nil {
+		t.Fatalf("Sample: %v", err)
+	}
+	// One per non-empty bucket, so the low-confidence relationship is included
+	if len(samples) != 3 {
+		t.Fatalf("samples = %+v, want 3", samples)
+	}
+	sawLow := false
+	for _, s := range samples {
+		if s.Confidence == 0.15 {
+			sawLow = true
+		}
+		if s.SourceName != "Tyler" || s.Target != "x" {
+			t.Errorf("sample = %+v", s)
+		}
+	}
+	if !sawLow {
+		t.Error("stratified sample should include the low-confidence bucket")
+	}
+
+	labels := map[string]bool{"rel-0": true, "rel-1": true, "rel-2": true, "rel-3": false, "rel-4": true, "rel-5": false}
+	for id, correct := range labels {
+		if err := calibration.Label(ctx, id, correct, ""); err != nil {
+			t.Fatalf("Label %s: %v", id, err)
+		}
+	}
+	if err := calibration.Label(ctx, "missing", true, ""); err == nil {
+		t.Error("expected error labeling unknown relationship")
+	}
+
+	// Labeled relationships are not sampled again
+	samples, _ = calibration.Sample(ctx, 10, 10)
+	if len(samples) != 1 || samples[0].RelationshipID != "rel-6" {
+		t.Errorf("samples after labeling = %+v, want only rel-6", samples)
+	}
+
+	report, err := calibration.Report(ctx, 10)
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	if report.Labeled != 6 || report.Correct != 4 {
+		t.Errorf("labeled/correct = %d/%d, want 6/4", report.Labeled, report.Correct)
+	}
+	high := report.Buckets[9]
+	if high.Relationships != 4 || high.Labeled != 4 || high.Precision != 0.75 {
+		t.Errorf("high bucket = %+v", high)
+	}
+	mid := report.Buckets[5]
+	if mid.Labeled != 2 || mid.Precision != 0.5 {
+		t.Errorf("mid bucket = %+v", mid)
+	}
+	if report.Buckets[1].Relationships != 1 || report.Buckets[1].Labeled != 0 {
+		t.Errorf("low bucket = %+v", report.Buckets[1])
+	}
+	// ECE = (4*|0.75-0.95| + 2*|0.5-0.55|) / 6
+	if want := (4*0.2 + 2*0.05) / 6; math.Abs(report.ECE-want) > 1e-9 {
+		t.Errorf("ECE = %v, want %v", report.ECE, want)
+	}
+}
+
+func TestBucketIndex(t *testing.T) {
+	tests := []struct {
+		conf float64
+		want int
+	}{
+		{0, 0}, {0.05, 0}, {0.1, 1}, {0.7, 7}, {0.99, 9}, {1.0, 9}, {1.5, 9}, {-0.1, 0},
+	}
+	for _, tt := range tests {
+		if got := bucketIndex(tt.conf, 10); got != tt.want {
+			t.Errorf("bucketIndex(%v) = %d, want %d", tt.conf, got, tt.want)
+		}
+	}
+}
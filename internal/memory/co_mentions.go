@@ -0,0 +1,294 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Co-mention analysis finds people who keep coming up in the same
+// conversations but have no relationship in the graph - usually because
+// nobody ever said outright that they know each other - and queues a KNOWS
+// edge for review. Accepted suggestions become low-confidence inferred
+// edges.
+const (
+	// CoMentionMinEpisodes is how many shared episodes make a pair worth
+	// suggesting.
+	CoMentionMinEpisodes = 5
+	// CoMentionMaxPeople skips episodes mentioning more people than this:
+	// in a big group chat everyone co-occurs with everyone.
+	CoMentionMaxPeople = 12
+	// CoMentionRelationType is the edge suggested for a pair.
+	CoMentionRelationType = "KNOWS"
+	// CoMentionConfidence is the confidence of an accepted suggestion.
+	CoMentionConfidence = 0.3
+)
+
+// Edge suggestion statuses.
+const (
+	EdgeSuggestionPending  = "pending"
+	EdgeSuggestionAccepted = "accepted" // the edge was added
+	EdgeSuggestionRejected = "rejected" // never suggested again
+)
+
+// EdgeSuggestion is a proposed edge between two frequently co-mentioned
+// entities.
+type EdgeSuggestion struct {
+	ID             string  `json:"id"`
+	EntityAID      string  `json:"entity_a_id"`
+	EntityAName    string  `json:"entity_a_name"`
+	EntityBID      string  `json:"entity_b_id"`
+	EntityBName    string  `json:"entity_b_name"`
+	RelationType   string  `json:"relation_type"`
+	CoMentions     int     `json:"co_mentions"` // episodes mentioning both
+	Status         string  `json:"status"`
+	RelationshipID *string `json:"relationship_id,omitempty"`
+	CreatedAt      string  `json:"created_at"`
+	ResolvedAt     *string `json:"resolved_at,omitempty"`
+}
+
+// CoMentionOptions configures AnalyzeCoMentions.
+type CoMentionOptions struct {
+	MinEpisodes int  // default CoMentionMinEpisodes
+	Limit       int  // 0 = no limit
+	Record      bool // store suggestions as pending
+}
+
+// CoMentionResult is the output of AnalyzeCoMentions.
+type CoMentionResult struct {
+	Suggestions []EdgeSuggestion `json:"suggestions"`
+	Created     int              `json:"created"` // suggestions newly recorded
+}
+
+// AnalyzeCoMentions finds pairs of people (other than the user) mentioned
+// together in at least MinEpisodes episodes with no relationship between
+// them in either direction, most co-mentions first. Pairs already rejected
+// or accepted are skipped. With Record, new pairs are stored as pending
+// suggestions and pending ones get their count refreshed.
+func AnalyzeCoMentions(ctx context.Context, db *sql.DB, opts CoMentionOptions) (*CoMentionResult, error) {
+	if opts.MinEpisodes <= 0 {
+		opts.MinEpisodes = CoMentionMinEpisodes
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		WITH people AS (
+			SELECT m.episode_id, m.entity_id
+			FROM episode_entity_mentions m
+			JOIN entities e ON e.id = m.entity_id
+			WHERE e.merged_into IS NULL AND e.entity_type_id = ?
+			  AND e.id NOT IN (
+				SELECT l.entity_id FROM person_entity_links l
+				JOIN persons p ON p.id = l.person_id
+				WHERE p.is_me = 1
+			  )
+		),
+		small AS (
+			SELECT episode_id FROM people GROUP BY episode_id HAVING COUNT(*) BETWEEN 2 AND ?
+		)
+		SELECT a.entity_id, ea.canonical_name, b.entity_id, eb.canonical_name, COUNT(*) AS n
+		FROM people a
+		JOIN people b ON b.episode_id = a.episode_id AND a.entity_id < b.entity_id
+		JOIN entities ea ON ea.id = a.entity_id
+		JOIN entities eb ON eb.id = b.entity_id
+		WHERE a.episode_id IN (SELECT episode_id FROM small)
+		GROUP BY a.entity_id, b.entity_id
+		HAVING n >= ?
+		   AND NOT EXISTS (
+			SELECT 1 FROM relationships r
+			WHERE (r.source_entity_id = a.entity_id AND r.target_entity_id = b.entity_id)
+			   OR (r.source_entity_id = b.entity_id AND r.target_entity_id = a.entity_id)
+		   )
+		   AND NOT EXISTS (
+			SELECT 1 FROM edge_suggestions s
+			WHERE s.entity_a_id = a.entity_id AND s.entity_b_id = b.entity_id AND s.status != ?
+		   )
+		ORDER BY n DESC, a.entity_id, b.entity_id
+		LIMIT ?
+	`, EntityTypePerson, CoMentionMaxPeople, opts.MinEpisodes, EdgeSuggestionPending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query co-mentions: %w", err)
+	}
+	defer rows.Close()
+
+	result := &CoMentionResult{Suggestions: []EdgeSuggestion{}}
+	now := time.Now().Format(time.RFC3339)
+	for rows.Next() {
+		s := EdgeSuggestion{
+			ID:           uuid.New().String(),
+			RelationType: CoMentionRelationType,
+			Status:       EdgeSuggestionPending,
+			CreatedAt:    now,
+		}
+		if err := rows.Scan(&s.EntityAID, &s.EntityAName, &s.EntityBID, &s.EntityBName, &s.CoMentions); err != nil {
+			return nil, fmt.Errorf("scan co-mention: %w", err)
+		}
+		result.Suggestions = append(result.Suggestions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if opts.Record {
+		for i := range result.Suggestions {
+			s := &result.Suggestions[i]
+			res, err := db.ExecContext(ctx, `
+				INSERT INTO edge_suggestions (id, entity_a_id, entity_b_id, relation_type, co_mentions, status, created_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT(entity_a_id, entity_b_id) DO NOTHING
+			`, s.ID, s.EntityAID, s.EntityBID, s.RelationType, s.CoMentions, s.Status, s.CreatedAt)
+			if err != nil {
+				return nil, fmt.Errorf("insert edge suggestion: %w", err)
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				result.Created++
+				continue
+			}
+			if err := db.QueryRowContext(ctx, `
+				UPDATE edge_suggestions SET co_mentions = ?
+				WHERE entity_a_id = ? AND entity_b_id = ?
+				RETURNING id, created_at
+			`, s.CoMentions, s.EntityAID, s.EntityBID).Scan(&s.ID, &s.CreatedAt); err != nil {
+				return nil, fmt.Errorf("update edge suggestion: %w", err)
+			}
+		}
+	}
+	return result, nil
+}
+
+// ListEdgeSuggestions returns suggestions with the given status (all if
+// empty), most co-mentions first. Pending suggestions for pairs that have
+// since gained a relationship are left out.
+func ListEdgeSuggestions(ctx context.Context, db *sql.DB, status string, limit int) ([]EdgeSuggestion, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	query := `
+		SELECT s.id, s.entity_a_id, a.canonical_name, s.entity_b_id, b.canonical_name, s.relation_type,
+		       s.co_mentions, s.status, s.relationship_id, s.created_at, s.resolved_at
+		FROM edge_suggestions s
+		JOIN entities a ON a.id = s.entity_a_id
+		JOIN entities b ON b.id = s.entity_b_id
+		WHERE a.merged_into IS NULL AND b.merged_into IS NULL
+		  AND (s.status != ? OR NOT EXISTS (
+			SELECT 1 FROM relationships r
+			WHERE (r.source_entity_id = s.entity_a_id AND r.target_entity_id = s.entity_b_id)
+			   OR (r.source_entity_id = s.entity_b_id AND r.target_entity_id = s.entity_a_id)
+		  ))
+	`
+	args := []interface{}{EdgeSuggestionPending}
+	if status != "" {
+		query += ` AND s.status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY s.co_mentions DESC, s.created_at DESC, s.id LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query edge suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	var suggestions []EdgeSuggestion
+	for rows.Next() {
+		var s EdgeSuggestion
+		var relationshipID, resolvedAt sql.NullString
+		if err := rows.Scan(&s.ID, &s.EntityAID, &s.EntityAName, &s.EntityBID, &s.EntityBName, &s.RelationType,
+			&s.CoMentions, &s.Status, &relationshipID, &s.CreatedAt, &resolvedAt); err != nil {
+			return nil, fmt.Errorf("scan edge suggestion: %w", err)
+		}
+		if relationshipID.Valid {
+			s.RelationshipID = &relationshipID.String
+		}
+		if resolvedAt.Valid {
+			s.ResolvedAt = &resolvedAt.String
+		}
+		suggestions = append(suggestions, s)
+	}
+	return suggestions, rows.Err()
+}
+
+// AcceptEdgeSuggestion adds the suggested edge as an inferred relationship
+// with CoMentionConfidence and returns it. If the pair already has an edge
+// of that type, it is linked instead of duplicated.
+func AcceptEdgeSuggestion(ctx context.Context, db *sql.DB, id string) (*EntityRelationship, error) {
+	var s EdgeSuggestion
+	err := db.QueryRowContext(ctx, `
+		SELECT s.entity_a_id, a.canonical_name, s.entity_b_id, b.canonical_name, s.relation_type, s.co_mentions
+		FROM edge_suggestions s
+		JOIN entities a ON a.id = s.entity_a_id
+		JOIN entities b ON b.id = s.entity_b_id
+		WHERE s.id = ? AND s.status = ?
+	`, id, EdgeSuggestionPending).Scan(&s.EntityAID, &s.EntityAName, &s.EntityBID, &s.EntityBName, &s.RelationType, &s.CoMentions)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no pending edge suggestion: %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get edge suggestion: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Format(time.RFC3339)
+	var relID string
+	err = tx.QueryRowContext(ctx, `
+		SELECT id FROM relationships
+		WHERE relation_type = ?
+		  AND ((source_entity_id = ? AND target_entity_id = ?) OR (source_entity_id = ? AND target_entity_id = ?))
+		LIMIT 1
+	`, s.RelationType, s.EntityAID, s.EntityBID, s.EntityBID, s.EntityAID).Scan(&relID)
+	if err == sql.ErrNoRows {
+		relID = uuid.New().String()
+		fact := fmt.Sprintf("%s and %s know each other (mentioned together in %d conversations)", s.EntityAName, s.EntityBName, s.CoMentions)
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO relationships (
+				id, source_entity_id, target_entity_id, relation_type, fact, created_at, confidence, source_type
+			) VALUES (?, ?, ?, ?, ?, ?, ?, 'inferred')
+		`, relID, s.EntityAID, s.EntityBID, s.RelationType, fact, now, CoMentionConfidence); err != nil {
+			return nil, fmt.Errorf("insert relationship: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("find existing relationship: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE edge_suggestions SET status = ?, relationship_id = ?, resolved_at = ? WHERE id = ?
+	`, EdgeSuggestionAccepted, relID, now, id); err != nil {
+		return nil, fmt.Errorf("update edge suggestion: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	if err := NewCurrentFactsStore(db).RefreshEntities(ctx, []string{s.EntityAID, s.EntityBID}); err != nil {
+		return nil, err
+	}
+	return GetFact(ctx, db, relID)
+}
+
+// RejectEdgeSuggestion marks a suggestion wrong; the pair is not suggested
+// again.
+func RejectEdgeSuggestion(ctx context.Context, db *sql.DB, id string) error {
+	res, err := db.ExecContext(ctx, `
+		UPDATE edge_suggestions SET status = ?, resolved_at = ? WHERE id = ? AND status = ?
+	`, EdgeSuggestionRejected, time.Now().Format(time.RFC3339), id, EdgeSuggestionPending)
+	if err != nil {
+		return fmt.Errorf("update edge suggestion: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("no pending edge suggestion: %s", id)
+	}
+	return nil
+}
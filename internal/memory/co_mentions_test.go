@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestCoMentionSuggestions(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, e := range []struct{ id, name string }{
+		{"casey", "Casey"}, {"dana", "Dana"}, {"eli", "Eli"}, {"me", "Tyler"},
+	} {
+		insertQueryEngineTestEntity(t, db, e.id, e.name, EntityTypePerson)
+	}
+	insertQueryEngineTestEntity(t, db, "acme", "Acme", EntityTypeCompany)
+	if _, err := db.Exec(`
+		INSERT INTO persons (id, canonical_name, is_me, created_at, updated_at) VALUES ('p-me', 'Tyler', 1, 0, 0);
+		INSERT INTO person_entity_links (person_id, entity_id, method, created_at) VALUES ('p-me', 'me', 'name', 0);
+		INSERT INTO threads (id, channel, source_adapter, source_id, created_at, updated_at) VALUES ('t1', 'imessage', 'imessage', 't1', 0, 0);
+		INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0);
+	`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	// Casey, Dana, Eli, the user and Acme come up together in 6 episodes;
+	// Casey and Eli already have an edge.
+	for i := 0; i < 6; i++ {
+		ep := fmt.Sprintf("ep%d", i)
+		if _, err := db.Exec(`INSERT INTO episodes (id, definition_id, channel, thread_id, start_time, end_time, event_count, created_at) VALUES (?, 'def', 'imessage', 't1', ?, ?, 1, 0)`, ep, i, i); err != nil {
+			t.Fatalf("episode: %v", err)
+		}
+		for _, id := range []string{"casey", "dana", "eli", "me", "acme"} {
+			if _, err := db.Exec(`INSERT INTO episode_entity_mentions (episode_id, entity_id, created_at) VALUES (?, ?, 'x')`, ep, id); err != nil {
+				t.Fatalf("mention: %v", err)
+			}
+		}
+	}
+	eli := "eli"
+	insertCurrentFactsRel(t, db, "r1", "casey", &eli, nil, "FRIEND_OF", "", nil)
+
+	result, err := AnalyzeCoMentions(ctx, db, CoMentionOptions{Record: true})
+	if err != nil {
+		t.Fatalf("AnalyzeCoMentions: %v", err)
+	}
+	var pairs []string
+	for _, s := range result.Suggestions {
+		pairs = append(pairs, s.EntityAID+"+"+s.EntityBID)
+	}
+	if len(pairs) != 2 || pairs[0] != "casey+dana" || pairs[1] != "dana+eli" || result.Created != 2 {
+		t.Fatalf("suggested %v (created %d), want casey+dana and dana+eli", pairs, result.Created)
+	}
+	if result.Suggestions[0].CoMentions != 6 {
+		t.Errorf("co-mentions = %d, want 6", result.Suggestions[0].CoMentions)
+	}
+
+	again, err := AnalyzeCoMentions(ctx, db, CoMentionOptions{Record: true})
+	if err != nil || again.Created != 0 || again.Suggestions[0].ID != result.Suggestions[0].ID {
+		t.Fatalf("second run = %+v, %v; want the same pending suggestions", again, err)
+	}
+	if high, _ := AnalyzeCoMentions(ctx, db, CoMentionOptions{MinEpisodes: 7}); len(high.Suggestions) != 0 {
+		t.Errorf("min 7 episodes suggested %+v", high.Suggestions)
+	}
+
+	rel, err := AcceptEdgeSuggestion(ctx, db, result.Suggestions[0].ID)
+	if err != nil {
+		t.Fatalf("AcceptEdgeSuggestion: %v", err)
+	}
+	if rel.RelationType != CoMentionRelationType || rel.Confidence != CoMentionConfidence {
+		t.Errorf("accepted edge = %+v", rel)
+	}
+	if err := RejectEdgeSuggestion(ctx, db, result.Suggestions[1].ID); err != nil {
+		t.Fatalf("RejectEdgeSuggestion: %v", err)
+	}
+	if err := RejectEdgeSuggestion(ctx, db, result.Suggestions[1].ID); err == nil {
+		t.Error("rejected a suggestion twice")
+	}
+
+	pending, err := ListEdgeSuggestions(ctx, db, EdgeSuggestionPending, 0)
+	if err != nil || len(pending) != 0 {
+		t.Errorf("pending after review = %+v, %v", pending, err)
+	}
+	if last, _ := AnalyzeCoMentions(ctx, db, CoMentionOptions{}); len(last.Suggestions) != 0 {
+		t.Errorf("reviewed pairs suggested again: %+v", last.Suggestions)
+	}
+}
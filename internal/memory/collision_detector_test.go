@@ -51,7 +51,9 @@ func setupCollisionTestDB(t *testing.T) *sql.DB {
 			valid_at TEXT,
 			invalid_at TEXT,
 			created_at TEXT NOT NULL,
-			confidence REAL DEFAULT 1.0
+			confidence REAL DEFAULT 1.0,
+			weight REAL DEFAULT 0,
+			source_type TEXT
 		);
 
 		CREATE TABLE merge_candidates (
@@ -0,0 +1,211 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxConflictObservations caps the observations kept per conflicting value.
+const MaxConflictObservations = 5
+
+// conflictExcerptRadius is how much event text to keep on each side of the value.
+const conflictExcerptRadius = 80
+
+// ConflictObservation is one place a conflicting value was seen.
+type ConflictObservation struct {
+	EpisodeID  string  `json:"episode_id"`
+	EventID    *string `json:"event_id,omitempty"`
+	ObservedAt string  `json:"observed_at"` // RFC3339, episode start
+	Excerpt    string  `json:"excerpt"`     // event text around the value, else the extracted fact
+	SourceType string  `json:"source_type,omitempty"`
+}
+
+// ConflictValueEvidence is where one entity's side of a conflict came from.
+type ConflictValueEvidence struct {
+	Entity       string                `json:"entity"` // "a" or "b"
+	EntityID     string                `json:"entity_id"`
+	Value        string                `json:"value"`
+	Observations []ConflictObservation `json:"observations"`
+}
+
+// ConflictReview gathers the evidence for both sides of a conflict, with a
+// suggestion of which side looks stale, for a human or LLM adjudicator.
+type ConflictReview struct {
+	Type       string                  `json:"type"`
+	Values     []ConflictValueEvidence `json:"values"`
+	Suggestion string                  `json:"suggestion"`
+}
+
+// GatherConflictEvidence finds the episodes and events where each value of
+// the candidate's conflicts was observed, newest first.
+func (m *AutoMerger) GatherConflictEvidence(ctx context.Context, candidate *MergeCandidate) ([]ConflictReview, error) {
+	reviews := make([]ConflictReview, 0, len(candidate.Conflicts))
+	for _, conflict := range candidate.Conflicts {
+		review := ConflictReview{Type: conflict.Type}
+		sides := []struct {
+			entity, entityID string
+			values           []string
+		}{
+			{"a", candidate.EntityAID, conflict.ValuesA},
+			{"b", candidate.EntityBID, conflict.ValuesB},
+		}
+		for _, side := range sides {
+			for _, value := range side.values {
+				observations, err := m.observeConflictValue(ctx, conflict.Type, side.entityID, value)
+				if err != nil {
+					return nil, fmt.Errorf("gather evidence for %s: %w", conflict.Type, err)
+				}
+				review.Values = append(review.Values, ConflictValueEvidence{
+					Entity:       side.entity,
+					EntityID:     side.entityID,
+					Value:        value,
+					Observations: observations,
+				})
+			}
+		}
+		review.Suggestion = suggestConflictResolution(review.Values)
+		reviews = append(reviews, review)
+	}
+	return reviews, nil
+}
+
+// observeConflictValue returns where an entity's value for a conflict type
+// was extracted from, newest first.
+func (m *AutoMerger) observeConflictValue(ctx context.Context, conflictType, entityID, value string) ([]ConflictObservation, error) {
+	var rows *sql.Rows
+	var err error
+	switch conflictType {
+	case "different_phones", "different_emails":
+		aliasType := strings.TrimSuffix(strings.TrimPrefix(conflictType, "different_"), "s")
+		rows, err = m.db.QueryContext(ctx, `
+			SELECT erm.episode_id, ep.start_time, erm.extracted_fact, COALESCE(erm.source_type, ''),
+			       COALESCE(erm.target_literal, ea.alias)
+			FROM episode_relationship_mentions erm
+			JOIN entity_aliases ea ON ea.id = erm.alias_id
+			JOIN episodes ep ON ep.id = erm.episode_id
+			WHERE ea.entity_id = ? AND ea.alias_type = ? AND ea.normalized = ?
+			ORDER BY ep.start_time DESC
+			LIMIT ?
+		`, entityID, aliasType, value, MaxConflictObservations)
+	case "different_birthdates":
+		rows, err = m.db.QueryContext(ctx, `
+			SELECT erm.episode_id, ep.start_time, erm.extracted_fact, COALESCE(erm.source_type, ''),
+			       r.target_literal
+			FROM episode_relationship_mentions erm
+			JOIN relationships r ON r.id = erm.relationship_id
+			JOIN episodes ep ON ep.id = erm.episode_id
+			WHERE r.source_entity_id = ? AND r.relation_type = 'BORN_ON' AND r.target_literal = ?
+			ORDER BY ep.start_time DESC
+			LIMIT ?
+		`, entityID, value, MaxConflictObservations)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	type observed struct {
+		obs ConflictObservation
+		raw string
+	}
+	var found []observed
+	for rows.Next() {
+		var o observed
+		var startTime int64
+		if err := rows.Scan(&o.obs.EpisodeID, &startTime, &o.obs.Excerpt, &o.obs.SourceType, &o.raw); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		o.obs.ObservedAt = time.Unix(startTime, 0).UTC().Format(time.RFC3339)
+		found = append(found, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Point at the message that contains the value, when there is one
+	observations := make([]ConflictObservation, 0, len(found))
+	for _, o := range found {
+		var eventID, content string
+		err := m.db.QueryRowContext(ctx, `
+			SELECT e.id, e.content
+			FROM episode_events ee
+			JOIN events e ON e.id = ee.event_id
+			WHERE ee.episode_id = ? AND e.content LIKE '%' || ? || '%'
+			ORDER BY ee.position
+			LIMIT 1
+		`, o.obs.EpisodeID, o.raw).Scan(&eventID, &content)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if err == nil {
+			o.obs.EventID = &eventID
+			o.obs.Excerpt = excerptAround(content, o.raw, conflictExcerptRadius)
+		}
+		observations = append(observations, o.obs)
+	}
+	return observations, nil
+}
+
+// suggestConflictResolution names the side whose values were observed less
+// recently as the likely stale one.
+func suggestConflictResolution(values []ConflictValueEvidence) string {
+	latest := map[string]string{}
+	latestValue := map[string]string{}
+	for _, v := range values {
+		for _, o := range v.Observations {
+			if o.ObservedAt > latest[v.Entity] {
+				latest[v.Entity] = o.ObservedAt
+				latestValue[v.Entity] = v.Value
+			}
+		}
+	}
+
+	switch {
+	case latest["a"] == "" && latest["b"] == "":
+		return "No source episodes found for either value"
+	case latest["a"] == "":
+		return fmt.Sprintf("Only entity B's value has a source (%s, last seen %s); entity A's may be stale or misattributed", latestValue["b"], latest["b"])
+	case latest["b"] == "":
+		return fmt.Sprintf("Only entity A's value has a source (%s, last seen %s); entity B's may be stale or misattributed", latestValue["a"], latest["a"])
+	case latest["a"] == latest["b"]:
+		return "Both values were seen at the same time; likely different people"
+	case latest["a"] > latest["b"]:
+		return fmt.Sprintf("Entity A's %s (last seen %s) is newer than entity B's %s (last seen %s); B's value may be stale",
+			latestValue["a"], latest["a"], latestValue["b"], latest["b"])
+	default:
+		return fmt.Sprintf("Entity B's %s (last seen %s) is newer than entity A's %s (last seen %s); A's value may be stale",
+			latestValue["b"], latest["b"], latestValue["a"], latest["a"])
+	}
+}
+
+// excerptAround returns the text within radius characters of the first
+// occurrence of value, marking cut ends with "...".
+func excerptAround(text, value string, radius int) string {
+	i := strings.Index(text, value)
+	if i < 0 {
+		return text
+	}
+	start, end := i-radius, i+len(value)+radius
+	prefix, suffix := "...", "..."
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	if end >= len(text) {
+		end, suffix = len(text), ""
+	}
+	// Don't cut a multi-byte character in half
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+	return prefix + text[start:end] + suffix
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestProcessMergeCandidates_ConflictEvidence(t *testing.T) {
+	db := setupAutoMergerTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	createTestEntity(t, db, "entity-a", "Tyler A", 1)
+	createTestEntity(t, db, "entity-b", "Tyler B", 1)
+	createTestAlias(t, db, "entity-a", "+1-555-111-1111", "phone", "+15551111111", false)
+	createTestAlias(t, db, "entity-b", "+1-555-222-2222", "phone", "+15552222222", false)
+
+	// A's phone was last seen in 2020, B's in 2024
+	stmts := []string{
+		`INSERT INTO episodes (id, channel, start_time, end_time, created_at) VALUES ('ep-old', 'imessage', 1577836800, 1577836800, 0), ('ep-new', 'imessage', 1704067200, 1704067200, 0)`,
+		`INSERT INTO events (id, timestamp, content) VALUES ('ev-old', 1577836800, 'new number, save it: +1-555-111-1111 thanks'), ('ev-other', 1704067200, 'hey')`,
+		`INSERT INTO episode_events (episode_id, event_id, position) VALUES ('ep-old', 'ev-old', 1), ('ep-new', 'ev-other', 1)`,
+		`INSERT INTO episode_relationship_mentions (id, episode_id, extracted_fact, source_type, target_literal, alias_id, created_at) VALUES
+			('m1', 'ep-old', 'Tyler has phone +1-555-111-1111', 'self_disclosed', '+1-555-111-1111', 'alias-+1-555-111-1111-entity-a', ''),
+			('m2', 'ep-new', 'Tyler has phone +1-555-222-2222', 'self_disclosed', '+1-555-222-2222', 'alias-+1-555-222-2222-entity-b', '')`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("setup: %v", err)
+		}
+	}
+	id := createTestMergeCandidate(t, db, "entity-a", "entity-b", 0.95, true, "hard_identifier")
+
+	merger := NewAutoMerger(db)
+	if _, err := merger.ProcessMergeCandidates(ctx); err != nil {
+		t.Fatalf("ProcessMergeCandidates: %v", err)
+	}
+	candidate, err := merger.GetCandidateByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetCandidateByID: %v", err)
+	}
+	reviews, ok := candidate.Context["conflict_evidence"].([]interface{})
+	if !ok || len(reviews) != 1 {
+		t.Fatalf("context = %+v, want one conflict review", candidate.Context)
+	}
+	review := reviews[0].(map[string]interface{})
+	if suggestion, _ := review["suggestion"].(string); !strings.Contains(suggestion, "A's value may be stale") {
+		t.Errorf("suggestion = %q", suggestion)
+	}
+
+	// Evidence is returned newest first per value, pointing at the message
+	candidate.Conflicts = []Conflict{{Type: "different_phones", ValuesA: []string{"+15551111111"}, ValuesB: []string{"+15552222222"}}}
+	gathered, err := merger.GatherConflictEvidence(ctx, candidate)
+	if err != nil {
+		t.Fatalf("GatherConflictEvidence: %v", err)
+	}
+	a := gathered[0].Values[0]
+	if len(a.Observations) != 1 || a.Observations[0].EventID == nil || *a.Observations[0].EventID != "ev-old" ||
+		!strings.Contains(a.Observations[0].Excerpt, "save it") {
+		t.Errorf("A evidence = %+v", a)
+	}
+	b := gathered[0].Values[1]
+	if len(b.Observations) != 1 || b.Observations[0].EventID != nil || b.Observations[0].Excerpt != "Tyler has phone +1-555-222-2222" {
+		t.Errorf("B evidence = %+v", b)
+	}
+}
+
+func TestExcerptAround(t *testing.T) {
+	text := strings.Repeat("a", 100) + "VALUE" + strings.Repeat("b", 100)
+	got := excerptAround(text, "VALUE", 10)
+	if got != "..."+strings.Repeat("a", 10)+"VALUE"+strings.Repeat("b", 10)+"..." {
+		t.Errorf("excerptAround = %q", got)
+	}
+	if got := excerptAround("call me at 555", "555", 80); got != "call me at 555" {
+		t.Errorf("short text = %q", got)
+	}
+}
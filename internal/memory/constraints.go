@@ -0,0 +1,439 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ConstraintKind is what a graph constraint requires of a relation type's
+// edges.
+type ConstraintKind string
+
+const (
+	// ConstraintAtMostOne allows an entity one current edge of the type as
+	// its source (one birthdate).
+	ConstraintAtMostOne ConstraintKind = "at_most_one"
+	// ConstraintExclusive forbids an entity's edges of the type to
+	// different entities from overlapping in time (one spouse at a time).
+	// For symmetric types both ends count.
+	ConstraintExclusive ConstraintKind = "exclusive"
+	// ConstraintSymmetric requires a symmetric fact to be stored once: not
+	// both ways round, and never from an entity to itself.
+	ConstraintSymmetric ConstraintKind = "symmetric"
+)
+
+// ConstraintKinds lists the valid kinds.
+var ConstraintKinds = []ConstraintKind{ConstraintAtMostOne, ConstraintExclusive, ConstraintSymmetric}
+
+// Constraint declares a consistency rule for one relation type.
+type Constraint struct {
+	Name         string         `json:"name"`
+	Kind         ConstraintKind `json:"kind"`
+	RelationType string         `json:"relation_type"`
+}
+
+// DefaultConstraints are checked when no constraints are configured.
+var DefaultConstraints = []Constraint{
+	{Name: "one_birthdate", Kind: ConstraintAtMostOne, RelationType: "BORN_ON"},
+	{Name: "one_birthplace", Kind: ConstraintAtMostOne, RelationType: "BORN_IN"},
+	{Name: "one_death_date", Kind: ConstraintAtMostOne, RelationType: "DIED_ON"},
+	{Name: "married_symmetric", Kind: ConstraintSymmetric, RelationType: "MARRIED_TO"},
+	{Name: "married_exclusive", Kind: ConstraintExclusive, RelationType: "MARRIED_TO"},
+	{Name: "spouse_symmetric", Kind: ConstraintSymmetric, RelationType: "SPOUSE_OF"},
+	{Name: "spouse_exclusive", Kind: ConstraintExclusive, RelationType: "SPOUSE_OF"},
+	{Name: "sibling_symmetric", Kind: ConstraintSymmetric, RelationType: "SIBLING_OF"},
+}
+
+// ValidateConstraints checks constraints and upper-cases their relation
+// types. Constraints name the stored type of an inverse pair (WORKS_AT, not
+// EMPLOYS), since that is the type the edges have.
+func ValidateConstraints(constraints []Constraint) ([]Constraint, error) {
+	seen := map[string]bool{}
+	out := make([]Constraint, 0, len(constraints))
+	for _, c := range constraints {
+		c.Name = strings.TrimSpace(c.Name)
+		if c.Name == "" {
+			return nil, fmt.Errorf("constraint needs a name")
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("duplicate constraint %q", c.Name)
+		}
+		seen[c.Name] = true
+		valid := false
+		for _, k := range ConstraintKinds {
+			valid = valid || c.Kind == k
+		}
+		if !valid {
+			return nil, fmt.Errorf("constraint %q has unknown kind %q (want at_most_one, exclusive or symmetric)", c.Name, c.Kind)
+		}
+		c.RelationType = strings.ToUpper(strings.TrimSpace(c.RelationType))
+		if c.RelationType == "" {
+			return nil, fmt.Errorf("constraint %q needs a relation type", c.Name)
+		}
+		if canonical, swap := CanonicalRelationType(c.RelationType); swap {
+			return nil, fmt.Errorf("constraint %q: %s facts are stored as %s; constrain that type", c.Name, c.RelationType, canonical)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// Fix actions a constraint violation suggests.
+const (
+	FixInvalidate = "invalidate" // end the fact today
+	FixEnd        = "end"        // end the fact at InvalidAt
+	FixStart      = "start"      // start the fact at ValidAt
+	FixDelete     = "delete"     // remove a duplicate or wrong fact
+)
+
+// ConstraintFix is a suggested change to one edge.
+type ConstraintFix struct {
+	Action         string `json:"action"`
+	RelationshipID string `json:"relationship_id"`
+	ValidAt        string `json:"valid_at,omitempty"`   // for FixStart
+	InvalidAt      string `json:"invalid_at,omitempty"` // for FixEnd
+	Reason         string `json:"reason"`
+}
+
+// ConstraintViolation is one place the graph breaks a constraint.
+type ConstraintViolation struct {
+	Constraint   string               `json:"constraint"`
+	Kind         ConstraintKind       `json:"kind"`
+	RelationType string               `json:"relation_type"`
+	EntityID     string               `json:"entity_id"`
+	EntityName   string               `json:"entity_name"`
+	Message      string               `json:"message"`
+	Edges        []EntityRelationship `json:"edges"`
+	Fixes        []ConstraintFix      `json:"fixes"`
+}
+
+// ConstraintReport is the result of a constraint check.
+type ConstraintReport struct {
+	Constraints []Constraint          `json:"constraints"`
+	Violations  []ConstraintViolation `json:"violations"`
+}
+
+// CheckOptions configures a constraint check.
+type CheckOptions struct {
+	Constraints []Constraint // default: DefaultConstraints
+	Names       []string     // check only these constraints (nil = all)
+}
+
+// CheckConstraints checks the graph against constraints and reports each
+// violation with the offending edges and fixes that would resolve it.
+// Nothing is changed. Edges of merged entities are not checked.
+func CheckConstraints(ctx context.Context, db *sql.DB, opts CheckOptions) (*ConstraintReport, error) {
+	constraints := opts.Constraints
+	if len(constraints) == 0 {
+		constraints = DefaultConstraints
+	}
+	constraints, err := ValidateConstraints(constraints)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.Names) > 0 {
+		byName := map[string]Constraint{}
+		for _, c := range constraints {
+			byName[c.Name] = c
+		}
+		var selected []Constraint
+		for _, name := range opts.Names {
+			c, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown constraint %q", name)
+			}
+			selected = append(selected, c)
+		}
+		constraints = selected
+	}
+
+	report := &ConstraintReport{Constraints: constraints, Violations: []ConstraintViolation{}}
+	for _, c := range constraints {
+		edges, err := loadConstraintEdges(ctx, db, c.RelationType)
+		if err != nil {
+			return nil, err
+		}
+		var found []ConstraintViolation
+		switch c.Kind {
+		case ConstraintAtMostOne:
+			found = checkAtMostOne(c, edges)
+		case ConstraintExclusive:
+			found = checkExclusive(c, edges)
+		case ConstraintSymmetric:
+			found = checkSymmetric(c, edges)
+		}
+		for _, v := range found {
+			if err := fillViolation(ctx, db, &v); err != nil {
+				return nil, err
+			}
+			report.Violations = append(report.Violations, v)
+		}
+	}
+	return report, nil
+}
+
+// constraintEdge is the part of an edge the checks look at. Edges of a
+// violation are reported in full (see fillViolation).
+type constraintEdge struct {
+	id        string
+	source    string
+	target    string // "" for literal targets
+	validAt   string // "" when unknown
+	invalidAt string // "" while current
+	createdAt string
+	manual    bool
+	conf      float64
+}
+
+// loadConstraintEdges loads every edge of relType between active entities,
+// current or ended, oldest first.
+func loadConstraintEdges(ctx context.Context, db *sql.DB, relType string) ([]constraintEdge, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT r.id, r.source_entity_id, COALESCE(r.target_entity_id, ''),
+		       COALESCE(r.valid_at, ''), COALESCE(r.invalid_at, ''), r.created_at,
+		       COALESCE(r.origin, '') = ?, COALESCE(r.confidence, 1.0)
+		FROM relationships r
+		JOIN entities src ON src.id = r.source_entity_id AND src.merged_into IS NULL
+		LEFT JOIN entities tgt ON tgt.id = r.target_entity_id
+		WHERE r.relation_type = ?
+		  AND (r.target_entity_id IS NULL OR tgt.merged_into IS NULL)
+		ORDER BY r.created_at, r.id
+	`, OriginManual, relType)
+	if err != nil {
+		return nil, fmt.Errorf("load %s edges: %w", relType, err)
+	}
+	defer rows.Close()
+	var edges []constraintEdge
+	for rows.Next() {
+		var e constraintEdge
+		if err := rows.Scan(&e.id, &e.source, &e.target, &e.validAt, &e.invalidAt, &e.createdAt, &e.manual, &e.conf); err != nil {
+			return nil, fmt.Errorf("scan %s edge: %w", relType, err)
+		}
+		edges = append(edges, e)
+	}
+	return edges, rows.Err()
+}
+
+// checkAtMostOne reports entities with several current edges. The manual
+// edge, else the most confident, else the oldest is kept.
+func checkAtMostOne(c Constraint, edges []constraintEdge) []ConstraintViolation {
+	bySource := map[string][]constraintEdge{}
+	var sources []string
+	for _, e := range edges {
+		if e.invalidAt != "" {
+			continue
+		}
+		if _, ok := bySource[e.source]; !ok {
+			sources = append(sources, e.source)
+		}
+		bySource[e.source] = append(bySource[e.source], e)
+	}
+
+	var out []ConstraintViolation
+	for _, source := range sources {
+		current := bySource[source]
+		if len(current) < 2 {
+			continue
+		}
+		sort.SliceStable(current, func(i, j int) bool {
+			if current[i].manual != current[j].manual {
+				return current[i].manual
+			}
+			return current[i].conf > current[j].conf
+		})
+		keep := current[0]
+		v := ConstraintViolation{
+			Constraint:   c.Name,
+			Kind:         c.Kind,
+			RelationType: c.RelationType,
+			EntityID:     source,
+			Message:      fmt.Sprintf("%d current %s facts; at most one is allowed", len(current), c.RelationType),
+		}
+		for _, e := range current {
+			v.Edges = append(v.Edges, EntityRelationship{ID: e.id})
+			if e.id != keep.id {
+				v.Fixes = append(v.Fixes, ConstraintFix{
+					Action:         FixInvalidate,
+					RelationshipID: e.id,
+					Reason:         "conflicts with " + keep.id + keptBecause(keep, e),
+				})
+			}
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+func keptBecause(keep, other constraintEdge) string {
+	switch {
+	case keep.manual && !other.manual:
+		return ", which was entered by hand"
+	case keep.conf > other.conf:
+		return ", which is more confident"
+	default:
+		return ", which was stated first"
+	}
+}
+
+// checkExclusive reports entities with overlapping edges to different
+// entities. Each overlapping edge but the last to begin is ended where the
+// next one begins; when that is unknown, the next one starts where an ended
+// edge ends, or a current edge is ended today.
+func checkExclusive(c Constraint, edges []constraintEdge) []ConstraintViolation {
+	symmetric := IsSymmetricRelationType(c.RelationType)
+	byEntity := map[string][]constraintEdge{}
+	var entities []string
+	add := func(entity string, e constraintEdge) {
+		if _, ok := byEntity[entity]; !ok {
+			entities = append(entities, entity)
+		}
+		byEntity[entity] = append(byEntity[entity], e)
+	}
+	for _, e := range edges {
+		if e.target == "" || e.target == e.source {
+			continue
+		}
+		add(e.source, e)
+		if symmetric {
+			add(e.target, e)
+		}
+	}
+
+	var out []ConstraintViolation
+	for _, entity := range entities {
+		list := byEntity[entity]
+		other := func(e constraintEdge) string {
+			if e.source == entity {
+				return e.target
+			}
+			return e.source
+		}
+		overlapping := map[string]bool{}
+		for i := range list {
+			for j := i + 1; j < len(list); j++ {
+				if other(list[i]) != other(list[j]) && edgesOverlap(list[i], list[j]) {
+					overlapping[list[i].id], overlapping[list[j].id] = true, true
+				}
+			}
+		}
+		if len(overlapping) == 0 {
+			continue
+		}
+
+		var clash []constraintEdge
+		for _, e := range list {
+			if overlapping[e.id] {
+				clash = append(clash, e)
+			}
+		}
+		// By start; unknown starts first, then by when they were stored
+		sort.SliceStable(clash, func(i, j int) bool {
+			if clash[i].validAt != clash[j].validAt {
+				return clash[i].validAt < clash[j].validAt
+			}
+			return clash[i].createdAt < clash[j].createdAt
+		})
+		v := ConstraintViolation{
+			Constraint:   c.Name,
+			Kind:         c.Kind,
+			RelationType: c.RelationType,
+			EntityID:     entity,
+			Message:      fmt.Sprintf("%d %s facts overlap in time; only one may hold at once", len(clash), c.RelationType),
+		}
+		for i, e := range clash {
+			v.Edges = append(v.Edges, EntityRelationship{ID: e.id})
+			if i == len(clash)-1 {
+				continue
+			}
+			next := clash[i+1]
+			switch {
+			case next.validAt != "":
+				v.Fixes = append(v.Fixes, ConstraintFix{
+					Action:         FixEnd,
+					RelationshipID: e.id,
+					InvalidAt:      next.validAt,
+					Reason:         "ends where " + next.id + " begins",
+				})
+			case e.invalidAt != "":
+				v.Fixes = append(v.Fixes, ConstraintFix{
+					Action:         FixStart,
+					RelationshipID: next.id,
+					ValidAt:        e.invalidAt,
+					Reason:         "begins where " + e.id + " ends",
+				})
+			default:
+				v.Fixes = append(v.Fixes, ConstraintFix{
+					Action:         FixInvalidate,
+					RelationshipID: e.id,
+					Reason:         "superseded by " + next.id,
+				})
+			}
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// edgesOverlap reports whether two edges' validity intervals overlap. An
+// unknown start is open, as is the end of a current edge. ISO dates of
+// mixed precision compare correctly as strings.
+func edgesOverlap(a, b constraintEdge) bool {
+	before := func(end, start string) bool {
+		return end != "" && start != "" && end <= start
+	}
+	return !before(a.invalidAt, b.validAt) && !before(b.invalidAt, a.validAt)
+}
+
+// checkSymmetric reports current edges stored both ways round and edges
+// from an entity to itself. Of a pair, the edge stored later is deleted.
+func checkSymmetric(c Constraint, edges []constraintEdge) []ConstraintViolation {
+	var out []ConstraintViolation
+	seen := map[string]constraintEdge{}
+	for _, e := range edges {
+		if e.invalidAt != "" || e.target == "" {
+			continue
+		}
+		v := ConstraintViolation{
+			Constraint:   c.Name,
+			Kind:         c.Kind,
+			RelationType: c.RelationType,
+			EntityID:     e.source,
+		}
+		if e.target == e.source {
+			v.Message = fmt.Sprintf("%s fact from an entity to itself", c.RelationType)
+			v.Edges = []EntityRelationship{{ID: e.id}}
+			v.Fixes = []ConstraintFix{{Action: FixDelete, RelationshipID: e.id, Reason: "an entity cannot be related to itself this way"}}
+			out = append(out, v)
+			continue
+		}
+		if first, ok := seen[e.target+"|"+e.source]; ok {
+			v.EntityID = first.source
+			v.Message = fmt.Sprintf("%s fact stored both ways round", c.RelationType)
+			v.Edges = []EntityRelationship{{ID: first.id}, {ID: e.id}}
+			v.Fixes = []ConstraintFix{{Action: FixDelete, RelationshipID: e.id, Reason: "duplicates " + first.id}}
+			out = append(out, v)
+			continue
+		}
+		seen[e.source+"|"+e.target] = e
+	}
+	return out
+}
+
+// fillViolation loads the violation's edges in full and names its entity.
+func fillViolation(ctx context.Context, db *sql.DB, v *ConstraintViolation) error {
+	for i, edge := range v.Edges {
+		rel, err := GetFact(ctx, db, edge.ID)
+		if err != nil {
+			return err
+		}
+		v.Edges[i] = *rel
+	}
+	if err := db.QueryRowContext(ctx, `SELECT canonical_name FROM entities WHERE id = ?`, v.EntityID).Scan(&v.EntityName); err != nil {
+		return fmt.Errorf("entity %s: %w", v.EntityID, err)
+	}
+	v.Message = v.EntityName + ": " + v.Message
+	return nil
+}
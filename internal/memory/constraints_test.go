@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestCheckConstraints(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, name := range []string{"Tyler", "Sam", "Casey", "Dana", "Lee"} {
+		insertQueryEngineTestEntity(t, db, name, name, EntityTypePerson)
+	}
+	sam, casey, dana, lee := "Sam", "Casey", "Dana", "Lee"
+	d1, d2 := "1990-04-02", "1990-02-04"
+	insertQueryEngineTestRelationship(t, db, "born-1", "Tyler", nil, &d1, "BORN_ON", "Tyler was born on 1990-04-02", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "born-2", "Tyler", nil, &d2, "BORN_ON", "Tyler was born on 1990-02-04", nil, nil)
+	if _, err := db.Exec(`UPDATE relationships SET confidence = 0.6 WHERE id = 'born-1'`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Married to Sam until 2020, to Casey since 2019; Dana's marriage has no dates
+	until, since := "2020-06", "2019"
+	insertQueryEngineTestRelationship(t, db, "m-sam", "Tyler", &sam, nil, "MARRIED_TO", "Tyler is married to Sam", nil, &until)
+	insertQueryEngineTestRelationship(t, db, "m-casey", "Casey", &dana, nil, "MARRIED_TO", "Casey is married to Dana", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "m-casey-2", "Tyler", &casey, nil, "MARRIED_TO", "Tyler is married to Casey", &since, nil)
+	// Stored both ways round, and a self-loop
+	insertQueryEngineTestRelationship(t, db, "s-1", "Dana", &lee, nil, "SIBLING_OF", "Dana and Lee are siblings", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "s-2", "Lee", &dana, nil, "SIBLING_OF", "Lee and Dana are siblings", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "s-3", "Sam", &sam, nil, "SIBLING_OF", "Sam is a sibling of Sam", nil, nil)
+
+	report, err := CheckConstraints(ctx, db, CheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byKey := map[string]ConstraintViolation{}
+	for _, v := range report.Violations {
+		byKey[v.Constraint+"|"+v.EntityID] = v
+	}
+	if len(report.Violations) != 5 {
+		t.Errorf("violations = %+v", report.Violations)
+	}
+
+	if v := byKey["one_birthdate|Tyler"]; len(v.Edges) != 2 || len(v.Fixes) != 1 ||
+		v.Fixes[0].Action != FixInvalidate || v.Fixes[0].RelationshipID != "born-1" || v.EntityName != "Tyler" {
+		t.Errorf("birthdate = %+v", v)
+	}
+
+	// Tyler's two marriages overlap from 2019 to 2020-06
+	if v := byKey["married_exclusive|Tyler"]; len(v.Edges) != 2 || len(v.Fixes) != 1 || v.Fixes[0].Action != FixEnd ||
+		v.Fixes[0].RelationshipID != "m-sam" || v.Fixes[0].InvalidAt != "2019" {
+		t.Errorf("Tyler's marriages = %+v", v)
+	}
+	// Casey is married to Tyler and, from the other end, to Dana
+	if v := byKey["married_exclusive|Casey"]; len(v.Edges) != 2 || len(v.Fixes) != 1 || v.Fixes[0].Action != FixEnd ||
+		v.Fixes[0].RelationshipID != "m-casey" || v.Fixes[0].InvalidAt != "2019" {
+		t.Errorf("Casey's marriages = %+v", v)
+	}
+
+	if v := byKey["sibling_symmetric|Dana"]; len(v.Edges) != 2 || v.Fixes[0].Action != FixDelete || v.Fixes[0].RelationshipID != "s-2" {
+		t.Errorf("duplicate siblings = %+v", v)
+	}
+	if v := byKey["sibling_symmetric|Sam"]; len(v.Fixes) != 1 || v.Fixes[0].RelationshipID != "s-3" {
+		t.Errorf("self-loop = %+v", v)
+	}
+
+	// Applying the fixes clears the report
+	for _, v := range report.Violations {
+		for _, fix := range v.Fixes {
+			var err error
+			switch fix.Action {
+			case FixInvalidate:
+				_, err = InvalidateFact(ctx, db, fix.RelationshipID, "")
+			case FixEnd:
+				_, err = db.Exec(`UPDATE relationships SET invalid_at = ? WHERE id = ?`, fix.InvalidAt, fix.RelationshipID)
+			case FixDelete:
+				err = DeleteFact(ctx, db, fix.RelationshipID)
+			}
+			if err != nil {
+				t.Fatalf("%+v: %v", fix, err)
+			}
+		}
+	}
+	report, err = CheckConstraints(ctx, db, CheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Violations) != 0 {
+		t.Errorf("after fixes = %+v", report.Violations)
+	}
+}
+
+func TestCheckConstraints_ConfiguredAndSelected(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertQueryEngineTestEntity(t, db, "tyler", "Tyler", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "acme", "Acme", EntityTypeOrganization)
+	insertQueryEngineTestEntity(t, db, "initech", "Initech", EntityTypeOrganization)
+	acme, initech := "acme", "initech"
+	insertQueryEngineTestRelationship(t, db, "w1", "tyler", &acme, nil, "WORKS_AT", "Tyler works at Acme", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "w2", "tyler", &initech, nil, "WORKS_AT", "Tyler works at Initech", nil, nil)
+
+	constraints := []Constraint{
+		{Name: "one_employer", Kind: ConstraintExclusive, RelationType: "works_at"},
+		{Name: "one_birthdate", Kind: ConstraintAtMostOne, RelationType: "BORN_ON"},
+	}
+	report, err := CheckConstraints(ctx, db, CheckOptions{Constraints: constraints, Names: []string{"one_employer"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Constraints) != 1 || len(report.Violations) != 1 || report.Violations[0].Fixes[0].Action != FixInvalidate ||
+		report.Violations[0].Fixes[0].RelationshipID != "w1" {
+		t.Errorf("report = %+v", report)
+	}
+
+	if _, err := CheckConstraints(ctx, db, CheckOptions{Names: []string{"nope"}}); err == nil {
+		t.Error("unknown constraint: no error")
+	}
+	for _, bad := range []Constraint{
+		{Name: "x", Kind: "unique", RelationType: "WORKS_AT"},
+		{Name: "x", Kind: ConstraintAtMostOne, RelationType: "EMPLOYS"},
+		{Name: "", Kind: ConstraintAtMostOne, RelationType: "BORN_ON"},
+	} {
+		if _, err := ValidateConstraints([]Constraint{bad}); err == nil {
+			t.Errorf("%+v: no error", bad)
+		}
+	}
+}
@@ -11,45 +11,47 @@ import (
 type ContradictionResult struct {
 	ContradictionsFound int      // Number of contradictions detected
 	InvalidatedIDs      []string // IDs of relationships that were invalidated
-}
-
-// ContradictingRelationType defines how relationships contradict each other.
-// Some relationships are "exclusive" - only one can be true at a time (WORKS_AT, LIVES_IN).
-// When a new exclusive relationship is found, the old one is invalidated.
-var ExclusiveRelationTypes = map[string]bool{
-	// Employment - typically one primary employer at a time
-	"WORKS_AT": true,
-
-	// Residence - typically one primary residence at a time
-	"LIVES_IN": true,
-
-	// Marital status - mutually exclusive
-	"SPOUSE_OF": true,
-	"MARRIED_TO": true,
-
-	// Dating - typically exclusive
-	"DATING": true,
-
-	// Attendance at institutions (e.g., which school you attend)
-	// Note: ATTENDED can be multiple historical, but current attendance is singular
-	// We handle this by checking valid_at/invalid_at
+	ViolationsFlagged   int      // Cardinality violations recorded for review
+	InferencesRetracted int      // Inferred facts ended because a premise was invalidated
 }
 
 // ContradictionDetector finds and invalidates contradicted facts.
 // When a new fact contradicts an existing fact, the old fact gets
 // invalid_at set to mark it as no longer true.
+//
+// Which relation types can be contradicted is driven by cardinality rules
+// (see DefaultCardinalityRules). CardinalityOne types with EnforceFlag record
+// a cardinality_violations row for review instead of invalidating.
 type ContradictionDetector struct {
-	db *sql.DB
+	db    *sql.DB
+	rules map[string]CardinalityRule
 }
 
-// NewContradictionDetector creates a new ContradictionDetector.
+// NewContradictionDetector creates a new ContradictionDetector using
+// DefaultCardinalityRules.
 func NewContradictionDetector(db *sql.DB) *ContradictionDetector {
-	return &ContradictionDetector{db: db}
+	return &ContradictionDetector{db: db, rules: CardinalityRules(nil)}
+}
+
+// SetCardinalityRules applies per-relation-type overrides on top of
+// DefaultCardinalityRules.
+func (d *ContradictionDetector) SetCardinalityRules(overrides map[string]CardinalityRule) {
+	d.rules = CardinalityRules(overrides)
+}
+
+// CardinalityRule returns the effective rule for a relation type.
+// Types without a rule are CardinalityMany.
+func (d *ContradictionDetector) CardinalityRule(relType string) CardinalityRule {
+	if rule, ok := d.rules[relType]; ok {
+		return rule
+	}
+	return CardinalityRule{Cardinality: CardinalityMany}
 }
 
 // Detect finds existing facts that are contradicted by new facts and invalidates them.
-// For exclusive relationship types (WORKS_AT, LIVES_IN, etc.), having a new relationship
-// implies the old one is no longer true.
+// For CardinalityOne relationship types (WORKS_AT, LIVES_IN, etc.), having a new relationship
+// implies the old one is no longer true. Types enforced with EnforceFlag are
+// recorded as violations instead.
 //
 // The invalidationTime is used as the invalid_at value when the new relationship
 // doesn't have an explicit valid_at date.
@@ -61,19 +63,29 @@ func (d *ContradictionDetector) Detect(ctx context.Context, newRelationshipIDs [
 	}
 
 	for _, newRelID := range newRelationshipIDs {
-		invalidated, err := d.detectForRelationship(ctx, newRelID, invalidationTime)
+		invalidated, flagged, err := d.detectForRelationship(ctx, newRelID, invalidationTime)
 		if err != nil {
 			return nil, fmt.Errorf("detect contradictions for relationship %s: %w", newRelID, err)
 		}
 		result.InvalidatedIDs = append(result.InvalidatedIDs, invalidated...)
 		result.ContradictionsFound += len(invalidated)
+		result.ViolationsFlagged += flagged
+	}
+
+	if len(result.InvalidatedIDs) > 0 {
+		retracted, err := RetractInferences(ctx, d.db)
+		if err != nil {
+			return nil, fmt.Errorf("retract inferences: %w", err)
+		}
+		result.InferencesRetracted = retracted
 	}
 
 	return result, nil
 }
 
 // detectForRelationship checks if a new relationship contradicts any existing relationships.
-func (d *ContradictionDetector) detectForRelationship(ctx context.Context, newRelID string, invalidationTime time.Time) ([]string, error) {
+// Returns the IDs of invalidated relationships and the number of violations flagged.
+func (d *ContradictionDetector) detectForRelationship(ctx context.Context, newRelID string, invalidationTime time.Time) ([]string, int, error) {
 	// Get the new relationship's details
 	var sourceEntityID, relationType string
 	var targetEntityID, targetLiteral, validAt sql.NullString
@@ -86,14 +98,34 @@ func (d *ContradictionDetector) detectForRelationship(ctx context.Context, newRe
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, nil // Relationship doesn't exist
+			return nil, 0, nil // Relationship doesn't exist
 		}
-		return nil, fmt.Errorf("get relationship details: %w", err)
+		return nil, 0, fmt.Errorf("get relationship details: %w", err)
 	}
 
-	// Only check exclusive relationship types
-	if !ExclusiveRelationTypes[relationType] {
-		return nil, nil
+	// Only relation types declared CardinalityOne can be contradicted
+	rule, ok := d.rules[relationType]
+	if !ok || rule.Cardinality != CardinalityOne {
+		return nil, 0, nil
+	}
+
+	if rule.Enforcement == EnforceFlag {
+		// Any other current value is a violation, regardless of dates
+		conflicting, err := d.findConflicting(ctx, newRelID, sourceEntityID, relationType, targetEntityID, targetLiteral, sql.NullString{}, false)
+		if err != nil {
+			return nil, 0, err
+		}
+		flagged := 0
+		for _, oldID := range conflicting {
+			created, err := d.recordViolation(ctx, sourceEntityID, relationType, newRelID, oldID)
+			if err != nil {
+				return nil, 0, err
+			}
+			if created {
+				flagged++
+			}
+		}
+		return nil, flagged, nil
 	}
 
 	// Find existing relationships that might be contradicted
@@ -103,138 +135,83 @@ func (d *ContradictionDetector) detectForRelationship(ctx context.Context, newRe
 	// 3. Different target (entity or literal)
 	// 4. Old relationship is still "current" (invalid_at IS NULL)
 	// 5. Old relationship's valid_at is before the new one (or both NULL)
-	invalidated := make([]string, 0)
-
-	if targetEntityID.Valid {
-		// Entity-targeted relationship - find contradicting entity targets
-		// Only invalidate relationships that are older than the new one:
-		// - If new has valid_at: invalidate if old has NULL or earlier valid_at
-		// - If new has no valid_at: invalidate if old also has no valid_at (same-episode fallback)
-		var query string
-		var args []interface{}
-		if validAt.Valid {
-			// New relationship has a date - only invalidate older ones
-			query = `
-				SELECT id FROM relationships
-				WHERE source_entity_id = ?
-				  AND relation_type = ?
-				  AND target_entity_id IS NOT NULL
-				  AND target_entity_id != ?
-				  AND invalid_at IS NULL
-				  AND id != ?
-				  AND (valid_at IS NULL OR valid_at < ?)
-			`
-			args = []interface{}{sourceEntityID, relationType, targetEntityID.String, newRelID, validAt.String}
-		} else {
-			// New relationship has no date - only invalidate others without dates
-			// This handles same-episode conflicts when neither has explicit dates
-			query = `
-				SELECT id FROM relationships
-				WHERE source_entity_id = ?
-				  AND relation_type = ?
-				  AND target_entity_id IS NOT NULL
-				  AND target_entity_id != ?
-				  AND invalid_at IS NULL
-				  AND id != ?
-				  AND valid_at IS NULL
-			`
-			args = []interface{}{sourceEntityID, relationType, targetEntityID.String, newRelID}
-		}
-		rows, err := d.db.QueryContext(ctx, query, args...)
+	oldIDs, err := d.findConflicting(ctx, newRelID, sourceEntityID, relationType, targetEntityID, targetLiteral, validAt, true)
+	if err != nil {
+		return nil, 0, err
+	}
 
-		if err != nil {
-			return nil, fmt.Errorf("find entity contradictions: %w", err)
+	// Now invalidate each relationship (after rows is closed)
+	invalidated := make([]string, 0, len(oldIDs))
+	for _, oldID := range oldIDs {
+		if err := d.invalidateRelationship(ctx, oldID, invalidationTime, validAt); err != nil {
+			return nil, 0, err
 		}
+		invalidated = append(invalidated, oldID)
+	}
 
-		// Collect all IDs first before closing rows
-		// (SQLite doesn't support concurrent queries on same connection)
-		var oldIDs []string
-		for rows.Next() {
-			var oldID string
-			if err := rows.Scan(&oldID); err != nil {
-				rows.Close()
-				return nil, err
-			}
-			oldIDs = append(oldIDs, oldID)
-		}
-		rows.Close()
+	return invalidated, 0, nil
+}
 
-		if err := rows.Err(); err != nil {
-			return nil, err
-		}
+// findConflicting returns current relationships from the same source and of
+// the same type whose target differs from the new relationship's target.
+//
+// With ordered set, only relationships older than the new one are returned:
+// - If new has valid_at: old has NULL or earlier valid_at
+// - If new has no valid_at: old also has no valid_at (same-episode fallback)
+func (d *ContradictionDetector) findConflicting(ctx context.Context, newRelID, sourceEntityID, relationType string, targetEntityID, targetLiteral, validAt sql.NullString, ordered bool) ([]string, error) {
+	var targetColumn, target string
+	switch {
+	case targetEntityID.Valid:
+		targetColumn, target = "target_entity_id", targetEntityID.String
+	case targetLiteral.Valid:
+		targetColumn, target = "target_literal", targetLiteral.String
+	default:
+		return nil, nil
+	}
 
-		// Now invalidate each relationship (after rows is closed)
-		for _, oldID := range oldIDs {
-			if err := d.invalidateRelationship(ctx, oldID, invalidationTime, validAt); err != nil {
-				return nil, err
-			}
-			invalidated = append(invalidated, oldID)
-		}
-	} else if targetLiteral.Valid {
-		// Literal-targeted relationship - find contradicting literal targets
-		// Only invalidate relationships that are older than the new one:
-		// - If new has valid_at: invalidate if old has NULL or earlier valid_at
-		// - If new has no valid_at: invalidate if old also has no valid_at (same-episode fallback)
-		var query string
-		var args []interface{}
+	query := `
+		SELECT id FROM relationships
+		WHERE source_entity_id = ?
+		  AND relation_type = ?
+		  AND ` + targetColumn + ` IS NOT NULL
+		  AND ` + targetColumn + ` != ?
+		  AND invalid_at IS NULL
+		  AND id != ?
+	`
+	args := []interface{}{sourceEntityID, relationType, target, newRelID}
+	if ordered {
 		if validAt.Valid {
-			query = `
-				SELECT id FROM relationships
-				WHERE source_entity_id = ?
-				  AND relation_type = ?
-				  AND target_literal IS NOT NULL
-				  AND target_literal != ?
-				  AND invalid_at IS NULL
-				  AND id != ?
-				  AND (valid_at IS NULL OR valid_at < ?)
-			`
-			args = []interface{}{sourceEntityID, relationType, targetLiteral.String, newRelID, validAt.String}
+			// New relationship has a date - only older ones
+			query += ` AND (valid_at IS NULL OR valid_at < ?)`
+			args = append(args, validAt.String)
 		} else {
-			query = `
-				SELECT id FROM relationships
-				WHERE source_entity_id = ?
-				  AND relation_type = ?
-				  AND target_literal IS NOT NULL
-				  AND target_literal != ?
-				  AND invalid_at IS NULL
-				  AND id != ?
-				  AND valid_at IS NULL
-			`
-			args = []interface{}{sourceEntityID, relationType, targetLiteral.String, newRelID}
-		}
-		rows, err := d.db.QueryContext(ctx, query, args...)
-
-		if err != nil {
-			return nil, fmt.Errorf("find literal contradictions: %w", err)
+			// New relationship has no date - only others without dates
+			query += ` AND valid_at IS NULL`
 		}
+	}
 
-		// Collect all IDs first before closing rows
-		// (SQLite doesn't support concurrent queries on same connection)
-		var oldIDs []string
-		for rows.Next() {
-			var oldID string
-			if err := rows.Scan(&oldID); err != nil {
-				rows.Close()
-				return nil, err
-			}
-			oldIDs = append(oldIDs, oldID)
-		}
-		rows.Close()
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("find contradictions: %w", err)
+	}
 
-		if err := rows.Err(); err != nil {
+	// Collect all IDs first before closing rows
+	// (SQLite doesn't support concurrent queries on same connection)
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
 			return nil, err
 		}
-
-		// Now invalidate each relationship (after rows is closed)
-		for _, oldID := range oldIDs {
-			if err := d.invalidateRelationship(ctx, oldID, invalidationTime, validAt); err != nil {
-				return nil, err
-			}
-			invalidated = append(invalidated, oldID)
-		}
+		ids = append(ids, id)
 	}
+	rows.Close()
 
-	return invalidated, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
 }
 
 // invalidateRelationship sets invalid_at on an old relationship.
@@ -274,15 +251,17 @@ func (d *ContradictionDetector) DetectAndInvalidate(ctx context.Context, newRelI
 }
 
 // IsExclusiveRelationType returns true if the relation type is mutually exclusive
-// (only one can be current at a time for a given source entity).
+// (only one can be current at a time for a given source entity), i.e. its
+// default cardinality rule is CardinalityOne enforced by invalidation.
 func IsExclusiveRelationType(relType string) bool {
-	return ExclusiveRelationTypes[relType]
+	rule := DefaultCardinalityRules[relType]
+	return rule.Cardinality == CardinalityOne && rule.Enforcement == EnforceInvalidate
 }
 
 // GetContradictingRelationships finds existing relationships that would be contradicted
 // by a new relationship (without actually invalidating them). Useful for preview/dry-run.
 func (d *ContradictionDetector) GetContradictingRelationships(ctx context.Context, sourceEntityID, relationType string, targetEntityID, targetLiteral *string) ([]string, error) {
-	if !ExclusiveRelationTypes[relationType] {
+	if d.CardinalityRule(relationType).Cardinality != CardinalityOne {
 		return nil, nil
 	}
 
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Napageneral/mnemonic/internal/config"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -41,7 +42,32 @@ func setupContradictionTestDB(t *testing.T) *sql.DB {
 			valid_at TEXT,
 			invalid_at TEXT,
 			created_at TEXT NOT NULL,
-			confidence REAL DEFAULT 1.0
+			confidence REAL DEFAULT 1.0,
+			weight REAL DEFAULT 0,
+			source_type TEXT,
+			origin TEXT
+		);
+
+		CREATE TABLE relationship_premises (
+			relationship_id TEXT NOT NULL,
+			derivation TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			premise_id TEXT NOT NULL,
+			rule TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			PRIMARY KEY (relationship_id, derivation, position)
+		);
+
+		CREATE TABLE cardinality_violations (
+			id TEXT PRIMARY KEY,
+			entity_id TEXT NOT NULL,
+			relation_type TEXT NOT NULL,
+			relationship_id TEXT NOT NULL,
+			conflicting_relationship_id TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			created_at TEXT NOT NULL,
+			resolved_at TEXT,
+			UNIQUE(relationship_id, conflicting_relationship_id)
 		);
 	`
 
@@ -554,3 +580,127 @@ func TestContradictionDetector_NonexistentRelationship(t *testing.T) {
 		t.Errorf("Expected 0 contradictions, got %d", result.ContradictionsFound)
 	}
 }
+
+func TestContradictionDetector_FlagsImmutableCardinality(t *testing.T) {
+	db := setupContradictionTestDB(t)
+	defer db.Close()
+
+	detector := NewContradictionDetector(db)
+	ctx := context.Background()
+
+	insertContradictionTestEntity(t, db, "tyler", "Tyler", 1)
+	insertContradictionTestRelWithLiteral(t, db, "born-1", "tyler", "1990-05-01", "BORN_ON", nil, nil)
+	insertContradictionTestRelWithLiteral(t, db, "born-2", "tyler", "1991-05-01", "BORN_ON", nil, nil)
+
+	result, err := detector.Detect(ctx, []string{"born-2"}, time.Now())
+	if err != nil {
+		t.Fatalf("Detect failed: %v", err)
+	}
+	if result.ContradictionsFound != 0 || result.ViolationsFlagged != 1 {
+		t.Fatalf("Expected 0 contradictions and 1 violation, got %+v", result)
+	}
+	if getRelationshipInvalidAt(t, db, "born-1") != nil {
+		t.Error("Flagged relationship should not be invalidated")
+	}
+
+	// Re-detecting does not duplicate the violation
+	result, _ = detector.Detect(ctx, []string{"born-2"}, time.Now())
+	if result.ViolationsFlagged != 0 {
+		t.Errorf("Expected no new violations on re-detect, got %d", result.ViolationsFlagged)
+	}
+
+	violations, err := detector.ListCardinalityViolations(ctx, ViolationStatusPending, 0)
+	if err != nil {
+		t.Fatalf("ListCardinalityViolations failed: %v", err)
+	}
+	if len(violations) != 1 {
+		t.Fatalf("Expected 1 pending violation, got %d", len(violations))
+	}
+	v := violations[0]
+	if v.RelationshipID != "born-2" || v.ConflictingRelationshipID != "born-1" || v.EntityID != "tyler" {
+		t.Errorf("Unexpected violation: %+v", v)
+	}
+
+	// Keep the original birthdate: the new edge is invalidated
+	if err := detector.ResolveCardinalityViolation(ctx, v.ID, "born-1"); err != nil {
+		t.Fatalf("ResolveCardinalityViolation failed: %v", err)
+	}
+	if getRelationshipInvalidAt(t, db, "born-2") == nil {
+		t.Error("Expected born-2 to be invalidated")
+	}
+	if getRelationshipInvalidAt(t, db, "born-1") != nil {
+		t.Error("Expected born-1 to remain valid")
+	}
+	if err := detector.ResolveCardinalityViolation(ctx, v.ID, ""); err == nil {
+		t.Error("Expected error resolving an already-resolved violation")
+	}
+}
+
+func TestContradictionDetector_CardinalityOverrides(t *testing.T) {
+	db := setupContradictionTestDB(t)
+	defer db.Close()
+
+	detector := NewContradictionDetector(db)
+	detector.SetCardinalityRules(map[string]CardinalityRule{
+		"WORKS_AT":  {Cardinality: CardinalityMany}, // Allow multiple employers
+		"MEMBER_OF": {Cardinality: CardinalityOne},  // Defaults to invalidate
+		"BORN_ON":   {Cardinality: CardinalityOne, Enforcement: EnforceInvalidate},
+	})
+	ctx := context.Background()
+
+	insertContradictionTestEntity(t, db, "tyler", "Tyler", 1)
+	insertContradictionTestEntity(t, db, "acme", "Acme", 2)
+	insertContradictionTestEntity(t, db, "globex", "Globex", 2)
+	insertContradictionTestRelWithEntity(t, db, "work-1", "tyler", "acme", "WORKS_AT", nil, nil)
+	insertContradictionTestRelWithEntity(t, db, "work-2", "tyler", "globex", "WORKS_AT", nil, nil)
+	insertContradictionTestRelWithEntity(t, db, "member-1", "tyler", "acme", "MEMBER_OF", nil, nil)
+	insertContradictionTestRelWithEntity(t, db, "member-2", "tyler", "globex", "MEMBER_OF", nil, nil)
+
+	result, err := detector.Detect(ctx, []string{"work-2", "member-2"}, time.Now())
+	if err != nil {
+		t.Fatalf("Detect failed: %v", err)
+	}
+	if result.ContradictionsFound != 1 || result.InvalidatedIDs[0] != "member-1" {
+		t.Errorf("Expected only member-1 invalidated, got %+v", result)
+	}
+	if getRelationshipInvalidAt(t, db, "work-1") != nil {
+		t.Error("WORKS_AT overridden to many should not invalidate")
+	}
+
+	if rule := detector.CardinalityRule("BORN_ON"); rule.Enforcement != EnforceInvalidate {
+		t.Errorf("Expected BORN_ON override to invalidate, got %+v", rule)
+	}
+	if rule := detector.CardinalityRule("UNKNOWN_TYPE"); rule.Cardinality != CardinalityMany {
+		t.Errorf("Expected unknown types to be many, got %+v", rule)
+	}
+	if DefaultCardinalityRules["WORKS_AT"].Cardinality != CardinalityOne {
+		t.Error("Overrides must not modify DefaultCardinalityRules")
+	}
+}
+
+func TestCardinalityRulesFromConfig(t *testing.T) {
+	pc, err := NewPipelineConfig(config.MemoryConfig{Cardinality: map[string]config.CardinalityConfig{
+		"works_at": {Cardinality: "one", Enforcement: "flag"},
+		"DATING":   {},
+	}})
+	if err != nil {
+		t.Fatalf("NewPipelineConfig: %v", err)
+	}
+	detector := NewContradictionDetector(nil)
+	detector.SetCardinalityRules(pc.CardinalityRules)
+	if rule := detector.CardinalityRule("WORKS_AT"); rule.Enforcement != EnforceFlag {
+		t.Errorf("WORKS_AT rule = %+v, want flagged", rule)
+	}
+	if rule := detector.CardinalityRule("DATING"); rule.Cardinality != CardinalityMany {
+		t.Errorf("DATING rule = %+v, want the default removed", rule)
+	}
+	if rule := detector.CardinalityRule("BORN_ON"); rule.Enforcement != EnforceFlag {
+		t.Errorf("BORN_ON rule = %+v, want the default kept", rule)
+	}
+
+	for _, bad := range []config.CardinalityConfig{{Cardinality: "two"}, {Cardinality: "one", Enforcement: "ignore"}} {
+		if _, err := NewPipelineConfig(config.MemoryConfig{Cardinality: map[string]config.CardinalityConfig{"WORKS_AT": bad}}); err == nil {
+			t.Errorf("cardinality %+v accepted", bad)
+		}
+	}
+}
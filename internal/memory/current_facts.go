@@ -0,0 +1,230 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CurrentFact is an entity's latest currently-valid edge for one relation type.
+type CurrentFact struct {
+	EntityID       string  `json:"entity_id"`
+	RelationType   string  `json:"relation_type"`
+	RelationshipID string  `json:"relationship_id"`
+	TargetEntityID *string `json:"target_entity_id,omitempty"`
+	TargetName     *string `json:"target_name,omitempty"`
+	TargetLiteral  *string `json:"target_literal,omitempty"`
+	Fact           string  `json:"fact"`
+	ValidAt        *string `json:"valid_at,omitempty"`
+	Confidence     float64 `json:"confidence"`
+	Weight         float64 `json:"weight"`
+	UpdatedAt      string  `json:"updated_at"`
+}
+
+// CurrentFactsStore maintains entity_current_facts: for each entity, the latest
+// non-invalidated outgoing edge per relation type. Readers ('entity show',
+// /api/entities/{id}) use it through QueryEngine.GetCurrentFacts instead of
+// re-applying temporal filtering on every read.
+//
+// The table is refreshed incrementally for entities touched by the pipeline
+// and merges. Edges whose invalid_at lies in the future become stale once that
+// time passes; the current_facts maintenance task runs RefreshAll daily to
+// correct them.
+type CurrentFactsStore struct {
+	db *sql.DB
+}
+
+// NewCurrentFactsStore creates a new CurrentFactsStore.
+func NewCurrentFactsStore(db *sql.DB) *CurrentFactsStore {
+	return &CurrentFactsStore{db: db}
+}
+
+// currentFactsSelect selects the latest valid edge per (source, relation_type).
+// Ordered by valid_at (falling back to created_at), newest first.
+const currentFactsSelect = `
+	SELECT source_entity_id, relation_type, id, target_entity_id, target_literal,
+	       fact, valid_at, confidence
+	FROM (
+		SELECT r.*,
+		       ROW_NUMBER() OVER (
+		           PARTITION BY r.source_entity_id, r.relation_type
+		           ORDER BY COALESCE(r.valid_at, r.created_at) DESC, r.created_at DESC, r.id
+		       ) AS rn
+		FROM relationships r
+		JOIN entities src ON src.id = r.source_entity_id AND src.merged_into IS NULL
+		LEFT JOIN entities tgt ON tgt.id = r.target_entity_id
+		WHERE (r.invalid_at IS NULL OR r.invalid_at > ?)
+		  AND (r.target_entity_id IS NULL OR tgt.merged_into IS NULL)
+		  %s
+	)
+	WHERE rn = 1
+`
+
+// RefreshEntities recomputes current facts for the given entities.
+func (s *CurrentFactsStore) RefreshEntities(ctx context.Context, entityIDs []string) error {
+	ids := uniqueIDs(entityIDs)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Format(time.RFC3339)
+	for _, chunk := range chunkIDs(ids, maxBatchIDs) {
+		args := idArgs(chunk)
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM entity_current_facts WHERE entity_id IN (`+placeholderList(len(chunk))+`)
+		`, args...); err != nil {
+			return fmt.Errorf("clear current facts: %w", err)
+		}
+
+		insertArgs := append([]interface{}{now, now}, args...)
+		filter := "AND r.source_entity_id IN (" + placeholderList(len(chunk)) + ")"
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO entity_current_facts (
+				entity_id, relation_type, relationship_id, target_entity_id, target_literal,
+				fact, valid_at, confidence, updated_at
+			)
+			SELECT cf.*, ? FROM (`+fmt.Sprintf(currentFactsSelect, filter)+`) cf
+		`, insertArgs...); err != nil {
+			return fmt.Errorf("insert current facts: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RefreshAfterMerge refreshes the merge target, drops the merged source, and
+// refreshes entities whose current facts pointed at either side.
+func (s *CurrentFactsStore) RefreshAfterMerge(ctx context.Context, sourceID, targetID string) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT entity_id FROM entity_current_facts
+		WHERE target_entity_id IN (?, ?)
+	`, sourceID, targetID)
+	if err != nil {
+		return fmt.Errorf("find dependent facts: %w", err)
+	}
+	ids := []string{sourceID, targetID}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan dependent entity: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return s.RefreshEntities(ctx, ids)
+}
+
+// RefreshAll rebuilds the whole table. Returns the number of facts written.
+func (s *CurrentFactsStore) RefreshAll(ctx context.Context) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM entity_current_facts`); err != nil {
+		return 0, fmt.Errorf("clear current facts: %w", err)
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO entity_current_facts (
+			entity_id, relation_type, relationship_id, target_entity_id, target_literal,
+			fact, valid_at, confidence, updated_at
+		)
+		SELECT cf.*, ? FROM (`+fmt.Sprintf(currentFactsSelect, "")+`) cf
+	`, now, now)
+	if err != nil {
+		return 0, fmt.Errorf("insert current facts: %w", err)
+	}
+	written, _ := res.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return int(written), nil
+}
+
+// GetCurrentFacts returns an entity's current facts, strongest relationship
+// first (ties ordered by relation type).
+func (s *CurrentFactsStore) GetCurrentFacts(ctx context.Context, entityID string) ([]CurrentFact, error) {
+	if entityID == "" {
+		return nil, fmt.Errorf("entityID is required")
+	}
+	facts, err := s.GetCurrentFactsBatch(ctx, []string{entityID})
+	if err != nil {
+		return nil, err
+	}
+	return facts[entityID], nil
+}
+
+// GetCurrentFactsBatch returns current facts for many entities, keyed by
+// entity ID, each ordered like GetCurrentFacts.
+func (s *CurrentFactsStore) GetCurrentFactsBatch(ctx context.Context, entityIDs []string) (map[string][]CurrentFact, error) {
+	results := make(map[string][]CurrentFact, len(entityIDs))
+
+	for _, chunk := range chunkIDs(uniqueIDs(entityIDs), maxBatchIDs) {
+		rows, err := s.db.QueryContext(ctx, `
+			SELECT cf.entity_id, cf.relation_type, cf.relationship_id, cf.target_entity_id,
+			       tgt.canonical_name, cf.target_literal, cf.fact, cf.valid_at, cf.confidence,
+			       COALESCE(r.weight, 0), cf.updated_at
+			FROM entity_current_facts cf
+			LEFT JOIN entities tgt ON tgt.id = cf.target_entity_id
+			LEFT JOIN relationships r ON r.id = cf.relationship_id
+			WHERE cf.entity_id IN (`+placeholderList(len(chunk))+`)
+			ORDER BY cf.entity_id, COALESCE(r.weight, 0) DESC, cf.relation_type
+		`, idArgs(chunk)...)
+		if err != nil {
+			return nil, fmt.Errorf("query current facts: %w", err)
+		}
+
+		for rows.Next() {
+			var (
+				f             CurrentFact
+				targetID      sql.NullString
+				targetName    sql.NullString
+				targetLiteral sql.NullString
+				validAt       sql.NullString
+			)
+			if err := rows.Scan(&f.EntityID, &f.RelationType, &f.RelationshipID, &targetID,
+				&targetName, &targetLiteral, &f.Fact, &validAt, &f.Confidence, &f.Weight, &f.UpdatedAt); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scan current fact: %w", err)
+			}
+			if targetID.Valid {
+				f.TargetEntityID = &targetID.String
+			}
+			if targetName.Valid {
+				f.TargetName = &targetName.String
+			}
+			if targetLiteral.Valid {
+				f.TargetLiteral = &targetLiteral.String
+			}
+			if validAt.Valid {
+				f.ValidAt = &validAt.String
+			}
+			results[f.EntityID] = append(results[f.EntityID], f)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
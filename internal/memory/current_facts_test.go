@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+// insertCurrentFactsRel inserts a relationship with explicit temporal bounds.
+func insertCurrentFactsRel(t *testing.T, db *sql.DB, id, sourceID string, targetID, literal *string, relType, validAt string, invalidAt *string) {
+	t.Helper()
+	var valid interface{}
+	if validAt != "" {
+		valid = validAt
+	}
+	_, err := db.Exec(`
+		INSERT INTO relationships (id, source_entity_id, target_entity_id, target_literal, relation_type, fact, valid_at, invalid_at, created_at, confidence)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1.0)
+	`, id, sourceID, targetID, literal, relType, relType+" fact", valid, invalidAt, time.Now().Format(time.RFC3339))
+	if err != nil {
+		t.Fatalf("insert relationship: %v", err)
+	}
+}
+
+func TestCurrentFactsStore_RefreshEntities(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertQueryEngineTestEntity(t, db, "tyler", "Tyler", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "acme", "Acme", EntityTypeCompany)
+	insertQueryEngineTestEntity(t, db, "anthropic", "Anthropic", EntityTypeCompany)
+
+	acme, anthropic := "acme", "anthropic"
+	ended := "2023-06-01"
+	birthday := "1990-05-01"
+	insertCurrentFactsRel(t, db, "r1", "tyler", &acme, nil, "WORKS_AT", "2020-01-01", &ended)
+	insertCurrentFactsRel(t, db, "r2", "tyler", &anthropic, nil, "WORKS_AT", "2023-06-01", nil)
+	insertCurrentFactsRel(t, db, "r3", "tyler", nil, &birthday, "BORN_ON", "", nil)
+
+	store := NewCurrentFactsStore(db)
+	if err := store.RefreshEntities(ctx, []string{"tyler", "tyler", "acme"}); err != nil {
+		t.Fatalf("RefreshEntities: %v", err)
+	}
+
+	facts, err := store.GetCurrentFacts(ctx, "tyler")
+	if err != nil {
+		t.Fatalf("GetCurrentFacts: %v", err)
+	}
+	if len(facts) != 2 {
+		t.Fatalf("expected 2 current facts, got %d: %+v", len(facts), facts)
+	}
+	byType := map[string]CurrentFact{}
+	for _, f := range facts {
+		byType[f.RelationType] = f
+	}
+	if byType["WORKS_AT"].RelationshipID != "r2" {
+		t.Errorf("WORKS_AT current = %s, want r2 (r1 invalidated)", byType["WORKS_AT"].RelationshipID)
+	}
+	if name := byType["WORKS_AT"].TargetName; name == nil || *name != "Anthropic" {
+		t.Errorf("expected target name Anthropic, got %v", name)
+	}
+	if lit := byType["BORN_ON"].TargetLiteral; lit == nil || *lit != birthday {
+		t.Errorf("expected BORN_ON literal %s, got %v", birthday, lit)
+	}
+
+	// Invalidate the current edge: refresh falls back to nothing for WORKS_AT
+	if _, err := db.Exec(`UPDATE relationships SET invalid_at = '2024-01-01' WHERE id = 'r2'`); err != nil {
+		t.Fatalf("invalidate: %v", err)
+	}
+	if err := store.RefreshEntities(ctx, []string{"tyler"}); err != nil {
+		t.Fatalf("RefreshEntities: %v", err)
+	}
+	facts, _ = store.GetCurrentFacts(ctx, "tyler")
+	if len(facts) != 1 || facts[0].RelationType != "BORN_ON" {
+		t.Errorf("after invalidation expected only BORN_ON, got %+v", facts)
+	}
+}
+
+func TestCurrentFactsStore_LatestByValidAt(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertQueryEngineTestEntity(t, db, "tyler", "Tyler", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "austin", "Austin", EntityTypeLocation)
+	insertQueryEngineTestEntity(t, db, "denver", "Denver", EntityTypeLocation)
+
+	austin, denver := "austin", "denver"
+	insertCurrentFactsRel(t, db, "r-denver", "tyler", &denver, nil, "LIVES_IN", "2024-02-01", nil)
+	insertCurrentFactsRel(t, db, "r-austin", "tyler", &austin, nil, "LIVES_IN", "2019-01-01", nil)
+
+	store := NewCurrentFactsStore(db)
+	n, err := store.RefreshAll(ctx)
+	if err != nil {
+		t.Fatalf("RefreshAll: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("RefreshAll wrote %d facts, want 1", n)
+	}
+	facts, _ := store.GetCurrentFacts(ctx, "tyler")
+	if len(facts) != 1 || facts[0].RelationshipID != "r-denver" {
+		t.Errorf("expected latest LIVES_IN Denver, got %+v", facts)
+	}
+}
+
+func TestCurrentFactsStore_RefreshAfterMerge(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertQueryEngineTestEntity(t, db, "tyler", "Tyler", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "acme-dup", "ACME Inc", EntityTypeCompany)
+	insertQueryEngineTestEntity(t, db, "acme", "Acme", EntityTypeCompany)
+
+	dup := "acme-dup"
+	insertCurrentFactsRel(t, db, "r1", "tyler", &dup, nil, "WORKS_AT", "2020-01-01", nil)
+
+	store := NewCurrentFactsStore(db)
+	if _, err := store.RefreshAll(ctx); err != nil {
+		t.Fatalf("RefreshAll: %v", err)
+	}
+
+	// Simulate a merge of acme-dup into acme (relationships repointed)
+	if _, err := db.Exec(`UPDATE relationships SET target_entity_id = 'acme' WHERE target_entity_id = 'acme-dup'`); err != nil {
+		t.Fatalf("repoint: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE entities SET merged_into = 'acme' WHERE id = 'acme-dup'`); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if err := store.RefreshAfterMerge(ctx, "acme-dup", "acme"); err != nil {
+		t.Fatalf("RefreshAfterMerge: %v", err)
+	}
+
+	facts, _ := store.GetCurrentFacts(ctx, "tyler")
+	if len(facts) != 1 || facts[0].TargetEntityID == nil || *facts[0].TargetEntityID != "acme" {
+		t.Errorf("expected Tyler's WORKS_AT to point at acme, got %+v", facts)
+	}
+}
@@ -0,0 +1,250 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultDuplicateThreshold is the similarity at or above which a pair is reported.
+const DefaultDuplicateThreshold = 0.85
+
+// DuplicateReportOptions configures a possible-duplicates report.
+type DuplicateReportOptions struct {
+	Threshold    float64 // Minimum of max(name, embedding) similarity (default: DefaultDuplicateThreshold)
+	Limit        int     // Maximum pairs returned (0 = all)
+	EntityTypeID *int    // Optional: only compare entities of this type
+}
+
+// DuplicateEntity is one side of a possible-duplicate pair.
+type DuplicateEntity struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	EntityTypeID int    `json:"entity_type_id"`
+	Episodes     int    `json:"episodes"`
+}
+
+// DuplicatePair is a suspiciously similar pair of entities that isn't yet a
+// merge candidate.
+type DuplicatePair struct {
+	A                   DuplicateEntity `json:"a"`
+	B                   DuplicateEntity `json:"b"`
+	NameSimilarity      float64         `json:"name_similarity"`
+	EmbeddingSimilarity *float64        `json:"embedding_similarity,omitempty"` // nil if either lacks an embedding
+	Score               float64         `json:"score"`
+	SharedEpisodes      int             `json:"shared_episodes"`
+}
+
+// DuplicateFinder builds a human-browsable dedup worklist. It never creates
+// merge candidates or merges anything.
+type DuplicateFinder struct {
+	db    *sql.DB
+	model string
+}
+
+// NewDuplicateFinder creates a new DuplicateFinder comparing embeddings from the given model.
+func NewDuplicateFinder(db *sql.DB, model string) *DuplicateFinder {
+	if model == "" {
+		model = DefaultEmbeddingModel
+	}
+	return &DuplicateFinder{db: db, model: model}
+}
+
+// Find returns entity pairs of the same type whose name or embedding
+// similarity meets the threshold, sorted by score. Pairs are drawn from
+// entities sharing a blocking key, so unrelated entities are never compared.
+func (f *DuplicateFinder) Find(ctx context.Context, opts DuplicateReportOptions) ([]DuplicatePair, error) {
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultDuplicateThreshold
+	}
+
+	// Catch up entities written before the blocking index existed
+	if _, err := NewBlockingIndex(f.db).IndexMissing(ctx); err != nil {
+		// Non-fatal - report on whatever is indexed
+		_ = err
+	}
+
+	pairs, err := f.candidatePairs(ctx, opts.EntityTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	embeddings := make(map[string][]float64)
+	loadEmbedding := func(id string) []float64 {
+		if emb, ok := embeddings[id]; ok {
+			return emb
+		}
+		var blob []byte
+		err := f.db.QueryRowContext(ctx, `
+			SELECT embedding_blob FROM embeddings
+			WHERE target_type = ? AND target_id = ? AND model = ?
+		`, TargetTypeEntity, id, f.model).Scan(&blob)
+		var emb []float64
+		if err == nil {
+			emb = blobToFloat64Slice(blob)
+		}
+		embeddings[id] = emb
+		return emb
+	}
+
+	var results []DuplicatePair
+	for _, p := range pairs {
+		pair := DuplicatePair{A: p[0], B: p[1], NameSimilarity: nameSimilarity(p[0].Name, p[1].Name)}
+		pair.Score = pair.NameSimilarity
+		if a, b := loadEmbedding(p[0].ID), loadEmbedding(p[1].ID); a != nil && b != nil {
+			sim := cosineSimilarity(a, b)
+			pair.EmbeddingSimilarity = &sim
+			if sim > pair.Score {
+				pair.Score = sim
+			}
+		}
+		if pair.Score < opts.Threshold {
+			continue
+		}
+		results = append(results, pair)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+
+	for i := range results {
+		if err := f.fillEpisodeCounts(ctx, &results[i]); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// candidatePairs returns active same-type entity pairs sharing a blocking
+// key that aren't already merge candidates (in any status). Keys shared by
+// more than MaxBlockingCandidates entities are too common to be useful.
+func (f *DuplicateFinder) candidatePairs(ctx context.Context, entityTypeID *int) ([][2]DuplicateEntity, error) {
+	query := `
+		WITH useful_keys AS (
+			SELECT key_type, key FROM entity_blocking_keys
+			GROUP BY key_type, key
+			HAVING COUNT(*) BETWEEN 2 AND ?
+		)
+		SELECT DISTINCT ea.id, ea.canonical_name, ea.entity_type_id,
+			eb.id, eb.canonical_name, eb.entity_type_id
+		FROM useful_keys k
+		JOIN entity_blocking_keys ka ON ka.key_type = k.key_type AND ka.key = k.key
+		JOIN entity_blocking_keys kb ON kb.key_type = k.key_type AND kb.key = k.key AND kb.entity_id > ka.entity_id
+		JOIN entities ea ON ea.id = ka.entity_id AND ea.merged_into IS NULL
+		JOIN entities eb ON eb.id = kb.entity_id AND eb.merged_into IS NULL
+		WHERE ea.entity_type_id = eb.entity_type_id
+		  AND NOT EXISTS (
+			SELECT 1 FROM merge_candidates mc
+			WHERE (mc.entity_a_id = ea.id AND mc.entity_b_id = eb.id)
+			   OR (mc.entity_a_id = eb.id AND mc.entity_b_id = ea.id)
+		  )`
+	args := []interface{}{MaxBlockingCandidates}
+	if entityTypeID != nil {
+		query += ` AND ea.entity_type_id = ?`
+		args = append(args, *entityTypeID)
+	}
+
+	rows, err := f.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query candidate pairs: %w", err)
+	}
+	defer rows.Close()
+
+	var pairs [][2]DuplicateEntity
+	for rows.Next() {
+		var p [2]DuplicateEntity
+		if err := rows.Scan(&p[0].ID, &p[0].Name, &p[0].EntityTypeID, &p[1].ID, &p[1].Name, &p[1].EntityTypeID); err != nil {
+			return nil, fmt.Errorf("scan candidate pair: %w", err)
+		}
+		pairs = append(pairs, p)
+	}
+	return pairs, rows.Err()
+}
+
+// fillEpisodeCounts sets each side's episode count and the number of
+// episodes mentioning both.
+func (f *DuplicateFinder) fillEpisodeCounts(ctx context.Context, pair *DuplicatePair) error {
+	err := f.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM episode_entity_mentions WHERE entity_id = ?),
+			(SELECT COUNT(*) FROM episode_entity_mentions WHERE entity_id = ?),
+			(SELECT COUNT(*) FROM episode_entity_mentions a
+			 JOIN episode_entity_mentions b ON b.episode_id = a.episode_id AND b.entity_id = ?
+			 WHERE a.entity_id = ?)
+	`, pair.A.ID, pair.B.ID, pair.B.ID, pair.A.ID).Scan(&pair.A.Episodes, &pair.B.Episodes, &pair.SharedEpisodes)
+	if err != nil {
+		return fmt.Errorf("count episodes: %w", err)
+	}
+	return nil
+}
+
+// nameSimilarity scores two names from 0 to 1 as the better of token
+// overlap (order-insensitive) and edit-distance similarity.
+func nameSimilarity(a, b string) float64 {
+	a, b = normalizeAlias(a), normalizeAlias(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	tokensA, tokensB := strings.Fields(a), strings.Fields(b)
+	setA := make(map[string]bool, len(tokensA))
+	for _, t := range tokensA {
+		setA[t] = true
+	}
+	shared, union := 0, len(setA)
+	seenB := make(map[string]bool, len(tokensB))
+	for _, t := range tokensB {
+		if seenB[t] {
+			continue
+		}
+		seenB[t] = true
+		if setA[t] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	jaccard := float64(shared) / float64(union)
+
+	ra, rb := []rune(a), []rune(b)
+	maxLen := len(ra)
+	if len(rb) > maxLen {
+		maxLen = len(rb)
+	}
+	edit := 1 - float64(levenshtein(ra, rb))/float64(maxLen)
+
+	if jaccard > edit {
+		return jaccard
+	}
+	return edit
+}
+
+// levenshtein returns the edit distance between two rune slices.
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
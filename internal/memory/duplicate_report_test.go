@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		min, max float64
+	}{
+		{"Casey Smith", "casey smith", 1, 1},
+		{"Smith Casey", "Casey Smith", 1, 1},
+		{"Casey Smith", "Casey Smyth", 0.85, 0.95},
+		{"Casey", "Jordan", 0, 0.2},
+		{"", "Casey", 0, 0},
+	}
+	for _, tt := range tests {
+		got := nameSimilarity(tt.a, tt.b)
+		if got < tt.min || got > tt.max {
+			t.Errorf("nameSimilarity(%q, %q) = %.2f, want [%.2f, %.2f]", tt.a, tt.b, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestDuplicateFinder(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insert := func(id, name string, typeID int) {
+		if _, err := db.Exec(`INSERT INTO entities (id, canonical_name, entity_type_id, origin, created_at, updated_at) VALUES (?, ?, ?, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')`, id, name, typeID); err != nil {
+			t.Fatalf("insert entity: %v", err)
+		}
+	}
+	insert("casey-1", "Casey Smith", EntityTypePerson)
+	insert("casey-2", "Casey Smyth", EntityTypePerson)
+	insert("casey-org", "Casey Smith", EntityTypeCompany) // different type
+	insert("robert", "Robert Jones", EntityTypePerson)
+	insert("bob", "Bob Jones", EntityTypePerson)
+	insert("sam-1", "Sam Lee", EntityTypePerson)
+	insert("sam-2", "Sam Lee", EntityTypePerson)
+
+	// Robert/Bob only match by embedding
+	embedder := NewEntityEmbedder(db, nil, "")
+	embedder.storeEmbedding(ctx, "robert", []float64{1, 0.1, 0}, hashText("Robert Jones"))
+	embedder.storeEmbedding(ctx, "bob", []float64{1, 0.12, 0}, hashText("Bob Jones"))
+
+	// Sam Lee is already a merge candidate
+	db.Exec(`INSERT INTO merge_candidates (id, entity_a_id, entity_b_id, confidence, reason, created_at) VALUES ('mc', 'sam-1', 'sam-2', 0.9, 'name_similarity', '2026-01-01T00:00:00Z')`)
+
+	db.Exec(`INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0)`)
+	for _, ep := range []string{"ep1", "ep2"} {
+		db.Exec(`INSERT INTO episodes (id, definition_id, start_time, end_time, event_count, created_at) VALUES (?, 'def', 0, 0, 1, 0)`, ep)
+	}
+	for _, m := range [][2]string{{"ep1", "casey-1"}, {"ep1", "casey-2"}, {"ep2", "casey-1"}} {
+		db.Exec(`INSERT INTO episode_entity_mentions (episode_id, entity_id, created_at) VALUES (?, ?, '2026-01-01T00:00:00Z')`, m[0], m[1])
+	}
+
+	pairs, err := NewDuplicateFinder(db, "").Find(ctx, DuplicateReportOptions{Threshold: 0.85})
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if len(pairs) != 2 {
+		t.Fatalf("pairs = %+v, want casey and robert/bob", pairs)
+	}
+
+	byA := map[string]DuplicatePair{}
+	for _, p := range pairs {
+		byA[p.A.ID+"/"+p.B.ID] = p
+	}
+	casey, ok := byA["casey-1/casey-2"]
+	if !ok {
+		t.Fatalf("missing casey pair: %+v", pairs)
+	}
+	if casey.SharedEpisodes != 1 || casey.A.Episodes != 2 || casey.B.Episodes != 1 {
+		t.Errorf("casey episodes = %+v", casey)
+	}
+	if casey.EmbeddingSimilarity != nil {
+		t.Errorf("casey has no embeddings, got %v", *casey.EmbeddingSimilarity)
+	}
+	bob, ok := byA["bob/robert"]
+	if !ok || bob.EmbeddingSimilarity == nil || bob.NameSimilarity >= 0.85 {
+		t.Errorf("bob/robert pair = %+v", bob)
+	}
+
+	// Type filter
+	orgType := EntityTypeCompany
+	pairs, _ = NewDuplicateFinder(db, "").Find(ctx, DuplicateReportOptions{EntityTypeID: &orgType})
+	if len(pairs) != 0 {
+		t.Errorf("org pairs = %+v, want none", pairs)
+	}
+}
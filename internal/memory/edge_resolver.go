@@ -75,6 +75,9 @@ func (r *EdgeResolver) Resolve(ctx context.Context, episodeID string, relationsh
 			// Relationship already exists - just create mention
 			relationshipID = existingID
 			result.ExistingRelationships++
+			if err := r.promoteSourceType(ctx, relationshipID, resolved.SourceType); err != nil {
+				return nil, fmt.Errorf("update source type: %w", err)
+			}
 		} else {
 			// New relationship - create it
 			relationshipID, err = r.createRelationship(ctx, resolved)
@@ -112,6 +115,9 @@ func (r *EdgeResolver) toResolvedRelationship(rel ExtractedRelationship, entitie
 		InvalidAt:      rel.InvalidAt,
 		Confidence:     1.0, // Default confidence
 	}
+	if rel.Confidence != nil {
+		resolved.Confidence = *rel.Confidence
+	}
 
 	// Handle target - either entity ID or literal
 	if rel.TargetEntityID != nil {
@@ -126,11 +132,21 @@ func (r *EdgeResolver) toResolvedRelationship(rel ExtractedRelationship, entitie
 		return nil, fmt.Errorf("relationship has no target")
 	}
 
+	// Inverse types are stored as their canonical type (EMPLOYS as WORKS_AT)
+	if resolved.TargetEntityID != nil {
+		if canonical, swap := CanonicalRelationType(resolved.RelationType); swap {
+			source := resolved.SourceEntityID
+			resolved.SourceEntityID, resolved.TargetEntityID = *resolved.TargetEntityID, &source
+			resolved.RelationType = canonical
+		}
+	}
+
 	return resolved, nil
 }
 
 // findExisting checks if a relationship already exists in the database.
 // Matches on: source_entity_id, target (entity_id or literal), relation_type, valid_at.
+// Symmetric relation types (FRIEND_OF) also match with source and target swapped.
 // Returns the existing relationship ID if found, empty string otherwise.
 func (r *EdgeResolver) findExisting(ctx context.Context, rel *ResolvedRelationship) (string, error) {
 	var existingID string
@@ -145,6 +161,16 @@ func (r *EdgeResolver) findExisting(ctx context.Context, rel *ResolvedRelationsh
 			  AND (valid_at IS NULL AND ? IS NULL OR valid_at = ?)
 		`, rel.SourceEntityID, *rel.TargetEntityID, rel.RelationType, rel.ValidAt, rel.ValidAt).Scan(&existingID)
 
+		if err == sql.ErrNoRows && IsSymmetricRelationType(rel.RelationType) {
+			// A symmetric fact may be stored the other way round
+			err = r.db.QueryRowContext(ctx, `
+				SELECT id FROM relationships
+				WHERE source_entity_id = ?
+				  AND target_entity_id = ?
+				  AND relation_type = ?
+				  AND (valid_at IS NULL AND ? IS NULL OR valid_at = ?)
+			`, *rel.TargetEntityID, rel.SourceEntityID, rel.RelationType, rel.ValidAt, rel.ValidAt).Scan(&existingID)
+		}
 		if err == sql.ErrNoRows {
 			return "", nil
 		}
@@ -176,15 +202,16 @@ func (r *EdgeResolver) findExisting(ctx context.Context, rel *ResolvedRelationsh
 func (r *EdgeResolver) createRelationship(ctx context.Context, rel *ResolvedRelationship) (string, error) {
 	id := uuid.New().String()
 	now := time.Now().Format(time.RFC3339)
+	fact := normalizeResolvedFact(ctx, r.db, rel)
 
 	_, err := r.db.ExecContext(ctx, `
 		INSERT INTO relationships (
 			id, source_entity_id, target_entity_id, target_literal,
-			relation_type, fact, valid_at, invalid_at, created_at, confidence
+			relation_type, fact, valid_at, invalid_at, created_at, confidence, source_type
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, id, rel.SourceEntityID, rel.TargetEntityID, rel.TargetLiteral,
-		rel.RelationType, rel.Fact, rel.ValidAt, rel.InvalidAt, now, rel.Confidence)
+		rel.RelationType, fact, rel.ValidAt, rel.InvalidAt, now, rel.Confidence, nullIfEmpty(rel.SourceType))
 
 	if err != nil {
 		return "", err
@@ -192,6 +219,39 @@ func (r *EdgeResolver) createRelationship(ctx context.Context, rel *ResolvedRela
 	return id, nil
 }
 
+// SourceTypeRank orders mention source types by how directly they assert a
+// fact: self_disclosed > mentioned > inferred. Unknown types rank 0.
+func SourceTypeRank(sourceType string) int {
+	switch sourceType {
+	case "self_disclosed":
+		return 3
+	case "mentioned":
+		return 2
+	case "inferred":
+		return 1
+	}
+	return 0
+}
+
+// sourceTypeRankSQL is SourceTypeRank as a SQL expression over a column.
+func sourceTypeRankSQL(column string) string {
+	return "CASE " + column + " WHEN 'self_disclosed' THEN 3 WHEN 'mentioned' THEN 2 WHEN 'inferred' THEN 1 ELSE 0 END"
+}
+
+// promoteSourceType raises a relationship's source_type when a new mention
+// asserts it more directly, so relationships.source_type is always the
+// strongest source type across its mentions.
+func (r *EdgeResolver) promoteSourceType(ctx context.Context, relationshipID, sourceType string) error {
+	if SourceTypeRank(sourceType) == 0 {
+		return nil
+	}
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE relationships SET source_type = ?
+		WHERE id = ? AND `+sourceTypeRankSQL("source_type")+` < ?
+	`, sourceType, relationshipID, SourceTypeRank(sourceType))
+	return err
+}
+
 // createMention creates an episode_relationship_mentions record for provenance.
 func (r *EdgeResolver) createMention(ctx context.Context, episodeID, relationshipID string, rel ExtractedRelationship) error {
 	id := uuid.New().String()
@@ -203,13 +263,18 @@ func (r *EdgeResolver) createMention(ctx context.Context, episodeID, relationshi
 		targetLiteral = rel.TargetLiteral
 	}
 
+	confidence := 1.0
+	if rel.Confidence != nil {
+		confidence = *rel.Confidence
+	}
+
 	_, err := r.db.ExecContext(ctx, `
 		INSERT INTO episode_relationship_mentions (
 			id, episode_id, relationship_id, extracted_fact,
 			asserted_by_entity_id, source_type, target_literal, alias_id, confidence, created_at
 		)
 		VALUES (?, ?, ?, ?, NULL, ?, ?, NULL, ?, ?)
-	`, id, episodeID, relationshipID, rel.Fact, rel.SourceType, targetLiteral, 1.0, now)
+	`, id, episodeID, relationshipID, rel.Fact, rel.SourceType, targetLiteral, confidence, now)
 
 	return err
 }
@@ -243,6 +308,9 @@ func (r *EdgeResolver) ResolveWithAssertedBy(ctx context.Context, episodeID stri
 			// Relationship already exists - just create mention
 			relationshipID = existingID
 			result.ExistingRelationships++
+			if err := r.promoteSourceType(ctx, relationshipID, resolved.SourceType); err != nil {
+				return nil, fmt.Errorf("update source type: %w", err)
+			}
 		} else {
 			// New relationship - create it
 			relationshipID, err = r.createRelationship(ctx, resolved)
@@ -272,6 +340,10 @@ func (r *EdgeResolver) createMentionWithAssertedBy(ctx context.Context, episodeI
 	if rel.TargetLiteral != nil {
 		targetLiteral = rel.TargetLiteral
 	}
+	confidence := 1.0
+	if rel.Confidence != nil {
+		confidence = *rel.Confidence
+	}
 
 	_, err := r.db.ExecContext(ctx, `
 		INSERT INTO episode_relationship_mentions (
@@ -279,7 +351,7 @@ func (r *EdgeResolver) createMentionWithAssertedBy(ctx context.Context, episodeI
 			asserted_by_entity_id, source_type, target_literal, alias_id, confidence, created_at
 		)
 		VALUES (?, ?, ?, ?, ?, ?, ?, NULL, ?, ?)
-	`, id, episodeID, relationshipID, rel.Fact, assertedByEntityID, rel.SourceType, targetLiteral, 1.0, now)
+	`, id, episodeID, relationshipID, rel.Fact, assertedByEntityID, rel.SourceType, targetLiteral, confidence, now)
 
 	return err
 }
@@ -3,6 +3,7 @@ package memory
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"testing"
 	"time"
 
@@ -52,6 +53,8 @@ func setupEdgeResolverTestDB(t *testing.T) *sql.DB {
 			invalid_at TEXT,
 			created_at TEXT NOT NULL,
 			confidence REAL DEFAULT 1.0,
+			weight REAL DEFAULT 0,
+			source_type TEXT,
 			CHECK (
 				(target_entity_id IS NOT NULL AND target_literal IS NULL) OR
 				(target_entity_id IS NULL AND target_literal IS NOT NULL)
@@ -431,6 +434,55 @@ func TestEdgeResolver_MentionIncludesSourceType(t *testing.T) {
 	}
 }
 
+func TestEdgeResolver_PromotesStrongestSourceType(t *testing.T) {
+	db := setupEdgeResolverTestDB(t)
+	defer db.Close()
+
+	insertEdgeResolverTestEntity(t, db, "entity-tyler", "Tyler", EntityTypePerson)
+	insertEdgeResolverTestEntity(t, db, "entity-anthropic", "Anthropic", EntityTypeCompany)
+
+	resolver := NewEdgeResolver(db)
+	resolvedEntities := []ResolvedEntity{
+		{ID: "entity-tyler", Name: "Tyler", EntityTypeID: EntityTypePerson},
+		{ID: "entity-anthropic", Name: "Anthropic", EntityTypeID: EntityTypeCompany},
+	}
+	targetID := 1
+
+	// Each later mention is checked against the strongest seen so far
+	steps := []struct {
+		sourceType string
+		want       string
+	}{
+		{"inferred", "inferred"},
+		{"mentioned", "mentioned"},
+		{"inferred", "mentioned"},
+		{"self_disclosed", "self_disclosed"},
+		{"mentioned", "self_disclosed"},
+	}
+	for i, step := range steps {
+		episodeID := fmt.Sprintf("episode-%d", i)
+		insertEdgeResolverTestEpisode(t, db, episodeID)
+		relationships := []ExtractedRelationship{{
+			SourceEntityID: 0,
+			RelationType:   "WORKS_AT",
+			TargetEntityID: &targetID,
+			Fact:           "Tyler works at Anthropic",
+			SourceType:     step.sourceType,
+		}}
+		if _, err := resolver.Resolve(context.Background(), episodeID, relationships, resolvedEntities); err != nil {
+			t.Fatalf("Resolve %d error: %v", i, err)
+		}
+
+		var sourceType string
+		if err := db.QueryRow(`SELECT source_type FROM relationships`).Scan(&sourceType); err != nil {
+			t.Fatalf("query relationship: %v", err)
+		}
+		if sourceType != step.want {
+			t.Errorf("after %s mention: source_type = %q, want %q", step.sourceType, sourceType, step.want)
+		}
+	}
+}
+
 func TestEdgeResolver_MentionIncludesExtractedFact(t *testing.T) {
 	db := setupEdgeResolverTestDB(t)
 	defer db.Close()
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/Napageneral/taskengine/queue"
+)
+
+// EmbeddingJobType is the job type drained by the compute engine's
+// embedding worker (compute.JobTypeEmbedding).
+const EmbeddingJobType = "embedding"
+
+// embeddingJobPayload matches compute.EmbeddingJobPayload.
+type embeddingJobPayload struct {
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+}
+
+// EntityEmbeddingJobKey returns the idempotency key for embedding an entity
+// under its current name. The key includes a hash of the name so a rename
+// queues a fresh job instead of colliding with the completed one.
+func EntityEmbeddingJobKey(entityID, canonicalName string) string {
+	return fmt.Sprintf("embedding:entity:%s:%s", entityID, hashText(strings.TrimSpace(canonicalName))[:16])
+}
+
+// EmbeddingQueue enqueues entity embedding jobs so the pipeline doesn't
+// block on the embeddings API; `mnemonic compute run` drains them.
+type EmbeddingQueue struct {
+	queue    *queue.Queue
+	embedder *EntityEmbedder
+}
+
+// NewEmbeddingQueue creates a new EmbeddingQueue, creating the jobs table if needed.
+// The embedder is used to skip entities whose embedding is already current.
+func NewEmbeddingQueue(db *sql.DB, embedder *EntityEmbedder) (*EmbeddingQueue, error) {
+	if err := queue.Init(db); err != nil {
+		return nil, fmt.Errorf("init job queue: %w", err)
+	}
+	return &EmbeddingQueue{queue: queue.New(db), embedder: embedder}, nil
+}
+
+// EnqueueStale queues embedding jobs for entities with no embedding or one
+// computed from an older name. Returns the number of jobs queued.
+func (q *EmbeddingQueue) EnqueueStale(ctx context.Context, entities []Entity) (int, error) {
+	count := 0
+	for _, ent := range entities {
+		name := strings.TrimSpace(ent.CanonicalName)
+		if ent.ID == "" || name == "" {
+			continue
+		}
+		current, err := q.embedder.embeddingExists(ctx, ent.ID, hashText(name))
+		if err != nil {
+			return count, fmt.Errorf("check embedding for %s: %w", ent.ID, err)
+		}
+		if current {
+			continue
+		}
+		if err := q.queue.Enqueue(queue.EnqueueOptions{
+			Type:    EmbeddingJobType,
+			Key:     EntityEmbeddingJobKey(ent.ID, name),
+			Payload: embeddingJobPayload{EntityType: TargetTypeEntity, EntityID: ent.ID},
+		}); err != nil {
+			return count, fmt.Errorf("enqueue embedding for %s: %w", ent.ID, err)
+		}
+		count++
+	}
+	return count, nil
+}
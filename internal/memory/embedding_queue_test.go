@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestEmbeddingQueue_EnqueueStale(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	embedder := NewEntityEmbedder(db, nil, "")
+	for _, ent := range []struct{ id, name string }{{"fresh", "Casey"}, {"new", "Sarah"}, {"renamed", "Bob Smith"}} {
+		db.Exec(`INSERT INTO entities (id, canonical_name, entity_type_id, origin, created_at, updated_at) VALUES (?, ?, 1, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')`, ent.id, ent.name)
+	}
+	if err := embedder.storeEmbedding(ctx, "fresh", []float64{0.1, 0.2}, hashText("Casey")); err != nil {
+		t.Fatalf("store embedding: %v", err)
+	}
+	if err := embedder.storeEmbedding(ctx, "renamed", []float64{0.1, 0.2}, hashText("Bob")); err != nil {
+		t.Fatalf("store embedding: %v", err)
+	}
+
+	q, err := NewEmbeddingQueue(db, embedder)
+	if err != nil {
+		t.Fatalf("NewEmbeddingQueue: %v", err)
+	}
+	entities := []Entity{
+		{ID: "fresh", CanonicalName: "Casey"},
+		{ID: "new", CanonicalName: "Sarah"},
+		{ID: "renamed", CanonicalName: "Bob Smith"},
+	}
+	queued, err := q.EnqueueStale(ctx, entities)
+	if err != nil {
+		t.Fatalf("EnqueueStale: %v", err)
+	}
+	if queued != 2 {
+		t.Errorf("queued = %d, want 2 (new and renamed)", queued)
+	}
+
+	// Re-queuing the same entities doesn't duplicate jobs
+	if _, err := q.EnqueueStale(ctx, entities); err != nil {
+		t.Fatalf("EnqueueStale again: %v", err)
+	}
+	var jobs int
+	db.QueryRow(`SELECT COUNT(*) FROM jobs WHERE type = ?`, EmbeddingJobType).Scan(&jobs)
+	if jobs != 2 {
+		t.Errorf("jobs = %d, want 2", jobs)
+	}
+
+	var payload string
+	db.QueryRow(`SELECT payload_json FROM jobs WHERE key = ?`, EntityEmbeddingJobKey("new", "Sarah")).Scan(&payload)
+	if payload != `{"entity_type":"entity","entity_id":"new"}` {
+		t.Errorf("payload = %s", payload)
+	}
+
+	// A rename gets a new job key
+	if EntityEmbeddingJobKey("renamed", "Bob") == EntityEmbeddingJobKey("renamed", "Bob Smith") {
+		t.Error("job key should change with the name")
+	}
+}
@@ -0,0 +1,174 @@
+package memory
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultEntityCacheSize is the default number of alias lookups kept in the
+// hot-entity cache during a pipeline run.
+const DefaultEntityCacheSize = 1000
+
+// EntityCacheStats reports cache effectiveness.
+type EntityCacheStats struct {
+	Hits          int64 `json:"hits"`
+	Misses        int64 `json:"misses"`
+	Evictions     int64 `json:"evictions"`
+	Invalidations int64 `json:"invalidations"`
+	Size          int   `json:"size"`
+}
+
+// entityCacheEntry is one cached alias lookup.
+type entityCacheEntry struct {
+	name       string                // Trimmed lookup name (cache key)
+	normalized string                // normalizeAlias(name)
+	candidates []ResolutionCandidate // Alias candidates (entity ID, canonical name, type, score)
+}
+
+// EntityCache is an in-memory LRU cache of alias → entity lookups used during
+// a pipeline batch. Long threads mention the same handful of people in every
+// episode; caching avoids re-querying entity_aliases for each one.
+//
+// The cache must be invalidated when the graph changes underneath it:
+// new aliases (InvalidateAlias) and merges (InvalidateEntity).
+type EntityCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+	stats    EntityCacheStats
+}
+
+// NewEntityCache creates a new EntityCache holding up to capacity lookups.
+func NewEntityCache(capacity int) *EntityCache {
+	if capacity <= 0 {
+		capacity = DefaultEntityCacheSize
+	}
+	return &EntityCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// GetAliasCandidates returns cached alias candidates for a name.
+func (c *EntityCache) GetAliasCandidates(name string) ([]ResolutionCandidate, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[name]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.ll.MoveToFront(el)
+	entry := el.Value.(*entityCacheEntry)
+	return append([]ResolutionCandidate(nil), entry.candidates...), true
+}
+
+// PutAliasCandidates caches alias candidates for a name, evicting the least
+// recently used lookup when full.
+func (c *EntityCache) PutAliasCandidates(name string, candidates []ResolutionCandidate) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &entityCacheEntry{
+		name:       name,
+		normalized: normalizeAlias(name),
+		candidates: append([]ResolutionCandidate(nil), candidates...),
+	}
+	if el, ok := c.items[name]; ok {
+		el.Value = entry
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[name] = c.ll.PushFront(entry)
+
+	for c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.removeElement(oldest)
+		c.stats.Evictions++
+	}
+}
+
+// InvalidateAlias drops lookups that a new or changed alias could affect.
+func (c *EntityCache) InvalidateAlias(alias, normalized string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for el := c.ll.Front(); el != nil; {
+		next := el.Next()
+		entry := el.Value.(*entityCacheEntry)
+		if entry.normalized == normalized || entry.name == alias {
+			c.removeElement(el)
+			c.stats.Invalidations++
+		}
+		el = next
+	}
+}
+
+// InvalidateEntity drops every lookup that resolved to the entity.
+// Call for both sides of a merge.
+func (c *EntityCache) InvalidateEntity(entityID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for el := c.ll.Front(); el != nil; {
+		next := el.Next()
+		entry := el.Value.(*entityCacheEntry)
+		for _, cand := range entry.candidates {
+			if cand.EntityID == entityID {
+				c.removeElement(el)
+				c.stats.Invalidations++
+				break
+			}
+		}
+		el = next
+	}
+}
+
+// Clear drops all cached lookups.
+func (c *EntityCache) Clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.Invalidations += int64(c.ll.Len())
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+// Stats returns a snapshot of cache statistics.
+func (c *EntityCache) Stats() EntityCacheStats {
+	if c == nil {
+		return EntityCacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Size = c.ll.Len()
+	return stats
+}
+
+// removeElement removes an element from the list and index. Caller holds mu.
+func (c *EntityCache) removeElement(el *list.Element) {
+	entry := el.Value.(*entityCacheEntry)
+	delete(c.items, entry.name)
+	c.ll.Remove(el)
+}
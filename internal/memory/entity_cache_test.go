@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEntityCache_LRUEviction(t *testing.T) {
+	cache := NewEntityCache(2)
+
+	cache.PutAliasCandidates("Casey", []ResolutionCandidate{{EntityID: "e1"}})
+	cache.PutAliasCandidates("Jordan", []ResolutionCandidate{{EntityID: "e2"}})
+
+	// Touch Casey so Jordan becomes least recently used
+	if _, ok := cache.GetAliasCandidates("Casey"); !ok {
+		t.Fatal("expected Casey to be cached")
+	}
+	cache.PutAliasCandidates("Riley", []ResolutionCandidate{{EntityID: "e3"}})
+
+	if _, ok := cache.GetAliasCandidates("Jordan"); ok {
+		t.Error("Jordan should have been evicted")
+	}
+	if _, ok := cache.GetAliasCandidates("Casey"); !ok {
+		t.Error("Casey should still be cached")
+	}
+
+	stats := cache.Stats()
+	if stats.Evictions != 1 || stats.Size != 2 {
+		t.Errorf("stats = %+v, want 1 eviction and size 2", stats)
+	}
+	if stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("stats = %+v, want 2 hits and 1 miss", stats)
+	}
+}
+
+func TestEntityCache_ReturnsCopies(t *testing.T) {
+	cache := NewEntityCache(10)
+	cache.PutAliasCandidates("Casey", []ResolutionCandidate{{EntityID: "e1", AliasScore: 1.0}})
+
+	got, _ := cache.GetAliasCandidates("Casey")
+	got[0].AliasScore = 0
+
+	again, _ := cache.GetAliasCandidates("Casey")
+	if again[0].AliasScore != 1.0 {
+		t.Error("mutating a returned slice should not change the cache")
+	}
+}
+
+func TestEntityCache_Invalidation(t *testing.T) {
+	cache := NewEntityCache(10)
+	cache.PutAliasCandidates("Casey", []ResolutionCandidate{{EntityID: "e1"}})
+	cache.PutAliasCandidates("casey ", []ResolutionCandidate{{EntityID: "e1"}})
+	cache.PutAliasCandidates("Jordan", []ResolutionCandidate{{EntityID: "e2"}, {EntityID: "e3"}})
+	cache.PutAliasCandidates("Unknown", nil)
+
+	cache.InvalidateAlias("CASEY", "casey")
+	if _, ok := cache.GetAliasCandidates("Casey"); ok {
+		t.Error("Casey should be invalidated by normalized alias")
+	}
+	if _, ok := cache.GetAliasCandidates("casey "); ok {
+		t.Error("'casey ' should be invalidated by normalized alias")
+	}
+
+	cache.InvalidateEntity("e3")
+	if _, ok := cache.GetAliasCandidates("Jordan"); ok {
+		t.Error("Jordan lookup referencing e3 should be invalidated")
+	}
+	if _, ok := cache.GetAliasCandidates("Unknown"); !ok {
+		t.Error("unrelated negative lookup should remain cached")
+	}
+
+	cache.Clear()
+	if cache.Stats().Size != 0 {
+		t.Error("Clear should empty the cache")
+	}
+}
+
+func TestEntityCache_NilSafe(t *testing.T) {
+	var cache *EntityCache
+	cache.PutAliasCandidates("Casey", nil)
+	if _, ok := cache.GetAliasCandidates("Casey"); ok {
+		t.Error("nil cache should never hit")
+	}
+	cache.InvalidateAlias("Casey", "casey")
+	cache.InvalidateEntity("e1")
+	cache.Clear()
+	_ = cache.Stats()
+}
+
+func TestEntityResolver_UsesCache(t *testing.T) {
+	db := setupResolverTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertTestEntity(t, db, "e1", "Casey Adams", EntityTypePerson)
+	insertTestAlias(t, db, "a1", "e1", "Casey", "name", false)
+
+	cache := NewEntityCache(10)
+	resolver := NewEntityResolver(db, nil, "")
+	resolver.SetCache(cache)
+
+	first, err := resolver.findAliasCandidates(ctx, "Casey", EntityTypePerson)
+	if err != nil || len(first) != 1 {
+		t.Fatalf("first lookup = %v, %v; want 1 candidate", first, err)
+	}
+
+	// Remove the alias behind the cache's back: cached result is still served
+	if _, err := db.Exec(`DELETE FROM entity_aliases WHERE id = 'a1'`); err != nil {
+		t.Fatalf("delete alias: %v", err)
+	}
+	second, _ := resolver.findAliasCandidates(ctx, "Casey", EntityTypePerson)
+	if len(second) != 1 {
+		t.Fatalf("second lookup should be served from cache, got %v", second)
+	}
+
+	// A merge touching e1 invalidates the lookup
+	cache.InvalidateEntity("e1")
+	third, _ := resolver.findAliasCandidates(ctx, "Casey", EntityTypePerson)
+	if len(third) != 0 {
+		t.Errorf("lookup after invalidation = %v, want none", third)
+	}
+
+	// Creating a new entity invalidates the cached negative lookup
+	_, err = resolver.Resolve(ctx, []ExtractedEntity{{ID: 0, Name: "Casey", EntityTypeID: EntityTypePerson}}, ResolutionContext{})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	fourth, _ := resolver.findAliasCandidates(ctx, "Casey", EntityTypePerson)
+	if len(fourth) != 1 {
+		t.Errorf("lookup after create = %v, want the new entity", fourth)
+	}
+}
@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Entity change types recorded in entity_changes by the schema triggers.
+const (
+	ChangeRelationshipAdded       = "relationship_added"
+	ChangeRelationshipInvalidated = "relationship_invalidated"
+	ChangeMerged                  = "merged"      // another entity was merged into this one
+	ChangeMergedInto              = "merged_into" // this entity was merged into another
+	ChangeSummaryUpdated          = "summary_updated"
+	ChangeRenamed                 = "renamed"
+)
+
+// EntityChangesPageSize caps one GetEntityChanges call; callers page with
+// the returned cursor.
+const EntityChangesPageSize = 500
+
+// EntityChange is one entry in an entity's change feed.
+type EntityChange struct {
+	Seq       int64  `json:"seq"`
+	EntityID  string `json:"entity_id"`
+	Type      string `json:"type"`
+	RefID     string `json:"ref_id,omitempty"` // relationship ID, or the other entity for merges
+	CreatedAt string `json:"created_at"`
+
+	// Relationship changes carry the relationship as it is now
+	RelationType string  `json:"relation_type,omitempty"`
+	Fact         string  `json:"fact,omitempty"`
+	InvalidAt    *string `json:"invalid_at,omitempty"`
+
+	// OtherEntityID/OtherName is the relationship's other end, or the other
+	// entity of a merge
+	OtherEntityID string `json:"other_entity_id,omitempty"`
+	OtherName     string `json:"other_name,omitempty"`
+}
+
+// GetEntityChanges returns changes to an entity recorded after sinceCursor,
+// oldest first, and the cursor to pass next time. Pass 0 for the whole feed.
+// At most one page is returned; when it is full, call again with the new
+// cursor. The cursor is unchanged when there is nothing new.
+func (q *QueryEngine) GetEntityChanges(ctx context.Context, entityID string, sinceCursor int64) ([]EntityChange, int64, error) {
+	if entityID == "" {
+		return nil, sinceCursor, fmt.Errorf("entityID is required")
+	}
+
+	rows, err := q.query(ctx, `
+		SELECT c.seq, c.entity_id, c.change_type, COALESCE(c.ref_id, ''), c.created_at,
+		       COALESCE(r.relation_type, ''), COALESCE(r.fact, ''), r.invalid_at,
+		       COALESCE(CASE
+		           WHEN r.id IS NULL THEN c.ref_id
+		           WHEN r.source_entity_id = c.entity_id THEN r.target_entity_id
+		           ELSE r.source_entity_id
+		       END, ''),
+		       COALESCE(o.canonical_name, '')
+		FROM entity_changes c
+		LEFT JOIN relationships r
+		       ON c.change_type IN (?, ?) AND r.id = c.ref_id
+		LEFT JOIN entities o ON o.id = CASE
+		       WHEN r.id IS NULL THEN c.ref_id
+		       WHEN r.source_entity_id = c.entity_id THEN r.target_entity_id
+		       ELSE r.source_entity_id
+		   END
+		WHERE c.entity_id = ? AND c.seq > ?
+		ORDER BY c.seq
+		LIMIT ?
+	`, ChangeRelationshipAdded, ChangeRelationshipInvalidated, entityID, sinceCursor, EntityChangesPageSize)
+	if err != nil {
+		return nil, sinceCursor, fmt.Errorf("query entity changes: %w", err)
+	}
+	defer rows.Close()
+
+	cursor := sinceCursor
+	var changes []EntityChange
+	for rows.Next() {
+		var c EntityChange
+		var invalidAt sql.NullString
+		if err := rows.Scan(&c.Seq, &c.EntityID, &c.Type, &c.RefID, &c.CreatedAt,
+			&c.RelationType, &c.Fact, &invalidAt, &c.OtherEntityID, &c.OtherName); err != nil {
+			return nil, sinceCursor, fmt.Errorf("scan entity change: %w", err)
+		}
+		if invalidAt.Valid {
+			c.InvalidAt = &invalidAt.String
+		}
+		changes = append(changes, c)
+		cursor = c.Seq
+	}
+	if err := rows.Err(); err != nil {
+		return nil, sinceCursor, err
+	}
+	return changes, cursor, nil
+}
@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestGetEntityChanges(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertQueryEngineTestEntity(t, db, "tyler", "Tyler", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "acme", "Acme", EntityTypeCompany)
+	insertQueryEngineTestEntity(t, db, "acme-dup", "ACME Inc", EntityTypeCompany)
+
+	acme := "acme"
+	insertCurrentFactsRel(t, db, "r1", "tyler", &acme, nil, "WORKS_AT", "2020-01-01", nil)
+
+	q := NewQueryEngine(db)
+	defer q.Close()
+
+	changes, cursor, err := q.GetEntityChanges(ctx, "tyler", 0)
+	if err != nil {
+		t.Fatalf("GetEntityChanges: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Type != ChangeRelationshipAdded || changes[0].RefID != "r1" {
+		t.Fatalf("changes = %+v, want one relationship_added for r1", changes)
+	}
+	if changes[0].OtherEntityID != "acme" || changes[0].OtherName != "Acme" || changes[0].RelationType != "WORKS_AT" {
+		t.Errorf("relationship details = %+v", changes[0])
+	}
+
+	if _, err := db.Exec(`
+		UPDATE relationships SET invalid_at = '2024-01-01' WHERE id = 'r1';
+		UPDATE entities SET summary = 'Engineer' WHERE id = 'tyler';
+		UPDATE entities SET summary = 'Engineer' WHERE id = 'tyler';
+		UPDATE entities SET merged_into = 'acme' WHERE id = 'acme-dup';
+	`); err != nil {
+		t.Fatalf("updates: %v", err)
+	}
+
+	changes, next, err := q.GetEntityChanges(ctx, "tyler", cursor)
+	if err != nil {
+		t.Fatalf("GetEntityChanges since cursor: %v", err)
+	}
+	var types []string
+	for _, c := range changes {
+		types = append(types, c.Type)
+	}
+	if len(types) != 2 || types[0] != ChangeRelationshipInvalidated || types[1] != ChangeSummaryUpdated {
+		t.Fatalf("types since cursor = %v, want invalidated then summary_updated", types)
+	}
+	if changes[0].InvalidAt == nil || *changes[0].InvalidAt != "2024-01-01" {
+		t.Errorf("invalidation invalid_at = %v", changes[0].InvalidAt)
+	}
+
+	again, same, err := q.GetEntityChanges(ctx, "tyler", next)
+	if err != nil || len(again) != 0 || same != next {
+		t.Errorf("nothing new: got %d changes, cursor %d (want %d), err %v", len(again), same, next, err)
+	}
+
+	merged, _, err := q.GetEntityChanges(ctx, "acme", 0)
+	if err != nil {
+		t.Fatalf("GetEntityChanges acme: %v", err)
+	}
+	last := merged[len(merged)-1]
+	if last.Type != ChangeMerged || last.OtherEntityID != "acme-dup" || last.OtherName != "ACME Inc" {
+		t.Errorf("last acme change = %+v, want merged from acme-dup", last)
+	}
+	loser, _, err := q.GetEntityChanges(ctx, "acme-dup", 0)
+	if err != nil || len(loser) != 1 || loser[0].Type != ChangeMergedInto || loser[0].RefID != "acme" {
+		t.Errorf("acme-dup changes = %+v, err %v", loser, err)
+	}
+}
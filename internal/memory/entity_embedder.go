@@ -143,6 +143,7 @@ func (e *EntityEmbedder) generateEmbedding(ctx context.Context, text string) ([]
 	if err != nil {
 		return nil, err
 	}
+	recordEmbedUsage(ctx, len(text))
 	if resp.Embedding == nil || len(resp.Embedding.Values) == 0 {
 		return nil, fmt.Errorf("empty embedding response")
 	}
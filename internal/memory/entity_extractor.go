@@ -16,6 +16,11 @@ type ExtractedEntity struct {
 	ID           int    `json:"id"`             // Temporary ID for reference within this extraction
 	Name         string `json:"name"`           // Name of the extracted entity
 	EntityTypeID int    `json:"entity_type_id"` // ID from DefaultEntityTypes
+	// Surface forms as written in the episode ("Casey", "Case"); forms not
+	// found in the content are dropped during validation
+	Mentions []string `json:"mentions,omitempty"`
+	// Where the entity appears in the episode content (computed, not from the LLM)
+	Offsets []MentionOffset `json:"offsets,omitempty"`
 }
 
 // EntityExtractionResult contains the output from entity extraction.
@@ -25,8 +30,9 @@ type EntityExtractionResult struct {
 
 // KnownEntity represents an entity we already know about (e.g., thread participant)
 type KnownEntity struct {
-	Name       string `json:"name"`
-	EntityType string `json:"entity_type"`
+	Name       string   `json:"name"`
+	EntityType string   `json:"entity_type"`
+	Aliases    []string `json:"aliases,omitempty"` // Optional: alternate names/handles the entity goes by
 }
 
 // EntityExtractionInput contains the input for entity extraction.
@@ -36,6 +42,11 @@ type EntityExtractionInput struct {
 	PreviousEpisodes   []string      // Optional: previous episodes for coreference context
 	KnownEntities      []KnownEntity // Optional: entities we already know are in this context (e.g., thread participants)
 	CustomInstructions string        // Optional: domain-specific extraction guidance
+	UserNotes          []string      // Optional: notes the user attached to the episode
+	Model              string        // Optional: overrides the extractor's model (e.g. from a ModelRouter)
+	// Optional: entities found by an earlier pass; set on gleaning rounds so
+	// the model only returns what it missed
+	AlreadyExtracted []ExtractedEntity
 }
 
 // EntityExtractor extracts entities from episode content using an LLM.
@@ -43,6 +54,7 @@ type EntityExtractionInput struct {
 type EntityExtractor struct {
 	geminiClient *gemini.Client
 	model        string
+	params       GenerationParams
 }
 
 // NewEntityExtractor creates a new EntityExtractor.
@@ -56,6 +68,11 @@ func NewEntityExtractor(geminiClient *gemini.Client, model string) *EntityExtrac
 	}
 }
 
+// SetGenerationParams sets the sampling settings for extraction and gleaning calls.
+func (e *EntityExtractor) SetGenerationParams(params GenerationParams) {
+	e.params = params
+}
+
 // Extract extracts entities from episode content.
 // Returns a list of extracted entities with temporary IDs (0, 1, 2...).
 func (e *EntityExtractor) Extract(ctx context.Context, input EntityExtractionInput) (*EntityExtractionResult, error) {
@@ -72,15 +89,18 @@ func (e *EntityExtractor) Extract(ctx context.Context, input EntityExtractionInp
 			Role:  "user",
 			Parts: []gemini.Part{{Text: prompt}},
 		}},
-		GenerationConfig: &gemini.GenerationConfig{
-			ResponseMimeType: "application/json",
-		},
+		GenerationConfig: e.params.generationConfig(),
 	}
 
-	resp, err := e.geminiClient.GenerateContent(ctx, e.model, req)
+	model := e.model
+	if input.Model != "" {
+		model = input.Model
+	}
+	resp, err := e.geminiClient.GenerateContent(ctx, model, req)
 	if err != nil {
 		return nil, fmt.Errorf("generate content: %w", err)
 	}
+	recordLLMUsage(ctx, model, resp.UsageMetadata)
 
 	text := extractTextFromResponse(resp)
 	if text == "" {
@@ -105,9 +125,12 @@ func (e *EntityExtractor) Extract(ctx context.Context, input EntityExtractionInp
 			// Default to Entity (type 0) if invalid
 			entity.EntityTypeID = EntityTypeEntity
 		}
+		// Locate mentions for provenance highlighting
+		entity.Offsets, entity.Mentions = locateMentions(input.EpisodeContent, entity.Name, entity.Mentions)
 		filtered = append(filtered, entity)
 	}
-	result.ExtractedEntities = filtered
+	// Drop entities the episode never mentions (injected or hallucinated)
+	result.ExtractedEntities = groundExtractedEntities(filtered, input.EpisodeContent, input.KnownEntities)
 
 	return &result, nil
 }
@@ -144,10 +167,15 @@ func (e *EntityExtractor) buildPrompt(input EntityExtractionInput) string {
 	// Known entities (e.g., thread participants we already know about)
 	if len(input.KnownEntities) > 0 {
 		sb.WriteString("<KNOWN_ENTITIES>\n")
-		sb.WriteString("These entities are already known to be present in this context. Include them in your output if they appear in the content:\n")
+		sb.WriteString("These entities are already known (thread participants and frequent contacts). Include them in your output only if they appear in the content:\n")
 		for _, ke := range input.KnownEntities {
-			sb.WriteString(fmt.Sprintf("- %s (%s)\n", ke.Name, ke.EntityType))
+			if len(ke.Aliases) > 0 {
+				sb.WriteString(fmt.Sprintf("- %s (%s) aka %s\n", ke.Name, ke.EntityType, strings.Join(ke.Aliases, ", ")))
+			} else {
+				sb.WriteString(fmt.Sprintf("- %s (%s)\n", ke.Name, ke.EntityType))
+			}
 		}
+		sb.WriteString("When one of these entities is referred to by an alias, output it using the canonical name shown first.\n")
 		sb.WriteString("</KNOWN_ENTITIES>\n\n")
 	}
 
@@ -155,7 +183,7 @@ func (e *EntityExtractor) buildPrompt(input EntityExtractionInput) string {
 	if len(input.PreviousEpisodes) > 0 {
 		sb.WriteString("<PREVIOUS_EPISODES>\n")
 		for _, ep := range input.PreviousEpisodes {
-			sb.WriteString(ep)
+			sb.WriteString(sanitizePromptContent(ep))
 			sb.WriteString("\n---\n")
 		}
 		sb.WriteString("</PREVIOUS_EPISODES>\n\n")
@@ -163,8 +191,11 @@ func (e *EntityExtractor) buildPrompt(input EntityExtractionInput) string {
 
 	// Current episode
 	sb.WriteString("<CURRENT_EPISODE>\n")
-	sb.WriteString(input.EpisodeContent)
+	sb.WriteString(sanitizePromptContent(input.EpisodeContent))
 	sb.WriteString("\n</CURRENT_EPISODE>\n\n")
+	sb.WriteString(userNotesSection(input.UserNotes))
+
+	sb.WriteString(untrustedContentRules)
 
 	// Instructions
 	sb.WriteString(`## Instructions
@@ -194,6 +225,7 @@ Extract **entity nodes** from the CURRENT_EPISODE.
 - Use the most complete name available (full names over nicknames)
 - Resolve pronouns to their referent when clear from context
 - For conversations: always extract speakers as entities (if they're people)
+- Also extract people who are only talked about, not just the speakers ("Casey's sister Dana" → Casey and Dana)
 
 `)
 
@@ -203,6 +235,17 @@ Extract **entity nodes** from the CURRENT_EPISODE.
 		sb.WriteString("\n\n")
 	}
 
+	// Gleaning round: list what was already found and ask for what was missed
+	if len(input.AlreadyExtracted) > 0 {
+		sb.WriteString("<ALREADY_EXTRACTED>\n")
+		for _, ent := range input.AlreadyExtracted {
+			sb.WriteString(fmt.Sprintf("- %s\n", ent.Name))
+		}
+		sb.WriteString("</ALREADY_EXTRACTED>\n\n")
+		sb.WriteString(gleaningInstruction)
+		sb.WriteString("\n")
+	}
+
 	// Output schema
 	sb.WriteString(`## Output Schema
 
@@ -212,7 +255,8 @@ Return a JSON object with this exact structure:
     {
       "id": 0,
       "name": "Entity Name",
-      "entity_type_id": 1
+      "entity_type_id": 1,
+      "mentions": ["Entity Name", "Nickname"]
     }
   ]
 }
@@ -221,6 +265,7 @@ Where:
 - id: Temporary integer ID starting from 0
 - name: Name of the extracted entity
 - entity_type_id: ID from ENTITY_TYPES (0-7)
+- mentions: Every distinct way the entity is written in CURRENT_EPISODE, copied exactly (names, nicknames, short forms; not pronouns)
 
 Return ONLY the JSON object, no other text.
 `)
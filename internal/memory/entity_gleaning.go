@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// GleaningConfig controls re-prompting of episodes whose first extraction
+// pass looks low-recall (many messages, few entities).
+type GleaningConfig struct {
+	// Maximum number of gleaning re-prompts per episode (default: 2)
+	MaxRounds int
+	// Episodes with fewer messages than this are never gleaned (default: 10)
+	MinMessages int
+	// Glean when there is less than one entity per this many messages (default: 15)
+	MessagesPerEntity int
+}
+
+// DefaultGleaningConfig returns the default gleaning thresholds.
+func DefaultGleaningConfig() GleaningConfig {
+	return GleaningConfig{
+		MaxRounds:         2,
+		MinMessages:       10,
+		MessagesPerEntity: 15,
+	}
+}
+
+// GleaningResult contains the merged entities after gleaning.
+type GleaningResult struct {
+	ExtractedEntities []ExtractedEntity
+	Rounds            int // Gleaning re-prompts issued
+	Added             int // Entities found by gleaning rounds
+}
+
+// gleaningInstruction is appended to the extraction prompt on gleaning rounds.
+const gleaningInstruction = `## Gleaning
+
+A previous pass extracted only the entities listed in ALREADY_EXTRACTED, which seems low for this conversation.
+You may have missed entities such as people referenced by first name only, nicknames, family members ("Mom", "my sister"),
+places mentioned in passing, or organizations named only once. Re-read the CURRENT_EPISODE carefully.
+
+Return ONLY entities that are NOT already in ALREADY_EXTRACTED. Return an empty list if nothing was missed.
+`
+
+// Glean re-prompts the extractor up to MaxRounds times while the extraction
+// looks low-recall, merging any newly found entities into the initial set.
+// It stops early once a round finds nothing new.
+func (e *EntityExtractor) Glean(ctx context.Context, input EntityExtractionInput, initial []ExtractedEntity, config GleaningConfig) (*GleaningResult, error) {
+	config = withGleaningDefaults(config)
+	result := &GleaningResult{ExtractedEntities: initial}
+
+	for result.Rounds < config.MaxRounds && needsGleaning(input.EpisodeContent, len(result.ExtractedEntities), config) {
+		roundInput := input
+		roundInput.AlreadyExtracted = result.ExtractedEntities
+		round, err := e.Extract(ctx, roundInput)
+		result.Rounds++
+		if err != nil {
+			return result, fmt.Errorf("gleaning round %d: %w", result.Rounds, err)
+		}
+
+		merged, added := mergeGleanedEntities(result.ExtractedEntities, round.ExtractedEntities)
+		result.ExtractedEntities = merged
+		result.Added += added
+		if added == 0 {
+			break
+		}
+	}
+	return result, nil
+}
+
+func withGleaningDefaults(config GleaningConfig) GleaningConfig {
+	defaults := DefaultGleaningConfig()
+	if config.MaxRounds <= 0 {
+		config.MaxRounds = defaults.MaxRounds
+	}
+	if config.MinMessages <= 0 {
+		config.MinMessages = defaults.MinMessages
+	}
+	if config.MessagesPerEntity <= 0 {
+		config.MessagesPerEntity = defaults.MessagesPerEntity
+	}
+	return config
+}
+
+// needsGleaning reports whether an episode yielded too few entities for
+// its number of messages.
+func needsGleaning(content string, entityCount int, config GleaningConfig) bool {
+	messages := countEpisodeMessages(content)
+	if messages < config.MinMessages {
+		return false
+	}
+	return entityCount*config.MessagesPerEntity < messages
+}
+
+// countEpisodeMessages counts non-empty lines in episode content, which is
+// rendered one message per line.
+func countEpisodeMessages(content string) int {
+	count := 0
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// mergeGleanedEntities appends entities not already present (by
+// case-insensitive name), renumbering temporary IDs to stay sequential.
+func mergeGleanedEntities(existing, gleaned []ExtractedEntity) ([]ExtractedEntity, int) {
+	seen := make(map[string]bool, len(existing))
+	merged := make([]ExtractedEntity, 0, len(existing)+len(gleaned))
+	for _, ent := range existing {
+		seen[strings.ToLower(strings.TrimSpace(ent.Name))] = true
+		merged = append(merged, ent)
+	}
+
+	added := 0
+	for _, ent := range gleaned {
+		key := strings.ToLower(strings.TrimSpace(ent.Name))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		ent.ID = len(merged)
+		merged = append(merged, ent)
+		added++
+	}
+	return merged, added
+}
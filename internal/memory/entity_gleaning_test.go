@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNeedsGleaning(t *testing.T) {
+	config := DefaultGleaningConfig()
+	many := strings.Repeat("Tyler: hey\n", 30)
+
+	tests := []struct {
+		name     string
+		content  string
+		entities int
+		want     bool
+	}{
+		{"short episode", strings.Repeat("Tyler: hey\n", 5), 0, false},
+		{"many messages, no entities", many, 0, true},
+		{"many messages, one entity", many, 1, true},
+		{"many messages, enough entities", many, 2, false},
+		{"blank lines ignored", strings.Repeat("Tyler: hey\n\n\n", 9), 0, false},
+	}
+	for _, tt := range tests {
+		if got := needsGleaning(tt.content, tt.entities, config); got != tt.want {
+			t.Errorf("%s: needsGleaning = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMergeGleanedEntities(t *testing.T) {
+	existing := []ExtractedEntity{
+		{ID: 0, Name: "Tyler", EntityTypeID: EntityTypePerson},
+	}
+	gleaned := []ExtractedEntity{
+		{ID: 0, Name: "tyler", EntityTypeID: EntityTypePerson}, // duplicate
+		{ID: 1, Name: "Sarah", EntityTypeID: EntityTypePerson},
+		{ID: 2, Name: " ", EntityTypeID: EntityTypePerson},
+		{ID: 3, Name: "Mom", EntityTypeID: EntityTypePerson},
+	}
+
+	merged, added := mergeGleanedEntities(existing, gleaned)
+	if added != 2 || len(merged) != 3 {
+		t.Fatalf("merged = %+v, added = %d", merged, added)
+	}
+	for i, ent := range merged {
+		if ent.ID != i {
+			t.Errorf("merged[%d].ID = %d, want sequential IDs", i, ent.ID)
+		}
+	}
+	if merged[1].Name != "Sarah" || merged[2].Name != "Mom" {
+		t.Errorf("merged = %+v", merged)
+	}
+}
+
+func TestGlean_SkipsWellCoveredEpisodes(t *testing.T) {
+	// No LLM call is made when the episode is not low-recall
+	extractor := NewEntityExtractor(nil, "")
+	initial := []ExtractedEntity{{ID: 0, Name: "Tyler"}}
+	result, err := extractor.Glean(context.Background(), EntityExtractionInput{EpisodeContent: "Tyler: hi"}, initial, GleaningConfig{})
+	if err != nil {
+		t.Fatalf("Glean: %v", err)
+	}
+	if result.Rounds != 0 || len(result.ExtractedEntities) != 1 {
+		t.Errorf("result = %+v, want no rounds", result)
+	}
+}
+
+func TestBuildPromptWithAlreadyExtracted(t *testing.T) {
+	extractor := NewEntityExtractor(nil, "")
+	prompt := extractor.buildPrompt(EntityExtractionInput{
+		EpisodeContent:   "Tyler: dinner with Sam tonight",
+		AlreadyExtracted: []ExtractedEntity{{ID: 0, Name: "Tyler"}},
+	})
+
+	if !strings.Contains(prompt, "<ALREADY_EXTRACTED>\n- Tyler\n</ALREADY_EXTRACTED>") {
+		t.Error("prompt should list already extracted entities")
+	}
+	if !strings.Contains(prompt, "first name only") {
+		t.Error("prompt should include gleaning instruction")
+	}
+
+	plain := extractor.buildPrompt(EntityExtractionInput{EpisodeContent: "Tyler: hi"})
+	if strings.Contains(plain, "ALREADY_EXTRACTED") {
+		t.Error("first-pass prompt should not include gleaning section")
+	}
+}
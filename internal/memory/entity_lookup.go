@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// entityCandidateFacts is how many facts describe each candidate.
+const entityCandidateFacts = 3
+
+// EntityCandidate is an entity that matches a lookup, with enough context
+// (type, strongest facts, when it was last mentioned) for a person to tell
+// two "Chris" entities apart.
+type EntityCandidate struct {
+	Entity
+	TypeName      string     `json:"type_name"`
+	ExactName     bool       `json:"exact_name"` // canonical name equals the lookup, ignoring case
+	TopFacts      []string   `json:"top_facts,omitempty"`
+	LastMentionAt *time.Time `json:"last_mention_at,omitempty"`
+	Mentions      int        `json:"mentions"`
+}
+
+// LookupEntities resolves ref to the entities it could mean. An entity ID
+// matches only that entity. Otherwise names are matched like
+// FindEntitiesByName; when some names match exactly (ignoring case), the
+// partial matches are dropped. Candidates are ordered most mentioned first,
+// then most recently mentioned.
+func (q *QueryEngine) LookupEntities(ctx context.Context, ref string, entityTypeID *int) ([]EntityCandidate, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, fmt.Errorf("entity name or ID is required")
+	}
+
+	var matches []Entity
+	entity, err := q.GetEntity(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if entity != nil && entity.MergedInto == nil {
+		matches = []Entity{*entity}
+	} else {
+		matches, err = q.FindEntitiesByName(ctx, ref, entityTypeID)
+		if err != nil {
+			return nil, err
+		}
+		var exact []Entity
+		for _, m := range matches {
+			if strings.EqualFold(m.CanonicalName, ref) {
+				exact = append(exact, m)
+			}
+		}
+		if len(exact) > 0 {
+			matches = exact
+		}
+	}
+
+	candidates := make([]EntityCandidate, 0, len(matches))
+	for _, m := range matches {
+		c := EntityCandidate{Entity: m, ExactName: strings.EqualFold(m.CanonicalName, ref)}
+		if et := GetEntityTypeByID(m.EntityTypeID); et != nil {
+			c.TypeName = et.Name
+		}
+		if err := q.describeCandidate(ctx, &c); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Mentions != b.Mentions {
+			return a.Mentions > b.Mentions
+		}
+		if (a.LastMentionAt == nil) != (b.LastMentionAt == nil) {
+			return a.LastMentionAt != nil
+		}
+		return a.LastMentionAt != nil && a.LastMentionAt.After(*b.LastMentionAt)
+	})
+	return candidates, nil
+}
+
+// describeCandidate fills in a candidate's mention stats and top facts.
+func (q *QueryEngine) describeCandidate(ctx context.Context, c *EntityCandidate) error {
+	var lastMention sql.NullInt64
+	if err := q.queryRow(ctx, `
+		SELECT COUNT(*), MAX(ep.end_time)
+		FROM episode_entity_mentions m
+		JOIN episodes ep ON ep.id = m.episode_id
+		WHERE m.entity_id = ?
+	`, c.ID).Scan(&c.Mentions, &lastMention); err != nil {
+		return fmt.Errorf("load mentions for %s: %w", c.ID, err)
+	}
+	if lastMention.Valid {
+		t := time.Unix(lastMention.Int64, 0)
+		c.LastMentionAt = &t
+	}
+
+	rows, err := q.query(ctx, `
+		SELECT fact FROM relationships
+		WHERE (source_entity_id = ? OR target_entity_id = ?) AND invalid_at IS NULL
+		ORDER BY weight DESC, confidence DESC, created_at DESC
+		LIMIT ?
+	`, c.ID, c.ID, entityCandidateFacts)
+	if err != nil {
+		return fmt.Errorf("load facts for %s: %w", c.ID, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var fact string
+		if err := rows.Scan(&fact); err != nil {
+			return err
+		}
+		c.TopFacts = append(c.TopFacts, fact)
+	}
+	return rows.Err()
+}
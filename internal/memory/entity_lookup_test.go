@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestLookupEntities(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertQueryEngineTestEntity(t, db, "chris-a", "Chris", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "chris-b", "Chris", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "christina", "Christina", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "acme", "Acme", EntityTypeCompany)
+
+	acme := "acme"
+	insertCurrentFactsRel(t, db, "r1", "chris-b", &acme, nil, "WORKS_AT", "2020-01-01", nil)
+	if _, err := db.Exec(`
+		INSERT INTO threads (id, channel, source_adapter, source_id, created_at, updated_at) VALUES ('t1', 'imessage', 'imessage', 't1', 0, 0);
+		INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0);
+		INSERT INTO episodes (id, definition_id, channel, thread_id, start_time, end_time, event_count, created_at) VALUES
+			('ep1', 'def', 'imessage', 't1', 100, 1700000000, 1, 0),
+			('ep2', 'def', 'imessage', 't1', 100, 1710000000, 1, 0);
+		INSERT INTO episode_entity_mentions (episode_id, entity_id, created_at) VALUES
+			('ep1', 'chris-b', 'x'), ('ep2', 'chris-b', 'x'), ('ep1', 'chris-a', 'x');
+	`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	q := NewQueryEngine(db)
+	defer q.Close()
+
+	candidates, err := q.LookupEntities(ctx, "chris", nil)
+	if err != nil {
+		t.Fatalf("LookupEntities: %v", err)
+	}
+	if len(candidates) != 2 {
+		t.Fatalf("got %d candidates, want the two exact matches: %+v", len(candidates), candidates)
+	}
+	top := candidates[0]
+	if top.ID != "chris-b" || top.Mentions != 2 || top.TypeName != "Person" || !top.ExactName {
+		t.Errorf("top candidate = %+v", top)
+	}
+	if top.LastMentionAt == nil || top.LastMentionAt.Unix() != 1710000000 {
+		t.Errorf("last mention = %v", top.LastMentionAt)
+	}
+	if len(top.TopFacts) != 1 || top.TopFacts[0] != "WORKS_AT fact" {
+		t.Errorf("top facts = %v", top.TopFacts)
+	}
+
+	partial, err := q.LookupEntities(ctx, "christ", nil)
+	if err != nil || len(partial) != 1 || partial[0].ID != "christina" {
+		t.Errorf("partial lookup = %+v, %v", partial, err)
+	}
+	byID, err := q.LookupEntities(ctx, "chris-a", nil)
+	if err != nil || len(byID) != 1 || byID[0].ID != "chris-a" {
+		t.Errorf("ID lookup = %+v, %v", byID, err)
+	}
+}
@@ -0,0 +1,253 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reasons an entity's canonical name changed.
+const (
+	NameChangeMerge            = "merge"
+	NameChangeGeoNormalization = "geo_normalization"
+	NameChangeManual           = "manual"
+)
+
+// NameChange is one entry of an entity's name history.
+type NameChange struct {
+	ID             string  `json:"id"`
+	EntityID       string  `json:"entity_id"`
+	OldName        string  `json:"old_name"`
+	NewName        string  `json:"new_name"`
+	Reason         string  `json:"reason"`
+	SourceEntityID *string `json:"source_entity_id,omitempty"`
+	ChangedBy      string  `json:"changed_by"` // "auto" or "user"
+	CreatedAt      string  `json:"created_at"`
+}
+
+// sqlExecer is satisfied by *sql.DB and *sql.Tx.
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// RenameEntity sets an entity's canonical name by hand, keeping the old name
+// as an alias. With lock, automated renames (merges, normalization) leave the
+// name alone from then on; an existing lock is kept either way.
+func RenameEntity(ctx context.Context, db *sql.DB, entityID, name string, lock bool) (*NameChange, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var oldName string
+	err = tx.QueryRowContext(ctx, `
+		SELECT canonical_name FROM entities WHERE id = ? AND merged_into IS NULL
+	`, entityID).Scan(&oldName)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("entity not found: %s", entityID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get entity: %w", err)
+	}
+
+	var change *NameChange
+	if name != oldName {
+		change, err = renameEntity(ctx, tx, entityID, oldName, name, NameChangeManual, "", "user")
+		if err != nil {
+			return nil, err
+		}
+		for _, alias := range []string{oldName, name} {
+			if err := ensureNameAlias(ctx, tx, entityID, alias); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if lock {
+		if _, err := tx.ExecContext(ctx, `UPDATE entities SET name_locked = 1 WHERE id = ?`, entityID); err != nil {
+			return nil, fmt.Errorf("lock name: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	if change != nil {
+		if err := NewBlockingIndex(db).IndexAlias(ctx, entityID, name, "name"); err != nil {
+			// Non-fatal - IndexMissing catches up on the next run
+			_ = err
+		}
+	}
+	return change, nil
+}
+
+// SetEntityNameLocked locks or unlocks an entity's canonical name against
+// automated renames.
+func SetEntityNameLocked(ctx context.Context, db *sql.DB, entityID string, locked bool) error {
+	res, err := db.ExecContext(ctx, `
+		UPDATE entities SET name_locked = ?, updated_at = ? WHERE id = ? AND merged_into IS NULL
+	`, locked, time.Now().Format(time.RFC3339), entityID)
+	if err != nil {
+		return fmt.Errorf("update entity: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("entity not found: %s", entityID)
+	}
+	return nil
+}
+
+// GetNameHistory returns an entity's name changes, oldest first.
+func GetNameHistory(ctx context.Context, db *sql.DB, entityID string) ([]NameChange, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, entity_id, old_name, new_name, reason, source_entity_id, changed_by, created_at
+		FROM entity_name_history
+		WHERE entity_id = ?
+		ORDER BY created_at, rowid
+	`, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("query name history: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []NameChange
+	for rows.Next() {
+		var c NameChange
+		var sourceID sql.NullString
+		if err := rows.Scan(&c.ID, &c.EntityID, &c.OldName, &c.NewName, &c.Reason, &sourceID, &c.ChangedBy, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan name change: %w", err)
+		}
+		if sourceID.Valid {
+			c.SourceEntityID = &sourceID.String
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// renameEntity updates canonical_name and records the change.
+func renameEntity(ctx context.Context, ex sqlExecer, entityID, oldName, newName, reason, sourceEntityID, changedBy string) (*NameChange, error) {
+	now := time.Now().Format(time.RFC3339)
+	if _, err := ex.ExecContext(ctx, `
+		UPDATE entities SET canonical_name = ?, updated_at = ? WHERE id = ?
+	`, newName, now, entityID); err != nil {
+		return nil, fmt.Errorf("rename entity: %w", err)
+	}
+
+	change := &NameChange{
+		ID:        uuid.New().String(),
+		EntityID:  entityID,
+		OldName:   oldName,
+		NewName:   newName,
+		Reason:    reason,
+		ChangedBy: changedBy,
+		CreatedAt: now,
+	}
+	var source interface{}
+	if sourceEntityID != "" {
+		change.SourceEntityID = &sourceEntityID
+		source = sourceEntityID
+	}
+	if _, err := ex.ExecContext(ctx, `
+		INSERT INTO entity_name_history (id, entity_id, old_name, new_name, reason, source_entity_id, changed_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, change.ID, entityID, oldName, newName, reason, source, changedBy, now); err != nil {
+		return nil, fmt.Errorf("record name change: %w", err)
+	}
+	return change, nil
+}
+
+// isNameLocked reports whether an entity's name is locked against automated renames.
+func isNameLocked(ctx context.Context, ex sqlExecer, entityID string) (bool, error) {
+	var locked bool
+	err := ex.QueryRowContext(ctx, `
+		SELECT COALESCE(name_locked, 0) FROM entities WHERE id = ?
+	`, entityID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return locked, err
+}
+
+// contactCardName returns the name an entity has on a contact card: its own
+// name when it was imported from contacts, else the name of the contacts-graph
+// person linked to it. Returns "" when there is none.
+func contactCardName(ctx context.Context, ex sqlExecer, entityID string) (string, error) {
+	var name, origin string
+	err := ex.QueryRowContext(ctx, `
+		SELECT canonical_name, COALESCE(origin, '') FROM entities WHERE id = ?
+	`, entityID).Scan(&name, &origin)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if origin == OriginContactSeed {
+		return name, nil
+	}
+
+	var personName string
+	err = ex.QueryRowContext(ctx, `
+		SELECT p.canonical_name FROM person_entity_links l
+		JOIN persons p ON p.id = l.person_id
+		WHERE l.entity_id = ?
+		ORDER BY l.created_at
+		LIMIT 1
+	`, entityID).Scan(&personName)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !looksLikeContactName(personName) {
+		return "", nil
+	}
+	return personName, nil
+}
+
+// looksLikeContactName rejects person names that are really identifiers
+// (a phone number or email standing in for a name).
+func looksLikeContactName(name string) bool {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.Contains(name, "@") || name == "Unknown" {
+		return false
+	}
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r > 127 {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureNameAlias adds a name alias to an entity if it doesn't have it.
+func ensureNameAlias(ctx context.Context, ex sqlExecer, entityID, alias string) error {
+	normalized := normalizeAlias(alias)
+	var exists int
+	if err := ex.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM entity_aliases
+		WHERE entity_id = ? AND alias_type = 'name' AND normalized = ?
+	`, entityID, normalized).Scan(&exists); err != nil {
+		return fmt.Errorf("check alias: %w", err)
+	}
+	if exists > 0 {
+		return nil
+	}
+	if _, err := ex.ExecContext(ctx, `
+		INSERT INTO entity_aliases (id, entity_id, alias, alias_type, normalized, is_shared, created_at)
+		VALUES (?, ?, ?, 'name', ?, FALSE, ?)
+	`, uuid.New().String(), entityID, alias, normalized, time.Now().Format(time.RFC3339)); err != nil {
+		return fmt.Errorf("insert alias: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestExecuteMerge_NameSelection(t *testing.T) {
+	db := setupAutoMergerTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	merger := NewAutoMerger(db)
+
+	name := func(id string) string {
+		var n string
+		db.QueryRow(`SELECT canonical_name FROM entities WHERE id = ?`, id).Scan(&n)
+		return n
+	}
+	merge := func(source, target string) {
+		t.Helper()
+		if _, err := merger.ExecuteMerge(ctx, &MergeCandidate{EntityAID: source, EntityBID: target, Reason: "manual"}, "test"); err != nil {
+			t.Fatalf("ExecuteMerge: %v", err)
+		}
+	}
+
+	// Better name wins, and the change is recorded
+	createTestEntity(t, db, "a", "Tyler Brandt", 1)
+	createTestEntity(t, db, "b", "TYLER", 1)
+	merge("a", "b")
+	history, err := GetNameHistory(ctx, db, "b")
+	if err != nil {
+		t.Fatalf("GetNameHistory: %v", err)
+	}
+	if name("b") != "Tyler Brandt" || len(history) != 1 || history[0].OldName != "TYLER" ||
+		history[0].Reason != NameChangeMerge || history[0].SourceEntityID == nil || *history[0].SourceEntityID != "a" {
+		t.Errorf("name = %q, history = %+v", name("b"), history)
+	}
+
+	// A locked target keeps its name
+	createTestEntity(t, db, "c", "Tyler Brandt Jr", 1)
+	if err := SetEntityNameLocked(ctx, db, "b", true); err != nil {
+		t.Fatalf("SetEntityNameLocked: %v", err)
+	}
+	merge("c", "b")
+	if name("b") != "Tyler Brandt" {
+		t.Errorf("locked name changed to %q", name("b"))
+	}
+
+	// A contact-card name beats a "better" extracted one
+	createTestEntity(t, db, "d", "Katherine Elizabeth Smith", 1)
+	createTestEntity(t, db, "e", "Kat", 1)
+	db.Exec(`INSERT INTO persons (id, canonical_name) VALUES ('p-kat', 'Kat Smith')`)
+	db.Exec(`INSERT INTO person_entity_links (person_id, entity_id, method, created_at) VALUES ('p-kat', 'e', 'identifier', 0)`)
+	merge("d", "e")
+	if name("e") != "Kat Smith" {
+		t.Errorf("name = %q, want contact-card name", name("e"))
+	}
+}
+
+func TestRenameEntity(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertQueryEngineTestEntity(t, db, "austin-tx", "Austin TX", EntityTypeLocation)
+	change, err := RenameEntity(ctx, db, "austin-tx", "The ATX", true)
+	if err != nil {
+		t.Fatalf("RenameEntity: %v", err)
+	}
+	if change == nil || change.OldName != "Austin TX" || change.ChangedBy != "user" {
+		t.Errorf("change = %+v", change)
+	}
+	var aliases int
+	db.QueryRow(`SELECT COUNT(*) FROM entity_aliases WHERE entity_id = 'austin-tx' AND normalized IN ('austin tx', 'the atx')`).Scan(&aliases)
+	if aliases != 2 {
+		t.Errorf("name aliases = %d, want old and new", aliases)
+	}
+
+	// Locked: geo normalization would rename "ATX" to "Austin" but must not
+	insertQueryEngineTestEntity(t, db, "atx", "ATX", EntityTypeLocation)
+	if err := SetEntityNameLocked(ctx, db, "atx", true); err != nil {
+		t.Fatalf("SetEntityNameLocked: %v", err)
+	}
+	if _, err := NewGeoNormalizer(db).NormalizeAll(ctx); err != nil {
+		t.Fatalf("NormalizeAll: %v", err)
+	}
+	var name string
+	db.QueryRow(`SELECT canonical_name FROM entities WHERE id = 'atx'`).Scan(&name)
+	if name != "ATX" {
+		t.Errorf("locked name normalized to %q", name)
+	}
+	if history, _ := GetNameHistory(ctx, db, "atx"); len(history) != 0 {
+		t.Errorf("history = %+v, want none", history)
+	}
+
+	if _, err := RenameEntity(ctx, db, "missing", "X", false); err == nil {
+		t.Error("renaming a missing entity should fail")
+	}
+}
@@ -0,0 +1,49 @@
+package memory
+
+// Entity origins record how an entity came to exist (entities.origin).
+const (
+	OriginExtracted   = "extracted"    // named in an episode and extracted by the pipeline
+	OriginImported    = "imported"     // mirrored from imported data, such as person facts
+	OriginContactSeed = "contact_seed" // seeded from the contacts graph
+	OriginManual      = "manual"       // created by hand
+	// OriginInferred marks entities automation created without anyone
+	// mentioning them, like the regions geo normalization adds as parents.
+	OriginInferred = "inferred"
+)
+
+// EntityOrigins lists the valid origins.
+var EntityOrigins = []string{OriginExtracted, OriginImported, OriginContactSeed, OriginManual, OriginInferred}
+
+// IsValidOrigin reports whether origin is one of EntityOrigins.
+func IsValidOrigin(origin string) bool {
+	for _, o := range EntityOrigins {
+		if o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// originCondition returns the SQL condition restricting entities (aliased
+// alias) to opts.Origins and away from opts.ExcludeOrigins, or "" when
+// unfiltered.
+func originCondition(opts QueryOptions, alias string) (string, []interface{}) {
+	var cond string
+	var args []interface{}
+	if len(opts.Origins) > 0 {
+		cond = alias + ".origin IN (" + placeholderList(len(opts.Origins)) + ")"
+		for _, o := range opts.Origins {
+			args = append(args, o)
+		}
+	}
+	if len(opts.ExcludeOrigins) > 0 {
+		if cond != "" {
+			cond += " AND "
+		}
+		cond += alias + ".origin NOT IN (" + placeholderList(len(opts.ExcludeOrigins)) + ")"
+		for _, o := range opts.ExcludeOrigins {
+			args = append(args, o)
+		}
+	}
+	return cond, args
+}
@@ -80,6 +80,13 @@ type EntityResolver struct {
 	db           *sql.DB
 	geminiClient *gemini.Client
 	model        string
+
+	// Blocking index narrows embedding comparisons; nil when unavailable
+	blockingIndex   *BlockingIndex
+	blockingChecked bool
+
+	// Optional hot-entity cache for alias lookups (nil = no caching)
+	cache *EntityCache
 }
 
 // NewEntityResolver creates a new EntityResolver.
@@ -88,12 +95,18 @@ func NewEntityResolver(db *sql.DB, geminiClient *gemini.Client, model string) *E
 		model = DefaultEmbeddingModel
 	}
 	return &EntityResolver{
-		db:           db,
-		geminiClient: geminiClient,
-		model:        model,
+		db:            db,
+		geminiClient:  geminiClient,
+		model:         model,
+		blockingIndex: NewBlockingIndex(db),
 	}
 }
 
+// SetCache enables the hot-entity cache for alias lookups.
+func (r *EntityResolver) SetCache(cache *EntityCache) {
+	r.cache = cache
+}
+
 // Resolve resolves a list of extracted entities against the existing graph.
 // Returns a ResolutionResult with resolved entities and a UUID map.
 func (r *EntityResolver) Resolve(ctx context.Context, extracted []ExtractedEntity, resCtx ResolutionContext) (*ResolutionResult, error) {
@@ -159,6 +172,10 @@ func (r *EntityResolver) resolveOne(ctx context.Context, ext ExtractedEntity, re
 
 // findAliasCandidates searches entity_aliases for matching aliases.
 func (r *EntityResolver) findAliasCandidates(ctx context.Context, name string, entityTypeID int) ([]ResolutionCandidate, error) {
+	if cached, ok := r.cache.GetAliasCandidates(name); ok {
+		return cached, nil
+	}
+
 	normalized := normalizeAlias(name)
 
 	rows, err := r.db.QueryContext(ctx, `
@@ -226,11 +243,23 @@ func (r *EntityResolver) findAliasCandidates(ctx context.Context, name string, e
 	for _, c := range candidateMap {
 		candidates = append(candidates, *c)
 	}
-	return candidates, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	r.cache.PutAliasCandidates(name, candidates)
+	return candidates, nil
 }
 
 // findEmbeddingCandidates searches entities by embedding similarity.
+// When the blocking index is available, only entities sharing a blocking key
+// with the name are compared (and no embedding is generated if there are none).
 func (r *EntityResolver) findEmbeddingCandidates(ctx context.Context, name string, entityTypeID int) ([]ResolutionCandidate, error) {
+	blockedIDs, blocked := r.blockedEntityIDs(ctx, name)
+	if blocked && len(blockedIDs) == 0 {
+		return nil, nil
+	}
+
 	// Generate embedding for the query name
 	queryEmbedding, err := r.generateEmbedding(ctx, name)
 	if err != nil {
@@ -239,14 +268,24 @@ func (r *EntityResolver) findEmbeddingCandidates(ctx context.Context, name strin
 	}
 
 	// Search entity embeddings
-	rows, err := r.db.QueryContext(ctx, `
+	query := `
 		SELECT e.id, e.canonical_name, e.entity_type_id,
 		       emb.embedding_blob, emb.dimension
 		FROM entities e
 		JOIN embeddings emb ON emb.target_id = e.id AND emb.target_type = ?
 		WHERE e.merged_into IS NULL
 		  AND emb.model = ?
-	`, TargetTypeEntity, r.model)
+	`
+	args := []interface{}{TargetTypeEntity, r.model}
+	if blocked {
+		placeholders := make([]string, len(blockedIDs))
+		for i, id := range blockedIDs {
+			placeholders[i] = "?"
+			args = append(args, id)
+		}
+		query += " AND e.id IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -291,6 +330,28 @@ func (r *EntityResolver) findEmbeddingCandidates(ctx context.Context, name strin
 	return candidates, rows.Err()
 }
 
+// blockedEntityIDs returns entities sharing a blocking key with the name.
+// The second return is false when the blocking index is unavailable (e.g., the
+// table does not exist), in which case callers fall back to a full scan.
+// On first use, entities written without blocking keys are indexed.
+func (r *EntityResolver) blockedEntityIDs(ctx context.Context, name string) ([]string, bool) {
+	if r.blockingIndex == nil {
+		return nil, false
+	}
+	if !r.blockingChecked {
+		r.blockingChecked = true
+		if _, err := r.blockingIndex.IndexMissing(ctx); err != nil {
+			r.blockingIndex = nil
+			return nil, false
+		}
+	}
+	ids, err := r.blockingIndex.Candidates(ctx, name, MaxBlockingCandidates)
+	if err != nil {
+		return nil, false
+	}
+	return ids, true
+}
+
 // mergeCandidates merges candidates from alias and embedding searches.
 func (r *EntityResolver) mergeCandidates(alias, embedding []ResolutionCandidate) []ResolutionCandidate {
 	merged := make(map[string]*ResolutionCandidate)
@@ -525,8 +586,8 @@ func (r *EntityResolver) createNewEntity(ctx context.Context, ext ExtractedEntit
 
 	_, err := r.db.ExecContext(ctx, `
 		INSERT INTO entities (id, canonical_name, entity_type_id, origin, confidence, created_at, updated_at)
-		VALUES (?, ?, ?, 'extracted', 1.0, ?, ?)
-	`, id, ext.Name, ext.EntityTypeID, now, now)
+		VALUES (?, ?, ?, ?, 1.0, ?, ?)
+	`, id, ext.Name, ext.EntityTypeID, OriginExtracted, now, now)
 	if err != nil {
 		return nil, err
 	}
@@ -542,6 +603,17 @@ func (r *EntityResolver) createNewEntity(ctx context.Context, ext ExtractedEntit
 		_ = err
 	}
 
+	// Later lookups of this name must see the new entity
+	r.cache.InvalidateAlias(ext.Name, normalizeAlias(ext.Name))
+
+	// Keep the blocking index current so later lookups find this entity
+	if r.blockingIndex != nil {
+		if err := r.blockingIndex.IndexAlias(ctx, id, ext.Name, "name"); err != nil {
+			// Non-fatal - IndexMissing catches up on the next run
+			_ = err
+		}
+	}
+
 	return &Entity{
 		ID:            id,
 		CanonicalName: ext.Name,
@@ -603,6 +675,7 @@ func (r *EntityResolver) generateEmbedding(ctx context.Context, text string) ([]
 	if err != nil {
 		return nil, err
 	}
+	recordEmbedUsage(ctx, len(text))
 	if resp.Embedding == nil || len(resp.Embedding.Values) == 0 {
 		return nil, fmt.Errorf("empty embedding response")
 	}
@@ -68,7 +68,9 @@ func setupResolverTestDB(t *testing.T) *sql.DB {
 			valid_at TEXT,
 			invalid_at TEXT,
 			created_at TEXT NOT NULL,
-			confidence REAL DEFAULT 1.0
+			confidence REAL DEFAULT 1.0,
+			weight REAL DEFAULT 0,
+			source_type TEXT
 		);
 
 		CREATE TABLE episodes (
@@ -85,6 +87,7 @@ func setupResolverTestDB(t *testing.T) *sql.DB {
 			episode_id TEXT NOT NULL REFERENCES episodes(id),
 			entity_id TEXT NOT NULL REFERENCES entities(id),
 			mention_count INTEGER DEFAULT 1,
+			mention_offsets TEXT,
 			created_at TEXT NOT NULL,
 			PRIMARY KEY (episode_id, entity_id)
 		);
@@ -0,0 +1,381 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TypeSignature lists the entity types that fit each end of a relation type.
+// An empty list leaves that end unconstrained.
+type TypeSignature struct {
+	Source []int
+	Target []int
+}
+
+// RelationTypeSignatures declares which entity types a relation type's
+// endpoints should have. Extraction sometimes types a person as a company
+// (a colleague named like a brand); their edges - SPOUSE_OF, FRIEND_OF -
+// still say person, which is what the type checker looks for.
+var RelationTypeSignatures = map[string]TypeSignature{
+	"SPOUSE_OF":  {Source: []int{EntityTypePerson}, Target: []int{EntityTypePerson}},
+	"MARRIED_TO": {Source: []int{EntityTypePerson}, Target: []int{EntityTypePerson}},
+	"DATING":     {Source: []int{EntityTypePerson}, Target: []int{EntityTypePerson}},
+	"SIBLING_OF": {Source: []int{EntityTypePerson}, Target: []int{EntityTypePerson}},
+	"FRIEND_OF":  {Source: []int{EntityTypePerson}, Target: []int{EntityTypePerson}},
+	"KNOWS":      {Source: []int{EntityTypePerson}, Target: []int{EntityTypePerson}},
+	"PARENT_OF":  {Source: []int{EntityTypePerson}, Target: []int{EntityTypePerson, EntityTypePet}},
+	"CHILD_OF":   {Source: []int{EntityTypePerson, EntityTypePet}, Target: []int{EntityTypePerson}},
+
+	"WORKS_AT":  {Source: []int{EntityTypePerson}, Target: []int{EntityTypeOrganization}},
+	"MEMBER_OF": {Source: []int{EntityTypePerson, EntityTypeOrganization}, Target: []int{EntityTypeOrganization}},
+
+	"BORN_ON": {Source: []int{EntityTypePerson, EntityTypePet}},
+	"DIED_ON": {Source: []int{EntityTypePerson, EntityTypePet}},
+	"BORN_IN": {Source: []int{EntityTypePerson, EntityTypePet}, Target: []int{EntityTypeLocation}},
+
+	"LIVES_IN":         {Source: []int{EntityTypePerson, EntityTypePet}, Target: []int{EntityTypeLocation}},
+	"HEADQUARTERED_IN": {Source: []int{EntityTypeOrganization}, Target: []int{EntityTypeLocation}},
+	"LOCATED_IN":       {Source: []int{EntityTypeOrganization, EntityTypeLocation, EntityTypeEvent}, Target: []int{EntityTypeLocation}},
+}
+
+// TypeCheckMinEvidence is how many edges must point to another type before
+// an entity is flagged, so one stray extraction never flags anything.
+const TypeCheckMinEvidence = 2
+
+// Type flag statuses.
+const (
+	TypeFlagStatusPending  = "pending"
+	TypeFlagStatusAccepted = "accepted" // retyped to the suggested type
+	TypeFlagStatusRejected = "rejected" // the type was right; never suggested again
+	TypeFlagStatusResolved = "resolved" // retyped by hand, to any type
+)
+
+// TypeFlag is an entity whose relationships fit another type better than its
+// own, waiting for review.
+type TypeFlag struct {
+	ID              string   `json:"id"`
+	EntityID        string   `json:"entity_id"`
+	CanonicalName   string   `json:"canonical_name"`
+	CurrentTypeID   int      `json:"current_type_id"`
+	SuggestedTypeID int      `json:"suggested_type_id"`
+	Evidence        int      `json:"evidence"` // edges expecting the suggested type
+	Total           int      `json:"total"`    // edges whose relation type constrains the entity's type
+	RelationTypes   []string `json:"relation_types"`
+	Status          string   `json:"status"`
+	CreatedAt       string   `json:"created_at"`
+	ResolvedAt      *string  `json:"resolved_at,omitempty"`
+}
+
+// TypeCheckResult contains the output of a type consistency check.
+type TypeCheckResult struct {
+	Checked int        `json:"checked"` // entities with at least one constrained edge
+	Flagged []TypeFlag `json:"flagged"`
+	Created int        `json:"created"` // flags newly recorded (not seen or rejected before)
+}
+
+// TypeChecker finds entities whose relationship patterns are inconsistent
+// with their type. Entities retyped by hand are skipped.
+type TypeChecker struct {
+	db *sql.DB
+}
+
+// NewTypeChecker creates a new TypeChecker.
+func NewTypeChecker(db *sql.DB) *TypeChecker {
+	return &TypeChecker{db: db}
+}
+
+// Check scores every entity's edges against RelationTypeSignatures. An
+// entity is flagged when at least TypeCheckMinEvidence edges expect one
+// other type and fewer than half of its constrained edges fit its current
+// type. When record is true, flags are stored as pending; a suggestion
+// already pending or rejected for the entity is not recorded again.
+func (c *TypeChecker) Check(ctx context.Context, record bool) (*TypeCheckResult, error) {
+	relTypes := make([]string, 0, len(RelationTypeSignatures))
+	for relType := range RelationTypeSignatures {
+		relTypes = append(relTypes, relType)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(relTypes)), ",")
+	args := make([]interface{}, 0, 2*len(relTypes))
+	for range 2 {
+		for _, relType := range relTypes {
+			args = append(args, relType)
+		}
+	}
+
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT e.id, e.canonical_name, e.entity_type_id, x.relation_type, x.is_source, x.n
+		FROM (
+			SELECT source_entity_id AS entity_id, relation_type, 1 AS is_source, COUNT(*) AS n
+			FROM relationships
+			WHERE relation_type IN (`+placeholders+`)
+			GROUP BY source_entity_id, relation_type
+			UNION ALL
+			SELECT target_entity_id, relation_type, 0, COUNT(*)
+			FROM relationships
+			WHERE target_entity_id IS NOT NULL AND relation_type IN (`+placeholders+`)
+			GROUP BY target_entity_id, relation_type
+		) x
+		JOIN entities e ON e.id = x.entity_id
+		WHERE e.merged_into IS NULL AND COALESCE(e.type_locked, 0) = 0
+		ORDER BY e.id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query constrained edges: %w", err)
+	}
+
+	type tally struct {
+		name      string
+		typeID    int
+		total     int
+		votes     map[int]int
+		misfitRel map[string]bool
+	}
+	var order []string
+	tallies := make(map[string]*tally)
+	for rows.Next() {
+		var id, name, relType string
+		var typeID, n int
+		var isSource bool
+		if err := rows.Scan(&id, &name, &typeID, &relType, &isSource, &n); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan edge count: %w", err)
+		}
+		allowed := RelationTypeSignatures[relType].Target
+		if isSource {
+			allowed = RelationTypeSignatures[relType].Source
+		}
+		if len(allowed) == 0 {
+			continue
+		}
+		t := tallies[id]
+		if t == nil {
+			t = &tally{name: name, typeID: typeID, votes: make(map[int]int), misfitRel: make(map[string]bool)}
+			tallies[id] = t
+			order = append(order, id)
+		}
+		t.total += n
+		fits := false
+		for _, allowedID := range allowed {
+			t.votes[allowedID] += n
+			fits = fits || allowedID == t.typeID
+		}
+		if !fits {
+			t.misfitRel[relType] = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &TypeCheckResult{Checked: len(order), Flagged: make([]TypeFlag, 0)}
+	now := time.Now().Format(time.RFC3339)
+	for _, id := range order {
+		t := tallies[id]
+		if t.total < TypeCheckMinEvidence || 2*t.votes[t.typeID] >= t.total {
+			continue
+		}
+		// Most-voted other type; ties go to the lower type ID
+		suggested, best := -1, 0
+		for typeID, n := range t.votes {
+			if typeID != t.typeID && (n > best || (n == best && typeID < suggested)) {
+				suggested, best = typeID, n
+			}
+		}
+		if best < TypeCheckMinEvidence {
+			continue
+		}
+
+		flag := TypeFlag{
+			ID:              uuid.New().String(),
+			EntityID:        id,
+			CanonicalName:   t.name,
+			CurrentTypeID:   t.typeID,
+			SuggestedTypeID: suggested,
+			Evidence:        best,
+			Total:           t.total,
+			Status:          TypeFlagStatusPending,
+			CreatedAt:       now,
+		}
+		for relType := range t.misfitRel {
+			flag.RelationTypes = append(flag.RelationTypes, relType)
+		}
+		sort.Strings(flag.RelationTypes)
+		result.Flagged = append(result.Flagged, flag)
+
+		if record {
+			created, err := recordTypeFlag(ctx, c.db, flag)
+			if err != nil {
+				return nil, err
+			}
+			if created {
+				result.Created++
+			}
+		}
+	}
+	return result, nil
+}
+
+// recordTypeFlag inserts a pending flag (idempotent per entity and
+// suggested type).
+func recordTypeFlag(ctx context.Context, db *sql.DB, flag TypeFlag) (bool, error) {
+	relTypes, err := json.Marshal(flag.RelationTypes)
+	if err != nil {
+		return false, err
+	}
+	res, err := db.ExecContext(ctx, `
+		INSERT INTO entity_type_flags (
+			id, entity_id, current_type_id, suggested_type_id, evidence, total, relation_types, status, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(entity_id, suggested_type_id) DO NOTHING
+	`, flag.ID, flag.EntityID, flag.CurrentTypeID, flag.SuggestedTypeID, flag.Evidence, flag.Total,
+		string(relTypes), flag.Status, flag.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("insert type flag: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ListTypeFlags returns flags with the given status (all statuses if empty)
+// on entities that still exist unmerged, most evidence first.
+func ListTypeFlags(ctx context.Context, db *sql.DB, status string, limit int) ([]TypeFlag, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	query := `
+		SELECT f.id, f.entity_id, e.canonical_name, f.current_type_id, f.suggested_type_id,
+		       f.evidence, f.total, f.relation_types, f.status, f.created_at, f.resolved_at
+		FROM entity_type_flags f
+		JOIN entities e ON e.id = f.entity_id
+		WHERE e.merged_into IS NULL
+	`
+	var args []interface{}
+	if status != "" {
+		query += ` AND f.status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY f.evidence DESC, f.created_at DESC, f.id LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query type flags: %w", err)
+	}
+	defer rows.Close()
+
+	var flags []TypeFlag
+	for rows.Next() {
+		var f TypeFlag
+		var relTypes string
+		var resolvedAt sql.NullString
+		if err := rows.Scan(&f.ID, &f.EntityID, &f.CanonicalName, &f.CurrentTypeID, &f.SuggestedTypeID,
+			&f.Evidence, &f.Total, &relTypes, &f.Status, &f.CreatedAt, &resolvedAt); err != nil {
+			return nil, fmt.Errorf("scan type flag: %w", err)
+		}
+		if err := json.Unmarshal([]byte(relTypes), &f.RelationTypes); err != nil {
+			return nil, fmt.Errorf("decode relation types of flag %s: %w", f.ID, err)
+		}
+		if resolvedAt.Valid {
+			f.ResolvedAt = &resolvedAt.String
+		}
+		flags = append(flags, f)
+	}
+	return flags, rows.Err()
+}
+
+// TypeChange is the result of retyping an entity.
+type TypeChange struct {
+	EntityID      string `json:"entity_id"`
+	OldTypeID     int    `json:"old_type_id"`
+	NewTypeID     int    `json:"new_type_id"`
+	FlagsResolved int    `json:"flags_resolved"` // pending flags closed by the change
+}
+
+// RetypeEntity sets an entity's type by hand and locks it, so the type
+// checker leaves the entity alone from then on. Pending flags on the entity
+// are closed: accepted if they suggested the new type, resolved otherwise.
+// Entities of the new type with the same name are not merged here; the
+// duplicate report finds them.
+func RetypeEntity(ctx context.Context, db *sql.DB, entityID string, typeID int) (*TypeChange, error) {
+	if !IsValidEntityTypeID(typeID) {
+		return nil, fmt.Errorf("unknown entity type: %d", typeID)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	change := &TypeChange{EntityID: entityID, NewTypeID: typeID}
+	err = tx.QueryRowContext(ctx, `
+		SELECT entity_type_id FROM entities WHERE id = ? AND merged_into IS NULL
+	`, entityID).Scan(&change.OldTypeID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("entity not found: %s", entityID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get entity: %w", err)
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE entities SET entity_type_id = ?, type_locked = 1, updated_at = ? WHERE id = ?
+	`, typeID, now, entityID); err != nil {
+		return nil, fmt.Errorf("retype entity: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `
+		UPDATE entity_type_flags
+		SET status = CASE WHEN suggested_type_id = ? THEN ? ELSE ? END, resolved_at = ?
+		WHERE entity_id = ? AND status = ?
+	`, typeID, TypeFlagStatusAccepted, TypeFlagStatusResolved, now, entityID, TypeFlagStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("close type flags: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	change.FlagsResolved = int(n)
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return change, nil
+}
+
+// AcceptTypeFlag retypes the flagged entity to the suggested type.
+func AcceptTypeFlag(ctx context.Context, db *sql.DB, flagID string) (*TypeChange, error) {
+	var entityID string
+	var suggested int
+	err := db.QueryRowContext(ctx, `
+		SELECT entity_id, suggested_type_id FROM entity_type_flags WHERE id = ? AND status = ?
+	`, flagID, TypeFlagStatusPending).Scan(&entityID, &suggested)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no pending type flag: %s", flagID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get type flag: %w", err)
+	}
+	return RetypeEntity(ctx, db, entityID, suggested)
+}
+
+// RejectTypeFlag marks a flag wrong; the checker will not suggest that type
+// for the entity again.
+func RejectTypeFlag(ctx context.Context, db *sql.DB, flagID string) error {
+	res, err := db.ExecContext(ctx, `
+		UPDATE entity_type_flags SET status = ?, resolved_at = ? WHERE id = ? AND status = ?
+	`, TypeFlagStatusRejected, time.Now().Format(time.RFC3339), flagID, TypeFlagStatusPending)
+	if err != nil {
+		return fmt.Errorf("update type flag: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("no pending type flag: %s", flagID)
+	}
+	return nil
+}
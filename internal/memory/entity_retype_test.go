@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestTypeCheckerAndRetype(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	rel := func(id, source, relType, target string) {
+		insertQueryEngineTestRelationship(t, db, id, source, &target, nil, relType, id, nil, nil)
+	}
+
+	// "Jordan" the colleague, extracted as an organization
+	insertQueryEngineTestEntity(t, db, "me", "Me", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "sam", "Sam", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "jordan", "Jordan", EntityTypeOrganization)
+	rel("r1", "me", "FRIEND_OF", "jordan")
+	rel("r2", "jordan", "SPOUSE_OF", "sam")
+	rel("r3", "me", "KNOWS", "jordan")
+
+	// A real company: people work at it, one stray KNOWS edge is outvoted
+	insertQueryEngineTestEntity(t, db, "acme", "Acme", EntityTypeOrganization)
+	rel("r4", "me", "WORKS_AT", "acme")
+	rel("r5", "sam", "WORKS_AT", "acme")
+	rel("r6", "sam", "KNOWS", "acme")
+
+	checker := NewTypeChecker(db)
+	dry, err := checker.Check(ctx, false)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(dry.Flagged) != 1 || dry.Created != 0 {
+		t.Fatalf("dry run = %+v, want one unrecorded flag", dry)
+	}
+	f := dry.Flagged[0]
+	if f.EntityID != "jordan" || f.SuggestedTypeID != EntityTypePerson || f.Evidence != 3 || f.Total != 3 || len(f.RelationTypes) != 3 {
+		t.Errorf("flag = %+v", f)
+	}
+
+	result, err := checker.Check(ctx, true)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if result.Created != 1 {
+		t.Fatalf("created = %d, want 1", result.Created)
+	}
+	if again, _ := checker.Check(ctx, true); again.Created != 0 {
+		t.Errorf("re-run created %d duplicate flags", again.Created)
+	}
+
+	flags, err := ListTypeFlags(ctx, db, TypeFlagStatusPending, 0)
+	if err != nil || len(flags) != 1 {
+		t.Fatalf("ListTypeFlags = %+v, %v", flags, err)
+	}
+	change, err := AcceptTypeFlag(ctx, db, flags[0].ID)
+	if err != nil {
+		t.Fatalf("AcceptTypeFlag: %v", err)
+	}
+	if change.OldTypeID != EntityTypeOrganization || change.NewTypeID != EntityTypePerson || change.FlagsResolved != 1 {
+		t.Errorf("change = %+v", change)
+	}
+	var typeID, locked int
+	db.QueryRow(`SELECT entity_type_id, type_locked FROM entities WHERE id = 'jordan'`).Scan(&typeID, &locked)
+	if typeID != EntityTypePerson || locked != 1 {
+		t.Errorf("jordan type = %d, locked = %d", typeID, locked)
+	}
+	if accepted, _ := ListTypeFlags(ctx, db, TypeFlagStatusAccepted, 0); len(accepted) != 1 {
+		t.Errorf("accepted flags = %+v", accepted)
+	}
+	if _, err := AcceptTypeFlag(ctx, db, flags[0].ID); err == nil {
+		t.Error("accepting a closed flag should fail")
+	}
+
+	// A hand-set type is never flagged, even against the evidence
+	if _, err := RetypeEntity(ctx, db, "jordan", EntityTypeOrganization); err != nil {
+		t.Fatalf("RetypeEntity: %v", err)
+	}
+	if after, _ := checker.Check(ctx, true); len(after.Flagged) != 0 {
+		t.Errorf("locked entity flagged: %+v", after.Flagged)
+	}
+
+	// Rejected suggestions are not made again
+	insertQueryEngineTestEntity(t, db, "paris", "Paris", EntityTypePerson)
+	rel("r7", "me", "LIVES_IN", "paris")
+	rel("r8", "sam", "BORN_IN", "paris")
+	if _, err := checker.Check(ctx, true); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	flags, _ = ListTypeFlags(ctx, db, TypeFlagStatusPending, 0)
+	if len(flags) != 1 || flags[0].EntityID != "paris" || flags[0].SuggestedTypeID != EntityTypeLocation {
+		t.Fatalf("pending = %+v", flags)
+	}
+	if err := RejectTypeFlag(ctx, db, flags[0].ID); err != nil {
+		t.Fatalf("RejectTypeFlag: %v", err)
+	}
+	if again, _ := checker.Check(ctx, true); again.Created != 0 {
+		t.Errorf("rejected suggestion recorded again")
+	}
+
+	if _, err := RetypeEntity(ctx, db, "paris", 99); err == nil {
+		t.Error("unknown type should fail")
+	}
+	if _, err := RetypeEntity(ctx, db, "missing", EntityTypePerson); err == nil {
+		t.Error("missing entity should fail")
+	}
+}
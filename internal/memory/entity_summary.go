@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SummaryMaxFacts is how many facts an entity summary includes.
+const SummaryMaxFacts = 5
+
+// RefreshEntitySummaries regenerates the summary of up to limit entities
+// whose summary is missing or older than their newest relationship. The
+// summary lists the entity's strongest currently valid facts. It returns how
+// many summaries were written.
+func RefreshEntitySummaries(ctx context.Context, db *sql.DB, limit int) (int, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT e.id FROM entities e
+		WHERE e.merged_into IS NULL
+		  AND EXISTS (
+		    SELECT 1 FROM relationships r
+		    WHERE r.source_entity_id = e.id AND r.invalid_at IS NULL
+		      AND (e.summary_updated_at IS NULL OR r.created_at > e.summary_updated_at)
+		  )
+		ORDER BY e.summary_updated_at IS NOT NULL, e.summary_updated_at
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("query stale summaries: %w", err)
+	}
+
+	// Collect first (SQLite concurrent query limitation)
+	var entityIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		entityIDs = append(entityIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	refreshed := 0
+	for _, entityID := range entityIDs {
+		summary, err := buildEntitySummary(ctx, db, entityID)
+		if err != nil {
+			return refreshed, err
+		}
+		now := time.Now().UTC().Format(time.RFC3339)
+		if _, err := db.ExecContext(ctx, `
+			UPDATE entities SET summary = ?, summary_updated_at = ? WHERE id = ?
+		`, summary, now, entityID); err != nil {
+			return refreshed, fmt.Errorf("update summary: %w", err)
+		}
+		refreshed++
+	}
+	return refreshed, nil
+}
+
+// buildEntitySummary joins the entity's strongest current facts into one
+// paragraph.
+func buildEntitySummary(ctx context.Context, db *sql.DB, entityID string) (string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT fact FROM relationships
+		WHERE source_entity_id = ? AND invalid_at IS NULL AND TRIM(fact) != ''
+		ORDER BY confidence DESC, created_at DESC
+		LIMIT ?
+	`, entityID, SummaryMaxFacts)
+	if err != nil {
+		return "", fmt.Errorf("query facts: %w", err)
+	}
+	defer rows.Close()
+
+	var facts []string
+	for rows.Next() {
+		var fact string
+		if err := rows.Scan(&fact); err != nil {
+			return "", err
+		}
+		fact = strings.TrimSpace(fact)
+		if !strings.HasSuffix(fact, ".") {
+			fact += "."
+		}
+		facts = append(facts, fact)
+	}
+	return strings.Join(facts, " "), rows.Err()
+}
@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestRefreshEntitySummaries(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	stmts := []string{
+		`INSERT INTO entities (id, canonical_name, entity_type_id, origin, summary, summary_updated_at, created_at, updated_at) VALUES
+			('e-casey', 'Casey', 1, 'extracted', NULL, NULL, '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z'),
+			('e-sam', 'Sam', 1, 'extracted', 'Sam is fresh.', '2026-02-01T00:00:00Z', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')`,
+		`INSERT INTO relationships (id, source_entity_id, target_literal, relation_type, fact, confidence, invalid_at, created_at) VALUES
+			('r1', 'e-casey', 'Denver', 'LIVES_IN', 'Casey lives in Denver', 0.9, NULL, '2026-01-01T00:00:00Z'),
+			('r2', 'e-casey', 'Acme', 'WORKS_AT', 'Casey works at Acme.', 0.7, NULL, '2026-01-01T00:00:00Z'),
+			('r3', 'e-casey', 'Boston', 'LIVES_IN', 'Casey lives in Boston', 1.0, '2025-06-01', '2026-01-01T00:00:00Z'),
+			('r4', 'e-sam', 'Acme', 'WORKS_AT', 'Sam works at Acme', 1.0, NULL, '2026-01-15T00:00:00Z')`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("setup: %v", err)
+		}
+	}
+
+	// Sam's summary is newer than Sam's facts
+	n, err := RefreshEntitySummaries(ctx, db, 10)
+	if err != nil || n != 1 {
+		t.Fatalf("RefreshEntitySummaries = %d, %v; want 1", n, err)
+	}
+	var summary string
+	if err := db.QueryRow(`SELECT summary FROM entities WHERE id = 'e-casey'`).Scan(&summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary != "Casey lives in Denver. Casey works at Acme." {
+		t.Errorf("summary = %q", summary)
+	}
+
+	if n, err := RefreshEntitySummaries(ctx, db, 10); err != nil || n != 0 {
+		t.Errorf("second refresh = %d, %v; want 0", n, err)
+	}
+}
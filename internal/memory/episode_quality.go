@@ -0,0 +1,177 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Reasons an episode is skipped by the quality gate.
+const (
+	SkipReasonLittleText    = "too_little_text"       // barely any text overall ("ok", "see you")
+	SkipReasonLinksOrEmoji  = "mostly_links_or_emoji" // link shares, reactions, emoji-only replies
+	SkipReasonShortMessages = "short_messages"        // many one-word messages ("lol", "yeah", "k")
+)
+
+// EpisodeQualityConfig sets when an episode carries too little information
+// to be worth an extraction call.
+type EpisodeQualityConfig struct {
+	// Episodes with fewer characters of text (links and emoji excluded) are skipped (default: 40)
+	MinChars int
+	// Episodes whose messages average fewer characters are skipped (default: 6)
+	MinAvgChars int
+	// Episodes where at least this share of messages are only links or emoji are skipped (default: 0.8)
+	MaxJunkRatio float64
+}
+
+// DefaultEpisodeQualityConfig returns the default quality thresholds.
+func DefaultEpisodeQualityConfig() EpisodeQualityConfig {
+	return EpisodeQualityConfig{
+		MinChars:     40,
+		MinAvgChars:  6,
+		MaxJunkRatio: 0.8,
+	}
+}
+
+// EpisodeQuality is an episode's information score and, when it falls
+// below the thresholds, why it is skipped.
+type EpisodeQuality struct {
+	Messages   int     `json:"messages"`
+	TextChars  int     `json:"text_chars"` // characters left after removing links
+	AvgChars   float64 `json:"avg_chars"`
+	JunkRatio  float64 `json:"junk_ratio"` // share of messages that are only links or emoji
+	Score      float64 `json:"score"`      // 0 (no information) to 1
+	SkipReason string  `json:"skip_reason,omitempty"`
+}
+
+// EpisodeQualityScorer scores episodes before extraction so low-information
+// ones can be skipped.
+type EpisodeQualityScorer struct {
+	db     *sql.DB
+	config EpisodeQualityConfig
+}
+
+// NewEpisodeQualityScorer creates a new EpisodeQualityScorer; zero config
+// fields take defaults.
+func NewEpisodeQualityScorer(db *sql.DB, config EpisodeQualityConfig) *EpisodeQualityScorer {
+	defaults := DefaultEpisodeQualityConfig()
+	if config.MinChars <= 0 {
+		config.MinChars = defaults.MinChars
+	}
+	if config.MinAvgChars <= 0 {
+		config.MinAvgChars = defaults.MinAvgChars
+	}
+	if config.MaxJunkRatio <= 0 {
+		config.MaxJunkRatio = defaults.MaxJunkRatio
+	}
+	return &EpisodeQualityScorer{db: db, config: config}
+}
+
+// Score rates an episode from its stored messages, falling back to the
+// lines of its content when it has no events (fixtures, memory-only DBs).
+func (s *EpisodeQualityScorer) Score(ctx context.Context, episode EpisodeInput) EpisodeQuality {
+	messages := s.episodeMessages(ctx, episode.ID)
+	if len(messages) == 0 {
+		messages = contentMessages(episode.Content)
+	}
+	return ScoreEpisodeQuality(messages, s.config)
+}
+
+func (s *EpisodeQualityScorer) episodeMessages(ctx context.Context, episodeID string) []string {
+	if s.db == nil {
+		return nil
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT ev.content
+		FROM episode_events ee
+		JOIN events ev ON ev.id = ee.event_id
+		WHERE ee.episode_id = ?
+		ORDER BY ee.position
+	`, episodeID)
+	if err != nil {
+		return nil // Non-fatal - score the content instead
+	}
+	defer rows.Close()
+
+	var messages []string
+	for rows.Next() {
+		var content sql.NullString
+		if err := rows.Scan(&content); err != nil {
+			return nil
+		}
+		if strings.TrimSpace(content.String) != "" {
+			messages = append(messages, content.String)
+		}
+	}
+	if rows.Err() != nil {
+		return nil
+	}
+	return messages
+}
+
+var (
+	urlPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+	// "[2024-01-02T10:00:00Z] " timestamps and "-> " reaction markers
+	contentLinePrefix = regexp.MustCompile(`^(?:\[[^\]]*\]\s*)*(?:->\s*)?`)
+)
+
+// contentMessages splits encoded episode content into messages, dropping
+// timestamp and "Sender: " prefixes.
+func contentMessages(content string) []string {
+	var messages []string
+	for _, line := range strings.Split(content, "\n") {
+		line = contentLinePrefix.ReplaceAllString(strings.TrimSpace(line), "")
+		if i := strings.Index(line, ": "); i > 0 && i <= 40 && !strings.Contains(line[:i], "/") {
+			line = line[i+2:]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			messages = append(messages, line)
+		}
+	}
+	return messages
+}
+
+// ScoreEpisodeQuality scores a list of messages against the thresholds.
+func ScoreEpisodeQuality(messages []string, config EpisodeQualityConfig) EpisodeQuality {
+	q := EpisodeQuality{Messages: len(messages)}
+	junk := 0
+	for _, msg := range messages {
+		text := strings.TrimSpace(urlPattern.ReplaceAllString(msg, ""))
+		if !hasLetterOrDigit(text) {
+			junk++
+			continue
+		}
+		q.TextChars += utf8.RuneCountInString(text)
+	}
+	if q.Messages > 0 {
+		q.AvgChars = float64(q.TextChars) / float64(q.Messages)
+		q.JunkRatio = float64(junk) / float64(q.Messages)
+	}
+
+	amount := math.Min(1, float64(q.TextChars)/float64(4*config.MinChars))
+	density := math.Min(1, q.AvgChars/float64(4*config.MinAvgChars))
+	q.Score = math.Round((1-q.JunkRatio)*amount*density*100) / 100
+
+	switch {
+	case q.TextChars < config.MinChars:
+		q.SkipReason = SkipReasonLittleText
+	case q.JunkRatio >= config.MaxJunkRatio:
+		q.SkipReason = SkipReasonLinksOrEmoji
+	case q.AvgChars < float64(config.MinAvgChars):
+		q.SkipReason = SkipReasonShortMessages
+	}
+	return q
+}
+
+func hasLetterOrDigit(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
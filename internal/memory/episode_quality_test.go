@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestScoreEpisodeQuality(t *testing.T) {
+	config := DefaultEpisodeQualityConfig()
+	tests := []struct {
+		name     string
+		messages []string
+		reason   string
+	}{
+		{"lone ok", []string{"ok"}, SkipReasonLittleText},
+		{"link shares", []string{"https://example.com/a-very-long-article-slug", "😂😂", "www.example.org/thread", "👍", "check this out, it is about the new office in Denver"}, SkipReasonLinksOrEmoji},
+		{"one-word replies", []string{"lol", "yeah", "k", "haha", "sure", "ok", "yep", "nice", "cool", "omg", "wow", "true", "same", "lmao"}, SkipReasonShortMessages},
+		{"real conversation", []string{"Are you still starting at Anthropic in January?", "Yes! Moving to SF the week before"}, ""},
+		{"single email", []string{"Hi Tyler, following up on the lease for 12 Main St. The landlord agreed to the March start date."}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := ScoreEpisodeQuality(tt.messages, config)
+			if q.SkipReason != tt.reason {
+				t.Errorf("skip reason = %q, want %q (%+v)", q.SkipReason, tt.reason, q)
+			}
+			if tt.reason == "" && q.Score < 0.5 {
+				t.Errorf("score = %.2f for an informative episode", q.Score)
+			}
+		})
+	}
+
+	lines := contentMessages("[2024-01-02T10:00:00Z] Tyler: https://example.com/x\n[2024-01-02T10:01:00Z] -> Sam reacted 👍")
+	if len(lines) != 2 || lines[0] != "https://example.com/x" || lines[1] != "Sam reacted 👍" {
+		t.Errorf("content messages = %q", lines)
+	}
+}
+
+func TestPipelineQualityGate(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.Exec(`
+		INSERT INTO threads (id, channel, source_adapter, source_id, created_at, updated_at) VALUES ('t1', 'imessage', 'imessage', 't1', 0, 0);
+		INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0);
+		INSERT INTO episodes (id, definition_id, channel, thread_id, start_time, end_time, event_count, created_at) VALUES ('ep-junk', 'def', 'imessage', 't1', 0, 0, 2, 0);
+	`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	quality := DefaultEpisodeQualityConfig()
+	pipeline := NewMemoryPipeline(db, nil, &PipelineConfig{SkipEmbeddings: true, QualityGate: &quality})
+
+	result, err := pipeline.Process(ctx, EpisodeInput{
+		ID:        "ep-junk",
+		Channel:   "imessage",
+		Content:   "[2024-01-02T10:00:00Z] Sam: https://example.com/x\n[2024-01-02T10:01:00Z] Tyler: 😂",
+		StartTime: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if !result.Skipped || result.SkipReason != SkipReasonLittleText || result.LLMCalls != 0 {
+		t.Fatalf("result = %+v, want skipped for too little text", result)
+	}
+
+	skipped, err := NewProcessingLog(db).SkippedEpisodes(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("SkippedEpisodes: %v", err)
+	}
+	if len(skipped) != 1 || skipped[0].EpisodeID != "ep-junk" || skipped[0].SkipReason != SkipReasonLittleText ||
+		skipped[0].QualityScore == nil || skipped[0].Status != ProcessingStatusSkipped {
+		t.Errorf("skipped episodes = %+v", skipped)
+	}
+	if top, _ := NewProcessingLog(db).TopEpisodes(ctx, 10); len(top) != 0 {
+		t.Errorf("skipped episode counted in cost report: %+v", top)
+	}
+}
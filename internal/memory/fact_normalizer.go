@@ -0,0 +1,245 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// relationFactPhrases is the canonical wording of each relation type, placed
+// between the source and target: "<source> works at <target>". Types not
+// listed are phrased from their name ("EMPLOYED_BY" -> "employed by").
+var relationFactPhrases = map[string]string{
+	// Personal and social
+	"KNOWS":          "knows",
+	"FRIEND_OF":      "is friends with",
+	"SPOUSE_OF":      "is the spouse of",
+	"MARRIED_TO":     "is married to",
+	"DATING":         "is dating",
+	"PARENT_OF":      "is a parent of",
+	"CHILD_OF":       "is a child of",
+	"SIBLING_OF":     "is a sibling of",
+	"GRANDPARENT_OF": "is a grandparent of",
+	"GRANDCHILD_OF":  "is a grandchild of",
+	"HAS_PET":        "has a pet named",
+
+	// Professional
+	"WORKS_AT":    "works at",
+	"MEMBER_OF":   "is a member of",
+	"OWNS":        "owns",
+	"FOUNDED":     "founded",
+	"CUSTOMER_OF": "is a customer of",
+	"USES":        "uses",
+	"ATTENDED":    "attended",
+
+	// Places
+	"BORN_IN":          "was born in",
+	"LIVES_IN":         "lives in",
+	"HEADQUARTERED_IN": "is headquartered in",
+	"LOCATED_IN":       "is located in",
+	"VISITED":          "visited",
+
+	// Dates (the target is the date)
+	"BORN_ON":        "was born on",
+	"DIED_ON":        "died on",
+	"ANNIVERSARY_ON": "has an anniversary on",
+	"OCCURRED_ON":    "occurred on",
+	"SCHEDULED_FOR":  "is scheduled for",
+	"STARTED_ON":     "started on",
+	"ENDED_ON":       "ended on",
+
+	// Legal
+	"SUED_BY":             "was sued by",
+	"DEFENDANT_IN":        "is a defendant in",
+	"PLAINTIFF_IN":        "is a plaintiff in",
+	"FILED_BANKRUPTCY_IN": "filed for bankruptcy in",
+
+	// Projects, events and content
+	"CREATED":        "created",
+	"BUILDING":       "is building",
+	"WORKING_ON":     "is working on",
+	"CONTRIBUTED_TO": "contributed to",
+	"HOSTED":         "hosted",
+	"AUTHORED":       "authored",
+	"REFERENCES":     "references",
+
+	// Financial
+	"WIRED_TO":      "wired money to",
+	"RECEIVED_FROM": "received money from",
+
+	// Preferences
+	"PREFERS":  "prefers",
+	"DISLIKES": "dislikes",
+	"WANTS":    "wants",
+	"HAS_SIZE": "wears size",
+}
+
+// relationFactPhrase returns the canonical wording of a relation type.
+func relationFactPhrase(relType string) string {
+	if phrase, ok := relationFactPhrases[relType]; ok {
+		return phrase
+	}
+	return strings.ToLower(strings.ReplaceAll(relType, "_", " "))
+}
+
+// NormalizeFactText phrases a fact with its relation type's template:
+// "<source> works at <target> since <valid_at>", "... until <invalid_at>",
+// or "... from <valid_at> until <invalid_at>". Date relations (BORN_ON, ...)
+// already name their date and get no suffix.
+func NormalizeFactText(source, relType, target string, validAt, invalidAt *string) string {
+	fact := source + " " + relationFactPhrase(relType) + " " + target
+	if isTemporalRelationType(relType) {
+		return fact
+	}
+	from, until := factDate(validAt), factDate(invalidAt)
+	switch {
+	case from != "" && until != "":
+		fact += " from " + from + " until " + until
+	case from != "":
+		fact += " since " + from
+	case until != "":
+		fact += " until " + until
+	}
+	return fact
+}
+
+// factDate shortens a stored date for fact text: full timestamps become
+// their day, partial dates ("2024-03") are kept as they are.
+func factDate(s *string) string {
+	if s == nil {
+		return ""
+	}
+	date := strings.TrimSpace(*s)
+	if t, err := time.Parse(time.RFC3339, date); err == nil {
+		return t.Format("2006-01-02")
+	}
+	if len(date) > 10 && date[10] == 'T' {
+		return date[:10]
+	}
+	return date
+}
+
+// normalizeResolvedFact rewrites a new relationship's fact into its
+// canonical template, looking up the endpoint names. The extracted wording
+// is kept on the mention. If a name can't be found the fact is left as is.
+func normalizeResolvedFact(ctx context.Context, db *sql.DB, rel *ResolvedRelationship) string {
+	source, ok := entityCanonicalName(ctx, db, rel.SourceEntityID)
+	if !ok {
+		return rel.Fact
+	}
+	var target string
+	switch {
+	case rel.TargetEntityID != nil:
+		if target, ok = entityCanonicalName(ctx, db, *rel.TargetEntityID); !ok {
+			return rel.Fact
+		}
+	case rel.TargetLiteral != nil:
+		target = *rel.TargetLiteral
+	default:
+		return rel.Fact
+	}
+	return NormalizeFactText(source, rel.RelationType, target, rel.ValidAt, rel.InvalidAt)
+}
+
+func entityCanonicalName(ctx context.Context, db *sql.DB, id string) (string, bool) {
+	var name string
+	if err := db.QueryRowContext(ctx, `SELECT canonical_name FROM entities WHERE id = ?`, id).Scan(&name); err != nil {
+		return "", false
+	}
+	return name, strings.TrimSpace(name) != ""
+}
+
+// FactNormalizeOptions controls a fact normalization pass.
+type FactNormalizeOptions struct {
+	DryRun bool // report the rewrites without applying them
+	Limit  int  // rewrite at most this many facts (0 = all)
+}
+
+// FactRewrite is one fact rewritten into its canonical template.
+type FactRewrite struct {
+	RelationshipID string `json:"relationship_id"`
+	RelationType   string `json:"relation_type"`
+	Before         string `json:"before"`
+	After          string `json:"after"`
+}
+
+// FactNormalizeResult reports a fact normalization pass.
+type FactNormalizeResult struct {
+	Checked  int           `json:"checked"`
+	Rewrites []FactRewrite `json:"rewrites"`
+	DryRun   bool          `json:"dry_run,omitempty"`
+}
+
+// NormalizeFacts rewrites existing extracted facts into their canonical
+// templates. Only facts with at least one mention are rewritten, since the
+// mention keeps the original wording; manual facts are the user's own words
+// and are never touched.
+func NormalizeFacts(ctx context.Context, db *sql.DB, opts FactNormalizeOptions) (*FactNormalizeResult, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT r.id, r.relation_type, r.fact, r.valid_at, r.invalid_at,
+		       s.canonical_name, COALESCE(t.canonical_name, r.target_literal)
+		FROM relationships r
+		JOIN entities s ON s.id = r.source_entity_id
+		LEFT JOIN entities t ON t.id = r.target_entity_id
+		WHERE r.origin IS NULL
+		  AND EXISTS (SELECT 1 FROM episode_relationship_mentions m WHERE m.relationship_id = r.id)
+		ORDER BY r.created_at, r.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query facts: %w", err)
+	}
+	defer rows.Close()
+
+	result := &FactNormalizeResult{Rewrites: []FactRewrite{}, DryRun: opts.DryRun}
+	for rows.Next() {
+		var rw FactRewrite
+		var validAt, invalidAt, target sql.NullString
+		var source string
+		if err := rows.Scan(&rw.RelationshipID, &rw.RelationType, &rw.Before, &validAt, &invalidAt, &source, &target); err != nil {
+			return nil, fmt.Errorf("scan fact: %w", err)
+		}
+		result.Checked++
+		if !target.Valid || target.String == "" {
+			continue
+		}
+		var from, until *string
+		if validAt.Valid {
+			from = &validAt.String
+		}
+		if invalidAt.Valid {
+			until = &invalidAt.String
+		}
+		rw.After = NormalizeFactText(source, rw.RelationType, target.String, from, until)
+		if rw.After == rw.Before {
+			continue
+		}
+		result.Rewrites = append(result.Rewrites, rw)
+		if opts.Limit > 0 && len(result.Rewrites) >= opts.Limit {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if opts.DryRun || len(result.Rewrites) == 0 {
+		return result, nil
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	for _, rw := range result.Rewrites {
+		if _, err := tx.ExecContext(ctx, `UPDATE relationships SET fact = ? WHERE id = ?`, rw.After, rw.RelationshipID); err != nil {
+			return nil, fmt.Errorf("rewrite fact %s: %w", rw.RelationshipID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
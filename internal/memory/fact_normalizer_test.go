@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestNormalizeFactText(t *testing.T) {
+	date := func(s string) *string { return &s }
+	tests := []struct {
+		relType            string
+		target             string
+		validAt, invalidAt *string
+		want               string
+	}{
+		{"WORKS_AT", "Anthropic", date("2024-01-15T00:00:00Z"), nil, "Tyler works at Anthropic since 2024-01-15"},
+		{"WORKS_AT", "Intent Systems", date("2021-03"), date("2023-12"), "Tyler works at Intent Systems from 2021-03 until 2023-12"},
+		{"LIVES_IN", "Austin", nil, date("2022"), "Tyler lives in Austin until 2022"},
+		{"FRIEND_OF", "Casey", nil, nil, "Tyler is friends with Casey"},
+		{"BORN_ON", "1990-05-01", date("1990-05-01"), nil, "Tyler was born on 1990-05-01"},
+		{"EMPLOYED_BY", "Acme", nil, nil, "Tyler employed by Acme"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeFactText("Tyler", tt.relType, tt.target, tt.validAt, tt.invalidAt); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.relType, got, tt.want)
+		}
+	}
+}
+
+func TestFactNormalization(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertQueryEngineTestEntity(t, db, "tyler", "Tyler", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "anthropic", "Anthropic", EntityTypeCompany)
+	insertQueryEngineTestEntity(t, db, "austin", "Austin", EntityTypeLocation)
+	if _, err := db.Exec(`
+		INSERT INTO threads (id, channel, source_adapter, source_id, created_at, updated_at) VALUES ('t1', 'imessage', 'imessage', 't1', 0, 0);
+		INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0);
+		INSERT INTO episodes (id, definition_id, channel, thread_id, start_time, end_time, event_count, created_at) VALUES ('ep1', 'def', 'imessage', 't1', 0, 0, 1, 0);
+	`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	// New extracted facts are stored in canonical form; the mention keeps the wording
+	target := 1
+	validAt := "2024-01-15"
+	resolved := []ResolvedEntity{
+		{ID: "tyler", Name: "Tyler", EntityTypeID: EntityTypePerson},
+		{ID: "anthropic", Name: "Anthropic", EntityTypeID: EntityTypeCompany},
+	}
+	if _, err := NewEdgeResolver(db).Resolve(ctx, "ep1", []ExtractedRelationship{{
+		SourceEntityID: 0, RelationType: "WORKS_AT", TargetEntityID: &target,
+		Fact: "Tyler started his new job at Anthropic in mid-January", SourceType: "self_disclosed", ValidAt: &validAt,
+	}}, resolved); err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	var fact, extracted string
+	db.QueryRow(`SELECT fact FROM relationships WHERE relation_type = 'WORKS_AT'`).Scan(&fact)
+	db.QueryRow(`SELECT extracted_fact FROM episode_relationship_mentions`).Scan(&extracted)
+	if fact != "Tyler works at Anthropic since 2024-01-15" || extracted != "Tyler started his new job at Anthropic in mid-January" {
+		t.Errorf("fact = %q, mention = %q", fact, extracted)
+	}
+
+	// Older free-text facts are rewritten by the backfill; facts without a
+	// mention to keep their wording, and manual facts, are left alone
+	austin := "austin"
+	insertCurrentFactsRel(t, db, "old", "tyler", &austin, nil, "LIVES_IN", "", nil)
+	insertCurrentFactsRel(t, db, "unmentioned", "tyler", &austin, nil, "VISITED", "", nil)
+	insertCurrentFactsRel(t, db, "manual", "tyler", &austin, nil, "BORN_IN", "", nil)
+	if _, err := db.Exec(`
+		UPDATE relationships SET origin = 'manual' WHERE id = 'manual';
+		INSERT INTO episode_relationship_mentions (id, episode_id, relationship_id, extracted_fact, created_at) VALUES
+			('m-old', 'ep1', 'old', 'LIVES_IN fact', 'x'), ('m-manual', 'ep1', 'manual', 'BORN_IN fact', 'x');
+	`); err != nil {
+		t.Fatalf("seed facts: %v", err)
+	}
+
+	dry, err := NormalizeFacts(ctx, db, FactNormalizeOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("NormalizeFacts dry run: %v", err)
+	}
+	if dry.Checked != 2 || len(dry.Rewrites) != 1 || dry.Rewrites[0].RelationshipID != "old" ||
+		dry.Rewrites[0].After != "Tyler lives in Austin" {
+		t.Fatalf("dry run = %+v", dry)
+	}
+	db.QueryRow(`SELECT fact FROM relationships WHERE id = 'old'`).Scan(&fact)
+	if fact != "LIVES_IN fact" {
+		t.Errorf("dry run rewrote the fact: %q", fact)
+	}
+
+	if res, err := NormalizeFacts(ctx, db, FactNormalizeOptions{}); err != nil || len(res.Rewrites) != 1 {
+		t.Fatalf("NormalizeFacts = %+v, %v", res, err)
+	}
+	for id, want := range map[string]string{"old": "Tyler lives in Austin", "unmentioned": "VISITED fact", "manual": "BORN_IN fact"} {
+		db.QueryRow(`SELECT fact FROM relationships WHERE id = ?`, id).Scan(&fact)
+		if fact != want {
+			t.Errorf("%s fact = %q, want %q", id, fact, want)
+		}
+	}
+	if again, _ := NormalizeFacts(ctx, db, FactNormalizeOptions{}); len(again.Rewrites) != 0 {
+		t.Errorf("second pass rewrote %+v", again.Rewrites)
+	}
+}
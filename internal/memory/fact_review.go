@@ -0,0 +1,253 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Review spreadsheet actions. A blank action leaves the row alone.
+const (
+	ReviewActionKeep   = "keep"   // the fact is right as is
+	ReviewActionFix    = "fix"    // apply the edited target, fact and date cells
+	ReviewActionDelete = "delete" // the fact is wrong; remove it
+)
+
+// factReviewColumns is the review CSV header. Reviewers fill in action (and
+// note) and, for fixes, edit target, fact, valid_at or invalid_at in place.
+var factReviewColumns = []string{
+	"id", "source", "relation_type", "target", "fact", "valid_at", "invalid_at", "confidence", "action", "note",
+}
+
+// DefaultReviewMaxConfidence is the confidence below which facts are
+// exported for review.
+const DefaultReviewMaxConfidence = 0.7
+
+// FactReviewExportOptions selects facts for a review spreadsheet.
+type FactReviewExportOptions struct {
+	MaxConfidence float64 // export facts below this confidence
+	Limit         int     // 0 = no limit
+	// IncludeReviewed also exports facts already labeled in an earlier review
+	// (or a calibration spot-check).
+	IncludeReviewed bool
+}
+
+// ExportFactsForReview writes low-confidence facts as CSV, least confident
+// first, and returns how many rows were written. Manual facts are never
+// exported: they are already ground truth.
+func ExportFactsForReview(ctx context.Context, db *sql.DB, w io.Writer, opts FactReviewExportOptions) (int, error) {
+	if opts.MaxConfidence <= 0 {
+		opts.MaxConfidence = DefaultReviewMaxConfidence
+	}
+	query := `
+		SELECT r.id, s.canonical_name, r.relation_type, COALESCE(t.canonical_name, r.target_literal, ''),
+		       r.fact, COALESCE(r.valid_at, ''), COALESCE(r.invalid_at, ''), COALESCE(r.confidence, 1.0)
+		FROM relationships r
+		JOIN entities s ON s.id = r.source_entity_id
+		LEFT JOIN entities t ON t.id = r.target_entity_id
+		WHERE COALESCE(r.confidence, 1.0) < ?
+		  AND COALESCE(r.origin, '') != ?
+	`
+	args := []interface{}{opts.MaxConfidence, OriginManual}
+	if !opts.IncludeReviewed {
+		query += ` AND NOT EXISTS (SELECT 1 FROM relationship_labels l WHERE l.relationship_id = r.id)`
+	}
+	query += ` ORDER BY COALESCE(r.confidence, 1.0), r.created_at, r.id`
+	if opts.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, opts.Limit)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("query facts: %w", err)
+	}
+	defer rows.Close()
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(factReviewColumns); err != nil {
+		return 0, err
+	}
+	n := 0
+	for rows.Next() {
+		var id, source, relType, target, fact, validAt, invalidAt string
+		var confidence float64
+		if err := rows.Scan(&id, &source, &relType, &target, &fact, &validAt, &invalidAt, &confidence); err != nil {
+			return n, fmt.Errorf("scan fact: %w", err)
+		}
+		record := []string{id, source, relType, target, fact, validAt, invalidAt,
+			strconv.FormatFloat(confidence, 'f', 2, 64), "", ""}
+		if err := cw.Write(record); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+	cw.Flush()
+	return n, cw.Error()
+}
+
+// FactReviewRowError is a spreadsheet row that could not be applied.
+type FactReviewRowError struct {
+	Line    int    `json:"line"`
+	ID      string `json:"id,omitempty"`
+	Message string `json:"message"`
+}
+
+// FactReviewImportResult summarizes an imported review spreadsheet.
+type FactReviewImportResult struct {
+	Rows    int                  `json:"rows"`
+	Kept    int                  `json:"kept"`
+	Fixed   int                  `json:"fixed"`
+	Deleted int                  `json:"deleted"`
+	Skipped int                  `json:"skipped"` // blank action
+	Errors  []FactReviewRowError `json:"errors,omitempty"`
+	DryRun  bool                 `json:"dry_run,omitempty"`
+}
+
+// ImportFactReview applies an annotated review spreadsheet produced by
+// ExportFactsForReview. Columns are matched by header name, so reordered or
+// extra columns are fine; id and action are required. Kept facts are
+// labeled correct; fixed facts are labeled incorrect and then corrected
+// with EditFact, which marks them manual; deleted facts are removed. Rows
+// that fail are reported and the rest still apply. With dryRun, actions
+// and fact IDs are checked but nothing is written.
+func ImportFactReview(ctx context.Context, db *sql.DB, r io.Reader, dryRun bool) (*FactReviewImportResult, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"id", "action"} {
+		if _, ok := col[required]; !ok {
+			return nil, fmt.Errorf("missing %q column", required)
+		}
+	}
+
+	// Read every row first: applying them writes to the database
+	type reviewRow struct {
+		line   int
+		record []string
+	}
+	var records []reviewRow
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read spreadsheet: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		records = append(records, reviewRow{line: line, record: record})
+	}
+
+	result := &FactReviewImportResult{DryRun: dryRun}
+	calibration := NewCalibration(db)
+	for _, row := range records {
+		cell := func(name string) (string, bool) {
+			i, ok := col[name]
+			if !ok || i >= len(row.record) {
+				return "", false
+			}
+			return strings.TrimSpace(row.record[i]), true
+		}
+		id, _ := cell("id")
+		action, _ := cell("action")
+		note, _ := cell("note")
+		if id == "" && action == "" {
+			continue
+		}
+		result.Rows++
+		fail := func(err error) {
+			result.Errors = append(result.Errors, FactReviewRowError{Line: row.line, ID: id, Message: err.Error()})
+		}
+
+		action = strings.ToLower(action)
+		if action == "" {
+			result.Skipped++
+			continue
+		}
+		rel, err := GetFact(ctx, db, id)
+		if err != nil {
+			fail(err)
+			continue
+		}
+
+		switch action {
+		case ReviewActionKeep:
+			if !dryRun {
+				if err := calibration.Label(ctx, id, true, note); err != nil {
+					fail(err)
+					continue
+				}
+			}
+			result.Kept++
+
+		case ReviewActionFix:
+			edit := reviewEdit(rel, cell)
+			if edit == (FactEdit{}) {
+				fail(fmt.Errorf("fix without changes: edit the target, fact, valid_at or invalid_at cell"))
+				continue
+			}
+			if !dryRun {
+				if err := calibration.Label(ctx, id, false, note); err != nil {
+					fail(err)
+					continue
+				}
+				if _, err := EditFact(ctx, db, id, edit); err != nil {
+					// Unlabel so the next export offers the fact again
+					_, _ = db.ExecContext(ctx, `DELETE FROM relationship_labels WHERE relationship_id = ?`, id)
+					fail(err)
+					continue
+				}
+			}
+			result.Fixed++
+
+		case ReviewActionDelete:
+			if !dryRun {
+				if err := DeleteFact(ctx, db, id); err != nil {
+					fail(err)
+					continue
+				}
+			}
+			result.Deleted++
+
+		default:
+			fail(fmt.Errorf("unknown action %q (want keep, fix or delete)", action))
+		}
+	}
+	return result, nil
+}
+
+// reviewEdit returns the changes between a fact and its edited spreadsheet
+// cells. Missing columns count as unchanged.
+func reviewEdit(rel *EntityRelationship, cell func(string) (string, bool)) FactEdit {
+	var edit FactEdit
+	changed := func(name, current string) *string {
+		if v, ok := cell(name); ok && v != current {
+			return &v
+		}
+		return nil
+	}
+	target := derefString(rel.TargetLiteral)
+	if rel.TargetName != nil {
+		target = *rel.TargetName
+	}
+	edit.Target = changed("target", target)
+	edit.Fact = changed("fact", rel.Fact)
+	edit.ValidAt = changed("valid_at", derefString(rel.ValidAt))
+	edit.InvalidAt = changed("invalid_at", derefString(rel.InvalidAt))
+	return edit
+}
@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestFactReviewRoundTrip(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	for _, stmt := range []string{
+		`INSERT INTO entities (id, canonical_name, entity_type_id, origin, created_at, updated_at) VALUES
+			('tyler', 'Tyler', 1, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z'),
+			('jane', 'Jane', 1, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z'),
+			('acme', 'Acme', 2, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z'),
+			('anthropic', 'Anthropic', 2, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')`,
+		`INSERT INTO relationships (id, source_entity_id, target_entity_id, target_literal, relation_type, fact, valid_at, confidence, origin, created_at) VALUES
+			('r-keep', 'tyler', 'jane', NULL, 'KNOWS', 'Tyler knows Jane', NULL, 0.5, NULL, '2026-01-01T00:00:00Z'),
+			('r-fix', 'tyler', 'acme', NULL, 'WORKS_AT', 'Tyler works at Acme', '2025', 0.4, NULL, '2026-01-01T00:00:00Z'),
+			('r-delete', 'jane', 'tyler', NULL, 'SPOUSE_OF', 'Jane is married to Tyler', NULL, 0.3, NULL, '2026-01-01T00:00:00Z'),
+			('r-bad', 'jane', NULL, '1990', 'BORN_ON', 'Jane was born in 1990', NULL, 0.6, NULL, '2026-01-01T00:00:00Z'),
+			('r-skip', 'jane', 'acme', NULL, 'WORKS_AT', 'Jane works at Acme', NULL, 0.65, NULL, '2026-01-01T00:00:00Z'),
+			('r-sure', 'jane', 'anthropic', NULL, 'MEMBER_OF', 'Jane is in the Anthropic book club', NULL, 0.9, NULL, '2026-01-01T00:00:00Z'),
+			('r-manual', 'tyler', NULL, '1988-02-03', 'BORN_ON', 'Tyler was born on 1988-02-03', NULL, 0.2, 'manual', '2026-01-01T00:00:00Z')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	n, err := ExportFactsForReview(ctx, db, &buf, FactReviewExportOptions{})
+	if err != nil {
+		t.Fatalf("ExportFactsForReview: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 || len(records) != 6 || records[1][0] != "r-delete" || records[5][0] != "r-skip" {
+		t.Fatalf("exported %d rows: %v", n, records)
+	}
+
+	// Annotate the way a reviewer would in a spreadsheet
+	for _, rec := range records[1:] {
+		switch rec[0] {
+		case "r-keep":
+			rec[8] = "keep"
+		case "r-fix":
+			rec[3], rec[5], rec[8], rec[9] = "Anthropic", "2026-01", "Fix", "wrong employer"
+		case "r-delete":
+			rec[8] = "delete"
+		case "r-bad":
+			rec[8] = "fix" // nothing edited
+		}
+	}
+	records = append(records,
+		[]string{"r-gone", "", "", "", "", "", "", "", "keep", ""},
+		[]string{"r-keep", "", "", "", "", "", "", "", "approve", ""},
+	)
+	var annotated bytes.Buffer
+	w := csv.NewWriter(&annotated)
+	w.WriteAll(records)
+
+	dry, err := ImportFactReview(ctx, db, bytes.NewReader(annotated.Bytes()), true)
+	if err != nil {
+		t.Fatalf("ImportFactReview dry run: %v", err)
+	}
+	var labels int
+	db.QueryRow(`SELECT COUNT(*) FROM relationship_labels`).Scan(&labels)
+	if dry.Kept != 1 || dry.Fixed != 1 || dry.Deleted != 1 || labels != 0 {
+		t.Errorf("dry run = %+v, %d labels written", dry, labels)
+	}
+
+	res, err := ImportFactReview(ctx, db, bytes.NewReader(annotated.Bytes()), false)
+	if err != nil {
+		t.Fatalf("ImportFactReview: %v", err)
+	}
+	if res.Rows != 7 || res.Kept != 1 || res.Fixed != 1 || res.Deleted != 1 || res.Skipped != 1 || len(res.Errors) != 3 {
+		t.Errorf("import = %+v", res)
+	}
+	for _, e := range res.Errors {
+		if e.Line < 2 {
+			t.Errorf("error without a spreadsheet line: %+v", e)
+		}
+	}
+
+	fixed, err := GetFact(ctx, db, "r-fix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var origin, note string
+	var correct bool
+	db.QueryRow(`SELECT origin FROM relationships WHERE id = 'r-fix'`).Scan(&origin)
+	db.QueryRow(`SELECT correct, note FROM relationship_labels WHERE relationship_id = 'r-fix'`).Scan(&correct, &note)
+	if *fixed.TargetName != "Anthropic" || *fixed.ValidAt != "2026-01" || origin != OriginManual || correct || note != "wrong employer" {
+		t.Errorf("fixed = %+v, origin %q, label correct=%v note %q", fixed, origin, correct, note)
+	}
+	db.QueryRow(`SELECT correct FROM relationship_labels WHERE relationship_id = 'r-keep'`).Scan(&correct)
+	if !correct {
+		t.Error("kept fact not labeled correct")
+	}
+	if _, err := GetFact(ctx, db, "r-delete"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("deleted fact: %v", err)
+	}
+
+	// Reviewed facts are not exported again
+	buf.Reset()
+	if n, _ := ExportFactsForReview(ctx, db, &buf, FactReviewExportOptions{}); n != 2 {
+		t.Errorf("second export has %d rows, want the untouched 2:\n%s", n, buf.String())
+	}
+
+	if _, err := ImportFactReview(ctx, db, strings.NewReader("id,source\nr-keep,Tyler\n"), false); err == nil {
+		t.Error("a spreadsheet without an action column was accepted")
+	}
+}
@@ -0,0 +1,48 @@
+package memory
+
+import "github.com/Napageneral/mnemonic/internal/gemini"
+
+// GenerationParams are the sampling settings for one pipeline stage's LLM
+// calls. Unset fields leave the model's default in place.
+type GenerationParams struct {
+	Temperature      *float64 // Lower is more deterministic; extraction wants it low
+	TopP             *float64
+	MaxOutputTokens  int    // 0 uses the model's limit
+	ResponseMimeType string // default: application/json (every stage parses JSON)
+}
+
+// DefaultEntityGenerationParams returns the entity extraction (and
+// gleaning) settings: near-deterministic, so names are copied verbatim.
+func DefaultEntityGenerationParams() GenerationParams {
+	return GenerationParams{Temperature: float64Ptr(0.1)}
+}
+
+// DefaultRelationshipGenerationParams returns the relationship extraction
+// settings: low temperature, leaving a little room to phrase facts.
+func DefaultRelationshipGenerationParams() GenerationParams {
+	return GenerationParams{Temperature: float64Ptr(0.2)}
+}
+
+// DefaultCritiqueGenerationParams returns the self-critique settings: the
+// review should give the same verdict every time.
+func DefaultCritiqueGenerationParams() GenerationParams {
+	return GenerationParams{Temperature: float64Ptr(0)}
+}
+
+// generationConfig builds the request's generation config.
+func (p GenerationParams) generationConfig() *gemini.GenerationConfig {
+	mimeType := p.ResponseMimeType
+	if mimeType == "" {
+		mimeType = "application/json"
+	}
+	return &gemini.GenerationConfig{
+		Temperature:      p.Temperature,
+		TopP:             p.TopP,
+		MaxOutputTokens:  p.MaxOutputTokens,
+		ResponseMimeType: mimeType,
+	}
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,33 @@
+package memory
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGenerationParams(t *testing.T) {
+	// Zero params keep the model defaults and still ask for JSON
+	raw, _ := json.Marshal(GenerationParams{}.generationConfig())
+	if string(raw) != `{"responseMimeType":"application/json"}` {
+		t.Errorf("zero params = %s", raw)
+	}
+
+	topP := 0.9
+	raw, _ = json.Marshal(GenerationParams{Temperature: float64Ptr(0), TopP: &topP, MaxOutputTokens: 2048, ResponseMimeType: "text/plain"}.generationConfig())
+	if string(raw) != `{"temperature":0,"topP":0.9,"maxOutputTokens":2048,"responseMimeType":"text/plain"}` {
+		t.Errorf("params = %s", raw)
+	}
+
+	db := setupPipelineTestDB(t)
+	defer db.Close()
+	config := DefaultPipelineConfig()
+	config.SkipEmbeddings = true
+	config.RelationshipGeneration.MaxOutputTokens = 4096
+	p := NewMemoryPipeline(db, nil, config)
+	if got := p.entityExtractor.params.Temperature; got == nil || *got != 0.1 {
+		t.Errorf("entity temperature = %v", got)
+	}
+	if got := p.relationshipExtractor.params; got.MaxOutputTokens != 4096 || got.Temperature == nil || *got.Temperature != 0.2 {
+		t.Errorf("relationship params = %+v", got)
+	}
+}
@@ -0,0 +1,713 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// GeoLevel is a place's level in the location hierarchy.
+type GeoLevel string
+
+const (
+	GeoLevelCity    GeoLevel = "city"
+	GeoLevelRegion  GeoLevel = "region" // State, province
+	GeoLevelCountry GeoLevel = "country"
+)
+
+// RelationLocatedIn links a location to its parent (Austin → Texas → United States).
+const RelationLocatedIn = "LOCATED_IN"
+
+// ReasonGeoNormalization is the merge_candidates reason for location entities
+// that normalize to the same place ("Austin" vs "Austin TX" vs "ATX").
+const ReasonGeoNormalization = "geo_normalization"
+
+// GeoNormalizationConfidence is the merge candidate confidence for two
+// location entities that normalize to the same gazetteer place.
+const GeoNormalizationConfidence = 0.95
+
+// GeoPlace is a normalized place.
+type GeoPlace struct {
+	Name   string   `json:"name"`             // Canonical name ("Austin")
+	Level  GeoLevel `json:"level"`            // city, region, country
+	Parent string   `json:"parent,omitempty"` // Canonical name of the parent place ("Texas")
+	Known  bool     `json:"known"`            // True if the place itself is in the gazetteer
+}
+
+type gazetteerEntry struct {
+	name    string
+	level   GeoLevel
+	parent  string
+	aliases []string
+}
+
+// gazetteer is a small built-in list of places and their common variants.
+// Unknown cities are still normalized when qualified by a known region
+// ("Round Rock, TX" → Round Rock in Texas).
+var gazetteer = []gazetteerEntry{
+	// Countries
+	{"United States", GeoLevelCountry, "", []string{"USA", "US", "U.S.", "U.S.A.", "United States of America", "America"}},
+	{"Canada", GeoLevelCountry, "", nil},
+	{"Mexico", GeoLevelCountry, "", []string{"MX"}},
+	{"United Kingdom", GeoLevelCountry, "", []string{"UK", "U.K.", "Great Britain", "Britain", "GB"}},
+	{"Ireland", GeoLevelCountry, "", nil},
+	{"France", GeoLevelCountry, "", nil},
+	{"Germany", GeoLevelCountry, "", nil},
+	{"Spain", GeoLevelCountry, "", nil},
+	{"Italy", GeoLevelCountry, "", nil},
+	{"Netherlands", GeoLevelCountry, "", []string{"Holland", "The Netherlands"}},
+	{"Japan", GeoLevelCountry, "", nil},
+	{"China", GeoLevelCountry, "", nil},
+	{"India", GeoLevelCountry, "", nil},
+	{"Australia", GeoLevelCountry, "", nil},
+	{"Brazil", GeoLevelCountry, "", nil},
+
+	// US states
+	{"Alabama", GeoLevelRegion, "United States", []string{"AL"}},
+	{"Alaska", GeoLevelRegion, "United States", []string{"AK"}},
+	{"Arizona", GeoLevelRegion, "United States", []string{"AZ"}},
+	{"Arkansas", GeoLevelRegion, "United States", []string{"AR"}},
+	{"California", GeoLevelRegion, "United States", []string{"CA", "Calif", "Cali"}},
+	{"Colorado", GeoLevelRegion, "United States", []string{"CO"}},
+	{"Connecticut", GeoLevelRegion, "United States", []string{"CT"}},
+	{"Delaware", GeoLevelRegion, "United States", []string{"DE"}},
+	{"Florida", GeoLevelRegion, "United States", []string{"FL"}},
+	{"Georgia", GeoLevelRegion, "United States", []string{"GA"}},
+	{"Hawaii", GeoLevelRegion, "United States", []string{"HI"}},
+	{"Idaho", GeoLevelRegion, "United States", []string{"ID"}},
+	{"Illinois", GeoLevelRegion, "United States", []string{"IL"}},
+	{"Indiana", GeoLevelRegion, "United States", []string{"IN"}},
+	{"Iowa", GeoLevelRegion, "United States", []string{"IA"}},
+	{"Kansas", GeoLevelRegion, "United States", []string{"KS"}},
+	{"Kentucky", GeoLevelRegion, "United States", []string{"KY"}},
+	{"Louisiana", GeoLevelRegion, "United States", []string{"LA"}},
+	{"Maine", GeoLevelRegion, "United States", []string{"ME"}},
+	{"Maryland", GeoLevelRegion, "United States", []string{"MD"}},
+	{"Massachusetts", GeoLevelRegion, "United States", []string{"MA", "Mass"}},
+	{"Michigan", GeoLevelRegion, "United States", []string{"MI"}},
+	{"Minnesota", GeoLevelRegion, "United States", []string{"MN"}},
+	{"Mississippi", GeoLevelRegion, "United States", []string{"MS"}},
+	{"Missouri", GeoLevelRegion, "United States", []string{"MO"}},
+	{"Montana", GeoLevelRegion, "United States", []string{"MT"}},
+	{"Nebraska", GeoLevelRegion, "United States", []string{"NE"}},
+	{"Nevada", GeoLevelRegion, "United States", []string{"NV"}},
+	{"New Hampshire", GeoLevelRegion, "United States", []string{"NH"}},
+	{"New Jersey", GeoLevelRegion, "United States", []string{"NJ"}},
+	{"New Mexico", GeoLevelRegion, "United States", []string{"NM"}},
+	{"New York", GeoLevelRegion, "United States", []string{"NY", "New York State"}},
+	{"North Carolina", GeoLevelRegion, "United States", []string{"NC"}},
+	{"North Dakota", GeoLevelRegion, "United States", []string{"ND"}},
+	{"Ohio", GeoLevelRegion, "United States", []string{"OH"}},
+	{"Oklahoma", GeoLevelRegion, "United States", []string{"OK"}},
+	{"Oregon", GeoLevelRegion, "United States", []string{"OR"}},
+	{"Pennsylvania", GeoLevelRegion, "United States", []string{"PA"}},
+	{"Rhode Island", GeoLevelRegion, "United States", []string{"RI"}},
+	{"South Carolina", GeoLevelRegion, "United States", []string{"SC"}},
+	{"South Dakota", GeoLevelRegion, "United States", []string{"SD"}},
+	{"Tennessee", GeoLevelRegion, "United States", []string{"TN"}},
+	{"Texas", GeoLevelRegion, "United States", []string{"TX"}},
+	{"Utah", GeoLevelRegion, "United States", []string{"UT"}},
+	{"Vermont", GeoLevelRegion, "United States", []string{"VT"}},
+	{"Virginia", GeoLevelRegion, "United States", []string{"VA"}},
+	{"Washington", GeoLevelRegion, "United States", []string{"WA", "Washington State"}},
+	{"West Virginia", GeoLevelRegion, "United States", []string{"WV"}},
+	{"Wisconsin", GeoLevelRegion, "United States", []string{"WI"}},
+	{"Wyoming", GeoLevelRegion, "United States", []string{"WY"}},
+	{"District of Columbia", GeoLevelRegion, "United States", []string{"DC", "D.C."}},
+
+	// Canadian provinces
+	{"Ontario", GeoLevelRegion, "Canada", []string{"ON"}},
+	{"Quebec", GeoLevelRegion, "Canada", []string{"QC"}},
+	{"British Columbia", GeoLevelRegion, "Canada", []string{"BC"}},
+	{"Alberta", GeoLevelRegion, "Canada", []string{"AB"}},
+
+	// Cities
+	{"Austin", GeoLevelCity, "Texas", []string{"ATX"}},
+	{"Houston", GeoLevelCity, "Texas", []string{"HTX", "H-Town"}},
+	{"Dallas", GeoLevelCity, "Texas", []string{"DFW"}},
+	{"San Antonio", GeoLevelCity, "Texas", []string{"SATX"}},
+	{"New York City", GeoLevelCity, "New York", []string{"NYC", "New York, NY", "Manhattan", "The Big Apple"}},
+	{"Brooklyn", GeoLevelCity, "New York", nil},
+	{"San Francisco", GeoLevelCity, "California", []string{"SF", "San Fran", "Frisco"}},
+	{"Los Angeles", GeoLevelCity, "California", []string{"LA", "L.A."}},
+	{"San Diego", GeoLevelCity, "California", nil},
+	{"San Jose", GeoLevelCity, "California", nil},
+	{"Oakland", GeoLevelCity, "California", nil},
+	{"Seattle", GeoLevelCity, "Washington", nil},
+	{"Portland", GeoLevelCity, "Oregon", []string{"PDX"}},
+	{"Denver", GeoLevelCity, "Colorado", nil},
+	{"Boulder", GeoLevelCity, "Colorado", nil},
+	{"Chicago", GeoLevelCity, "Illinois", []string{"Chi-Town", "CHI"}},
+	{"Boston", GeoLevelCity, "Massachusetts", nil},
+	{"Philadelphia", GeoLevelCity, "Pennsylvania", []string{"Philly"}},
+	{"Pittsburgh", GeoLevelCity, "Pennsylvania", nil},
+	{"Miami", GeoLevelCity, "Florida", nil},
+	{"Atlanta", GeoLevelCity, "Georgia", []string{"ATL"}},
+	{"Nashville", GeoLevelCity, "Tennessee", nil},
+	{"New Orleans", GeoLevelCity, "Louisiana", []string{"NOLA"}},
+	{"Las Vegas", GeoLevelCity, "Nevada", []string{"Vegas"}},
+	{"Phoenix", GeoLevelCity, "Arizona", nil},
+	{"Salt Lake City", GeoLevelCity, "Utah", []string{"SLC"}},
+	{"Minneapolis", GeoLevelCity, "Minnesota", nil},
+	{"Detroit", GeoLevelCity, "Michigan", nil},
+	{"Washington, D.C.", GeoLevelCity, "District of Columbia", []string{"Washington DC", "Washington D.C."}},
+	{"Toronto", GeoLevelCity, "Ontario", nil},
+	{"Montreal", GeoLevelCity, "Quebec", nil},
+	{"Vancouver", GeoLevelCity, "British Columbia", nil},
+	{"London", GeoLevelCity, "United Kingdom", nil},
+	{"Paris", GeoLevelCity, "France", nil},
+	{"Berlin", GeoLevelCity, "Germany", nil},
+	{"Tokyo", GeoLevelCity, "Japan", nil},
+}
+
+var (
+	gazetteerByName     map[string]*gazetteerEntry   // normalized canonical name → entry
+	gazetteerByAlias    map[string][]*gazetteerEntry // normalized name or alias → entries
+	gazetteerQualifiers map[string][]*gazetteerEntry // regions and countries only
+)
+
+func init() {
+	gazetteerByName = make(map[string]*gazetteerEntry, len(gazetteer))
+	gazetteerByAlias = make(map[string][]*gazetteerEntry, len(gazetteer)*2)
+	gazetteerQualifiers = make(map[string][]*gazetteerEntry, len(gazetteer)*2)
+	for i := range gazetteer {
+		e := &gazetteer[i]
+		gazetteerByName[normalizeGeoName(e.name)] = e
+		for _, variant := range append([]string{e.name}, e.aliases...) {
+			key := normalizeGeoName(variant)
+			gazetteerByAlias[key] = appendEntry(gazetteerByAlias[key], e)
+			if e.level != GeoLevelCity {
+				gazetteerQualifiers[key] = appendEntry(gazetteerQualifiers[key], e)
+			}
+		}
+	}
+}
+
+// appendEntry adds e to a bucket unless it is already there.
+func appendEntry(bucket []*gazetteerEntry, e *gazetteerEntry) []*gazetteerEntry {
+	for _, have := range bucket {
+		if have == e {
+			return bucket
+		}
+	}
+	return append(bucket, e)
+}
+
+// normalizeGeoName lowercases and strips punctuation and extra whitespace.
+func normalizeGeoName(name string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case r == '.':
+			continue
+		case r == ',' || r == ' ' || r == '\t':
+			space = true
+		default:
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// lookupPlace looks up a (possibly abbreviated) place name and returns every
+// entry it names. A canonical name is exact and wins over other entries'
+// aliases; an alias shared by several places ("LA") returns all of them.
+// Qualifiers (the trailing part of "Austin, TX") only match regions and countries.
+func lookupPlace(name string, asQualifier bool) []*gazetteerEntry {
+	key := normalizeGeoName(name)
+	if key == "" {
+		return nil
+	}
+	if e := gazetteerByName[key]; e != nil && (!asQualifier || e.level != GeoLevelCity) {
+		return []*gazetteerEntry{e}
+	}
+	if asQualifier {
+		return gazetteerQualifiers[key]
+	}
+	return gazetteerByAlias[key]
+}
+
+// NormalizeLocation normalizes a location name using the built-in gazetteer.
+// Handles variants ("ATX"), qualified names ("Austin, Texas, USA")
+// and unknown cities qualified by a known region ("Round Rock, TX").
+// Returns false if the name cannot be normalized or names several places
+// (see AmbiguousLocation).
+func NormalizeLocation(name string) (GeoPlace, bool) {
+	places := matchLocation(name)
+	if len(places) != 1 {
+		return GeoPlace{}, false
+	}
+	return places[0], true
+}
+
+// AmbiguousLocation returns the places a name could refer to when it matches
+// more than one ("LA" is Los Angeles or Louisiana), or nil otherwise.
+// A qualifier disambiguates ("LA, California").
+func AmbiguousLocation(name string) []GeoPlace {
+	places := matchLocation(name)
+	if len(places) < 2 {
+		return nil
+	}
+	return places
+}
+
+// matchLocation returns every place a name could refer to.
+func matchLocation(name string) []GeoPlace {
+	if entries := lookupPlace(name, false); len(entries) > 0 {
+		places := make([]GeoPlace, len(entries))
+		for i, e := range entries {
+			places[i] = GeoPlace{Name: e.name, Level: e.level, Parent: e.parent, Known: true}
+		}
+		return places
+	}
+
+	// Split off a trailing qualifier, by comma first, then by whitespace
+	commas := strings.Contains(name, ",")
+	var parts []string
+	if commas {
+		for _, p := range strings.Split(name, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				parts = append(parts, p)
+			}
+		}
+	} else {
+		parts = strings.Fields(name)
+	}
+	if len(parts) < 2 {
+		return nil
+	}
+
+	// Try the longest multi-word qualifier first ("New York", "North Carolina")
+	for width := 2; width >= 1; width-- {
+		split := len(parts) - width
+		if split < 1 {
+			continue
+		}
+		qualifierText := strings.Join(parts[split:], " ")
+		// Without commas, abbreviations must be written in capitals unless the
+		// whole name is lowercase ("austin tx", but not "meet me in Austin")
+		lowercase := name == strings.ToLower(name)
+		if !commas && !lowercase && len(qualifierText) <= 3 && qualifierText != strings.ToUpper(qualifierText) {
+			continue
+		}
+		qualifiers := lookupPlace(qualifierText, true)
+		if len(qualifiers) != 1 {
+			continue
+		}
+		qualifier := qualifiers[0]
+		head := strings.Join(parts[:split], " ")
+
+		// Known places (or qualified places) inside the qualifier
+		var within []GeoPlace
+		for _, place := range matchLocation(head) {
+			for _, ancestor := range LocationHierarchy(place) {
+				if ancestor == qualifier.name {
+					within = append(within, place)
+					break
+				}
+			}
+		}
+		if len(within) > 0 {
+			return within
+		}
+
+		// Unknown (or same-named, e.g. "Portland, ME") place within the qualifier.
+		// Without commas the head must look like a proper name
+		// ("Whole Foods in OR" shouldn't become a city in Oregon)
+		if !commas && !lowercase && !isCapitalized(head) {
+			return nil
+		}
+		level := GeoLevelCity
+		if qualifier.level == GeoLevelCountry {
+			level = GeoLevelRegion
+		}
+		return []GeoPlace{{Name: titleIfLower(head), Level: level, Parent: qualifier.name}}
+	}
+	return nil
+}
+
+// isCapitalized reports whether every word starts with an uppercase letter.
+func isCapitalized(name string) bool {
+	for _, w := range strings.Fields(name) {
+		r, _ := utf8.DecodeRuneInString(w)
+		if !unicode.IsUpper(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// titleIfLower capitalizes an all-lowercase name; other casing is kept as written.
+func titleIfLower(name string) string {
+	if name != strings.ToLower(name) {
+		return name
+	}
+	words := strings.Fields(name)
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// LocationHierarchy returns the chain of ancestors for a canonical place name,
+// nearest first (Austin → [Texas, United States]).
+func LocationHierarchy(place GeoPlace) []string {
+	var chain []string
+	parent := place.Parent
+	for depth := 0; parent != "" && depth < 4; depth++ {
+		chain = append(chain, parent)
+		e := gazetteerByName[normalizeGeoName(parent)]
+		if e == nil {
+			break
+		}
+		parent = e.parent
+	}
+	return chain
+}
+
+// GeoNormalizeResult contains the output of location normalization.
+type GeoNormalizeResult struct {
+	Normalized      int `json:"normalized"`       // Location entities matched to a place
+	Renamed         int `json:"renamed"`          // Canonical names rewritten to the normalized name
+	ParentsCreated  int `json:"parents_created"`  // Parent location entities created
+	LinksCreated    int `json:"links_created"`    // LOCATED_IN edges created
+	CandidatesFound int `json:"candidates_found"` // Near-duplicate merge candidates created
+
+	// Ambiguous lists location names left as is because they match several
+	// places ("LA" is Los Angeles or Louisiana)
+	Ambiguous []string `json:"ambiguous,omitempty"`
+}
+
+// GeoNormalizer normalizes location entities: canonical names, parent links
+// (LOCATED_IN up the hierarchy), and merge candidates for near-duplicates.
+// Duplicates are queued as auto-eligible merge candidates rather than merged
+// directly, so AutoMerger's conflict checks and audit trail apply.
+type GeoNormalizer struct {
+	db    *sql.DB
+	cache *EntityCache // Optional: invalidated when aliases are added
+}
+
+// NewGeoNormalizer creates a new GeoNormalizer.
+func NewGeoNormalizer(db *sql.DB) *GeoNormalizer {
+	return &GeoNormalizer{db: db}
+}
+
+// SetCache sets the hot-entity cache to invalidate when aliases are added.
+func (g *GeoNormalizer) SetCache(cache *EntityCache) {
+	g.cache = cache
+}
+
+// NormalizeEntities normalizes the given entities (non-location entities are skipped).
+func (g *GeoNormalizer) NormalizeEntities(ctx context.Context, entityIDs []string) (*GeoNormalizeResult, error) {
+	result := &GeoNormalizeResult{}
+	places, err := g.indexLocations(ctx)
+	if err != nil {
+		return result, err
+	}
+	for _, id := range uniqueIDs(entityIDs) {
+		if err := g.normalizeEntity(ctx, id, places, result); err != nil {
+			return result, fmt.Errorf("normalize location %s: %w", id, err)
+		}
+	}
+	return result, nil
+}
+
+// NormalizeAll normalizes every active location entity.
+func (g *GeoNormalizer) NormalizeAll(ctx context.Context) (*GeoNormalizeResult, error) {
+	rows, err := g.db.QueryContext(ctx, `
+		SELECT id FROM entities
+		WHERE entity_type_id = ? AND merged_into IS NULL
+		ORDER BY created_at
+	`, EntityTypeLocation)
+	if err != nil {
+		return nil, fmt.Errorf("query locations: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return g.NormalizeEntities(ctx, ids)
+}
+
+// placeKey identifies a normalized place in a locationIndex.
+func placeKey(place GeoPlace) string {
+	return place.Name + "|" + place.Parent
+}
+
+// locationIndex buckets active location entity IDs by the place their name
+// normalizes to, so duplicates are found without rescanning every location.
+type locationIndex map[string][]string
+
+// indexLocations builds the locationIndex from one scan of active locations.
+func (g *GeoNormalizer) indexLocations(ctx context.Context) (locationIndex, error) {
+	rows, err := g.db.QueryContext(ctx, `
+		SELECT id, canonical_name FROM entities
+		WHERE entity_type_id = ? AND merged_into IS NULL
+		ORDER BY created_at
+	`, EntityTypeLocation)
+	if err != nil {
+		return nil, fmt.Errorf("query locations: %w", err)
+	}
+	defer rows.Close()
+
+	index := make(locationIndex)
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		if place, ok := NormalizeLocation(name); ok {
+			index.add(place, id)
+		}
+	}
+	return index, rows.Err()
+}
+
+// add records entityID under place unless it is already there.
+func (idx locationIndex) add(place GeoPlace, entityID string) {
+	key := placeKey(place)
+	for _, id := range idx[key] {
+		if id == entityID {
+			return
+		}
+	}
+	idx[key] = append(idx[key], entityID)
+}
+
+// duplicates returns the other location entities indexed under place.
+func (idx locationIndex) duplicates(place GeoPlace, entityID string) []string {
+	var dupes []string
+	for _, id := range idx[placeKey(place)] {
+		if id != entityID {
+			dupes = append(dupes, id)
+		}
+	}
+	return dupes
+}
+
+// normalizeEntity normalizes one location entity.
+func (g *GeoNormalizer) normalizeEntity(ctx context.Context, entityID string, places locationIndex, result *GeoNormalizeResult) error {
+	var name string
+	var typeID int
+	err := g.db.QueryRowContext(ctx, `
+		SELECT canonical_name, entity_type_id FROM entities
+		WHERE id = ? AND merged_into IS NULL
+	`, entityID).Scan(&name, &typeID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if typeID != EntityTypeLocation {
+		return nil
+	}
+
+	place, ok := NormalizeLocation(name)
+	if !ok {
+		if AmbiguousLocation(name) != nil {
+			result.Ambiguous = append(result.Ambiguous, name)
+		}
+		return nil
+	}
+	result.Normalized++
+	places.add(place, entityID)
+
+	// Rename to the canonical name, keeping the original as an alias.
+	// A name the user locked is kept as is.
+	locked, err := isNameLocked(ctx, g.db, entityID)
+	if err != nil {
+		return err
+	}
+	if place.Name != name && !locked {
+		if err := g.addAlias(ctx, entityID, place.Name); err != nil {
+			return err
+		}
+		if err := g.addAlias(ctx, entityID, name); err != nil {
+			return err
+		}
+		if _, err := renameEntity(ctx, g.db, entityID, name, place.Name, NameChangeGeoNormalization, "", "auto"); err != nil {
+			return fmt.Errorf("rename location: %w", err)
+		}
+		result.Renamed++
+	}
+
+	// Link up the hierarchy: this place → parent → grandparent...
+	childID := entityID
+	childName := place.Name
+	parents := LocationHierarchy(place)
+	for _, parentName := range parents {
+		parentID, created, err := g.findOrCreateLocation(ctx, parentName)
+		if err != nil {
+			return err
+		}
+		if created {
+			result.ParentsCreated++
+			if parent, ok := NormalizeLocation(parentName); ok {
+				places.add(parent, parentID)
+			}
+		}
+		linked, err := g.linkParent(ctx, childID, childName, parentID, parentName)
+		if err != nil {
+			return err
+		}
+		if linked {
+			result.LinksCreated++
+		}
+		childID, childName = parentID, parentName
+	}
+
+	// Near-duplicates: other location entities normalizing to the same place
+	for _, dupeID := range places.duplicates(place, entityID) {
+		created, err := g.createMergeCandidate(ctx, dupeID, entityID, name, place)
+		if err != nil {
+			return err
+		}
+		if created {
+			result.CandidatesFound++
+		}
+	}
+	return nil
+}
+
+// findOrCreateLocation returns the location entity for a canonical place name,
+// creating it if needed.
+func (g *GeoNormalizer) findOrCreateLocation(ctx context.Context, name string) (string, bool, error) {
+	var id string
+	err := g.db.QueryRowContext(ctx, `
+		SELECT e.id FROM entities e
+		LEFT JOIN entity_aliases ea ON ea.entity_id = e.id AND ea.alias_type = 'name'
+		WHERE e.entity_type_id = ? AND e.merged_into IS NULL
+		  AND (e.canonical_name = ? OR ea.normalized = ?)
+		ORDER BY e.created_at
+		LIMIT 1
+	`, EntityTypeLocation, name, normalizeAlias(name)).Scan(&id)
+	if err == nil {
+		return id, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", false, fmt.Errorf("find location: %w", err)
+	}
+
+	id = uuid.New().String()
+	now := time.Now().Format(time.RFC3339)
+	if _, err := g.db.ExecContext(ctx, `
+		INSERT INTO entities (id, canonical_name, entity_type_id, origin, confidence, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 1.0, ?, ?)
+	`, id, name, EntityTypeLocation, OriginInferred, now, now); err != nil {
+		return "", false, fmt.Errorf("create location: %w", err)
+	}
+	if err := g.addAlias(ctx, id, name); err != nil {
+		return "", false, err
+	}
+	return id, true, nil
+}
+
+// addAlias adds a name alias if the entity doesn't already have it.
+func (g *GeoNormalizer) addAlias(ctx context.Context, entityID, alias string) error {
+	normalized := normalizeAlias(alias)
+	var exists int
+	err := g.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM entity_aliases
+		WHERE entity_id = ? AND alias_type = 'name' AND normalized = ?
+	`, entityID, normalized).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("check alias: %w", err)
+	}
+	if exists > 0 {
+		return nil
+	}
+	if _, err := g.db.ExecContext(ctx, `
+		INSERT INTO entity_aliases (id, entity_id, alias, alias_type, normalized, is_shared, created_at)
+		VALUES (?, ?, ?, 'name', ?, FALSE, ?)
+	`, uuid.New().String(), entityID, alias, normalized, time.Now().Format(time.RFC3339)); err != nil {
+		return fmt.Errorf("insert alias: %w", err)
+	}
+	g.cache.InvalidateAlias(alias, normalized)
+	return nil
+}
+
+// linkParent creates a LOCATED_IN edge from child to parent if none is current.
+func (g *GeoNormalizer) linkParent(ctx context.Context, childID, childName, parentID, parentName string) (bool, error) {
+	if childID == parentID {
+		return false, nil
+	}
+	var exists int
+	err := g.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM relationships
+		WHERE source_entity_id = ? AND target_entity_id = ? AND relation_type = ?
+		  AND invalid_at IS NULL
+	`, childID, parentID, RelationLocatedIn).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check parent link: %w", err)
+	}
+	if exists > 0 {
+		return false, nil
+	}
+	_, err = g.db.ExecContext(ctx, `
+		INSERT INTO relationships (
+			id, source_entity_id, target_entity_id, relation_type, fact, created_at, confidence
+		) VALUES (?, ?, ?, ?, ?, ?, 1.0)
+	`, uuid.New().String(), childID, parentID, RelationLocatedIn,
+		fmt.Sprintf("%s is located in %s", childName, parentName), time.Now().Format(time.RFC3339))
+	if err != nil {
+		return false, fmt.Errorf("insert parent link: %w", err)
+	}
+	return true, nil
+}
+
+// createMergeCandidate queues merging source into target. Returns false if a
+// candidate for the pair already exists.
+func (g *GeoNormalizer) createMergeCandidate(ctx context.Context, sourceID, targetID, variant string, place GeoPlace) (bool, error) {
+	var existing int
+	err := g.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM merge_candidates
+		WHERE (entity_a_id = ? AND entity_b_id = ?) OR (entity_a_id = ? AND entity_b_id = ?)
+	`, sourceID, targetID, targetID, sourceID).Scan(&existing)
+	if err != nil {
+		return false, fmt.Errorf("check merge candidate: %w", err)
+	}
+	if existing > 0 {
+		return false, nil
+	}
+
+	facts, _ := json.Marshal([]map[string]interface{}{{"type": "location", "value": place.Name}})
+	contextJSON, _ := json.Marshal(map[string]interface{}{
+		"variant":   variant,
+		"canonical": place.Name,
+		"parent":    place.Parent,
+		"level":     place.Level,
+	})
+	_, err = g.db.ExecContext(ctx, `
+		INSERT INTO merge_candidates (
+			id, entity_a_id, entity_b_id, confidence, auto_eligible,
+			reason, matching_facts, context, status, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, 'pending', ?)
+	`, uuid.New().String(), sourceID, targetID, GeoNormalizationConfidence, place.Known,
+		ReasonGeoNormalization, string(facts), string(contextJSON), time.Now().Format(time.RFC3339))
+	if err != nil {
+		return false, fmt.Errorf("insert merge candidate: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,174 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestNormalizeLocation(t *testing.T) {
+	tests := []struct {
+		input  string
+		name   string
+		parent string
+		ok     bool
+	}{
+		{"Austin", "Austin", "Texas", true},
+		{"austin tx", "Austin", "Texas", true},
+		{"Austin TX", "Austin", "Texas", true},
+		{"ATX", "Austin", "Texas", true},
+		{"Austin, Texas, USA", "Austin", "Texas", true},
+		{"NYC", "New York City", "New York", true},
+		{"New York, NY", "New York City", "New York", true},
+		{"Brooklyn New York", "Brooklyn", "New York", true},
+		{"LA", "", "", false}, // Los Angeles or Louisiana
+		{"LA, California", "Los Angeles", "California", true},
+		{"Austin, LA", "Austin", "Louisiana", true},
+		{"Round Rock, TX", "Round Rock", "Texas", true},
+		{"Portland, ME", "Portland", "Maine", true},
+		{"Texas", "Texas", "United States", true},
+		{"USA", "United States", "", true},
+		{"Whole Foods in OR", "", "", false},
+		{"Mom's house", "", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := NormalizeLocation(tt.input)
+		if ok != tt.ok {
+			t.Errorf("NormalizeLocation(%q) ok = %v, want %v", tt.input, ok, tt.ok)
+			continue
+		}
+		if ok && (got.Name != tt.name || got.Parent != tt.parent) {
+			t.Errorf("NormalizeLocation(%q) = %s in %s, want %s in %s", tt.input, got.Name, got.Parent, tt.name, tt.parent)
+		}
+	}
+}
+
+func TestAmbiguousLocation(t *testing.T) {
+	places := AmbiguousLocation("LA")
+	if len(places) != 2 || places[0].Name != "Louisiana" || places[1].Name != "Los Angeles" {
+		t.Errorf("AmbiguousLocation(LA) = %+v, want Louisiana and Los Angeles", places)
+	}
+	for _, name := range []string{"Austin", "LA, California", "Mom's house"} {
+		if places := AmbiguousLocation(name); places != nil {
+			t.Errorf("AmbiguousLocation(%q) = %+v, want nil", name, places)
+		}
+	}
+}
+
+func TestLocationHierarchy(t *testing.T) {
+	place, _ := NormalizeLocation("ATX")
+	chain := LocationHierarchy(place)
+	if len(chain) != 2 || chain[0] != "Texas" || chain[1] != "United States" {
+		t.Errorf("LocationHierarchy(Austin) = %v, want [Texas United States]", chain)
+	}
+}
+
+func TestGeoNormalizer_NormalizeEntities(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertQueryEngineTestEntity(t, db, "austin", "Austin", EntityTypeLocation)
+	insertQueryEngineTestEntity(t, db, "austin-tx", "Austin TX", EntityTypeLocation)
+	insertQueryEngineTestEntity(t, db, "tyler", "Tyler", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "la", "LA", EntityTypeLocation)
+
+	normalizer := NewGeoNormalizer(db)
+	result, err := normalizer.NormalizeAll(ctx)
+	if err != nil {
+		t.Fatalf("NormalizeAll: %v", err)
+	}
+	if result.Normalized != 2 || result.Renamed != 1 {
+		t.Errorf("result = %+v, want 2 normalized, 1 renamed", result)
+	}
+	if result.ParentsCreated != 2 {
+		t.Errorf("ParentsCreated = %d, want 2 (Texas, United States)", result.ParentsCreated)
+	}
+	if result.CandidatesFound != 1 {
+		t.Errorf("CandidatesFound = %d, want 1", result.CandidatesFound)
+	}
+	if len(result.Ambiguous) != 1 || result.Ambiguous[0] != "LA" {
+		t.Errorf("Ambiguous = %v, want [LA]", result.Ambiguous)
+	}
+
+	var name string
+	if err := db.QueryRow(`SELECT canonical_name FROM entities WHERE id = 'austin-tx'`).Scan(&name); err != nil {
+		t.Fatalf("query entity: %v", err)
+	}
+	if name != "Austin" {
+		t.Errorf("canonical_name = %q, want Austin", name)
+	}
+	var aliases int
+	db.QueryRow(`SELECT COUNT(*) FROM entity_aliases WHERE entity_id = 'austin-tx' AND normalized = 'austin tx'`).Scan(&aliases)
+	if aliases != 1 {
+		t.Error("original name should be kept as an alias")
+	}
+
+	// Running again is idempotent
+	again, err := normalizer.NormalizeAll(ctx)
+	if err != nil {
+		t.Fatalf("NormalizeAll again: %v", err)
+	}
+	if again.ParentsCreated != 0 || again.LinksCreated != 0 || again.CandidatesFound != 0 {
+		t.Errorf("second run = %+v, want no new parents, links or candidates", again)
+	}
+
+	// The duplicate is auto-mergeable
+	merger := NewAutoMerger(db)
+	candidates, err := merger.GetPendingCandidates(ctx)
+	if err != nil {
+		t.Fatalf("GetPendingCandidates: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].Reason != ReasonGeoNormalization {
+		t.Fatalf("candidates = %+v, want one geo_normalization candidate", candidates)
+	}
+	if !merger.ShouldAutoMerge(&candidates[0]) {
+		t.Error("geo normalization candidate should be auto-mergeable")
+	}
+}
+
+func TestQueryEngine_FindEntitiesInLocation(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertQueryEngineTestEntity(t, db, "austin", "ATX", EntityTypeLocation)
+	insertQueryEngineTestEntity(t, db, "dallas", "Dallas", EntityTypeLocation)
+	insertQueryEngineTestEntity(t, db, "denver", "Denver", EntityTypeLocation)
+	insertQueryEngineTestEntity(t, db, "tyler", "Tyler", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "casey", "Casey", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "riley", "Riley", EntityTypePerson)
+
+	austin, dallas, denver := "austin", "dallas", "denver"
+	insertQueryEngineTestRelationship(t, db, "r1", "tyler", &austin, nil, "LIVES_IN", "Tyler lives in Austin", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "r2", "casey", &dallas, nil, "LIVES_IN", "Casey lives in Dallas", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "r3", "riley", &denver, nil, "LIVES_IN", "Riley lives in Denver", nil, nil)
+
+	if _, err := NewGeoNormalizer(db).NormalizeAll(ctx); err != nil {
+		t.Fatalf("NormalizeAll: %v", err)
+	}
+
+	var texasID string
+	if err := db.QueryRow(`SELECT id FROM entities WHERE canonical_name = 'Texas'`).Scan(&texasID); err != nil {
+		t.Fatalf("find Texas: %v", err)
+	}
+
+	q := NewQueryEngine(db)
+	defer q.Close()
+	residents, err := q.FindEntitiesInLocation(ctx, "LIVES_IN", texasID, DefaultQueryOptions())
+	if err != nil {
+		t.Fatalf("FindEntitiesInLocation: %v", err)
+	}
+	if len(residents) != 2 || residents[0].ID != "casey" || residents[1].ID != "tyler" {
+		t.Errorf("Texas residents = %+v, want casey and tyler", residents)
+	}
+
+	var usID string
+	db.QueryRow(`SELECT id FROM entities WHERE canonical_name = 'United States'`).Scan(&usID)
+	all, _ := q.FindEntitiesInLocation(ctx, "LIVES_IN", usID, DefaultQueryOptions())
+	if len(all) != 3 {
+		t.Errorf("United States residents = %d, want 3", len(all))
+	}
+}
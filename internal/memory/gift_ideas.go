@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PreferenceItem is one stated preference of a person, with its provenance.
+type PreferenceItem struct {
+	RelationshipID string  `json:"relationship_id"`
+	RelationType   string  `json:"relation_type"`
+	Value          string  `json:"value"`
+	TargetEntityID *string `json:"target_entity_id,omitempty"`
+	Fact           string  `json:"fact"`
+	SourceType     string  `json:"source_type,omitempty"`
+	Weight         float64 `json:"weight"`
+	Mentions       int     `json:"mentions"`
+	LastEpisodeID  *string `json:"last_episode_id,omitempty"`
+	LastMentioned  *string `json:"last_mentioned,omitempty"` // RFC3339, from the latest mentioning episode
+}
+
+// GiftProfile gathers what a person has said they want, like, and dislike,
+// and their sizes, alongside their next birthday.
+type GiftProfile struct {
+	EntityID     string           `json:"entity_id"`
+	Name         string           `json:"name"`
+	Birthday     *string          `json:"birthday,omitempty"` // BORN_ON literal as stored
+	NextBirthday *string          `json:"next_birthday,omitempty"`
+	DaysUntil    *int             `json:"days_until,omitempty"`
+	Wants        []PreferenceItem `json:"wants"`
+	Likes        []PreferenceItem `json:"likes"`
+	Dislikes     []PreferenceItem `json:"dislikes"`
+	Sizes        []PreferenceItem `json:"sizes"`
+}
+
+// GetGiftProfile returns the gift profile of a Person entity as of now.
+// Only currently valid preferences are included, strongest first.
+func GetGiftProfile(ctx context.Context, db *sql.DB, entityID string, now time.Time) (*GiftProfile, error) {
+	profile := GiftProfile{EntityID: entityID}
+	err := db.QueryRowContext(ctx, `
+		SELECT canonical_name FROM entities WHERE id = ? AND merged_into IS NULL
+	`, entityID).Scan(&profile.Name)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("entity not found: %s", entityID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get entity: %w", err)
+	}
+
+	var birthday sql.NullString
+	err = db.QueryRowContext(ctx, `
+		SELECT target_literal FROM relationships
+		WHERE source_entity_id = ? AND relation_type = 'BORN_ON' AND invalid_at IS NULL
+		ORDER BY confidence DESC, created_at DESC
+		LIMIT 1
+	`, entityID).Scan(&birthday)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("get birthday: %w", err)
+	}
+	if birthday.Valid {
+		profile.Birthday = &birthday.String
+		if next, ok := nextBirthday(birthday.String, now); ok {
+			date := next.Format("2006-01-02")
+			days := int(next.Sub(startOfDay(now)).Hours()/24 + 0.5)
+			profile.NextBirthday, profile.DaysUntil = &date, &days
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT r.id, r.relation_type, r.target_entity_id, COALESCE(t.canonical_name, r.target_literal),
+			r.fact, COALESCE(r.source_type, ''), COALESCE(r.weight, 0),
+			(SELECT COUNT(*) FROM episode_relationship_mentions m WHERE m.relationship_id = r.id),
+			(SELECT m.episode_id FROM episode_relationship_mentions m
+				JOIN episodes ep ON ep.id = m.episode_id
+				WHERE m.relationship_id = r.id
+				ORDER BY ep.start_time DESC LIMIT 1),
+			(SELECT MAX(ep.start_time) FROM episode_relationship_mentions m
+				JOIN episodes ep ON ep.id = m.episode_id
+				WHERE m.relationship_id = r.id)
+		FROM relationships r
+		LEFT JOIN entities t ON t.id = r.target_entity_id
+		WHERE r.source_entity_id = ?
+		AND r.relation_type IN ('PREFERS', 'DISLIKES', 'WANTS', 'HAS_SIZE')
+		AND r.invalid_at IS NULL
+		ORDER BY r.weight DESC, r.confidence DESC, r.created_at DESC
+	`, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("query preferences: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item PreferenceItem
+		var targetID, lastEpisode sql.NullString
+		var lastStart sql.NullInt64
+		if err := rows.Scan(&item.RelationshipID, &item.RelationType, &targetID, &item.Value,
+			&item.Fact, &item.SourceType, &item.Weight, &item.Mentions, &lastEpisode, &lastStart); err != nil {
+			return nil, fmt.Errorf("scan preference: %w", err)
+		}
+		if targetID.Valid {
+			item.TargetEntityID = &targetID.String
+		}
+		if lastEpisode.Valid {
+			item.LastEpisodeID = &lastEpisode.String
+		}
+		if lastStart.Valid {
+			ts := time.Unix(lastStart.Int64, 0).UTC().Format(time.RFC3339)
+			item.LastMentioned = &ts
+		}
+
+		switch item.RelationType {
+		case "WANTS":
+			profile.Wants = append(profile.Wants, item)
+		case "PREFERS":
+			profile.Likes = append(profile.Likes, item)
+		case "DISLIKES":
+			profile.Dislikes = append(profile.Dislikes, item)
+		case "HAS_SIZE":
+			profile.Sizes = append(profile.Sizes, item)
+		}
+	}
+	return &profile, rows.Err()
+}
+
+// UpcomingBirthdays returns gift profiles for Person entities whose birthday
+// falls within the given window from now, soonest first.
+func UpcomingBirthdays(ctx context.Context, db *sql.DB, now time.Time, within time.Duration) ([]GiftProfile, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT DISTINCT e.id, r.target_literal
+		FROM entities e
+		JOIN relationships r ON r.source_entity_id = e.id
+		WHERE e.entity_type_id = ? AND e.merged_into IS NULL
+		AND r.relation_type = 'BORN_ON' AND r.invalid_at IS NULL
+	`, EntityTypePerson)
+	if err != nil {
+		return nil, fmt.Errorf("query birthdays: %w", err)
+	}
+	cutoff := startOfDay(now).Add(within)
+	seen := make(map[string]bool)
+	var entityIDs []string
+	for rows.Next() {
+		var entityID, born string
+		if err := rows.Scan(&entityID, &born); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if next, ok := nextBirthday(born, now); ok && !next.After(cutoff) && !seen[entityID] {
+			seen[entityID] = true
+			entityIDs = append(entityIDs, entityID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	profiles := make([]GiftProfile, 0, len(entityIDs))
+	for _, entityID := range entityIDs {
+		profile, err := GetGiftProfile(ctx, db, entityID, now)
+		if err != nil {
+			return nil, err
+		}
+		// The profile's birthday is the strongest BORN_ON, which may fall outside the window
+		if profile.DaysUntil == nil || startOfDay(now).AddDate(0, 0, *profile.DaysUntil).After(cutoff) {
+			continue
+		}
+		profiles = append(profiles, *profile)
+	}
+	sort.SliceStable(profiles, func(i, j int) bool { return *profiles[i].DaysUntil < *profiles[j].DaysUntil })
+	return profiles, nil
+}
+
+// nextBirthday returns the next occurrence (today included) of a birthday
+// stored as YYYY-MM-DD or MM-DD. Year-only or unparseable dates return false.
+func nextBirthday(born string, now time.Time) (time.Time, bool) {
+	born = strings.TrimPrefix(strings.TrimSpace(born), "--")
+	var month, day int
+	if t, err := time.Parse("2006-01-02", born); err == nil {
+		month, day = int(t.Month()), t.Day()
+	} else if t, err := time.Parse("01-02", born); err == nil {
+		month, day = int(t.Month()), t.Day()
+	} else {
+		return time.Time{}, false
+	}
+
+	today := startOfDay(now)
+	next := time.Date(today.Year(), time.Month(month), day, 0, 0, 0, 0, today.Location())
+	if next.Before(today) {
+		next = time.Date(today.Year()+1, time.Month(month), day, 0, 0, 0, 0, today.Location())
+	}
+	return next, true
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
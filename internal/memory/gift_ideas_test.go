@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestGiftProfile(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	stmts := []string{
+		`INSERT INTO entities (id, canonical_name, entity_type_id, origin, created_at, updated_at) VALUES
+			('e-casey', 'Casey', 1, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z'),
+			('e-sam', 'Sam', 1, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z'),
+			('e-kindle', 'Kindle Paperwhite', 0, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')`,
+		`INSERT INTO relationships (id, source_entity_id, target_entity_id, target_literal, relation_type, fact, weight, invalid_at, created_at) VALUES
+			('r-born', 'e-casey', NULL, '1990-03-10', 'BORN_ON', 'Casey was born on 1990-03-10', 0, NULL, '2026-01-01T00:00:00Z'),
+			('r-want', 'e-casey', 'e-kindle', NULL, 'WANTS', 'Casey wants a Kindle Paperwhite', 0.5, NULL, '2026-01-01T00:00:00Z'),
+			('r-like1', 'e-casey', NULL, 'dark chocolate', 'PREFERS', 'Casey loves dark chocolate', 0.9, NULL, '2026-01-01T00:00:00Z'),
+			('r-like2', 'e-casey', NULL, 'lilies', 'PREFERS', 'Casey likes lilies', 0.2, NULL, '2026-01-01T00:00:00Z'),
+			('r-old', 'e-casey', NULL, 'coffee', 'PREFERS', 'Casey used to love coffee', 1, '2025-06-01', '2026-01-01T00:00:00Z'),
+			('r-dislike', 'e-casey', NULL, 'scented candles', 'DISLIKES', 'Casey hates scented candles', 0.4, NULL, '2026-01-01T00:00:00Z'),
+			('r-size', 'e-casey', NULL, 'shoe 8', 'HAS_SIZE', 'Casey wears a size 8 shoe', 0.3, NULL, '2026-01-01T00:00:00Z'),
+			('r-sam-born', 'e-sam', NULL, '1985-09-01', 'BORN_ON', 'Sam was born on 1985-09-01', 0, NULL, '2026-01-01T00:00:00Z')`,
+		`INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0)`,
+		`INSERT INTO episodes (id, definition_id, start_time, end_time, event_count, created_at) VALUES ('ep1', 'def', 100, 100, 1, 0), ('ep2', 'def', 200, 200, 1, 0)`,
+		`INSERT INTO episode_relationship_mentions (id, episode_id, relationship_id, extracted_fact, created_at) VALUES
+			('m1', 'ep1', 'r-want', 'wants a kindle', '2026-01-01T00:00:00Z'),
+			('m2', 'ep2', 'r-want', 'still wants a kindle', '2026-01-01T00:00:00Z')`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("setup: %v", err)
+		}
+	}
+
+	now := time.Date(2026, 2, 25, 15, 0, 0, 0, time.UTC)
+	profile, err := GetGiftProfile(ctx, db, "e-casey", now)
+	if err != nil {
+		t.Fatalf("GetGiftProfile: %v", err)
+	}
+	if profile.NextBirthday == nil || *profile.NextBirthday != "2026-03-10" || *profile.DaysUntil != 13 {
+		t.Errorf("next birthday = %v in %v, want 2026-03-10 in 13 days", profile.NextBirthday, profile.DaysUntil)
+	}
+	if len(profile.Wants) != 1 || profile.Wants[0].Value != "Kindle Paperwhite" || profile.Wants[0].Mentions != 2 ||
+		profile.Wants[0].LastEpisodeID == nil || *profile.Wants[0].LastEpisodeID != "ep2" {
+		t.Errorf("wants = %+v", profile.Wants)
+	}
+	// Strongest first; invalidated preferences dropped
+	if len(profile.Likes) != 2 || profile.Likes[0].Value != "dark chocolate" {
+		t.Errorf("likes = %+v", profile.Likes)
+	}
+	if len(profile.Dislikes) != 1 || len(profile.Sizes) != 1 || profile.Sizes[0].Value != "shoe 8" {
+		t.Errorf("dislikes = %+v, sizes = %+v", profile.Dislikes, profile.Sizes)
+	}
+
+	upcoming, err := UpcomingBirthdays(ctx, db, now, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("UpcomingBirthdays: %v", err)
+	}
+	if len(upcoming) != 1 || upcoming[0].EntityID != "e-casey" {
+		t.Errorf("upcoming = %+v, want only Casey", upcoming)
+	}
+
+	// Past this year's birthday rolls over to next year
+	if next, ok := nextBirthday("1990-03-10", time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)); !ok || next.Year() != 2027 {
+		t.Errorf("nextBirthday after = %v, %v", next, ok)
+	}
+	if _, ok := nextBirthday("1990", now); ok {
+		t.Error("year-only birthday should not parse")
+	}
+}
@@ -0,0 +1,448 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Graph query language
+//
+// A query is a chain of steps separated by dots, starting from a source:
+//
+//	entity("Tyler").out("WORKS_AT").valid_at("2024-06")
+//	id("ent-123").both().type("Person").limit(10)
+//	entity("Acme").in("WORKS_AT", "FOUNDED").name("sam")
+//
+// Sources:   entity(name...)  id(entity_id...)
+// Traversal: out(rel...)  in(rel...)  both(rel...)   (no args = any relation)
+// Filters:   type(name...)  name(substring)  limit(n)
+// Modifiers: valid_at(date)  history()  source(type...)
+//
+// Modifiers apply to every traversal in the query: valid_at follows only
+// relationships valid at that time (YYYY, YYYY-MM, YYYY-MM-DD, or RFC3339),
+// history also follows invalidated ones, and source follows only
+// relationships whose strongest evidence has one of the given source types
+// (self_disclosed, mentioned, inferred).
+
+// GraphQueryEdge is the relationship a traversal followed to reach a row.
+type GraphQueryEdge struct {
+	From         string  `json:"from"`
+	FromName     string  `json:"from_name"`
+	RelationType string  `json:"relation_type"`
+	Direction    string  `json:"direction"`
+	Fact         string  `json:"fact"`
+	ValidAt      *string `json:"valid_at,omitempty"`
+	InvalidAt    *string `json:"invalid_at,omitempty"`
+}
+
+// GraphQueryRow is one result: an entity, or a literal reached by a traversal.
+type GraphQueryRow struct {
+	ID      string          `json:"id,omitempty"`
+	Name    string          `json:"name,omitempty"`
+	Type    string          `json:"type,omitempty"`
+	Literal *string         `json:"literal,omitempty"`
+	Via     *GraphQueryEdge `json:"via,omitempty"`
+}
+
+// GraphQueryResult is the output of RunGraphQuery.
+type GraphQueryResult struct {
+	Expr  string          `json:"expr"`
+	Rows  []GraphQueryRow `json:"rows"`
+	Count int             `json:"count"`
+}
+
+// graphStep is one parsed call in a query chain.
+type graphStep struct {
+	name string
+	args []string
+	pos  int
+}
+
+// RunGraphQuery parses and evaluates a graph query expression.
+func (q *QueryEngine) RunGraphQuery(ctx context.Context, expr string) (*GraphQueryResult, error) {
+	steps, err := parseGraphQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Modifiers apply to the whole query, so resolve them before traversing
+	opts := DefaultQueryOptions()
+	for _, step := range steps {
+		switch step.name {
+		case "valid_at":
+			if len(step.args) != 1 {
+				return nil, fmt.Errorf("valid_at takes one date (at %d)", step.pos)
+			}
+			t, err := parseGraphQueryTime(step.args[0])
+			if err != nil {
+				return nil, fmt.Errorf("valid_at: %w (at %d)", err, step.pos)
+			}
+			opts.AsOfTime = &t
+		case "history":
+			opts.IncludeInvalidated = true
+		case "source":
+			if len(step.args) == 0 {
+				return nil, fmt.Errorf("source takes at least one source type (at %d)", step.pos)
+			}
+			for _, st := range step.args {
+				opts.SourceTypes = append(opts.SourceTypes, strings.ToLower(st))
+			}
+		}
+	}
+
+	var rows []GraphQueryRow
+	for i, step := range steps {
+		isSource := step.name == "entity" || step.name == "id"
+		if i == 0 && !isSource {
+			return nil, fmt.Errorf("query must start with entity() or id(), got %s() (at %d)", step.name, step.pos)
+		}
+		if i > 0 && isSource {
+			return nil, fmt.Errorf("%s() is only valid at the start of a query (at %d)", step.name, step.pos)
+		}
+
+		switch step.name {
+		case "entity":
+			rows, err = q.graphQueryEntities(ctx, step)
+		case "id":
+			rows, err = q.graphQueryIDs(ctx, step)
+		case "out", "in", "both":
+			rows, err = q.graphQueryTraverse(ctx, rows, step, opts)
+		case "type":
+			rows, err = filterGraphRowsByType(rows, step)
+		case "name":
+			if len(step.args) != 1 {
+				return nil, fmt.Errorf("name takes one substring (at %d)", step.pos)
+			}
+			needle := strings.ToLower(step.args[0])
+			filtered := rows[:0]
+			for _, row := range rows {
+				if row.Literal == nil && strings.Contains(strings.ToLower(row.Name), needle) {
+					filtered = append(filtered, row)
+				}
+			}
+			rows = filtered
+		case "limit":
+			n, convErr := strconv.Atoi(strings.Join(step.args, ""))
+			if len(step.args) != 1 || convErr != nil || n < 0 {
+				return nil, fmt.Errorf("limit takes one non-negative number (at %d)", step.pos)
+			}
+			if len(rows) > n {
+				rows = rows[:n]
+			}
+		case "valid_at", "history", "source":
+			// Applied above
+		default:
+			return nil, fmt.Errorf("unknown step %s() (at %d)", step.name, step.pos)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rows == nil {
+		rows = []GraphQueryRow{}
+	}
+	return &GraphQueryResult{Expr: expr, Rows: rows, Count: len(rows)}, nil
+}
+
+// graphQueryEntities resolves entity("name") to entities whose canonical name
+// matches exactly (case-insensitive), falling back to substring matches.
+func (q *QueryEngine) graphQueryEntities(ctx context.Context, step graphStep) ([]GraphQueryRow, error) {
+	if len(step.args) == 0 {
+		return nil, fmt.Errorf("entity takes at least one name (at %d)", step.pos)
+	}
+	var rows []GraphQueryRow
+	seen := map[string]bool{}
+	for _, name := range step.args {
+		matches, err := q.FindEntitiesByName(ctx, name, nil)
+		if err != nil {
+			return nil, fmt.Errorf("find entity %q: %w", name, err)
+		}
+		var exact []Entity
+		for _, ent := range matches {
+			if strings.EqualFold(ent.CanonicalName, name) {
+				exact = append(exact, ent)
+			}
+		}
+		if len(exact) > 0 {
+			matches = exact
+		}
+		for _, ent := range matches {
+			if !seen[ent.ID] {
+				seen[ent.ID] = true
+				rows = append(rows, graphRowForEntity(&ent))
+			}
+		}
+	}
+	return rows, nil
+}
+
+// graphQueryIDs resolves id("...") to entities, following merges.
+func (q *QueryEngine) graphQueryIDs(ctx context.Context, step graphStep) ([]GraphQueryRow, error) {
+	if len(step.args) == 0 {
+		return nil, fmt.Errorf("id takes at least one entity ID (at %d)", step.pos)
+	}
+	entities, err := q.GetEntities(ctx, step.args)
+	if err != nil {
+		return nil, fmt.Errorf("get entities: %w", err)
+	}
+	var rows []GraphQueryRow
+	seen := map[string]bool{}
+	for _, id := range step.args {
+		ent := entities[id]
+		if ent != nil && ent.MergedInto != nil {
+			if ent, err = q.GetEntity(ctx, *ent.MergedInto); err != nil {
+				return nil, fmt.Errorf("get merged entity: %w", err)
+			}
+		}
+		if ent != nil && !seen[ent.ID] {
+			seen[ent.ID] = true
+			rows = append(rows, graphRowForEntity(ent))
+		}
+	}
+	return rows, nil
+}
+
+// graphQueryTraverse follows relationships from every entity row.
+func (q *QueryEngine) graphQueryTraverse(ctx context.Context, rows []GraphQueryRow, step graphStep, opts QueryOptions) ([]GraphQueryRow, error) {
+	switch step.name {
+	case "out":
+		opts.Direction = DirectionOutgoing
+	case "in":
+		opts.Direction = DirectionIncoming
+	default:
+		opts.Direction = DirectionBoth
+	}
+	opts.RelationTypes = nil
+	for _, rel := range step.args {
+		opts.RelationTypes = append(opts.RelationTypes, strings.ToUpper(rel))
+	}
+
+	var next []GraphQueryRow
+	var ids []string
+	seen := map[string]bool{}
+	for _, row := range rows {
+		if row.Literal != nil {
+			continue
+		}
+		rels, err := q.GetEntityRelationships(ctx, row.ID, opts)
+		if err != nil {
+			return nil, fmt.Errorf("traverse from %s: %w", row.ID, err)
+		}
+		for _, rel := range rels {
+			via := &GraphQueryEdge{
+				From:         row.ID,
+				FromName:     row.Name,
+				RelationType: rel.RelationType,
+				Direction:    rel.Direction,
+				Fact:         rel.Fact,
+				ValidAt:      rel.ValidAt,
+				InvalidAt:    rel.InvalidAt,
+			}
+			switch {
+			case rel.TargetLiteral != nil:
+				key := "literal:" + *rel.TargetLiteral
+				if !seen[key] {
+					seen[key] = true
+					next = append(next, GraphQueryRow{Literal: rel.TargetLiteral, Via: via})
+				}
+			case rel.Direction == "incoming":
+				if !seen[rel.SourceEntityID] {
+					seen[rel.SourceEntityID] = true
+					next = append(next, GraphQueryRow{ID: rel.SourceEntityID, Name: rel.SourceName, Via: via})
+					ids = append(ids, rel.SourceEntityID)
+				}
+			case rel.TargetEntityID != nil && rel.TargetName != nil:
+				if !seen[*rel.TargetEntityID] {
+					seen[*rel.TargetEntityID] = true
+					next = append(next, GraphQueryRow{ID: *rel.TargetEntityID, Name: *rel.TargetName, Via: via})
+					ids = append(ids, *rel.TargetEntityID)
+				}
+			}
+		}
+	}
+
+	// Relationships don't carry entity types, so fill them in one batch
+	entities, err := q.GetEntities(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("get entities: %w", err)
+	}
+	for i := range next {
+		if ent := entities[next[i].ID]; ent != nil {
+			next[i].Type = entityTypeName(ent.EntityTypeID)
+		}
+	}
+	return next, nil
+}
+
+func filterGraphRowsByType(rows []GraphQueryRow, step graphStep) ([]GraphQueryRow, error) {
+	if len(step.args) == 0 {
+		return nil, fmt.Errorf("type takes at least one entity type (at %d)", step.pos)
+	}
+	want := map[string]bool{}
+	for _, name := range step.args {
+		et := GetEntityTypeByName(name)
+		if et == nil {
+			return nil, fmt.Errorf("unknown entity type %q (valid: %s)", name, strings.Join(EntityTypeNames(), ", "))
+		}
+		want[et.Name] = true
+	}
+	filtered := rows[:0]
+	for _, row := range rows {
+		if row.Literal == nil && want[row.Type] {
+			filtered = append(filtered, row)
+		}
+	}
+	return filtered, nil
+}
+
+func graphRowForEntity(ent *Entity) GraphQueryRow {
+	return GraphQueryRow{ID: ent.ID, Name: ent.CanonicalName, Type: entityTypeName(ent.EntityTypeID)}
+}
+
+func entityTypeName(id int) string {
+	if et := GetEntityTypeByID(id); et != nil {
+		return et.Name
+	}
+	return ""
+}
+
+// parseGraphQueryTime accepts a year, year-month, date, or RFC3339 timestamp.
+func parseGraphQueryTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02", "2006-01", "2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (use YYYY, YYYY-MM, YYYY-MM-DD, or RFC3339)", s)
+}
+
+// parseGraphQuery splits an expression into calls. Arguments may be quoted
+// strings (single or double quotes, backslash escapes) or bare words/numbers.
+func parseGraphQuery(expr string) ([]graphStep, error) {
+	p := &graphQueryParser{src: []rune(expr)}
+	var steps []graphStep
+	for {
+		p.skipSpace()
+		if len(steps) > 0 {
+			if p.eof() {
+				break
+			}
+			if !p.consume('.') {
+				return nil, p.errorf("expected '.' between steps")
+			}
+			p.skipSpace()
+		}
+		step, err := p.call()
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+	return steps, nil
+}
+
+type graphQueryParser struct {
+	src []rune
+	pos int
+}
+
+func (p *graphQueryParser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *graphQueryParser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *graphQueryParser) consume(r rune) bool {
+	if !p.eof() && p.src[p.pos] == r {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *graphQueryParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("parse error at %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *graphQueryParser) word() string {
+	start := p.pos
+	for !p.eof() {
+		r := p.src[p.pos]
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' && r != ':' {
+			break
+		}
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+func (p *graphQueryParser) call() (graphStep, error) {
+	step := graphStep{pos: p.pos}
+	if p.eof() {
+		return step, p.errorf("expected a step name")
+	}
+	step.name = strings.ToLower(p.word())
+	if step.name == "" {
+		return step, p.errorf("expected a step name, got %q", p.src[p.pos])
+	}
+	p.skipSpace()
+	if !p.consume('(') {
+		return step, p.errorf("expected '(' after %s", step.name)
+	}
+	for {
+		p.skipSpace()
+		if p.consume(')') {
+			return step, nil
+		}
+		if len(step.args) > 0 {
+			if !p.consume(',') {
+				return step, p.errorf("expected ',' or ')' in %s()", step.name)
+			}
+			p.skipSpace()
+		}
+		arg, err := p.arg()
+		if err != nil {
+			return step, err
+		}
+		step.args = append(step.args, arg)
+	}
+}
+
+func (p *graphQueryParser) arg() (string, error) {
+	if p.eof() {
+		return "", p.errorf("unterminated argument list")
+	}
+	quote := p.src[p.pos]
+	if quote != '"' && quote != '\'' {
+		if w := p.word(); w != "" {
+			return w, nil
+		}
+		return "", p.errorf("unexpected %q", p.src[p.pos])
+	}
+	p.pos++
+	var b strings.Builder
+	for !p.eof() {
+		r := p.src[p.pos]
+		p.pos++
+		switch {
+		case r == '\\' && !p.eof():
+			b.WriteRune(p.src[p.pos])
+			p.pos++
+		case r == quote:
+			return b.String(), nil
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
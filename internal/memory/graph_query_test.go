@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseGraphQuery(t *testing.T) {
+	steps, err := parseGraphQuery(`entity("Tyler \"T\"", 'Bob').out(WORKS_AT) . limit(5)`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(steps) != 3 {
+		t.Fatalf("steps = %+v", steps)
+	}
+	if steps[0].name != "entity" || len(steps[0].args) != 2 || steps[0].args[0] != `Tyler "T"` || steps[0].args[1] != "Bob" {
+		t.Errorf("entity step = %+v", steps[0])
+	}
+	if steps[1].name != "out" || steps[1].args[0] != "WORKS_AT" || steps[2].args[0] != "5" {
+		t.Errorf("steps = %+v", steps)
+	}
+
+	for _, bad := range []string{"", `entity("x"`, `entity("x) `, `entity("x") out()`, `entity("x").`, `.out()`} {
+		if _, err := parseGraphQuery(bad); err == nil {
+			t.Errorf("parseGraphQuery(%q) should fail", bad)
+		}
+	}
+}
+
+func TestQueryEngine_RunGraphQuery(t *testing.T) {
+	db := setupQueryEngineTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	qe := NewQueryEngine(db)
+
+	insertQueryEngineTestEntity(t, db, "tyler", "Tyler", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "tyler-b", "Tyler Brown", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "acme", "Acme", EntityTypeOrganization)
+	insertQueryEngineTestEntity(t, db, "initech", "Initech", EntityTypeOrganization)
+	insertQueryEngineTestEntity(t, db, "casey", "Casey", EntityTypePerson)
+
+	acme, initech, tyler := "acme", "initech", "tyler"
+	may2023, jan2024 := "2023-05-01", "2024-01-01"
+	insertQueryEngineTestRelationship(t, db, "r1", "tyler", &initech, nil, "WORKS_AT", "Tyler worked at Initech", &may2023, &jan2024)
+	insertQueryEngineTestRelationship(t, db, "r2", "tyler", &acme, nil, "WORKS_AT", "Tyler works at Acme", &jan2024, nil)
+	insertQueryEngineTestRelationship(t, db, "r3", "casey", &acme, nil, "WORKS_AT", "Casey works at Acme", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "r4", "casey", &tyler, nil, "KNOWS", "Casey knows Tyler", nil, nil)
+	email := "tyler@acme.com"
+	insertQueryEngineTestRelationship(t, db, "r5", "tyler", nil, &email, "HAS_EMAIL", "Tyler's email", nil, nil)
+	db.Exec(`UPDATE relationships SET source_type = 'self_disclosed' WHERE id = 'r3'`)
+
+	// Rows are compared sorted; traversal order follows relationship creation time
+	names := func(res *GraphQueryResult) string {
+		var out []string
+		for _, row := range res.Rows {
+			if row.Literal != nil {
+				out = append(out, *row.Literal)
+			} else {
+				out = append(out, row.Name)
+			}
+		}
+		sort.Strings(out)
+		return strings.Join(out, ",")
+	}
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		// Exact name match wins over "Tyler Brown"
+		{`entity("tyler")`, "Tyler"},
+		{`entity("Tyler").out("WORKS_AT")`, "Acme"},
+		{`entity("Tyler").out("works_at").valid_at("2023-06")`, "Initech"},
+		{`entity("Tyler").out("WORKS_AT").history()`, "Acme,Initech"},
+		{`entity("Acme").in("WORKS_AT")`, "Casey,Tyler"},
+		{`entity("Acme").in("WORKS_AT").name("cas")`, "Casey"},
+		{`entity("Acme").in("WORKS_AT").source("self_disclosed")`, "Casey"},
+		{`id("casey").out().type("Person")`, "Tyler"},
+		{`entity("Tyler").out("HAS_EMAIL")`, email},
+		{`entity("Acme").in().out("WORKS_AT").limit(1)`, "Acme"},
+	}
+	for _, tt := range tests {
+		res, err := qe.RunGraphQuery(ctx, tt.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if got := names(res); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+
+	res, _ := qe.RunGraphQuery(ctx, `entity("Tyler").out("WORKS_AT")`)
+	if via := res.Rows[0].Via; via == nil || via.From != "tyler" || via.Fact != "Tyler works at Acme" || res.Rows[0].Type != "Organization" {
+		t.Errorf("row = %+v", res.Rows[0])
+	}
+
+	for _, bad := range []string{`out("WORKS_AT")`, `entity("Tyler").id("x")`, `entity("Tyler").fly()`, `entity("Tyler").type("Robot")`, `entity("Tyler").valid_at("June")`, `entity("Tyler").source()`} {
+		if _, err := qe.RunGraphQuery(ctx, bad); err == nil {
+			t.Errorf("RunGraphQuery(%q) should fail", bad)
+		}
+	}
+}
@@ -43,12 +43,19 @@ type IdentityPromotionResult struct {
 // Identity relationships (HAS_EMAIL, HAS_PHONE, HAS_HANDLE, HAS_USERNAME, ALSO_KNOWN_AS)
 // use target_literal and go to entity_aliases, NOT the relationships table.
 type IdentityPromoter struct {
-	db *sql.DB
+	db            *sql.DB
+	blockingIndex *BlockingIndex
+	cache         *EntityCache // Optional: invalidated when aliases change
 }
 
 // NewIdentityPromoter creates a new IdentityPromoter.
 func NewIdentityPromoter(db *sql.DB) *IdentityPromoter {
-	return &IdentityPromoter{db: db}
+	return &IdentityPromoter{db: db, blockingIndex: NewBlockingIndex(db)}
+}
+
+// SetCache sets the hot-entity cache to invalidate when aliases are promoted.
+func (p *IdentityPromoter) SetCache(cache *EntityCache) {
+	p.cache = cache
 }
 
 // Promote processes extracted relationships and promotes identity relationships to aliases.
@@ -135,6 +142,10 @@ func (p *IdentityPromoter) promoteOne(ctx context.Context, episodeID, sourceEnti
 			if err != nil {
 				return nil, fmt.Errorf("insert alias: %w", err)
 			}
+			if err := p.blockingIndex.IndexAlias(ctx, sourceEntityID, targetLiteral, aliasType); err != nil {
+				// Non-fatal - IndexMissing catches up on the next run
+				_ = err
+			}
 		} else if err != nil {
 			return nil, fmt.Errorf("check existing alias: %w", err)
 		} else {
@@ -148,6 +159,9 @@ func (p *IdentityPromoter) promoteOne(ctx context.Context, episodeID, sourceEnti
 			// Log but don't fail
 			_ = err
 		}
+
+		// New or newly-shared aliases change lookup results for this value
+		p.cache.InvalidateAlias(targetLiteral, normalized)
 	} else {
 		// Non-self_disclosed: Find existing alias if any (for provenance link)
 		_ = p.db.QueryRowContext(ctx, `
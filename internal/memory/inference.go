@@ -0,0 +1,636 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InferenceRule derives a relation from a chain of premise edges: with If
+// [PARENT_OF, PARENT_OF] and Then GRANDPARENT_OF, "A PARENT_OF B" and
+// "B PARENT_OF C" give "A GRANDPARENT_OF C". Premise types may be inverse
+// types (CHILD_OF), and symmetric types match their edges either way round,
+// so SIBLING_OF needs no symmetry rule of its own.
+type InferenceRule struct {
+	Name string   `json:"name"`
+	If   []string `json:"if"`
+	Then string   `json:"then"`
+}
+
+// DefaultInferenceRules are used when no rules are configured.
+var DefaultInferenceRules = []InferenceRule{
+	{Name: "grandparent", If: []string{"PARENT_OF", "PARENT_OF"}, Then: "GRANDPARENT_OF"},
+	{Name: "shared_parent", If: []string{"CHILD_OF", "PARENT_OF"}, Then: "SIBLING_OF"},
+	{Name: "sibling_of_sibling", If: []string{"SIBLING_OF", "SIBLING_OF"}, Then: "SIBLING_OF"},
+}
+
+// MaxInferencePremises caps the length of a rule's premise chain.
+const MaxInferencePremises = 4
+
+// maxInferenceRounds bounds how many times inferred edges feed further
+// inference in one run.
+const maxInferenceRounds = 8
+
+// InferOptions configures an inference run.
+type InferOptions struct {
+	Rules  []InferenceRule // default: DefaultInferenceRules
+	DryRun bool            // report without storing or retracting anything
+}
+
+// InferredFact is an edge an inference run added or brought back.
+type InferredFact struct {
+	RelationshipID string   `json:"relationship_id,omitempty"` // empty for new facts in a dry run
+	Rule           string   `json:"rule"`
+	SourceEntityID string   `json:"source_entity_id"`
+	RelationType   string   `json:"relation_type"`
+	TargetEntityID string   `json:"target_entity_id"`
+	Fact           string   `json:"fact"`
+	Premises       []string `json:"premises"` // premise facts of its first derivation
+	Revived        bool     `json:"revived,omitempty"`
+}
+
+// InferResult reports an inference run.
+type InferResult struct {
+	Inferred  []InferredFact `json:"inferred"`
+	Unchanged int            `json:"unchanged"` // still derivable and already stored
+	Retracted int            `json:"retracted"` // no longer derivable, so ended
+	DryRun    bool           `json:"dry_run,omitempty"`
+}
+
+// InferencePremise is one premise of an inferred fact's derivation.
+type InferencePremise struct {
+	Derivation string `json:"derivation"`
+	Rule       string `json:"rule"`
+	Position   int    `json:"position"`
+	EntityRelationship
+}
+
+// ValidateInferenceRules checks rules and upper-cases their relation types.
+func ValidateInferenceRules(rules []InferenceRule) ([]InferenceRule, error) {
+	seen := map[string]bool{}
+	out := make([]InferenceRule, 0, len(rules))
+	for _, r := range rules {
+		r.Name = strings.TrimSpace(r.Name)
+		if r.Name == "" {
+			return nil, fmt.Errorf("inference rule needs a name")
+		}
+		if seen[r.Name] {
+			return nil, fmt.Errorf("duplicate inference rule %q", r.Name)
+		}
+		seen[r.Name] = true
+		if len(r.If) == 0 || len(r.If) > MaxInferencePremises {
+			return nil, fmt.Errorf("inference rule %q needs 1 to %d premise types", r.Name, MaxInferencePremises)
+		}
+		premises := make([]string, len(r.If))
+		for i, t := range r.If {
+			premises[i] = strings.ToUpper(strings.TrimSpace(t))
+			if premises[i] == "" {
+				return nil, fmt.Errorf("inference rule %q has an empty premise type", r.Name)
+			}
+		}
+		r.If = premises
+		r.Then = strings.ToUpper(strings.TrimSpace(r.Then))
+		if r.Then == "" {
+			return nil, fmt.Errorf("inference rule %q needs a relation type to infer", r.Name)
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// inferenceEdge is a current edge between entities, stored or inferred in
+// this run, under its canonical relation type.
+type inferenceEdge struct {
+	key        string
+	id         string // stored relationships.id; "" for edges inferred in this run
+	source     string
+	target     string
+	relType    string
+	confidence float64
+}
+
+// derivedEdge is an edge the rules derive, with every chain deriving it.
+type derivedEdge struct {
+	inferenceEdge
+	rule        string
+	derivations [][]string // premise edge keys, in chain order
+	seen        map[string]bool
+}
+
+// inferenceKey names an edge by its canonical ends and type; a symmetric
+// edge has the same key either way round.
+func inferenceKey(source, relType, target string) string {
+	if IsSymmetricRelationType(relType) && target < source {
+		source, target = target, source
+	}
+	return source + "|" + relType + "|" + target
+}
+
+// RunInference applies inference rules to the current edges, stores what
+// they derive with origin inferred and its premises in
+// relationship_premises, and ends inferred edges that no longer follow.
+// Inferred edges feed further inference, but never support themselves:
+// every run starts again from extracted and manual edges. Edges already
+// stated by hand or by extraction are not inferred again.
+func RunInference(ctx context.Context, db *sql.DB, opts InferOptions) (*InferResult, error) {
+	rules := opts.Rules
+	if len(rules) == 0 {
+		rules = DefaultInferenceRules
+	}
+	rules, err := ValidateInferenceRules(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := loadInferenceEdges(ctx, db, rules)
+	if err != nil {
+		return nil, err
+	}
+	derived, order := deriveEdges(rules, base)
+
+	stored, err := loadInferredEdges(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	// Names for fact text, read before the transaction holds the connection
+	names := map[string]string{}
+	for _, key := range order {
+		d := derived[key]
+		for _, id := range []string{d.source, d.target} {
+			if _, ok := names[id]; ok {
+				continue
+			}
+			var name string
+			if err := db.QueryRowContext(ctx, `SELECT canonical_name FROM entities WHERE id = ?`, id).Scan(&name); err != nil {
+				return nil, fmt.Errorf("entity %s: %w", id, err)
+			}
+			names[id] = name
+		}
+	}
+
+	result := &InferResult{Inferred: []InferredFact{}, DryRun: opts.DryRun}
+	var tx *sql.Tx
+	if !opts.DryRun {
+		if tx, err = db.BeginTx(ctx, nil); err != nil {
+			return nil, fmt.Errorf("begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+	}
+
+	// Store the edges first, so every premise has an ID
+	now := time.Now().Format(time.RFC3339)
+	touched := map[string]bool{}
+	for _, key := range order {
+		d := derived[key]
+		if s, ok := stored[key]; ok {
+			d.id = s.id
+			if !s.invalid {
+				result.Unchanged++
+				continue
+			}
+		}
+		fact := NormalizeFactText(names[d.source], d.relType, names[d.target], nil, nil)
+		inferred := InferredFact{
+			RelationshipID: d.id,
+			Rule:           d.rule,
+			SourceEntityID: d.source,
+			RelationType:   d.relType,
+			TargetEntityID: d.target,
+			Fact:           fact,
+			Revived:        d.id != "",
+		}
+		touched[d.source] = true
+		if !opts.DryRun {
+			var err error
+			if d.id != "" {
+				_, err = tx.ExecContext(ctx, `
+					UPDATE relationships SET invalid_at = NULL, fact = ?, confidence = ? WHERE id = ?
+				`, fact, d.confidence, d.id)
+			} else {
+				d.id = uuid.New().String()
+				inferred.RelationshipID = d.id
+				_, err = tx.ExecContext(ctx, `
+					INSERT INTO relationships (
+						id, source_entity_id, target_entity_id, relation_type, fact,
+						created_at, confidence, source_type, origin
+					) VALUES (?, ?, ?, ?, ?, ?, ?, 'inferred', ?)
+				`, d.id, d.source, d.target, d.relType, fact, now, d.confidence, OriginInferred)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("store inferred fact: %w", err)
+			}
+		}
+		result.Inferred = append(result.Inferred, inferred)
+	}
+
+	premiseID := func(key string) string {
+		if e, ok := base[key]; ok {
+			return e.id
+		}
+		return derived[key].id
+	}
+	for i, inferred := range result.Inferred {
+		for _, key := range derived[inferenceKey(inferred.SourceEntityID, inferred.RelationType, inferred.TargetEntityID)].derivations[0] {
+			result.Inferred[i].Premises = append(result.Inferred[i].Premises, premiseID(key))
+		}
+	}
+
+	for key, s := range stored {
+		if _, ok := derived[key]; !ok && !s.invalid {
+			result.Retracted++
+			touched[s.source] = true
+		}
+	}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	for _, key := range order {
+		d := derived[key]
+		if _, err := tx.ExecContext(ctx, `DELETE FROM relationship_premises WHERE relationship_id = ?`, d.id); err != nil {
+			return nil, fmt.Errorf("clear premises: %w", err)
+		}
+		for _, chain := range d.derivations {
+			ids := make([]string, len(chain))
+			for i, key := range chain {
+				ids[i] = premiseID(key)
+			}
+			derivation := strings.Join(ids, ",")
+			for i, id := range ids {
+				if _, err := tx.ExecContext(ctx, `
+					INSERT INTO relationship_premises (relationship_id, derivation, position, premise_id, rule, created_at)
+					VALUES (?, ?, ?, ?, ?, ?)
+				`, d.id, derivation, i, id, d.rule, now); err != nil {
+					return nil, fmt.Errorf("store premise: %w", err)
+				}
+			}
+		}
+	}
+	today := time.Now().Format("2006-01-02")
+	for key, s := range stored {
+		if _, ok := derived[key]; !ok && !s.invalid {
+			if _, err := tx.ExecContext(ctx, `UPDATE relationships SET invalid_at = ? WHERE id = ?`, today, s.id); err != nil {
+				return nil, fmt.Errorf("retract inferred fact: %w", err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	if err := NewCurrentFactsStore(db).RefreshEntities(ctx, sortedKeys(touched)); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// loadInferenceEdges loads the current extracted and manual edges of the
+// types the rules' premises use, by key.
+func loadInferenceEdges(ctx context.Context, db *sql.DB, rules []InferenceRule) (map[string]*inferenceEdge, error) {
+	types := map[string]bool{}
+	for _, r := range rules {
+		for _, t := range r.If {
+			canonical, _ := CanonicalRelationType(t)
+			types[canonical] = true
+		}
+		canonical, _ := CanonicalRelationType(r.Then)
+		types[canonical] = true
+	}
+	list := sortedKeys(types)
+	args := make([]interface{}, len(list))
+	for i, t := range list {
+		args[i] = t
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT r.id, r.source_entity_id, r.target_entity_id, r.relation_type, COALESCE(r.confidence, 1.0)
+		FROM relationships r
+		JOIN entities src ON src.id = r.source_entity_id AND src.merged_into IS NULL
+		JOIN entities tgt ON tgt.id = r.target_entity_id AND tgt.merged_into IS NULL
+		WHERE r.invalid_at IS NULL
+		  AND COALESCE(r.origin, '') != ?
+		  AND r.relation_type IN (`+placeholderList(len(list))+`)
+		ORDER BY r.created_at, r.id
+	`, append([]interface{}{OriginInferred}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("load premise edges: %w", err)
+	}
+	defer rows.Close()
+
+	edges := map[string]*inferenceEdge{}
+	for rows.Next() {
+		var e inferenceEdge
+		if err := rows.Scan(&e.id, &e.source, &e.target, &e.relType, &e.confidence); err != nil {
+			return nil, fmt.Errorf("scan premise edge: %w", err)
+		}
+		e.key = inferenceKey(e.source, e.relType, e.target)
+		if _, ok := edges[e.key]; !ok {
+			edges[e.key] = &e
+		}
+	}
+	return edges, rows.Err()
+}
+
+// deriveEdges applies the rules until nothing new follows, returning the
+// derived edges by key and their keys in the order they were found.
+func deriveEdges(rules []InferenceRule, base map[string]*inferenceEdge) (map[string]*derivedEdge, []string) {
+	derived := map[string]*derivedEdge{}
+	var order []string
+	edge := func(key string) *inferenceEdge {
+		if e, ok := base[key]; ok {
+			return e
+		}
+		return &derived[key].inferenceEdge
+	}
+
+	for round := 0; round < maxInferenceRounds; round++ {
+		// Premise steps by relation type as read in rules: from -> to, edge key
+		steps := map[string]map[string][][2]string{}
+		addStep := func(relType, from, to, key string) {
+			if steps[relType] == nil {
+				steps[relType] = map[string][][2]string{}
+			}
+			steps[relType][from] = append(steps[relType][from], [2]string{to, key})
+		}
+		all := make([]*inferenceEdge, 0, len(base)+len(derived))
+		for _, e := range base {
+			all = append(all, e)
+		}
+		for _, key := range order {
+			all = append(all, &derived[key].inferenceEdge)
+		}
+		for _, r := range rules {
+			for _, t := range r.If {
+				if steps[t] != nil {
+					continue
+				}
+				steps[t] = map[string][][2]string{}
+				canonical, swap := CanonicalRelationType(t)
+				for _, e := range all {
+					if e.relType != canonical {
+						continue
+					}
+					switch {
+					case IsSymmetricRelationType(canonical):
+						addStep(t, e.source, e.target, e.key)
+						addStep(t, e.target, e.source, e.key)
+					case swap:
+						addStep(t, e.target, e.source, e.key)
+					default:
+						addStep(t, e.source, e.target, e.key)
+					}
+				}
+			}
+		}
+
+		grew := false
+		for _, r := range rules {
+			type path struct {
+				nodes []string
+				keys  []string
+			}
+			var paths []path
+			for from, next := range steps[r.If[0]] {
+				for _, step := range next {
+					paths = append(paths, path{nodes: []string{from, step[0]}, keys: []string{step[1]}})
+				}
+			}
+			for _, t := range r.If[1:] {
+				var longer []path
+				for _, p := range paths {
+				extend:
+					for _, step := range steps[t][p.nodes[len(p.nodes)-1]] {
+						for _, n := range p.nodes {
+							if n == step[0] {
+								continue extend
+							}
+						}
+						longer = append(longer, path{
+							nodes: append(append([]string{}, p.nodes...), step[0]),
+							keys:  append(append([]string{}, p.keys...), step[1]),
+						})
+					}
+				}
+				paths = longer
+			}
+			sort.Slice(paths, func(i, j int) bool {
+				return strings.Join(paths[i].keys, ",") < strings.Join(paths[j].keys, ",")
+			})
+
+			canonical, swap := CanonicalRelationType(r.Then)
+			for _, p := range paths {
+				source, target := p.nodes[0], p.nodes[len(p.nodes)-1]
+				if swap {
+					source, target = target, source
+				}
+				if IsSymmetricRelationType(canonical) && target < source {
+					source, target = target, source
+				}
+				key := inferenceKey(source, canonical, target)
+				if _, ok := base[key]; ok {
+					continue
+				}
+				chain := strings.Join(p.keys, ",")
+				d, ok := derived[key]
+				if !ok {
+					d = &derivedEdge{
+						inferenceEdge: inferenceEdge{key: key, source: source, target: target, relType: canonical, confidence: math.Inf(1)},
+						rule:          r.Name,
+						seen:          map[string]bool{},
+					}
+					derived[key] = d
+					order = append(order, key)
+					grew = true
+				}
+				if d.seen[chain] {
+					continue
+				}
+				d.seen[chain] = true
+				d.derivations = append(d.derivations, p.keys)
+				// As sure as its least sure premise, by its best derivation
+				least := 1.0
+				for _, k := range p.keys {
+					least = math.Min(least, edge(k).confidence)
+				}
+				if math.IsInf(d.confidence, 1) || least > d.confidence {
+					d.confidence = least
+				}
+			}
+		}
+		if !grew {
+			break
+		}
+	}
+	return derived, order
+}
+
+type storedInference struct {
+	id      string
+	source  string
+	invalid bool
+}
+
+// loadInferredEdges loads every stored inferred edge by key.
+func loadInferredEdges(ctx context.Context, db *sql.DB) (map[string]storedInference, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, source_entity_id, target_entity_id, relation_type, invalid_at IS NOT NULL
+		FROM relationships
+		WHERE origin = ? AND target_entity_id IS NOT NULL
+		ORDER BY invalid_at IS NULL, created_at
+	`, OriginInferred)
+	if err != nil {
+		return nil, fmt.Errorf("load inferred facts: %w", err)
+	}
+	defer rows.Close()
+	stored := map[string]storedInference{}
+	for rows.Next() {
+		var s storedInference
+		var target, relType string
+		if err := rows.Scan(&s.id, &s.source, &target, &relType, &s.invalid); err != nil {
+			return nil, fmt.Errorf("scan inferred fact: %w", err)
+		}
+		// Current edges come last and win over ended ones
+		stored[inferenceKey(s.source, relType, target)] = s
+	}
+	return stored, rows.Err()
+}
+
+// RetractInferences ends inferred edges that none of their stored
+// derivations still supports, after premises were invalidated or deleted.
+// A derivation holds while all its premises are current, and inferred
+// premises must hold on their own: edges only deriving each other fall
+// together. It returns how many edges were ended.
+func RetractInferences(ctx context.Context, db *sql.DB) (int, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT r.id, r.source_entity_id, COALESCE(p.relationship_id, ''), COALESCE(p.derivation, ''),
+		       COALESCE(p.premise_id, ''), COALESCE(pr.invalid_at IS NULL, 0), COALESCE(pr.origin, '')
+		FROM relationships r
+		LEFT JOIN relationship_premises p ON p.relationship_id = r.id
+		LEFT JOIN relationships pr ON pr.id = p.premise_id
+		WHERE r.origin = ? AND r.invalid_at IS NULL
+	`, OriginInferred)
+	if err != nil {
+		return 0, fmt.Errorf("load inferred facts: %w", err)
+	}
+	type premise struct {
+		id       string
+		current  bool
+		inferred bool
+	}
+	sources := map[string]string{}
+	derivations := map[string]map[string][]premise{} // by edge, then derivation
+	for rows.Next() {
+		var id, source, relID, derivation, premiseID, origin string
+		var current bool
+		if err := rows.Scan(&id, &source, &relID, &derivation, &premiseID, &current, &origin); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan premise: %w", err)
+		}
+		sources[id] = source
+		if derivations[id] == nil {
+			derivations[id] = map[string][]premise{}
+		}
+		if relID != "" {
+			derivations[id][derivation] = append(derivations[id][derivation], premise{premiseID, current, origin == OriginInferred})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// Grow the supported set from edges whose premises are all stated
+	supported := map[string]bool{}
+	for grew := true; grew; {
+		grew = false
+		for id, byDerivation := range derivations {
+			if supported[id] {
+				continue
+			}
+			for derivation, premises := range byDerivation {
+				// Deleted premises take their rows with them
+				ok := len(premises) == len(strings.Split(derivation, ","))
+				for _, p := range premises {
+					ok = ok && p.current && (!p.inferred || supported[p.id])
+				}
+				if ok {
+					supported[id] = true
+					grew = true
+					break
+				}
+			}
+		}
+	}
+
+	today := time.Now().Format("2006-01-02")
+	touched := map[string]bool{}
+	retracted := 0
+	for id, source := range sources {
+		if supported[id] {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, `UPDATE relationships SET invalid_at = ? WHERE id = ?`, today, id); err != nil {
+			return retracted, fmt.Errorf("retract inferred fact: %w", err)
+		}
+		retracted++
+		touched[source] = true
+	}
+	if retracted > 0 {
+		if err := NewCurrentFactsStore(db).RefreshEntities(ctx, sortedKeys(touched)); err != nil {
+			return retracted, err
+		}
+	}
+	return retracted, nil
+}
+
+// GetInferencePremises returns the premises an inferred fact was derived
+// from, by derivation and position.
+func GetInferencePremises(ctx context.Context, db *sql.DB, relationshipID string) ([]InferencePremise, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT derivation, rule, position, premise_id
+		FROM relationship_premises
+		WHERE relationship_id = ?
+		ORDER BY derivation, position
+	`, relationshipID)
+	if err != nil {
+		return nil, fmt.Errorf("get premises: %w", err)
+	}
+	var out []InferencePremise
+	var ids []string
+	for rows.Next() {
+		var p InferencePremise
+		var id string
+		if err := rows.Scan(&p.Derivation, &p.Rule, &p.Position, &id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan premise: %w", err)
+		}
+		out = append(out, p)
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i, id := range ids {
+		rel, err := GetFact(ctx, db, id)
+		if err != nil {
+			return nil, err
+		}
+		out[i].EntityRelationship = *rel
+	}
+	return out, nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestRunInference(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, name := range []string{"Bea", "Alex", "Sam", "Jo"} {
+		insertQueryEngineTestEntity(t, db, strings.ToLower(name), name, EntityTypePerson)
+	}
+	alex, sam, jo := "alex", "sam", "jo"
+	insertQueryEngineTestRelationship(t, db, "r1", "bea", &alex, nil, "PARENT_OF", "Bea is a parent of Alex", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "r2", "alex", &sam, nil, "PARENT_OF", "Alex is a parent of Sam", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "r3", "alex", &jo, nil, "PARENT_OF", "Alex is a parent of Jo", nil, nil)
+
+	inferred := func() string {
+		rows, err := db.Query(`
+			SELECT source_entity_id || ' ' || relation_type || ' ' || target_entity_id FROM relationships
+			WHERE origin = ? AND invalid_at IS NULL
+		`, OriginInferred)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var out []string
+		for rows.Next() {
+			var s string
+			rows.Scan(&s)
+			out = append(out, s)
+		}
+		sort.Strings(out)
+		return strings.Join(out, ",")
+	}
+
+	dry, err := RunInference(ctx, db, InferOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dry.Inferred) != 3 || !dry.DryRun || inferred() != "" {
+		t.Fatalf("dry run = %+v, stored %q", dry, inferred())
+	}
+
+	res, err := RunInference(ctx, db, InferOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Inferred) != 3 || res.Retracted != 0 {
+		t.Fatalf("first run = %+v", res)
+	}
+	if got := inferred(); got != "bea GRANDPARENT_OF jo,bea GRANDPARENT_OF sam,jo SIBLING_OF sam" &&
+		got != "bea GRANDPARENT_OF jo,bea GRANDPARENT_OF sam,sam SIBLING_OF jo" {
+		t.Errorf("inferred = %q", got)
+	}
+	var grandID, fact string
+	var confidence float64
+	if err := db.QueryRow(`
+		SELECT id, fact, confidence FROM relationships WHERE relation_type = 'GRANDPARENT_OF' AND target_entity_id = 'sam'
+	`).Scan(&grandID, &fact, &confidence); err != nil {
+		t.Fatal(err)
+	}
+	if fact != "Bea is a grandparent of Sam" || confidence != 1.0 {
+		t.Errorf("grandparent fact = %q (%v)", fact, confidence)
+	}
+	premises, err := GetInferencePremises(ctx, db, grandID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(premises) != 2 || premises[0].ID != "r1" || premises[1].ID != "r2" || premises[0].Rule != "grandparent" {
+		t.Errorf("premises = %+v", premises)
+	}
+
+	// A second run changes nothing
+	res, err = RunInference(ctx, db, InferOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Inferred) != 0 || res.Unchanged != 3 {
+		t.Errorf("second run = %+v", res)
+	}
+
+	// Ending a premise retracts what followed from it
+	if _, err := InvalidateFact(ctx, db, "r1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := inferred(); strings.Contains(got, "GRANDPARENT_OF") || !strings.Contains(got, "SIBLING_OF") {
+		t.Errorf("after invalidating r1 = %q", got)
+	}
+
+	// The sibling facts are gone with the shared parent, even though a
+	// later run could chain them through each other
+	if err := DeleteFact(ctx, db, "r2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := inferred(); got != "" {
+		t.Errorf("after deleting r2 = %q", got)
+	}
+	res, err = RunInference(ctx, db, InferOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Inferred) != 0 || inferred() != "" {
+		t.Errorf("run after retraction = %+v", res)
+	}
+}
+
+func TestRunInference_SiblingCycle(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, name := range []string{"Ann", "Ben", "Cal"} {
+		insertQueryEngineTestEntity(t, db, strings.ToLower(name), name, EntityTypePerson)
+	}
+	ben, cal := "ben", "cal"
+	insertQueryEngineTestRelationship(t, db, "r1", "ann", &ben, nil, "SIBLING_OF", "Ann and Ben are siblings", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "r2", "ben", &cal, nil, "SIBLING_OF", "Ben and Cal are siblings", nil, nil)
+
+	res, err := RunInference(ctx, db, InferOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Inferred) != 1 || res.Inferred[0].Rule != "sibling_of_sibling" {
+		t.Fatalf("run = %+v", res)
+	}
+
+	if _, err := InvalidateFact(ctx, db, "r2", ""); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	db.QueryRow(`SELECT COUNT(*) FROM relationships WHERE origin = ? AND invalid_at IS NULL`, OriginInferred).Scan(&n)
+	if n != 0 {
+		t.Errorf("%d inferred facts left", n)
+	}
+}
+
+func TestValidateInferenceRules(t *testing.T) {
+	rules, err := ValidateInferenceRules([]InferenceRule{{Name: "aunt", If: []string{"sibling_of", "parent_of"}, Then: "aunt_of"}})
+	if err != nil || rules[0].If[1] != "PARENT_OF" || rules[0].Then != "AUNT_OF" {
+		t.Errorf("rules = %+v (%v)", rules, err)
+	}
+	for _, bad := range []InferenceRule{
+		{Name: "", If: []string{"PARENT_OF", "PARENT_OF"}, Then: "GRANDPARENT_OF"},
+		{Name: "x", If: nil, Then: "X"},
+		{Name: "x", If: []string{"A", "B", "C", "D", "E"}, Then: "X"},
+		{Name: "x", If: []string{"A", "B"}},
+	} {
+		if _, err := ValidateInferenceRules([]InferenceRule{bad}); err == nil {
+			t.Errorf("%+v: no error", bad)
+		}
+	}
+}
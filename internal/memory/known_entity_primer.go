@@ -0,0 +1,266 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultKnownEntityTokenBudget is the default prompt budget (in estimated
+	// tokens) spent on primed known entities beyond the thread participants.
+	DefaultKnownEntityTokenBudget = 400
+
+	// DefaultMaxAliasesPerKnownEntity caps how many alias variants are listed per entity.
+	DefaultMaxAliasesPerKnownEntity = 3
+
+	// defaultPrimerCandidateLimit bounds how many contacts are considered for priming.
+	defaultPrimerCandidateLimit = 200
+)
+
+// KnownEntityPrimerConfig controls how contacts are selected for priming.
+type KnownEntityPrimerConfig struct {
+	// Estimated token budget for primed entities (participants are always kept)
+	TokenBudget int
+	// Maximum alias variants listed per primed entity
+	MaxAliases int
+	// Only count events newer than this window when ranking correspondents (0 = all time)
+	Lookback time.Duration
+}
+
+// DefaultKnownEntityPrimerConfig returns the default priming configuration.
+func DefaultKnownEntityPrimerConfig() KnownEntityPrimerConfig {
+	return KnownEntityPrimerConfig{
+		TokenBudget: DefaultKnownEntityTokenBudget,
+		MaxAliases:  DefaultMaxAliasesPerKnownEntity,
+	}
+}
+
+// primerCandidate is a person from the contacts graph ranked for priming.
+type primerCandidate struct {
+	PersonID   string
+	Name       string
+	Aliases    []string
+	EventCount int
+	Starred    bool // Curated person (is_me or relationship_type set)
+}
+
+// KnownEntityPrimer selects high-signal contacts (frequent correspondents and
+// starred people) to add to the extractor's known entities. Selection is
+// token-budgeted so a large address book never blows up the prompt. Only
+// names are primed; emails and handles are identifiers, not aliases.
+// Safe for concurrent use.
+type KnownEntityPrimer struct {
+	db     *sql.DB
+	config KnownEntityPrimerConfig
+
+	// Ranked candidates, loaded once per primer (one pipeline run)
+	once       sync.Once
+	candidates []primerCandidate
+	loadErr    error
+}
+
+// NewKnownEntityPrimer creates a new KnownEntityPrimer.
+func NewKnownEntityPrimer(db *sql.DB, config KnownEntityPrimerConfig) *KnownEntityPrimer {
+	if config.MaxAliases <= 0 {
+		config.MaxAliases = DefaultMaxAliasesPerKnownEntity
+	}
+	return &KnownEntityPrimer{db: db, config: config}
+}
+
+// Prime returns the base known entities followed by primed contacts that fit
+// in the token budget. Base entities (e.g., thread participants) are always kept
+// and are enriched with alias variants when they match a primed contact.
+func (p *KnownEntityPrimer) Prime(ctx context.Context, base []KnownEntity) ([]KnownEntity, error) {
+	if err := p.load(ctx); err != nil {
+		return base, err
+	}
+
+	byName := make(map[string]primerCandidate, len(p.candidates))
+	for _, c := range p.candidates {
+		byName[normalizeAlias(c.Name)] = c
+	}
+
+	result := make([]KnownEntity, 0, len(base)+len(p.candidates))
+	seen := make(map[string]bool, len(base))
+	for _, ke := range base {
+		key := normalizeAlias(ke.Name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if c, ok := byName[key]; ok && len(ke.Aliases) == 0 {
+			ke.Aliases = c.Aliases
+		}
+		result = append(result, ke)
+	}
+
+	remaining := p.config.TokenBudget
+	for _, c := range p.candidates {
+		key := normalizeAlias(c.Name)
+		if seen[key] {
+			continue
+		}
+		ke := KnownEntity{
+			Name:       c.Name,
+			EntityType: "Person",
+			Aliases:    c.Aliases,
+		}
+		cost := estimateKnownEntityTokens(ke)
+		if cost > remaining {
+			// Try again without aliases before giving up on this candidate
+			ke.Aliases = nil
+			cost = estimateKnownEntityTokens(ke)
+			if cost > remaining {
+				break
+			}
+		}
+		remaining -= cost
+		seen[key] = true
+		result = append(result, ke)
+	}
+
+	return result, nil
+}
+
+// load fetches and ranks candidate contacts once. A failed load is not retried
+// (e.g., a memory-only database without contact tables), leaving no candidates.
+func (p *KnownEntityPrimer) load(ctx context.Context) error {
+	p.once.Do(func() {
+		p.candidates, p.loadErr = p.rankCandidates(ctx)
+	})
+	return p.loadErr
+}
+
+// rankCandidates returns persons ordered by priming priority:
+// starred people first, then by number of events exchanged.
+func (p *KnownEntityPrimer) rankCandidates(ctx context.Context) ([]primerCandidate, error) {
+	var since int64
+	if p.config.Lookback > 0 {
+		since = time.Now().Add(-p.config.Lookback).Unix()
+	}
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT p.id, p.canonical_name, COALESCE(p.display_name, ''),
+		       p.is_me, COALESCE(p.relationship_type, ''),
+		       COALESCE(ec.event_count, 0)
+		FROM persons p
+		LEFT JOIN (
+			SELECT pcl.person_id, COUNT(DISTINCT ep.event_id) AS event_count
+			FROM person_contact_links pcl
+			JOIN event_participants ep ON ep.contact_id = pcl.contact_id
+			JOIN events e ON e.id = ep.event_id
+			WHERE e.timestamp >= ?
+			GROUP BY pcl.person_id
+		) ec ON ec.person_id = p.id
+		WHERE p.is_me = 1
+		   OR COALESCE(p.relationship_type, '') != ''
+		   OR COALESCE(ec.event_count, 0) > 0
+		ORDER BY (p.is_me = 1 OR COALESCE(p.relationship_type, '') != '') DESC,
+		         COALESCE(ec.event_count, 0) DESC,
+		         p.canonical_name
+		LIMIT ?
+	`, since, defaultPrimerCandidateLimit)
+	if err != nil {
+		return nil, fmt.Errorf("query priming candidates: %w", err)
+	}
+
+	// Collect all rows first (SQLite single connection)
+	type candidateRow struct {
+		candidate   primerCandidate
+		displayName string
+	}
+	var collected []candidateRow
+	for rows.Next() {
+		var r candidateRow
+		var isMe int
+		var relType string
+		if err := rows.Scan(&r.candidate.PersonID, &r.candidate.Name, &r.displayName, &isMe, &relType, &r.candidate.EventCount); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan priming candidate: %w", err)
+		}
+		r.candidate.Starred = isMe == 1 || relType != ""
+		collected = append(collected, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate priming candidates: %w", err)
+	}
+
+	candidates := make([]primerCandidate, 0, len(collected))
+	for _, r := range collected {
+		c := r.candidate
+		if strings.TrimSpace(c.Name) == "" {
+			continue
+		}
+		variants := []string{r.displayName}
+		contactVariants, err := p.contactNames(ctx, c.PersonID)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, contactVariants...)
+		c.Aliases = dedupeAliases(c.Name, variants, p.config.MaxAliases)
+		candidates = append(candidates, c)
+	}
+
+	return candidates, nil
+}
+
+// contactNames returns the display names of a person's contacts.
+func (p *KnownEntityPrimer) contactNames(ctx context.Context, personID string) ([]string, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT c.display_name
+		FROM person_contact_links pcl
+		JOIN contacts c ON c.id = pcl.contact_id
+		WHERE pcl.person_id = ? AND COALESCE(c.display_name, '') != ''
+		ORDER BY c.display_name
+	`, personID)
+	if err != nil {
+		return nil, fmt.Errorf("query contact names: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scan contact name: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// dedupeAliases drops empty variants, identifiers standing in for a name
+// (an email or phone number as display name) and those equal to the
+// canonical name, keeping at most max.
+func dedupeAliases(name string, variants []string, max int) []string {
+	seen := map[string]bool{normalizeAlias(name): true}
+	var out []string
+	for _, v := range variants {
+		v = strings.TrimSpace(v)
+		key := normalizeAlias(v)
+		if key == "" || seen[key] || !looksLikeContactName(v) {
+			continue
+		}
+		seen[key] = true
+		out = append(out, v)
+		if len(out) >= max {
+			break
+		}
+	}
+	return out
+}
+
+// estimateKnownEntityTokens approximates the prompt cost of one KNOWN_ENTITIES line
+// using the common ~4 characters per token heuristic.
+func estimateKnownEntityTokens(ke KnownEntity) int {
+	chars := len(ke.Name) + len(ke.EntityType) + 6
+	if len(ke.Aliases) > 0 {
+		chars += 5 + len(strings.Join(ke.Aliases, ", "))
+	}
+	return chars/4 + 1
+}
@@ -0,0 +1,233 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// setupPrimerTestDB creates an in-memory database with the contact tables used for priming.
+func setupPrimerTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	schema := `
+		CREATE TABLE persons (
+			id TEXT PRIMARY KEY,
+			canonical_name TEXT NOT NULL,
+			display_name TEXT,
+			is_me INTEGER DEFAULT 0,
+			relationship_type TEXT,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL
+		);
+		CREATE TABLE contacts (
+			id TEXT PRIMARY KEY,
+			display_name TEXT,
+			source TEXT,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL
+		);
+		CREATE TABLE contact_identifiers (
+			id TEXT PRIMARY KEY,
+			contact_id TEXT NOT NULL,
+			type TEXT NOT NULL,
+			value TEXT NOT NULL,
+			normalized TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			last_seen_at INTEGER
+		);
+		CREATE TABLE person_contact_links (
+			id TEXT PRIMARY KEY,
+			person_id TEXT NOT NULL,
+			contact_id TEXT NOT NULL,
+			confidence REAL DEFAULT 1.0,
+			source_type TEXT,
+			first_seen_at INTEGER,
+			last_seen_at INTEGER
+		);
+		CREATE TABLE events (
+			id TEXT PRIMARY KEY,
+			timestamp INTEGER NOT NULL
+		);
+		CREATE TABLE event_participants (
+			event_id TEXT NOT NULL,
+			contact_id TEXT NOT NULL,
+			role TEXT NOT NULL,
+			PRIMARY KEY (event_id, contact_id, role)
+		);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	return db
+}
+
+// insertPrimerPerson inserts a person with one linked contact and n events.
+func insertPrimerPerson(t *testing.T, db *sql.DB, id, name, contactName, relType string, events int, identifiers map[string]string) {
+	t.Helper()
+	var rel interface{}
+	if relType != "" {
+		rel = relType
+	}
+	mustExec := func(query string, args ...interface{}) {
+		if _, err := db.Exec(query, args...); err != nil {
+			t.Fatalf("exec %q: %v", query, err)
+		}
+	}
+	contactID := "c-" + id
+	mustExec(`INSERT INTO persons (id, canonical_name, relationship_type, created_at, updated_at) VALUES (?, ?, ?, 0, 0)`, id, name, rel)
+	mustExec(`INSERT INTO contacts (id, display_name, created_at, updated_at) VALUES (?, ?, 0, 0)`, contactID, contactName)
+	mustExec(`INSERT INTO person_contact_links (id, person_id, contact_id) VALUES (?, ?, ?)`, "l-"+id, id, contactID)
+	for typ, value := range identifiers {
+		mustExec(`INSERT INTO contact_identifiers (id, contact_id, type, value, normalized, created_at) VALUES (?, ?, ?, ?, ?, 0)`,
+			"ci-"+id+"-"+typ, contactID, typ, value, value)
+	}
+	for i := 0; i < events; i++ {
+		eventID := fmt.Sprintf("ev-%s-%d", id, i)
+		mustExec(`INSERT INTO events (id, timestamp) VALUES (?, ?)`, eventID, 1700000000+i)
+		mustExec(`INSERT INTO event_participants (event_id, contact_id, role) VALUES (?, ?, 'sender')`, eventID, contactID)
+	}
+}
+
+func TestKnownEntityPrimer_Prime(t *testing.T) {
+	db := setupPrimerTestDB(t)
+	defer db.Close()
+
+	insertPrimerPerson(t, db, "p1", "Casey Adams", "Case", "", 10, map[string]string{"email": "casey@example.com", "phone": "+15550001111"})
+	insertPrimerPerson(t, db, "p2", "Mom", "Mom", "family", 1, nil)
+	insertPrimerPerson(t, db, "p3", "Jordan Lee", "Jordan Lee", "", 3, nil)
+	insertPrimerPerson(t, db, "p4", "Dormant Contact", "", "", 0, nil)
+
+	primer := NewKnownEntityPrimer(db, DefaultKnownEntityPrimerConfig())
+	got, err := primer.Prime(context.Background(), []KnownEntity{{Name: "Jordan Lee", EntityType: "Person"}})
+	if err != nil {
+		t.Fatalf("Prime() error = %v", err)
+	}
+
+	var names []string
+	for _, ke := range got {
+		names = append(names, ke.Name)
+	}
+	// Participants first, then starred, then by event count; dormant contacts skipped
+	want := []string{"Jordan Lee", "Mom", "Casey Adams"}
+	if fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Fatalf("Prime() names = %v, want %v", names, want)
+	}
+
+	casey := got[2]
+	if fmt.Sprint(casey.Aliases) != fmt.Sprint([]string{"Case"}) {
+		t.Errorf("Casey aliases = %v, want the display name only (no email or phone)", casey.Aliases)
+	}
+	if len(got[1].Aliases) != 0 {
+		t.Errorf("Mom aliases = %v, want none (display name equals canonical)", got[1].Aliases)
+	}
+}
+
+func TestKnownEntityPrimer_ConcurrentNamesOnly(t *testing.T) {
+	db := setupPrimerTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	insertPrimerPerson(t, db, "p1", "Casey Adams", "casey.adams@work.example", "", 5, map[string]string{"handle": "@casey"})
+	insertPrimerPerson(t, db, "p2", "Jordan Lee", "+1 555 010 0199", "", 3, nil)
+
+	primer := NewKnownEntityPrimer(db, DefaultKnownEntityPrimerConfig())
+	var wg sync.WaitGroup
+	results := make([][]KnownEntity, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = primer.Prime(context.Background(), nil)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, got := range results {
+		if len(got) != 2 {
+			t.Fatalf("Prime() = %v, want both people", got)
+		}
+		for _, ke := range got {
+			if len(ke.Aliases) != 0 {
+				t.Errorf("%s aliases = %v, want no identifiers", ke.Name, ke.Aliases)
+			}
+		}
+	}
+}
+
+func TestKnownEntityPrimer_TokenBudget(t *testing.T) {
+	db := setupPrimerTestDB(t)
+	defer db.Close()
+
+	for i := 0; i < 50; i++ {
+		id := fmt.Sprintf("p%02d", i)
+		insertPrimerPerson(t, db, id, fmt.Sprintf("Person Number %02d", i), fmt.Sprintf("Nickname %02d", i), "", 50-i, nil)
+	}
+
+	config := DefaultKnownEntityPrimerConfig()
+	config.TokenBudget = 40
+	primer := NewKnownEntityPrimer(db, config)
+
+	got, err := primer.Prime(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Prime() error = %v", err)
+	}
+	if len(got) == 0 || len(got) >= 50 {
+		t.Fatalf("Prime() returned %d entities, want a budget-limited subset", len(got))
+	}
+
+	spent := 0
+	for _, ke := range got {
+		spent += estimateKnownEntityTokens(ke)
+	}
+	if spent > config.TokenBudget {
+		t.Errorf("spent %d tokens, budget %d", spent, config.TokenBudget)
+	}
+	if got[0].Name != "Person Number 00" {
+		t.Errorf("first primed = %q, want most frequent correspondent", got[0].Name)
+	}
+}
+
+func TestKnownEntityPrimer_MissingTables(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	base := []KnownEntity{{Name: "Tyler", EntityType: "Person"}}
+	primer := NewKnownEntityPrimer(db, DefaultKnownEntityPrimerConfig())
+	got, err := primer.Prime(context.Background(), base)
+	if err == nil {
+		t.Fatal("Prime() expected error without contact tables")
+	}
+	if len(got) != 1 || got[0].Name != "Tyler" {
+		t.Errorf("Prime() = %v, want base entities unchanged", got)
+	}
+}
+
+func TestEntityExtractor_buildPromptKnownEntityAliases(t *testing.T) {
+	extractor := NewEntityExtractor(nil, "test-model")
+	prompt := extractor.buildPrompt(EntityExtractionInput{
+		EpisodeContent: "Case said hi.",
+		KnownEntities: []KnownEntity{
+			{Name: "Casey Adams", EntityType: "Person", Aliases: []string{"Case", "casey@example.com"}},
+			{Name: "Jordan", EntityType: "Person"},
+		},
+	})
+	for _, want := range []string{
+		"- Casey Adams (Person) aka Case, casey@example.com",
+		"- Jordan (Person)\n",
+	} {
+		if !contains(prompt, want) {
+			t.Errorf("prompt should contain %q", want)
+		}
+	}
+}
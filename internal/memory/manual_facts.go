@@ -0,0 +1,556 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ManualFact is a fact entered by hand as ground truth. Source and Target
+// are entity IDs or exact names; Target is the literal value for literal
+// relation types (BORN_ON 1990-04-12, HAS_SIZE M).
+type ManualFact struct {
+	Source       string
+	RelationType string
+	Target       string
+	Fact         string // generated from the endpoints when empty
+	ValidAt      string // YYYY, YYYY-MM, YYYY-MM-DD or RFC 3339
+	InvalidAt    string
+	// SourceTypeID and TargetTypeID set the type of an endpoint that does not
+	// exist yet. Zero takes the relation type's only expected type, if any.
+	SourceTypeID int
+	TargetTypeID int
+	// AllowNewType accepts a relation type the ontology does not know.
+	AllowNewType bool
+}
+
+// ManualFactResult describes what AddManualFact wrote.
+type ManualFactResult struct {
+	Relationship    *EntityRelationship `json:"relationship"`
+	Existing        bool                `json:"existing,omitempty"` // the fact was already known and is now marked manual
+	CreatedEntities []string            `json:"created_entities,omitempty"`
+	// Invalidated lists facts the new one superseded (CardinalityOne types).
+	Invalidated []string `json:"invalidated,omitempty"`
+	// ViolationsFlagged counts conflicts recorded for review instead.
+	ViolationsFlagged int `json:"violations_flagged,omitempty"`
+}
+
+// FactEdit changes a fact. Nil fields are left alone; an empty ValidAt or
+// InvalidAt clears the date.
+type FactEdit struct {
+	Fact      *string
+	Target    *string
+	ValidAt   *string
+	InvalidAt *string
+}
+
+var relationTypePattern = regexp.MustCompile(`^[A-Z][A-Z0-9]*(_[A-Z0-9]+)*$`)
+
+// KnownRelationType reports whether the ontology declares relType: it has a
+// type signature, a cardinality rule, an inverse, or a literal target.
+func KnownRelationType(relType string) bool {
+	if InverseRelationType(relType) != "" {
+		return true
+	}
+	if _, ok := RelationTypeSignatures[relType]; ok {
+		return true
+	}
+	if _, ok := DefaultCardinalityRules[relType]; ok {
+		return true
+	}
+	return IsLiteralTargetRelationType(relType)
+}
+
+// validateFactDate accepts the date formats extraction produces.
+func validateFactDate(field, value string) error {
+	if _, err := parseGraphQueryTime(value); err != nil {
+		return fmt.Errorf("invalid %s %q (want YYYY, YYYY-MM, YYYY-MM-DD or RFC 3339)", field, value)
+	}
+	return nil
+}
+
+// validateFactDates checks both dates and that the fact does not end before
+// it starts. ISO dates of mixed precision compare correctly as strings.
+func validateFactDates(validAt, invalidAt string) error {
+	if validAt != "" {
+		if err := validateFactDate("valid_at", validAt); err != nil {
+			return err
+		}
+	}
+	if invalidAt != "" {
+		if err := validateFactDate("invalid_at", invalidAt); err != nil {
+			return err
+		}
+	}
+	if validAt != "" && invalidAt != "" && invalidAt < validAt {
+		return fmt.Errorf("invalid_at %s is before valid_at %s", invalidAt, validAt)
+	}
+	return nil
+}
+
+// factEndpoint is a resolved (or to-be-created) end of a manual fact.
+type factEndpoint struct {
+	ID     string
+	Name   string
+	TypeID int
+	New    bool
+}
+
+// resolveFactEndpoint finds the active entity with ID or exact name ref
+// (canonical name first, then name aliases). A missing entity is returned
+// with New set and its type taken from typeID or the only type in expected.
+func resolveFactEndpoint(ctx context.Context, db *sql.DB, ref string, typeID int, expected []int, role string) (*factEndpoint, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, fmt.Errorf("%s is required", role)
+	}
+
+	var ep factEndpoint
+	var mergedInto sql.NullString
+	err := db.QueryRowContext(ctx, `
+		SELECT id, canonical_name, entity_type_id, merged_into FROM entities WHERE id = ?
+	`, ref).Scan(&ep.ID, &ep.Name, &ep.TypeID, &mergedInto)
+	if err == nil {
+		if mergedInto.Valid {
+			return nil, fmt.Errorf("%s %s was merged into %s", role, ref, mergedInto.String)
+		}
+		return &ep, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("get %s: %w", role, err)
+	}
+
+	matches, err := entitiesNamed(ctx, db, ref)
+	if err != nil {
+		return nil, fmt.Errorf("find %s: %w", role, err)
+	}
+	switch {
+	case len(matches) == 1:
+		return &matches[0], nil
+	case len(matches) > 1:
+		ids := make([]string, len(matches))
+		for i, m := range matches {
+			ids[i] = m.ID
+		}
+		return nil, fmt.Errorf("%s %q is ambiguous (%s); pass an entity ID", role, ref, strings.Join(ids, ", "))
+	}
+
+	if typeID == 0 {
+		if len(expected) != 1 {
+			return nil, fmt.Errorf("%s %q not found and its type is unknown; pass the %s type", role, ref, role)
+		}
+		typeID = expected[0]
+	}
+	if !IsValidEntityTypeID(typeID) {
+		return nil, fmt.Errorf("unknown entity type %d", typeID)
+	}
+	return &factEndpoint{Name: ref, TypeID: typeID, New: true}, nil
+}
+
+// entitiesNamed returns active entities whose canonical name, or failing
+// that a name alias, equals name (case-insensitive).
+func entitiesNamed(ctx context.Context, db *sql.DB, name string) ([]factEndpoint, error) {
+	for _, lookup := range []struct{ query, arg string }{
+		{`
+			SELECT id, canonical_name, entity_type_id FROM entities
+			WHERE merged_into IS NULL AND LOWER(canonical_name) = LOWER(?)
+			ORDER BY id
+		`, name},
+		{`
+			SELECT DISTINCT e.id, e.canonical_name, e.entity_type_id
+			FROM entity_aliases a JOIN entities e ON e.id = a.entity_id
+			WHERE e.merged_into IS NULL AND a.alias_type = 'name' AND a.normalized = ?
+			ORDER BY e.id
+		`, normalizeAlias(name)},
+	} {
+		rows, err := db.QueryContext(ctx, lookup.query, lookup.arg)
+		if err != nil {
+			return nil, err
+		}
+		var matches []factEndpoint
+		for rows.Next() {
+			var ep factEndpoint
+			if err := rows.Scan(&ep.ID, &ep.Name, &ep.TypeID); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			matches = append(matches, ep)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		if len(matches) > 0 {
+			return matches, nil
+		}
+	}
+	return nil, nil
+}
+
+// checkEndpointType rejects an endpoint whose type the relation type's
+// signature does not allow.
+func checkEndpointType(ep *factEndpoint, expected []int, relType, role string) error {
+	if len(expected) == 0 {
+		return nil
+	}
+	for _, t := range expected {
+		if t == ep.TypeID {
+			return nil
+		}
+	}
+	names := make([]string, len(expected))
+	for i, t := range expected {
+		names[i] = entityTypeName(t)
+	}
+	return fmt.Errorf("%s expects a %s of type %s, but %q is %s",
+		relType, role, strings.Join(names, " or "), ep.Name, entityTypeName(ep.TypeID))
+}
+
+// createManualEntity creates an endpoint entity with origin manual.
+func createManualEntity(ctx context.Context, db *sql.DB, ep *factEndpoint) error {
+	ep.ID = uuid.New().String()
+	now := time.Now().Format(time.RFC3339)
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO entities (id, canonical_name, entity_type_id, origin, confidence, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 1.0, ?, ?)
+	`, ep.ID, ep.Name, ep.TypeID, OriginManual, now, now); err != nil {
+		return fmt.Errorf("create entity %q: %w", ep.Name, err)
+	}
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO entity_aliases (id, entity_id, alias, alias_type, normalized, is_shared, created_at)
+		VALUES (?, ?, ?, 'name', ?, FALSE, ?)
+	`, uuid.New().String(), ep.ID, ep.Name, normalizeAlias(ep.Name), now); err != nil {
+		return fmt.Errorf("create alias %q: %w", ep.Name, err)
+	}
+	if err := NewBlockingIndex(db).IndexAlias(ctx, ep.ID, ep.Name, "name"); err != nil {
+		// Non-fatal - IndexMissing catches up on the next run
+		_ = err
+	}
+	return nil
+}
+
+// validateLiteralTarget checks a literal target value for relType.
+func validateLiteralTarget(relType, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s needs a value", relType)
+	}
+	if isTemporalRelationType(relType) {
+		return validateFactDate(strings.ToLower(relType)+" value", value)
+	}
+	return nil
+}
+
+// defaultFactText phrases a fact from its endpoints: "Tyler works at Anthropic".
+func defaultFactText(source, relType, target string) string {
+	return NormalizeFactText(source, relType, target, nil, nil)
+}
+
+// AddManualFact records a fact entered by hand with origin manual and full
+// confidence. Endpoints are checked against the relation type's signature;
+// endpoints that do not exist yet are created as manual entities. If the
+// same fact already exists it is marked manual instead of duplicated. Like
+// extracted facts, a new fact of a CardinalityOne type supersedes (or is
+// flagged against) the source's other current facts of that type, and an
+// inverse type is stored as its canonical type with the ends swapped.
+func AddManualFact(ctx context.Context, db *sql.DB, f ManualFact) (*ManualFactResult, error) {
+	relType := strings.ToUpper(strings.TrimSpace(f.RelationType))
+	if !relationTypePattern.MatchString(relType) {
+		return nil, fmt.Errorf("invalid relation type %q (want SCREAMING_SNAKE_CASE, e.g. WORKS_AT)", f.RelationType)
+	}
+	if IsIdentityRelationType(relType) {
+		return nil, fmt.Errorf("%s is an identity relation; identifiers are stored as entity aliases, not facts", relType)
+	}
+	if !f.AllowNewType && !KnownRelationType(relType) {
+		return nil, fmt.Errorf("relation type %s is not in the ontology", relType)
+	}
+	if err := validateFactDates(f.ValidAt, f.InvalidAt); err != nil {
+		return nil, err
+	}
+	// Inverse types are stored as their canonical type (EMPLOYS as WORKS_AT)
+	if canonical, swap := CanonicalRelationType(relType); swap {
+		relType = canonical
+		f.Source, f.Target = f.Target, f.Source
+		f.SourceTypeID, f.TargetTypeID = f.TargetTypeID, f.SourceTypeID
+	}
+
+	sig := RelationTypeSignatures[relType]
+	source, err := resolveFactEndpoint(ctx, db, f.Source, f.SourceTypeID, sig.Source, "source")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkEndpointType(source, sig.Source, relType, "source"); err != nil {
+		return nil, err
+	}
+
+	var target *factEndpoint
+	literal := strings.TrimSpace(f.Target)
+	if IsLiteralTargetRelationType(relType) {
+		if err := validateLiteralTarget(relType, literal); err != nil {
+			return nil, err
+		}
+	} else {
+		target, err = resolveFactEndpoint(ctx, db, f.Target, f.TargetTypeID, sig.Target, "target")
+		if err != nil {
+			return nil, err
+		}
+		if err := checkEndpointType(target, sig.Target, relType, "target"); err != nil {
+			return nil, err
+		}
+		if !source.New && !target.New && source.ID == target.ID {
+			return nil, fmt.Errorf("source and target are the same entity")
+		}
+	}
+
+	result := &ManualFactResult{}
+	for _, ep := range []*factEndpoint{source, target} {
+		if ep == nil || !ep.New {
+			continue
+		}
+		if err := createManualEntity(ctx, db, ep); err != nil {
+			return nil, err
+		}
+		result.CreatedEntities = append(result.CreatedEntities, ep.ID)
+	}
+
+	targetName := literal
+	var targetEntityID, targetLiteral interface{} = nil, literal
+	if target != nil {
+		targetName = target.Name
+		targetEntityID, targetLiteral = target.ID, nil
+	}
+	fact := strings.TrimSpace(f.Fact)
+	if fact == "" {
+		fact = defaultFactText(source.Name, relType, targetName)
+	}
+
+	var existingID string
+	err = db.QueryRowContext(ctx, `
+		SELECT id FROM relationships
+		WHERE source_entity_id = ?
+		  AND relation_type = ?
+		  AND (target_entity_id = ? OR target_literal = ?)
+		  AND (valid_at IS NULL AND ? IS NULL OR valid_at = ?)
+	`, source.ID, relType, targetEntityID, targetLiteral, nullIfEmpty(f.ValidAt), nullIfEmpty(f.ValidAt)).Scan(&existingID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("find existing fact: %w", err)
+	}
+
+	id := existingID
+	if existingID != "" {
+		result.Existing = true
+		_, err = db.ExecContext(ctx, `
+			UPDATE relationships
+			SET fact = ?, invalid_at = ?, confidence = 1.0, origin = ?
+			WHERE id = ?
+		`, fact, nullIfEmpty(f.InvalidAt), OriginManual, existingID)
+		if err != nil {
+			return nil, fmt.Errorf("update fact: %w", err)
+		}
+	} else {
+		id = uuid.New().String()
+		_, err = db.ExecContext(ctx, `
+			INSERT INTO relationships (
+				id, source_entity_id, target_entity_id, target_literal,
+				relation_type, fact, valid_at, invalid_at, created_at, confidence, origin
+			)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1.0, ?)
+		`, id, source.ID, targetEntityID, targetLiteral, relType, fact,
+			nullIfEmpty(f.ValidAt), nullIfEmpty(f.InvalidAt), time.Now().Format(time.RFC3339), OriginManual)
+		if err != nil {
+			return nil, fmt.Errorf("insert fact: %w", err)
+		}
+	}
+
+	if f.InvalidAt == "" {
+		detected, err := NewContradictionDetector(db).Detect(ctx, []string{id}, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		result.Invalidated = detected.InvalidatedIDs
+		result.ViolationsFlagged = detected.ViolationsFlagged
+	}
+
+	if err := NewCurrentFactsStore(db).RefreshEntities(ctx, []string{source.ID}); err != nil {
+		return nil, err
+	}
+	result.Relationship, err = GetFact(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetFact returns a relationship by ID, or an error if it does not exist.
+func GetFact(ctx context.Context, db *sql.DB, id string) (*EntityRelationship, error) {
+	var rel EntityRelationship
+	var targetEntityID, targetName, targetLiteral, validAt, invalidAt sql.NullString
+	err := db.QueryRowContext(ctx, `
+		SELECT r.id, r.source_entity_id, src.canonical_name, r.target_entity_id, tgt.canonical_name,
+		       r.target_literal, r.relation_type, r.fact, r.valid_at, r.invalid_at, r.created_at,
+		       COALESCE(r.confidence, 1.0)
+		FROM relationships r
+		JOIN entities src ON src.id = r.source_entity_id
+		LEFT JOIN entities tgt ON tgt.id = r.target_entity_id
+		WHERE r.id = ?
+	`, id).Scan(&rel.ID, &rel.SourceEntityID, &rel.SourceName, &targetEntityID, &targetName,
+		&targetLiteral, &rel.RelationType, &rel.Fact, &validAt, &invalidAt, &rel.CreatedAt, &rel.Confidence)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("fact not found: %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get fact: %w", err)
+	}
+	rel.Direction = "outgoing"
+	if targetEntityID.Valid {
+		rel.TargetEntityID = &targetEntityID.String
+		rel.TargetName = &targetName.String
+	}
+	if targetLiteral.Valid {
+		rel.TargetLiteral = &targetLiteral.String
+	}
+	if validAt.Valid {
+		rel.ValidAt = &validAt.String
+	}
+	if invalidAt.Valid {
+		rel.InvalidAt = &invalidAt.String
+	}
+	return &rel, nil
+}
+
+// EditFact corrects a fact by hand and marks it manual. A new Target must
+// fit the relation type like in AddManualFact, but is never created: add a
+// fact to introduce a new entity.
+func EditFact(ctx context.Context, db *sql.DB, id string, edit FactEdit) (*EntityRelationship, error) {
+	rel, err := GetFact(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	validAt, invalidAt := derefString(rel.ValidAt), derefString(rel.InvalidAt)
+	if edit.ValidAt != nil {
+		validAt = strings.TrimSpace(*edit.ValidAt)
+	}
+	if edit.InvalidAt != nil {
+		invalidAt = strings.TrimSpace(*edit.InvalidAt)
+	}
+	if err := validateFactDates(validAt, invalidAt); err != nil {
+		return nil, err
+	}
+
+	fact := rel.Fact
+	if edit.Fact != nil {
+		fact = strings.TrimSpace(*edit.Fact)
+		if fact == "" {
+			return nil, fmt.Errorf("fact text cannot be empty")
+		}
+	}
+
+	targetEntityID, targetLiteral := rel.TargetEntityID, rel.TargetLiteral
+	if edit.Target != nil {
+		if rel.TargetLiteral != nil {
+			value := strings.TrimSpace(*edit.Target)
+			if err := validateLiteralTarget(rel.RelationType, value); err != nil {
+				return nil, err
+			}
+			targetLiteral = &value
+		} else {
+			expected := RelationTypeSignatures[rel.RelationType].Target
+			target, err := resolveFactEndpoint(ctx, db, *edit.Target, 0, nil, "target")
+			if err != nil {
+				return nil, err
+			}
+			if target.New {
+				return nil, fmt.Errorf("target %q not found", *edit.Target)
+			}
+			if err := checkEndpointType(target, expected, rel.RelationType, "target"); err != nil {
+				return nil, err
+			}
+			if target.ID == rel.SourceEntityID {
+				return nil, fmt.Errorf("source and target are the same entity")
+			}
+			targetEntityID = &target.ID
+		}
+	}
+
+	_, err = db.ExecContext(ctx, `
+		UPDATE relationships
+		SET fact = ?, target_entity_id = ?, target_literal = ?, valid_at = ?, invalid_at = ?,
+		    confidence = 1.0, origin = ?
+		WHERE id = ?
+	`, fact, targetEntityID, targetLiteral, nullIfEmpty(validAt), nullIfEmpty(invalidAt), OriginManual, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return nil, fmt.Errorf("an identical fact already exists")
+		}
+		return nil, fmt.Errorf("update fact: %w", err)
+	}
+
+	if invalidAt == "" {
+		if _, err := NewContradictionDetector(db).Detect(ctx, []string{id}, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	if err := NewCurrentFactsStore(db).RefreshEntities(ctx, []string{rel.SourceEntityID}); err != nil {
+		return nil, err
+	}
+	return GetFact(ctx, db, id)
+}
+
+// InvalidateFact ends a current fact at (an ISO date; today when empty).
+// Inferred facts that no longer follow are ended with it.
+func InvalidateFact(ctx context.Context, db *sql.DB, id, at string) (*EntityRelationship, error) {
+	rel, err := GetFact(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+	if rel.InvalidAt != nil {
+		return nil, fmt.Errorf("fact %s is already invalid since %s", id, *rel.InvalidAt)
+	}
+	at = strings.TrimSpace(at)
+	if at == "" {
+		at = time.Now().Format("2006-01-02")
+	}
+	if err := validateFactDates(derefString(rel.ValidAt), at); err != nil {
+		return nil, err
+	}
+
+	if _, err := db.ExecContext(ctx, `UPDATE relationships SET invalid_at = ? WHERE id = ?`, at, id); err != nil {
+		return nil, fmt.Errorf("invalidate fact: %w", err)
+	}
+	if err := NewCurrentFactsStore(db).RefreshEntities(ctx, []string{rel.SourceEntityID}); err != nil {
+		return nil, err
+	}
+	if _, err := RetractInferences(ctx, db); err != nil {
+		return nil, err
+	}
+	return GetFact(ctx, db, id)
+}
+
+// DeleteFact removes a wrong fact along with its mentions, labels and
+// conflict records, and recomputes the source's current facts.
+func DeleteFact(ctx context.Context, db *sql.DB, id string) error {
+	rel, err := GetFact(ctx, db, id)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, `DELETE FROM relationships WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete fact: %w", err)
+	}
+	if err := NewCurrentFactsStore(db).RefreshEntities(ctx, []string{rel.SourceEntityID}); err != nil {
+		return err
+	}
+	_, err = RetractInferences(ctx, db)
+	return err
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
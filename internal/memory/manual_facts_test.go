@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestManualFacts(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	for _, e := range []struct {
+		id, name string
+		typeID   int
+	}{
+		{"tyler", "Tyler", EntityTypePerson},
+		{"intent", "Intent Systems", EntityTypeOrganization},
+		{"sf", "San Francisco", EntityTypeLocation},
+	} {
+		if _, err := db.Exec(`
+			INSERT INTO entities (id, canonical_name, entity_type_id, origin, created_at, updated_at)
+			VALUES (?, ?, ?, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')
+		`, e.id, e.name, e.typeID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec(`
+		INSERT INTO relationships (id, source_entity_id, target_entity_id, relation_type, fact, valid_at, created_at)
+		VALUES ('old-job', 'tyler', 'intent', 'WORKS_AT', 'Tyler works at Intent Systems', '2024-03', '2026-01-01T00:00:00Z')
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		fact    ManualFact
+		wantErr string
+	}{
+		{ManualFact{Source: "Tyler", RelationType: "works at", Target: "Anthropic"}, "invalid relation type"},
+		{ManualFact{Source: "Tyler", RelationType: "ADMIRES", Target: "Intent Systems"}, "not in the ontology"},
+		{ManualFact{Source: "Tyler", RelationType: "HAS_EMAIL", Target: "t@example.com"}, "alias"},
+		{ManualFact{Source: "Tyler", RelationType: "WORKS_AT", Target: "San Francisco"}, "expects a target of type Organization"},
+		{ManualFact{Source: "Intent Systems", RelationType: "SPOUSE_OF", Target: "Tyler"}, "expects a source of type Person"},
+		{ManualFact{Source: "Tyler", RelationType: "BORN_ON", Target: "April 1990"}, "invalid born_on value"},
+		{ManualFact{Source: "Tyler", RelationType: "WORKS_AT", Target: "Anthropic", ValidAt: "Jan 2026"}, "invalid valid_at"},
+		{ManualFact{Source: "Tyler", RelationType: "WORKS_AT", Target: "Anthropic", ValidAt: "2026-01", InvalidAt: "2025"}, "before valid_at"},
+		{ManualFact{Source: "Tyler", RelationType: "KNOWS", Target: "Jane"}, ""},
+		{ManualFact{Source: "Tyler", RelationType: "ATTENDED", Target: "Stanford"}, "type is unknown"},
+		{ManualFact{Source: "Tyler", RelationType: "ADMIRES", Target: "Intent Systems", AllowNewType: true}, ""},
+	} {
+		_, err := AddManualFact(ctx, db, tc.fact)
+		if tc.wantErr == "" && err != nil {
+			t.Errorf("%+v: %v", tc.fact, err)
+		}
+		if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+			t.Errorf("%+v: err = %v, want %q", tc.fact, err, tc.wantErr)
+		}
+	}
+	var anthropic int
+	db.QueryRow(`SELECT COUNT(*) FROM entities WHERE canonical_name = 'Anthropic'`).Scan(&anthropic)
+	if anthropic != 0 {
+		t.Error("a rejected fact created its target")
+	}
+
+	// A new employer is created, supersedes the old job, and becomes current
+	res, err := AddManualFact(ctx, db, ManualFact{Source: "tyler", RelationType: "WORKS_AT", Target: "Anthropic", ValidAt: "2026-01"})
+	if err != nil {
+		t.Fatalf("AddManualFact: %v", err)
+	}
+	rel := res.Relationship
+	if rel.Fact != "Tyler works at Anthropic" || rel.TargetName == nil || *rel.TargetName != "Anthropic" ||
+		len(res.CreatedEntities) != 1 || len(res.Invalidated) != 1 || res.Invalidated[0] != "old-job" {
+		t.Errorf("AddManualFact = %+v, relationship %+v", res, rel)
+	}
+	var origin, targetOrigin, oldInvalidAt, current string
+	db.QueryRow(`SELECT origin FROM relationships WHERE id = ?`, rel.ID).Scan(&origin)
+	db.QueryRow(`SELECT origin FROM entities WHERE id = ?`, *rel.TargetEntityID).Scan(&targetOrigin)
+	db.QueryRow(`SELECT invalid_at FROM relationships WHERE id = 'old-job'`).Scan(&oldInvalidAt)
+	db.QueryRow(`SELECT relationship_id FROM entity_current_facts WHERE entity_id = 'tyler' AND relation_type = 'WORKS_AT'`).Scan(&current)
+	if origin != OriginManual || targetOrigin != OriginManual || oldInvalidAt != "2026-01" || current != rel.ID {
+		t.Errorf("origin %q, target origin %q, old invalid_at %q, current %q", origin, targetOrigin, oldInvalidAt, current)
+	}
+
+	// Re-adding the same fact marks it manual instead of duplicating it
+	res, err = AddManualFact(ctx, db, ManualFact{Source: "Tyler", RelationType: "WORKS_AT", Target: "anthropic", ValidAt: "2026-01", Fact: "Tyler joined Anthropic"})
+	if err != nil || !res.Existing || res.Relationship.ID != rel.ID || res.Relationship.Fact != "Tyler joined Anthropic" {
+		t.Errorf("re-add = %+v, %v", res, err)
+	}
+
+	// Literal targets are validated by type
+	if res, err := AddManualFact(ctx, db, ManualFact{Source: "Tyler", RelationType: "born_on", Target: "1990-04-12"}); err != nil ||
+		res.Relationship.TargetLiteral == nil || *res.Relationship.TargetLiteral != "1990-04-12" {
+		t.Errorf("BORN_ON = %+v, %v", res, err)
+	}
+
+	// Edit: the target must exist and fit; dates stay ordered
+	if _, err := EditFact(ctx, db, rel.ID, FactEdit{Target: strPtr("San Francisco")}); err == nil {
+		t.Error("EditFact accepted a location as employer")
+	}
+	if _, err := EditFact(ctx, db, rel.ID, FactEdit{Target: strPtr("Acme")}); err == nil {
+		t.Error("EditFact created a missing target")
+	}
+	if _, err := EditFact(ctx, db, rel.ID, FactEdit{InvalidAt: strPtr("2025-06")}); err == nil {
+		t.Error("EditFact ended a fact before it began")
+	}
+	edited, err := EditFact(ctx, db, "old-job", FactEdit{ValidAt: strPtr("2024-02"), Fact: strPtr("Tyler worked at Intent Systems")})
+	if err != nil {
+		t.Fatalf("EditFact: %v", err)
+	}
+	db.QueryRow(`SELECT origin FROM relationships WHERE id = 'old-job'`).Scan(&origin)
+	if edited.ValidAt == nil || *edited.ValidAt != "2024-02" || edited.Fact != "Tyler worked at Intent Systems" || origin != OriginManual {
+		t.Errorf("edited = %+v, origin %q", edited, origin)
+	}
+
+	// Invalidate ends the current fact and clears it from current facts
+	if _, err := InvalidateFact(ctx, db, rel.ID, "2025-12"); err == nil {
+		t.Error("InvalidateFact ended a fact before it began")
+	}
+	ended, err := InvalidateFact(ctx, db, rel.ID, "2026-02")
+	if err != nil || ended.InvalidAt == nil || *ended.InvalidAt != "2026-02" {
+		t.Fatalf("InvalidateFact = %+v, %v", ended, err)
+	}
+	if _, err := InvalidateFact(ctx, db, rel.ID, ""); err == nil {
+		t.Error("invalidating twice should fail")
+	}
+	var remaining int
+	db.QueryRow(`SELECT COUNT(*) FROM entity_current_facts WHERE relationship_id = ?`, rel.ID).Scan(&remaining)
+	if remaining != 0 {
+		t.Error("invalidated fact is still current")
+	}
+	if _, err := GetFact(ctx, db, "nope"); err == nil {
+		t.Error("GetFact(nope) should fail")
+	}
+}
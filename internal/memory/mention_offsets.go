@@ -0,0 +1,148 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// MentionOffset locates one mention of an entity within episode content.
+// Start and End are character (rune) offsets into the episode content,
+// End exclusive. Message is the 0-based line of the content the mention is
+// on; episodes are rendered one message per line.
+type MentionOffset struct {
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Message int    `json:"message"`
+	Text    string `json:"text"`
+}
+
+// locateMentions finds every whole-word, case-insensitive occurrence of the
+// entity's surface forms in content. Surface forms that never occur are
+// dropped from the returned list, so hallucinated mentions don't survive.
+// Overlapping matches keep the longest span.
+func locateMentions(content string, name string, surfaceForms []string) ([]MentionOffset, []string) {
+	runes := []rune(content)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// Line index of each rune
+	lines := make([]int, len(runes))
+	line := 0
+	for i, r := range runes {
+		lines[i] = line
+		if r == '\n' {
+			line++
+		}
+	}
+
+	seen := make(map[string]bool)
+	var valid []string
+	var matches []MentionOffset
+	forms := append([]string{name}, surfaceForms...)
+	for i, form := range forms {
+		form = strings.TrimSpace(form)
+		key := strings.ToLower(form)
+		if form == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		needle := []rune(key)
+		found := false
+		for start := 0; start+len(needle) <= len(lower); start++ {
+			if !runesEqualAt(lower, needle, start) || !isWordBoundary(runes, start, start+len(needle)) {
+				continue
+			}
+			end := start + len(needle)
+			matches = append(matches, MentionOffset{
+				Start:   start,
+				End:     end,
+				Message: lines[start],
+				Text:    string(runes[start:end]),
+			})
+			found = true
+		}
+		// The canonical name is always considered; only LLM surface forms are validated
+		if found && i > 0 {
+			valid = append(valid, form)
+		}
+	}
+
+	return dedupeMentionOffsets(matches), valid
+}
+
+// dedupeMentionOffsets sorts offsets and drops spans overlapping an
+// earlier, longer one.
+func dedupeMentionOffsets(offsets []MentionOffset) []MentionOffset {
+	sort.Slice(offsets, func(i, j int) bool {
+		if offsets[i].Start != offsets[j].Start {
+			return offsets[i].Start < offsets[j].Start
+		}
+		return offsets[i].End > offsets[j].End
+	})
+	var result []MentionOffset
+	for _, o := range offsets {
+		if len(result) > 0 && o.Start < result[len(result)-1].End {
+			continue
+		}
+		result = append(result, o)
+	}
+	return result
+}
+
+func runesEqualAt(haystack, needle []rune, start int) bool {
+	for j, r := range needle {
+		if haystack[start+j] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// isWordBoundary reports whether [start, end) isn't embedded in a longer word.
+func isWordBoundary(runes []rune, start, end int) bool {
+	if start > 0 && isWordRune(runes[start-1]) {
+		return false
+	}
+	if end < len(runes) && isWordRune(runes[end]) {
+		return false
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// GetMentionOffsets returns where an entity appears in an episode's content,
+// for highlighting when viewing provenance. Returns nil if the mention has
+// no recorded offsets.
+func GetMentionOffsets(ctx context.Context, db *sql.DB, episodeID, entityID string) ([]MentionOffset, error) {
+	var raw sql.NullString
+	err := db.QueryRowContext(ctx, `
+		SELECT mention_offsets FROM episode_entity_mentions
+		WHERE episode_id = ? AND entity_id = ?
+	`, episodeID, entityID).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query mention offsets: %w", err)
+	}
+	if !raw.Valid || raw.String == "" {
+		return nil, nil
+	}
+
+	var offsets []MentionOffset
+	if err := json.Unmarshal([]byte(raw.String), &offsets); err != nil {
+		return nil, fmt.Errorf("parse mention offsets: %w", err)
+	}
+	return offsets, nil
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestLocateMentions(t *testing.T) {
+	content := "Tyler: did Casey call?\nCasey: yes — Case here 👋 casey\nTyler: Caseyville is far"
+
+	offsets, valid := locateMentions(content, "Casey Smith", []string{"Casey", "Case", "Casey Jones", "casey"})
+
+	// "Casey Jones" never appears and is dropped; "casey" duplicates "Casey"
+	if len(valid) != 2 || valid[0] != "Casey" || valid[1] != "Case" {
+		t.Errorf("valid = %v, want [Casey Case]", valid)
+	}
+
+	want := []struct {
+		text    string
+		message int
+	}{
+		{"Casey", 0}, {"Casey", 1}, {"Case", 1}, {"casey", 1},
+	}
+	if len(offsets) != len(want) {
+		t.Fatalf("offsets = %+v, want %d (Caseyville must not match)", offsets, len(want))
+	}
+	runes := []rune(content)
+	for i, w := range want {
+		o := offsets[i]
+		if o.Text != w.text || o.Message != w.message {
+			t.Errorf("offsets[%d] = %+v, want %q on message %d", i, o, w.text, w.message)
+		}
+		if got := string(runes[o.Start:o.End]); got != o.Text {
+			t.Errorf("offsets[%d] spans %q, want %q", i, got, o.Text)
+		}
+	}
+}
+
+func TestLocateMentions_PrefersLongestSpan(t *testing.T) {
+	offsets, _ := locateMentions("Lunch with Casey Smith today", "Casey Smith", []string{"Casey"})
+	if len(offsets) != 1 || offsets[0].Text != "Casey Smith" {
+		t.Errorf("offsets = %+v, want single Casey Smith span", offsets)
+	}
+}
+
+func TestCreateEntityMentions_StoresOffsets(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	db.Exec(`INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0)`)
+	db.Exec(`INSERT INTO episodes (id, definition_id, start_time, end_time, event_count, created_at) VALUES ('ep1', 'def', 0, 0, 1, 0)`)
+	db.Exec(`INSERT INTO entities (id, canonical_name, entity_type_id, origin, created_at, updated_at) VALUES ('casey', 'Casey', 1, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')`)
+
+	content := "Tyler: hi Casey\nCasey: hey"
+	offsets, _ := locateMentions(content, "Casey", nil)
+	pipeline := &MemoryPipeline{db: db}
+	_, err := pipeline.createEntityMentions(ctx, "ep1",
+		[]ResolvedEntity{{ID: "casey", Name: "Casey"}},
+		[]ExtractedEntity{{ID: 0, Name: "Casey", Offsets: offsets}})
+	if err != nil {
+		t.Fatalf("createEntityMentions: %v", err)
+	}
+
+	got, err := GetMentionOffsets(ctx, db, "ep1", "casey")
+	if err != nil {
+		t.Fatalf("GetMentionOffsets: %v", err)
+	}
+	if len(got) != 2 || got[0].Start != 10 || got[1].Message != 1 {
+		t.Errorf("offsets = %+v", got)
+	}
+
+	none, err := GetMentionOffsets(ctx, db, "ep1", "nobody")
+	if err != nil || none != nil {
+		t.Errorf("missing mention = %v, %v", none, err)
+	}
+}
@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Model routing tiers.
+const (
+	RouteTierCheap  = "cheap"
+	RouteTierStrong = "strong"
+)
+
+// ModelRouterConfig configures per-episode model routing.
+type ModelRouterConfig struct {
+	// Model for short/simple episodes (default: the pipeline's ExtractionModel)
+	CheapModel string
+	// Model for long/dense or group-chat episodes (default: gemini-2.5-pro)
+	StrongModel string
+	// Episodes estimated above this many tokens go to the strong model (default: 3000)
+	MaxCheapTokens int
+	// Episodes with more participants than this go to the strong model (default: 2, i.e. 1:1 chats stay cheap)
+	MaxCheapParticipants int
+}
+
+// DefaultModelRouterConfig returns the default routing thresholds.
+func DefaultModelRouterConfig() ModelRouterConfig {
+	return ModelRouterConfig{
+		CheapModel:           "gemini-2.0-flash",
+		StrongModel:          "gemini-2.5-pro",
+		MaxCheapTokens:       3000,
+		MaxCheapParticipants: 2,
+	}
+}
+
+// RouteDecision is the model chosen for an episode and why.
+type RouteDecision struct {
+	Model           string `json:"model"`
+	Tier            string `json:"tier"`   // cheap, strong
+	Reason          string `json:"reason"` // short, long, group
+	EstimatedTokens int    `json:"estimated_tokens"`
+	Participants    int    `json:"participants"`
+}
+
+// ModelRouter sends short/simple episodes to a cheap model and long or
+// group-chat episodes to a stronger one.
+type ModelRouter struct {
+	db     *sql.DB
+	config ModelRouterConfig
+}
+
+// NewModelRouter creates a new ModelRouter; zero config fields take defaults.
+func NewModelRouter(db *sql.DB, config ModelRouterConfig) *ModelRouter {
+	defaults := DefaultModelRouterConfig()
+	if config.CheapModel == "" {
+		config.CheapModel = defaults.CheapModel
+	}
+	if config.StrongModel == "" {
+		config.StrongModel = defaults.StrongModel
+	}
+	if config.MaxCheapTokens <= 0 {
+		config.MaxCheapTokens = defaults.MaxCheapTokens
+	}
+	if config.MaxCheapParticipants <= 0 {
+		config.MaxCheapParticipants = defaults.MaxCheapParticipants
+	}
+	return &ModelRouter{db: db, config: config}
+}
+
+// Route picks the extraction model for an episode based on its estimated
+// token count and participant count.
+func (r *ModelRouter) Route(ctx context.Context, episode EpisodeInput) RouteDecision {
+	decision := RouteDecision{
+		EstimatedTokens: estimateContentTokens(episode.Content),
+		Participants:    r.participantCount(ctx, episode),
+	}
+
+	switch {
+	case decision.Participants > r.config.MaxCheapParticipants:
+		decision.Tier, decision.Reason = RouteTierStrong, "group"
+	case decision.EstimatedTokens > r.config.MaxCheapTokens:
+		decision.Tier, decision.Reason = RouteTierStrong, "long"
+	default:
+		decision.Tier, decision.Reason = RouteTierCheap, "short"
+	}
+
+	decision.Model = r.config.CheapModel
+	if decision.Tier == RouteTierStrong {
+		decision.Model = r.config.StrongModel
+	}
+	return decision
+}
+
+// participantCount uses the caller's count, then the episode's event
+// participants, then the known entities passed in.
+func (r *ModelRouter) participantCount(ctx context.Context, episode EpisodeInput) int {
+	if episode.ParticipantCount > 0 {
+		return episode.ParticipantCount
+	}
+	if r.db != nil {
+		var count int
+		err := r.db.QueryRowContext(ctx, `
+			SELECT COUNT(DISTINCT ep.contact_id)
+			FROM episode_events ee
+			JOIN event_participants ep ON ep.event_id = ee.event_id
+			WHERE ee.episode_id = ?
+		`, episode.ID).Scan(&count)
+		if err == nil && count > 0 {
+			return count
+		}
+		// Non-fatal - fall back to known entities (table may not exist in memory-only DBs)
+	}
+	return len(episode.KnownEntities)
+}
+
+// estimateContentTokens approximates the token count of episode content
+// using the common ~4 characters per token heuristic.
+func estimateContentTokens(content string) int {
+	if content == "" {
+		return 0
+	}
+	return len(content)/4 + 1
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/config"
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestModelRouter_Route(t *testing.T) {
+	ctx := context.Background()
+	router := NewModelRouter(nil, ModelRouterConfig{CheapModel: "flash", StrongModel: "pro", MaxCheapTokens: 100})
+
+	tests := []struct {
+		name    string
+		episode EpisodeInput
+		tier    string
+		reason  string
+	}{
+		{"short dm", EpisodeInput{Content: "hey, lunch tomorrow?", ParticipantCount: 2}, RouteTierCheap, "short"},
+		{"long dm", EpisodeInput{Content: strings.Repeat("word ", 200), ParticipantCount: 2}, RouteTierStrong, "long"},
+		{"group chat", EpisodeInput{Content: "hi all", ParticipantCount: 5}, RouteTierStrong, "group"},
+		{"known entities fallback", EpisodeInput{Content: "hi", KnownEntities: []KnownEntity{{Name: "A"}, {Name: "B"}, {Name: "C"}}}, RouteTierStrong, "group"},
+	}
+	for _, tt := range tests {
+		got := router.Route(ctx, tt.episode)
+		if got.Tier != tt.tier || got.Reason != tt.reason {
+			t.Errorf("%s: got %s/%s, want %s/%s", tt.name, got.Tier, got.Reason, tt.tier, tt.reason)
+		}
+		wantModel := "flash"
+		if tt.tier == RouteTierStrong {
+			wantModel = "pro"
+		}
+		if got.Model != wantModel {
+			t.Errorf("%s: model = %s, want %s", tt.name, got.Model, wantModel)
+		}
+	}
+}
+
+func TestModelRouter_ParticipantsFromEvents(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	db.Exec(`INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0)`)
+	db.Exec(`INSERT INTO episodes (id, definition_id, start_time, end_time, event_count, created_at) VALUES ('ep1', 'def', 0, 0, 3, 0)`)
+	for i, contact := range []string{"c1", "c2", "c3"} {
+		eventID := "e" + contact
+		db.Exec(`INSERT INTO contacts (id, created_at, updated_at) VALUES (?, 0, 0)`, contact)
+		db.Exec(`INSERT INTO events (id, timestamp, channel, content_types, direction, source_adapter, source_id) VALUES (?, 0, 'imessage', '["text"]', 'received', 'test', ?)`, eventID, eventID)
+		db.Exec(`INSERT INTO episode_events (episode_id, event_id, position) VALUES ('ep1', ?, ?)`, eventID, i+1)
+		db.Exec(`INSERT INTO event_participants (event_id, contact_id, role) VALUES (?, ?, 'sender')`, eventID, contact)
+	}
+
+	decision := NewModelRouter(db, ModelRouterConfig{}).Route(ctx, EpisodeInput{ID: "ep1", Content: "short"})
+	if decision.Participants != 3 || decision.Tier != RouteTierStrong {
+		t.Errorf("decision = %+v, want 3 participants routed strong", decision)
+	}
+
+	// Decisions round-trip through the processing log for evaluation
+	log := NewProcessingLog(db)
+	if err := log.Record(ctx, EpisodeProcessing{EpisodeID: "ep1", Model: decision.Model, Route: &decision, Status: ProcessingStatusOK}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	top, err := log.TopEpisodes(ctx, 1)
+	if err != nil || len(top) != 1 || top[0].Route == nil {
+		t.Fatalf("TopEpisodes = %+v, %v", top, err)
+	}
+	if top[0].Route.Reason != "group" || top[0].Route.Participants != 3 {
+		t.Errorf("route = %+v, want group with 3 participants", top[0].Route)
+	}
+	byModel, _ := log.CostBreakdown(ctx, "model", 0)
+	if len(byModel) != 1 || byModel[0].Key != DefaultModelRouterConfig().StrongModel {
+		t.Errorf("byModel = %+v", byModel)
+	}
+}
+
+func TestNewPipelineConfig_ModelRouting(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	pc, err := NewPipelineConfig(config.MemoryConfig{})
+	if err != nil {
+		t.Fatalf("NewPipelineConfig: %v", err)
+	}
+	if NewMemoryPipeline(db, nil, pc).modelRouter != nil {
+		t.Error("routing enabled without a model_routing section")
+	}
+
+	if _, err := NewPipelineConfig(config.MemoryConfig{ModelRouting: &config.ModelRoutingConfig{MaxCheapTokens: -1}}); err == nil {
+		t.Error("negative threshold accepted")
+	}
+
+	pc, err = NewPipelineConfig(config.MemoryConfig{ModelRouting: &config.ModelRoutingConfig{StrongModel: "pro", MaxCheapTokens: 100}})
+	if err != nil {
+		t.Fatalf("NewPipelineConfig: %v", err)
+	}
+	router := NewMemoryPipeline(db, nil, pc).modelRouter
+	if router == nil {
+		t.Fatal("model_routing did not enable the router")
+	}
+	// The cheap tier falls back to the extraction model
+	if got := router.Route(ctx, EpisodeInput{Content: "hey", ParticipantCount: 2}); got.Model != pc.ExtractionModel {
+		t.Errorf("short episode model = %s, want %s", got.Model, pc.ExtractionModel)
+	}
+	if got := router.Route(ctx, EpisodeInput{Content: strings.Repeat("word ", 200), ParticipantCount: 2}); got.Model != "pro" {
+		t.Errorf("long episode model = %s, want pro", got.Model)
+	}
+}
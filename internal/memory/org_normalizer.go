@@ -0,0 +1,465 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReasonOrgNormalization is the merge_candidates reason for organization
+// entities that normalize to the same company ("Anthropic" vs "Anthropic, PBC").
+const ReasonOrgNormalization = "org_normalization"
+
+// Organization merge candidate confidences.
+const (
+	OrgNameAndDomainConfidence = 0.95 // Same normalized name and a shared domain
+	OrgKnownAliasConfidence    = 0.92 // Both names in the same well-known alias group
+	OrgNameConfidence          = 0.90 // Same normalized name (suffixes stripped)
+	OrgDomainConfidence        = 0.85 // Shared email domain only
+)
+
+// legalSuffixes are stripped from the end of company names.
+var legalSuffixes = map[string]bool{
+	"inc": true, "incorporated": true, "llc": true, "ltd": true, "limited": true,
+	"corp": true, "corporation": true, "co": true, "company": true,
+	"gmbh": true, "plc": true, "pbc": true, "llp": true, "lp": true,
+	"sa": true, "ag": true, "bv": true, "nv": true, "pty": true, "srl": true,
+}
+
+// knownCompanies maps well-known alternate names and domains to one canonical
+// name. Keys of aliases are CompanyKey-normalized.
+var knownCompanies = []struct {
+	name    string
+	aliases []string
+	domains []string
+}{
+	{"Alphabet", nil, []string{"abc.xyz"}},
+	{"Google", nil, []string{"google.com"}},
+	{"Meta", []string{"Facebook", "Meta Platforms"}, []string{"meta.com", "fb.com", "facebook.com"}},
+	{"Amazon", []string{"Amazon.com", "AMZN"}, []string{"amazon.com"}},
+	{"Amazon Web Services", []string{"AWS"}, []string{"aws.com"}},
+	{"Microsoft", []string{"MSFT"}, []string{"microsoft.com"}},
+	{"Apple", []string{"AAPL", "Apple Computer"}, []string{"apple.com"}},
+	{"IBM", []string{"International Business Machines"}, []string{"ibm.com"}},
+	{"X", []string{"Twitter", "X Corp"}, []string{"x.com", "twitter.com"}},
+	{"OpenAI", []string{"Open AI"}, []string{"openai.com"}},
+	{"Anthropic", []string{"Anthropic PBC"}, []string{"anthropic.com"}},
+	{"GitHub", []string{"Github"}, []string{"github.com"}},
+	{"Goldman Sachs", []string{"GS", "Goldman"}, []string{"gs.com"}},
+	{"JPMorgan Chase", []string{"JPMorgan", "JP Morgan", "Chase", "JPMC"}, []string{"jpmorgan.com", "jpmchase.com"}},
+	{"McKinsey & Company", []string{"McKinsey"}, []string{"mckinsey.com"}},
+}
+
+// publicEmailDomains are consumer email providers that say nothing about an employer.
+var publicEmailDomains = map[string]bool{
+	"gmail.com": true, "googlemail.com": true, "yahoo.com": true, "hotmail.com": true,
+	"outlook.com": true, "icloud.com": true, "aol.com": true, "proton.me": true,
+	"protonmail.com": true, "live.com": true, "msn.com": true, "me.com": true,
+	"mac.com": true, "gmx.com": true, "fastmail.com": true, "hey.com": true,
+}
+
+var (
+	knownCompanyByKey    map[string]string // CompanyKey(alias or name) → canonical name
+	knownCompanyByDomain map[string]string // domain → canonical name
+)
+
+func init() {
+	knownCompanyByKey = make(map[string]string)
+	knownCompanyByDomain = make(map[string]string)
+	for _, c := range knownCompanies {
+		knownCompanyByKey[companyBaseKey(c.name)] = c.name
+		for _, alias := range c.aliases {
+			knownCompanyByKey[companyBaseKey(alias)] = c.name
+		}
+		for _, domain := range c.domains {
+			knownCompanyByDomain[domain] = c.name
+		}
+	}
+}
+
+// companyBaseKey lowercases, strips punctuation, a leading "the" and trailing
+// legal suffixes ("The Acme Corp., Inc." → "acme").
+func companyBaseKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch r {
+		case '.', ',', '\'', '"', '(', ')':
+			continue
+		case '-', '/':
+			b.WriteByte(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	words := strings.Fields(b.String())
+	if len(words) > 1 && words[0] == "the" {
+		words = words[1:]
+	}
+	for len(words) > 1 && legalSuffixes[words[len(words)-1]] {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+// CompanyKey returns the normalized matching key for a company name.
+// Well-known alternate names map to the same key ("Facebook" and "Meta").
+func CompanyKey(name string) string {
+	key := companyBaseKey(name)
+	if canonical, ok := knownCompanyByKey[key]; ok {
+		return companyBaseKey(canonical)
+	}
+	return key
+}
+
+// CanonicalCompanyName returns the well-known canonical name for a company,
+// or the name with legal suffixes stripped if it isn't well known.
+func CanonicalCompanyName(name string) string {
+	if canonical, ok := knownCompanyByKey[companyBaseKey(name)]; ok {
+		return canonical
+	}
+	words := strings.Fields(strings.NewReplacer(",", " ").Replace(name))
+	if len(words) > 1 && strings.EqualFold(words[0], "the") {
+		words = words[1:]
+	}
+	for len(words) > 1 && legalSuffixes[companyBaseKey(words[len(words)-1])] {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+// EmailDomain returns the lowercased domain of an email address, or "" if
+// the address is malformed or uses a public email provider.
+func EmailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 || at == len(email)-1 {
+		return ""
+	}
+	domain := strings.ToLower(strings.TrimSpace(email[at+1:]))
+	if publicEmailDomains[domain] || !strings.Contains(domain, ".") {
+		return ""
+	}
+	return domain
+}
+
+// CompanyKeyForDomain returns the company key a domain implies: the
+// well-known company for the domain, else its registrable label
+// ("mail.acme.co.uk" → "acme").
+func CompanyKeyForDomain(domain string) string {
+	if canonical, ok := knownCompanyByDomain[domain]; ok {
+		return CompanyKey(canonical)
+	}
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return ""
+	}
+	label := labels[len(labels)-2]
+	// Second-level country domains: acme.co.uk, acme.com.au
+	if len(labels) >= 3 && len(labels[len(labels)-1]) == 2 && (label == "co" || label == "com" || label == "org" || label == "ac") {
+		label = labels[len(labels)-3]
+	}
+	return CompanyKey(label)
+}
+
+// OrgNormalizeResult contains the output of organization normalization.
+type OrgNormalizeResult struct {
+	Normalized      int `json:"normalized"`       // Organization entities examined
+	AliasesAdded    int `json:"aliases_added"`    // Name and domain aliases added
+	CandidatesFound int `json:"candidates_found"` // Merge candidates created
+}
+
+// OrgNormalizer normalizes organization entities: normalized-name and
+// well-known aliases, domain aliases learned from email aliases in the graph
+// (someone at "@anthropic.com" ⇒ Anthropic), and merge candidates between
+// organizations that share a normalized name or domain.
+type OrgNormalizer struct {
+	db    *sql.DB
+	cache *EntityCache // Optional: invalidated when aliases are added
+}
+
+// NewOrgNormalizer creates a new OrgNormalizer.
+func NewOrgNormalizer(db *sql.DB) *OrgNormalizer {
+	return &OrgNormalizer{db: db}
+}
+
+// SetCache sets the hot-entity cache to invalidate when aliases are added.
+func (o *OrgNormalizer) SetCache(cache *EntityCache) {
+	o.cache = cache
+}
+
+// orgInfo is an organization entity's normalization state.
+type orgInfo struct {
+	id      string
+	name    string
+	key     string
+	domains map[string]bool
+}
+
+// NormalizeAll normalizes every active organization entity.
+func (o *OrgNormalizer) NormalizeAll(ctx context.Context) (*OrgNormalizeResult, error) {
+	return o.normalize(ctx, nil)
+}
+
+// NormalizeEntities normalizes the given entities (non-organizations are skipped)
+// and compares them against all active organizations.
+func (o *OrgNormalizer) NormalizeEntities(ctx context.Context, entityIDs []string) (*OrgNormalizeResult, error) {
+	ids := uniqueIDs(entityIDs)
+	if len(ids) == 0 {
+		return &OrgNormalizeResult{}, nil
+	}
+	only := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		only[id] = true
+	}
+	return o.normalize(ctx, only)
+}
+
+// normalize normalizes organizations (all if only is nil).
+func (o *OrgNormalizer) normalize(ctx context.Context, only map[string]bool) (*OrgNormalizeResult, error) {
+	result := &OrgNormalizeResult{}
+
+	orgs, err := o.loadOrganizations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	emailDomains, err := o.loadEmailDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, org := range orgs {
+		if only != nil && !only[org.id] {
+			continue
+		}
+		result.Normalized++
+
+		// Domains seen in email aliases that imply this company
+		for domain, key := range emailDomains {
+			if key != org.key || org.domains[domain] {
+				continue
+			}
+			org.domains[domain] = true
+			added, err := o.addAlias(ctx, org.id, domain, "domain")
+			if err != nil {
+				return nil, err
+			}
+			if added {
+				result.AliasesAdded++
+			}
+		}
+
+		// Suffix-stripped or well-known canonical name as an alias
+		if name := CanonicalCompanyName(org.name); normalizeAlias(name) != normalizeAlias(org.name) {
+			added, err := o.addAlias(ctx, org.id, name, "name")
+			if err != nil {
+				return nil, err
+			}
+			if added {
+				result.AliasesAdded++
+			}
+		}
+	}
+
+	// Merge candidates between organizations with the same key or a shared domain
+	for i, a := range orgs {
+		for _, b := range orgs[i+1:] {
+			if only != nil && !only[a.id] && !only[b.id] {
+				continue
+			}
+			confidence, matched := orgMatch(a, b)
+			if confidence == 0 {
+				continue
+			}
+			// Merge the newer entity into the older one
+			created, err := o.createMergeCandidate(ctx, b, a, confidence, matched)
+			if err != nil {
+				return nil, err
+			}
+			if created {
+				result.CandidatesFound++
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// orgMatch scores two organizations. Returns 0 if they don't match.
+func orgMatch(a, b *orgInfo) (float64, []map[string]interface{}) {
+	var facts []map[string]interface{}
+	sameKey := a.key != "" && a.key == b.key
+	if sameKey {
+		facts = append(facts, map[string]interface{}{"type": "company_name", "value": a.key})
+	}
+	sharedDomain := false
+	for domain := range a.domains {
+		if b.domains[domain] {
+			sharedDomain = true
+			facts = append(facts, map[string]interface{}{"type": "domain", "value": domain})
+		}
+	}
+
+	switch {
+	case sameKey && sharedDomain:
+		return OrgNameAndDomainConfidence, facts
+	case sameKey && companyBaseKey(a.name) != companyBaseKey(b.name):
+		// Different names joined by the well-known alias table (Facebook/Meta)
+		if _, known := knownCompanyByKey[companyBaseKey(a.name)]; known {
+			return OrgKnownAliasConfidence, facts
+		}
+		return OrgNameConfidence, facts
+	case sameKey:
+		return OrgNameConfidence, facts
+	case sharedDomain:
+		return OrgDomainConfidence, facts
+	}
+	return 0, nil
+}
+
+// loadOrganizations loads active organization entities with their domain aliases,
+// oldest first.
+func (o *OrgNormalizer) loadOrganizations(ctx context.Context) ([]*orgInfo, error) {
+	rows, err := o.db.QueryContext(ctx, `
+		SELECT e.id, e.canonical_name, ea.alias_type, ea.normalized
+		FROM entities e
+		LEFT JOIN entity_aliases ea ON ea.entity_id = e.id AND ea.alias_type IN ('domain', 'email')
+		WHERE e.entity_type_id = ? AND e.merged_into IS NULL
+		ORDER BY e.created_at, e.id
+	`, EntityTypeOrganization)
+	if err != nil {
+		return nil, fmt.Errorf("query organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var orgs []*orgInfo
+	byID := make(map[string]*orgInfo)
+	for rows.Next() {
+		var id, name string
+		var aliasType, normalized sql.NullString
+		if err := rows.Scan(&id, &name, &aliasType, &normalized); err != nil {
+			return nil, err
+		}
+		org, ok := byID[id]
+		if !ok {
+			org = &orgInfo{id: id, name: name, key: CompanyKey(name), domains: make(map[string]bool)}
+			byID[id] = org
+			orgs = append(orgs, org)
+		}
+		if !normalized.Valid {
+			continue
+		}
+		switch aliasType.String {
+		case "domain":
+			org.domains[normalized.String] = true
+		case "email":
+			if domain := EmailDomain(normalized.String); domain != "" {
+				org.domains[domain] = true
+			}
+		}
+	}
+	return orgs, rows.Err()
+}
+
+// loadEmailDomains returns non-public email domains seen in aliases, mapped to
+// the company key each implies.
+func (o *OrgNormalizer) loadEmailDomains(ctx context.Context) (map[string]string, error) {
+	rows, err := o.db.QueryContext(ctx, `
+		SELECT DISTINCT normalized FROM entity_aliases
+		WHERE alias_type = 'email' AND normalized IS NOT NULL
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query email aliases: %w", err)
+	}
+	defer rows.Close()
+
+	domains := make(map[string]string)
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		domain := EmailDomain(email)
+		if domain == "" {
+			continue
+		}
+		if _, seen := domains[domain]; !seen {
+			domains[domain] = CompanyKeyForDomain(domain)
+		}
+	}
+	return domains, rows.Err()
+}
+
+// addAlias adds an alias if the entity doesn't already have it.
+func (o *OrgNormalizer) addAlias(ctx context.Context, entityID, alias, aliasType string) (bool, error) {
+	normalized := normalizeAlias(alias)
+	var exists int
+	err := o.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM entity_aliases
+		WHERE entity_id = ? AND alias_type = ? AND normalized = ?
+	`, entityID, aliasType, normalized).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check alias: %w", err)
+	}
+	if exists > 0 {
+		return false, nil
+	}
+	if _, err := o.db.ExecContext(ctx, `
+		INSERT INTO entity_aliases (id, entity_id, alias, alias_type, normalized, is_shared, created_at)
+		VALUES (?, ?, ?, ?, ?, FALSE, ?)
+	`, uuid.New().String(), entityID, alias, aliasType, normalized, time.Now().Format(time.RFC3339)); err != nil {
+		return false, fmt.Errorf("insert alias: %w", err)
+	}
+	o.cache.InvalidateAlias(alias, normalized)
+	return true, nil
+}
+
+// hasDomainFact reports whether the matching facts include a shared domain.
+func hasDomainFact(facts []map[string]interface{}) bool {
+	for _, f := range facts {
+		if f["type"] == "domain" {
+			return true
+		}
+	}
+	return false
+}
+
+// createMergeCandidate records a pending candidate to fold the newer
+// organization into the older one, with the name and domain facts that
+// matched and both names for review. Only a name-and-domain match is
+// confident enough to be auto-merged. A pair that was already proposed
+// (in either order, whatever its status) is left alone and false returned.
+func (o *OrgNormalizer) createMergeCandidate(ctx context.Context, source, target *orgInfo, confidence float64, facts []map[string]interface{}) (bool, error) {
+	var existing int
+	err := o.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM merge_candidates
+		WHERE (entity_a_id = ? AND entity_b_id = ?) OR (entity_a_id = ? AND entity_b_id = ?)
+	`, source.id, target.id, target.id, source.id).Scan(&existing)
+	if err != nil {
+		return false, fmt.Errorf("check merge candidate: %w", err)
+	}
+	if existing > 0 {
+		return false, nil
+	}
+
+	factsJSON, _ := json.Marshal(facts)
+	contextJSON, _ := json.Marshal(map[string]interface{}{
+		"name_a": source.name,
+		"name_b": target.name,
+		"key":    target.key,
+	})
+	_, err = o.db.ExecContext(ctx, `
+		INSERT INTO merge_candidates (
+			id, entity_a_id, entity_b_id, confidence, auto_eligible,
+			reason, matching_facts, context, status, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, 'pending', ?)
+	`, uuid.New().String(), source.id, target.id, confidence, confidence >= OrgNameAndDomainConfidence && hasDomainFact(facts),
+		ReasonOrgNormalization, string(factsJSON), string(contextJSON), time.Now().Format(time.RFC3339))
+	if err != nil {
+		return false, fmt.Errorf("insert merge candidate: %w", err)
+	}
+	return true, nil
+}
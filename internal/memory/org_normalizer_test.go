@@ -0,0 +1,165 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestCompanyKey(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"Anthropic", "Anthropic, PBC", true},
+		{"Acme Inc.", "ACME LLC", true},
+		{"The Acme Corporation", "Acme", true},
+		{"Facebook", "Meta Platforms, Inc.", true},
+		{"Twitter", "X Corp", true},
+		{"Google", "Alphabet", false},
+		{"Acme", "Acme Labs", false},
+	}
+	for _, tt := range tests {
+		if got := CompanyKey(tt.a) == CompanyKey(tt.b); got != tt.same {
+			t.Errorf("CompanyKey(%q) == CompanyKey(%q) = %v, want %v", tt.a, tt.b, got, tt.same)
+		}
+	}
+
+	if got := CanonicalCompanyName("Acme, Inc."); got != "Acme" {
+		t.Errorf("CanonicalCompanyName(Acme, Inc.) = %q, want Acme", got)
+	}
+	if got := CanonicalCompanyName("facebook"); got != "Meta" {
+		t.Errorf("CanonicalCompanyName(facebook) = %q, want Meta", got)
+	}
+}
+
+func TestCompanyKeyForDomain(t *testing.T) {
+	tests := map[string]string{
+		"anthropic.com":   "anthropic",
+		"fb.com":          "meta",
+		"mail.acme.co.uk": "acme",
+		"eng.globex.io":   "globex",
+	}
+	for domain, want := range tests {
+		if got := CompanyKeyForDomain(domain); got != want {
+			t.Errorf("CompanyKeyForDomain(%q) = %q, want %q", domain, got, want)
+		}
+	}
+	if EmailDomain("casey@gmail.com") != "" {
+		t.Error("public email domains should be ignored")
+	}
+	if EmailDomain("Casey@Anthropic.com") != "anthropic.com" {
+		t.Error("expected lowercased domain")
+	}
+}
+
+func TestOrgNormalizer_NormalizeAll(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertQueryEngineTestEntity(t, db, "anthropic", "Anthropic", EntityTypeOrganization)
+	insertQueryEngineTestEntity(t, db, "anthropic-pbc", "Anthropic, PBC", EntityTypeOrganization)
+	insertQueryEngineTestEntity(t, db, "acme", "Acme Inc", EntityTypeOrganization)
+	insertQueryEngineTestEntity(t, db, "acme-labs", "Acme Labs", EntityTypeOrganization)
+	insertQueryEngineTestEntity(t, db, "casey", "Casey", EntityTypePerson)
+	insertTestAlias(t, db, "a1", "casey", "casey@anthropic.com", "email", false)
+	insertTestAlias(t, db, "a2", "casey", "casey@gmail.com", "email", false)
+
+	normalizer := NewOrgNormalizer(db)
+	result, err := normalizer.NormalizeAll(ctx)
+	if err != nil {
+		t.Fatalf("NormalizeAll: %v", err)
+	}
+	if result.Normalized != 4 {
+		t.Errorf("Normalized = %d, want 4", result.Normalized)
+	}
+	if result.CandidatesFound != 1 {
+		t.Errorf("CandidatesFound = %d, want 1 (Anthropic pair)", result.CandidatesFound)
+	}
+
+	// Both Anthropic entities learn the domain; Acme gets its stripped name
+	var domainAliases int
+	db.QueryRow(`SELECT COUNT(*) FROM entity_aliases WHERE alias_type = 'domain' AND normalized = 'anthropic.com'`).Scan(&domainAliases)
+	if domainAliases != 2 {
+		t.Errorf("anthropic.com domain aliases = %d, want 2", domainAliases)
+	}
+	var acmeAlias int
+	db.QueryRow(`SELECT COUNT(*) FROM entity_aliases WHERE entity_id = 'acme' AND normalized = 'acme'`).Scan(&acmeAlias)
+	if acmeAlias != 1 {
+		t.Error("expected suffix-stripped alias for Acme Inc")
+	}
+
+	merger := NewAutoMerger(db)
+	candidates, err := merger.GetPendingCandidates(ctx)
+	if err != nil {
+		t.Fatalf("GetPendingCandidates: %v", err)
+	}
+	if len(candidates) != 1 {
+		t.Fatalf("candidates = %+v, want 1", candidates)
+	}
+	c := candidates[0]
+	if c.Reason != ReasonOrgNormalization || c.EntityAID != "anthropic-pbc" || c.EntityBID != "anthropic" {
+		t.Errorf("unexpected candidate: %+v", c)
+	}
+	if c.Confidence != OrgNameAndDomainConfidence || !merger.ShouldAutoMerge(&c) {
+		t.Errorf("name + domain match should be auto-mergeable, got %+v", c)
+	}
+
+	// Idempotent
+	again, err := normalizer.NormalizeAll(ctx)
+	if err != nil {
+		t.Fatalf("NormalizeAll again: %v", err)
+	}
+	if again.AliasesAdded != 0 || again.CandidatesFound != 0 {
+		t.Errorf("second run = %+v, want nothing new", again)
+	}
+}
+
+func TestOrgNormalizer_NameOnlyNeedsReview(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertQueryEngineTestEntity(t, db, "globex", "Globex", EntityTypeOrganization)
+	insertQueryEngineTestEntity(t, db, "globex-llc", "Globex LLC", EntityTypeOrganization)
+
+	result, err := NewOrgNormalizer(db).NormalizeEntities(ctx, []string{"globex-llc"})
+	if err != nil {
+		t.Fatalf("NormalizeEntities: %v", err)
+	}
+	if result.Normalized != 1 || result.CandidatesFound != 1 {
+		t.Fatalf("result = %+v, want 1 normalized and 1 candidate", result)
+	}
+
+	merger := NewAutoMerger(db)
+	candidates, _ := merger.GetPendingCandidates(ctx)
+	if len(candidates) != 1 || candidates[0].Confidence != OrgNameConfidence {
+		t.Fatalf("candidates = %+v, want one name-only candidate", candidates)
+	}
+	if merger.ShouldAutoMerge(&candidates[0]) {
+		t.Error("name-only match should require review")
+	}
+}
+
+func TestOrgNormalizer_KnownAliasNeedsReview(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertQueryEngineTestEntity(t, db, "meta", "Meta", EntityTypeOrganization)
+	insertQueryEngineTestEntity(t, db, "facebook", "Facebook", EntityTypeOrganization)
+
+	if _, err := NewOrgNormalizer(db).NormalizeAll(ctx); err != nil {
+		t.Fatalf("NormalizeAll: %v", err)
+	}
+	merger := NewAutoMerger(db)
+	candidates, _ := merger.GetPendingCandidates(ctx)
+	if len(candidates) != 1 || candidates[0].Confidence != OrgKnownAliasConfidence || candidates[0].AutoEligible {
+		t.Fatalf("candidates = %+v, want one alias-only candidate", candidates)
+	}
+	if merger.ShouldAutoMerge(&candidates[0]) {
+		t.Error("alias-only match should require review")
+	}
+}
@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Kinds of pair timeline entry.
+const (
+	PairEdgeStarted   = "started" // a relationship between the two became true (valid_at)
+	PairEdgeEnded     = "ended"   // it stopped being true (invalid_at)
+	PairEdgeLearned   = "learned" // undated relationship, placed where it was first mentioned
+	PairSharedEpisode = "episode" // an episode mentioning both
+)
+
+// PairTimelineEntry is one point in the history between two entities.
+type PairTimelineEntry struct {
+	At   string `json:"at"` // ISO 8601; relationship dates keep their precision ("2021", "2021-06")
+	Kind string `json:"kind"`
+
+	// Relationship entries
+	RelationshipID string `json:"relationship_id,omitempty"`
+	RelationType   string `json:"relation_type,omitempty"`
+	Fact           string `json:"fact,omitempty"`
+
+	// Episode entries, with the facts about the pair extracted from them
+	EpisodeID string   `json:"episode_id,omitempty"`
+	Channel   string   `json:"channel,omitempty"`
+	ThreadID  string   `json:"thread_id,omitempty"`
+	Facts     []string `json:"facts,omitempty"`
+}
+
+// GetPairTimeline returns the history between two entities, oldest first:
+// when each relationship between them started and ended (including
+// invalidated ones), and the episodes that mention both. Relationships
+// without a valid_at are placed at their first mention. With
+// includeEpisodes false only relationship entries are returned.
+func (q *QueryEngine) GetPairTimeline(ctx context.Context, aID, bID string, includeEpisodes bool) ([]PairTimelineEntry, error) {
+	if aID == "" || bID == "" {
+		return nil, fmt.Errorf("two entity IDs are required")
+	}
+	if aID == bID {
+		return nil, fmt.Errorf("a pair needs two different entities")
+	}
+
+	var entries []PairTimelineEntry
+	rows, err := q.query(ctx, `
+		SELECT r.id, r.relation_type, r.fact, r.valid_at, r.invalid_at, r.created_at,
+		       (SELECT MIN(ep.start_time)
+		        FROM episode_relationship_mentions m
+		        JOIN episodes ep ON ep.id = m.episode_id
+		        WHERE m.relationship_id = r.id)
+		FROM relationships r
+		WHERE (r.source_entity_id = ? AND r.target_entity_id = ?)
+		   OR (r.source_entity_id = ? AND r.target_entity_id = ?)
+	`, aID, bID, bID, aID)
+	if err != nil {
+		return nil, fmt.Errorf("query pair relationships: %w", err)
+	}
+	for rows.Next() {
+		var id, relType, fact, createdAt string
+		var validAt, invalidAt sql.NullString
+		var firstMention sql.NullInt64
+		if err := rows.Scan(&id, &relType, &fact, &validAt, &invalidAt, &createdAt, &firstMention); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan pair relationship: %w", err)
+		}
+		edge := PairTimelineEntry{RelationshipID: id, RelationType: relType, Fact: fact}
+		switch {
+		case validAt.Valid && validAt.String != "":
+			edge.At, edge.Kind = validAt.String, PairEdgeStarted
+		case firstMention.Valid:
+			edge.At, edge.Kind = unixToISO(firstMention.Int64), PairEdgeLearned
+		default:
+			edge.At, edge.Kind = createdAt, PairEdgeLearned
+		}
+		entries = append(entries, edge)
+		if invalidAt.Valid && invalidAt.String != "" {
+			ended := edge
+			ended.At, ended.Kind = invalidAt.String, PairEdgeEnded
+			entries = append(entries, ended)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	if includeEpisodes {
+		episodes, err := q.sharedEpisodes(ctx, aID, bID)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, episodes...)
+	}
+
+	// Relationship entries sort before an episode at the same instant
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].At != entries[j].At {
+			return entries[i].At < entries[j].At
+		}
+		return entries[i].Kind != PairSharedEpisode && entries[j].Kind == PairSharedEpisode
+	})
+	return entries, nil
+}
+
+// sharedEpisodes returns an entry per episode mentioning both entities, with
+// the facts about the pair extracted from it.
+func (q *QueryEngine) sharedEpisodes(ctx context.Context, aID, bID string) ([]PairTimelineEntry, error) {
+	rows, err := q.query(ctx, `
+		SELECT ep.id, ep.start_time, COALESCE(ep.channel, ''), COALESCE(ep.thread_id, '')
+		FROM episode_entity_mentions a
+		JOIN episode_entity_mentions b ON b.episode_id = a.episode_id AND b.entity_id = ?
+		JOIN episodes ep ON ep.id = a.episode_id
+		WHERE a.entity_id = ?
+		ORDER BY ep.start_time
+	`, bID, aID)
+	if err != nil {
+		return nil, fmt.Errorf("query shared episodes: %w", err)
+	}
+	var entries []PairTimelineEntry
+	for rows.Next() {
+		var e PairTimelineEntry
+		var start int64
+		if err := rows.Scan(&e.EpisodeID, &start, &e.Channel, &e.ThreadID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan shared episode: %w", err)
+		}
+		e.At, e.Kind = unixToISO(start), PairSharedEpisode
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range entries {
+		facts, err := q.query(ctx, `
+			SELECT DISTINCT m.extracted_fact
+			FROM episode_relationship_mentions m
+			JOIN relationships r ON r.id = m.relationship_id
+			WHERE m.episode_id = ?
+			  AND ((r.source_entity_id = ? AND r.target_entity_id = ?)
+			    OR (r.source_entity_id = ? AND r.target_entity_id = ?))
+		`, entries[i].EpisodeID, aID, bID, bID, aID)
+		if err != nil {
+			return nil, fmt.Errorf("query episode facts: %w", err)
+		}
+		for facts.Next() {
+			var fact string
+			if err := facts.Scan(&fact); err != nil {
+				facts.Close()
+				return nil, err
+			}
+			entries[i].Facts = append(entries[i].Facts, fact)
+		}
+		err = facts.Err()
+		facts.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+func unixToISO(ts int64) string {
+	return time.Unix(ts, 0).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestGetPairTimeline(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertQueryEngineTestEntity(t, db, "tyler", "Tyler", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "sam", "Sam", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "acme", "Acme", EntityTypeCompany)
+
+	sam, tyler, acme := "sam", "tyler", "acme"
+	ended := "2023-05"
+	insertCurrentFactsRel(t, db, "dating", "tyler", &sam, nil, "DATING", "2021-06", &ended)
+	insertCurrentFactsRel(t, db, "friends", "sam", &tyler, nil, "FRIEND_OF", "", nil)
+	insertCurrentFactsRel(t, db, "job", "tyler", &acme, nil, "WORKS_AT", "2020", nil)
+
+	if _, err := db.Exec(`
+		INSERT INTO threads (id, channel, source_adapter, source_id, created_at, updated_at) VALUES ('t1', 'imessage', 'imessage', 't1', 0, 0);
+		INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0);
+		INSERT INTO episodes (id, definition_id, channel, thread_id, start_time, end_time, event_count, created_at) VALUES
+			('ep1', 'def', 'imessage', 't1', 1577836800, 1577840000, 3, 0),
+			('ep2', 'def', 'imessage', 't1', 1640995200, 1640999000, 3, 0);
+		INSERT INTO episode_entity_mentions (episode_id, entity_id, created_at) VALUES
+			('ep1', 'tyler', 'x'), ('ep1', 'sam', 'x'), ('ep2', 'tyler', 'x');
+		INSERT INTO episode_relationship_mentions (id, episode_id, relationship_id, extracted_fact, created_at) VALUES
+			('m1', 'ep1', 'friends', 'Sam and Tyler are friends', 'x');
+	`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	q := NewQueryEngine(db)
+	defer q.Close()
+
+	entries, err := q.GetPairTimeline(ctx, "tyler", "sam", true)
+	if err != nil {
+		t.Fatalf("GetPairTimeline: %v", err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Kind+"@"+e.At)
+	}
+	want := []string{
+		"learned@2020-01-01T00:00:00Z", // undated, placed at its first mention
+		"episode@2020-01-01T00:00:00Z",
+		"started@2021-06",
+		"ended@2023-05",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("timeline = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("timeline = %v, want %v", got, want)
+		}
+	}
+	if ep := entries[1]; ep.EpisodeID != "ep1" || len(ep.Facts) != 1 || ep.Facts[0] != "Sam and Tyler are friends" {
+		t.Errorf("shared episode = %+v", ep)
+	}
+
+	edges, err := q.GetPairTimeline(ctx, "sam", "tyler", false)
+	if err != nil || len(edges) != 3 {
+		t.Errorf("edges only = %+v, %v", edges, err)
+	}
+	if _, err := q.GetPairTimeline(ctx, "sam", "sam", true); err == nil {
+		t.Error("same entity twice accepted")
+	}
+}
@@ -0,0 +1,704 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/identify"
+	"github.com/google/uuid"
+)
+
+// Bridge link origins: which store a mirrored fact came from.
+const (
+	BridgeOriginPersonFact = "person_fact"
+	BridgeOriginGraph      = "graph"
+)
+
+// personFactRelation maps a person_facts type to the relationship it is
+// mirrored as in the memory graph.
+type personFactRelation struct {
+	factType     string
+	category     string
+	relationType string
+	targetType   int // entity type of the target, or literalTarget
+	verb         string
+}
+
+// literalTarget marks relations whose target is a literal value (a date).
+const literalTarget = -1
+
+var personFactRelations = []personFactRelation{
+	{identify.FactTypeEmployerCurrent, identify.CategoryProfessional, "WORKS_AT", EntityTypeOrganization, "works at"},
+	{identify.FactTypeBusinessOwned, identify.CategoryProfessional, "OWNS", EntityTypeOrganization, "owns"},
+	{identify.FactTypeLocationCurrent, identify.CategoryLocation, "LIVES_IN", EntityTypeLocation, "lives in"},
+	{identify.FactTypeSchoolAttended, identify.CategoryEducation, "ATTENDED", EntityTypeOrganization, "attended"},
+	{identify.FactTypeBirthdate, identify.CategoryCoreIdentity, "BORN_ON", literalTarget, "was born on"},
+}
+
+// aliasFactTypes is the person fact type an entity alias becomes when
+// mirrored out of the graph.
+var aliasFactTypes = map[string]string{
+	"email":  identify.FactTypeEmailPersonal,
+	"phone":  identify.FactTypePhoneMobile,
+	"handle": identify.FactTypeUsernameGeneric,
+}
+
+// aliasTypeForFact returns the entity alias type an identifier fact is
+// mirrored as, or "" if it isn't one.
+func aliasTypeForFact(factType string) string {
+	switch {
+	case strings.HasPrefix(factType, "email_"):
+		return "email"
+	case strings.HasPrefix(factType, "phone_"):
+		return "phone"
+	case strings.HasPrefix(factType, "social_"), factType == identify.FactTypeUsernameGeneric:
+		return "handle"
+	}
+	return ""
+}
+
+// PersonFactBridgeStats summarizes a bridge sync.
+type PersonFactBridgeStats struct {
+	PersonsLinked          int `json:"persons_linked"`
+	RelationshipsFromFacts int `json:"relationships_from_facts"`
+	AliasesFromFacts       int `json:"aliases_from_facts"`
+	FactsFromGraph         int `json:"facts_from_graph"`
+	RelationshipsEnded     int `json:"relationships_ended"`
+}
+
+// PersonFactBridge keeps person_facts (written by the identify/PII path) and
+// the memory graph in step. Persons are linked to Person entities by shared
+// identifiers or an unambiguous name; each linked person's facts are then
+// mirrored as relationships or aliases on the entity, and the entity's
+// relationships and aliases as person facts.
+//
+// Every mirrored pair is recorded in person_fact_graph_links with the side
+// it came from, so nothing is mirrored back to where it started, and
+// mirrors carry the source's confidence, source type, and episode. Facts
+// private to a household member (person_facts.namespace) stay out of the
+// shared graph.
+type PersonFactBridge struct {
+	db            *sql.DB
+	blockingIndex *BlockingIndex
+	now           func() time.Time
+}
+
+// NewPersonFactBridge creates a new PersonFactBridge.
+func NewPersonFactBridge(db *sql.DB) *PersonFactBridge {
+	return &PersonFactBridge{db: db, blockingIndex: NewBlockingIndex(db), now: time.Now}
+}
+
+// Sync links persons to entities and mirrors unmirrored facts both ways.
+func (b *PersonFactBridge) Sync(ctx context.Context) (*PersonFactBridgeStats, error) {
+	stats := &PersonFactBridgeStats{}
+	var err error
+	if stats.PersonsLinked, err = b.LinkPersons(ctx); err != nil {
+		return nil, err
+	}
+
+	var created []string
+	if created, stats.AliasesFromFacts, err = b.mirrorFactsToGraph(ctx); err != nil {
+		return nil, err
+	}
+	stats.RelationshipsFromFacts = len(created)
+	if stats.FactsFromGraph, err = b.mirrorGraphToFacts(ctx); err != nil {
+		return nil, err
+	}
+	if stats.RelationshipsEnded, err = b.endSupersededRelationships(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := NewRelationshipWeigher(b.db).RefreshRelationships(ctx, created); err != nil {
+		// Non-fatal - nightly reweighting catches up
+		_ = err
+	}
+	return stats, nil
+}
+
+// LinkPersons links unlinked persons to Person entities: by a contact
+// identifier matching exactly one entity's unshared alias, else by a
+// canonical name matching exactly one entity. Links to merged entities are
+// moved to the surviving entity. Returns the number of new links.
+func (b *PersonFactBridge) LinkPersons(ctx context.Context) (int, error) {
+	// Follow entity merges (bounded in case of a merge cycle)
+	for i := 0; i < 10; i++ {
+		res, err := b.db.ExecContext(ctx, `
+			UPDATE person_entity_links
+			SET entity_id = (SELECT merged_into FROM entities WHERE id = person_entity_links.entity_id)
+			WHERE entity_id IN (SELECT id FROM entities WHERE merged_into IS NOT NULL)
+		`)
+		if err != nil {
+			return 0, fmt.Errorf("follow merged entities: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			break
+		}
+	}
+
+	aliasIndex, err := b.identifierAliasIndex(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	type person struct{ id, name string }
+	var persons []person
+	rows, err := b.db.QueryContext(ctx, `
+		SELECT id, canonical_name FROM persons
+		WHERE canonical_name NOT LIKE '%[MERGED%'
+		  AND id NOT IN (SELECT person_id FROM person_entity_links)
+		ORDER BY id
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("query unlinked persons: %w", err)
+	}
+	for rows.Next() {
+		var p person
+		if err := rows.Scan(&p.id, &p.name); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan person: %w", err)
+		}
+		persons = append(persons, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	linked := 0
+	for _, p := range persons {
+		entityID, method, err := b.matchPerson(ctx, p.id, p.name, aliasIndex)
+		if err != nil {
+			return linked, err
+		}
+		if entityID == "" {
+			continue
+		}
+		if _, err := b.db.ExecContext(ctx, `
+			INSERT INTO person_entity_links (person_id, entity_id, method, created_at) VALUES (?, ?, ?, ?)
+		`, p.id, entityID, method, b.now().Unix()); err != nil {
+			return linked, fmt.Errorf("link person %s: %w", p.id, err)
+		}
+		linked++
+	}
+	return linked, nil
+}
+
+// identifierAliasIndex maps "type:normalized" identifiers (normalized the
+// way contact identifiers are) to the Person entities holding them.
+func (b *PersonFactBridge) identifierAliasIndex(ctx context.Context) (map[string]map[string]bool, error) {
+	rows, err := b.db.QueryContext(ctx, `
+		SELECT ea.entity_id, ea.alias, ea.alias_type
+		FROM entity_aliases ea
+		JOIN entities e ON e.id = ea.entity_id
+		WHERE e.entity_type_id = ? AND e.merged_into IS NULL
+		  AND ea.alias_type IN ('email', 'phone', 'handle')
+		  AND NOT COALESCE(ea.is_shared, FALSE)
+	`, EntityTypePerson)
+	if err != nil {
+		return nil, fmt.Errorf("query identifier aliases: %w", err)
+	}
+	defer rows.Close()
+
+	index := make(map[string]map[string]bool)
+	for rows.Next() {
+		var entityID, alias, aliasType string
+		if err := rows.Scan(&entityID, &alias, &aliasType); err != nil {
+			return nil, fmt.Errorf("scan alias: %w", err)
+		}
+		key := aliasType + ":" + contacts.NormalizeIdentifier(alias, aliasType)
+		if index[key] == nil {
+			index[key] = make(map[string]bool)
+		}
+		index[key][entityID] = true
+	}
+	return index, rows.Err()
+}
+
+// matchPerson finds the single entity a person is, returning "" if none or
+// several match.
+func (b *PersonFactBridge) matchPerson(ctx context.Context, personID, name string, aliasIndex map[string]map[string]bool) (string, string, error) {
+	rows, err := b.db.QueryContext(ctx, `
+		SELECT ci.type, ci.normalized
+		FROM person_contact_links pcl
+		JOIN contact_identifiers ci ON ci.contact_id = pcl.contact_id
+		WHERE pcl.person_id = ?
+	`, personID)
+	if err != nil {
+		return "", "", fmt.Errorf("query person identifiers: %w", err)
+	}
+	matches := make(map[string]bool)
+	for rows.Next() {
+		var idType, normalized string
+		if err := rows.Scan(&idType, &normalized); err != nil {
+			rows.Close()
+			return "", "", fmt.Errorf("scan identifier: %w", err)
+		}
+		for entityID := range aliasIndex[idType+":"+normalized] {
+			matches[entityID] = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", "", err
+	}
+	if len(matches) > 1 {
+		return "", "", nil
+	}
+	for entityID := range matches {
+		return entityID, "identifier", nil
+	}
+
+	var ids []string
+	nameRows, err := b.db.QueryContext(ctx, `
+		SELECT id FROM entities
+		WHERE entity_type_id = ? AND merged_into IS NULL AND LOWER(canonical_name) = LOWER(?)
+		LIMIT 2
+	`, EntityTypePerson, strings.TrimSpace(name))
+	if err != nil {
+		return "", "", fmt.Errorf("query entities by name: %w", err)
+	}
+	for nameRows.Next() {
+		var id string
+		if err := nameRows.Scan(&id); err != nil {
+			nameRows.Close()
+			return "", "", err
+		}
+		ids = append(ids, id)
+	}
+	nameRows.Close()
+	if len(ids) == 1 {
+		return ids[0], "name", nameRows.Err()
+	}
+	return "", "", nameRows.Err()
+}
+
+// bridgedFact is a current person fact awaiting mirroring into the graph.
+type bridgedFact struct {
+	id, factType, value, sourceType string
+	confidence                      float64
+	episodeID, evidence             sql.NullString
+	entityID, entityName            string
+}
+
+// mirrorFactsToGraph mirrors unlinked, current person facts of linked persons
+// as relationships or aliases. Returns IDs of created relationships and the
+// number of aliases added.
+func (b *PersonFactBridge) mirrorFactsToGraph(ctx context.Context) ([]string, int, error) {
+	var factTypes []string
+	for _, rel := range personFactRelations {
+		factTypes = append(factTypes, rel.factType)
+	}
+	for _, factType := range identify.HardIdentifiers {
+		if aliasTypeForFact(factType) != "" {
+			factTypes = append(factTypes, factType)
+		}
+	}
+
+	rows, err := b.db.QueryContext(ctx, `
+		SELECT pf.id, pf.fact_type, pf.fact_value, pf.source_type, pf.confidence,
+			pf.source_episode_id, pf.evidence, e.id, e.canonical_name
+		FROM person_facts pf
+		JOIN person_entity_links pel ON pel.person_id = pf.person_id
+		JOIN entities e ON e.id = pel.entity_id
+		WHERE pf.superseded_at IS NULL
+		  AND pf.namespace IS NULL
+		  AND pf.fact_type IN (`+placeholderList(len(factTypes))+`)
+		  AND NOT EXISTS (SELECT 1 FROM person_fact_graph_links l WHERE l.person_fact_id = pf.id)
+		ORDER BY pf.created_at, pf.id
+	`, idArgs(factTypes)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query unmirrored facts: %w", err)
+	}
+	var facts []bridgedFact
+	for rows.Next() {
+		var f bridgedFact
+		var confidence sql.NullFloat64
+		if err := rows.Scan(&f.id, &f.factType, &f.value, &f.sourceType, &confidence,
+			&f.episodeID, &f.evidence, &f.entityID, &f.entityName); err != nil {
+			rows.Close()
+			return nil, 0, fmt.Errorf("scan fact: %w", err)
+		}
+		f.confidence = confidence.Float64
+		facts = append(facts, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var created []string
+	aliases := 0
+	for _, f := range facts {
+		if aliasType := aliasTypeForFact(f.factType); aliasType != "" {
+			aliasID, isNew, err := b.ensureAlias(ctx, f.entityID, f.value, aliasType)
+			if err != nil {
+				return created, aliases, err
+			}
+			if err := b.link(ctx, f.id, "", aliasID, BridgeOriginPersonFact); err != nil {
+				return created, aliases, err
+			}
+			if isNew {
+				aliases++
+			}
+			continue
+		}
+
+		for _, rel := range personFactRelations {
+			if rel.factType != f.factType {
+				continue
+			}
+			relID, isNew, err := b.ensureRelationship(ctx, f, rel)
+			if err != nil {
+				return created, aliases, err
+			}
+			if err := b.link(ctx, f.id, relID, "", BridgeOriginPersonFact); err != nil {
+				return created, aliases, err
+			}
+			if isNew {
+				created = append(created, relID)
+			}
+		}
+	}
+	return created, aliases, nil
+}
+
+// ensureRelationship finds or creates the relationship a fact mirrors as.
+func (b *PersonFactBridge) ensureRelationship(ctx context.Context, f bridgedFact, rel personFactRelation) (string, bool, error) {
+	var targetID, targetLiteral *string
+	var existing string
+	var err error
+	if rel.targetType == literalTarget {
+		targetLiteral = &f.value
+		err = b.db.QueryRowContext(ctx, `
+			SELECT id FROM relationships
+			WHERE source_entity_id = ? AND relation_type = ? AND target_literal = ? AND invalid_at IS NULL
+			LIMIT 1
+		`, f.entityID, rel.relationType, f.value).Scan(&existing)
+	} else {
+		id, err := b.findOrCreateTarget(ctx, f.value, rel.targetType)
+		if err != nil {
+			return "", false, err
+		}
+		targetID = &id
+		err = b.db.QueryRowContext(ctx, `
+			SELECT id FROM relationships
+			WHERE source_entity_id = ? AND relation_type = ? AND target_entity_id = ? AND invalid_at IS NULL
+			LIMIT 1
+		`, f.entityID, rel.relationType, id).Scan(&existing)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return "", false, fmt.Errorf("find relationship: %w", err)
+	}
+
+	var sourceType *string
+	if SourceTypeRank(f.sourceType) > 0 {
+		sourceType = &f.sourceType
+	}
+	if existing != "" {
+		if sourceType != nil {
+			if _, err := b.db.ExecContext(ctx, `
+				UPDATE relationships SET source_type = ?
+				WHERE id = ? AND `+sourceTypeRankSQL("source_type")+` < ?
+			`, *sourceType, existing, SourceTypeRank(*sourceType)); err != nil {
+				return "", false, fmt.Errorf("update source type: %w", err)
+			}
+		}
+		return existing, false, nil
+	}
+
+	id := uuid.New().String()
+	now := b.now().Format(time.RFC3339)
+	fact := fmt.Sprintf("%s %s %s", f.entityName, rel.verb, f.value)
+	if _, err := b.db.ExecContext(ctx, `
+		INSERT INTO relationships (
+			id, source_entity_id, target_entity_id, target_literal,
+			relation_type, fact, created_at, confidence, source_type
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, f.entityID, targetID, targetLiteral, rel.relationType, fact, now, f.confidence, sourceType); err != nil {
+		return "", false, fmt.Errorf("insert relationship: %w", err)
+	}
+
+	// Keep the fact's provenance: a mention in the episode it came from
+	if f.episodeID.Valid {
+		var exists int
+		_ = b.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM episodes WHERE id = ?`, f.episodeID.String).Scan(&exists)
+		if exists > 0 {
+			extracted := fact
+			if f.evidence.Valid && f.evidence.String != "" {
+				extracted = f.evidence.String
+			}
+			if _, err := b.db.ExecContext(ctx, `
+				INSERT INTO episode_relationship_mentions (
+					id, episode_id, relationship_id, extracted_fact, source_type, confidence, created_at
+				) VALUES (?, ?, ?, ?, ?, ?, ?)
+			`, uuid.New().String(), f.episodeID.String, id, extracted, sourceType, f.confidence, now); err != nil {
+				return "", false, fmt.Errorf("insert mention: %w", err)
+			}
+		}
+	}
+	return id, true, nil
+}
+
+// findOrCreateTarget returns the entity named value, preferring the expected
+// type, creating one if none exists.
+func (b *PersonFactBridge) findOrCreateTarget(ctx context.Context, value string, entityType int) (string, error) {
+	var id string
+	err := b.db.QueryRowContext(ctx, `
+		SELECT e.id FROM entities e
+		LEFT JOIN entity_aliases ea ON ea.entity_id = e.id AND ea.alias_type = 'name'
+		WHERE e.merged_into IS NULL
+		  AND (LOWER(e.canonical_name) = LOWER(?) OR ea.normalized = ?)
+		ORDER BY e.entity_type_id = ? DESC, e.created_at
+		LIMIT 1
+	`, value, normalizeAlias(value), entityType).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", fmt.Errorf("find target entity: %w", err)
+	}
+
+	id = uuid.New().String()
+	now := b.now().Format(time.RFC3339)
+	if _, err := b.db.ExecContext(ctx, `
+		INSERT INTO entities (id, canonical_name, entity_type_id, origin, confidence, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 1.0, ?, ?)
+	`, id, value, entityType, OriginImported, now, now); err != nil {
+		return "", fmt.Errorf("create target entity: %w", err)
+	}
+	if _, err := b.db.ExecContext(ctx, `
+		INSERT INTO entity_aliases (id, entity_id, alias, alias_type, normalized, is_shared, created_at)
+		VALUES (?, ?, ?, 'name', ?, FALSE, ?)
+	`, uuid.New().String(), id, value, normalizeAlias(value), now); err != nil {
+		return "", fmt.Errorf("create target alias: %w", err)
+	}
+	if err := b.blockingIndex.IndexAlias(ctx, id, value, "name"); err != nil {
+		// Non-fatal - IndexMissing catches up on the next run
+		_ = err
+	}
+	return id, nil
+}
+
+// ensureAlias finds or adds an identifier alias on an entity.
+func (b *PersonFactBridge) ensureAlias(ctx context.Context, entityID, value, aliasType string) (string, bool, error) {
+	normalized := normalizeIdentityValue(value, aliasType)
+	var id string
+	err := b.db.QueryRowContext(ctx, `
+		SELECT id FROM entity_aliases WHERE entity_id = ? AND alias_type = ? AND normalized = ?
+	`, entityID, aliasType, normalized).Scan(&id)
+	if err == nil {
+		return id, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", false, fmt.Errorf("find alias: %w", err)
+	}
+
+	id = uuid.New().String()
+	if _, err := b.db.ExecContext(ctx, `
+		INSERT INTO entity_aliases (id, entity_id, alias, alias_type, normalized, is_shared, created_at)
+		VALUES (?, ?, ?, ?, ?, FALSE, ?)
+	`, id, entityID, value, aliasType, normalized, b.now().Format(time.RFC3339)); err != nil {
+		return "", false, fmt.Errorf("insert alias: %w", err)
+	}
+	if err := b.blockingIndex.IndexAlias(ctx, entityID, value, aliasType); err != nil {
+		// Non-fatal - IndexMissing catches up on the next run
+		_ = err
+	}
+	return id, true, nil
+}
+
+// mirrorGraphToFacts mirrors unlinked current relationships and identifier
+// aliases of linked entities as person facts. Returns the number mirrored.
+func (b *PersonFactBridge) mirrorGraphToFacts(ctx context.Context) (int, error) {
+	relationTypes := make([]string, 0, len(personFactRelations))
+	byRelation := make(map[string]personFactRelation, len(personFactRelations))
+	for _, rel := range personFactRelations {
+		relationTypes = append(relationTypes, rel.relationType)
+		byRelation[rel.relationType] = rel
+	}
+
+	type graphFact struct {
+		relationshipID, aliasID string
+		personID, factType      string
+		category, value         string
+		confidence              float64
+		sourceType              string
+		episodeID, evidence     sql.NullString
+	}
+	var pending []graphFact
+
+	rows, err := b.db.QueryContext(ctx, `
+		SELECT r.id, pel.person_id, r.relation_type, COALESCE(t.canonical_name, r.target_literal),
+			r.confidence, r.source_type, r.fact,
+			(SELECT erm.episode_id FROM episode_relationship_mentions erm
+			 WHERE erm.relationship_id = r.id ORDER BY erm.created_at LIMIT 1)
+		FROM relationships r
+		JOIN person_entity_links pel ON pel.entity_id = r.source_entity_id
+		LEFT JOIN entities t ON t.id = r.target_entity_id
+		WHERE r.invalid_at IS NULL
+		  AND r.relation_type IN (`+placeholderList(len(relationTypes))+`)
+		  AND NOT EXISTS (SELECT 1 FROM person_fact_graph_links l WHERE l.relationship_id = r.id)
+		ORDER BY r.created_at, r.id
+	`, idArgs(relationTypes)...)
+	if err != nil {
+		return 0, fmt.Errorf("query unmirrored relationships: %w", err)
+	}
+	for rows.Next() {
+		var g graphFact
+		var relType string
+		var value, sourceType sql.NullString
+		var confidence sql.NullFloat64
+		if err := rows.Scan(&g.relationshipID, &g.personID, &relType, &value,
+			&confidence, &sourceType, &g.evidence, &g.episodeID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan relationship: %w", err)
+		}
+		if !value.Valid || value.String == "" {
+			continue
+		}
+		rel := byRelation[relType]
+		g.factType, g.category, g.value = rel.factType, rel.category, value.String
+		g.confidence, g.sourceType = confidence.Float64, sourceType.String
+		pending = append(pending, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	aliasRows, err := b.db.QueryContext(ctx, `
+		SELECT ea.id, pel.person_id, ea.alias, ea.alias_type
+		FROM entity_aliases ea
+		JOIN person_entity_links pel ON pel.entity_id = ea.entity_id
+		WHERE ea.alias_type IN ('email', 'phone', 'handle')
+		  AND NOT EXISTS (SELECT 1 FROM person_fact_graph_links l WHERE l.alias_id = ea.id)
+		ORDER BY ea.created_at, ea.id
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("query unmirrored aliases: %w", err)
+	}
+	for aliasRows.Next() {
+		var g graphFact
+		var aliasType string
+		if err := aliasRows.Scan(&g.aliasID, &g.personID, &g.value, &aliasType); err != nil {
+			aliasRows.Close()
+			return 0, fmt.Errorf("scan alias: %w", err)
+		}
+		g.factType = aliasFactTypes[aliasType]
+		g.category = identify.CategoryContactInfo
+		if aliasType == "handle" {
+			g.category = identify.CategoryDigitalIdentity
+		}
+		pending = append(pending, g)
+	}
+	aliasRows.Close()
+	if err := aliasRows.Err(); err != nil {
+		return 0, err
+	}
+
+	mirrored := 0
+	for _, g := range pending {
+		// An identifier already on file under a more specific type (e.g.
+		// email_work) is the same fact, not a new one
+		var factID string
+		if g.aliasID != "" {
+			family := strings.SplitN(g.factType, "_", 2)[0]
+			_ = b.db.QueryRowContext(ctx, `
+				SELECT id FROM person_facts
+				WHERE person_id = ? AND fact_type LIKE ? AND normalized_value = ?
+				LIMIT 1
+			`, g.personID, family+"_%", identify.NormalizeFactValue(g.factType, g.value)).Scan(&factID)
+		}
+
+		if factID == "" {
+			sourceType := g.sourceType
+			if sourceType == "" {
+				sourceType = "mentioned"
+			}
+			channel := "memory"
+			fact := identify.PersonFact{
+				PersonID:      g.personID,
+				Category:      g.category,
+				FactType:      g.factType,
+				FactValue:     g.value,
+				Confidence:    g.confidence,
+				SourceType:    sourceType,
+				SourceChannel: &channel,
+			}
+			if fact.Confidence <= 0 {
+				fact.Confidence = 0.5
+			}
+			if g.episodeID.Valid {
+				fact.SourceSegment = &g.episodeID.String
+			}
+			if g.evidence.Valid {
+				fact.Evidence = &g.evidence.String
+			}
+			id, err := identify.UpsertFact(b.db, fact)
+			if err != nil {
+				return mirrored, fmt.Errorf("mirror into person facts: %w", err)
+			}
+			factID = id
+			mirrored++
+		}
+		if err := b.link(ctx, factID, g.relationshipID, g.aliasID, BridgeOriginGraph); err != nil {
+			return mirrored, err
+		}
+	}
+	return mirrored, nil
+}
+
+// endSupersededRelationships invalidates relationships mirrored from person
+// facts that a newer value has since superseded. Relationships that came
+// from the graph itself are left alone.
+func (b *PersonFactBridge) endSupersededRelationships(ctx context.Context) (int, error) {
+	rows, err := b.db.QueryContext(ctx, `
+		SELECT r.id, pf.superseded_at
+		FROM person_fact_graph_links l
+		JOIN person_facts pf ON pf.id = l.person_fact_id
+		JOIN relationships r ON r.id = l.relationship_id
+		WHERE l.origin = ? AND pf.superseded_at IS NOT NULL AND r.invalid_at IS NULL
+	`, BridgeOriginPersonFact)
+	if err != nil {
+		return 0, fmt.Errorf("query superseded facts: %w", err)
+	}
+	ended := make(map[string]int64)
+	for rows.Next() {
+		var id string
+		var at int64
+		if err := rows.Scan(&id, &at); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan superseded fact: %w", err)
+		}
+		ended[id] = at
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for id, at := range ended {
+		if _, err := b.db.ExecContext(ctx, `
+			UPDATE relationships SET invalid_at = ? WHERE id = ?
+		`, time.Unix(at, 0).UTC().Format(time.RFC3339), id); err != nil {
+			return 0, fmt.Errorf("invalidate relationship: %w", err)
+		}
+	}
+	return len(ended), nil
+}
+
+// link records that a person fact and a relationship or alias mirror each other.
+func (b *PersonFactBridge) link(ctx context.Context, factID, relationshipID, aliasID, origin string) error {
+	_, err := b.db.ExecContext(ctx, `
+		INSERT INTO person_fact_graph_links (id, person_fact_id, relationship_id, alias_id, origin, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, uuid.New().String(), factID, nullIfEmpty(relationshipID), nullIfEmpty(aliasID), origin, b.now().Unix())
+	if err != nil {
+		return fmt.Errorf("record bridge link: %w", err)
+	}
+	return nil
+}
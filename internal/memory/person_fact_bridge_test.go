@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/identify"
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestPersonFactBridge_Sync(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, p := range [][2]string{{"casey", "Casey Jones"}, {"sam", "Sam Lee"}} {
+		if _, err := db.Exec(`INSERT INTO persons (id, canonical_name, created_at, updated_at) VALUES (?, ?, 0, 0)`, p[0], p[1]); err != nil {
+			t.Fatalf("insert person: %v", err)
+		}
+	}
+	contactID, _, err := contacts.GetOrCreateContact(db, "email", "casey@example.com", "", "test")
+	if err != nil {
+		t.Fatalf("create contact: %v", err)
+	}
+	if err := contacts.EnsurePersonContactLink(db, "casey", contactID, "test", 1.0); err != nil {
+		t.Fatalf("link contact: %v", err)
+	}
+
+	// Casey's entity goes by a different name but shares the email; Sam's
+	// matches by name alone
+	for _, ent := range [][3]string{{"e-casey", "CJ", "1"}, {"e-sam", "Sam Lee", "1"}, {"e-austin", "Austin", "4"}} {
+		if _, err := db.Exec(`INSERT INTO entities (id, canonical_name, entity_type_id, origin, created_at, updated_at) VALUES (?, ?, ?, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')`, ent[0], ent[1], ent[2]); err != nil {
+			t.Fatalf("insert entity: %v", err)
+		}
+	}
+	db.Exec(`INSERT INTO entity_aliases (id, entity_id, alias, alias_type, normalized, created_at) VALUES ('a-casey', 'e-casey', 'Casey@Example.com', 'email', 'casey@example.com', '2026-01-01T00:00:00Z')`)
+	db.Exec(`INSERT INTO relationships (id, source_entity_id, target_entity_id, relation_type, fact, confidence, source_type, created_at) VALUES ('r-sam-austin', 'e-sam', 'e-austin', 'LIVES_IN', 'Sam lives in Austin', 0.8, 'self_disclosed', '2026-01-01T00:00:00Z')`)
+
+	if err := identify.InsertFact(db, identify.PersonFact{PersonID: "casey", Category: identify.CategoryProfessional, FactType: identify.FactTypeEmployerCurrent, FactValue: "Acme", Confidence: 0.9, SourceType: "self_disclosed"}); err != nil {
+		t.Fatalf("insert fact: %v", err)
+	}
+
+	bridge := NewPersonFactBridge(db)
+	stats, err := bridge.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if stats.PersonsLinked != 2 || stats.RelationshipsFromFacts != 1 || stats.FactsFromGraph != 2 {
+		t.Errorf("stats = %+v, want 2 linked, 1 relationship, 2 facts (location and email)", stats)
+	}
+	var method string
+	db.QueryRow(`SELECT method FROM person_entity_links WHERE person_id = 'casey' AND entity_id = 'e-casey'`).Scan(&method)
+	if method != "identifier" {
+		t.Errorf("casey link method = %q, want identifier", method)
+	}
+
+	// Fact -> graph: Acme is created as an organization, provenance kept
+	var acmeType int
+	var sourceType string
+	if err := db.QueryRow(`
+		SELECT e.entity_type_id, r.source_type FROM relationships r JOIN entities e ON e.id = r.target_entity_id
+		WHERE r.source_entity_id = 'e-casey' AND r.relation_type = 'WORKS_AT' AND r.invalid_at IS NULL
+	`).Scan(&acmeType, &sourceType); err != nil {
+		t.Fatalf("WORKS_AT relationship: %v", err)
+	}
+	if acmeType != EntityTypeOrganization || sourceType != "self_disclosed" {
+		t.Errorf("Acme type %d, source type %q", acmeType, sourceType)
+	}
+
+	// Graph -> facts
+	facts, err := identify.GetFactsForPerson(db, "sam")
+	if err != nil {
+		t.Fatalf("get facts: %v", err)
+	}
+	if len(facts) != 1 || facts[0].FactType != identify.FactTypeLocationCurrent || facts[0].FactValue != "Austin" || facts[0].SourceType != "self_disclosed" {
+		t.Errorf("sam facts = %+v, want location_current Austin", facts)
+	}
+
+	// Nothing mirrors back on a second run
+	stats, err = bridge.Sync(ctx)
+	if err != nil {
+		t.Fatalf("second Sync: %v", err)
+	}
+	if *stats != (PersonFactBridgeStats{}) {
+		t.Errorf("second sync stats = %+v, want nothing new", stats)
+	}
+	var relCount int
+	db.QueryRow(`SELECT COUNT(*) FROM relationships`).Scan(&relCount)
+	if relCount != 2 {
+		t.Errorf("relationships = %d, want 2", relCount)
+	}
+
+	// A new employer supersedes Acme and ends the mirrored relationship
+	if err := identify.InsertFact(db, identify.PersonFact{PersonID: "casey", Category: identify.CategoryProfessional, FactType: identify.FactTypeEmployerCurrent, FactValue: "Globex", Confidence: 0.9, SourceType: "self_disclosed"}); err != nil {
+		t.Fatalf("insert new employer: %v", err)
+	}
+	stats, err = bridge.Sync(ctx)
+	if err != nil {
+		t.Fatalf("third Sync: %v", err)
+	}
+	if stats.RelationshipsFromFacts != 1 || stats.RelationshipsEnded != 1 {
+		t.Errorf("third sync stats = %+v, want 1 new and 1 ended", stats)
+	}
+	var current string
+	db.QueryRow(`
+		SELECT e.canonical_name FROM relationships r JOIN entities e ON e.id = r.target_entity_id
+		WHERE r.source_entity_id = 'e-casey' AND r.relation_type = 'WORKS_AT' AND r.invalid_at IS NULL
+	`).Scan(&current)
+	if current != "Globex" {
+		t.Errorf("current employer = %q, want Globex", current)
+	}
+}
@@ -2,11 +2,19 @@ package memory
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/Napageneral/mnemonic/internal/chunk"
+	"github.com/Napageneral/mnemonic/internal/config"
 	"github.com/Napageneral/mnemonic/internal/gemini"
+	"github.com/Napageneral/mnemonic/internal/notes"
 )
 
 // PipelineConfig holds configuration for the memory extraction pipeline.
@@ -17,22 +25,122 @@ type PipelineConfig struct {
 	EmbeddingModel string
 	// Whether to skip embedding generation (useful for testing)
 	SkipEmbeddings bool
+	// Embed new entities synchronously instead of queuing embedding jobs
+	// for the compute worker (slower; the default is fire-and-forget)
+	InlineEmbeddings bool
 	// Optional custom instructions for extraction
 	CustomInstructions string
 	// Number of previous episodes to include for context (default: 0)
 	LookbackEpisodes int
+	// Estimated token budget for priming known entities from contacts (0 disables priming)
+	KnownEntityTokenBudget int
+	// Number of alias lookups kept in the hot-entity cache (0 disables caching)
+	EntityCacheSize int
+	// Per-relation-type cardinality overrides applied on top of DefaultCardinalityRules
+	CardinalityRules map[string]CardinalityRule
+	// Optional per-episode model routing (nil always uses ExtractionModel)
+	ModelRouting *ModelRouterConfig
+	// Run a second LLM pass that reviews extracted relationships against the
+	// episode text, dropping unsupported facts and setting confidence
+	SelfCritique bool
+	// Model for the self-critique pass (default: the episode's extraction model)
+	CritiqueModel string
+	// Reviewed relationships below this confidence are dropped (default: DefaultCritiqueMinConfidence)
+	CritiqueMinConfidence float64
+	// Re-prompt entity extraction on low-recall episodes (nil disables gleaning)
+	Gleaning *GleaningConfig
+	// Allowed/blocked relation types by channel (channels not listed extract everything)
+	RelationTypePolicies map[string]RelationTypePolicy
+	// Sampling settings per stage (zero values use the model's defaults)
+	EntityGeneration       GenerationParams // entity extraction and gleaning
+	RelationshipGeneration GenerationParams
+	CritiqueGeneration     GenerationParams
+	// Skip low-information episodes (link shares, emoji replies, "ok") before
+	// extraction, recording why in episode_processing (nil disables)
+	QualityGate *EpisodeQualityConfig
 }
 
 // DefaultPipelineConfig returns a default pipeline configuration.
 func DefaultPipelineConfig() *PipelineConfig {
+	gleaning := DefaultGleaningConfig()
 	return &PipelineConfig{
-		ExtractionModel:  "gemini-2.0-flash",
-		EmbeddingModel:   DefaultEmbeddingModel,
-		SkipEmbeddings:   false,
-		LookbackEpisodes: 0,
+		ExtractionModel:        "gemini-2.0-flash",
+		EmbeddingModel:         DefaultEmbeddingModel,
+		SkipEmbeddings:         false,
+		LookbackEpisodes:       0,
+		KnownEntityTokenBudget: DefaultKnownEntityTokenBudget,
+		EntityCacheSize:        DefaultEntityCacheSize,
+		Gleaning:               &gleaning,
+		RelationTypePolicies:   DefaultRelationTypePolicies(),
+		EntityGeneration:       DefaultEntityGenerationParams(),
+		RelationshipGeneration: DefaultRelationshipGenerationParams(),
+		CritiqueGeneration:     DefaultCritiqueGenerationParams(),
 	}
 }
 
+// NewPipelineConfig returns the default pipeline configuration with the
+// memory section of the config file applied.
+func NewPipelineConfig(cfg config.MemoryConfig) (*PipelineConfig, error) {
+	pc := DefaultPipelineConfig()
+	if r := cfg.ModelRouting; r != nil {
+		if r.MaxCheapTokens < 0 || r.MaxCheapParticipants < 0 {
+			return nil, fmt.Errorf("model_routing thresholds must not be negative")
+		}
+		pc.ModelRouting = &ModelRouterConfig{
+			CheapModel:           r.CheapModel,
+			StrongModel:          r.StrongModel,
+			MaxCheapTokens:       r.MaxCheapTokens,
+			MaxCheapParticipants: r.MaxCheapParticipants,
+		}
+	}
+	for channel, rt := range cfg.RelationTypes {
+		allowed, err := configRelationTypes(channel, rt.Allowed)
+		if err != nil {
+			return nil, err
+		}
+		blocked, err := configRelationTypes(channel, rt.Blocked)
+		if err != nil {
+			return nil, err
+		}
+		pc.RelationTypePolicies[channel] = RelationTypePolicy{Allowed: allowed, Blocked: blocked}
+	}
+	if len(cfg.Cardinality) > 0 {
+		pc.CardinalityRules = make(map[string]CardinalityRule, len(cfg.Cardinality))
+	}
+	for relType, c := range cfg.Cardinality {
+		relType = strings.ToUpper(strings.TrimSpace(relType))
+		if !relationTypePattern.MatchString(relType) {
+			return nil, fmt.Errorf("cardinality: invalid relation type %q (want SCREAMING_SNAKE_CASE, e.g. WORKS_AT)", relType)
+		}
+		rule := CardinalityRule{Cardinality: Cardinality(c.Cardinality), Enforcement: CardinalityEnforcement(c.Enforcement)}
+		switch rule.Cardinality {
+		case "", CardinalityMany, CardinalityOne:
+		default:
+			return nil, fmt.Errorf("cardinality.%s: unknown cardinality %q (want one or many)", relType, c.Cardinality)
+		}
+		switch rule.Enforcement {
+		case "", EnforceInvalidate, EnforceFlag:
+		default:
+			return nil, fmt.Errorf("cardinality.%s: unknown enforcement %q (want invalidate or flag)", relType, c.Enforcement)
+		}
+		pc.CardinalityRules[relType] = rule
+	}
+	return pc, nil
+}
+
+// configRelationTypes normalizes a channel's configured relation types.
+func configRelationTypes(channel string, types []string) ([]string, error) {
+	var out []string
+	for _, t := range types {
+		t = strings.ToUpper(strings.TrimSpace(t))
+		if !relationTypePattern.MatchString(t) {
+			return nil, fmt.Errorf("relation_types.%s: invalid relation type %q (want SCREAMING_SNAKE_CASE, e.g. WORKS_AT)", channel, t)
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
 // EpisodeInput represents the input episode to process.
 type EpisodeInput struct {
 	ID            string        // Episode UUID
@@ -42,6 +150,15 @@ type EpisodeInput struct {
 	StartTime     time.Time     // Episode start time (used for contradiction detection)
 	ReferenceTime string        // ISO 8601 timestamp for temporal reference in extraction
 	KnownEntities []KnownEntity // Optional: entities we already know about (e.g., thread participants)
+	// Optional: number of distinct participants, used for model routing
+	// (looked up from event_participants when zero)
+	ParticipantCount int
+	// Extract even if the quality gate would skip the episode (revisiting skipped episodes)
+	IgnoreQualityGate bool
+	// Extract even if this content was extracted before (targeted replays)
+	Replay bool
+	// Optional: replaces the channel's allowed relation types (its blocked ones still apply)
+	RelationTypes *RelationTypePolicy
 }
 
 // PipelineResult contains the results of pipeline processing.
@@ -51,30 +168,46 @@ type PipelineResult struct {
 	ResolvedEntities  []ResolvedEntity  `json:"resolved_entities"`
 	NewEntities       int               `json:"new_entities"`
 	ExistingEntities  int               `json:"existing_entities"`
+	GleaningRounds    int               `json:"gleaning_rounds"`
+	GleanedEntities   int               `json:"gleaned_entities"`
 
 	// Relationship extraction
 	ExtractedRelationships []ExtractedRelationship `json:"extracted_relationships"`
 	NewRelationships       int                     `json:"new_relationships"`
 	ExistingRelationships  int                     `json:"existing_relationships"`
 
+	// Self-critique (when enabled)
+	CritiqueDropped  []DroppedRelationship `json:"critique_dropped,omitempty"`
+	CritiqueAdjusted int                   `json:"critique_adjusted"`
+
 	// Identity promotion
 	PromotedIdentities int `json:"promoted_identities"`
 	AliasesCreated     int `json:"aliases_created"`
 
 	// Contradiction detection
-	ContradictionsFound int `json:"contradictions_found"`
+	ContradictionsFound   int `json:"contradictions_found"`
+	CardinalityViolations int `json:"cardinality_violations"`
 
 	// Embeddings
 	EmbeddingsGenerated int `json:"embeddings_generated"`
+	EmbeddingsQueued    int `json:"embeddings_queued"`
 
 	// Episode mentions
 	EntityMentionsCreated       int `json:"entity_mentions_created"`
 	RelationshipMentionsCreated int `json:"relationship_mentions_created"`
 
 	// Processing metadata
-	ProcessedAt time.Time     `json:"processed_at"`
-	Duration    time.Duration `json:"duration"`
-	Skipped     bool          `json:"skipped"` // True if episode was already processed
+	ProcessedAt  time.Time       `json:"processed_at"`
+	Duration     time.Duration   `json:"duration"`
+	Skipped      bool            `json:"skipped"`               // True if episode was already processed or failed the quality gate
+	SkipReason   string          `json:"skip_reason,omitempty"` // Set when the quality gate skipped the episode
+	Quality      *EpisodeQuality `json:"quality,omitempty"`     // Set when the quality gate is enabled
+	ContentHash  string          `json:"content_hash,omitempty"`
+	LLMCalls     int             `json:"llm_calls"`
+	PromptTokens int64           `json:"prompt_tokens"`
+	OutputTokens int64           `json:"output_tokens"`
+	CostUSD      float64         `json:"cost_usd"`
+	Route        *RouteDecision  `json:"route,omitempty"` // Set when model routing is enabled
 }
 
 // MemoryPipeline orchestrates the full memory extraction pipeline.
@@ -93,6 +226,17 @@ type MemoryPipeline struct {
 	edgeResolver          *EdgeResolver
 	contradictionDetector *ContradictionDetector
 	entityEmbedder        *EntityEmbedder
+	knownEntityPrimer     *KnownEntityPrimer // nil when priming is disabled
+	entityCache           *EntityCache       // nil when caching is disabled
+	currentFacts          *CurrentFactsStore
+	relationshipWeigher   *RelationshipWeigher
+	geoNormalizer         *GeoNormalizer
+	orgNormalizer         *OrgNormalizer
+	processingLog         *ProcessingLog
+	modelRouter           *ModelRouter          // nil when routing is disabled
+	relationshipCritic    *RelationshipCritic   // nil when self-critique is disabled
+	embeddingQueue        *EmbeddingQueue       // nil when embedding inline or skipped
+	qualityScorer         *EpisodeQualityScorer // nil when the quality gate is disabled
 }
 
 // NewMemoryPipeline creates a new MemoryPipeline.
@@ -101,7 +245,19 @@ func NewMemoryPipeline(db *sql.DB, geminiClient *gemini.Client, config *Pipeline
 		config = DefaultPipelineConfig()
 	}
 
-	return &MemoryPipeline{
+	var primer *KnownEntityPrimer
+	if config.KnownEntityTokenBudget > 0 {
+		primerConfig := DefaultKnownEntityPrimerConfig()
+		primerConfig.TokenBudget = config.KnownEntityTokenBudget
+		primer = NewKnownEntityPrimer(db, primerConfig)
+	}
+
+	var cache *EntityCache
+	if config.EntityCacheSize > 0 {
+		cache = NewEntityCache(config.EntityCacheSize)
+	}
+
+	p := &MemoryPipeline{
 		db:                    db,
 		geminiClient:          geminiClient,
 		config:                config,
@@ -112,7 +268,51 @@ func NewMemoryPipeline(db *sql.DB, geminiClient *gemini.Client, config *Pipeline
 		edgeResolver:          NewEdgeResolver(db),
 		contradictionDetector: NewContradictionDetector(db),
 		entityEmbedder:        NewEntityEmbedder(db, geminiClient, config.EmbeddingModel),
+		knownEntityPrimer:     primer,
+		entityCache:           cache,
+		currentFacts:          NewCurrentFactsStore(db),
+		relationshipWeigher:   NewRelationshipWeigher(db),
+		geoNormalizer:         NewGeoNormalizer(db),
+		orgNormalizer:         NewOrgNormalizer(db),
+		processingLog:         NewProcessingLog(db),
+	}
+	p.entityExtractor.SetGenerationParams(config.EntityGeneration)
+	p.relationshipExtractor.SetGenerationParams(config.RelationshipGeneration)
+	p.entityResolver.SetCache(cache)
+	p.identityPromoter.SetCache(cache)
+	p.geoNormalizer.SetCache(cache)
+	p.orgNormalizer.SetCache(cache)
+	if config.CardinalityRules != nil {
+		p.contradictionDetector.SetCardinalityRules(config.CardinalityRules)
+	}
+	if config.ModelRouting != nil {
+		routing := *config.ModelRouting
+		if routing.CheapModel == "" {
+			routing.CheapModel = config.ExtractionModel
+		}
+		p.modelRouter = NewModelRouter(db, routing)
+	}
+	if !config.SkipEmbeddings && !config.InlineEmbeddings {
+		embeddingQueue, err := NewEmbeddingQueue(db, p.entityEmbedder)
+		if err == nil {
+			p.embeddingQueue = embeddingQueue
+		}
+		// Non-fatal - without a job queue, embeddings are generated inline
+	}
+	if config.QualityGate != nil {
+		p.qualityScorer = NewEpisodeQualityScorer(db, *config.QualityGate)
 	}
+	if config.SelfCritique {
+		p.relationshipCritic = NewRelationshipCritic(geminiClient, config.ExtractionModel, config.CritiqueMinConfidence)
+		p.relationshipCritic.SetGenerationParams(config.CritiqueGeneration)
+	}
+	return p
+}
+
+// EntityCache returns the pipeline's hot-entity cache (nil if disabled).
+// Share it with an AutoMerger (SetCache) so merges invalidate cached lookups.
+func (p *MemoryPipeline) EntityCache() *EntityCache {
+	return p.entityCache
 }
 
 // Process runs the full memory extraction pipeline for an episode.
@@ -120,10 +320,99 @@ func NewMemoryPipeline(db *sql.DB, geminiClient *gemini.Client, config *Pipeline
 //
 // The pipeline is idempotent: reprocessing the same episode yields no new
 // entities/relationships (existing ones are reused via deduplication).
+//
+// Tokens, model, estimated cost and wall time are recorded per episode in
+// episode_processing whenever the LLM was called, including failed runs.
+// Episodes skipped by the quality gate are recorded with status "skipped"
+// and the reason, so they can be listed and revisited.
 func (p *MemoryPipeline) Process(ctx context.Context, episode EpisodeInput) (*PipelineResult, error) {
 	startTime := time.Now()
+	var route *RouteDecision
+	if p.modelRouter != nil && episode.Content != "" {
+		decision := p.modelRouter.Route(ctx, episode)
+		route = &decision
+	}
+	tracker := &UsageTracker{}
+	result, err := p.process(WithUsageTracker(ctx, tracker), episode, startTime, route)
+
+	if err == nil && result != nil && result.SkipReason != "" {
+		p.recordQualitySkip(ctx, episode, result)
+		return result, nil
+	}
+	if tracker.Calls == 0 && tracker.EmbedChars == 0 {
+		return result, err // Skipped or empty - nothing spent
+	}
+	rec := EpisodeProcessing{
+		EpisodeID:    episode.ID,
+		Channel:      episode.Channel,
+		Model:        tracker.Model,
+		LLMCalls:     tracker.Calls,
+		PromptTokens: tracker.PromptTokens,
+		OutputTokens: tracker.OutputTokens,
+		EmbedChars:   tracker.EmbedChars,
+		CostUSD:      tracker.CostUSD(),
+		DurationMs:   time.Since(startTime).Milliseconds(),
+		ContentChars: len(episode.Content),
+		Status:       ProcessingStatusOK,
+		ProcessedAt:  startTime,
+		Route:        route,
+	}
+	if result != nil {
+		rec.ContentHash = result.ContentHash
+		if result.Quality != nil {
+			rec.QualityScore = &result.Quality.Score
+		}
+	}
+	if episode.ThreadID != nil {
+		rec.ThreadID = *episode.ThreadID
+	}
+	if err != nil {
+		rec.Status = ProcessingStatusError
+		rec.Error = err.Error()
+	}
+	if recErr := p.processingLog.Record(ctx, rec); recErr != nil {
+		// Non-fatal - cost attribution is best effort
+		_ = recErr
+	}
+	if result != nil {
+		result.LLMCalls = rec.LLMCalls
+		result.PromptTokens = rec.PromptTokens
+		result.OutputTokens = rec.OutputTokens
+		result.CostUSD = rec.CostUSD
+	}
+	return result, err
+}
+
+// recordQualitySkip records an episode skipped by the quality gate.
+func (p *MemoryPipeline) recordQualitySkip(ctx context.Context, episode EpisodeInput, result *PipelineResult) {
+	rec := EpisodeProcessing{
+		EpisodeID:    episode.ID,
+		Channel:      episode.Channel,
+		ContentChars: len(episode.Content),
+		QualityScore: &result.Quality.Score,
+		SkipReason:   result.SkipReason,
+		ContentHash:  result.ContentHash,
+		Status:       ProcessingStatusSkipped,
+		ProcessedAt:  result.ProcessedAt,
+	}
+	if episode.ThreadID != nil {
+		rec.ThreadID = *episode.ThreadID
+	}
+	if err := p.processingLog.Record(ctx, rec); err != nil {
+		// Non-fatal - the episode is simply scored again next time
+		_ = err
+	}
+}
+
+// process runs the pipeline steps; Process wraps it with cost attribution.
+func (p *MemoryPipeline) process(ctx context.Context, episode EpisodeInput, startTime time.Time, route *RouteDecision) (*PipelineResult, error) {
 	result := &PipelineResult{
 		ProcessedAt: startTime,
+		Route:       route,
+	}
+	var model string
+	if route != nil {
+		model = route.Model
 	}
 
 	// Validate input
@@ -135,17 +424,40 @@ func (p *MemoryPipeline) Process(ctx context.Context, episode EpisodeInput) (*Pi
 		return result, nil // Empty content - nothing to process
 	}
 
+	// Notes the user attached to the episode steer extraction, and count as
+	// content: adding or removing one makes the episode eligible again
+	episodeNotes, err := notes.ForEpisode(ctx, p.db, episode.ID)
+	if err != nil {
+		// Non-fatal - extract without notes (no notes table in memory-only DBs)
+		episodeNotes = nil
+	}
+	userNotes := notes.Lines(episodeNotes)
+
 	// Check if episode was already processed (idempotency)
-	processed, err := p.isEpisodeProcessed(ctx, episode.ID)
+	result.ContentHash = withNotesHash(p.contentHash(ctx, episode.ID), episodeNotes)
+	processed, err := p.isContentProcessed(ctx, episode.ID, result.ContentHash)
 	if err != nil {
 		return nil, fmt.Errorf("check if episode processed: %w", err)
 	}
-	if processed {
+	if processed && !episode.Replay {
 		result.Skipped = true
 		result.Duration = time.Since(startTime)
 		return result, nil
 	}
 
+	// Skip low-information episodes before spending any LLM calls
+	if p.qualityScorer != nil {
+		quality := p.qualityScorer.Score(ctx, episode)
+		result.Quality = &quality
+		if quality.SkipReason != "" && !episode.IgnoreQualityGate {
+			result.Skipped = true
+			result.SkipReason = quality.SkipReason
+			result.Route = nil
+			result.Duration = time.Since(startTime)
+			return result, nil
+		}
+	}
+
 	// Get previous episodes for context (if configured)
 	var previousEpisodes []string
 	if p.config.LookbackEpisodes > 0 {
@@ -156,19 +468,43 @@ func (p *MemoryPipeline) Process(ctx context.Context, episode EpisodeInput) (*Pi
 		}
 	}
 
+	// Prime known entities with frequent/starred contacts (if configured)
+	knownEntities := episode.KnownEntities
+	if p.knownEntityPrimer != nil {
+		primed, err := p.knownEntityPrimer.Prime(ctx, knownEntities)
+		if err == nil {
+			knownEntities = primed
+		}
+		// Non-fatal - continue with the caller's known entities
+	}
+
 	// Step 1: Extract entities (graph-independent)
 	entityInput := EntityExtractionInput{
 		EpisodeContent:     episode.Content,
 		ReferenceTime:      episode.ReferenceTime,
 		PreviousEpisodes:   previousEpisodes,
-		KnownEntities:      episode.KnownEntities,
+		KnownEntities:      knownEntities,
 		CustomInstructions: p.config.CustomInstructions,
+		UserNotes:          userNotes,
+		Model:              model,
 	}
 
 	entityResult, err := p.entityExtractor.Extract(ctx, entityInput)
 	if err != nil {
 		return nil, fmt.Errorf("extract entities: %w", err)
 	}
+
+	// Step 1b: Glean low-recall episodes for missed entities
+	if p.config.Gleaning != nil {
+		gleaned, err := p.entityExtractor.Glean(ctx, entityInput, entityResult.ExtractedEntities, *p.config.Gleaning)
+		if err != nil {
+			// Non-fatal - keep whatever earlier rounds found
+			_ = err
+		}
+		entityResult.ExtractedEntities = gleaned.ExtractedEntities
+		result.GleaningRounds = gleaned.Rounds
+		result.GleanedEntities = gleaned.Added
+	}
 	result.ExtractedEntities = entityResult.ExtractedEntities
 
 	// If no entities extracted, we're done (no relationships possible)
@@ -203,11 +539,21 @@ func (p *MemoryPipeline) Process(ctx context.Context, episode EpisodeInput) (*Pi
 
 	// Step 3: Extract relationships (graph-independent)
 	relInput := RelationshipExtractionInput{
-		EpisodeContent:   episode.Content,
-		ResolvedEntities: resolutionResult.ResolvedEntities,
-		ReferenceTime:    episode.ReferenceTime,
-		PreviousEpisodes: previousEpisodes,
+		EpisodeContent:     episode.Content,
+		ResolvedEntities:   resolutionResult.ResolvedEntities,
+		ReferenceTime:      episode.ReferenceTime,
+		PreviousEpisodes:   previousEpisodes,
 		CustomInstructions: p.config.CustomInstructions,
+		UserNotes:          userNotes,
+		Model:              model,
+	}
+	if episode.RelationTypes != nil {
+		// The channel's blocked types still apply
+		policy := *episode.RelationTypes
+		policy.Blocked = append(slices.Clone(policy.Blocked), p.config.RelationTypePolicies[episode.Channel].Blocked...)
+		relInput.RelationTypes = &policy
+	} else if policy, ok := p.config.RelationTypePolicies[episode.Channel]; ok {
+		relInput.RelationTypes = &policy
 	}
 
 	relResult, err := p.relationshipExtractor.Extract(ctx, relInput)
@@ -216,6 +562,30 @@ func (p *MemoryPipeline) Process(ctx context.Context, episode EpisodeInput) (*Pi
 	}
 	result.ExtractedRelationships = relResult.ExtractedRelationships
 
+	// Step 3b: Optional self-critique of extracted relationships
+	if p.relationshipCritic != nil && len(relResult.ExtractedRelationships) > 0 {
+		critiqueModel := model
+		if p.config.CritiqueModel != "" {
+			critiqueModel = p.config.CritiqueModel
+		}
+		critique, err := p.relationshipCritic.Critique(ctx, CritiqueInput{
+			EpisodeContent:   episode.Content,
+			ResolvedEntities: resolutionResult.ResolvedEntities,
+			Relationships:    relResult.ExtractedRelationships,
+			UserNotes:        userNotes,
+			Model:            critiqueModel,
+		})
+		if err != nil {
+			// Non-fatal - keep the unreviewed relationships
+			_ = err
+		} else {
+			relResult.ExtractedRelationships = critique.Relationships
+			result.ExtractedRelationships = critique.Relationships
+			result.CritiqueDropped = critique.Dropped
+			result.CritiqueAdjusted = critique.Adjusted
+		}
+	}
+
 	// Step 4: Promote identity relationships (HAS_EMAIL, HAS_PHONE, etc.)
 	identityResult, err := p.identityPromoter.Promote(ctx, episode.ID, relResult.ExtractedRelationships, resolutionResult.ResolvedEntities)
 	if err != nil {
@@ -248,12 +618,70 @@ func (p *MemoryPipeline) Process(ctx context.Context, episode EpisodeInput) (*Pi
 				_ = err
 			} else {
 				result.ContradictionsFound = contradictionResult.ContradictionsFound
+				result.CardinalityViolations = contradictionResult.ViolationsFlagged
 			}
 		}
 	}
 
-	// Step 7: Generate embeddings for new entities
-	if !p.config.SkipEmbeddings && result.NewEntities > 0 {
+	// Re-weight relationships this episode mentioned (frequency and recency changed)
+	if edgeResult.MentionsCreated > 0 {
+		if err := p.relationshipWeigher.RefreshEpisode(ctx, episode.ID); err != nil {
+			// Non-fatal - RefreshAll recomputes weights
+			_ = err
+		}
+	}
+
+	// Refresh materialized current facts for entities touched by this episode
+	if edgeResult.NewRelationships > 0 {
+		touched := make([]string, 0, len(resolutionResult.ResolvedEntities))
+		for _, ent := range resolutionResult.ResolvedEntities {
+			touched = append(touched, ent.ID)
+		}
+		if err := p.currentFacts.RefreshEntities(ctx, touched); err != nil {
+			// Non-fatal - the current_facts maintenance task repairs the table
+			_ = err
+		}
+	}
+
+	// Normalize new location and organization entities (aliases, parents, duplicates)
+	var newLocations, newOrgs []string
+	for _, ent := range resolutionResult.ResolvedEntities {
+		if !ent.IsNew {
+			continue
+		}
+		switch ent.EntityTypeID {
+		case EntityTypeLocation:
+			newLocations = append(newLocations, ent.ID)
+		case EntityTypeOrganization:
+			newOrgs = append(newOrgs, ent.ID)
+		}
+	}
+	if len(newLocations) > 0 {
+		if _, err := p.geoNormalizer.NormalizeEntities(ctx, newLocations); err != nil {
+			// Non-fatal - NormalizeAll catches up
+			_ = err
+		}
+	}
+	if len(newOrgs) > 0 {
+		if _, err := p.orgNormalizer.NormalizeEntities(ctx, newOrgs); err != nil {
+			// Non-fatal - NormalizeAll catches up
+			_ = err
+		}
+	}
+
+	// Step 7: Queue embeddings for new or renamed entities (or embed new ones inline)
+	if p.embeddingQueue != nil {
+		entities := make([]Entity, len(resolutionResult.ResolvedEntities))
+		for i, ent := range resolutionResult.ResolvedEntities {
+			entities[i] = Entity{ID: ent.ID, CanonicalName: ent.Name}
+		}
+		queued, err := p.embeddingQueue.EnqueueStale(ctx, entities)
+		if err != nil {
+			// Non-fatal - `compute enqueue entity-embeddings` backfills missed entities
+			_ = err
+		}
+		result.EmbeddingsQueued = queued
+	} else if !p.config.SkipEmbeddings && result.NewEntities > 0 {
 		newEntities := filterNewEntities(resolutionResult.ResolvedEntities)
 		embeddingsGenerated, err := p.entityEmbedder.EmbedEntities(ctx, newEntities)
 		if err != nil {
@@ -265,7 +693,7 @@ func (p *MemoryPipeline) Process(ctx context.Context, episode EpisodeInput) (*Pi
 	}
 
 	// Step 8: Create episode_entity_mentions
-	mentionsCreated, err := p.createEntityMentions(ctx, episode.ID, resolutionResult.ResolvedEntities)
+	mentionsCreated, err := p.createEntityMentions(ctx, episode.ID, resolutionResult.ResolvedEntities, entityResult.ExtractedEntities)
 	if err != nil {
 		return nil, fmt.Errorf("create entity mentions: %w", err)
 	}
@@ -275,6 +703,56 @@ func (p *MemoryPipeline) Process(ctx context.Context, episode EpisodeInput) (*Pi
 	return result, nil
 }
 
+// contentHash refreshes and returns the episode's content hash; empty when
+// the episode has no stored events (fixtures, memory-only DBs).
+func (p *MemoryPipeline) contentHash(ctx context.Context, episodeID string) string {
+	hash, err := chunk.UpdateContentHash(ctx, p.db, episodeID)
+	if err != nil {
+		return "" // Non-fatal - fall back to mention-based idempotency
+	}
+	return hash
+}
+
+// withNotesHash folds the hash of an episode's notes into its content hash.
+// Episodes without notes keep the plain content hash.
+func withNotesHash(contentHash string, episodeNotes []notes.Note) string {
+	notesHash := notes.Hash(episodeNotes)
+	if contentHash == "" || notesHash == "" {
+		return contentHash
+	}
+	sum := sha256.Sum256([]byte(contentHash + "\x00" + notesHash))
+	return hex.EncodeToString(sum[:])
+}
+
+// isContentProcessed checks if an episode's content has already been
+// extracted. With a content hash, an episode is processed when its last
+// successful run saw the same hash, or another episode with identical
+// content (e.g. the same messages rechunked) was processed; an edited
+// message changes the hash and makes it eligible again. Episodes processed
+// before hashing, or without a hash, fall back to isEpisodeProcessed.
+func (p *MemoryPipeline) isContentProcessed(ctx context.Context, episodeID, hash string) (bool, error) {
+	if hash != "" {
+		var lastHash sql.NullString
+		err := p.db.QueryRowContext(ctx, `
+			SELECT content_hash FROM episode_processing WHERE episode_id = ? AND status = ?
+		`, episodeID, ProcessingStatusOK).Scan(&lastHash)
+		if err == nil && lastHash.Valid {
+			return lastHash.String == hash, nil
+		}
+		if err == nil || err == sql.ErrNoRows {
+			var duplicates int
+			err = p.db.QueryRowContext(ctx, `
+				SELECT COUNT(*) FROM episode_processing WHERE content_hash = ? AND status = ?
+			`, hash, ProcessingStatusOK).Scan(&duplicates)
+			if err == nil && duplicates > 0 {
+				return true, nil
+			}
+		}
+		// Non-fatal - fall back to mentions (no processing log in memory-only DBs)
+	}
+	return p.isEpisodeProcessed(ctx, episodeID)
+}
+
 // isEpisodeProcessed checks if an episode has already been processed.
 // We consider an episode processed if it has any entity mentions.
 func (p *MemoryPipeline) isEpisodeProcessed(ctx context.Context, episodeID string) (bool, error) {
@@ -290,12 +768,15 @@ func (p *MemoryPipeline) isEpisodeProcessed(ctx context.Context, episodeID strin
 
 // getPreviousEpisodes retrieves content from previous episodes for context.
 func (p *MemoryPipeline) getPreviousEpisodes(ctx context.Context, episode EpisodeInput) ([]string, error) {
-	// Find episodes in the same channel before this one
+	// Find episodes in the same channel before this one. Only the
+	// channel's primary definition counts: the same messages chunked by
+	// another definition would repeat context.
 	rows, err := p.db.QueryContext(ctx, `
 		SELECT e.id
 		FROM episodes e
 		WHERE e.channel = ?
 		  AND e.start_time < ?
+		  AND `+chunk.PrimaryEpisodeCondition("e")+`
 		ORDER BY e.start_time DESC
 		LIMIT ?
 	`, episode.Channel, episode.StartTime.Unix(), p.config.LookbackEpisodes)
@@ -397,17 +878,34 @@ func (p *MemoryPipeline) getRecentlyCreatedRelationshipIDs(ctx context.Context,
 }
 
 // createEntityMentions creates episode_entity_mentions records for all resolved entities.
-func (p *MemoryPipeline) createEntityMentions(ctx context.Context, episodeID string, entities []ResolvedEntity) (int, error) {
+// extracted is index-aligned with entities and supplies mention offsets for
+// highlighting; it may be nil.
+func (p *MemoryPipeline) createEntityMentions(ctx context.Context, episodeID string, entities []ResolvedEntity, extracted []ExtractedEntity) (int, error) {
 	now := time.Now().Format(time.RFC3339)
 	count := 0
 
+	// Several extracted entities can resolve to the same entity; combine their offsets
+	offsets := make(map[string][]MentionOffset)
+	for i, ent := range entities {
+		if i < len(extracted) {
+			offsets[ent.ID] = append(offsets[ent.ID], extracted[i].Offsets...)
+		}
+	}
+
 	for _, ent := range entities {
+		var offsetsJSON *string
+		if entOffsets := dedupeMentionOffsets(offsets[ent.ID]); len(entOffsets) > 0 {
+			data, _ := json.Marshal(entOffsets)
+			s := string(data)
+			offsetsJSON = &s
+		}
 		_, err := p.db.ExecContext(ctx, `
-			INSERT INTO episode_entity_mentions (episode_id, entity_id, mention_count, created_at)
-			VALUES (?, ?, 1, ?)
+			INSERT INTO episode_entity_mentions (episode_id, entity_id, mention_count, mention_offsets, created_at)
+			VALUES (?, ?, 1, ?, ?)
 			ON CONFLICT(episode_id, entity_id) DO UPDATE SET
-				mention_count = episode_entity_mentions.mention_count + 1
-		`, episodeID, ent.ID, now)
+				mention_count = episode_entity_mentions.mention_count + 1,
+				mention_offsets = COALESCE(excluded.mention_offsets, episode_entity_mentions.mention_offsets)
+		`, episodeID, ent.ID, offsetsJSON, now)
 		if err != nil {
 			return count, fmt.Errorf("insert entity mention for %s: %w", ent.ID, err)
 		}
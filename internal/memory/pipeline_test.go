@@ -6,6 +6,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Napageneral/mnemonic/internal/chunk"
+	"github.com/Napageneral/mnemonic/internal/testutil"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -52,6 +54,12 @@ func setupPipelineTestDB(t *testing.T) *sql.DB {
 			PRIMARY KEY (episode_id, event_id)
 		);
 
+		CREATE TABLE IF NOT EXISTS episode_primary_definitions (
+			channel TEXT PRIMARY KEY,
+			definition_id TEXT NOT NULL,
+			updated_at INTEGER NOT NULL
+		);
+
 		CREATE TABLE IF NOT EXISTS entities (
 			id TEXT PRIMARY KEY,
 			canonical_name TEXT NOT NULL,
@@ -90,6 +98,8 @@ func setupPipelineTestDB(t *testing.T) *sql.DB {
 			invalid_at TEXT,
 			created_at TEXT NOT NULL,
 			confidence REAL DEFAULT 1.0,
+			weight REAL DEFAULT 0,
+			source_type TEXT,
 			CHECK ((target_entity_id IS NOT NULL AND target_literal IS NULL) OR
 			       (target_entity_id IS NULL AND target_literal IS NOT NULL))
 		);
@@ -101,6 +111,7 @@ func setupPipelineTestDB(t *testing.T) *sql.DB {
 			episode_id TEXT NOT NULL,
 			entity_id TEXT NOT NULL,
 			mention_count INTEGER DEFAULT 1,
+			mention_offsets TEXT,
 			created_at TEXT NOT NULL,
 			PRIMARY KEY (episode_id, entity_id)
 		);
@@ -337,7 +348,7 @@ func TestCreateEntityMentions(t *testing.T) {
 		{ID: "ent-002", Name: "Bob", EntityTypeID: 1, IsNew: false},
 	}
 
-	count, err := pipeline.createEntityMentions(ctx, episodeID, entities)
+	count, err := pipeline.createEntityMentions(ctx, episodeID, entities, nil)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -359,7 +370,7 @@ func TestCreateEntityMentions(t *testing.T) {
 	}
 
 	// Test idempotency - creating same mentions again should increment count
-	count2, err := pipeline.createEntityMentions(ctx, episodeID, entities)
+	count2, err := pipeline.createEntityMentions(ctx, episodeID, entities, nil)
 	if err != nil {
 		t.Fatalf("Unexpected error on second call: %v", err)
 	}
@@ -575,6 +586,18 @@ func TestGetPreviousEpisodes(t *testing.T) {
 	if len(previous) > 0 && previous[0] != "Previous content" {
 		t.Errorf("Expected 'Previous content', got '%s'", previous[0])
 	}
+
+	// Episodes of another definition than the channel's primary are skipped
+	if _, err := db.ExecContext(ctx, `INSERT INTO episode_primary_definitions (channel, definition_id, updated_at) VALUES ('test', 'def-2', 0)`); err != nil {
+		t.Fatalf("Failed to set primary definition: %v", err)
+	}
+	previous, err = pipeline.getPreviousEpisodes(ctx, episode)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(previous) != 0 {
+		t.Errorf("Expected no previous episodes from the primary definition, got %d", len(previous))
+	}
 }
 
 // TestProcessBatch tests batch processing.
@@ -650,3 +673,63 @@ func TestIdempotency(t *testing.T) {
 		t.Errorf("Expected 0 new entities when skipped, got %d", result.NewEntities)
 	}
 }
+
+// TestContentHashIdempotency tests that unchanged and rechunked content is
+// not re-extracted, while edited content is.
+func TestContentHashIdempotency(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.Exec(`
+		INSERT INTO threads (id, channel, source_adapter, source_id, created_at, updated_at) VALUES ('t1', 'imessage', 'imessage', 't1', 0, 0);
+		INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0);
+		INSERT INTO events (id, timestamp, channel, content_types, content, direction, thread_id, source_adapter, source_id) VALUES
+			('e1', 100, 'imessage', '["text"]', 'Starting at Anthropic in January', 'received', 't1', 'imessage', 'e1'),
+			('e2', 200, 'imessage', '["text"]', 'Congrats!', 'sent', 't1', 'imessage', 'e2');
+		INSERT INTO episodes (id, definition_id, channel, thread_id, start_time, end_time, event_count, created_at) VALUES
+			('ep-old', 'def', 'imessage', 't1', 100, 200, 2, 0),
+			('ep-new', 'def', 'imessage', 't1', 100, 200, 2, 0);
+		INSERT INTO episode_events (episode_id, event_id, position) VALUES
+			('ep-old', 'e1', 1), ('ep-old', 'e2', 2), ('ep-new', 'e1', 1), ('ep-new', 'e2', 2);
+	`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	pipeline := NewMemoryPipeline(db, nil, &PipelineConfig{SkipEmbeddings: true})
+	hash := pipeline.contentHash(ctx, "ep-old")
+	if hash == "" {
+		t.Fatal("no content hash for an episode with events")
+	}
+	if err := pipeline.processingLog.Record(ctx, EpisodeProcessing{
+		EpisodeID: "ep-old", LLMCalls: 2, ContentHash: hash, Status: ProcessingStatusOK,
+	}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	// ep-new is ep-old rechunked: skipped without calling the (nil) LLM client
+	result, err := pipeline.Process(ctx, EpisodeInput{ID: "ep-new", Channel: "imessage", Content: "Starting at Anthropic in January\nCongrats!"})
+	if err != nil || !result.Skipped || result.ContentHash != hash {
+		t.Fatalf("rechunked episode: result %+v, err %v", result, err)
+	}
+
+	// Editing a message makes the old episode eligible again
+	if _, err := db.Exec(`
+		INSERT INTO events (id, timestamp, channel, content_types, content, direction, thread_id, source_adapter, source_id, supersedes)
+		VALUES ('e1-edit', 150, 'imessage', '["text"]', 'Starting at Anthropic in February', 'received', 't1', 'imessage', 'e1-edit', 'e1')
+	`); err != nil {
+		t.Fatal(err)
+	}
+	edited := pipeline.contentHash(ctx, "ep-old")
+	if processed, err := pipeline.isContentProcessed(ctx, "ep-old", edited); err != nil || processed {
+		t.Errorf("edited episode processed = %v, %v; want eligible", processed, err)
+	}
+	var stored string
+	db.QueryRow(`SELECT content_hash FROM episodes WHERE id = 'ep-old'`).Scan(&stored)
+	if stored != edited || edited == hash {
+		t.Errorf("stored content hash = %q, want the edited hash %q", stored, edited)
+	}
+	if recomputed, _ := chunk.ContentHash(ctx, db, "ep-new"); recomputed != edited {
+		t.Errorf("rechunked episode hash = %q, want %q", recomputed, edited)
+	}
+}
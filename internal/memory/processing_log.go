@@ -0,0 +1,314 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/gemini"
+)
+
+// Processing statuses recorded in episode_processing.
+const (
+	ProcessingStatusOK      = "ok"
+	ProcessingStatusError   = "error"
+	ProcessingStatusSkipped = "skipped" // failed the quality gate; no LLM calls
+)
+
+// UsageTracker accumulates LLM usage for one unit of work (an episode).
+// Attach it to a context with WithUsageTracker; extractors record into it.
+type UsageTracker struct {
+	mu           sync.Mutex
+	Model        string
+	Calls        int
+	PromptTokens int64
+	OutputTokens int64
+	EmbedChars   int64
+}
+
+type usageKey struct{}
+
+// WithUsageTracker attaches a usage tracker to the context.
+func WithUsageTracker(ctx context.Context, t *UsageTracker) context.Context {
+	return context.WithValue(ctx, usageKey{}, t)
+}
+
+func getUsageTracker(ctx context.Context) *UsageTracker {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(usageKey{}).(*UsageTracker)
+	return t
+}
+
+// recordLLMUsage adds a generate call's usage to the context's tracker (if any).
+func recordLLMUsage(ctx context.Context, model string, usage *gemini.UsageMetadata) {
+	t := getUsageTracker(ctx)
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Calls++
+	if t.Model == "" {
+		t.Model = model
+	}
+	if usage != nil {
+		t.PromptTokens += int64(usage.PromptTokenCount)
+		t.OutputTokens += int64(usage.CandidatesTokenCount)
+	}
+}
+
+// recordEmbedUsage adds embedded characters to the context's tracker (if any).
+func recordEmbedUsage(ctx context.Context, chars int) {
+	t := getUsageTracker(ctx)
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.EmbedChars += int64(chars)
+}
+
+// CostUSD returns the estimated cost of the tracked usage.
+func (t *UsageTracker) CostUSD() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return gemini.EstimateCost(t.PromptTokens, t.OutputTokens, t.EmbedChars)
+}
+
+// EpisodeProcessing is the cost and latency record for one processed episode.
+type EpisodeProcessing struct {
+	EpisodeID    string         `json:"episode_id"`
+	Channel      string         `json:"channel,omitempty"`
+	ThreadID     string         `json:"thread_id,omitempty"`
+	Model        string         `json:"model,omitempty"`
+	LLMCalls     int            `json:"llm_calls"`
+	PromptTokens int64          `json:"prompt_tokens"`
+	OutputTokens int64          `json:"output_tokens"`
+	EmbedChars   int64          `json:"embed_chars"`
+	CostUSD      float64        `json:"cost_usd"`
+	DurationMs   int64          `json:"duration_ms"`
+	ContentChars int            `json:"content_chars"`
+	Route        *RouteDecision `json:"route,omitempty"`
+	QualityScore *float64       `json:"quality_score,omitempty"` // set when the quality gate is enabled
+	SkipReason   string         `json:"skip_reason,omitempty"`
+	ContentHash  string         `json:"content_hash,omitempty"` // episode content hash at processing time
+	Status       string         `json:"status"`
+	Error        string         `json:"error,omitempty"`
+	ProcessedAt  time.Time      `json:"processed_at"`
+}
+
+// ProcessingCostGroup aggregates processing cost for a channel, thread or model.
+type ProcessingCostGroup struct {
+	Key           string  `json:"key"`
+	Episodes      int     `json:"episodes"`
+	Errors        int     `json:"errors"`
+	PromptTokens  int64   `json:"prompt_tokens"`
+	OutputTokens  int64   `json:"output_tokens"`
+	CostUSD       float64 `json:"cost_usd"`
+	AvgCostUSD    float64 `json:"avg_cost_usd"`
+	AvgDurationMs int64   `json:"avg_duration_ms"`
+}
+
+// ProcessingLog stores and reports per-episode processing cost.
+type ProcessingLog struct {
+	db *sql.DB
+}
+
+// NewProcessingLog creates a new ProcessingLog.
+func NewProcessingLog(db *sql.DB) *ProcessingLog {
+	return &ProcessingLog{db: db}
+}
+
+// Record upserts the processing record for an episode (the latest run wins).
+func (l *ProcessingLog) Record(ctx context.Context, rec EpisodeProcessing) error {
+	if rec.ProcessedAt.IsZero() {
+		rec.ProcessedAt = time.Now()
+	}
+	var routeTier, routeReason, estimatedTokens, participants interface{}
+	if rec.Route != nil {
+		routeTier, routeReason = rec.Route.Tier, rec.Route.Reason
+		estimatedTokens, participants = rec.Route.EstimatedTokens, rec.Route.Participants
+	}
+	_, err := l.db.ExecContext(ctx, `
+		INSERT INTO episode_processing (
+			episode_id, channel, thread_id, model, llm_calls, prompt_tokens, output_tokens,
+			embed_chars, cost_usd, duration_ms, content_chars, route_tier, route_reason,
+			estimated_tokens, participants, quality_score, skip_reason, content_hash, status, error,
+			processed_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(episode_id) DO UPDATE SET
+			channel = excluded.channel,
+			thread_id = excluded.thread_id,
+			model = excluded.model,
+			llm_calls = excluded.llm_calls,
+			prompt_tokens = excluded.prompt_tokens,
+			output_tokens = excluded.output_tokens,
+			embed_chars = excluded.embed_chars,
+			cost_usd = excluded.cost_usd,
+			duration_ms = excluded.duration_ms,
+			content_chars = excluded.content_chars,
+			route_tier = excluded.route_tier,
+			route_reason = excluded.route_reason,
+			estimated_tokens = excluded.estimated_tokens,
+			participants = excluded.participants,
+			quality_score = excluded.quality_score,
+			skip_reason = excluded.skip_reason,
+			content_hash = excluded.content_hash,
+			status = excluded.status,
+			error = excluded.error,
+			processed_at = excluded.processed_at
+	`, rec.EpisodeID, nullIfEmpty(rec.Channel), nullIfEmpty(rec.ThreadID), nullIfEmpty(rec.Model),
+		rec.LLMCalls, rec.PromptTokens, rec.OutputTokens, rec.EmbedChars, rec.CostUSD,
+		rec.DurationMs, rec.ContentChars, routeTier, routeReason, estimatedTokens, participants,
+		rec.QualityScore, nullIfEmpty(rec.SkipReason), nullIfEmpty(rec.ContentHash), rec.Status, nullIfEmpty(rec.Error),
+		rec.ProcessedAt.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("record episode processing: %w", err)
+	}
+	return nil
+}
+
+// processingGroupColumns maps CostBreakdown group names to columns.
+var processingGroupColumns = map[string]string{
+	"channel": "channel",
+	"thread":  "thread_id",
+	"model":   "model",
+	"route":   "route_tier",
+}
+
+// CostBreakdown aggregates processing cost by "channel", "thread", "model"
+// or "route" (routing tier), most expensive first.
+func (l *ProcessingLog) CostBreakdown(ctx context.Context, groupBy string, limit int) ([]ProcessingCostGroup, error) {
+	column, ok := processingGroupColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown group %q (want channel, thread, model or route)", groupBy)
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	rows, err := l.db.QueryContext(ctx, `
+		SELECT COALESCE(`+column+`, ''), COUNT(*),
+		       SUM(CASE WHEN status = 'error' THEN 1 ELSE 0 END),
+		       SUM(prompt_tokens), SUM(output_tokens), SUM(cost_usd), AVG(duration_ms)
+		FROM episode_processing
+		WHERE status != 'skipped'
+		GROUP BY 1
+		ORDER BY SUM(cost_usd) DESC, 1
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query cost breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []ProcessingCostGroup
+	for rows.Next() {
+		var g ProcessingCostGroup
+		var avgDuration float64
+		if err := rows.Scan(&g.Key, &g.Episodes, &g.Errors, &g.PromptTokens, &g.OutputTokens, &g.CostUSD, &avgDuration); err != nil {
+			return nil, fmt.Errorf("scan cost breakdown: %w", err)
+		}
+		if g.Episodes > 0 {
+			g.AvgCostUSD = g.CostUSD / float64(g.Episodes)
+		}
+		g.AvgDurationMs = int64(avgDuration)
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// processingColumns is the column list scanned by scanEpisodeProcessing.
+const processingColumns = `
+	episode_id, COALESCE(channel, ''), COALESCE(thread_id, ''), COALESCE(model, ''),
+	llm_calls, prompt_tokens, output_tokens, embed_chars, cost_usd, duration_ms,
+	content_chars, route_tier, route_reason, estimated_tokens, participants,
+	quality_score, COALESCE(skip_reason, ''), COALESCE(content_hash, ''), status, COALESCE(error, ''), processed_at`
+
+// TopEpisodes returns the most expensive processed episodes.
+func (l *ProcessingLog) TopEpisodes(ctx context.Context, limit int) ([]EpisodeProcessing, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	rows, err := l.db.QueryContext(ctx, `
+		SELECT `+processingColumns+`
+		FROM episode_processing
+		WHERE status != 'skipped'
+		ORDER BY cost_usd DESC, duration_ms DESC, episode_id
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query top episodes: %w", err)
+	}
+	defer rows.Close()
+	return scanEpisodeProcessing(rows)
+}
+
+// SkippedEpisodes returns episodes the quality gate skipped, lowest score
+// first, optionally only those skipped for reason.
+func (l *ProcessingLog) SkippedEpisodes(ctx context.Context, reason string, limit int) ([]EpisodeProcessing, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	query := `SELECT ` + processingColumns + `
+		FROM episode_processing
+		WHERE status = 'skipped'`
+	args := []interface{}{}
+	if reason != "" {
+		query += ` AND skip_reason = ?`
+		args = append(args, reason)
+	}
+	query += ` ORDER BY quality_score, processed_at DESC, episode_id LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := l.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query skipped episodes: %w", err)
+	}
+	defer rows.Close()
+	return scanEpisodeProcessing(rows)
+}
+
+func scanEpisodeProcessing(rows *sql.Rows) ([]EpisodeProcessing, error) {
+	var out []EpisodeProcessing
+	for rows.Next() {
+		var rec EpisodeProcessing
+		var processedAt string
+		var routeTier, routeReason sql.NullString
+		var estimatedTokens, participants sql.NullInt64
+		var qualityScore sql.NullFloat64
+		if err := rows.Scan(&rec.EpisodeID, &rec.Channel, &rec.ThreadID, &rec.Model,
+			&rec.LLMCalls, &rec.PromptTokens, &rec.OutputTokens, &rec.EmbedChars, &rec.CostUSD,
+			&rec.DurationMs, &rec.ContentChars, &routeTier, &routeReason, &estimatedTokens, &participants,
+			&qualityScore, &rec.SkipReason, &rec.ContentHash, &rec.Status, &rec.Error, &processedAt); err != nil {
+			return nil, fmt.Errorf("scan episode processing: %w", err)
+		}
+		if routeTier.Valid {
+			rec.Route = &RouteDecision{
+				Model:           rec.Model,
+				Tier:            routeTier.String,
+				Reason:          routeReason.String,
+				EstimatedTokens: int(estimatedTokens.Int64),
+				Participants:    int(participants.Int64),
+			}
+		}
+		if qualityScore.Valid {
+			rec.QualityScore = &qualityScore.Float64
+		}
+		rec.ProcessedAt, _ = time.Parse(time.RFC3339, processedAt)
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
+// nullIfEmpty stores empty strings as NULL.
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/gemini"
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestUsageTracker(t *testing.T) {
+	// No tracker attached - recording is a no-op
+	recordLLMUsage(context.Background(), "m", &gemini.UsageMetadata{PromptTokenCount: 10})
+
+	tracker := &UsageTracker{}
+	ctx := WithUsageTracker(context.Background(), tracker)
+	recordLLMUsage(ctx, "gemini-2.0-flash", &gemini.UsageMetadata{PromptTokenCount: 1000, CandidatesTokenCount: 200})
+	recordLLMUsage(ctx, "gemini-2.0-flash", &gemini.UsageMetadata{PromptTokenCount: 500, CandidatesTokenCount: 100})
+	recordEmbedUsage(ctx, 40)
+
+	if tracker.Calls != 2 || tracker.PromptTokens != 1500 || tracker.OutputTokens != 300 || tracker.EmbedChars != 40 {
+		t.Errorf("tracker = %+v", tracker)
+	}
+	if tracker.Model != "gemini-2.0-flash" {
+		t.Errorf("Model = %q", tracker.Model)
+	}
+	if got, want := tracker.CostUSD(), gemini.EstimateCost(1500, 300, 40); got != want {
+		t.Errorf("CostUSD = %v, want %v", got, want)
+	}
+}
+
+func TestProcessingLog(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	db.Exec(`INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0)`)
+	for _, id := range []string{"ep1", "ep2", "ep3"} {
+		if _, err := db.Exec(`INSERT INTO episodes (id, definition_id, start_time, end_time, event_count, created_at) VALUES (?, 'def', 0, 0, 1, 0)`, id); err != nil {
+			t.Fatalf("insert episode: %v", err)
+		}
+	}
+
+	log := NewProcessingLog(db)
+	records := []EpisodeProcessing{
+		{EpisodeID: "ep1", Channel: "imessage", ThreadID: "group", Model: "flash", LLMCalls: 2, PromptTokens: 90000, CostUSD: 0.5, DurationMs: 9000, Status: ProcessingStatusOK},
+		{EpisodeID: "ep2", Channel: "imessage", ThreadID: "dm", Model: "flash", LLMCalls: 2, PromptTokens: 1000, CostUSD: 0.01, DurationMs: 800, Status: ProcessingStatusOK},
+		{EpisodeID: "ep3", Channel: "gmail", ThreadID: "mail", Model: "flash", LLMCalls: 1, PromptTokens: 3000, CostUSD: 0.05, DurationMs: 1200, Status: ProcessingStatusError, Error: "parse response JSON"},
+	}
+	for _, rec := range records {
+		if err := log.Record(ctx, rec); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	// Re-recording replaces the previous run
+	records[1].CostUSD = 0.02
+	if err := log.Record(ctx, records[1]); err != nil {
+		t.Fatalf("Record again: %v", err)
+	}
+
+	byChannel, err := log.CostBreakdown(ctx, "channel", 0)
+	if err != nil {
+		t.Fatalf("CostBreakdown: %v", err)
+	}
+	if len(byChannel) != 2 || byChannel[0].Key != "imessage" || byChannel[0].Episodes != 2 {
+		t.Fatalf("byChannel = %+v", byChannel)
+	}
+	if got := byChannel[0].CostUSD; got < 0.519 || got > 0.521 {
+		t.Errorf("imessage cost = %v, want 0.52", got)
+	}
+	if byChannel[1].Errors != 1 {
+		t.Errorf("gmail errors = %d, want 1", byChannel[1].Errors)
+	}
+
+	if _, err := log.CostBreakdown(ctx, "person", 0); err == nil {
+		t.Error("expected error for unknown group")
+	}
+
+	top, err := log.TopEpisodes(ctx, 1)
+	if err != nil {
+		t.Fatalf("TopEpisodes: %v", err)
+	}
+	if len(top) != 1 || top[0].EpisodeID != "ep1" || top[0].ThreadID != "group" {
+		t.Errorf("top = %+v, want ep1", top)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -27,6 +29,8 @@ type RelatedEntity struct {
 	ValidAt       *string `json:"valid_at,omitempty"`
 	InvalidAt     *string `json:"invalid_at,omitempty"`
 	Fact          string  `json:"fact,omitempty"` // The natural language fact
+	Weight        float64 `json:"weight"`         // Relationship strength used for ranking (0-1)
+	Inverse       bool    `json:"inverse,omitempty"` // Stored as the inverse type, from the other end
 }
 
 // EntityRelationship represents a relationship from the graph.
@@ -44,6 +48,7 @@ type EntityRelationship struct {
 	CreatedAt       string  `json:"created_at"`
 	Confidence      float64 `json:"confidence"`
 	Direction       string  `json:"direction"` // "outgoing" or "incoming" relative to queried entity
+	Inverse         bool    `json:"inverse,omitempty"` // Stored as the inverse type, from the other end
 }
 
 // QueryOptions configures graph traversal queries.
@@ -54,6 +59,18 @@ type QueryOptions struct {
 	// RelationTypes filters to specific relationship types (nil = all)
 	RelationTypes []string `json:"relation_types,omitempty"`
 
+	// SourceTypes filters on a relationship's strongest source_type, e.g.
+	// []string{"self_disclosed"} for facts people stated themselves (nil = all)
+	SourceTypes []string `json:"source_types,omitempty"`
+
+	// Origins keeps only related entities with these origins, e.g.
+	// []string{OriginExtracted, OriginManual} (nil = all)
+	Origins []string `json:"origins,omitempty"`
+
+	// ExcludeOrigins drops related entities with these origins, e.g.
+	// []string{OriginInferred} to hide entities nobody mentioned
+	ExcludeOrigins []string `json:"exclude_origins,omitempty"`
+
 	// IncludeInvalidated includes relationships with invalid_at set
 	IncludeInvalidated bool `json:"include_invalidated,omitempty"`
 
@@ -76,14 +93,76 @@ func DefaultQueryOptions() QueryOptions {
 	}
 }
 
+// maxCachedStmts bounds the prepared statement cache. Query text varies with
+// the number of relation-type filters, so the set of shapes is small; batch
+// queries with variable IN lists are not cached.
+const maxCachedStmts = 64
+
 // QueryEngine provides graph traversal queries for the memory system.
+// Prepared statements are cached per query shape so large batch workloads
+// (digests, exports) skip re-parsing the same SQL; call Close when done.
 type QueryEngine struct {
 	db *sql.DB
+
+	stmtMu sync.Mutex
+	stmts  map[string]*sql.Stmt
 }
 
 // NewQueryEngine creates a new QueryEngine.
 func NewQueryEngine(db *sql.DB) *QueryEngine {
-	return &QueryEngine{db: db}
+	return &QueryEngine{db: db, stmts: make(map[string]*sql.Stmt)}
+}
+
+// Close releases cached prepared statements. The underlying DB is not closed.
+func (q *QueryEngine) Close() error {
+	q.stmtMu.Lock()
+	defer q.stmtMu.Unlock()
+
+	var firstErr error
+	for query, stmt := range q.stmts {
+		if err := stmt.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(q.stmts, query)
+	}
+	return firstErr
+}
+
+// prepared returns a cached prepared statement for the query, preparing it on
+// first use. Returns nil if the cache is full or preparation fails, in which
+// case callers run the query unprepared.
+func (q *QueryEngine) prepared(ctx context.Context, query string) *sql.Stmt {
+	q.stmtMu.Lock()
+	defer q.stmtMu.Unlock()
+
+	if stmt, ok := q.stmts[query]; ok {
+		return stmt
+	}
+	if len(q.stmts) >= maxCachedStmts {
+		return nil
+	}
+	stmt, err := q.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil
+	}
+	q.stmts[query] = stmt
+	return stmt
+}
+
+// query runs a query through the prepared statement cache.
+func (q *QueryEngine) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if stmt := q.prepared(ctx, query); stmt != nil {
+		return stmt.QueryContext(ctx, args...)
+	}
+	return q.db.QueryContext(ctx, query, args...)
+}
+
+// queryRow runs a single-row query through the prepared statement cache.
+func (q *QueryEngine) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if stmt := q.prepared(ctx, query); stmt != nil {
+		return stmt.QueryRowContext(ctx, args...)
+	}
+	return q.db.QueryRowContext(ctx, query, args...)
 }
 
 // GetRelatedEntities returns entities related to the given entity via specified relationship types.
@@ -122,6 +201,23 @@ func (q *QueryEngine) GetRelatedEntities(ctx context.Context, entityID string, o
 		results = append(results, incoming...)
 	}
 
+	// Facts stored as the inverse type, seen from this end
+	for _, lookup := range inverseLookups(opts) {
+		var found []RelatedEntity
+		if lookup.Direction == DirectionOutgoing {
+			found, err = q.getOutgoingRelatedEntities(ctx, entityID, lookup, asOfStr)
+		} else {
+			found, err = q.getIncomingRelatedEntities(ctx, entityID, lookup, asOfStr)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get inverse: %w", err)
+		}
+		results = append(results, relabelInverse(found, results)...)
+	}
+
+	// Strongest relationships first, so limits keep the most relevant
+	sortRelatedByWeight(results)
+
 	// Apply limit if specified
 	if opts.Limit > 0 && len(results) > opts.Limit {
 		results = results[:opts.Limit]
@@ -130,10 +226,19 @@ func (q *QueryEngine) GetRelatedEntities(ctx context.Context, entityID string, o
 	return results, err
 }
 
+// sortRelatedByWeight orders related entities by descending relationship
+// weight, keeping the existing order among equal weights.
+func sortRelatedByWeight(related []RelatedEntity) {
+	sort.SliceStable(related, func(i, j int) bool {
+		return related[i].Weight > related[j].Weight
+	})
+}
+
 // getOutgoingRelatedEntities finds entities where the given entity is the source.
 func (q *QueryEngine) getOutgoingRelatedEntities(ctx context.Context, entityID string, opts QueryOptions, asOfStr string) ([]RelatedEntity, error) {
 	query := `
-		SELECT e.id, e.canonical_name, e.entity_type_id, r.relation_type, r.valid_at, r.invalid_at, r.fact
+		SELECT e.id, e.canonical_name, e.entity_type_id, r.relation_type, r.valid_at, r.invalid_at, r.fact,
+		       COALESCE(r.weight, 0)
 		FROM relationships r
 		JOIN entities e ON r.target_entity_id = e.id
 		WHERE r.source_entity_id = ?
@@ -164,8 +269,18 @@ func (q *QueryEngine) getOutgoingRelatedEntities(ctx context.Context, entityID s
 		}
 		query += fmt.Sprintf(" AND r.relation_type IN (%s)", strings.Join(placeholders, ","))
 	}
+	if clause, sourceArgs := sourceTypeFilter(opts); clause != "" {
+		query += clause
+		args = append(args, sourceArgs...)
+	}
+	if cond, originArgs := originCondition(opts, "e"); cond != "" {
+		query += " AND " + cond
+		args = append(args, originArgs...)
+	}
 
-	rows, err := q.db.QueryContext(ctx, query, args...)
+	query += " ORDER BY r.weight DESC"
+
+	rows, err := q.query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -181,8 +296,9 @@ func (q *QueryEngine) getOutgoingRelatedEntities(ctx context.Context, entityID s
 			validAt     sql.NullString
 			invalidAt   sql.NullString
 			fact        string
+			weight      float64
 		)
-		if err := rows.Scan(&id, &name, &typeID, &relType, &validAt, &invalidAt, &fact); err != nil {
+		if err := rows.Scan(&id, &name, &typeID, &relType, &validAt, &invalidAt, &fact, &weight); err != nil {
 			return nil, err
 		}
 
@@ -193,6 +309,7 @@ func (q *QueryEngine) getOutgoingRelatedEntities(ctx context.Context, entityID s
 			RelationType:  relType,
 			Direction:     "outgoing",
 			Fact:          fact,
+			Weight:        weight,
 		}
 		if validAt.Valid {
 			rel.ValidAt = &validAt.String
@@ -209,7 +326,8 @@ func (q *QueryEngine) getOutgoingRelatedEntities(ctx context.Context, entityID s
 // getIncomingRelatedEntities finds entities where the given entity is the target.
 func (q *QueryEngine) getIncomingRelatedEntities(ctx context.Context, entityID string, opts QueryOptions, asOfStr string) ([]RelatedEntity, error) {
 	query := `
-		SELECT e.id, e.canonical_name, e.entity_type_id, r.relation_type, r.valid_at, r.invalid_at, r.fact
+		SELECT e.id, e.canonical_name, e.entity_type_id, r.relation_type, r.valid_at, r.invalid_at, r.fact,
+		       COALESCE(r.weight, 0)
 		FROM relationships r
 		JOIN entities e ON r.source_entity_id = e.id
 		WHERE r.target_entity_id = ?
@@ -236,8 +354,18 @@ func (q *QueryEngine) getIncomingRelatedEntities(ctx context.Context, entityID s
 		}
 		query += fmt.Sprintf(" AND r.relation_type IN (%s)", strings.Join(placeholders, ","))
 	}
+	if clause, sourceArgs := sourceTypeFilter(opts); clause != "" {
+		query += clause
+		args = append(args, sourceArgs...)
+	}
+	if cond, originArgs := originCondition(opts, "e"); cond != "" {
+		query += " AND " + cond
+		args = append(args, originArgs...)
+	}
 
-	rows, err := q.db.QueryContext(ctx, query, args...)
+	query += " ORDER BY r.weight DESC"
+
+	rows, err := q.query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -253,8 +381,9 @@ func (q *QueryEngine) getIncomingRelatedEntities(ctx context.Context, entityID s
 			validAt     sql.NullString
 			invalidAt   sql.NullString
 			fact        string
+			weight      float64
 		)
-		if err := rows.Scan(&id, &name, &typeID, &relType, &validAt, &invalidAt, &fact); err != nil {
+		if err := rows.Scan(&id, &name, &typeID, &relType, &validAt, &invalidAt, &fact, &weight); err != nil {
 			return nil, err
 		}
 
@@ -265,6 +394,7 @@ func (q *QueryEngine) getIncomingRelatedEntities(ctx context.Context, entityID s
 			RelationType:  relType,
 			Direction:     "incoming",
 			Fact:          fact,
+			Weight:        weight,
 		}
 		if validAt.Valid {
 			rel.ValidAt = &validAt.String
@@ -312,6 +442,21 @@ func (q *QueryEngine) GetEntityRelationships(ctx context.Context, entityID strin
 		results = append(results, incoming...)
 	}
 
+	// Facts stored as the inverse type, seen from this end
+	for _, lookup := range inverseLookups(opts) {
+		var found []EntityRelationship
+		var err error
+		if lookup.Direction == DirectionOutgoing {
+			found, err = q.getOutgoingRelationships(ctx, entityID, lookup, asOfStr)
+		} else {
+			found, err = q.getIncomingRelationships(ctx, entityID, lookup, asOfStr)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get inverse relationships: %w", err)
+		}
+		results = append(results, relabelInverseRelationships(found, results)...)
+	}
+
 	// Apply limit if specified
 	if opts.Limit > 0 && len(results) > opts.Limit {
 		results = results[:opts.Limit]
@@ -353,10 +498,19 @@ func (q *QueryEngine) getOutgoingRelationships(ctx context.Context, entityID str
 		}
 		query += fmt.Sprintf(" AND r.relation_type IN (%s)", strings.Join(placeholders, ","))
 	}
+	if clause, sourceArgs := sourceTypeFilter(opts); clause != "" {
+		query += clause
+		args = append(args, sourceArgs...)
+	}
+	if cond, originArgs := originCondition(opts, "tgt"); cond != "" {
+		// Literal targets have no entity and always pass
+		query += " AND (r.target_entity_id IS NULL OR (" + cond + "))"
+		args = append(args, originArgs...)
+	}
 
 	query += " ORDER BY r.created_at DESC"
 
-	rows, err := q.db.QueryContext(ctx, query, args...)
+	rows, err := q.query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -446,10 +600,18 @@ func (q *QueryEngine) getIncomingRelationships(ctx context.Context, entityID str
 		}
 		query += fmt.Sprintf(" AND r.relation_type IN (%s)", strings.Join(placeholders, ","))
 	}
+	if clause, sourceArgs := sourceTypeFilter(opts); clause != "" {
+		query += clause
+		args = append(args, sourceArgs...)
+	}
+	if cond, originArgs := originCondition(opts, "src"); cond != "" {
+		query += " AND " + cond
+		args = append(args, originArgs...)
+	}
 
 	query += " ORDER BY r.created_at DESC"
 
-	rows, err := q.db.QueryContext(ctx, query, args...)
+	rows, err := q.query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -507,6 +669,12 @@ func (q *QueryEngine) getIncomingRelationships(ctx context.Context, entityID str
 	return results, rows.Err()
 }
 
+// GetCurrentFacts returns an entity's latest valid edge per relation type
+// from entity_current_facts, strongest first.
+func (q *QueryEngine) GetCurrentFacts(ctx context.Context, entityID string) ([]CurrentFact, error) {
+	return NewCurrentFactsStore(q.db).GetCurrentFacts(ctx, entityID)
+}
+
 // GetEntity retrieves a single entity by ID.
 func (q *QueryEngine) GetEntity(ctx context.Context, entityID string) (*Entity, error) {
 	if entityID == "" {
@@ -515,7 +683,7 @@ func (q *QueryEngine) GetEntity(ctx context.Context, entityID string) (*Entity,
 
 	var entity Entity
 	var mergedInto sql.NullString
-	err := q.db.QueryRowContext(ctx, `
+	err := q.queryRow(ctx, `
 		SELECT id, canonical_name, entity_type_id, summary, origin, confidence, merged_into, created_at, updated_at
 		FROM entities
 		WHERE id = ?
@@ -554,7 +722,7 @@ func (q *QueryEngine) FindEntitiesByName(ctx context.Context, name string, entit
 
 	query += " ORDER BY canonical_name"
 
-	rows, err := q.db.QueryContext(ctx, query, args...)
+	rows, err := q.query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -582,6 +750,7 @@ func (q *QueryEngine) FindEntitiesByName(ctx context.Context, name string, entit
 
 // FindEntitiesByRelationType finds all entities that have a specific relationship type (as source or target).
 // For example: "Who works at Anthropic?" - find people with WORKS_AT relationship to Anthropic.
+// Facts stored as the inverse type (Anthropic EMPLOYS ...) count too.
 func (q *QueryEngine) FindEntitiesByRelationType(ctx context.Context, relationType string, targetEntityID string, opts QueryOptions) ([]RelatedEntity, error) {
 	// Determine time for temporal filtering
 	asOf := time.Now()
@@ -605,10 +774,18 @@ func (q *QueryEngine) FindEntitiesByRelationType(ctx context.Context, relationTy
 		query += " AND (r.invalid_at IS NULL OR r.invalid_at > ?)"
 		args = append(args, asOfStr)
 	}
+	if clause, sourceArgs := sourceTypeFilter(opts); clause != "" {
+		query += clause
+		args = append(args, sourceArgs...)
+	}
+	if cond, originArgs := originCondition(opts, "e"); cond != "" {
+		query += " AND " + cond
+		args = append(args, originArgs...)
+	}
 
 	query += " ORDER BY e.canonical_name"
 
-	rows, err := q.db.QueryContext(ctx, query, args...)
+	rows, err := q.query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -645,13 +822,31 @@ func (q *QueryEngine) FindEntitiesByRelationType(ctx context.Context, relationTy
 		}
 		results = append(results, rel)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return results, rows.Err()
+	if inverse := InverseRelationType(relationType); inverse != "" {
+		lookup := opts
+		lookup.RelationTypes = []string{inverse}
+		found, err := q.getOutgoingRelatedEntities(ctx, targetEntityID, lookup, asOfStr)
+		if err != nil {
+			return nil, fmt.Errorf("get inverse: %w", err)
+		}
+		if found = relabelInverse(found, results); len(found) > 0 {
+			results = append(results, found...)
+			sort.SliceStable(results, func(i, j int) bool {
+				return results[i].CanonicalName < results[j].CanonicalName
+			})
+		}
+	}
+
+	return results, nil
 }
 
 // GetEntityAliases retrieves all aliases for an entity.
 func (q *QueryEngine) GetEntityAliases(ctx context.Context, entityID string) ([]EntityAlias, error) {
-	rows, err := q.db.QueryContext(ctx, `
+	rows, err := q.query(ctx, `
 		SELECT id, entity_id, alias, alias_type, normalized, is_shared, created_at
 		FROM entity_aliases
 		WHERE entity_id = ?
@@ -689,3 +884,329 @@ type EntityAlias struct {
 	IsShared   bool   `json:"is_shared"`
 	CreatedAt  string `json:"created_at"`
 }
+
+// maxBatchIDs caps the number of IDs bound in a single IN clause, keeping
+// batch queries well under SQLite's bound-parameter limit.
+const maxBatchIDs = 500
+
+// GetEntities retrieves multiple entities by ID in a single query per chunk.
+// Returns a map keyed by entity ID; missing IDs are absent from the map.
+func (q *QueryEngine) GetEntities(ctx context.Context, entityIDs []string) (map[string]*Entity, error) {
+	results := make(map[string]*Entity, len(entityIDs))
+
+	for _, chunk := range chunkIDs(uniqueIDs(entityIDs), maxBatchIDs) {
+		args := idArgs(chunk)
+		rows, err := q.db.QueryContext(ctx, `
+			SELECT id, canonical_name, entity_type_id, summary, origin, confidence, merged_into, created_at, updated_at
+			FROM entities
+			WHERE id IN (`+placeholderList(len(chunk))+`)
+		`, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var entity Entity
+			var mergedInto sql.NullString
+			if err := rows.Scan(
+				&entity.ID, &entity.CanonicalName, &entity.EntityTypeID,
+				&entity.Summary, &entity.Origin, &entity.Confidence,
+				&mergedInto, &entity.CreatedAt, &entity.UpdatedAt,
+			); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if mergedInto.Valid {
+				entity.MergedInto = &mergedInto.String
+			}
+			results[entity.ID] = &entity
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// GetRelatedEntitiesBatch returns related entities for many entities at once,
+// keyed by the queried entity ID. It applies the same filters as
+// GetRelatedEntities, with opts.Limit applied per queried entity.
+func (q *QueryEngine) GetRelatedEntitiesBatch(ctx context.Context, entityIDs []string, opts QueryOptions) (map[string][]RelatedEntity, error) {
+	asOf := time.Now()
+	if opts.AsOfTime != nil {
+		asOf = *opts.AsOfTime
+	}
+	asOfStr := asOf.Format(time.RFC3339)
+
+	ids := uniqueIDs(entityIDs)
+	results := make(map[string][]RelatedEntity, len(ids))
+
+	for _, chunk := range chunkIDs(ids, maxBatchIDs) {
+		if opts.Direction == DirectionOutgoing || opts.Direction == DirectionBoth || opts.Direction == "" {
+			if err := q.collectRelatedBatch(ctx, chunk, opts, asOfStr, "outgoing", results); err != nil {
+				return nil, fmt.Errorf("get outgoing: %w", err)
+			}
+		}
+		if opts.Direction == DirectionIncoming || opts.Direction == DirectionBoth || opts.Direction == "" {
+			if err := q.collectRelatedBatch(ctx, chunk, opts, asOfStr, "incoming", results); err != nil {
+				return nil, fmt.Errorf("get incoming: %w", err)
+			}
+		}
+		// Facts stored as the inverse type, seen from each entity's end
+		for _, lookup := range inverseLookups(opts) {
+			found := make(map[string][]RelatedEntity)
+			if err := q.collectRelatedBatch(ctx, chunk, lookup, asOfStr, string(lookup.Direction), found); err != nil {
+				return nil, fmt.Errorf("get inverse: %w", err)
+			}
+			for id, related := range found {
+				results[id] = append(results[id], relabelInverse(related, results[id])...)
+			}
+		}
+	}
+
+	for id, related := range results {
+		sortRelatedByWeight(related)
+		if opts.Limit > 0 && len(related) > opts.Limit {
+			results[id] = related[:opts.Limit]
+		}
+	}
+
+	return results, nil
+}
+
+// collectRelatedBatch runs one direction of GetRelatedEntitiesBatch for a chunk of IDs.
+func (q *QueryEngine) collectRelatedBatch(ctx context.Context, ids []string, opts QueryOptions, asOfStr, direction string, results map[string][]RelatedEntity) error {
+	anchorCol, otherCol := "r.source_entity_id", "r.target_entity_id"
+	if direction == "incoming" {
+		anchorCol, otherCol = "r.target_entity_id", "r.source_entity_id"
+	}
+
+	query := `
+		SELECT ` + anchorCol + `, e.id, e.canonical_name, e.entity_type_id, r.relation_type, r.valid_at, r.invalid_at, r.fact,
+		       COALESCE(r.weight, 0)
+		FROM relationships r
+		JOIN entities e ON ` + otherCol + ` = e.id
+		WHERE ` + anchorCol + ` IN (` + placeholderList(len(ids)) + `)
+		  AND e.merged_into IS NULL
+		  AND r.target_entity_id IS NOT NULL
+	`
+	args := idArgs(ids)
+
+	if !opts.IncludeInvalidated {
+		query += " AND (r.invalid_at IS NULL OR r.invalid_at > ?)"
+		args = append(args, asOfStr)
+	}
+	if opts.AsOfTime != nil {
+		query += " AND (r.valid_at IS NULL OR r.valid_at <= ?)"
+		args = append(args, asOfStr)
+	}
+	if len(opts.RelationTypes) > 0 {
+		query += " AND r.relation_type IN (" + placeholderList(len(opts.RelationTypes)) + ")"
+		for _, rt := range opts.RelationTypes {
+			args = append(args, rt)
+		}
+	}
+	if clause, sourceArgs := sourceTypeFilter(opts); clause != "" {
+		query += clause
+		args = append(args, sourceArgs...)
+	}
+	if cond, originArgs := originCondition(opts, "e"); cond != "" {
+		query += " AND " + cond
+		args = append(args, originArgs...)
+	}
+
+	rows, err := q.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			anchorID  string
+			rel       RelatedEntity
+			validAt   sql.NullString
+			invalidAt sql.NullString
+		)
+		if err := rows.Scan(&anchorID, &rel.ID, &rel.CanonicalName, &rel.EntityTypeID,
+			&rel.RelationType, &validAt, &invalidAt, &rel.Fact, &rel.Weight); err != nil {
+			return err
+		}
+		rel.Direction = direction
+		if validAt.Valid {
+			rel.ValidAt = &validAt.String
+		}
+		if invalidAt.Valid {
+			rel.InvalidAt = &invalidAt.String
+		}
+		results[anchorID] = append(results[anchorID], rel)
+	}
+
+	return rows.Err()
+}
+
+// GetAliasesBatch retrieves aliases for many entities at once, keyed by entity ID.
+func (q *QueryEngine) GetAliasesBatch(ctx context.Context, entityIDs []string) (map[string][]EntityAlias, error) {
+	results := make(map[string][]EntityAlias, len(entityIDs))
+
+	for _, chunk := range chunkIDs(uniqueIDs(entityIDs), maxBatchIDs) {
+		rows, err := q.db.QueryContext(ctx, `
+			SELECT id, entity_id, alias, alias_type, normalized, is_shared, created_at
+			FROM entity_aliases
+			WHERE entity_id IN (`+placeholderList(len(chunk))+`)
+			ORDER BY entity_id, alias_type, alias
+		`, idArgs(chunk)...)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var alias EntityAlias
+			var normalized sql.NullString
+			if err := rows.Scan(&alias.ID, &alias.EntityID, &alias.Alias, &alias.AliasType,
+				&normalized, &alias.IsShared, &alias.CreatedAt); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if normalized.Valid {
+				alias.Normalized = normalized.String
+			}
+			results[alias.EntityID] = append(results[alias.EntityID], alias)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// maxLocationDepth bounds LOCATED_IN traversal (venue → city → region → country).
+const maxLocationDepth = 6
+
+// GetLocationDescendants returns the location and every location transitively
+// LOCATED_IN it (Texas → Austin, Round Rock, ...).
+func (q *QueryEngine) GetLocationDescendants(ctx context.Context, locationID string) ([]string, error) {
+	rows, err := q.query(ctx, `
+		WITH RECURSIVE descendants(id, depth) AS (
+			SELECT ?, 0
+			UNION
+			SELECT r.source_entity_id, d.depth + 1
+			FROM relationships r
+			JOIN descendants d ON r.target_entity_id = d.id
+			JOIN entities e ON e.id = r.source_entity_id AND e.merged_into IS NULL
+			WHERE r.relation_type = ?
+			  AND r.invalid_at IS NULL
+			  AND d.depth < ?
+		)
+		SELECT DISTINCT id FROM descendants
+	`, locationID, RelationLocatedIn, maxLocationDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// FindEntitiesInLocation is FindEntitiesByRelationType rolled up the location
+// hierarchy: "Who lives in Texas?" includes people who LIVES_IN Austin.
+// Each result's Fact names the specific place it matched.
+func (q *QueryEngine) FindEntitiesInLocation(ctx context.Context, relationType string, locationID string, opts QueryOptions) ([]RelatedEntity, error) {
+	locationIDs, err := q.GetLocationDescendants(ctx, locationID)
+	if err != nil {
+		return nil, fmt.Errorf("get location descendants: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var results []RelatedEntity
+	for _, id := range locationIDs {
+		related, err := q.FindEntitiesByRelationType(ctx, relationType, id, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range related {
+			if seen[rel.ID] {
+				continue
+			}
+			seen[rel.ID] = true
+			results = append(results, rel)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].CanonicalName < results[j].CanonicalName
+	})
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+	return results, nil
+}
+
+// sourceTypeFilter returns the SQL clause and args restricting relationships
+// (aliased r) to opts.SourceTypes, or "" when unfiltered.
+func sourceTypeFilter(opts QueryOptions) (string, []interface{}) {
+	if len(opts.SourceTypes) == 0 {
+		return "", nil
+	}
+	args := make([]interface{}, len(opts.SourceTypes))
+	for i, st := range opts.SourceTypes {
+		args[i] = st
+	}
+	return " AND r.source_type IN (" + placeholderList(len(opts.SourceTypes)) + ")", args
+}
+
+// uniqueIDs drops empty and duplicate IDs, preserving order.
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	return out
+}
+
+// chunkIDs splits ids into slices of at most size elements.
+func chunkIDs(ids []string, size int) [][]string {
+	var chunks [][]string
+	for len(ids) > size {
+		chunks = append(chunks, ids[:size])
+		ids = ids[size:]
+	}
+	if len(ids) > 0 {
+		chunks = append(chunks, ids)
+	}
+	return chunks
+}
+
+// placeholderList returns "?, ?, ..." with n placeholders.
+func placeholderList(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.Repeat("?, ", n-1) + "?"
+}
+
+// idArgs converts IDs to query arguments.
+func idArgs(ids []string) []interface{} {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
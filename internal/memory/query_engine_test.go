@@ -3,9 +3,11 @@ package memory
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/Napageneral/mnemonic/internal/testutil"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -52,6 +54,8 @@ func setupQueryEngineTestDB(t *testing.T) *sql.DB {
 			invalid_at TEXT,
 			created_at TEXT NOT NULL,
 			confidence REAL DEFAULT 1.0,
+			weight REAL DEFAULT 0,
+			source_type TEXT,
 			CHECK (
 				(target_entity_id IS NOT NULL AND target_literal IS NULL) OR
 				(target_entity_id IS NULL AND target_literal IS NOT NULL)
@@ -263,6 +267,105 @@ func TestQueryEngine_GetRelatedEntities_FilterByRelationType(t *testing.T) {
 	}
 }
 
+func TestQueryEngine_GetRelatedEntities_FilterBySourceType(t *testing.T) {
+	db := setupQueryEngineTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	qe := NewQueryEngine(db)
+
+	insertQueryEngineTestEntity(t, db, "tyler-id", "Tyler", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "anthropic-id", "Anthropic", EntityTypeCompany)
+	insertQueryEngineTestEntity(t, db, "austin-id", "Austin", EntityTypeLocation)
+
+	// Tyler said where he works; where he lives was only inferred
+	anthropicID := "anthropic-id"
+	insertQueryEngineTestRelationship(t, db, "rel-1", "tyler-id", &anthropicID, nil, "WORKS_AT", "Tyler works at Anthropic", nil, nil)
+	austinID := "austin-id"
+	insertQueryEngineTestRelationship(t, db, "rel-2", "tyler-id", &austinID, nil, "LIVES_IN", "Tyler lives in Austin", nil, nil)
+	db.Exec(`UPDATE relationships SET source_type = 'self_disclosed' WHERE id = 'rel-1'`)
+	db.Exec(`UPDATE relationships SET source_type = 'inferred' WHERE id = 'rel-2'`)
+
+	opts := DefaultQueryOptions()
+	opts.SourceTypes = []string{"self_disclosed"}
+	results, err := qe.GetRelatedEntities(ctx, "tyler-id", opts)
+	if err != nil {
+		t.Fatalf("GetRelatedEntities: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "anthropic-id" {
+		t.Fatalf("expected only Anthropic, got %+v", results)
+	}
+
+	batch, err := qe.GetRelatedEntitiesBatch(ctx, []string{"tyler-id"}, opts)
+	if err != nil {
+		t.Fatalf("GetRelatedEntitiesBatch: %v", err)
+	}
+	if got := batch["tyler-id"]; len(got) != 1 || got[0].ID != "anthropic-id" {
+		t.Errorf("batch expected only Anthropic, got %+v", got)
+	}
+
+	opts.SourceTypes = []string{"self_disclosed", "inferred"}
+	rels, err := qe.GetEntityRelationships(ctx, "tyler-id", opts)
+	if err != nil {
+		t.Fatalf("GetEntityRelationships: %v", err)
+	}
+	if len(rels) != 2 {
+		t.Errorf("expected 2 relationships, got %d", len(rels))
+	}
+}
+
+func TestQueryEngine_GetRelatedEntities_FilterByOrigin(t *testing.T) {
+	db := setupQueryEngineTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	qe := NewQueryEngine(db)
+
+	insertQueryEngineTestEntity(t, db, "tyler-id", "Tyler", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "austin-id", "Austin", EntityTypeLocation)
+	insertQueryEngineTestEntity(t, db, "texas-id", "Texas", EntityTypeLocation)
+	db.Exec(`UPDATE entities SET origin = ? WHERE id = 'texas-id'`, OriginInferred)
+
+	austinID, texasID, email := "austin-id", "texas-id", "tyler@example.com"
+	insertQueryEngineTestRelationship(t, db, "rel-1", "tyler-id", &austinID, nil, "LIVES_IN", "Tyler lives in Austin", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "rel-2", "tyler-id", &texasID, nil, "LIVES_IN", "Tyler lives in Texas", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "rel-3", "tyler-id", nil, &email, "HAS_EMAIL", "Tyler's email", nil, nil)
+
+	opts := DefaultQueryOptions()
+	opts.ExcludeOrigins = []string{OriginInferred}
+	results, err := qe.GetRelatedEntities(ctx, "tyler-id", opts)
+	if err != nil {
+		t.Fatalf("GetRelatedEntities: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "austin-id" {
+		t.Fatalf("expected only Austin, got %+v", results)
+	}
+	batch, err := qe.GetRelatedEntitiesBatch(ctx, []string{"tyler-id"}, opts)
+	if err != nil {
+		t.Fatalf("GetRelatedEntitiesBatch: %v", err)
+	}
+	if got := batch["tyler-id"]; len(got) != 1 || got[0].ID != "austin-id" {
+		t.Errorf("batch expected only Austin, got %+v", got)
+	}
+	// Literal targets have no origin and are kept
+	rels, err := qe.GetEntityRelationships(ctx, "tyler-id", opts)
+	if err != nil {
+		t.Fatalf("GetEntityRelationships: %v", err)
+	}
+	if len(rels) != 2 {
+		t.Errorf("expected Austin and the email, got %+v", rels)
+	}
+
+	opts = DefaultQueryOptions()
+	opts.Origins = []string{OriginInferred}
+	if results, _ := qe.GetRelatedEntities(ctx, "tyler-id", opts); len(results) != 1 || results[0].ID != "texas-id" {
+		t.Errorf("expected only Texas, got %+v", results)
+	}
+	if found, _ := qe.FindEntitiesByRelationType(ctx, "LIVES_IN", "texas-id", opts); len(found) != 0 {
+		t.Errorf("Tyler is extracted, not inferred: %+v", found)
+	}
+}
+
 func TestQueryEngine_GetRelatedEntities_TemporalFiltering(t *testing.T) {
 	db := setupQueryEngineTestDB(t)
 	defer db.Close()
@@ -663,3 +766,249 @@ func TestQueryEngine_Limit(t *testing.T) {
 		t.Errorf("expected 2 results with limit, got %d", len(results))
 	}
 }
+
+func TestQueryEngine_GetEntities(t *testing.T) {
+	db := setupQueryEngineTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	qe := NewQueryEngine(db)
+
+	insertQueryEngineTestEntity(t, db, "tyler-id", "Tyler", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "casey-id", "Casey", EntityTypePerson)
+
+	entities, err := qe.GetEntities(ctx, []string{"tyler-id", "casey-id", "tyler-id", "missing-id", ""})
+	if err != nil {
+		t.Fatalf("GetEntities: %v", err)
+	}
+	if len(entities) != 2 {
+		t.Fatalf("expected 2 entities, got %d", len(entities))
+	}
+	if entities["casey-id"] == nil || entities["casey-id"].CanonicalName != "Casey" {
+		t.Errorf("expected Casey, got %+v", entities["casey-id"])
+	}
+	if _, ok := entities["missing-id"]; ok {
+		t.Error("missing IDs should be absent from the result")
+	}
+
+	empty, err := qe.GetEntities(ctx, nil)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("GetEntities(nil) = %v, %v; want empty map", empty, err)
+	}
+}
+
+func TestQueryEngine_GetRelatedEntitiesBatch(t *testing.T) {
+	db := setupQueryEngineTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	qe := NewQueryEngine(db)
+
+	insertQueryEngineTestEntity(t, db, "tyler-id", "Tyler", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "casey-id", "Casey", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "anthropic-id", "Anthropic", EntityTypeCompany)
+
+	anthropicID := "anthropic-id"
+	caseyID := "casey-id"
+	past := "2020-01-01T00:00:00Z"
+	insertQueryEngineTestRelationship(t, db, "rel-1", "tyler-id", &anthropicID, nil, "WORKS_AT", "Tyler works at Anthropic", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "rel-2", "casey-id", &anthropicID, nil, "WORKS_AT", "Casey works at Anthropic", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "rel-3", "tyler-id", &caseyID, nil, "KNOWS", "Tyler knows Casey", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "rel-4", "casey-id", &anthropicID, nil, "INVESTED_IN", "Casey invested in Anthropic", nil, &past)
+
+	related, err := qe.GetRelatedEntitiesBatch(ctx, []string{"tyler-id", "casey-id", "anthropic-id"}, DefaultQueryOptions())
+	if err != nil {
+		t.Fatalf("GetRelatedEntitiesBatch: %v", err)
+	}
+
+	// Batch results must match the single-entity API
+	for _, id := range []string{"tyler-id", "casey-id", "anthropic-id"} {
+		single, err := qe.GetRelatedEntities(ctx, id, DefaultQueryOptions())
+		if err != nil {
+			t.Fatalf("GetRelatedEntities(%s): %v", id, err)
+		}
+		if len(related[id]) != len(single) {
+			t.Errorf("%s: batch returned %d, single returned %d", id, len(related[id]), len(single))
+		}
+	}
+	if len(related["anthropic-id"]) != 2 {
+		t.Errorf("expected 2 incoming for Anthropic (invalidated excluded), got %d", len(related["anthropic-id"]))
+	}
+
+	// Relation type filter + per-entity limit
+	opts := DefaultQueryOptions()
+	opts.RelationTypes = []string{"WORKS_AT"}
+	opts.Direction = DirectionOutgoing
+	opts.Limit = 1
+	related, err = qe.GetRelatedEntitiesBatch(ctx, []string{"tyler-id", "casey-id"}, opts)
+	if err != nil {
+		t.Fatalf("GetRelatedEntitiesBatch (filtered): %v", err)
+	}
+	for _, id := range []string{"tyler-id", "casey-id"} {
+		if len(related[id]) != 1 || related[id][0].ID != "anthropic-id" {
+			t.Errorf("%s: expected only WORKS_AT Anthropic, got %+v", id, related[id])
+		}
+	}
+}
+
+func TestQueryEngine_GetAliasesBatch(t *testing.T) {
+	db := setupQueryEngineTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	qe := NewQueryEngine(db)
+
+	insertQueryEngineTestEntity(t, db, "tyler-id", "Tyler", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "casey-id", "Casey", EntityTypePerson)
+	insertQueryEngineTestAlias(t, db, "alias-1", "tyler-id", "tyler@example.com", "email", false)
+	insertQueryEngineTestAlias(t, db, "alias-2", "tyler-id", "Tyler Napathy", "name", false)
+	insertQueryEngineTestAlias(t, db, "alias-3", "casey-id", "@casey", "handle", false)
+
+	aliases, err := qe.GetAliasesBatch(ctx, []string{"tyler-id", "casey-id"})
+	if err != nil {
+		t.Fatalf("GetAliasesBatch: %v", err)
+	}
+	if len(aliases["tyler-id"]) != 2 {
+		t.Errorf("expected 2 aliases for Tyler, got %d", len(aliases["tyler-id"]))
+	}
+	if len(aliases["casey-id"]) != 1 || aliases["casey-id"][0].Alias != "@casey" {
+		t.Errorf("expected @casey for Casey, got %+v", aliases["casey-id"])
+	}
+}
+
+func TestChunkIDs(t *testing.T) {
+	ids := []string{"a", "b", "c", "d", "e"}
+	chunks := chunkIDs(ids, 2)
+	if len(chunks) != 3 || len(chunks[2]) != 1 {
+		t.Errorf("chunkIDs = %v, want 3 chunks with last of size 1", chunks)
+	}
+	if chunkIDs(nil, 2) != nil {
+		t.Error("chunkIDs(nil) should be nil")
+	}
+	if placeholderList(3) != "?, ?, ?" {
+		t.Errorf("placeholderList(3) = %q", placeholderList(3))
+	}
+}
+
+func TestQueryEngine_PreparedStatementCache(t *testing.T) {
+	db := setupQueryEngineTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	qe := NewQueryEngine(db)
+
+	insertQueryEngineTestEntity(t, db, "tyler-id", "Tyler", EntityTypePerson)
+
+	for i := 0; i < 3; i++ {
+		entity, err := qe.GetEntity(ctx, "tyler-id")
+		if err != nil || entity == nil {
+			t.Fatalf("GetEntity: %v, %v", entity, err)
+		}
+		if _, err := qe.GetRelatedEntities(ctx, "tyler-id", DefaultQueryOptions()); err != nil {
+			t.Fatalf("GetRelatedEntities: %v", err)
+		}
+	}
+
+	qe.stmtMu.Lock()
+	cached := len(qe.stmts)
+	qe.stmtMu.Unlock()
+	// GetEntity + outgoing + incoming shapes
+	if cached != 3 {
+		t.Errorf("expected 3 cached statements, got %d", cached)
+	}
+
+	if err := qe.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if len(qe.stmts) != 0 {
+		t.Error("Close should release cached statements")
+	}
+
+	// Engine remains usable after Close (statements are re-prepared)
+	if entity, err := qe.GetEntity(ctx, "tyler-id"); err != nil || entity == nil {
+		t.Errorf("GetEntity after Close: %v, %v", entity, err)
+	}
+}
+
+func TestQueryEngine_HotPathIndexes(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+
+	plans := map[string]string{
+		`SELECT id FROM relationships WHERE source_entity_id = ? AND relation_type = ? AND invalid_at IS NULL`: "idx_relationships_source_type_invalid",
+		`SELECT id FROM relationships WHERE target_entity_id = ? AND relation_type = ? AND invalid_at IS NULL`: "idx_relationships_target_type_invalid",
+		`SELECT id FROM entity_aliases WHERE normalized = ? AND alias_type = ?`:                                "idx_entity_aliases_normalized",
+	}
+	for query, wantIndex := range plans {
+		rows, err := db.Query("EXPLAIN QUERY PLAN "+query, "a", "b")
+		if err != nil {
+			t.Fatalf("explain %q: %v", query, err)
+		}
+		var plan strings.Builder
+		for rows.Next() {
+			var id, parent, notused int
+			var detail string
+			if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+				t.Fatalf("scan plan: %v", err)
+			}
+			plan.WriteString(detail)
+		}
+		rows.Close()
+		if !strings.Contains(plan.String(), wantIndex) {
+			t.Errorf("plan for %q = %q, want index %s", query, plan.String(), wantIndex)
+		}
+	}
+}
+
+func TestQueryEngine_ThirdPartyTraversal(t *testing.T) {
+	db := setupQueryEngineTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	qe := NewQueryEngine(db)
+
+	// "Casey's sister Dana lives in Denver": no edge touches the user
+	insertQueryEngineTestEntity(t, db, "me", "Tyler", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "casey", "Casey", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "dana", "Dana", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "denver", "Denver", EntityTypeLocation)
+	casey, denver := "casey", "denver"
+	insertQueryEngineTestRelationship(t, db, "r1", "me", &casey, nil, "FRIEND_OF", "Tyler is friends with Casey", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "r2", "dana", &casey, nil, "SIBLING_OF", "Dana is Casey's sister", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "r3", "dana", &denver, nil, "LIVES_IN", "Dana lives in Denver", nil, nil)
+
+	related, err := qe.GetRelatedEntities(ctx, "dana", DefaultQueryOptions())
+	if err != nil {
+		t.Fatalf("GetRelatedEntities: %v", err)
+	}
+	if len(related) != 2 {
+		t.Errorf("Dana's neighbors = %d, want 2 (Casey, Denver)", len(related))
+	}
+
+	for _, expr := range []string{
+		`entity("Casey").in("SIBLING_OF").out("LIVES_IN")`,
+		`entity("Tyler").out("FRIEND_OF").in("SIBLING_OF").out("LIVES_IN")`,
+	} {
+		res, err := qe.RunGraphQuery(ctx, expr)
+		if err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		if len(res.Rows) != 1 || res.Rows[0].Name != "Denver" {
+			t.Errorf("%s = %+v, want Denver", expr, res.Rows)
+		}
+	}
+
+	// Third-party edges show up in the subgraph of an entity two hops away
+	graph, err := qe.GetSubgraph(ctx, "me", SubgraphOptions{Depth: 3})
+	if err != nil {
+		t.Fatalf("GetSubgraph: %v", err)
+	}
+	found := false
+	for _, l := range graph.Links {
+		if l.Source == "dana" && l.Target == "denver" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("subgraph links = %+v, want dana -> denver", graph.Links)
+	}
+}
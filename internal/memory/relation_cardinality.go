@@ -0,0 +1,203 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cardinality declares how many current edges of a relation type a source
+// entity may have at once.
+type Cardinality string
+
+const (
+	CardinalityOne  Cardinality = "one"  // At most one current edge (employer, birthdate)
+	CardinalityMany Cardinality = "many" // Any number of current edges (friends)
+)
+
+// CardinalityEnforcement declares what happens when a new edge violates a
+// CardinalityOne rule.
+type CardinalityEnforcement string
+
+const (
+	// EnforceInvalidate sets invalid_at on the superseded edges.
+	EnforceInvalidate CardinalityEnforcement = "invalidate"
+	// EnforceFlag keeps both edges and records a violation for review.
+	// Used for facts that should never change (a birthdate), where a second
+	// value is more likely an extraction error than a real update.
+	EnforceFlag CardinalityEnforcement = "flag"
+)
+
+// CardinalityRule is the ontology's cardinality declaration for a relation type.
+type CardinalityRule struct {
+	Cardinality Cardinality            `json:"cardinality"`
+	Enforcement CardinalityEnforcement `json:"enforcement,omitempty"`
+}
+
+// DefaultCardinalityRules declares cardinality for known relation types.
+// Relation types without a rule are treated as CardinalityMany.
+var DefaultCardinalityRules = map[string]CardinalityRule{
+	// Superseded by newer facts (see IsExclusiveRelationType)
+	"WORKS_AT":   {Cardinality: CardinalityOne, Enforcement: EnforceInvalidate},
+	"LIVES_IN":   {Cardinality: CardinalityOne, Enforcement: EnforceInvalidate},
+	"SPOUSE_OF":  {Cardinality: CardinalityOne, Enforcement: EnforceInvalidate},
+	"MARRIED_TO": {Cardinality: CardinalityOne, Enforcement: EnforceInvalidate},
+	"DATING":     {Cardinality: CardinalityOne, Enforcement: EnforceInvalidate},
+
+	// Immutable facts - a second value is flagged, not silently replaced
+	"BORN_ON": {Cardinality: CardinalityOne, Enforcement: EnforceFlag},
+	"BORN_IN": {Cardinality: CardinalityOne, Enforcement: EnforceFlag},
+	"DIED_ON": {Cardinality: CardinalityOne, Enforcement: EnforceFlag},
+
+	// Explicitly many
+	"KNOWS":      {Cardinality: CardinalityMany},
+	"FRIEND_OF":  {Cardinality: CardinalityMany},
+	"SIBLING_OF": {Cardinality: CardinalityMany},
+	"PARENT_OF":  {Cardinality: CardinalityMany},
+	"CHILD_OF":   {Cardinality: CardinalityMany},
+	"ATTENDED":   {Cardinality: CardinalityMany},
+}
+
+// CardinalityRules returns a copy of DefaultCardinalityRules with overrides
+// applied. An override with an empty Cardinality removes the rule.
+func CardinalityRules(overrides map[string]CardinalityRule) map[string]CardinalityRule {
+	rules := make(map[string]CardinalityRule, len(DefaultCardinalityRules)+len(overrides))
+	for relType, rule := range DefaultCardinalityRules {
+		rules[relType] = rule
+	}
+	for relType, rule := range overrides {
+		if rule.Cardinality == "" {
+			delete(rules, relType)
+			continue
+		}
+		if rule.Cardinality == CardinalityOne && rule.Enforcement == "" {
+			rule.Enforcement = EnforceInvalidate
+		}
+		rules[relType] = rule
+	}
+	return rules
+}
+
+// Violation statuses.
+const (
+	ViolationStatusPending   = "pending"
+	ViolationStatusResolved  = "resolved"
+	ViolationStatusDismissed = "dismissed"
+)
+
+// CardinalityViolation records a new edge that conflicts with an existing
+// current edge of a CardinalityOne relation type under EnforceFlag.
+type CardinalityViolation struct {
+	ID                        string  `json:"id"`
+	EntityID                  string  `json:"entity_id"`
+	RelationType              string  `json:"relation_type"`
+	RelationshipID            string  `json:"relationship_id"`             // The new edge
+	ConflictingRelationshipID string  `json:"conflicting_relationship_id"` // The existing edge
+	Status                    string  `json:"status"`
+	CreatedAt                 string  `json:"created_at"`
+	ResolvedAt                *string `json:"resolved_at,omitempty"`
+}
+
+// recordViolation inserts a pending violation (idempotent per edge pair).
+func (d *ContradictionDetector) recordViolation(ctx context.Context, entityID, relationType, newRelID, existingRelID string) (bool, error) {
+	res, err := d.db.ExecContext(ctx, `
+		INSERT INTO cardinality_violations (
+			id, entity_id, relation_type, relationship_id, conflicting_relationship_id, status, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(relationship_id, conflicting_relationship_id) DO NOTHING
+	`, uuid.New().String(), entityID, relationType, newRelID, existingRelID,
+		ViolationStatusPending, time.Now().Format(time.RFC3339))
+	if err != nil {
+		return false, fmt.Errorf("insert cardinality violation: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ListCardinalityViolations returns violations with the given status
+// (all statuses if empty), newest first.
+func (d *ContradictionDetector) ListCardinalityViolations(ctx context.Context, status string, limit int) ([]CardinalityViolation, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	query := `
+		SELECT id, entity_id, relation_type, relationship_id, conflicting_relationship_id,
+		       status, created_at, resolved_at
+		FROM cardinality_violations
+	`
+	var args []interface{}
+	if status != "" {
+		query += ` WHERE status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY created_at DESC, id LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query cardinality violations: %w", err)
+	}
+	defer rows.Close()
+
+	var violations []CardinalityViolation
+	for rows.Next() {
+		var v CardinalityViolation
+		var resolvedAt sql.NullString
+		if err := rows.Scan(&v.ID, &v.EntityID, &v.RelationType, &v.RelationshipID,
+			&v.ConflictingRelationshipID, &v.Status, &v.CreatedAt, &resolvedAt); err != nil {
+			return nil, fmt.Errorf("scan cardinality violation: %w", err)
+		}
+		if resolvedAt.Valid {
+			v.ResolvedAt = &resolvedAt.String
+		}
+		violations = append(violations, v)
+	}
+	return violations, rows.Err()
+}
+
+// ResolveCardinalityViolation resolves a pending violation. If keepRelID is
+// one of the two edges, the other edge is invalidated; if empty, the
+// violation is dismissed and both edges are kept.
+func (d *ContradictionDetector) ResolveCardinalityViolation(ctx context.Context, violationID, keepRelID string) error {
+	var newRelID, existingRelID, status string
+	err := d.db.QueryRowContext(ctx, `
+		SELECT relationship_id, conflicting_relationship_id, status
+		FROM cardinality_violations WHERE id = ?
+	`, violationID).Scan(&newRelID, &existingRelID, &status)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("violation not found: %s", violationID)
+	}
+	if err != nil {
+		return fmt.Errorf("get violation: %w", err)
+	}
+	if status != ViolationStatusPending {
+		return fmt.Errorf("violation %s is already %s", violationID, status)
+	}
+
+	now := time.Now()
+	newStatus := ViolationStatusDismissed
+	switch keepRelID {
+	case "":
+	case newRelID, existingRelID:
+		drop := existingRelID
+		if keepRelID == existingRelID {
+			drop = newRelID
+		}
+		if err := d.invalidateRelationship(ctx, drop, now, sql.NullString{}); err != nil {
+			return err
+		}
+		newStatus = ViolationStatusResolved
+	default:
+		return fmt.Errorf("relationship %s is not part of violation %s", keepRelID, violationID)
+	}
+
+	if _, err := d.db.ExecContext(ctx, `
+		UPDATE cardinality_violations SET status = ?, resolved_at = ? WHERE id = ?
+	`, newStatus, now.Format(time.RFC3339), violationID); err != nil {
+		return fmt.Errorf("update violation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package memory
+
+import "sort"
+
+// InverseRelationTypes pairs relation types that state the same fact from
+// opposite ends: "Acme EMPLOYS Sam" is "Sam WORKS_AT Acme". Facts are
+// stored once, as the canonical type (the key); the edge resolver flips an
+// extracted inverse into it, and the query engine exposes the inverse from
+// the other end, so extraction never needs to emit both. Symmetric types
+// are their own inverse.
+var InverseRelationTypes = map[string]string{
+	"WORKS_AT":       "EMPLOYS",
+	"PARENT_OF":      "CHILD_OF",
+	"GRANDPARENT_OF": "GRANDCHILD_OF",
+	"MEMBER_OF":      "HAS_MEMBER",
+	"OWNS":           "OWNED_BY",
+	"FOUNDED":        "FOUNDED_BY",
+	"CREATED":        "CREATED_BY",
+	"AUTHORED":       "AUTHORED_BY",
+	"HOSTED":         "HOSTED_BY",
+	"SUED_BY":        "SUED",
+
+	// Symmetric
+	"KNOWS":      "KNOWS",
+	"FRIEND_OF":  "FRIEND_OF",
+	"SPOUSE_OF":  "SPOUSE_OF",
+	"MARRIED_TO": "MARRIED_TO",
+	"DATING":     "DATING",
+	"SIBLING_OF": "SIBLING_OF",
+}
+
+// InverseRelationType returns the type that states relType's facts from the
+// other end, or "" if it has none.
+func InverseRelationType(relType string) string {
+	if inverse, ok := InverseRelationTypes[relType]; ok {
+		return inverse
+	}
+	for canonical, inverse := range InverseRelationTypes {
+		if inverse == relType {
+			return canonical
+		}
+	}
+	return ""
+}
+
+// IsSymmetricRelationType reports whether relType reads the same in both
+// directions (FRIEND_OF, SPOUSE_OF, ...).
+func IsSymmetricRelationType(relType string) bool {
+	return InverseRelationTypes[relType] == relType
+}
+
+// CanonicalRelationType returns the type a fact of relType is stored as,
+// and whether its source and target swap to get there.
+func CanonicalRelationType(relType string) (string, bool) {
+	if _, ok := InverseRelationTypes[relType]; ok {
+		return relType, false
+	}
+	for canonical, inverse := range InverseRelationTypes {
+		if inverse == relType {
+			return canonical, true
+		}
+	}
+	return relType, false
+}
+
+// invertibleRelationTypes lists every type with an inverse, sorted.
+func invertibleRelationTypes() []string {
+	types := make([]string, 0, 2*len(InverseRelationTypes))
+	for canonical, inverse := range InverseRelationTypes {
+		types = append(types, canonical)
+		if inverse != canonical {
+			types = append(types, inverse)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// inverseLookups returns the lookups that find the edges a traversal
+// reaches through inverse types: each looks the other way for the inverses
+// of the requested types (of every invertible type when none are). Its
+// results are relabelled with relabelInverse. Edges a direct lookup already
+// covers are not looked up again, so an unfiltered traversal in both
+// directions needs none.
+func inverseLookups(opts QueryOptions) []QueryOptions {
+	both := opts.Direction == DirectionBoth || opts.Direction == ""
+	if both && len(opts.RelationTypes) == 0 {
+		return nil
+	}
+	requested := map[string]bool{}
+	for _, rt := range opts.RelationTypes {
+		requested[rt] = true
+	}
+
+	var types []string
+	if len(opts.RelationTypes) == 0 {
+		types = invertibleRelationTypes()
+	} else {
+		seen := map[string]bool{}
+		for _, rt := range opts.RelationTypes {
+			inverse := InverseRelationType(rt)
+			// In both directions, stored edges of a requested type are
+			// already found as they are
+			if inverse == "" || seen[inverse] || (both && requested[inverse]) {
+				continue
+			}
+			seen[inverse] = true
+			types = append(types, inverse)
+		}
+	}
+	if len(types) == 0 {
+		return nil
+	}
+
+	var lookups []QueryOptions
+	for _, dir := range []QueryDirection{DirectionOutgoing, DirectionIncoming} {
+		// dir is the way the lookup looks; it serves the traversal the other way
+		if !both && opts.Direction == dir {
+			continue
+		}
+		lookup := opts
+		lookup.Direction = dir
+		lookup.RelationTypes = types
+		lookup.Limit = 0
+		lookups = append(lookups, lookup)
+	}
+	return lookups
+}
+
+// flipDirection returns the opposite of an "outgoing" or "incoming" label.
+func flipDirection(direction string) string {
+	if direction == string(DirectionOutgoing) {
+		return string(DirectionIncoming)
+	}
+	return string(DirectionOutgoing)
+}
+
+// relabelInverse turns related entities found by an inverse lookup into the
+// inverse edges they stand for, dropping any already in have.
+func relabelInverse(found, have []RelatedEntity) []RelatedEntity {
+	seen := make(map[string]bool, len(have))
+	for _, rel := range have {
+		seen[relatedKey(rel)] = true
+	}
+	var out []RelatedEntity
+	for _, rel := range found {
+		rel.RelationType = InverseRelationType(rel.RelationType)
+		rel.Direction = flipDirection(rel.Direction)
+		rel.Inverse = true
+		if key := relatedKey(rel); !seen[key] {
+			seen[key] = true
+			out = append(out, rel)
+		}
+	}
+	return out
+}
+
+func relatedKey(rel RelatedEntity) string {
+	return rel.ID + "|" + rel.RelationType + "|" + rel.Direction + "|" + derefString(rel.ValidAt)
+}
+
+// relabelInverseRelationships is relabelInverse for relationships: the
+// stored edge is presented from the queried entity's side, with source and
+// target swapped.
+func relabelInverseRelationships(found, have []EntityRelationship) []EntityRelationship {
+	seen := make(map[string]bool, len(have))
+	for _, rel := range have {
+		seen[relationshipKey(rel)] = true
+	}
+	var out []EntityRelationship
+	for _, rel := range found {
+		if rel.TargetEntityID == nil || rel.TargetName == nil {
+			continue
+		}
+		sourceID, sourceName := rel.SourceEntityID, rel.SourceName
+		rel.SourceEntityID, rel.TargetEntityID = *rel.TargetEntityID, &sourceID
+		rel.SourceName, rel.TargetName = *rel.TargetName, &sourceName
+		rel.RelationType = InverseRelationType(rel.RelationType)
+		rel.Direction = flipDirection(rel.Direction)
+		rel.Inverse = true
+		if key := relationshipKey(rel); !seen[key] {
+			seen[key] = true
+			out = append(out, rel)
+		}
+	}
+	return out
+}
+
+func relationshipKey(rel EntityRelationship) string {
+	return rel.SourceEntityID + "|" + derefString(rel.TargetEntityID) + "|" + derefString(rel.TargetLiteral) + "|" +
+		rel.RelationType + "|" + derefString(rel.ValidAt)
+}
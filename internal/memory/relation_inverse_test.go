@@ -0,0 +1,186 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestCanonicalRelationType(t *testing.T) {
+	tests := []struct {
+		relType   string
+		canonical string
+		swap      bool
+		inverse   string
+	}{
+		{"EMPLOYS", "WORKS_AT", true, "WORKS_AT"},
+		{"WORKS_AT", "WORKS_AT", false, "EMPLOYS"},
+		{"CHILD_OF", "PARENT_OF", true, "PARENT_OF"},
+		{"FRIEND_OF", "FRIEND_OF", false, "FRIEND_OF"},
+		{"LIVES_IN", "LIVES_IN", false, ""},
+	}
+	for _, tt := range tests {
+		canonical, swap := CanonicalRelationType(tt.relType)
+		if canonical != tt.canonical || swap != tt.swap || InverseRelationType(tt.relType) != tt.inverse {
+			t.Errorf("%s: canonical = %s, %v; inverse = %q", tt.relType, canonical, swap, InverseRelationType(tt.relType))
+		}
+	}
+	if !IsSymmetricRelationType("SIBLING_OF") || IsSymmetricRelationType("PARENT_OF") {
+		t.Error("IsSymmetricRelationType")
+	}
+}
+
+func TestEdgeResolver_StoresInverseAsCanonical(t *testing.T) {
+	db := setupEdgeResolverTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertEdgeResolverTestEntity(t, db, "sam", "Sam", EntityTypePerson)
+	insertEdgeResolverTestEntity(t, db, "acme", "Acme", EntityTypeCompany)
+	insertEdgeResolverTestEntity(t, db, "dana", "Dana", EntityTypePerson)
+	insertEdgeResolverTestEpisode(t, db, "episode-1")
+	insertEdgeResolverTestEpisode(t, db, "episode-2")
+
+	entities := []ResolvedEntity{
+		{ID: "sam", Name: "Sam", EntityTypeID: EntityTypePerson},
+		{ID: "acme", Name: "Acme", EntityTypeID: EntityTypeCompany},
+		{ID: "dana", Name: "Dana", EntityTypeID: EntityTypePerson},
+	}
+	sam, acme, dana := 0, 1, 2
+	resolver := NewEdgeResolver(db)
+
+	res, err := resolver.Resolve(ctx, "episode-1", []ExtractedRelationship{
+		{SourceEntityID: acme, RelationType: "EMPLOYS", TargetEntityID: &sam, Fact: "Acme hired Sam"},
+		{SourceEntityID: sam, RelationType: "FRIEND_OF", TargetEntityID: &dana, Fact: "Sam and Dana are friends"},
+	}, entities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.NewRelationships != 2 {
+		t.Fatalf("first episode = %+v", res)
+	}
+
+	// The same facts stated from the other ends
+	res, err = resolver.Resolve(ctx, "episode-2", []ExtractedRelationship{
+		{SourceEntityID: sam, RelationType: "WORKS_AT", TargetEntityID: &acme, Fact: "Sam works at Acme"},
+		{SourceEntityID: dana, RelationType: "FRIEND_OF", TargetEntityID: &sam, Fact: "Dana is Sam's friend"},
+	}, entities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.NewRelationships != 0 || res.ExistingRelationships != 2 {
+		t.Errorf("second episode = %+v", res)
+	}
+
+	var source, relType, target, fact string
+	if err := db.QueryRow(`
+		SELECT source_entity_id, relation_type, target_entity_id, fact FROM relationships WHERE relation_type != 'FRIEND_OF'
+	`).Scan(&source, &relType, &target, &fact); err != nil {
+		t.Fatal(err)
+	}
+	if source != "sam" || relType != "WORKS_AT" || target != "acme" || fact != "Sam works at Acme" {
+		t.Errorf("stored %s %s %s (%q)", source, relType, target, fact)
+	}
+}
+
+func TestQueryEngine_InverseRelations(t *testing.T) {
+	db := setupQueryEngineTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	qe := NewQueryEngine(db)
+
+	insertQueryEngineTestEntity(t, db, "sam", "Sam", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "alex", "Alex", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "dana", "Dana", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "acme", "Acme", EntityTypeOrganization)
+
+	sam, acme := "sam", "acme"
+	insertQueryEngineTestRelationship(t, db, "r1", "sam", &acme, nil, "WORKS_AT", "Sam works at Acme", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "r2", "alex", &sam, nil, "PARENT_OF", "Alex is a parent of Sam", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "r3", "dana", &sam, nil, "FRIEND_OF", "Dana is friends with Sam", nil, nil)
+	// Stored before inverses were canonicalized: the same fact both ways
+	insertQueryEngineTestRelationship(t, db, "r4", "acme", &sam, nil, "EMPLOYS", "Acme employs Sam", nil, nil)
+
+	names := func(res *GraphQueryResult) string {
+		var out []string
+		for _, row := range res.Rows {
+			out = append(out, row.Name+":"+row.Via.RelationType)
+		}
+		sort.Strings(out)
+		return strings.Join(out, ",")
+	}
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`entity("Acme").out("EMPLOYS")`, "Sam:EMPLOYS"},
+		{`entity("Sam").out("CHILD_OF")`, "Alex:CHILD_OF"},
+		{`entity("Alex").in("CHILD_OF")`, "Sam:CHILD_OF"},
+		{`entity("Sam").out("FRIEND_OF")`, "Dana:FRIEND_OF"},
+		{`entity("Sam").out()`, "Acme:WORKS_AT,Alex:CHILD_OF,Dana:FRIEND_OF"},
+		{`entity("Sam").both("EMPLOYS")`, "Acme:EMPLOYS"},
+		{`entity("Sam").both()`, "Acme:WORKS_AT,Alex:PARENT_OF,Dana:FRIEND_OF"},
+	}
+	for _, tt := range tests {
+		res, err := qe.RunGraphQuery(ctx, tt.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if got := names(res); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+
+	rels, err := qe.GetEntityRelationships(ctx, "sam", QueryOptions{Direction: DirectionOutgoing, RelationTypes: []string{"CHILD_OF"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rels) != 1 || !rels[0].Inverse || rels[0].ID != "r2" || rels[0].SourceEntityID != "sam" || *rels[0].TargetEntityID != "alex" || rels[0].TargetName == nil || *rels[0].TargetName != "Alex" {
+		t.Errorf("relationships = %+v", rels)
+	}
+
+	// The duplicate is reported once
+	related, err := qe.GetRelatedEntities(ctx, "acme", QueryOptions{Direction: DirectionOutgoing, RelationTypes: []string{"EMPLOYS"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(related) != 1 || related[0].ID != "sam" || related[0].Inverse {
+		t.Errorf("related = %+v", related)
+	}
+
+	found, err := qe.FindEntitiesByRelationType(ctx, "CHILD_OF", "alex", DefaultQueryOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].ID != "sam" || found[0].RelationType != "CHILD_OF" || found[0].Direction != "incoming" {
+		t.Errorf("FindEntitiesByRelationType = %+v", found)
+	}
+
+	batch, err := qe.GetRelatedEntitiesBatch(ctx, []string{"alex", "sam"}, QueryOptions{Direction: DirectionOutgoing, RelationTypes: []string{"PARENT_OF", "CHILD_OF"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, s := batch["alex"], batch["sam"]; len(a) != 1 || a[0].ID != "sam" || a[0].Inverse ||
+		len(s) != 1 || s[0].ID != "alex" || s[0].RelationType != "CHILD_OF" || !s[0].Inverse {
+		t.Errorf("batch = %+v", batch)
+	}
+}
+
+func TestAddManualFact_InverseType(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	res, err := AddManualFact(ctx, db, ManualFact{Source: "Acme", RelationType: "employs", Target: "Sam", SourceTypeID: EntityTypeOrganization})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel := res.Relationship
+	if rel.RelationType != "WORKS_AT" || rel.SourceName != "Sam" || rel.TargetName == nil || *rel.TargetName != "Acme" {
+		t.Errorf("stored %s %s %v", rel.SourceName, rel.RelationType, rel.TargetName)
+	}
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+)
+
+// RelationTypePolicy limits the relation types extraction keeps for a
+// channel. Blocked wins over Allowed; an empty Allowed list allows every
+// type that is not blocked.
+type RelationTypePolicy struct {
+	Allowed []string
+	Blocked []string
+}
+
+// Permits reports whether relType may be extracted under the policy.
+func (p RelationTypePolicy) Permits(relType string) bool {
+	relType = strings.ToUpper(strings.TrimSpace(relType))
+	for _, t := range p.Blocked {
+		if strings.EqualFold(t, relType) {
+			return false
+		}
+	}
+	if len(p.Allowed) == 0 {
+		return true
+	}
+	for _, t := range p.Allowed {
+		if strings.EqualFold(t, relType) {
+			return true
+		}
+	}
+	return false
+}
+
+// promptSection tells the model which relation types to leave out, so it
+// does not spend output on facts validateRelationships would drop anyway.
+func (p RelationTypePolicy) promptSection() string {
+	if len(p.Allowed) == 0 && len(p.Blocked) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("### Relation Types for This Channel\n\n")
+	if len(p.Allowed) > 0 {
+		var allowed []string
+		for _, t := range p.Allowed {
+			if p.Permits(t) {
+				allowed = append(allowed, strings.ToUpper(t))
+			}
+		}
+		sort.Strings(allowed)
+		sb.WriteString("Only extract these relation types: " + strings.Join(allowed, ", ") + ".\n")
+	}
+	if len(p.Blocked) > 0 {
+		blocked := make([]string, len(p.Blocked))
+		for i, t := range p.Blocked {
+			blocked[i] = strings.ToUpper(t)
+		}
+		sort.Strings(blocked)
+		sb.WriteString("Do not extract these relation types: " + strings.Join(blocked, ", ") + ".\n")
+	}
+	return sb.String()
+}
+
+// DefaultRelationTypePolicies blocks contact details on AI-session channels,
+// where the assistant's output is full of made-up example emails and phone
+// numbers that would otherwise become aliases.
+func DefaultRelationTypePolicies() map[string]RelationTypePolicy {
+	aiSession := RelationTypePolicy{Blocked: []string{"HAS_EMAIL", "HAS_PHONE"}}
+	return map[string]RelationTypePolicy{
+		"cursor":      aiSession,
+		"codex":       aiSession,
+		"nexus":       aiSession,
+		"nexus_agent": aiSession,
+	}
+}
@@ -0,0 +1,256 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Napageneral/mnemonic/internal/gemini"
+)
+
+// DefaultCritiqueMinConfidence is the confidence below which a reviewed
+// relationship is dropped even if the critic did not reject it outright.
+const DefaultCritiqueMinConfidence = 0.3
+
+// Critique verdicts.
+const (
+	CritiqueVerdictKeep = "keep"
+	CritiqueVerdictDrop = "drop"
+)
+
+// CritiqueInput contains the inputs for reviewing extracted relationships.
+type CritiqueInput struct {
+	EpisodeContent   string
+	ResolvedEntities []ResolvedEntity
+	Relationships    []ExtractedRelationship
+	// Optional notes the user attached to the episode
+	UserNotes []string
+	// Optional per-call model override (e.g. from model routing)
+	Model string
+}
+
+// CritiqueVerdict is the critic's judgement on a single relationship,
+// referenced by its index in the reviewed list.
+type CritiqueVerdict struct {
+	Index      int      `json:"index"`
+	Verdict    string   `json:"verdict"` // keep, drop
+	Confidence *float64 `json:"confidence"`
+	Reason     string   `json:"reason"`
+}
+
+// CritiqueResult contains the relationships that survived review.
+type CritiqueResult struct {
+	Relationships []ExtractedRelationship `json:"relationships"`
+	Dropped       []DroppedRelationship   `json:"dropped"`
+	Adjusted      int                     `json:"adjusted"` // Kept relationships whose confidence was set
+}
+
+// DroppedRelationship is a relationship the critic rejected and why.
+type DroppedRelationship struct {
+	Relationship ExtractedRelationship `json:"relationship"`
+	Reason       string                `json:"reason"`
+}
+
+// RelationshipCritic runs a second LLM pass over extracted relationships,
+// checking each against the episode text to drop hallucinated facts and
+// assign a confidence.
+type RelationshipCritic struct {
+	geminiClient  *gemini.Client
+	model         string
+	minConfidence float64
+	params        GenerationParams
+}
+
+// NewRelationshipCritic creates a new RelationshipCritic.
+func NewRelationshipCritic(geminiClient *gemini.Client, model string, minConfidence float64) *RelationshipCritic {
+	if model == "" {
+		model = "gemini-2.0-flash" // Default model
+	}
+	if minConfidence <= 0 {
+		minConfidence = DefaultCritiqueMinConfidence
+	}
+	return &RelationshipCritic{
+		geminiClient:  geminiClient,
+		model:         model,
+		minConfidence: minConfidence,
+	}
+}
+
+// SetGenerationParams sets the sampling settings for critique calls.
+func (c *RelationshipCritic) SetGenerationParams(params GenerationParams) {
+	c.params = params
+}
+
+// Critique reviews relationships against the episode text.
+func (c *RelationshipCritic) Critique(ctx context.Context, input CritiqueInput) (*CritiqueResult, error) {
+	if len(input.Relationships) == 0 || input.EpisodeContent == "" {
+		return &CritiqueResult{Relationships: input.Relationships}, nil
+	}
+
+	prompt := c.buildPrompt(input)
+	writeDebugFile(ctx, "critique_prompt.txt", prompt)
+
+	req := &gemini.GenerateContentRequest{
+		Contents: []gemini.Content{{
+			Role:  "user",
+			Parts: []gemini.Part{{Text: prompt}},
+		}},
+		GenerationConfig: c.params.generationConfig(),
+	}
+
+	model := c.model
+	if input.Model != "" {
+		model = input.Model
+	}
+	resp, err := c.geminiClient.GenerateContent(ctx, model, req)
+	if err != nil {
+		return nil, fmt.Errorf("generate content: %w", err)
+	}
+	recordLLMUsage(ctx, model, resp.UsageMetadata)
+
+	text := strings.TrimSpace(extractTextFromResponse(resp))
+	if text == "" {
+		return nil, fmt.Errorf("empty response from LLM")
+	}
+	writeDebugFile(ctx, "critique_response.json", text)
+
+	verdicts, err := parseCritiqueVerdicts(text)
+	if err != nil {
+		return nil, err
+	}
+	return c.applyVerdicts(input.Relationships, verdicts), nil
+}
+
+// parseCritiqueVerdicts parses the critic's JSON response.
+func parseCritiqueVerdicts(text string) ([]CritiqueVerdict, error) {
+	clean := strings.TrimSpace(text)
+	clean = strings.TrimPrefix(clean, "```json")
+	clean = strings.TrimPrefix(clean, "```")
+	clean = strings.TrimSuffix(clean, "```")
+
+	var parsed struct {
+		Verdicts []CritiqueVerdict `json:"verdicts"`
+	}
+	if err := json.Unmarshal([]byte(clean), &parsed); err != nil {
+		return nil, fmt.Errorf("parse response JSON: %w (response: %s)", err, text)
+	}
+	return parsed.Verdicts, nil
+}
+
+// applyVerdicts drops rejected or low-confidence relationships and records
+// the critic's confidence on the rest. Relationships without a verdict are
+// kept unchanged.
+func (c *RelationshipCritic) applyVerdicts(rels []ExtractedRelationship, verdicts []CritiqueVerdict) *CritiqueResult {
+	byIndex := make(map[int]CritiqueVerdict, len(verdicts))
+	for _, v := range verdicts {
+		if v.Index >= 0 && v.Index < len(rels) {
+			byIndex[v.Index] = v
+		}
+	}
+
+	result := &CritiqueResult{Relationships: []ExtractedRelationship{}}
+	for i, rel := range rels {
+		v, ok := byIndex[i]
+		if !ok {
+			result.Relationships = append(result.Relationships, rel)
+			continue
+		}
+
+		verdict := strings.ToLower(strings.TrimSpace(v.Verdict))
+		if verdict == CritiqueVerdictDrop {
+			result.Dropped = append(result.Dropped, DroppedRelationship{Relationship: rel, Reason: v.Reason})
+			continue
+		}
+		if v.Confidence != nil {
+			conf := clampConfidence(*v.Confidence)
+			if conf < c.minConfidence {
+				reason := v.Reason
+				if reason == "" {
+					reason = fmt.Sprintf("confidence %.2f below threshold", conf)
+				}
+				result.Dropped = append(result.Dropped, DroppedRelationship{Relationship: rel, Reason: reason})
+				continue
+			}
+			rel.Confidence = &conf
+			result.Adjusted++
+		}
+		result.Relationships = append(result.Relationships, rel)
+	}
+	return result
+}
+
+func clampConfidence(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+// buildPrompt builds the critique prompt listing each relationship by index.
+func (c *RelationshipCritic) buildPrompt(input CritiqueInput) string {
+	var sb strings.Builder
+
+	sb.WriteString(`You are reviewing facts that were extracted from a conversation.
+For each numbered relationship below, check whether the episode text (CURRENT_EPISODE) actually supports it.
+
+Drop a relationship if:
+- The episode text does not state or clearly imply it
+- It attributes a fact to the wrong entity
+- It misreads a joke, hypothetical, question, or quoted third-party claim as fact
+
+Otherwise keep it and give a confidence from 0.0 to 1.0:
+- 0.9-1.0: explicitly stated
+- 0.6-0.8: strongly implied
+- below 0.6: weakly implied or ambiguous
+
+## Entities
+
+`)
+	for i, ent := range input.ResolvedEntities {
+		sb.WriteString(fmt.Sprintf("%d: %s\n", i, ent.Name))
+	}
+
+	sb.WriteString("\n## Relationships\n\n")
+	for i, rel := range input.Relationships {
+		source := entityNameAt(input.ResolvedEntities, rel.SourceEntityID)
+		target := ""
+		if rel.TargetEntityID != nil {
+			target = entityNameAt(input.ResolvedEntities, *rel.TargetEntityID)
+		} else if rel.TargetLiteral != nil {
+			target = fmt.Sprintf("%q", *rel.TargetLiteral)
+		}
+		sb.WriteString(fmt.Sprintf("[%d] %s %s %s — %s\n", i, source, rel.RelationType, target, rel.Fact))
+	}
+
+	sb.WriteString("\n<CURRENT_EPISODE>\n")
+	sb.WriteString(sanitizePromptContent(input.EpisodeContent))
+	sb.WriteString("\n</CURRENT_EPISODE>\n\n")
+	sb.WriteString(userNotesSection(input.UserNotes))
+	sb.WriteString(untrustedContentRules)
+
+	sb.WriteString(`## Output Format
+
+Return JSON with one verdict per relationship:
+{"verdicts": [{"index": 0, "verdict": "keep", "confidence": 0.9, "reason": "stated directly"}]}
+
+- verdict: "keep" or "drop"
+- confidence: 0.0-1.0 (for kept relationships)
+- reason: short justification
+
+Return ONLY the JSON object, no other text.
+`)
+
+	return sb.String()
+}
+
+// entityNameAt returns the name of the resolved entity at a prompt index.
+func entityNameAt(entities []ResolvedEntity, idx int) string {
+	if idx < 0 || idx >= len(entities) {
+		return fmt.Sprintf("entity#%d", idx)
+	}
+	return entities[idx].Name
+}
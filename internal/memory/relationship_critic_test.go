@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseCritiqueVerdicts(t *testing.T) {
+	text := "```json\n{\"verdicts\": [{\"index\": 0, \"verdict\": \"keep\", \"confidence\": 0.9, \"reason\": \"stated\"}, {\"index\": 1, \"verdict\": \"drop\", \"reason\": \"joke\"}]}\n```"
+	verdicts, err := parseCritiqueVerdicts(text)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(verdicts) != 2 || verdicts[1].Verdict != "drop" || verdicts[0].Confidence == nil || *verdicts[0].Confidence != 0.9 {
+		t.Errorf("verdicts = %+v", verdicts)
+	}
+
+	if _, err := parseCritiqueVerdicts("not json"); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestApplyVerdicts(t *testing.T) {
+	critic := NewRelationshipCritic(nil, "", 0)
+	target := 1
+	rels := []ExtractedRelationship{
+		{SourceEntityID: 0, RelationType: "WORKS_AT", TargetEntityID: &target, Fact: "Tyler works at Anthropic"},
+		{SourceEntityID: 0, RelationType: "LIVES_IN", TargetEntityID: &target, Fact: "Tyler lives on the moon"},
+		{SourceEntityID: 0, RelationType: "KNOWS", TargetEntityID: &target, Fact: "Tyler knows Sarah"},
+		{SourceEntityID: 0, RelationType: "LIKES", TargetEntityID: &target, Fact: "Tyler likes sushi"},
+	}
+	high, low := 0.95, 0.1
+	verdicts := []CritiqueVerdict{
+		{Index: 0, Verdict: "keep", Confidence: &high},
+		{Index: 1, Verdict: "DROP", Reason: "sarcasm"},
+		{Index: 2, Verdict: "keep", Confidence: &low},
+		{Index: 9, Verdict: "drop"}, // out of range - ignored
+	}
+
+	result := critic.applyVerdicts(rels, verdicts)
+
+	if len(result.Relationships) != 2 {
+		t.Fatalf("kept %d relationships, want 2", len(result.Relationships))
+	}
+	if result.Relationships[0].Confidence == nil || *result.Relationships[0].Confidence != 0.95 {
+		t.Errorf("kept[0] confidence = %v, want 0.95", result.Relationships[0].Confidence)
+	}
+	if result.Relationships[1].RelationType != "LIKES" || result.Relationships[1].Confidence != nil {
+		t.Errorf("relationship without verdict should be kept unchanged: %+v", result.Relationships[1])
+	}
+	if len(result.Dropped) != 2 || result.Dropped[0].Reason != "sarcasm" {
+		t.Errorf("dropped = %+v", result.Dropped)
+	}
+	if !strings.Contains(result.Dropped[1].Reason, "below threshold") {
+		t.Errorf("low-confidence drop reason = %q", result.Dropped[1].Reason)
+	}
+	if result.Adjusted != 1 {
+		t.Errorf("Adjusted = %d, want 1", result.Adjusted)
+	}
+}
+
+func TestCritiqueBuildPrompt(t *testing.T) {
+	critic := NewRelationshipCritic(nil, "", 0)
+	target := 1
+	literal := "1990-05-01"
+	prompt := critic.buildPrompt(CritiqueInput{
+		EpisodeContent: "Tyler: I just started at Anthropic",
+		ResolvedEntities: []ResolvedEntity{
+			{ID: "e1", Name: "Tyler"},
+			{ID: "e2", Name: "Anthropic"},
+		},
+		Relationships: []ExtractedRelationship{
+			{SourceEntityID: 0, RelationType: "WORKS_AT", TargetEntityID: &target, Fact: "Tyler works at Anthropic"},
+			{SourceEntityID: 0, RelationType: "BORN_ON", TargetLiteral: &literal, Fact: "Tyler was born May 1, 1990"},
+		},
+	})
+
+	for _, want := range []string{
+		"[0] Tyler WORKS_AT Anthropic",
+		`[1] Tyler BORN_ON "1990-05-01"`,
+		"Tyler: I just started at Anthropic",
+		`"verdicts"`,
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q", want)
+		}
+	}
+}
+
+func TestCritiqueNoRelationships(t *testing.T) {
+	critic := NewRelationshipCritic(nil, "", 0)
+	result, err := critic.Critique(context.Background(), CritiqueInput{EpisodeContent: "hi"})
+	if err != nil || len(result.Relationships) != 0 {
+		t.Errorf("Critique = %+v, %v", result, err)
+	}
+}
+
+func TestEdgeResolver_UsesCritiqueConfidence(t *testing.T) {
+	db := setupEdgeResolverTestDB(t)
+	defer db.Close()
+
+	insertEdgeResolverTestEntity(t, db, "entity-tyler", "Tyler", EntityTypePerson)
+	insertEdgeResolverTestEntity(t, db, "entity-anthropic", "Anthropic", EntityTypeCompany)
+	insertEdgeResolverTestEpisode(t, db, "episode-1")
+
+	resolvedEntities := []ResolvedEntity{
+		{ID: "entity-tyler", Name: "Tyler", EntityTypeID: EntityTypePerson},
+		{ID: "entity-anthropic", Name: "Anthropic", EntityTypeID: EntityTypeCompany},
+	}
+	target := 1
+	conf := 0.7
+	rels := []ExtractedRelationship{{
+		SourceEntityID: 0,
+		RelationType:   "WORKS_AT",
+		TargetEntityID: &target,
+		Fact:           "Tyler works at Anthropic",
+		SourceType:     "mentioned",
+		Confidence:     &conf,
+	}}
+
+	if _, err := NewEdgeResolver(db).Resolve(context.Background(), "episode-1", rels, resolvedEntities); err != nil {
+		t.Fatalf("Resolve error: %v", err)
+	}
+
+	var relConf, mentionConf float64
+	if err := db.QueryRow(`SELECT confidence FROM relationships`).Scan(&relConf); err != nil {
+		t.Fatalf("query relationship: %v", err)
+	}
+	if err := db.QueryRow(`SELECT confidence FROM episode_relationship_mentions`).Scan(&mentionConf); err != nil {
+		t.Fatalf("query mention: %v", err)
+	}
+	if relConf != 0.7 || mentionConf != 0.7 {
+		t.Errorf("confidence = %v/%v, want 0.7", relConf, mentionConf)
+	}
+}
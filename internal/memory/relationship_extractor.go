@@ -21,6 +21,8 @@ type ExtractedRelationship struct {
 	SourceType     string  `json:"source_type"`      // 'self_disclosed', 'mentioned', 'inferred'
 	ValidAt        *string `json:"valid_at"`         // ISO 8601 date when became true (optional)
 	InvalidAt      *string `json:"invalid_at"`       // ISO 8601 date when stopped being true (optional)
+	// Set by the self-critique pass; nil means unreviewed (stored as 1.0)
+	Confidence *float64 `json:"confidence,omitempty"`
 }
 
 // RelationshipExtractionResult contains the output from relationship extraction.
@@ -35,6 +37,9 @@ type RelationshipExtractionInput struct {
 	ReferenceTime    string           // ISO 8601 timestamp for temporal reference
 	PreviousEpisodes []string         // Optional: previous episodes for coreference context
 	CustomInstructions string         // Optional: domain-specific extraction guidance
+	UserNotes          []string       // Optional: notes the user attached to the episode
+	Model              string         // Optional: overrides the extractor's model (e.g. from a ModelRouter)
+	RelationTypes      *RelationTypePolicy // Optional: the channel's allowed/blocked relation types
 }
 
 // ResolvedEntityForPrompt is the structure passed to the LLM prompt.
@@ -52,6 +57,7 @@ type ResolvedEntityForPrompt struct {
 type RelationshipExtractor struct {
 	geminiClient *gemini.Client
 	model        string
+	params       GenerationParams
 }
 
 // NewRelationshipExtractor creates a new RelationshipExtractor.
@@ -65,6 +71,11 @@ func NewRelationshipExtractor(geminiClient *gemini.Client, model string) *Relati
 	}
 }
 
+// SetGenerationParams sets the sampling settings for extraction calls.
+func (e *RelationshipExtractor) SetGenerationParams(params GenerationParams) {
+	e.params = params
+}
+
 // Extract extracts relationships from episode content.
 // The resolved entities are passed in with their UUIDs, and relationships
 // reference them via temporary IDs (0, 1, 2...).
@@ -85,15 +96,18 @@ func (e *RelationshipExtractor) Extract(ctx context.Context, input RelationshipE
 			Role:  "user",
 			Parts: []gemini.Part{{Text: prompt}},
 		}},
-		GenerationConfig: &gemini.GenerationConfig{
-			ResponseMimeType: "application/json",
-		},
+		GenerationConfig: e.params.generationConfig(),
 	}
 
-	resp, err := e.geminiClient.GenerateContent(ctx, e.model, req)
+	model := e.model
+	if input.Model != "" {
+		model = input.Model
+	}
+	resp, err := e.geminiClient.GenerateContent(ctx, model, req)
 	if err != nil {
 		return nil, fmt.Errorf("generate content: %w", err)
 	}
+	recordLLMUsage(ctx, model, resp.UsageMetadata)
 
 	text := strings.TrimSpace(extractTextFromResponse(resp))
 	if text == "" {
@@ -116,13 +130,16 @@ func (e *RelationshipExtractor) Extract(ctx context.Context, input RelationshipE
 	}
 
 	// Validate extracted relationships
-	result.ExtractedRelationships = e.validateRelationships(result.ExtractedRelationships, len(input.ResolvedEntities))
+	result.ExtractedRelationships = e.validateRelationships(result.ExtractedRelationships, len(input.ResolvedEntities), input.RelationTypes)
+	// Identifiers must be copied from the episode, not invented or planted
+	result.ExtractedRelationships = groundIdentityLiterals(result.ExtractedRelationships, input.EpisodeContent)
 
 	return &result, nil
 }
 
 // validateRelationships validates and filters extracted relationships.
-func (e *RelationshipExtractor) validateRelationships(rels []ExtractedRelationship, entityCount int) []ExtractedRelationship {
+// Relation types the channel's policy (if any) does not permit are dropped.
+func (e *RelationshipExtractor) validateRelationships(rels []ExtractedRelationship, entityCount int, policy *RelationTypePolicy) []ExtractedRelationship {
 	valid := make([]ExtractedRelationship, 0, len(rels))
 
 	for _, rel := range rels {
@@ -135,6 +152,9 @@ func (e *RelationshipExtractor) validateRelationships(rels []ExtractedRelationsh
 		if rel.RelationType == "" {
 			continue
 		}
+		if policy != nil && !policy.Permits(rel.RelationType) {
+			continue
+		}
 
 		// Validate exactly one of target_entity_id or target_literal is set
 		hasTargetEntity := rel.TargetEntityID != nil
@@ -142,7 +162,7 @@ func (e *RelationshipExtractor) validateRelationships(rels []ExtractedRelationsh
 
 		if hasTargetEntity && hasTargetLiteral {
 			// Both set - prefer entity for non-identity types, literal for identity types
-			if isIdentityRelationType(rel.RelationType) || isTemporalRelationType(rel.RelationType) {
+			if IsLiteralTargetRelationType(rel.RelationType) {
 				rel.TargetEntityID = nil
 			} else {
 				rel.TargetLiteral = nil
@@ -208,9 +228,9 @@ func isValidSourceType(sourceType string) bool {
 }
 
 // IsLiteralTargetRelationType returns true if the relation type uses target_literal.
-// This includes both identity and temporal relationship types.
+// This includes identity and temporal relationship types, and sizes.
 func IsLiteralTargetRelationType(relType string) bool {
-	return isIdentityRelationType(relType) || isTemporalRelationType(relType)
+	return isIdentityRelationType(relType) || isTemporalRelationType(relType) || relType == "HAS_SIZE"
 }
 
 // buildPrompt constructs the extraction prompt from the template.
@@ -238,7 +258,7 @@ func (e *RelationshipExtractor) buildPrompt(input RelationshipExtractionInput) s
 	if len(input.PreviousEpisodes) > 0 {
 		sb.WriteString("<PREVIOUS_EPISODES>\n")
 		for _, ep := range input.PreviousEpisodes {
-			sb.WriteString(ep)
+			sb.WriteString(sanitizePromptContent(ep))
 			sb.WriteString("\n---\n")
 		}
 		sb.WriteString("</PREVIOUS_EPISODES>\n\n")
@@ -246,8 +266,11 @@ func (e *RelationshipExtractor) buildPrompt(input RelationshipExtractionInput) s
 
 	// Current episode
 	sb.WriteString("<CURRENT_EPISODE>\n")
-	sb.WriteString(input.EpisodeContent)
+	sb.WriteString(sanitizePromptContent(input.EpisodeContent))
 	sb.WriteString("\n</CURRENT_EPISODE>\n\n")
+	sb.WriteString(userNotesSection(input.UserNotes))
+
+	sb.WriteString(untrustedContentRules)
 
 	// Instructions
 	sb.WriteString(`## Instructions
@@ -284,6 +307,7 @@ These relationship types use target_literal instead of target_entity_id:
 | **Temporal** | SCHEDULED_FOR | 2026-01-25 | No |
 | **Temporal** | STARTED_ON | 2024-01 | No |
 | **Temporal** | ENDED_ON | 2025-12 | No |
+| **Preference** | HAS_SIZE | shoe 9, shirt M, ring 6 | No |
 
 **Date format:** ISO 8601 — YYYY-MM-DD (full date), YYYY-MM (month), or YYYY (year).
 
@@ -316,6 +340,7 @@ All other relationships point to entities:
 **Direction semantics for hierarchical relationships:**
 - PARENT_OF: source is the parent of target (e.g., "Alice is the parent of Bob" → Alice --PARENT_OF--> Bob)
 - CHILD_OF: source is the child of target (e.g., "Bob is the child of Alice" → Bob --CHILD_OF--> Alice)
+- State each fact once. Inverses are implied: Alice --PARENT_OF--> Bob already means Bob --CHILD_OF--> Alice, and Sam --WORKS_AT--> Acme means Acme employs Sam. Symmetric types (KNOWS, FRIEND_OF, SPOUSE_OF, MARRIED_TO, DATING, SIBLING_OF) need only one direction.
 | Legal | SUED_BY, DEFENDANT_IN, PLAINTIFF_IN | Person or Organization |
 | Legal | FILED_BANKRUPTCY_IN | Location (court jurisdiction) |
 | Projects | CREATED, BUILDING, WORKING_ON, CONTRIBUTED_TO | Project |
@@ -323,6 +348,20 @@ All other relationships point to entities:
 | Location | LOCATED_IN, VISITED | Location |
 | Content | AUTHORED, REFERENCES | Document |
 | Financial | WIRED_TO, RECEIVED_FROM | Person or Company |
+| Preference | PREFERS, DISLIKES, WANTS | Any entity, or target_literal for things that aren't entities ("dark chocolate") |
+
+**Preferences:** Extract stated likes (PREFERS), dislikes (DISLIKES), and things
+someone wants or has on a wishlist (WANTS), plus clothing and other sizes
+(HAS_SIZE). Keep the target specific ("Le Labo Santal 33", not "perfume").
+Only extract lasting tastes, not one-off choices ("I'll have the salmon").
+
+### Relationships Between Third Parties
+
+Extract facts between ANY two entities, not just those involving the speakers.
+People often describe others: "Casey's sister Dana lives in Denver" yields
+Dana --SIBLING_OF--> Casey and Dana --LIVES_IN--> Denver, even if neither Casey
+nor Dana is in the conversation. Attribute each fact to the entity it is about,
+not to the speaker who said it; such facts are usually source_type "mentioned".
 
 ### Required Fields
 
@@ -352,6 +391,8 @@ Use REFERENCE_TIME to resolve relative dates ("last month" = month before refere
 - **mentioned**: Someone else mentioned this fact about the source entity
 - **inferred**: The fact is implied but not explicitly stated
 
+Lines marked "(draft, not sent)" are things the user plans to say or send but has not. Never extract a fact from a draft as something the recipient said, was told, or knows.
+
 `)
 
 	// Custom instructions
@@ -360,6 +401,14 @@ Use REFERENCE_TIME to resolve relative dates ("last month" = month before refere
 		sb.WriteString("\n\n")
 	}
 
+	// Channel relation type policy
+	if input.RelationTypes != nil {
+		if section := input.RelationTypes.promptSection(); section != "" {
+			sb.WriteString(section)
+			sb.WriteString("\n")
+		}
+	}
+
 	// Output schema
 	sb.WriteString(`## Output Schema
 
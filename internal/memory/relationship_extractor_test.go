@@ -2,7 +2,10 @@ package memory
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/config"
 )
 
 func TestRelationshipExtractionResultParsing(t *testing.T) {
@@ -138,6 +141,7 @@ func TestValidateRelationships(t *testing.T) {
 		input       []ExtractedRelationship
 		entityCount int
 		wantCount   int
+		policy      *RelationTypePolicy
 	}{
 		{
 			name: "valid relationships pass through",
@@ -322,11 +326,31 @@ func TestValidateRelationships(t *testing.T) {
 			entityCount: 2,
 			wantCount:   1,
 		},
+		{
+			name: "blocked relation type dropped",
+			input: []ExtractedRelationship{
+				{SourceEntityID: 0, RelationType: "HAS_EMAIL", TargetLiteral: strPtr("user@example.com"), Fact: "Example email", SourceType: "mentioned"},
+				{SourceEntityID: 0, RelationType: "WORKS_AT", TargetEntityID: intPtr(1), Fact: "Tyler works at Anthropic", SourceType: "self_disclosed"},
+			},
+			entityCount: 2,
+			wantCount:   1,
+			policy:      &RelationTypePolicy{Blocked: []string{"HAS_EMAIL", "HAS_PHONE"}},
+		},
+		{
+			name: "only allowed relation types kept",
+			input: []ExtractedRelationship{
+				{SourceEntityID: 0, RelationType: "WORKS_AT", TargetEntityID: intPtr(1), Fact: "Tyler works at Anthropic", SourceType: "self_disclosed"},
+				{SourceEntityID: 0, RelationType: "KNOWS", TargetEntityID: intPtr(1), Fact: "Tyler knows Anthropic", SourceType: "mentioned"},
+			},
+			entityCount: 2,
+			wantCount:   1,
+			policy:      &RelationTypePolicy{Allowed: []string{"works_at"}},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := extractor.validateRelationships(tt.input, tt.entityCount)
+			result := extractor.validateRelationships(tt.input, tt.entityCount, tt.policy)
 			if len(result) != tt.wantCount {
 				t.Errorf("Expected %d relationships, got %d", tt.wantCount, len(result))
 			}
@@ -489,6 +513,9 @@ func TestBuildPrompt(t *testing.T) {
 	if !contains(prompt, "target_entity_id") {
 		t.Error("Prompt should mention target_entity_id")
 	}
+	if !contains(prompt, "Relationships Between Third Parties") {
+		t.Error("Prompt should ask for relationships between third parties")
+	}
 }
 
 func TestBuildPromptWithPreviousEpisodes(t *testing.T) {
@@ -618,3 +645,76 @@ func TestGetTargetEntityUUID(t *testing.T) {
 }
 
 // Note: contains helper is defined in entity_extractor_test.go
+
+func TestRelationTypePolicyPrompt(t *testing.T) {
+	extractor := NewRelationshipExtractor(nil, "")
+	input := RelationshipExtractionInput{
+		EpisodeContent:   "call me at 555-0100",
+		ResolvedEntities: []ResolvedEntity{{ID: "u1", Name: "Tyler", EntityTypeID: EntityTypePerson}},
+	}
+	if prompt := extractor.buildPrompt(input); strings.Contains(prompt, "Relation Types for This Channel") {
+		t.Error("prompt without a policy mentions one")
+	}
+
+	input.RelationTypes = &RelationTypePolicy{Allowed: []string{"WORKS_AT", "HAS_PHONE"}, Blocked: []string{"has_phone"}}
+	prompt := extractor.buildPrompt(input)
+	if !strings.Contains(prompt, "Only extract these relation types: WORKS_AT.") {
+		t.Error("prompt does not list the allowed types (minus blocked ones)")
+	}
+	if !strings.Contains(prompt, "Do not extract these relation types: HAS_PHONE.") {
+		t.Error("prompt does not list the blocked types")
+	}
+}
+
+func TestRelationTypePoliciesFromConfig(t *testing.T) {
+	if policy := DefaultRelationTypePolicies()["nexus_agent"]; policy.Permits("HAS_EMAIL") {
+		t.Error("nexus agent transcripts extract emails by default")
+	}
+
+	pc, err := NewPipelineConfig(config.MemoryConfig{RelationTypes: map[string]config.RelationTypesConfig{
+		"codex": {},
+		"slack": {Blocked: []string{"has_phone"}},
+	}})
+	if err != nil {
+		t.Fatalf("NewPipelineConfig: %v", err)
+	}
+	if !pc.RelationTypePolicies["codex"].Permits("HAS_EMAIL") {
+		t.Error("an empty entry did not lift the default policy")
+	}
+	if pc.RelationTypePolicies["slack"].Permits("HAS_PHONE") || !pc.RelationTypePolicies["slack"].Permits("WORKS_AT") {
+		t.Errorf("slack policy = %+v", pc.RelationTypePolicies["slack"])
+	}
+	if pc.RelationTypePolicies["cursor"].Permits("HAS_EMAIL") {
+		t.Error("unlisted channels lost their default policy")
+	}
+
+	if _, err := NewPipelineConfig(config.MemoryConfig{RelationTypes: map[string]config.RelationTypesConfig{
+		"slack": {Allowed: []string{"works at"}},
+	}}); err == nil {
+		t.Error("invalid relation type accepted")
+	}
+}
+
+func TestGroundIdentityLiterals(t *testing.T) {
+	content := "Tyler: text me at (555) 010-0199 or tyler@example.com, I'm @tbrandt everywhere"
+	lit := func(s string) *string { return &s }
+	target := 1
+	rels := []ExtractedRelationship{
+		{RelationType: "HAS_PHONE", TargetLiteral: lit("+1 555-010-0199")},
+		{RelationType: "HAS_PHONE", TargetLiteral: lit("+1 555-666-0000")},
+		{RelationType: "HAS_EMAIL", TargetLiteral: lit("Tyler@Example.com")},
+		{RelationType: "HAS_EMAIL", TargetLiteral: lit("attacker@evil.test")},
+		{RelationType: "HAS_HANDLE", TargetLiteral: lit("@tbrandt")},
+		{RelationType: "BORN_ON", TargetLiteral: lit("1990-04-12")},
+		{RelationType: "KNOWS", TargetEntityID: &target},
+	}
+	grounded := groundIdentityLiterals(rels, content)
+	if len(grounded) != 5 {
+		t.Fatalf("kept %d relationships, want 5: %+v", len(grounded), grounded)
+	}
+	for _, rel := range grounded {
+		if rel.TargetLiteral != nil && (strings.Contains(*rel.TargetLiteral, "666") || strings.Contains(*rel.TargetLiteral, "evil")) {
+			t.Errorf("kept planted identifier %s", *rel.TargetLiteral)
+		}
+	}
+}
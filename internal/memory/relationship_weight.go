@@ -0,0 +1,186 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+)
+
+// WeightHalfLife is how long until a mention counts half as much toward a
+// relationship's weight.
+const WeightHalfLife = 180 * 24 * time.Hour
+
+// sourceTypeWeights scores how directly a mention asserts the relationship.
+var sourceTypeWeights = map[string]float64{
+	"self_disclosed": 1.0,
+	"mentioned":      0.7,
+	"inferred":       0.4,
+}
+
+// defaultSourceTypeWeight applies to mentions with no or unknown source type.
+const defaultSourceTypeWeight = 0.55
+
+// WeightMention is one piece of evidence for a relationship.
+type WeightMention struct {
+	SourceType string
+	Confidence float64
+	At         time.Time
+}
+
+// ComputeRelationshipWeight scores a relationship from 0 to 1. Each mention
+// contributes its source-type weight times its confidence, decayed by age
+// with WeightHalfLife; contributions add up and saturate, so frequently and
+// recently self-disclosed relationships approach 1.
+func ComputeRelationshipWeight(mentions []WeightMention, now time.Time) float64 {
+	var total float64
+	for _, m := range mentions {
+		sourceWeight, ok := sourceTypeWeights[m.SourceType]
+		if !ok {
+			sourceWeight = defaultSourceTypeWeight
+		}
+		confidence := m.Confidence
+		if confidence <= 0 || confidence > 1 {
+			confidence = 1
+		}
+		decay := 1.0
+		if age := now.Sub(m.At); !m.At.IsZero() && age > 0 {
+			decay = math.Pow(0.5, float64(age)/float64(WeightHalfLife))
+		}
+		total += sourceWeight * confidence * decay
+	}
+	return 1 - math.Exp(-total)
+}
+
+// RelationshipWeigher maintains relationships.weight.
+type RelationshipWeigher struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+// NewRelationshipWeigher creates a new RelationshipWeigher.
+func NewRelationshipWeigher(db *sql.DB) *RelationshipWeigher {
+	return &RelationshipWeigher{db: db, now: time.Now}
+}
+
+// RefreshEpisode recomputes weights for relationships mentioned in an episode.
+func (w *RelationshipWeigher) RefreshEpisode(ctx context.Context, episodeID string) error {
+	rows, err := w.db.QueryContext(ctx, `
+		SELECT DISTINCT relationship_id FROM episode_relationship_mentions
+		WHERE episode_id = ? AND relationship_id IS NOT NULL
+	`, episodeID)
+	if err != nil {
+		return fmt.Errorf("query episode relationships: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan relationship id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return w.RefreshRelationships(ctx, ids)
+}
+
+// RefreshRelationships recomputes weights for the given relationships.
+func (w *RelationshipWeigher) RefreshRelationships(ctx context.Context, relationshipIDs []string) error {
+	for _, chunk := range chunkIDs(uniqueIDs(relationshipIDs), maxBatchIDs) {
+		if err := w.refresh(ctx, `WHERE r.id IN (`+placeholderList(len(chunk))+`)`, idArgs(chunk)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RefreshAll recomputes every relationship's weight so recency decay stays
+// current (e.g., from nightly maintenance). Returns the number updated.
+func (w *RelationshipWeigher) RefreshAll(ctx context.Context) (int, error) {
+	var count int
+	if err := w.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM relationships`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count relationships: %w", err)
+	}
+	if err := w.refresh(ctx, "", nil); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// refresh loads mentions for relationships matching the where clause and
+// writes their weights. A relationship with no mentions (e.g., manual or
+// imported) is weighted as a single mention at its creation time.
+func (w *RelationshipWeigher) refresh(ctx context.Context, where string, args []interface{}) error {
+	rows, err := w.db.QueryContext(ctx, `
+		SELECT r.id, r.created_at, r.confidence,
+		       erm.source_type, erm.confidence, ep.end_time, erm.created_at
+		FROM relationships r
+		LEFT JOIN episode_relationship_mentions erm ON erm.relationship_id = r.id
+		LEFT JOIN episodes ep ON ep.id = erm.episode_id
+		`+where+`
+		ORDER BY r.id
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("query relationship mentions: %w", err)
+	}
+
+	mentions := make(map[string][]WeightMention)
+	var order []string
+	for rows.Next() {
+		var (
+			relID, relCreated       string
+			relConfidence           sql.NullFloat64
+			sourceType, mentionedAt sql.NullString
+			mentionConfidence       sql.NullFloat64
+			episodeEnd              sql.NullInt64
+		)
+		if err := rows.Scan(&relID, &relCreated, &relConfidence, &sourceType, &mentionConfidence, &episodeEnd, &mentionedAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan relationship mention: %w", err)
+		}
+		if _, ok := mentions[relID]; !ok {
+			order = append(order, relID)
+			mentions[relID] = nil
+		}
+		if !mentionedAt.Valid {
+			at, _ := time.Parse(time.RFC3339, relCreated)
+			mentions[relID] = append(mentions[relID], WeightMention{Confidence: relConfidence.Float64, At: at})
+			continue
+		}
+		// Prefer when the conversation happened over when it was processed
+		m := WeightMention{SourceType: sourceType.String, Confidence: mentionConfidence.Float64}
+		if episodeEnd.Valid && episodeEnd.Int64 > 0 {
+			m.At = time.Unix(episodeEnd.Int64, 0)
+		} else {
+			m.At, _ = time.Parse(time.RFC3339, mentionedAt.String)
+		}
+		mentions[relID] = append(mentions[relID], m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := w.now()
+	for _, id := range order {
+		weight := ComputeRelationshipWeight(mentions[id], now)
+		if _, err := tx.ExecContext(ctx, `UPDATE relationships SET weight = ? WHERE id = ?`, weight, id); err != nil {
+			return fmt.Errorf("update weight for %s: %w", id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
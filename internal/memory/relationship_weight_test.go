@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestComputeRelationshipWeight(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := now.Add(-24 * time.Hour)
+	old := now.Add(-2 * 365 * 24 * time.Hour)
+
+	weight := func(mentions ...WeightMention) float64 {
+		return ComputeRelationshipWeight(mentions, now)
+	}
+	selfDisclosed := weight(WeightMention{SourceType: "self_disclosed", Confidence: 1, At: recent})
+	mentioned := weight(WeightMention{SourceType: "mentioned", Confidence: 1, At: recent})
+	inferred := weight(WeightMention{SourceType: "inferred", Confidence: 1, At: recent})
+	if !(selfDisclosed > mentioned && mentioned > inferred) {
+		t.Errorf("source types: self %.3f, mentioned %.3f, inferred %.3f", selfDisclosed, mentioned, inferred)
+	}
+
+	stale := weight(WeightMention{SourceType: "self_disclosed", Confidence: 1, At: old})
+	if stale >= selfDisclosed/4 {
+		t.Errorf("two-year-old mention %.3f should decay well below %.3f", stale, selfDisclosed)
+	}
+
+	repeated := weight(
+		WeightMention{SourceType: "mentioned", Confidence: 1, At: recent},
+		WeightMention{SourceType: "mentioned", Confidence: 1, At: recent},
+		WeightMention{SourceType: "mentioned", Confidence: 1, At: recent},
+	)
+	if repeated <= mentioned || repeated >= 1 {
+		t.Errorf("three mentions = %.3f, want between %.3f and 1", repeated, mentioned)
+	}
+
+	if w := weight(); w != 0 {
+		t.Errorf("no mentions = %.3f, want 0", w)
+	}
+}
+
+func TestRelationshipWeigher_RanksRelatedEntities(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, ent := range [][2]string{{"tyler", "Tyler"}, {"acme", "Acme"}, {"casey", "Casey"}, {"sam", "Sam"}} {
+		db.Exec(`INSERT INTO entities (id, canonical_name, entity_type_id, origin, created_at, updated_at) VALUES (?, ?, 1, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')`, ent[0], ent[1])
+	}
+	for _, rel := range [][3]string{{"r-acme", "acme", "WORKS_AT"}, {"r-casey", "casey", "KNOWS"}, {"r-sam", "sam", "KNOWS"}} {
+		db.Exec(`INSERT INTO relationships (id, source_entity_id, target_entity_id, relation_type, fact, created_at) VALUES (?, 'tyler', ?, ?, 'fact', '2025-12-01T00:00:00Z')`, rel[0], rel[1], rel[2])
+	}
+
+	db.Exec(`INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0)`)
+	episodes := map[string]time.Time{
+		"ep-recent": time.Date(2025, 12, 30, 0, 0, 0, 0, time.UTC),
+		"ep-old":    time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for id, at := range episodes {
+		db.Exec(`INSERT INTO episodes (id, definition_id, start_time, end_time, event_count, created_at) VALUES (?, 'def', ?, ?, 1, 0)`, id, at.Unix(), at.Unix())
+	}
+	mention := func(id, episodeID, relID, sourceType string) {
+		if _, err := db.Exec(`INSERT INTO episode_relationship_mentions (id, episode_id, relationship_id, extracted_fact, source_type, confidence, created_at) VALUES (?, ?, ?, 'fact', ?, 0.9, '2026-01-01T00:00:00Z')`, id, episodeID, relID, sourceType); err != nil {
+			t.Fatalf("insert mention: %v", err)
+		}
+	}
+	// Casey: frequent and recent. Acme: recent but inferred. Sam: self-disclosed long ago.
+	mention("m1", "ep-recent", "r-casey", "mentioned")
+	mention("m2", "ep-old", "r-casey", "mentioned")
+	mention("m3", "ep-recent", "r-acme", "inferred")
+	mention("m4", "ep-old", "r-sam", "self_disclosed")
+
+	weigher := NewRelationshipWeigher(db)
+	weigher.now = func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) }
+	if err := weigher.RefreshEpisode(ctx, "ep-recent"); err != nil {
+		t.Fatalf("RefreshEpisode: %v", err)
+	}
+	var samWeight float64
+	db.QueryRow(`SELECT weight FROM relationships WHERE id = 'r-sam'`).Scan(&samWeight)
+	if samWeight != 0 {
+		t.Errorf("r-sam not in ep-recent but weight = %.3f", samWeight)
+	}
+
+	if n, err := weigher.RefreshAll(ctx); err != nil || n != 3 {
+		t.Fatalf("RefreshAll = %d, %v", n, err)
+	}
+
+	related, err := NewQueryEngine(db).GetRelatedEntities(ctx, "tyler", DefaultQueryOptions())
+	if err != nil {
+		t.Fatalf("GetRelatedEntities: %v", err)
+	}
+	if len(related) != 3 || related[0].ID != "casey" || related[1].ID != "acme" || related[2].ID != "sam" {
+		t.Fatalf("related = %+v, want casey, acme, sam", related)
+	}
+	if related[0].Weight <= related[1].Weight || related[2].Weight <= 0 {
+		t.Errorf("weights = %.3f, %.3f, %.3f", related[0].Weight, related[1].Weight, related[2].Weight)
+	}
+
+	// Current facts come back strongest first too
+	if _, err := NewCurrentFactsStore(db).RefreshAll(ctx); err != nil {
+		t.Fatalf("refresh current facts: %v", err)
+	}
+	facts, err := NewCurrentFactsStore(db).GetCurrentFacts(ctx, "tyler")
+	if err != nil {
+		t.Fatalf("GetCurrentFacts: %v", err)
+	}
+	if len(facts) != 2 || facts[0].RelationType != "KNOWS" || facts[0].Weight <= facts[1].Weight {
+		t.Errorf("facts = %+v, want KNOWS first", facts)
+	}
+}
@@ -0,0 +1,244 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Napageneral/mnemonic/internal/chunk"
+)
+
+// DefaultReplayMinScore is the embedding similarity (cosine scaled to 0-1,
+// as in search) an episode needs to be replayed by an embedding prefilter.
+const DefaultReplayMinScore = 0.8
+
+// replayMatchContext is how much text around a keyword match a candidate
+// keeps, so the user can see why it was picked.
+const replayMatchContext = 40
+
+// EpisodeTextFunc returns the text extraction sees for an episode.
+type EpisodeTextFunc func(ctx context.Context, episodeID string) (string, error)
+
+// ReplayOptions selects the episodes a targeted replay re-extracts. At
+// least one prefilter (Filter or QueryEmbedding) is required; an episode
+// matching either is a candidate.
+type ReplayOptions struct {
+	// Filter is a regular expression, matched case-insensitively against
+	// the episode text (e.g. "vegan|allerg").
+	Filter string
+	// QueryEmbedding selects episodes whose embedding is within MinScore
+	// of it (default DefaultReplayMinScore).
+	QueryEmbedding []float64
+	EmbeddingModel string // default DefaultEmbeddingModel
+	MinScore       float64
+	// Channel limits the replay to one channel.
+	Channel string
+	// Since skips episodes that started before it.
+	Since time.Time
+	// Limit caps the candidates, oldest first (0 = all).
+	Limit int
+}
+
+// ReplayCandidate is an episode a prefilter picked for replay.
+type ReplayCandidate struct {
+	EpisodeID string  `json:"episode_id"`
+	Channel   string  `json:"channel"`
+	ThreadID  string  `json:"thread_id,omitempty"`
+	StartTime int64   `json:"start_time"`
+	EndTime   int64   `json:"end_time"`
+	Match     string  `json:"match,omitempty"` // text around the keyword match
+	Score     float64 `json:"score,omitempty"` // embedding similarity
+}
+
+// ReplayResult summarizes a targeted replay.
+type ReplayResult struct {
+	RelationType     string            `json:"relation_type"`
+	Scanned          int               `json:"scanned"`
+	Candidates       []ReplayCandidate `json:"candidates"`
+	Processed        int               `json:"processed"`
+	Failed           int               `json:"failed"`
+	NewRelationships int               `json:"new_relationships"`
+	Facts            []string          `json:"facts,omitempty"` // facts of the type extracted, new or known
+	CostUSD          float64           `json:"cost_usd"`
+}
+
+// FindReplayCandidates returns the processed episodes likely to mention a
+// relation type: those whose text matches the keyword filter or whose
+// embedding is close to the query embedding. Only episodes extracted
+// successfully before, of their channel's primary definition, are
+// considered; new episodes pick up the relation type when first extracted.
+// Also returns how many episodes were scanned.
+func FindReplayCandidates(ctx context.Context, db *sql.DB, opts ReplayOptions, text EpisodeTextFunc) ([]ReplayCandidate, int, error) {
+	if opts.Filter == "" && len(opts.QueryEmbedding) == 0 {
+		return nil, 0, fmt.Errorf("replay needs a keyword filter or a query embedding")
+	}
+	var filter *regexp.Regexp
+	if opts.Filter != "" {
+		var err error
+		if filter, err = regexp.Compile("(?i)" + opts.Filter); err != nil {
+			return nil, 0, fmt.Errorf("invalid filter: %w", err)
+		}
+	}
+	model := opts.EmbeddingModel
+	if model == "" {
+		model = DefaultEmbeddingModel
+	}
+	minScore := opts.MinScore
+	if minScore <= 0 {
+		minScore = DefaultReplayMinScore
+	}
+
+	query := `
+		SELECT ep.id, ep.channel, COALESCE(ep.thread_id, ''), ep.start_time, ep.end_time,
+		       (SELECT em.embedding_blob FROM embeddings em
+		        WHERE em.target_type = 'episode' AND em.target_id = ep.id AND em.model = ?)
+		FROM episodes ep
+		JOIN episode_processing pr ON pr.episode_id = ep.id AND pr.status = ?
+		WHERE ` + chunk.PrimaryEpisodeCondition("ep")
+	args := []any{model, ProcessingStatusOK}
+	if opts.Channel != "" {
+		query += ` AND ep.channel = ?`
+		args = append(args, opts.Channel)
+	}
+	if !opts.Since.IsZero() {
+		query += ` AND ep.start_time >= ?`
+		args = append(args, opts.Since.Unix())
+	}
+	query += ` ORDER BY ep.start_time, ep.id`
+
+	type episodeRow struct {
+		ReplayCandidate
+		embedding []byte
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list processed episodes: %w", err)
+	}
+	var episodes []episodeRow
+	for rows.Next() {
+		var ep episodeRow
+		if err := rows.Scan(&ep.EpisodeID, &ep.Channel, &ep.ThreadID, &ep.StartTime, &ep.EndTime, &ep.embedding); err != nil {
+			rows.Close()
+			return nil, 0, fmt.Errorf("scan episode: %w", err)
+		}
+		episodes = append(episodes, ep)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var candidates []ReplayCandidate
+	for _, ep := range episodes {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		c := ep.ReplayCandidate
+		matched := false
+		if len(opts.QueryEmbedding) > 0 && len(ep.embedding) > 0 {
+			embedding := blobToFloat64Slice(ep.embedding)
+			if len(embedding) == len(opts.QueryEmbedding) {
+				if score := normalizeCosine(cosineSimilarity(opts.QueryEmbedding, embedding)); score >= minScore {
+					c.Score = score
+					matched = true
+				}
+			}
+		}
+		if filter != nil {
+			content, err := text(ctx, ep.EpisodeID)
+			if err != nil {
+				return nil, 0, fmt.Errorf("episode %s text: %w", ep.EpisodeID, err)
+			}
+			if loc := filter.FindStringIndex(content); loc != nil {
+				c.Match = matchContext(content, loc[0], loc[1])
+				matched = true
+			}
+		}
+		if !matched {
+			continue
+		}
+		candidates = append(candidates, c)
+		if opts.Limit > 0 && len(candidates) >= opts.Limit {
+			break
+		}
+	}
+	return candidates, len(episodes), nil
+}
+
+// matchContext returns the text around content[start:end] on one line.
+func matchContext(content string, start, end int) string {
+	from, to := max(0, start-replayMatchContext), min(len(content), end+replayMatchContext)
+	// Don't cut a multi-byte character in half
+	for from > 0 && !utf8.RuneStart(content[from]) {
+		from--
+	}
+	for to < len(content) && !utf8.RuneStart(content[to]) {
+		to++
+	}
+	snippet := strings.Join(strings.Fields(content[from:to]), " ")
+	if from > 0 {
+		snippet = "…" + snippet
+	}
+	if to < len(content) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// Replay re-extracts the candidate episodes for one relation type, e.g.
+// HAS_DIETARY_RESTRICTION after adding it to the ontology. The
+// episodes' earlier extraction is not undone: entities are resolved again
+// (reusing existing ones) and only relationships of the relation type are
+// kept, so the replay adds the new facts without duplicating the rest.
+// An episode that fails is counted and the replay goes on.
+func (p *MemoryPipeline) Replay(ctx context.Context, relationType string, candidates []ReplayCandidate, text EpisodeTextFunc) (*ReplayResult, error) {
+	relationType = strings.ToUpper(strings.TrimSpace(relationType))
+	if !relationTypePattern.MatchString(relationType) {
+		return nil, fmt.Errorf("invalid relation type %q (want SCREAMING_SNAKE_CASE, e.g. WORKS_AT)", relationType)
+	}
+	result := &ReplayResult{RelationType: relationType, Candidates: candidates}
+	policy := RelationTypePolicy{Allowed: []string{relationType}}
+	for _, c := range candidates {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		content, err := text(ctx, c.EpisodeID)
+		if err != nil {
+			return result, fmt.Errorf("episode %s text: %w", c.EpisodeID, err)
+		}
+		input := EpisodeInput{
+			ID:                c.EpisodeID,
+			Channel:           c.Channel,
+			Content:           content,
+			StartTime:         time.Unix(c.StartTime, 0),
+			ReferenceTime:     time.Unix(c.EndTime, 0).UTC().Format(time.RFC3339),
+			IgnoreQualityGate: true,
+			Replay:            true,
+			RelationTypes:     &policy,
+		}
+		if c.ThreadID != "" {
+			threadID := c.ThreadID
+			input.ThreadID = &threadID
+		}
+		res, err := p.Process(ctx, input)
+		if res != nil {
+			result.CostUSD += res.CostUSD
+		}
+		if err != nil {
+			result.Failed++
+			continue
+		}
+		result.Processed++
+		result.NewRelationships += res.NewRelationships
+		for _, rel := range res.ExtractedRelationships {
+			if rel.RelationType == relationType && rel.Fact != "" {
+				result.Facts = append(result.Facts, rel.Fact)
+			}
+		}
+	}
+	return result, nil
+}
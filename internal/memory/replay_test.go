@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestFindReplayCandidates(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.Exec(`
+		INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES
+			('def', 'time_gap', 'thread', '{}', 0, 0), ('other', 'daily', 'thread', '{}', 0, 0);
+		INSERT INTO episode_primary_definitions (channel, definition_id, updated_at) VALUES ('imessage', 'def', 0);
+		INSERT INTO episodes (id, definition_id, channel, start_time, end_time, event_count, created_at) VALUES
+			('ep-vegan', 'def', 'imessage', 100, 200, 2, 0),
+			('ep-unprocessed', 'def', 'imessage', 300, 400, 2, 0),
+			('ep-rechunked', 'other', 'imessage', 100, 200, 2, 0),
+			('ep-dinner', 'def', 'imessage', 500, 600, 2, 0),
+			('ep-mail', 'def', 'gmail', 700, 800, 1, 0),
+			('ep-weather', 'def', 'imessage', 900, 950, 1, 0);
+	`); err != nil {
+		t.Fatal(err)
+	}
+	log := NewProcessingLog(db)
+	for _, id := range []string{"ep-vegan", "ep-rechunked", "ep-dinner", "ep-mail", "ep-weather"} {
+		if err := log.Record(ctx, EpisodeProcessing{EpisodeID: id, Status: ProcessingStatusOK}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Only the dinner episode's embedding is close to the query
+	for id, vec := range map[string][]float64{"ep-dinner": {0.9, 0.1, 0}, "ep-weather": {0, 0, 1}} {
+		if _, err := db.Exec(`
+			INSERT INTO embeddings (id, target_type, target_id, model, embedding_blob, dimension, created_at)
+			VALUES (?, 'episode', ?, ?, ?, 3, 0)
+		`, "emb-"+id, id, DefaultEmbeddingModel, float64SliceToBlob(vec)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	texts := map[string]string{
+		"ep-vegan":       "Casey: I went vegan last month, so no brisket for me",
+		"ep-unprocessed": "Dana: my nut allergy is acting up",
+		"ep-rechunked":   "Casey: I went vegan last month, so no brisket for me",
+		"ep-dinner":      "Dana: what should we cook Saturday?",
+		"ep-mail":        "Reminder: the VEGAN potluck is Friday",
+		"ep-weather":     "Sam: it's raining again",
+	}
+	text := func(ctx context.Context, episodeID string) (string, error) {
+		content, ok := texts[episodeID]
+		if !ok {
+			return "", fmt.Errorf("no episode %s", episodeID)
+		}
+		return content, nil
+	}
+	ids := func(candidates []ReplayCandidate) string {
+		var out []string
+		for _, c := range candidates {
+			out = append(out, c.EpisodeID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	if _, _, err := FindReplayCandidates(ctx, db, ReplayOptions{}, text); err == nil {
+		t.Error("replay without a prefilter accepted")
+	}
+	if _, _, err := FindReplayCandidates(ctx, db, ReplayOptions{Filter: "vegan("}, text); err == nil {
+		t.Error("invalid filter accepted")
+	}
+
+	// Keywords match case-insensitively; unprocessed and rechunked episodes are left out
+	candidates, scanned, err := FindReplayCandidates(ctx, db, ReplayOptions{Filter: "vegan|allerg"}, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(candidates); got != "ep-vegan,ep-mail" || scanned != 4 {
+		t.Errorf("keyword candidates = %s (scanned %d)", got, scanned)
+	}
+	if candidates[0].Match != "Casey: I went vegan last month, so no brisket for me" || candidates[0].ThreadID != "" {
+		t.Errorf("keyword match = %+v", candidates[0])
+	}
+
+	// An embedding prefilter adds episodes the keywords miss
+	candidates, _, err = FindReplayCandidates(ctx, db, ReplayOptions{Filter: "vegan", QueryEmbedding: []float64{1, 0, 0}, Channel: "imessage"}, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(candidates); got != "ep-vegan,ep-dinner" || candidates[1].Score < DefaultReplayMinScore || candidates[1].Match != "" {
+		t.Errorf("keyword+embedding candidates = %s %+v", got, candidates)
+	}
+
+	candidates, _, err = FindReplayCandidates(ctx, db, ReplayOptions{Filter: "vegan", Limit: 1}, text)
+	if err != nil || ids(candidates) != "ep-vegan" {
+		t.Errorf("limited candidates = %s (%v)", ids(candidates), err)
+	}
+}
+
+func TestReplayRejectsInvalidRelationType(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+
+	pipeline := NewMemoryPipeline(db, nil, &PipelineConfig{SkipEmbeddings: true})
+	if _, err := pipeline.Replay(context.Background(), "dietary restriction", nil, nil); err == nil {
+		t.Error("invalid relation type accepted")
+	}
+	result, err := pipeline.Replay(context.Background(), "has_dietary_restriction", nil, nil)
+	if err != nil || result.RelationType != "HAS_DIETARY_RESTRICTION" {
+		t.Errorf("replay = %+v, %v", result, err)
+	}
+}
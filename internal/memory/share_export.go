@@ -0,0 +1,324 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ShareScope selects and redacts the part of the graph to share with
+// another instance (say, a work assistant). Zero fields mean no filter.
+// Raw messages, episodes and mention provenance are never shared.
+type ShareScope struct {
+	RelationTypes []string `json:"relation_types,omitempty"` // only these relation types
+	EntityTypes   []int    `json:"entity_types,omitempty"`   // only entities of these types
+	// Roots limits the export to entities within Depth hops of these
+	// entities over the selected relationships.
+	Roots              []string `json:"roots,omitempty"`
+	Depth              int      `json:"depth,omitempty"` // default DefaultSubgraphDepth
+	IncludeInvalidated bool     `json:"include_invalidated,omitempty"`
+	// IncludeIdentifiers keeps email, phone, handle and username aliases and
+	// facts; by default only name aliases are shared.
+	IncludeIdentifiers bool `json:"include_identifiers,omitempty"`
+	// IncludeSummaries keeps entity summaries. They are written from every
+	// fact about an entity, so they can leak facts outside the scope.
+	IncludeSummaries bool `json:"include_summaries,omitempty"`
+}
+
+// ShareScopePresets are named scopes for common sharing needs.
+var ShareScopePresets = map[string]ShareScope{
+	"work": {
+		RelationTypes: []string{
+			"WORKS_AT", "MEMBER_OF", "FOUNDED", "OWNS", "CUSTOMER_OF", "USES",
+			"CREATED", "BUILDING", "WORKING_ON", "CONTRIBUTED_TO", "AUTHORED", "REFERENCES",
+			"HEADQUARTERED_IN", "STARTED_ON", "ENDED_ON",
+		},
+		EntityTypes: []int{
+			EntityTypePerson, EntityTypeOrganization, EntityTypeProject,
+			EntityTypeLocation, EntityTypeDocument,
+		},
+	},
+}
+
+// shareRedactedRelationTypes are never shared, whatever the scope.
+var shareRedactedRelationTypes = map[string]bool{
+	"HAS_ACCOUNT_NUMBER": true,
+	"HAS_ROUTING_NUMBER": true,
+	"HAS_PASSWORD":       true,
+	"HAS_IP_ADDRESS":     true,
+}
+
+// shareNameAliasTypes are the alias types shared without IncludeIdentifiers.
+var shareNameAliasTypes = map[string]bool{"name": true, "nickname": true}
+
+// ShareBundleFormat identifies share bundles.
+const ShareBundleFormat = "mnemonic-share/1"
+
+// SharedEntity is an entity in a ShareBundle.
+type SharedEntity struct {
+	ID            string  `json:"id"`
+	CanonicalName string  `json:"canonical_name"`
+	EntityTypeID  int     `json:"entity_type_id"`
+	Type          string  `json:"type"`
+	Summary       string  `json:"summary,omitempty"`
+	Aliases       []Alias `json:"aliases,omitempty"`
+}
+
+// Alias is a shared entity alias.
+type Alias struct {
+	Alias string `json:"alias"`
+	Type  string `json:"type"`
+}
+
+// SharedRelationship is a relationship in a ShareBundle.
+type SharedRelationship struct {
+	ID             string  `json:"id"`
+	SourceEntityID string  `json:"source_entity_id"`
+	TargetEntityID string  `json:"target_entity_id,omitempty"`
+	TargetLiteral  string  `json:"target_literal,omitempty"`
+	RelationType   string  `json:"relation_type"`
+	Fact           string  `json:"fact"`
+	ValidAt        string  `json:"valid_at,omitempty"`
+	InvalidAt      string  `json:"invalid_at,omitempty"`
+	Confidence     float64 `json:"confidence"`
+	CreatedAt      string  `json:"created_at"`
+}
+
+// ShareBundle is a scoped, redacted, self-contained slice of the graph.
+type ShareBundle struct {
+	Format        string               `json:"format"`
+	ExportedAt    string               `json:"exported_at"`
+	Scope         ShareScope           `json:"scope"`
+	Entities      []SharedEntity       `json:"entities"`
+	Relationships []SharedRelationship `json:"relationships"`
+}
+
+// BuildShareBundle collects the relationships the scope allows, between
+// unmerged entities of allowed types, and the entities they connect. With
+// Roots, only what is reachable from the roots within Depth hops is kept.
+func BuildShareBundle(ctx context.Context, db *sql.DB, scope ShareScope) (*ShareBundle, error) {
+	if scope.Depth <= 0 {
+		scope.Depth = DefaultSubgraphDepth
+	}
+	relTypes := make(map[string]bool, len(scope.RelationTypes))
+	for _, rt := range scope.RelationTypes {
+		relTypes[strings.ToUpper(strings.TrimSpace(rt))] = true
+	}
+	entityTypes := make(map[int]bool, len(scope.EntityTypes))
+	for _, et := range scope.EntityTypes {
+		entityTypes[et] = true
+	}
+	allowedEntity := func(typeID int) bool {
+		return len(entityTypes) == 0 || entityTypes[typeID]
+	}
+
+	// Every unmerged entity of an allowed type
+	entities := make(map[string]*SharedEntity)
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, canonical_name, entity_type_id, COALESCE(summary, '')
+		FROM entities WHERE merged_into IS NULL
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query entities: %w", err)
+	}
+	for rows.Next() {
+		var e SharedEntity
+		if err := rows.Scan(&e.ID, &e.CanonicalName, &e.EntityTypeID, &e.Summary); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan entity: %w", err)
+		}
+		if !allowedEntity(e.EntityTypeID) {
+			continue
+		}
+		e.Type = entityTypeName(e.EntityTypeID)
+		if !scope.IncludeSummaries {
+			e.Summary = ""
+		}
+		entities[e.ID] = &e
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, root := range scope.Roots {
+		if entities[root] == nil {
+			return nil, fmt.Errorf("root %s is not an unmerged entity of an allowed type", root)
+		}
+	}
+
+	// Relationships the scope allows
+	query := `
+		SELECT id, source_entity_id, COALESCE(target_entity_id, ''), COALESCE(target_literal, ''),
+		       relation_type, fact, COALESCE(valid_at, ''), COALESCE(invalid_at, ''),
+		       COALESCE(confidence, 1.0), created_at
+		FROM relationships
+	`
+	if !scope.IncludeInvalidated {
+		query += ` WHERE invalid_at IS NULL`
+	}
+	query += ` ORDER BY created_at, id`
+	rows, err = db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query relationships: %w", err)
+	}
+	var rels []SharedRelationship
+	for rows.Next() {
+		var r SharedRelationship
+		if err := rows.Scan(&r.ID, &r.SourceEntityID, &r.TargetEntityID, &r.TargetLiteral, &r.RelationType,
+			&r.Fact, &r.ValidAt, &r.InvalidAt, &r.Confidence, &r.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan relationship: %w", err)
+		}
+		switch {
+		case shareRedactedRelationTypes[r.RelationType]:
+			continue
+		case len(relTypes) > 0 && !relTypes[r.RelationType]:
+			continue
+		case IsIdentityRelationType(r.RelationType) && !scope.IncludeIdentifiers:
+			continue
+		case entities[r.SourceEntityID] == nil:
+			continue
+		case r.TargetEntityID != "" && entities[r.TargetEntityID] == nil:
+			continue
+		}
+		rels = append(rels, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Keep what the roots reach, or everything connected
+	keep := make(map[string]bool)
+	if len(scope.Roots) > 0 {
+		adjacent := make(map[string][]string)
+		for _, r := range rels {
+			if r.TargetEntityID != "" {
+				adjacent[r.SourceEntityID] = append(adjacent[r.SourceEntityID], r.TargetEntityID)
+				adjacent[r.TargetEntityID] = append(adjacent[r.TargetEntityID], r.SourceEntityID)
+			}
+		}
+		frontier := scope.Roots
+		for _, root := range scope.Roots {
+			keep[root] = true
+		}
+		for hop := 0; hop < scope.Depth && len(frontier) > 0; hop++ {
+			var next []string
+			for _, id := range frontier {
+				for _, n := range adjacent[id] {
+					if !keep[n] {
+						keep[n] = true
+						next = append(next, n)
+					}
+				}
+			}
+			frontier = next
+		}
+	}
+
+	bundle := &ShareBundle{
+		Format:        ShareBundleFormat,
+		ExportedAt:    time.Now().UTC().Format(time.RFC3339),
+		Scope:         scope,
+		Entities:      []SharedEntity{},
+		Relationships: []SharedRelationship{},
+	}
+	included := make(map[string]bool)
+	for _, root := range scope.Roots {
+		included[root] = true
+	}
+	for _, r := range rels {
+		if len(scope.Roots) > 0 && (!keep[r.SourceEntityID] || (r.TargetEntityID != "" && !keep[r.TargetEntityID])) {
+			continue
+		}
+		bundle.Relationships = append(bundle.Relationships, r)
+		included[r.SourceEntityID] = true
+		if r.TargetEntityID != "" {
+			included[r.TargetEntityID] = true
+		}
+	}
+	for id := range included {
+		bundle.Entities = append(bundle.Entities, *entities[id])
+	}
+	sort.Slice(bundle.Entities, func(i, j int) bool {
+		if bundle.Entities[i].CanonicalName != bundle.Entities[j].CanonicalName {
+			return bundle.Entities[i].CanonicalName < bundle.Entities[j].CanonicalName
+		}
+		return bundle.Entities[i].ID < bundle.Entities[j].ID
+	})
+
+	if err := loadSharedAliases(ctx, db, bundle, scope.IncludeIdentifiers); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// loadSharedAliases attaches aliases to the bundle's entities, keeping only
+// name aliases unless identifiers are included.
+func loadSharedAliases(ctx context.Context, db *sql.DB, bundle *ShareBundle, identifiers bool) error {
+	index := make(map[string]int, len(bundle.Entities))
+	for i, e := range bundle.Entities {
+		index[e.ID] = i
+	}
+	rows, err := db.QueryContext(ctx, `SELECT entity_id, alias, alias_type FROM entity_aliases ORDER BY created_at, id`)
+	if err != nil {
+		return fmt.Errorf("query aliases: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entityID string
+		var a Alias
+		if err := rows.Scan(&entityID, &a.Alias, &a.Type); err != nil {
+			return fmt.Errorf("scan alias: %w", err)
+		}
+		i, ok := index[entityID]
+		if !ok || (!identifiers && !shareNameAliasTypes[a.Type]) {
+			continue
+		}
+		bundle.Entities[i].Aliases = append(bundle.Entities[i].Aliases, a)
+	}
+	return rows.Err()
+}
+
+// WriteShareBundle stores a bundle in dst, a database with the full schema
+// (see db.Create), so another instance can open it as its own graph.
+// Entities arrive with origin "imported".
+func WriteShareBundle(ctx context.Context, dst *sql.DB, bundle *ShareBundle) error {
+	tx, err := dst.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, e := range bundle.Entities {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO entities (id, canonical_name, entity_type_id, summary, origin, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, e.ID, e.CanonicalName, e.EntityTypeID, nullIfEmpty(e.Summary), OriginImported, now, now); err != nil {
+			return fmt.Errorf("insert entity %s: %w", e.ID, err)
+		}
+		for i, a := range e.Aliases {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO entity_aliases (id, entity_id, alias, alias_type, normalized, created_at)
+				VALUES (?, ?, ?, ?, ?, ?)
+			`, fmt.Sprintf("%s:%d", e.ID, i), e.ID, a.Alias, a.Type, normalizeAlias(a.Alias), now); err != nil {
+				return fmt.Errorf("insert alias: %w", err)
+			}
+		}
+	}
+	for _, r := range bundle.Relationships {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO relationships (id, source_entity_id, target_entity_id, target_literal, relation_type,
+				fact, valid_at, invalid_at, confidence, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, r.ID, r.SourceEntityID, nullIfEmpty(r.TargetEntityID), nullIfEmpty(r.TargetLiteral), r.RelationType,
+			r.Fact, nullIfEmpty(r.ValidAt), nullIfEmpty(r.InvalidAt), r.Confidence, r.CreatedAt); err != nil {
+			return fmt.Errorf("insert relationship %s: %w", r.ID, err)
+		}
+	}
+	return tx.Commit()
+}
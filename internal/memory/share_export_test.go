@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestBuildShareBundle(t *testing.T) {
+	db := setupQueryEngineTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertQueryEngineTestEntity(t, db, "tyler", "Tyler", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "acme", "Acme", EntityTypeOrganization)
+	insertQueryEngineTestEntity(t, db, "widget", "Widget", EntityTypeProject)
+	insertQueryEngineTestEntity(t, db, "casey", "Casey", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "initech", "Initech", EntityTypeOrganization)
+	insertQueryEngineTestAlias(t, db, "a1", "tyler", "Ty", "nickname", false)
+	insertQueryEngineTestAlias(t, db, "a2", "tyler", "tyler@example.com", "email", false)
+
+	rel := func(id, source, target, relType string) {
+		insertQueryEngineTestRelationship(t, db, id, source, &target, nil, relType, source+" "+relType+" "+target, nil, nil)
+	}
+	rel("r1", "tyler", "acme", "WORKS_AT")
+	rel("r2", "tyler", "widget", "WORKING_ON")
+	rel("r3", "casey", "tyler", "DATING")
+	rel("r4", "casey", "initech", "WORKS_AT")
+	email, account := "tyler@example.com", "12345678"
+	insertQueryEngineTestRelationship(t, db, "r5", "tyler", nil, &email, "HAS_EMAIL", "Tyler's email", nil, nil)
+	insertQueryEngineTestRelationship(t, db, "r6", "tyler", nil, &account, "HAS_ACCOUNT_NUMBER", "Tyler's account", nil, nil)
+	ended := "2024-01-01T00:00:00Z"
+	insertQueryEngineTestRelationship(t, db, "r7", "tyler", strPtr("initech"), nil, "WORKS_AT", "Tyler worked at Initech", nil, &ended)
+
+	bundle, err := BuildShareBundle(ctx, db, ShareScopePresets["work"])
+	if err != nil {
+		t.Fatalf("BuildShareBundle: %v", err)
+	}
+	rels := map[string]bool{}
+	for _, r := range bundle.Relationships {
+		rels[r.ID] = true
+	}
+	if len(rels) != 3 || !rels["r1"] || !rels["r2"] || !rels["r4"] {
+		t.Errorf("work relationships = %v, want r1, r2, r4", rels)
+	}
+	for _, e := range bundle.Entities {
+		if e.ID != "tyler" {
+			continue
+		}
+		if len(e.Aliases) != 1 || e.Aliases[0].Alias != "Ty" {
+			t.Errorf("tyler aliases = %+v, want only the nickname", e.Aliases)
+		}
+	}
+
+	// Roots keep only what they reach; identifiers are opt-in, account numbers never shared
+	bundle, err = BuildShareBundle(ctx, db, ShareScope{Roots: []string{"tyler"}, Depth: 1, IncludeIdentifiers: true})
+	if err != nil {
+		t.Fatalf("BuildShareBundle with root: %v", err)
+	}
+	rels = map[string]bool{}
+	for _, r := range bundle.Relationships {
+		rels[r.ID] = true
+	}
+	if len(rels) != 4 || !rels["r5"] || rels["r4"] || rels["r6"] {
+		t.Errorf("rooted relationships = %v, want r1, r2, r3, r5", rels)
+	}
+
+	dst := testutil.OpenTestDB(t)
+	if err := WriteShareBundle(ctx, dst, bundle); err != nil {
+		t.Fatalf("WriteShareBundle: %v", err)
+	}
+	var entities, relationships int
+	dst.QueryRow(`SELECT COUNT(*) FROM entities WHERE origin = ?`, OriginImported).Scan(&entities)
+	dst.QueryRow(`SELECT COUNT(*) FROM relationships`).Scan(&relationships)
+	if entities != len(bundle.Entities) || relationships != 4 {
+		t.Errorf("written %d entities, %d relationships; want %d, 4", entities, relationships, len(bundle.Entities))
+	}
+}
@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SharedAliasTypes are the alias types households commonly share
+// (a family email, a landline). Handles are treated as personal.
+var SharedAliasTypes = []string{"email", "phone"}
+
+// HouseholdRelationTypes link people who plausibly share an email or phone.
+var HouseholdRelationTypes = []string{"SPOUSE_OF", "MARRIED_TO", "DATING", "PARENT_OF", "CHILD_OF", "SIBLING_OF"}
+
+// SharedAliasMinCoMentions is how many episodes must mention two entities
+// together before they are taken to be different people.
+const SharedAliasMinCoMentions = 2
+
+// Reasons an alias was judged shared.
+const (
+	SharedReasonHousehold   = "household_relation"   // the holders are related (spouse, parent, sibling, ...)
+	SharedReasonCoMentioned = "co_mentioned"         // the holders appear together in several episodes
+	SharedReasonBirthdates  = "different_birthdates" // the holders have different birthdates
+	SharedReasonSameSurname = "same_surname"         // same last name, different first names
+)
+
+// SharedAlias is an email or phone held by several entities that are
+// evidently different people.
+type SharedAlias struct {
+	AliasType  string   `json:"alias_type"`
+	Normalized string   `json:"normalized"`
+	EntityIDs  []string `json:"entity_ids"`
+	Reason     string   `json:"reason"`
+}
+
+// SharedAliasResult contains the output of shared alias detection.
+type SharedAliasResult struct {
+	Checked int           `json:"checked"` // alias values held by more than one entity
+	Shared  []SharedAlias `json:"shared"`
+}
+
+// SharedAliasDetector marks aliases as shared based on how their holders
+// relate to each other. A value held by two entities is either a duplicate
+// (merge them) or a shared household identifier; the detector only marks the
+// second kind, leaving the rest to collision detection.
+type SharedAliasDetector struct {
+	db *sql.DB
+}
+
+// NewSharedAliasDetector creates a new SharedAliasDetector.
+func NewSharedAliasDetector(db *sql.DB) *SharedAliasDetector {
+	return &SharedAliasDetector{db: db}
+}
+
+// Detect finds email and phone aliases held by several non-merged entities
+// that are evidently different people. When mark is true, every alias with
+// that value is set is_shared = TRUE.
+func (d *SharedAliasDetector) Detect(ctx context.Context, mark bool) (*SharedAliasResult, error) {
+	result := &SharedAliasResult{Shared: make([]SharedAlias, 0)}
+
+	for _, aliasType := range SharedAliasTypes {
+		rows, err := d.db.QueryContext(ctx, `
+			SELECT ea.normalized, GROUP_CONCAT(DISTINCT ea.entity_id)
+			FROM entity_aliases ea
+			JOIN entities e ON ea.entity_id = e.id
+			WHERE ea.alias_type = ?
+			  AND e.merged_into IS NULL
+			GROUP BY ea.normalized
+			HAVING COUNT(DISTINCT ea.entity_id) > 1
+			   AND SUM(CASE WHEN COALESCE(ea.is_shared, FALSE) THEN 0 ELSE 1 END) > 0
+		`, aliasType)
+		if err != nil {
+			return nil, fmt.Errorf("query %s aliases: %w", aliasType, err)
+		}
+
+		// Collect first (SQLite concurrent query limitation)
+		var groups []SharedAlias
+		for rows.Next() {
+			var g SharedAlias
+			var entityIDs string
+			if err := rows.Scan(&g.Normalized, &entityIDs); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			g.AliasType = aliasType
+			g.EntityIDs = splitCSV(entityIDs)
+			sort.Strings(g.EntityIDs)
+			groups = append(groups, g)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		for _, g := range groups {
+			result.Checked++
+			reason, err := d.distinctPeopleReason(ctx, g.EntityIDs)
+			if err != nil {
+				return nil, err
+			}
+			if reason == "" {
+				continue
+			}
+			g.Reason = reason
+			if mark {
+				if _, err := d.db.ExecContext(ctx, `
+					UPDATE entity_aliases SET is_shared = TRUE
+					WHERE alias_type = ? AND normalized = ?
+				`, g.AliasType, g.Normalized); err != nil {
+					return nil, fmt.Errorf("mark shared alias: %w", err)
+				}
+			}
+			result.Shared = append(result.Shared, g)
+		}
+	}
+
+	return result, nil
+}
+
+// distinctPeopleReason returns why some pair of the entities is evidently two
+// different people, or "" if none is.
+func (d *SharedAliasDetector) distinctPeopleReason(ctx context.Context, entityIDs []string) (string, error) {
+	for i := 0; i < len(entityIDs)-1; i++ {
+		for j := i + 1; j < len(entityIDs); j++ {
+			reason, err := d.pairReason(ctx, entityIDs[i], entityIDs[j])
+			if err != nil || reason != "" {
+				return reason, err
+			}
+		}
+	}
+	return "", nil
+}
+
+func (d *SharedAliasDetector) pairReason(ctx context.Context, entityAID, entityBID string) (string, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(HouseholdRelationTypes)), ",")
+	args := []interface{}{entityAID, entityBID, entityBID, entityAID}
+	for _, relType := range HouseholdRelationTypes {
+		args = append(args, relType)
+	}
+	var related int
+	err := d.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM relationships
+		WHERE ((source_entity_id = ? AND target_entity_id = ?) OR (source_entity_id = ? AND target_entity_id = ?))
+		  AND relation_type IN (`+placeholders+`)
+		  AND invalid_at IS NULL
+	`, args...).Scan(&related)
+	if err != nil {
+		return "", fmt.Errorf("check household relation: %w", err)
+	}
+	if related > 0 {
+		return SharedReasonHousehold, nil
+	}
+
+	var coMentions int
+	err = d.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM episode_entity_mentions a
+		JOIN episode_entity_mentions b ON b.episode_id = a.episode_id
+		WHERE a.entity_id = ? AND b.entity_id = ?
+	`, entityAID, entityBID).Scan(&coMentions)
+	if err != nil {
+		return "", fmt.Errorf("check co-mentions: %w", err)
+	}
+	if coMentions >= SharedAliasMinCoMentions {
+		return SharedReasonCoMentioned, nil
+	}
+
+	conflict, err := NewAutoMerger(d.db).checkDifferentBirthdates(ctx, entityAID, entityBID)
+	if err != nil {
+		return "", fmt.Errorf("check birthdates: %w", err)
+	}
+	if conflict != nil {
+		return SharedReasonBirthdates, nil
+	}
+
+	var nameA, nameB string
+	err = d.db.QueryRowContext(ctx, `
+		SELECT a.canonical_name, b.canonical_name FROM entities a, entities b
+		WHERE a.id = ? AND b.id = ?
+	`, entityAID, entityBID).Scan(&nameA, &nameB)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("get names: %w", err)
+	}
+	if sameSurnameDifferentFirst(nameA, nameB) {
+		return SharedReasonSameSurname, nil
+	}
+
+	return "", nil
+}
+
+// sameSurnameDifferentFirst reports whether two full names share a last name
+// but not a first name ("Ann Smith" / "Bob Smith"). An initial matches any
+// first name it could abbreviate ("A. Smith" / "Ann Smith").
+func sameSurnameDifferentFirst(a, b string) bool {
+	partsA := strings.Fields(strings.ToLower(a))
+	partsB := strings.Fields(strings.ToLower(b))
+	if len(partsA) < 2 || len(partsB) < 2 || partsA[len(partsA)-1] != partsB[len(partsB)-1] {
+		return false
+	}
+	firstA := strings.TrimSuffix(partsA[0], ".")
+	firstB := strings.TrimSuffix(partsB[0], ".")
+	return !strings.HasPrefix(firstA, firstB) && !strings.HasPrefix(firstB, firstA)
+}
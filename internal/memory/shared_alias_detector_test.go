@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSharedAliasDetector(t *testing.T) {
+	db := setupAutoMergerTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	// Same surname sharing a family email
+	createTestEntity(t, db, "ann", "Ann Smith", 1)
+	createTestEntity(t, db, "bob", "Bob Smith", 1)
+	createTestAlias(t, db, "ann", "smiths@example.com", "email", "smiths@example.com", false)
+	createTestAlias(t, db, "bob", "smiths@example.com", "email", "smiths@example.com", false)
+
+	// Spouses sharing a landline
+	createTestEntity(t, db, "mom", "Mom", 1)
+	createTestEntity(t, db, "dad", "Dad", 1)
+	createTestAlias(t, db, "mom", "555-0100", "phone", "5550100", false)
+	createTestAlias(t, db, "dad", "555-0100", "phone", "5550100", false)
+	createTestRelationship(t, db, "mom", "SPOUSE_OF", strPtr("dad"), nil)
+
+	// Likely duplicates: nothing says they are different people
+	createTestEntity(t, db, "tyler-a", "Tyler", 1)
+	createTestEntity(t, db, "tyler-b", "T. Brown", 1)
+	createTestAlias(t, db, "tyler-a", "tyler@example.com", "email", "tyler@example.com", false)
+	createTestAlias(t, db, "tyler-b", "tyler@example.com", "email", "tyler@example.com", false)
+
+	detector := NewSharedAliasDetector(db)
+	dry, err := detector.Detect(ctx, false)
+	if err != nil {
+		t.Fatalf("Detect: %v", err)
+	}
+	if dry.Checked != 3 || len(dry.Shared) != 2 {
+		t.Fatalf("dry run = %+v, want 3 checked, 2 shared", dry)
+	}
+	var shared int
+	db.QueryRow(`SELECT COUNT(*) FROM entity_aliases WHERE is_shared`).Scan(&shared)
+	if shared != 0 {
+		t.Errorf("dry run marked %d aliases", shared)
+	}
+
+	result, err := detector.Detect(ctx, true)
+	if err != nil {
+		t.Fatalf("Detect: %v", err)
+	}
+	reasons := map[string]string{}
+	for _, s := range result.Shared {
+		reasons[s.Normalized] = s.Reason
+	}
+	if reasons["smiths@example.com"] != SharedReasonSameSurname || reasons["5550100"] != SharedReasonHousehold {
+		t.Errorf("reasons = %v", reasons)
+	}
+	db.QueryRow(`SELECT COUNT(*) FROM entity_aliases WHERE is_shared`).Scan(&shared)
+	if shared != 4 {
+		t.Errorf("marked %d aliases, want 4", shared)
+	}
+
+	// Already-shared values are not rechecked
+	again, _ := detector.Detect(ctx, true)
+	if again.Checked != 1 || len(again.Shared) != 0 {
+		t.Errorf("second run = %+v, want only the unshared value checked", again)
+	}
+}
+
+func TestSharedAliasDetector_CoMentionedAndBirthdates(t *testing.T) {
+	db := setupAutoMergerTestDB(t)
+	defer db.Close()
+
+	createTestEntity(t, db, "kid-a", "Jamie", 1)
+	createTestEntity(t, db, "kid-b", "Riley", 1)
+	createTestAlias(t, db, "kid-a", "family@example.com", "email", "family@example.com", false)
+	createTestAlias(t, db, "kid-b", "family@example.com", "email", "family@example.com", false)
+	for _, ep := range []string{"ep1", "ep2"} {
+		createTestEpisode(t, db, ep)
+		createTestEpisodeMention(t, db, ep, "kid-a", 1)
+		createTestEpisodeMention(t, db, ep, "kid-b", 1)
+	}
+
+	createTestEntity(t, db, "twin-a", "Alex", 1)
+	createTestEntity(t, db, "twin-b", "Sam", 1)
+	createTestAlias(t, db, "twin-a", "+15550199", "phone", "+15550199", false)
+	createTestAlias(t, db, "twin-b", "+15550199", "phone", "+15550199", false)
+	createTestRelationship(t, db, "twin-a", "BORN_ON", nil, strPtr("1990-01-01"))
+	createTestRelationship(t, db, "twin-b", "BORN_ON", nil, strPtr("1992-05-05"))
+
+	result, err := NewSharedAliasDetector(db).Detect(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Detect: %v", err)
+	}
+	reasons := map[string]string{}
+	for _, s := range result.Shared {
+		reasons[s.Normalized] = s.Reason
+	}
+	if reasons["family@example.com"] != SharedReasonCoMentioned || reasons["+15550199"] != SharedReasonBirthdates {
+		t.Errorf("reasons = %v", reasons)
+	}
+}
+
+func TestSameSurnameDifferentFirst(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Ann Smith", "Bob Smith", true},
+		{"Ann Smith", "ann smith", false},
+		{"A. Smith", "Ann Smith", false},
+		{"Ann Smith", "Ann Jones", false},
+		{"Mom", "Dad", false},
+	}
+	for _, tt := range tests {
+		if got := sameSurnameDifferentFirst(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameSurnameDifferentFirst(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
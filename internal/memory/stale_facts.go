@@ -0,0 +1,308 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Facts that change over a life - where someone lives or works - go stale
+// quietly: nobody mentions the old employer again, but nobody says they
+// left either. Re-verification finds current facts of such relation types
+// that nothing has reaffirmed for a long time. If a newer fact of the same
+// type points elsewhere, the old one is ended automatically; otherwise it
+// is queued for the user to confirm or end.
+const (
+	// DefaultStaleAfter is how long a fact may go unmentioned before it is
+	// re-verified.
+	DefaultStaleAfter = 365 * 24 * time.Hour
+	// DefaultStaleLimit caps the pending verifications, so the review queue
+	// fills a little at a time instead of all at once.
+	DefaultStaleLimit = 20
+)
+
+// DefaultStaleRelationTypes are re-verified when no list is configured.
+var DefaultStaleRelationTypes = []string{"LIVES_IN", "WORKS_AT", "DATING"}
+
+// Fact verification statuses.
+const (
+	FactVerificationPending    = "pending"
+	FactVerificationConfirmed  = "confirmed"  // still true; the fact's clock restarts
+	FactVerificationEnded      = "ended"      // no longer true; invalid_at was set
+	FactVerificationSuperseded = "superseded" // ended by a newer contradicting fact
+)
+
+// FactVerification is a stale fact queued for re-verification.
+type FactVerification struct {
+	ID             string  `json:"id"`
+	RelationshipID string  `json:"relationship_id"`
+	SourceName     string  `json:"source_name"`
+	RelationType   string  `json:"relation_type"`
+	Target         string  `json:"target"`
+	Fact           string  `json:"fact"`
+	LastSeenAt     string  `json:"last_seen_at"` // creation, latest mention or confirmation
+	Status         string  `json:"status"`
+	SupersededBy   *string `json:"superseded_by,omitempty"`
+	CreatedAt      string  `json:"created_at"`
+	ResolvedAt     *string `json:"resolved_at,omitempty"`
+}
+
+// StaleFactOptions configures FindStaleFacts.
+type StaleFactOptions struct {
+	After         time.Duration // default DefaultStaleAfter
+	RelationTypes []string      // default DefaultStaleRelationTypes
+	Limit         int           // max pending verifications; default DefaultStaleLimit
+	DryRun        bool          // report without queueing or ending facts
+}
+
+// StaleFactResult is the output of FindStaleFacts.
+type StaleFactResult struct {
+	Stale      []FactVerification `json:"stale"`
+	Queued     int                `json:"queued"`
+	Superseded int                `json:"superseded"`
+	Deferred   int                `json:"deferred"` // left for a later run: the queue was full
+	DryRun     bool               `json:"dry_run,omitempty"`
+}
+
+// FindStaleFacts finds current facts of the configured relation types last
+// created, mentioned or confirmed more than After ago, oldest first, and
+// not already pending. A fact with a newer fact of the same type and
+// source but a different target is ended where the newer one begins and
+// recorded as superseded. The rest are queued as pending verifications
+// until Limit are pending; the remainder is picked up by later runs.
+func FindStaleFacts(ctx context.Context, db *sql.DB, opts StaleFactOptions) (*StaleFactResult, error) {
+	if opts.After <= 0 {
+		opts.After = DefaultStaleAfter
+	}
+	if len(opts.RelationTypes) == 0 {
+		opts.RelationTypes = DefaultStaleRelationTypes
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultStaleLimit
+	}
+	now := time.Now().UTC()
+	cutoff := now.Add(-opts.After).Format(time.RFC3339)
+
+	query := `
+		WITH seen AS (
+			SELECT r.id, MAX(
+				julianday(r.created_at),
+				COALESCE((SELECT MAX(julianday(m.created_at)) FROM episode_relationship_mentions m WHERE m.relationship_id = r.id), 0),
+				COALESCE((SELECT MAX(julianday(v.resolved_at)) FROM fact_verifications v WHERE v.relationship_id = r.id AND v.status = ?), 0)
+			) AS last_seen
+			FROM relationships r
+			WHERE r.invalid_at IS NULL AND r.relation_type IN (` + placeholderList(len(opts.RelationTypes)) + `)
+		)
+		SELECT r.id, s.canonical_name, r.relation_type, COALESCE(t.canonical_name, r.target_literal, ''), r.fact,
+		       strftime('%Y-%m-%dT%H:%M:%SZ', seen.last_seen),
+		       (SELECT n.id FROM relationships n
+		        WHERE n.source_entity_id = r.source_entity_id AND n.relation_type = r.relation_type AND n.id != r.id
+		          AND COALESCE(n.target_entity_id, n.target_literal) != COALESCE(r.target_entity_id, r.target_literal)
+		          AND julianday(n.created_at) > seen.last_seen
+		        ORDER BY julianday(n.created_at) DESC, n.id LIMIT 1)
+		FROM seen
+		JOIN relationships r ON r.id = seen.id
+		JOIN entities s ON s.id = r.source_entity_id
+		LEFT JOIN entities t ON t.id = r.target_entity_id
+		WHERE s.merged_into IS NULL
+		  AND seen.last_seen < julianday(?)
+		  AND NOT EXISTS (SELECT 1 FROM fact_verifications v WHERE v.relationship_id = r.id AND v.status = ?)
+		ORDER BY seen.last_seen, r.id
+	`
+	args := []interface{}{FactVerificationConfirmed}
+	for _, relType := range opts.RelationTypes {
+		args = append(args, relType)
+	}
+	args = append(args, cutoff, FactVerificationPending)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query stale facts: %w", err)
+	}
+	defer rows.Close()
+
+	result := &StaleFactResult{Stale: []FactVerification{}, DryRun: opts.DryRun}
+	nowStr := now.Format(time.RFC3339)
+	for rows.Next() {
+		v := FactVerification{ID: uuid.New().String(), Status: FactVerificationPending, CreatedAt: nowStr}
+		var supersededBy sql.NullString
+		if err := rows.Scan(&v.RelationshipID, &v.SourceName, &v.RelationType, &v.Target, &v.Fact, &v.LastSeenAt, &supersededBy); err != nil {
+			return nil, fmt.Errorf("scan stale fact: %w", err)
+		}
+		if supersededBy.Valid {
+			v.Status = FactVerificationSuperseded
+			v.SupersededBy = &supersededBy.String
+			v.ResolvedAt = &nowStr
+		}
+		result.Stale = append(result.Stale, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	var pending int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM fact_verifications WHERE status = ?`, FactVerificationPending).Scan(&pending); err != nil {
+		return nil, fmt.Errorf("count pending verifications: %w", err)
+	}
+
+	var ended []string
+	kept := result.Stale[:0]
+	for _, v := range result.Stale {
+		switch {
+		case v.Status == FactVerificationSuperseded:
+			result.Superseded++
+			if !opts.DryRun {
+				source, err := supersedeStaleFact(ctx, db, v)
+				if err != nil {
+					return nil, err
+				}
+				ended = append(ended, source)
+			}
+		case pending >= opts.Limit:
+			result.Deferred++
+			continue
+		default:
+			pending++
+			result.Queued++
+			if !opts.DryRun {
+				if _, err := db.ExecContext(ctx, `
+					INSERT INTO fact_verifications (id, relationship_id, last_seen_at, status, created_at)
+					VALUES (?, ?, ?, ?, ?)
+				`, v.ID, v.RelationshipID, v.LastSeenAt, v.Status, v.CreatedAt); err != nil {
+					return nil, fmt.Errorf("insert fact verification: %w", err)
+				}
+			}
+		}
+		kept = append(kept, v)
+	}
+	result.Stale = kept
+
+	if len(ended) > 0 {
+		if err := NewCurrentFactsStore(db).RefreshEntities(ctx, ended); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// supersedeStaleFact ends a stale fact where its newer replacement begins
+// (its valid_at, or the day it was learned) and records the resolution. It
+// returns the fact's source entity.
+func supersedeStaleFact(ctx context.Context, db *sql.DB, v FactVerification) (string, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var source string
+	if err := tx.QueryRowContext(ctx, `
+		UPDATE relationships
+		SET invalid_at = (SELECT COALESCE(n.valid_at, date(n.created_at)) FROM relationships n WHERE n.id = ?)
+		WHERE id = ? AND invalid_at IS NULL
+		RETURNING source_entity_id
+	`, *v.SupersededBy, v.RelationshipID).Scan(&source); err != nil {
+		return "", fmt.Errorf("end superseded fact: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO fact_verifications (id, relationship_id, last_seen_at, status, superseded_by, created_at, resolved_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, v.ID, v.RelationshipID, v.LastSeenAt, v.Status, *v.SupersededBy, v.CreatedAt, *v.ResolvedAt); err != nil {
+		return "", fmt.Errorf("insert fact verification: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("commit: %w", err)
+	}
+	return source, nil
+}
+
+// ListFactVerifications returns verifications with the given status (all
+// if empty), the longest unconfirmed first.
+func ListFactVerifications(ctx context.Context, db *sql.DB, status string, limit int) ([]FactVerification, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	query := `
+		SELECT v.id, v.relationship_id, s.canonical_name, r.relation_type, COALESCE(t.canonical_name, r.target_literal, ''),
+		       r.fact, v.last_seen_at, v.status, v.superseded_by, v.created_at, v.resolved_at
+		FROM fact_verifications v
+		JOIN relationships r ON r.id = v.relationship_id
+		JOIN entities s ON s.id = r.source_entity_id
+		LEFT JOIN entities t ON t.id = r.target_entity_id
+	`
+	var args []interface{}
+	if status != "" {
+		query += ` WHERE v.status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY v.last_seen_at, v.created_at, v.id LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query fact verifications: %w", err)
+	}
+	defer rows.Close()
+
+	var verifications []FactVerification
+	for rows.Next() {
+		var v FactVerification
+		var supersededBy, resolvedAt sql.NullString
+		if err := rows.Scan(&v.ID, &v.RelationshipID, &v.SourceName, &v.RelationType, &v.Target,
+			&v.Fact, &v.LastSeenAt, &v.Status, &supersededBy, &v.CreatedAt, &resolvedAt); err != nil {
+			return nil, fmt.Errorf("scan fact verification: %w", err)
+		}
+		if supersededBy.Valid {
+			v.SupersededBy = &supersededBy.String
+		}
+		if resolvedAt.Valid {
+			v.ResolvedAt = &resolvedAt.String
+		}
+		verifications = append(verifications, v)
+	}
+	return verifications, rows.Err()
+}
+
+// ConfirmFactVerification records that a stale fact still holds. The fact
+// is not re-verified until it has gone unmentioned for another period.
+func ConfirmFactVerification(ctx context.Context, db *sql.DB, id string) error {
+	res, err := db.ExecContext(ctx, `
+		UPDATE fact_verifications SET status = ?, resolved_at = ? WHERE id = ? AND status = ?
+	`, FactVerificationConfirmed, time.Now().UTC().Format(time.RFC3339), id, FactVerificationPending)
+	if err != nil {
+		return fmt.Errorf("update fact verification: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("no pending fact verification: %s", id)
+	}
+	return nil
+}
+
+// EndFactVerification records that a stale fact no longer holds and ends it
+// at (an ISO date; today when empty) with InvalidateFact.
+func EndFactVerification(ctx context.Context, db *sql.DB, id, at string) (*EntityRelationship, error) {
+	var relID string
+	err := db.QueryRowContext(ctx, `
+		SELECT relationship_id FROM fact_verifications WHERE id = ? AND status = ?
+	`, id, FactVerificationPending).Scan(&relID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no pending fact verification: %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get fact verification: %w", err)
+	}
+	rel, err := InvalidateFact(ctx, db, relID, at)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, `
+		UPDATE fact_verifications SET status = ?, resolved_at = ? WHERE id = ?
+	`, FactVerificationEnded, time.Now().UTC().Format(time.RFC3339), id); err != nil {
+		return nil, fmt.Errorf("update fact verification: %w", err)
+	}
+	return rel, nil
+}
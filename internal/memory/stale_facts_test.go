@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestFindStaleFacts(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, e := range []struct{ id, name string }{{"casey", "Casey"}, {"dana", "Dana"}, {"eli", "Eli"}} {
+		insertQueryEngineTestEntity(t, db, e.id, e.name, EntityTypePerson)
+	}
+	for _, e := range []struct{ id, name string }{{"austin", "Austin"}, {"denver", "Denver"}} {
+		insertQueryEngineTestEntity(t, db, e.id, e.name, EntityTypeLocation)
+	}
+	insertQueryEngineTestEntity(t, db, "acme", "Acme", EntityTypeCompany)
+
+	ago := func(days int) string { return time.Now().AddDate(0, 0, -days).UTC().Format(time.RFC3339) }
+	for _, r := range []struct{ id, source, target, relType, created string }{
+		{"casey-austin", "casey", "austin", "LIVES_IN", ago(800)},
+		{"casey-denver", "casey", "denver", "LIVES_IN", ago(180)}, // newer: supersedes Austin
+		{"casey-acme", "casey", "acme", "WORKS_AT", ago(800)},     // mentioned last month
+		{"dana-acme", "dana", "acme", "WORKS_AT", ago(700)},
+		{"eli-acme", "eli", "acme", "WORKS_AT", ago(1000)},
+		{"casey-dana", "casey", "dana", "FRIEND_OF", ago(1000)}, // not re-verified
+	} {
+		if _, err := db.Exec(`
+			INSERT INTO relationships (id, source_entity_id, target_entity_id, relation_type, fact, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, r.id, r.source, r.target, r.relType, r.id, r.created); err != nil {
+			t.Fatalf("insert relationship: %v", err)
+		}
+	}
+	if _, err := db.Exec(`
+		INSERT INTO threads (id, channel, source_adapter, source_id, created_at, updated_at) VALUES ('t1', 'imessage', 'imessage', 't1', 0, 0);
+		INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0);
+		INSERT INTO episodes (id, definition_id, channel, thread_id, start_time, end_time, event_count, created_at) VALUES ('ep1', 'def', 'imessage', 't1', 0, 0, 1, 0);
+	`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO episode_relationship_mentions (id, episode_id, relationship_id, extracted_fact, created_at)
+		VALUES ('m1', 'ep1', 'casey-acme', 'Casey still at Acme', ?)
+	`, ago(30)); err != nil {
+		t.Fatalf("insert mention: %v", err)
+	}
+
+	dry, err := FindStaleFacts(ctx, db, StaleFactOptions{Limit: 1, DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	var n int
+	db.QueryRow(`SELECT COUNT(*) FROM fact_verifications`).Scan(&n)
+	if dry.Queued != 1 || dry.Superseded != 1 || dry.Deferred != 1 || n != 0 {
+		t.Fatalf("dry run = %+v, %d rows written", dry, n)
+	}
+
+	result, err := FindStaleFacts(ctx, db, StaleFactOptions{Limit: 1})
+	if err != nil {
+		t.Fatalf("FindStaleFacts: %v", err)
+	}
+	if len(result.Stale) != 2 || result.Stale[0].RelationshipID != "eli-acme" || result.Stale[1].RelationshipID != "casey-austin" ||
+		result.Queued != 1 || result.Superseded != 1 || result.Deferred != 1 {
+		t.Fatalf("result = %+v", result)
+	}
+	var invalidAt string
+	db.QueryRow(`SELECT invalid_at FROM relationships WHERE id = 'casey-austin'`).Scan(&invalidAt)
+	if want := ago(180)[:10]; invalidAt != want {
+		t.Errorf("superseded fact invalid_at = %q, want %q", invalidAt, want)
+	}
+	var current string
+	db.QueryRow(`SELECT target_entity_id FROM entity_current_facts WHERE entity_id = 'casey' AND relation_type = 'LIVES_IN'`).Scan(&current)
+	if current != "denver" {
+		t.Errorf("current LIVES_IN = %q", current)
+	}
+
+	// The queue is full until Eli's fact is confirmed
+	if again, err := FindStaleFacts(ctx, db, StaleFactOptions{Limit: 1}); err != nil || again.Queued != 0 || again.Deferred != 1 {
+		t.Fatalf("second run = %+v, %v", again, err)
+	}
+	pending, err := ListFactVerifications(ctx, db, FactVerificationPending, 0)
+	if err != nil || len(pending) != 1 || pending[0].RelationshipID != "eli-acme" || pending[0].SourceName != "Eli" || pending[0].Target != "Acme" {
+		t.Fatalf("pending = %+v, %v", pending, err)
+	}
+	if err := ConfirmFactVerification(ctx, db, pending[0].ID); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	if err := ConfirmFactVerification(ctx, db, pending[0].ID); err == nil {
+		t.Error("confirming twice should fail")
+	}
+
+	// A confirmed fact is fresh again; Dana's is next
+	third, err := FindStaleFacts(ctx, db, StaleFactOptions{Limit: 1})
+	if err != nil || third.Queued != 1 || third.Stale[0].RelationshipID != "dana-acme" {
+		t.Fatalf("third run = %+v, %v", third, err)
+	}
+	pending, _ = ListFactVerifications(ctx, db, FactVerificationPending, 0)
+	rel, err := EndFactVerification(ctx, db, pending[0].ID, "2025-03-01")
+	if err != nil {
+		t.Fatalf("end: %v", err)
+	}
+	if rel.InvalidAt == nil || *rel.InvalidAt != "2025-03-01" {
+		t.Errorf("ended fact = %+v", rel)
+	}
+	all, _ := ListFactVerifications(ctx, db, "", 0)
+	statuses := map[string]string{}
+	for _, v := range all {
+		statuses[v.RelationshipID] = v.Status
+	}
+	if statuses["eli-acme"] != FactVerificationConfirmed || statuses["dana-acme"] != FactVerificationEnded ||
+		statuses["casey-austin"] != FactVerificationSuperseded || len(all) != 3 {
+		t.Errorf("statuses = %v", statuses)
+	}
+}
@@ -0,0 +1,229 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Subgraph defaults keep the explorer responsive around hub entities.
+const (
+	DefaultSubgraphDepth        = 2
+	DefaultSubgraphMaxNodes     = 150
+	DefaultSubgraphMaxNeighbors = 25
+)
+
+// SubgraphOptions bounds a neighborhood query.
+type SubgraphOptions struct {
+	Depth        int          // Hops from the root (default: DefaultSubgraphDepth)
+	MaxNodes     int          // Total node budget (default: DefaultSubgraphMaxNodes)
+	MaxNeighbors int          // New neighbors kept per expanded node, strongest first (default: DefaultSubgraphMaxNeighbors)
+	Query        QueryOptions // Temporal, relation-type and origin filters applied to every hop
+}
+
+// GraphNode is an entity in a Subgraph.
+type GraphNode struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	TypeID   int    `json:"type_id"`
+	Origin   string `json:"origin,omitempty"` // How the entity was created (see EntityOrigins)
+	Depth    int    `json:"depth"`            // Hops from the root
+	Expanded bool   `json:"expanded"`         // Whether this node's neighbors were fetched
+	Degree   int    `json:"degree"`           // Distinct related entities (expanded nodes only)
+	Hidden   int    `json:"hidden"`           // Related entities left out by sampling or the node budget
+
+	// Attributes collapses literal edges (emails, dates, ...) into the node,
+	// keyed by relation type.
+	Attributes map[string][]string `json:"attributes,omitempty"`
+}
+
+// GraphLink aggregates the relationships of one type between two nodes.
+type GraphLink struct {
+	Source string  `json:"source"`
+	Target string  `json:"target"`
+	Type   string  `json:"type"`
+	Weight float64 `json:"weight"` // Sum of relationship confidences
+	Count  int     `json:"count"`
+	Fact   string  `json:"fact,omitempty"` // Most recent fact
+}
+
+// Subgraph is a size-bounded neighborhood of an entity in the nodes/links
+// shape graph renderers expect.
+type Subgraph struct {
+	Root      string      `json:"root"`
+	Nodes     []GraphNode `json:"nodes"`
+	Links     []GraphLink `json:"links"`
+	Truncated bool        `json:"truncated"` // Some neighbors were sampled out
+}
+
+// GetSubgraph returns the neighborhood of an entity up to opts.Depth hops.
+// Each expanded node keeps at most opts.MaxNeighbors new neighbors, ranked by
+// total relationship confidence, so hubs don't flood the result; links to
+// nodes already in the graph are always kept. Literal-valued relationships
+// become node attributes rather than nodes.
+func (q *QueryEngine) GetSubgraph(ctx context.Context, entityID string, opts SubgraphOptions) (*Subgraph, error) {
+	if opts.Depth <= 0 {
+		opts.Depth = DefaultSubgraphDepth
+	}
+	if opts.MaxNodes <= 0 {
+		opts.MaxNodes = DefaultSubgraphMaxNodes
+	}
+	if opts.MaxNeighbors <= 0 {
+		opts.MaxNeighbors = DefaultSubgraphMaxNeighbors
+	}
+	opts.Query.Limit = 0
+
+	root, err := q.GetEntity(ctx, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("get entity: %w", err)
+	}
+	if root != nil && root.MergedInto != nil {
+		if root, err = q.GetEntity(ctx, *root.MergedInto); err != nil {
+			return nil, fmt.Errorf("get merged entity: %w", err)
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("entity %s not found", entityID)
+	}
+
+	graph := &Subgraph{Root: root.ID}
+	nodes := map[string]*GraphNode{}
+	var order []string
+	addNode := func(ent *Entity, depth int) {
+		node := &GraphNode{ID: ent.ID, Name: ent.CanonicalName, TypeID: ent.EntityTypeID, Origin: ent.Origin, Depth: depth}
+		if et := GetEntityTypeByID(ent.EntityTypeID); et != nil {
+			node.Type = et.Name
+		}
+		nodes[ent.ID] = node
+		order = append(order, ent.ID)
+	}
+	addNode(root, 0)
+
+	type linkKey struct{ source, target, relType string }
+	links := map[linkKey]*GraphLink{}
+	var linkOrder []linkKey
+	seenRels := map[string]bool{}
+	addLink := func(rel EntityRelationship) {
+		if seenRels[rel.ID] {
+			return
+		}
+		seenRels[rel.ID] = true
+		key := linkKey{rel.SourceEntityID, *rel.TargetEntityID, rel.RelationType}
+		link, ok := links[key]
+		if !ok {
+			link = &GraphLink{Source: key.source, Target: key.target, Type: key.relType, Fact: rel.Fact}
+			links[key] = link
+			linkOrder = append(linkOrder, key)
+		}
+		link.Weight += rel.Confidence
+		link.Count++
+	}
+
+	frontier := []string{root.ID}
+	for depth := 1; depth <= opts.Depth && len(frontier) > 0; depth++ {
+		var next []string
+		for _, id := range frontier {
+			node := nodes[id]
+			node.Expanded = true
+
+			rels, err := q.GetEntityRelationships(ctx, id, opts.Query)
+			if err != nil {
+				return nil, fmt.Errorf("get relationships for %s: %w", id, err)
+			}
+
+			weights := map[string]float64{}
+			byNeighbor := map[string][]EntityRelationship{}
+			for _, rel := range rels {
+				if rel.TargetLiteral != nil {
+					if rel.Direction == "outgoing" {
+						addAttribute(node, rel.RelationType, *rel.TargetLiteral)
+					}
+					continue
+				}
+				// Outgoing edges to merged entities have no target name
+				if rel.TargetEntityID == nil || rel.TargetName == nil {
+					continue
+				}
+				other := *rel.TargetEntityID
+				if rel.Direction == "incoming" {
+					other = rel.SourceEntityID
+				}
+				if other == id {
+					continue
+				}
+				weights[other] += rel.Confidence
+				byNeighbor[other] = append(byNeighbor[other], rel)
+			}
+			node.Degree = len(weights)
+
+			ranked := make([]string, 0, len(weights))
+			for other := range weights {
+				ranked = append(ranked, other)
+			}
+			sort.Slice(ranked, func(i, j int) bool {
+				if weights[ranked[i]] != weights[ranked[j]] {
+					return weights[ranked[i]] > weights[ranked[j]]
+				}
+				return ranked[i] < ranked[j]
+			})
+
+			// Pick new neighbors within the sampling and node budgets
+			var candidates []string
+			for _, other := range ranked {
+				if _, ok := nodes[other]; ok {
+					continue
+				}
+				if len(candidates) >= opts.MaxNeighbors || len(nodes)+len(candidates) >= opts.MaxNodes {
+					node.Hidden++
+					graph.Truncated = true
+					continue
+				}
+				candidates = append(candidates, other)
+			}
+			entities, err := q.GetEntities(ctx, candidates)
+			if err != nil {
+				return nil, fmt.Errorf("get neighbors of %s: %w", id, err)
+			}
+			for _, other := range candidates {
+				if ent := entities[other]; ent != nil && ent.MergedInto == nil {
+					addNode(ent, depth)
+					next = append(next, other)
+				}
+			}
+
+			for _, other := range ranked {
+				if _, ok := nodes[other]; !ok {
+					continue
+				}
+				for _, rel := range byNeighbor[other] {
+					addLink(rel)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	graph.Nodes = make([]GraphNode, 0, len(order))
+	for _, id := range order {
+		graph.Nodes = append(graph.Nodes, *nodes[id])
+	}
+	graph.Links = make([]GraphLink, 0, len(linkOrder))
+	for _, key := range linkOrder {
+		graph.Links = append(graph.Links, *links[key])
+	}
+	return graph, nil
+}
+
+// addAttribute records a literal value on a node, skipping duplicates.
+func addAttribute(node *GraphNode, relType, value string) {
+	if node.Attributes == nil {
+		node.Attributes = map[string][]string{}
+	}
+	for _, v := range node.Attributes[relType] {
+		if v == value {
+			return
+		}
+	}
+	node.Attributes[relType] = append(node.Attributes[relType], value)
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestQueryEngine_GetSubgraph(t *testing.T) {
+	db := setupQueryEngineTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	qe := NewQueryEngine(db)
+
+	insertQueryEngineTestEntity(t, db, "tyler", "Tyler", EntityTypePerson)
+	insertQueryEngineTestEntity(t, db, "acme", "Acme", EntityTypeOrganization)
+	insertQueryEngineTestEntity(t, db, "sf", "San Francisco", EntityTypeLocation)
+	insertQueryEngineTestEntity(t, db, "casey", "Casey", EntityTypePerson)
+
+	rel := func(id, source, target, relType string) {
+		insertQueryEngineTestRelationship(t, db, id, source, &target, nil, relType, source+" "+relType+" "+target, nil, nil)
+	}
+	rel("r1", "tyler", "acme", "WORKS_AT")
+	rel("r2", "tyler", "acme", "FOUNDED")
+	rel("r3", "acme", "sf", "LOCATED_IN")
+	rel("r3b", "acme", "sf", "HEADQUARTERED_IN")
+	rel("r4", "casey", "tyler", "KNOWS")
+	email := "tyler@example.com"
+	insertQueryEngineTestRelationship(t, db, "r5", "tyler", nil, &email, "HAS_EMAIL", "Tyler's email", nil, nil)
+
+	// Acme is a hub: only the strongest neighbors survive sampling
+	for i := 0; i < 5; i++ {
+		id := fmt.Sprintf("emp%d", i)
+		insertQueryEngineTestEntity(t, db, id, id, EntityTypePerson)
+		rel("r-"+id, id, "acme", "WORKS_AT")
+	}
+
+	graph, err := qe.GetSubgraph(ctx, "tyler", SubgraphOptions{Depth: 2, MaxNeighbors: 3})
+	if err != nil {
+		t.Fatalf("GetSubgraph: %v", err)
+	}
+
+	nodes := map[string]GraphNode{}
+	for _, n := range graph.Nodes {
+		nodes[n.ID] = n
+	}
+	if graph.Root != "tyler" || graph.Nodes[0].ID != "tyler" {
+		t.Errorf("root = %s, first node = %s", graph.Root, graph.Nodes[0].ID)
+	}
+	if got := nodes["tyler"].Attributes["HAS_EMAIL"]; len(got) != 1 || got[0] != email {
+		t.Errorf("literal edge not collapsed: %+v", nodes["tyler"])
+	}
+	if n := nodes["acme"]; n.Type != "Organization" || n.Depth != 1 || n.Degree != 7 || n.Hidden != 3 {
+		t.Errorf("acme = %+v, want depth 1, degree 7, 3 hidden", n)
+	}
+	if n, ok := nodes["sf"]; !ok || n.Depth != 2 || n.Expanded {
+		t.Errorf("sf = %+v, want unexpanded depth-2 node", n)
+	}
+	if _, ok := nodes["emp2"]; ok {
+		t.Error("emp2 should be sampled out")
+	}
+	if !graph.Truncated || len(graph.Nodes) != 6 {
+		t.Errorf("nodes = %d, truncated = %v, want 6 and true", len(graph.Nodes), graph.Truncated)
+	}
+
+	// Links are deduplicated across both endpoints and grouped by type
+	if len(graph.Links) != 7 {
+		t.Fatalf("links = %+v, want 7", graph.Links)
+	}
+	for _, l := range graph.Links {
+		if _, ok := nodes[l.Source]; !ok {
+			t.Errorf("link source %s not a node", l.Source)
+		}
+		if _, ok := nodes[l.Target]; !ok {
+			t.Errorf("link target %s not a node", l.Target)
+		}
+		if l.Count != 1 || l.Weight != 1 {
+			t.Errorf("link %+v, want count 1 weight 1", l)
+		}
+	}
+
+	// Depth 1 with a node budget of 2
+	graph, err = qe.GetSubgraph(ctx, "tyler", SubgraphOptions{Depth: 1, MaxNodes: 2})
+	if err != nil {
+		t.Fatalf("GetSubgraph budget: %v", err)
+	}
+	if len(graph.Nodes) != 2 || graph.Nodes[1].ID != "acme" || graph.Nodes[0].Hidden != 1 {
+		t.Errorf("budgeted graph = %+v, want tyler and strongest neighbor acme", graph.Nodes)
+	}
+
+	if _, err := qe.GetSubgraph(ctx, "nobody", SubgraphOptions{}); err == nil {
+		t.Error("expected error for unknown entity")
+	}
+}
@@ -77,6 +77,9 @@ type VerificationResult struct {
 	Warnings     []string
 	ActualOutput *VerificationOutput
 	Duration     time.Duration
+	// Self-critique effect (zero unless the pipeline has SelfCritique enabled)
+	CritiqueDropped  []DroppedRelationship
+	CritiqueAdjusted int
 }
 
 // VerificationFailure represents a single assertion failure
@@ -275,10 +278,12 @@ func (h *VerificationHarness) RunFixture(ctx context.Context, fixture *Fixture)
 	}
 
 	// Run the pipeline
-	_, err = h.pipeline.Process(ctx, episodeInput)
+	pipelineResult, err := h.pipeline.Process(ctx, episodeInput)
 	if err != nil {
 		return nil, fmt.Errorf("pipeline process: %w", err)
 	}
+	result.CritiqueDropped = pipelineResult.CritiqueDropped
+	result.CritiqueAdjusted = pipelineResult.CritiqueAdjusted
 
 	// Collect actual outputs
 	output, err := h.collectOutputs(ctx, fixture.Episode.ID)
@@ -660,6 +665,24 @@ func (h *VerificationHarness) matchRelationship(expected map[string]interface{},
 }
 
 func (h *VerificationHarness) relationshipMatches(expected map[string]interface{}, rel VerifyRelationship) bool {
+	// symmetric: true accepts the relationship in either direction (SPOUSE_OF, DATING, ...)
+	if symmetric, _ := expected["symmetric"].(bool); symmetric {
+		directed := make(map[string]interface{}, len(expected))
+		swapped := make(map[string]interface{}, len(expected))
+		for k, v := range expected {
+			switch k {
+			case "symmetric":
+			case "source_entity_name_contains":
+				directed[k], swapped["target_entity_name_contains"] = v, v
+			case "target_entity_name_contains":
+				directed[k], swapped["source_entity_name_contains"] = v, v
+			default:
+				directed[k], swapped[k] = v, v
+			}
+		}
+		return h.relationshipMatches(directed, rel) || h.relationshipMatches(swapped, rel)
+	}
+
 	// Check relation_type
 	if relType, ok := expected["relation_type"].(string); ok {
 		if rel.RelationType != relType {
@@ -1017,6 +1040,13 @@ func FormatResult(result *VerificationResult) string {
 		}
 	}
 
+	if len(result.CritiqueDropped) > 0 || result.CritiqueAdjusted > 0 {
+		sb.WriteString(fmt.Sprintf("  Critique: %d dropped, %d adjusted\n", len(result.CritiqueDropped), result.CritiqueAdjusted))
+		for _, d := range result.CritiqueDropped {
+			sb.WriteString(fmt.Sprintf("    - dropped %s: %s\n", d.Relationship.RelationType, d.Reason))
+		}
+	}
+
 	if len(result.Warnings) > 0 {
 		sb.WriteString("  Warnings:\n")
 		for _, w := range result.Warnings {
@@ -1033,15 +1063,22 @@ func FormatSummary(results []*VerificationResult) string {
 
 	passed := 0
 	failed := 0
+	dropped := 0
+	adjusted := 0
 	for _, r := range results {
 		if r.Passed {
 			passed++
 		} else {
 			failed++
 		}
+		dropped += len(r.CritiqueDropped)
+		adjusted += r.CritiqueAdjusted
 	}
 
 	sb.WriteString(fmt.Sprintf("Verification Summary: %d passed, %d failed, %d total\n", passed, failed, len(results)))
+	if dropped > 0 || adjusted > 0 {
+		sb.WriteString(fmt.Sprintf("Self-critique: %d relationships dropped, %d confidence-adjusted\n", dropped, adjusted))
+	}
 	sb.WriteString(strings.Repeat("-", 60) + "\n")
 
 	for _, result := range results {
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -91,6 +92,8 @@ func setupVerifyTestDB(t *testing.T) *sql.DB {
 			invalid_at TEXT,
 			created_at TEXT DEFAULT (datetime('now')),
 			confidence REAL DEFAULT 1.0,
+			weight REAL DEFAULT 0,
+			source_type TEXT,
 			CHECK ((target_entity_id IS NULL) != (target_literal IS NULL))
 		);
 
@@ -98,6 +101,7 @@ func setupVerifyTestDB(t *testing.T) *sql.DB {
 			episode_id TEXT NOT NULL REFERENCES episodes(id) ON DELETE CASCADE,
 			entity_id TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
 			mention_count INTEGER DEFAULT 1,
+			mention_offsets TEXT,
 			created_at TEXT DEFAULT (datetime('now')),
 			PRIMARY KEY (episode_id, entity_id)
 		);
@@ -875,3 +879,60 @@ func TestFormatSummary(t *testing.T) {
 func verifyStrPtr(s string) *string {
 	return &s
 }
+
+func TestRelationshipMatches_Symmetric(t *testing.T) {
+	h := NewVerificationHarness(nil, "", nil)
+	dana := "Dana"
+	rel := VerifyRelationship{RelationType: "SIBLING_OF", SourceEntityName: "Casey Adams", TargetEntityName: &dana}
+	expected := map[string]interface{}{
+		"relation_type":               "SIBLING_OF",
+		"source_entity_name_contains": "Dana",
+		"target_entity_name_contains": "Casey",
+	}
+	if h.relationshipMatches(expected, rel) {
+		t.Error("directed expectation should not match the reversed relationship")
+	}
+	expected["symmetric"] = true
+	if !h.relationshipMatches(expected, rel) {
+		t.Error("symmetric expectation should match either direction")
+	}
+}
+
+func TestNonEgoEvalSet(t *testing.T) {
+	h := NewVerificationHarness(nil, filepath.Join("testdata", "eval", "non_ego"), nil)
+	fixtures, err := h.LoadAllFixtures()
+	if err != nil {
+		t.Fatalf("LoadAllFixtures: %v", err)
+	}
+	if len(fixtures) < 5 {
+		t.Fatalf("loaded %d fixtures, want at least 5", len(fixtures))
+	}
+
+	for _, f := range fixtures {
+		speakers := map[string]bool{}
+		for _, e := range f.Episode.Events {
+			speakers[strings.ToLower(e.Sender)] = true
+		}
+		isSpeaker := func(v interface{}) bool {
+			name, _ := v.(string)
+			for speaker := range speakers {
+				if name != "" && strings.Contains(speaker, strings.ToLower(name)) {
+					return true
+				}
+			}
+			return false
+		}
+
+		// The point of the set: at least one expected edge between two people
+		// or things who aren't in the conversation
+		nonEgo := false
+		for _, rel := range f.Expectations.Relationships.MustHave {
+			if !isSpeaker(rel["source_entity_name_contains"]) && !isSpeaker(rel["target_entity_name_contains"]) {
+				nonEgo = true
+			}
+		}
+		if !nonEgo {
+			t.Errorf("%s/%s: no must_have relationship between non-participants", f.Source, f.Name)
+		}
+	}
+}
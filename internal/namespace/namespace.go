@@ -0,0 +1,53 @@
+// Package namespace separates household members who share one database.
+// Each member's "me" person and the sensitive facts extracted while they
+// are active carry that member's namespace; everything else - contacts,
+// events, the memory graph and ordinary facts - is shared. Rows without a
+// namespace are visible to everyone, so a single-user database behaves
+// exactly as before namespaces existed.
+package namespace
+
+import (
+	"fmt"
+	"regexp"
+	"sync/atomic"
+)
+
+var current atomic.Value // string
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// Validate checks a namespace name: lowercase letters, digits, '-' and '_',
+// at most 32 characters.
+func Validate(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid namespace %q: use lowercase letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// Set makes name the active household member for this process. Empty
+// means no member: only shared rows are visible. Set from the me.namespace
+// config key or MNEMONIC_NAMESPACE.
+func Set(name string) {
+	current.Store(name)
+}
+
+// Current returns the active household member, or "" when none is set.
+func Current() string {
+	name, _ := current.Load().(string)
+	return name
+}
+
+// Value is Current as a column value: nil (shared) when no member is set.
+func Value() interface{} {
+	if name := Current(); name != "" {
+		return name
+	}
+	return nil
+}
+
+// Visible returns a SQL condition that holds when column is shared (NULL)
+// or belongs to the active member, and its argument.
+func Visible(column string) (string, interface{}) {
+	return "(" + column + " IS NULL OR " + column + " = ?)", Current()
+}
@@ -0,0 +1,28 @@
+package namespace
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	for _, name := range []string{"tyler", "casey-lee", "user_2"} {
+		if err := Validate(name); err != nil {
+			t.Errorf("Validate(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "Tyler", "-casey", "a b", "this-namespace-name-is-far-too-long"} {
+		if err := Validate(name); err == nil {
+			t.Errorf("Validate(%q) should fail", name)
+		}
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	defer Set("")
+	if Current() != "" || Value() != nil {
+		t.Fatalf("default namespace = %q", Current())
+	}
+	Set("casey")
+	cond, arg := Visible("p.namespace")
+	if cond != "(p.namespace IS NULL OR p.namespace = ?)" || arg != "casey" || Value() != "casey" {
+		t.Errorf("Visible = %s, %v", cond, arg)
+	}
+}
@@ -0,0 +1,252 @@
+// Package notes keeps freeform notes and corrections people attach to an
+// episode or to a single event ("this was sarcasm", "Sam is my cousin, not
+// my coworker"). Notes are never part of the episode text: they are added
+// as context whenever the episode is analyzed or extracted again, so human
+// knowledge steers what gets extracted.
+package notes
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned for an unknown note ID.
+var ErrNotFound = errors.New("note not found")
+
+// ErrUnknownTarget is returned when a note is added to an ID that is
+// neither an episode nor an event.
+var ErrUnknownTarget = errors.New("no episode or event with this ID")
+
+// snippetChars bounds how much of a noted event is quoted with its note.
+const snippetChars = 80
+
+// Note is a note on an episode or on one event.
+type Note struct {
+	ID        string    `json:"id"`
+	EpisodeID string    `json:"episode_id,omitempty"`
+	EventID   string    `json:"event_id,omitempty"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	// Start of the noted event's content, so the note can be placed
+	EventSnippet string `json:"event_snippet,omitempty"`
+}
+
+// querier is satisfied by *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Add attaches a note to an episode or event. target is the episode or
+// event ID, or an unambiguous prefix of one.
+func Add(ctx context.Context, db *sql.DB, target, content string) (*Note, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, fmt.Errorf("note is empty")
+	}
+	episodeID, eventID, err := ResolveTarget(ctx, db, target)
+	if err != nil {
+		return nil, err
+	}
+	n := &Note{ID: uuid.New().String(), EpisodeID: episodeID, EventID: eventID, Content: content, CreatedAt: time.Now()}
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO episode_notes (id, episode_id, event_id, content, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, n.ID, nullIfEmpty(episodeID), nullIfEmpty(eventID), n.Content, n.CreatedAt.Unix()); err != nil {
+		return nil, fmt.Errorf("insert note: %w", err)
+	}
+	return n, nil
+}
+
+// ResolveTarget finds the episode or event an ID (or unambiguous ID prefix)
+// refers to. Exactly one of the returned IDs is set.
+func ResolveTarget(ctx context.Context, q querier, target string) (episodeID, eventID string, err error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return "", "", ErrUnknownTarget
+	}
+	for _, table := range []string{"episodes", "events"} {
+		id, err := resolveID(ctx, q, table, target)
+		if err != nil {
+			return "", "", err
+		}
+		if id == "" {
+			continue
+		}
+		if table == "episodes" {
+			return id, "", nil
+		}
+		return "", id, nil
+	}
+	return "", "", fmt.Errorf("%w: %s", ErrUnknownTarget, target)
+}
+
+// resolveID returns the row of table with this ID, or the only one whose ID
+// starts with it; empty when there is none.
+func resolveID(ctx context.Context, q querier, table, target string) (string, error) {
+	var id string
+	err := q.QueryRowContext(ctx, `SELECT id FROM `+table+` WHERE id = ?`, target).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", fmt.Errorf("lookup %s: %w", table, err)
+	}
+	rows, err := q.QueryContext(ctx, `SELECT id FROM `+table+` WHERE id LIKE ? || '%' LIMIT 2`, target)
+	if err != nil {
+		return "", fmt.Errorf("lookup %s: %w", table, err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		if err := rows.Scan(&id); err != nil {
+			return "", err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if len(ids) > 1 {
+		return "", fmt.Errorf("ID prefix %s matches more than one of %s", target, table)
+	}
+	if len(ids) == 1 {
+		return ids[0], nil
+	}
+	return "", nil
+}
+
+// List returns notes, newest first: every note when target is empty, the
+// notes on an episode and its events when it is an episode, or the notes
+// on one event.
+func List(ctx context.Context, db *sql.DB, target string) ([]Note, error) {
+	if target == "" {
+		return query(ctx, db, `1 = 1`, `n.created_at DESC, n.id`)
+	}
+	episodeID, eventID, err := ResolveTarget(ctx, db, target)
+	if err != nil {
+		return nil, err
+	}
+	if episodeID != "" {
+		return query(ctx, db, episodeCondition, `n.created_at DESC, n.id`, episodeID, episodeID)
+	}
+	return query(ctx, db, `n.event_id = ?`, `n.created_at DESC, n.id`, eventID)
+}
+
+// ForEpisode returns the notes on an episode and on its events, oldest
+// first, for adding to the episode's prompt context.
+func ForEpisode(ctx context.Context, q querier, episodeID string) ([]Note, error) {
+	return query(ctx, q, episodeCondition, `n.created_at, n.id`, episodeID, episodeID)
+}
+
+// episodeCondition matches an episode's own notes and its events' notes.
+const episodeCondition = `(n.episode_id = ? OR n.event_id IN (SELECT event_id FROM episode_events WHERE episode_id = ?))`
+
+func query(ctx context.Context, q querier, where, order string, args ...interface{}) ([]Note, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT n.id, COALESCE(n.episode_id, ''), COALESCE(n.event_id, ''), n.content, n.created_at,
+		       COALESCE((SELECT ev.content FROM events ev WHERE ev.id = n.event_id), '')
+		FROM episode_notes n
+		WHERE `+where+`
+		ORDER BY `+order, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query notes: %w", err)
+	}
+	defer rows.Close()
+	var notes []Note
+	for rows.Next() {
+		var n Note
+		var createdAt int64
+		var eventContent string
+		if err := rows.Scan(&n.ID, &n.EpisodeID, &n.EventID, &n.Content, &createdAt, &eventContent); err != nil {
+			return nil, fmt.Errorf("scan note: %w", err)
+		}
+		n.CreatedAt = time.Unix(createdAt, 0)
+		n.EventSnippet = snippet(eventContent)
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+// Delete removes a note by ID or unambiguous ID prefix.
+func Delete(ctx context.Context, db *sql.DB, id string) error {
+	full, err := resolveID(ctx, db, "episode_notes", strings.TrimSpace(id))
+	if err != nil {
+		return err
+	}
+	if full == "" {
+		return ErrNotFound
+	}
+	if _, err := db.ExecContext(ctx, `DELETE FROM episode_notes WHERE id = ?`, full); err != nil {
+		return fmt.Errorf("delete note: %w", err)
+	}
+	return nil
+}
+
+// Lines renders notes one per line, each note on an event prefixed with
+// the start of that event.
+func Lines(notes []Note) []string {
+	lines := make([]string, 0, len(notes))
+	for _, n := range notes {
+		if n.EventSnippet != "" {
+			lines = append(lines, fmt.Sprintf("On the message %q: %s", n.EventSnippet, n.Content))
+		} else {
+			lines = append(lines, n.Content)
+		}
+	}
+	return lines
+}
+
+// PromptSection renders notes as a prompt block; empty without notes.
+func PromptSection(notes []Note) string {
+	if len(notes) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("<USER_NOTES>\n")
+	sb.WriteString("Notes the user added to this conversation. They are the user's own knowledge: use them to interpret the conversation, and trust them over the conversation where they disagree.\n")
+	for _, line := range Lines(notes) {
+		sb.WriteString("- ")
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("</USER_NOTES>\n")
+	return sb.String()
+}
+
+// Hash returns a hash of the notes' content, empty without notes. Folded
+// into an episode's content hash, it makes a new or removed note count as
+// changed content.
+func Hash(notes []Note) string {
+	if len(notes) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	for _, n := range notes {
+		fmt.Fprintf(h, "%s\x00%s\n", n.ID, n.Content)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func snippet(content string) string {
+	content = strings.Join(strings.Fields(content), " ")
+	if len([]rune(content)) <= snippetChars {
+		return content
+	}
+	return string([]rune(content)[:snippetChars]) + "..."
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
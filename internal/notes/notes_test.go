@@ -0,0 +1,100 @@
+package notes
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestNotes(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.Exec(`
+		INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0);
+		INSERT INTO events (id, timestamp, channel, content_types, content, direction, source_adapter, source_id)
+		VALUES ('ev1', 100, 'imessage', '["text"]', 'Oh great, another Monday at my favorite job', 'received', 'test', 'ev1'),
+		       ('ev2', 110, 'imessage', '["text"]', 'lol', 'sent', 'test', 'ev2'),
+		       ('ev3', 500, 'imessage', '["text"]', 'elsewhere', 'sent', 'test', 'ev3');
+		INSERT INTO episodes (id, definition_id, channel, start_time, end_time, event_count, created_at)
+		VALUES ('ep-1234', 'def', 'imessage', 100, 110, 2, 0), ('ep-9999', 'def', 'imessage', 500, 500, 1, 0);
+		INSERT INTO episode_events (episode_id, event_id, position) VALUES ('ep-1234', 'ev1', 1), ('ep-1234', 'ev2', 2), ('ep-9999', 'ev3', 1);
+	`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	if _, err := Add(ctx, db, "ep-1234", "  "); err == nil {
+		t.Fatal("empty note accepted")
+	}
+	if _, err := Add(ctx, db, "nope", "text"); !errors.Is(err, ErrUnknownTarget) {
+		t.Fatalf("unknown target: err = %v, want ErrUnknownTarget", err)
+	}
+	if _, err := Add(ctx, db, "ep-", "text"); err == nil {
+		t.Fatal("ambiguous prefix accepted")
+	}
+
+	onEpisode, err := Add(ctx, db, "ep-12", "Casey hates her job; this is sarcasm")
+	if err != nil {
+		t.Fatalf("Add episode note: %v", err)
+	}
+	if onEpisode.EpisodeID != "ep-1234" || onEpisode.EventID != "" {
+		t.Errorf("episode note target = %q/%q", onEpisode.EpisodeID, onEpisode.EventID)
+	}
+	onEvent, err := Add(ctx, db, "ev1", "this was sarcasm")
+	if err != nil {
+		t.Fatalf("Add event note: %v", err)
+	}
+	if onEvent.EventID != "ev1" || onEvent.EpisodeID != "" {
+		t.Errorf("event note target = %q/%q", onEvent.EpisodeID, onEvent.EventID)
+	}
+	if _, err := Add(ctx, db, "ev3", "unrelated"); err != nil {
+		t.Fatalf("Add other note: %v", err)
+	}
+
+	got, err := ForEpisode(ctx, db, "ep-1234")
+	if err != nil {
+		t.Fatalf("ForEpisode: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("ForEpisode = %d notes, want 2 (episode + its event)", len(got))
+	}
+	lines := Lines(got)
+	if !strings.Contains(strings.Join(lines, "\n"), `On the message "Oh great, another Monday at my favorite job": this was sarcasm`) {
+		t.Errorf("Lines = %q", lines)
+	}
+	section := PromptSection(got)
+	if !strings.HasPrefix(section, "<USER_NOTES>") || !strings.Contains(section, "- Casey hates her job") {
+		t.Errorf("PromptSection = %q", section)
+	}
+	if PromptSection(nil) != "" || Hash(nil) != "" {
+		t.Error("empty notes should render nothing")
+	}
+
+	list, err := List(ctx, db, "ev1")
+	if err != nil || len(list) != 1 || list[0].ID != onEvent.ID {
+		t.Fatalf("List(ev1) = %+v, %v", list, err)
+	}
+	all, err := List(ctx, db, "")
+	if err != nil || len(all) != 3 {
+		t.Fatalf("List() = %d notes, %v; want 3", len(all), err)
+	}
+
+	before := Hash(got)
+	if err := Delete(ctx, db, onEvent.ID[:8]); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := Delete(ctx, db, onEvent.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete: err = %v, want ErrNotFound", err)
+	}
+	got, err = ForEpisode(ctx, db, "ep-1234")
+	if err != nil || len(got) != 1 {
+		t.Fatalf("ForEpisode after delete = %d notes, %v", len(got), err)
+	}
+	if Hash(got) == before {
+		t.Error("hash unchanged after removing a note")
+	}
+}
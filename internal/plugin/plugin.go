@@ -0,0 +1,305 @@
+// Package plugin runs external programs that extend cortex without being
+// compiled into it: adapters that sync events from sources cortex doesn't
+// know, post-processors that rewrite analysis output before it is stored,
+// and exporters that receive a slice of the memory graph.
+//
+// The protocol is JSON over stdio. For every call cortex starts the
+// program, writes one Request as a JSON line to its stdin and closes it.
+// The program answers with JSON lines on stdout, each a Message:
+//
+//	{"event": {...}}     an adapter event (adapters only, any number)
+//	{"log": "..."}       a progress line, written to cortex's log
+//	{"result": {...}}    the call's result (once, at the end)
+//	{"error": "..."}     the call failed
+//
+// and exits 0. Stderr is kept for error messages. Requests carry the
+// protocol version; plugins should fail on versions they don't know.
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/config"
+)
+
+// ProtocolVersion is sent with every request.
+const ProtocolVersion = 1
+
+// DefaultTimeout bounds a call when the plugin config sets none.
+const DefaultTimeout = 10 * time.Minute
+
+// maxMessageBytes caps one stdout line (a single event or result).
+const maxMessageBytes = 16 * 1024 * 1024
+
+// stderrTail is how much of the plugin's stderr is kept for errors.
+const stderrTail = 4096
+
+// Kind is what a plugin extends.
+type Kind string
+
+const (
+	KindAdapter       Kind = "adapter"
+	KindPostprocessor Kind = "postprocessor"
+	KindExporter      Kind = "exporter"
+)
+
+var kinds = map[Kind]bool{KindAdapter: true, KindPostprocessor: true, KindExporter: true}
+
+// Plugin is a configured external program.
+type Plugin struct {
+	Name    string
+	Kind    Kind
+	Command string
+	Args    []string
+	Env     map[string]string
+	Timeout time.Duration
+	// AnalysisTypes limits a post-processor to these analysis types (empty = all)
+	AnalysisTypes []string
+}
+
+// Request is written to the plugin's stdin.
+type Request struct {
+	Protocol int         `json:"protocol"`
+	Kind     Kind        `json:"kind"`
+	Plugin   string      `json:"plugin"`
+	Params   interface{} `json:"params"`
+}
+
+// Message is one line of plugin output.
+type Message struct {
+	Event  json.RawMessage `json:"event,omitempty"`
+	Log    string          `json:"log,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// FromConfig validates a plugin config.
+func FromConfig(name string, cfg config.PluginConfig) (*Plugin, error) {
+	p := &Plugin{
+		Name:          name,
+		Kind:          Kind(strings.ToLower(strings.TrimSpace(cfg.Kind))),
+		Command:       expandHome(strings.TrimSpace(cfg.Command)),
+		Args:          cfg.Args,
+		Env:           cfg.Env,
+		Timeout:       DefaultTimeout,
+		AnalysisTypes: cfg.AnalysisTypes,
+	}
+	if !kinds[p.Kind] {
+		return nil, fmt.Errorf("plugin %s: unknown kind %q (adapter, postprocessor or exporter)", name, cfg.Kind)
+	}
+	if p.Command == "" {
+		return nil, fmt.Errorf("plugin %s: command is required", name)
+	}
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("plugin %s: invalid timeout %q", name, cfg.Timeout)
+		}
+		p.Timeout = d
+	}
+	return p, nil
+}
+
+// FromOptions builds an adapter plugin from an adapter's config options
+// (command, args, env, timeout).
+func FromOptions(name string, opts map[string]interface{}) (*Plugin, error) {
+	cfg := config.PluginConfig{Kind: string(KindAdapter)}
+	cfg.Command, _ = opts["command"].(string)
+	cfg.Timeout, _ = opts["timeout"].(string)
+	if args, ok := opts["args"].([]interface{}); ok {
+		for _, a := range args {
+			cfg.Args = append(cfg.Args, fmt.Sprint(a))
+		}
+	}
+	if env, ok := opts["env"].(map[string]interface{}); ok {
+		cfg.Env = map[string]string{}
+		for k, v := range env {
+			cfg.Env[k] = fmt.Sprint(v)
+		}
+	}
+	return FromConfig(name, cfg)
+}
+
+// Load returns the configured plugins of one kind, by name.
+func Load(cfg *config.Config, kind Kind) ([]*Plugin, error) {
+	var plugins []*Plugin
+	for name, pc := range cfg.Plugins {
+		p, err := FromConfig(name, pc)
+		if err != nil {
+			return nil, err
+		}
+		if p.Kind == kind {
+			plugins = append(plugins, p)
+		}
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins, nil
+}
+
+// Get returns the configured plugin with this name, which must be of kind.
+func Get(cfg *config.Config, name string, kind Kind) (*Plugin, error) {
+	pc, ok := cfg.Plugins[name]
+	if !ok {
+		return nil, fmt.Errorf("plugin %s is not configured", name)
+	}
+	p, err := FromConfig(name, pc)
+	if err != nil {
+		return nil, err
+	}
+	if p.Kind != kind {
+		return nil, fmt.Errorf("plugin %s is of kind %s, not %s", name, p.Kind, kind)
+	}
+	return p, nil
+}
+
+// Handles reports whether a post-processor runs on this analysis type.
+func (p *Plugin) Handles(analysisType string) bool {
+	if len(p.AnalysisTypes) == 0 {
+		return true
+	}
+	for _, t := range p.AnalysisTypes {
+		if t == analysisType {
+			return true
+		}
+	}
+	return false
+}
+
+// Call runs the plugin once with params and returns its result. Events are
+// passed to onEvent as they arrive; an error from onEvent stops the plugin
+// and is returned. A nil onEvent makes events a protocol error.
+func (p *Plugin) Call(ctx context.Context, params interface{}, onEvent func(json.RawMessage) error) (json.RawMessage, error) {
+	input, err := json.Marshal(Request{Protocol: ProtocolVersion, Kind: p.Kind, Plugin: p.Name, Params: params})
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: encode request: %w", p.Name, err)
+	}
+
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("CORTEX_PLUGIN_PROTOCOL=%d", ProtocolVersion))
+	for k, v := range p.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	stderr := &tailBuffer{max: stderrTail}
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", p.Name, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("plugin %s: start %s: %w", p.Name, p.Command, err)
+	}
+
+	result, pluginErr, readErr := p.read(stdout, onEvent)
+	if readErr != nil {
+		cancel()
+	}
+	waitErr := cmd.Wait()
+
+	switch {
+	case readErr != nil:
+		return nil, readErr
+	case ctx.Err() == context.DeadlineExceeded:
+		return nil, fmt.Errorf("plugin %s: timed out after %s", p.Name, timeout)
+	case pluginErr != "":
+		return nil, fmt.Errorf("plugin %s: %s", p.Name, pluginErr)
+	case waitErr != nil:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("plugin %s: %v: %s", p.Name, waitErr, msg)
+		}
+		return nil, fmt.Errorf("plugin %s: %w", p.Name, waitErr)
+	case result == nil:
+		return nil, fmt.Errorf("plugin %s: exited without a result", p.Name)
+	}
+	return result, nil
+}
+
+// read consumes the plugin's messages until stdout closes.
+func (p *Plugin) read(stdout io.Reader, onEvent func(json.RawMessage) error) (result json.RawMessage, pluginErr string, err error) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageBytes)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var msg Message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return nil, "", fmt.Errorf("plugin %s: invalid message %q: %w", p.Name, truncate(string(line), 200), err)
+		}
+		switch {
+		case msg.Event != nil:
+			if onEvent == nil {
+				return nil, "", fmt.Errorf("plugin %s: %s plugins can't send events", p.Name, p.Kind)
+			}
+			if err := onEvent(msg.Event); err != nil {
+				return nil, "", err
+			}
+		case msg.Error != "":
+			pluginErr = msg.Error
+		case msg.Result != nil:
+			result = msg.Result
+		case msg.Log != "":
+			log.Printf("plugin %s: %s", p.Name, msg.Log)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", fmt.Errorf("plugin %s: read output: %w", p.Name, err)
+	}
+	return result, pluginErr, nil
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	buf []byte
+	max int
+}
+
+func (t *tailBuffer) Write(b []byte) (int, error) {
+	t.buf = append(t.buf, b...)
+	if len(t.buf) > t.max {
+		t.buf = t.buf[len(t.buf)-t.max:]
+	}
+	return len(b), nil
+}
+
+func (t *tailBuffer) String() string {
+	return string(t.buf)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
@@ -0,0 +1,101 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/config"
+)
+
+// script is a plugin running a shell script.
+func script(kind Kind, body string) *Plugin {
+	return &Plugin{Name: "test", Kind: kind, Command: "sh", Args: []string{"-c", body}, Timeout: 10 * time.Second}
+}
+
+func TestCall(t *testing.T) {
+	ctx := context.Background()
+
+	// The request arrives on stdin; events stream before the result
+	p := script(KindAdapter, `
+		read req
+		case "$req" in *'"protocol":1'*'"params":{"x":2}'*) ;; *) echo '{"error":"bad request"}'; exit 0;; esac
+		echo '{"log":"starting"}'
+		echo '{"event":{"id":"a"}}'
+		echo
+		echo '{"event":{"id":"b"}}'
+		echo '{"result":{"ok":true}}'
+	`)
+	var events []string
+	res, err := p.Call(ctx, map[string]int{"x": 2}, func(ev json.RawMessage) error {
+		events = append(events, string(ev))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if string(res) != `{"ok":true}` || len(events) != 2 || events[1] != `{"id":"b"}` {
+		t.Errorf("result %s, events %v", res, events)
+	}
+
+	failures := []struct {
+		name, body, want string
+	}{
+		{"error message", `echo '{"error":"token expired"}'`, "plugin test: token expired"},
+		{"exit status", `echo 'no such account' >&2; exit 3`, "exit status 3: no such account"},
+		{"no result", `true`, "exited without a result"},
+		{"invalid output", `echo 'hello'`, "invalid message"},
+		{"unexpected event", `echo '{"event":{}}'`, "can't send events"},
+	}
+	for _, f := range failures {
+		kind := KindPostprocessor
+		_, err := script(kind, f.body).Call(ctx, nil, nil)
+		if err == nil || !strings.Contains(err.Error(), f.want) {
+			t.Errorf("%s: err = %v, want %q", f.name, err, f.want)
+		}
+	}
+
+	slow := script(KindExporter, `exec sleep 5; echo '{"result":{}}'`)
+	slow.Timeout = 100 * time.Millisecond
+	if _, err := slow.Call(ctx, nil, nil); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("slow plugin: %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	cfg := &config.Config{Plugins: map[string]config.PluginConfig{
+		"redact": {Kind: "postprocessor", Command: "redact", AnalysisTypes: []string{"pii_extraction_v1"}},
+		"notion": {Kind: "exporter", Command: "notion-export", Timeout: "30s"},
+		"audit":  {Kind: "postprocessor", Command: "audit"},
+	}}
+	post, err := Load(cfg, KindPostprocessor)
+	if err != nil || len(post) != 2 || post[0].Name != "audit" {
+		t.Fatalf("Load = %+v, %v", post, err)
+	}
+	if !post[0].Handles("anything") || post[1].Handles("anything") || !post[1].Handles("pii_extraction_v1") {
+		t.Error("analysis type filter")
+	}
+	if p, err := Get(cfg, "notion", KindExporter); err != nil || p.Timeout != 30*time.Second {
+		t.Errorf("Get = %+v, %v", p, err)
+	}
+	if _, err := Get(cfg, "redact", KindExporter); err == nil {
+		t.Error("Get accepted a post-processor as an exporter")
+	}
+
+	for _, bad := range []config.PluginConfig{
+		{Kind: "importer", Command: "x"},
+		{Kind: "exporter"},
+		{Kind: "exporter", Command: "x", Timeout: "soon"},
+	} {
+		if _, err := FromConfig("bad", bad); err == nil {
+			t.Errorf("FromConfig accepted %+v", bad)
+		}
+	}
+
+	p, err := FromOptions("slack", map[string]interface{}{"command": "slack-sync", "args": []interface{}{"--team", 42}})
+	if err != nil || p.Kind != KindAdapter || strings.Join(p.Args, " ") != "--team 42" {
+		t.Errorf("FromOptions = %+v, %v", p, err)
+	}
+}
@@ -0,0 +1,230 @@
+package power
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/config"
+)
+
+// Action is what a job should do under the current power state.
+type Action string
+
+const (
+	ActionRun      Action = "run"
+	ActionThrottle Action = "throttle" // run, but at most one job per ThrottleDelay
+	ActionPause    Action = "pause"    // wait until conditions allow
+)
+
+// Defaults for PowerConfig fields left empty.
+const (
+	DefaultIdleAfter     = 5 * time.Minute
+	DefaultThrottleDelay = 2 * time.Second
+	DefaultPollInterval  = 30 * time.Second
+)
+
+// Policy maps power conditions to actions for one job type.
+type Policy struct {
+	OnBattery  Action `json:"on_battery"`
+	WhenActive Action `json:"when_active"`
+}
+
+// DefaultPolicy pauses on battery and throttles while the user is active.
+var DefaultPolicy = Policy{OnBattery: ActionPause, WhenActive: ActionThrottle}
+
+// Decide returns the strictest action that applies to state, and why.
+func (p Policy) Decide(state State, idleAfter time.Duration) (Action, string) {
+	action, reason := ActionRun, ""
+	if state.OnBattery && rank(p.OnBattery) > rank(action) {
+		action, reason = p.OnBattery, "on battery"
+	}
+	if state.IdleKnown && state.IdleFor < idleAfter && rank(p.WhenActive) > rank(action) {
+		action, reason = p.WhenActive, "user active"
+	}
+	return action, reason
+}
+
+func rank(a Action) int {
+	switch a {
+	case ActionThrottle:
+		return 1
+	case ActionPause:
+		return 2
+	}
+	return 0
+}
+
+// Gate holds background jobs back according to per-job-type policies. A nil
+// *Gate lets everything run, so callers need not check whether power
+// management is enabled.
+type Gate struct {
+	IdleAfter     time.Duration
+	ThrottleDelay time.Duration
+	PollInterval  time.Duration // how often state is re-probed
+	Logf          func(format string, args ...any)
+
+	def      Policy
+	policies map[string]Policy
+
+	probe func() State
+	now   func() time.Time
+
+	mu        sync.Mutex
+	state     State
+	probedAt  time.Time
+	paused    map[string]string // job type -> reason, while paused
+	throttle  sync.Mutex
+	lastStart time.Time
+}
+
+// NewGate builds a Gate from config. It returns nil when power management is
+// disabled.
+func NewGate(cfg config.PowerConfig) (*Gate, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	g := &Gate{
+		IdleAfter:     DefaultIdleAfter,
+		ThrottleDelay: DefaultThrottleDelay,
+		PollInterval:  DefaultPollInterval,
+		Logf:          log.Printf,
+		policies:      make(map[string]Policy),
+		probe:         Probe,
+		now:           time.Now,
+		paused:        make(map[string]string),
+	}
+	var err error
+	if cfg.IdleAfter != "" {
+		if g.IdleAfter, err = time.ParseDuration(cfg.IdleAfter); err != nil || g.IdleAfter < 0 {
+			return nil, fmt.Errorf("invalid power.idle_after: %q", cfg.IdleAfter)
+		}
+	}
+	if cfg.ThrottleDelay != "" {
+		if g.ThrottleDelay, err = time.ParseDuration(cfg.ThrottleDelay); err != nil || g.ThrottleDelay < 0 {
+			return nil, fmt.Errorf("invalid power.throttle_delay: %q", cfg.ThrottleDelay)
+		}
+	}
+	if g.def, err = parsePolicy(cfg.Default, DefaultPolicy); err != nil {
+		return nil, fmt.Errorf("power.default: %w", err)
+	}
+	for jobType, pc := range cfg.Jobs {
+		if g.policies[jobType], err = parsePolicy(pc, g.def); err != nil {
+			return nil, fmt.Errorf("power.jobs.%s: %w", jobType, err)
+		}
+	}
+	return g, nil
+}
+
+func parsePolicy(pc config.PowerPolicyConfig, base Policy) (Policy, error) {
+	p := base
+	for _, f := range []struct {
+		value string
+		dst   *Action
+	}{{pc.OnBattery, &p.OnBattery}, {pc.WhenActive, &p.WhenActive}} {
+		switch Action(f.value) {
+		case "":
+		case ActionRun, ActionThrottle, ActionPause:
+			*f.dst = Action(f.value)
+		default:
+			return p, fmt.Errorf("invalid action %q (want run, throttle, or pause)", f.value)
+		}
+	}
+	return p, nil
+}
+
+// Policy returns the policy for a job type.
+func (g *Gate) Policy(jobType string) Policy {
+	if p, ok := g.policies[jobType]; ok {
+		return p
+	}
+	return g.def
+}
+
+// State returns the power state, re-probing at most every PollInterval.
+func (g *Gate) State() State {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if now := g.now(); g.probedAt.IsZero() || now.Sub(g.probedAt) >= g.PollInterval {
+		g.state, g.probedAt = g.probe(), now
+	}
+	return g.state
+}
+
+// Decide returns what a job of the given type should do right now.
+func (g *Gate) Decide(jobType string) (Action, string) {
+	if g == nil {
+		return ActionRun, ""
+	}
+	return g.Policy(jobType).Decide(g.State(), g.IdleAfter)
+}
+
+// Wait blocks until a job of the given type may start: while paused it
+// re-checks every PollInterval, and when throttled it spaces job starts at
+// least ThrottleDelay apart across all job types.
+func (g *Gate) Wait(ctx context.Context, jobType string) error {
+	if g == nil {
+		return nil
+	}
+	for {
+		action, reason := g.Decide(jobType)
+		g.notePause(jobType, action, reason)
+		switch action {
+		case ActionPause:
+			if err := sleepCtx(ctx, g.PollInterval); err != nil {
+				return err
+			}
+			continue
+		case ActionThrottle:
+			g.throttle.Lock()
+			wait := g.lastStart.Add(g.ThrottleDelay).Sub(g.now())
+			err := sleepCtx(ctx, wait)
+			g.lastStart = g.now()
+			g.throttle.Unlock()
+			return err
+		}
+		return nil
+	}
+}
+
+// Allow reports whether a job of the given type may start now, for callers
+// that skip work rather than wait. Throttling counts as allowed.
+func (g *Gate) Allow(jobType string) (bool, string) {
+	if g == nil {
+		return true, ""
+	}
+	action, reason := g.Decide(jobType)
+	g.notePause(jobType, action, reason)
+	return action != ActionPause, reason
+}
+
+// notePause logs when a job type starts or stops being paused.
+func (g *Gate) notePause(jobType string, action Action, reason string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, was := g.paused[jobType]
+	switch {
+	case action == ActionPause && !was:
+		g.paused[jobType] = reason
+		g.Logf("power: pausing %s jobs (%s)", jobType, reason)
+	case action != ActionPause && was:
+		delete(g.paused, jobType)
+		g.Logf("power: resuming %s jobs", jobType)
+	}
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,100 @@
+package power
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/config"
+)
+
+func TestParsers(t *testing.T) {
+	onBattery, percent := parsePmset("Now drawing from 'Battery Power'\n -InternalBattery-0 (id=4653155)\t85%; discharging; 4:12 remaining present: true\n")
+	if !onBattery || percent != 85 {
+		t.Errorf("pmset battery = %v, %d", onBattery, percent)
+	}
+	if onBattery, _ := parsePmset("Now drawing from 'AC Power'\n -InternalBattery-0 (id=1)\t100%; charged; 0:00 remaining\n"); onBattery {
+		t.Error("pmset AC should not be on battery")
+	}
+
+	idle, ok := parseIoregIdle(`    |   "HIDIdleTime" = 90000000000` + "\n")
+	if !ok || idle != 90*time.Second {
+		t.Errorf("ioreg idle = %v, %v", idle, ok)
+	}
+
+	root := t.TempDir()
+	write := func(dir, file, value string) {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, dir, file), []byte(value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("AC", "type", "Mains")
+	write("AC", "online", "0")
+	write("BAT0", "type", "Battery")
+	write("BAT0", "capacity", "42")
+	if onBattery, percent := readPowerSupply(root); !onBattery || percent != 42 {
+		t.Errorf("unplugged laptop = %v, %d", onBattery, percent)
+	}
+	write("AC", "online", "1")
+	if onBattery, _ := readPowerSupply(root); onBattery {
+		t.Error("plugged-in laptop should not be on battery")
+	}
+}
+
+func TestGate(t *testing.T) {
+	if g, err := NewGate(config.PowerConfig{}); g != nil || err != nil {
+		t.Fatalf("disabled gate = %v, %v", g, err)
+	}
+	var nilGate *Gate
+	if err := nilGate.Wait(context.Background(), "analysis"); err != nil {
+		t.Errorf("nil gate Wait = %v", err)
+	}
+
+	g, err := NewGate(config.PowerConfig{
+		Enabled: true,
+		Default: config.PowerPolicyConfig{WhenActive: "run"},
+		Jobs:    map[string]config.PowerPolicyConfig{"backup": {OnBattery: "run", WhenActive: "pause"}},
+	})
+	if err != nil {
+		t.Fatalf("NewGate: %v", err)
+	}
+	state := State{OnBattery: true, IdleKnown: true, IdleFor: time.Minute}
+	g.probe = func() State { return state }
+	g.Logf = func(string, ...any) {}
+	g.PollInterval = 0
+
+	// Per-type overrides fall back to the default for unset fields
+	if p := g.Policy("analysis"); p.OnBattery != ActionPause || p.WhenActive != ActionRun {
+		t.Errorf("analysis policy = %+v", p)
+	}
+	if action, reason := g.Decide("analysis"); action != ActionPause || reason != "on battery" {
+		t.Errorf("analysis on battery = %s (%s)", action, reason)
+	}
+	if action, reason := g.Decide("backup"); action != ActionPause || reason != "user active" {
+		t.Errorf("backup while active = %s (%s)", action, reason)
+	}
+
+	// Plugged in and idle: everything runs
+	state = State{IdleKnown: true, IdleFor: time.Hour}
+	if ok, _ := g.Allow("backup"); !ok {
+		t.Error("backup should run when plugged in and idle")
+	}
+
+	// A paused Wait returns when the context ends
+	state = State{OnBattery: true}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	g.PollInterval, g.probedAt = 5*time.Millisecond, time.Time{}
+	if err := g.Wait(ctx, "analysis"); err == nil {
+		t.Error("paused Wait should end with the context error")
+	}
+
+	if _, err := NewGate(config.PowerConfig{Enabled: true, Default: config.PowerPolicyConfig{OnBattery: "sleep"}}); err == nil {
+		t.Error("invalid action should fail")
+	}
+}
@@ -0,0 +1,119 @@
+// Package power detects battery and user-idle state so background jobs can
+// pause or slow down while a laptop is unplugged or in use.
+package power
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// State is a snapshot of the machine's power and input state. Fields that
+// could not be determined are left at their "don't hold back" values: not on
+// battery, idle.
+type State struct {
+	OnBattery      bool          `json:"on_battery"`
+	BatteryPercent int           `json:"battery_percent"` // -1 if unknown
+	IdleFor        time.Duration `json:"idle_for"`        // time since last keyboard/mouse input
+	IdleKnown      bool          `json:"idle_known"`
+}
+
+// Probe reads the current state. On macOS it uses pmset and ioreg; on Linux
+// /sys/class/power_supply and, when installed, xprintidle.
+func Probe() State {
+	state := State{BatteryPercent: -1}
+	switch runtime.GOOS {
+	case "darwin":
+		if out, err := exec.Command("pmset", "-g", "batt").Output(); err == nil {
+			state.OnBattery, state.BatteryPercent = parsePmset(string(out))
+		}
+		if out, err := exec.Command("ioreg", "-c", "IOHIDSystem", "-d", "4", "-r", "-k", "HIDIdleTime").Output(); err == nil {
+			state.IdleFor, state.IdleKnown = parseIoregIdle(string(out))
+		}
+	case "linux":
+		state.OnBattery, state.BatteryPercent = readPowerSupply("/sys/class/power_supply")
+		if out, err := exec.Command("xprintidle").Output(); err == nil {
+			if ms, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64); err == nil {
+				state.IdleFor, state.IdleKnown = time.Duration(ms)*time.Millisecond, true
+			}
+		}
+	}
+	return state
+}
+
+var percentRe = regexp.MustCompile(`(\d+)%`)
+
+// parsePmset parses `pmset -g batt`:
+//
+//	Now drawing from 'Battery Power'
+//	 -InternalBattery-0 (id=1234)	85%; discharging; 4:12 remaining present: true
+func parsePmset(out string) (onBattery bool, percent int) {
+	percent = -1
+	onBattery = strings.Contains(out, "'Battery Power'")
+	if m := percentRe.FindStringSubmatch(out); m != nil {
+		percent, _ = strconv.Atoi(m[1])
+	}
+	return onBattery, percent
+}
+
+var hidIdleRe = regexp.MustCompile(`"HIDIdleTime"\s*=\s*(\d+)`)
+
+// parseIoregIdle parses the HIDIdleTime (nanoseconds) from ioreg output.
+func parseIoregIdle(out string) (time.Duration, bool) {
+	m := hidIdleRe.FindStringSubmatch(out)
+	if m == nil {
+		return 0, false
+	}
+	ns, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(ns), true
+}
+
+// readPowerSupply reads Linux power supplies under root. The machine is on
+// battery when it has a mains supply and none is online; desktops without
+// a battery never are.
+func readPowerSupply(root string) (onBattery bool, percent int) {
+	percent = -1
+	dirs, err := os.ReadDir(root)
+	if err != nil {
+		return false, percent
+	}
+	hasMains, mainsOnline, hasBattery := false, false, false
+	for _, dir := range dirs {
+		path := filepath.Join(root, dir.Name())
+		switch readFirstLine(filepath.Join(path, "type")) {
+		case "Mains":
+			hasMains = true
+			if readFirstLine(filepath.Join(path, "online")) == "1" {
+				mainsOnline = true
+			}
+		case "Battery":
+			hasBattery = true
+			if n, err := strconv.Atoi(readFirstLine(filepath.Join(path, "capacity"))); err == nil && percent < 0 {
+				percent = n
+			}
+		}
+	}
+	return hasMains && hasBattery && !mainsOnline, percent
+}
+
+func readFirstLine(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	if scanner.Scan() {
+		return strings.TrimSpace(scanner.Text())
+	}
+	return ""
+}
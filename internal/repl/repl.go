@@ -0,0 +1,388 @@
+// Package repl implements 'mnemonic repl', an interactive session over one
+// open database. Exploratory lookups - find an entity, show it, walk to its
+// neighbours - then cost a query each instead of a process start, config
+// load, and database open.
+package repl
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/errs"
+	"github.com/Napageneral/mnemonic/internal/memory"
+	"github.com/Napageneral/mnemonic/internal/search"
+)
+
+// Limits on what one command prints and how much history is kept.
+const (
+	ListLimit   = 20
+	HistoryKeep = 1000
+)
+
+const helpText = `Commands:
+  find <name>          entities whose name contains <name>
+  show <entity>        an entity's summary, aliases, and relationships
+  related <entity>     entities connected to <entity>, strongest first
+  ask <question>       semantic search over episodes (needs GEMINI_API_KEY)
+  query <expr>         graph query, as in 'mnemonic query'
+  history              previous commands; !! repeats the last, !<n> repeats n
+  help                 this text
+  quit                 leave (also exit, or Ctrl-D)
+
+<entity> is an entity ID, a #n from the last list, or a name that matches
+exactly one entity.
+`
+
+// Session is one REPL session. Set Searcher to enable ask.
+type Session struct {
+	DB          *sql.DB
+	Out         io.Writer
+	HistoryPath string // where history persists; empty keeps it in memory
+	Prompt      string
+
+	Searcher *search.Searcher
+	Model    string // embedding model for ask
+	// Preview returns a short excerpt of an episode for ask results.
+	Preview func(ctx context.Context, episodeID string) string
+
+	engine  *memory.QueryEngine
+	history []string
+	last    []string // entity IDs of the last list, for #n references
+}
+
+// New returns a session over db that writes to out. Call Close when done.
+func New(db *sql.DB, out io.Writer) *Session {
+	return &Session{DB: db, Out: out, Prompt: "mnemonic> ", engine: memory.NewQueryEngine(db)}
+}
+
+// Close releases the session's prepared statements.
+func (s *Session) Close() error {
+	return s.engine.Close()
+}
+
+// errQuit ends Run.
+var errQuit = errors.New("quit")
+
+// Run reads commands from in until EOF, quit, or ctx ends. Command errors
+// are printed and the session continues.
+func (s *Session) Run(ctx context.Context, in io.Reader) error {
+	s.loadHistory()
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for {
+		fmt.Fprint(s.Out, s.Prompt)
+		if !scanner.Scan() {
+			fmt.Fprintln(s.Out)
+			return scanner.Err()
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		line, err := s.expandHistory(strings.TrimSpace(scanner.Text()))
+		if err != nil {
+			fmt.Fprintf(s.Out, "Error: %v\n", err)
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		s.addHistory(line)
+
+		if err := s.Exec(ctx, line); errors.Is(err, errQuit) {
+			return nil
+		} else if err != nil {
+			fmt.Fprintf(s.Out, "Error: %v\n", err)
+		}
+	}
+}
+
+// Exec runs one command line.
+func (s *Session) Exec(ctx context.Context, line string) error {
+	name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	arg = strings.TrimSpace(arg)
+
+	switch strings.ToLower(name) {
+	case "":
+		return nil
+	case "help", "?":
+		fmt.Fprint(s.Out, helpText)
+		return nil
+	case "quit", "exit", "q":
+		return errQuit
+	case "history":
+		for i, h := range s.history {
+			fmt.Fprintf(s.Out, "%5d  %s\n", i+1, h)
+		}
+		return nil
+	}
+
+	if arg == "" {
+		return fmt.Errorf("usage: %s <argument> (see help)", name)
+	}
+	switch strings.ToLower(name) {
+	case "find", "f":
+		return s.find(ctx, arg)
+	case "show", "s":
+		return s.show(ctx, arg)
+	case "related", "r":
+		return s.related(ctx, arg)
+	case "ask", "a":
+		return s.ask(ctx, arg)
+	case "query":
+		return s.query(ctx, arg)
+	}
+	return fmt.Errorf("unknown command %q (type help)", name)
+}
+
+func (s *Session) find(ctx context.Context, name string) error {
+	entities, err := s.engine.FindEntitiesByName(ctx, name, nil)
+	if err != nil {
+		return err
+	}
+	if len(entities) == 0 {
+		fmt.Fprintf(s.Out, "No entities matching %q\n", name)
+		return nil
+	}
+	s.last = s.last[:0]
+	for i, e := range entities {
+		if i == ListLimit {
+			fmt.Fprintf(s.Out, "  ... %d more\n", len(entities)-ListLimit)
+			break
+		}
+		s.last = append(s.last, e.ID)
+		fmt.Fprintf(s.Out, "%3d. %s [%s] %s\n", i+1, e.CanonicalName, typeName(e.EntityTypeID), e.ID)
+	}
+	return nil
+}
+
+func (s *Session) show(ctx context.Context, ref string) error {
+	ent, err := s.resolve(ctx, ref)
+	if err != nil || ent == nil {
+		return err
+	}
+
+	fmt.Fprintf(s.Out, "%s [%s] %s\n", ent.CanonicalName, typeName(ent.EntityTypeID), ent.ID)
+	if ent.Summary != nil && *ent.Summary != "" {
+		fmt.Fprintf(s.Out, "  %s\n", *ent.Summary)
+	}
+	aliases, err := s.engine.GetEntityAliases(ctx, ent.ID)
+	if err != nil {
+		return err
+	}
+	if len(aliases) > 0 {
+		names := make([]string, len(aliases))
+		for i, a := range aliases {
+			names[i] = a.Alias
+		}
+		fmt.Fprintf(s.Out, "  Also: %s\n", strings.Join(names, ", "))
+	}
+
+	opts := memory.DefaultQueryOptions()
+	opts.Limit = ListLimit
+	rels, err := s.engine.GetEntityRelationships(ctx, ent.ID, opts)
+	if err != nil {
+		return err
+	}
+	if len(rels) > 0 {
+		fmt.Fprintln(s.Out, "  Relationships:")
+	}
+	for _, r := range rels {
+		fmt.Fprintf(s.Out, "    %s\n", r.Fact)
+	}
+	return nil
+}
+
+func (s *Session) related(ctx context.Context, ref string) error {
+	ent, err := s.resolve(ctx, ref)
+	if err != nil || ent == nil {
+		return err
+	}
+	opts := memory.DefaultQueryOptions()
+	opts.Limit = ListLimit
+	related, err := s.engine.GetRelatedEntities(ctx, ent.ID, opts)
+	if err != nil {
+		return err
+	}
+	if len(related) == 0 {
+		fmt.Fprintf(s.Out, "Nothing related to %s\n", ent.CanonicalName)
+		return nil
+	}
+	s.last = s.last[:0]
+	for i, r := range related {
+		s.last = append(s.last, r.ID)
+		arrow := "->"
+		if r.Direction == string(memory.DirectionIncoming) {
+			arrow = "<-"
+		}
+		fmt.Fprintf(s.Out, "%3d. %s %s %s [%s] (%.2f)\n", i+1, arrow, r.RelationType, r.CanonicalName, typeName(r.EntityTypeID), r.Weight)
+	}
+	return nil
+}
+
+func (s *Session) ask(ctx context.Context, question string) error {
+	if s.Searcher == nil {
+		return errs.New(errs.ErrNoAPIKey, "ask needs GEMINI_API_KEY for embeddings")
+	}
+	resp, err := s.Searcher.SearchEpisodes(ctx, search.EpisodeSearchRequest{
+		Query:         question,
+		Limit:         5,
+		Model:         s.Model,
+		UseEmbeddings: true,
+	})
+	if err != nil {
+		return err
+	}
+	if len(resp.Results) == 0 {
+		fmt.Fprintln(s.Out, "No matching episodes (are embeddings generated?)")
+		return nil
+	}
+	for i, r := range resp.Results {
+		where := r.ThreadName
+		if where == "" {
+			where = r.Channel
+		}
+		fmt.Fprintf(s.Out, "%d. [%.2f] %s - %s (%d messages)\n", i+1, r.Score,
+			time.Unix(r.StartTime, 0).Format("2006-01-02 15:04"), where, r.EventCount)
+		if s.Preview != nil {
+			if preview := s.Preview(ctx, r.EpisodeID); preview != "" {
+				fmt.Fprintf(s.Out, "   %s\n", preview)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Session) query(ctx context.Context, expr string) error {
+	result, err := s.engine.RunGraphQuery(ctx, expr)
+	if err != nil {
+		return err
+	}
+	s.last = s.last[:0]
+	for i, row := range result.Rows {
+		label := row.Name
+		if row.Literal != nil {
+			label = strconv.Quote(*row.Literal)
+		} else {
+			s.last = append(s.last, row.ID)
+		}
+		if row.Via != nil {
+			label = fmt.Sprintf("%s %s %s", row.Via.FromName, row.Via.RelationType, label)
+		}
+		fmt.Fprintf(s.Out, "%3d. %s\n", i+1, label)
+	}
+	fmt.Fprintf(s.Out, "(%d rows)\n", result.Count)
+	return nil
+}
+
+// resolve finds the entity ref names: #n from the last list, an ID, or a
+// name matching one entity. Several name matches are listed for picking,
+// and resolve returns nil.
+func (s *Session) resolve(ctx context.Context, ref string) (*memory.Entity, error) {
+	if n, err := strconv.Atoi(strings.TrimPrefix(ref, "#")); err == nil {
+		if n < 1 || n > len(s.last) {
+			return nil, fmt.Errorf("no #%d in the last list", n)
+		}
+		ref = s.last[n-1]
+	}
+	if ent, err := s.engine.GetEntity(ctx, ref); err != nil || ent != nil {
+		return ent, err
+	}
+
+	matches, err := s.engine.FindEntitiesByName(ctx, ref, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range matches {
+		if strings.EqualFold(matches[i].CanonicalName, ref) {
+			return &matches[i], nil
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no entity matches %q", ref)
+	case 1:
+		return &matches[0], nil
+	}
+	fmt.Fprintf(s.Out, "%q matches %d entities; pick one by #n:\n", ref, len(matches))
+	return nil, s.find(ctx, ref)
+}
+
+// expandHistory replaces !! and !<n> with earlier commands.
+func (s *Session) expandHistory(line string) (string, error) {
+	if !strings.HasPrefix(line, "!") {
+		return line, nil
+	}
+	if line == "!!" {
+		if len(s.history) == 0 {
+			return "", errors.New("no history")
+		}
+		line = s.history[len(s.history)-1]
+	} else {
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 1 || n > len(s.history) {
+			return "", fmt.Errorf("no history entry %s", line[1:])
+		}
+		line = s.history[n-1]
+	}
+	fmt.Fprintln(s.Out, line)
+	return line, nil
+}
+
+func (s *Session) loadHistory() {
+	if s.HistoryPath == "" {
+		return
+	}
+	data, err := os.ReadFile(s.HistoryPath)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			s.history = append(s.history, line)
+		}
+	}
+	if len(s.history) > HistoryKeep {
+		s.history = s.history[len(s.history)-HistoryKeep:]
+		// Non-fatal - history is a convenience
+		err := os.WriteFile(s.HistoryPath, []byte(strings.Join(s.history, "\n")+"\n"), 0600)
+		_ = err
+	}
+}
+
+func (s *Session) addHistory(line string) {
+	if len(s.history) > 0 && s.history[len(s.history)-1] == line {
+		return
+	}
+	s.history = append(s.history, line)
+	if s.HistoryPath == "" {
+		return
+	}
+	// Non-fatal - history is a convenience
+	if err := os.MkdirAll(filepath.Dir(s.HistoryPath), 0755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(s.HistoryPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, line)
+}
+
+func typeName(id int) string {
+	if et := memory.GetEntityTypeByID(id); et != nil {
+		return et.Name
+	}
+	return "?"
+}
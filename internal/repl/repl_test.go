@@ -0,0 +1,74 @@
+package repl
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestSession(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	now := time.Now().Format(time.RFC3339)
+	for _, e := range []struct {
+		id, name string
+		typeID   int
+	}{{"tyler", "Tyler", 1}, {"tyler-b", "Tyler Brown", 1}, {"acme", "Acme", 2}} {
+		if _, err := db.Exec(`INSERT INTO entities (id, canonical_name, entity_type_id, origin, created_at, updated_at) VALUES (?, ?, ?, 'manual', ?, ?)`,
+			e.id, e.name, e.typeID, now, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO relationships (id, source_entity_id, target_entity_id, relation_type, fact, created_at, confidence) VALUES ('r1', 'tyler', 'acme', 'WORKS_AT', 'Tyler works at Acme', ?, 1.0)`, now); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	s := New(db, &out)
+	defer s.Close()
+	s.HistoryPath = filepath.Join(t.TempDir(), "history")
+
+	input := "find tyler\nshow #1\nrelated Acme\n!!\nask anything\nquit\nfind never-run\n"
+	if err := s.Run(context.Background(), strings.NewReader(input)); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"1. Tyler [Person] tyler",
+		"2. Tyler Brown [Person] tyler-b",
+		"Tyler works at Acme",             // show #1 resolves from the find list
+		"1. <- WORKS_AT Tyler [Person]",   // related by exact name
+		"Error: ask needs GEMINI_API_KEY", // no searcher configured
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "<- WORKS_AT Tyler") != 2 {
+		t.Errorf("!! should rerun related:\n%s", got)
+	}
+
+	// History persists, with !! stored as the command it ran
+	data, err := os.ReadFile(s.HistoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "find tyler\nshow #1\nrelated Acme\nask anything\nquit\n"; string(data) != want {
+		t.Errorf("history = %q, want %q", data, want)
+	}
+
+	// Ambiguous names list candidates instead of guessing
+	out.Reset()
+	if err := s.Exec(context.Background(), "show Tyl"); err != nil {
+		t.Fatalf("show ambiguous: %v", err)
+	}
+	if !strings.Contains(out.String(), `"Tyl" matches 2 entities`) {
+		t.Errorf("ambiguous show = %q", out.String())
+	}
+}
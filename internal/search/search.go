@@ -9,7 +9,10 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/Napageneral/mnemonic/internal/vectorindex"
 )
 
 const (
@@ -20,6 +23,10 @@ const (
 type Searcher struct {
 	db       *sql.DB
 	embedder Embedder
+
+	indexDir string
+	indexMu  sync.Mutex
+	indexes  map[string]*vectorindex.Store
 }
 
 // NewSearcher creates a new searcher with an optional embedder.
@@ -27,6 +34,32 @@ func NewSearcher(db *sql.DB, embedder Embedder) *Searcher {
 	return &Searcher{db: db, embedder: embedder}
 }
 
+// SetIndexDir persists vector indexes under dir so large collections don't
+// rebuild their ANN graph on every run. Without it, indexes live in memory.
+func (s *Searcher) SetIndexDir(dir string) {
+	s.indexDir = dir
+}
+
+// nearestEpisodes returns the k episode embeddings closest to the query,
+// brute force for small collections and HNSW for large ones.
+func (s *Searcher) nearestEpisodes(ctx context.Context, queryEmbedding []float64, model string, k int) ([]vectorindex.Match, error) {
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+
+	if s.indexes == nil {
+		s.indexes = make(map[string]*vectorindex.Store)
+	}
+	store, ok := s.indexes[model]
+	if !ok {
+		store = vectorindex.NewStore(s.db, vectorindex.Options{TargetType: "episode", Model: model, Dir: s.indexDir})
+		s.indexes[model] = store
+	}
+	if _, err := store.Sync(ctx); err != nil {
+		return nil, err
+	}
+	return store.Search(queryEmbedding, k), nil
+}
+
 // SearchEpisodes performs embedding search over episodes.
 func (s *Searcher) SearchEpisodes(ctx context.Context, req EpisodeSearchRequest) (EpisodeSearchResponse, error) {
 	if s.db == nil {
@@ -72,6 +105,21 @@ func (s *Searcher) SearchEpisodes(ctx context.Context, req EpisodeSearchRequest)
 		embeddingUsed = true
 	}
 
+	// Without filters, the vector index narrows scoring to the top matches
+	var nearestIDs []string
+	if embeddingUsed && req.Channel == "" && req.DefinitionName == "" {
+		matches, err := s.nearestEpisodes(ctx, queryEmbedding, model, limit)
+		if err != nil {
+			return EpisodeSearchResponse{}, err
+		}
+		if len(matches) == 0 {
+			return EpisodeSearchResponse{Query: query, Model: model, EmbeddingUsed: embeddingUsed, Results: []EpisodeSearchResult{}}, nil
+		}
+		for _, m := range matches {
+			nearestIDs = append(nearestIDs, m.ID)
+		}
+	}
+
 	querySQL := `
 		SELECT e.target_id, e.embedding_blob, e.dimension,
 		       ep.channel, ep.thread_id, ep.start_time, ep.end_time, ep.event_count,
@@ -91,6 +139,12 @@ func (s *Searcher) SearchEpisodes(ctx context.Context, req EpisodeSearchRequest)
 		querySQL += " AND d.name = ?"
 		args = append(args, req.DefinitionName)
 	}
+	if len(nearestIDs) > 0 {
+		querySQL += " AND e.target_id IN (?" + strings.Repeat(",?", len(nearestIDs)-1) + ")"
+		for _, id := range nearestIDs {
+			args = append(args, id)
+		}
+	}
 
 	rows, err := s.db.QueryContext(ctx, querySQL, args...)
 	if err != nil {
@@ -829,39 +883,21 @@ func (s *Searcher) searchEventsFTS(ctx context.Context, query string, channels [
 }
 
 func (s *Searcher) searchEventsVector(ctx context.Context, queryEmbedding []float64, model string, channels []string, threadID string, since, until int64, limit int) map[string]float64 {
-	// Load episode embeddings and find matching events
-	query := `
-		SELECT e.target_id, e.embedding_blob, e.dimension
-		FROM embeddings e
-		WHERE e.target_type = 'episode' AND e.model = ?`
-	args := []any{model}
-
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	// Find the nearest episodes and map them to events
+	matches, err := s.nearestEpisodes(ctx, queryEmbedding, model, limit)
 	if err != nil {
 		return nil
 	}
-	defer rows.Close()
 
 	type candidate struct {
 		episodeID string
 		score     float64
 	}
 	var candidates []candidate
-
-	for rows.Next() {
-		var episodeID string
-		var blob []byte
-		var dim int
-		if err := rows.Scan(&episodeID, &blob, &dim); err != nil {
-			continue
-		}
-		if dim != len(queryEmbedding) {
-			continue
-		}
-		embedding := blobToFloat64Slice(blob)
-		score := normalizeCosine(cosineSimilarity(queryEmbedding, embedding))
+	for _, m := range matches {
+		score := normalizeCosine(m.Score)
 		if score > 0.1 { // Threshold
-			candidates = append(candidates, candidate{episodeID: episodeID, score: score})
+			candidates = append(candidates, candidate{episodeID: m.ID, score: score})
 		}
 	}
 
@@ -869,14 +905,6 @@ func (s *Searcher) searchEventsVector(ctx context.Context, queryEmbedding []floa
 		return nil
 	}
 
-	// Sort by score and take top N
-	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].score > candidates[j].score
-	})
-	if len(candidates) > limit {
-		candidates = candidates[:limit]
-	}
-
 	// Map episodes to events
 	episodeIDs := make([]string, len(candidates))
 	episodeScores := make(map[string]float64)
@@ -0,0 +1,84 @@
+package seed
+
+// Vocabulary for generated people, threads, and messages. Phone numbers use
+// the 555 range and emails example.com, so nothing seeded is reachable.
+
+var firstNames = []string{
+	"Olivia", "Liam", "Emma", "Noah", "Ava", "Elijah", "Sophia", "James", "Isabella", "Lucas",
+	"Mia", "Mason", "Amelia", "Ethan", "Harper", "Logan", "Evelyn", "Jacob", "Abigail", "Michael",
+	"Emily", "Daniel", "Ella", "Henry", "Scarlett", "Jackson", "Grace", "Sebastian", "Chloe", "Aiden",
+	"Priya", "Arjun", "Mei", "Wei", "Yuki", "Kenji", "Fatima", "Omar", "Sofia", "Mateo",
+	"Zara", "Kwame", "Amara", "Diego", "Lucia", "Nikolai", "Ingrid", "Tariq", "Leila", "Rafael",
+}
+
+var lastNames = []string{
+	"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Rodriguez", "Martinez",
+	"Hernandez", "Lopez", "Gonzalez", "Wilson", "Anderson", "Thomas", "Taylor", "Moore", "Jackson", "Martin",
+	"Lee", "Perez", "Thompson", "White", "Harris", "Sanchez", "Clark", "Ramirez", "Lewis", "Robinson",
+	"Patel", "Nguyen", "Kim", "Chen", "Tanaka", "Okafor", "Haddad", "Novak", "Larsen", "Rossi",
+}
+
+var groupNames = []string{
+	"Family", "Book Club", "Climbing Crew", "College Friends", "Soccer Sunday", "Wedding Planning",
+	"Neighbors", "Work Lunch", "Ski Trip", "Fantasy League", "Game Night", "Running Club",
+}
+
+var places = []string{
+	"the taco place", "Blue Bottle", "the park", "Trader Joe's", "the gym", "the airport",
+	"the new ramen spot", "the farmers market", "the office", "the beach",
+}
+
+var smallTalk = []string{
+	"hey! how's it going?", "haha yes", "omw", "running 10 min late sorry", "want to grab dinner this week?",
+	"did you see the game last night", "lol", "sounds good", "can't tonight, maybe tomorrow?",
+	"meet at {place}?", "just got to {place}", "thanks again for yesterday", "happy friday!",
+	"ok see you there", "what time works for you", "that's hilarious", "call you in a bit",
+	"are we still on for saturday", "👍", "miss you guys", "I'll bring snacks", "can you send me that link",
+	"traffic is insane", "just landed", "good luck today!", "congrats!!", "how was the trip?",
+	"we should do {place} again soon", "sorry just seeing this", "totally agree",
+}
+
+var emailBodies = []string{
+	"Hi,\n\nFollowing up on our conversation - let me know if next week works.\n\nThanks",
+	"Attached are the notes from today. Let me know if I missed anything.",
+	"Quick question about the invoice - can you resend it?",
+	"Thanks for the intro! Happy to find time to chat.",
+	"Reminder: the meeting moved to Thursday at 3pm.",
+	"Here's the doc we discussed. Comments welcome.",
+}
+
+// factKind is one kind of plantable fact with its possible values and the
+// sentences that state it.
+type factKind struct {
+	category, factType string
+	values             []string
+	templates          []string
+}
+
+var factKinds = []factKind{
+	{
+		category: "professional", factType: "employer_current",
+		values:    []string{"Stripe", "Anthropic", "Kaiser Permanente", "Patagonia", "Genentech", "Figma", "Wells Fargo", "Pixar"},
+		templates: []string{"Big news - I just started at {value}!", "First week at {value} done, it's going great", "I work at {value} now, remember?"},
+	},
+	{
+		category: "location", factType: "location_current",
+		values:    []string{"Denver", "Austin", "Portland", "Oakland", "Brooklyn", "Seattle", "Chicago", "Lisbon"},
+		templates: []string{"We finally moved to {value}!", "Loving life in {value} so far", "Now that I live in {value} you have to visit"},
+	},
+	{
+		category: "relationships", factType: "spouse",
+		values:    []string{"Jordan", "Taylor", "Casey", "Morgan", "Riley", "Alexis", "Jamie", "Avery"},
+		templates: []string{"{value} and I got married last weekend!!", "My wife {value} says hi", "My husband {value} is cooking tonight, come over"},
+	},
+	{
+		category: "core_identity", factType: "birthdate",
+		values:    []string{"March 3", "July 19", "October 30", "January 12", "May 25", "December 8"},
+		templates: []string{"My birthday is {value}, don't forget 😂", "Doing something small for my birthday on {value}"},
+	},
+	{
+		category: "preferences", factType: "dietary_restriction",
+		values:    []string{"vegetarian", "vegan", "gluten-free", "allergic to peanuts"},
+		templates: []string{"Heads up, I'm {value} now", "Just so you know for dinner - I'm {value}"},
+	},
+}
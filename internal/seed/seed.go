@@ -0,0 +1,453 @@
+// Package seed generates a fake communications corpus for demos and
+// regression tests: people with phone and email contacts, one-on-one and
+// group threads across iMessage and Gmail, and message traffic with facts
+// planted at known events. The planted facts are the ground truth an
+// extraction run over the corpus should recover.
+//
+// Everything seeded is tagged with SourceAdapter (and contacts with it as
+// their source), so Remove can delete it again.
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/google/uuid"
+)
+
+// SourceAdapter marks seeded events, threads, and contacts.
+const SourceAdapter = "dev-seed"
+
+// Options configures Generate. Zero fields take defaults.
+type Options struct {
+	People int       // people besides me (default 200)
+	Events int       // messages in total (default 100000)
+	Groups int       // group chats (default People/10)
+	Facts  int       // planted facts (default People)
+	Days   int       // history length ending at End (default 365)
+	End    time.Time // default now
+	Seed   int64     // random seed; the same seed gives the same corpus
+}
+
+// PlantedFact is one fact stated in a seeded message.
+type PlantedFact struct {
+	PersonID   string `json:"person_id"`
+	PersonName string `json:"person_name"`
+	Category   string `json:"category"`
+	FactType   string `json:"fact_type"`
+	Value      string `json:"value"`
+	SourceType string `json:"source_type"` // self_disclosed: the person said it about themselves
+	EventID    string `json:"event_id"`
+	Channel    string `json:"channel"`
+	Text       string `json:"text"`
+}
+
+// Result summarizes a Generate run.
+type Result struct {
+	People  int           `json:"people"`
+	Threads int           `json:"threads"`
+	Groups  int           `json:"groups"`
+	Events  int           `json:"events"`
+	Facts   []PlantedFact `json:"facts"`
+	MeID    string        `json:"me_id"`
+}
+
+type person struct {
+	id, contactID, name, first, phone, email string
+	weight                                   float64 // how often they talk to me
+}
+
+type thread struct {
+	id, channel, name string
+	members           []int // indexes into people; me is not included
+	weight            float64
+}
+
+// Generate writes a fake corpus into db. It refuses if seeded data is
+// already present; call Remove first to reseed.
+func Generate(ctx context.Context, db *sql.DB, opts Options) (*Result, error) {
+	if opts.People <= 0 {
+		opts.People = 200
+	}
+	if opts.Events <= 0 {
+		opts.Events = 100000
+	}
+	if opts.Groups <= 0 {
+		opts.Groups = max(1, opts.People/10)
+	}
+	if opts.Facts <= 0 {
+		opts.Facts = opts.People
+	}
+	if opts.Days <= 0 {
+		opts.Days = 365
+	}
+	if opts.End.IsZero() {
+		opts.End = time.Now()
+	}
+
+	var existing int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM events WHERE source_adapter = ?`, SourceAdapter).Scan(&existing); err != nil {
+		return nil, fmt.Errorf("check existing seed data: %w", err)
+	}
+	if existing > 0 {
+		return nil, fmt.Errorf("database already has %d seeded events; remove them first", existing)
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	g := &generator{tx: tx, rng: rng, opts: opts}
+	if err := g.ensureMe(); err != nil {
+		return nil, err
+	}
+	if err := g.createPeople(); err != nil {
+		return nil, err
+	}
+	if err := g.createThreads(); err != nil {
+		return nil, err
+	}
+	if err := g.createEvents(ctx); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		People:  len(g.people),
+		Threads: len(g.threads),
+		Groups:  opts.Groups,
+		Events:  g.events,
+		Facts:   g.facts,
+		MeID:    g.me.id,
+	}, nil
+}
+
+// WriteTruth writes the planted facts to path as JSON.
+func WriteTruth(path string, facts []PlantedFact) error {
+	data, err := json.MarshalIndent(facts, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Remove deletes everything Generate created: seeded events and threads,
+// seeded contacts, and people linked only to seeded contacts (never me).
+// It returns the number of events removed.
+func Remove(ctx context.Context, db *sql.DB) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// The events_fts delete trigger scans the FTS table per row; clearing the
+	// seeded rows in one pass first keeps large removals fast
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM events_fts WHERE event_id IN (SELECT id FROM events WHERE source_adapter = ?)
+	`, SourceAdapter); err != nil {
+		return 0, fmt.Errorf("delete seeded search rows: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM events WHERE source_adapter = ?`, SourceAdapter)
+	if err != nil {
+		return 0, fmt.Errorf("delete seeded events: %w", err)
+	}
+	events, _ := res.RowsAffected()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM threads WHERE source_adapter = ?`, SourceAdapter); err != nil {
+		return 0, fmt.Errorf("delete seeded threads: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM persons
+		WHERE is_me = 0
+		  AND id IN (
+			SELECT l.person_id FROM person_contact_links l JOIN contacts c ON c.id = l.contact_id
+			WHERE c.source = ?
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM person_contact_links l JOIN contacts c ON c.id = l.contact_id
+			WHERE l.person_id = persons.id AND c.source != ?
+		  )
+	`, SourceAdapter, SourceAdapter); err != nil {
+		return 0, fmt.Errorf("delete seeded people: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM contacts WHERE source = ?`, SourceAdapter); err != nil {
+		return 0, fmt.Errorf("delete seeded contacts: %w", err)
+	}
+	return events, tx.Commit()
+}
+
+type generator struct {
+	tx   *sql.Tx
+	rng  *rand.Rand
+	opts Options
+
+	me      person
+	people  []person
+	threads []thread
+	events  int
+	facts   []PlantedFact
+}
+
+// ensureMe reuses the configured me, giving it a seeded contact to send from.
+func (g *generator) ensureMe() error {
+	g.me = person{name: "Sam Carter", first: "Sam", phone: "+15550100000", email: "sam.carter@example.com"}
+	var name string
+	err := g.tx.QueryRow(`SELECT id, canonical_name FROM persons WHERE is_me = 1 LIMIT 1`).Scan(&g.me.id, &name)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("look up me: %w", err)
+	}
+	if err == nil {
+		g.me.name = name
+	}
+
+	contactID, _, err := contacts.GetOrCreateContact(g.tx, "phone", g.me.phone, g.me.name, SourceAdapter)
+	if err != nil {
+		return err
+	}
+	g.me.contactID = contactID
+	if err := contacts.EnsureContactIdentifier(g.tx, contactID, "email", g.me.email); err != nil {
+		return err
+	}
+	if g.me.id == "" {
+		now := time.Now().Unix()
+		g.me.id = uuid.New().String()
+		if _, err := g.tx.Exec(`
+			INSERT INTO persons (id, canonical_name, is_me, created_at, updated_at) VALUES (?, ?, 1, ?, ?)
+		`, g.me.id, g.me.name, now, now); err != nil {
+			return fmt.Errorf("insert me: %w", err)
+		}
+	}
+	return contacts.EnsurePersonContactLink(g.tx, g.me.id, contactID, SourceAdapter, 1.0)
+}
+
+func (g *generator) createPeople() error {
+	used := make(map[string]bool)
+	for i := 0; i < g.opts.People; i++ {
+		var first, last, name string
+		for attempt := 0; ; attempt++ {
+			first = firstNames[g.rng.Intn(len(firstNames))]
+			last = lastNames[g.rng.Intn(len(lastNames))]
+			name = first + " " + last
+			if !used[name] {
+				break
+			}
+			if attempt > 20 {
+				name = fmt.Sprintf("%s %s %d", first, last, i)
+				break
+			}
+		}
+		used[name] = true
+
+		p := person{
+			name:  name,
+			first: first,
+			phone: fmt.Sprintf("+1555%07d", 100001+i),
+			email: fmt.Sprintf("%s.%s%d@example.com", strings.ToLower(first), strings.ToLower(last), i),
+			// A few close contacts dominate, as in real message histories
+			weight: 1 / float64(1+i%50),
+		}
+		contactID, _, err := contacts.GetOrCreateContact(g.tx, "phone", p.phone, name, SourceAdapter)
+		if err != nil {
+			return err
+		}
+		if err := contacts.EnsureContactIdentifier(g.tx, contactID, "email", p.email); err != nil {
+			return err
+		}
+		personID, _, err := contacts.EnsurePersonForContact(g.tx, contactID, name, SourceAdapter, 1.0)
+		if err != nil {
+			return err
+		}
+		p.id, p.contactID = personID, contactID
+		g.people = append(g.people, p)
+	}
+	return nil
+}
+
+func (g *generator) createThreads() error {
+	now := time.Now().Unix()
+	add := func(t thread) error {
+		_, err := g.tx.Exec(`
+			INSERT INTO threads (id, channel, name, is_group, source_adapter, source_id, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, t.id, t.channel, t.name, len(t.members) > 1, SourceAdapter, t.id, now, now)
+		if err != nil {
+			return fmt.Errorf("insert thread: %w", err)
+		}
+		g.threads = append(g.threads, t)
+		return nil
+	}
+
+	for i, p := range g.people {
+		if err := add(thread{id: fmt.Sprintf("%s:imessage-%d", SourceAdapter, i), channel: "imessage", name: p.name, members: []int{i}, weight: p.weight}); err != nil {
+			return err
+		}
+		// Every third person also emails
+		if i%3 == 0 {
+			if err := add(thread{id: fmt.Sprintf("%s:gmail-%d", SourceAdapter, i), channel: "gmail", name: p.name, members: []int{i}, weight: p.weight / 4}); err != nil {
+				return err
+			}
+		}
+	}
+	for i := 0; i < g.opts.Groups && len(g.people) > 1; i++ {
+		size := min(len(g.people), 2+g.rng.Intn(7))
+		members := g.rng.Perm(len(g.people))[:size]
+		sort.Ints(members)
+		name := groupNames[i%len(groupNames)]
+		if i >= len(groupNames) {
+			name = fmt.Sprintf("%s %d", name, i/len(groupNames)+1)
+		}
+		if err := add(thread{id: fmt.Sprintf("%s:group-%d", SourceAdapter, i), channel: "imessage", name: name, members: members, weight: 0.3}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createEvents emits conversations - bursts of messages seconds to minutes
+// apart - at random times across the history, then plants each fact as a
+// message from its person in their one-on-one thread.
+func (g *generator) createEvents(ctx context.Context) error {
+	eventStmt, err := g.tx.Prepare(`
+		INSERT INTO events (id, timestamp, channel, content_types, content, direction, thread_id, source_adapter, source_id)
+		VALUES (?, ?, ?, '["text"]', ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer eventStmt.Close()
+	partStmt, err := g.tx.Prepare(`INSERT OR IGNORE INTO event_participants (event_id, contact_id, role) VALUES (?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer partStmt.Close()
+
+	// insert writes one message; sender -1 is me
+	insert := func(t thread, sender int, ts int64, content string) (string, error) {
+		eventID := fmt.Sprintf("%s:ev-%d", SourceAdapter, g.events)
+		direction, senderContact := "sent", g.me.contactID
+		if sender >= 0 {
+			direction, senderContact = "received", g.people[sender].contactID
+		}
+		if _, err := eventStmt.Exec(eventID, ts, t.channel, content, direction, t.id, SourceAdapter, eventID); err != nil {
+			return "", fmt.Errorf("insert event: %w", err)
+		}
+		recipients := []string{g.me.contactID}
+		if sender < 0 {
+			recipients = nil
+		}
+		for _, member := range t.members {
+			if member != sender {
+				recipients = append(recipients, g.people[member].contactID)
+			}
+		}
+		if _, err := partStmt.Exec(eventID, senderContact, "sender"); err != nil {
+			return "", fmt.Errorf("insert participant: %w", err)
+		}
+		for _, contactID := range recipients {
+			if _, err := partStmt.Exec(eventID, contactID, "recipient"); err != nil {
+				return "", fmt.Errorf("insert participant: %w", err)
+			}
+		}
+		g.events++
+		return eventID, nil
+	}
+
+	var total float64
+	for _, t := range g.threads {
+		total += t.weight
+	}
+	pick := func() thread {
+		r := g.rng.Float64() * total
+		for _, t := range g.threads {
+			if r -= t.weight; r < 0 {
+				return t
+			}
+		}
+		return g.threads[len(g.threads)-1]
+	}
+
+	start := g.opts.End.Add(-time.Duration(g.opts.Days) * 24 * time.Hour).Unix()
+	span := max(1, g.opts.End.Unix()-start)
+	facts := min(g.opts.Facts, g.opts.Events)
+	conversational := g.opts.Events - facts
+
+	for g.events < conversational {
+		if g.events%10000 == 0 && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		t := pick()
+		ts := start + g.rng.Int63n(span)
+		length := min(conversational-g.events, 3+g.rng.Intn(25))
+		for m := 0; m < length; m++ {
+			ts += int64(5 + g.rng.Intn(600))
+			sender := -1
+			if g.rng.Intn(2) == 0 {
+				sender = t.members[g.rng.Intn(len(t.members))]
+			}
+			if _, err := insert(t, sender, ts, g.message(t)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// One-on-one iMessage threads come first, in people order
+	order := g.rng.Perm(len(g.people))
+	for k := 0; k < facts; k++ {
+		idx := order[k%len(order)]
+		t := g.threads[0]
+		for _, candidate := range g.threads {
+			if candidate.channel == "imessage" && len(candidate.members) == 1 && candidate.members[0] == idx {
+				t = candidate
+				break
+			}
+		}
+		fact := g.plantFact(g.people[idx])
+		eventID, err := insert(t, idx, start+g.rng.Int63n(span), fact.Text)
+		if err != nil {
+			return err
+		}
+		fact.EventID, fact.Channel = eventID, t.channel
+		g.facts = append(g.facts, fact)
+	}
+	return nil
+}
+
+func (g *generator) message(t thread) string {
+	text := smallTalk[g.rng.Intn(len(smallTalk))]
+	if t.channel == "gmail" {
+		text = emailBodies[g.rng.Intn(len(emailBodies))]
+	}
+	if strings.Contains(text, "{place}") {
+		text = strings.ReplaceAll(text, "{place}", places[g.rng.Intn(len(places))])
+	}
+	return text
+}
+
+// plantFact returns a message in which p states a fact about themselves.
+func (g *generator) plantFact(p person) PlantedFact {
+	kind := factKinds[g.rng.Intn(len(factKinds))]
+	value := kind.values[g.rng.Intn(len(kind.values))]
+	template := kind.templates[g.rng.Intn(len(kind.templates))]
+	return PlantedFact{
+		PersonID:   p.id,
+		PersonName: p.name,
+		Category:   kind.category,
+		FactType:   kind.factType,
+		Value:      value,
+		SourceType: "self_disclosed",
+		Text:       strings.ReplaceAll(template, "{value}", value),
+	}
+}
@@ -0,0 +1,65 @@
+package seed
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestGenerateAndRemove(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	res, err := Generate(ctx, db, Options{People: 12, Events: 500, Groups: 2, Facts: 6, Seed: 3})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if res.People != 12 || res.Events != 500 || len(res.Facts) != 6 {
+		t.Fatalf("result = %+v", res)
+	}
+
+	var events, groups, people int
+	db.QueryRow(`SELECT COUNT(*) FROM events WHERE source_adapter = ?`, SourceAdapter).Scan(&events)
+	db.QueryRow(`SELECT COUNT(*) FROM threads WHERE is_group = 1`).Scan(&groups)
+	db.QueryRow(`SELECT COUNT(*) FROM persons WHERE is_me = 0`).Scan(&people)
+	if events != 500 || groups != 2 || people != 12 {
+		t.Errorf("events=%d groups=%d people=%d", events, groups, people)
+	}
+
+	// Each planted fact is a message sent by its person, stating the value
+	for _, f := range res.Facts {
+		var content, sender string
+		err := db.QueryRow(`
+			SELECT e.content, l.person_id FROM events e
+			JOIN event_participants ep ON ep.event_id = e.id AND ep.role = 'sender'
+			JOIN person_contact_links l ON l.contact_id = ep.contact_id
+			WHERE e.id = ?
+		`, f.EventID).Scan(&content, &sender)
+		if err != nil {
+			t.Fatalf("fact event %s: %v", f.EventID, err)
+		}
+		if sender != f.PersonID || !strings.Contains(content, f.Value) || content != f.Text {
+			t.Errorf("fact %+v: event sent by %s says %q", f, sender, content)
+		}
+	}
+
+	if _, err := Generate(ctx, db, Options{People: 1, Events: 10}); err == nil {
+		t.Error("second Generate should refuse while seeded data exists")
+	}
+
+	removed, err := Remove(ctx, db)
+	if err != nil || removed != 500 {
+		t.Fatalf("Remove = %d, %v", removed, err)
+	}
+	db.QueryRow(`SELECT COUNT(*) FROM persons WHERE is_me = 0`).Scan(&people)
+	var contacts, me int
+	db.QueryRow(`SELECT COUNT(*) FROM contacts`).Scan(&contacts)
+	db.QueryRow(`SELECT COUNT(*) FROM persons WHERE is_me = 1`).Scan(&me)
+	if people != 0 || contacts != 0 || me != 1 {
+		t.Errorf("after Remove: people=%d contacts=%d me=%d", people, contacts, me)
+	}
+}
@@ -0,0 +1,579 @@
+// Package server is the HTTP API behind 'serve'. Every endpoint except
+// /healthz needs an API token (Authorization: Bearer <token>) whose scopes
+// cover it, so a dashboard widget can be given read-graph alone: it can read
+// the graph but not raw message content, and cannot trigger merges.
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Napageneral/mnemonic/internal/adapters"
+	"github.com/Napageneral/mnemonic/internal/audit"
+	"github.com/Napageneral/mnemonic/internal/avatars"
+	"github.com/Napageneral/mnemonic/internal/changelog"
+	"github.com/Napageneral/mnemonic/internal/drafts"
+	"github.com/Napageneral/mnemonic/internal/memory"
+)
+
+// Server serves the HTTP API over one database.
+type Server struct {
+	db  *sql.DB
+	mux *http.ServeMux
+}
+
+// New creates a Server. Tokens are checked against the database on every
+// request, so tokens created or revoked while serving take effect at once.
+func New(db *sql.DB) *Server {
+	s := &Server{db: db, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+	})
+
+	s.handle("GET /api/entities", ScopeReadGraph, s.findEntities)
+	s.handle("GET /api/entities/{id}", ScopeReadGraph, s.getEntity)
+	s.handle("GET /api/entities/{id}/avatar", ScopeReadGraph, s.entityAvatar)
+	s.handle("GET /api/entities/{id}/changes", ScopeReadGraph, s.entityChanges)
+	s.handle("GET /api/persons/{id}/avatar", ScopeReadGraph, s.personAvatar)
+	s.handle("GET /api/merge-candidates", ScopeReadGraph, s.listMergeCandidates)
+	s.handle("GET /api/changes", ScopeReadGraph, s.listChanges)
+	s.handle("GET /api/events", ScopeReadEvents, s.listEvents)
+	s.handle("POST /api/events", ScopeWriteEvents, s.ingestEvents)
+	s.handle("POST /api/merge-candidates/{id}/accept", ScopeWriteMerges, s.acceptMergeCandidate)
+	s.handle("POST /api/merge-candidates/{id}/reject", ScopeWriteMerges, s.rejectMergeCandidate)
+	s.handle("GET /api/drafts", ScopeReadEvents, s.listDrafts)
+	s.handle("POST /api/drafts", ScopeWriteDrafts, s.addDraft)
+	s.handle("POST /api/drafts/{id}/status", ScopeWriteDrafts, s.setDraftStatus)
+	s.handle("GET /api/tokens", ScopeAdmin, s.listTokens)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+type tokenKey struct{}
+
+// TokenFromContext returns the token that authenticated a request.
+func TokenFromContext(ctx context.Context) *Token {
+	t, _ := ctx.Value(tokenKey{}).(*Token)
+	return t
+}
+
+// handle registers an endpoint that requires scope.
+func (s *Server) handle(pattern, scope string, h http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(strings.ToLower(auth), "bearer ") {
+			writeError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+		token, err := Authenticate(s.db, strings.TrimSpace(auth[7:]))
+		if errors.Is(err, ErrInvalidToken) {
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !token.Allows(scope) {
+			writeError(w, http.StatusForbidden, "token lacks the "+scope+" scope")
+			return
+		}
+		h(w, r.WithContext(context.WithValue(r.Context(), tokenKey{}, token)))
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{"ok": false, "message": message})
+}
+
+// queryInt reads an integer query parameter, clamped to [1, max].
+func queryInt(r *http.Request, name string, def, max int) int {
+	n, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil || n <= 0 {
+		return def
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// GET /api/entities?name=<name>[&type=<entity type>]
+func (s *Server) findEntities(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	var typeID *int
+	if typeName := r.URL.Query().Get("type"); typeName != "" {
+		et := memory.GetEntityTypeByName(typeName)
+		if et == nil {
+			writeError(w, http.StatusBadRequest, "unknown entity type: "+typeName)
+			return
+		}
+		typeID = &et.ID
+	}
+
+	engine := memory.NewQueryEngine(s.db)
+	defer engine.Close()
+	entities, err := engine.FindEntitiesByName(r.Context(), name, typeID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "entities": entities})
+}
+
+// GET /api/entities/{id}: the entity with its aliases, current relationships
+// and its latest fact per relation type.
+func (s *Server) getEntity(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	engine := memory.NewQueryEngine(s.db)
+	defer engine.Close()
+
+	entity, err := engine.GetEntity(ctx, r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if entity == nil {
+		writeError(w, http.StatusNotFound, "entity not found")
+		return
+	}
+	aliases, err := engine.GetEntityAliases(ctx, entity.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	relationships, err := engine.GetEntityRelationships(ctx, entity.ID, memory.DefaultQueryOptions())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	current, err := engine.GetCurrentFacts(ctx, entity.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if current == nil {
+		current = []memory.CurrentFact{}
+	}
+	// Identifiers are only returned once their read is on record
+	if err := s.logIdentifierReads(ctx, entity.ID, aliases); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to write audit log: "+err.Error())
+		return
+	}
+	resp := map[string]any{
+		"ok":            true,
+		"entity":        entity,
+		"aliases":       aliases,
+		"relationships": relationships,
+		"current_facts": current,
+	}
+	if avatar, err := avatars.ForEntity(s.db, entity.ID); err == nil && avatar != nil {
+		resp["avatar_url"] = "/api/entities/" + entity.ID + "/avatar"
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// logIdentifierReads records the identifier aliases (emails, phones,
+// handles) of an entity in the audit log under the request's token.
+func (s *Server) logIdentifierReads(ctx context.Context, entityID string, aliases []memory.EntityAlias) error {
+	var personID string
+	if err := s.db.QueryRowContext(ctx, `SELECT person_id FROM person_entity_links WHERE entity_id = ? LIMIT 1`, entityID).Scan(&personID); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	var reads []audit.Read
+	for _, a := range aliases {
+		if audit.IdentifierAliasTypes[a.AliasType] {
+			reads = append(reads, audit.Read{Table: audit.TableEntityAliases, RowID: a.ID, PersonID: personID, FactType: a.AliasType})
+		}
+	}
+	return audit.Log(s.db, audit.Caller{TokenID: TokenFromContext(ctx).ID}, reads)
+}
+
+// GET /api/entities/{id}/changes?since=<cursor>: changes to the entity after
+// the cursor, oldest first. Clients keep the returned cursor and poll with it;
+// "more" means another page is waiting.
+func (s *Server) entityChanges(w http.ResponseWriter, r *http.Request) {
+	var since int64
+	if v := r.URL.Query().Get("since"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "since must be a non-negative cursor")
+			return
+		}
+		since = n
+	}
+	engine := memory.NewQueryEngine(s.db)
+	defer engine.Close()
+
+	changes, cursor, err := engine.GetEntityChanges(r.Context(), r.PathValue("id"), since)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if changes == nil {
+		changes = []memory.EntityChange{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":      true,
+		"changes": changes,
+		"cursor":  cursor,
+		"more":    len(changes) == memory.EntityChangesPageSize,
+	})
+}
+
+// GET /api/changes?since=<seq>[&limit=N][&type=entity_created,...]: the
+// global change log after a seq. "cursor" is the seq to poll with next;
+// "head" is the newest seq overall, so a consumer can tell it has caught up.
+func (s *Server) listChanges(w http.ResponseWriter, r *http.Request) {
+	opts := changelog.ReadOptions{Limit: queryInt(r, "limit", changelog.DefaultLimit, changelog.MaxLimit)}
+	if v := r.URL.Query().Get("since"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "since must be a non-negative seq")
+			return
+		}
+		opts.Since = n
+	}
+	if v := r.URL.Query().Get("type"); v != "" {
+		opts.Types = strings.Split(v, ",")
+	}
+
+	changes, cursor, err := changelog.Read(s.db, opts)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	head, err := changelog.Head(s.db)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":      true,
+		"changes": changes,
+		"cursor":  cursor,
+		"head":    head,
+	})
+}
+
+// GET /api/entities/{id}/avatar: the photo of the person linked to the entity.
+func (s *Server) entityAvatar(w http.ResponseWriter, r *http.Request) {
+	avatar, err := avatars.ForEntity(s.db, r.PathValue("id"))
+	writeAvatar(w, r, avatar, err)
+}
+
+// GET /api/persons/{id}/avatar
+func (s *Server) personAvatar(w http.ResponseWriter, r *http.Request) {
+	avatar, err := avatars.ForPerson(s.db, r.PathValue("id"))
+	writeAvatar(w, r, avatar, err)
+}
+
+// writeAvatar serves image bytes, letting clients cache them by hash.
+func writeAvatar(w http.ResponseWriter, r *http.Request, avatar *avatars.Avatar, err error) {
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if avatar == nil {
+		writeError(w, http.StatusNotFound, "no avatar")
+		return
+	}
+	etag := `"` + avatar.SHA256 + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", avatar.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(avatar.Data)))
+	_, _ = w.Write(avatar.Data)
+}
+
+// GET /api/merge-candidates?limit=<n>: pending candidates, most confident first.
+func (s *Server) listMergeCandidates(w http.ResponseWriter, r *http.Request) {
+	candidates, err := memory.NewAutoMerger(s.db).GetPendingCandidates(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if limit := queryInt(r, "limit", 100, 1000); len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "candidates": candidates})
+}
+
+// POST /api/merge-candidates/{id}/accept
+func (s *Server) acceptMergeCandidate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	merger := memory.NewAutoMerger(s.db)
+	candidate, err := merger.GetCandidateByID(ctx, r.PathValue("id"))
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, "merge candidate not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if candidate.Status != "pending" {
+		writeError(w, http.StatusConflict, "merge candidate is already "+candidate.Status)
+		return
+	}
+	result, err := merger.ExecuteMerge(ctx, candidate, "api:"+TokenFromContext(ctx).Name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "merge": result})
+}
+
+// POST /api/merge-candidates/{id}/reject with optional {"reason": "..."}
+func (s *Server) rejectMergeCandidate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+			return
+		}
+	}
+	merger := memory.NewAutoMerger(s.db)
+	candidate, err := merger.GetCandidateByID(ctx, r.PathValue("id"))
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, "merge candidate not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if candidate.Status != "pending" {
+		writeError(w, http.StatusConflict, "merge candidate is already "+candidate.Status)
+		return
+	}
+	if err := merger.RejectCandidate(ctx, candidate.ID, "api:"+TokenFromContext(ctx).Name, body.Reason); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// Event is a message as returned by /api/events.
+type Event struct {
+	ID        string `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+	Channel   string `json:"channel"`
+	Direction string `json:"direction"`
+	ThreadID  string `json:"thread_id,omitempty"`
+	Content   string `json:"content"`
+	Unsent    bool   `json:"unsent,omitempty"`  // a draft: planned, never sent
+	Edited    bool   `json:"edited,omitempty"`  // content is the newest edit
+	Deleted   bool   `json:"deleted,omitempty"` // unsent after it was sent; content is empty
+}
+
+// latestRevisionSQL selects a column of the newest edit or unsend
+// (tombstone) superseding event e; NULL if the message was never changed.
+func latestRevisionSQL(column string) string {
+	return `(SELECT r.` + column + ` FROM events r WHERE r.supersedes = e.id ORDER BY r.timestamp DESC, r.id DESC LIMIT 1)`
+}
+
+// GET /api/events?[thread_id=<id>][&channel=<ch>][&before=<unix>][&limit=<n>]:
+// newest first. Edits and unsends are folded into the message they revise.
+func (s *Server) listEvents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := `SELECT e.id, e.timestamp, e.channel, e.direction, COALESCE(e.thread_id, ''),
+		COALESCE(` + latestRevisionSQL("content") + `, e.content, ''), ` + latestRevisionSQL("direction") + `
+		FROM events e`
+	where := []string{"e.supersedes IS NULL"}
+	var args []any
+	if v := q.Get("thread_id"); v != "" {
+		where = append(where, "e.thread_id = ?")
+		args = append(args, v)
+	}
+	if v := q.Get("channel"); v != "" {
+		where = append(where, "e.channel = ?")
+		args = append(args, v)
+	}
+	if v := q.Get("before"); v != "" {
+		before, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "before must be a unix timestamp")
+			return
+		}
+		where = append(where, "e.timestamp < ?")
+		args = append(args, before)
+	}
+	query += " WHERE " + strings.Join(where, " AND ")
+	query += " ORDER BY e.timestamp DESC LIMIT ?"
+	args = append(args, queryInt(r, "limit", 100, 1000))
+
+	rows, err := s.db.QueryContext(r.Context(), query, args...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+	events := []Event{}
+	for rows.Next() {
+		var e Event
+		var revision sql.NullString
+		if err := rows.Scan(&e.ID, &e.Timestamp, &e.Channel, &e.Direction, &e.ThreadID, &e.Content, &revision); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		e.Unsent = drafts.IsDraft(e.Direction)
+		e.Edited = revision.Valid && revision.String != "deleted"
+		if revision.String == "deleted" {
+			e.Deleted, e.Content = true, ""
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "events": events})
+}
+
+// GET /api/drafts?[person_id=<id>][&status=<pending|sent|discarded|all>][&limit=<n>]:
+// newest first.
+func (s *Server) listDrafts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	list, err := drafts.List(s.db, drafts.ListOptions{
+		PersonID: q.Get("person_id"),
+		Status:   q.Get("status"),
+		Limit:    queryInt(r, "limit", 100, 1000),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if list == nil {
+		list = []drafts.Draft{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "drafts": list})
+}
+
+// POST /api/drafts with {"content": "...", "kind": "draft|talking_point",
+// "person_id": "...", "thread_id": "..."}
+func (s *Server) addDraft(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Content  string `json:"content"`
+		Kind     string `json:"kind"`
+		PersonID string `json:"person_id"`
+		ThreadID string `json:"thread_id"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	if body.PersonID != "" {
+		var exists bool
+		if err := s.db.QueryRowContext(r.Context(), `SELECT EXISTS (SELECT 1 FROM persons WHERE id = ?)`, body.PersonID).Scan(&exists); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !exists {
+			writeError(w, http.StatusNotFound, "person not found")
+			return
+		}
+	}
+	d, err := drafts.Add(s.db, drafts.Draft{
+		Content:  body.Content,
+		Kind:     body.Kind,
+		PersonID: body.PersonID,
+		ThreadID: body.ThreadID,
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"ok": true, "draft": d})
+}
+
+// maxIngestBody caps a POST /api/events body; larger backfills are sent in
+// batches.
+const maxIngestBody = 16 << 20
+
+// POST /api/events with {"source": "forum", "events": [...]}: events in the
+// adapter plugin format, stored under the adapter name source. A batch is
+// written in one transaction; resending events updates them in place.
+func (s *Server) ingestEvents(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Source string                 `json:"source"`
+		Events []adapters.PluginEvent `json:"events"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxIngestBody)).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	if len(body.Events) == 0 {
+		writeError(w, http.StatusBadRequest, "events is required")
+		return
+	}
+	res, err := adapters.IngestEvents(r.Context(), s.db, body.Source, body.Events)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":              true,
+		"events_created":  res.EventsCreated,
+		"events_updated":  res.EventsUpdated,
+		"threads_created": res.ThreadsCreated,
+		"persons_created": res.PersonsCreated,
+	})
+}
+
+// POST /api/drafts/{id}/status with {"status": "sent|discarded|pending"}
+func (s *Server) setDraftStatus(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	if err := drafts.SetStatus(s.db, r.PathValue("id"), body.Status); err != nil {
+		if errors.Is(err, drafts.ErrNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
+		} else {
+			writeError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// GET /api/tokens
+func (s *Server) listTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := ListTokens(s.db)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "tokens": tokens})
+}
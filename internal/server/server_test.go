@@ -0,0 +1,280 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/avatars"
+	"github.com/Napageneral/mnemonic/internal/contacts"
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestScopedTokens(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range []string{
+		`INSERT INTO entities (id, canonical_name, entity_type_id, origin, created_at, updated_at) VALUES
+			('casey', 'Casey', 1, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z'),
+			('casey-2', 'Casey', 1, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')`,
+		`INSERT INTO entity_aliases (id, entity_id, alias, alias_type, normalized, created_at) VALUES
+			('al-name', 'casey', 'Case', 'nickname', 'case', '2026-01-01T00:00:00Z'),
+			('al-email', 'casey', 'casey@example.com', 'email', 'casey@example.com', '2026-01-01T00:00:00Z')`,
+		`INSERT INTO merge_candidates (id, entity_a_id, entity_b_id, confidence, auto_eligible, reason, status, created_at)
+			VALUES ('mc-1', 'casey-2', 'casey', 0.8, 0, 'same name', 'pending', '2026-01-01T00:00:00Z')`,
+		`INSERT INTO events (id, timestamp, channel, content_types, content, direction, source_adapter, source_id)
+			VALUES ('ev-1', 1767225600, 'imessage', '["text"]', 'see you at 6', 'received', 'test', 'ev-1')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, _, err := CreateToken(db, "bad", []string{"read-everything"}); err == nil {
+		t.Error("CreateToken accepted an unknown scope")
+	}
+	widgetToken, widget, err := CreateToken(db, "widget", []string{"read-graph"})
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+	_, merger, err := CreateToken(db, "merger", []string{"read-graph,write-merges"})
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+	_, admin, err := CreateToken(db, "admin", []string{"admin"})
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+	if _, _, err := CreateToken(db, "widget", []string{"admin"}); err == nil {
+		t.Error("CreateToken reused a name")
+	}
+
+	srv := New(db)
+	do := func(method, path, secret string) (int, string) {
+		req := httptest.NewRequest(method, path, nil)
+		if secret != "" {
+			req.Header.Set("Authorization", "Bearer "+secret)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	for _, tc := range []struct {
+		method, path, secret string
+		want                 int
+	}{
+		{"GET", "/healthz", "", http.StatusOK},
+		{"GET", "/api/entities?name=Casey", "", http.StatusUnauthorized},
+		{"GET", "/api/entities?name=Casey", "mn_nope", http.StatusUnauthorized},
+		{"GET", "/api/entities?name=Casey", widget, http.StatusOK},
+		{"GET", "/api/entities/casey", widget, http.StatusOK},
+		{"GET", "/api/entities/nobody", widget, http.StatusNotFound},
+		{"GET", "/api/merge-candidates", widget, http.StatusOK},
+		{"GET", "/api/events", widget, http.StatusForbidden},
+		{"POST", "/api/merge-candidates/mc-1/accept", widget, http.StatusForbidden},
+		{"GET", "/api/tokens", merger, http.StatusForbidden},
+		{"POST", "/api/merge-candidates/mc-1/accept", merger, http.StatusOK},
+		{"POST", "/api/merge-candidates/mc-1/reject", merger, http.StatusConflict},
+		{"GET", "/api/events", admin, http.StatusOK},
+		{"GET", "/api/tokens", admin, http.StatusOK},
+	} {
+		if code, body := do(tc.method, tc.path, tc.secret); code != tc.want {
+			t.Errorf("%s %s = %d, want %d: %s", tc.method, tc.path, code, tc.want, body)
+		}
+	}
+
+	// Identifiers returned by the API are audited under the token
+	var auditedRow, auditedToken string
+	if err := db.QueryRow(`SELECT row_id, token_id FROM audit_log WHERE table_name = 'entity_aliases'`).Scan(&auditedRow, &auditedToken); err != nil {
+		t.Fatalf("audit log: %v", err)
+	}
+	if auditedRow != "al-email" || auditedToken != widgetToken.ID {
+		t.Errorf("audited %q by %q, want al-email by %q", auditedRow, auditedToken, widgetToken.ID)
+	}
+
+	var resolvedBy, mergedInto string
+	db.QueryRow(`SELECT resolved_by FROM merge_candidates WHERE id = 'mc-1'`).Scan(&resolvedBy)
+	db.QueryRow(`SELECT merged_into FROM entities WHERE id = 'casey-2'`).Scan(&mergedInto)
+	if resolvedBy != "api:merger" || mergedInto != "casey" {
+		t.Errorf("merge resolved by %q, merged into %q", resolvedBy, mergedInto)
+	}
+	if _, body := do("GET", "/api/events", admin); !strings.Contains(body, "see you at 6") {
+		t.Errorf("events = %s", body)
+	}
+
+	// Revoked tokens stop working at once
+	if _, err := RevokeToken(db, "widget"); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	if code, _ := do("GET", "/api/entities/casey", widget); code != http.StatusUnauthorized {
+		t.Errorf("revoked token got %d", code)
+	}
+	if n, _ := ActiveTokenCount(db); n != 2 {
+		t.Errorf("active tokens = %d, want 2", n)
+	}
+	tokens, _ := ListTokens(db)
+	if len(tokens) != 3 || tokens[1].LastUsedAt == nil {
+		t.Errorf("tokens = %+v", tokens)
+	}
+}
+
+func TestAvatars(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	contactID, _, err := contacts.GetOrCreateContact(db, "email", "casey@example.com", "Casey Jones", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	personID, _, err := contacts.EnsurePersonForContact(db, contactID, "Casey Jones", "test", 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`INSERT INTO entities (id, canonical_name, entity_type_id, origin, created_at, updated_at)
+			VALUES ('casey', 'Casey Jones', 1, 'imported', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')`,
+		`INSERT INTO person_entity_links (person_id, entity_id, method, created_at) VALUES ('` + personID + `', 'casey', 'identifier', 0)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := avatars.Store(db, contactID, "test", png, ""); err != nil {
+		t.Fatal(err)
+	}
+	_, secret, err := CreateToken(db, "ui", []string{ScopeReadGraph})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := New(db)
+	get := func(path, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+secret)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/api/entities/casey/avatar", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" || rec.Body.String() != string(png) {
+		t.Fatalf("entity avatar = %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if rec := get("/api/entities/casey/avatar", rec.Header().Get("ETag")); rec.Code != http.StatusNotModified {
+		t.Errorf("cached avatar = %d, want 304", rec.Code)
+	}
+	if rec := get("/api/persons/"+personID+"/avatar", ""); rec.Code != http.StatusOK {
+		t.Errorf("person avatar = %d", rec.Code)
+	}
+	if rec := get("/api/persons/nobody/avatar", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("missing avatar = %d, want 404", rec.Code)
+	}
+	if rec := get("/api/entities/casey", ""); !strings.Contains(rec.Body.String(), `"avatar_url":"/api/entities/casey/avatar"`) {
+		t.Errorf("entity = %s, want an avatar_url", rec.Body.String())
+	}
+}
+
+func TestIngestEvents(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	_, reader, err := CreateToken(db, "reader", []string{ScopeReadEvents})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, pusher, err := CreateToken(db, "pusher", []string{ScopeWriteEvents})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := New(db)
+	post := func(secret, body string) (int, string) {
+		req := httptest.NewRequest("POST", "/api/events", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+secret)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	batch := `{"source": "forum", "events": [
+		{"id": "p1", "timestamp": 1767225600, "content": "first post", "thread_id": "t1", "sender": {"identifier": "bob@example.com", "name": "Bob"}},
+		{"id": "p2", "timestamp": 1767225660, "content": "a reply", "thread_id": "t1", "reply_to": "p1", "direction": "sent"}
+	]}`
+	if code, body := post(reader, batch); code != http.StatusForbidden {
+		t.Errorf("read-events token pushed events: %d %s", code, body)
+	}
+	code, body := post(pusher, batch)
+	if code != http.StatusOK || !strings.Contains(body, `"events_created":2`) || !strings.Contains(body, `"threads_created":1`) {
+		t.Fatalf("ingest = %d %s", code, body)
+	}
+	// Resending is idempotent
+	if _, body := post(pusher, batch); !strings.Contains(body, `"events_created":0`) {
+		t.Errorf("resend = %s", body)
+	}
+
+	var replyTo string
+	db.QueryRow(`SELECT reply_to FROM events WHERE id = 'forum:p2'`).Scan(&replyTo)
+	if replyTo != "forum:p1" {
+		t.Errorf("reply_to = %q", replyTo)
+	}
+
+	for _, bad := range []string{
+		`{"source": "forum", "events": []}`,
+		`{"source": "a:b", "events": [{"id": "x", "timestamp": 1}]}`,
+		`{"source": "forum", "events": [{"id": "x", "timestamp": 1, "direction": "sideways"}]}`,
+		`not json`,
+	} {
+		if code, body := post(pusher, bad); code != http.StatusBadRequest {
+			t.Errorf("%s = %d %s, want 400", bad, code, body)
+		}
+	}
+}
+
+func TestListEventsFoldsRevisions(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`INSERT INTO events (id, timestamp, channel, content_types, content, direction, source_adapter, source_id, supersedes) VALUES
+		('m1', 100, 'imessage', '["text"]', 'see you at 6', 'received', 'test', 'm1', NULL),
+		('m1-edit', 150, 'imessage', '["text"]', 'see you at 7', 'received', 'test', 'm1-edit', 'm1'),
+		('m2', 200, 'imessage', '["text"]', 'oops wrong chat', 'sent', 'test', 'm2', NULL),
+		('m2-unsend', 250, 'imessage', '["tombstone"]', NULL, 'deleted', 'test', 'm2-unsend', 'm2')`); err != nil {
+		t.Fatal(err)
+	}
+	_, reader, err := CreateToken(db, "reader", []string{ScopeReadEvents})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/events", nil)
+	req.Header.Set("Authorization", "Bearer "+reader)
+	rec := httptest.NewRecorder()
+	New(db).ServeHTTP(rec, req)
+	var resp struct {
+		Events []Event `json:"events"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Events) != 2 {
+		t.Fatalf("events = %+v, want the two originals", resp.Events)
+	}
+	if e := resp.Events[0]; e.ID != "m2" || !e.Deleted || e.Content != "" || e.Direction != "sent" {
+		t.Errorf("unsent message = %+v", e)
+	}
+	if e := resp.Events[1]; e.ID != "m1" || !e.Edited || e.Content != "see you at 7" {
+		t.Errorf("edited message = %+v", e)
+	}
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Token scopes. A token may only call endpoints covered by its scopes;
+// admin covers everything.
+const (
+	ScopeReadGraph   = "read-graph"   // entities, relationships, merge candidates
+	ScopeReadEvents  = "read-events"  // raw message content
+	ScopeWriteMerges = "write-merges" // accept or reject merge candidates
+	ScopeWriteDrafts = "write-drafts" // record drafts and talking points
+	ScopeWriteEvents = "write-events" // push events into the store
+	ScopeAdmin       = "admin"        // all of the above plus token management
+)
+
+// Scopes lists every scope, least privileged first.
+var Scopes = []string{ScopeReadGraph, ScopeReadEvents, ScopeWriteMerges, ScopeWriteDrafts, ScopeWriteEvents, ScopeAdmin}
+
+// tokenPrefix marks secrets so they are recognizable in configs and logs.
+const tokenPrefix = "mn_"
+
+// ErrInvalidToken is returned for unknown and revoked tokens.
+var ErrInvalidToken = errors.New("invalid or revoked token")
+
+// Token is an API token. The secret is only available when created.
+type Token struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Allows reports whether the token grants scope.
+func (t *Token) Allows(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeScopes validates scopes and returns them deduplicated in the
+// order of Scopes.
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required (%s)", strings.Join(Scopes, ", "))
+	}
+	want := make(map[string]bool, len(scopes))
+	for _, s := range scopes {
+		for _, part := range strings.Split(s, ",") {
+			part = strings.ToLower(strings.TrimSpace(part))
+			if part == "" {
+				continue
+			}
+			valid := false
+			for _, known := range Scopes {
+				valid = valid || part == known
+			}
+			if !valid {
+				return nil, fmt.Errorf("unknown scope %q (want %s)", part, strings.Join(Scopes, ", "))
+			}
+			want[part] = true
+		}
+	}
+	var result []string
+	for _, s := range Scopes {
+		if want[s] {
+			result = append(result, s)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("at least one scope is required (%s)", strings.Join(Scopes, ", "))
+	}
+	return result, nil
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateToken creates a named token with the given scopes and returns it
+// with its secret, which is not stored and cannot be shown again.
+func CreateToken(db *sql.DB, name string, scopes []string) (*Token, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("token name is required")
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", fmt.Errorf("generate token: %w", err)
+	}
+	secret := tokenPrefix + hex.EncodeToString(buf)
+
+	token := &Token{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: time.Unix(time.Now().Unix(), 0),
+	}
+	_, err = db.Exec(`
+		INSERT INTO api_tokens (id, name, token_hash, scopes, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, token.ID, token.Name, hashToken(secret), strings.Join(scopes, ","), token.CreatedAt.Unix())
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return nil, "", fmt.Errorf("a token named %q already exists", name)
+		}
+		return nil, "", fmt.Errorf("insert token: %w", err)
+	}
+	return token, secret, nil
+}
+
+const tokenColumns = `id, name, scopes, created_at, last_used_at, revoked_at`
+
+func scanToken(scan func(...interface{}) error) (*Token, error) {
+	var t Token
+	var scopes string
+	var createdAt int64
+	var lastUsedAt, revokedAt sql.NullInt64
+	if err := scan(&t.ID, &t.Name, &scopes, &createdAt, &lastUsedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	t.Scopes = strings.Split(scopes, ",")
+	t.CreatedAt = time.Unix(createdAt, 0)
+	if lastUsedAt.Valid {
+		at := time.Unix(lastUsedAt.Int64, 0)
+		t.LastUsedAt = &at
+	}
+	if revokedAt.Valid {
+		at := time.Unix(revokedAt.Int64, 0)
+		t.RevokedAt = &at
+	}
+	return &t, nil
+}
+
+// ListTokens returns all tokens, including revoked ones, oldest first.
+func ListTokens(db *sql.DB) ([]Token, error) {
+	rows, err := db.Query(`SELECT ` + tokenColumns + ` FROM api_tokens ORDER BY created_at, name`)
+	if err != nil {
+		return nil, fmt.Errorf("query tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []Token
+	for rows.Next() {
+		t, err := scanToken(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("scan token: %w", err)
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, rows.Err()
+}
+
+// ActiveTokenCount returns the number of tokens that are not revoked.
+func ActiveTokenCount(db *sql.DB) (int, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM api_tokens WHERE revoked_at IS NULL`).Scan(&n)
+	return n, err
+}
+
+// RevokeToken revokes the token with this ID or name.
+func RevokeToken(db *sql.DB, ref string) (*Token, error) {
+	t, err := scanToken(db.QueryRow(`SELECT `+tokenColumns+` FROM api_tokens WHERE id = ? OR name = ?`, ref, ref).Scan)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("token not found: %s", ref)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query token: %w", err)
+	}
+	if t.RevokedAt != nil {
+		return nil, fmt.Errorf("token %q is already revoked", t.Name)
+	}
+	now := time.Unix(time.Now().Unix(), 0)
+	if _, err := db.Exec(`UPDATE api_tokens SET revoked_at = ? WHERE id = ?`, now.Unix(), t.ID); err != nil {
+		return nil, fmt.Errorf("revoke token: %w", err)
+	}
+	t.RevokedAt = &now
+	return t, nil
+}
+
+// Authenticate returns the active token with this secret and records its use.
+func Authenticate(db *sql.DB, secret string) (*Token, error) {
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		return nil, ErrInvalidToken
+	}
+	t, err := scanToken(db.QueryRow(`
+		SELECT `+tokenColumns+` FROM api_tokens
+		WHERE token_hash = ? AND revoked_at IS NULL
+	`, hashToken(secret)).Scan)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query token: %w", err)
+	}
+	// Non-fatal - last use is informational
+	_, err = db.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, time.Now().Unix(), t.ID)
+	_ = err
+	return t, nil
+}
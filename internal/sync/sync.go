@@ -4,11 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
 	"strings"
+	gosync "sync"
 	"time"
 
 	"github.com/Napageneral/mnemonic/internal/adapters"
 	"github.com/Napageneral/mnemonic/internal/config"
+	"github.com/Napageneral/mnemonic/internal/errs"
+	"github.com/Napageneral/mnemonic/internal/plugin"
 )
 
 // AdapterResult contains the result of syncing a single adapter
@@ -16,6 +20,7 @@ type AdapterResult struct {
 	AdapterName        string            `json:"adapter_name"`
 	Success            bool              `json:"success"`
 	Error              string            `json:"error,omitempty"`
+	ErrorCode          string            `json:"error_code,omitempty"` // errs class, e.g. adapter_source_missing
 	EventsCreated      int               `json:"events_created"`
 	EventsUpdated      int               `json:"events_updated"`
 	PersonsCreated     int               `json:"persons_created"`
@@ -36,37 +41,43 @@ type SyncResult struct {
 	Adapters []AdapterResult `json:"adapters,omitempty"`
 }
 
-// SyncAll runs all enabled adapters
+// SyncOptions controls SyncAdapters.
+type SyncOptions struct {
+	Full        bool // re-sync everything instead of incrementally
+	Concurrency int  // adapters synced at once (default 1)
+}
+
+// SyncAll runs all enabled adapters, one at a time
 func SyncAll(ctx context.Context, db *sql.DB, cfg *config.Config, full bool) SyncResult {
-	result := SyncResult{OK: true}
+	return SyncAdapters(ctx, db, cfg, nil, SyncOptions{Full: full})
+}
 
-	if len(cfg.Adapters) == 0 {
-		result.Message = "No adapters configured"
-		return result
-	}
+// SyncOne runs a specific adapter by name
+func SyncOne(ctx context.Context, db *sql.DB, cfg *config.Config, adapterName string, full bool) SyncResult {
+	return SyncAdapters(ctx, db, cfg, []string{adapterName}, SyncOptions{Full: full})
+}
 
-	enabledCount := 0
-	for _, adapter := range cfg.Adapters {
-		if adapter.Enabled {
-			enabledCount++
-		}
-	}
+// SyncAdapters runs the named adapters, or every enabled adapter when names
+// is empty, up to opts.Concurrency at a time. A named adapter that is not
+// configured or is disabled fails the run before anything syncs. Results
+// follow the order of names (adapter name order when syncing all); one
+// adapter failing doesn't stop the others.
+func SyncAdapters(ctx context.Context, db *sql.DB, cfg *config.Config, names []string, opts SyncOptions) SyncResult {
+	result := SyncResult{OK: true}
 
-	if enabledCount == 0 {
-		result.Message = "No adapters enabled"
+	selected, message := selectAdapters(cfg, names)
+	if message != "" {
+		result.OK = len(names) == 0
+		result.Message = message
 		return result
 	}
 
-	ftsDisabled := false
-	if full {
+	if opts.Full {
 		if err := disableFTS(db); err != nil {
 			result.OK = false
 			result.Message = fmt.Sprintf("Failed to disable FTS triggers: %v", err)
 			return result
 		}
-		ftsDisabled = true
-	}
-	if ftsDisabled {
 		defer func() {
 			if err := rebuildFTS(db); err != nil {
 				result.OK = false
@@ -77,69 +88,80 @@ func SyncAll(ctx context.Context, db *sql.DB, cfg *config.Config, full bool) Syn
 		}()
 	}
 
-	// Sync each enabled adapter
-	for name, adapterCfg := range cfg.Adapters {
-		if !adapterCfg.Enabled {
-			continue
-		}
-
-		adapterResult := syncAdapter(ctx, db, name, adapterCfg, full)
-		result.Adapters = append(result.Adapters, adapterResult)
+	workers := opts.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(selected) {
+		workers = len(selected)
+	}
+	result.Adapters = make([]AdapterResult, len(selected))
+	next := make(chan int)
+	var wg gosync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				name := selected[i]
+				if ctx.Err() != nil {
+					result.Adapters[i] = AdapterResult{AdapterName: name, Error: "Sync canceled", ErrorCode: errs.Classify(ctx.Err()).Code}
+					continue
+				}
+				result.Adapters[i] = syncAdapter(ctx, db, name, cfg.Adapters[name], opts.Full)
+			}
+		}()
+	}
+	for i := range selected {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
 
+	for _, adapterResult := range result.Adapters {
 		if !adapterResult.Success {
-			// One adapter failing doesn't stop others, but overall sync is not OK
 			result.OK = false
 		}
 	}
-
 	return result
 }
 
-// SyncOne runs a specific adapter by name
-func SyncOne(ctx context.Context, db *sql.DB, cfg *config.Config, adapterName string, full bool) SyncResult {
-	result := SyncResult{OK: true}
-
-	adapterCfg, exists := cfg.Adapters[adapterName]
-	if !exists {
-		result.OK = false
-		result.Message = fmt.Sprintf("Adapter '%s' not configured", adapterName)
-		return result
-	}
-
-	if !adapterCfg.Enabled {
-		result.OK = false
-		result.Message = fmt.Sprintf("Adapter '%s' is disabled", adapterName)
-		return result
-	}
-
-	ftsDisabled := false
-	if full {
-		if err := disableFTS(db); err != nil {
-			result.OK = false
-			result.Message = fmt.Sprintf("Failed to disable FTS triggers: %v", err)
-			return result
-		}
-		ftsDisabled = true
-	}
-	if ftsDisabled {
-		defer func() {
-			if err := rebuildFTS(db); err != nil {
-				result.OK = false
-				if result.Message == "" {
-					result.Message = fmt.Sprintf("Failed to rebuild FTS index: %v", err)
-				}
+// selectAdapters resolves the adapters to sync: names deduplicated, or all
+// enabled adapters sorted by name. message explains why there is nothing
+// to sync.
+func selectAdapters(cfg *config.Config, names []string) (selected []string, message string) {
+	if len(names) == 0 {
+		if len(cfg.Adapters) == 0 {
+			return nil, "No adapters configured"
+		}
+		for name, adapter := range cfg.Adapters {
+			if adapter.Enabled {
+				selected = append(selected, name)
 			}
-		}()
+		}
+		if len(selected) == 0 {
+			return nil, "No adapters enabled"
+		}
+		sort.Strings(selected)
+		return selected, ""
 	}
 
-	adapterResult := syncAdapter(ctx, db, adapterName, adapterCfg, full)
-	result.Adapters = []AdapterResult{adapterResult}
-
-	if !adapterResult.Success {
-		result.OK = false
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		adapter, exists := cfg.Adapters[name]
+		if !exists {
+			return nil, fmt.Sprintf("Adapter '%s' not configured", name)
+		}
+		if !adapter.Enabled {
+			return nil, fmt.Sprintf("Adapter '%s' is disabled", name)
+		}
+		selected = append(selected, name)
 	}
-
-	return result
+	return selected, ""
 }
 
 func disableFTS(db *sql.DB) error {
@@ -200,6 +222,50 @@ func syncAdapter(ctx context.Context, db *sql.DB, name string, cfg config.Adapte
 		adapter, err = adapters.NewEveAdapter()
 		if err != nil {
 			result.Error = fmt.Sprintf("Failed to create adapter: %v", err)
+			result.ErrorCode = errs.Classify(err).Code
+			return result
+		}
+
+	case "chatdb":
+		// iMessage straight from the Messages database (~/Library/Messages/chat.db),
+		// for machines without Eve. Needs Full Disk Access.
+		path, _ := cfg.Options["path"].(string)
+		adapter, err = adapters.NewIMessageAdapter(path)
+		if err != nil {
+			result.Error = fmt.Sprintf("Failed to create adapter: %v", err)
+			result.ErrorCode = errs.Classify(err).Code
+			return result
+		}
+
+	case "callhistory":
+		// Phone and FaceTime calls from the macOS call history, and
+		// voicemails (options: path, voicemail). Needs Full Disk Access.
+		var opts adapters.CallHistoryAdapterOptions
+		opts.Path, _ = cfg.Options["path"].(string)
+		opts.Voicemail, _ = cfg.Options["voicemail"].(string)
+		adapter, err = adapters.NewCallHistoryAdapter(opts)
+		if err != nil {
+			result.Error = fmt.Sprintf("Failed to create adapter: %v", err)
+			result.ErrorCode = errs.Classify(err).Code
+			return result
+		}
+
+	case "discord":
+		// Discord DMs and guild channels through the Discord API (options:
+		// token_env, bot, dms, channels)
+		var opts adapters.DiscordAdapterOptions
+		opts.TokenEnv, _ = cfg.Options["token_env"].(string)
+		opts.Bot, _ = cfg.Options["bot"].(bool)
+		opts.DMs, _ = cfg.Options["dms"].(bool)
+		if channels, ok := cfg.Options["channels"].([]interface{}); ok {
+			for _, ch := range channels {
+				opts.Channels = append(opts.Channels, fmt.Sprint(ch))
+			}
+		}
+		adapter, err = adapters.NewDiscordAdapter(opts)
+		if err != nil {
+			result.Error = fmt.Sprintf("Failed to create adapter: %v", err)
+			result.ErrorCode = errs.Classify(err).Code
 			return result
 		}
 
@@ -234,6 +300,7 @@ func syncAdapter(ctx context.Context, db *sql.DB, name string, cfg config.Adapte
 		adapter, err = adapters.NewGmailAdapter(instanceName, account, opts)
 		if err != nil {
 			result.Error = fmt.Sprintf("Failed to create adapter: %v", err)
+			result.ErrorCode = errs.Classify(err).Code
 			return result
 		}
 
@@ -252,6 +319,19 @@ func syncAdapter(ctx context.Context, db *sql.DB, name string, cfg config.Adapte
 		adapter, err = adapters.NewCalendarAdapter(instanceName, account)
 		if err != nil {
 			result.Error = fmt.Sprintf("Failed to create adapter: %v", err)
+			result.ErrorCode = errs.Classify(err).Code
+			return result
+		}
+
+	case "ics":
+		// iCalendar files, a directory of them, or a feed URL (options: path, timezone)
+		var opts adapters.ICSAdapterOptions
+		opts.Path, _ = cfg.Options["path"].(string)
+		opts.Timezone, _ = cfg.Options["timezone"].(string)
+		adapter, err = adapters.NewICSAdapter(name, opts)
+		if err != nil {
+			result.Error = fmt.Sprintf("Failed to create adapter: %v", err)
+			result.ErrorCode = errs.Classify(err).Code
 			return result
 		}
 
@@ -284,6 +364,18 @@ func syncAdapter(ctx context.Context, db *sql.DB, name string, cfg config.Adapte
 		adapter, err = adapters.NewContactsAdapter(instanceName, account, opts)
 		if err != nil {
 			result.Error = fmt.Sprintf("Failed to create adapter: %v", err)
+			result.ErrorCode = errs.Classify(err).Code
+			return result
+		}
+
+	case "addressbook":
+		// vCard files, a directory of them, or macOS Contacts (options: path)
+		var opts adapters.AddressBookAdapterOptions
+		opts.Path, _ = cfg.Options["path"].(string)
+		adapter, err = adapters.NewAddressBookAdapter(name, opts)
+		if err != nil {
+			result.Error = fmt.Sprintf("Failed to create adapter: %v", err)
+			result.ErrorCode = errs.Classify(err).Code
 			return result
 		}
 
@@ -301,6 +393,7 @@ func syncAdapter(ctx context.Context, db *sql.DB, name string, cfg config.Adapte
 		adapter, err = adapters.NewAixAdapter(source)
 		if err != nil {
 			result.Error = fmt.Sprintf("Failed to create adapter: %v", err)
+			result.ErrorCode = errs.Classify(err).Code
 			return result
 		}
 
@@ -319,6 +412,7 @@ func syncAdapter(ctx context.Context, db *sql.DB, name string, cfg config.Adapte
 		adapter, err = adapters.NewAixEventsAdapter(source)
 		if err != nil {
 			result.Error = fmt.Sprintf("Failed to create adapter: %v", err)
+			result.ErrorCode = errs.Classify(err).Code
 			return result
 		}
 
@@ -337,6 +431,7 @@ func syncAdapter(ctx context.Context, db *sql.DB, name string, cfg config.Adapte
 		adapter, err = adapters.NewAixAgentsAdapter(source)
 		if err != nil {
 			result.Error = fmt.Sprintf("Failed to create adapter: %v", err)
+			result.ErrorCode = errs.Classify(err).Code
 			return result
 		}
 
@@ -360,6 +455,7 @@ func syncAdapter(ctx context.Context, db *sql.DB, name string, cfg config.Adapte
 		adapter, err = adapters.NewNexusAdapter(opts)
 		if err != nil {
 			result.Error = fmt.Sprintf("Failed to create adapter: %v", err)
+			result.ErrorCode = errs.Classify(err).Code
 			return result
 		}
 
@@ -370,6 +466,49 @@ func syncAdapter(ctx context.Context, db *sql.DB, name string, cfg config.Adapte
 			username, _ = usernameVal.(string)
 		}
 		adapter, err = adapters.NewBirdAdapter(username)
+		if err != nil {
+			result.Error = fmt.Sprintf("Failed to create adapter: %v", err)
+			result.ErrorCode = errs.Classify(err).Code
+			return result
+		}
+
+	case "whatsapp_export":
+		// WhatsApp "Export chat" files (.txt or .zip) in a directory
+		var opts adapters.WhatsAppAdapterOptions
+		opts.Path, _ = cfg.Options["path"].(string)
+		opts.Me, _ = cfg.Options["me"].(string)
+		opts.Timezone, _ = cfg.Options["timezone"].(string)
+		opts.DateOrder, _ = cfg.Options["date_order"].(string)
+		adapter, err = adapters.NewWhatsAppAdapter(opts)
+		if err != nil {
+			result.Error = fmt.Sprintf("Failed to create adapter: %v", err)
+			result.ErrorCode = errs.Classify(err).Code
+			return result
+		}
+
+	case "generic":
+		// JSONL/CSV files with a field mapping (options: path, format,
+		// channel, mapping, timestamp_format, timezone, me)
+		opts, oerr := adapters.GenericOptionsFromConfig(cfg.Options)
+		if oerr != nil {
+			result.Error = fmt.Sprintf("Failed to create adapter: %v", oerr)
+			return result
+		}
+		adapter, err = adapters.NewGenericAdapter(name, opts)
+		if err != nil {
+			result.Error = fmt.Sprintf("Failed to create adapter: %v", err)
+			result.ErrorCode = errs.Classify(err).Code
+			return result
+		}
+
+	case "plugin":
+		// External program speaking the plugin protocol (options: command, args, env, timeout)
+		p, perr := plugin.FromOptions(name, cfg.Options)
+		if perr != nil {
+			result.Error = fmt.Sprintf("Failed to create adapter: %v", perr)
+			return result
+		}
+		adapter, err = adapters.NewPluginAdapter(name, p)
 		if err != nil {
 			result.Error = fmt.Sprintf("Failed to create adapter: %v", err)
 			return result
@@ -384,6 +523,7 @@ func syncAdapter(ctx context.Context, db *sql.DB, name string, cfg config.Adapte
 	syncResult, err := adapter.Sync(ctx, db, full)
 	if err != nil {
 		result.Error = fmt.Sprintf("Sync failed: %v", err)
+		result.ErrorCode = errs.Classify(err).Code
 		_ = FinishJobError(db, name, "sync", nil, result.Error, nil)
 		return result
 	}
@@ -0,0 +1,69 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/config"
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestSyncAdapters(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	// Like db.Open; concurrent adapters share the one connection
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	cfg := &config.Config{Adapters: map[string]config.AdapterConfig{}}
+	for i, name := range []string{"forum", "irc", "matrix", "sms"} {
+		path := filepath.Join(dir, name+".jsonl")
+		row := fmt.Sprintf(`{"id": "%s-1", "timestamp": %d, "sender": "bob@example.com", "content": "hi", "thread": "t"}`, name, 1767225600+i)
+		if err := os.WriteFile(path, []byte(row+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cfg.Adapters[name] = config.AdapterConfig{Type: "generic", Enabled: name != "sms", Options: map[string]interface{}{"path": path}}
+	}
+	cfg.Adapters["gone"] = config.AdapterConfig{Type: "generic", Enabled: true, Options: map[string]interface{}{"path": filepath.Join(dir, "missing.jsonl")}}
+
+	res := SyncAdapters(ctx, db, cfg, nil, SyncOptions{Concurrency: 3})
+	if res.OK {
+		t.Error("OK despite a failed adapter")
+	}
+	var got []string
+	for _, a := range res.Adapters {
+		got = append(got, fmt.Sprintf("%s:%v:%d", a.AdapterName, a.Success, a.EventsCreated))
+	}
+	if want := "forum:true:1 gone:false:0 irc:true:1 matrix:true:1"; strings.Join(got, " ") != want {
+		t.Errorf("results = %s, want %s", strings.Join(got, " "), want)
+	}
+	if res.Adapters[1].ErrorCode != "adapter_source_missing" {
+		t.Errorf("gone error code = %q", res.Adapters[1].ErrorCode)
+	}
+
+	// Named adapters run in the order given
+	res = SyncAdapters(ctx, db, cfg, []string{"matrix", "forum", "matrix"}, SyncOptions{Full: true, Concurrency: 8})
+	if !res.OK || len(res.Adapters) != 2 || res.Adapters[0].AdapterName != "matrix" || res.Adapters[1].AdapterName != "forum" {
+		t.Errorf("named = %+v", res)
+	}
+
+	for names, want := range map[string]string{
+		"irc,nope": "Adapter 'nope' not configured",
+		"irc,sms":  "Adapter 'sms' is disabled",
+	} {
+		res := SyncAdapters(ctx, db, cfg, strings.Split(names, ","), SyncOptions{})
+		if res.OK || res.Message != want || len(res.Adapters) != 0 {
+			t.Errorf("%s: %+v, want %q", names, res, want)
+		}
+	}
+
+	res = SyncAll(ctx, db, &config.Config{}, false)
+	if !res.OK || res.Message != "No adapters configured" {
+		t.Errorf("no adapters = %+v", res)
+	}
+}
@@ -0,0 +1,218 @@
+package testutil
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Entity type IDs used by generated graphs (see memory.EntityTypePerson etc.)
+const (
+	genEntityTypePerson       = 1
+	genEntityTypeOrganization = 2
+)
+
+// genRelationTypes are cycled through by GenerateGraph.
+var genRelationTypes = []string{"KNOWS", "WORKS_AT", "LIVES_IN", "FRIEND_OF", "MENTIONED_WITH"}
+
+// Scaled multiplies n by MNEMONIC_BENCH_SCALE (default 1), so benchmarks
+// sized for the real workload can run quickly, e.g. with scale 0.01.
+func Scaled(n int) int {
+	scale, err := strconv.ParseFloat(os.Getenv("MNEMONIC_BENCH_SCALE"), 64)
+	if err != nil || scale <= 0 {
+		return n
+	}
+	return max(1, int(float64(n)*scale))
+}
+
+// EventSpec shapes GenerateEvents. Zero fields take defaults.
+type EventSpec struct {
+	Events  int    // total events
+	Threads int    // events are spread round-robin-ish across threads (default Events/100)
+	Channel string // default "imessage"
+	Start   int64  // unix time of the first event (default 2024-01-01)
+	Seed    int64
+}
+
+// GenerateEvents inserts synthetic message events: bursts of a few minutes
+// apart separated by gaps of hours, so time-gap chunking finds episodes.
+// Event IDs are "ev-<n>" and thread IDs "th-<n>".
+func GenerateEvents(tb testing.TB, db *sql.DB, spec EventSpec) {
+	tb.Helper()
+	if spec.Threads <= 0 {
+		spec.Threads = max(1, spec.Events/100)
+	}
+	if spec.Channel == "" {
+		spec.Channel = "imessage"
+	}
+	if spec.Start == 0 {
+		spec.Start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	}
+	rng := rand.New(rand.NewSource(spec.Seed))
+
+	err := inTx(db, func(tx *sql.Tx) error {
+		for i := 0; i < spec.Threads; i++ {
+			if _, err := tx.Exec(`
+				INSERT INTO threads (id, channel, name, source_adapter, source_id, created_at, updated_at)
+				VALUES (?, ?, ?, 'bench', ?, ?, ?)
+			`, fmt.Sprintf("th-%d", i), spec.Channel, fmt.Sprintf("Thread %d", i), fmt.Sprintf("th-%d", i), spec.Start, spec.Start); err != nil {
+				return err
+			}
+		}
+
+		stmt, err := tx.Prepare(`
+			INSERT INTO events (id, timestamp, channel, content_types, content, direction, thread_id, source_adapter, source_id)
+			VALUES (?, ?, ?, '["text"]', ?, ?, ?, 'bench', ?)
+		`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		clock := make([]int64, spec.Threads)
+		for i := range clock {
+			clock[i] = spec.Start
+		}
+		for i := 0; i < spec.Events; i++ {
+			thread := rng.Intn(spec.Threads)
+			if rng.Intn(10) == 0 {
+				clock[thread] += int64(2*3600 + rng.Intn(48*3600))
+			} else {
+				clock[thread] += int64(5 + rng.Intn(300))
+			}
+			direction := "received"
+			if rng.Intn(2) == 0 {
+				direction = "sent"
+			}
+			id := fmt.Sprintf("ev-%d", i)
+			if _, err := stmt.Exec(id, clock[thread], spec.Channel, fmt.Sprintf("message %d", i), direction, fmt.Sprintf("th-%d", thread), id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		tb.Fatalf("generate events: %v", err)
+	}
+}
+
+// GraphSpec shapes GenerateGraph. Zero fields take defaults.
+type GraphSpec struct {
+	Entities         int
+	Edges            int
+	AliasesPerEntity int // name aliases besides the canonical name (default 0)
+	Seed             int64
+}
+
+// GenerateGraph inserts a synthetic memory graph: Entities entities (one in
+// five an organization) named "Entity <n>", and Edges relationships with a
+// skewed degree distribution, so a few hubs have many neighbours as in real
+// graphs. Each entity gets its canonical name as an alias plus
+// AliasesPerEntity more ("entity <n> alt<k>"). It returns the entity IDs,
+// "ent-<n>".
+func GenerateGraph(tb testing.TB, db *sql.DB, spec GraphSpec) []string {
+	tb.Helper()
+	rng := rand.New(rand.NewSource(spec.Seed))
+	now := time.Now().UTC().Format(time.RFC3339)
+	ids := make([]string, spec.Entities)
+
+	err := inTx(db, func(tx *sql.Tx) error {
+		entStmt, err := tx.Prepare(`
+			INSERT INTO entities (id, canonical_name, entity_type_id, origin, created_at, updated_at)
+			VALUES (?, ?, ?, 'extracted', ?, ?)
+		`)
+		if err != nil {
+			return err
+		}
+		defer entStmt.Close()
+		aliasStmt, err := tx.Prepare(`
+			INSERT INTO entity_aliases (id, entity_id, alias, alias_type, normalized, created_at)
+			VALUES (?, ?, ?, 'name', lower(?), ?)
+		`)
+		if err != nil {
+			return err
+		}
+		defer aliasStmt.Close()
+
+		for i := range ids {
+			ids[i] = fmt.Sprintf("ent-%d", i)
+			name := fmt.Sprintf("Entity %d", i)
+			typeID := genEntityTypePerson
+			if i%5 == 0 {
+				typeID = genEntityTypeOrganization
+			}
+			if _, err := entStmt.Exec(ids[i], name, typeID, now, now); err != nil {
+				return err
+			}
+			for k := 0; k <= spec.AliasesPerEntity; k++ {
+				alias := name
+				if k > 0 {
+					alias = fmt.Sprintf("%s alt%d", name, k)
+				}
+				if _, err := aliasStmt.Exec(fmt.Sprintf("alias-%d-%d", i, k), ids[i], alias, alias, now); err != nil {
+					return err
+				}
+			}
+		}
+
+		relStmt, err := tx.Prepare(`
+			INSERT INTO relationships (id, source_entity_id, target_entity_id, relation_type, fact, created_at, weight)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`)
+		if err != nil {
+			return err
+		}
+		defer relStmt.Close()
+
+		for i := 0; i < spec.Edges && len(ids) > 1; i++ {
+			src := skewedIndex(rng, len(ids))
+			dst := rng.Intn(len(ids))
+			if dst == src {
+				dst = (dst + 1) % len(ids)
+			}
+			rel := genRelationTypes[i%len(genRelationTypes)]
+			fact := fmt.Sprintf("Entity %d %s Entity %d", src, rel, dst)
+			if _, err := relStmt.Exec(fmt.Sprintf("rel-%d", i), ids[src], ids[dst], rel, fact, now, rng.Float64()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		tb.Fatalf("generate graph: %v", err)
+	}
+	return ids
+}
+
+// Embedding returns a deterministic pseudo-random vector of length dims.
+func Embedding(seed int64, dims int) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	v := make([]float64, dims)
+	for i := range v {
+		v[i] = rng.Float64()*2 - 1
+	}
+	return v
+}
+
+// skewedIndex picks from [0, n) favouring low indexes: squaring a uniform
+// draw gives index 0..n/4 about half the picks.
+func skewedIndex(rng *rand.Rand, n int) int {
+	f := rng.Float64()
+	return int(f * f * float64(n))
+}
+
+func inTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
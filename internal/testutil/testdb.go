@@ -11,7 +11,7 @@ import (
 )
 
 // OpenTestDB creates an in-memory SQLite DB and applies the cortex schema.
-func OpenTestDB(t *testing.T) *sql.DB {
+func OpenTestDB(t testing.TB) *sql.DB {
 	t.Helper()
 
 	db, err := sql.Open("sqlite", ":memory:")
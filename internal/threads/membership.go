@@ -0,0 +1,264 @@
+package threads
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Membership sources.
+const (
+	MembershipFromEvent    = "membership_event" // added/removed events
+	MembershipFromObserved = "observed"         // took part, with no membership events
+)
+
+// membershipContentTypes marks membership events (adapters store the action,
+// "added" or "removed", as content and in metadata_json.action).
+const membershipContentTypes = `["membership"]`
+
+// Membership is one span of a contact's membership in a group thread.
+// A nil JoinedAt means a member since before the first record; a nil
+// LeftAt means still a member.
+type Membership struct {
+	ThreadID     string     `json:"thread_id"`
+	ContactID    string     `json:"contact_id"`
+	Name         string     `json:"name"`
+	PersonID     string     `json:"person_id,omitempty"`
+	JoinedAt     *time.Time `json:"joined_at,omitempty"`
+	LeftAt       *time.Time `json:"left_at,omitempty"`
+	JoinEventID  string     `json:"join_event_id,omitempty"`
+	LeaveEventID string     `json:"leave_event_id,omitempty"`
+	Source       string     `json:"source"`
+}
+
+// span is a membership span being derived, with unix times (0 = open).
+type span struct {
+	contactID        string
+	joinedAt, leftAt int64
+	joinEvent        string
+	leaveEvent       string
+	source           string
+}
+
+// RebuildMembership recomputes thread_membership for group threads (and
+// any thread with membership events) that have events at or after since
+// (0 rebuilds all). Returns the number of threads rebuilt.
+func RebuildMembership(db *sql.DB, since int64) (int, error) {
+	rows, err := db.Query(`
+		SELECT DISTINCT e.thread_id FROM events e
+		LEFT JOIN threads t ON t.id = e.thread_id
+		WHERE e.thread_id IS NOT NULL AND e.timestamp >= ?
+		  AND (t.is_group = 1 OR e.content_types = ?)
+	`, since, membershipContentTypes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query group threads: %w", err)
+	}
+	var threadIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan thread: %w", err)
+		}
+		threadIDs = append(threadIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	for _, id := range threadIDs {
+		if err := rebuildThreadMembership(tx, id); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return len(threadIDs), nil
+}
+
+// rebuildThreadMembership replaces a thread's membership spans. Spans come
+// from membership events in order: "added" opens a span, "removed" closes
+// it (a removal with nothing open means a member from before the first
+// record). Contacts who took part in the thread without any membership
+// events are members throughout, and a contact seen before their first
+// recorded join was a member from the start.
+func rebuildThreadMembership(tx *sql.Tx, threadID string) error {
+	if _, err := tx.Exec(`DELETE FROM thread_membership WHERE thread_id = ?`, threadID); err != nil {
+		return fmt.Errorf("failed to clear membership: %w", err)
+	}
+
+	var isGroup bool
+	err := tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM threads WHERE id = ? AND is_group = 1)
+		    OR EXISTS (SELECT 1 FROM events WHERE thread_id = ? AND content_types = ?)
+	`, threadID, threadID, membershipContentTypes).Scan(&isGroup)
+	if err != nil {
+		return fmt.Errorf("failed to check thread %s: %w", threadID, err)
+	}
+	if !isGroup {
+		return nil
+	}
+
+	spans := make(map[string][]*span)
+	var order []string
+	add := func(s *span) {
+		if _, ok := spans[s.contactID]; !ok {
+			order = append(order, s.contactID)
+		}
+		spans[s.contactID] = append(spans[s.contactID], s)
+	}
+
+	rows, err := tx.Query(`
+		SELECT e.id, e.timestamp, COALESCE(json_extract(e.metadata_json, '$.action'), e.content, ''), ep.contact_id
+		FROM events e
+		JOIN event_participants ep ON ep.event_id = e.id AND ep.role = 'member'
+		WHERE e.thread_id = ? AND e.content_types = ?
+		ORDER BY e.timestamp, e.id
+	`, threadID, membershipContentTypes)
+	if err != nil {
+		return fmt.Errorf("failed to query membership events: %w", err)
+	}
+	for rows.Next() {
+		var eventID, action, contactID string
+		var ts int64
+		if err := rows.Scan(&eventID, &ts, &action, &contactID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan membership event: %w", err)
+		}
+		list := spans[contactID]
+		var open *span
+		if n := len(list); n > 0 && list[n-1].leftAt == 0 {
+			open = list[n-1]
+		}
+		switch action {
+		case "added":
+			if open == nil {
+				add(&span{contactID: contactID, joinedAt: ts, joinEvent: eventID, source: MembershipFromEvent})
+			}
+		case "removed":
+			if open != nil {
+				open.leftAt, open.leaveEvent = ts, eventID
+			} else if len(list) == 0 {
+				add(&span{contactID: contactID, leftAt: ts, leaveEvent: eventID, source: MembershipFromEvent})
+			}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = tx.Query(`
+		SELECT ep.contact_id, MIN(e.timestamp)
+		FROM events e
+		JOIN event_participants ep ON ep.event_id = e.id
+		WHERE e.thread_id = ? AND e.content_types != ?
+		GROUP BY ep.contact_id
+	`, threadID, membershipContentTypes)
+	if err != nil {
+		return fmt.Errorf("failed to query participants: %w", err)
+	}
+	for rows.Next() {
+		var contactID string
+		var firstSeen int64
+		if err := rows.Scan(&contactID, &firstSeen); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan participant: %w", err)
+		}
+		list := spans[contactID]
+		if len(list) == 0 {
+			add(&span{contactID: contactID, source: MembershipFromObserved})
+		} else if list[0].joinedAt != 0 && firstSeen < list[0].joinedAt {
+			list[0].joinedAt, list[0].joinEvent = 0, ""
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	sort.Strings(order)
+	for _, contactID := range order {
+		for _, s := range spans[contactID] {
+			if _, err := tx.Exec(`
+				INSERT INTO thread_membership (thread_id, contact_id, joined_at, left_at, join_event_id, leave_event_id, source)
+				VALUES (?, ?, ?, ?, ?, ?, ?)
+			`, threadID, s.contactID, nullIfZero(s.joinedAt), nullIfZero(s.leftAt),
+				nullIfEmpty(s.joinEvent), nullIfEmpty(s.leaveEvent), s.source); err != nil {
+				return fmt.Errorf("failed to insert membership: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Roster returns every membership span of a thread, earliest first.
+func Roster(db *sql.DB, threadID string) ([]Membership, error) {
+	return queryMemberships(db, `WHERE m.thread_id = ?`, threadID)
+}
+
+// MembersAt returns who was in a thread at a point in time.
+func MembersAt(db *sql.DB, threadID string, at time.Time) ([]Membership, error) {
+	return queryMemberships(db, `
+		WHERE m.thread_id = ?
+		  AND (m.joined_at IS NULL OR m.joined_at <= ?)
+		  AND (m.left_at IS NULL OR m.left_at > ?)
+	`, threadID, at.Unix(), at.Unix())
+}
+
+func queryMemberships(db *sql.DB, where string, args ...interface{}) ([]Membership, error) {
+	rows, err := db.Query(`
+		SELECT m.thread_id, m.contact_id,
+		       COALESCE(p.canonical_name, c.display_name, ''), COALESCE(p.id, ''),
+		       m.joined_at, m.left_at, COALESCE(m.join_event_id, ''), COALESCE(m.leave_event_id, ''), m.source
+		FROM thread_membership m
+		LEFT JOIN contacts c ON c.id = m.contact_id
+		LEFT JOIN persons p ON p.id = (
+			SELECT person_id FROM person_contact_links
+			WHERE contact_id = m.contact_id
+			ORDER BY confidence DESC, last_seen_at DESC
+			LIMIT 1
+		)
+		`+where+`
+		ORDER BY COALESCE(m.joined_at, 0), m.id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query membership: %w", err)
+	}
+	defer rows.Close()
+
+	var members []Membership
+	for rows.Next() {
+		var m Membership
+		var joinedAt, leftAt sql.NullInt64
+		if err := rows.Scan(&m.ThreadID, &m.ContactID, &m.Name, &m.PersonID, &joinedAt, &leftAt,
+			&m.JoinEventID, &m.LeaveEventID, &m.Source); err != nil {
+			return nil, fmt.Errorf("failed to scan membership: %w", err)
+		}
+		if joinedAt.Valid {
+			t := time.Unix(joinedAt.Int64, 0)
+			m.JoinedAt = &t
+		}
+		if leftAt.Valid {
+			t := time.Unix(leftAt.Int64, 0)
+			m.LeftAt = &t
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+func nullIfZero(n int64) interface{} {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
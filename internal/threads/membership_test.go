@@ -0,0 +1,108 @@
+package threads
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestRebuildMembership(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+
+	exec := func(query string, args ...interface{}) {
+		t.Helper()
+		if _, err := db.Exec(query, args...); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+	}
+	exec(`
+		INSERT INTO threads (id, channel, name, is_group, source_adapter, source_id, created_at, updated_at)
+		VALUES ('imessage:chat1', 'imessage', 'Trip', 1, 'imessage', 'chat1', 0, 0)
+	`)
+	for _, c := range []string{"alice", "bob", "carol", "dave"} {
+		exec(`INSERT INTO contacts (id, display_name, source, created_at, updated_at) VALUES (?, ?, 'test', 0, 0)`, c, c)
+	}
+	message := func(id string, ts int64, sender string) {
+		exec(`
+			INSERT INTO events (id, timestamp, channel, content_types, content, direction, thread_id, source_adapter, source_id)
+			VALUES (?, ?, 'imessage', '["text"]', 'hi', 'received', 'imessage:chat1', 'imessage', ?)
+		`, id, ts, id)
+		exec(`INSERT INTO event_participants (event_id, contact_id, role) VALUES (?, ?, 'sender')`, id, sender)
+	}
+	membership := func(id string, ts int64, action, member string) {
+		exec(`
+			INSERT INTO events (id, timestamp, channel, content_types, content, direction, thread_id, source_adapter, source_id, metadata_json)
+			VALUES (?, ?, 'imessage', '["membership"]', ?, 'observed', 'imessage:chat1', 'imessage', ?, json_object('action', ?))
+		`, id, ts, action, id, action)
+		exec(`INSERT INTO event_participants (event_id, contact_id, role) VALUES (?, ?, 'member')`, id, member)
+	}
+
+	message("m1", 100, "alice") // alice: no membership events, member throughout
+	membership("j1", 200, "added", "bob")
+	message("m2", 250, "bob")
+	membership("l1", 300, "removed", "bob")
+	membership("j2", 400, "added", "bob")     // bob rejoins
+	membership("l2", 150, "removed", "carol") // carol: in before the first record
+	message("m3", 50, "dave")                 // dave spoke before his recorded join
+	membership("j3", 500, "added", "dave")
+
+	n, err := RebuildMembership(db, 0)
+	if err != nil {
+		t.Fatalf("RebuildMembership: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("threads rebuilt = %d, want 1", n)
+	}
+
+	roster, err := Roster(db, "imessage:chat1")
+	if err != nil {
+		t.Fatalf("Roster: %v", err)
+	}
+	if len(roster) != 5 {
+		t.Fatalf("roster has %d spans, want 5: %+v", len(roster), roster)
+	}
+
+	at := func(ts int64) map[string]bool {
+		t.Helper()
+		members, err := MembersAt(db, "imessage:chat1", time.Unix(ts, 0))
+		if err != nil {
+			t.Fatalf("MembersAt: %v", err)
+		}
+		got := make(map[string]bool)
+		for _, m := range members {
+			got[m.ContactID] = true
+		}
+		return got
+	}
+	tests := []struct {
+		ts   int64
+		want []string
+	}{
+		{100, []string{"alice", "carol", "dave"}},
+		{220, []string{"alice", "bob", "dave"}},
+		{350, []string{"alice", "dave"}},
+		{450, []string{"alice", "bob", "dave"}},
+	}
+	for _, tt := range tests {
+		got := at(tt.ts)
+		if len(got) != len(tt.want) {
+			t.Errorf("members at %d = %v, want %v", tt.ts, got, tt.want)
+			continue
+		}
+		for _, c := range tt.want {
+			if !got[c] {
+				t.Errorf("members at %d = %v, want %v", tt.ts, got, tt.want)
+			}
+		}
+	}
+
+	// Rebuilding is idempotent
+	if _, err := RebuildMembership(db, 0); err != nil {
+		t.Fatalf("RebuildMembership again: %v", err)
+	}
+	again, _ := Roster(db, "imessage:chat1")
+	if len(again) != len(roster) {
+		t.Fatalf("roster after rebuild has %d spans, want %d", len(again), len(roster))
+	}
+}
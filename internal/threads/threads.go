@@ -0,0 +1,387 @@
+package threads
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChangeResult describes a completed merge or split.
+type ChangeResult struct {
+	ChangeID        string `json:"change_id"`
+	SourceThreadID  string `json:"source_thread_id"`
+	TargetThreadID  string `json:"target_thread_id"`
+	EventsMoved     int    `json:"events_moved"`
+	EpisodesUpdated int    `json:"episodes_updated"`
+}
+
+// Change is a recorded thread merge or split.
+type Change struct {
+	ID              string    `json:"id"`
+	Operation       string    `json:"operation"`
+	SourceThreadID  string    `json:"source_thread_id"`
+	TargetThreadID  string    `json:"target_thread_id"`
+	EventIDs        []string  `json:"event_ids"`
+	EpisodesUpdated int       `json:"episodes_updated"`
+	Reason          string    `json:"reason,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// SplitOptions selects which events move to the new thread.
+type SplitOptions struct {
+	Since       int64    // Move events at or after this unix timestamp
+	EventIDs    []string // Move exactly these events (overrides Since)
+	NewThreadID string   // ID for the new thread (generated if empty)
+	Name        string   // Name for the new thread (defaults to the original's)
+	Reason      string
+}
+
+// Merge moves every event of thread fromID into thread intoID, e.g. when a
+// phone number change or channel migration fragmented a conversation.
+// Episodes scoped to fromID are re-pointed at intoID. The source thread row
+// is kept so future syncs still have somewhere to land, and the move is
+// recorded in thread_changes.
+func Merge(db *sql.DB, fromID, intoID, reason string) (*ChangeResult, error) {
+	if fromID == intoID {
+		return nil, fmt.Errorf("cannot merge a thread into itself")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := requireThread(tx, fromID); err != nil {
+		return nil, err
+	}
+	if err := requireThread(tx, intoID); err != nil {
+		return nil, err
+	}
+
+	eventIDs, err := queryIDs(tx, `SELECT id FROM events WHERE thread_id = ? ORDER BY timestamp, id`, fromID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load events: %w", err)
+	}
+	if len(eventIDs) == 0 {
+		return nil, fmt.Errorf("thread %s has no events", fromID)
+	}
+
+	result, err := moveEvents(tx, "merge", fromID, intoID, eventIDs, reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// Split moves part of a thread into a new thread: either the listed events
+// or everything since a timestamp. Episodes entirely inside the moved set
+// follow it; episodes straddling the split are marked as spanning threads.
+func Split(db *sql.DB, threadID string, opts SplitOptions) (*ChangeResult, error) {
+	if len(opts.EventIDs) == 0 && opts.Since == 0 {
+		return nil, fmt.Errorf("split requires event IDs or a --since timestamp")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := requireThread(tx, threadID); err != nil {
+		return nil, err
+	}
+
+	var eventIDs []string
+	if len(opts.EventIDs) > 0 {
+		for _, id := range opts.EventIDs {
+			var got string
+			err := tx.QueryRow(`SELECT thread_id FROM events WHERE id = ?`, id).Scan(&got)
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("event not found: %s", id)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to load event %s: %w", id, err)
+			}
+			if got != threadID {
+				return nil, fmt.Errorf("event %s is not in thread %s", id, threadID)
+			}
+			eventIDs = append(eventIDs, id)
+		}
+	} else {
+		eventIDs, err = queryIDs(tx, `SELECT id FROM events WHERE thread_id = ? AND timestamp >= ? ORDER BY timestamp, id`, threadID, opts.Since)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load events: %w", err)
+		}
+	}
+	if len(eventIDs) == 0 {
+		return nil, fmt.Errorf("no events selected to split")
+	}
+
+	var total int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM events WHERE thread_id = ?`, threadID).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count events: %w", err)
+	}
+	if len(eventIDs) == total {
+		return nil, fmt.Errorf("split would move every event in %s; nothing would remain", threadID)
+	}
+
+	newID := strings.TrimSpace(opts.NewThreadID)
+	if newID == "" {
+		newID = threadID + ":split:" + uuid.New().String()[:8]
+	}
+	if err := createSplitThread(tx, threadID, newID, opts.Name); err != nil {
+		return nil, err
+	}
+
+	result, err := moveEvents(tx, "split", threadID, newID, eventIDs, opts.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// ListChanges returns recorded merges and splits touching a thread (all if threadID is empty).
+func ListChanges(db *sql.DB, threadID string, limit int) ([]Change, error) {
+	query := `
+		SELECT id, operation, source_thread_id, target_thread_id, event_ids,
+		       episodes_updated, COALESCE(reason, ''), created_at
+		FROM thread_changes
+	`
+	var args []interface{}
+	if threadID != "" {
+		query += ` WHERE source_thread_id = ? OR target_thread_id = ?`
+		args = append(args, threadID, threadID)
+	}
+	query += ` ORDER BY created_at DESC, id`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query thread changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []Change
+	for rows.Next() {
+		var c Change
+		var eventIDs string
+		var createdAt int64
+		if err := rows.Scan(&c.ID, &c.Operation, &c.SourceThreadID, &c.TargetThreadID, &eventIDs,
+			&c.EpisodesUpdated, &c.Reason, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan thread change: %w", err)
+		}
+		_ = json.Unmarshal([]byte(eventIDs), &c.EventIDs)
+		c.CreatedAt = time.Unix(createdAt, 0)
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// requireThread errors unless the thread has a threads row or events.
+func requireThread(tx *sql.Tx, threadID string) error {
+	var exists int
+	err := tx.QueryRow(`
+		SELECT 1 WHERE EXISTS (SELECT 1 FROM threads WHERE id = ?)
+		   OR EXISTS (SELECT 1 FROM events WHERE thread_id = ?)
+	`, threadID, threadID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("thread not found: %s", threadID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check thread %s: %w", threadID, err)
+	}
+	return nil
+}
+
+// createSplitThread creates the threads row for a split, copying the
+// original's channel and adapter when it has one.
+func createSplitThread(tx *sql.Tx, fromID, newID, name string) error {
+	var channel, adapter string
+	var origName sql.NullString
+	var isGroup int
+	err := tx.QueryRow(`
+		SELECT channel, name, is_group, source_adapter FROM threads WHERE id = ?
+	`, fromID).Scan(&channel, &origName, &isGroup, &adapter)
+	if err == sql.ErrNoRows {
+		// No thread row to copy; events alone define the thread
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load thread %s: %w", fromID, err)
+	}
+	if name == "" && origName.Valid {
+		name = origName.String
+	}
+
+	now := time.Now().Unix()
+	if _, err := tx.Exec(`
+		INSERT INTO threads (id, channel, name, is_group, source_adapter, source_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, newID, channel, nullIfEmpty(name), isGroup, adapter, newID, now, now); err != nil {
+		return fmt.Errorf("failed to create thread %s: %w", newID, err)
+	}
+	return nil
+}
+
+// moveEvents rewrites thread_id for the given events, updates affected
+// episodes and both threads' rosters, and records the change.
+func moveEvents(tx *sql.Tx, operation, fromID, toID string, eventIDs []string, reason string) (*ChangeResult, error) {
+	moved := make(map[string]bool, len(eventIDs))
+	for _, id := range eventIDs {
+		if _, err := tx.Exec(`UPDATE events SET thread_id = ? WHERE id = ?`, toID, id); err != nil {
+			return nil, fmt.Errorf("failed to move event %s: %w", id, err)
+		}
+		moved[id] = true
+	}
+
+	episodesUpdated, err := updateEpisodes(tx, fromID, toID, moved)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range []string{fromID, toID} {
+		if err := rebuildThreadMembership(tx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().Unix()
+	_, _ = tx.Exec(`UPDATE threads SET updated_at = ? WHERE id IN (?, ?)`, now, fromID, toID)
+
+	idsJSON, err := json.Marshal(eventIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event IDs: %w", err)
+	}
+	changeID := uuid.New().String()
+	if _, err := tx.Exec(`
+		INSERT INTO thread_changes (id, operation, source_thread_id, target_thread_id, event_ids, episodes_updated, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, changeID, operation, fromID, toID, string(idsJSON), episodesUpdated, nullIfEmpty(reason), now); err != nil {
+		return nil, fmt.Errorf("failed to record thread change: %w", err)
+	}
+
+	return &ChangeResult{
+		ChangeID:        changeID,
+		SourceThreadID:  fromID,
+		TargetThreadID:  toID,
+		EventsMoved:     len(eventIDs),
+		EpisodesUpdated: episodesUpdated,
+	}, nil
+}
+
+// updateEpisodes re-scopes episodes of the source thread that contain moved
+// events: episodes made up only of moved events follow them to the target
+// thread; mixed episodes now span two threads, so their thread_id is cleared.
+func updateEpisodes(tx *sql.Tx, fromID, toID string, moved map[string]bool) (int, error) {
+	rows, err := tx.Query(`
+		SELECT ep.id, ee.event_id
+		FROM episodes ep
+		JOIN episode_events ee ON ee.episode_id = ep.id
+		WHERE ep.thread_id = ?
+		ORDER BY ep.id
+	`, fromID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load episodes: %w", err)
+	}
+
+	type episodeState struct {
+		moved, kept int
+	}
+	var order []string
+	states := make(map[string]*episodeState)
+	for rows.Next() {
+		var episodeID, eventID string
+		if err := rows.Scan(&episodeID, &eventID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan episode event: %w", err)
+		}
+		st, ok := states[episodeID]
+		if !ok {
+			st = &episodeState{}
+			states[episodeID] = st
+			order = append(order, episodeID)
+		}
+		if moved[eventID] {
+			st.moved++
+		} else {
+			st.kept++
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate episodes: %w", err)
+	}
+
+	updated := 0
+	for _, episodeID := range order {
+		st := states[episodeID]
+		if st.moved == 0 {
+			continue
+		}
+		var threadID interface{}
+		if st.kept == 0 {
+			if err := ensureThreadRow(tx, toID, fromID); err != nil {
+				return 0, err
+			}
+			threadID = toID
+		}
+		if _, err := tx.Exec(`UPDATE episodes SET thread_id = ? WHERE id = ?`, threadID, episodeID); err != nil {
+			return 0, fmt.Errorf("failed to update episode %s: %w", episodeID, err)
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// ensureThreadRow makes sure a threads row exists for id (episodes reference
+// threads), copying channel and adapter from template.
+func ensureThreadRow(tx *sql.Tx, id, template string) error {
+	var exists int
+	err := tx.QueryRow(`SELECT 1 FROM threads WHERE id = ?`, id).Scan(&exists)
+	if err == nil {
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check thread %s: %w", id, err)
+	}
+	return createSplitThread(tx, template, id, "")
+}
+
+func queryIDs(tx *sql.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
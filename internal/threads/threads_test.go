@@ -0,0 +1,135 @@
+package threads
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func seedThread(t *testing.T, db *sql.DB, threadID string, events map[string]int64) {
+	t.Helper()
+	if _, err := db.Exec(`
+		INSERT INTO threads (id, channel, name, source_adapter, source_id, created_at, updated_at)
+		VALUES (?, 'imessage', 'Casey', 'imessage', ?, 0, 0)
+	`, threadID, threadID); err != nil {
+		t.Fatalf("insert thread: %v", err)
+	}
+	for id, ts := range events {
+		if _, err := db.Exec(`
+			INSERT INTO events (id, timestamp, channel, content_types, content, direction, thread_id, source_adapter, source_id)
+			VALUES (?, ?, 'imessage', '["text"]', 'hi', 'received', ?, 'imessage', ?)
+		`, id, ts, threadID, id); err != nil {
+			t.Fatalf("insert event: %v", err)
+		}
+	}
+}
+
+func seedEpisode(t *testing.T, db *sql.DB, episodeID, threadID string, eventIDs ...string) {
+	t.Helper()
+	db.Exec(`INSERT OR IGNORE INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0)`)
+	if _, err := db.Exec(`
+		INSERT INTO episodes (id, definition_id, channel, thread_id, start_time, end_time, event_count, created_at)
+		VALUES (?, 'def', 'imessage', ?, 0, 0, ?, 0)
+	`, episodeID, threadID, len(eventIDs)); err != nil {
+		t.Fatalf("insert episode: %v", err)
+	}
+	for i, id := range eventIDs {
+		if _, err := db.Exec(`INSERT INTO episode_events (episode_id, event_id, position) VALUES (?, ?, ?)`, episodeID, id, i+1); err != nil {
+			t.Fatalf("insert episode event: %v", err)
+		}
+	}
+}
+
+func threadOf(t *testing.T, db *sql.DB, table, id string) sql.NullString {
+	t.Helper()
+	var threadID sql.NullString
+	if err := db.QueryRow(`SELECT thread_id FROM `+table+` WHERE id = ?`, id).Scan(&threadID); err != nil {
+		t.Fatalf("load %s %s: %v", table, id, err)
+	}
+	return threadID
+}
+
+func TestMerge(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+
+	seedThread(t, db, "old", map[string]int64{"e1": 100, "e2": 200})
+	seedThread(t, db, "new", map[string]int64{"e3": 300})
+	seedEpisode(t, db, "ep1", "old", "e1", "e2")
+
+	if _, err := Merge(db, "old", "old", ""); err == nil {
+		t.Error("expected error merging a thread into itself")
+	}
+	if _, err := Merge(db, "missing", "new", ""); err == nil {
+		t.Error("expected error for unknown thread")
+	}
+
+	res, err := Merge(db, "old", "new", "number change")
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if res.EventsMoved != 2 || res.EpisodesUpdated != 1 {
+		t.Errorf("result = %+v, want 2 events and 1 episode", res)
+	}
+	if got := threadOf(t, db, "events", "e1"); got.String != "new" {
+		t.Errorf("e1 thread = %q, want new", got.String)
+	}
+	if got := threadOf(t, db, "episodes", "ep1"); got.String != "new" {
+		t.Errorf("ep1 thread = %q, want new", got.String)
+	}
+
+	changes, err := ListChanges(db, "new", 0)
+	if err != nil {
+		t.Fatalf("ListChanges: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Operation != "merge" || len(changes[0].EventIDs) != 2 || changes[0].Reason != "number change" {
+		t.Errorf("changes = %+v, want one merge of 2 events", changes)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+
+	seedThread(t, db, "t1", map[string]int64{"e1": 100, "e2": 200, "e3": 300, "e4": 400})
+	seedEpisode(t, db, "ep1", "t1", "e1", "e2")
+	seedEpisode(t, db, "ep2", "t1", "e2", "e3")
+	seedEpisode(t, db, "ep3", "t1", "e3", "e4")
+
+	if _, err := Split(db, "t1", SplitOptions{Since: 50}); err == nil {
+		t.Error("expected error when every event would move")
+	}
+
+	res, err := Split(db, "t1", SplitOptions{Since: 300, NewThreadID: "t2"})
+	if err != nil {
+		t.Fatalf("Split: %v", err)
+	}
+	if res.EventsMoved != 2 || res.EpisodesUpdated != 2 {
+		t.Errorf("result = %+v, want 2 events and 2 episodes", res)
+	}
+
+	var name string
+	if err := db.QueryRow(`SELECT name FROM threads WHERE id = 't2'`).Scan(&name); err != nil || name != "Casey" {
+		t.Errorf("new thread name = %q (%v), want copied from original", name, err)
+	}
+	if got := threadOf(t, db, "events", "e2"); got.String != "t1" {
+		t.Errorf("e2 thread = %q, want t1", got.String)
+	}
+	if got := threadOf(t, db, "events", "e4"); got.String != "t2" {
+		t.Errorf("e4 thread = %q, want t2", got.String)
+	}
+	if got := threadOf(t, db, "episodes", "ep1"); got.String != "t1" {
+		t.Errorf("ep1 thread = %q, want t1 (untouched)", got.String)
+	}
+	if got := threadOf(t, db, "episodes", "ep2"); got.Valid {
+		t.Errorf("ep2 thread = %q, want NULL (spans both threads)", got.String)
+	}
+	if got := threadOf(t, db, "episodes", "ep3"); got.String != "t2" {
+		t.Errorf("ep3 thread = %q, want t2", got.String)
+	}
+
+	if _, err := Split(db, "t1", SplitOptions{EventIDs: []string{"e4"}}); err == nil {
+		t.Error("expected error splitting an event from another thread")
+	}
+}
@@ -21,7 +21,8 @@ type TimelineOptions struct {
 	EndDate   time.Time
 }
 
-// QueryTimeline retrieves event statistics grouped by day for the specified time period
+// QueryTimeline retrieves event statistics grouped by day for the specified time period.
+// Edits and unsends are not counted; only the messages they revise are.
 func QueryTimeline(db *sql.DB, opts TimelineOptions) ([]DayStats, error) {
 	// Query to get daily aggregations
 	query := `
@@ -46,6 +47,7 @@ func QueryTimeline(db *sql.DB, opts TimelineOptions) ([]DayStats, error) {
 			LIMIT 1
 		)
 		WHERE e.timestamp >= ? AND e.timestamp < ?
+		  AND e.supersedes IS NULL
 		GROUP BY day, sender_name, e.channel, e.direction
 		ORDER BY day DESC, count DESC
 	`
@@ -98,6 +100,7 @@ func QueryTimeline(db *sql.DB, opts TimelineOptions) ([]DayStats, error) {
 			COUNT(*) as total
 		FROM events
 		WHERE timestamp >= ? AND timestamp < ?
+		  AND supersedes IS NULL
 		GROUP BY day
 		ORDER BY day DESC
 	`
@@ -0,0 +1,313 @@
+package todos
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Owners: who owes an action item.
+const (
+	OwnerMe   = "me"
+	OwnerThem = "them"
+)
+
+// Statuses of an action item.
+const (
+	StatusOpen      = "open"
+	StatusDone      = "done"
+	StatusDismissed = "dismissed"
+)
+
+// Item is a commitment or request extracted from a conversation
+type Item struct {
+	ID              string     `json:"id"`
+	Description     string     `json:"description"`
+	Owner           string     `json:"owner"`
+	PersonID        *string    `json:"person_id,omitempty"`
+	PersonName      *string    `json:"person_name,omitempty"`
+	DueAt           *time.Time `json:"due_at,omitempty"`
+	Status          string     `json:"status"`
+	Evidence        *string    `json:"evidence,omitempty"`
+	Confidence      float64    `json:"confidence"`
+	SourceEpisodeID *string    `json:"source_episode_id,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+}
+
+// ListOptions filters List.
+type ListOptions struct {
+	Status    string    // "" = open
+	Owner     string    // "" = both
+	PersonID  string    // "" = anyone
+	DueBefore time.Time // zero = any due date (including none)
+	Limit     int       // 0 = no limit
+}
+
+// List returns action items, soonest due first, then newest.
+func List(db *sql.DB, opts ListOptions) ([]Item, error) {
+	status := opts.Status
+	if status == "" {
+		status = StatusOpen
+	}
+	query := `
+		SELECT a.id, a.description, a.owner, a.person_id, p.canonical_name, a.due_at,
+			a.status, a.evidence, a.confidence, a.source_episode_id, a.created_at, a.completed_at
+		FROM action_items a
+		LEFT JOIN persons p ON p.id = a.person_id
+		WHERE a.status = ?
+	`
+	args := []interface{}{status}
+	if opts.Owner != "" {
+		query += ` AND a.owner = ?`
+		args = append(args, opts.Owner)
+	}
+	if opts.PersonID != "" {
+		query += ` AND a.person_id = ?`
+		args = append(args, opts.PersonID)
+	}
+	if !opts.DueBefore.IsZero() {
+		query += ` AND a.due_at IS NOT NULL AND a.due_at < ?`
+		args = append(args, opts.DueBefore.Unix())
+	}
+	query += ` ORDER BY a.due_at IS NULL, a.due_at, a.created_at DESC`
+	if opts.Limit > 0 {
+		query += fmt.Sprintf(` LIMIT %d`, opts.Limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query action items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []Item
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+	return items, rows.Err()
+}
+
+// Get returns an action item by ID or unique ID prefix.
+func Get(db *sql.DB, ref string) (*Item, error) {
+	rows, err := db.Query(`
+		SELECT a.id, a.description, a.owner, a.person_id, p.canonical_name, a.due_at,
+			a.status, a.evidence, a.confidence, a.source_episode_id, a.created_at, a.completed_at
+		FROM action_items a
+		LEFT JOIN persons p ON p.id = a.person_id
+		WHERE a.id = ? OR a.id LIKE ?
+		LIMIT 2
+	`, ref, ref+"%")
+	if err != nil {
+		return nil, fmt.Errorf("query action item: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*Item
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		if item.ID == ref {
+			return item, nil
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	switch len(items) {
+	case 0:
+		return nil, fmt.Errorf("action item not found: %s", ref)
+	case 1:
+		return items[0], nil
+	default:
+		return nil, fmt.Errorf("ambiguous action item ID: %s", ref)
+	}
+}
+
+// SetStatus marks an action item open, done, or dismissed.
+func SetStatus(db *sql.DB, ref, status string) (*Item, error) {
+	switch status {
+	case StatusOpen, StatusDone, StatusDismissed:
+	default:
+		return nil, fmt.Errorf("invalid status: %s", status)
+	}
+	item, err := Get(db, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	var completedAt interface{}
+	if status == StatusDone {
+		completedAt = now
+	}
+	if _, err := db.Exec(`
+		UPDATE action_items SET status = ?, completed_at = ?, updated_at = ? WHERE id = ?
+	`, status, completedAt, now, item.ID); err != nil {
+		return nil, fmt.Errorf("update action item: %w", err)
+	}
+	return Get(db, item.ID)
+}
+
+func scanItem(rows *sql.Rows) (*Item, error) {
+	var item Item
+	var personID, personName, evidence, episodeID sql.NullString
+	var dueAt, completedAt sql.NullInt64
+	var confidence sql.NullFloat64
+	var createdAt int64
+	if err := rows.Scan(&item.ID, &item.Description, &item.Owner, &personID, &personName, &dueAt,
+		&item.Status, &evidence, &confidence, &episodeID, &createdAt, &completedAt); err != nil {
+		return nil, fmt.Errorf("scan action item: %w", err)
+	}
+	if personID.Valid {
+		item.PersonID = &personID.String
+	}
+	if personName.Valid {
+		item.PersonName = &personName.String
+	}
+	if dueAt.Valid {
+		t := time.Unix(dueAt.Int64, 0)
+		item.DueAt = &t
+	}
+	if evidence.Valid {
+		item.Evidence = &evidence.String
+	}
+	if episodeID.Valid {
+		item.SourceEpisodeID = &episodeID.String
+	}
+	if completedAt.Valid {
+		t := time.Unix(completedAt.Int64, 0)
+		item.CompletedAt = &t
+	}
+	item.Confidence = confidence.Float64
+	item.CreatedAt = time.Unix(createdAt, 0)
+	return &item, nil
+}
+
+// extractedItem is one entry of the action_items analysis output.
+type extractedItem struct {
+	Description    string  `json:"description"`
+	Owner          string  `json:"owner"` // "user" or "other"
+	OwnerRef       string  `json:"owner_ref"`
+	RequestedByRef string  `json:"requested_by_ref"`
+	Due            string  `json:"due"`
+	Evidence       string  `json:"evidence"`
+	Confidence     float64 `json:"confidence"`
+}
+
+// SyncRun stores the action items from an action_items analysis run. The
+// other party is matched by name among the episode's participants, falling
+// back to the only other participant. Safe to rerun. Returns the number of
+// new items.
+func SyncRun(db *sql.DB, runID, episodeID, output string) (int, error) {
+	var parsed struct {
+		ActionItems []extractedItem `json:"action_items"`
+	}
+	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		return 0, fmt.Errorf("parse action items: %w", err)
+	}
+
+	participants, err := loadParticipants(db, episodeID)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().Unix()
+	created := 0
+	for _, extracted := range parsed.ActionItems {
+		description := strings.TrimSpace(extracted.Description)
+		if description == "" {
+			continue
+		}
+		owner, otherRef := OwnerMe, extracted.RequestedByRef
+		if extracted.Owner == "other" {
+			owner, otherRef = OwnerThem, extracted.OwnerRef
+		}
+
+		var dueAt interface{}
+		if due, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(extracted.Due), time.Local); err == nil {
+			dueAt = due.Unix()
+		}
+		var evidence interface{}
+		if extracted.Evidence != "" {
+			evidence = extracted.Evidence
+		}
+
+		res, err := db.Exec(`
+			INSERT INTO action_items (
+				id, description, owner, person_id, due_at, status, evidence, confidence,
+				source_episode_id, analysis_run_id, created_at, updated_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(analysis_run_id, description) DO NOTHING
+		`, uuid.New().String(), description, owner, participants.match(otherRef), dueAt, StatusOpen,
+			evidence, extracted.Confidence, episodeID, runID, now, now)
+		if err != nil {
+			return created, fmt.Errorf("insert action item: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			created++
+		}
+	}
+	return created, nil
+}
+
+type participant struct {
+	id, name, displayName string
+}
+
+type participantList []participant
+
+// match returns the participant a reference names, or the only participant
+// if there is just one; nil if neither.
+func (ps participantList) match(ref string) interface{} {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if ref != "" {
+		for _, p := range ps {
+			if strings.Contains(strings.ToLower(p.name), ref) ||
+				(p.displayName != "" && strings.Contains(strings.ToLower(p.displayName), ref)) {
+				return p.id
+			}
+		}
+	}
+	if len(ps) == 1 {
+		return ps[0].id
+	}
+	return nil
+}
+
+// loadParticipants returns the persons other than me in an episode.
+func loadParticipants(db *sql.DB, episodeID string) (participantList, error) {
+	rows, err := db.Query(`
+		SELECT DISTINCT p.id, p.canonical_name, COALESCE(p.display_name, '')
+		FROM episode_events ee
+		JOIN event_participants evp ON evp.event_id = ee.event_id
+		JOIN person_contact_links pcl ON pcl.contact_id = evp.contact_id
+		JOIN persons p ON p.id = pcl.person_id
+		WHERE ee.episode_id = ? AND COALESCE(p.is_me, 0) = 0
+	`, episodeID)
+	if err != nil {
+		return nil, fmt.Errorf("query participants: %w", err)
+	}
+	defer rows.Close()
+
+	var ps participantList
+	for rows.Next() {
+		var p participant
+		if err := rows.Scan(&p.id, &p.name, &p.displayName); err != nil {
+			return nil, fmt.Errorf("scan participant: %w", err)
+		}
+		ps = append(ps, p)
+	}
+	return ps, rows.Err()
+}
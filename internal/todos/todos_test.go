@@ -0,0 +1,73 @@
+package todos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestSyncRun(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+
+	stmts := []string{
+		`INSERT INTO persons (id, canonical_name, is_me, created_at, updated_at) VALUES ('me', 'Me', 1, 0, 0), ('casey', 'Casey Jones', 0, 0, 0)`,
+		`INSERT INTO contacts (id, display_name, source, created_at, updated_at) VALUES ('c-me', 'Me', 'test', 0, 0), ('c-casey', 'Casey', 'test', 0, 0)`,
+		`INSERT INTO person_contact_links (person_id, contact_id, confidence, source_type, first_seen_at, last_seen_at) VALUES ('me', 'c-me', 1, 'test', 0, 0), ('casey', 'c-casey', 1, 'test', 0, 0)`,
+		`INSERT INTO events (id, timestamp, channel, content_types, direction, source_adapter, source_id) VALUES ('e1', 0, 'imessage', '["text"]', 'sent', 'test', 'e1')`,
+		`INSERT INTO event_participants (event_id, contact_id, role) VALUES ('e1', 'c-me', 'sender'), ('e1', 'c-casey', 'recipient')`,
+		`INSERT INTO episode_definitions (id, name, strategy, config_json, created_at, updated_at) VALUES ('def', 'test', 'thread', '{}', 0, 0)`,
+		`INSERT INTO episodes (id, definition_id, start_time, end_time, event_count, created_at) VALUES ('ep1', 'def', 0, 0, 1, 0)`,
+		`INSERT INTO episode_events (episode_id, event_id, position) VALUES ('ep1', 'e1', 1)`,
+		`INSERT INTO analysis_types (id, name, version, output_type, prompt_template, created_at, updated_at) VALUES ('ai', 'action_items', '1.0.0', 'structured', '', 0, 0)`,
+		`INSERT INTO analysis_runs (id, analysis_type_id, episode_id, status, created_at) VALUES ('run1', 'ai', 'ep1', 'completed', 0)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("setup %q: %v", stmt, err)
+		}
+	}
+
+	output := `{"action_items": [
+		{"description": "Send the contract", "owner": "user", "due": "2030-03-04", "evidence": "I'll send the contract Monday", "confidence": 0.9},
+		{"description": "Book the venue", "owner": "other", "owner_ref": "Casey", "confidence": 0.8},
+		{"description": "  ", "owner": "user"}
+	]}`
+	n, err := SyncRun(db, "run1", "ep1", output)
+	if err != nil || n != 2 {
+		t.Fatalf("SyncRun = %d, %v; want 2", n, err)
+	}
+	if n, err := SyncRun(db, "run1", "ep1", output); err != nil || n != 0 {
+		t.Errorf("rerun = %d, %v; want 0 new", n, err)
+	}
+
+	items, err := List(db, ListOptions{})
+	if err != nil || len(items) != 2 {
+		t.Fatalf("List = %+v, %v", items, err)
+	}
+	// Due items first; both attributed to the only other participant
+	contract, venue := items[0], items[1]
+	if contract.Description != "Send the contract" || contract.Owner != OwnerMe || contract.DueAt == nil || contract.DueAt.Format("2006-01-02") != "2030-03-04" {
+		t.Errorf("contract = %+v", contract)
+	}
+	if venue.Owner != OwnerThem || venue.PersonID == nil || *venue.PersonID != "casey" || venue.DueAt != nil {
+		t.Errorf("venue = %+v", venue)
+	}
+
+	mine, _ := List(db, ListOptions{Owner: OwnerMe, DueBefore: time.Date(2030, 12, 31, 0, 0, 0, 0, time.Local)})
+	if len(mine) != 1 || mine[0].ID != contract.ID {
+		t.Errorf("mine due before 2031 = %+v", mine)
+	}
+
+	done, err := SetStatus(db, contract.ID[:8], StatusDone)
+	if err != nil || done.Status != StatusDone || done.CompletedAt == nil {
+		t.Fatalf("SetStatus = %+v, %v", done, err)
+	}
+	if open, _ := List(db, ListOptions{}); len(open) != 1 {
+		t.Errorf("open after done = %d, want 1", len(open))
+	}
+	if _, err := SetStatus(db, contract.ID, "later"); err == nil {
+		t.Error("invalid status should fail")
+	}
+}
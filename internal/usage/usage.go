@@ -0,0 +1,408 @@
+// Package usage persists API token and cost usage per provider, API key,
+// and day, so usage adds up across processes and can be checked against the
+// quotas in config (usage.quotas).
+//
+// API clients report each call to a Recorder, which buffers totals in memory
+// and flushes them from its own goroutine: a client may be called while its
+// caller holds the database's only connection (an open cursor or
+// transaction), so recording must never wait on the database.
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/config"
+	"github.com/Napageneral/mnemonic/internal/gemini"
+)
+
+// ProviderGemini is the provider name of Gemini API usage.
+const ProviderGemini = "gemini"
+
+// DefaultWarnAt is the fraction of a quota that triggers a warning when
+// config leaves usage.warn_at unset.
+const DefaultWarnAt = 0.8
+
+// FlushInterval is how often a started Recorder writes buffered usage.
+const FlushInterval = 30 * time.Second
+
+// Totals is accumulated usage.
+type Totals struct {
+	PromptTokens int64   `json:"prompt_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	EmbedChars   int64   `json:"embed_chars"`
+	Calls        int64   `json:"calls"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// Tokens is prompt plus output tokens, what token quotas count.
+func (t Totals) Tokens() int64 {
+	return t.PromptTokens + t.OutputTokens
+}
+
+func (t *Totals) add(o Totals) {
+	t.PromptTokens += o.PromptTokens
+	t.OutputTokens += o.OutputTokens
+	t.EmbedChars += o.EmbedChars
+	t.Calls += o.Calls
+	t.CostUSD += o.CostUSD
+}
+
+type bucket struct {
+	provider, keyID, day string
+}
+
+// Recorder buffers usage and writes it to the api_usage table.
+type Recorder struct {
+	db  *sql.DB
+	cfg config.UsageConfig
+	now func() time.Time
+
+	mu      sync.Mutex
+	pending map[bucket]*Totals
+	warned  map[string]bool // quota warnings already logged, by provider/period/metric/period start
+
+	stopOnce sync.Once
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+// NewRecorder creates a Recorder checking usage against cfg's quotas.
+func NewRecorder(db *sql.DB, cfg config.UsageConfig) *Recorder {
+	return &Recorder{
+		db:      db,
+		cfg:     cfg,
+		now:     time.Now,
+		pending: make(map[bucket]*Totals),
+		warned:  make(map[string]bool),
+	}
+}
+
+// Record adds usage for provider and keyID to today's totals.
+func (r *Recorder) Record(provider, keyID string, t Totals) {
+	b := bucket{provider: provider, keyID: keyID, day: r.now().Format("2006-01-02")}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	total := r.pending[b]
+	if total == nil {
+		total = &Totals{}
+		r.pending[b] = total
+	}
+	total.add(t)
+}
+
+// Track records every call client makes.
+func (r *Recorder) Track(client *gemini.Client) {
+	keyID := client.KeyID()
+	client.SetUsageSink(func(u gemini.UsageRecord) {
+		r.Record(ProviderGemini, keyID, Totals{
+			PromptTokens: u.PromptTokens,
+			OutputTokens: u.OutputTokens,
+			EmbedChars:   u.EmbedChars,
+			Calls:        1,
+			CostUSD:      gemini.EstimateCost(u.PromptTokens, u.OutputTokens, u.EmbedChars),
+		})
+	})
+}
+
+// Flush writes buffered usage, then logs a warning for each quota that
+// usage has newly approached or passed.
+func (r *Recorder) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = make(map[bucket]*Totals)
+	r.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	now := r.now().Unix()
+	for b, t := range pending {
+		_, err := r.db.ExecContext(ctx, `
+			INSERT INTO api_usage (provider, key_id, day, prompt_tokens, output_tokens, embed_chars, calls, cost_usd, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(provider, key_id, day) DO UPDATE SET
+				prompt_tokens = prompt_tokens + excluded.prompt_tokens,
+				output_tokens = output_tokens + excluded.output_tokens,
+				embed_chars = embed_chars + excluded.embed_chars,
+				calls = calls + excluded.calls,
+				cost_usd = cost_usd + excluded.cost_usd,
+				updated_at = excluded.updated_at
+		`, b.provider, b.keyID, b.day, t.PromptTokens, t.OutputTokens, t.EmbedChars, t.Calls, t.CostUSD, now)
+		if err != nil {
+			// Keep the rest for the next flush
+			for b, t := range pending {
+				r.Record(b.provider, b.keyID, *t)
+			}
+			return fmt.Errorf("record usage: %w", err)
+		}
+		delete(pending, b)
+	}
+
+	statuses, err := CheckQuotas(ctx, r.db, r.cfg, r.now())
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		if !s.Warning {
+			continue
+		}
+		key := fmt.Sprintf("%s/%s/%s/%s/%t", s.Provider, s.Period, s.Metric, s.Since, s.Exceeded)
+		r.mu.Lock()
+		seen := r.warned[key]
+		r.warned[key] = true
+		r.mu.Unlock()
+		if !seen {
+			log.Printf("Warning: %s", s.Message())
+		}
+	}
+	return nil
+}
+
+// Start flushes every FlushInterval until Stop.
+func (r *Recorder) Start(ctx context.Context) {
+	r.done = make(chan struct{})
+	r.stopped = make(chan struct{})
+	go func() {
+		defer close(r.stopped)
+		ticker := time.NewTicker(FlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.done:
+				return
+			case <-ticker.C:
+				if err := r.Flush(ctx); err != nil {
+					log.Printf("usage: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop ends the flush loop, if started, and writes what is left.
+func (r *Recorder) Stop() {
+	r.stopOnce.Do(func() {
+		if r.done != nil {
+			close(r.done)
+			<-r.stopped
+		}
+		if err := r.Flush(context.Background()); err != nil {
+			log.Printf("usage: %v", err)
+		}
+	})
+}
+
+// Attach tracks client's usage in db against the configured quotas, and
+// returns a func that stops tracking and flushes. Callers defer it before
+// closing db.
+func Attach(db *sql.DB, client *gemini.Client) func() {
+	var cfg config.UsageConfig
+	if c, err := config.Load(); err == nil {
+		cfg = c.Usage
+	}
+	r := NewRecorder(db, cfg)
+	r.Track(client)
+	r.Start(context.Background())
+	return r.Stop
+}
+
+// KeyUsage is one API key's usage today and this month.
+type KeyUsage struct {
+	Provider string `json:"provider"`
+	KeyID    string `json:"key_id"`
+	Today    Totals `json:"today"`
+	Month    Totals `json:"month"`
+}
+
+// DayUsage is one provider's usage on one day.
+type DayUsage struct {
+	Day      string `json:"day"`
+	Provider string `json:"provider"`
+	Totals
+}
+
+// QuotaStatus compares a provider's usage in a period with one quota.
+type QuotaStatus struct {
+	Provider string  `json:"provider"`
+	Period   string  `json:"period"` // "day" or "month"
+	Since    string  `json:"since"`  // first day of the period
+	Metric   string  `json:"metric"` // "tokens" or "usd"
+	Used     float64 `json:"used"`
+	Limit    float64 `json:"limit"`
+	Fraction float64 `json:"fraction"`
+	Warning  bool    `json:"warning"` // at or past the warn_at fraction
+	Exceeded bool    `json:"exceeded"`
+}
+
+// Message describes the status for a warning.
+func (s QuotaStatus) Message() string {
+	quota := "daily"
+	if s.Period == "month" {
+		quota = "monthly"
+	}
+	used, limit := fmt.Sprintf("%.0f", s.Used), fmt.Sprintf("%.0f", s.Limit)
+	if s.Metric == "usd" {
+		quota += " spend"
+		used, limit = fmt.Sprintf("$%.2f", s.Used), fmt.Sprintf("$%.2f", s.Limit)
+	} else {
+		quota += " token"
+	}
+	if s.Exceeded {
+		return fmt.Sprintf("%s usage has exceeded its %s quota (%s of %s)", s.Provider, quota, used, limit)
+	}
+	return fmt.Sprintf("%s usage is at %.0f%% of its %s quota (%s of %s)", s.Provider, s.Fraction*100, quota, used, limit)
+}
+
+// Report is usage by key, recent daily usage, and quota statuses.
+type Report struct {
+	Keys   []KeyUsage    `json:"keys"`
+	Daily  []DayUsage    `json:"daily,omitempty"`
+	Quotas []QuotaStatus `json:"quotas,omitempty"`
+}
+
+// periodStarts returns the first day of now's day and month.
+func periodStarts(now time.Time) (day, month string) {
+	return now.Format("2006-01-02"), now.Format("2006-01") + "-01"
+}
+
+// GetReport summarizes usage as of now, with a daily breakdown of the last
+// days days (0 = none).
+func GetReport(ctx context.Context, db *sql.DB, cfg config.UsageConfig, now time.Time, days int) (*Report, error) {
+	today, monthStart := periodStarts(now)
+	rows, err := db.QueryContext(ctx, `
+		SELECT provider, key_id, day, prompt_tokens, output_tokens, embed_chars, calls, cost_usd
+		FROM api_usage
+		WHERE day >= ?
+		ORDER BY provider, key_id
+	`, monthStart)
+	if err != nil {
+		return nil, fmt.Errorf("query usage: %w", err)
+	}
+	defer rows.Close()
+
+	report := &Report{Keys: []KeyUsage{}}
+	byKey := make(map[string]int) // index into report.Keys
+	for rows.Next() {
+		var provider, keyID, day string
+		var t Totals
+		if err := rows.Scan(&provider, &keyID, &day, &t.PromptTokens, &t.OutputTokens, &t.EmbedChars, &t.Calls, &t.CostUSD); err != nil {
+			return nil, fmt.Errorf("scan usage: %w", err)
+		}
+		i, ok := byKey[provider+"/"+keyID]
+		if !ok {
+			i = len(report.Keys)
+			report.Keys = append(report.Keys, KeyUsage{Provider: provider, KeyID: keyID})
+			byKey[provider+"/"+keyID] = i
+		}
+		k := &report.Keys[i]
+		k.Month.add(t)
+		if day == today {
+			k.Today.add(t)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if days > 0 {
+		since := now.AddDate(0, 0, -(days - 1)).Format("2006-01-02")
+		dayRows, err := db.QueryContext(ctx, `
+			SELECT day, provider, SUM(prompt_tokens), SUM(output_tokens), SUM(embed_chars), SUM(calls), SUM(cost_usd)
+			FROM api_usage
+			WHERE day >= ?
+			GROUP BY day, provider
+			ORDER BY day DESC, provider
+		`, since)
+		if err != nil {
+			return nil, fmt.Errorf("query daily usage: %w", err)
+		}
+		defer dayRows.Close()
+		for dayRows.Next() {
+			var d DayUsage
+			if err := dayRows.Scan(&d.Day, &d.Provider, &d.PromptTokens, &d.OutputTokens, &d.EmbedChars, &d.Calls, &d.CostUSD); err != nil {
+				return nil, fmt.Errorf("scan daily usage: %w", err)
+			}
+			report.Daily = append(report.Daily, d)
+		}
+		if err := dayRows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	report.Quotas = quotaStatuses(report.Keys, cfg, now)
+	return report, nil
+}
+
+// CheckQuotas returns the status of every configured quota as of now.
+func CheckQuotas(ctx context.Context, db *sql.DB, cfg config.UsageConfig, now time.Time) ([]QuotaStatus, error) {
+	if len(cfg.Quotas) == 0 {
+		return nil, nil
+	}
+	report, err := GetReport(ctx, db, cfg, now, 0)
+	if err != nil {
+		return nil, err
+	}
+	return report.Quotas, nil
+}
+
+// quotaStatuses compares per-provider totals (all keys) with cfg's quotas.
+func quotaStatuses(keys []KeyUsage, cfg config.UsageConfig, now time.Time) []QuotaStatus {
+	warnAt := cfg.WarnAt
+	if warnAt <= 0 {
+		warnAt = DefaultWarnAt
+	}
+	today, monthStart := periodStarts(now)
+
+	providers := make([]string, 0, len(cfg.Quotas))
+	for p := range cfg.Quotas {
+		providers = append(providers, p)
+	}
+	sort.Strings(providers)
+
+	var statuses []QuotaStatus
+	for _, provider := range providers {
+		var day, month Totals
+		for _, k := range keys {
+			if k.Provider == provider {
+				day.add(k.Today)
+				month.add(k.Month)
+			}
+		}
+		q := cfg.Quotas[provider]
+		for _, c := range []struct {
+			period, since, metric string
+			used, limit           float64
+		}{
+			{"day", today, "tokens", float64(day.Tokens()), float64(q.DailyTokens)},
+			{"day", today, "usd", day.CostUSD, q.DailyUSD},
+			{"month", monthStart, "tokens", float64(month.Tokens()), float64(q.MonthlyTokens)},
+			{"month", monthStart, "usd", month.CostUSD, q.MonthlyUSD},
+		} {
+			if c.limit <= 0 {
+				continue
+			}
+			fraction := c.used / c.limit
+			statuses = append(statuses, QuotaStatus{
+				Provider: provider,
+				Period:   c.period,
+				Since:    c.since,
+				Metric:   c.metric,
+				Used:     c.used,
+				Limit:    c.limit,
+				Fraction: fraction,
+				Warning:  fraction >= warnAt,
+				Exceeded: fraction >= 1,
+			})
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,84 @@
+package usage
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/config"
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestRecorderQuotas(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	flags := log.Flags()
+	log.SetFlags(0)
+	defer log.SetFlags(flags)
+
+	cfg := config.UsageConfig{Quotas: map[string]config.QuotaConfig{
+		ProviderGemini: {DailyTokens: 1000, MonthlyUSD: 10},
+	}}
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.Local)
+	r := NewRecorder(db, cfg)
+
+	// Earlier this month, on another key, and last month
+	r.now = func() time.Time { return now.AddDate(0, 0, -3) }
+	r.Record(ProviderGemini, "k2", Totals{PromptTokens: 5000, Calls: 1, CostUSD: 2})
+	r.now = func() time.Time { return now.AddDate(0, -1, 0) }
+	r.Record(ProviderGemini, "k1", Totals{PromptTokens: 9000, Calls: 1, CostUSD: 50})
+	r.now = func() time.Time { return now }
+	r.Record(ProviderGemini, "k1", Totals{PromptTokens: 500, OutputTokens: 100, Calls: 1, CostUSD: 1})
+	r.Record(ProviderGemini, "k1", Totals{PromptTokens: 150, OutputTokens: 50, Calls: 1, CostUSD: 1})
+	if err := r.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if !strings.Contains(logs.String(), "is at 80% of its daily token quota (800 of 1000)") {
+		t.Errorf("no 80%% warning logged:\n%s", logs.String())
+	}
+
+	// The same warning is not repeated; exceeding is
+	logs.Reset()
+	r.Record(ProviderGemini, "k1", Totals{PromptTokens: 10, Calls: 1})
+	r.Flush(ctx)
+	if logs.Len() != 0 {
+		t.Errorf("warning repeated:\n%s", logs.String())
+	}
+	r.Record(ProviderGemini, "k1", Totals{PromptTokens: 300, Calls: 1})
+	r.Flush(ctx)
+	if !strings.Contains(logs.String(), "has exceeded its daily token quota (1110 of 1000)") {
+		t.Errorf("no exceeded warning:\n%s", logs.String())
+	}
+
+	report, err := GetReport(ctx, db, cfg, now, 7)
+	if err != nil {
+		t.Fatalf("GetReport: %v", err)
+	}
+	if len(report.Keys) != 2 {
+		t.Fatalf("keys = %+v", report.Keys)
+	}
+	k1, k2 := report.Keys[0], report.Keys[1]
+	if k1.KeyID != "k1" || k1.Today.Tokens() != 1110 || k1.Today.Calls != 4 || k1.Month.CostUSD != 2 ||
+		k2.Today.Calls != 0 || k2.Month.PromptTokens != 5000 {
+		t.Errorf("keys = %+v", report.Keys)
+	}
+	if len(report.Daily) != 2 || report.Daily[0].Day != "2026-03-15" || report.Daily[1].Day != "2026-03-12" {
+		t.Errorf("daily = %+v", report.Daily)
+	}
+	if len(report.Quotas) != 2 {
+		t.Fatalf("quotas = %+v", report.Quotas)
+	}
+	tokens, spend := report.Quotas[0], report.Quotas[1]
+	if tokens.Period != "day" || !tokens.Exceeded || spend.Period != "month" || spend.Used != 4 || spend.Warning {
+		t.Errorf("quotas = %+v", report.Quotas)
+	}
+}
@@ -0,0 +1,335 @@
+package vectorindex
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+)
+
+// HNSW defaults, following the parameters recommended in the HNSW paper for
+// high-recall text embeddings.
+const (
+	DefaultM              = 16
+	DefaultEfConstruction = 200
+	DefaultEfSearch       = 64
+)
+
+// HNSWConfig tunes graph construction and search.
+type HNSWConfig struct {
+	M              int // Max neighbors per node per layer (2*M on layer 0)
+	EfConstruction int // Candidate list size while inserting
+	EfSearch       int // Candidate list size while searching (raised to k if smaller)
+}
+
+func (c HNSWConfig) withDefaults() HNSWConfig {
+	if c.M <= 1 {
+		c.M = DefaultM
+	}
+	if c.EfConstruction <= 0 {
+		c.EfConstruction = DefaultEfConstruction
+	}
+	if c.EfSearch <= 0 {
+		c.EfSearch = DefaultEfSearch
+	}
+	return c
+}
+
+type hnswNode struct {
+	id      string
+	vec     []float32
+	links   [][]int32 // links[l] are neighbors on layer l
+	deleted bool
+}
+
+// HNSW is an approximate nearest-neighbor index (Hierarchical Navigable Small
+// World graph). Removed vectors are tombstoned: they still route searches but
+// are never returned. Store rebuilds the graph once tombstones dominate.
+type HNSW struct {
+	cfg      HNSWConfig
+	nodes    []hnswNode
+	ids      map[string]int32
+	entry    int32
+	maxLevel int
+	dim      int
+	deleted  int
+
+	levelMult float64
+	rng       *rand.Rand
+	visited   []uint32
+	visitGen  uint32
+}
+
+// NewHNSW creates an empty HNSW index.
+func NewHNSW(cfg HNSWConfig) *HNSW {
+	cfg = cfg.withDefaults()
+	return &HNSW{
+		cfg:       cfg,
+		ids:       make(map[string]int32),
+		entry:     -1,
+		levelMult: 1 / math.Log(float64(cfg.M)),
+		rng:       rand.New(rand.NewSource(1)),
+	}
+}
+
+// Add inserts or replaces the vector for id.
+func (h *HNSW) Add(id string, vec []float64) {
+	if h.dim == 0 {
+		h.dim = len(vec)
+	}
+	if len(vec) != h.dim || h.dim == 0 {
+		return
+	}
+	h.Remove(id)
+
+	q := normalize(vec)
+	level := h.randomLevel()
+	n := int32(len(h.nodes))
+	h.nodes = append(h.nodes, hnswNode{id: id, vec: q, links: make([][]int32, level+1)})
+	h.ids[id] = n
+
+	if h.entry < 0 {
+		h.entry = n
+		h.maxLevel = level
+		return
+	}
+
+	ep := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.greedy(q, ep, l)
+	}
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(q, ep, h.cfg.EfConstruction, l)
+		neighbors := make([]int32, 0, h.cfg.M)
+		for _, c := range candidates {
+			if len(neighbors) == h.cfg.M {
+				break
+			}
+			neighbors = append(neighbors, c.node)
+		}
+		h.nodes[n].links[l] = neighbors
+		for _, nb := range neighbors {
+			h.link(nb, n, l)
+		}
+		ep = candidates[0].node
+	}
+	if level > h.maxLevel {
+		h.maxLevel = level
+		h.entry = n
+	}
+}
+
+// Remove tombstones id.
+func (h *HNSW) Remove(id string) {
+	n, ok := h.ids[id]
+	if !ok {
+		return
+	}
+	delete(h.ids, id)
+	h.nodes[n].deleted = true
+	h.deleted++
+}
+
+// Search returns up to k approximate nearest neighbors.
+func (h *HNSW) Search(query []float64, k int) []Match {
+	if k <= 0 || h.entry < 0 || len(query) != h.dim {
+		return nil
+	}
+	q := normalize(query)
+	ep := h.entry
+	for l := h.maxLevel; l > 0; l-- {
+		ep = h.greedy(q, ep, l)
+	}
+	// Tombstones occupy candidate slots, so widen the search to compensate
+	ef := max(h.cfg.EfSearch, k) + min(h.deleted, k)
+	candidates := h.searchLayer(q, ep, ef, 0)
+
+	matches := make([]Match, 0, k)
+	for _, c := range candidates {
+		node := &h.nodes[c.node]
+		if node.deleted {
+			continue
+		}
+		matches = append(matches, Match{ID: node.id, Score: float64(1 - c.dist)})
+		if len(matches) == k {
+			break
+		}
+	}
+	sortMatches(matches)
+	return matches
+}
+
+// Len returns the number of live vectors.
+func (h *HNSW) Len() int { return len(h.ids) }
+
+// Dimension returns the vector dimension.
+func (h *HNSW) Dimension() int { return h.dim }
+
+// Deleted returns the number of tombstoned nodes.
+func (h *HNSW) Deleted() int { return h.deleted }
+
+func (h *HNSW) randomLevel() int {
+	return int(-math.Log(1-h.rng.Float64()) * h.levelMult)
+}
+
+func (h *HNSW) distance(q []float32, n int32) float32 {
+	return 1 - dot(q, h.nodes[n].vec)
+}
+
+// greedy walks layer l towards q, returning the closest node found.
+func (h *HNSW) greedy(q []float32, ep int32, l int) int32 {
+	best := h.distance(q, ep)
+	for changed := true; changed; {
+		changed = false
+		for _, nb := range h.nodes[ep].links[l] {
+			if d := h.distance(q, nb); d < best {
+				best, ep, changed = d, nb, true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer returns up to ef nodes on layer l closest to q, nearest first.
+func (h *HNSW) searchLayer(q []float32, ep int32, ef int, l int) []candidate {
+	h.resetVisited()
+	h.visited[ep] = h.visitGen
+
+	d := h.distance(q, ep)
+	frontier := &candidateHeap{items: []candidate{{ep, d}}}
+	results := &candidateHeap{max: true, items: []candidate{{ep, d}}}
+	for frontier.Len() > 0 {
+		c := heap.Pop(frontier).(candidate)
+		if c.dist > results.items[0].dist && results.Len() >= ef {
+			break
+		}
+		for _, nb := range h.nodes[c.node].links[l] {
+			if h.visited[nb] == h.visitGen {
+				continue
+			}
+			h.visited[nb] = h.visitGen
+			nd := h.distance(q, nb)
+			if results.Len() < ef || nd < results.items[0].dist {
+				heap.Push(frontier, candidate{nb, nd})
+				heap.Push(results, candidate{nb, nd})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	out := make([]candidate, results.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(results).(candidate)
+	}
+	return out
+}
+
+// link adds a directed edge from -> to on layer l, pruning from's neighbor
+// list to its closest entries when it overflows.
+func (h *HNSW) link(from, to int32, l int) {
+	maxConn := h.cfg.M
+	if l == 0 {
+		maxConn = 2 * h.cfg.M
+	}
+	links := append(h.nodes[from].links[l], to)
+	if len(links) > maxConn {
+		vec := h.nodes[from].vec
+		ranked := &candidateHeap{max: true}
+		for _, nb := range links {
+			heap.Push(ranked, candidate{nb, h.distance(vec, nb)})
+			if ranked.Len() > maxConn {
+				heap.Pop(ranked)
+			}
+		}
+		links = links[:0]
+		for _, c := range ranked.items {
+			links = append(links, c.node)
+		}
+	}
+	h.nodes[from].links[l] = links
+}
+
+func (h *HNSW) resetVisited() {
+	if len(h.visited) < len(h.nodes) {
+		h.visited = make([]uint32, len(h.nodes)+len(h.nodes)/2)
+		h.visitGen = 0
+	}
+	h.visitGen++
+	if h.visitGen == 0 {
+		clear(h.visited)
+		h.visitGen = 1
+	}
+}
+
+type candidate struct {
+	node int32
+	dist float32
+}
+
+// candidateHeap is a min-heap by distance, or a max-heap when max is set.
+type candidateHeap struct {
+	items []candidate
+	max   bool
+}
+
+func (c *candidateHeap) Len() int { return len(c.items) }
+func (c *candidateHeap) Less(i, j int) bool {
+	if c.max {
+		return c.items[i].dist > c.items[j].dist
+	}
+	return c.items[i].dist < c.items[j].dist
+}
+func (c *candidateHeap) Swap(i, j int) { c.items[i], c.items[j] = c.items[j], c.items[i] }
+func (c *candidateHeap) Push(x any)    { c.items = append(c.items, x.(candidate)) }
+func (c *candidateHeap) Pop() any {
+	last := c.items[len(c.items)-1]
+	c.items = c.items[:len(c.items)-1]
+	return last
+}
+
+// hnswSnapshot is the gob-encoded form of an HNSW graph.
+type hnswSnapshot struct {
+	Config   HNSWConfig
+	Dim      int
+	Entry    int32
+	MaxLevel int
+	IDs      []string
+	Vectors  [][]float32
+	Links    [][][]int32
+	Deleted  []bool
+}
+
+func (h *HNSW) snapshot() hnswSnapshot {
+	s := hnswSnapshot{
+		Config:   h.cfg,
+		Dim:      h.dim,
+		Entry:    h.entry,
+		MaxLevel: h.maxLevel,
+		IDs:      make([]string, len(h.nodes)),
+		Vectors:  make([][]float32, len(h.nodes)),
+		Links:    make([][][]int32, len(h.nodes)),
+		Deleted:  make([]bool, len(h.nodes)),
+	}
+	for i, n := range h.nodes {
+		s.IDs[i], s.Vectors[i], s.Links[i], s.Deleted[i] = n.id, n.vec, n.links, n.deleted
+	}
+	return s
+}
+
+func hnswFromSnapshot(s hnswSnapshot) *HNSW {
+	h := NewHNSW(s.Config)
+	h.dim, h.entry, h.maxLevel = s.Dim, s.Entry, s.MaxLevel
+	h.nodes = make([]hnswNode, len(s.IDs))
+	for i := range s.IDs {
+		h.nodes[i] = hnswNode{id: s.IDs[i], vec: s.Vectors[i], links: s.Links[i], deleted: s.Deleted[i]}
+		if s.Deleted[i] {
+			h.deleted++
+		} else {
+			h.ids[s.IDs[i]] = int32(i)
+		}
+	}
+	// Keep level draws from repeating the sequence used to build the graph
+	h.rng = rand.New(rand.NewSource(int64(len(h.nodes)) + 1))
+	return h
+}
@@ -0,0 +1,116 @@
+// Package vectorindex provides nearest-neighbor search over stored embeddings.
+//
+// Small collections are searched exactly (Flat); large ones use an HNSW graph
+// persisted to the data directory and updated incrementally as embeddings are
+// written. Store picks the backend, so callers only see Search.
+package vectorindex
+
+import (
+	"math"
+	"sort"
+)
+
+// Match is a search hit. Score is the cosine similarity (-1 to 1).
+type Match struct {
+	ID    string
+	Score float64
+}
+
+// Index is a nearest-neighbor index over unit-normalized vectors.
+type Index interface {
+	// Add inserts or replaces the vector for id.
+	Add(id string, vec []float64)
+	// Remove deletes id from the index (no-op if absent).
+	Remove(id string)
+	// Search returns up to k matches ordered by descending score.
+	Search(query []float64, k int) []Match
+	// Len returns the number of live vectors.
+	Len() int
+	// Dimension returns the vector dimension, or 0 if empty.
+	Dimension() int
+}
+
+// Flat is an exact brute-force index.
+type Flat struct {
+	vectors map[string][]float32
+	dim     int
+}
+
+// NewFlat creates an empty brute-force index.
+func NewFlat() *Flat {
+	return &Flat{vectors: make(map[string][]float32)}
+}
+
+// Add inserts or replaces the vector for id.
+func (f *Flat) Add(id string, vec []float64) {
+	if f.dim == 0 {
+		f.dim = len(vec)
+	}
+	if len(vec) != f.dim {
+		return
+	}
+	f.vectors[id] = normalize(vec)
+}
+
+// Remove deletes id from the index.
+func (f *Flat) Remove(id string) {
+	delete(f.vectors, id)
+}
+
+// Search scores every vector against the query.
+func (f *Flat) Search(query []float64, k int) []Match {
+	if k <= 0 || len(query) != f.dim {
+		return nil
+	}
+	q := normalize(query)
+	matches := make([]Match, 0, len(f.vectors))
+	for id, vec := range f.vectors {
+		matches = append(matches, Match{ID: id, Score: float64(dot(q, vec))})
+	}
+	sortMatches(matches)
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+// Len returns the number of vectors.
+func (f *Flat) Len() int { return len(f.vectors) }
+
+// Dimension returns the vector dimension.
+func (f *Flat) Dimension() int { return f.dim }
+
+// normalize converts to float32 with unit length so cosine is a dot product.
+func normalize(vec []float64) []float32 {
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	out := make([]float32, len(vec))
+	if norm == 0 {
+		return out
+	}
+	norm = math.Sqrt(norm)
+	for i, v := range vec {
+		out[i] = float32(v / norm)
+	}
+	return out
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// sortMatches orders by descending score, breaking ties by ID for stable output.
+func sortMatches(matches []Match) {
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+}
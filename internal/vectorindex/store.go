@@ -0,0 +1,325 @@
+package vectorindex
+
+import (
+	"context"
+	"database/sql"
+	"encoding/gob"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Backend names reported by Store.Backend.
+const (
+	BackendFlat = "flat"
+	BackendHNSW = "hnsw"
+)
+
+// DefaultHNSWThreshold is the collection size at which Store switches from
+// exact search to HNSW. Below it, brute force is fast and exact.
+const DefaultHNSWThreshold = 10000
+
+// storeFormat is bumped whenever the persisted layout changes; files in an
+// older format are ignored and rebuilt.
+const storeFormat = 1
+
+// syncBatchSize bounds the IN (...) list used to load changed vectors.
+const syncBatchSize = 500
+
+// Options configures a Store.
+type Options struct {
+	TargetType    string     // embeddings.target_type to index ("episode", "entity", ...)
+	Model         string     // embeddings.model to index
+	Dir           string     // Directory for the persisted index ("" = in-memory only)
+	HNSWThreshold int        // Vector count that switches to HNSW (default: DefaultHNSWThreshold)
+	HNSW          HNSWConfig // HNSW tuning (zero values use defaults)
+}
+
+// SyncStats reports what a Sync changed.
+type SyncStats struct {
+	Added   int  `json:"added"`
+	Removed int  `json:"removed"`
+	Rebuilt bool `json:"rebuilt"`
+}
+
+// Store keeps an Index in step with the embeddings table for one
+// target_type/model. Each Sync reads only IDs and source hashes, loading
+// vectors just for embeddings that are new or changed since the last sync
+// (or since the persisted index was written).
+type Store struct {
+	db       *sql.DB
+	opts     Options
+	index    Index
+	versions map[string]string // target_id -> source hash at index time
+}
+
+// storeFile is the gob-encoded persisted form of an HNSW-backed Store.
+type storeFile struct {
+	Format     int
+	TargetType string
+	Model      string
+	Versions   map[string]string
+	Graph      hnswSnapshot
+}
+
+// NewStore creates a Store, loading a persisted index from opts.Dir when one
+// exists. A missing or unreadable file just means the first Sync rebuilds.
+func NewStore(db *sql.DB, opts Options) *Store {
+	if opts.HNSWThreshold <= 0 {
+		opts.HNSWThreshold = DefaultHNSWThreshold
+	}
+	s := &Store{db: db, opts: opts, index: NewFlat(), versions: make(map[string]string)}
+	if err := s.load(); err != nil {
+		// Non-fatal - fall back to rebuilding from the embeddings table
+		_ = err
+	}
+	return s
+}
+
+// Backend returns the active backend name.
+func (s *Store) Backend() string {
+	if _, ok := s.index.(*HNSW); ok {
+		return BackendHNSW
+	}
+	return BackendFlat
+}
+
+// Len returns the number of indexed vectors.
+func (s *Store) Len() int {
+	return s.index.Len()
+}
+
+// Path returns the persisted index file, or "" when in-memory only.
+func (s *Store) Path() string {
+	if s.opts.Dir == "" {
+		return ""
+	}
+	return filepath.Join(s.opts.Dir, indexFileName(s.opts.TargetType, s.opts.Model))
+}
+
+// Search returns up to k nearest vectors to the query. Call Sync first to
+// pick up embeddings written since the last search.
+func (s *Store) Search(query []float64, k int) []Match {
+	return s.index.Search(query, k)
+}
+
+// Sync applies embedding inserts, updates, and deletes to the index,
+// switching to HNSW once the collection reaches the threshold and rebuilding
+// when tombstones outnumber live vectors. An HNSW index is re-persisted
+// whenever it changes.
+func (s *Store) Sync(ctx context.Context) (SyncStats, error) {
+	var stats SyncStats
+
+	current, err := s.currentVersions(ctx)
+	if err != nil {
+		return stats, err
+	}
+
+	switch idx := s.index.(type) {
+	case *Flat:
+		if len(current) >= s.opts.HNSWThreshold {
+			s.reset(NewHNSW(s.opts.HNSW))
+			stats.Rebuilt = true
+		}
+	case *HNSW:
+		if idx.Deleted() > idx.Len() {
+			s.reset(NewHNSW(s.opts.HNSW))
+			stats.Rebuilt = true
+		}
+	}
+
+	for id := range s.versions {
+		if _, ok := current[id]; !ok {
+			s.index.Remove(id)
+			delete(s.versions, id)
+			stats.Removed++
+		}
+	}
+
+	var changed []string
+	for id, version := range current {
+		if existing, ok := s.versions[id]; !ok || existing != version {
+			changed = append(changed, id)
+		}
+	}
+	for start := 0; start < len(changed); start += syncBatchSize {
+		end := min(start+syncBatchSize, len(changed))
+		added, err := s.loadVectors(ctx, changed[start:end], current)
+		stats.Added += added
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	if s.Backend() == BackendHNSW && (stats.Added > 0 || stats.Removed > 0) {
+		if err := s.Save(); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// Save persists an HNSW index to opts.Dir. Flat indexes and in-memory stores
+// are not persisted.
+func (s *Store) Save() error {
+	h, ok := s.index.(*HNSW)
+	path := s.Path()
+	if !ok || path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(s.opts.Dir, 0o755); err != nil {
+		return fmt.Errorf("create index dir: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.opts.Dir, ".index-*")
+	if err != nil {
+		return fmt.Errorf("create index file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	file := storeFile{
+		Format:     storeFormat,
+		TargetType: s.opts.TargetType,
+		Model:      s.opts.Model,
+		Versions:   s.versions,
+		Graph:      h.snapshot(),
+	}
+	if err := gob.NewEncoder(tmp).Encode(file); err != nil {
+		tmp.Close()
+		return fmt.Errorf("encode index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace index: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) load() error {
+	path := s.Path()
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	var file storeFile
+	if err := gob.NewDecoder(f).Decode(&file); err != nil {
+		return fmt.Errorf("decode index: %w", err)
+	}
+	if file.Format != storeFormat || file.TargetType != s.opts.TargetType || file.Model != s.opts.Model {
+		return fmt.Errorf("index %s is stale", path)
+	}
+	s.index = hnswFromSnapshot(file.Graph)
+	s.versions = file.Versions
+	if s.versions == nil {
+		s.versions = make(map[string]string)
+	}
+	return nil
+}
+
+func (s *Store) reset(index Index) {
+	s.index = index
+	s.versions = make(map[string]string)
+}
+
+// currentVersions returns target_id -> version for every embedding of the
+// store's target type and model. The version combines the source text hash
+// and dimension so re-embeddings are picked up.
+func (s *Store) currentVersions(ctx context.Context) (map[string]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT target_id, COALESCE(source_text_hash, ''), dimension
+		FROM embeddings
+		WHERE target_type = ? AND model = ?
+	`, s.opts.TargetType, s.opts.Model)
+	if err != nil {
+		return nil, fmt.Errorf("query embedding versions: %w", err)
+	}
+	defer rows.Close()
+
+	current := make(map[string]string)
+	for rows.Next() {
+		var id, hash string
+		var dim int
+		if err := rows.Scan(&id, &hash, &dim); err != nil {
+			return nil, fmt.Errorf("scan embedding version: %w", err)
+		}
+		current[id] = hash + ":" + strconv.Itoa(dim)
+	}
+	return current, rows.Err()
+}
+
+// loadVectors adds the given embeddings to the index.
+func (s *Store) loadVectors(ctx context.Context, ids []string, versions map[string]string) (int, error) {
+	placeholders := make([]string, len(ids))
+	args := []any{s.opts.TargetType, s.opts.Model}
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args = append(args, id)
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT target_id, embedding_blob
+		FROM embeddings
+		WHERE target_type = ? AND model = ? AND target_id IN (`+strings.Join(placeholders, ",")+`)
+	`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("query embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	added := 0
+	for rows.Next() {
+		var id string
+		var blob []byte
+		if err := rows.Scan(&id, &blob); err != nil {
+			return added, fmt.Errorf("scan embedding: %w", err)
+		}
+		// Vectors of a different dimension than the index are recorded but
+		// not searchable, matching the brute-force dimension check
+		if vec := blobToFloat64Slice(blob); len(vec) > 0 {
+			s.index.Add(id, vec)
+		}
+		s.versions[id] = versions[id]
+		added++
+	}
+	return added, rows.Err()
+}
+
+// indexFileName returns a filesystem-safe name for a target type and model.
+func indexFileName(targetType, model string) string {
+	safe := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' {
+				return r
+			}
+			return '_'
+		}, s)
+	}
+	return safe(targetType) + "-" + safe(model) + ".hnsw"
+}
+
+func blobToFloat64Slice(blob []byte) []float64 {
+	if len(blob)%8 != 0 {
+		return nil
+	}
+	values := make([]float64, len(blob)/8)
+	for i := 0; i < len(values); i++ {
+		bits := uint64(0)
+		for j := 0; j < 8; j++ {
+			bits |= uint64(blob[i*8+j]) << (j * 8)
+		}
+		values[i] = math.Float64frombits(bits)
+	}
+	return values
+}
@@ -0,0 +1,171 @@
+package vectorindex
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func randomVectors(n, dim int, seed int64) [][]float64 {
+	rng := rand.New(rand.NewSource(seed))
+	vecs := make([][]float64, n)
+	for i := range vecs {
+		vecs[i] = make([]float64, dim)
+		for j := range vecs[i] {
+			vecs[i][j] = rng.NormFloat64()
+		}
+	}
+	return vecs
+}
+
+func TestHNSW_RecallMatchesFlat(t *testing.T) {
+	vecs := randomVectors(2000, 32, 1)
+	flat, graph := NewFlat(), NewHNSW(HNSWConfig{})
+	for i, v := range vecs {
+		id := fmt.Sprintf("v%d", i)
+		flat.Add(id, v)
+		graph.Add(id, v)
+	}
+
+	const k = 10
+	hits, total := 0, 0
+	for _, q := range randomVectors(50, 32, 2) {
+		want := make(map[string]bool)
+		for _, m := range flat.Search(q, k) {
+			want[m.ID] = true
+		}
+		for _, m := range graph.Search(q, k) {
+			if want[m.ID] {
+				hits++
+			}
+		}
+		total += k
+	}
+	if recall := float64(hits) / float64(total); recall < 0.95 {
+		t.Errorf("recall@%d = %.3f, want >= 0.95", k, recall)
+	}
+}
+
+func TestHNSW_RemoveAndReplace(t *testing.T) {
+	h := NewHNSW(HNSWConfig{})
+	h.Add("a", []float64{1, 0})
+	h.Add("b", []float64{0, 1})
+	h.Add("c", []float64{0.9, 0.1})
+
+	h.Remove("a")
+	if got := h.Search([]float64{1, 0}, 1); len(got) != 1 || got[0].ID != "c" {
+		t.Errorf("after remove = %+v, want c", got)
+	}
+
+	h.Add("b", []float64{1, 0})
+	got := h.Search([]float64{1, 0}, 3)
+	if len(got) != 2 || got[0].ID != "b" || math.Abs(got[0].Score-1) > 1e-6 {
+		t.Errorf("after replace = %+v, want b first with score 1", got)
+	}
+	if h.Len() != 2 || h.Deleted() != 2 {
+		t.Errorf("Len = %d, Deleted = %d, want 2, 2", h.Len(), h.Deleted())
+	}
+}
+
+func insertEmbedding(t *testing.T, db *sql.DB, id, hash string, vec []float64) {
+	t.Helper()
+	blob := make([]byte, len(vec)*8)
+	for i, v := range vec {
+		binary.LittleEndian.PutUint64(blob[i*8:], math.Float64bits(v))
+	}
+	_, err := db.Exec(`
+		INSERT INTO embeddings (id, target_type, target_id, model, embedding_blob, dimension, source_text_hash, created_at)
+		VALUES (?, 'episode', ?, 'test-model', ?, ?, ?, 0)
+		ON CONFLICT(target_type, target_id, model) DO UPDATE SET
+			embedding_blob = excluded.embedding_blob, source_text_hash = excluded.source_text_hash
+	`, "emb-"+id, id, blob, len(vec), hash)
+	if err != nil {
+		t.Fatalf("insert embedding: %v", err)
+	}
+}
+
+func TestStore_SyncIsIncremental(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	insertEmbedding(t, db, "a", "h1", []float64{1, 0})
+	insertEmbedding(t, db, "b", "h1", []float64{0, 1})
+
+	store := NewStore(db, Options{TargetType: "episode", Model: "test-model"})
+	stats, err := store.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if stats.Added != 2 || store.Backend() != BackendFlat {
+		t.Errorf("first sync = %+v on %s", stats, store.Backend())
+	}
+
+	// Unchanged embeddings aren't reloaded
+	if stats, _ := store.Sync(ctx); stats.Added != 0 || stats.Removed != 0 {
+		t.Errorf("no-op sync = %+v", stats)
+	}
+
+	// Re-embedding "a" and deleting "b" are both picked up
+	insertEmbedding(t, db, "a", "h2", []float64{0, 1})
+	db.Exec(`DELETE FROM embeddings WHERE target_id = 'b'`)
+	stats, _ = store.Sync(ctx)
+	if stats.Added != 1 || stats.Removed != 1 {
+		t.Errorf("update sync = %+v, want 1 added, 1 removed", stats)
+	}
+	if got := store.Search([]float64{0, 1}, 5); len(got) != 1 || got[0].ID != "a" {
+		t.Errorf("Search = %+v, want only a", got)
+	}
+}
+
+func TestStore_PersistsHNSW(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	for i, v := range randomVectors(20, 8, 3) {
+		insertEmbedding(t, db, fmt.Sprintf("v%d", i), "h", v)
+	}
+	opts := Options{TargetType: "episode", Model: "test-model", Dir: dir, HNSWThreshold: 10}
+
+	store := NewStore(db, opts)
+	stats, err := store.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if !stats.Rebuilt || store.Backend() != BackendHNSW {
+		t.Fatalf("sync = %+v on %s, want HNSW rebuild", stats, store.Backend())
+	}
+	query := randomVectors(1, 8, 4)[0]
+	want := store.Search(query, 5)
+
+	// A fresh store loads the graph and only syncs the new embedding
+	insertEmbedding(t, db, "new", "h", query)
+	reloaded := NewStore(db, opts)
+	if reloaded.Backend() != BackendHNSW || reloaded.Len() != 20 {
+		t.Fatalf("reloaded %s with %d vectors, want hnsw with 20", reloaded.Backend(), reloaded.Len())
+	}
+	stats, err = reloaded.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Sync reloaded: %v", err)
+	}
+	if stats.Added != 1 || stats.Rebuilt {
+		t.Errorf("reloaded sync = %+v, want 1 added", stats)
+	}
+	got := reloaded.Search(query, 6)
+	if len(got) != 6 || got[0].ID != "new" {
+		t.Fatalf("reloaded Search = %+v, want new first", got)
+	}
+	for i, m := range want {
+		if got[i+1].ID != m.ID {
+			t.Errorf("result %d = %s, want %s", i+1, got[i+1].ID, m.ID)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Napageneral/mnemonic/internal/adapters"
+)
+
+// ImportResult is the outcome of importing one inbox file.
+type ImportResult struct {
+	File           string `json:"file"`
+	Source         string `json:"source"`
+	EventsCreated  int    `json:"events_created"`
+	EventsUpdated  int    `json:"events_updated"`
+	ThreadsCreated int    `json:"threads_created"`
+	Error          string `json:"error,omitempty"`
+}
+
+// ImportInbox imports the files waiting in an inbox directory. Files are
+// JSONL, one event per line in the adapter plugin format, under a
+// subdirectory named after their source: <inbox>/<source>/<file>.jsonl.
+// Each file is imported in one transaction and then moved to done/ in its
+// source directory, or to failed/ with a .error note. Write files
+// elsewhere and move them in, so a half-written file is never read.
+func ImportInbox(ctx context.Context, db *sql.DB, inbox string) ([]ImportResult, error) {
+	sources, err := os.ReadDir(inbox)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read inbox: %w", err)
+	}
+
+	var results []ImportResult
+	for _, src := range sources {
+		if !src.IsDir() || strings.HasPrefix(src.Name(), ".") {
+			continue
+		}
+		dir := filepath.Join(inbox, src.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return results, fmt.Errorf("read inbox: %w", err)
+		}
+		var files []string
+		for _, e := range entries {
+			if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".jsonl") && !strings.HasPrefix(e.Name(), ".") {
+				files = append(files, e.Name())
+			}
+		}
+		sort.Strings(files)
+		for _, name := range files {
+			if ctx.Err() != nil {
+				return results, ctx.Err()
+			}
+			res := importFile(ctx, db, src.Name(), filepath.Join(dir, name))
+			to := "done"
+			if res.Error != "" {
+				to = "failed"
+			}
+			if err := moveInto(filepath.Join(dir, name), filepath.Join(dir, to)); err != nil {
+				return append(results, res), fmt.Errorf("move %s: %w", name, err)
+			}
+			if res.Error != "" {
+				_ = os.WriteFile(filepath.Join(dir, to, name+".error"), []byte(res.Error+"\n"), 0o644)
+			}
+			results = append(results, res)
+		}
+	}
+	return results, nil
+}
+
+func importFile(ctx context.Context, db *sql.DB, source, path string) ImportResult {
+	res := ImportResult{File: filepath.Join(source, filepath.Base(path)), Source: source}
+	f, err := os.Open(path)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer f.Close()
+
+	var events []adapters.PluginEvent
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var ev adapters.PluginEvent
+		if err := json.Unmarshal([]byte(text), &ev); err != nil {
+			res.Error = fmt.Sprintf("line %d: %v", line, err)
+			return res
+		}
+		events = append(events, ev)
+	}
+	if err := scanner.Err(); err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if len(events) == 0 {
+		return res
+	}
+
+	sr, err := adapters.IngestEvents(ctx, db, source, events)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.EventsCreated = sr.EventsCreated
+	res.EventsUpdated = sr.EventsUpdated
+	res.ThreadsCreated = sr.ThreadsCreated
+	return res
+}
+
+// moveInto moves a file into dir, replacing a file of the same name.
+func moveInto(path, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.Rename(path, filepath.Join(dir, filepath.Base(path)))
+}
@@ -0,0 +1,363 @@
+// Package worker is the headless processing mode behind 'worker', for
+// containers and NAS boxes. A worker runs no local source adapters: events
+// arrive over the API (POST /api/events) or as files dropped into an inbox
+// directory. Each cycle it imports the inbox, chunks new events into
+// episodes, queues analysis and embeddings and drains the queue, while
+// serving the HTTP API and health endpoints.
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/chunk"
+	"github.com/Napageneral/mnemonic/internal/compute"
+	"github.com/Napageneral/mnemonic/internal/gemini"
+	"github.com/Napageneral/mnemonic/internal/maintenance"
+	"github.com/Napageneral/mnemonic/internal/server"
+)
+
+// Environment variables read by ConfigFromEnv.
+const (
+	EnvBind           = "MNEMONIC_WORKER_BIND"     // default 0.0.0.0
+	EnvPort           = "MNEMONIC_WORKER_PORT"     // default 8787
+	EnvInterval       = "MNEMONIC_WORKER_INTERVAL" // time between cycles, default 5m
+	EnvInbox          = "MNEMONIC_WORKER_INBOX"    // default <data dir>/inbox; "off" disables
+	EnvAnalysis       = "MNEMONIC_WORKER_ANALYSIS" // comma-separated analysis types; "off" disables
+	EnvComputeWorkers = "MNEMONIC_WORKER_COMPUTE_WORKERS"
+	EnvGeminiAPIKey   = "GEMINI_API_KEY"
+)
+
+// DefaultAnalysisType is queued for new episodes unless EnvAnalysis says
+// otherwise.
+const DefaultAnalysisType = "convo-all-v1"
+
+// Config is a worker's configuration.
+type Config struct {
+	Bind           string
+	Port           int
+	Interval       time.Duration
+	InboxDir       string   // "" disables inbox imports
+	AnalysisTypes  []string // queued for new episodes each cycle
+	ComputeWorkers int
+	GeminiAPIKey   string // without one, episodes are built but not analyzed
+}
+
+// Addr is the address the API listens on.
+func (c Config) Addr() string {
+	return net.JoinHostPort(c.Bind, strconv.Itoa(c.Port))
+}
+
+// ConfigFromEnv reads a Config from the environment (see the Env
+// constants). dataDir is where the default inbox lives.
+func ConfigFromEnv(getenv func(string) string, dataDir string) (Config, error) {
+	cfg := Config{
+		Bind:           "0.0.0.0",
+		Port:           8787,
+		Interval:       5 * time.Minute,
+		InboxDir:       filepath.Join(dataDir, "inbox"),
+		AnalysisTypes:  []string{DefaultAnalysisType},
+		ComputeWorkers: 10,
+		GeminiAPIKey:   strings.TrimSpace(getenv(EnvGeminiAPIKey)),
+	}
+	if v := strings.TrimSpace(getenv(EnvBind)); v != "" {
+		cfg.Bind = v
+	}
+	if v := strings.TrimSpace(getenv(EnvPort)); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil || port <= 0 || port > 65535 {
+			return cfg, fmt.Errorf("%s: invalid port %q", EnvPort, v)
+		}
+		cfg.Port = port
+	}
+	if v := strings.TrimSpace(getenv(EnvInterval)); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Second {
+			return cfg, fmt.Errorf("%s: invalid interval %q (e.g. 30s, 5m)", EnvInterval, v)
+		}
+		cfg.Interval = d
+	}
+	if v := strings.TrimSpace(getenv(EnvInbox)); v != "" {
+		cfg.InboxDir = v
+		if isOff(v) {
+			cfg.InboxDir = ""
+		}
+	}
+	if v := strings.TrimSpace(getenv(EnvAnalysis)); v != "" {
+		cfg.AnalysisTypes = nil
+		if !isOff(v) {
+			for _, t := range strings.Split(v, ",") {
+				if t = strings.TrimSpace(t); t != "" {
+					cfg.AnalysisTypes = append(cfg.AnalysisTypes, t)
+				}
+			}
+		}
+	}
+	if v := strings.TrimSpace(getenv(EnvComputeWorkers)); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("%s: invalid worker count %q", EnvComputeWorkers, v)
+		}
+		cfg.ComputeWorkers = n
+	}
+	return cfg, nil
+}
+
+func isOff(v string) bool {
+	switch strings.ToLower(v) {
+	case "off", "none", "false", "0":
+		return true
+	}
+	return false
+}
+
+// CycleResult is what one processing cycle did.
+type CycleResult struct {
+	StartedAt       time.Time      `json:"started_at"`
+	Duration        time.Duration  `json:"duration"`
+	Imports         []ImportResult `json:"imports,omitempty"`
+	EpisodesCreated int            `json:"episodes_created"`
+	JobsEnqueued    int            `json:"jobs_enqueued"`
+	JobsSucceeded   int            `json:"jobs_succeeded"`
+	JobsFailed      int            `json:"jobs_failed"`
+	Errors          []string       `json:"errors,omitempty"`
+}
+
+// Status is a worker's state, as reported by /readyz.
+type Status struct {
+	StartedAt time.Time    `json:"started_at"`
+	Cycles    int          `json:"cycles"`
+	Running   bool         `json:"running"` // a cycle is in progress
+	LastCycle *CycleResult `json:"last_cycle,omitempty"`
+	Analysis  bool         `json:"analysis"` // analysis and embeddings run
+}
+
+// Worker runs the headless processing loop and serves the API.
+type Worker struct {
+	DB     *sql.DB
+	Config Config
+
+	// Compute configures the engine that drains the job queue; its worker
+	// count comes from Config.
+	Compute compute.Config
+	// Gemini runs analysis and embeddings; nil without an API key.
+	Gemini *gemini.Client
+	// Maintenance runs alongside the cycles when set.
+	Maintenance *maintenance.Scheduler
+	Logf        func(format string, args ...any)
+
+	mu     sync.Mutex
+	status Status
+}
+
+// New creates a worker over db.
+func New(db *sql.DB, cfg Config) *Worker {
+	computeCfg := compute.DefaultConfig()
+	computeCfg.WorkerCount = cfg.ComputeWorkers
+	w := &Worker{
+		DB:      db,
+		Config:  cfg,
+		Compute: computeCfg,
+		Logf:    func(string, ...any) {},
+	}
+	if cfg.GeminiAPIKey != "" {
+		w.Gemini = gemini.NewClient(cfg.GeminiAPIKey)
+	}
+	return w
+}
+
+// Status returns a snapshot of the worker's state.
+func (w *Worker) Status() Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := w.status
+	s.Analysis = w.Gemini != nil && len(w.Config.AnalysisTypes) > 0
+	return s
+}
+
+// Handler serves the API plus /readyz, which reports the worker's status
+// and fails with 503 while the database is unreachable. /healthz (from the
+// API) only says the process is up.
+func (w *Worker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /readyz", func(rw http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		status := http.StatusOK
+		body := map[string]any{"ok": true, "worker": w.Status()}
+		if err := w.DB.PingContext(ctx); err != nil {
+			status = http.StatusServiceUnavailable
+			body["ok"] = false
+			body["message"] = "database unavailable: " + err.Error()
+		}
+		writeJSON(rw, status, body)
+	})
+	mux.Handle("/", server.New(w.DB))
+	return mux
+}
+
+// Run serves the API and runs a cycle every Config.Interval until ctx is
+// done, then shuts the API down.
+func (w *Worker) Run(ctx context.Context) error {
+	w.mu.Lock()
+	w.status.StartedAt = time.Now()
+	w.mu.Unlock()
+
+	// Listen before the first cycle, so a taken port fails at once
+	ln, err := net.Listen("tcp", w.Config.Addr())
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	srv := &http.Server{Handler: w.Handler()}
+	serveErr := make(chan error, 1)
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	if w.Maintenance != nil {
+		go w.Maintenance.Run(ctx)
+	}
+
+	ticker := time.NewTicker(w.Config.Interval)
+	defer ticker.Stop()
+	for {
+		res := w.Cycle(ctx)
+		if res.EpisodesCreated > 0 || res.JobsEnqueued > 0 || res.JobsSucceeded > 0 || res.JobsFailed > 0 {
+			w.Logf("cycle: %d episodes created, %d jobs queued, %d succeeded, %d failed (%s)",
+				res.EpisodesCreated, res.JobsEnqueued, res.JobsSucceeded, res.JobsFailed, res.Duration.Round(time.Millisecond))
+		}
+		if len(res.Errors) > 0 {
+			w.Logf("cycle finished with errors: %s", strings.Join(res.Errors, "; "))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-serveErr:
+			if ok {
+				return fmt.Errorf("serve %s: %w", w.Config.Addr(), err)
+			}
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Cycle imports the inbox, chunks new events into episodes and, with an
+// API key, queues analysis and embeddings and drains the queue. Failed
+// steps are recorded in the result; later steps still run.
+func (w *Worker) Cycle(ctx context.Context) CycleResult {
+	res := CycleResult{StartedAt: time.Now()}
+	w.mu.Lock()
+	w.status.Running = true
+	w.mu.Unlock()
+	defer func() {
+		res.Duration = time.Since(res.StartedAt)
+		w.mu.Lock()
+		w.status.Running = false
+		w.status.Cycles++
+		w.status.LastCycle = &res
+		w.mu.Unlock()
+	}()
+
+	if w.Config.InboxDir != "" {
+		imports, err := ImportInbox(ctx, w.DB, w.Config.InboxDir)
+		if err != nil {
+			res.Errors = append(res.Errors, err.Error())
+		}
+		for _, imp := range imports {
+			if imp.Error != "" {
+				res.Errors = append(res.Errors, fmt.Sprintf("import %s: %s", imp.File, imp.Error))
+			} else {
+				w.Logf("imported %s: %d events created, %d updated", imp.File, imp.EventsCreated, imp.EventsUpdated)
+			}
+		}
+		res.Imports = imports
+	}
+
+	defs, err := chunk.ListDefinitions(ctx, w.DB)
+	if err != nil {
+		res.Errors = append(res.Errors, err.Error())
+	}
+	for _, def := range defs {
+		if ctx.Err() != nil {
+			return res
+		}
+		chunker, err := chunk.GetChunkerForDefinition(ctx, w.DB, def.ID)
+		if err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("chunk %s: %v", def.Name, err))
+			continue
+		}
+		cr, err := chunker.Chunk(ctx, w.DB, def.ID)
+		if err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("chunk %s: %v", def.Name, err))
+			continue
+		}
+		res.EpisodesCreated += cr.EpisodesCreated
+	}
+
+	if w.Gemini == nil || len(w.Config.AnalysisTypes) == 0 || ctx.Err() != nil {
+		return res
+	}
+	if err := w.process(ctx, &res); err != nil {
+		res.Errors = append(res.Errors, err.Error())
+	}
+	return res
+}
+
+// process queues analysis and embeddings for new episodes and drains the
+// job queue.
+func (w *Worker) process(ctx context.Context, res *CycleResult) error {
+	engine, err := compute.NewEngine(w.DB, w.Gemini, w.Compute)
+	if err != nil {
+		return fmt.Errorf("create compute engine: %w", err)
+	}
+	defer engine.Close()
+
+	for _, analysisType := range w.Config.AnalysisTypes {
+		n, err := engine.EnqueueAnalysis(ctx, analysisType)
+		if err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("enqueue %s: %v", analysisType, err))
+			continue
+		}
+		res.JobsEnqueued += n
+	}
+	n, err := engine.EnqueueEmbeddings(ctx)
+	if err != nil {
+		res.Errors = append(res.Errors, fmt.Sprintf("enqueue embeddings: %v", err))
+	}
+	res.JobsEnqueued += n
+
+	stats, err := engine.Run(ctx)
+	if stats != nil {
+		res.JobsSucceeded = stats.Succeeded
+		res.JobsFailed = stats.Failed
+	}
+	if err != nil {
+		return fmt.Errorf("compute: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
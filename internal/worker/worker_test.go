@@ -0,0 +1,129 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Napageneral/mnemonic/internal/chunk"
+	"github.com/Napageneral/mnemonic/internal/testutil"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	env := map[string]string{}
+	getenv := func(k string) string { return env[k] }
+
+	cfg, err := ConfigFromEnv(getenv, "/data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr() != "0.0.0.0:8787" || cfg.InboxDir != "/data/inbox" || cfg.Interval != 5*time.Minute ||
+		len(cfg.AnalysisTypes) != 1 || cfg.AnalysisTypes[0] != DefaultAnalysisType {
+		t.Errorf("defaults = %+v", cfg)
+	}
+
+	env = map[string]string{
+		EnvBind: "127.0.0.1", EnvPort: "9000", EnvInterval: "30s", EnvInbox: "off",
+		EnvAnalysis: "convo-all-v1, pii_extraction_v1", EnvComputeWorkers: "4", EnvGeminiAPIKey: "k",
+	}
+	cfg, err = ConfigFromEnv(getenv, "/data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr() != "127.0.0.1:9000" || cfg.InboxDir != "" || cfg.Interval != 30*time.Second ||
+		len(cfg.AnalysisTypes) != 2 || cfg.ComputeWorkers != 4 || cfg.GeminiAPIKey != "k" {
+		t.Errorf("overrides = %+v", cfg)
+	}
+
+	for k, v := range map[string]string{EnvPort: "http", EnvInterval: "10ms", EnvComputeWorkers: "0"} {
+		env = map[string]string{k: v}
+		if _, err := ConfigFromEnv(getenv, "/data"); err == nil || !strings.Contains(err.Error(), k) {
+			t.Errorf("%s=%s: err = %v", k, v, err)
+		}
+	}
+}
+
+func TestCycleImportsInboxAndChunks(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := chunk.CreateDefinition(ctx, db, "threads", "", "thread", chunk.ThreadConfig{}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	inbox := t.TempDir()
+	forum := filepath.Join(inbox, "forum")
+	if err := os.MkdirAll(forum, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	good := `{"id": "p1", "timestamp": 1767225600, "content": "hello", "thread_id": "t1", "sender": {"identifier": "bob@example.com"}}
+
+{"id": "p2", "timestamp": 1767225660, "content": "hi bob", "thread_id": "t1", "direction": "sent"}
+`
+	if err := os.WriteFile(filepath.Join(forum, "batch-1.jsonl"), []byte(good), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(forum, "batch-2.jsonl"), []byte("{not json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(forum, "notes.txt"), []byte("ignored"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := New(db, Config{InboxDir: inbox, AnalysisTypes: []string{DefaultAnalysisType}, ComputeWorkers: 1})
+	res := w.Cycle(ctx)
+	if len(res.Imports) != 2 || res.Imports[0].EventsCreated != 2 || res.Imports[1].Error == "" {
+		t.Fatalf("imports = %+v", res.Imports)
+	}
+	if len(res.Errors) != 1 || !strings.Contains(res.Errors[0], "batch-2.jsonl: line 1") {
+		t.Errorf("errors = %q", res.Errors)
+	}
+	if res.EpisodesCreated != 1 || res.JobsEnqueued != 0 {
+		t.Errorf("episodes = %d, jobs = %d (no API key, so no jobs)", res.EpisodesCreated, res.JobsEnqueued)
+	}
+	for _, path := range []string{"done/batch-1.jsonl", "failed/batch-2.jsonl", "failed/batch-2.jsonl.error", "notes.txt"} {
+		if _, err := os.Stat(filepath.Join(forum, path)); err != nil {
+			t.Errorf("missing %s: %v", path, err)
+		}
+	}
+
+	// The next cycle finds nothing new
+	res = w.Cycle(ctx)
+	if len(res.Imports) != 0 || res.EpisodesCreated != 0 || len(res.Errors) != 0 {
+		t.Errorf("second cycle = %+v", res)
+	}
+	if s := w.Status(); s.Cycles != 2 || s.Running || s.LastCycle == nil || s.Analysis {
+		t.Errorf("status = %+v", s)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	w := New(db, Config{})
+
+	get := func(path string) (int, string) {
+		rec := httptest.NewRecorder()
+		w.Handler().ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Code, rec.Body.String()
+	}
+	if code, body := get("/readyz"); code != http.StatusOK || !strings.Contains(body, `"cycles":0`) {
+		t.Errorf("readyz = %d %s", code, body)
+	}
+	if code, _ := get("/healthz"); code != http.StatusOK {
+		t.Errorf("healthz = %d", code)
+	}
+	if code, _ := get("/api/events"); code != http.StatusUnauthorized {
+		t.Errorf("API without a token = %d, want 401", code)
+	}
+
+	db.Close()
+	if code, body := get("/readyz"); code != http.StatusServiceUnavailable || !strings.Contains(body, "database unavailable") {
+		t.Errorf("readyz with a closed database = %d %s", code, body)
+	}
+}
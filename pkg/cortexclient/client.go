@@ -0,0 +1,344 @@
+// Package cortexclient is a typed Go client for the HTTP API served by
+// 'serve', so other programs can read the graph, follow changes, review
+// merges and record drafts without importing internal packages.
+//
+// Every call needs an API token (see 'token create') whose scopes cover the
+// endpoint; a missing scope comes back as an *APIError with status 403.
+//
+//	c := cortexclient.New("http://127.0.0.1:8787", os.Getenv("CORTEX_TOKEN"))
+//	matches, err := c.FindEntities(ctx, "Casey", "")
+package cortexclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Client calls one server with one token. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests (default
+// http.DefaultClient), e.g. to set a timeout or transport.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// New creates a client for the server at baseURL (e.g.
+// "http://127.0.0.1:8787") authenticating with token.
+func New(baseURL, token string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is a non-2xx response from the server.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("cortex api: %d %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from the server.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Health checks that the server is up. It needs no token.
+func (c *Client) Health(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/healthz", nil, nil, nil)
+}
+
+// FindEntities returns entities whose name or aliases match name, optionally
+// restricted to an entity type name such as "Person" (read-graph).
+func (c *Client) FindEntities(ctx context.Context, name, entityType string) ([]Entity, error) {
+	q := url.Values{"name": {name}}
+	if entityType != "" {
+		q.Set("type", entityType)
+	}
+	var resp struct {
+		Entities []Entity `json:"entities"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/entities", q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Entities, nil
+}
+
+// GetEntity returns an entity with its aliases and current relationships
+// (read-graph). An unknown ID is an error for which IsNotFound is true.
+func (c *Client) GetEntity(ctx context.Context, id string) (*EntityDetail, error) {
+	var resp EntityDetail
+	if err := c.do(ctx, http.MethodGet, "/api/entities/"+url.PathEscape(id), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetEntityChanges returns changes to an entity after sinceCursor, oldest
+// first (read-graph). Keep the page's Cursor and pass it next time; More
+// means another page is already waiting.
+func (c *Client) GetEntityChanges(ctx context.Context, entityID string, sinceCursor int64) (*EntityChangesPage, error) {
+	q := url.Values{"since": {strconv.FormatInt(sinceCursor, 10)}}
+	var resp EntityChangesPage
+	if err := c.do(ctx, http.MethodGet, "/api/entities/"+url.PathEscape(entityID)+"/changes", q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Changes reads the global change log after opts.Since (read-graph).
+func (c *Client) Changes(ctx context.Context, opts ChangesOptions) (*ChangesPage, error) {
+	q := url.Values{"since": {strconv.FormatInt(opts.Since, 10)}}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if len(opts.Types) > 0 {
+		q.Set("type", strings.Join(opts.Types, ","))
+	}
+	var resp ChangesPage
+	if err := c.do(ctx, http.MethodGet, "/api/changes", q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// EntityAvatar returns the photo of the person linked to an entity
+// (read-graph). No photo is an error for which IsNotFound is true.
+func (c *Client) EntityAvatar(ctx context.Context, entityID string) (*Avatar, error) {
+	return c.avatar(ctx, "/api/entities/"+url.PathEscape(entityID)+"/avatar")
+}
+
+// PersonAvatar returns a person's photo (read-graph).
+func (c *Client) PersonAvatar(ctx context.Context, personID string) (*Avatar, error) {
+	return c.avatar(ctx, "/api/persons/"+url.PathEscape(personID)+"/avatar")
+}
+
+// MergeCandidates returns pending merge candidates, most confident first
+// (read-graph). limit <= 0 uses the server default.
+func (c *Client) MergeCandidates(ctx context.Context, limit int) ([]MergeCandidate, error) {
+	var q url.Values
+	if limit > 0 {
+		q = url.Values{"limit": {strconv.Itoa(limit)}}
+	}
+	var resp struct {
+		Candidates []MergeCandidate `json:"candidates"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/merge-candidates", q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Candidates, nil
+}
+
+// AcceptMergeCandidate executes a pending merge (write-merges). A candidate
+// that is no longer pending is a 409 APIError.
+func (c *Client) AcceptMergeCandidate(ctx context.Context, id string) (*MergeResult, error) {
+	var resp struct {
+		Merge MergeResult `json:"merge"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/merge-candidates/"+url.PathEscape(id)+"/accept", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Merge, nil
+}
+
+// RejectMergeCandidate rejects a pending merge with an optional reason
+// (write-merges).
+func (c *Client) RejectMergeCandidate(ctx context.Context, id, reason string) error {
+	body := map[string]string{"reason": reason}
+	return c.do(ctx, http.MethodPost, "/api/merge-candidates/"+url.PathEscape(id)+"/reject", nil, body, nil)
+}
+
+// Events returns messages, newest first (read-events).
+func (c *Client) Events(ctx context.Context, opts EventsOptions) ([]Event, error) {
+	q := url.Values{}
+	if opts.ThreadID != "" {
+		q.Set("thread_id", opts.ThreadID)
+	}
+	if opts.Channel != "" {
+		q.Set("channel", opts.Channel)
+	}
+	if opts.Before > 0 {
+		q.Set("before", strconv.FormatInt(opts.Before, 10))
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	var resp struct {
+		Events []Event `json:"events"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/events", q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Events, nil
+}
+
+// IngestEvents stores a batch of events under the adapter name source, in
+// one transaction (write-events). Resent events are updated in place.
+func (c *Client) IngestEvents(ctx context.Context, source string, events []NewEvent) (*IngestResult, error) {
+	body := map[string]any{"source": source, "events": events}
+	var resp IngestResult
+	if err := c.do(ctx, http.MethodPost, "/api/events", nil, body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Drafts returns drafts and talking points, newest first (read-events).
+func (c *Client) Drafts(ctx context.Context, opts DraftsOptions) ([]Draft, error) {
+	q := url.Values{}
+	if opts.PersonID != "" {
+		q.Set("person_id", opts.PersonID)
+	}
+	if opts.Status != "" {
+		q.Set("status", opts.Status)
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	var resp struct {
+		Drafts []Draft `json:"drafts"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/drafts", q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Drafts, nil
+}
+
+// AddDraft records a draft or talking point (write-drafts).
+func (c *Client) AddDraft(ctx context.Context, d NewDraft) (*Draft, error) {
+	var resp struct {
+		Draft Draft `json:"draft"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/drafts", nil, d, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Draft, nil
+}
+
+// SetDraftStatus marks a draft sent, discarded or pending (write-drafts).
+func (c *Client) SetDraftStatus(ctx context.Context, id, status string) error {
+	body := map[string]string{"status": status}
+	return c.do(ctx, http.MethodPost, "/api/drafts/"+url.PathEscape(id)+"/status", nil, body, nil)
+}
+
+// Tokens lists API tokens, without their secrets (admin).
+func (c *Client) Tokens(ctx context.Context) ([]Token, error) {
+	var resp struct {
+		Tokens []Token `json:"tokens"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/tokens", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Tokens, nil
+}
+
+func (c *Client) avatar(ctx context.Context, path string) (*Avatar, error) {
+	resp, err := c.send(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, decodeError(resp)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read avatar: %w", err)
+	}
+	return &Avatar{
+		Data:        data,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        resp.Header.Get("ETag"),
+	}, nil
+}
+
+// do sends a request with an optional JSON body and decodes a JSON response
+// into out (when non-nil).
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return decodeError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body any) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encode request: %w", err)
+		}
+		reader = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	return resp, nil
+}
+
+// decodeError turns an error response ({"ok": false, "message": ...}) into
+// an *APIError.
+func decodeError(resp *http.Response) error {
+	var body struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err := json.Unmarshal(data, &body); err != nil || body.Message == "" {
+		body.Message = strings.TrimSpace(string(data))
+	}
+	if body.Message == "" {
+		body.Message = http.StatusText(resp.StatusCode)
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: body.Message}
+}
@@ -0,0 +1,134 @@
+package cortexclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Napageneral/mnemonic/internal/server"
+	"github.com/Napageneral/mnemonic/internal/testutil"
+	"github.com/Napageneral/mnemonic/pkg/cortexclient"
+)
+
+func TestClientAgainstServer(t *testing.T) {
+	db := testutil.OpenTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`
+		INSERT INTO entities (id, canonical_name, entity_type_id, origin, created_at, updated_at) VALUES
+			('casey', 'Casey', 1, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z'),
+			('casey-2', 'Casey', 1, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z'),
+			('acme', 'Acme', 2, 'extracted', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z');
+		INSERT INTO relationships (id, source_entity_id, target_entity_id, relation_type, fact, created_at)
+			VALUES ('r1', 'casey', 'acme', 'WORKS_AT', 'Casey works at Acme', '2026-01-01T00:00:00Z');
+		INSERT INTO merge_candidates (id, entity_a_id, entity_b_id, confidence, auto_eligible, reason, status, created_at)
+			VALUES ('mc-1', 'casey-2', 'casey', 0.8, 0, 'same name', 'pending', '2026-01-01T00:00:00Z');
+		INSERT INTO events (id, timestamp, channel, content_types, content, direction, source_adapter, source_id)
+			VALUES ('ev-1', 1767225600, 'imessage', '["text"]', 'see you at 6', 'received', 'test', 'ev-1');
+	`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	_, readOnly, err := server.CreateToken(db, "widget", []string{"read-graph"})
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+	_, admin, err := server.CreateToken(db, "sync", []string{"admin"})
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+
+	ts := httptest.NewServer(server.New(db))
+	defer ts.Close()
+	ctx := context.Background()
+	c := cortexclient.New(ts.URL+"/", admin)
+
+	if err := c.Health(ctx); err != nil {
+		t.Fatalf("Health: %v", err)
+	}
+
+	found, err := c.FindEntities(ctx, "Acme", "")
+	if err != nil || len(found) != 1 || found[0].ID != "acme" {
+		t.Fatalf("FindEntities = %+v, %v", found, err)
+	}
+	detail, err := c.GetEntity(ctx, "casey")
+	if err != nil {
+		t.Fatalf("GetEntity: %v", err)
+	}
+	if detail.Entity.CanonicalName != "Casey" || len(detail.Relationships) != 1 || detail.Relationships[0].Fact != "Casey works at Acme" {
+		t.Errorf("GetEntity = %+v", detail)
+	}
+	if _, err := c.GetEntity(ctx, "nobody"); !cortexclient.IsNotFound(err) {
+		t.Errorf("GetEntity(nobody) err = %v, want not found", err)
+	}
+
+	page, err := c.Changes(ctx, cortexclient.ChangesOptions{})
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
+	if len(page.Changes) != 4 || page.Cursor != page.Head {
+		t.Fatalf("Changes = %+v", page)
+	}
+
+	candidates, err := c.MergeCandidates(ctx, 0)
+	if err != nil || len(candidates) != 1 {
+		t.Fatalf("MergeCandidates = %+v, %v", candidates, err)
+	}
+	merge, err := c.AcceptMergeCandidate(ctx, candidates[0].ID)
+	if err != nil || merge.TargetEntityID == "" {
+		t.Fatalf("AcceptMergeCandidate = %+v, %v", merge, err)
+	}
+	var apiErr *cortexclient.APIError
+	if err := c.RejectMergeCandidate(ctx, "mc-1", "dup"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Errorf("RejectMergeCandidate after accept err = %v, want 409", err)
+	}
+
+	next, err := c.Changes(ctx, cortexclient.ChangesOptions{Since: page.Cursor, Types: []string{cortexclient.MergeExecuted}})
+	if err != nil || len(next.Changes) != 1 {
+		t.Fatalf("Changes since cursor = %+v, %v", next, err)
+	}
+	feed, err := c.GetEntityChanges(ctx, merge.TargetEntityID, 0)
+	if err != nil {
+		t.Fatalf("GetEntityChanges: %v", err)
+	}
+	last := feed.Changes[len(feed.Changes)-1]
+	if last.Type != cortexclient.ChangeMerged || last.OtherEntityID != merge.SourceEntityID || feed.More {
+		t.Errorf("entity feed = %+v", feed)
+	}
+
+	events, err := c.Events(ctx, cortexclient.EventsOptions{Channel: "imessage"})
+	if err != nil || len(events) != 1 || events[0].Content != "see you at 6" {
+		t.Fatalf("Events = %+v, %v", events, err)
+	}
+	ingested, err := c.IngestEvents(ctx, "forum", []cortexclient.NewEvent{{
+		ID: "p1", Timestamp: 1767225600, Content: "new post", ThreadID: "t1",
+		Sender: &cortexclient.EventContact{Identifier: "bob@example.com", Name: "Bob"},
+	}})
+	if err != nil || ingested.EventsCreated != 1 || ingested.ThreadsCreated != 1 {
+		t.Fatalf("IngestEvents = %+v, %v", ingested, err)
+	}
+	d, err := c.AddDraft(ctx, cortexclient.NewDraft{Content: "ask about the move", Kind: cortexclient.DraftKindTalkingPoint})
+	if err != nil || d.Status != cortexclient.DraftStatusPending {
+		t.Fatalf("AddDraft = %+v, %v", d, err)
+	}
+	if err := c.SetDraftStatus(ctx, d.ID, cortexclient.DraftStatusDiscarded); err != nil {
+		t.Fatalf("SetDraftStatus: %v", err)
+	}
+	if list, err := c.Drafts(ctx, cortexclient.DraftsOptions{}); err != nil || len(list) != 0 {
+		t.Errorf("pending drafts = %+v, %v", list, err)
+	}
+	if tokens, err := c.Tokens(ctx); err != nil || len(tokens) != 2 {
+		t.Errorf("Tokens = %+v, %v", tokens, err)
+	}
+	if _, err := c.EntityAvatar(ctx, "casey"); !cortexclient.IsNotFound(err) {
+		t.Errorf("EntityAvatar err = %v, want not found", err)
+	}
+
+	// Scopes are enforced server-side and surface as 403s
+	widget := cortexclient.New(ts.URL, readOnly)
+	if _, err := widget.Events(ctx, cortexclient.EventsOptions{}); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("read-graph Events err = %v, want 403", err)
+	}
+}
@@ -0,0 +1,257 @@
+package cortexclient
+
+import "time"
+
+// Entity is a node in the graph: a person, company, place, project, ...
+type Entity struct {
+	ID            string  `json:"id"`
+	CanonicalName string  `json:"canonical_name"`
+	EntityTypeID  int     `json:"entity_type_id"`
+	Summary       *string `json:"summary,omitempty"`
+	Origin        string  `json:"origin"` // 'extracted', 'imported', 'contact_seed', 'manual', 'inferred'
+	Confidence    float64 `json:"confidence"`
+	MergedInto    *string `json:"merged_into,omitempty"`
+	CreatedAt     string  `json:"created_at"`
+	UpdatedAt     string  `json:"updated_at"`
+}
+
+// EntityAlias is an identifier or name variant of an entity.
+type EntityAlias struct {
+	ID         string `json:"id"`
+	EntityID   string `json:"entity_id"`
+	Alias      string `json:"alias"`
+	AliasType  string `json:"alias_type"` // 'name', 'email', 'phone', 'handle', ...
+	Normalized string `json:"normalized,omitempty"`
+	IsShared   bool   `json:"is_shared"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// Relationship is a fact connecting an entity to another entity or to a
+// literal (such as a date).
+type Relationship struct {
+	ID             string  `json:"id"`
+	SourceEntityID string  `json:"source_entity_id"`
+	SourceName     string  `json:"source_name"`
+	TargetEntityID *string `json:"target_entity_id,omitempty"`
+	TargetName     *string `json:"target_name,omitempty"`
+	TargetLiteral  *string `json:"target_literal,omitempty"`
+	RelationType   string  `json:"relation_type"`
+	Fact           string  `json:"fact"`
+	ValidAt        *string `json:"valid_at,omitempty"`
+	InvalidAt      *string `json:"invalid_at,omitempty"`
+	CreatedAt      string  `json:"created_at"`
+	Confidence     float64 `json:"confidence"`
+	Direction      string  `json:"direction"` // "outgoing" or "incoming" relative to the entity asked about
+}
+
+// EntityDetail is an entity with its aliases and current relationships.
+type EntityDetail struct {
+	Entity        Entity         `json:"entity"`
+	Aliases       []EntityAlias  `json:"aliases"`
+	Relationships []Relationship `json:"relationships"`
+	AvatarURL     string         `json:"avatar_url,omitempty"`
+}
+
+// Entity change types (EntityChange.Type).
+const (
+	ChangeRelationshipAdded       = "relationship_added"
+	ChangeRelationshipInvalidated = "relationship_invalidated"
+	ChangeMerged                  = "merged"
+	ChangeMergedInto              = "merged_into"
+	ChangeSummaryUpdated          = "summary_updated"
+	ChangeRenamed                 = "renamed"
+)
+
+// EntityChange is one entry in an entity's change feed.
+type EntityChange struct {
+	Seq           int64   `json:"seq"`
+	EntityID      string  `json:"entity_id"`
+	Type          string  `json:"type"`
+	RefID         string  `json:"ref_id,omitempty"`
+	CreatedAt     string  `json:"created_at"`
+	RelationType  string  `json:"relation_type,omitempty"`
+	Fact          string  `json:"fact,omitempty"`
+	InvalidAt     *string `json:"invalid_at,omitempty"`
+	OtherEntityID string  `json:"other_entity_id,omitempty"`
+	OtherName     string  `json:"other_name,omitempty"`
+}
+
+// EntityChangesPage is one page of an entity's change feed.
+type EntityChangesPage struct {
+	Changes []EntityChange `json:"changes"`
+	Cursor  int64          `json:"cursor"`
+	More    bool           `json:"more"`
+}
+
+// Change log types (Change.Type).
+const (
+	EntityCreated     = "entity_created"
+	RelationshipAdded = "relationship_added"
+	MergeExecuted     = "merge_executed"
+	EpisodeProcessed  = "episode_processed"
+)
+
+// Change is one entry in the global change log.
+type Change struct {
+	Seq       int64  `json:"seq"`
+	Type      string `json:"type"`
+	SubjectID string `json:"subject_id"`
+	RefID     string `json:"ref_id,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// ChangesOptions filters Changes. Zero values mean no filter.
+type ChangesOptions struct {
+	Since int64
+	Limit int
+	Types []string
+}
+
+// ChangesPage is one page of the change log. Cursor is the Since for the
+// next call; Cursor == Head means the caller has caught up.
+type ChangesPage struct {
+	Changes []Change `json:"changes"`
+	Cursor  int64    `json:"cursor"`
+	Head    int64    `json:"head"`
+}
+
+// Avatar is a contact photo.
+type Avatar struct {
+	Data        []byte
+	ContentType string
+	ETag        string
+}
+
+// MergeConflict is evidence that two merge candidates are different
+// entities, such as different phone numbers.
+type MergeConflict struct {
+	Type    string   `json:"type"`
+	ValuesA []string `json:"values_a"`
+	ValuesB []string `json:"values_b"`
+}
+
+// MergeCandidate is a proposed merge of two entities.
+type MergeCandidate struct {
+	ID               string                   `json:"id"`
+	EntityAID        string                   `json:"entity_a_id"`
+	EntityBID        string                   `json:"entity_b_id"`
+	Confidence       float64                  `json:"confidence"`
+	AutoEligible     bool                     `json:"auto_eligible"`
+	Reason           string                   `json:"reason"`
+	MatchingFacts    []map[string]interface{} `json:"matching_facts,omitempty"`
+	Context          map[string]interface{}   `json:"context,omitempty"`
+	Conflicts        []MergeConflict          `json:"conflicts,omitempty"`
+	Status           string                   `json:"status"`
+	CreatedAt        string                   `json:"created_at"`
+	ResolvedAt       *string                  `json:"resolved_at,omitempty"`
+	ResolvedBy       *string                  `json:"resolved_by,omitempty"`
+	ResolutionReason *string                  `json:"resolution_reason,omitempty"`
+}
+
+// MergeResult describes an executed merge.
+type MergeResult struct {
+	SourceEntityID string `json:"source_entity_id"` // merged away
+	TargetEntityID string `json:"target_entity_id"` // remains
+	MergeEventID   string `json:"merge_event_id"`
+	AliasesMoved   int    `json:"aliases_moved"`
+	RelationsMoved int    `json:"relations_moved"`
+	MentionsMoved  int    `json:"mentions_moved"`
+}
+
+// Event is a message.
+type Event struct {
+	ID        string `json:"id"`
+	Timestamp int64  `json:"timestamp"` // unix seconds
+	Channel   string `json:"channel"`
+	Direction string `json:"direction"`
+	ThreadID  string `json:"thread_id,omitempty"`
+	Content   string `json:"content"`
+	Unsent    bool   `json:"unsent,omitempty"` // a draft: planned, never sent
+}
+
+// EventsOptions filters Events. Zero values mean no filter.
+type EventsOptions struct {
+	ThreadID string
+	Channel  string
+	Before   int64 // unix seconds
+	Limit    int
+}
+
+// NewEvent is an event to push with IngestEvents. IDs are the source's
+// own; cortex prefixes them with the source name.
+type NewEvent struct {
+	ID         string         `json:"id"`
+	Timestamp  int64          `json:"timestamp"`           // unix seconds
+	Channel    string         `json:"channel,omitempty"`   // default: the source name
+	Direction  string         `json:"direction,omitempty"` // sent, received (default) or observed
+	Content    string         `json:"content"`
+	ThreadID   string         `json:"thread_id,omitempty"`
+	ThreadName string         `json:"thread_name,omitempty"`
+	IsGroup    bool           `json:"is_group,omitempty"`
+	ReplyTo    string         `json:"reply_to,omitempty"` // ID of the event replied to
+	Sender     *EventContact  `json:"sender,omitempty"`
+	Recipients []EventContact `json:"recipients,omitempty"`
+}
+
+// EventContact is a participant of a NewEvent.
+type EventContact struct {
+	Identifier string `json:"identifier"`
+	Type       string `json:"type,omitempty"` // email, phone, ...; guessed when empty
+	Name       string `json:"name,omitempty"`
+}
+
+// IngestResult counts what an IngestEvents batch changed.
+type IngestResult struct {
+	EventsCreated  int `json:"events_created"`
+	EventsUpdated  int `json:"events_updated"`
+	ThreadsCreated int `json:"threads_created"`
+	PersonsCreated int `json:"persons_created"`
+}
+
+// Draft kinds and statuses.
+const (
+	DraftKindDraft        = "draft"
+	DraftKindTalkingPoint = "talking_point"
+
+	DraftStatusPending   = "pending"
+	DraftStatusSent      = "sent"
+	DraftStatusDiscarded = "discarded"
+)
+
+// Draft is an unsent message or talking point.
+type Draft struct {
+	ID        string    `json:"id"`
+	Content   string    `json:"content"`
+	Kind      string    `json:"kind"`
+	Status    string    `json:"status"`
+	PersonID  string    `json:"person_id,omitempty"`
+	Recipient string    `json:"recipient,omitempty"`
+	ThreadID  string    `json:"thread_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewDraft is a draft to record with AddDraft.
+type NewDraft struct {
+	Content  string `json:"content"`
+	Kind     string `json:"kind,omitempty"` // default DraftKindDraft
+	PersonID string `json:"person_id,omitempty"`
+	ThreadID string `json:"thread_id,omitempty"`
+}
+
+// DraftsOptions filters Drafts. Status defaults to pending; "all" lists
+// every status.
+type DraftsOptions struct {
+	PersonID string
+	Status   string
+	Limit    int
+}
+
+// Token is an API token (never its secret).
+type Token struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}